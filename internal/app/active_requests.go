@@ -2,6 +2,8 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -23,6 +25,7 @@ type ActiveRequest struct {
 	APIKeyUsed          string  `json:"api_key_used,omitempty"`           // 脱敏后的key
 	TokenID             int64   `json:"token_id,omitempty"`               // 令牌ID（用于前端筛选，0表示无令牌）
 	BytesReceived       int64   `json:"bytes_received,omitempty"`         // 上游已返回的字节数（快照）
+	LastActivity        int64   `json:"last_activity,omitempty"`          // 最近一次收到上游字节的时间（Unix毫秒，0表示尚未收到）
 	ClientFirstByteTime float64 `json:"client_first_byte_time,omitempty"` // 客户端侧首字节响应时间（秒），流式请求有效
 }
 
@@ -37,8 +40,10 @@ type activeRequest struct {
 	ChannelType string
 	APIKeyUsed  string
 	TokenID     int64
+	cancel      context.CancelCauseFunc // 管理员取消（2026-10新增；nil 表示不可取消）
 
 	bytesCounter            atomic.Int64 // 上游已返回的字节数（原子累加）
+	lastActivityMs          atomic.Int64 // 最近一次收到上游字节的时间（Unix毫秒）
 	clientFirstByteTimeUsec atomic.Int64 // 客户端侧首字节响应时间（微秒），CAS保证只写一次，0表示未设置
 }
 
@@ -47,6 +52,7 @@ type activeRequestManager struct {
 	mu       sync.RWMutex
 	requests map[int64]*activeRequest
 	nextID   atomic.Int64
+	changed  atomic.Bool // 自上次 takeChanged 以来是否有请求增删/更新（供事件流判断是否推送快照）
 }
 
 func newActiveRequestManager() *activeRequestManager {
@@ -68,6 +74,7 @@ func (m *activeRequestManager) Register(startTime time.Time, model, clientIP str
 	m.mu.Lock()
 	m.requests[id] = req
 	m.mu.Unlock()
+	m.changed.Store(true)
 	return id
 }
 
@@ -82,6 +89,7 @@ func (m *activeRequestManager) Update(id int64, channelID int64, channelName, ch
 		req.TokenID = tokenID
 	}
 	m.mu.Unlock()
+	m.changed.Store(true)
 }
 
 // Remove 移除一个活跃请求
@@ -89,6 +97,38 @@ func (m *activeRequestManager) Remove(id int64) {
 	m.mu.Lock()
 	delete(m.requests, id)
 	m.mu.Unlock()
+	m.changed.Store(true)
+}
+
+// errRequestCancelledByAdmin 管理员从实时视图取消请求时的 context 取消原因（2026-10新增）
+// 取消沿用客户端取消的处理路径（499、不冷却、保留已消耗Token统计），处理器据此向客户端发送终止错误
+var errRequestCancelledByAdmin = errors.New("request cancelled by administrator")
+
+// SetCancel 绑定请求的取消函数（在创建请求 context 后调用）
+func (m *activeRequestManager) SetCancel(id int64, cancel context.CancelCauseFunc) {
+	m.mu.Lock()
+	if req, ok := m.requests[id]; ok {
+		req.cancel = cancel
+	}
+	m.mu.Unlock()
+}
+
+// Cancel 取消进行中的请求（中断上游请求），请求不存在或不可取消时返回 false
+// 请求在处理器返回后才从列表移除
+func (m *activeRequestManager) Cancel(id int64) bool {
+	m.mu.RLock()
+	req := m.requests[id]
+	m.mu.RUnlock()
+	if req == nil || req.cancel == nil {
+		return false
+	}
+	req.cancel(errRequestCancelledByAdmin)
+	return true
+}
+
+// takeChanged 返回并清除变化标记
+func (m *activeRequestManager) takeChanged() bool {
+	return m.changed.Swap(false)
 }
 
 // AddBytes 原子地增加指定请求的字节数（线程安全）
@@ -101,6 +141,7 @@ func (m *activeRequestManager) AddBytes(id int64, n int64) {
 	m.mu.RUnlock()
 	if req != nil {
 		req.bytesCounter.Add(n)
+		req.lastActivityMs.Store(time.Now().UnixMilli())
 	}
 }
 
@@ -139,6 +180,7 @@ func (m *activeRequestManager) List() []*ActiveRequest {
 			APIKeyUsed:    req.APIKeyUsed,
 			TokenID:       req.TokenID,
 			BytesReceived: req.bytesCounter.Load(),
+			LastActivity:  req.lastActivityMs.Load(),
 		}
 		if usec := req.clientFirstByteTimeUsec.Load(); usec > 0 {
 			view.ClientFirstByteTime = float64(usec) / 1e6
@@ -1,10 +1,22 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestActiveRequestManager_ListSnapshotAndSort(t *testing.T) {
@@ -71,3 +83,149 @@ func TestActiveRequestManager_BytesAndFirstByteTime(t *testing.T) {
 		t.Fatalf("expected client_first_byte_time≈0.75, got %f", got[0].ClientFirstByteTime)
 	}
 }
+
+func TestActiveRequestManager_Cancel(t *testing.T) {
+	m := newActiveRequestManager()
+	id := m.Register(time.Now(), "m", "1.1.1.1", true)
+	if m.Cancel(id) {
+		t.Fatal("未绑定取消函数的请求不应可取消")
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	m.SetCancel(id, cancel)
+	if !m.Cancel(id) {
+		t.Fatal("expected cancel to succeed")
+	}
+	if !errors.Is(context.Cause(ctx), errRequestCancelledByAdmin) {
+		t.Fatalf("unexpected cause: %v", context.Cause(ctx))
+	}
+
+	m.Remove(id)
+	if m.Cancel(id) || m.Cancel(id+100) {
+		t.Fatal("已结束/不存在的请求不应可取消")
+	}
+}
+
+// TestCancelActiveRequest_Routes DELETE /admin/logs/active/:id 与别名 /admin/active-requests/:id 都能取消进行中的请求
+func TestCancelActiveRequest_Routes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	srv.activeRequests = newActiveRequestManager()
+	r := gin.New()
+	srv.SetupRoutes(r)
+
+	const adminToken = "test-admin-token"
+	srv.authService.tokensMux.Lock()
+	srv.authService.validTokens[model.HashToken(adminToken)] = model.AdminSession{ExpiresAt: time.Now().Add(time.Hour), Role: model.AdminRoleAdmin}
+	srv.authService.tokensMux.Unlock()
+
+	for _, prefix := range []string{"/admin/logs/active/", "/admin/active-requests/"} {
+		id := srv.activeRequests.Register(time.Now(), "m", "1.1.1.1", true)
+		ctx, cancel := context.WithCancelCause(context.Background())
+		srv.activeRequests.SetCancel(id, cancel)
+
+		del := func() int {
+			req := httptest.NewRequest(http.MethodDelete, prefix+strconv.FormatInt(id, 10), nil)
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+		if code := del(); code != http.StatusOK {
+			t.Fatalf("DELETE %s: expected 200, got %d", prefix, code)
+		}
+		if !errors.Is(context.Cause(ctx), errRequestCancelledByAdmin) {
+			t.Fatalf("%s: 上游上下文应被取消, cause=%v", prefix, context.Cause(ctx))
+		}
+		srv.activeRequests.Remove(id)
+		if code := del(); code != http.StatusNotFound {
+			t.Fatalf("DELETE %s on finished request: expected 404, got %d", prefix, code)
+		}
+	}
+}
+
+func TestHandleProxyRequest_AdminCancelStreaming(t *testing.T) {
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+	srv.responseBufferBytes = 0
+
+	// 上游输出一个事件后挂起，直到请求被取消
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":3}}}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "runaway", URL: upstream.URL, ChannelType: "anthropic", Priority: 1, Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-x"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+		bytes.NewBufferString(`{"model":"claude-x","stream":true,"max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.HandleProxyRequest(c)
+	}()
+
+	// 等待上游开始输出后取消
+	var activeID int64
+	deadline := time.Now().Add(5 * time.Second)
+	for activeID == 0 && time.Now().Before(deadline) {
+		for _, req := range srv.activeRequests.List() {
+			if req.BytesReceived > 0 {
+				activeID = req.ID
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if activeID == 0 {
+		t.Fatal("请求未进入流式输出")
+	}
+
+	aw := httptest.NewRecorder()
+	ac, _ := gin.CreateTestContext(aw)
+	ac.Params = gin.Params{{Key: "id", Value: "999999"}}
+	srv.HandleCancelActiveRequest(ac)
+	if aw.Code != http.StatusNotFound {
+		t.Fatalf("不存在的请求应返回404, got %d", aw.Code)
+	}
+	if !srv.activeRequests.Cancel(activeID) {
+		t.Fatal("取消失败")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("取消后请求未结束")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "message_start") || !strings.HasSuffix(body, "event: error\ndata: "+adminCancelledErrorBody+"\n\n") {
+		t.Fatalf("流式响应应以终止错误事件结束: %q", body)
+	}
+	if len(srv.activeRequests.List()) != 0 {
+		t.Fatal("请求结束后应从活跃列表移除")
+	}
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -14,3 +15,20 @@ func (s *Server) HandleActiveRequests(c *gin.Context) {
 	}
 	RespondJSONWithCount(c, http.StatusOK, requests, len(requests))
 }
+
+// HandleCancelActiveRequest 取消进行中的请求（2026-10新增）
+// 中断上游请求；已开始输出的流式响应以 SSE error 事件结束，否则返回 499
+// DELETE /admin/logs/active/:id（别名 DELETE /admin/active-requests/:id）
+func (s *Server) HandleCancelActiveRequest(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil || id <= 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request id")
+		return
+	}
+	if s.activeRequests == nil || !s.activeRequests.Cancel(id) {
+		RespondErrorMsg(c, http.StatusNotFound, "request not found or already finished")
+		return
+	}
+	log.Printf("[INFO] [请求取消] 管理员取消进行中的请求 ID=%d", id)
+	RespondJSON(c, http.StatusOK, gin.H{"id": id, "cancelled": true})
+}
@@ -795,3 +795,50 @@ func TestHealthEndpoint(t *testing.T) {
 
 	t.Logf("[INFO] 健康检查测试通过")
 }
+
+// TestHealthEndpoint_Detail 测试详细健康检查（需管理员Token）
+func TestHealthEndpoint_Detail(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	r := gin.New()
+	server.SetupRoutes(r)
+
+	// 未携带管理员Token → 401
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/health?detail=1", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("期望状态码 401，实际: %d", w.Code)
+	}
+
+	const adminToken = "test-admin-token"
+	server.authService.tokensMux.Lock()
+	server.authService.validTokens[model.HashToken(adminToken)] = model.AdminSession{ExpiresAt: time.Now().Add(time.Hour), Role: model.AdminRoleAdmin}
+	server.authService.tokensMux.Unlock()
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/health?detail=1", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 200，实际: %d, 响应: %s", w.Code, w.Body.String())
+	}
+
+	var wrapper struct {
+		Data HealthDetailResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &wrapper); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if wrapper.Data.Status == "error" {
+		t.Fatalf("期望非error状态，实际: %s", wrapper.Data.Status)
+	}
+	for _, name := range []string{"database", "redis", "channel_cache", "log_workers", "runtime"} {
+		if _, ok := wrapper.Data.Components[name]; !ok {
+			t.Errorf("缺少组件 %s", name)
+		}
+	}
+	if db := wrapper.Data.Components["database"]; db.Status != "ok" {
+		t.Errorf("期望 database=ok，实际: %s (%s)", db.Status, db.Error)
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"ccLoad/internal/model"
+	"ccLoad/internal/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,6 +23,12 @@ import (
 // API访问令牌管理 (Admin API)
 // ============================================================================
 
+// maxTokenOwnerLen 令牌归属方最大长度（与 auth_tokens.owner 列宽一致）
+const maxTokenOwnerLen = 64
+
+// maxTokenFallbackModelLen 令牌默认回退模型最大长度（与 auth_tokens.fallback_model 列宽一致）
+const maxTokenFallbackModelLen = 191
+
 // HandleListAuthTokens 列出所有API访问令牌（支持时间范围统计，2025-12扩展）
 // GET /admin/auth-tokens?range=today
 func (s *Server) HandleListAuthTokens(c *gin.Context) {
@@ -40,10 +51,11 @@ func (s *Server) HandleListAuthTokens(c *gin.Context) {
 	}
 
 	type AuthTokenListResponse struct {
-		Tokens          []*model.AuthToken `json:"tokens"`
-		DurationSeconds float64            `json:"duration_seconds,omitempty"`
-		RPMStats        *model.RPMStats    `json:"rpm_stats,omitempty"`
-		IsToday         bool               `json:"is_today"`
+		Tokens          []*model.AuthToken   `json:"tokens"`
+		DurationSeconds float64              `json:"duration_seconds,omitempty"`
+		RPMStats        *model.RPMStats      `json:"rpm_stats,omitempty"`
+		IsToday         bool                 `json:"is_today"`
+		Currency        *DisplayCurrencyInfo `json:"currency,omitempty"` // 展示币种（2026-10新增）
 	}
 
 	resp := AuthTokenListResponse{
@@ -127,6 +139,13 @@ func (s *Server) HandleListAuthTokens(c *gin.Context) {
 
 	}
 
+	// 展示币种换算（累计或时间范围内的费用）
+	if resp.Currency = s.displayCurrency.info(); resp.Currency != nil {
+		for _, t := range tokens {
+			t.TotalCostDisplay = resp.Currency.convert(t.TotalCostUSD)
+		}
+	}
+
 	RespondJSON(c, http.StatusOK, resp)
 }
 
@@ -138,26 +157,55 @@ func (s *Server) HandleCreateAuthToken(c *gin.Context) {
 		ExpiresAt     *int64   `json:"expires_at"`     // Unix毫秒时间戳，nil表示永不过期
 		IsActive      *bool    `json:"is_active"`      // nil表示默认启用
 		AllowedModels []string `json:"allowed_models"` // 允许的模型列表，空表示无限制
+		BlockedModels []string `json:"blocked_models"` // 屏蔽的模型（支持*通配符），空表示不屏蔽
 		CostLimitUSD  *float64 `json:"cost_limit_usd"` // 费用上限（0=无限制）
+		// mTLS客户端证书标识（CN或任一SAN），空表示不绑定证书
+		ClientCertSubject string `json:"client_cert_subject"`
+		Owner             string `json:"owner"`         // 归属团队/负责人，空表示未分配
+		FailoverInfo      bool   `json:"failover_info"` // 响应中附带故障转移信息（尝试次数/最终渠道类型）
+		RPMLimit          int    `json:"rpm_limit"`     // 每分钟请求数上限（0=不限制）
+		TPMLimit          int64  `json:"tpm_limit"`     // 每分钟Token数上限（0=不限制）
+		// 请求模型未匹配任何渠道时改用的默认模型，空表示不回退
+		FallbackModel string `json:"fallback_model"`
+		// 一次性取回链接有效期（分钟），0表示不生成
+		HandoffMinutes int `json:"handoff_minutes"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if req.HandoffMinutes < 0 || req.HandoffMinutes > tokenHandoffMaxMinutes {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("handoff_minutes must be between 0 and %d", tokenHandoffMaxMinutes))
+		return
+	}
 	if req.CostLimitUSD != nil && *req.CostLimitUSD < 0 {
 		RespondErrorMsg(c, http.StatusBadRequest, "cost_limit_usd must be >= 0")
 		return
 	}
+	if req.RPMLimit < 0 || req.TPMLimit < 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "rpm_limit and tpm_limit must be >= 0")
+		return
+	}
+	req.ClientCertSubject = strings.TrimSpace(req.ClientCertSubject)
+	if !s.ensureCertSubjectAvailable(c, req.ClientCertSubject, 0) {
+		return
+	}
+	owner, ok := normalizeTokenOwner(c, req.Owner)
+	if !ok {
+		return
+	}
+	fallbackModel, ok := normalizeFallbackModel(c, req.FallbackModel)
+	if !ok {
+		return
+	}
 
-	// 生成安全令牌(64字符十六进制)
-	tokenBytes := make([]byte, 32)
-	if _, err := rand.Read(tokenBytes); err != nil {
+	tokenPlain, err := newTokenSecret()
+	if err != nil {
 		log.Print("❌ 生成令牌失败: " + err.Error())
 		RespondError(c, http.StatusInternalServerError, err)
 		return
 	}
-	tokenPlain := hex.EncodeToString(tokenBytes)
 
 	// 计算SHA256哈希用于存储
 	tokenHash := model.HashToken(tokenPlain)
@@ -168,11 +216,18 @@ func (s *Server) HandleCreateAuthToken(c *gin.Context) {
 	}
 
 	authToken := &model.AuthToken{
-		Token:         tokenHash,
-		Description:   req.Description,
-		ExpiresAt:     req.ExpiresAt,
-		IsActive:      isActive,
-		AllowedModels: req.AllowedModels,
+		Token:             tokenHash,
+		Description:       req.Description,
+		ExpiresAt:         req.ExpiresAt,
+		IsActive:          isActive,
+		AllowedModels:     req.AllowedModels,
+		BlockedModels:     normalizeModelList(req.BlockedModels),
+		ClientCertSubject: req.ClientCertSubject,
+		Owner:             owner,
+		FailoverInfo:      req.FailoverInfo,
+		RPMLimit:          req.RPMLimit,
+		TPMLimit:          req.TPMLimit,
+		FallbackModel:     fallbackModel,
 	}
 	if req.CostLimitUSD != nil {
 		authToken.SetCostLimitUSD(*req.CostLimitUSD)
@@ -195,14 +250,177 @@ func (s *Server) HandleCreateAuthToken(c *gin.Context) {
 	log.Printf("[INFO] 创建API令牌: ID=%d, 描述=%s", authToken.ID, authToken.Description)
 
 	// 返回明文令牌（仅此一次机会）
+	resp := gin.H{
+		"id":                  authToken.ID,
+		"token":               tokenPlain, // 明文令牌，仅创建时返回
+		"description":         authToken.Description,
+		"created_at":          authToken.CreatedAt,
+		"expires_at":          authToken.ExpiresAt,
+		"is_active":           authToken.IsActive,
+		"allowed_models":      authToken.AllowedModels,
+		"blocked_models":      authToken.BlockedModels,
+		"client_cert_subject": authToken.ClientCertSubject,
+		"owner":               authToken.Owner,
+		"failover_info":       authToken.FailoverInfo,
+		"rpm_limit":           authToken.RPMLimit,
+		"tpm_limit":           authToken.TPMLimit,
+		"fallback_model":      authToken.FallbackModel,
+	}
+	if req.HandoffMinutes > 0 {
+		handoffID, handoffExpiresAt, err := s.tokenHandoffs.create(authToken.ID, authToken.Description, tokenPlain, time.Duration(req.HandoffMinutes)*time.Minute)
+		if err != nil {
+			// 令牌已创建，链接生成失败不回滚，明文仍在本次响应中返回
+			log.Print("[WARN]  生成一次性取回链接失败: " + err.Error())
+		} else {
+			resp["handoff_url"] = tokenHandoffPagePath + handoffID
+			resp["handoff_expires_at"] = handoffExpiresAt.UnixMilli()
+		}
+	}
+	RespondJSON(c, http.StatusOK, resp)
+}
+
+// newTokenSecret 生成安全令牌(64字符十六进制)
+func newTokenSecret() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tokenBytes), nil
+}
+
+// maxBatchTokenCount 单次批量创建的令牌数上限
+const maxBatchTokenCount = 200
+
+// HandleBatchCreateAuthTokens 按模板批量创建API访问令牌（2026-10新增）
+// POST /admin/auth-tokens/batch
+// description_pattern 中的 {n} 替换为序号（从 start_index 开始，默认1；未包含 {n} 时追加 "-{n}"）。
+// 明文令牌仅在本次响应中返回；任一令牌创建失败时删除本批已创建的令牌，避免留下无人知晓明文的令牌。
+func (s *Server) HandleBatchCreateAuthTokens(c *gin.Context) {
+	var req struct {
+		Count              int      `json:"count" binding:"required"`
+		DescriptionPattern string   `json:"description_pattern" binding:"required"`
+		StartIndex         *int     `json:"start_index"`
+		ExpiresAt          *int64   `json:"expires_at"`
+		IsActive           *bool    `json:"is_active"`
+		AllowedModels      []string `json:"allowed_models"`
+		BlockedModels      []string `json:"blocked_models"`
+		CostLimitUSD       *float64 `json:"cost_limit_usd"` // 每个令牌各自的费用上限（0=无限制）
+		Owner              string   `json:"owner"`
+		HandoffMinutes     int      `json:"handoff_minutes"` // 为每个令牌生成一次性取回链接
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Count < 1 || req.Count > maxBatchTokenCount {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxBatchTokenCount))
+		return
+	}
+	if req.HandoffMinutes < 0 || req.HandoffMinutes > tokenHandoffMaxMinutes {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("handoff_minutes must be between 0 and %d", tokenHandoffMaxMinutes))
+		return
+	}
+	if req.CostLimitUSD != nil && *req.CostLimitUSD < 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "cost_limit_usd must be >= 0")
+		return
+	}
+	pattern := strings.TrimSpace(req.DescriptionPattern)
+	if !strings.Contains(pattern, "{n}") {
+		pattern += "-{n}"
+	}
+	start := 1
+	if req.StartIndex != nil {
+		if *req.StartIndex < 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "start_index must be >= 0")
+			return
+		}
+		start = *req.StartIndex
+	}
+	owner, ok := normalizeTokenOwner(c, req.Owner)
+	if !ok {
+		return
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+	blocked := normalizeModelList(req.BlockedModels)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	type createdToken struct {
+		ID               int64  `json:"id"`
+		Token            string `json:"token"` // 明文令牌，仅创建时返回
+		Description      string `json:"description"`
+		HandoffURL       string `json:"handoff_url,omitempty"`
+		HandoffExpiresAt int64  `json:"handoff_expires_at,omitempty"`
+	}
+	created := make([]createdToken, 0, req.Count)
+	rollback := func() {
+		for _, t := range created {
+			if err := s.store.DeleteAuthToken(ctx, t.ID); err != nil {
+				log.Printf("[WARN]  批量创建回滚失败: 令牌ID=%d: %v", t.ID, err)
+			}
+		}
+	}
+
+	for i := range req.Count {
+		tokenPlain, err := newTokenSecret()
+		if err != nil {
+			rollback()
+			log.Print("❌ 生成令牌失败: " + err.Error())
+			RespondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		authToken := &model.AuthToken{
+			Token:         model.HashToken(tokenPlain),
+			Description:   strings.ReplaceAll(pattern, "{n}", strconv.Itoa(start+i)),
+			ExpiresAt:     req.ExpiresAt,
+			IsActive:      isActive,
+			AllowedModels: req.AllowedModels,
+			BlockedModels: blocked,
+			Owner:         owner,
+		}
+		if req.CostLimitUSD != nil {
+			authToken.SetCostLimitUSD(*req.CostLimitUSD)
+		}
+		if err := s.store.CreateAuthToken(ctx, authToken); err != nil {
+			rollback()
+			log.Printf("❌ 批量创建令牌失败（第%d个，已回滚%d个）: %v", i+1, len(created), err)
+			RespondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		created = append(created, createdToken{ID: authToken.ID, Token: tokenPlain, Description: authToken.Description})
+	}
+
+	// 触发热更新（立即生效）
+	if err := s.authService.ReloadAuthTokens(); err != nil {
+		log.Print("[WARN]  热更新失败: " + err.Error())
+	}
+
+	if req.HandoffMinutes > 0 {
+		for i := range created {
+			t := &created[i]
+			handoffID, handoffExpiresAt, err := s.tokenHandoffs.create(t.ID, t.Description, t.Token, time.Duration(req.HandoffMinutes)*time.Minute)
+			if err != nil {
+				log.Print("[WARN]  生成一次性取回链接失败: " + err.Error())
+				continue
+			}
+			t.HandoffURL = tokenHandoffPagePath + handoffID
+			t.HandoffExpiresAt = handoffExpiresAt.UnixMilli()
+		}
+	}
+
+	log.Printf("[INFO] 批量创建API令牌: %d个, 描述模板=%s, 归属方=%s", len(created), pattern, owner)
 	RespondJSON(c, http.StatusOK, gin.H{
-		"id":             authToken.ID,
-		"token":          tokenPlain, // 明文令牌，仅创建时返回
-		"description":    authToken.Description,
-		"created_at":     authToken.CreatedAt,
-		"expires_at":     authToken.ExpiresAt,
-		"is_active":      authToken.IsActive,
-		"allowed_models": authToken.AllowedModels,
+		"count":          len(created),
+		"tokens":         created,
+		"expires_at":     req.ExpiresAt,
+		"is_active":      isActive,
+		"allowed_models": req.AllowedModels,
+		"blocked_models": blocked,
+		"owner":          owner,
 	})
 }
 
@@ -216,11 +434,20 @@ func (s *Server) HandleUpdateAuthToken(c *gin.Context) {
 	}
 
 	var req struct {
-		Description   *string  `json:"description"`
-		IsActive      *bool    `json:"is_active"`
-		ExpiresAt     *int64   `json:"expires_at"`
-		AllowedModels []string `json:"allowed_models"` // 允许的模型列表，空数组表示清除限制
-		CostLimitUSD  *float64 `json:"cost_limit_usd"` // 费用上限（0=无限制）
+		Description   *string   `json:"description"`
+		IsActive      *bool     `json:"is_active"`
+		ExpiresAt     *int64    `json:"expires_at"`
+		AllowedModels []string  `json:"allowed_models"` // 允许的模型列表，空数组表示清除限制
+		BlockedModels *[]string `json:"blocked_models"` // 屏蔽的模型，nil表示不修改，空数组表示清除屏蔽
+		CostLimitUSD  *float64  `json:"cost_limit_usd"` // 费用上限（0=无限制）
+		// mTLS客户端证书标识，nil表示不修改，空字符串表示解除绑定
+		ClientCertSubject *string `json:"client_cert_subject"`
+		Owner             *string `json:"owner"`         // nil表示不修改，空字符串表示清除归属
+		FailoverInfo      *bool   `json:"failover_info"` // nil表示不修改
+		RPMLimit          *int    `json:"rpm_limit"`     // nil表示不修改，0表示取消限制
+		TPMLimit          *int64  `json:"tpm_limit"`     // nil表示不修改，0表示取消限制
+		// 默认回退模型，nil表示不修改，空字符串表示取消回退
+		FallbackModel *string `json:"fallback_model"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -231,6 +458,31 @@ func (s *Server) HandleUpdateAuthToken(c *gin.Context) {
 		RespondErrorMsg(c, http.StatusBadRequest, "cost_limit_usd must be >= 0")
 		return
 	}
+	if (req.RPMLimit != nil && *req.RPMLimit < 0) || (req.TPMLimit != nil && *req.TPMLimit < 0) {
+		RespondErrorMsg(c, http.StatusBadRequest, "rpm_limit and tpm_limit must be >= 0")
+		return
+	}
+	if req.ClientCertSubject != nil {
+		subj := strings.TrimSpace(*req.ClientCertSubject)
+		req.ClientCertSubject = &subj
+		if !s.ensureCertSubjectAvailable(c, subj, id) {
+			return
+		}
+	}
+	if req.Owner != nil {
+		owner, ok := normalizeTokenOwner(c, *req.Owner)
+		if !ok {
+			return
+		}
+		req.Owner = &owner
+	}
+	if req.FallbackModel != nil {
+		fallbackModel, ok := normalizeFallbackModel(c, *req.FallbackModel)
+		if !ok {
+			return
+		}
+		req.FallbackModel = &fallbackModel
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -254,10 +506,31 @@ func (s *Server) HandleUpdateAuthToken(c *gin.Context) {
 	}
 	// allowed_models 总是更新（空数组表示清除限制）
 	token.AllowedModels = req.AllowedModels
+	if req.BlockedModels != nil {
+		token.BlockedModels = normalizeModelList(*req.BlockedModels)
+	}
 	// cost_limit_usd 只有传入时才更新
 	if req.CostLimitUSD != nil {
 		token.SetCostLimitUSD(*req.CostLimitUSD)
 	}
+	if req.ClientCertSubject != nil {
+		token.ClientCertSubject = *req.ClientCertSubject
+	}
+	if req.Owner != nil {
+		token.Owner = *req.Owner
+	}
+	if req.FailoverInfo != nil {
+		token.FailoverInfo = *req.FailoverInfo
+	}
+	if req.RPMLimit != nil {
+		token.RPMLimit = *req.RPMLimit
+	}
+	if req.TPMLimit != nil {
+		token.TPMLimit = *req.TPMLimit
+	}
+	if req.FallbackModel != nil {
+		token.FallbackModel = *req.FallbackModel
+	}
 
 	if err := s.store.UpdateAuthToken(ctx, token); err != nil {
 		log.Print("❌ 更新令牌失败: " + err.Error())
@@ -275,6 +548,50 @@ func (s *Server) HandleUpdateAuthToken(c *gin.Context) {
 	RespondJSON(c, http.StatusOK, token)
 }
 
+// normalizeTokenOwner 规范化令牌归属方（去除首尾空白，最长64字符）；非法时写出400并返回false
+func normalizeTokenOwner(c *gin.Context, raw string) (string, bool) {
+	owner := strings.TrimSpace(raw)
+	if utf8.RuneCountInString(owner) > maxTokenOwnerLen {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("owner too long (max %d)", maxTokenOwnerLen))
+		return "", false
+	}
+	return owner, true
+}
+
+// normalizeFallbackModel 规范化令牌默认回退模型（去除首尾空白，不允许通配符）；非法时写出400并返回false
+func normalizeFallbackModel(c *gin.Context, raw string) (string, bool) {
+	fallback := strings.TrimSpace(raw)
+	if utf8.RuneCountInString(fallback) > maxTokenFallbackModelLen {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("fallback_model too long (max %d)", maxTokenFallbackModelLen))
+		return "", false
+	}
+	if strings.Contains(fallback, "*") {
+		RespondErrorMsg(c, http.StatusBadRequest, "fallback_model must be a concrete model name")
+		return "", false
+	}
+	return fallback, true
+}
+
+// ensureCertSubjectAvailable 校验证书标识未被其他令牌占用（一个证书标识只能映射到一个令牌）
+// excludeID 为当前更新的令牌ID（创建时传0）；冲突时写出409并返回false
+func (s *Server) ensureCertSubjectAvailable(c *gin.Context, subject string, excludeID int64) bool {
+	if subject == "" {
+		return true
+	}
+	tokens, err := s.store.ListAuthTokens(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	for _, t := range tokens {
+		if t.ID != excludeID && t.ClientCertSubject == subject {
+			RespondErrorMsg(c, http.StatusConflict, "client_cert_subject already bound to another token")
+			return false
+		}
+	}
+	return true
+}
+
 // HandleDeleteAuthToken 删除令牌
 // DELETE /admin/auth-tokens/:id
 func (s *Server) HandleDeleteAuthToken(c *gin.Context) {
@@ -298,7 +615,104 @@ func (s *Server) HandleDeleteAuthToken(c *gin.Context) {
 		log.Print("[WARN]  热更新失败: " + err.Error())
 	}
 
+	s.tokenHandoffs.revokeToken(id)
 	log.Printf("[INFO] 删除API令牌: ID=%d", id)
 
 	RespondJSON(c, http.StatusOK, gin.H{"id": id})
 }
+
+// HandleIntrospectAuthToken 令牌自省（管理员）
+// POST /admin/auth-tokens/introspect
+// 令牌不存在/不可用时仍返回200，由 active=false + reason 表示（与RFC 7662语义一致）
+func (s *Server) HandleIntrospectAuthToken(c *gin.Context) {
+	var req TokenIntrospectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	RespondJSON(c, http.StatusOK, s.introspectToken(ctx, model.HashToken(strings.TrimSpace(req.Token)), true))
+}
+
+// HandleIntrospectSelf 令牌自省（令牌持有者，仅返回自身的受限视图）
+// GET /api/token/introspect（需API令牌认证）
+func (s *Server) HandleIntrospectSelf(c *gin.Context) {
+	tokenHash, _ := c.Get("token_hash")
+	hash, _ := tokenHash.(string)
+	if hash == "" {
+		RespondErrorMsg(c, http.StatusUnauthorized, "invalid or missing authorization")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	RespondJSON(c, http.StatusOK, s.introspectToken(ctx, hash, false))
+}
+
+// introspectToken 构建令牌自省结果
+// full=false 时省略ID/描述等管理信息
+func (s *Server) introspectToken(ctx context.Context, tokenHash string, full bool) *TokenIntrospection {
+	token, err := s.store.GetAuthTokenByValue(ctx, tokenHash)
+	if err != nil || token == nil {
+		return &TokenIntrospection{Active: false, Reason: "not_found", AllowedModels: []string{}, BlockedModels: []string{}}
+	}
+	// 数据库以0表示永不过期（与 ReloadAuthTokens 的 expiresAt > 0 约定一致）
+	if token.ExpiresAt != nil && *token.ExpiresAt <= 0 {
+		token.ExpiresAt = nil
+	}
+
+	result := &TokenIntrospection{
+		Active:        token.IsValid(),
+		ExpiresAt:     token.ExpiresAt,
+		AllowedModels: token.AllowedModels,
+	}
+	if result.AllowedModels == nil {
+		result.AllowedModels = []string{}
+	}
+	switch {
+	case !token.IsActive:
+		result.Reason = "disabled"
+	case token.IsExpired():
+		result.Reason = "expired"
+	}
+	if full {
+		result.ID = token.ID
+		result.Description = token.Description
+	}
+
+	// 费用：内存缓存比数据库更及时（数据库由异步worker更新）
+	usedMicro, limitMicro := token.CostUsedMicroUSD, token.CostLimitMicroUSD
+	if cachedUsed, cachedLimit, _ := s.authService.IsCostLimitExceeded(tokenHash); cachedLimit > 0 && cachedUsed > usedMicro {
+		usedMicro = cachedUsed
+	}
+	result.CostUsedUSD = util.MicroUSDToUSD(usedMicro)
+	result.CostLimitUSD = util.MicroUSDToUSD(limitMicro)
+	if limitMicro > 0 {
+		remaining := util.MicroUSDToUSD(max(limitMicro-usedMicro, 0))
+		result.CostRemainingUSD = &remaining
+		result.CostLimitExceeded = usedMicro >= limitMicro
+	}
+
+	// 模型屏蔽：全局规则在前，令牌规则去重追加
+	result.BlockedModels = []string{}
+	if p := s.blockedModels.Load(); p != nil {
+		result.BlockedModels = append(result.BlockedModels, *p...)
+	}
+	for _, m := range token.BlockedModels {
+		if !slices.Contains(result.BlockedModels, m) {
+			result.BlockedModels = append(result.BlockedModels, m)
+		}
+	}
+
+	// 速率限制：窗口用量只在内存中维护（未受限的令牌不计数）
+	result.RPMLimit, result.TPMLimit = token.RPMLimit, token.TPMLimit
+	if s.authService.rateLimiter != nil {
+		result.RPMUsed, result.TPMUsed = s.authService.rateLimiter.usage(tokenHash, time.Now())
+	}
+
+	return result
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"ccLoad/internal/model"
 
@@ -131,3 +135,267 @@ func TestAdminAPI_ListAuthTokens_ResponseShape(t *testing.T) {
 		t.Fatalf("Expected data.tokens to be array, got %T", tokens)
 	}
 }
+
+func TestAdminAPI_IntrospectAuthToken(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	const plain = "introspect-test-token"
+	token := &model.AuthToken{
+		Token:         model.HashToken(plain),
+		Description:   "gateway",
+		IsActive:      true,
+		AllowedModels: []string{"claude-sonnet-4-5"},
+		BlockedModels: []string{"claude-opus-*", "gpt-4o"},
+		RPMLimit:      30,
+		TPMLimit:      5000,
+	}
+	token.SetCostLimitUSD(10)
+	if err := server.store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("CreateAuthToken failed: %v", err)
+	}
+
+	introspect := func(value string) TokenIntrospection {
+		body, _ := json.Marshal(map[string]any{"token": value})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/auth-tokens/introspect", bytes.NewBuffer(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		server.HandleIntrospectAuthToken(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data TokenIntrospection `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		return resp.Data
+	}
+
+	if err := server.authService.ReloadAuthTokens(); err != nil {
+		t.Fatalf("ReloadAuthTokens failed: %v", err)
+	}
+	server.blockedModels.Store(&[]string{"gpt-4o"})
+	limit := tokenRateLimit{rpm: 30, tpm: 5000}
+	now := time.Now()
+	for range 2 {
+		if exceeded, _ := server.authService.rateLimiter.allow(token.Token, limit, now); exceeded != "" {
+			t.Fatalf("unexpected rate limit: %s", exceeded)
+		}
+	}
+	server.authService.rateLimiter.addTokens(token.Token, 120, now)
+
+	got := introspect(plain)
+	if !got.Active || got.ID != token.ID || got.Description != "gateway" {
+		t.Fatalf("unexpected introspection: %+v", got)
+	}
+	if len(got.AllowedModels) != 1 || got.AllowedModels[0] != "claude-sonnet-4-5" {
+		t.Errorf("AllowedModels = %v", got.AllowedModels)
+	}
+	if got.CostLimitUSD != 10 || got.CostRemainingUSD == nil || *got.CostRemainingUSD != 10 || got.CostLimitExceeded {
+		t.Errorf("unexpected budget: %+v", got)
+	}
+	if strings.Join(got.BlockedModels, ",") != "gpt-4o,claude-opus-*" {
+		t.Errorf("BlockedModels should merge global and token rules, got %v", got.BlockedModels)
+	}
+	if got.RPMLimit != 30 || got.TPMLimit != 5000 || got.RPMUsed != 2 || got.TPMUsed != 120 {
+		t.Errorf("unexpected rate limit usage: %+v", got)
+	}
+
+	missing := introspect("no-such-token")
+	if missing.Active || missing.Reason != "not_found" || missing.BlockedModels == nil || missing.RPMUsed != 0 {
+		t.Errorf("expected not_found, got %+v", missing)
+	}
+
+	stored, err := server.store.GetAuthToken(ctx, token.ID)
+	if err != nil {
+		t.Fatalf("GetAuthToken failed: %v", err)
+	}
+	stored.IsActive = false
+	if err := server.store.UpdateAuthToken(ctx, stored); err != nil {
+		t.Fatalf("UpdateAuthToken failed: %v", err)
+	}
+	disabled := introspect(plain)
+	if disabled.Active || disabled.Reason != "disabled" {
+		t.Errorf("expected disabled, got %+v", disabled)
+	}
+}
+
+func TestAdminAPI_AuthTokenOwner(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	do := func(method, path string, body map[string]any, h func(*gin.Context), params ...gin.Param) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewBuffer(data))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		h(c)
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/auth-tokens", map[string]any{"description": "t", "owner": "  team-a  "}, server.HandleCreateAuthToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data struct {
+			ID    int64  `json:"id"`
+			Owner string `json:"owner"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if created.Data.Owner != "team-a" {
+		t.Fatalf("owner should be trimmed, got %q", created.Data.Owner)
+	}
+
+	long := make([]byte, maxTokenOwnerLen+1)
+	for i := range long {
+		long[i] = 'x'
+	}
+	if w := do(http.MethodPost, "/admin/auth-tokens", map[string]any{"description": "t", "owner": string(long)}, server.HandleCreateAuthToken); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for long owner, got %d", w.Code)
+	}
+
+	idParam := gin.Param{Key: "id", Value: strconv.FormatInt(created.Data.ID, 10)}
+	if w := do(http.MethodPut, "/admin/auth-tokens/x", map[string]any{"owner": "team-b"}, server.HandleUpdateAuthToken, idParam); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	stored, err := server.store.GetAuthToken(context.Background(), created.Data.ID)
+	if err != nil {
+		t.Fatalf("DB error: %v", err)
+	}
+	if stored.Owner != "team-b" {
+		t.Fatalf("owner not updated, got %q", stored.Owner)
+	}
+}
+
+func TestAdminAPI_CreateAuthToken_HandoffLink(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.tokenHandoffs = newTokenHandoffStore()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/auth-tokens", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		server.HandleCreateAuthToken(c)
+		return w
+	}
+	if w := create(`{"description":"x","handoff_minutes":100000}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("超出上限的 handoff_minutes 应返回400，实际 %d", w.Code)
+	}
+
+	w := create(`{"description":"handoff","handoff_minutes":15}`)
+	var created struct {
+		Data struct {
+			ID               int64  `json:"id"`
+			Token            string `json:"token"`
+			HandoffURL       string `json:"handoff_url"`
+			HandoffExpiresAt int64  `json:"handoff_expires_at"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("创建失败: %d %s", w.Code, w.Body.String())
+	}
+	handoffID, ok := strings.CutPrefix(created.Data.HandoffURL, tokenHandoffPagePath)
+	if !ok || len(handoffID) != 64 || created.Data.HandoffExpiresAt <= time.Now().UnixMilli() {
+		t.Fatalf("取回链接不符: %+v", created.Data)
+	}
+
+	consume := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/public/token-handoff/"+id, nil)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		server.HandleConsumeTokenHandoff(c)
+		return w
+	}
+	w = consume(handoffID)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), created.Data.Token) || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("取回失败: %d %s", w.Code, w.Body.String())
+	}
+	if w = consume(handoffID); w.Code != http.StatusNotFound {
+		t.Fatalf("链接应只能使用一次，实际 %d", w.Code)
+	}
+
+	// 删除令牌时作废未取回的链接
+	id, _, err := server.tokenHandoffs.create(created.Data.ID, "handoff", created.Data.Token, time.Minute)
+	if err != nil {
+		t.Fatalf("生成链接失败: %v", err)
+	}
+	server.tokenHandoffs.revokeToken(created.Data.ID)
+	if w = consume(id); w.Code != http.StatusNotFound {
+		t.Fatalf("已作废的链接不应可用，实际 %d", w.Code)
+	}
+}
+
+func TestAdminAPI_BatchCreateAuthTokens(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.tokenHandoffs = newTokenHandoffStore()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/auth-tokens/batch", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		server.HandleBatchCreateAuthTokens(c)
+		return w
+	}
+	for _, body := range []string{
+		`{"count":0,"description_pattern":"x"}`,
+		`{"count":201,"description_pattern":"x"}`,
+		`{"count":2,"description_pattern":"x","cost_limit_usd":-1}`,
+	} {
+		if w := create(body); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s 应返回400，实际 %d", body, w.Code)
+		}
+	}
+
+	w := create(`{"count":3,"description_pattern":"class-{n}","start_index":10,"owner":"cs101","allowed_models":["claude-haiku-4-5"],"cost_limit_usd":5,"handoff_minutes":30}`)
+	var resp struct {
+		Data struct {
+			Count  int `json:"count"`
+			Tokens []struct {
+				ID          int64  `json:"id"`
+				Token       string `json:"token"`
+				Description string `json:"description"`
+				HandoffURL  string `json:"handoff_url"`
+			} `json:"tokens"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("批量创建失败: %d %s", w.Code, w.Body.String())
+	}
+	if resp.Data.Count != 3 || len(resp.Data.Tokens) != 3 {
+		t.Fatalf("期望创建3个令牌: %+v", resp.Data)
+	}
+	seen := make(map[string]bool)
+	for i, tok := range resp.Data.Tokens {
+		if want := fmt.Sprintf("class-%d", 10+i); tok.Description != want {
+			t.Fatalf("描述应为 %s，实际 %s", want, tok.Description)
+		}
+		if len(tok.Token) != 64 || seen[tok.Token] || !strings.HasPrefix(tok.HandoffURL, tokenHandoffPagePath) {
+			t.Fatalf("令牌明文/取回链接不符: %+v", tok)
+		}
+		seen[tok.Token] = true
+
+		stored, err := server.store.GetAuthToken(context.Background(), tok.ID)
+		if err != nil {
+			t.Fatalf("读取令牌失败: %v", err)
+		}
+		if stored.Token != model.HashToken(tok.Token) || stored.Owner != "cs101" || stored.CostLimitUSD() != 5 ||
+			len(stored.AllowedModels) != 1 || !stored.IsActive {
+			t.Fatalf("令牌属性不符: %+v", stored)
+		}
+	}
+}
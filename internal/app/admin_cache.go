@@ -0,0 +1,123 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 缓存可见性与手动失效（2026-10新增）
+// ============================================================================
+// 排查“缓存导致的路由决策过期”时无需重启进程：
+//   - GET /admin/cache/entries 查看渠道列表、API Keys、冷却状态三类缓存的内容与新鲜度
+//   - DELETE /admin/cache?type=channels|api_keys|cooldowns 按类型失效（省略 type 表示全部）
+//   - DELETE /admin/cache/channels/:id 失效单个渠道相关缓存（渠道列表与冷却缓存为整体缓存，同时失效）
+
+const (
+	cacheTypeChannels  = "channels"
+	cacheTypeAPIKeys   = "api_keys"
+	cacheTypeCooldowns = "cooldowns"
+)
+
+// HandleCacheEntries 缓存内容快照
+// GET /admin/cache/entries?channel_id=1（可选，仅返回该渠道的条目）
+func (s *Server) HandleCacheEntries(c *gin.Context) {
+	cache := s.getChannelCache()
+	if cache == nil {
+		RespondErrorMsg(c, http.StatusServiceUnavailable, "channel cache not enabled")
+		return
+	}
+	var channelID int64
+	if v := c.Query("channel_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid channel_id")
+			return
+		}
+		channelID = id
+	}
+
+	snap := cache.Snapshot(time.Now())
+	if channelID > 0 {
+		filterCacheSnapshot(&snap, channelID)
+	}
+	RespondJSON(c, http.StatusOK, snap)
+}
+
+// filterCacheSnapshot 仅保留指定渠道的条目（新鲜度字段不变）
+func filterCacheSnapshot(snap *storage.CacheSnapshot, channelID int64) {
+	channels := snap.Channels.Entries[:0]
+	for _, e := range snap.Channels.Entries {
+		if e.ID == channelID {
+			channels = append(channels, e)
+		}
+	}
+	snap.Channels.Entries = channels
+
+	keys := snap.APIKeys[:0]
+	for _, e := range snap.APIKeys {
+		if e.ChannelID == channelID {
+			keys = append(keys, e)
+		}
+	}
+	snap.APIKeys = keys
+
+	filterCooldowns := func(entries []storage.CacheCooldownEntry) []storage.CacheCooldownEntry {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.ChannelID == channelID {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	}
+	snap.Cooldowns.Channels = filterCooldowns(snap.Cooldowns.Channels)
+	snap.Cooldowns.Keys = filterCooldowns(snap.Cooldowns.Keys)
+}
+
+// HandleInvalidateCache 按类型失效缓存
+// DELETE /admin/cache?type=channels|api_keys|cooldowns（省略 type 表示全部）
+func (s *Server) HandleInvalidateCache(c *gin.Context) {
+	cacheType := c.Query("type")
+	var invalidated []string
+	switch cacheType {
+	case cacheTypeChannels:
+		s.InvalidateChannelListCache()
+	case cacheTypeAPIKeys:
+		s.InvalidateAllAPIKeysCache()
+	case cacheTypeCooldowns:
+		s.invalidateCooldownCache()
+	case "":
+		s.InvalidateChannelListCache()
+		s.InvalidateAllAPIKeysCache()
+		s.invalidateCooldownCache()
+		invalidated = []string{cacheTypeChannels, cacheTypeAPIKeys, cacheTypeCooldowns}
+	default:
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid type (expected channels, api_keys or cooldowns)")
+		return
+	}
+	if invalidated == nil {
+		invalidated = []string{cacheType}
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"invalidated": invalidated})
+}
+
+// HandleInvalidateChannelCache 失效单个渠道相关缓存
+// DELETE /admin/cache/channels/:id
+func (s *Server) HandleInvalidateChannelCache(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil || id <= 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	s.invalidateChannelRelatedCache(id)
+	RespondJSON(c, http.StatusOK, gin.H{
+		"channel_id":  id,
+		"invalidated": []string{cacheTypeChannels, cacheTypeAPIKeys, cacheTypeCooldowns},
+	})
+}
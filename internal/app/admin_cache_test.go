@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminCacheEntriesAndInvalidation(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	server.channelCache = storage.NewChannelCache(store, time.Minute)
+	ctx := context.Background()
+
+	var ids []int64
+	for _, name := range []string{"a", "b"} {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: name, URL: "https://example.com", ChannelType: "anthropic", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "m"}},
+		})
+		if err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+		if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{ChannelID: cfg.ID, APIKey: "sk-" + name, KeyStrategy: model.KeyStrategySequential}}); err != nil {
+			t.Fatalf("创建Key失败: %v", err)
+		}
+		ids = append(ids, cfg.ID)
+	}
+	// 预热缓存
+	if _, err := server.GetEnabledChannelsByModel(ctx, "m"); err != nil {
+		t.Fatalf("加载渠道缓存失败: %v", err)
+	}
+	for _, id := range ids {
+		if _, err := server.getAPIKeys(ctx, id); err != nil {
+			t.Fatalf("加载Key缓存失败: %v", err)
+		}
+	}
+	if _, err := server.getAllChannelCooldowns(ctx); err != nil {
+		t.Fatalf("加载冷却缓存失败: %v", err)
+	}
+
+	entries := func(query string) storage.CacheSnapshot {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/cache/entries"+query, nil)
+		server.HandleCacheEntries(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data storage.CacheSnapshot `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		return resp.Data
+	}
+
+	snap := entries("")
+	if snap.Channels.LastRefresh == nil || snap.Channels.Stale || len(snap.Channels.Entries) != 2 || len(snap.APIKeys) != 2 {
+		t.Fatalf("缓存快照不符: %+v", snap)
+	}
+	if snap.Cooldowns.LastUpdate == nil || snap.Cooldowns.Stale {
+		t.Fatalf("冷却缓存应已加载: %+v", snap.Cooldowns)
+	}
+	if snap = entries("?channel_id=" + strconv.FormatInt(ids[1], 10)); len(snap.Channels.Entries) != 1 || len(snap.APIKeys) != 1 || snap.APIKeys[0].ChannelID != ids[1] {
+		t.Fatalf("按渠道过滤不符: %+v", snap)
+	}
+
+	invalidate := func(path string, handler gin.HandlerFunc, params gin.Params) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, path, nil)
+		c.Params = params
+		handler(c)
+		return w.Code
+	}
+
+	if code := invalidate("/admin/cache?type=bogus", server.HandleInvalidateCache, nil); code != http.StatusBadRequest {
+		t.Fatalf("非法类型应返回400, got %d", code)
+	}
+	if code := invalidate("/admin/cache?type=api_keys", server.HandleInvalidateCache, nil); code != http.StatusOK {
+		t.Fatalf("按类型失效失败: %d", code)
+	}
+	if snap = entries(""); len(snap.APIKeys) != 0 || snap.Channels.Stale {
+		t.Fatalf("仅应失效Key缓存: %+v", snap)
+	}
+
+	if _, err := server.getAPIKeys(ctx, ids[0]); err != nil {
+		t.Fatalf("加载Key缓存失败: %v", err)
+	}
+	code := invalidate("/admin/cache/channels/x", server.HandleInvalidateChannelCache, gin.Params{{Key: "id", Value: strconv.FormatInt(ids[0], 10)}})
+	if code != http.StatusOK {
+		t.Fatalf("按渠道失效失败: %d", code)
+	}
+	if snap = entries(""); len(snap.APIKeys) != 0 || !snap.Channels.Stale || !snap.Cooldowns.Stale {
+		t.Fatalf("渠道相关缓存应全部失效: %+v", snap)
+	}
+}
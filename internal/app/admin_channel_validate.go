@@ -0,0 +1,356 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 渠道配置实时校验（2026-10新增）
+// ============================================================================
+// POST /admin/channels/validate 对未保存的渠道配置执行与创建/更新相同的完整校验，
+// 一次返回全部字段错误与非致命警告（不写库），供前端在输入时实时提示。
+// reachability=true 时额外做连通性检查：DNS解析、TLS握手（应用 cert_pins）、对基础URL发HEAD请求（应用 local_addr）。
+
+const (
+	channelValidateMaxBody      = 1 << 20
+	channelValidateStepTimeout  = 5 * time.Second
+	channelCertExpiryWarnWithin = 14 * 24 * time.Hour
+)
+
+// ChannelValidateRequest 校验请求：渠道字段 + 可选连通性检查
+// 字段允许不完整（输入过程中校验），缺失的必填字段作为错误返回而非400
+type ChannelValidateRequest struct {
+	ChannelRequest
+	ChannelID    int64 `json:"channel_id"`   // 编辑已有渠道时传入，用于重名检查排除自身
+	Reachability bool  `json:"reachability"` // 是否执行 DNS/TLS/HEAD 连通性检查
+}
+
+// ChannelValidationIssue 字段级错误/警告
+type ChannelValidationIssue struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ChannelReachabilityCheck 单项连通性检查结果
+type ChannelReachabilityCheck struct {
+	Name       string `json:"name"` // dns / tls / head
+	OK         bool   `json:"ok"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ChannelValidateResponse 校验结果（valid=false 时 errors 非空；警告不影响 valid）
+type ChannelValidateResponse struct {
+	Valid      bool                       `json:"valid"`
+	Errors     []ChannelValidationIssue   `json:"errors"`
+	Warnings   []ChannelValidationIssue   `json:"warnings"`
+	Normalized *model.Config              `json:"normalized,omitempty"` // 标准化后的配置（仅 valid 时返回，不含Key）
+	Checks     []ChannelReachabilityCheck `json:"checks,omitempty"`
+}
+
+// HandleValidateChannel 校验渠道配置但不保存
+// POST /admin/channels/validate
+func (s *Server) HandleValidateChannel(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, channelValidateMaxBody))
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	var req ChannelValidateRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	resp := ChannelValidateResponse{
+		Errors:   []ChannelValidationIssue{},
+		Warnings: []ChannelValidationIssue{},
+	}
+	cr := &req.ChannelRequest
+	urlValid := true
+	for _, issue := range cr.validateFields() {
+		if issue.Field == "url" {
+			urlValid = false
+		}
+		resp.Errors = append(resp.Errors, ChannelValidationIssue{Field: issue.Field, Message: issue.Err.Error()})
+	}
+	if err := s.validateClientProfile(cr.ClientProfile); err != nil {
+		resp.Errors = append(resp.Errors, ChannelValidationIssue{Field: "client_profile", Message: err.Error()})
+	}
+	resp.Warnings = append(resp.Warnings, s.channelRequestWarnings(c.Request.Context(), cr, req.ChannelID, urlValid)...)
+	resp.Valid = len(resp.Errors) == 0
+	if resp.Valid {
+		resp.Normalized = cr.ToConfig()
+	}
+
+	if req.Reachability {
+		if urlValid {
+			checks, warnings := s.checkChannelReachability(c.Request.Context(), cr.ToConfig())
+			resp.Checks = checks
+			resp.Warnings = append(resp.Warnings, warnings...)
+		} else {
+			resp.Checks = []ChannelReachabilityCheck{{Name: "dns", Skipped: true, Detail: "url is invalid"}}
+		}
+	}
+
+	RespondJSON(c, http.StatusOK, resp)
+}
+
+// validateClientProfile client_profile 必须是代理可解析的profile（内置或 client_profiles 设置中定义），空值表示透传
+func (s *Server) validateClientProfile(name string) error {
+	if name == "" || s.lookupClientProfile(name) != nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.clientProfiles))
+	for n := range s.clientProfiles {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return fmt.Errorf("unknown client_profile: %q (defined: %s)", name, strings.Join(names, ", "))
+}
+
+// channelRequestWarnings 非致命问题：配置可以保存，但大概率不是操作者的本意
+func (s *Server) channelRequestWarnings(ctx context.Context, cr *ChannelRequest, selfID int64, urlValid bool) []ChannelValidationIssue {
+	var warnings []ChannelValidationIssue
+	warn := func(field, format string, args ...any) {
+		warnings = append(warnings, ChannelValidationIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if urlValid && cr.URL != "" {
+		if u, err := neturl.Parse(cr.URL); err == nil {
+			host := u.Hostname()
+			if u.Scheme == "http" && !isPrivateHost(host) {
+				warn("url", "upstream uses plain http; the API key will be sent unencrypted")
+			}
+			if u.Scheme == "http" && cr.CertPins != "" {
+				warn("cert_pins", "cert_pins has no effect on http urls")
+			}
+			if expected := officialHostChannelType(host); expected != "" && cr.ChannelType != "" && cr.ChannelType != expected {
+				warn("channel_type", "host %s is the official %s endpoint but channel_type is %s", host, expected, cr.ChannelType)
+			}
+		}
+	}
+
+	seenModels := make(map[string]bool, len(cr.Models))
+	for i, m := range cr.Models {
+		if m.Model == "" {
+			continue
+		}
+		if seenModels[m.Model] {
+			warn(fmt.Sprintf("models[%d]", i), "duplicate model %q", m.Model)
+		}
+		seenModels[m.Model] = true
+	}
+
+	if keys := util.ParseAPIKeys(cr.APIKey); len(keys) > 1 {
+		seenKeys := make(map[string]bool, len(keys))
+		dup := 0
+		for _, k := range keys {
+			if seenKeys[k] {
+				dup++
+			}
+			seenKeys[k] = true
+		}
+		if dup > 0 {
+			warn("api_key", "api_key contains %d duplicate key(s)", dup)
+		}
+	}
+
+	// 跨渠道重复Key：速率翻倍且冷却互不相通
+	if s.store != nil {
+		if conflicts, err := s.findKeyConflicts(ctx, util.ParseAPIKeys(cr.APIKey), selfID); err == nil {
+			for _, cf := range conflicts {
+				warn("api_key", "key #%d is already used by channel #%d (%s)", cf.KeyIndex+1, cf.ChannelID, cf.ChannelName)
+			}
+		}
+	}
+
+	if cr.LocalAddr != "" {
+		if _, err := util.ResolveLocalAddr(cr.LocalAddr); err != nil {
+			warn("local_addr", "%v", err)
+		}
+	}
+
+	if name := strings.TrimSpace(cr.Name); name != "" && s.store != nil {
+		if configs, err := s.store.ListConfigs(ctx); err == nil {
+			for _, cfg := range configs {
+				if cfg.ID != selfID && cfg.Name == name {
+					warn("name", "name is already used by channel #%d", cfg.ID)
+					break
+				}
+			}
+		}
+	}
+
+	return warnings
+}
+
+// officialHostChannelType 官方API域名对应的渠道类型（未知域名返回空）
+func officialHostChannelType(host string) string {
+	switch strings.ToLower(host) {
+	case "api.anthropic.com":
+		return util.ChannelTypeAnthropic
+	case "generativelanguage.googleapis.com":
+		return util.ChannelTypeGemini
+	}
+	return ""
+}
+
+// isPrivateHost 本机/内网地址（明文http在此类地址上属于常见部署，不告警）
+func isPrivateHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// checkChannelReachability 依次执行 DNS → TLS（仅https）→ HEAD，前一步失败时跳过后续步骤
+// 握手成功但证书即将过期时以警告返回
+func (s *Server) checkChannelReachability(ctx context.Context, cfg *model.Config) ([]ChannelReachabilityCheck, []ChannelValidationIssue) {
+	if isMockChannel(cfg) {
+		return []ChannelReachabilityCheck{{Name: "dns", Skipped: true, Detail: "mock channel"}}, nil
+	}
+	u, err := neturl.Parse(cfg.URL)
+	if err != nil {
+		return nil, nil
+	}
+	var warnings []ChannelValidationIssue
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	checks := make([]ChannelReachabilityCheck, 0, 3)
+	run := func(name string, fn func(ctx context.Context) (string, error)) bool {
+		stepCtx, cancel := context.WithTimeout(ctx, channelValidateStepTimeout)
+		defer cancel()
+		start := time.Now()
+		detail, err := fn(stepCtx)
+		chk := ChannelReachabilityCheck{Name: name, OK: err == nil, DurationMs: time.Since(start).Milliseconds(), Detail: detail}
+		if err != nil {
+			chk.Error = err.Error()
+		}
+		checks = append(checks, chk)
+		return err == nil
+	}
+	skip := func(names ...string) {
+		for _, n := range names {
+			checks = append(checks, ChannelReachabilityCheck{Name: n, Skipped: true})
+		}
+	}
+
+	if !run("dns", func(ctx context.Context) (string, error) {
+		if net.ParseIP(host) != nil {
+			return "ip literal", nil
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return "", err
+		}
+		ips := make([]string, 0, len(addrs))
+		for _, a := range addrs {
+			ips = append(ips, a.IP.String())
+		}
+		return strings.Join(ips, ", "), nil
+	}) {
+		skip("tls", "head")
+		return checks, warnings
+	}
+
+	if u.Scheme == "https" {
+		if !run("tls", func(ctx context.Context) (string, error) {
+			detail, expiryWarning, err := probeChannelTLS(ctx, cfg, host, net.JoinHostPort(host, port))
+			if expiryWarning != "" {
+				warnings = append(warnings, ChannelValidationIssue{Field: "url", Message: expiryWarning})
+			}
+			return detail, err
+		}) {
+			skip("head")
+			return checks, warnings
+		}
+	} else {
+		skip("tls")
+	}
+
+	run("head", func(ctx context.Context) (string, error) {
+		client := s.newChannelHTTPClient(cfg)
+		defer client.CloseIdleConnections()
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, cfg.URL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		_ = resp.Body.Close()
+		// 基础URL通常返回404/405，只要有HTTP响应即视为可达
+		return "HTTP " + strconv.Itoa(resp.StatusCode), nil
+	})
+	return checks, warnings
+}
+
+// probeChannelTLS 完成一次TLS握手（应用渠道 cert_pins 与 local_addr），返回证书摘要与即将过期提示
+func probeChannelTLS(ctx context.Context, cfg *model.Config, serverName, addr string) (detail, warning string, err error) {
+	dialer := newUpstreamDialer()
+	if cfg.LocalAddr != "" {
+		laddr, err := util.ResolveLocalAddr(cfg.LocalAddr)
+		if err != nil {
+			return "", "", err
+		}
+		dialer.LocalAddr = laddr
+	}
+	tlsCfg := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+	if cfg.CertPins != "" {
+		pins, err := util.ParseCertPins(cfg.CertPins)
+		if err != nil {
+			return "", "", err
+		}
+		tlsCfg.VerifyConnection = util.CertPinVerifier(pins)
+	}
+
+	conn, err := (&tls.Dialer{NetDialer: dialer, Config: tlsCfg}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = conn.Close() }()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return "", "", errors.New("unexpected connection type")
+	}
+	state := tlsConn.ConnectionState()
+	detail = tls.VersionName(state.Version)
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		detail += fmt.Sprintf(", cert %s expires %s", leaf.Subject.CommonName, leaf.NotAfter.UTC().Format(time.DateOnly))
+		if remaining := time.Until(leaf.NotAfter); remaining < channelCertExpiryWarnWithin {
+			warning = fmt.Sprintf("upstream certificate expires in %d day(s)", int(remaining.Hours()/24))
+		}
+	}
+	return detail, warning, nil
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+func callValidateChannel(t *testing.T, s *Server, body map[string]any) ChannelValidateResponse {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/channels/validate", bytes.NewReader(data))
+	s.HandleValidateChannel(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data ChannelValidateResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return resp.Data
+}
+
+func TestHandleValidateChannel_CollectsAllErrors(t *testing.T) {
+	server, _, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+
+	resp := callValidateChannel(t, server, map[string]any{
+		"name":         "draft",
+		"url":          "https://api.example.com/v1/messages",
+		"channel_type": "bogus",
+		"models":       []map[string]any{},
+	})
+	if resp.Valid || resp.Normalized != nil {
+		t.Fatalf("不完整配置不应通过: %+v", resp)
+	}
+	fields := map[string]bool{}
+	for _, e := range resp.Errors {
+		fields[e.Field] = true
+	}
+	for _, f := range []string{"api_key", "models", "url", "channel_type"} {
+		if !fields[f] {
+			t.Errorf("缺少字段错误 %s: %+v", f, resp.Errors)
+		}
+	}
+	if fields["name"] {
+		t.Errorf("name 合法不应报错: %+v", resp.Errors)
+	}
+
+	// Validate 仍保持 fail-fast 语义：返回第一个错误
+	cr := &ChannelRequest{Name: "x", URL: "https://api.example.com"}
+	if err := cr.Validate(); err == nil || err.Error() != "api_key cannot be empty" {
+		t.Fatalf("Validate 第一个错误不符: %v", err)
+	}
+}
+
+func TestHandleValidateChannel_WarningsAndReachability(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+
+	existing, err := store.CreateConfig(context.Background(), &model.Config{
+		Name:         "taken",
+		URL:          "https://api.example.com",
+		Priority:     1,
+		ModelEntries: []model.ModelEntry{{Model: "m"}},
+		Enabled:      true,
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+
+	var headSeen atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headSeen.Store(r.Method == http.MethodHead)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer upstream.Close()
+
+	body := map[string]any{
+		"name":         "taken",
+		"api_key":      "sk-a,sk-a",
+		"url":          upstream.URL + "/",
+		"channel_type": "anthropic",
+		"models":       []map[string]any{{"model": "claude-x"}, {"model": "claude-x"}},
+		"reachability": true,
+	}
+	resp := callValidateChannel(t, server, body)
+	if !resp.Valid || resp.Normalized == nil || resp.Normalized.URL != upstream.URL {
+		t.Fatalf("期望校验通过且返回标准化URL: %+v", resp)
+	}
+	warned := map[string]bool{}
+	for _, w := range resp.Warnings {
+		warned[w.Field] = true
+	}
+	for _, f := range []string{"name", "api_key", "models[1]"} {
+		if !warned[f] {
+			t.Errorf("缺少警告 %s: %+v", f, resp.Warnings)
+		}
+	}
+
+	checks := map[string]ChannelReachabilityCheck{}
+	for _, chk := range resp.Checks {
+		checks[chk.Name] = chk
+	}
+	if !checks["dns"].OK || !checks["tls"].Skipped || !checks["head"].OK || checks["head"].Detail != "HTTP 404" {
+		t.Fatalf("连通性检查结果不符: %+v", resp.Checks)
+	}
+	if !headSeen.Load() {
+		t.Fatal("上游未收到HEAD请求")
+	}
+
+	// 编辑自身时不报重名
+	body["channel_id"] = existing.ID
+	body["reachability"] = false
+	resp = callValidateChannel(t, server, body)
+	for _, w := range resp.Warnings {
+		if w.Field == "name" {
+			t.Fatalf("编辑自身不应报重名: %+v", resp.Warnings)
+		}
+	}
+	if resp.Checks != nil {
+		t.Fatalf("未请求连通性检查时不应返回checks: %+v", resp.Checks)
+	}
+}
+
+func TestHandleValidateChannel_RejectsUnknownClientProfile(t *testing.T) {
+	server, _, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	profiles, err := util.ParseClientProfiles(`{"team-cli":{"User-Agent":"team/1.0"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.clientProfiles = profiles
+
+	base := map[string]any{
+		"name": "p", "api_key": "sk-x", "url": "https://api.example.com",
+		"models": []map[string]any{{"model": "m"}},
+	}
+	for profile, wantValid := range map[string]bool{
+		"":                true,
+		"claude-cli":      true,
+		"team-cli":        true,
+		"claude-cli-typo": false,
+	} {
+		base["client_profile"] = profile
+		resp := callValidateChannel(t, server, base)
+		if resp.Valid != wantValid {
+			t.Fatalf("client_profile=%q: 期望 valid=%v，实际 %+v", profile, wantValid, resp.Errors)
+		}
+		if !wantValid && (len(resp.Errors) != 1 || resp.Errors[0].Field != "client_profile") {
+			t.Fatalf("未定义的 client_profile 应报字段错误: %+v", resp.Errors)
+		}
+	}
+}
@@ -174,6 +174,13 @@ func (s *Server) handleCreateChannel(c *gin.Context) {
 		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
 		return
 	}
+	if err := s.validateClientProfile(req.ClientProfile); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if s.rejectDuplicateKeys(c, util.ParseAPIKeys(req.APIKey), 0) {
+		return
+	}
 
 	// 创建渠道（不包含API Key）
 	created, err := s.store.CreateConfig(c.Request.Context(), req.ToConfig())
@@ -208,11 +215,94 @@ func (s *Server) handleCreateChannel(c *gin.Context) {
 	}
 
 	// 新增、删除或更新渠道后，失效缓存保持一致性
+	s.invalidateChannelRelatedCache(created.ID)
+	s.refreshKnownSecrets()
+
+	RespondJSON(c, http.StatusCreated, created)
+}
+
+// HandleCloneChannel 克隆渠道配置（不含API Key）
+// POST /admin/channels/:id/clone
+// 复制模型/重定向、URL、优先级、成本限额、请求头profile等配置，便于为同一供应商快速添加第二个账号
+func (s *Server) HandleCloneChannel(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+
+	var req ChannelCloneRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	src, err := s.store.GetConfig(ctx, id)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "channel not found")
+		return
+	}
+
+	existing, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	names := make(map[string]struct{}, len(existing))
+	for _, cfg := range existing {
+		names[cfg.Name] = struct{}{}
+	}
+
+	clone := &model.Config{
+		Name:           uniqueCloneName(src.Name, req.Suffix(), names),
+		ChannelType:    src.ChannelType,
+		URL:            src.URL,
+		Priority:       src.Priority,
+		Enabled:        src.Enabled && !req.Disabled,
+		ModelEntries:   append([]model.ModelEntry(nil), src.ModelEntries...),
+		DailyCostLimit: src.DailyCostLimit,
+		CostMultiplier: src.CostMultiplier,
+		ClientProfile:  src.ClientProfile,
+		CertPins:       src.CertPins,
+		LocalAddr:      src.LocalAddr,
+
+		RequestCompression: src.RequestCompression,
+		AcceptEncoding:     src.AcceptEncoding,
+		AnthropicCompat:    src.AnthropicCompat,
+		OpenAICompat:       src.OpenAICompat,
+		IdempotencyKeys:    src.IdempotencyKeys,
+		Weight:             src.Weight,
+		Regions:            src.Regions,
+		BetaFeatures:       src.BetaFeatures,
+		RewriteRules:       src.RewriteRules,
+		ModelConcurrency:   src.ModelConcurrency,
+	}
+
+	created, err := s.store.CreateConfig(ctx, clone)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
 	s.invalidateChannelRelatedCache(created.ID)
 
 	RespondJSON(c, http.StatusCreated, created)
 }
 
+// uniqueCloneName 生成克隆渠道名称：源名称+后缀，重名时追加序号（name 列唯一）
+func uniqueCloneName(base, suffix string, taken map[string]struct{}) string {
+	name := base + suffix
+	for i := 2; ; i++ {
+		if _, exists := taken[name]; !exists {
+			return name
+		}
+		name = fmt.Sprintf("%s%s %d", base, suffix, i)
+	}
+}
+
 // HandleChannelByID 处理单个渠道的CRUD操作
 func (s *Server) HandleChannelByID(c *gin.Context) {
 	id, err := ParseInt64Param(c, "id")
@@ -309,6 +399,10 @@ func (s *Server) handleUpdateChannel(c *gin.Context, id int64) {
 		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := s.validateClientProfile(req.ClientProfile); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// 检测api_key是否变化（需要重建API Keys）
 	oldKeys, err := s.getAPIKeys(c.Request.Context(), id)
@@ -334,6 +428,11 @@ func (s *Server) handleUpdateChannel(c *gin.Context, id int64) {
 		}
 	}
 
+	// 仅在Key变化时检查跨渠道重复，已存在的重复不阻塞其他字段的编辑
+	if keyChanged && s.rejectDuplicateKeys(c, newKeys, id) {
+		return
+	}
+
 	// [INFO] 修复 (2025-10-11): 检测策略变化
 	strategyChanged := false
 	if !keyChanged && len(oldKeys) > 0 && len(newKeys) > 0 {
@@ -357,16 +456,24 @@ func (s *Server) handleUpdateChannel(c *gin.Context, id int64) {
 		_ = s.store.DeleteAllAPIKeys(c.Request.Context(), id)
 
 		// 批量创建新的API Keys（优化：单次事务插入替代循环单条插入）
+		// 保留的Key沿用原账号分组（按Key内容匹配，索引可能已变化）
+		oldGroups := make(map[string]string, len(oldKeys))
+		for _, k := range oldKeys {
+			if k.AccountGroup != "" {
+				oldGroups[k.APIKey] = k.AccountGroup
+			}
+		}
 		now := time.Now()
 		apiKeys := make([]*model.APIKey, 0, len(newKeys))
 		for i, key := range newKeys {
 			apiKeys = append(apiKeys, &model.APIKey{
-				ChannelID:   id,
-				KeyIndex:    i,
-				APIKey:      key,
-				KeyStrategy: keyStrategy,
-				CreatedAt:   model.JSONTime{Time: now},
-				UpdatedAt:   model.JSONTime{Time: now},
+				ChannelID:    id,
+				KeyIndex:     i,
+				APIKey:       key,
+				KeyStrategy:  keyStrategy,
+				AccountGroup: oldGroups[key],
+				CreatedAt:    model.JSONTime{Time: now},
+				UpdatedAt:    model.JSONTime{Time: now},
 			})
 		}
 		if err := s.store.CreateAPIKeysBatch(c.Request.Context(), apiKeys); err != nil {
@@ -384,11 +491,17 @@ func (s *Server) handleUpdateChannel(c *gin.Context, id int64) {
 	if s.cooldownManager != nil {
 		if err := s.cooldownManager.ClearChannelCooldown(c.Request.Context(), id); err != nil {
 			log.Printf("[WARN] 清除渠道冷却状态失败 (channel=%d): %v", id, err)
+		} else {
+			s.publishCooldownEvent(id, AdminCooldownEvent{KeyIndex: -1, Action: "cleared", Source: "manual"})
 		}
 	}
 
+	// 渠道配置已修改，清除模型不存在标记（2026-10新增）
+	s.modelNotFound.clear(id)
+
 	// 渠道更新后刷新缓存，避免返回陈旧数据
 	s.invalidateChannelRelatedCache(id)
+	s.refreshKnownSecrets()
 
 	RespondJSON(c, http.StatusOK, upd)
 }
@@ -403,6 +516,7 @@ func (s *Server) handleDeleteChannel(c *gin.Context, id int64) {
 	if s.keySelector != nil {
 		s.keySelector.RemoveChannelCounter(id)
 	}
+	s.modelNotFound.clear(id)
 	// 删除渠道后刷新缓存
 	s.invalidateChannelRelatedCache(id)
 	// 数据库级联删除会自动清理冷却数据（无需手动清理缓存）
@@ -474,6 +588,46 @@ func (s *Server) HandleDeleteAPIKey(c *gin.Context) {
 	})
 }
 
+// maxAccountGroupLen 账号分组标识最大长度（与 api_keys.account_group 列宽一致）
+const maxAccountGroupLen = 64
+
+// HandleSetKeyAccountGroup 设置Key的上游账号分组（2026-10新增）
+// PUT /admin/channels/:id/keys/:keyIndex/account-group  {"account_group": "acme-main"}
+// 同一上游账号挂在多个渠道下时设置相同分组，任一渠道上的Key级冷却会同步到组内所有Key；空字符串取消分组
+func (s *Server) HandleSetKeyAccountGroup(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	keyIndex, err := strconv.Atoi(c.Param("keyIndex"))
+	if err != nil || keyIndex < 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid key index")
+		return
+	}
+
+	var req struct {
+		AccountGroup string `json:"account_group"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, err)
+		return
+	}
+	group := strings.TrimSpace(req.AccountGroup)
+	if len(group) > maxAccountGroupLen {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("account_group exceeds %d characters", maxAccountGroupLen))
+		return
+	}
+
+	if err := s.store.UpdateAPIKeyAccountGroup(c.Request.Context(), channelID, keyIndex, group); err != nil {
+		RespondError(c, http.StatusNotFound, err)
+		return
+	}
+	s.InvalidateAPIKeysCache(channelID)
+
+	RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "key_index": keyIndex, "account_group": group})
+}
+
 // HandleAddModels 添加模型到渠道（去重）
 // POST /admin/channels/:id/models
 func (s *Server) HandleAddModels(c *gin.Context) {
@@ -569,6 +723,7 @@ func (s *Server) HandleDeleteModels(c *gin.Context) {
 		return
 	}
 
+	s.modelNotFound.clear(channelID)
 	s.InvalidateChannelListCache()
 	RespondJSON(c, http.StatusOK, gin.H{"remaining": len(remaining)})
 }
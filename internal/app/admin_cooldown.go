@@ -35,6 +35,8 @@ func (s *Server) HandleSetChannelCooldown(c *gin.Context) {
 
 	// 精确计数(手动设置渠道冷却
 
+	s.publishCooldownEvent(id, AdminCooldownEvent{KeyIndex: -1, Action: "cooldown", Source: "manual", Until: until.UnixMilli()})
+
 	RespondJSON(c, http.StatusOK, gin.H{"message": fmt.Sprintf("渠道已冷却 %d 毫秒", req.DurationMs)})
 }
 
@@ -69,5 +71,7 @@ func (s *Server) HandleSetKeyCooldown(c *gin.Context) {
 	// [INFO] 修复：使API Keys缓存失效，确保前端能立即看到冷却状态
 	s.InvalidateAPIKeysCache(id)
 
+	s.publishCooldownEvent(id, AdminCooldownEvent{KeyIndex: keyIndex, Action: "cooldown", Source: "manual", Until: until.UnixMilli()})
+
 	RespondJSON(c, http.StatusOK, gin.H{"message": fmt.Sprintf("Key #%d 已冷却 %d 毫秒", keyIndex+1, req.DurationMs)})
 }
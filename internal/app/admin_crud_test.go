@@ -175,6 +175,19 @@ func TestHandleCreateChannel(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			checkSuccess:   false,
 		},
+		{
+			name: "未定义的client_profile",
+			payload: ChannelRequest{
+				Name:          "Test",
+				APIKey:        "sk-test",
+				URL:           "https://api.com",
+				Priority:      50,
+				Models:        []model.ModelEntry{{Model: "model", RedirectModel: ""}},
+				ClientProfile: "claude-cli-typo",
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkSuccess:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -846,3 +859,90 @@ func TestChannelRequestValidate(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleCloneChannel 测试克隆渠道（复制配置，不复制Key）
+func TestHandleCloneChannel(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	src, err := store.CreateConfig(ctx, &model.Config{
+		Name:           "Provider-A",
+		URL:            "https://api.example.com",
+		Priority:       20,
+		ChannelType:    "openai",
+		ModelEntries:   []model.ModelEntry{{Model: "gpt-4o", RedirectModel: "gpt-4o-2024-08-06"}, {Model: "gpt-4o-mini"}},
+		Enabled:        true,
+		DailyCostLimit: 12.5,
+		CostMultiplier: 0.8,
+	})
+	if err != nil {
+		t.Fatalf("创建测试渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{ChannelID: src.ID, KeyIndex: 0, APIKey: "sk-src", KeyStrategy: model.KeyStrategySequential}}); err != nil {
+		t.Fatalf("创建测试Key失败: %v", err)
+	}
+
+	clone := func(body string) (*httptest.ResponseRecorder, *model.Config) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/channels/"+strconv.FormatInt(src.ID, 10)+"/clone", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(src.ID, 10)}}
+		server.HandleCloneChannel(c)
+
+		var resp struct {
+			Data *model.Config `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	w, cloned := clone("")
+	if w.Code != http.StatusCreated || cloned == nil {
+		t.Fatalf("期望状态码201，实际%d，响应体: %s", w.Code, w.Body.String())
+	}
+	if cloned.Name != "Provider-A (copy)" || !cloned.Enabled {
+		t.Errorf("默认克隆: name=%q enabled=%v", cloned.Name, cloned.Enabled)
+	}
+	if cloned.ChannelType != "openai" || cloned.Priority != 20 || cloned.DailyCostLimit != 12.5 || cloned.CostMultiplier != 0.8 {
+		t.Errorf("配置未完整复制: %+v", cloned)
+	}
+	if len(cloned.ModelEntries) != 2 || cloned.ModelEntries[0].RedirectModel != "gpt-4o-2024-08-06" {
+		t.Errorf("模型/重定向未复制: %+v", cloned.ModelEntries)
+	}
+	keys, err := store.GetAPIKeys(ctx, cloned.ID)
+	if err != nil {
+		t.Fatalf("查询Key失败: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("克隆渠道不应包含Key，实际%d个", len(keys))
+	}
+
+	// 重名时追加序号，并支持禁用
+	w, cloned = clone(`{"disabled":true}`)
+	if w.Code != http.StatusCreated || cloned == nil {
+		t.Fatalf("期望状态码201，实际%d，响应体: %s", w.Code, w.Body.String())
+	}
+	if cloned.Name != "Provider-A (copy) 2" || cloned.Enabled {
+		t.Errorf("重名克隆: name=%q enabled=%v", cloned.Name, cloned.Enabled)
+	}
+
+	w, cloned = clone(`{"name_suffix":"-account2"}`)
+	if w.Code != http.StatusCreated || cloned == nil || cloned.Name != "Provider-A-account2" {
+		t.Fatalf("自定义后缀克隆失败: code=%d body=%s", w.Code, w.Body.String())
+	}
+
+	// 不存在的渠道
+	req := httptest.NewRequest(http.MethodPost, "/admin/channels/999/clone", nil)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "999"}}
+	server.HandleCloneChannel(c)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("期望状态码404，实际%d", rec.Code)
+	}
+}
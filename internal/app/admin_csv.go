@@ -118,7 +118,13 @@ func (s *Server) HandleExportChannelsCSV(c *gin.Context) {
 
 // HandleImportChannelsCSV 导入渠道CSV
 // POST /admin/channels/import
+// 可选 ?format=oneapi|newapi|litellm 导入其他代理工具的配置（见 admin_import_formats.go）
 func (s *Server) HandleImportChannelsCSV(c *gin.Context) {
+	if format := strings.ToLower(strings.TrimSpace(c.Query("format"))); format != "" && format != "csv" {
+		s.handleImportChannelsForeign(c, format)
+		return
+	}
+
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
 		RespondErrorMsg(c, http.StatusBadRequest, "缺少上传文件")
@@ -302,6 +308,11 @@ func (s *Server) HandleImportChannelsCSV(c *gin.Context) {
 		})
 	}
 
+	s.commitChannelImport(c, validChannels, summary)
+}
+
+// commitChannelImport 批量写入解析后的渠道并返回导入统计（CSV与其他格式共用）
+func (s *Server) commitChannelImport(c *gin.Context, validChannels []*model.ChannelWithKeys, summary ChannelImportSummary) {
 	// 批量导入所有有效记录(单事务 + 预编译语句)
 	if len(validChannels) > 0 {
 		created, updated, err := s.store.ImportChannelBatch(c.Request.Context(), validChannels)
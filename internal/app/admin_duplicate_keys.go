@@ -0,0 +1,166 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 重复上游Key检测（2026-10新增）
+// ============================================================================
+// 同一个API Key被粘贴到多个渠道时，实际速率翻倍且冷却状态互不相通。
+// 按Key的SHA-256指纹跨渠道比对：校验接口（/admin/channels/validate）返回警告，
+// GET /admin/channels/duplicate-keys 列出全部重复Key及涉及的渠道（只输出脱敏Key与指纹）。
+// 系统设置 reject_duplicate_api_keys=true 时，创建/更新渠道若引入其他渠道已有的Key直接返回409。
+
+// DuplicateKeyChannel 使用重复Key的渠道
+type DuplicateKeyChannel struct {
+	ChannelID   int64  `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	ChannelType string `json:"channel_type"`
+	Enabled     bool   `json:"enabled"`
+	KeyIndex    int    `json:"key_index"`
+}
+
+// DuplicateKeyGroup 一个被多个渠道共用的Key
+type DuplicateKeyGroup struct {
+	Fingerprint string                `json:"fingerprint"` // SHA-256 前16位十六进制
+	MaskedKey   string                `json:"masked_key"`
+	Channels    []DuplicateKeyChannel `json:"channels"`
+}
+
+// apiKeyFingerprint Key指纹（比对与对外展示用，不可逆）
+func apiKeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// findDuplicateAPIKeys 跨渠道比对Key指纹，返回出现在两个及以上渠道中的Key（同一渠道内的重复不计入）
+// 结果按首个渠道ID、指纹排序
+func findDuplicateAPIKeys(configs []*model.Config, keysByChannel map[int64][]*model.APIKey) []DuplicateKeyGroup {
+	byFingerprint := make(map[string]*DuplicateKeyGroup)
+	for _, cfg := range configs {
+		for _, k := range keysByChannel[cfg.ID] {
+			fp := apiKeyFingerprint(k.APIKey)
+			g, ok := byFingerprint[fp]
+			if !ok {
+				g = &DuplicateKeyGroup{Fingerprint: fp, MaskedKey: util.MaskAPIKey(strings.TrimSpace(k.APIKey))}
+				byFingerprint[fp] = g
+			}
+			if slices.ContainsFunc(g.Channels, func(ch DuplicateKeyChannel) bool { return ch.ChannelID == cfg.ID }) {
+				continue
+			}
+			g.Channels = append(g.Channels, DuplicateKeyChannel{
+				ChannelID:   cfg.ID,
+				ChannelName: cfg.Name,
+				ChannelType: cfg.GetChannelType(),
+				Enabled:     cfg.Enabled,
+				KeyIndex:    k.KeyIndex,
+			})
+		}
+	}
+
+	groups := make([]DuplicateKeyGroup, 0)
+	for _, g := range byFingerprint {
+		if len(g.Channels) < 2 {
+			continue
+		}
+		slices.SortFunc(g.Channels, func(a, b DuplicateKeyChannel) int { return cmp.Compare(a.ChannelID, b.ChannelID) })
+		groups = append(groups, *g)
+	}
+	slices.SortFunc(groups, func(a, b DuplicateKeyGroup) int {
+		return cmp.Or(cmp.Compare(a.Channels[0].ChannelID, b.Channels[0].ChannelID), strings.Compare(a.Fingerprint, b.Fingerprint))
+	})
+	return groups
+}
+
+// duplicateKeyConflict 待保存Key与其他渠道的冲突
+type duplicateKeyConflict struct {
+	KeyIndex    int // 待保存Key列表中的位置
+	ChannelID   int64
+	ChannelName string
+}
+
+// findKeyConflicts 查找 keys 中已配置在其他渠道（排除 selfID）的Key，每个Key只报告首个冲突渠道
+func (s *Server) findKeyConflicts(ctx context.Context, keys []string, selfID int64) ([]duplicateKeyConflict, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	configs, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keysByChannel, err := s.store.GetAllAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owner := make(map[string]*model.Config)
+	for _, cfg := range configs {
+		if cfg.ID == selfID {
+			continue
+		}
+		for _, k := range keysByChannel[cfg.ID] {
+			fp := apiKeyFingerprint(k.APIKey)
+			if _, ok := owner[fp]; !ok {
+				owner[fp] = cfg
+			}
+		}
+	}
+	var conflicts []duplicateKeyConflict
+	for i, key := range keys {
+		if cfg, ok := owner[apiKeyFingerprint(key)]; ok {
+			conflicts = append(conflicts, duplicateKeyConflict{KeyIndex: i, ChannelID: cfg.ID, ChannelName: cfg.Name})
+		}
+	}
+	return conflicts, nil
+}
+
+// rejectDuplicateKeys 开启 reject_duplicate_api_keys 时检查冲突并返回409（已响应时返回 true）
+func (s *Server) rejectDuplicateKeys(c *gin.Context, keys []string, selfID int64) bool {
+	if s.configService == nil || !s.configService.GetBool("reject_duplicate_api_keys", false) {
+		return false
+	}
+	conflicts, err := s.findKeyConflicts(c.Request.Context(), keys, selfID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return true
+	}
+	if len(conflicts) == 0 {
+		return false
+	}
+	parts := make([]string, 0, len(conflicts))
+	for _, cf := range conflicts {
+		parts = append(parts, fmt.Sprintf("key #%d is already used by channel #%d (%s)", cf.KeyIndex+1, cf.ChannelID, cf.ChannelName))
+	}
+	RespondErrorMsg(c, http.StatusConflict, "duplicate api key: "+strings.Join(parts, "; "))
+	return true
+}
+
+// HandleDuplicateKeys 列出被多个渠道共用的上游Key
+// GET /admin/channels/duplicate-keys
+func (s *Server) HandleDuplicateKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+	configs, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	keysByChannel, err := s.store.GetAllAPIKeys(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	groups := findDuplicateAPIKeys(configs, keysByChannel)
+	RespondJSONWithCount(c, http.StatusOK, groups, len(groups))
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDuplicateKeys_ReportWarnAndReject(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "dupkeys.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	var ids []int64
+	for i, keys := range [][]string{{"sk-shared-0001", "sk-only-a-0001"}, {"sk-other-0001", "sk-shared-0001"}, {"sk-only-c-0001", "sk-only-c-0001"}} {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: "ch" + strconv.Itoa(i), URL: "https://api.example.com", ChannelType: "anthropic", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+		})
+		if err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+		var apiKeys []*model.APIKey
+		for j, k := range keys {
+			apiKeys = append(apiKeys, &model.APIKey{ChannelID: cfg.ID, KeyIndex: j, APIKey: k, KeyStrategy: model.KeyStrategySequential})
+		}
+		if err := store.CreateAPIKeysBatch(ctx, apiKeys); err != nil {
+			t.Fatalf("创建Key失败: %v", err)
+		}
+		ids = append(ids, cfg.ID)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	call := func(handler gin.HandlerFunc, method, path, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		handler(c)
+		return w
+	}
+
+	// 报告：只列出跨渠道的重复（渠道内部重复不计入），不含明文Key
+	w := call(srv.HandleDuplicateKeys, http.MethodGet, "/admin/channels/duplicate-keys", "", nil)
+	var report struct {
+		Data  []DuplicateKeyGroup `json:"data"`
+		Count int                 `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if report.Count != 1 || len(report.Data[0].Channels) != 2 || report.Data[0].Channels[0].ChannelID != ids[0] ||
+		report.Data[0].Channels[1].KeyIndex != 1 || report.Data[0].MaskedKey != "sk-s...0001" ||
+		strings.Contains(w.Body.String(), "sk-shared-0001") {
+		t.Fatalf("重复Key报告不符: %s", w.Body.String())
+	}
+
+	// 校验接口：编辑自身时不与自己比对
+	resp := callValidateChannel(t, srv, map[string]any{
+		"name": "new", "api_key": "sk-fresh-0001,sk-other-0001", "channel_type": "anthropic",
+		"url": "https://api.example.com", "models": []map[string]any{{"model": "m"}},
+	})
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0].Message, "key #2 is already used by channel #"+strconv.FormatInt(ids[1], 10)) {
+		t.Fatalf("应提示跨渠道重复Key: %+v", resp.Warnings)
+	}
+	resp = callValidateChannel(t, srv, map[string]any{
+		"channel_id": ids[1], "name": "ch1-edit", "api_key": "sk-other-0001", "channel_type": "anthropic",
+		"url": "https://api.example.com", "models": []map[string]any{{"model": "m"}},
+	})
+	if len(resp.Warnings) != 0 {
+		t.Fatalf("渠道自身的Key不应告警: %+v", resp.Warnings)
+	}
+
+	const createBody = `{"name":"dup","api_key":"sk-shared-0001","channel_type":"anthropic","url":"https://api.example.com","models":[{"model":"m"}]}`
+	if w := call(srv.HandleChannels, http.MethodPost, "/admin/channels", createBody, nil); w.Code != http.StatusCreated {
+		t.Fatalf("默认仅告警，应允许创建: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := call(srv.AdminUpdateSetting, http.MethodPut, "/admin/settings/reject_duplicate_api_keys", `{"value":"true"}`,
+		gin.Params{{Key: "key", Value: "reject_duplicate_api_keys"}}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "立即生效") {
+		t.Fatalf("热更新失败: %d %s", w.Code, w.Body.String())
+	}
+	w = call(srv.HandleChannels, http.MethodPost, "/admin/channels", strings.Replace(createBody, `"dup"`, `"dup2"`, 1), nil)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "channel #"+strconv.FormatInt(ids[0], 10)) {
+		t.Fatalf("开启拒绝后应返回409: %d %s", w.Code, w.Body.String())
+	}
+
+	// 更新：Key未变化时不阻塞（已存在的重复）
+	idParam := gin.Params{{Key: "id", Value: strconv.FormatInt(ids[1], 10)}}
+	updateBody := `{"name":"ch1","api_key":"sk-other-0001,sk-shared-0001","channel_type":"anthropic","url":"https://api.example.com","priority":5,"models":[{"model":"m"}]}`
+	if w := call(srv.HandleChannelByID, http.MethodPut, "/admin/channels/x", updateBody, idParam); w.Code != http.StatusOK {
+		t.Fatalf("Key未变化的更新应成功: %d %s", w.Code, w.Body.String())
+	}
+	updateBody = strings.Replace(updateBody, "sk-other-0001", "sk-only-a-0001", 1)
+	if w := call(srv.HandleChannelByID, http.MethodPut, "/admin/channels/x", updateBody, idParam); w.Code != http.StatusConflict {
+		t.Fatalf("引入其他渠道的Key应返回409: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,520 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 管理端统一事件流（2026-10新增）
+// ============================================================================
+// GET /admin/events 以单条SSE长连接推送管理端关心的实时变化，替代各页面各自轮询：
+//   - log：请求日志写入（摘要，不含消息体）
+//   - cooldown：渠道/Key进入或解除冷却（自动判定与手动设置）
+//   - active_requests：进行中请求快照（有变化或存在进行中请求时每秒一次）
+//   - key_quota：上游报告的Key配额快照
+//   - model_unsupported：重定向模型被上游报告不存在，渠道模型已临时标记（需修正渠道配置）
+//   - token_drift：渠道+模型的Token估算值与上游实际值持续偏离（协议转换/估算器缺陷）
+// 客户端通过 ?types=log,cooldown&channel_id=1,2 订阅过滤；无 channel_id 的全局事件不受渠道过滤影响。
+// 订阅者缓冲区满时丢弃事件并随后发送 dropped 事件，提示客户端全量刷新。
+// 断线续传：事件带 SSE id，总线保留最近的事件（进行中请求快照除外），客户端重连时携带 Last-Event-ID
+// （请求头或 ?last_event_id=）即可补发错过的事件；超出保留范围（含服务重启）时发送 dropped 事件提示全量刷新。
+// 流开始时下发 retry 提示，统一客户端重连间隔。
+// 关闭时统一由 CloseAdminEvents 结束所有流（注册为 http.Server 的 OnShutdown 钩子）。
+
+// 管理端事件类型
+const (
+	AdminEventLog              = "log"
+	AdminEventCooldown         = "cooldown"
+	AdminEventActiveRequests   = "active_requests"
+	AdminEventKeyQuota         = "key_quota"
+	AdminEventModelUnsupported = "model_unsupported"
+	AdminEventTokenDrift       = "token_drift"
+)
+
+// adminEventTypes 可订阅的事件类型（hello/dropped 为控制事件，始终发送）
+var adminEventTypes = []string{AdminEventLog, AdminEventCooldown, AdminEventActiveRequests, AdminEventKeyQuota, AdminEventModelUnsupported, AdminEventTokenDrift}
+
+const (
+	adminEventSubBuffer         = 256
+	adminEventHeartbeatInterval = 15 * time.Second
+	adminEventSnapshotInterval  = time.Second
+
+	adminEventReplaySize   = 1024            // 断线续传保留的事件数
+	adminEventReplayWindow = 5 * time.Minute // 断线续传保留时长（无订阅者超过该时长后停止记录）
+	adminEventRetryMs      = 3000            // SSE retry 提示（毫秒）
+)
+
+// AdminEvent 推送给管理端的事件
+type AdminEvent struct {
+	ID        uint64 `json:"id"`
+	Type      string `json:"type"`
+	Time      int64  `json:"time"` // Unix毫秒
+	ChannelID int64  `json:"channel_id,omitempty"`
+	Data      any    `json:"data"`
+}
+
+// AdminLogEvent log 事件数据（日志摘要）
+type AdminLogEvent struct {
+	Model        string  `json:"model"`
+	ActualModel  string  `json:"actual_model,omitempty"`
+	StatusCode   int     `json:"status_code"`
+	Duration     float64 `json:"duration"`
+	IsStreaming  bool    `json:"is_streaming"`
+	AuthTokenID  int64   `json:"auth_token_id,omitempty"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	Cost         float64 `json:"cost,omitempty"`
+}
+
+// AdminCooldownEvent cooldown 事件数据
+type AdminCooldownEvent struct {
+	KeyIndex   int    `json:"key_index"`             // -1 表示渠道级
+	Action     string `json:"action"`                // cooldown / cleared
+	Source     string `json:"source"`                // auto / manual
+	Until      int64  `json:"until,omitempty"`       // 冷却截止时间（Unix毫秒，仅手动设置时已知）
+	StatusCode int    `json:"status_code,omitempty"` // 触发冷却的上游状态码（自动判定时）
+}
+
+// AdminKeyQuotaEvent key_quota 事件数据
+type AdminKeyQuotaEvent struct {
+	KeyIndex  int            `json:"key_index"`
+	Exhausted bool           `json:"exhausted"`
+	Quota     model.KeyQuota `json:"quota"`
+}
+
+// adminEventFilter 订阅过滤条件（空集合表示不过滤）
+type adminEventFilter struct {
+	types    map[string]bool
+	channels map[int64]bool
+}
+
+func (f adminEventFilter) wants(eventType string) bool {
+	return len(f.types) == 0 || f.types[eventType]
+}
+
+func (f adminEventFilter) match(ev *AdminEvent) bool {
+	if !f.wants(ev.Type) {
+		return false
+	}
+	return ev.ChannelID == 0 || len(f.channels) == 0 || f.channels[ev.ChannelID]
+}
+
+// parseAdminEventFilter 解析 types / channel_id 查询参数（逗号分隔）
+func parseAdminEventFilter(types, channels string) (adminEventFilter, error) {
+	var f adminEventFilter
+	for _, t := range strings.Split(types, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, k := range adminEventTypes {
+			if k == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return f, fmt.Errorf("unknown event type %q (supported: %s)", t, strings.Join(adminEventTypes, ","))
+		}
+		if f.types == nil {
+			f.types = make(map[string]bool)
+		}
+		f.types[t] = true
+	}
+	for _, c := range strings.Split(channels, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(c, 10, 64)
+		if err != nil || id <= 0 {
+			return f, fmt.Errorf("invalid channel_id %q", c)
+		}
+		if f.channels == nil {
+			f.channels = make(map[int64]bool)
+		}
+		f.channels[id] = true
+	}
+	return f, nil
+}
+
+// adminEventSub 单个订阅者
+type adminEventSub struct {
+	ch      chan *AdminEvent
+	filter  adminEventFilter
+	dropped atomic.Uint64 // 自上次通知以来因缓冲区满丢弃的事件数
+}
+
+// adminEventBus 进程内发布/订阅中心；发布方不阻塞，慢订阅者丢事件
+type adminEventBus struct {
+	mu     sync.RWMutex
+	subs   map[*adminEventSub]struct{}
+	closed bool
+
+	// 断线续传缓冲（环形，按ID递增；记录的事件ID连续，便于判断是否有遗漏）
+	history   []*AdminEvent
+	histStart int       // 最旧事件下标
+	lastID    uint64    // 最近分配的事件ID
+	idleSince time.Time // 最后一个订阅者断开的时间（有订阅者时为零值）
+
+	published atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func newAdminEventBus() *adminEventBus {
+	return &adminEventBus{
+		subs: make(map[*adminEventSub]struct{}),
+		// 以启动时间为ID起点：服务重启后新ID大于旧ID，客户端携带的旧ID会被识别为超出保留范围
+		lastID:    uint64(time.Now().UnixMilli()) << 10,
+		idleSince: time.Now(),
+	}
+}
+
+// adminEventResume 断线续传结果
+type adminEventResume struct {
+	events []*AdminEvent // 需补发的事件（已按订阅过滤）
+	gap    bool          // 错过的事件已不在保留范围内（或服务已重启）
+}
+
+// subscribe 注册订阅者；lastEventID>0 时同时返回需补发的事件（与注册原子完成，不重不漏）
+// 总线已关闭时返回 nil
+func (b *adminEventBus) subscribe(filter adminEventFilter, lastEventID uint64) (*adminEventSub, adminEventResume) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, adminEventResume{}
+	}
+	var resume adminEventResume
+	if lastEventID > 0 {
+		resume = b.resumeLocked(filter, lastEventID, time.Now())
+	}
+	sub := &adminEventSub{ch: make(chan *AdminEvent, adminEventSubBuffer), filter: filter}
+	b.subs[sub] = struct{}{}
+	b.idleSince = time.Time{}
+	return sub, resume
+}
+
+// resumeLocked 收集 lastEventID 之后的事件；保留的事件不足以覆盖时标记 gap
+func (b *adminEventBus) resumeLocked(filter adminEventFilter, lastEventID uint64, now time.Time) adminEventResume {
+	var r adminEventResume
+	if lastEventID > b.lastID {
+		r.gap = true // 来自其他进程（服务已重启）
+		return r
+	}
+	missed := b.lastID - lastEventID
+	var retained uint64
+	cutoff := now.Add(-adminEventReplayWindow).UnixMilli()
+	for i := range b.history {
+		ev := b.history[(b.histStart+i)%len(b.history)]
+		if ev.ID <= lastEventID || ev.Time < cutoff {
+			continue
+		}
+		retained++
+		if filter.match(ev) {
+			r.events = append(r.events, ev)
+		}
+	}
+	r.gap = retained < missed
+	return r
+}
+
+// unsubscribe 移除订阅者（幂等；总线关闭后通道已由 close 关闭）
+func (b *adminEventBus) unsubscribe(sub *adminEventSub) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+		if len(b.subs) == 0 {
+			b.idleSince = time.Now()
+		}
+	}
+}
+
+// rememberLocked 记录事件供断线续传（环形缓冲，满时覆盖最旧事件）
+func (b *adminEventBus) rememberLocked(ev *AdminEvent) {
+	if len(b.history) < adminEventReplaySize {
+		b.history = append(b.history, ev)
+		return
+	}
+	b.history[b.histStart] = ev
+	b.histStart = (b.histStart + 1) % len(b.history)
+}
+
+// wants 是否有订阅者关心该类型事件（用于跳过快照构建等开销）
+func (b *adminEventBus) wants(eventType string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if sub.filter.wants(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// publish 向匹配的订阅者投递事件（非阻塞）
+// 进行中请求快照不带ID、不进入续传缓冲（重连时直接推送最新快照）
+func (b *adminEventBus) publish(eventType string, channelID int64, data any) {
+	if b == nil {
+		return
+	}
+	now := time.Now()
+	replayable := eventType != AdminEventActiveRequests
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	if len(b.subs) == 0 && (!replayable || now.Sub(b.idleSince) > adminEventReplayWindow) {
+		if replayable {
+			b.lastID++ // 未记录也占用ID，长时间断线后重连可识别出遗漏
+		}
+		return
+	}
+	ev := &AdminEvent{
+		Type:      eventType,
+		Time:      now.UnixMilli(),
+		ChannelID: channelID,
+		Data:      data,
+	}
+	if replayable {
+		b.lastID++
+		ev.ID = b.lastID
+		b.rememberLocked(ev)
+	}
+	b.published.Add(1)
+	for sub := range b.subs {
+		if !sub.filter.match(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// close 关闭总线并结束所有订阅（幂等）
+func (b *adminEventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		close(sub.ch)
+	}
+	b.subs = make(map[*adminEventSub]struct{})
+}
+
+func (b *adminEventBus) stats() map[string]any {
+	b.mu.RLock()
+	subscribers := len(b.subs)
+	b.mu.RUnlock()
+	return map[string]any{
+		"subscribers": subscribers,
+		"published":   b.published.Load(),
+		"dropped":     b.dropped.Load(),
+	}
+}
+
+// CloseAdminEvents 结束所有管理端事件流（供 http.Server.RegisterOnShutdown 调用，避免长连接拖住优雅关闭）
+func (s *Server) CloseAdminEvents() {
+	if s.adminEvents != nil {
+		s.adminEvents.close()
+	}
+}
+
+// publishLogEvent 发布日志摘要
+func (s *Server) publishLogEvent(entry *model.LogEntry) {
+	s.adminEvents.publish(AdminEventLog, entry.ChannelID, AdminLogEvent{
+		Model:        entry.Model,
+		ActualModel:  entry.ActualModel,
+		StatusCode:   entry.StatusCode,
+		Duration:     entry.Duration,
+		IsStreaming:  entry.IsStreaming,
+		AuthTokenID:  entry.AuthTokenID,
+		InputTokens:  entry.InputTokens,
+		OutputTokens: entry.OutputTokens,
+		Cost:         entry.Cost,
+	})
+}
+
+// publishCooldownEvent 发布冷却变化
+func (s *Server) publishCooldownEvent(channelID int64, ev AdminCooldownEvent) {
+	s.adminEvents.publish(AdminEventCooldown, channelID, ev)
+	s.notifyCooldownWebhook(channelID, ev)
+}
+
+// adminEventsSnapshotLoop 周期推送进行中请求快照：有变化时推送一次，存在进行中请求时持续推送（字节数实时更新）
+func (s *Server) adminEventsSnapshotLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(adminEventSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			changed := s.activeRequests.takeChanged()
+			if !s.adminEvents.wants(AdminEventActiveRequests) {
+				continue
+			}
+			list := s.activeRequests.List()
+			if !changed && len(list) == 0 {
+				continue
+			}
+			s.adminEvents.publish(AdminEventActiveRequests, 0, list)
+		}
+	}
+}
+
+// HandleAdminEvents 管理端统一事件流（SSE）
+// GET /admin/events?types=log,cooldown&channel_id=1,2
+func (s *Server) HandleAdminEvents(c *gin.Context) {
+	filter, err := parseAdminEventFilter(c.Query("types"), c.Query("channel_id"))
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	sub, resume := s.adminEvents.subscribe(filter, parseLastEventID(c))
+	if sub == nil {
+		RespondErrorMsg(c, http.StatusServiceUnavailable, "server is shutting down")
+		return
+	}
+	defer s.adminEvents.unsubscribe(sub)
+
+	w := c.Writer
+	rc := http.NewResponseController(w)
+	// 长连接不受 WriteTimeout 约束（同流式代理）
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	subscribed := adminEventTypes
+	if len(filter.types) > 0 {
+		subscribed = make([]string, 0, len(filter.types))
+		for _, t := range adminEventTypes {
+			if filter.types[t] {
+				subscribed = append(subscribed, t)
+			}
+		}
+	}
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", adminEventRetryMs); err != nil {
+		return
+	}
+	hello := gin.H{"types": subscribed, "resumed": len(resume.events)}
+	if writeAdminEvent(w, rc, &AdminEvent{Type: "hello", Time: time.Now().UnixMilli(), Data: hello}) != nil {
+		return
+	}
+	// 断线续传：先通知遗漏（客户端全量刷新），再补发保留范围内的事件
+	if resume.gap {
+		if writeAdminEvent(w, rc, &AdminEvent{Type: "dropped", Time: time.Now().UnixMilli(), Data: gin.H{"count": -1, "reason": "resume_gap"}}) != nil {
+			return
+		}
+	}
+	for _, ev := range resume.events {
+		if writeAdminEvent(w, rc, ev) != nil {
+			return
+		}
+	}
+	// 新订阅者立即拿到一次进行中请求快照，无需等待下一个变化
+	if filter.wants(AdminEventActiveRequests) {
+		if writeAdminEvent(w, rc, &AdminEvent{Type: AdminEventActiveRequests, Time: time.Now().UnixMilli(), Data: s.activeRequests.List()}) != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(adminEventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			if rc.Flush() != nil {
+				return
+			}
+		case ev, ok := <-sub.ch:
+			if !ok {
+				return // 总线已关闭（服务关闭）
+			}
+			if writeAdminEvent(w, rc, ev) != nil {
+				return
+			}
+			if n := sub.dropped.Swap(0); n > 0 {
+				if writeAdminEvent(w, rc, &AdminEvent{Type: "dropped", Time: time.Now().UnixMilli(), Data: gin.H{"count": n}}) != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// parseLastEventID 读取断线续传位置（Last-Event-ID 请求头优先，其次 ?last_event_id=；无效值视为不续传）
+func parseLastEventID(c *gin.Context) uint64 {
+	raw := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if raw == "" {
+		raw = strings.TrimSpace(c.Query("last_event_id"))
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// writeAdminEvent 以SSE格式写出单个事件并立即刷新
+func writeAdminEvent(w http.ResponseWriter, rc *http.ResponseController, ev *AdminEvent) error {
+	data, err := sonic.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	if ev.ID > 0 {
+		sb.WriteString("id: ")
+		sb.WriteString(strconv.FormatUint(ev.ID, 10))
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("event: ")
+	sb.WriteString(ev.Type)
+	sb.WriteString("\ndata: ")
+	sb.Write(data)
+	sb.WriteString("\n\n")
+	if _, err := w.Write([]byte(sb.String())); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
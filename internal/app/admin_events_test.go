@@ -0,0 +1,177 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminEventBus_FilterDropAndClose(t *testing.T) {
+	if _, err := parseAdminEventFilter("log,bogus", ""); err == nil {
+		t.Fatal("未知事件类型应报错")
+	}
+	if _, err := parseAdminEventFilter("", "1,x"); err == nil {
+		t.Fatal("非法channel_id应报错")
+	}
+
+	bus := newAdminEventBus()
+	filter, err := parseAdminEventFilter("cooldown", "7")
+	if err != nil {
+		t.Fatalf("解析过滤条件失败: %v", err)
+	}
+	sub, _ := bus.subscribe(filter, 0)
+	if !bus.wants(AdminEventCooldown) || bus.wants(AdminEventLog) {
+		t.Fatal("wants 应只匹配已订阅类型")
+	}
+
+	bus.publish(AdminEventLog, 7, nil)      // 类型不匹配
+	bus.publish(AdminEventCooldown, 8, nil) // 渠道不匹配
+	bus.publish(AdminEventCooldown, 7, AdminCooldownEvent{KeyIndex: -1, Action: "cooldown", Source: "manual"})
+	if got := len(sub.ch); got != 1 {
+		t.Fatalf("期望投递1个事件, got %d", got)
+	}
+	if ev := <-sub.ch; ev.Type != AdminEventCooldown || ev.ChannelID != 7 || ev.ID == 0 {
+		t.Fatalf("事件不符: %+v", ev)
+	}
+
+	// 缓冲区满时丢弃而不阻塞发布方
+	for i := 0; i < adminEventSubBuffer+5; i++ {
+		bus.publish(AdminEventCooldown, 7, nil)
+	}
+	if sub.dropped.Load() != 5 || bus.stats()["dropped"].(uint64) != 5 {
+		t.Fatalf("丢弃计数不符: sub=%d stats=%v", sub.dropped.Load(), bus.stats())
+	}
+
+	bus.close()
+	for range sub.ch {
+		// 排空直至通道关闭
+	}
+	bus.unsubscribe(sub) // 关闭后幂等
+	if sub, _ := bus.subscribe(adminEventFilter{}, 0); sub != nil {
+		t.Fatal("关闭后不应再接受订阅")
+	}
+}
+
+func TestHandleAdminEvents_StreamsFilteredEvents(t *testing.T) {
+	s := &Server{adminEvents: newAdminEventBus(), activeRequests: newActiveRequestManager()}
+	s.activeRequests.Register(time.Now(), "claude-x", "127.0.0.1", true)
+
+	r := gin.New()
+	r.GET("/admin/events", s.HandleAdminEvents)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	bad, err := http.Get(ts.URL + "/admin/events?types=nope")
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	_ = bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("非法types应返回400, got %d", bad.StatusCode)
+	}
+
+	resp, err := http.Get(ts.URL + "/admin/events?types=log,active_requests&channel_id=3")
+	if err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type=%q", ct)
+	}
+
+	events := make(chan AdminEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var ev AdminEvent
+				if json.Unmarshal([]byte(data), &ev) == nil {
+					events <- ev
+				}
+			}
+		}
+	}()
+	next := func() AdminEvent {
+		t.Helper()
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("事件流提前结束")
+			}
+			return ev
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待事件超时")
+		}
+		return AdminEvent{}
+	}
+
+	if ev := next(); ev.Type != "hello" {
+		t.Fatalf("首个事件应为hello: %+v", ev)
+	}
+	if ev := next(); ev.Type != AdminEventActiveRequests {
+		t.Fatalf("订阅后应立即收到进行中请求快照: %+v", ev)
+	}
+
+	s.publishCooldownEvent(3, AdminCooldownEvent{KeyIndex: -1, Action: "cooldown", Source: "manual"}) // 未订阅类型
+	s.adminEvents.publish(AdminEventLog, 4, AdminLogEvent{Model: "other"})                            // 未订阅渠道
+	s.adminEvents.publish(AdminEventLog, 3, AdminLogEvent{Model: "claude-x", StatusCode: 200})
+	ev := next()
+	if ev.Type != AdminEventLog || ev.ChannelID != 3 {
+		t.Fatalf("期望收到渠道3的log事件: %+v", ev)
+	}
+
+	// 关闭总线即结束所有流
+	s.CloseAdminEvents()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("关闭后不应再收到事件")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("关闭总线后事件流未结束")
+	}
+}
+
+func TestAdminEventBus_Resume(t *testing.T) {
+	bus := newAdminEventBus()
+	filter, _ := parseAdminEventFilter("log", "")
+	sub, resume := bus.subscribe(filter, 0)
+	if len(resume.events) != 0 || resume.gap {
+		t.Fatalf("首次订阅不应补发: %+v", resume)
+	}
+
+	bus.publish(AdminEventLog, 1, AdminLogEvent{Model: "a"})
+	bus.publish(AdminEventActiveRequests, 0, nil) // 快照不占用ID、不进入续传缓冲
+	first := <-sub.ch
+	bus.unsubscribe(sub)
+
+	// 断线期间的事件仍被记录
+	bus.publish(AdminEventLog, 1, AdminLogEvent{Model: "b"})
+	bus.publish(AdminEventCooldown, 1, AdminCooldownEvent{KeyIndex: -1})
+	bus.publish(AdminEventLog, 2, AdminLogEvent{Model: "c"})
+
+	sub, resume = bus.subscribe(filter, first.ID)
+	defer bus.unsubscribe(sub)
+	if resume.gap || len(resume.events) != 2 || resume.events[0].ID != first.ID+1 || resume.events[1].ID != first.ID+3 {
+		t.Fatalf("应补发断线期间订阅类型的事件: gap=%v events=%d", resume.gap, len(resume.events))
+	}
+
+	// 来自重启前进程的ID / 超出保留范围：标记遗漏
+	if _, r := bus.subscribe(filter, first.ID+1000); !r.gap {
+		t.Fatal("未来ID（服务已重启）应标记遗漏")
+	}
+	for i := 0; i < adminEventReplaySize+1; i++ {
+		bus.publish(AdminEventLog, 1, nil)
+	}
+	if _, r := bus.subscribe(filter, first.ID); !r.gap || len(r.events) != adminEventReplaySize {
+		t.Fatalf("超出缓冲的续传应标记遗漏并补发保留部分: gap=%v events=%d", r.gap, len(r.events))
+	}
+}
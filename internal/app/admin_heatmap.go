@@ -0,0 +1,155 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 历史延迟热力图（2026-10新增）
+// ============================================================================
+// GET /admin/metrics/heatmap?days=28&channel_id=3&model=xxx
+// 将最近 days 天（默认28，最长90）的请求延迟按 星期×小时（服务器本地时区）聚合为 7×24 网格，
+// 用于识别特定上游周期性变慢的时段，据此安排路由偏好（如工作日高峰降低优先级）。
+// 筛选参数同 /admin/metrics（channel_id、channel_type、channel_name_like、model 等）。
+// slow_windows 给出平均耗时高于整体且样本足够的最慢时段（最多 heatmapSlowWindowLimit 个）。
+
+const (
+	heatmapDefaultDays     = 28
+	heatmapMaxDays         = 90
+	heatmapMinSamples      = 5 // 单元格成功样本少于该值时不参与 slow_windows 排名
+	heatmapSlowWindowLimit = 5
+)
+
+// LatencyHeatmapCell 星期×小时单元格
+type LatencyHeatmapCell struct {
+	Weekday          int      `json:"weekday"` // 1=周一 … 7=周日
+	Hour             int      `json:"hour"`    // 0-23（服务器本地时区）
+	Requests         int64    `json:"requests"`
+	Errors           int64    `json:"errors"`
+	ErrorRate        float64  `json:"error_rate"`
+	Samples          int64    `json:"samples"`                       // 计入平均耗时的成功请求数
+	AvgDuration      *float64 `json:"avg_duration,omitempty"`        // 成功请求平均耗时（秒）
+	MaxDuration      float64  `json:"max_duration"`                  // 成功请求最大耗时（秒）
+	AvgFirstByteTime *float64 `json:"avg_first_byte_time,omitempty"` // 成功流式请求平均首字节时间（秒）
+
+	durationSum float64
+	ttfbSum     float64
+	ttfbCount   int64
+}
+
+// LatencyHeatmap 延迟热力图
+type LatencyHeatmap struct {
+	Since       int64                `json:"since"` // Unix毫秒
+	Until       int64                `json:"until"`
+	Days        int                  `json:"days"`
+	Timezone    string               `json:"timezone"`
+	AvgDuration *float64             `json:"avg_duration,omitempty"` // 整体成功请求平均耗时（秒）
+	Cells       []LatencyHeatmapCell `json:"cells"`                  // 168个，按 周一0时 … 周日23时 排列
+	SlowWindows []LatencyHeatmapCell `json:"slow_windows"`
+}
+
+// buildLatencyHeatmap 将UTC小时聚合折叠为本地 星期×小时 网格
+func buildLatencyHeatmap(rows []model.HourlyLatency, loc *time.Location) ([]LatencyHeatmapCell, *float64) {
+	cells := make([]LatencyHeatmapCell, 7*24)
+	for i := range cells {
+		cells[i].Weekday = i/24 + 1
+		cells[i].Hour = i % 24
+	}
+
+	var totalSum float64
+	var totalCount int64
+	for _, r := range rows {
+		t := time.Unix(r.Hour*3600, 0).In(loc)
+		wd := int(t.Weekday()+6) % 7 // 周一=0
+		c := &cells[wd*24+t.Hour()]
+		c.Requests += r.Requests
+		c.Errors += r.Errors
+		c.Samples += r.DurationCount
+		c.durationSum += r.DurationSum
+		c.MaxDuration = max(c.MaxDuration, r.MaxDuration)
+		c.ttfbSum += r.TTFBSum
+		c.ttfbCount += r.TTFBCount
+		totalSum += r.DurationSum
+		totalCount += r.DurationCount
+	}
+
+	for i := range cells {
+		c := &cells[i]
+		if c.Requests > 0 {
+			c.ErrorRate = float64(c.Errors) / float64(c.Requests)
+		}
+		if c.Samples > 0 {
+			avg := c.durationSum / float64(c.Samples)
+			c.AvgDuration = &avg
+		}
+		if c.ttfbCount > 0 {
+			avg := c.ttfbSum / float64(c.ttfbCount)
+			c.AvgFirstByteTime = &avg
+		}
+	}
+
+	if totalCount == 0 {
+		return cells, nil
+	}
+	overall := totalSum / float64(totalCount)
+	return cells, &overall
+}
+
+// slowLatencyWindows 平均耗时高于整体且样本足够的单元格，按平均耗时降序
+func slowLatencyWindows(cells []LatencyHeatmapCell, overall *float64) []LatencyHeatmapCell {
+	out := []LatencyHeatmapCell{}
+	if overall == nil {
+		return out
+	}
+	for _, c := range cells {
+		if c.Samples >= heatmapMinSamples && c.AvgDuration != nil && *c.AvgDuration > *overall {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return *out[i].AvgDuration > *out[j].AvgDuration })
+	if len(out) > heatmapSlowWindowLimit {
+		out = out[:heatmapSlowWindowLimit]
+	}
+	return out
+}
+
+// HandleMetricsHeatmap 历史延迟热力图
+// GET /admin/metrics/heatmap?days=28&channel_id=3&model=xxx
+func (s *Server) HandleMetricsHeatmap(c *gin.Context) {
+	days := heatmapDefaultDays
+	if v := c.Query("days"); v != "" {
+		var err error
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 || days > heatmapMaxDays {
+			RespondErrorMsg(c, http.StatusBadRequest, "days must be within 1-90")
+			return
+		}
+	}
+
+	lf := BuildLogFilter(c)
+	until := time.Now()
+	since := until.AddDate(0, 0, -days)
+	rows, err := s.store.GetHourlyLatency(c.Request.Context(), since, until, &lf)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	cells, overall := buildLatencyHeatmap(rows, time.Local)
+	RespondJSON(c, http.StatusOK, LatencyHeatmap{
+		Since:       since.UnixMilli(),
+		Until:       until.UnixMilli(),
+		Days:        days,
+		Timezone:    until.Location().String(),
+		AvgDuration: overall,
+		Cells:       cells,
+		SlowWindows: slowLatencyWindows(cells, overall),
+	})
+}
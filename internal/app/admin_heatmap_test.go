@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildLatencyHeatmap(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	// 2026-10-12 是周一；UTC 周日16时 = 本地周一0时
+	mon0 := time.Date(2026, 10, 12, 0, 0, 0, 0, loc).Unix() / 3600
+	wed9 := time.Date(2026, 10, 14, 9, 0, 0, 0, loc).Unix() / 3600
+	rows := []model.HourlyLatency{
+		{Hour: mon0, Requests: 10, Errors: 0, DurationSum: 10, DurationCount: 10, MaxDuration: 2},
+		{Hour: wed9, Requests: 6, Errors: 1, DurationSum: 25, DurationCount: 5, MaxDuration: 9, TTFBSum: 4, TTFBCount: 2},
+		{Hour: wed9 + 7*24, Requests: 4, Errors: 2, DurationSum: 10, DurationCount: 2, MaxDuration: 6}, // 下周同一时段合并
+	}
+
+	cells, overall := buildLatencyHeatmap(rows, loc)
+	if len(cells) != 168 {
+		t.Fatalf("cells = %d, want 168", len(cells))
+	}
+	if c := cells[0]; c.Weekday != 1 || c.Hour != 0 || c.Requests != 10 || c.AvgDuration == nil || *c.AvgDuration != 1 {
+		t.Fatalf("周一0时单元格不符: %+v", c)
+	}
+	c := cells[2*24+9]
+	if c.Weekday != 3 || c.Hour != 9 || c.Requests != 10 || c.Errors != 3 || c.ErrorRate != 0.3 || c.Samples != 7 {
+		t.Fatalf("周三9时单元格不符: %+v", c)
+	}
+	if *c.AvgDuration != 5 || c.MaxDuration != 9 || c.AvgFirstByteTime == nil || *c.AvgFirstByteTime != 2 {
+		t.Fatalf("周三9时延迟不符: %+v", c)
+	}
+	if overall == nil || *overall != 45.0/17 {
+		t.Fatalf("overall = %v", overall)
+	}
+	if cells[100].AvgDuration != nil || cells[100].Requests != 0 {
+		t.Fatalf("空单元格应无平均值: %+v", cells[100])
+	}
+
+	slow := slowLatencyWindows(cells, overall)
+	if len(slow) != 1 || slow[0].Weekday != 3 || slow[0].Hour != 9 {
+		t.Fatalf("slow windows = %+v", slow)
+	}
+
+	if _, overall := buildLatencyHeatmap(nil, loc); overall != nil || len(slowLatencyWindows(cells, overall)) != 0 {
+		t.Fatal("无数据时不应有整体平均与慢时段")
+	}
+}
+
+func TestHandleMetricsHeatmap(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	at := time.Now().Add(-2 * time.Hour)
+	entry := func(channelID int64, status int, duration float64) *model.LogEntry {
+		return &model.LogEntry{Time: model.JSONTime{Time: at}, Model: "m", ChannelID: channelID, StatusCode: status, Duration: duration}
+	}
+	logs := []*model.LogEntry{
+		entry(1, 200, 2), entry(1, 200, 4), entry(1, 200, 6), entry(1, 502, 30),
+		entry(1, 499, 50), // 客户端取消不计入
+		entry(2, 200, 100),
+	}
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("写入日志失败: %v", err)
+	}
+
+	call := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/metrics/heatmap"+query, nil)
+		server.HandleMetricsHeatmap(c)
+		return w
+	}
+
+	if w := call("?days=0"); w.Code != http.StatusBadRequest {
+		t.Fatalf("days越界应返回400, got %d", w.Code)
+	}
+
+	w := call("?days=7&channel_id=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data LatencyHeatmap `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	h := resp.Data
+	if h.Days != 7 || len(h.Cells) != 168 {
+		t.Fatalf("unexpected heatmap: days=%d cells=%d", h.Days, len(h.Cells))
+	}
+	local := at.In(time.Local)
+	c := h.Cells[(int(local.Weekday())+6)%7*24+local.Hour()]
+	if c.Requests != 4 || c.Errors != 1 || c.Samples != 3 || c.AvgDuration == nil || *c.AvgDuration != 4 || c.MaxDuration != 6 {
+		t.Fatalf("单元格聚合不符: %+v", c)
+	}
+	var total int64
+	for _, cell := range h.Cells {
+		total += cell.Requests
+	}
+	if total != 4 {
+		t.Fatalf("按渠道筛选后总请求数 = %d, want 4", total)
+	}
+}
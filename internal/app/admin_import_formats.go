@@ -0,0 +1,372 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// ==================== 其他代理工具配置导入（2026-10新增） ====================
+// 方便从同类工具迁移：POST /admin/channels/import?format=...
+//   - oneapi / newapi: one-api / new-api 渠道导出（渠道数组，或管理接口返回的 {"data":[...]}）
+//   - litellm: litellm config.yaml 的 model_list
+// 上传方式与CSV一致（multipart 字段 file），也接受直接放在请求体中的原始内容。
+
+// maxForeignImportSize 导入文件大小上限
+const maxForeignImportSize = 10 << 20
+
+// one-api 渠道类型编号（仅映射 ccLoad 支持的协议，其余 OpenAI 兼容类型统一按 openai 处理）
+const (
+	oneAPITypeOpenAI    = 1
+	oneAPITypeAzure     = 3
+	oneAPITypeAnthropic = 14
+	oneAPITypeGemini    = 24
+)
+
+// 各协议官方默认地址（源配置未填写 base_url 时使用）
+var importDefaultBaseURLs = map[string]string{
+	util.ChannelTypeAnthropic: "https://api.anthropic.com",
+	util.ChannelTypeOpenAI:    "https://api.openai.com",
+	util.ChannelTypeGemini:    "https://generativelanguage.googleapis.com",
+}
+
+// handleImportChannelsForeign 解析非CSV格式的渠道配置并批量导入
+func (s *Server) handleImportChannelsForeign(c *gin.Context, format string) {
+	var parse func([]byte, *ChannelImportSummary) ([]*model.ChannelWithKeys, error)
+	switch format {
+	case "oneapi", "one-api", "newapi", "new-api":
+		parse = parseOneAPIChannels
+	case "litellm":
+		parse = parseLiteLLMConfig
+	default:
+		RespondErrorMsg(c, http.StatusBadRequest, "不支持的导入格式: "+format+"（支持 csv/oneapi/newapi/litellm）")
+		return
+	}
+
+	data, err := readImportPayload(c)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary := ChannelImportSummary{}
+	channels, err := parse(data, &summary)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.commitChannelImport(c, channels, summary)
+}
+
+// readImportPayload 读取上传文件（优先）或原始请求体
+func readImportPayload(c *gin.Context) ([]byte, error) {
+	var src io.Reader = c.Request.Body
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		f, err := fileHeader.Open()
+		if err != nil {
+			return nil, fmt.Errorf("读取上传文件失败: %v", err)
+		}
+		defer func() { _ = f.Close() }()
+		src = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(src, maxForeignImportSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取导入内容失败: %v", err)
+	}
+	if len(data) > maxForeignImportSize {
+		return nil, fmt.Errorf("导入内容过大（上限 %d MB）", maxForeignImportSize>>20)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, fmt.Errorf("导入内容为空")
+	}
+	return data, nil
+}
+
+// ==================== one-api / new-api ====================
+
+// oneAPIChannel one-api/new-api 渠道导出结构（仅解析迁移所需字段）
+type oneAPIChannel struct {
+	Type         int     `json:"type"`
+	Key          string  `json:"key"`
+	Name         string  `json:"name"`
+	BaseURL      *string `json:"base_url"`
+	Models       string  `json:"models"`
+	ModelMapping *string `json:"model_mapping"`
+	Priority     *int64  `json:"priority"`
+	Status       int     `json:"status"` // 1=启用，2=手动禁用，3=自动禁用
+}
+
+// parseOneAPIChannels 解析 one-api/new-api 渠道导出
+func parseOneAPIChannels(data []byte, summary *ChannelImportSummary) ([]*model.ChannelWithKeys, error) {
+	var list []oneAPIChannel
+	if err := sonic.Unmarshal(data, &list); err != nil {
+		// 兼容管理接口响应格式：{"success":true,"data":[...]}（new-api 分页时 data.items）
+		var wrapped struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err2 := sonic.Unmarshal(data, &wrapped); err2 != nil || len(wrapped.Data) == 0 {
+			return nil, fmt.Errorf("无法解析one-api渠道JSON: %v", err)
+		}
+		if err := sonic.Unmarshal(wrapped.Data, &list); err != nil {
+			var paged struct {
+				Items []oneAPIChannel `json:"items"`
+			}
+			if err := sonic.Unmarshal(wrapped.Data, &paged); err != nil {
+				return nil, fmt.Errorf("无法解析one-api渠道JSON: %v", err)
+			}
+			list = paged.Items
+		}
+	}
+
+	channels := make([]*model.ChannelWithKeys, 0, len(list))
+	for i, ch := range list {
+		label := fmt.Sprintf("第%d个渠道(%s)", i+1, ch.Name)
+		skip := func(reason string) {
+			summary.Errors = append(summary.Errors, label+": "+reason)
+			summary.Skipped++
+		}
+
+		channelType := util.ChannelTypeOpenAI
+		switch ch.Type {
+		case oneAPITypeAnthropic:
+			channelType = util.ChannelTypeAnthropic
+		case oneAPITypeGemini:
+			channelType = util.ChannelTypeGemini
+		case oneAPITypeAzure:
+			skip("Azure渠道暂不支持导入")
+			continue
+		}
+
+		baseURL := ""
+		if ch.BaseURL != nil {
+			baseURL = strings.TrimSpace(*ch.BaseURL)
+		}
+		if baseURL == "" {
+			if ch.Type != oneAPITypeOpenAI && ch.Type != oneAPITypeAnthropic && ch.Type != oneAPITypeGemini {
+				skip(fmt.Sprintf("渠道类型 %d 未填写 base_url", ch.Type))
+				continue
+			}
+			baseURL = importDefaultBaseURLs[channelType]
+		}
+
+		var mapping map[string]string
+		if ch.ModelMapping != nil && strings.TrimSpace(*ch.ModelMapping) != "" {
+			if err := sonic.UnmarshalString(*ch.ModelMapping, &mapping); err != nil {
+				skip("model_mapping 格式错误: " + err.Error())
+				continue
+			}
+		}
+
+		priority := 0
+		if ch.Priority != nil {
+			priority = int(*ch.Priority)
+		}
+
+		cwk, err := buildImportedChannel(ch.Name, baseURL, channelType, priority, ch.Status == 1,
+			splitImportList(ch.Models), mapping, splitImportKeys(ch.Key))
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+		channels = append(channels, cwk)
+	}
+	return channels, nil
+}
+
+// ==================== litellm ====================
+
+// liteLLMConfig litellm config.yaml（仅解析 model_list）
+type liteLLMConfig struct {
+	ModelList []struct {
+		ModelName     string `yaml:"model_name"`
+		LiteLLMParams struct {
+			Model   string `yaml:"model"`
+			APIBase string `yaml:"api_base"`
+			APIKey  string `yaml:"api_key"`
+		} `yaml:"litellm_params"`
+	} `yaml:"model_list"`
+}
+
+// liteLLMProviderTypes litellm 模型前缀 → ccLoad 渠道类型
+var liteLLMProviderTypes = map[string]string{
+	"anthropic": util.ChannelTypeAnthropic,
+	"openai":    util.ChannelTypeOpenAI,
+	"gemini":    util.ChannelTypeGemini,
+}
+
+// parseLiteLLMConfig 解析 litellm config.yaml
+// 同一 (协议, api_base) 的部署合并为一个渠道：model_name 作为对外模型名，litellm_params.model 去掉前缀后作为重定向目标
+func parseLiteLLMConfig(data []byte, summary *ChannelImportSummary) ([]*model.ChannelWithKeys, error) {
+	var cfg liteLLMConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("无法解析litellm配置YAML: %v", err)
+	}
+	if len(cfg.ModelList) == 0 {
+		return nil, fmt.Errorf("litellm配置中没有 model_list")
+	}
+
+	type group struct {
+		channelType string
+		baseURL     string
+		models      []string
+		redirects   map[string]string
+		keys        []string
+	}
+	groups := make(map[string]*group)
+	order := make([]string, 0)
+
+	for i, entry := range cfg.ModelList {
+		label := fmt.Sprintf("第%d个模型(%s)", i+1, entry.ModelName)
+		skip := func(reason string) {
+			summary.Errors = append(summary.Errors, label+": "+reason)
+			summary.Skipped++
+		}
+
+		params := entry.LiteLLMParams
+		provider, upstreamModel, ok := strings.Cut(params.Model, "/")
+		if !ok {
+			// 无前缀时 litellm 默认按 OpenAI 处理
+			provider, upstreamModel = "openai", params.Model
+		}
+		channelType, supported := liteLLMProviderTypes[provider]
+		if !supported {
+			skip("不支持的provider: " + provider)
+			continue
+		}
+		// 不解析 os.environ/XXX：避免把服务端环境变量（含管理员密码等）写入渠道Key
+		apiKey := strings.TrimSpace(params.APIKey)
+		if apiKey == "" || strings.HasPrefix(apiKey, "os.environ/") {
+			skip("api_key 必须为明文（不支持 os.environ/ 引用）")
+			continue
+		}
+		modelName := strings.TrimSpace(entry.ModelName)
+		if modelName == "" || upstreamModel == "" {
+			skip("model_name 或 litellm_params.model 为空")
+			continue
+		}
+
+		baseURL := strings.TrimSpace(params.APIBase)
+		if baseURL == "" {
+			baseURL = importDefaultBaseURLs[channelType]
+		}
+		groupKey := channelType + "|" + strings.TrimRight(baseURL, "/")
+		g, exists := groups[groupKey]
+		if !exists {
+			g = &group{channelType: channelType, baseURL: baseURL, redirects: make(map[string]string)}
+			groups[groupKey] = g
+			order = append(order, groupKey)
+		}
+		if _, seen := g.redirects[modelName]; !seen {
+			g.models = append(g.models, modelName)
+			g.redirects[modelName] = upstreamModel
+		}
+		if !slices.Contains(g.keys, apiKey) {
+			g.keys = append(g.keys, apiKey)
+		}
+	}
+
+	channels := make([]*model.ChannelWithKeys, 0, len(order))
+	for _, groupKey := range order {
+		g := groups[groupKey]
+		name := "litellm-" + g.channelType
+		if u, err := neturl.Parse(g.baseURL); err == nil && u.Host != "" {
+			name += "-" + u.Host
+		}
+		cwk, err := buildImportedChannel(name, g.baseURL, g.channelType, 0, true, g.models, g.redirects, g.keys)
+		if err != nil {
+			summary.Errors = append(summary.Errors, name+": "+err.Error())
+			summary.Skipped++
+			continue
+		}
+		channels = append(channels, cwk)
+	}
+	return channels, nil
+}
+
+// ==================== 公共辅助函数 ====================
+
+// buildImportedChannel 校验并构建待导入渠道
+// redirects: 对外模型名 → 上游模型名（与对外名相同时视为透传）
+func buildImportedChannel(name, baseURL, channelType string, priority int, enabled bool,
+	models []string, redirects map[string]string, keys []string) (*model.ChannelWithKeys, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("渠道名称为空")
+	}
+	normalizedURL, err := validateChannelBaseURL(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("URL无效: %v", err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("缺少API Key")
+	}
+
+	// one-api 的 model_mapping 键可能不在 models 中，同样视为可用模型
+	extra := make([]string, 0)
+	for from := range redirects {
+		if !slices.Contains(models, from) {
+			extra = append(extra, from)
+		}
+	}
+	sort.Strings(extra)
+	models = append(models, extra...)
+	if len(models) == 0 {
+		return nil, fmt.Errorf("缺少模型列表")
+	}
+
+	entries := make([]model.ModelEntry, 0, len(models))
+	for _, m := range models {
+		entry := model.ModelEntry{Model: m}
+		if to := redirects[m]; to != "" && to != m {
+			entry.RedirectModel = to
+		}
+		entries = append(entries, entry)
+	}
+
+	apiKeys := make([]model.APIKey, len(keys))
+	for i, k := range keys {
+		apiKeys[i] = model.APIKey{KeyIndex: i, APIKey: k, KeyStrategy: model.KeyStrategySequential}
+	}
+
+	return &model.ChannelWithKeys{
+		Config: &model.Config{
+			Name:         name,
+			URL:          normalizedURL,
+			Priority:     priority,
+			ModelEntries: entries,
+			ChannelType:  channelType,
+			Enabled:      enabled,
+		},
+		APIKeys: apiKeys,
+	}, nil
+}
+
+// splitImportList 拆分逗号分隔列表（去空、去重、保序）
+func splitImportList(raw string) []string {
+	out := make([]string, 0)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !slices.Contains(out, item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// splitImportKeys 拆分Key列表（one-api 多Key以换行分隔，同时兼容逗号）
+func splitImportKeys(raw string) []string {
+	return splitImportList(strings.NewReplacer("\r\n", ",", "\n", ",").Replace(raw))
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseOneAPIChannels(t *testing.T) {
+	data := []byte(`{"success":true,"data":[
+		{"type":14,"name":"claude-main","key":"sk-a\nsk-b","base_url":"","models":"claude-sonnet-4-5,claude-haiku-4-5",
+		 "model_mapping":"{\"claude-haiku-4-5\":\"claude-3-5-haiku-20241022\"}","priority":5,"status":1},
+		{"type":8,"name":"custom-no-base","key":"sk-c","models":"gpt-4o","status":1},
+		{"type":3,"name":"azure","key":"sk-d","base_url":"https://x.openai.azure.com","models":"gpt-4o","status":1},
+		{"type":1,"name":"openai-disabled","key":"sk-e","base_url":"https://relay.example.com/","models":"gpt-4o","status":2}
+	]}`)
+
+	var summary ChannelImportSummary
+	channels, err := parseOneAPIChannels(data, &summary)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(channels) != 2 || summary.Skipped != 2 {
+		t.Fatalf("expected 2 channels and 2 skipped, got %d / %+v", len(channels), summary)
+	}
+
+	claude := channels[0]
+	if claude.Config.ChannelType != "anthropic" || claude.Config.URL != "https://api.anthropic.com" || claude.Config.Priority != 5 {
+		t.Fatalf("unexpected anthropic channel: %+v", claude.Config)
+	}
+	if len(claude.APIKeys) != 2 || claude.APIKeys[1].APIKey != "sk-b" {
+		t.Fatalf("expected newline-separated keys, got %+v", claude.APIKeys)
+	}
+	if redirect, ok := claude.Config.GetRedirectModel("claude-haiku-4-5"); !ok || redirect != "claude-3-5-haiku-20241022" {
+		t.Fatalf("model_mapping not applied: %q %v", redirect, ok)
+	}
+
+	disabled := channels[1]
+	if disabled.Config.Enabled || disabled.Config.ChannelType != "openai" {
+		t.Fatalf("unexpected openai channel: %+v", disabled.Config)
+	}
+}
+
+func TestParseLiteLLMConfig(t *testing.T) {
+	data := []byte(`
+model_list:
+  - model_name: sonnet
+    litellm_params:
+      model: anthropic/claude-sonnet-4-5
+      api_key: sk-ant-1
+  - model_name: sonnet
+    litellm_params:
+      model: anthropic/claude-sonnet-4-5
+      api_key: sk-ant-2
+  - model_name: gpt-4o
+    litellm_params:
+      model: gpt-4o
+      api_base: https://relay.example.com
+      api_key: sk-oai
+  - model_name: env-key
+    litellm_params:
+      model: openai/gpt-4o-mini
+      api_key: os.environ/OPENAI_API_KEY
+  - model_name: bedrock
+    litellm_params:
+      model: bedrock/anthropic.claude-v2
+      api_key: x
+`)
+
+	var summary ChannelImportSummary
+	channels, err := parseLiteLLMConfig(data, &summary)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(channels) != 2 || summary.Skipped != 2 {
+		t.Fatalf("expected 2 channels and 2 skipped, got %d / %+v", len(channels), summary)
+	}
+
+	anthropic := channels[0]
+	if anthropic.Config.Name != "litellm-anthropic-api.anthropic.com" || len(anthropic.APIKeys) != 2 {
+		t.Fatalf("deployments should merge into one channel: %+v keys=%d", anthropic.Config, len(anthropic.APIKeys))
+	}
+	if redirect, ok := anthropic.Config.GetRedirectModel("sonnet"); !ok || redirect != "claude-sonnet-4-5" {
+		t.Fatalf("expected sonnet → claude-sonnet-4-5, got %q %v", redirect, ok)
+	}
+
+	openai := channels[1]
+	if openai.Config.URL != "https://relay.example.com" || openai.Config.ModelEntries[0].RedirectModel != "" {
+		t.Fatalf("unexpected openai channel: %+v", openai.Config)
+	}
+}
+
+func TestHandleImportChannels_ForeignFormat(t *testing.T) {
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	body := `[{"type":14,"name":"imported","key":"sk-x","models":"claude-sonnet-4-5","status":1}]`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/channels/import?format=oneapi", strings.NewReader(body))
+	srv.HandleImportChannelsCSV(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	configs, err := srv.store.ListConfigs(context.Background())
+	if err != nil || len(configs) != 1 || configs[0].Name != "imported" {
+		t.Fatalf("channel not imported: %v %+v", err, configs)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/channels/import?format=unknown", strings.NewReader(body))
+	srv.HandleImportChannelsCSV(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown format, got %d", w.Code)
+	}
+}
@@ -0,0 +1,318 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Key历史表现对比报表（2026-10新增）
+// ============================================================================
+// GET /admin/channels/:id/keys/report?range=7d 返回渠道内每个Key的逐日成功率、平均延迟、Token用量与趋势，
+// 用于续费时决定替换哪些Key。
+// 日志按脱敏Key归属到当前Key（删除Key会使后续下标前移，下标本身不稳定）；脱敏Key冲突时再用日志中的 key_index 区分。
+// 已不在渠道中的Key以 retired=true 单独列出。
+
+const (
+	keyReportDefaultDays = 7
+	keyReportMaxDays     = 90
+
+	keyTrendMinRequests     = 20   // 前后半段各自的最少请求数，不足时趋势为 insufficient_data
+	keyTrendSuccessDelta    = 0.05 // 成功率变化超过5个百分点视为显著
+	keyTrendLatencyRelDelta = 0.25 // 平均延迟相对变化超过25%视为显著
+)
+
+// 趋势方向
+const (
+	KeyTrendImproving        = "improving"
+	KeyTrendDegrading        = "degrading"
+	KeyTrendStable           = "stable"
+	KeyTrendInsufficientData = "insufficient_data"
+)
+
+// KeyReportDay 单个Key单日表现（无请求时 success_rate/avg_latency 为空）
+type KeyReportDay struct {
+	Date         string   `json:"date"` // YYYY-MM-DD（服务器本地时区）
+	Requests     int64    `json:"requests"`
+	SuccessRate  *float64 `json:"success_rate,omitempty"`
+	AvgLatency   *float64 `json:"avg_latency,omitempty"` // 成功请求平均耗时（秒）
+	TokensServed int64    `json:"tokens_served"`         // 输入+输出Token
+}
+
+// KeyReportTrend 前后半段对比
+type KeyReportTrend struct {
+	Direction        string   `json:"direction"`
+	SuccessRateDelta *float64 `json:"success_rate_delta,omitempty"` // 后半段 - 前半段
+	LatencyChange    *float64 `json:"latency_change,omitempty"`     // 后半段 / 前半段 - 1
+}
+
+// KeyReport 单个Key的报表
+type KeyReport struct {
+	KeyIndex     int            `json:"key_index"` // 当前下标；retired 时为-1
+	APIKey       string         `json:"api_key"`   // 脱敏Key
+	Retired      bool           `json:"retired,omitempty"`
+	Requests     int64          `json:"requests"`
+	SuccessCount int64          `json:"success_count"`
+	FailureCount int64          `json:"failure_count"`
+	SuccessRate  *float64       `json:"success_rate,omitempty"`
+	AvgLatency   *float64       `json:"avg_latency,omitempty"`
+	TokensServed int64          `json:"tokens_served"`
+	CacheTokens  int64          `json:"cache_tokens"` // 缓存读+写Token
+	TotalCost    float64        `json:"total_cost"`
+	Trend        KeyReportTrend `json:"trend"`
+	Daily        []KeyReportDay `json:"daily"`
+
+	dayIndex map[string]*keyReportAcc // 日期 → 当日累加
+}
+
+// KeyReportResponse 报表响应
+type KeyReportResponse struct {
+	ChannelID            int64       `json:"channel_id"`
+	ChannelName          string      `json:"channel_name"`
+	RangeDays            int         `json:"range_days"`
+	Since                int64       `json:"since"` // Unix秒（含）
+	Until                int64       `json:"until"` // Unix秒（不含）
+	Keys                 []KeyReport `json:"keys"`
+	UnattributedRequests int64       `json:"unattributed_requests,omitempty"` // 无法归属到具体Key的请求数
+}
+
+// keyReportAcc 聚合累加器
+type keyReportAcc struct {
+	success, failure    int64
+	durationSum         float64
+	tokens, cacheTokens int64
+	cost                float64
+}
+
+func (a *keyReportAcc) add(st *model.KeyDailyStats) {
+	a.success += st.SuccessCount
+	a.failure += st.FailureCount
+	a.durationSum += st.DurationSum
+	a.tokens += st.InputTokens + st.OutputTokens
+	a.cacheTokens += st.CacheReadTokens + st.CacheCreationTokens
+	a.cost += st.TotalCost
+}
+
+func (a *keyReportAcc) merge(o *keyReportAcc) {
+	a.success += o.success
+	a.failure += o.failure
+	a.durationSum += o.durationSum
+	a.tokens += o.tokens
+	a.cacheTokens += o.cacheTokens
+	a.cost += o.cost
+}
+
+func (a *keyReportAcc) requests() int64 { return a.success + a.failure }
+
+func (a *keyReportAcc) successRate() *float64 {
+	if a.requests() == 0 {
+		return nil
+	}
+	v := float64(a.success) / float64(a.requests())
+	return &v
+}
+
+func (a *keyReportAcc) avgLatency() *float64 {
+	if a.success == 0 {
+		return nil
+	}
+	v := a.durationSum / float64(a.success)
+	return &v
+}
+
+// parseKeyReportRange 解析 range 参数：Nd（1-90天），默认7d
+func parseKeyReportRange(raw string) (int, error) {
+	if raw == "" {
+		return keyReportDefaultDays, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(raw)), "d"))
+	if err != nil || days < 1 || days > keyReportMaxDays {
+		return 0, fmt.Errorf("invalid range %q (expected 1d-%dd)", raw, keyReportMaxDays)
+	}
+	return days, nil
+}
+
+// HandleChannelKeyReport Key历史表现对比报表
+// GET /admin/channels/:id/keys/report?range=7d
+func (s *Server) HandleChannelKeyReport(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	days, err := parseKeyReportRange(c.Query("range"))
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	cfg, err := s.store.GetConfig(ctx, id)
+	if err != nil {
+		RespondError(c, http.StatusNotFound, fmt.Errorf("channel not found"))
+		return
+	}
+	keys, err := s.store.GetAPIKeys(ctx, id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 按本地自然日切分：[今天0点-(days-1)天, 明天0点)
+	now := time.Now()
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := todayStart.AddDate(0, 0, -(days - 1))
+	until := todayStart.AddDate(0, 0, 1)
+	_, offsetSec := now.Zone()
+
+	rows, err := s.store.GetKeyDailyStats(ctx, id, since, until, time.Duration(offsetSec)*time.Second)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	report := buildKeyReport(keys, rows, since, days)
+	report.ChannelID = cfg.ID
+	report.ChannelName = cfg.Name
+	report.Until = until.Unix()
+	RespondJSON(c, http.StatusOK, report)
+}
+
+// buildKeyReport 将 Key×日 聚合行归属到当前Key并计算逐日指标与趋势
+func buildKeyReport(keys []*model.APIKey, rows []model.KeyDailyStats, since time.Time, days int) *KeyReportResponse {
+	resp := &KeyReportResponse{RangeDays: days, Since: since.Unix(), Keys: []KeyReport{}}
+
+	// 脱敏Key → 当前下标（脱敏结果冲突的Key只能依赖日志中的 key_index）
+	masks := make(map[int]string, len(keys))
+	maskOwners := make(map[string][]int, len(keys))
+	for _, k := range keys {
+		m := util.MaskAPIKey(k.APIKey)
+		masks[k.KeyIndex] = m
+		maskOwners[m] = append(maskOwners[m], k.KeyIndex)
+	}
+
+	current := make(map[int]*KeyReport, len(keys))
+	for _, k := range keys {
+		current[k.KeyIndex] = &KeyReport{KeyIndex: k.KeyIndex, APIKey: masks[k.KeyIndex], dayIndex: map[string]*keyReportAcc{}}
+	}
+	retired := map[string]*KeyReport{}
+
+	for i := range rows {
+		row := &rows[i]
+		mask := util.MaskAPIKey(row.APIKeyUsed)
+		var target *KeyReport
+		switch owners := maskOwners[mask]; {
+		case len(owners) == 1:
+			target = current[owners[0]]
+		case len(owners) > 1:
+			if masks[row.KeyIndex] == mask {
+				target = current[row.KeyIndex]
+			}
+		default:
+			target = retired[mask]
+			if target == nil {
+				target = &KeyReport{KeyIndex: -1, APIKey: mask, Retired: true, dayIndex: map[string]*keyReportAcc{}}
+				retired[mask] = target
+			}
+		}
+		if target == nil {
+			resp.UnattributedRequests += row.SuccessCount + row.FailureCount
+			continue
+		}
+		// Day 已按时区偏移，直接按UTC格式化即为本地日期
+		date := time.Unix(row.Day*86400, 0).UTC().Format(time.DateOnly)
+		acc := target.dayIndex[date]
+		if acc == nil {
+			acc = &keyReportAcc{}
+			target.dayIndex[date] = acc
+		}
+		acc.add(row)
+	}
+
+	finish := func(r *KeyReport) KeyReport {
+		var total, firstHalf, secondHalf keyReportAcc
+		r.Daily = make([]KeyReportDay, 0, days)
+		for d := 0; d < days; d++ {
+			point := KeyReportDay{Date: since.AddDate(0, 0, d).Format(time.DateOnly)}
+			if acc := r.dayIndex[point.Date]; acc != nil {
+				point.Requests = acc.requests()
+				point.SuccessRate = acc.successRate()
+				point.AvgLatency = acc.avgLatency()
+				point.TokensServed = acc.tokens
+				total.merge(acc)
+				// 奇数天时中间一天不参与前后对比
+				if d < days/2 {
+					firstHalf.merge(acc)
+				} else if d >= (days+1)/2 {
+					secondHalf.merge(acc)
+				}
+			}
+			r.Daily = append(r.Daily, point)
+		}
+		r.Requests = total.requests()
+		r.SuccessCount = total.success
+		r.FailureCount = total.failure
+		r.SuccessRate = total.successRate()
+		r.AvgLatency = total.avgLatency()
+		r.TokensServed = total.tokens
+		r.CacheTokens = total.cacheTokens
+		r.TotalCost = total.cost
+		r.Trend = keyReportTrend(&firstHalf, &secondHalf)
+		return *r
+	}
+
+	indexes := make([]int, 0, len(current))
+	for idx := range current {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	for _, idx := range indexes {
+		resp.Keys = append(resp.Keys, finish(current[idx]))
+	}
+	retiredMasks := make([]string, 0, len(retired))
+	for m := range retired {
+		retiredMasks = append(retiredMasks, m)
+	}
+	sort.Strings(retiredMasks)
+	for _, m := range retiredMasks {
+		resp.Keys = append(resp.Keys, finish(retired[m]))
+	}
+	return resp
+}
+
+// keyReportTrend 比较前后半段：成功率变化优先，其次平均延迟变化
+func keyReportTrend(first, second *keyReportAcc) KeyReportTrend {
+	if first.requests() < keyTrendMinRequests || second.requests() < keyTrendMinRequests {
+		return KeyReportTrend{Direction: KeyTrendInsufficientData}
+	}
+	trend := KeyReportTrend{Direction: KeyTrendStable}
+	delta := *second.successRate() - *first.successRate()
+	trend.SuccessRateDelta = &delta
+
+	l1, l2 := first.avgLatency(), second.avgLatency()
+	if l1 != nil && l2 != nil && *l1 > 0 {
+		change := *l2 / *l1 - 1
+		trend.LatencyChange = &change
+	}
+
+	switch {
+	case delta <= -keyTrendSuccessDelta:
+		trend.Direction = KeyTrendDegrading
+	case delta >= keyTrendSuccessDelta:
+		trend.Direction = KeyTrendImproving
+	case trend.LatencyChange != nil && *trend.LatencyChange >= keyTrendLatencyRelDelta:
+		trend.Direction = KeyTrendDegrading
+	case trend.LatencyChange != nil && *trend.LatencyChange <= -keyTrendLatencyRelDelta:
+		trend.Direction = KeyTrendImproving
+	}
+	return trend
+}
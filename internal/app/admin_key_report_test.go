@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleChannelKeyReport(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:         "report",
+		URL:          "https://api.example.com",
+		Priority:     1,
+		ModelEntries: []model.ModelEntry{{Model: "m"}},
+		Enabled:      true,
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, KeyIndex: 0, APIKey: "sk-aaaa-0000-key-zero", KeyStrategy: model.KeyStrategySequential},
+		{ChannelID: cfg.ID, KeyIndex: 1, APIKey: "sk-bbbb-1111-key-once", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, now.Location())
+	var logs []*model.LogEntry
+	add := func(day int, key string, keyIndex, status, n int, duration float64) {
+		for i := 0; i < n; i++ {
+			logs = append(logs, &model.LogEntry{
+				Time:         model.JSONTime{Time: today.AddDate(0, 0, -day)},
+				Model:        "m",
+				ChannelID:    cfg.ID,
+				StatusCode:   status,
+				Message:      "x",
+				Duration:     duration,
+				APIKeyUsed:   key,
+				KeyIndex:     keyIndex,
+				InputTokens:  10,
+				OutputTokens: 5,
+			})
+		}
+	}
+	// Key#0：前半段全部成功，后半段大量失败 → degrading
+	add(5, "sk-aaaa-0000-key-zero", 0, 200, 30, 1.0)
+	add(1, "sk-aaaa-0000-key-zero", 0, 200, 15, 1.0)
+	add(1, "sk-aaaa-0000-key-zero", 0, 500, 15, 0)
+	// Key#1：日志写入时下标为2（之后前面的Key被删除导致前移），应按脱敏Key归属
+	add(2, "sk-bbbb-1111-key-once", 2, 200, 3, 2.0)
+	// 已删除的Key
+	add(3, "sk-gone-2222-key-gone", 1, 429, 4, 0)
+	// 客户端取消与范围外日志不计入
+	add(1, "sk-aaaa-0000-key-zero", 0, 499, 5, 0)
+	add(8, "sk-aaaa-0000-key-zero", 0, 200, 5, 1.0)
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("写入日志失败: %v", err)
+	}
+
+	call := func(query string) (*httptest.ResponseRecorder, KeyReportResponse) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(cfg.ID, 10)}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/channels/x/keys/report"+query, nil)
+		server.HandleChannelKeyReport(c)
+		var resp struct {
+			Data KeyReportResponse `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	if w, _ := call("?range=abc"); w.Code != http.StatusBadRequest {
+		t.Fatalf("非法range应返回400, got %d", w.Code)
+	}
+
+	w, report := call("?range=7d")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if report.RangeDays != 7 || len(report.Keys) != 3 {
+		t.Fatalf("报表结构不符: %+v", report)
+	}
+
+	k0, k1, gone := report.Keys[0], report.Keys[1], report.Keys[2]
+	if k0.KeyIndex != 0 || k0.Requests != 60 || k0.SuccessCount != 45 || len(k0.Daily) != 7 {
+		t.Fatalf("Key#0 汇总不符: %+v", k0)
+	}
+	if k0.TokensServed != 60*15 {
+		t.Fatalf("Key#0 Token不符: %d", k0.TokensServed)
+	}
+	if k0.Trend.Direction != KeyTrendDegrading {
+		t.Fatalf("Key#0 趋势应为degrading: %+v", k0.Trend)
+	}
+	if day := k0.Daily[5]; day.Requests != 30 || day.SuccessRate == nil || *day.SuccessRate != 0.5 || day.AvgLatency == nil || *day.AvgLatency != 1.0 {
+		t.Fatalf("Key#0 昨日数据不符: %+v", day)
+	}
+	if k1.KeyIndex != 1 || k1.Requests != 3 || k1.Trend.Direction != KeyTrendInsufficientData {
+		t.Fatalf("Key#1 应按脱敏Key归属: %+v", k1)
+	}
+	if !gone.Retired || gone.KeyIndex != -1 || gone.FailureCount != 4 {
+		t.Fatalf("已删除Key应单独列出: %+v", gone)
+	}
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 后台/管理出站通道（2026-10新增）
+// ============================================================================
+// 管理员触发的渠道测试与生产流量共用同一批上游Key，批量测试时容易触发上游限流，
+// 反过来影响真实客户端请求。所有管理端发起的上游调用统一经过低优先级通道：
+// - 独立信号量：与生产并发槽（concurrencySem）互不占用
+// - 节流：相邻两次调用的启动间隔不小于 interval
+// - 让路：生产并发占用超过阈值时暂缓启动，直到负载回落或请求取消
+
+// 默认值（可通过系统设置 admin_lane_concurrency / admin_lane_interval_ms 调整，重启生效）
+const (
+	defaultAdminLaneConcurrency = 2
+	defaultAdminLaneInterval    = 200 * time.Millisecond
+
+	// adminLaneBusyRatio 生产并发占用率达到该比例时，后台调用让路
+	adminLaneBusyRatio = 0.8
+)
+
+// backgroundLane 低优先级出站通道
+type backgroundLane struct {
+	sem      chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // 下一次允许启动的时间
+
+	// busy 返回生产流量是否繁忙（nil 表示不检测）
+	busy func() bool
+}
+
+func newBackgroundLane(concurrency int, interval time.Duration, busy func() bool) *backgroundLane {
+	if concurrency < 1 {
+		concurrency = defaultAdminLaneConcurrency
+	}
+	if interval < 0 {
+		interval = 0
+	}
+	return &backgroundLane{
+		sem:      make(chan struct{}, concurrency),
+		interval: interval,
+		busy:     busy,
+	}
+}
+
+// acquire 获取一个后台调用名额，成功时返回释放函数
+// 阻塞顺序：并发槽 → 生产让路 → 节流间隔；任一阶段 ctx 结束即返回错误
+func (l *backgroundLane) acquire(ctx context.Context) (func(), error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-l.sem }
+
+	if err := l.waitIdle(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if err := l.pace(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// waitIdle 生产流量繁忙时轮询等待
+func (l *backgroundLane) waitIdle(ctx context.Context) error {
+	if l.busy == nil {
+		return nil
+	}
+	poll := max(l.interval, 50*time.Millisecond)
+	for l.busy() {
+		select {
+		case <-time.After(poll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// pace 按 interval 预约启动时间并等待到点
+func (l *backgroundLane) pace(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	wait := time.Until(start)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// productionBusy 生产并发占用是否超过让路阈值
+func (s *Server) productionBusy() bool {
+	if s.maxConcurrency <= 0 {
+		return false
+	}
+	return float64(len(s.concurrencySem)) >= float64(s.maxConcurrency)*adminLaneBusyRatio
+}
+
+// acquireAdminLane 获取后台通道名额（未初始化时直接放行，便于测试构造最小Server）
+func (s *Server) acquireAdminLane(ctx context.Context) (func(), error) {
+	if s.adminLane == nil {
+		return func() {}, nil
+	}
+	return s.adminLane.acquire(ctx)
+}
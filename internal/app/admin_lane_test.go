@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackgroundLane_ConcurrencyAndPacing(t *testing.T) {
+	lane := newBackgroundLane(1, 30*time.Millisecond, nil)
+	ctx := context.Background()
+
+	start := time.Now()
+	release, err := lane.acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	// 并发槽已满：带超时的获取应失败
+	shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := lane.acquire(shortCtx); err == nil {
+		t.Fatal("expected acquire to block while the only slot is held")
+	}
+
+	release()
+	release2, err := lane.acquire(ctx)
+	if err != nil {
+		t.Fatalf("second acquire failed: %v", err)
+	}
+	release2()
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected pacing interval between starts, elapsed=%v", elapsed)
+	}
+}
+
+func TestBackgroundLane_YieldsToProduction(t *testing.T) {
+	var busy atomic.Bool
+	busy.Store(true)
+	lane := newBackgroundLane(2, 0, busy.Load)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := lane.acquire(ctx); err == nil {
+		t.Fatal("expected acquire to wait while production is busy")
+	}
+	// 失败时应归还并发槽
+	if n := len(lane.sem); n != 0 {
+		t.Fatalf("slot leaked after failed acquire: %d", n)
+	}
+
+	busy.Store(false)
+	release, err := lane.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire after load drops failed: %v", err)
+	}
+	release()
+}
+
+func TestServer_ProductionBusy(t *testing.T) {
+	s := &Server{concurrencySem: make(chan struct{}, 10), maxConcurrency: 10}
+	for range 7 {
+		s.concurrencySem <- struct{}{}
+	}
+	if s.productionBusy() {
+		t.Fatal("70% usage should not be busy")
+	}
+	s.concurrencySem <- struct{}{}
+	if !s.productionBusy() {
+		t.Fatal("80% usage should be busy")
+	}
+}
@@ -174,3 +174,83 @@ func determineSource(channelType string) string {
 		return "predefined" // 预定义列表
 	}
 }
+
+// HandlePreviewModelMatches 预览渠道模型配置（含通配符）实际匹配到的请求模型
+// 路由: GET /admin/channels/:id/models/preview?hours=24&models=a,b
+// 候选模型 = 最近 hours 小时日志中出现过的请求模型（默认24，最大720）+ models 参数额外指定的模型
+func (s *Server) HandlePreviewModelMatches(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "无效的渠道ID")
+		return
+	}
+
+	ctx := c.Request.Context()
+	cfg, err := s.store.GetConfig(ctx, channelID)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "渠道不存在")
+		return
+	}
+
+	hours := 24
+	if raw := c.Query("hours"); raw != "" {
+		if _, err := fmt.Sscanf(raw, "%d", &hours); err != nil || hours < 1 || hours > 720 {
+			RespondErrorMsg(c, http.StatusBadRequest, "hours 取值范围 1-720")
+			return
+		}
+	}
+
+	now := time.Now()
+	candidates, err := s.store.GetDistinctModels(ctx, now.Add(-time.Duration(hours)*time.Hour), now, "")
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	seen := make(map[string]struct{}, len(candidates))
+	for _, m := range candidates {
+		seen[m] = struct{}{}
+	}
+	for _, m := range strings.Split(c.Query("models"), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			if _, ok := seen[m]; !ok {
+				seen[m] = struct{}{}
+				candidates = append(candidates, m)
+			}
+		}
+	}
+
+	matches := make([]ModelPatternMatch, 0)
+	for _, requested := range candidates {
+		if model.IsModelPattern(requested) {
+			continue
+		}
+		entry, ok := cfg.MatchedModelEntry(requested)
+		if !ok {
+			continue
+		}
+		matches = append(matches, ModelPatternMatch{
+			Model:         requested,
+			MatchedBy:     entry.Model,
+			Wildcard:      model.IsModelPattern(entry.Model),
+			RedirectModel: entry.RedirectModel,
+		})
+	}
+
+	RespondJSON(c, http.StatusOK, gin.H{
+		"channel_id": channelID,
+		"hours":      hours,
+		"patterns":   channelModelPatterns(cfg),
+		"matches":    matches,
+	})
+}
+
+// channelModelPatterns 返回渠道配置的通配符模型
+func channelModelPatterns(cfg *model.Config) []string {
+	patterns := make([]string, 0)
+	for _, e := range cfg.ModelEntries {
+		if model.IsModelPattern(e.Model) {
+			patterns = append(patterns, e.Model)
+		}
+	}
+	return patterns
+}
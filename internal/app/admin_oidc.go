@@ -0,0 +1,637 @@
+package app
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/config"
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 管理界面 SSO 登录（OIDC，2026-10新增）
+// ============================================================================
+// 授权码流程 + PKCE（S256），适用于 Authentik / Keycloak / Google Workspace 等 OIDC 身份提供方：
+//   GET /login/oidc           → 302 跳转到身份提供方授权页（?redirect= 登录后返回的页面）
+//   GET /login/oidc/callback  → 换取并校验 ID Token，按组映射角色后签发管理员会话，
+//                               302 回登录页（会话Token放在 URL 片段中，不会发送到服务端日志）
+//   GET /login/oidc/status    → 登录页据此显示 SSO 按钮
+// 角色映射：CCLOAD_OIDC_ROLE_MAP="ccload-admins=admin,ccload-viewers=viewer"，
+// 同时命中多个组时取最高权限；均未命中时使用 CCLOAD_OIDC_DEFAULT_ROLE（为空则拒绝登录）。
+// state 同时以哈希形式写入短期 HttpOnly、SameSite=Lax Cookie，回调时校验请求携带的 Cookie，
+// 防止攻击者诱导受害者浏览器完成攻击者发起的登录流程（登录 CSRF）。
+// 密码登录（CCLOAD_PASS）保持可用，作为身份提供方故障时的后备入口。
+// 配置仅来自环境变量（包含客户端密钥，不进入数据库/设置页）。
+
+const (
+	oidcHTTPTimeout      = 10 * time.Second
+	oidcStateTTL         = 10 * time.Minute
+	oidcMaxPending       = 1000             // 未完成的登录流程上限（防止恶意刷授权跳转占用内存）
+	oidcJWKSRefreshEvery = time.Minute      // 未知 kid 时刷新 JWKS 的最小间隔
+	oidcClockSkew        = 60 * time.Second // exp/nbf 校验允许的时钟偏差
+	oidcLoginPage        = "/web/login.html"
+	oidcDefaultScopes    = "openid profile email"
+	oidcDefaultGroups    = "groups"
+	oidcStateCookie      = "ccload_oidc_state"
+	oidcStateCookiePath  = "/login/oidc"
+	oidcMaxResponseBytes = 1 << 20
+)
+
+// oidcConfig SSO 配置（环境变量）
+type oidcConfig struct {
+	issuer       string
+	clientID     string
+	clientSecret string            // 公共客户端（仅 PKCE）可为空
+	redirectURL  string            // 为空时按请求 Host 推导 {scheme}://{host}/login/oidc/callback
+	scopes       []string          // 默认 openid profile email
+	groupsClaim  string            // 组声明名称，支持点号路径（如 Keycloak 的 realm_access.roles）
+	roleMap      map[string]string // 组 → 角色
+	defaultRole  string            // 未命中任何组时的角色（空=拒绝）
+}
+
+// oidcConfigFromEnv 从环境变量加载 SSO 配置（未设置 CCLOAD_OIDC_ISSUER 时返回 nil）
+func oidcConfigFromEnv() (*oidcConfig, error) {
+	issuer := strings.TrimSpace(os.Getenv("CCLOAD_OIDC_ISSUER"))
+	if issuer == "" {
+		return nil, nil
+	}
+	cfg := &oidcConfig{
+		issuer:       strings.TrimRight(issuer, "/"),
+		clientID:     strings.TrimSpace(os.Getenv("CCLOAD_OIDC_CLIENT_ID")),
+		clientSecret: strings.TrimSpace(os.Getenv("CCLOAD_OIDC_CLIENT_SECRET")),
+		redirectURL:  strings.TrimSpace(os.Getenv("CCLOAD_OIDC_REDIRECT_URL")),
+		scopes:       strings.Fields(oidcEnvOr(os.Getenv("CCLOAD_OIDC_SCOPES"), oidcDefaultScopes)),
+		groupsClaim:  strings.TrimSpace(oidcEnvOr(os.Getenv("CCLOAD_OIDC_GROUPS_CLAIM"), oidcDefaultGroups)),
+		defaultRole:  strings.ToLower(strings.TrimSpace(os.Getenv("CCLOAD_OIDC_DEFAULT_ROLE"))),
+	}
+	if cfg.clientID == "" {
+		return nil, errors.New("CCLOAD_OIDC_CLIENT_ID is required")
+	}
+	if u, err := url.Parse(cfg.issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid CCLOAD_OIDC_ISSUER %q", issuer)
+	}
+	if !slices.Contains(cfg.scopes, "openid") {
+		cfg.scopes = append([]string{"openid"}, cfg.scopes...)
+	}
+	if cfg.defaultRole != "" && !model.IsValidAdminRole(cfg.defaultRole) {
+		return nil, fmt.Errorf("invalid CCLOAD_OIDC_DEFAULT_ROLE %q (admin/viewer)", cfg.defaultRole)
+	}
+	roleMap, err := parseOIDCRoleMap(os.Getenv("CCLOAD_OIDC_ROLE_MAP"))
+	if err != nil {
+		return nil, err
+	}
+	if len(roleMap) == 0 && cfg.defaultRole == "" {
+		return nil, errors.New("CCLOAD_OIDC_ROLE_MAP or CCLOAD_OIDC_DEFAULT_ROLE is required (otherwise nobody can log in)")
+	}
+	cfg.roleMap = roleMap
+	return cfg, nil
+}
+
+// oidcEnvOr 返回第一个非空白字符串
+func oidcEnvOr(v, fallback string) string {
+	if strings.TrimSpace(v) == "" {
+		return fallback
+	}
+	return v
+}
+
+// parseOIDCRoleMap 解析 "group=role,group2=role" 格式的组角色映射
+func parseOIDCRoleMap(raw string) (map[string]string, error) {
+	roleMap := make(map[string]string)
+	for item := range strings.SplitSeq(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(item, "=")
+		group, role = strings.TrimSpace(group), strings.ToLower(strings.TrimSpace(role))
+		if !ok || group == "" || !model.IsValidAdminRole(role) {
+			return nil, fmt.Errorf("invalid CCLOAD_OIDC_ROLE_MAP entry %q (expected group=admin|viewer)", item)
+		}
+		roleMap[group] = role
+	}
+	return roleMap, nil
+}
+
+// oidcDiscovery 身份提供方元数据（/.well-known/openid-configuration）
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// oidcPending 未完成的登录流程（state → nonce/PKCE verifier）
+type oidcPending struct {
+	nonce       string
+	verifier    string
+	redirectURL string // 授权与换取 Token 时必须一致
+	returnTo    string // 登录后返回的页面
+	expiresAt   time.Time
+}
+
+// oidcProvider SSO 登录流程（元数据与 JWKS 懒加载缓存）
+type oidcProvider struct {
+	cfg    oidcConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+	pending       map[string]oidcPending
+}
+
+func newOIDCProvider(cfg oidcConfig, client *http.Client) *oidcProvider {
+	if client == nil {
+		client = &http.Client{Timeout: oidcHTTPTimeout}
+	}
+	return &oidcProvider{cfg: cfg, client: client, pending: make(map[string]oidcPending)}
+}
+
+// loadOIDCProvider 按环境变量初始化 SSO（未配置返回 nil）
+// 配置了 CCLOAD_OIDC_ISSUER 但配置无效时 Fail-Fast 退出，避免静默丢弃运维配置的角色/组策略
+func loadOIDCProvider() *oidcProvider {
+	cfg, err := oidcConfigFromEnv()
+	if err != nil {
+		log.Fatalf("[FATAL] SSO 登录配置无效: %v", err)
+	}
+	if cfg == nil {
+		return nil
+	}
+	log.Printf("[INFO] 已启用 SSO 登录: issuer=%s（密码登录保持可用）", cfg.issuer)
+	return newOIDCProvider(*cfg, nil)
+}
+
+// getJSON 获取身份提供方的 JSON 文档
+func (p *oidcProvider) getJSON(ctx context.Context, rawURL string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", rawURL, resp.StatusCode)
+	}
+	return sonic.Unmarshal(body, out)
+}
+
+// discover 获取（并缓存）身份提供方元数据
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	cached := p.discovery
+	p.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var d oidcDiscovery
+	if err := p.getJSON(ctx, p.cfg.issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimRight(d.Issuer, "/") != p.cfg.issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch (%s)", d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing endpoints")
+	}
+	p.mu.Lock()
+	p.discovery = &d
+	p.mu.Unlock()
+	return &d, nil
+}
+
+// signingKeys 获取 JWKS；refresh=true 时在最小间隔外重新拉取（处理密钥轮换）
+func (p *oidcProvider) signingKeys(ctx context.Context, d *oidcDiscovery, refresh bool) (map[string]crypto.PublicKey, error) {
+	p.mu.Lock()
+	keys, fetchedAt := p.keys, p.keysFetchedAt
+	p.mu.Unlock()
+	if keys != nil && (!refresh || time.Since(fetchedAt) < oidcJWKSRefreshEvery) {
+		return keys, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: status %d", resp.StatusCode)
+	}
+	keys, err = util.ParseJWKS(body)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.keys, p.keysFetchedAt = keys, time.Now()
+	p.mu.Unlock()
+	return keys, nil
+}
+
+// randomURLToken 生成 URL 安全的随机串
+func randomURLToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// authCodeURL 创建登录流程，返回身份提供方授权地址与 state
+func (p *oidcProvider) authCodeURL(ctx context.Context, redirectURL, returnTo string, now time.Time) (string, string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err1 := randomURLToken()
+	nonce, err2 := randomURLToken()
+	verifier, err3 := randomURLToken()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return "", "", err
+	}
+
+	p.mu.Lock()
+	if len(p.pending) >= oidcMaxPending {
+		for k, v := range p.pending {
+			if now.After(v.expiresAt) {
+				delete(p.pending, k)
+			}
+		}
+	}
+	if len(p.pending) >= oidcMaxPending {
+		p.mu.Unlock()
+		return "", "", errors.New("too many pending sso logins, try again later")
+	}
+	p.pending[state] = oidcPending{
+		nonce: nonce, verifier: verifier, redirectURL: redirectURL, returnTo: returnTo, expiresAt: now.Add(oidcStateTTL),
+	}
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.clientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(p.cfg.scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), state, nil
+}
+
+// takePending 取出（一次性）登录流程
+func (p *oidcProvider) takePending(state string, now time.Time) (oidcPending, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending, ok := p.pending[state]
+	delete(p.pending, state)
+	if !ok || now.After(pending.expiresAt) {
+		return oidcPending{}, false
+	}
+	return pending, true
+}
+
+// exchange 用授权码换取 ID Token
+func (p *oidcProvider) exchange(ctx context.Context, d *oidcDiscovery, code string, pending oidcPending) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {pending.redirectURL},
+		"client_id":     {p.cfg.clientID},
+		"code_verifier": {pending.verifier},
+	}
+	// 客户端认证：元数据未声明或支持 client_secret_basic 时使用 Basic（规范默认值），否则放入表单
+	useBasic := p.cfg.clientSecret != "" &&
+		(len(d.TokenAuthMethods) == 0 || slices.Contains(d.TokenAuthMethods, "client_secret_basic"))
+	if p.cfg.clientSecret != "" && !useBasic {
+		form.Set("client_secret", p.cfg.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.clientID), url.QueryEscape(p.cfg.clientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token exchange: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("token exchange: %w", err)
+	}
+	var tok struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = sonic.Unmarshal(body, &tok)
+	if resp.StatusCode != http.StatusOK || tok.Error != "" {
+		return "", fmt.Errorf("token exchange: status %d %s %s", resp.StatusCode, tok.Error, tok.ErrorDescription)
+	}
+	if tok.IDToken == "" {
+		return "", errors.New("token exchange: response has no id_token")
+	}
+	return tok.IDToken, nil
+}
+
+// verifyIDToken 校验 ID Token 签名与 iss/aud/exp/nbf/nonce 声明
+func (p *oidcProvider) verifyIDToken(ctx context.Context, d *oidcDiscovery, raw, nonce string, now time.Time) (map[string]any, error) {
+	keys, err := p.signingKeys(ctx, d, false)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := util.VerifyJWT(raw, keys)
+	if errors.Is(err, util.ErrJWTUnknownKey) {
+		if keys, err = p.signingKeys(ctx, d, true); err != nil {
+			return nil, err
+		}
+		claims, err = util.VerifyJWT(raw, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != p.cfg.issuer {
+		return nil, fmt.Errorf("id_token issuer mismatch (%s)", iss)
+	}
+	var audiences []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = []string{aud}
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+	if !slices.Contains(audiences, p.cfg.clientID) {
+		return nil, errors.New("id_token audience mismatch")
+	}
+	if azp, ok := claims["azp"].(string); ok && len(audiences) > 1 && azp != p.cfg.clientID {
+		return nil, errors.New("id_token authorized party mismatch")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return nil, errors.New("id_token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(oidcClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("id_token not yet valid")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+	return claims, nil
+}
+
+// claimGroups 读取组声明（支持点号路径；值可为字符串数组或以空格/逗号分隔的字符串）
+func claimGroups(claims map[string]any, path string) []string {
+	var v any = claims
+	for part := range strings.SplitSeq(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	switch val := v.(type) {
+	case []any:
+		groups := make([]string, 0, len(val))
+		for _, g := range val {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	case string:
+		return strings.FieldsFunc(val, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	return nil
+}
+
+// roleForClaims 按组映射角色（多个组命中时取最高权限），未命中时使用默认角色；返回空串表示拒绝
+func (p *oidcProvider) roleForClaims(claims map[string]any) string {
+	role := ""
+	for _, g := range claimGroups(claims, p.cfg.groupsClaim) {
+		switch p.cfg.roleMap[g] {
+		case model.AdminRoleAdmin:
+			return model.AdminRoleAdmin
+		case model.AdminRoleViewer:
+			role = model.AdminRoleViewer
+		}
+	}
+	if role == "" {
+		role = p.cfg.defaultRole
+	}
+	return role
+}
+
+// oidcStateHash Cookie 中保存的 state 摘要（不直接暴露 state 本身）
+func oidcStateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// isHTTPSRequest 请求是否经由 HTTPS 到达（含反向代理传递的 X-Forwarded-Proto）
+func isHTTPSRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
+
+// setOIDCStateCookie 写入（maxAge<0 时清除）state 绑定 Cookie
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     oidcStateCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   isHTTPSRequest(c),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// stateCookieMatches 回调携带的 Cookie 是否与 state 对应（恒定时间比较）
+func stateCookieMatches(c *gin.Context, state string) bool {
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil || cookie == "" || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(oidcStateHash(state))) == 1
+}
+
+// callbackURL 回调地址（未配置时按请求推导，反向代理需正确传递 X-Forwarded-Proto）
+func (p *oidcProvider) callbackURL(c *gin.Context) string {
+	if p.cfg.redirectURL != "" {
+		return p.cfg.redirectURL
+	}
+	scheme := "http"
+	if isHTTPSRequest(c) {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/login/oidc/callback"
+}
+
+// safeReturnPath 登录后返回的页面（仅允许站内路径，防止开放重定向）
+func safeReturnPath(raw string) string {
+	if !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, "/\\") {
+		return "/web/index.html"
+	}
+	return raw
+}
+
+// redirectLoginError 跳转回登录页并显示错误（login.js 读取 ?error=）
+func redirectLoginError(c *gin.Context, msg string) {
+	c.Redirect(http.StatusFound, oidcLoginPage+"?error="+url.QueryEscape(msg))
+}
+
+// HandleOIDCStatus 登录页查询是否启用 SSO
+// GET /login/oidc/status
+func (s *AuthService) HandleOIDCStatus(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, gin.H{"enabled": s.oidc != nil})
+}
+
+// HandleOIDCLogin 跳转到身份提供方授权页
+// GET /login/oidc?redirect=/web/channels.html
+func (s *AuthService) HandleOIDCLogin(c *gin.Context) {
+	if s.oidc == nil {
+		RespondErrorMsg(c, http.StatusNotFound, "SSO login is not configured")
+		return
+	}
+	target, state, err := s.oidc.authCodeURL(c.Request.Context(), s.oidc.callbackURL(c), safeReturnPath(c.Query("redirect")), time.Now())
+	if err != nil {
+		log.Printf("[WARN] SSO 登录跳转失败: %v", err)
+		redirectLoginError(c, "SSO 服务暂不可用，请使用密码登录")
+		return
+	}
+	setOIDCStateCookie(c, oidcStateHash(state), int(oidcStateTTL/time.Second))
+	c.Redirect(http.StatusFound, target)
+}
+
+// HandleOIDCCallback 身份提供方回调：校验 ID Token、映射角色并签发管理员会话
+// GET /login/oidc/callback?code=...&state=...
+func (s *AuthService) HandleOIDCCallback(c *gin.Context) {
+	if s.oidc == nil {
+		RespondErrorMsg(c, http.StatusNotFound, "SSO login is not configured")
+		return
+	}
+	clientIP := c.ClientIP()
+	now := time.Now()
+
+	// state 必须与发起登录的浏览器绑定（Cookie 一次性使用）
+	state := c.Query("state")
+	cookieOK := stateCookieMatches(c, state)
+	setOIDCStateCookie(c, "", -1)
+	if !cookieOK {
+		log.Printf("[WARN] SSO 回调 state 与浏览器 Cookie 不匹配: IP=%s", clientIP)
+		redirectLoginError(c, "SSO 登录状态校验失败，请重新登录")
+		return
+	}
+	pending, ok := s.oidc.takePending(state, now)
+	if !ok {
+		redirectLoginError(c, "SSO 登录已过期，请重新登录")
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		log.Printf("[WARN] SSO 登录被身份提供方拒绝: IP=%s error=%s %s", clientIP, e, c.Query("error_description"))
+		redirectLoginError(c, "SSO 登录失败: "+e)
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		redirectLoginError(c, "SSO 登录失败: 缺少授权码")
+		return
+	}
+
+	ctx := c.Request.Context()
+	d, err := s.oidc.discover(ctx)
+	if err == nil {
+		var idToken string
+		if idToken, err = s.oidc.exchange(ctx, d, code, pending); err == nil {
+			var claims map[string]any
+			if claims, err = s.oidc.verifyIDToken(ctx, d, idToken, pending.nonce, now); err == nil {
+				s.completeOIDCLogin(c, claims, pending.returnTo)
+				return
+			}
+		}
+	}
+	log.Printf("[WARN] SSO 登录失败: IP=%s err=%v", clientIP, err)
+	redirectLoginError(c, "SSO 登录失败，请重试或使用密码登录")
+}
+
+// completeOIDCLogin 角色映射并签发会话，Token 经 URL 片段交给登录页写入 localStorage
+func (s *AuthService) completeOIDCLogin(c *gin.Context, claims map[string]any, returnTo string) {
+	subject, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	role := s.oidc.roleForClaims(claims)
+	if role == "" {
+		log.Printf("[WARN] SSO 登录被拒绝（未命中任何授权组）: sub=%s email=%s IP=%s", subject, email, c.ClientIP())
+		redirectLoginError(c, "当前账号未被授权访问管理界面")
+		return
+	}
+
+	token, err := s.issueAdminSession(role)
+	if err != nil {
+		log.Printf("ERROR: token generation failed: %v", err)
+		redirectLoginError(c, "internal error")
+		return
+	}
+	log.Printf("[INFO] SSO 登录成功: sub=%s email=%s role=%s IP=%s", subject, email, role, c.ClientIP())
+
+	fragment := url.Values{
+		"sso_token":  {token},
+		"expires_in": {fmt.Sprint(int(config.TokenExpiry.Seconds()))},
+		"role":       {role},
+		"redirect":   {returnTo},
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, oidcLoginPage+"#"+fragment.Encode())
+}
@@ -0,0 +1,271 @@
+package app
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeOIDCIssuer 模拟身份提供方（discovery/jwks/token），签发带指定组的 ID Token
+func fakeOIDCIssuer(t *testing.T, groups []string) (*httptest.Server, *map[string]string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	// 授权请求参数（由测试从跳转地址中提取后写入）
+	authParams := map[string]string{}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_, _ = w.Write([]byte(`{"issuer":"` + srv.URL + `","authorization_endpoint":"` + srv.URL + `/authorize",` +
+				`"token_endpoint":"` + srv.URL + `/token","jwks_uri":"` + srv.URL + `/jwks"}`))
+		case "/jwks":
+			_, _ = w.Write([]byte(`{"keys":[{"kty":"RSA","kid":"k1","use":"sig","n":"` + enc(key.N.Bytes()) +
+				`","e":"` + enc(big.NewInt(int64(key.E)).Bytes()) + `"}]}`))
+		case "/token":
+			_ = r.ParseForm()
+			user, pass, _ := r.BasicAuth()
+			verifierSum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if r.PostForm.Get("code") != "good-code" || user != "ccload" || pass != "secret" ||
+				enc(verifierSum[:]) != authParams["code_challenge"] {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+			groupsJSON := `[]`
+			if len(groups) > 0 {
+				groupsJSON = `["` + strings.Join(groups, `","`) + `"]`
+			}
+			payload := `{"iss":"` + srv.URL + `","aud":"ccload","sub":"u1","email":"alice@example.com",` +
+				`"exp":` + big.NewInt(time.Now().Add(time.Hour).Unix()).String() + `,"nonce":"` + authParams["nonce"] + `","groups":` + groupsJSON + `}`
+			signed := enc([]byte(`{"alg":"RS256","kid":"k1"}`)) + "." + enc([]byte(payload))
+			digest := sha256.Sum256([]byte(signed))
+			sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			_, _ = w.Write([]byte(`{"access_token":"at","token_type":"Bearer","id_token":"` + signed + "." + enc(sig) + `"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &authParams
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		name     string
+		groups   []string
+		wantRole string
+	}{
+		{"admin group wins", []string{"ccload-viewers", "ccload-admins"}, model.AdminRoleAdmin},
+		{"viewer group", []string{"ccload-viewers"}, model.AdminRoleViewer},
+		{"no matching group", []string{"other"}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			idp, authParams := fakeOIDCIssuer(t, tc.groups)
+			auth := NewAuthService("pw", nil, store)
+			defer auth.Close()
+			auth.oidc = newOIDCProvider(oidcConfig{
+				issuer: idp.URL, clientID: "ccload", clientSecret: "secret",
+				scopes: []string{"openid"}, groupsClaim: "groups",
+				roleMap: map[string]string{"ccload-admins": model.AdminRoleAdmin, "ccload-viewers": model.AdminRoleViewer},
+			}, nil)
+
+			r := gin.New()
+			r.GET("/login/oidc", auth.HandleOIDCLogin)
+			r.GET("/login/oidc/callback", auth.HandleOIDCCallback)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login/oidc?redirect=/web/logs.html", nil))
+			if w.Code != http.StatusFound {
+				t.Fatalf("expected redirect to provider, got %d", w.Code)
+			}
+			authURL, _ := url.Parse(w.Header().Get("Location"))
+			q := authURL.Query()
+			if authURL.Path != "/authorize" || q.Get("code_challenge_method") != "S256" || q.Get("redirect_uri") != "http://example.com/login/oidc/callback" {
+				t.Fatalf("unexpected authorization url: %s", authURL)
+			}
+			(*authParams)["nonce"] = q.Get("nonce")
+			(*authParams)["code_challenge"] = q.Get("code_challenge")
+			var stateCookie *http.Cookie
+			for _, ck := range w.Result().Cookies() {
+				if ck.Name == oidcStateCookie {
+					stateCookie = ck
+				}
+			}
+			if stateCookie == nil || !stateCookie.HttpOnly || stateCookie.SameSite != http.SameSiteLaxMode ||
+				stateCookie.MaxAge <= 0 || stateCookie.Value == q.Get("state") {
+				t.Fatalf("state cookie should be a short-lived HttpOnly Lax hash, got %+v", stateCookie)
+			}
+			callback := func(state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, "/login/oidc/callback?code=good-code&state="+state, nil)
+				if cookie != nil {
+					req.AddCookie(cookie)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			// 登录 CSRF：缺少或不匹配的 Cookie 均被拒绝，且不消耗 state
+			forged := &http.Cookie{Name: oidcStateCookie, Value: oidcStateHash("attacker-state")}
+			for _, ck := range []*http.Cookie{nil, forged} {
+				if loc, _ := url.Parse(callback(q.Get("state"), ck).Header().Get("Location")); loc.Query().Get("error") == "" {
+					t.Fatalf("callback with cookie %+v should be rejected", ck)
+				}
+			}
+
+			w = callback(q.Get("state"), stateCookie)
+			loc, _ := url.Parse(w.Header().Get("Location"))
+			if tc.wantRole == "" {
+				if loc.Query().Get("error") == "" {
+					t.Fatalf("unmapped user should be rejected, got %s", loc)
+				}
+				return
+			}
+			fragment, _ := url.ParseQuery(loc.Fragment)
+			token := fragment.Get("sso_token")
+			if role, ok := auth.tokenRole(token); !ok || role != tc.wantRole {
+				t.Fatalf("expected %s session, got %q %v (location %s)", tc.wantRole, role, ok, loc)
+			}
+			if fragment.Get("redirect") != "/web/logs.html" {
+				t.Fatalf("redirect should round-trip, got %q", fragment.Get("redirect"))
+			}
+
+			// state 一次性：重放回调必须失败
+			if loc, _ := url.Parse(callback(q.Get("state"), stateCookie).Header().Get("Location")); loc.Query().Get("error") == "" {
+				t.Fatal("replayed state should be rejected")
+			}
+		})
+	}
+}
+
+func TestRequireTokenAuth_ViewerReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	auth := NewAuthService("pw", nil, store)
+	defer auth.Close()
+
+	token, err := auth.issueAdminSession(model.AdminRoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	admin := r.Group("/admin", auth.RequireTokenAuth())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	admin.GET("/channels", ok)
+	admin.POST("/channels", ok)
+	admin.GET("/channels/export", ok)
+	admin.GET("/future-endpoint", ok) // 未登记的新端点默认仅管理员可读
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/admin/channels", http.StatusOK},
+		{http.MethodPost, "/admin/channels", http.StatusForbidden},
+		{http.MethodGet, "/admin/channels/export", http.StatusForbidden},
+		{http.MethodGet, "/admin/future-endpoint", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.path, tc.want, w.Code)
+		}
+	}
+}
+
+// TestViewerReadablePaths_Registered 白名单中的每个端点都必须是已注册的 GET 路由（防止路由改名后白名单失效）
+func TestViewerReadablePaths_Registered(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	r := gin.New()
+	server.SetupRoutes(r)
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		if route.Method == http.MethodGet {
+			registered[route.Path] = true
+		}
+	}
+	for path := range viewerReadablePaths {
+		if !registered[path] {
+			t.Errorf("viewer allowlist entry %s is not a registered GET route", path)
+		}
+	}
+}
+
+// TestRequireTokenAuth_ViewerBlockedFromCaptures 抓取记录含上游请求/响应头与请求体，viewer 不可读取任何抓取相关端点
+func TestRequireTokenAuth_ViewerBlockedFromCaptures(t *testing.T) {
+	server, cleanup := setupTestServer(t)
+	defer cleanup()
+	r := gin.New()
+	server.SetupRoutes(r)
+
+	const viewerToken = "test-viewer-token"
+	server.authService.tokensMux.Lock()
+	server.authService.validTokens[model.HashToken(viewerToken)] = model.AdminSession{ExpiresAt: time.Now().Add(time.Hour), Role: model.AdminRoleViewer}
+	server.authService.tokensMux.Unlock()
+
+	checked := 0
+	for _, route := range r.Routes() {
+		if route.Method != http.MethodGet || !strings.HasPrefix(route.Path, "/admin/") ||
+			(!strings.Contains(route.Path, "capture") && !strings.Contains(route.Path, "/traces")) {
+			continue
+		}
+		checked++
+		req := httptest.NewRequest(http.MethodGet, strings.ReplaceAll(route.Path, ":id", "1"), nil)
+		req.Header.Set("Authorization", "Bearer "+viewerToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("viewer GET %s: expected 403, got %d", route.Path, w.Code)
+		}
+	}
+	if checked < 3 {
+		t.Fatalf("expected at least 3 capture routes, got %d", checked)
+	}
+}
+
+func TestParseOIDCRoleMapAndClaimGroups(t *testing.T) {
+	m, err := parseOIDCRoleMap(" admins = Admin , ops=viewer,")
+	if err != nil || m["admins"] != model.AdminRoleAdmin || m["ops"] != model.AdminRoleViewer {
+		t.Fatalf("unexpected role map %v %v", m, err)
+	}
+	if _, err := parseOIDCRoleMap("admins=root"); err == nil {
+		t.Fatal("unknown role should be rejected")
+	}
+	claims := map[string]any{"realm_access": map[string]any{"roles": []any{"a", "b"}}, "grp": "x, y"}
+	if got := claimGroups(claims, "realm_access.roles"); len(got) != 2 || got[1] != "b" {
+		t.Fatalf("dotted claim path not resolved: %v", got)
+	}
+	if got := claimGroups(claims, "grp"); len(got) != 2 || got[1] != "y" {
+		t.Fatalf("string groups claim not split: %v", got)
+	}
+	if safeReturnPath("//evil.example") != "/web/index.html" || safeReturnPath("/web/logs.html") != "/web/logs.html" {
+		t.Fatal("return path must stay on site")
+	}
+}
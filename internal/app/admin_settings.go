@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"ccLoad/internal/model"
+	"ccLoad/internal/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +24,57 @@ const (
 	LogRetentionDaysDisabled = -1 // 永久保留
 )
 
+// hotReloadSettings 修改后立即生效、无需重启的配置项（2026-10新增）
+var hotReloadSettings = map[string]func(s *Server, value string){
+	"blocked_models":         (*Server).setBlockedModels,
+	"cost_cache_multipliers": (*Server).setCacheCostMultipliers,
+	"response_label_rules":   (*Server).setResponseLabelRules,
+	"display_currency":       (*Server).setDisplayCurrency,
+	"exchange_rate":          (*Server).setExchangeRate,
+	"exchange_rate_url":      (*Server).setExchangeRateURL,
+	// 每次创建/更新渠道时从配置缓存读取，无需额外应用
+	"reject_duplicate_api_keys": func(*Server, string) {},
+}
+
+// sensitiveSettings 含凭据的配置项（2026-10新增）：
+// 列表/读取接口返回掩码值；提交掩码值表示保持原值；实例同步仅在 include_secrets 时比较与传输
+var sensitiveSettings = map[string]bool{
+	"otel_exporter_otlp_headers": true,
+}
+
+// maskedSettingValue 敏感配置项的掩码值
+const maskedSettingValue = "******"
+
+// maskSetting 返回对外展示用的配置项副本（敏感且非空的值替换为掩码）
+func maskSetting(setting *model.SystemSetting) *model.SystemSetting {
+	if !sensitiveSettings[setting.Key] {
+		return setting
+	}
+	masked := *setting
+	masked.Sensitive = true
+	if masked.Value != "" {
+		masked.Value = maskedSettingValue
+	}
+	return &masked
+}
+
+// keepsSensitiveValue 提交的是敏感配置项的掩码值（保持原值不变）
+func keepsSensitiveValue(key, value string) bool {
+	return sensitiveSettings[key] && value == maskedSettingValue
+}
+
+// applyHotReloadSetting 若为热更新配置项则立即应用，返回是否已应用
+func (s *Server) applyHotReloadSetting(key, value string) bool {
+	apply, ok := hotReloadSettings[key]
+	if !ok {
+		return false
+	}
+	s.configService.setCachedValue(key, value)
+	apply(s, value)
+	log.Printf("[INFO] 配置 %s 已热更新", key)
+	return true
+}
+
 // AdminListSettings 获取所有配置项
 // GET /admin/settings
 func (s *Server) AdminListSettings(c *gin.Context) {
@@ -34,6 +88,9 @@ func (s *Server) AdminListSettings(c *gin.Context) {
 	if settings == nil {
 		settings = make([]*model.SystemSetting, 0)
 	}
+	for i, setting := range settings {
+		settings[i] = maskSetting(setting)
+	}
 	RespondJSON(c, http.StatusOK, settings)
 }
 
@@ -53,7 +110,7 @@ func (s *Server) AdminGetSetting(c *gin.Context) {
 		return
 	}
 
-	RespondJSON(c, http.StatusOK, setting)
+	RespondJSON(c, http.StatusOK, maskSetting(setting))
 }
 
 // AdminUpdateSetting 更新配置项
@@ -78,10 +135,18 @@ func (s *Server) AdminUpdateSetting(c *gin.Context) {
 		return
 	}
 
+	if keepsSensitiveValue(key, req.Value) {
+		RespondJSON(c, http.StatusOK, gin.H{"message": "配置未变更", "key": key, "value": maskedSettingValue})
+		return
+	}
 	if err := validateSettingValue(key, setting.ValueType, req.Value); err != nil {
 		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("invalid value for type %s: %v", setting.ValueType, err))
 		return
 	}
+	respValue := req.Value
+	if sensitiveSettings[key] && respValue != "" {
+		respValue = maskedSettingValue
+	}
 
 	// 更新配置
 	if err := s.configService.UpdateSetting(c.Request.Context(), key, req.Value); err != nil {
@@ -90,13 +155,22 @@ func (s *Server) AdminUpdateSetting(c *gin.Context) {
 		return
 	}
 
+	if s.applyHotReloadSetting(key, req.Value) {
+		RespondJSON(c, http.StatusOK, gin.H{
+			"message": "配置已保存并立即生效",
+			"key":     key,
+			"value":   respValue,
+		})
+		return
+	}
+
 	// log.Printf("[INFO] Setting updated: %s = %s (restart required)", key, req.Value)
 
 	// 返回成功响应，告知需要重启
 	RespondJSON(c, http.StatusOK, gin.H{
 		"message": "配置已保存，程序将在2秒后重启",
 		"key":     key,
-		"value":   req.Value,
+		"value":   respValue,
 	})
 
 	// 异步触发重启
@@ -126,6 +200,15 @@ func (s *Server) AdminResetSetting(c *gin.Context) {
 		return
 	}
 
+	if s.applyHotReloadSetting(key, setting.DefaultValue) {
+		RespondJSON(c, http.StatusOK, gin.H{
+			"message": "配置已重置为默认值并立即生效",
+			"key":     key,
+			"value":   setting.DefaultValue,
+		})
+		return
+	}
+
 	// log.Printf("[INFO] Setting reset to default: %s = %s (restart required)", key, setting.DefaultValue)
 
 	RespondJSON(c, http.StatusOK, gin.H{
@@ -152,13 +235,17 @@ func (s *Server) AdminBatchUpdateSettings(c *gin.Context) {
 		return
 	}
 
-	// 验证所有配置
+	// 验证所有配置（敏感配置项提交掩码值表示保持原值）
 	for key, value := range req {
 		setting := s.configService.GetSetting(key)
 		if setting == nil {
 			RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("unknown setting: %s", key))
 			return
 		}
+		if keepsSensitiveValue(key, value) {
+			delete(req, key)
+			continue
+		}
 
 		if err := validateSettingValue(key, setting.ValueType, value); err != nil {
 			RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("invalid value for %s: %v", key, err))
@@ -166,6 +253,11 @@ func (s *Server) AdminBatchUpdateSettings(c *gin.Context) {
 		}
 	}
 
+	if len(req) == 0 {
+		RespondJSON(c, http.StatusOK, gin.H{"message": "配置未变更"})
+		return
+	}
+
 	// 批量更新(事务保护)
 	if err := s.configService.BatchUpdateSettings(c.Request.Context(), req); err != nil {
 		log.Printf("[ERROR] AdminBatchUpdateSettings failed: %v", err)
@@ -173,6 +265,20 @@ func (s *Server) AdminBatchUpdateSettings(c *gin.Context) {
 		return
 	}
 
+	// 热更新配置项立即生效；全部为热更新配置项时无需重启
+	needRestart := false
+	for key, value := range req {
+		if !s.applyHotReloadSetting(key, value) {
+			needRestart = true
+		}
+	}
+	if !needRestart {
+		RespondJSON(c, http.StatusOK, gin.H{
+			"message": fmt.Sprintf("已保存 %d 项配置，已立即生效", len(req)),
+		})
+		return
+	}
+
 	log.Printf("[INFO] Batch updated %d settings (restart required)", len(req))
 
 	RespondJSON(c, http.StatusOK, gin.H{
@@ -201,6 +307,58 @@ func validateSettingValue(key, valueType, value string) error {
 			if intVal != LogRetentionDaysDisabled && (intVal < LogRetentionDaysMin || intVal > LogRetentionDaysMax) {
 				return fmt.Errorf("log_retention_days must be %d (永久) or %d-%d", LogRetentionDaysDisabled, LogRetentionDaysMin, LogRetentionDaysMax)
 			}
+		case "log_cleanup_batch_size":
+			if intVal < 100 || intVal > 50000 {
+				return fmt.Errorf("log_cleanup_batch_size must be 100-50000")
+			}
+		case "log_cleanup_batch_sleep_ms":
+			if intVal < 0 || intVal > 10000 {
+				return fmt.Errorf("log_cleanup_batch_sleep_ms must be 0-10000")
+			}
+		case "status_probe_interval_minutes":
+			if intVal < 0 || intVal > 1440 {
+				return fmt.Errorf("status_probe_interval_minutes must be 0-1440 (0 = disabled)")
+			}
+		case "db_backup_keep":
+			if intVal < 1 || intVal > dbBackupMaxKeep {
+				return fmt.Errorf("db_backup_keep must be 1-%d", dbBackupMaxKeep)
+			}
+		case "channel_health_check_interval_minutes":
+			if intVal < 0 || intVal > maxChannelHealthIntervalMinutes {
+				return fmt.Errorf("channel_health_check_interval_minutes must be 0-%d", maxChannelHealthIntervalMinutes)
+			}
+		case "channel_health_check_failure_threshold":
+			if intVal < 1 || intVal > maxChannelHealthFailThreshold {
+				return fmt.Errorf("channel_health_check_failure_threshold must be 1-%d", maxChannelHealthFailThreshold)
+			}
+		case "error_capture_budget_mb":
+			if intVal < 0 || intVal > maxErrorCaptureBudgetMB {
+				return fmt.Errorf("error_capture_budget_mb must be 0-%d", maxErrorCaptureBudgetMB)
+			}
+		case "error_capture_retention_hours":
+			if intVal < 1 || intVal > maxErrorCaptureRetentionHours {
+				return fmt.Errorf("error_capture_retention_hours must be 1-%d", maxErrorCaptureRetentionHours)
+			}
+		case "response_cache_ttl_seconds":
+			if intVal < 0 || intVal > maxResponseCacheTTLSeconds {
+				return fmt.Errorf("response_cache_ttl_seconds must be 0-%d (0 = disabled)", maxResponseCacheTTLSeconds)
+			}
+		case "response_cache_max_entries":
+			if intVal < 1 || intVal > maxResponseCacheMaxEntries {
+				return fmt.Errorf("response_cache_max_entries must be 1-%d", maxResponseCacheMaxEntries)
+			}
+		case "otel_sample_percent":
+			if intVal < 0 || intVal > 100 {
+				return fmt.Errorf("otel_sample_percent must be 0-100")
+			}
+		case "db_replication_interval_seconds":
+			if intVal < 1 || intVal > dbReplicationMaxIntervalSeconds {
+				return fmt.Errorf("db_replication_interval_seconds must be 1-%d", dbReplicationMaxIntervalSeconds)
+			}
+		case "log_db_analyze_hours", "log_db_vacuum_hours", "db_backup_interval_hours", "db_integrity_check_hours", "db_replication_lag_alert_seconds":
+			if intVal < 0 {
+				return fmt.Errorf("%s must be >= 0 (0 = disabled)", key)
+			}
 		default:
 			if intVal < -1 {
 				return fmt.Errorf("value must be >= -1")
@@ -222,7 +380,52 @@ func validateSettingValue(key, valueType, value string) error {
 		}
 
 	case "string":
-		// 字符串无需额外验证
+		// 字符串无需额外验证（JSON类配置除外）
+		if key == "client_profiles" {
+			if _, err := util.ParseClientProfiles(value); err != nil {
+				return err
+			}
+		}
+		if key == "cost_cache_multipliers" {
+			if err := util.ValidateCacheCostMultipliers(value); err != nil {
+				return err
+			}
+		}
+		if key == "display_currency" {
+			if _, err := parseDisplayCurrency(value); err != nil {
+				return err
+			}
+		}
+		if key == "exchange_rate" {
+			if _, err := parseExchangeRate(value); err != nil {
+				return err
+			}
+		}
+		if key == "exchange_rate_url" && strings.TrimSpace(value) != "" {
+			if u, err := url.Parse(strings.TrimSpace(value)); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("exchange_rate_url must be an http(s) URL")
+			}
+		}
+		if key == "response_label_rules" {
+			if _, err := parseResponseLabelRules(value); err != nil {
+				return err
+			}
+		}
+		if key == "geo_regions" {
+			if _, err := util.ParseGeoRegions(value); err != nil {
+				return err
+			}
+		}
+		if key == "otel_exporter_otlp_endpoint" && value != "" {
+			if _, err := otlpTracesEndpoint(value); err != nil {
+				return err
+			}
+		}
+		if key == "otel_exporter_otlp_headers" {
+			if _, err := parseOTLPHeaders(value); err != nil {
+				return err
+			}
+		}
 
 	default:
 		return fmt.Errorf("unknown value type: %s", valueType)
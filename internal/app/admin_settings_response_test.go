@@ -1,9 +1,11 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -42,3 +44,53 @@ func TestAdminAPI_ListSettings_ResponseShape(t *testing.T) {
 		t.Fatalf("Expected data to be array, got %T", data)
 	}
 }
+
+// TestAdminAPI_SensitiveSettingsMasked 含凭据的配置项在列表/读取接口中返回掩码，提交掩码值保持原值
+func TestAdminAPI_SensitiveSettingsMasked(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	const key, secret = "otel_exporter_otlp_headers", "Authorization=Bearer otlp-secret"
+	if err := store.UpdateSetting(ctx, key, secret); err != nil {
+		t.Fatal(err)
+	}
+	server.configService = NewConfigService(store)
+	if err := server.configService.LoadDefaults(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(handler gin.HandlerFunc, method, path, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		handler(c)
+		return w
+	}
+	keyParam := gin.Params{{Key: "key", Value: key}}
+
+	for _, w := range []*httptest.ResponseRecorder{
+		call(server.AdminListSettings, http.MethodGet, "/admin/settings", "", nil),
+		call(server.AdminGetSetting, http.MethodGet, "/admin/settings/"+key, "", keyParam),
+	} {
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "otlp-secret") ||
+			!strings.Contains(w.Body.String(), `"sensitive":true`) {
+			t.Fatalf("敏感配置项应返回掩码: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	// 提交掩码值（单项与批量）不覆盖已保存的凭据，也不触发重启
+	if w := call(server.AdminUpdateSetting, http.MethodPut, "/admin/settings/"+key,
+		`{"value":"`+maskedSettingValue+`"}`, keyParam); w.Code != http.StatusOK {
+		t.Fatalf("提交掩码值失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(server.AdminBatchUpdateSettings, http.MethodPost, "/admin/settings/batch",
+		`{"`+key+`":"`+maskedSettingValue+`"}`, nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "未变更") {
+		t.Fatalf("批量提交掩码值失败: %d %s", w.Code, w.Body.String())
+	}
+	if got, _ := store.GetSetting(ctx, key); got == nil || got.Value != secret {
+		t.Fatalf("提交掩码值后应保留原值, got %+v", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"runtime"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -76,13 +78,28 @@ func (s *Server) HandleMetrics(c *gin.Context) {
 }
 
 // HandleStats 获取渠道和模型统计
-// GET /admin/stats?range=today&channel_name_like=xxx&model_like=xxx
+// GET /admin/stats?range=today&channel_name_like=xxx&model_like=xxx&owner=xxx
+// group_by=owner 时改为按令牌归属方+模型分组（2026-10新增）
+// group_by=error_class 时改为按渠道+错误归类分组（2026-10新增，同 /admin/stats/errors）
 func (s *Server) HandleStats(c *gin.Context) {
 	params := ParsePaginationParams(c)
 	lf := BuildLogFilter(c)
 
 	startTime, endTime := params.GetTimeRange()
 
+	switch groupBy := c.Query("group_by"); groupBy {
+	case "", "channel":
+	case "owner":
+		s.respondOwnerStats(c, startTime, endTime, &lf, true)
+		return
+	case "error_class":
+		s.respondErrorClassStats(c, startTime, endTime, &lf)
+		return
+	default:
+		RespondErrorMsg(c, http.StatusBadRequest, "group_by must be channel, owner or error_class")
+		return
+	}
+
 	// 判断是否为本日（本日才计算最近一分钟）
 	isToday := params.Range == "today" || params.Range == ""
 
@@ -108,11 +125,159 @@ func (s *Server) HandleStats(c *gin.Context) {
 	// 计算健康时间线（固定48个时间点，当日显示最近4小时）
 	s.fillHealthTimeline(c.Request.Context(), stats, startTime, endTime, &lf, isToday)
 
+	// 展示币种换算（2026-10新增）
+	currency := s.displayCurrency.info()
+	var totalCost float64
+	for i := range stats {
+		if stats[i].TotalCost != nil {
+			totalCost += *stats[i].TotalCost
+			stats[i].TotalCostDisplay = currency.convert(*stats[i].TotalCost)
+		}
+	}
+
+	RespondJSON(c, http.StatusOK, gin.H{
+		"stats":              stats,
+		"duration_seconds":   durationSeconds,
+		"rpm_stats":          rpmStats,
+		"is_today":           isToday,
+		"total_cost":         totalCost,
+		"total_cost_display": currency.convert(totalCost),
+		"currency":           currency,
+	})
+}
+
+// HandleErrorClassStats 按渠道+错误归类汇总错误次数，定位错误率上升的原因（2026-10新增）
+// GET /admin/stats/errors?range=today&channel_id=1
+func (s *Server) HandleErrorClassStats(c *gin.Context) {
+	params := ParsePaginationParams(c)
+	lf := BuildLogFilter(c)
+	startTime, endTime := params.GetTimeRange()
+	s.respondErrorClassStats(c, startTime, endTime, &lf)
+}
+
+// respondErrorClassStats 查询并输出错误归类统计
+func (s *Server) respondErrorClassStats(c *gin.Context, startTime, endTime time.Time, lf *model.LogFilter) {
+	stats, err := s.store.GetErrorClassStats(c.Request.Context(), startTime, endTime, lf)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 全部类别都输出合计（无错误为0），便于前端固定配色/图例
+	totals := make(map[string]int64, len(util.ErrorClasses))
+	for _, class := range util.ErrorClasses {
+		totals[class] = 0
+	}
+	for _, st := range stats {
+		totals[st.ErrorClass] += st.Count
+	}
+
+	RespondJSON(c, http.StatusOK, gin.H{
+		"stats":   stats,
+		"totals":  totals,
+		"classes": util.ErrorClasses,
+	})
+}
+
+// HandleOwnerStats 按令牌归属方汇总统计，用于内部成本分摊（2026-10新增）
+// GET /admin/stats/owners?range=this_month&channel_type=anthropic
+// 已分配归属方但区间内无请求的令牌也会出现在结果中（计数为0），便于核对
+func (s *Server) HandleOwnerStats(c *gin.Context) {
+	params := ParsePaginationParams(c)
+	lf := BuildLogFilter(c)
+	startTime, endTime := params.GetTimeRange()
+	s.respondOwnerStats(c, startTime, endTime, &lf, false)
+}
+
+// ownerStatsRow 归属方统计行：区间聚合 + 归属方名下令牌数
+type ownerStatsRow struct {
+	model.OwnerStats
+	AssignedTokens   int      `json:"assigned_tokens"`              // 归属方名下令牌总数
+	ActiveTokens     int      `json:"active_tokens"`                // 其中启用的令牌数
+	TotalCostDisplay *float64 `json:"total_cost_display,omitempty"` // 费用（展示币种）
+}
+
+// respondOwnerStats 查询并输出归属方统计（byModel 控制是否细分到模型）
+func (s *Server) respondOwnerStats(c *gin.Context, startTime, endTime time.Time, lf *model.LogFilter, byModel bool) {
+	ctx := c.Request.Context()
+	stats, err := s.store.GetOwnerStatsInRange(ctx, startTime, endTime, lf, byModel)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	tokens, err := s.store.ListAuthTokens(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	type tokenCounts struct{ assigned, active int }
+	counts := make(map[string]*tokenCounts)
+	for _, t := range tokens {
+		if lf.Owner != "" && t.Owner != lf.Owner {
+			continue
+		}
+		tc := counts[t.Owner]
+		if tc == nil {
+			tc = &tokenCounts{}
+			counts[t.Owner] = tc
+		}
+		tc.assigned++
+		if t.IsActive {
+			tc.active++
+		}
+	}
+
+	rows := make([]ownerStatsRow, 0, len(stats)+len(counts))
+	seen := make(map[string]bool, len(counts))
+	var totalCost float64
+	for _, st := range stats {
+		row := ownerStatsRow{OwnerStats: st}
+		if tc := counts[st.Owner]; tc != nil {
+			row.AssignedTokens, row.ActiveTokens = tc.assigned, tc.active
+		}
+		seen[st.Owner] = true
+		totalCost += st.TotalCost
+		rows = append(rows, row)
+	}
+	// 汇总视图补齐无流量的归属方；按模型细分时只列出有数据的组合
+	if !byModel {
+		for owner, tc := range counts {
+			if owner != "" && !seen[owner] {
+				rows = append(rows, ownerStatsRow{
+					OwnerStats:     model.OwnerStats{Owner: owner},
+					AssignedTokens: tc.assigned,
+					ActiveTokens:   tc.active,
+				})
+			}
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].TotalCost != rows[j].TotalCost {
+				return rows[i].TotalCost > rows[j].TotalCost
+			}
+			return rows[i].Owner < rows[j].Owner
+		})
+	}
+
+	durationSeconds := endTime.Sub(startTime).Seconds()
+	if durationSeconds < 1 {
+		durationSeconds = 1 // 防止除零
+	}
+
+	currency := s.displayCurrency.info()
+	for i := range rows {
+		rows[i].TotalCostDisplay = currency.convert(rows[i].TotalCost)
+	}
+
 	RespondJSON(c, http.StatusOK, gin.H{
-		"stats":            stats,
-		"duration_seconds": durationSeconds,
-		"rpm_stats":        rpmStats,
-		"is_today":         isToday,
+		"group_by":           "owner",
+		"stats":              rows,
+		"total_cost":         totalCost,
+		"total_cost_display": currency.convert(totalCost),
+		"currency":           currency,
+		"duration_seconds":   durationSeconds,
+		"start_time":         startTime.UnixMilli(),
+		"end_time":           endTime.UnixMilli(),
 	})
 }
 
@@ -237,15 +402,26 @@ func (s *Server) HandlePublicSummary(c *gin.Context) {
 		}
 	}
 
+	// 展示币种换算（2026-10新增）
+	currency := s.displayCurrency.info()
+	var totalCost float64
+	for _, ts := range typeStats {
+		totalCost += ts.TotalCost
+		ts.TotalCostDisplay = currency.convert(ts.TotalCost)
+	}
+
 	response := gin.H{
-		"total_requests":   totalSuccess + totalError,
-		"success_requests": totalSuccess,
-		"error_requests":   totalError,
-		"range":            params.Range,
-		"duration_seconds": durationSeconds,
-		"rpm_stats":        rpmStats,
-		"is_today":         isToday,
-		"by_type":          typeStats, // 按渠道类型分组的统计
+		"total_cost":         totalCost,
+		"total_cost_display": currency.convert(totalCost),
+		"currency":           currency,
+		"total_requests":     totalSuccess + totalError,
+		"success_requests":   totalSuccess,
+		"error_requests":     totalError,
+		"range":              params.Range,
+		"duration_seconds":   durationSeconds,
+		"rpm_stats":          rpmStats,
+		"is_today":           isToday,
+		"by_type":            typeStats, // 按渠道类型分组的统计
 	}
 
 	RespondJSON(c, http.StatusOK, response)
@@ -253,15 +429,16 @@ func (s *Server) HandlePublicSummary(c *gin.Context) {
 
 // TypeSummary 按渠道类型的统计摘要
 type TypeSummary struct {
-	ChannelType              string  `json:"channel_type"`
-	TotalRequests            int     `json:"total_requests"`
-	SuccessRequests          int     `json:"success_requests"`
-	ErrorRequests            int     `json:"error_requests"`
-	TotalInputTokens         int64   `json:"total_input_tokens,omitempty"`          // 所有类型
-	TotalOutputTokens        int64   `json:"total_output_tokens,omitempty"`         // 所有类型
-	TotalCacheReadTokens     int64   `json:"total_cache_read_tokens,omitempty"`     // Claude/Codex专用（prompt caching）
-	TotalCacheCreationTokens int64   `json:"total_cache_creation_tokens,omitempty"` // Claude/Codex专用（prompt caching）
-	TotalCost                float64 `json:"total_cost,omitempty"`                  // 所有类型
+	ChannelType              string   `json:"channel_type"`
+	TotalRequests            int      `json:"total_requests"`
+	SuccessRequests          int      `json:"success_requests"`
+	ErrorRequests            int      `json:"error_requests"`
+	TotalInputTokens         int64    `json:"total_input_tokens,omitempty"`          // 所有类型
+	TotalOutputTokens        int64    `json:"total_output_tokens,omitempty"`         // 所有类型
+	TotalCacheReadTokens     int64    `json:"total_cache_read_tokens,omitempty"`     // Claude/Codex专用（prompt caching）
+	TotalCacheCreationTokens int64    `json:"total_cache_creation_tokens,omitempty"` // Claude/Codex专用（prompt caching）
+	TotalCost                float64  `json:"total_cost,omitempty"`                  // 所有类型
+	TotalCostDisplay         *float64 `json:"total_cost_display,omitempty"`          // 展示币种（配置 display_currency 时）
 }
 
 // fetchChannelTypesMap 查询所有渠道的类型映射
@@ -336,6 +513,12 @@ func (s *Server) HandleGetChannelTypes(c *gin.Context) {
 	RespondJSON(c, http.StatusOK, util.ChannelTypes)
 }
 
+// HandleGetBetaFeatures 获取已注册的上游Beta功能（渠道 beta_features 可选值，2026-10新增）
+// GET /public/beta-features
+func (s *Server) HandleGetBetaFeatures(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, util.BetaFeatures)
+}
+
 // HandlePublicVersion 获取当前版本信息(公开端点,前端显示版本)
 // GET /public/version
 func (s *Server) HandlePublicVersion(c *gin.Context) {
@@ -374,7 +557,22 @@ func (s *Server) HandleGetModels(c *gin.Context) {
 // HandleHealth 健康检查端点(公开访问,无需认证)
 // GET /health
 // 仅检查数据库连接是否活跃（适用于K8s liveness/readiness probe）
+// GET /health?detail=1 返回各组件状态（需管理员Token）
 func (s *Server) HandleHealth(c *gin.Context) {
+	if c.Query("detail") == "1" {
+		if !s.authService.IsAdminRequest(c) {
+			RespondErrorMsg(c, http.StatusUnauthorized, "未授权访问，请先登录")
+			return
+		}
+		resp := s.buildHealthDetail(c.Request.Context())
+		status := http.StatusOK
+		if resp.Status == "error" {
+			status = http.StatusServiceUnavailable
+		}
+		RespondJSON(c, status, resp)
+		return
+	}
+
 	// 设置100ms超时，避免慢查询阻塞healthcheck
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Millisecond)
 	defer cancel()
@@ -387,6 +585,216 @@ func (s *Server) HandleHealth(c *gin.Context) {
 	RespondJSON(c, http.StatusOK, gin.H{"status": "ok"})
 }
 
+const (
+	// healthStuckRequestThreshold 进行中请求超过该时长未收到任何上游字节视为疑似卡死
+	healthStuckRequestThreshold = 10 * time.Minute
+	// healthRedisPingTimeout 详细健康检查中 Redis Ping 的超时
+	healthRedisPingTimeout = 500 * time.Millisecond
+)
+
+// buildHealthDetail 汇总各组件健康状态
+// 数据库不可用 → error（503）；队列积压/缓存刷新失败/疑似卡死请求 → degraded（200）
+func (s *Server) buildHealthDetail(parent context.Context) HealthDetailResponse {
+	components := make(map[string]HealthComponent, 6)
+
+	// 1. 数据库：Ping延迟
+	ctx, cancel := context.WithTimeout(parent, time.Second)
+	defer cancel()
+	pingStart := time.Now()
+	db := HealthComponent{Status: "ok"}
+	if err := s.store.Ping(ctx); err != nil {
+		db.Status = "error"
+		db.Error = err.Error()
+	}
+	db.Latency = float64(time.Since(pingStart).Microseconds()) / 1000
+	components["database"] = db
+
+	// 2. Redis同步：短超时 Ping（Redis 仅用于备份/恢复，不可用时降级而非 error）
+	redis := HealthComponent{Status: "disabled"}
+	if s.store.IsRedisEnabled() {
+		redis.Status = "ok"
+		redisCtx, redisCancel := context.WithTimeout(parent, healthRedisPingTimeout)
+		redisStart := time.Now()
+		if err := s.store.PingRedis(redisCtx); err != nil {
+			redis.Status = "degraded"
+			redis.Error = err.Error()
+		}
+		redis.Latency = float64(time.Since(redisStart).Microseconds()) / 1000
+		redisCancel()
+	}
+	components["redis"] = redis
+
+	// 3. 渠道缓存：按需刷新（无请求时不刷新），刷新年龄不代表健康，以最近一次刷新是否失败判断
+	if s.channelCache != nil {
+		cacheComp := HealthComponent{Status: "ok"}
+		last, access, err := s.channelCache.RefreshStatus()
+		details := map[string]any{}
+		if !last.IsZero() {
+			details["last_refresh"] = last
+			details["age_seconds"] = time.Since(last).Seconds()
+		}
+		if !access.IsZero() {
+			details["last_access"] = access
+		}
+		if err != nil {
+			// 刷新失败时查询返回错误或沿用旧数据
+			cacheComp.Status = "degraded"
+			cacheComp.Error = err.Error()
+		}
+		if len(details) > 0 {
+			cacheComp.Details = details
+		}
+		components["channel_cache"] = cacheComp
+	}
+
+	// 4. 日志Worker队列
+	if s.logService != nil {
+		depth, capacity, dropped := s.logService.QueueStats()
+		components["log_workers"] = queueHealth(depth, capacity, map[string]any{"dropped": dropped})
+	}
+
+	// 5. Token统计Worker队列
+	if s.tokenStatsCh != nil {
+		components["token_stats_worker"] = queueHealth(len(s.tokenStatsCh), cap(s.tokenStatsCh), nil)
+	}
+
+	// 5.1 count_tokens 请求合并命中率
+	if s.countTokens != nil {
+		components["count_tokens"] = HealthComponent{Status: "ok", Details: s.countTokens.stats()}
+	}
+
+	// 5.2 上游瞬断微重试计数
+	if s.microRetryEnabled {
+		components["upstream_micro_retry"] = HealthComponent{Status: "ok", Details: s.microRetry.stats()}
+	}
+
+	// 5.3 管理端事件流订阅数与丢弃计数
+	if s.adminEvents != nil {
+		components["admin_events"] = HealthComponent{Status: "ok", Details: s.adminEvents.stats()}
+	}
+
+	// 5.4 请求用量预写日志（写入失败意味着崩溃时可能丢失用量）
+	if s.journal != nil {
+		jc := HealthComponent{Status: "ok", Details: s.journal.stats()}
+		if s.journal.writeErrors.Load() > 0 {
+			jc.Status = "degraded"
+		}
+		components["request_journal"] = jc
+	}
+
+	// 5.5 渠道模型不存在标记（存在标记说明渠道模型配置需要修正）
+	if s.modelNotFound != nil {
+		flags := s.modelNotFound.list(time.Now())
+		mc := HealthComponent{Status: "ok", Details: map[string]any{"active_flags": len(flags)}}
+		if len(flags) > 0 {
+			mc.Status = "degraded"
+		}
+		components["model_not_found"] = mc
+	}
+
+	// 5.6 数据库复制滞后（目标未收到最新快照或热备副本未更新超过告警阈值）
+	if s.dbReplication.enabled() {
+		st := s.dbReplication.Status()
+		rc := HealthComponent{Status: "ok", Details: map[string]any{"lag_seconds": st.LagSeconds, "lag_alert_seconds": st.LagAlertSeconds}}
+		if !st.Healthy {
+			rc.Status = "degraded"
+		}
+		if st.Primary != nil {
+			rc.Error = st.Primary.LastError
+			for _, t := range st.Primary.Targets {
+				if rc.Error == "" && t.LastError != "" {
+					rc.Error = t.Kind + ": " + t.LastError
+				}
+			}
+		}
+		components["db_replication"] = rc
+	}
+
+	// 5.7 链路追踪导出（最近一次导出失败时降级）
+	if s.tracer != nil {
+		tc := HealthComponent{Status: "ok", Details: s.tracer.stats()}
+		if s.tracer.failing.Load() {
+			tc.Status = "degraded"
+			tc.Error = "OTLP export failing"
+		}
+		components["tracing"] = tc
+	}
+
+	// 6. 健康度缓存：定时更新，最近一次更新失败或超过3个更新周期未成功更新时降级
+	if s.healthCache != nil {
+		hc := HealthComponent{Status: "disabled"}
+		if cfg := s.healthCache.Config(); cfg.Enabled {
+			hc.Status = "ok"
+			last, err := s.healthCache.UpdateStatus()
+			interval := time.Duration(cfg.UpdateIntervalSeconds) * time.Second
+			switch {
+			case err != nil:
+				hc.Status = "degraded"
+				hc.Error = err.Error()
+			case last.IsZero():
+				hc.Status = "degraded"
+				hc.Error = "health cache has not been updated yet"
+			case interval > 0 && time.Since(last) > 3*interval:
+				hc.Status = "degraded"
+				hc.Error = "health cache update is overdue"
+			}
+			if !last.IsZero() {
+				hc.Details = map[string]any{"last_update": last, "age_seconds": time.Since(last).Seconds()}
+			}
+		}
+		components["health_cache"] = hc
+	}
+
+	// 7. 运行时：goroutine数量与疑似卡死请求
+	var active []*ActiveRequest
+	if s.activeRequests != nil {
+		active = s.activeRequests.List()
+	}
+	// 仍在接收上游字节的请求（长时间流式/思考输出）不算卡死，按最近一次收到字节的时间判断
+	stuck := 0
+	cutoff := time.Now().Add(-healthStuckRequestThreshold).UnixMilli()
+	for _, req := range active {
+		if max(req.StartTime, req.LastActivity) < cutoff {
+			stuck++
+		}
+	}
+	rt := HealthComponent{Status: "ok", Details: map[string]any{
+		"goroutines":      runtime.NumGoroutine(),
+		"active_requests": len(active),
+		"stuck_requests":  stuck,
+	}}
+	if stuck > 0 {
+		rt.Status = "degraded"
+	}
+	components["runtime"] = rt
+
+	overall := "ok"
+	for _, comp := range components {
+		switch comp.Status {
+		case "error":
+			overall = "error"
+		case "degraded":
+			if overall == "ok" {
+				overall = "degraded"
+			}
+		}
+	}
+	return HealthDetailResponse{Status: overall, Components: components}
+}
+
+// queueHealth 根据队列水位判断状态（超过80%视为积压）
+func queueHealth(depth, capacity int, extra map[string]any) HealthComponent {
+	details := map[string]any{"depth": depth, "capacity": capacity}
+	for k, v := range extra {
+		details[k] = v
+	}
+	comp := HealthComponent{Status: "ok", Details: details}
+	if capacity > 0 && depth*5 >= capacity*4 {
+		comp.Status = "degraded"
+	}
+	return comp
+}
+
 // fillHealthTimeline 为每个统计条目填充健康时间线
 // isToday=true: 显示最近4小时，每5分钟一个状态（48个）
 // isToday=false: 按总时间跨度/48计算时间桶
@@ -2,12 +2,20 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"ccLoad/internal/model"
 	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestFillHealthTimeline_UsesSecondsForAvgTimes(t *testing.T) {
@@ -83,3 +91,260 @@ func TestFillHealthTimeline_UsesSecondsForAvgTimes(t *testing.T) {
 func ptrInt64(v int64) *int64 { return &v }
 
 func ptrInt(v int) *int { return &v }
+
+func TestHandleOwnerStats_GroupsAndFiltersByOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := storage.CreateSQLiteStore(t.TempDir()+"/test.db", nil)
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	s := &Server{store: store}
+	ctx := context.Background()
+
+	mkToken := func(hash, owner string) int64 {
+		tok := &model.AuthToken{Token: hash, Description: hash, IsActive: true, Owner: owner}
+		if err := store.CreateAuthToken(ctx, tok); err != nil {
+			t.Fatalf("创建令牌失败: %v", err)
+		}
+		return tok.ID
+	}
+	teamA1 := mkToken("hash-a1", "team-a")
+	teamA2 := mkToken("hash-a2", "team-a")
+	teamB := mkToken("hash-b", "team-b")
+	unowned := mkToken("hash-none", "")
+	mkToken("hash-idle", "team-idle")
+
+	now := time.Now()
+	addLog := func(tokenID int64, modelName string, status int, cost float64) {
+		if err := store.AddLog(ctx, &model.LogEntry{
+			Time:        model.JSONTime{Time: now.Add(-time.Minute)},
+			Model:       modelName,
+			ChannelID:   1,
+			StatusCode:  status,
+			AuthTokenID: tokenID,
+			InputTokens: 100,
+			Cost:        cost,
+		}); err != nil {
+			t.Fatalf("写入日志失败: %v", err)
+		}
+	}
+	addLog(teamA1, "claude-a", 200, 1.0)
+	addLog(teamA2, "claude-b", 500, 0.5)
+	addLog(teamB, "claude-a", 200, 2.0)
+	addLog(unowned, "claude-a", 200, 0.25)
+	addLog(teamB, "claude-a", 499, 9.0) // 客户端取消不计入
+
+	call := func(h gin.HandlerFunc, query string) map[string]any {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/stats/owners?range=today"+query, nil)
+		h(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		return resp.Data
+	}
+	byOwner := func(data map[string]any) map[string]map[string]any {
+		out := map[string]map[string]any{}
+		for _, r := range data["stats"].([]any) {
+			row := r.(map[string]any)
+			key := row["owner"].(string)
+			if m, ok := row["model"].(string); ok {
+				key += "/" + m
+			}
+			out[key] = row
+		}
+		return out
+	}
+
+	rows := byOwner(call(s.HandleOwnerStats, ""))
+	if len(rows) != 4 {
+		t.Fatalf("期望4个归属方(含未分配与无流量)，实际=%v", rows)
+	}
+	a := rows["team-a"]
+	if a["token_count"].(float64) != 2 || a["success_count"].(float64) != 1 || a["failure_count"].(float64) != 1 {
+		t.Fatalf("team-a 聚合错误: %v", a)
+	}
+	if math.Abs(a["total_cost"].(float64)-1.5) > 1e-9 || a["assigned_tokens"].(float64) != 2 {
+		t.Fatalf("team-a 费用/令牌数错误: %v", a)
+	}
+	if b := rows["team-b"]; math.Abs(b["total_cost"].(float64)-2.0) > 1e-9 {
+		t.Fatalf("team-b 费用应排除499: %v", b)
+	}
+	if idle := rows["team-idle"]; idle["success_count"].(float64) != 0 || idle["assigned_tokens"].(float64) != 1 {
+		t.Fatalf("无流量归属方应以0出现: %v", idle)
+	}
+	if _, ok := rows[""]; !ok {
+		t.Fatalf("未分配归属方的令牌应归入空owner")
+	}
+
+	// owner 过滤
+	rows = byOwner(call(s.HandleOwnerStats, "&owner=team-a"))
+	if len(rows) != 1 || rows["team-a"] == nil {
+		t.Fatalf("owner过滤后应只剩team-a: %v", rows)
+	}
+
+	// /admin/stats?group_by=owner 细分到模型
+	rows = byOwner(call(s.HandleStats, "&group_by=owner"))
+	if rows["team-a/claude-a"] == nil || rows["team-a/claude-b"] == nil || rows["team-b/claude-a"] == nil {
+		t.Fatalf("按owner+model分组结果错误: %v", rows)
+	}
+
+	// 常规统计同样支持 owner 过滤
+	stats, err := store.GetStats(ctx, now.Add(-time.Hour), now, &model.LogFilter{Owner: "team-b"}, false)
+	if err != nil {
+		t.Fatalf("GetStats失败: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Success != 1 {
+		t.Fatalf("owner过滤后的渠道统计错误: %+v", stats)
+	}
+}
+
+func TestHandleErrorClassStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := storage.CreateSQLiteStore(t.TempDir()+"/test.db", nil)
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	s := &Server{store: store}
+	ctx := context.Background()
+
+	now := time.Now()
+	addLog := func(channelID int64, status int, class string) {
+		if err := store.AddLog(ctx, &model.LogEntry{
+			Time:       model.JSONTime{Time: now.Add(-time.Minute)},
+			Model:      "claude-a",
+			ChannelID:  channelID,
+			StatusCode: status,
+			ErrorClass: class,
+		}); err != nil {
+			t.Fatalf("写入日志失败: %v", err)
+		}
+	}
+	addLog(1, 429, "overloaded")
+	addLog(1, 529, "overloaded")
+	addLog(1, 401, "auth_error")
+	addLog(2, 504, "network")
+	addLog(2, 200, "")
+	addLog(0, 503, "") // 汇总日志不计入
+
+	call := func(h gin.HandlerFunc, query string) (map[string]any, int) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/stats/errors?range=today"+query, nil)
+		h(c)
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Data, w.Code
+	}
+
+	data, code := call(s.HandleErrorClassStats, "")
+	if code != http.StatusOK {
+		t.Fatalf("status=%d", code)
+	}
+	totals := data["totals"].(map[string]any)
+	if totals["overloaded"].(float64) != 2 || totals["auth_error"].(float64) != 1 || totals["network"].(float64) != 1 || totals["content_filter"].(float64) != 0 {
+		t.Fatalf("错误归类合计错误: %v", totals)
+	}
+	if rows := data["stats"].([]any); len(rows) != 3 {
+		t.Fatalf("期望3个渠道+类别分组，实际=%v", rows)
+	}
+
+	// 渠道过滤 + group_by 入口
+	data, _ = call(s.HandleStats, "&group_by=error_class&channel_id=2")
+	if rows := data["stats"].([]any); len(rows) != 1 || rows[0].(map[string]any)["error_class"] != "network" {
+		t.Fatalf("渠道过滤结果错误: %v", rows)
+	}
+
+	// 日志列表支持按错误归类过滤
+	logs, err := store.ListLogs(ctx, now.Add(-time.Hour), 10, 0, &model.LogFilter{ErrorClass: "overloaded"})
+	if err != nil || len(logs) != 2 {
+		t.Fatalf("按错误归类过滤日志失败: %d %v", len(logs), err)
+	}
+}
+
+// 渠道缓存按需刷新：空闲导致的刷新年龄增长不算降级，只有刷新失败才降级
+func TestBuildHealthDetail_ChannelCacheJudgedByRefreshError(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	server.channelCache = storage.NewChannelCache(store, time.Millisecond)
+	if _, err := server.channelCache.GetEnabledChannelsByModel(ctx, "*"); err != nil {
+		t.Fatalf("load cache: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // 空闲超过TTL，期间无请求触发刷新
+
+	comp := server.buildHealthDetail(ctx).Components["channel_cache"]
+	if comp.Status != "ok" || comp.Details["last_access"] == nil {
+		t.Fatalf("idle cache should be ok, got %+v", comp)
+	}
+
+	_ = store.Close()
+	if _, err := server.channelCache.GetEnabledChannelsByModel(ctx, "*"); err == nil {
+		t.Fatal("refresh should fail after the store is closed")
+	}
+	if comp := server.buildHealthDetail(ctx).Components["channel_cache"]; comp.Status != "degraded" || comp.Error == "" {
+		t.Fatalf("failed refresh should degrade channel_cache, got %+v", comp)
+	}
+}
+
+// redisDownStore 模拟启用了 Redis 但连接不可用的存储
+type redisDownStore struct{ storage.Store }
+
+func (redisDownStore) IsRedisEnabled() bool { return true }
+func (redisDownStore) PingRedis(context.Context) error {
+	return errors.New("dial tcp: connection refused")
+}
+
+// 详细健康检查实际探测 Redis/健康度缓存；仍在接收上游字节的长请求不算卡死
+func TestBuildHealthDetail_ProbesComponents(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	server.store = redisDownStore{store}
+	if comp := server.buildHealthDetail(ctx).Components["redis"]; comp.Status != "degraded" || comp.Error == "" {
+		t.Fatalf("unreachable redis should degrade, got %+v", comp)
+	}
+	server.store = store
+
+	var shuttingDown atomic.Bool
+	server.healthCache = NewHealthCache(store, model.HealthScoreConfig{Enabled: true, UpdateIntervalSeconds: 30, WindowMinutes: 5},
+		make(chan struct{}), &shuttingDown, &sync.WaitGroup{})
+	if comp := server.buildHealthDetail(ctx).Components["health_cache"]; comp.Status != "degraded" {
+		t.Fatalf("never-updated health cache should degrade, got %+v", comp)
+	}
+	server.healthCache.update()
+	if comp := server.buildHealthDetail(ctx).Components["health_cache"]; comp.Status != "ok" || comp.Details["last_update"] == nil {
+		t.Fatalf("updated health cache should be ok, got %+v", comp)
+	}
+
+	server.activeRequests = newActiveRequestManager()
+	old := time.Now().Add(-time.Hour)
+	streaming := server.activeRequests.Register(old, "m", "127.0.0.1", true)
+	server.activeRequests.AddBytes(streaming, 128)
+	if rt := server.buildHealthDetail(ctx).Components["runtime"]; rt.Status != "ok" || rt.Details["stuck_requests"] != 0 {
+		t.Fatalf("long stream still receiving bytes is not stuck, got %+v", rt)
+	}
+	server.activeRequests.Register(old, "m", "127.0.0.1", false)
+	if rt := server.buildHealthDetail(ctx).Components["runtime"]; rt.Status != "degraded" || rt.Details["stuck_requests"] != 1 {
+		t.Fatalf("idle request should be stuck, got %+v", rt)
+	}
+
+	_ = store.Close()
+	server.healthCache.update()
+	if comp := server.buildHealthDetail(ctx).Components["health_cache"]; comp.Status != "degraded" || comp.Error == "" {
+		t.Fatalf("failed update should degrade health_cache, got %+v", comp)
+	}
+}
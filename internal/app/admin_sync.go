@@ -0,0 +1,764 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 实例间差异同步（2026-10新增）
+// ============================================================================
+// 热备实例跟随主实例的渠道/令牌/系统设置，无需反复导出导入：
+//   - 两端各自计算指纹清单（渠道按名称、令牌按哈希、设置按键），只传输与应用有差异的条目
+//   - POST /admin/sync/pull {peer, token}：从对端拉取差异并应用到本实例
+//   - POST /admin/sync/push {peer, token}：将本实例的差异推送到对端并由对端应用
+//   - include_secrets=false（默认）时不比较也不传输渠道API Key：已有渠道保留本地Key，新建渠道无Key
+//     与含凭据的系统设置（sensitiveSettings，如 otel_exporter_otlp_headers）：两端各自保留本地值
+//   - prune=true 时删除对端不存在的渠道/令牌（系统设置只更新不删除）；dry_run=true 只返回差异
+// 对端地址必须在 CCLOAD_SYNC_PEERS 白名单中（同时作为启用开关：未配置时所有同步接口返回404），
+// token 为对端的管理员登录Token。令牌同步的是哈希值，客户端使用同一明文令牌即可在两端认证。
+// 需要重启才生效的系统设置只保存不重启，结果中 restart_required=true 提示运维择机重启。
+
+const (
+	syncHTTPTimeout      = 30 * time.Second
+	syncMaxResponseBytes = 32 << 20
+)
+
+// syncVolatileChannelFields 渠道配置中不参与同步的字段（实例本地状态）
+var syncVolatileChannelFields = []string{"id", "cooldown_until", "cooldown_duration_ms", "key_count", "created_at", "updated_at"}
+
+// syncManifest 指纹清单
+type syncManifest struct {
+	Secrets  bool              `json:"secrets"`  // 渠道指纹是否包含API Key
+	Channels map[string]string `json:"channels"` // 渠道名 → 指纹
+	Tokens   map[string]string `json:"tokens"`   // 令牌哈希 → 指纹
+	Settings map[string]string `json:"settings"` // 设置键 → 指纹
+}
+
+// syncKey 渠道API Key（仅 include_secrets 时传输）
+type syncKey struct {
+	APIKey       string `json:"api_key"`
+	KeyStrategy  string `json:"key_strategy"`
+	AccountGroup string `json:"account_group,omitempty"`
+}
+
+// syncChannel 渠道同步条目
+type syncChannel struct {
+	Name   string         `json:"name"`
+	Config map[string]any `json:"config"`         // 渠道配置（不含ID/冷却状态/时间戳）
+	Keys   []syncKey      `json:"keys,omitempty"` // 仅 include_secrets
+}
+
+// syncToken 令牌同步条目（不含统计与已用额度）
+type syncToken struct {
+	TokenHash         string   `json:"token_hash"`
+	Description       string   `json:"description"`
+	ExpiresAt         *int64   `json:"expires_at,omitempty"`
+	IsActive          bool     `json:"is_active"`
+	CostLimitMicroUSD int64    `json:"cost_limit_micro_usd"`
+	AllowedModels     []string `json:"allowed_models,omitempty"`
+	BlockedModels     []string `json:"blocked_models,omitempty"`
+	ClientCertSubject string   `json:"client_cert_subject,omitempty"`
+	Owner             string   `json:"owner,omitempty"`
+	FailoverInfo      bool     `json:"failover_info,omitempty"`
+	RPMLimit          int      `json:"rpm_limit,omitempty"`
+	TPMLimit          int64    `json:"tpm_limit,omitempty"`
+	FallbackModel     string   `json:"fallback_model,omitempty"`
+}
+
+// syncDiff 差异（以来源为准需要写入/删除的条目）
+type syncDiff struct {
+	Channels       []string `json:"channels"`
+	Tokens         []string `json:"tokens"`
+	Settings       []string `json:"settings"`
+	DeleteChannels []string `json:"delete_channels"`
+	DeleteTokens   []string `json:"delete_tokens"`
+}
+
+func (d syncDiff) empty() bool {
+	return len(d.Channels)+len(d.Tokens)+len(d.Settings)+len(d.DeleteChannels)+len(d.DeleteTokens) == 0
+}
+
+// syncChangeset 差异条目（pull 时由对端返回，push 时发往对端应用）
+type syncChangeset struct {
+	Secrets        bool              `json:"secrets"`
+	Channels       []syncChannel     `json:"channels"`
+	Tokens         []syncToken       `json:"tokens"`
+	Settings       map[string]string `json:"settings"`
+	DeleteChannels []string          `json:"delete_channels,omitempty"`
+	DeleteTokens   []string          `json:"delete_tokens,omitempty"`
+}
+
+// syncResult 应用结果
+type syncResult struct {
+	ChannelsCreated int      `json:"channels_created"`
+	ChannelsUpdated int      `json:"channels_updated"`
+	ChannelsDeleted int      `json:"channels_deleted"`
+	TokensCreated   int      `json:"tokens_created"`
+	TokensUpdated   int      `json:"tokens_updated"`
+	TokensDeleted   int      `json:"tokens_deleted"`
+	SettingsUpdated int      `json:"settings_updated"`
+	RestartRequired bool     `json:"restart_required"`
+	Skipped         []string `json:"skipped,omitempty"` // 无法应用的条目及原因
+}
+
+// syncPeersFromEnv 读取同步对端白名单（逗号分隔的实例地址，如 https://primary.example.com）
+func syncPeersFromEnv() []string {
+	var peers []string
+	for p := range strings.SplitSeq(os.Getenv("CCLOAD_SYNC_PEERS"), ",") {
+		if p = normalizeSyncPeer(p); p != "" && !slices.Contains(peers, p) {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+func normalizeSyncPeer(raw string) string {
+	return strings.TrimRight(strings.TrimSpace(raw), "/")
+}
+
+// syncFingerprint 条目指纹（键排序的JSON的SHA-256）
+func syncFingerprint(v any) string {
+	data, err := sonic.ConfigStd.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// channelSyncConfig 渠道配置的同步表示（去除实例本地状态）
+func channelSyncConfig(cfg *model.Config) (map[string]any, error) {
+	data, err := sonic.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := sonic.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for _, f := range syncVolatileChannelFields {
+		delete(m, f)
+	}
+	return m, nil
+}
+
+func tokenSyncEntry(t *model.AuthToken) syncToken {
+	return syncToken{
+		TokenHash:         t.Token,
+		Description:       t.Description,
+		ExpiresAt:         t.ExpiresAt,
+		IsActive:          t.IsActive,
+		CostLimitMicroUSD: t.CostLimitMicroUSD,
+		AllowedModels:     t.AllowedModels,
+		BlockedModels:     t.BlockedModels,
+		ClientCertSubject: t.ClientCertSubject,
+		Owner:             t.Owner,
+		FailoverInfo:      t.FailoverInfo,
+		RPMLimit:          t.RPMLimit,
+		TPMLimit:          t.TPMLimit,
+		FallbackModel:     t.FallbackModel,
+	}
+}
+
+// syncLocalState 本实例的同步条目
+type syncLocalState struct {
+	channels map[string]syncChannel
+	configs  map[string]*model.Config
+	tokens   map[string]syncToken
+	settings map[string]string
+}
+
+func (s *Server) loadSyncState(ctx context.Context, secrets bool) (*syncLocalState, error) {
+	st := &syncLocalState{
+		channels: make(map[string]syncChannel),
+		configs:  make(map[string]*model.Config),
+		tokens:   make(map[string]syncToken),
+		settings: make(map[string]string),
+	}
+
+	configs, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list channels: %w", err)
+	}
+	var allKeys map[int64][]*model.APIKey
+	if secrets {
+		if allKeys, err = s.store.GetAllAPIKeys(ctx); err != nil {
+			return nil, fmt.Errorf("list api keys: %w", err)
+		}
+	}
+	for _, cfg := range configs {
+		m, err := channelSyncConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		ch := syncChannel{Name: cfg.Name, Config: m}
+		if secrets {
+			keys := allKeys[cfg.ID]
+			slices.SortFunc(keys, func(a, b *model.APIKey) int { return a.KeyIndex - b.KeyIndex })
+			ch.Keys = make([]syncKey, 0, len(keys))
+			for _, k := range keys {
+				ch.Keys = append(ch.Keys, syncKey{APIKey: k.APIKey, KeyStrategy: k.KeyStrategy, AccountGroup: k.AccountGroup})
+			}
+		}
+		st.channels[cfg.Name] = ch
+		st.configs[cfg.Name] = cfg
+	}
+
+	tokens, err := s.store.ListAuthTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list auth tokens: %w", err)
+	}
+	for _, t := range tokens {
+		st.tokens[t.Token] = tokenSyncEntry(t)
+	}
+
+	settings, err := s.store.ListAllSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list settings: %w", err)
+	}
+	for _, setting := range settings {
+		if sensitiveSettings[setting.Key] && !secrets {
+			continue
+		}
+		st.settings[setting.Key] = setting.Value
+	}
+	return st, nil
+}
+
+func (st *syncLocalState) manifest(secrets bool) syncManifest {
+	m := syncManifest{
+		Secrets:  secrets,
+		Channels: make(map[string]string, len(st.channels)),
+		Tokens:   make(map[string]string, len(st.tokens)),
+		Settings: make(map[string]string, len(st.settings)),
+	}
+	for name, ch := range st.channels {
+		m.Channels[name] = syncFingerprint(ch)
+	}
+	for hash, t := range st.tokens {
+		m.Tokens[hash] = syncFingerprint(t)
+	}
+	for key, value := range st.settings {
+		m.Settings[key] = syncFingerprint(value)
+	}
+	return m
+}
+
+// changeset 按差异取出本实例条目
+func (st *syncLocalState) changeset(d syncDiff, secrets bool) syncChangeset {
+	cs := syncChangeset{Secrets: secrets, Settings: make(map[string]string), DeleteChannels: d.DeleteChannels, DeleteTokens: d.DeleteTokens}
+	for _, name := range d.Channels {
+		if ch, ok := st.channels[name]; ok {
+			cs.Channels = append(cs.Channels, ch)
+		}
+	}
+	for _, hash := range d.Tokens {
+		if t, ok := st.tokens[hash]; ok {
+			cs.Tokens = append(cs.Tokens, t)
+		}
+	}
+	for _, key := range d.Settings {
+		if v, ok := st.settings[key]; ok {
+			cs.Settings[key] = v
+		}
+	}
+	return cs
+}
+
+// diffSyncManifests 计算将 dst 同步为 src 需要的变更（prune=false 时不删除）
+// 设置只同步双方都存在的键（版本不同的实例可能有各自独有的设置项）
+func diffSyncManifests(src, dst syncManifest, prune bool) syncDiff {
+	var d syncDiff
+	for name, fp := range src.Channels {
+		if dst.Channels[name] != fp {
+			d.Channels = append(d.Channels, name)
+		}
+	}
+	for hash, fp := range src.Tokens {
+		if dst.Tokens[hash] != fp {
+			d.Tokens = append(d.Tokens, hash)
+		}
+	}
+	for key, fp := range src.Settings {
+		if dstFP, ok := dst.Settings[key]; ok && dstFP != fp {
+			d.Settings = append(d.Settings, key)
+		}
+	}
+	if prune {
+		for name := range dst.Channels {
+			if _, ok := src.Channels[name]; !ok {
+				d.DeleteChannels = append(d.DeleteChannels, name)
+			}
+		}
+		for hash := range dst.Tokens {
+			if _, ok := src.Tokens[hash]; !ok {
+				d.DeleteTokens = append(d.DeleteTokens, hash)
+			}
+		}
+	}
+	for _, list := range [][]string{d.Channels, d.Tokens, d.Settings, d.DeleteChannels, d.DeleteTokens} {
+		slices.Sort(list)
+	}
+	return d
+}
+
+// applySyncChangeset 应用差异条目到本实例
+func (s *Server) applySyncChangeset(ctx context.Context, cs syncChangeset) (syncResult, error) {
+	var res syncResult
+	st, err := s.loadSyncState(ctx, false)
+	if err != nil {
+		return res, err
+	}
+
+	// 与渠道CRUD一致：变更/删除的渠道失效全部相关缓存（列表、Key、冷却），Key变更后刷新脱敏密钥集合
+	var touched []int64
+	keysChanged := false
+	for _, ch := range cs.Channels {
+		channelID, replacedKeys, err := s.applySyncChannel(ctx, st, ch, cs.Secrets, &res)
+		if channelID > 0 {
+			touched = append(touched, channelID)
+		}
+		keysChanged = keysChanged || replacedKeys
+		if err != nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("channel %s: %v", ch.Name, err))
+		}
+	}
+	for _, name := range cs.DeleteChannels {
+		if cfg, ok := st.configs[name]; ok {
+			if err := s.store.DeleteConfig(ctx, cfg.ID); err != nil {
+				res.Skipped = append(res.Skipped, fmt.Sprintf("delete channel %s: %v", name, err))
+				continue
+			}
+			touched = append(touched, cfg.ID)
+			keysChanged = true
+			res.ChannelsDeleted++
+		}
+	}
+	for _, id := range touched {
+		s.invalidateChannelRelatedCache(id)
+	}
+	if keysChanged {
+		s.refreshKnownSecrets()
+	}
+
+	for _, t := range cs.Tokens {
+		if err := s.applySyncToken(ctx, st, t, &res); err != nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("token %s…: %v", shortHash(t.TokenHash), err))
+		}
+	}
+	for _, hash := range cs.DeleteTokens {
+		existing, err := s.store.GetAuthTokenByValue(ctx, hash)
+		if err != nil {
+			continue
+		}
+		if err := s.store.DeleteAuthToken(ctx, existing.ID); err != nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("delete token %s…: %v", shortHash(hash), err))
+			continue
+		}
+		res.TokensDeleted++
+	}
+	if len(cs.Tokens)+len(cs.DeleteTokens) > 0 && s.authService != nil {
+		if err := s.authService.ReloadAuthTokens(); err != nil {
+			log.Printf("[WARN] 同步后重新加载API令牌失败: %v", err)
+		}
+	}
+
+	s.applySyncSettings(ctx, cs.Settings, cs.Secrets, &res)
+	return res, nil
+}
+
+func shortHash(h string) string {
+	return h[:min(len(h), 8)]
+}
+
+// applySyncChannel 创建或更新单个渠道；返回写入过的渠道ID（未写入时为0）与Key是否被替换，
+// 以便调用方在出错时仍能失效已部分写入渠道的缓存
+func (s *Server) applySyncChannel(ctx context.Context, st *syncLocalState, ch syncChannel, secrets bool, res *syncResult) (int64, bool, error) {
+	data, err := sonic.Marshal(ch.Config)
+	if err != nil {
+		return 0, false, err
+	}
+	cfg := &model.Config{}
+	if err := sonic.Unmarshal(data, cfg); err != nil {
+		return 0, false, fmt.Errorf("invalid config: %w", err)
+	}
+	cfg.Name = ch.Name
+	if strings.TrimSpace(cfg.Name) == "" || strings.TrimSpace(cfg.URL) == "" {
+		return 0, false, errors.New("name and url are required")
+	}
+
+	var channelID int64
+	if existing, ok := st.configs[ch.Name]; ok {
+		channelID = existing.ID
+		if _, err := s.store.UpdateConfig(ctx, channelID, cfg); err != nil {
+			return 0, false, err
+		}
+		if err := s.store.SetChannelRewriteRules(ctx, channelID, cfg.RewriteRules); err != nil {
+			return channelID, false, err
+		}
+		if err := s.store.SetChannelModelConcurrency(ctx, channelID, cfg.ModelConcurrency); err != nil {
+			return channelID, false, err
+		}
+		res.ChannelsUpdated++
+	} else {
+		created, err := s.store.CreateConfig(ctx, cfg)
+		if err != nil {
+			return 0, false, err
+		}
+		channelID = created.ID
+		res.ChannelsCreated++
+	}
+
+	if !secrets || ch.Keys == nil {
+		return channelID, false, nil
+	}
+	keys := make([]*model.APIKey, 0, len(ch.Keys))
+	for i, k := range ch.Keys {
+		keys = append(keys, &model.APIKey{ChannelID: channelID, KeyIndex: i, APIKey: k.APIKey, KeyStrategy: k.KeyStrategy, AccountGroup: k.AccountGroup})
+	}
+	// 删除旧Key与写入新Key在同一事务中，失败时保留原有Key
+	if err := s.store.ReplaceAPIKeys(ctx, channelID, keys); err != nil {
+		return channelID, false, err
+	}
+	return channelID, true, nil
+}
+
+func (s *Server) applySyncToken(ctx context.Context, st *syncLocalState, t syncToken, res *syncResult) error {
+	if len(t.TokenHash) != sha256.Size*2 {
+		return errors.New("invalid token hash")
+	}
+	tok := &model.AuthToken{
+		Token:             t.TokenHash,
+		Description:       t.Description,
+		ExpiresAt:         t.ExpiresAt,
+		IsActive:          t.IsActive,
+		CostLimitMicroUSD: t.CostLimitMicroUSD,
+		AllowedModels:     t.AllowedModels,
+		BlockedModels:     t.BlockedModels,
+		ClientCertSubject: t.ClientCertSubject,
+		Owner:             t.Owner,
+		FailoverInfo:      t.FailoverInfo,
+		RPMLimit:          t.RPMLimit,
+		TPMLimit:          t.TPMLimit,
+		FallbackModel:     t.FallbackModel,
+	}
+	if _, ok := st.tokens[t.TokenHash]; !ok {
+		if err := s.store.CreateAuthToken(ctx, tok); err != nil {
+			return err
+		}
+		res.TokensCreated++
+		return nil
+	}
+	existing, err := s.store.GetAuthTokenByValue(ctx, t.TokenHash)
+	if err != nil {
+		return err
+	}
+	tok.ID = existing.ID
+	tok.LastUsedAt = existing.LastUsedAt
+	if err := s.store.UpdateAuthToken(ctx, tok); err != nil {
+		return err
+	}
+	res.TokensUpdated++
+	return nil
+}
+
+// applySyncSettings 校验并保存设置；热更新项立即生效，其余标记需要重启（不自动重启）
+// 未携带 secrets 的变更集不覆盖本地的敏感配置项
+func (s *Server) applySyncSettings(ctx context.Context, settings map[string]string, secrets bool, res *syncResult) {
+	if len(settings) == 0 || s.configService == nil {
+		return
+	}
+	valid := make(map[string]string, len(settings))
+	for key, value := range settings {
+		if sensitiveSettings[key] && (!secrets || value == maskedSettingValue) {
+			continue
+		}
+		setting := s.configService.GetSetting(key)
+		if setting == nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("setting %s: unknown", key))
+			continue
+		}
+		if err := validateSettingValue(key, setting.ValueType, value); err != nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("setting %s: %v", key, err))
+			continue
+		}
+		valid[key] = value
+	}
+	if len(valid) == 0 {
+		return
+	}
+	if err := s.configService.BatchUpdateSettings(ctx, valid); err != nil {
+		res.Skipped = append(res.Skipped, fmt.Sprintf("settings: %v", err))
+		return
+	}
+	res.SettingsUpdated = len(valid)
+	for key, value := range valid {
+		if !s.applyHotReloadSetting(key, value) {
+			res.RestartRequired = true
+		}
+	}
+}
+
+// ============================================================================
+// 对端通信
+// ============================================================================
+
+// syncPeerClient 访问对端实例的同步接口
+type syncPeerClient struct {
+	base   string
+	token  string
+	client *http.Client
+}
+
+func (p *syncPeerClient) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := sonic.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.base+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("peer %s %s: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, syncMaxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("peer %s %s: %w", method, path, err)
+	}
+	var envelope struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+		Data    any    `json:"data"`
+	}
+	envelope.Data = out
+	if err := sonic.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("peer %s %s: status %d, invalid response", method, path, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || !envelope.Success {
+		return fmt.Errorf("peer %s %s: status %d: %s", method, path, resp.StatusCode, envelope.Error)
+	}
+	return nil
+}
+
+func (p *syncPeerClient) manifest(ctx context.Context, secrets bool) (syncManifest, error) {
+	var m syncManifest
+	err := p.call(ctx, http.MethodGet, "/admin/sync/manifest?secrets="+fmt.Sprint(secrets), nil, &m)
+	if err == nil && m.Secrets != secrets {
+		err = errors.New("peer does not support secret-aware manifests")
+	}
+	return m, err
+}
+
+// syncRequest pull/push 请求参数
+type syncRequest struct {
+	Peer           string `json:"peer" binding:"required"`
+	Token          string `json:"token" binding:"required"` // 对端管理员Token
+	IncludeSecrets bool   `json:"include_secrets"`
+	Prune          bool   `json:"prune"`
+	DryRun         bool   `json:"dry_run"`
+}
+
+// syncResponse pull/push 响应
+type syncResponse struct {
+	Peer   string      `json:"peer"`
+	DryRun bool        `json:"dry_run"`
+	Diff   syncDiff    `json:"diff"`
+	Result *syncResult `json:"result,omitempty"`
+}
+
+// syncEnabled 未配置白名单时同步接口不可用
+func (s *Server) syncEnabled(c *gin.Context) bool {
+	if len(s.syncPeers) == 0 {
+		RespondErrorMsg(c, http.StatusNotFound, "instance sync is not configured (CCLOAD_SYNC_PEERS)")
+		return false
+	}
+	return true
+}
+
+// bindSyncRequest 解析请求并校验对端白名单
+func (s *Server) bindSyncRequest(c *gin.Context) (*syncRequest, *syncPeerClient, bool) {
+	if !s.syncEnabled(c) {
+		return nil, nil, false
+	}
+	var req syncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: peer and token are required")
+		return nil, nil, false
+	}
+	peer := normalizeSyncPeer(req.Peer)
+	if u, err := url.Parse(peer); err != nil || (u.Scheme != "http" && u.Scheme != "https") || !slices.Contains(s.syncPeers, peer) {
+		RespondErrorMsg(c, http.StatusForbidden, "peer is not in the sync allowlist")
+		return nil, nil, false
+	}
+	req.Peer = peer
+	return &req, &syncPeerClient{base: peer, token: req.Token, client: &http.Client{Timeout: syncHTTPTimeout}}, true
+}
+
+// HandleSyncManifest 本实例的指纹清单
+// GET /admin/sync/manifest?secrets=true
+func (s *Server) HandleSyncManifest(c *gin.Context) {
+	if !s.syncEnabled(c) {
+		return
+	}
+	secrets := c.Query("secrets") == "true"
+	st, err := s.loadSyncState(c.Request.Context(), secrets)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, st.manifest(secrets))
+}
+
+// HandleSyncItems 按差异返回本实例的条目（供对端 pull）
+// POST /admin/sync/items {"channels":[...],"tokens":[...],"settings":[...],"secrets":false}
+func (s *Server) HandleSyncItems(c *gin.Context) {
+	if !s.syncEnabled(c) {
+		return
+	}
+	var req struct {
+		syncDiff
+		Secrets bool `json:"secrets"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+	st, err := s.loadSyncState(c.Request.Context(), req.Secrets)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	req.DeleteChannels, req.DeleteTokens = nil, nil
+	RespondJSON(c, http.StatusOK, st.changeset(req.syncDiff, req.Secrets))
+}
+
+// HandleSyncApply 应用对端推送的差异条目
+// POST /admin/sync/apply
+func (s *Server) HandleSyncApply(c *gin.Context) {
+	if !s.syncEnabled(c) {
+		return
+	}
+	var cs syncChangeset
+	if err := c.ShouldBindJSON(&cs); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid changeset")
+		return
+	}
+	res, err := s.applySyncChangeset(c.Request.Context(), cs)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("[INFO] 已应用同步变更（来自 %s）: %+v", c.ClientIP(), res)
+	RespondJSON(c, http.StatusOK, res)
+}
+
+// HandleSyncPull 从对端拉取差异并应用到本实例
+// POST /admin/sync/pull {"peer":"https://primary","token":"...","include_secrets":false,"prune":false,"dry_run":false}
+func (s *Server) HandleSyncPull(c *gin.Context) {
+	req, peer, ok := s.bindSyncRequest(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	remote, err := peer.manifest(ctx, req.IncludeSecrets)
+	if err != nil {
+		RespondError(c, http.StatusBadGateway, err)
+		return
+	}
+	local, err := s.loadSyncState(ctx, req.IncludeSecrets)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	diff := diffSyncManifests(remote, local.manifest(req.IncludeSecrets), req.Prune)
+	resp := syncResponse{Peer: req.Peer, DryRun: req.DryRun, Diff: diff}
+	if req.DryRun || diff.empty() {
+		RespondJSON(c, http.StatusOK, resp)
+		return
+	}
+
+	var cs syncChangeset
+	itemsReq := struct {
+		syncDiff
+		Secrets bool `json:"secrets"`
+	}{syncDiff{Channels: diff.Channels, Tokens: diff.Tokens, Settings: diff.Settings}, req.IncludeSecrets}
+	if err := peer.call(ctx, http.MethodPost, "/admin/sync/items", itemsReq, &cs); err != nil {
+		RespondError(c, http.StatusBadGateway, err)
+		return
+	}
+	cs.Secrets = req.IncludeSecrets
+	cs.DeleteChannels, cs.DeleteTokens = diff.DeleteChannels, diff.DeleteTokens
+
+	res, err := s.applySyncChangeset(ctx, cs)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("[INFO] 已从 %s 拉取同步变更: %+v", req.Peer, res)
+	resp.Result = &res
+	RespondJSON(c, http.StatusOK, resp)
+}
+
+// HandleSyncPush 将本实例的差异推送到对端
+// POST /admin/sync/push（参数同 pull）
+func (s *Server) HandleSyncPush(c *gin.Context) {
+	req, peer, ok := s.bindSyncRequest(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	remote, err := peer.manifest(ctx, req.IncludeSecrets)
+	if err != nil {
+		RespondError(c, http.StatusBadGateway, err)
+		return
+	}
+	local, err := s.loadSyncState(ctx, req.IncludeSecrets)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	diff := diffSyncManifests(local.manifest(req.IncludeSecrets), remote, req.Prune)
+	resp := syncResponse{Peer: req.Peer, DryRun: req.DryRun, Diff: diff}
+	if req.DryRun || diff.empty() {
+		RespondJSON(c, http.StatusOK, resp)
+		return
+	}
+
+	var res syncResult
+	if err := peer.call(ctx, http.MethodPost, "/admin/sync/apply", local.changeset(diff, req.IncludeSecrets), &res); err != nil {
+		RespondError(c, http.StatusBadGateway, err)
+		return
+	}
+	log.Printf("[INFO] 已推送同步变更到 %s: %+v", req.Peer, res)
+	resp.Result = &res
+	RespondJSON(c, http.StatusOK, resp)
+}
@@ -0,0 +1,211 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+func syncTestRouter(s *Server) *gin.Engine {
+	r := gin.New()
+	r.GET("/admin/sync/manifest", s.HandleSyncManifest)
+	r.POST("/admin/sync/items", s.HandleSyncItems)
+	r.POST("/admin/sync/apply", s.HandleSyncApply)
+	r.POST("/admin/sync/pull", s.HandleSyncPull)
+	r.POST("/admin/sync/push", s.HandleSyncPush)
+	return r
+}
+
+func TestDiffSyncManifests(t *testing.T) {
+	src := syncManifest{
+		Channels: map[string]string{"a": "1", "b": "2"},
+		Tokens:   map[string]string{"t1": "x"},
+		Settings: map[string]string{"k": "1", "only_src": "1"},
+	}
+	dst := syncManifest{
+		Channels: map[string]string{"a": "1", "b": "old", "gone": "3"},
+		Tokens:   map[string]string{},
+		Settings: map[string]string{"k": "2"},
+	}
+	d := diffSyncManifests(src, dst, false)
+	if len(d.Channels) != 1 || d.Channels[0] != "b" || len(d.Tokens) != 1 || len(d.DeleteChannels) != 0 {
+		t.Fatalf("unexpected diff %+v", d)
+	}
+	if len(d.Settings) != 1 || d.Settings[0] != "k" {
+		t.Fatalf("settings missing on the destination must not be synced, got %v", d.Settings)
+	}
+	if d = diffSyncManifests(src, dst, true); len(d.DeleteChannels) != 1 || d.DeleteChannels[0] != "gone" {
+		t.Fatalf("prune should delete channels missing on source, got %+v", d)
+	}
+	if d = diffSyncManifests(src, src, true); !d.empty() {
+		t.Fatalf("identical manifests should have no diff, got %+v", d)
+	}
+}
+
+func TestSyncPullAndPush(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	primary, primaryStore, cleanupPrimary := setupAdminTestServer(t)
+	defer cleanupPrimary()
+	standby, standbyStore, cleanupStandby := setupAdminTestServer(t)
+	defer cleanupStandby()
+
+	peer := httptest.NewServer(syncTestRouter(primary))
+	defer peer.Close()
+	primary.syncPeers = []string{"http://standby.invalid"}
+	standby.syncPeers = []string{peer.URL}
+
+	cfg, err := primaryStore.CreateConfig(ctx, &model.Config{Name: "main", URL: "https://api.example.com", Priority: 5, Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-a"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := primaryStore.CreateAPIKeysBatch(ctx, []*model.APIKey{{ChannelID: cfg.ID, KeyIndex: 0, APIKey: "primary-upstream-secret", KeyStrategy: model.KeyStrategySequential}}); err != nil {
+		t.Fatal(err)
+	}
+	hash := model.HashToken("client-token")
+	if err := primaryStore.CreateAuthToken(ctx, &model.AuthToken{Token: hash, Description: "ci", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	// 备机已有同名令牌的本地版本和一个主机不存在的渠道
+	if _, err := standbyStore.CreateConfig(ctx, &model.Config{Name: "stale", URL: "https://old.example.com", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	standbyRouter := syncTestRouter(standby)
+	do := func(path, body string) (int, syncResponse) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		standbyRouter.ServeHTTP(w, req)
+		var resp struct {
+			Data syncResponse `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data
+	}
+
+	if code, _ := do("/admin/sync/pull", `{"peer":"https://evil.example.com","token":"t"}`); code != http.StatusForbidden {
+		t.Fatalf("peer outside allowlist should be rejected, got %d", code)
+	}
+
+	code, resp := do("/admin/sync/pull", `{"peer":"`+peer.URL+`/","token":"t","prune":true,"dry_run":true}`)
+	if code != http.StatusOK || len(resp.Diff.Channels) != 1 || len(resp.Diff.DeleteChannels) != 1 || resp.Result != nil {
+		t.Fatalf("dry run should only report the diff, got %d %+v", code, resp)
+	}
+	if configs, _ := standbyStore.ListConfigs(ctx); len(configs) != 1 || configs[0].Name != "stale" {
+		t.Fatal("dry run must not modify the standby")
+	}
+
+	code, resp = do("/admin/sync/pull", `{"peer":"`+peer.URL+`","token":"t","prune":true,"include_secrets":true}`)
+	if code != http.StatusOK || resp.Result == nil || resp.Result.ChannelsCreated != 1 || resp.Result.ChannelsDeleted != 1 || resp.Result.TokensCreated != 1 {
+		t.Fatalf("unexpected pull result %d %+v", code, resp.Result)
+	}
+	configs, _ := standbyStore.ListConfigs(ctx)
+	if len(configs) != 1 || configs[0].Name != "main" || configs[0].Priority != 5 {
+		t.Fatalf("standby should mirror primary channels, got %+v", configs)
+	}
+	keys, _ := standbyStore.GetAPIKeys(ctx, configs[0].ID)
+	if len(keys) != 1 || keys[0].APIKey != "primary-upstream-secret" {
+		t.Fatalf("keys should be copied with include_secrets, got %+v", keys)
+	}
+	// 同步写入的Key加入脱敏集合
+	if got := util.RedactSecrets("upstream said primary-upstream-secret"); strings.Contains(got, "primary-upstream-secret") {
+		t.Fatalf("synced key should be redacted, got %q", got)
+	}
+	if tok, err := standbyStore.GetAuthTokenByValue(ctx, hash); err != nil || tok.Description != "ci" {
+		t.Fatalf("token should be synced by hash, got %+v %v", tok, err)
+	}
+
+	// 再次拉取：没有差异
+	if _, resp = do("/admin/sync/pull", `{"peer":"`+peer.URL+`","token":"t","include_secrets":true}`); !resp.Diff.empty() {
+		t.Fatalf("second pull should be a no-op, got %+v", resp.Diff)
+	}
+
+	// 备机修改后推送回主机；不含密钥时主机保留自己的Key；主机的渠道缓存随之失效
+	primary.channelCache = storage.NewChannelCache(primaryStore, time.Hour)
+	if cached, err := primary.channelCache.GetConfig(ctx, cfg.ID); err != nil || cached.Priority != 5 {
+		t.Fatalf("prime channel cache: %+v %v", cached, err)
+	}
+	standbyCfg := configs[0]
+	standbyCfg.Priority = 9
+	if _, err := standbyStore.UpdateConfig(ctx, standbyCfg.ID, standbyCfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := standbyStore.DeleteAllAPIKeys(ctx, standbyCfg.ID); err != nil {
+		t.Fatal(err)
+	}
+	code, resp = do("/admin/sync/push", `{"peer":"`+peer.URL+`","token":"t"}`)
+	if code != http.StatusOK || resp.Result == nil || resp.Result.ChannelsUpdated != 1 {
+		t.Fatalf("unexpected push result %d %+v", code, resp)
+	}
+	updated, _ := primaryStore.GetConfig(ctx, cfg.ID)
+	if updated.Priority != 9 {
+		t.Fatalf("push should update primary channel, got priority %d", updated.Priority)
+	}
+	if cached, _ := primary.channelCache.GetConfig(ctx, cfg.ID); cached == nil || cached.Priority != 9 {
+		t.Fatalf("push should invalidate primary channel cache, got %+v", cached)
+	}
+	if keys, _ := primaryStore.GetAPIKeys(ctx, cfg.ID); len(keys) != 1 {
+		t.Fatalf("keys must be untouched without include_secrets, got %d", len(keys))
+	}
+}
+
+func TestSyncDisabledWithoutPeers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, _, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	w := httptest.NewRecorder()
+	syncTestRouter(server).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/sync/manifest", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("sync endpoints should be disabled without peers, got %d", w.Code)
+	}
+}
+
+// TestSyncState_SensitiveSettingsRequireSecrets 含凭据的设置仅在 include_secrets 时比较与传输，不含密钥的变更集不覆盖本地值
+func TestSyncState_SensitiveSettingsRequireSecrets(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+	const key = "otel_exporter_otlp_headers"
+	if err := store.UpdateSetting(ctx, key, "Authorization=Bearer local"); err != nil {
+		t.Fatal(err)
+	}
+	server.configService = NewConfigService(store)
+	if err := server.configService.LoadDefaults(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := server.loadSyncState(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.manifest(false).Settings[key]; ok {
+		t.Fatal("sensitive setting must not be fingerprinted without secrets")
+	}
+	if cs := st.changeset(syncDiff{Settings: []string{key}}, false); cs.Settings[key] != "" {
+		t.Fatalf("sensitive setting must not be exported without secrets, got %q", cs.Settings[key])
+	}
+	if st, _ = server.loadSyncState(ctx, true); st.settings[key] != "Authorization=Bearer local" {
+		t.Fatalf("sensitive setting should be synced with secrets, got %q", st.settings[key])
+	}
+
+	var res syncResult
+	server.applySyncSettings(ctx, map[string]string{key: "Authorization=Bearer remote"}, false, &res)
+	if got, _ := store.GetSetting(ctx, key); got.Value != "Authorization=Bearer local" || res.SettingsUpdated != 0 {
+		t.Fatalf("changeset without secrets must keep the local credential, got %q %+v", got.Value, res)
+	}
+}
@@ -39,6 +39,12 @@ func (s *Server) HandleChannelTest(c *gin.Context) {
 		return
 	}
 
+	// 解析提示词库中的测试提示词
+	promptName, ok := s.resolveTestPrompt(c, &testReq)
+	if !ok {
+		return
+	}
+
 	// 获取渠道配置
 	cfg, err := s.store.GetConfig(c.Request.Context(), id)
 	if err != nil {
@@ -75,78 +81,108 @@ func (s *Server) HandleChannelTest(c *gin.Context) {
 		return
 	}
 
+	// 管理端测试走低优先级出站通道，避免与生产流量争抢上游限额
+	release, err := s.acquireAdminLane(c.Request.Context())
+	if err != nil {
+		RespondErrorMsg(c, http.StatusServiceUnavailable, "测试排队已取消: "+err.Error())
+		return
+	}
+	defer release()
+
 	// 执行测试（传递实际的API Key字符串）
 	testResult := s.testChannelAPI(cfg, selectedKey, &testReq)
 	// 添加测试的 Key 索引信息到结果中
 	testResult["tested_key_index"] = keyIndex
 	testResult["total_keys"] = len(apiKeys)
+	if promptName != "" {
+		testResult["test_prompt"] = promptName
+	}
 
 	// [INFO] 修复：根据测试结果应用冷却逻辑
+	s.applyChannelTestCooldown(c.Request.Context(), id, keyIndex, testResult)
+
+	// [INFO] 修复：统一使相关缓存失效，确保前端能立即看到状态更新
+	// 无论是Key级冷却还是渠道级冷却，都需要使缓存失效
+	s.invalidateChannelRelatedCache(id)
+
+	// 上游错误体可能回显请求中的Key，返回前脱敏（冷却判定已使用原始内容）
+	redactTestResult(testResult)
+	RespondJSON(c, http.StatusOK, testResult)
+}
+
+// redactTestResult 脱敏测试结果中的全部字符串字段（含嵌套的 api_error）
+func redactTestResult(result map[string]any) {
+	for k, v := range result {
+		switch val := v.(type) {
+		case string:
+			result[k] = util.RedactSecrets(val)
+		case map[string]any:
+			redactTestResult(val)
+		}
+	}
+}
+
+// applyChannelTestCooldown 根据测试结果应用冷却逻辑
+// 成功：清除Key级与渠道级冷却；失败：交给冷却管理器处理，并将决策写入 testResult["cooldown_action"]
+// 注意：不负责缓存失效，由调用方在（批量）测试结束后统一处理
+func (s *Server) applyChannelTestCooldown(ctx context.Context, channelID int64, keyIndex int, testResult map[string]any) {
 	if success, ok := testResult["success"].(bool); ok && success {
 		// 测试成功：清除该Key的冷却状态
-		if err := s.store.ResetKeyCooldown(c.Request.Context(), id, keyIndex); err != nil {
+		if err := s.store.ResetKeyCooldown(ctx, channelID, keyIndex); err != nil {
 			log.Printf("[WARN] 清除Key #%d冷却状态失败: %v", keyIndex, err)
 		}
 
 		// ✨ 优化：同时清除渠道级冷却（因为至少有一个Key可用）
 		// 设计理念：测试成功证明渠道恢复正常，应立即解除渠道级冷却，避免选择器过滤该渠道
-		_ = s.store.ResetChannelCooldown(c.Request.Context(), id)
-
-		// [INFO] 修复：统一使相关缓存失效，确保前端能立即看到状态更新
-		s.invalidateChannelRelatedCache(id)
-	} else {
-		// 🔥 修复：测试失败时应用冷却策略
-		// 提取状态码和错误体
-		statusCode, _ := testResult["status_code"].(int)
-		var errorBody []byte
-		if apiError, ok := testResult["api_error"].(map[string]any); ok {
-			errorBody, _ = sonic.Marshal(apiError)
-		} else if rawResp, ok := testResult["raw_response"].(string); ok {
-			errorBody = []byte(rawResp)
-		}
+		_ = s.store.ResetChannelCooldown(ctx, channelID)
+		return
+	}
 
-		// 提取响应头（用于429错误的精确分类）
-		var headers map[string][]string
-		if respHeaders, ok := testResult["response_headers"].(map[string]string); ok && statusCode == 429 {
-			headers = make(map[string][]string, len(respHeaders))
-			for k, v := range respHeaders {
-				headers[k] = []string{v}
-			}
-		}
+	// 🔥 修复：测试失败时应用冷却策略
+	// 提取状态码和错误体
+	statusCode, _ := testResult["status_code"].(int)
+	var errorBody []byte
+	if apiError, ok := testResult["api_error"].(map[string]any); ok {
+		errorBody, _ = sonic.Marshal(apiError)
+	} else if rawResp, ok := testResult["raw_response"].(string); ok {
+		errorBody = []byte(rawResp)
+	}
 
-		// 调用统一冷却管理器处理错误
-		action := s.cooldownManager.HandleError(
-			c.Request.Context(),
-			httpErrorInputFromParts(id, keyIndex, statusCode, errorBody, headers),
-		)
-
-		// [INFO] 修复：统一使相关缓存失效，确保前端能立即看到冷却状态更新
-		// 无论是Key级冷却还是渠道级冷却，都需要使缓存失效
-		s.invalidateChannelRelatedCache(id)
-
-		// 记录冷却决策结果到测试响应中
-		var actionStr string
-		switch action {
-		case cooldown.ActionRetryKey:
-			actionStr = "key_cooldown_applied"
-		case cooldown.ActionRetryChannel:
-			actionStr = "channel_cooldown_applied"
-		case cooldown.ActionReturnClient:
-			actionStr = "client_error_no_cooldown"
-		default:
-			actionStr = "unknown_action"
+	// 提取响应头（用于429错误的精确分类）
+	var headers map[string][]string
+	if respHeaders, ok := testResult["response_headers"].(map[string]string); ok && statusCode == 429 {
+		headers = make(map[string][]string, len(respHeaders))
+		for k, v := range respHeaders {
+			headers[k] = []string{v}
 		}
-		testResult["cooldown_action"] = actionStr
 	}
 
-	RespondJSON(c, http.StatusOK, testResult)
+	// 调用统一冷却管理器处理错误
+	action := s.cooldownManager.HandleError(
+		ctx,
+		httpErrorInputFromParts(channelID, keyIndex, statusCode, errorBody, headers),
+	)
+
+	// 记录冷却决策结果到测试响应中
+	var actionStr string
+	switch action {
+	case cooldown.ActionRetryKey:
+		actionStr = "key_cooldown_applied"
+	case cooldown.ActionRetryChannel:
+		actionStr = "channel_cooldown_applied"
+	case cooldown.ActionReturnClient:
+		actionStr = "client_error_no_cooldown"
+	default:
+		actionStr = "unknown_action"
+	}
+	testResult["cooldown_action"] = actionStr
 }
 
 // 测试渠道API连通性
-func (s *Server) testChannelAPI(cfg *model.Config, apiKey string, testReq *testutil.TestChannelRequest) map[string]any {
-	// 设置默认测试内容（从配置读取）
+func (s *Server) testChannelAPI(cfg *model.Config, apiKey string, testReq *testutil.TestChannelRequest) (out map[string]any) {
+	// 兜底测试内容（提示词库为空时；正常情况下调用方已通过 resolveTestPrompt 填充）
 	if strings.TrimSpace(testReq.Content) == "" {
-		testReq.Content = s.configService.GetString("channel_test_content", "sonnet 4.0的发布日期是什么")
+		testReq.Content = fallbackTestContent
 	}
 
 	// [INFO] 修复：应用模型重定向逻辑（与正常代理流程保持一致）
@@ -170,15 +206,15 @@ func (s *Server) testChannelAPI(cfg *model.Config, apiKey string, testReq *testu
 	var tester testutil.ChannelTester
 	switch channelType {
 	case "codex":
-		tester = &testutil.CodexTester{}
+		tester = &testutil.CodexTester{Profile: s.lookupClientProfile(util.ClientProfileCodexCLI)}
 	case "openai":
 		tester = &testutil.OpenAITester{}
+	case "azure":
+		tester = &testutil.AzureTester{}
 	case "gemini":
 		tester = &testutil.GeminiTester{}
-	case "anthropic":
-		tester = &testutil.AnthropicTester{}
-	default:
-		tester = &testutil.AnthropicTester{}
+	default: // anthropic
+		tester = &testutil.AnthropicTester{Profile: s.lookupClientProfile(util.ClientProfileClaudeCLI)}
 	}
 
 	// 构建请求（传递实际的API Key和重定向后的模型）
@@ -202,20 +238,60 @@ func (s *Server) testChannelAPI(cfg *model.Config, apiKey string, testReq *testu
 			req.Header.Add(k, v)
 		}
 	}
+	// 渠道指定的客户端profile（覆盖测试器默认头）
+	if profile, ok := s.clientProfile(cfg); ok {
+		profile.Apply(req.Header)
+	}
 	// 添加/覆盖自定义请求头
 	for key, value := range testReq.Headers {
 		req.Header.Set(key, value)
 	}
 
+	// 渠道级上游压缩（disable_compression=true 时跳过，用于对比基线）
+	compressCfg := cfg
+	if testReq.DisableCompression {
+		compressCfg = nil
+	}
+	requestWireBytes := applyUpstreamCompression(req, compressCfg, body)
+
 	// 发送请求
 	start := time.Now()
-	resp, err := s.client.Do(req)
+	resp, err := s.httpClientFor(cfg).Do(req)
 	duration := time.Since(start)
 	if err != nil {
 		return map[string]any{"success": false, "error": "网络请求失败: " + err.Error(), "duration_ms": duration.Milliseconds()}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	// 统计请求/响应字节数（Transport 自动解压gzip时线路字节数不可见，省略 response_wire_bytes）
+	transportDecoded := resp.Uncompressed
+	responseEncoding := resp.Header.Get("Content-Encoding")
+	if transportDecoded {
+		responseEncoding = "gzip"
+	}
+	wireCounter := &countingReadCloser{ReadCloser: resp.Body}
+	resp.Body = wireCounter
+	decodeUpstreamResponse(resp, compressCfg)
+	bodyCounter := &countingReadCloser{ReadCloser: resp.Body}
+	resp.Body = bodyCounter
+	defer func() {
+		if out == nil {
+			return
+		}
+		compression := map[string]any{
+			"request_bytes":      len(body),
+			"request_wire_bytes": requestWireBytes,
+			"request_encoding":   req.Header.Get("Content-Encoding"),
+			"accept_encoding":    req.Header.Get("Accept-Encoding"),
+			"response_encoding":  responseEncoding,
+			"response_bytes":     bodyCounter.n.Load(),
+		}
+		if !transportDecoded {
+			compression["response_wire_bytes"] = wireCounter.n.Load()
+		}
+		out["compression"] = compression
+	}()
+
 	// 判断是否为SSE响应，以及是否请求了流式
 	contentType := resp.Header.Get("Content-Type")
 	isEventStream := strings.Contains(strings.ToLower(contentType), "text/event-stream")
@@ -348,6 +424,11 @@ func (s *Server) testChannelAPI(cfg *model.Config, apiKey string, testReq *testu
 		}
 		result["raw_response"] = rawBuilder.String()
 
+		// 诊断模式：原始上游流经代理写出路径后的客户端流 + 事件顺序校验
+		if testReq.Diagnose {
+			result["diagnostics"] = diagnoseSSEStream(cfg, channelType, testReq.Model, []byte(rawBuilder.String()))
+		}
+
 		// 补齐tokens与成本信息（用于前端表格展示）
 		billableInput, output, cacheRead, _ := usageParser.GetUsage()
 		if lastUsage != nil {
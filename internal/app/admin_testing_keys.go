@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"ccLoad/internal/testutil"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ==================== 多Key并行测试（2026-10新增） ====================
+// 单Key测试（HandleChannelTest）对多Key渠道需要逐个点击，这里一次性并发测试所有Key：
+// - 并发度受 keyTestConcurrency 与管理端出站通道（adminLane）双重限制，避免瞬间打满上游限流
+// - 每个Key的测试结果同样驱动冷却逻辑（成功解除冷却，失败按分类冷却）
+// - 客户端请求 SSE（?stream=1 或 Accept: text/event-stream）时逐个推送进度
+
+// keyTestConcurrency 多Key测试的最大并发数
+const keyTestConcurrency = 5
+
+// HandleChannelTestAllKeys 并发测试渠道的所有Key
+// POST /admin/channels/:id/test-all-keys
+func (s *Server) HandleChannelTestAllKeys(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+
+	var testReq testutil.TestChannelRequest
+	if err := BindAndValidate(c, &testReq); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	if _, ok := s.resolveTestPrompt(c, &testReq); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	cfg, err := s.store.GetConfig(ctx, id)
+	if err != nil {
+		RespondError(c, http.StatusNotFound, fmt.Errorf("channel not found"))
+		return
+	}
+
+	apiKeys, err := s.store.GetAPIKeys(ctx, id)
+	if err != nil || len(apiKeys) == 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "渠道未配置有效的 API Key")
+		return
+	}
+
+	if !cfg.SupportsModel(testReq.Model) {
+		RespondErrorMsg(c, http.StatusBadRequest, "模型 "+testReq.Model+" 不在此渠道的支持列表中")
+		return
+	}
+
+	stream := c.Query("stream") == "1" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	if stream {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+	}
+
+	results := make(chan KeyTestResult)
+	sem := make(chan struct{}, keyTestConcurrency)
+	var wg sync.WaitGroup
+	for _, key := range apiKeys {
+		wg.Add(1)
+		go func(keyIndex int, apiKey string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// 经低优先级出站通道限流；客户端断开后剩余Key直接跳过（不影响冷却状态）
+			release, err := s.acquireAdminLane(ctx)
+			if err != nil {
+				results <- KeyTestResult{KeyIndex: keyIndex, Error: "测试已取消: " + err.Error()}
+				return
+			}
+			defer release()
+
+			// testChannelAPI 会修改请求（默认内容、模型重定向），每个Key使用独立副本
+			req := testReq
+			testResult := s.testChannelAPI(cfg, apiKey, &req)
+			// 使用独立context：客户端断开SSE不应中断冷却状态的写入
+			s.applyChannelTestCooldown(context.Background(), id, keyIndex, testResult)
+			results <- keyTestResultFrom(keyIndex, testResult)
+		}(key.KeyIndex, key.APIKey)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	summary := KeyTestSummary{Total: len(apiKeys), Results: make([]KeyTestResult, 0, len(apiKeys))}
+	for r := range results {
+		summary.Results = append(summary.Results, r)
+		if r.Success {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+		if stream {
+			writeSSEEvent(c, "progress", gin.H{
+				"completed": len(summary.Results),
+				"total":     summary.Total,
+				"result":    r,
+			})
+		}
+	}
+	slices.SortFunc(summary.Results, func(a, b KeyTestResult) int { return a.KeyIndex - b.KeyIndex })
+
+	// 所有Key测试完毕后统一失效缓存
+	s.invalidateChannelRelatedCache(id)
+
+	if stream {
+		writeSSEEvent(c, "done", summary)
+		return
+	}
+	RespondJSON(c, http.StatusOK, summary)
+}
+
+// keyTestResultFrom 将 testChannelAPI 的结果转换为结果表中的一行
+func keyTestResultFrom(keyIndex int, testResult map[string]any) KeyTestResult {
+	r := KeyTestResult{KeyIndex: keyIndex}
+	r.Success, _ = testResult["success"].(bool)
+	r.StatusCode, _ = testResult["status_code"].(int)
+	r.DurationMs, _ = testResult["duration_ms"].(int64)
+	r.Error, _ = testResult["error"].(string)
+	r.Error = util.RedactSecrets(r.Error)
+	r.CooldownAction, _ = testResult["cooldown_action"].(string)
+	if r.Success {
+		return r
+	}
+
+	switch {
+	case r.StatusCode == 0:
+		r.ErrorClass = "network"
+	case r.CooldownAction == "key_cooldown_applied":
+		r.ErrorClass = "key"
+	case r.CooldownAction == "channel_cooldown_applied":
+		r.ErrorClass = "channel"
+	default:
+		r.ErrorClass = "client"
+	}
+	return r
+}
+
+// writeSSEEvent 写入一条SSE事件并立即刷新（客户端断开时写入失败被忽略）
+func writeSSEEvent(c *gin.Context, event string, data any) {
+	payload, err := sonic.Marshal(data)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+	c.Writer.Flush()
+}
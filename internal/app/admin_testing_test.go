@@ -6,9 +6,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
+	"ccLoad/internal/cooldown"
 	"ccLoad/internal/model"
+	"ccLoad/internal/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -121,3 +125,152 @@ func TestHandleChannelTest(t *testing.T) {
 		})
 	}
 }
+
+// TestHandleChannelTestAllKeys 测试多Key并行测试：结果表、冷却应用与SSE进度
+func TestHandleChannelTestAllKeys(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") == "Bearer sk-bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"type":"message","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	srv.client = upstream.Client()
+	srv.cooldownManager = cooldown.NewManager(srv.store, nil)
+
+	ctx := context.Background()
+	created, err := srv.store.CreateConfig(ctx, &model.Config{
+		Name:         "multi-key",
+		URL:          upstream.URL,
+		Priority:     1,
+		ModelEntries: []model.ModelEntry{{Model: "test-model"}},
+		Enabled:      true,
+	})
+	if err != nil {
+		t.Fatalf("创建测试渠道失败: %v", err)
+	}
+	keys := []*model.APIKey{
+		{ChannelID: created.ID, KeyIndex: 0, APIKey: "sk-good", KeyStrategy: model.KeyStrategySequential},
+		{ChannelID: created.ID, KeyIndex: 1, APIKey: "sk-bad", KeyStrategy: model.KeyStrategySequential},
+		{ChannelID: created.ID, KeyIndex: 2, APIKey: "sk-good-2", KeyStrategy: model.KeyStrategySequential},
+	}
+	if err := srv.store.CreateAPIKeysBatch(ctx, keys); err != nil {
+		t.Fatalf("创建API Keys失败: %v", err)
+	}
+
+	doRequest := func(path string) *httptest.ResponseRecorder {
+		body := `{"model":"test-model","channel_type":"anthropic","content":"ping"}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+		srv.HandleChannelTestAllKeys(c)
+		return w
+	}
+
+	t.Run("JSON结果表", func(t *testing.T) {
+		w := doRequest("/admin/channels/1/test-all-keys")
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码200, 实际 %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data KeyTestSummary `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		sum := resp.Data
+		if sum.Total != 3 || sum.Succeeded != 2 || sum.Failed != 1 || len(sum.Results) != 3 {
+			t.Fatalf("汇总不符: %+v", sum)
+		}
+		bad := sum.Results[1]
+		if bad.KeyIndex != 1 || bad.Success || bad.StatusCode != http.StatusUnauthorized || bad.ErrorClass != "key" {
+			t.Fatalf("失败Key结果不符: %+v", bad)
+		}
+
+		cooldowns, err := srv.store.GetAllKeyCooldowns(ctx)
+		if err != nil {
+			t.Fatalf("查询Key冷却失败: %v", err)
+		}
+		if _, ok := cooldowns[created.ID][1]; !ok {
+			t.Fatalf("失败的Key应进入冷却: %+v", cooldowns)
+		}
+		if _, ok := cooldowns[created.ID][0]; ok {
+			t.Fatal("成功的Key不应冷却")
+		}
+	})
+
+	t.Run("SSE进度", func(t *testing.T) {
+		w := doRequest("/admin/channels/1/test-all-keys?stream=1")
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("期望SSE响应, 实际 Content-Type=%q", ct)
+		}
+		out := w.Body.String()
+		if n := strings.Count(out, "event: progress\n"); n != 3 {
+			t.Fatalf("期望3个progress事件, 实际 %d: %s", n, out)
+		}
+		if !strings.Contains(out, "event: done\n") {
+			t.Fatalf("缺少done事件: %s", out)
+		}
+	})
+}
+
+// TestHandleChannelTest_UsesResolvedClientProfile 测试器默认头按代理相同的规则解析（client_profiles 覆盖内置 claude-cli）
+func TestHandleChannelTest_UsesResolvedClientProfile(t *testing.T) {
+	gotUA := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA <- r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	srv.client = upstream.Client()
+	profiles, err := util.ParseClientProfiles(`{"claude-cli":{"User-Agent":"claude-cli/9.9.9 (external, cli)"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.clientProfiles = profiles
+
+	ctx := context.Background()
+	cfg, err := srv.store.CreateConfig(ctx, &model.Config{
+		Name: "profile-ch", URL: upstream.URL, Priority: 1, Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := srv.store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "sk-test", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/channels/x/test",
+		bytes.NewBufferString(`{"model":"m","content":"hi"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(cfg.ID, 10)}}
+	srv.HandleChannelTest(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("渠道测试失败: %d %s", w.Code, w.Body.String())
+	}
+	select {
+	case ua := <-gotUA:
+		if ua != "claude-cli/9.9.9 (external, cli)" {
+			t.Fatalf("应使用 client_profiles 覆盖的 claude-cli 头，实际 UA=%q", ua)
+		}
+	default:
+		t.Fatal("上游未收到测试请求")
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 令牌用量预测（2026-10新增）
+// ============================================================================
+// GET /admin/auth-tokens/:id/forecast?days=14 按最近 days 天（默认14，最长90）的历史费用计算消耗速率：
+//   - burn_rate_usd_per_day：窗口内总费用 / 窗口时长（令牌创建晚于窗口起点时从创建时间算起）
+//   - projected_month_end_usd：本月已花费 + 速率 × 本月剩余天数
+//   - 配置了 cost_limit_usd 时给出按当前速率耗尽额度的时间（exhaust_at/exhaust_date）
+// 用于在硬限额拦截前主动提醒，而不是等请求被拒绝才发现。
+
+const (
+	tokenForecastDefaultDays = 14
+	tokenForecastMaxDays     = 90
+	tokenForecastMinSpan     = time.Hour // 窗口时长下限，避免刚创建的令牌速率被放大
+)
+
+// TokenForecastDay 单日费用
+type TokenForecastDay struct {
+	Date     string  `json:"date"` // YYYY-MM-DD（服务器本地时区）
+	Requests int64   `json:"requests"`
+	CostUSD  float64 `json:"cost_usd"`
+}
+
+// TokenForecast 令牌用量预测结果
+type TokenForecast struct {
+	TokenID              int64              `json:"token_id"`
+	LookbackDays         int                `json:"lookback_days"`
+	BurnRateUSDPerDay    float64            `json:"burn_rate_usd_per_day"`
+	MonthToDateUSD       float64            `json:"month_to_date_usd"`
+	ProjectedMonthEndUSD float64            `json:"projected_month_end_usd"`
+	CostUsedUSD          float64            `json:"cost_used_usd"`
+	CostLimitUSD         float64            `json:"cost_limit_usd"`          // 0 表示无限额
+	RemainingUSD         *float64           `json:"remaining_usd,omitempty"` // 仅配置限额时返回
+	Exhausted            bool               `json:"exhausted,omitempty"`     // 已达到限额
+	ExhaustAt            *int64             `json:"exhaust_at,omitempty"`    // 预计耗尽时间（Unix毫秒；速率为0或无限额时为空）
+	ExhaustDate          string             `json:"exhaust_date,omitempty"`  // 预计耗尽日期（本地时区）
+	DaysUntilExhausted   *float64           `json:"days_until_exhausted,omitempty"`
+	ExhaustsThisMonth    bool               `json:"exhausts_this_month,omitempty"` // 预计在本月内耗尽
+	Daily                []TokenForecastDay `json:"daily"`
+}
+
+// buildTokenForecast 根据逐日费用计算预测（usedMicro/limitMicro 为当前已用与限额，微美元）
+func buildTokenForecast(token *model.AuthToken, rows []model.TokenDailyCost, usedMicro, limitMicro int64, days int, now time.Time) *TokenForecast {
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	nextMonthStart := monthStart.AddDate(0, 1, 0)
+	windowStart := todayStart.AddDate(0, 0, -days)
+
+	byDate := make(map[string]model.TokenDailyCost, len(rows))
+	for _, r := range rows {
+		// Day 已按时区偏移，直接按UTC格式化即为本地日期
+		byDate[time.Unix(r.Day*86400, 0).UTC().Format(time.DateOnly)] = r
+	}
+
+	f := &TokenForecast{TokenID: token.ID, LookbackDays: days, Daily: make([]TokenForecastDay, 0, days+1)}
+	var windowCost float64
+	for d := windowStart; !d.After(todayStart); d = d.AddDate(0, 0, 1) {
+		date := d.Format(time.DateOnly)
+		r := byDate[date]
+		f.Daily = append(f.Daily, TokenForecastDay{Date: date, Requests: r.Requests, CostUSD: r.Cost})
+		windowCost += r.Cost
+	}
+	for date, r := range byDate {
+		if date >= monthStart.Format(time.DateOnly) {
+			f.MonthToDateUSD += r.Cost
+		}
+	}
+
+	spanStart := windowStart
+	if token.CreatedAt.After(spanStart) {
+		spanStart = token.CreatedAt
+	}
+	span := max(now.Sub(spanStart), tokenForecastMinSpan)
+	f.BurnRateUSDPerDay = windowCost / span.Hours() * 24
+	f.ProjectedMonthEndUSD = f.MonthToDateUSD + f.BurnRateUSDPerDay*nextMonthStart.Sub(now).Hours()/24
+
+	f.CostUsedUSD = util.MicroUSDToUSD(usedMicro)
+	f.CostLimitUSD = util.MicroUSDToUSD(limitMicro)
+	if limitMicro <= 0 {
+		return f
+	}
+	remaining := util.MicroUSDToUSD(max(limitMicro-usedMicro, 0))
+	f.RemainingUSD = &remaining
+	if usedMicro >= limitMicro {
+		f.Exhausted = true
+		return f
+	}
+	if f.BurnRateUSDPerDay <= 0 {
+		return f
+	}
+	daysLeft := remaining / f.BurnRateUSDPerDay
+	exhaustAt := now.Add(time.Duration(daysLeft * float64(24*time.Hour)))
+	exhaustMs := exhaustAt.UnixMilli()
+	f.DaysUntilExhausted = &daysLeft
+	f.ExhaustAt = &exhaustMs
+	f.ExhaustDate = exhaustAt.Format(time.DateOnly)
+	f.ExhaustsThisMonth = exhaustAt.Before(nextMonthStart)
+	return f
+}
+
+// HandleAuthTokenForecast 令牌用量预测
+// GET /admin/auth-tokens/:id/forecast?days=14
+func (s *Server) HandleAuthTokenForecast(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid token id")
+		return
+	}
+	days := tokenForecastDefaultDays
+	if v := c.Query("days"); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 || days > tokenForecastMaxDays {
+			RespondErrorMsg(c, http.StatusBadRequest, "days must be within 1-90")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	token, err := s.store.GetAuthToken(ctx, id)
+	if err != nil || token == nil {
+		RespondErrorMsg(c, http.StatusNotFound, "token not found")
+		return
+	}
+
+	// 查询范围同时覆盖回看窗口与本月（取较早者）
+	now := time.Now()
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := todayStart.AddDate(0, 0, -days)
+	if monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()); monthStart.Before(since) {
+		since = monthStart
+	}
+	_, offsetSec := now.Zone()
+	rows, err := s.store.GetAuthTokenDailyCosts(ctx, id, since, todayStart.AddDate(0, 0, 1), time.Duration(offsetSec)*time.Second)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 费用：内存缓存比数据库更及时（数据库由异步worker更新）
+	usedMicro, limitMicro := token.CostUsedMicroUSD, token.CostLimitMicroUSD
+	if s.authService != nil {
+		if cachedUsed, cachedLimit, _ := s.authService.IsCostLimitExceeded(token.Token); cachedLimit > 0 && cachedUsed > usedMicro {
+			usedMicro = cachedUsed
+		}
+	}
+	RespondJSON(c, http.StatusOK, buildTokenForecast(token, rows, usedMicro, limitMicro, days, now))
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildTokenForecast(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	token := &model.AuthToken{ID: 3, CreatedAt: now.AddDate(0, -2, 0)}
+	day := func(tm time.Time) int64 {
+		_, off := tm.Zone()
+		return (tm.Unix() + int64(off)) / 86400
+	}
+	// 回看窗口10天（含今天上午）每天 $2 → 速率约 2*11/10.5 美元/天
+	var rows []model.TokenDailyCost
+	for d := 10; d >= 0; d-- {
+		rows = append(rows, model.TokenDailyCost{Day: day(now.AddDate(0, 0, -d)), Requests: 4, Cost: 2})
+	}
+
+	f := buildTokenForecast(token, rows, 80_000_000, 100_000_000, 10, now)
+	wantRate := 22 / 10.5
+	if math.Abs(f.BurnRateUSDPerDay-wantRate) > 1e-9 {
+		t.Fatalf("burn rate = %v, want %v", f.BurnRateUSDPerDay, wantRate)
+	}
+	if len(f.Daily) != 11 || f.Daily[10].Date != "2026-10-16" || f.Daily[0].CostUSD != 2 {
+		t.Fatalf("daily series = %+v", f.Daily)
+	}
+	if f.MonthToDateUSD != 22 { // 10月6日-16日
+		t.Fatalf("month to date = %v", f.MonthToDateUSD)
+	}
+	if want := 22 + wantRate*15.5; math.Abs(f.ProjectedMonthEndUSD-want) > 1e-9 {
+		t.Fatalf("projected = %v, want %v", f.ProjectedMonthEndUSD, want)
+	}
+	// 剩余 $20 / 约$2.1每天 ≈ 9.5天 → 10月26日
+	if f.RemainingUSD == nil || *f.RemainingUSD != 20 || f.DaysUntilExhausted == nil ||
+		math.Abs(*f.DaysUntilExhausted-20/wantRate) > 1e-9 || !f.ExhaustsThisMonth || f.ExhaustDate != "2026-10-26" {
+		t.Fatalf("exhaust forecast = %+v", f)
+	}
+
+	// 刚创建的令牌从创建时间起算
+	fresh := &model.AuthToken{ID: 4, CreatedAt: now.Add(-6 * time.Hour)}
+	f = buildTokenForecast(fresh, []model.TokenDailyCost{{Day: day(now), Cost: 3}}, 0, 0, 10, now)
+	if math.Abs(f.BurnRateUSDPerDay-12) > 1e-9 || f.RemainingUSD != nil || f.ExhaustAt != nil {
+		t.Fatalf("fresh token forecast = %+v", f)
+	}
+
+	if f = buildTokenForecast(token, nil, 100_000_000, 100_000_000, 10, now); !f.Exhausted || f.ExhaustAt != nil {
+		t.Fatalf("exhausted token forecast = %+v", f)
+	}
+}
+
+func TestHandleAuthTokenForecast(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now()
+	token := &model.AuthToken{Token: "hash-forecast", Description: "f", CreatedAt: now.AddDate(0, 0, -30), IsActive: true, CostLimitMicroUSD: 50_000_000}
+	if err := store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	var logs []*model.LogEntry
+	for d := range 5 {
+		logs = append(logs,
+			&model.LogEntry{Time: model.JSONTime{Time: now.Add(-time.Duration(d) * 24 * time.Hour)}, Model: "m", StatusCode: 200, AuthTokenID: token.ID, Cost: 1.5},
+			&model.LogEntry{Time: model.JSONTime{Time: now.Add(-time.Duration(d) * 24 * time.Hour)}, Model: "m", StatusCode: 499, AuthTokenID: token.ID, Cost: 9},
+		)
+	}
+	logs = append(logs, &model.LogEntry{Time: model.JSONTime{Time: now}, Model: "m", StatusCode: 200, AuthTokenID: token.ID + 1, Cost: 100})
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("写入日志失败: %v", err)
+	}
+
+	call := func(id int64, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(id, 10)}}
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/auth-tokens/x/forecast"+query, nil)
+		server.HandleAuthTokenForecast(c)
+		return w
+	}
+
+	if w := call(token.ID, "?days=91"); w.Code != http.StatusBadRequest {
+		t.Fatalf("days超限应返回400, got %d", w.Code)
+	}
+	if w := call(9999, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("不存在的令牌应返回404, got %d", w.Code)
+	}
+
+	w := call(token.ID, "?days=7")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data TokenForecast `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	f := resp.Data
+	var windowCost float64
+	for _, d := range f.Daily {
+		windowCost += d.CostUSD
+	}
+	if len(f.Daily) != 8 || math.Abs(windowCost-7.5) > 1e-9 {
+		t.Fatalf("窗口内应只统计本令牌非499的费用: %+v", f.Daily)
+	}
+	if f.BurnRateUSDPerDay <= 0 || f.CostLimitUSD != 50 || f.ExhaustAt == nil || f.ExhaustDate == "" {
+		t.Fatalf("预测结果不符: %+v", f)
+	}
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 访问拓扑导出（2026-10新增）
+// ============================================================================
+// GET /admin/topology 输出 令牌 → 可达渠道 → 上游端点 → 模型 的关系图，用于审计几十个令牌/渠道的访问范围。
+// 令牌本身不绑定渠道：令牌可达某渠道，当且仅当该渠道至少有一个模型对令牌可用
+// （在 allowed_models 内且未被全局/令牌 blocked_models 屏蔽）；边上记录这些模型。
+// format=json（默认）| dot（Graphviz）| mermaid；token_id=N 只输出该令牌的子图。令牌哈希不出现在输出中。
+
+// topologyNode 拓扑图节点（ID 形如 token:1、channel:2、endpoint:<url>、model:<name>）
+type topologyNode struct {
+	ID    string         `json:"id"`
+	Type  string         `json:"type"` // token | channel | endpoint | model
+	Label string         `json:"label"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// topologyEdge 拓扑图边（token→channel 为 access，channel→endpoint 为 upstream，channel→model 为 serves）
+type topologyEdge struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Type   string   `json:"type"`
+	Models []string `json:"models,omitempty"` // access 边：令牌在该渠道上可用的模型
+}
+
+type topologyGraph struct {
+	Nodes []topologyNode `json:"nodes"`
+	Edges []topologyEdge `json:"edges"`
+}
+
+// tokenChannelModels 渠道中对令牌可用的模型；通配符模型在令牌有 allowed_models 时展开为匹配的允许模型
+func tokenChannelModels(token *model.AuthToken, globalBlocked []string, cfg *model.Config) []string {
+	usable := func(name string) bool {
+		return matchBlockedModel(globalBlocked, name) == "" && matchBlockedModel(token.BlockedModels, name) == "" &&
+			token.IsModelAllowed(name)
+	}
+	var out []string
+	seen := make(map[string]struct{})
+	add := func(name string) {
+		key := strings.ToLower(name)
+		if _, dup := seen[key]; !dup {
+			seen[key] = struct{}{}
+			out = append(out, name)
+		}
+	}
+	for _, m := range cfg.GetModels() {
+		if model.IsModelPattern(m) && len(token.AllowedModels) > 0 {
+			for _, allowed := range token.AllowedModels {
+				if model.MatchModelPattern(strings.ToLower(m), strings.ToLower(allowed)) && usable(allowed) {
+					add(allowed)
+				}
+			}
+			continue
+		}
+		if usable(m) {
+			add(m)
+		}
+	}
+	return out
+}
+
+// buildTopology 构建访问拓扑；tokenID>0 时只保留该令牌及其可达的渠道、端点与模型
+func buildTopology(tokens []*model.AuthToken, configs []*model.Config, globalBlocked []string, tokenID int64) *topologyGraph {
+	g := &topologyGraph{Nodes: []topologyNode{}, Edges: []topologyEdge{}}
+	reachable := make(map[int64]bool)
+	for _, t := range tokens {
+		if tokenID > 0 && t.ID != tokenID {
+			continue
+		}
+		label := t.Description
+		if label == "" {
+			label = fmt.Sprintf("token #%d", t.ID)
+		}
+		attrs := map[string]any{"active": t.IsValid()}
+		if t.Owner != "" {
+			attrs["owner"] = t.Owner
+		}
+		if len(t.AllowedModels) > 0 {
+			attrs["allowed_models"] = t.AllowedModels
+		}
+		if len(t.BlockedModels) > 0 {
+			attrs["blocked_models"] = t.BlockedModels
+		}
+		tokenNode := fmt.Sprintf("token:%d", t.ID)
+		g.Nodes = append(g.Nodes, topologyNode{ID: tokenNode, Type: "token", Label: label, Attrs: attrs})
+		for _, cfg := range configs {
+			if models := tokenChannelModels(t, globalBlocked, cfg); len(models) > 0 {
+				reachable[cfg.ID] = true
+				g.Edges = append(g.Edges, topologyEdge{From: tokenNode, To: fmt.Sprintf("channel:%d", cfg.ID), Type: "access", Models: models})
+			}
+		}
+	}
+
+	endpoints := make(map[string]bool)
+	models := make(map[string]bool)
+	for _, cfg := range configs {
+		if tokenID > 0 && !reachable[cfg.ID] {
+			continue
+		}
+		channelNode := fmt.Sprintf("channel:%d", cfg.ID)
+		g.Nodes = append(g.Nodes, topologyNode{ID: channelNode, Type: "channel", Label: cfg.Name, Attrs: map[string]any{
+			"channel_type": cfg.GetChannelType(),
+			"enabled":      cfg.Enabled,
+			"priority":     cfg.Priority,
+		}})
+
+		endpointNode := "endpoint:" + cfg.URL
+		if !endpoints[cfg.URL] {
+			endpoints[cfg.URL] = true
+			g.Nodes = append(g.Nodes, topologyNode{ID: endpointNode, Type: "endpoint", Label: cfg.URL})
+		}
+		g.Edges = append(g.Edges, topologyEdge{From: channelNode, To: endpointNode, Type: "upstream"})
+
+		for _, entry := range cfg.ModelEntries {
+			modelNode := "model:" + entry.Model
+			if !models[entry.Model] {
+				models[entry.Model] = true
+				g.Nodes = append(g.Nodes, topologyNode{ID: modelNode, Type: "model", Label: entry.Model})
+			}
+			edge := topologyEdge{From: channelNode, To: modelNode, Type: "serves"}
+			if entry.RedirectModel != "" {
+				edge.Models = []string{entry.RedirectModel} // 实际请求上游的模型
+			}
+			g.Edges = append(g.Edges, edge)
+		}
+	}
+	return g
+}
+
+// renderTopologyDOT 输出 Graphviz DOT（节点ID加引号，标签转义双引号与反斜杠）
+func renderTopologyDOT(g *topologyGraph) string {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+	}
+	shapes := map[string]string{"token": "ellipse", "channel": "box", "endpoint": "component", "model": "note"}
+	var b strings.Builder
+	b.WriteString("digraph ccload {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", quote(n.ID), quote(n.Label), shapes[n.Type])
+	}
+	for _, e := range g.Edges {
+		if len(e.Models) > 0 && e.Type == "access" {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", quote(e.From), quote(e.To), quote(strings.Join(e.Models, ", ")))
+			continue
+		}
+		fmt.Fprintf(&b, "  %s -> %s;\n", quote(e.From), quote(e.To))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// renderTopologyMermaid 输出 Mermaid flowchart（节点ID只能是字母数字，按出现顺序映射为 n0、n1…）
+func renderTopologyMermaid(g *topologyGraph) string {
+	escape := strings.NewReplacer(`"`, "#quot;", "\n", " ")
+	ids := make(map[string]string, len(g.Nodes))
+	shapes := map[string][2]string{"token": {"([", "])"}, "channel": {"[", "]"}, "endpoint": {"[(", ")]"}, "model": {"{{", "}}"}}
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		id := "n" + strconv.Itoa(i)
+		ids[n.ID] = id
+		shape := shapes[n.Type]
+		fmt.Fprintf(&b, "  %s%s\"%s\"%s\n", id, shape[0], escape.Replace(n.Label), shape[1])
+	}
+	for _, e := range g.Edges {
+		if len(e.Models) > 0 && e.Type == "access" {
+			fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", ids[e.From], escape.Replace(strings.Join(e.Models, ", ")), ids[e.To])
+			continue
+		}
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+	return b.String()
+}
+
+// HandleTopology 导出令牌/渠道/端点/模型访问拓扑
+// GET /admin/topology?format=json|dot|mermaid&token_id=N
+func (s *Server) HandleTopology(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dot" && format != "mermaid" {
+		RespondErrorMsg(c, http.StatusBadRequest, "format must be json, dot or mermaid")
+		return
+	}
+	var tokenID int64
+	if v := c.Query("token_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid token_id")
+			return
+		}
+		tokenID = id
+	}
+
+	ctx := c.Request.Context()
+	tokens, err := s.store.ListAuthTokens(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if tokenID > 0 && !containsTokenID(tokens, tokenID) {
+		RespondErrorMsg(c, http.StatusNotFound, "token not found")
+		return
+	}
+	configs, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	var globalBlocked []string
+	if p := s.blockedModels.Load(); p != nil {
+		globalBlocked = *p
+	}
+
+	g := buildTopology(tokens, configs, globalBlocked, tokenID)
+	switch format {
+	case "dot":
+		c.Header("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		c.String(http.StatusOK, renderTopologyDOT(g))
+	case "mermaid":
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.String(http.StatusOK, renderTopologyMermaid(g))
+	default:
+		RespondJSON(c, http.StatusOK, g)
+	}
+}
+
+func containsTokenID(tokens []*model.AuthToken, id int64) bool {
+	for _, t := range tokens {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+)
+
+func TestBuildTopology(t *testing.T) {
+	tokens := []*model.AuthToken{
+		{ID: 1, Description: "ci", IsActive: true, AllowedModels: []string{"claude-sonnet-4-5", "gpt-4o"}},
+		{ID: 2, Description: `team "b"`, IsActive: true, BlockedModels: []string{"claude-opus-*"}},
+	}
+	configs := []*model.Config{
+		{ID: 10, Name: "anthropic-a", URL: "https://api.anthropic.com", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-*"}}},
+		{ID: 11, Name: "opus-only", URL: "https://api.anthropic.com", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-opus-4-1", RedirectModel: "claude-opus-4-1-20250805"}}},
+		{ID: 12, Name: "openai", ChannelType: "openai", URL: "https://api.openai.com", Enabled: false,
+			ModelEntries: []model.ModelEntry{{Model: "gpt-4o"}}},
+	}
+
+	g := buildTopology(tokens, configs, []string{"gpt-4o"}, 0)
+	access := map[string][]string{}
+	for _, e := range g.Edges {
+		if e.Type == "access" {
+			access[e.From+">"+e.To] = e.Models
+		}
+	}
+	// 令牌1：通配符渠道展开为允许的 claude 模型；gpt-4o 被全局屏蔽；opus 不在允许列表
+	if got := access["token:1>channel:10"]; len(got) != 1 || got[0] != "claude-sonnet-4-5" {
+		t.Fatalf("令牌1→渠道10 模型不符: %v", got)
+	}
+	// 令牌2：不限模型但屏蔽 opus，只能到达通配符渠道
+	if got := access["token:2>channel:10"]; len(got) != 1 || got[0] != "claude-*" {
+		t.Fatalf("令牌2→渠道10 模型不符: %v", got)
+	}
+	if len(access) != 2 {
+		t.Fatalf("可达关系不符: %v", access)
+	}
+
+	endpoints := 0
+	for _, n := range g.Nodes {
+		if n.Type == "endpoint" {
+			endpoints++
+		}
+	}
+	if endpoints != 2 {
+		t.Fatalf("相同URL的端点应合并: %d", endpoints)
+	}
+
+	sub := buildTopology(tokens, configs, nil, 1)
+	for _, n := range sub.Nodes {
+		if n.ID == "token:2" || n.ID == "channel:11" {
+			t.Fatalf("子图不应包含无关节点: %s", n.ID)
+		}
+	}
+
+	dot := renderTopologyDOT(g)
+	if !strings.HasPrefix(dot, "digraph ccload {") || !strings.Contains(dot, `"token:2" [label="team \"b\"", shape=ellipse];`) ||
+		!strings.Contains(dot, `"token:1" -> "channel:10" [label="claude-sonnet-4-5"];`) {
+		t.Fatalf("DOT 输出不符:\n%s", dot)
+	}
+	mermaid := renderTopologyMermaid(g)
+	if !strings.HasPrefix(mermaid, "flowchart LR\n") || !strings.Contains(mermaid, `n1(["team #quot;b#quot;"])`) ||
+		!strings.Contains(mermaid, `n0 -->|"claude-sonnet-4-5"| n2`) {
+		t.Fatalf("Mermaid 输出不符:\n%s", mermaid)
+	}
+}
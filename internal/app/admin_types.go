@@ -21,9 +21,22 @@ type ChannelRequest struct {
 	KeyStrategy    string             `json:"key_strategy,omitempty"` // Key使用策略:sequential, round_robin
 	URL            string             `json:"url" binding:"required,url"`
 	Priority       int                `json:"priority"`
+	Weight         int                `json:"weight"`                          // 负载均衡权重（同优先级渠道按权重分流），0表示按有效Key数量
 	Models         []model.ModelEntry `json:"models" binding:"required,min=1"` // 模型配置（包含重定向）
 	Enabled        bool               `json:"enabled"`
 	DailyCostLimit float64            `json:"daily_cost_limit"` // 每日成本限额（美元），0表示无限制
+	CostMultiplier float64            `json:"cost_multiplier"`  // 价格倍率（如0.8表示八折），0表示按官方定价
+	ClientProfile  string             `json:"client_profile"`   // 客户端请求头profile（空表示透传）
+	CertPins       string             `json:"cert_pins"`        // 上游证书SPKI指纹（逗号分隔 sha256/<base64>，空表示不固定）
+	LocalAddr      string             `json:"local_addr"`       // 出站本机IP或网卡名（空表示默认路由）
+
+	RequestCompression string `json:"request_compression"` // 请求体压缩：空=不压缩，gzip
+	AcceptEncoding     string `json:"accept_encoding"`     // 强制响应编码偏好（gzip/deflate/identity，空表示默认）
+	AnthropicCompat    bool   `json:"anthropic_compat"`    // gemini/openai渠道接受Anthropic /v1/messages请求（自动转换）
+	OpenAICompat       bool   `json:"openai_compat"`       // anthropic/gemini/codex渠道接受OpenAI /v1/chat/completions请求（自动转换）
+	IdempotencyKeys    bool   `json:"idempotency_keys"`    // 出站请求携带Idempotency-Key（微重试复用，避免重复计费）
+	Regions            string `json:"regions"`             // 地域标签（逗号分隔，如 eu,us；空表示全局渠道）
+	BetaFeatures       string `json:"beta_features"`       // 支持的Beta功能（逗号分隔，none=全部不支持，空表示不管理）
 }
 
 func validateChannelBaseURL(raw string) (string, error) {
@@ -61,20 +74,39 @@ func validateChannelBaseURL(raw string) (string, error) {
 // Validate 实现RequestValidator接口
 // [FIX] P0-1: 添加白名单校验和标准化（Fail-Fast + 边界防御）
 func (cr *ChannelRequest) Validate() error {
+	if issues := cr.validateFields(); len(issues) > 0 {
+		return issues[0].Err
+	}
+	return nil
+}
+
+// channelFieldIssue 字段级校验问题（Field 为 JSON 字段名）
+type channelFieldIssue struct {
+	Field string
+	Err   error
+}
+
+// validateFields 逐字段校验并标准化，收集全部问题（Validate 取第一个，校验接口返回全部）
+func (cr *ChannelRequest) validateFields() []channelFieldIssue {
+	var issues []channelFieldIssue
+	fail := func(field string, err error) {
+		issues = append(issues, channelFieldIssue{Field: field, Err: err})
+	}
+
 	// 必填字段校验（现有逻辑保留）
 	if strings.TrimSpace(cr.Name) == "" {
-		return fmt.Errorf("name cannot be empty")
+		fail("name", fmt.Errorf("name cannot be empty"))
 	}
 	if strings.TrimSpace(cr.APIKey) == "" {
-		return fmt.Errorf("api_key cannot be empty")
+		fail("api_key", fmt.Errorf("api_key cannot be empty"))
 	}
 	if len(cr.Models) == 0 {
-		return fmt.Errorf("models cannot be empty")
+		fail("models", fmt.Errorf("models cannot be empty"))
 	}
 	// 验证模型条目（DRY: 使用 ModelEntry.Validate()）
 	for i := range cr.Models {
 		if err := cr.Models[i].Validate(); err != nil {
-			return fmt.Errorf("models[%d]: %w", i, err)
+			fail(fmt.Sprintf("models[%d]", i), fmt.Errorf("models[%d]: %w", i, err))
 		}
 	}
 
@@ -83,11 +115,19 @@ func (cr *ChannelRequest) Validate() error {
 	// - 禁止 userinfo、query、fragment
 	// - 禁止包含 /v1 的 path（防止误填 endpoint 如 /v1/messages）
 	// - 允许其他 path（如 /api, /openai 等用于反向代理或 API gateway）
-	normalizedURL, err := validateChannelBaseURL(cr.URL)
-	if err != nil {
-		return err
+	// mock 渠道的URL为模拟参数（mock://local?latency_ms=...），按模拟上游规则校验
+	validateURL := validateChannelBaseURL
+	if util.NormalizeChannelType(strings.TrimSpace(cr.ChannelType)) == util.ChannelTypeMock {
+		validateURL = func(raw string) (string, error) {
+			_, normalized, err := parseMockChannelURL(raw)
+			return normalized, err
+		}
+	}
+	if normalizedURL, err := validateURL(cr.URL); err != nil {
+		fail("url", err)
+	} else {
+		cr.URL = normalizedURL
 	}
-	cr.URL = normalizedURL
 
 	// [FIX] channel_type 白名单校验 + 标准化
 	// 设计：空值允许（使用默认值anthropic），非空值必须合法
@@ -97,9 +137,10 @@ func (cr *ChannelRequest) Validate() error {
 		normalized := util.NormalizeChannelType(cr.ChannelType)
 		// 再白名单校验
 		if !util.IsValidChannelType(normalized) {
-			return fmt.Errorf("invalid channel_type: %q (allowed: anthropic, openai, gemini, codex)", cr.ChannelType)
+			fail("channel_type", fmt.Errorf("invalid channel_type: %q (allowed: anthropic, openai, gemini, codex, azure, mock)", cr.ChannelType))
+		} else {
+			cr.ChannelType = normalized // 应用标准化结果
 		}
-		cr.ChannelType = normalized // 应用标准化结果
 	}
 
 	// [FIX] key_strategy 白名单校验 + 标准化
@@ -110,12 +151,67 @@ func (cr *ChannelRequest) Validate() error {
 		normalized := strings.ToLower(cr.KeyStrategy)
 		// 再白名单校验
 		if !model.IsValidKeyStrategy(normalized) {
-			return fmt.Errorf("invalid key_strategy: %q (allowed: sequential, round_robin)", cr.KeyStrategy)
+			fail("key_strategy", fmt.Errorf("invalid key_strategy: %q (allowed: sequential, round_robin)", cr.KeyStrategy))
+		} else {
+			cr.KeyStrategy = normalized // 应用标准化结果
 		}
-		cr.KeyStrategy = normalized // 应用标准化结果
 	}
 
-	return nil
+	if cr.CostMultiplier < 0 {
+		fail("cost_multiplier", fmt.Errorf("cost_multiplier must be >= 0"))
+	}
+	if cr.Weight < 0 || cr.Weight > maxChannelWeight {
+		fail("weight", fmt.Errorf("weight must be between 0 and %d", maxChannelWeight))
+	}
+	cr.ClientProfile = strings.TrimSpace(cr.ClientProfile)
+	if len(cr.ClientProfile) > 64 {
+		fail("client_profile", fmt.Errorf("client_profile too long (max 64)"))
+	}
+	if cr.CertPins != "" {
+		if pins, err := util.ParseCertPins(cr.CertPins); err != nil {
+			fail("cert_pins", err)
+		} else {
+			cr.CertPins = strings.Join(pins, ",")
+			if len(cr.CertPins) > 1024 {
+				fail("cert_pins", fmt.Errorf("cert_pins too long (max 1024)"))
+			}
+		}
+	}
+	if localAddr, err := util.NormalizeLocalAddr(cr.LocalAddr); err != nil {
+		fail("local_addr", err)
+	} else {
+		cr.LocalAddr = localAddr
+	}
+	if v, err := util.NormalizeRequestCompression(cr.RequestCompression); err != nil {
+		fail("request_compression", err)
+	} else {
+		cr.RequestCompression = v
+	}
+	if v, err := util.NormalizeAcceptEncoding(cr.AcceptEncoding); err != nil {
+		fail("accept_encoding", err)
+	} else {
+		cr.AcceptEncoding = v
+	}
+	if cr.AnthropicCompat && !anthropicCompatChannelType(util.NormalizeChannelType(cr.ChannelType)) {
+		fail("anthropic_compat", fmt.Errorf("anthropic_compat is only supported for gemini and openai channels"))
+	}
+	if cr.OpenAICompat && !openaiCompatChannelType(util.NormalizeChannelType(cr.ChannelType)) {
+		fail("openai_compat", fmt.Errorf("openai_compat is only supported for anthropic, gemini and codex channels"))
+	}
+	if v, err := util.NormalizeRegionTags(cr.Regions); err != nil {
+		fail("regions", err)
+	} else {
+		cr.Regions = v
+	}
+	if v, err := util.NormalizeBetaFeatures(cr.BetaFeatures); err != nil {
+		fail("beta_features", err)
+	} else if v != "" && util.NormalizeChannelType(cr.ChannelType) != util.ChannelTypeAnthropic {
+		fail("beta_features", fmt.Errorf("beta_features is only supported for anthropic channels"))
+	} else {
+		cr.BetaFeatures = v
+	}
+
+	return issues
 }
 
 // ToConfig 转换为Config结构(不包含API Key,API Key单独处理)
@@ -135,9 +231,22 @@ func (cr *ChannelRequest) ToConfig() *model.Config {
 		ChannelType:    strings.TrimSpace(cr.ChannelType), // 传递渠道类型
 		URL:            strings.TrimSpace(cr.URL),
 		Priority:       cr.Priority,
+		Weight:         cr.Weight,
 		ModelEntries:   normalizedModels,
 		Enabled:        cr.Enabled,
 		DailyCostLimit: cr.DailyCostLimit,
+		CostMultiplier: cr.CostMultiplier,
+		ClientProfile:  cr.ClientProfile,
+		CertPins:       cr.CertPins,
+		LocalAddr:      cr.LocalAddr,
+
+		RequestCompression: cr.RequestCompression,
+		AcceptEncoding:     cr.AcceptEncoding,
+		AnthropicCompat:    cr.AnthropicCompat,
+		OpenAICompat:       cr.OpenAICompat,
+		IdempotencyKeys:    cr.IdempotencyKeys,
+		Regions:            cr.Regions,
+		BetaFeatures:       cr.BetaFeatures,
 	}
 }
 
@@ -173,6 +282,171 @@ type ChannelImportSummary struct {
 	RedisSyncedChannels int    `json:"redis_synced_channels,omitempty"` // 成功同步到Redis的渠道数量
 }
 
+// ChannelCloneRequest 渠道克隆请求（POST /admin/channels/:id/clone，请求体可省略）
+type ChannelCloneRequest struct {
+	NameSuffix *string `json:"name_suffix"` // 名称后缀（省略时为 " (copy)"，空字符串表示不加后缀）
+	Disabled   bool    `json:"disabled"`    // 克隆后保持禁用（默认沿用源渠道启用状态）
+}
+
+// Suffix 返回克隆渠道的名称后缀
+func (r *ChannelCloneRequest) Suffix() string {
+	if r.NameSuffix == nil {
+		return " (copy)"
+	}
+	return *r.NameSuffix
+}
+
+// RoutingScheduleRequest 分时路由规则创建/更新请求（/admin/routing-schedules）
+type RoutingScheduleRequest struct {
+	ChannelID        int64    `json:"channel_id" binding:"required"`
+	Description      string   `json:"description"`
+	Cron             string   `json:"cron" binding:"required"` // 5段cron表达式，描述生效窗口
+	Timezone         string   `json:"timezone"`                // IANA时区，空表示服务器时区
+	PriorityDelta    int      `json:"priority_delta"`
+	WeightMultiplier *float64 `json:"weight_multiplier"` // 省略时为1
+	Enabled          *bool    `json:"enabled"`           // 省略时为true
+}
+
+// ToRoutingSchedule 转换为规则模型（应用默认值）
+func (r *RoutingScheduleRequest) ToRoutingSchedule() *model.RoutingSchedule {
+	rule := &model.RoutingSchedule{
+		ChannelID:        r.ChannelID,
+		Description:      strings.TrimSpace(r.Description),
+		Cron:             strings.Join(strings.Fields(r.Cron), " "),
+		Timezone:         normalizeTimezone(r.Timezone),
+		PriorityDelta:    r.PriorityDelta,
+		WeightMultiplier: 1,
+		Enabled:          true,
+	}
+	if r.WeightMultiplier != nil {
+		rule.WeightMultiplier = *r.WeightMultiplier
+	}
+	if r.Enabled != nil {
+		rule.Enabled = *r.Enabled
+	}
+	return rule
+}
+
+// ModelAliasRequest 全局模型别名创建/更新请求（/admin/model-aliases）
+type ModelAliasRequest struct {
+	Alias       string `json:"alias" binding:"required"`
+	Target      string `json:"target" binding:"required"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled"` // 省略时为true
+}
+
+// ToModelAlias 转换为别名模型（应用默认值）
+func (r *ModelAliasRequest) ToModelAlias() *model.ModelAlias {
+	a := &model.ModelAlias{
+		Alias:       strings.TrimSpace(r.Alias),
+		Target:      strings.TrimSpace(r.Target),
+		Description: strings.TrimSpace(r.Description),
+		Enabled:     true,
+	}
+	if r.Enabled != nil {
+		a.Enabled = *r.Enabled
+	}
+	return a
+}
+
+// TestPromptRequest 渠道测试提示词创建/更新请求（/admin/test-prompts）
+type TestPromptRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Content     string `json:"content" binding:"required"`
+	Description string `json:"description"`
+	MaxTokens   int    `json:"max_tokens"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+// ToTestPrompt 转换为测试提示词模型
+func (r *TestPromptRequest) ToTestPrompt() *model.TestPrompt {
+	return &model.TestPrompt{
+		Name:        strings.TrimSpace(r.Name),
+		Content:     r.Content,
+		Description: strings.TrimSpace(r.Description),
+		MaxTokens:   r.MaxTokens,
+		IsDefault:   r.IsDefault,
+	}
+}
+
+// MonthlyBudgetRequest 月度预算创建/更新请求（/admin/budgets）
+type MonthlyBudgetRequest struct {
+	Scope    string  `json:"scope" binding:"required"` // token / channel
+	TargetID int64   `json:"target_id"`
+	LimitUSD float64 `json:"limit_usd"`
+}
+
+// ToMonthlyBudget 转换为月度预算模型
+func (r *MonthlyBudgetRequest) ToMonthlyBudget() *model.MonthlyBudget {
+	return &model.MonthlyBudget{
+		Scope:    strings.ToLower(strings.TrimSpace(r.Scope)),
+		TargetID: r.TargetID,
+		LimitUSD: r.LimitUSD,
+	}
+}
+
+// ModelPriceRequest 价格表条目创建/更新请求（/admin/pricing），价格单位：美元/百万tokens
+// effective_from 支持 YYYY-MM-DD（服务器时区当天零点）或 RFC3339；为空表示始终生效（覆盖全部历史）
+type ModelPriceRequest struct {
+	Model             string  `json:"model" binding:"required"`
+	InputPrice        float64 `json:"input_price"`
+	OutputPrice       float64 `json:"output_price"`
+	CacheReadPrice    float64 `json:"cache_read_price"`
+	CacheWritePrice   float64 `json:"cache_write_price"`
+	CacheWrite1hPrice float64 `json:"cache_write_1h_price"`
+	EffectiveFrom     string  `json:"effective_from"`
+	Description       string  `json:"description"`
+}
+
+// ToModelPrice 转换为价格表条目
+func (r *ModelPriceRequest) ToModelPrice() (*model.ModelPrice, error) {
+	p := &model.ModelPrice{
+		Model:             strings.TrimSpace(r.Model),
+		InputPrice:        r.InputPrice,
+		OutputPrice:       r.OutputPrice,
+		CacheReadPrice:    r.CacheReadPrice,
+		CacheWritePrice:   r.CacheWritePrice,
+		CacheWrite1hPrice: r.CacheWrite1hPrice,
+		Description:       strings.TrimSpace(r.Description),
+	}
+	if strings.TrimSpace(r.EffectiveFrom) != "" {
+		t, err := parseAdminTime(r.EffectiveFrom, false)
+		if err != nil {
+			return nil, err
+		}
+		p.EffectiveFrom = t.UnixMilli()
+	}
+	return p, nil
+}
+
+// WebhookRequest Webhook端点创建/更新请求（/admin/webhooks）
+type WebhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url" binding:"required"`
+	Secret  *string  `json:"secret"`  // HMAC签名密钥；省略时保持不变，空字符串表示不签名
+	Events  []string `json:"events"`  // 订阅的事件类型，空表示全部
+	Enabled *bool    `json:"enabled"` // 省略时为true
+}
+
+// GeminiProvisionerRequest Gemini Key自动供给配置创建/更新请求（/admin/gemini-provisioners）
+type GeminiProvisionerRequest struct {
+	ChannelID           int64  `json:"channel_id"`            // 创建时必填，更新时忽略
+	ProjectID           string `json:"project_id"`            // 省略时使用服务账号所属项目
+	Credentials         string `json:"credentials"`           // 服务账号JSON；更新时为空表示保持不变
+	KeyCount            *int   `json:"key_count"`             // 省略时为1
+	RotateIntervalHours int    `json:"rotate_interval_hours"` // 0=不轮换
+	Enabled             *bool  `json:"enabled"`               // 省略时为true
+}
+
+// CacheWarmupRequest 提示缓存预热计划创建/更新请求（/admin/cache-warmups）
+type CacheWarmupRequest struct {
+	ChannelID       int64  `json:"channel_id"`       // 创建时必填，更新时忽略
+	Model           string `json:"model"`            // 省略时使用渠道首个非通配符模型
+	Prompt          string `json:"prompt"`           // 预热的系统提示
+	IntervalSeconds *int   `json:"interval_seconds"` // 省略时为270秒（略短于5分钟缓存TTL）
+	Enabled         *bool  `json:"enabled"`          // 省略时为true
+}
+
 // CooldownRequest 冷却设置请求
 type CooldownRequest struct {
 	DurationMs int64 `json:"duration_ms" binding:"required,min=1000"` // 最少1秒
@@ -182,3 +456,118 @@ type CooldownRequest struct {
 type SettingUpdateRequest struct {
 	Value string `json:"value" binding:"required"`
 }
+
+// HealthComponent 健康检查组件状态（/health?detail=1）
+type HealthComponent struct {
+	Status  string         `json:"status"` // ok | degraded | error | disabled
+	Latency float64        `json:"latency_ms,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// HealthDetailResponse 详细健康检查响应
+type HealthDetailResponse struct {
+	Status     string                     `json:"status"` // ok | degraded | error
+	Components map[string]HealthComponent `json:"components"`
+}
+
+// TokenIntrospectRequest 令牌自省请求
+type TokenIntrospectRequest struct {
+	Token string `json:"token" binding:"required"` // 令牌明文
+}
+
+// TokenIntrospection 令牌自省结果（供外部网关做鉴权决策）
+type TokenIntrospection struct {
+	Active            bool     `json:"active"`                       // 令牌是否可用（存在、启用、未过期）
+	Reason            string   `json:"reason,omitempty"`             // 不可用原因: not_found | disabled | expired
+	ID                int64    `json:"id,omitempty"`                 // 令牌ID（仅管理员接口返回）
+	Description       string   `json:"description,omitempty"`        // 令牌描述（仅管理员接口返回）
+	ExpiresAt         *int64   `json:"expires_at,omitempty"`         // 过期时间(Unix毫秒)
+	AllowedModels     []string `json:"allowed_models"`               // 允许的模型（空数组表示无限制）
+	CostUsedUSD       float64  `json:"cost_used_usd"`                // 已消耗费用
+	CostLimitUSD      float64  `json:"cost_limit_usd"`               // 费用上限（0=无限制）
+	CostRemainingUSD  *float64 `json:"cost_remaining_usd,omitempty"` // 剩余额度（无限制时省略）
+	CostLimitExceeded bool     `json:"cost_limit_exceeded"`          // 是否已超出费用上限
+	BlockedModels     []string `json:"blocked_models"`               // 生效的屏蔽模型（全局 + 令牌，支持*通配符；空数组表示不屏蔽）
+	RPMLimit          int      `json:"rpm_limit"`                    // 每分钟请求数上限（0=无限制）
+	TPMLimit          int64    `json:"tpm_limit"`                    // 每分钟Token数上限（0=无限制）
+	RPMUsed           int64    `json:"rpm_used"`                     // 当前滑动窗口内的请求数（本实例内存计数）
+	TPMUsed           int64    `json:"tpm_used"`                     // 当前滑动窗口内的Token数（本实例内存计数）
+}
+
+// KeyTestResult 单个Key的测试结果（/admin/channels/:id/test-all-keys）
+type KeyTestResult struct {
+	KeyIndex       int    `json:"key_index"`
+	Success        bool   `json:"success"`
+	StatusCode     int    `json:"status_code"`               // 0 表示网络错误（未收到响应）
+	DurationMs     int64  `json:"duration_ms"`               // 请求耗时
+	ErrorClass     string `json:"error_class,omitempty"`     // 错误分类: network | key | channel | client
+	Error          string `json:"error,omitempty"`           // 错误信息
+	CooldownAction string `json:"cooldown_action,omitempty"` // 冷却决策（与单Key测试一致）
+}
+
+// KeyTestSummary 多Key并行测试汇总
+type KeyTestSummary struct {
+	Total     int             `json:"total"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Results   []KeyTestResult `json:"results"` // 按 key_index 升序
+}
+
+// ModelPatternMatch 渠道模型匹配预览条目（/admin/channels/:id/models/preview）
+type ModelPatternMatch struct {
+	Model         string `json:"model"`                    // 请求中出现的具体模型名
+	MatchedBy     string `json:"matched_by"`               // 命中的渠道模型条目（精确名或通配符）
+	Wildcard      bool   `json:"wildcard"`                 // 是否由通配符命中
+	RedirectModel string `json:"redirect_model,omitempty"` // 重定向目标（无重定向时省略）
+}
+
+// TokenAnomalyReport 输出Token异常日报（/admin/token-anomalies）
+type TokenAnomalyReport struct {
+	Date              string               `json:"date"`
+	Multiplier        int                  `json:"multiplier"`          // 判定倍数
+	MinOutputTokens   int64                `json:"min_output_tokens"`   // 判定绝对下限
+	TotalOutputTokens int64                `json:"total_output_tokens"` // 异常请求输出Token合计
+	TotalCost         float64              `json:"total_cost"`          // 异常请求成本合计（美元）
+	Anomalies         []model.TokenAnomaly `json:"anomalies"`           // 按输出Token降序
+}
+
+// CostRecomputeRequest 历史费用重算请求（POST /admin/pricing/recompute）
+// since/until 支持 YYYY-MM-DD（服务器时区，until 当天包含在内）或 RFC3339；until 为空表示当前时间
+type CostRecomputeRequest struct {
+	Since string `json:"since" binding:"required"`
+	Until string `json:"until"`
+}
+
+// parseAdminTime 解析 YYYY-MM-DD（服务器时区）或 RFC3339 时间；endOfDay 时日期取次日零点
+func parseAdminTime(v string, endOfDay bool) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected YYYY-MM-DD or RFC3339", v)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// TimeRange 解析重算时间范围 [since, until)
+func (r *CostRecomputeRequest) TimeRange(now time.Time) (since, until time.Time, err error) {
+	if since, err = parseAdminTime(r.Since, false); err != nil {
+		return
+	}
+	until = now
+	if strings.TrimSpace(r.Until) != "" {
+		if until, err = parseAdminTime(r.Until, true); err != nil {
+			return
+		}
+	}
+	if !since.Before(until) {
+		err = fmt.Errorf("since must be before until")
+	}
+	return
+}
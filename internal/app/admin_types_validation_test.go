@@ -259,3 +259,21 @@ func TestChannelRequestValidation_Combined(t *testing.T) {
 		})
 	}
 }
+
+func TestChannelRequest_Validate_AnthropicCompat(t *testing.T) {
+	req := newValidChannelRequest()
+	req.AnthropicCompat = true
+	if err := req.Validate(); err == nil || !strings.Contains(err.Error(), "anthropic_compat") {
+		t.Fatalf("非gemini渠道开启 anthropic_compat 应报错, got %v", err)
+	}
+
+	req = newValidChannelRequest()
+	req.ChannelType = "Gemini"
+	req.AnthropicCompat = true
+	if err := req.Validate(); err != nil {
+		t.Fatalf("gemini渠道应允许 anthropic_compat: %v", err)
+	}
+	if cfg := req.ToConfig(); !cfg.AnthropicCompat {
+		t.Fatal("ToConfig 应保留 anthropic_compat")
+	}
+}
@@ -0,0 +1,953 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// Anthropic ⇄ Gemini 协议转换（2026-10新增）
+// ============================================================================
+// 开启 anthropic_compat 的 gemini 渠道可服务 Anthropic POST /v1/messages 请求：
+//   - 请求：messages/system/tools/tool_choice/采样参数 → generateContent 请求体，
+//     路径改写为 /v1beta/models/{model}:generateContent（流式为 :streamGenerateContent?alt=sse）
+//   - 响应：非流式整体转换为 Anthropic message；流式逐个 candidate 转换为
+//     message_start / content_block_* / message_delta / message_stop 事件
+//   - 错误：上游 Gemini 错误体转换为 Anthropic error 格式
+//   - 安全拦截：stop_reason 为 refusal，拦截原因/安全评级放在扩展字段 gemini 中
+//     （非流式在 message 上，流式在 message_delta 事件上）
+//   - 多候选：非流式请求携带 X-CCLoad-Gemini-Candidates: N（2-8）时请求 N 个候选，
+//     首个候选照常转换，其余候选放在扩展字段 gemini.candidates 中
+// usage 统计仍基于上游原始字节（gemini 解析器），转换只作用于写回客户端的数据。
+
+const (
+	anthropicMessagesPath = "/v1/messages"
+
+	// headerCCLoadGeminiCandidates 请求 Gemini 返回多个候选（仅非流式，X-CCLoad-* 头不透传上游）
+	headerCCLoadGeminiCandidates = "X-CCLoad-Gemini-Candidates"
+	geminiMaxCandidates          = 8
+)
+
+// anthropicBridgeEnabled 判断本次渠道尝试是否需要 Anthropic → Gemini 转换
+func anthropicBridgeEnabled(cfg *model.Config, reqCtx *proxyRequestContext) bool {
+	return cfg.AnthropicCompat &&
+		cfg.GetChannelType() == util.ChannelTypeGemini &&
+		isAnthropicMessagesRequest(reqCtx.requestMethod, reqCtx.requestPath)
+}
+
+// geminiCandidateCount 解析客户端请求的候选数（流式、缺省或非法值返回 0，即不设置 candidateCount）
+func geminiCandidateCount(h http.Header, stream bool) int {
+	n, err := strconv.Atoi(strings.TrimSpace(h.Get(headerCCLoadGeminiCandidates)))
+	if stream || err != nil || n < 2 {
+		return 0
+	}
+	return min(n, geminiMaxCandidates)
+}
+
+// isAnthropicMessagesRequest 仅 POST /v1/messages 可被转换（count_tokens/batches 等子路径不支持）
+func isAnthropicMessagesRequest(method, path string) bool {
+	return method == http.MethodPost && path == anthropicMessagesPath
+}
+
+// ---------------------------------------------------------------------------
+// 请求转换
+// ---------------------------------------------------------------------------
+
+type anthropicMessagesRequest struct {
+	System        json.RawMessage    `json:"system"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature"`
+	TopP          *float64           `json:"top_p"`
+	TopK          *int               `json:"top_k"`
+	StopSequences []string           `json:"stop_sequences"`
+	Stream        bool               `json:"stream"`
+	Tools         []anthropicTool    `json:"tools"`
+	ToolChoice    *struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"tool_choice"`
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // string 或 content block 数组
+}
+
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	// image / document / search_result
+	Source *anthropicBlockSource `json:"source"`
+	Title  string                `json:"title"`
+	// tool_use
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+	// tool_result
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+// anthropicBlockSource image/document 块的 source 对象（2026-10调整）
+// search_result 块的 source 是字符串（来源URL或标识），解析到 URL；按对象解析会使整个请求转换失败
+type anthropicBlockSource struct {
+	Type      string          `json:"type"` // base64 / url / text / content
+	MediaType string          `json:"media_type"`
+	Data      string          `json:"data"`
+	URL       string          `json:"url"`
+	Content   json.RawMessage `json:"content"` // document 的 content 来源：文本块数组
+}
+
+func (s *anthropicBlockSource) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		return json.Unmarshal(trimmed, &s.URL)
+	}
+	type plain anthropicBlockSource
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type geminiGenerateRequest struct {
+	Contents          []geminiContent `json:"contents"`
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Tools             []struct {
+		FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+	} `json:"tools,omitempty"`
+	ToolConfig *struct {
+		FunctionCallingConfig struct {
+			Mode                 string   `json:"mode"`
+			AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+		} `json:"functionCallingConfig"`
+	} `json:"toolConfig,omitempty"`
+	GenerationConfig map[string]any `json:"generationConfig,omitempty"`
+}
+
+// parseAnthropicBlocks 解析 content 字段（字符串视为单个 text 块）
+func parseAnthropicBlocks(raw json.RawMessage) ([]anthropicContentBlock, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+	if trimmed[0] == '"' {
+		var text string
+		if err := sonic.Unmarshal(trimmed, &text); err != nil {
+			return nil, err
+		}
+		return []anthropicContentBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []anthropicContentBlock
+	if err := sonic.Unmarshal(trimmed, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// anthropicToolResultText 提取 tool_result 的文本内容（字符串或内容块数组，search_result 等块按 anthropicBlockText 展开）
+func anthropicToolResultText(raw json.RawMessage) string {
+	blocks, err := parseAnthropicBlocks(raw)
+	if err != nil {
+		return string(raw)
+	}
+	var sb strings.Builder
+	for _, b := range blocks {
+		if text := anthropicBlockText(b); text != "" {
+			if sb.Len() > 0 {
+				sb.WriteByte('\n')
+			}
+			sb.WriteString(text)
+		}
+	}
+	return sb.String()
+}
+
+// anthropicBlockText 可降级为纯文本的内容块（2026-10新增）：转换到不支持该块类型的协议时保留其文本，
+// 而不是丢弃或使转换失败。text 原样；search_result 为标题、来源与正文；document 仅文本来源（text/content）。
+// 其余块（thinking、server_tool_use、web_search_tool_result 等上游专有/服务端工具块）返回空
+func anthropicBlockText(b anthropicContentBlock) string {
+	switch b.Type {
+	case "text":
+		return b.Text
+	case "search_result":
+		var sb strings.Builder
+		if b.Title != "" {
+			sb.WriteString(b.Title)
+			sb.WriteByte('\n')
+		}
+		if b.Source != nil && b.Source.URL != "" {
+			sb.WriteString("Source: " + b.Source.URL + "\n")
+		}
+		sb.WriteString(anthropicToolResultText(b.Content))
+		return strings.TrimRight(sb.String(), "\n")
+	case "document":
+		if b.Source == nil {
+			return ""
+		}
+		var body string
+		switch b.Source.Type {
+		case "text":
+			body = b.Source.Data
+		case "content":
+			body = anthropicToolResultText(b.Source.Content)
+		}
+		if body != "" && b.Title != "" {
+			return b.Title + "\n" + body
+		}
+		return body
+	}
+	return ""
+}
+
+// geminiUnsupportedSchemaKeys Gemini functionDeclarations.parameters 不接受的 JSON Schema 关键字
+var geminiUnsupportedSchemaKeys = []string{"$schema", "$id", "additionalProperties", "default", "examples"}
+
+// sanitizeGeminiSchema 递归移除 Gemini 不支持的 Schema 关键字（原地修改）
+func sanitizeGeminiSchema(v any) {
+	switch node := v.(type) {
+	case map[string]any:
+		for _, k := range geminiUnsupportedSchemaKeys {
+			delete(node, k)
+		}
+		for _, child := range node {
+			sanitizeGeminiSchema(child)
+		}
+	case []any:
+		for _, child := range node {
+			sanitizeGeminiSchema(child)
+		}
+	}
+}
+
+// convertAnthropicToGemini 将 Anthropic Messages 请求体转换为 Gemini generateContent 请求体
+// h 为客户端请求头（X-CCLoad-Gemini-Candidates 仅对非流式生效）
+// 返回转换后的请求体与客户端是否要求流式
+func convertAnthropicToGemini(body []byte, h http.Header) ([]byte, bool, error) {
+	var req anthropicMessagesRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, false, fmt.Errorf("invalid anthropic request: %w", err)
+	}
+	if len(req.Messages) == 0 {
+		return nil, false, fmt.Errorf("invalid anthropic request: messages is empty")
+	}
+
+	out := geminiGenerateRequest{Contents: make([]geminiContent, 0, len(req.Messages))}
+
+	// system：字符串或 text 块数组 → systemInstruction
+	systemBlocks, err := parseAnthropicBlocks(req.System)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid anthropic system: %w", err)
+	}
+	for _, b := range systemBlocks {
+		if b.Type == "text" && b.Text != "" {
+			if out.SystemInstruction == nil {
+				out.SystemInstruction = &geminiContent{}
+			}
+			out.SystemInstruction.Parts = append(out.SystemInstruction.Parts, geminiPart{Text: b.Text})
+		}
+	}
+
+	// tool_use.id → 函数名（functionResponse 需要函数名，tool_result 只携带 tool_use_id）
+	toolNames := make(map[string]string)
+	for i, msg := range req.Messages {
+		blocks, err := parseAnthropicBlocks(msg.Content)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid anthropic messages[%d].content: %w", i, err)
+		}
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "model"
+		}
+		content := geminiContent{Role: role}
+		for _, b := range blocks {
+			switch b.Type {
+			case "text":
+				if b.Text != "" {
+					content.Parts = append(content.Parts, geminiPart{Text: b.Text})
+				}
+			case "image":
+				if b.Source == nil {
+					continue
+				}
+				if b.Source.Type == "url" {
+					content.Parts = append(content.Parts, geminiPart{FileData: &geminiFileData{MimeType: b.Source.MediaType, FileURI: b.Source.URL}})
+				} else {
+					content.Parts = append(content.Parts, geminiPart{InlineData: &geminiBlob{MimeType: b.Source.MediaType, Data: b.Source.Data}})
+				}
+			case "document":
+				// PDF 等二进制文档按内联/文件数据传递，文本来源降级为文本
+				if b.Source != nil && b.Source.Type == "url" {
+					content.Parts = append(content.Parts, geminiPart{FileData: &geminiFileData{MimeType: cmp.Or(b.Source.MediaType, "application/pdf"), FileURI: b.Source.URL}})
+				} else if b.Source != nil && b.Source.Type == "base64" {
+					content.Parts = append(content.Parts, geminiPart{InlineData: &geminiBlob{MimeType: b.Source.MediaType, Data: b.Source.Data}})
+				} else if text := anthropicBlockText(b); text != "" {
+					content.Parts = append(content.Parts, geminiPart{Text: text})
+				}
+			case "search_result":
+				if text := anthropicBlockText(b); text != "" {
+					content.Parts = append(content.Parts, geminiPart{Text: text})
+				}
+			case "tool_use":
+				toolNames[b.ID] = b.Name
+				var args map[string]any
+				if len(b.Input) > 0 {
+					_ = sonic.Unmarshal(b.Input, &args)
+				}
+				content.Parts = append(content.Parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: b.Name, Args: args}})
+			case "tool_result":
+				result := map[string]any{"content": anthropicToolResultText(b.Content)}
+				if b.IsError {
+					result = map[string]any{"error": result["content"]}
+				}
+				content.Parts = append(content.Parts, geminiPart{FunctionResponse: &geminiFunctionResponse{
+					Name:     toolNames[b.ToolUseID],
+					Response: result,
+				}})
+			}
+			// thinking/redacted_thinking、server_tool_use/web_search_tool_result 等 Anthropic 专有块无法回放给 Gemini，直接丢弃
+		}
+		if len(content.Parts) > 0 {
+			out.Contents = append(out.Contents, content)
+		}
+	}
+
+	if len(req.Tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			if t.Name == "" {
+				continue // 服务端工具（如 web_search）无函数定义
+			}
+			sanitizeGeminiSchema(t.InputSchema)
+			decls = append(decls, geminiFunctionDeclaration{Name: t.Name, Description: t.Description, Parameters: t.InputSchema})
+		}
+		if len(decls) > 0 {
+			out.Tools = append(out.Tools, struct {
+				FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+			}{FunctionDeclarations: decls})
+		}
+	}
+	if req.ToolChoice != nil && len(out.Tools) > 0 {
+		mode := ""
+		var allowed []string
+		switch req.ToolChoice.Type {
+		case "auto":
+			mode = "AUTO"
+		case "any":
+			mode = "ANY"
+		case "tool":
+			mode = "ANY"
+			allowed = []string{req.ToolChoice.Name}
+		case "none":
+			mode = "NONE"
+		}
+		if mode != "" {
+			out.ToolConfig = &struct {
+				FunctionCallingConfig struct {
+					Mode                 string   `json:"mode"`
+					AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+				} `json:"functionCallingConfig"`
+			}{}
+			out.ToolConfig.FunctionCallingConfig.Mode = mode
+			out.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = allowed
+		}
+	}
+
+	gen := map[string]any{}
+	if req.MaxTokens > 0 {
+		gen["maxOutputTokens"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		gen["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		gen["topP"] = *req.TopP
+	}
+	if req.TopK != nil {
+		gen["topK"] = *req.TopK
+	}
+	if len(req.StopSequences) > 0 {
+		gen["stopSequences"] = req.StopSequences
+	}
+	if n := geminiCandidateCount(h, req.Stream); n > 0 {
+		gen["candidateCount"] = n
+	}
+	if len(gen) > 0 {
+		out.GenerationConfig = gen
+	}
+
+	converted, err := sonic.Marshal(out)
+	if err != nil {
+		return nil, false, err
+	}
+	return converted, req.Stream, nil
+}
+
+// geminiBridgePath 转换后的上游路径与查询参数
+func geminiBridgePath(actualModel string, stream bool) (path, rawQuery string) {
+	if stream {
+		return "/v1beta/models/" + actualModel + ":streamGenerateContent", "alt=sse"
+	}
+	return "/v1beta/models/" + actualModel + ":generateContent", ""
+}
+
+// geminiBridgeHeader 复制客户端请求头并剔除 Anthropic 专有头（anthropic-version/anthropic-beta 等）
+func geminiBridgeHeader(src http.Header) http.Header {
+	hdr := src.Clone()
+	for k := range hdr {
+		if strings.HasPrefix(strings.ToLower(k), "anthropic-") {
+			hdr.Del(k)
+		}
+	}
+	hdr.Set("Content-Type", "application/json")
+	return hdr
+}
+
+// ---------------------------------------------------------------------------
+// 响应转换
+// ---------------------------------------------------------------------------
+
+type geminiGenerateResponse struct {
+	Candidates     []geminiCandidate     `json:"candidates"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback"`
+	UsageMetadata  *geminiUsageMetadata  `json:"usageMetadata"`
+	Error          *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type geminiCandidate struct {
+	Index   int `json:"index"`
+	Content struct {
+		Parts []geminiPart `json:"parts"`
+	} `json:"content"`
+	FinishReason  string               `json:"finishReason"`
+	FinishMessage string               `json:"finishMessage"`
+	SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// geminiPromptFeedback 提示词被拦截时 candidates 为空，原因在 blockReason
+type geminiPromptFeedback struct {
+	BlockReason        string               `json:"blockReason"`
+	BlockReasonMessage string               `json:"blockReasonMessage"`
+	SafetyRatings      []geminiSafetyRating `json:"safetyRatings"`
+}
+
+// geminiSafetyInfo 安全拦截信息（提示词拦截 + 首个候选的安全类结束原因）
+type geminiSafetyInfo struct {
+	prompt        *geminiPromptFeedback
+	finishReason  string
+	finishMessage string
+	ratings       []geminiSafetyRating
+}
+
+// observe 记录响应块中的安全信息（流式逐块调用，保留最后一次出现的值）
+func (si *geminiSafetyInfo) observe(resp *geminiGenerateResponse) {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		si.prompt = resp.PromptFeedback
+	}
+	if len(resp.Candidates) == 0 {
+		return
+	}
+	cand := &resp.Candidates[0]
+	if cand.FinishReason != "" {
+		si.finishReason, si.finishMessage = cand.FinishReason, cand.FinishMessage
+	}
+	if len(cand.SafetyRatings) > 0 {
+		si.ratings = cand.SafetyRatings
+	}
+}
+
+// blocked 提示词或输出是否被安全策略拦截
+func (si *geminiSafetyInfo) blocked() bool {
+	return si.prompt != nil || isGeminiSafetyFinish(si.finishReason)
+}
+
+// extension 构造扩展字段 gemini（未拦截且无额外候选时返回 nil）
+func (si *geminiSafetyInfo) extension(extra []map[string]any) map[string]any {
+	if !si.blocked() && len(extra) == 0 {
+		return nil
+	}
+	ext := map[string]any{}
+	if si.prompt != nil {
+		ext["block_reason"] = si.prompt.BlockReason
+		if si.prompt.BlockReasonMessage != "" {
+			ext["block_reason_message"] = si.prompt.BlockReasonMessage
+		}
+		if len(si.prompt.SafetyRatings) > 0 {
+			ext["prompt_safety_ratings"] = si.prompt.SafetyRatings
+		}
+	}
+	if isGeminiSafetyFinish(si.finishReason) {
+		ext["finish_reason"] = si.finishReason
+		if si.finishMessage != "" {
+			ext["finish_message"] = si.finishMessage
+		}
+		if len(si.ratings) > 0 {
+			ext["safety_ratings"] = si.ratings
+		}
+	}
+	if len(extra) > 0 {
+		ext["candidates"] = extra
+	}
+	return ext
+}
+
+// stopReason 安全拦截优先映射为 refusal
+func (si *geminiSafetyInfo) stopReason(hasToolUse bool) string {
+	if si.prompt != nil {
+		return "refusal"
+	}
+	return anthropicStopReason(si.finishReason, hasToolUse)
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int64 `json:"promptTokenCount"`
+	CandidatesTokenCount    int64 `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int64 `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int64 `json:"cachedContentTokenCount"`
+}
+
+// anthropicUsage Gemini usageMetadata → Anthropic usage
+// promptTokenCount 含缓存命中部分，Anthropic 的 input_tokens 不含 cache_read，需扣除
+func anthropicUsage(u *geminiUsageMetadata) map[string]any {
+	if u == nil {
+		return map[string]any{"input_tokens": 0, "output_tokens": 0}
+	}
+	return map[string]any{
+		"input_tokens":            max(u.PromptTokenCount-u.CachedContentTokenCount, 0),
+		"output_tokens":           u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		"cache_read_input_tokens": u.CachedContentTokenCount,
+	}
+}
+
+// isGeminiSafetyFinish 结束原因是否属于安全/合规拦截
+func isGeminiSafetyFinish(finishReason string) bool {
+	switch finishReason {
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return true
+	}
+	return false
+}
+
+// anthropicStopReason Gemini finishReason → Anthropic stop_reason
+func anthropicStopReason(finishReason string, hasToolUse bool) string {
+	if finishReason == "MAX_TOKENS" {
+		return "max_tokens"
+	}
+	if isGeminiSafetyFinish(finishReason) {
+		return "refusal"
+	}
+	if hasToolUse {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+// newAnthropicID 生成 Anthropic 风格的ID（msg_/toolu_ 前缀）
+func newAnthropicID(prefix string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// anthropicToolUseBlock functionCall → tool_use 块（Gemini 未返回 id 时生成）
+func anthropicToolUseBlock(fc *geminiFunctionCall) map[string]any {
+	id := fc.ID
+	if id == "" {
+		id = newAnthropicID("toolu_")
+	}
+	input := fc.Args
+	if input == nil {
+		input = map[string]any{}
+	}
+	return map[string]any{"type": "tool_use", "id": id, "name": fc.Name, "input": input}
+}
+
+// anthropicContentBlocks 候选 parts → Anthropic content 块（相邻文本合并，思考内容丢弃）
+func anthropicContentBlocks(parts []geminiPart) (content []map[string]any, hasToolUse bool) {
+	content = make([]map[string]any, 0, len(parts))
+	for i := range parts {
+		part := &parts[i]
+		switch {
+		case part.FunctionCall != nil:
+			content = append(content, anthropicToolUseBlock(part.FunctionCall))
+			hasToolUse = true
+		case part.Text != "" && !part.Thought:
+			if n := len(content); n > 0 && content[n-1]["type"] == "text" {
+				content[n-1]["text"] = content[n-1]["text"].(string) + part.Text
+			} else {
+				content = append(content, map[string]any{"type": "text", "text": part.Text})
+			}
+		}
+	}
+	return content, hasToolUse
+}
+
+// convertGeminiToAnthropic 将 Gemini 非流式响应体转换为 Anthropic message
+func convertGeminiToAnthropic(body []byte, modelName string) ([]byte, error) {
+	var resp geminiGenerateResponse
+	if err := sonic.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	content := make([]map[string]any, 0)
+	hasToolUse := false
+	if len(resp.Candidates) > 0 {
+		content, hasToolUse = anthropicContentBlocks(resp.Candidates[0].Content.Parts)
+	}
+	var safety geminiSafetyInfo
+	safety.observe(&resp)
+
+	// 多候选：其余候选按 Anthropic 内容块格式附在扩展字段中
+	var extra []map[string]any
+	for i := 1; i < len(resp.Candidates); i++ {
+		cand := &resp.Candidates[i]
+		blocks, toolUse := anthropicContentBlocks(cand.Content.Parts)
+		entry := map[string]any{
+			"index":       cand.Index,
+			"content":     blocks,
+			"stop_reason": anthropicStopReason(cand.FinishReason, toolUse),
+		}
+		if isGeminiSafetyFinish(cand.FinishReason) && len(cand.SafetyRatings) > 0 {
+			entry["safety_ratings"] = cand.SafetyRatings
+		}
+		extra = append(extra, entry)
+	}
+
+	msg := map[string]any{
+		"id":            newAnthropicID("msg_"),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         modelName,
+		"content":       content,
+		"stop_reason":   safety.stopReason(hasToolUse),
+		"stop_sequence": nil,
+		"usage":         anthropicUsage(resp.UsageMetadata),
+	}
+	if ext := safety.extension(extra); ext != nil {
+		msg["gemini"] = ext
+	}
+	return sonic.Marshal(msg)
+}
+
+// anthropicErrorType HTTP状态码 → Anthropic error.type
+func anthropicErrorType(status int) string {
+	switch {
+	case status == http.StatusBadRequest:
+		return "invalid_request_error"
+	case status == http.StatusUnauthorized:
+		return "authentication_error"
+	case status == http.StatusForbidden:
+		return "permission_error"
+	case status == http.StatusNotFound:
+		return "not_found_error"
+	case status == http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status == http.StatusServiceUnavailable || status == 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// convertGeminiErrorToAnthropic 将 Gemini 错误体（或纯文本错误）转换为 Anthropic error 格式
+func convertGeminiErrorToAnthropic(status int, body []byte) []byte {
+	msg := strings.TrimSpace(string(body))
+	var ge struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if sonic.Unmarshal(body, &ge) == nil && ge.Error != nil && ge.Error.Message != "" {
+		msg = ge.Error.Message
+	}
+	out, _ := sonic.Marshal(map[string]any{
+		"type":  "error",
+		"error": map[string]any{"type": anthropicErrorType(status), "message": msg},
+	})
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// 响应写入包装
+// ---------------------------------------------------------------------------
+
+// anthropicBridgeWriter 将写向客户端的 Gemini 响应转换为 Anthropic 格式
+// 非流式：缓存完整响应体，finish 时整体转换写出
+// 流式：按 SSE 事件增量转换，finish 时补齐 message_delta/message_stop
+type anthropicBridgeWriter struct {
+	http.ResponseWriter
+	model  string
+	stream bool
+	buf    bytes.Buffer // 非流式：完整响应体；流式：尚未成行的残余字节
+
+	// 流式状态
+	dataLines  []string
+	started    bool
+	nextIndex  int  // 下一个内容块下标
+	textOpen   bool // 当前是否有打开的 text 块
+	hasToolUse bool
+	safety     geminiSafetyInfo
+	usage      *geminiUsageMetadata
+}
+
+func newAnthropicBridgeWriter(w http.ResponseWriter, modelName string, stream bool) *anthropicBridgeWriter {
+	return &anthropicBridgeWriter{ResponseWriter: w, model: modelName, stream: stream}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter（SetWriteDeadline 等）
+func (w *anthropicBridgeWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush 透传 Flush（流式转换后的事件需立即下发）
+func (w *anthropicBridgeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *anthropicBridgeWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if !w.stream {
+		return len(p), nil
+	}
+	for {
+		data := w.buf.Bytes()
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(bytes.TrimRight(data[:idx], "\r"))
+		w.buf.Next(idx + 1)
+		if err := w.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// handleLine 逐行解析上游 SSE：累积 data 行，空行时处理一个事件
+func (w *anthropicBridgeWriter) handleLine(line string) error {
+	if after, ok := strings.CutPrefix(line, "data:"); ok {
+		w.dataLines = append(w.dataLines, strings.TrimSpace(after))
+		return nil
+	}
+	if line != "" || len(w.dataLines) == 0 {
+		return nil
+	}
+	payload := strings.Join(w.dataLines, "")
+	w.dataLines = w.dataLines[:0]
+	return w.handleChunk([]byte(payload))
+}
+
+func (w *anthropicBridgeWriter) writeEvent(event string, data map[string]any) error {
+	payload, err := sonic.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.ResponseWriter, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+func (w *anthropicBridgeWriter) ensureStarted() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	usage := anthropicUsage(w.usage)
+	usage["output_tokens"] = 0
+	return w.writeEvent("message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id":            newAnthropicID("msg_"),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         w.model,
+			"content":       []any{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         usage,
+		},
+	})
+}
+
+func (w *anthropicBridgeWriter) closeText() error {
+	if !w.textOpen {
+		return nil
+	}
+	w.textOpen = false
+	err := w.writeEvent("content_block_stop", map[string]any{"type": "content_block_stop", "index": w.nextIndex})
+	w.nextIndex++
+	return err
+}
+
+// handleChunk 将单个 Gemini 流式响应块转换为 Anthropic 事件
+func (w *anthropicBridgeWriter) handleChunk(payload []byte) error {
+	var chunk geminiGenerateResponse
+	if err := sonic.Unmarshal(payload, &chunk); err != nil {
+		return nil // 无法解析的事件直接跳过（容错）
+	}
+	if chunk.UsageMetadata != nil {
+		w.usage = chunk.UsageMetadata
+	}
+	if err := w.ensureStarted(); err != nil {
+		return err
+	}
+	if chunk.Error != nil {
+		return w.writeEvent("error", map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "api_error", "message": chunk.Error.Message},
+		})
+	}
+	w.safety.observe(&chunk)
+	if len(chunk.Candidates) == 0 {
+		return nil
+	}
+	cand := chunk.Candidates[0]
+	for i := range cand.Content.Parts {
+		part := &cand.Content.Parts[i]
+		switch {
+		case part.FunctionCall != nil:
+			if err := w.closeText(); err != nil {
+				return err
+			}
+			block := anthropicToolUseBlock(part.FunctionCall)
+			args, _ := sonic.Marshal(block["input"])
+			block["input"] = map[string]any{}
+			w.hasToolUse = true
+			if err := w.writeEvent("content_block_start", map[string]any{"type": "content_block_start", "index": w.nextIndex, "content_block": block}); err != nil {
+				return err
+			}
+			if err := w.writeEvent("content_block_delta", map[string]any{
+				"type": "content_block_delta", "index": w.nextIndex,
+				"delta": map[string]any{"type": "input_json_delta", "partial_json": string(args)},
+			}); err != nil {
+				return err
+			}
+			if err := w.writeEvent("content_block_stop", map[string]any{"type": "content_block_stop", "index": w.nextIndex}); err != nil {
+				return err
+			}
+			w.nextIndex++
+		case part.Text != "" && !part.Thought:
+			if !w.textOpen {
+				w.textOpen = true
+				if err := w.writeEvent("content_block_start", map[string]any{
+					"type": "content_block_start", "index": w.nextIndex,
+					"content_block": map[string]any{"type": "text", "text": ""},
+				}); err != nil {
+					return err
+				}
+			}
+			if err := w.writeEvent("content_block_delta", map[string]any{
+				"type": "content_block_delta", "index": w.nextIndex,
+				"delta": map[string]any{"type": "text_delta", "text": part.Text},
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return nil
+}
+
+// finishResponse 上游响应结束后收尾
+// completed=false（上游中断/失败）时流式不补发 message_stop，客户端据此识别截断
+func (w *anthropicBridgeWriter) finishResponse(completed bool) {
+	if !w.stream {
+		if w.buf.Len() == 0 {
+			return
+		}
+		out, err := convertGeminiToAnthropic(w.buf.Bytes(), w.model)
+		if err != nil {
+			out = w.buf.Bytes() // 无法解析时原样返回，避免吞掉响应
+		}
+		_, _ = w.ResponseWriter.Write(out)
+		return
+	}
+
+	if w.buf.Len() > 0 {
+		_ = w.handleLine(strings.TrimRight(w.buf.String(), "\r"))
+		w.buf.Reset()
+	}
+	_ = w.handleLine("") // 处理末尾未以空行结束的事件
+	if !completed || !w.started {
+		return
+	}
+	_ = w.closeText()
+	delta := map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": w.safety.stopReason(w.hasToolUse), "stop_sequence": nil},
+		"usage": anthropicUsage(w.usage),
+	}
+	if ext := w.safety.extension(nil); ext != nil {
+		delta["gemini"] = ext
+	}
+	_ = w.writeEvent("message_delta", delta)
+	_ = w.writeEvent("message_stop", map[string]any{"type": "message_stop"})
+	w.Flush()
+}
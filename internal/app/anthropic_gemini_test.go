@@ -0,0 +1,291 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/bytedance/sonic"
+)
+
+func TestConvertAnthropicToGemini(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-x",
+		"max_tokens": 256,
+		"temperature": 0.2,
+		"stop_sequences": ["END"],
+		"system": [{"type": "text", "text": "be brief"}],
+		"tools": [{"name": "get_weather", "description": "w", "input_schema": {"$schema": "x", "type": "object", "additionalProperties": false, "properties": {"city": {"type": "string"}}}}],
+		"tool_choice": {"type": "tool", "name": "get_weather"},
+		"messages": [
+			{"role": "user", "content": "weather in Paris?"},
+			{"role": "assistant", "content": [{"type": "thinking", "thinking": "..."}, {"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "sunny"}]}]}
+		]
+	}`)
+
+	out, stream, err := convertAnthropicToGemini(body, nil)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if stream {
+		t.Fatal("未设置stream时不应为流式")
+	}
+
+	var req geminiGenerateRequest
+	if err := sonic.Unmarshal(out, &req); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+	if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "be brief" {
+		t.Fatalf("system 应映射为 systemInstruction: %s", out)
+	}
+	if len(req.Contents) != 3 || req.Contents[1].Role != "model" || len(req.Contents[1].Parts) != 1 {
+		t.Fatalf("messages 映射不符（thinking 块应丢弃）: %s", out)
+	}
+	if fc := req.Contents[1].Parts[0].FunctionCall; fc == nil || fc.Name != "get_weather" || fc.Args["city"] != "Paris" {
+		t.Fatalf("tool_use 应映射为 functionCall: %s", out)
+	}
+	if fr := req.Contents[2].Parts[0].FunctionResponse; fr == nil || fr.Name != "get_weather" || fr.Response["content"] != "sunny" {
+		t.Fatalf("tool_result 应按 tool_use_id 映射为 functionResponse: %s", out)
+	}
+	params := req.Tools[0].FunctionDeclarations[0].Parameters
+	if _, ok := params["$schema"]; ok {
+		t.Fatalf("不支持的Schema关键字应移除: %v", params)
+	}
+	if _, ok := params["additionalProperties"]; ok {
+		t.Fatalf("不支持的Schema关键字应移除: %v", params)
+	}
+	if cfg := req.ToolConfig.FunctionCallingConfig; cfg.Mode != "ANY" || len(cfg.AllowedFunctionNames) != 1 {
+		t.Fatalf("tool_choice 映射不符: %+v", cfg)
+	}
+	if req.GenerationConfig["maxOutputTokens"] != float64(256) || req.GenerationConfig["stopSequences"] == nil {
+		t.Fatalf("generationConfig 映射不符: %v", req.GenerationConfig)
+	}
+
+	if _, _, err := convertAnthropicToGemini([]byte(`{"messages":[]}`), nil); err == nil {
+		t.Fatal("空 messages 应报错")
+	}
+}
+
+func TestConvertGeminiToAnthropic(t *testing.T) {
+	body := []byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"},{"text":"lo"},{"functionCall":{"name":"f","args":{"a":1}}}]},"finishReason":"STOP"}],
+		"usageMetadata":{"promptTokenCount":100,"candidatesTokenCount":7,"thoughtsTokenCount":3,"cachedContentTokenCount":40}}`)
+	out, err := convertGeminiToAnthropic(body, "claude-x")
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	var msg struct {
+		Type       string           `json:"type"`
+		Model      string           `json:"model"`
+		Content    []map[string]any `json:"content"`
+		StopReason string           `json:"stop_reason"`
+		Usage      map[string]int64 `json:"usage"`
+	}
+	if err := sonic.Unmarshal(out, &msg); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if msg.Type != "message" || msg.Model != "claude-x" || msg.StopReason != "tool_use" {
+		t.Fatalf("消息结构不符: %s", out)
+	}
+	if len(msg.Content) != 2 || msg.Content[0]["text"] != "Hello" || msg.Content[1]["type"] != "tool_use" {
+		t.Fatalf("content 映射不符: %s", out)
+	}
+	if msg.Usage["input_tokens"] != 60 || msg.Usage["cache_read_input_tokens"] != 40 || msg.Usage["output_tokens"] != 10 {
+		t.Fatalf("usage 映射不符: %v", msg.Usage)
+	}
+
+	errBody := convertGeminiErrorToAnthropic(http.StatusTooManyRequests, []byte(`{"error":{"code":429,"message":"quota","status":"RESOURCE_EXHAUSTED"}}`))
+	if !strings.Contains(string(errBody), `"rate_limit_error"`) || !strings.Contains(string(errBody), `"quota"`) {
+		t.Fatalf("错误体转换不符: %s", errBody)
+	}
+}
+
+func TestGeminiBridge_SafetyAndCandidates(t *testing.T) {
+	// 多候选仅非流式请求生效
+	body := []byte(`{"max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`)
+	hdr := http.Header{"X-Ccload-Gemini-Candidates": {"3"}}
+	out, _, err := convertAnthropicToGemini(body, hdr)
+	if err != nil || !strings.Contains(string(out), `"candidateCount":3`) {
+		t.Fatalf("应设置 candidateCount: %s %v", out, err)
+	}
+	out, _, _ = convertAnthropicToGemini([]byte(`{"stream":true,"messages":[{"role":"user","content":"hi"}]}`), hdr)
+	if strings.Contains(string(out), "candidateCount") {
+		t.Fatalf("流式请求不应设置 candidateCount: %s", out)
+	}
+
+	type message struct {
+		Content    []map[string]any `json:"content"`
+		StopReason string           `json:"stop_reason"`
+		Gemini     map[string]any   `json:"gemini"`
+	}
+	convert := func(raw string) message {
+		t.Helper()
+		out, err := convertGeminiToAnthropic([]byte(raw), "claude-x")
+		if err != nil {
+			t.Fatalf("转换失败: %v", err)
+		}
+		var msg message
+		if err := sonic.Unmarshal(out, &msg); err != nil {
+			t.Fatalf("解析失败: %v", err)
+		}
+		return msg
+	}
+
+	msg := convert(`{"candidates":[{"index":0,"content":{"parts":[{"text":"a"}]},"finishReason":"STOP"},{"index":1,"content":{"parts":[{"text":"b"}]},"finishReason":"STOP"}]}`)
+	extra, _ := msg.Gemini["candidates"].([]any)
+	if msg.Content[0]["text"] != "a" || len(extra) != 1 || !strings.Contains(fmt.Sprint(extra[0]), "b") {
+		t.Fatalf("其余候选应放入扩展字段: %+v", msg)
+	}
+	if _, ok := msg.Gemini["finish_reason"]; ok {
+		t.Fatalf("未拦截时不应附带安全信息: %+v", msg.Gemini)
+	}
+
+	msg = convert(`{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}}`)
+	if msg.StopReason != "refusal" || msg.Gemini["block_reason"] != "SAFETY" || msg.Gemini["prompt_safety_ratings"] == nil {
+		t.Fatalf("提示词拦截应映射为 refusal 并附带原因: %+v", msg)
+	}
+
+	if msg = convert(`{"candidates":[{"content":{"parts":[{"text":"x"}]},"finishReason":"STOP"}]}`); msg.Gemini != nil {
+		t.Fatalf("普通响应不应附带扩展字段: %+v", msg.Gemini)
+	}
+
+	// 流式：安全信息附在 message_delta 上
+	w := httptest.NewRecorder()
+	bw := newAnthropicBridgeWriter(w, "claude-x", true)
+	_, _ = io.WriteString(bw, `data: {"candidates":[{"content":{"parts":[{"text":"par"}]},"safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"LOW"}]}]}`+"\n\n")
+	_, _ = io.WriteString(bw, `data: {"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"HIGH","blocked":true}]}]}`+"\n\n")
+	bw.finishResponse(true)
+	stream := w.Body.String()
+	for _, want := range []string{`"stop_reason":"refusal"`, `"gemini":{`, `"probability":"HIGH"`, "event: message_stop"} {
+		if !strings.Contains(stream, want) {
+			t.Fatalf("流式输出缺少 %s:\n%s", want, stream)
+		}
+	}
+}
+
+func TestTryChannelWithKeys_AnthropicToGeminiStream(t *testing.T) {
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+	srv.responseBufferBytes = 0 // 本地上游一次性写完，关闭缓冲窗口避免首段读到EOF
+
+	var gotPath, gotQuery, gotKey, gotBeta string
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		gotKey, gotBeta = r.Header.Get("x-goog-api-key"), r.Header.Get("anthropic-beta")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hi\"}]}}]}\n\n")
+		_, _ = io.WriteString(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\" there\"},{\"functionCall\":{\"name\":\"f\",\"args\":{\"q\":\"x\"}}}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":12,\"candidatesTokenCount\":5}}\n\n")
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:            "gemini-compat",
+		URL:             upstream.URL,
+		ChannelType:     "gemini",
+		AnthropicCompat: true,
+		ModelEntries:    []model.ModelEntry{{Model: "claude-x", RedirectModel: "gemini-2.5-pro"}},
+		Enabled:         true,
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{ChannelID: cfg.ID, APIKey: "g-key", KeyStrategy: model.KeyStrategySequential}}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+
+	body := []byte(`{"model":"claude-x","max_tokens":64,"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	hdr := http.Header{"Anthropic-Beta": {"x"}, "Content-Type": {"application/json"}}
+	w := httptest.NewRecorder()
+	res, err := srv.tryChannelWithKeys(ctx, cfg, &proxyRequestContext{
+		originalModel: "claude-x",
+		requestMethod: http.MethodPost,
+		requestPath:   "/v1/messages",
+		body:          body,
+		header:        hdr,
+		isStreaming:   true,
+	}, w)
+	if err != nil || res == nil || !res.succeeded {
+		t.Fatalf("转发失败: res=%+v err=%v", res, err)
+	}
+
+	if gotPath != "/v1beta/models/gemini-2.5-pro:streamGenerateContent" || gotQuery != "alt=sse" {
+		t.Fatalf("上游路径不符: %s?%s", gotPath, gotQuery)
+	}
+	if gotKey != "g-key" || gotBeta != "" {
+		t.Fatalf("上游请求头不符: key=%q anthropic-beta=%q", gotKey, gotBeta)
+	}
+	if !strings.Contains(string(gotBody), `"contents"`) {
+		t.Fatalf("上游请求体应为Gemini格式: %s", gotBody)
+	}
+
+	out := w.Body.String()
+	for _, want := range []string{
+		"event: message_start", `"text_delta"`, `"text":" there"`, `"input_json_delta"`,
+		`"stop_reason":"tool_use"`, "event: message_stop",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("客户端流缺少 %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "candidates") {
+		t.Fatalf("客户端不应收到Gemini原始事件:\n%s", out)
+	}
+}
+
+func TestConvertAnthropicToGemini_SearchResultAndServerToolBlocks(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-x",
+		"max_tokens": 64,
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "search_result", "source": "https://docs.example.com/a", "title": "Doc A", "content": [{"type": "text", "text": "alpha"}], "citations": {"enabled": true}},
+				{"type": "document", "source": {"type": "base64", "media_type": "application/pdf", "data": "JVBERi0="}},
+				{"type": "document", "title": "Notes", "source": {"type": "text", "media_type": "text/plain", "data": "beta"}},
+				{"type": "text", "text": "summarize"}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "server_tool_use", "id": "srvtoolu_1", "name": "web_search", "input": {"query": "q"}},
+				{"type": "web_search_tool_result", "tool_use_id": "srvtoolu_1", "content": [{"type": "web_search_result", "url": "https://x.example", "title": "X", "encrypted_content": "..."}]},
+				{"type": "text", "text": "answer", "citations": [{"type": "search_result_location", "source": "https://docs.example.com/a", "cited_text": "alpha"}]},
+				{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {}}
+			]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": [
+				{"type": "search_result", "source": "kb://42", "title": "KB", "content": [{"type": "text", "text": "gamma"}]}
+			]}]}
+		]
+	}`)
+	out, _, err := convertAnthropicToGemini(body, nil)
+	if err != nil {
+		t.Fatalf("包含 search_result 的请求应能转换: %v", err)
+	}
+	var req geminiGenerateRequest
+	if err := sonic.Unmarshal(out, &req); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+	user := req.Contents[0].Parts
+	if len(user) != 4 || user[0].Text != "Doc A\nSource: https://docs.example.com/a\nalpha" ||
+		user[1].InlineData == nil || user[1].InlineData.MimeType != "application/pdf" || user[2].Text != "Notes\nbeta" {
+		t.Fatalf("search_result/document 映射不符: %s", out)
+	}
+	if model := req.Contents[1].Parts; len(model) != 2 || model[0].Text != "answer" || model[1].FunctionCall == nil {
+		t.Fatalf("服务端工具块应丢弃、正文保留: %s", out)
+	}
+	if fr := req.Contents[2].Parts[0].FunctionResponse; fr == nil || fr.Response["content"] != "KB\nSource: kb://42\ngamma" {
+		t.Fatalf("tool_result 中的 search_result 应展开为文本: %s", out)
+	}
+}
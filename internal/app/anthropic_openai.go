@@ -0,0 +1,690 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// Anthropic → OpenAI Chat Completions 协议转换（2026-10新增）
+// ============================================================================
+// 开启 anthropic_compat 的 openai 渠道（OpenAI 兼容上游）可服务 Anthropic POST /v1/messages 请求：
+//   - 请求：system/messages/tools/tool_choice/采样参数 → Chat Completions 请求体，路径改写为 /v1/chat/completions；
+//     流式请求附带 stream_options.include_usage，使上游在结束前返回 usage
+//   - 响应：非流式整体转换为 Anthropic message；流式逐块转换为 content_block_* 事件，
+//     tool_calls 参数增量转换为 input_json_delta
+//   - 错误：OpenAI error 格式与 Gemini 相同（error.message），复用 convertGeminiErrorToAnthropic
+//
+// 多工具交错：OpenAI 兼容上游可能交替发送多个 tool_calls[i] 的参数增量，而 Anthropic 客户端按顺序处理内容块
+// （同一时刻只有一个打开的块）。转换时当前块实时下发；其他工具调用/文本的增量先缓存，
+// 当前工具参数已是完整 JSON 时才切换到下一个块，流结束时按出现顺序补发剩余缓存。
+// usage 统计仍基于上游原始字节（openai 解析器），转换只作用于写回客户端的数据。
+
+// anthropicCompatChannelType 可开启 anthropic_compat 的渠道类型
+func anthropicCompatChannelType(channelType string) bool {
+	return channelType == util.ChannelTypeGemini || channelType == util.ChannelTypeOpenAI
+}
+
+// anthropicOpenAIBridgeEnabled 判断本次渠道尝试是否需要 Anthropic → Chat Completions 转换
+func anthropicOpenAIBridgeEnabled(cfg *model.Config, reqCtx *proxyRequestContext) bool {
+	return cfg.AnthropicCompat &&
+		cfg.GetChannelType() == util.ChannelTypeOpenAI &&
+		isAnthropicMessagesRequest(reqCtx.requestMethod, reqCtx.requestPath)
+}
+
+// ---------------------------------------------------------------------------
+// 请求转换
+// ---------------------------------------------------------------------------
+
+// openaiDataURL base64 图片 → data URL
+func openaiDataURL(mediaType, data string) string {
+	return "data:" + mediaType + ";base64," + data
+}
+
+// convertMessagesToOpenAIChat 将 Anthropic Messages 请求体转换为 Chat Completions 请求体
+// tool_result 块转换为紧随 assistant tool_calls 之后的 tool 消息；search_result/document 降级为文本或文件块；
+// thinking、server_tool_use、web_search_tool_result 等 Anthropic 专有块丢弃
+// 返回转换后的请求体与客户端是否要求流式
+func convertMessagesToOpenAIChat(body []byte, actualModel string) ([]byte, bool, error) {
+	var req anthropicMessagesRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, false, fmt.Errorf("invalid anthropic request: %w", err)
+	}
+	if len(req.Messages) == 0 {
+		return nil, false, fmt.Errorf("invalid anthropic request: messages is empty")
+	}
+
+	messages := make([]map[string]any, 0, len(req.Messages)+1)
+	systemBlocks, err := parseAnthropicBlocks(req.System)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid anthropic system: %w", err)
+	}
+	var system []string
+	for _, b := range systemBlocks {
+		if b.Type == "text" && b.Text != "" {
+			system = append(system, b.Text)
+		}
+	}
+	if len(system) > 0 {
+		messages = append(messages, map[string]any{"role": "system", "content": strings.Join(system, "\n\n")})
+	}
+
+	for i, msg := range req.Messages {
+		blocks, err := parseAnthropicBlocks(msg.Content)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid anthropic messages[%d].content: %w", i, err)
+		}
+		if msg.Role == "assistant" {
+			var text strings.Builder
+			var toolCalls []map[string]any
+			for _, b := range blocks {
+				switch b.Type {
+				case "text":
+					text.WriteString(b.Text)
+				case "tool_use":
+					args := "{}"
+					if len(bytes.TrimSpace(b.Input)) > 0 {
+						args = string(b.Input)
+					}
+					toolCalls = append(toolCalls, openaiToolCallEntry(b.ID, b.Name, args))
+				}
+			}
+			out := map[string]any{"role": "assistant", "content": text.String()}
+			if len(toolCalls) > 0 {
+				out["tool_calls"] = toolCalls
+				if text.Len() == 0 {
+					out["content"] = nil
+				}
+			}
+			messages = append(messages, out)
+			continue
+		}
+
+		// user：tool_result 先行（须紧跟上一条 assistant 的 tool_calls），其余内容合并为一条 user 消息
+		var parts []map[string]any
+		for _, b := range blocks {
+			switch b.Type {
+			case "text":
+				if b.Text != "" {
+					parts = append(parts, map[string]any{"type": "text", "text": b.Text})
+				}
+			case "image":
+				if b.Source == nil {
+					continue
+				}
+				url := b.Source.URL
+				if b.Source.Type != "url" {
+					url = openaiDataURL(b.Source.MediaType, b.Source.Data)
+				}
+				parts = append(parts, map[string]any{"type": "image_url", "image_url": map[string]any{"url": url}})
+			case "document", "search_result":
+				// base64 文档（PDF）按 file 内容块传递；search_result 与文本来源的 document 降级为文本
+				if b.Type == "document" && b.Source != nil && b.Source.Type == "base64" {
+					file := map[string]any{"file_data": openaiDataURL(b.Source.MediaType, b.Source.Data)}
+					if b.Title != "" {
+						file["filename"] = b.Title
+					}
+					parts = append(parts, map[string]any{"type": "file", "file": file})
+				} else if text := anthropicBlockText(b); text != "" {
+					parts = append(parts, map[string]any{"type": "text", "text": text})
+				}
+			case "tool_result":
+				content := anthropicToolResultText(b.Content)
+				if b.IsError && content != "" {
+					content = "Error: " + content
+				}
+				messages = append(messages, map[string]any{"role": "tool", "tool_call_id": b.ToolUseID, "content": content})
+			}
+		}
+		switch {
+		case len(parts) == 1 && parts[0]["type"] == "text":
+			messages = append(messages, map[string]any{"role": "user", "content": parts[0]["text"]})
+		case len(parts) > 0:
+			messages = append(messages, map[string]any{"role": "user", "content": parts})
+		}
+	}
+
+	out := map[string]any{"model": actualModel, "messages": messages}
+	if req.MaxTokens > 0 {
+		out["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		out["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		out["top_p"] = *req.TopP
+	}
+	if len(req.StopSequences) > 0 {
+		out["stop"] = req.StopSequences
+	}
+	if req.Stream {
+		out["stream"] = true
+		out["stream_options"] = map[string]any{"include_usage": true}
+	}
+
+	var tools []map[string]any
+	for _, t := range req.Tools {
+		if t.Name == "" {
+			continue // 服务端工具（如 web_search）无函数定义
+		}
+		fn := map[string]any{"name": t.Name, "parameters": t.InputSchema}
+		if t.Description != "" {
+			fn["description"] = t.Description
+		}
+		if t.InputSchema == nil {
+			fn["parameters"] = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, map[string]any{"type": "function", "function": fn})
+	}
+	if len(tools) > 0 {
+		out["tools"] = tools
+		if req.ToolChoice != nil {
+			switch req.ToolChoice.Type {
+			case "auto":
+				out["tool_choice"] = "auto"
+			case "any":
+				out["tool_choice"] = "required"
+			case "tool":
+				out["tool_choice"] = map[string]any{"type": "function", "function": map[string]any{"name": req.ToolChoice.Name}}
+			case "none":
+				out["tool_choice"] = "none"
+			}
+		}
+	}
+
+	converted, err := sonic.Marshal(out)
+	if err != nil {
+		return nil, false, err
+	}
+	return converted, req.Stream, nil
+}
+
+// prepareAnthropicOpenAIBridge 转换请求并包装响应写入器，返回改写后的请求上下文
+func prepareAnthropicOpenAIBridge(reqCtx *proxyRequestContext, body []byte, actualModel string, w http.ResponseWriter) (*proxyRequestContext, []byte, *anthropicOpenAIWriter, error) {
+	converted, stream, err := convertMessagesToOpenAIChat(body, actualModel)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bridged := *reqCtx
+	bridged.requestPath = openaiChatCompletionsPath
+	bridged.rawQuery = ""
+	bridged.header = geminiBridgeHeader(reqCtx.header)
+	bridged.protocolConverted = true
+	return &bridged, converted, newAnthropicOpenAIWriter(w, reqCtx.originalModel, stream), nil
+}
+
+// ---------------------------------------------------------------------------
+// 响应转换
+// ---------------------------------------------------------------------------
+
+type openaiChatUsage struct {
+	PromptTokens        int64 `json:"prompt_tokens"`
+	CompletionTokens    int64 `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int64 `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// anthropicUsageFromOpenAI Chat Completions usage → Anthropic usage（prompt_tokens 含缓存命中部分，需扣除）
+func anthropicUsageFromOpenAI(u *openaiChatUsage) map[string]any {
+	if u == nil {
+		return map[string]any{"input_tokens": 0, "output_tokens": 0}
+	}
+	var cached int64
+	if u.PromptTokensDetails != nil {
+		cached = u.PromptTokensDetails.CachedTokens
+	}
+	return map[string]any{
+		"input_tokens":            max(u.PromptTokens-cached, 0),
+		"output_tokens":           u.CompletionTokens,
+		"cache_read_input_tokens": cached,
+	}
+}
+
+// anthropicStopFromOpenAI finish_reason → Anthropic stop_reason
+func anthropicStopFromOpenAI(finish string, hasToolUse bool) string {
+	switch finish {
+	case "length":
+		return "max_tokens"
+	case "content_filter":
+		return "refusal"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	}
+	if hasToolUse {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+type openaiChatToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openaiChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   *string                   `json:"content"`
+			ToolCalls []openaiChatToolCallDelta `json:"tool_calls"`
+		} `json:"message"`
+		Delta struct {
+			Content   *string                   `json:"content"`
+			ToolCalls []openaiChatToolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openaiChatUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// convertOpenAIChatToAnthropicMessage 将 chat.completion 非流式响应体转换为 Anthropic message
+func convertOpenAIChatToAnthropicMessage(body []byte, modelName string) ([]byte, error) {
+	var resp openaiChatResponse
+	if err := sonic.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	content := make([]map[string]any, 0)
+	finish := ""
+	if len(resp.Choices) > 0 {
+		choice := &resp.Choices[0]
+		if choice.Message.Content != nil && *choice.Message.Content != "" {
+			content = append(content, map[string]any{"type": "text", "text": *choice.Message.Content})
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			id := tc.ID
+			if id == "" {
+				id = newAnthropicID("toolu_")
+			}
+			content = append(content, map[string]any{
+				"type": "tool_use", "id": id, "name": tc.Function.Name, "input": toolArgumentsJSON(tc.Function.Arguments),
+			})
+		}
+		if choice.FinishReason != nil {
+			finish = *choice.FinishReason
+		}
+	}
+	hasToolUse := len(content) > 0 && content[len(content)-1]["type"] == "tool_use"
+	return sonic.Marshal(map[string]any{
+		"id":            newAnthropicID("msg_"),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         modelName,
+		"content":       content,
+		"stop_reason":   anthropicStopFromOpenAI(finish, hasToolUse),
+		"stop_sequence": nil,
+		"usage":         anthropicUsageFromOpenAI(resp.Usage),
+	})
+}
+
+// ---------------------------------------------------------------------------
+// 响应写入包装
+// ---------------------------------------------------------------------------
+
+// openaiToolStream 单个上游工具调用的流式状态
+type openaiToolStream struct {
+	order   int // 首次出现顺序（流结束时按此顺序补发）
+	id      string
+	name    string
+	args    strings.Builder // 完整参数（用于判断是否已是完整 JSON）
+	pending strings.Builder // 尚未下发的参数增量
+	started bool            // 已下发 content_block_start
+	closed  bool            // 已下发 content_block_stop
+}
+
+// anthropicOpenAIWriter 将写向客户端的 Chat Completions 响应转换为 Anthropic 格式
+// 非流式：缓存完整响应体，finish 时整体转换写出
+// 流式：按 chunk 增量转换，收到 [DONE]（或上游正常结束）时补齐 message_delta/message_stop
+type anthropicOpenAIWriter struct {
+	http.ResponseWriter
+	model  string
+	stream bool
+	buf    bytes.Buffer // 非流式：完整响应体；流式：尚未成行的残余字节
+
+	// 流式状态
+	dataLines   []string
+	started     bool
+	done        bool
+	nextIndex   int               // 下一个内容块下标
+	textOpen    bool              // 当前打开的块是 text
+	active      *openaiToolStream // 当前打开的 tool_use 块
+	tools       map[int]*openaiToolStream
+	pendingText strings.Builder // 工具块打开期间到达的文本（切换或结束时下发）
+	hasToolUse  bool
+	finish      string
+	usage       *openaiChatUsage
+}
+
+func newAnthropicOpenAIWriter(w http.ResponseWriter, modelName string, stream bool) *anthropicOpenAIWriter {
+	return &anthropicOpenAIWriter{ResponseWriter: w, model: modelName, stream: stream, tools: make(map[int]*openaiToolStream)}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter（SetWriteDeadline 等）
+func (w *anthropicOpenAIWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush 透传 Flush（流式转换后的事件需立即下发）
+func (w *anthropicOpenAIWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *anthropicOpenAIWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if !w.stream {
+		return len(p), nil
+	}
+	for {
+		data := w.buf.Bytes()
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(bytes.TrimRight(data[:idx], "\r"))
+		w.buf.Next(idx + 1)
+		if err := w.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// handleLine 逐行解析上游 SSE：累积 data 行，空行时处理一个 chunk；[DONE] 时收尾
+func (w *anthropicOpenAIWriter) handleLine(line string) error {
+	if after, ok := strings.CutPrefix(line, "data:"); ok {
+		w.dataLines = append(w.dataLines, strings.TrimSpace(after))
+		return nil
+	}
+	if line != "" || len(w.dataLines) == 0 {
+		return nil
+	}
+	payload := strings.Join(w.dataLines, "")
+	w.dataLines = w.dataLines[:0]
+	if w.done {
+		return nil
+	}
+	if payload == "[DONE]" {
+		return w.finishStream()
+	}
+	return w.handleChunk([]byte(payload))
+}
+
+func (w *anthropicOpenAIWriter) writeEvent(event string, data map[string]any) error {
+	payload, err := sonic.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.ResponseWriter, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+func (w *anthropicOpenAIWriter) ensureStarted() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.writeEvent("message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id":            newAnthropicID("msg_"),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         w.model,
+			"content":       []any{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         map[string]any{"input_tokens": 0, "output_tokens": 0},
+		},
+	})
+}
+
+// closeBlock 关闭当前打开的块（text 或 tool_use）
+func (w *anthropicOpenAIWriter) closeBlock() error {
+	if !w.textOpen && w.active == nil {
+		return nil
+	}
+	if w.active != nil {
+		w.active.closed = true
+	}
+	w.textOpen, w.active = false, nil
+	err := w.writeEvent("content_block_stop", map[string]any{"type": "content_block_stop", "index": w.nextIndex})
+	w.nextIndex++
+	return err
+}
+
+// canSwitch 当前块是否可以关闭以切换到其他块（工具参数须已是完整 JSON，避免截断仍在增量发送的参数）
+func (w *anthropicOpenAIWriter) canSwitch() bool {
+	if w.active == nil {
+		return true
+	}
+	args := strings.TrimSpace(w.active.args.String())
+	return args != "" && json.Valid([]byte(args))
+}
+
+func (w *anthropicOpenAIWriter) writeText(text string) error {
+	if !w.textOpen {
+		if err := w.closeBlock(); err != nil {
+			return err
+		}
+		w.textOpen = true
+		if err := w.writeEvent("content_block_start", map[string]any{
+			"type": "content_block_start", "index": w.nextIndex,
+			"content_block": map[string]any{"type": "text", "text": ""},
+		}); err != nil {
+			return err
+		}
+	}
+	return w.writeEvent("content_block_delta", map[string]any{
+		"type": "content_block_delta", "index": w.nextIndex,
+		"delta": map[string]any{"type": "text_delta", "text": text},
+	})
+}
+
+// openTool 关闭当前块并打开工具块，下发已缓存的参数
+func (w *anthropicOpenAIWriter) openTool(tool *openaiToolStream) error {
+	if err := w.closeBlock(); err != nil {
+		return err
+	}
+	if tool.id == "" {
+		tool.id = newAnthropicID("toolu_")
+	}
+	tool.started = true
+	w.active = tool
+	w.hasToolUse = true
+	if err := w.writeEvent("content_block_start", map[string]any{
+		"type": "content_block_start", "index": w.nextIndex,
+		"content_block": map[string]any{"type": "tool_use", "id": tool.id, "name": tool.name, "input": map[string]any{}},
+	}); err != nil {
+		return err
+	}
+	return w.flushToolArgs(tool)
+}
+
+func (w *anthropicOpenAIWriter) flushToolArgs(tool *openaiToolStream) error {
+	if tool.pending.Len() == 0 {
+		return nil
+	}
+	partial := tool.pending.String()
+	tool.pending.Reset()
+	return w.writeEvent("content_block_delta", map[string]any{
+		"type": "content_block_delta", "index": w.nextIndex,
+		"delta": map[string]any{"type": "input_json_delta", "partial_json": partial},
+	})
+}
+
+// flushPending 当前块可切换时，下发缓存的文本与已知函数名的未开始工具（按出现顺序）
+// final=true（流结束）时当前块无论参数是否完整都可关闭
+func (w *anthropicOpenAIWriter) flushPending(final bool) error {
+	if !final && !w.canSwitch() {
+		return nil
+	}
+	if w.pendingText.Len() > 0 {
+		text := w.pendingText.String()
+		w.pendingText.Reset()
+		if err := w.writeText(text); err != nil {
+			return err
+		}
+	}
+	for _, tool := range w.waitingTools() {
+		if tool.name == "" && !final {
+			continue
+		}
+		if err := w.openTool(tool); err != nil {
+			return err
+		}
+		if !final && !w.canSwitch() {
+			return nil // 参数仍未完整：保持该工具块打开，等待后续增量
+		}
+	}
+	return nil
+}
+
+// waitingTools 尚未下发的工具调用（按首次出现顺序）
+func (w *anthropicOpenAIWriter) waitingTools() []*openaiToolStream {
+	var waiting []*openaiToolStream
+	for _, tool := range w.tools {
+		if !tool.started {
+			waiting = append(waiting, tool)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool { return waiting[i].order < waiting[j].order })
+	return waiting
+}
+
+// handleToolDelta 单个 tool_calls 增量：当前打开的工具实时下发，其他工具缓存
+func (w *anthropicOpenAIWriter) handleToolDelta(tc *openaiChatToolCallDelta) error {
+	tool := w.tools[tc.Index]
+	if tool == nil {
+		tool = &openaiToolStream{order: len(w.tools)}
+		w.tools[tc.Index] = tool
+	}
+	if tc.ID != "" && tool.id == "" {
+		tool.id = tc.ID
+	}
+	if tc.Function.Name != "" && tool.name == "" {
+		tool.name = tc.Function.Name
+	}
+	tool.args.WriteString(tc.Function.Arguments)
+	if tool.closed {
+		return nil // 参数已完整的块收到多余增量（通常为空白），无法再追加
+	}
+	tool.pending.WriteString(tc.Function.Arguments)
+	if tool == w.active {
+		return w.flushToolArgs(tool)
+	}
+	return nil // 未开始的工具由 flushPending 按出现顺序打开
+}
+
+// handleChunk 将单个 chat.completion.chunk 转换为 Anthropic 事件
+func (w *anthropicOpenAIWriter) handleChunk(payload []byte) error {
+	var chunk openaiChatResponse
+	if err := sonic.Unmarshal(payload, &chunk); err != nil {
+		return nil // 无法解析的事件直接跳过（容错）
+	}
+	if chunk.Usage != nil {
+		w.usage = chunk.Usage
+	}
+	if err := w.ensureStarted(); err != nil {
+		return err
+	}
+	if chunk.Error != nil {
+		w.done = true
+		return w.writeEvent("error", map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "api_error", "message": chunk.Error.Message},
+		})
+	}
+	if len(chunk.Choices) == 0 {
+		return nil
+	}
+	choice := &chunk.Choices[0]
+	if text := choice.Delta.Content; text != nil && *text != "" {
+		if w.active != nil && !w.canSwitch() {
+			w.pendingText.WriteString(*text)
+		} else if err := w.writeText(*text); err != nil {
+			return err
+		}
+	}
+	for i := range choice.Delta.ToolCalls {
+		if err := w.handleToolDelta(&choice.Delta.ToolCalls[i]); err != nil {
+			return err
+		}
+	}
+	if err := w.flushPending(false); err != nil {
+		return err
+	}
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		w.finish = *choice.FinishReason
+	}
+	w.Flush()
+	return nil
+}
+
+// finishStream 下发剩余缓存并写出 message_delta/message_stop（usage 块在 finish_reason 之后到达，须等到 [DONE]）
+func (w *anthropicOpenAIWriter) finishStream() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	if err := w.ensureStarted(); err != nil {
+		return err
+	}
+	if err := w.flushPending(true); err != nil {
+		return err
+	}
+	if err := w.closeBlock(); err != nil {
+		return err
+	}
+	if err := w.writeEvent("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": anthropicStopFromOpenAI(w.finish, w.hasToolUse), "stop_sequence": nil},
+		"usage": anthropicUsageFromOpenAI(w.usage),
+	}); err != nil {
+		return err
+	}
+	err := w.writeEvent("message_stop", map[string]any{"type": "message_stop"})
+	w.Flush()
+	return err
+}
+
+// finishResponse 上游响应结束后收尾
+// completed=false（上游中断/失败）时流式不补发 message_stop，客户端据此识别截断；
+// 部分上游不发送 [DONE]，正常结束时在此补齐
+func (w *anthropicOpenAIWriter) finishResponse(completed bool) {
+	if !w.stream {
+		if w.buf.Len() == 0 {
+			return
+		}
+		out, err := convertOpenAIChatToAnthropicMessage(w.buf.Bytes(), w.model)
+		if err != nil {
+			out = w.buf.Bytes() // 无法解析时原样返回，避免吞掉响应
+		}
+		_, _ = w.ResponseWriter.Write(out)
+		return
+	}
+
+	if w.buf.Len() > 0 {
+		_ = w.handleLine(strings.TrimRight(w.buf.String(), "\r"))
+		w.buf.Reset()
+	}
+	_ = w.handleLine("") // 处理末尾未以空行结束的事件
+	if !completed || !w.started {
+		return
+	}
+	_ = w.finishStream()
+}
@@ -0,0 +1,358 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+)
+
+func TestConvertMessagesToOpenAIChat(t *testing.T) {
+	body := []byte(`{
+		"model":"claude-x","max_tokens":256,"stream":true,"temperature":0.2,"stop_sequences":["END"],
+		"system":[{"type":"text","text":"be brief"}],
+		"tools":[{"name":"get_weather","description":"weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}],
+		"tool_choice":{"type":"tool","name":"get_weather"},
+		"messages":[
+			{"role":"user","content":[{"type":"text","text":"weather?"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAA"}}]},
+			{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"sunny"},{"type":"text","text":"thanks"}]}
+		]}`)
+	out, stream, err := convertMessagesToOpenAIChat(body, "gpt-x")
+	if err != nil || !stream {
+		t.Fatalf("convert: stream=%v err=%v", stream, err)
+	}
+	var req struct {
+		Model         string           `json:"model"`
+		MaxTokens     int              `json:"max_tokens"`
+		Stop          []string         `json:"stop"`
+		StreamOptions map[string]any   `json:"stream_options"`
+		Messages      []map[string]any `json:"messages"`
+		Tools         []map[string]any `json:"tools"`
+		ToolChoice    map[string]any   `json:"tool_choice"`
+	}
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Model != "gpt-x" || req.MaxTokens != 256 || req.Stop[0] != "END" || req.StreamOptions["include_usage"] != true {
+		t.Fatalf("采样参数转换错误: %s", out)
+	}
+	roles := make([]string, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		roles = append(roles, m["role"].(string))
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,tool,user" {
+		t.Fatalf("消息顺序 = %v", roles)
+	}
+	if parts := req.Messages[1]["content"].([]any); len(parts) != 2 || !strings.HasPrefix(parts[1].(map[string]any)["image_url"].(map[string]any)["url"].(string), "data:image/png;base64,") {
+		t.Fatalf("图片应转换为 data URL: %v", req.Messages[1])
+	}
+	call := req.Messages[2]["tool_calls"].([]any)[0].(map[string]any)
+	if call["id"] != "toolu_1" || call["function"].(map[string]any)["arguments"] != `{"city":"Paris"}` || req.Messages[2]["content"] != nil {
+		t.Fatalf("tool_use 转换错误: %v", req.Messages[2])
+	}
+	if req.Messages[3]["tool_call_id"] != "toolu_1" || req.Messages[3]["content"] != "sunny" || req.Messages[4]["content"] != "thanks" {
+		t.Fatalf("tool_result 转换错误: %v %v", req.Messages[3], req.Messages[4])
+	}
+	if len(req.Tools) != 1 || req.ToolChoice["function"].(map[string]any)["name"] != "get_weather" {
+		t.Fatalf("工具定义转换错误: %v %v", req.Tools, req.ToolChoice)
+	}
+
+	if _, _, err := convertMessagesToOpenAIChat([]byte(`{"messages":[]}`), "gpt-x"); err == nil {
+		t.Fatal("空 messages 应报错")
+	}
+}
+
+func TestConvertOpenAIChatToAnthropicMessage(t *testing.T) {
+	body := []byte(`{"choices":[{"message":{"content":"checking","tool_calls":[
+		{"id":"call_1","type":"function","function":{"name":"a","arguments":"{\"x\":1}"}},
+		{"id":"call_2","type":"function","function":{"name":"b","arguments":"not json"}}]},"finish_reason":"tool_calls"}],
+		"usage":{"prompt_tokens":20,"completion_tokens":7,"prompt_tokens_details":{"cached_tokens":5}}}`)
+	out, err := convertOpenAIChatToAnthropicMessage(body, "claude-x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg struct {
+		Model      string           `json:"model"`
+		Content    []map[string]any `json:"content"`
+		StopReason string           `json:"stop_reason"`
+		Usage      map[string]int64 `json:"usage"`
+	}
+	if err := json.Unmarshal(out, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Model != "claude-x" || msg.StopReason != "tool_use" || len(msg.Content) != 3 {
+		t.Fatalf("message = %s", out)
+	}
+	if msg.Content[1]["id"] != "call_1" || msg.Content[1]["input"].(map[string]any)["x"] != float64(1) {
+		t.Fatalf("tool_use 块错误: %v", msg.Content[1])
+	}
+	if len(msg.Content[2]["input"].(map[string]any)) != 0 {
+		t.Fatalf("无法解析的参数应为空对象: %v", msg.Content[2])
+	}
+	if msg.Usage["input_tokens"] != 15 || msg.Usage["cache_read_input_tokens"] != 5 || msg.Usage["output_tokens"] != 7 {
+		t.Fatalf("usage = %v", msg.Usage)
+	}
+}
+
+// anthropicSSEEvent 解析后的 Anthropic SSE 事件
+type anthropicSSEEvent struct {
+	name string
+	data map[string]any
+}
+
+func parseAnthropicSSE(t *testing.T, raw string) []anthropicSSEEvent {
+	t.Helper()
+	var events []anthropicSSEEvent
+	for _, block := range strings.Split(strings.TrimSpace(raw), "\n\n") {
+		var ev anthropicSSEEvent
+		for _, line := range strings.Split(block, "\n") {
+			if after, ok := strings.CutPrefix(line, "event: "); ok {
+				ev.name = after
+			} else if after, ok := strings.CutPrefix(line, "data: "); ok {
+				if err := json.Unmarshal([]byte(after), &ev.data); err != nil {
+					t.Fatalf("无效事件数据 %q: %v", after, err)
+				}
+			}
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// assembledBlocks 按客户端语义重建内容块，同时校验块严格按顺序打开/关闭
+func assembledBlocks(t *testing.T, events []anthropicSSEEvent) []map[string]any {
+	t.Helper()
+	var blocks []map[string]any
+	open := -1
+	for _, ev := range events {
+		switch ev.name {
+		case "content_block_start":
+			idx := int(ev.data["index"].(float64))
+			if open != -1 || idx != len(blocks) {
+				t.Fatalf("块 %d 在块 %d 未关闭时打开（或下标不连续）", idx, open)
+			}
+			open = idx
+			block := ev.data["content_block"].(map[string]any)
+			block["partial_json"] = ""
+			blocks = append(blocks, block)
+		case "content_block_delta":
+			idx := int(ev.data["index"].(float64))
+			if idx != open {
+				t.Fatalf("增量下标 %d 与打开的块 %d 不一致", idx, open)
+			}
+			delta := ev.data["delta"].(map[string]any)
+			switch delta["type"] {
+			case "text_delta":
+				blocks[idx]["text"] = blocks[idx]["text"].(string) + delta["text"].(string)
+			case "input_json_delta":
+				blocks[idx]["partial_json"] = blocks[idx]["partial_json"].(string) + delta["partial_json"].(string)
+			}
+		case "content_block_stop":
+			if int(ev.data["index"].(float64)) != open {
+				t.Fatalf("关闭的块不是当前打开的块: %v", ev.data)
+			}
+			open = -1
+		}
+	}
+	if open != -1 {
+		t.Fatalf("块 %d 未关闭", open)
+	}
+	return blocks
+}
+
+func openAIChunk(t *testing.T, delta map[string]any, finish any) string {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{"object": "chat.completion.chunk", "choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": finish}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "data: " + string(b) + "\n\n"
+}
+
+func toolDelta(index int, id, name, args string) map[string]any {
+	fn := map[string]any{"arguments": args}
+	if name != "" {
+		fn["name"] = name
+	}
+	tc := map[string]any{"index": index, "function": fn}
+	if id != "" {
+		tc["id"] = id
+		tc["type"] = "function"
+	}
+	return map[string]any{"tool_calls": []any{tc}}
+}
+
+func TestAnthropicOpenAIWriter_InterleavedToolCalls(t *testing.T) {
+	upstream := []string{
+		openAIChunk(t, map[string]any{"role": "assistant", "content": "Let me "}, nil),
+		openAIChunk(t, map[string]any{"content": "check."}, nil),
+		openAIChunk(t, toolDelta(0, "call_a", "get_weather", `{"ci`), nil),
+		// 第二个工具在第一个参数未完整时开始，两者参数交错到达
+		openAIChunk(t, toolDelta(1, "call_b", "get_time", ""), nil),
+		openAIChunk(t, toolDelta(1, "", "", `{"tz":`), nil),
+		openAIChunk(t, toolDelta(0, "", "", `ty":"Paris"`), nil),
+		openAIChunk(t, toolDelta(1, "", "", `"CET"`), nil),
+		openAIChunk(t, toolDelta(0, "", "", `}`), nil),
+		openAIChunk(t, toolDelta(1, "", "", `}`), nil),
+		// 第三个工具在前两个完成后按顺序到达，应实时下发
+		openAIChunk(t, toolDelta(2, "call_c", "noop", `{}`), nil),
+		openAIChunk(t, map[string]any{}, "tool_calls"),
+		`data: {"choices":[],"usage":{"prompt_tokens":30,"completion_tokens":12}}` + "\n\n",
+		"data: [DONE]\n\n",
+	}
+
+	rec := httptest.NewRecorder()
+	w := newAnthropicOpenAIWriter(rec, "claude-x", true)
+	for _, chunk := range upstream {
+		// 按任意边界切分写入，模拟网络分片
+		for len(chunk) > 0 {
+			n := min(7, len(chunk))
+			if _, err := w.Write([]byte(chunk[:n])); err != nil {
+				t.Fatal(err)
+			}
+			chunk = chunk[n:]
+		}
+	}
+	w.finishResponse(true)
+
+	events := parseAnthropicSSE(t, rec.Body.String())
+	if events[0].name != "message_start" || events[len(events)-1].name != "message_stop" {
+		t.Fatalf("事件序列首尾错误: %s", rec.Body.String())
+	}
+	blocks := assembledBlocks(t, events)
+	if len(blocks) != 4 || blocks[0]["text"] != "Let me check." {
+		t.Fatalf("内容块 = %v", blocks)
+	}
+	want := []struct{ id, name, args string }{
+		{"call_a", "get_weather", `{"city":"Paris"}`},
+		{"call_b", "get_time", `{"tz":"CET"}`},
+		{"call_c", "noop", `{}`},
+	}
+	for i, wt := range want {
+		b := blocks[i+1]
+		if b["type"] != "tool_use" || b["id"] != wt.id || b["name"] != wt.name || b["partial_json"] != wt.args {
+			t.Fatalf("工具块 %d = %v, want %+v", i+1, b, wt)
+		}
+	}
+	delta := events[len(events)-2]
+	if delta.name != "message_delta" || delta.data["delta"].(map[string]any)["stop_reason"] != "tool_use" ||
+		delta.data["usage"].(map[string]any)["output_tokens"] != float64(12) {
+		t.Fatalf("message_delta = %v", delta.data)
+	}
+
+	// 上游中断：不补发 message_stop
+	rec = httptest.NewRecorder()
+	w = newAnthropicOpenAIWriter(rec, "claude-x", true)
+	_, _ = w.Write([]byte(openAIChunk(t, toolDelta(0, "call_a", "f", `{"a":`), nil)))
+	w.finishResponse(false)
+	if strings.Contains(rec.Body.String(), "message_stop") {
+		t.Fatalf("中断的流不应补发 message_stop: %s", rec.Body.String())
+	}
+}
+
+func TestTryChannelWithKeys_AnthropicToOpenAIStream(t *testing.T) {
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+	srv.responseBufferBytes = 0 // 本地上游一次性写完，关闭缓冲窗口避免首段读到EOF
+
+	var gotPath, gotAuth, gotVersion string
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth, gotVersion = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("anthropic-version")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, openAIChunk(t, map[string]any{"content": "Hi"}, nil))
+		_, _ = io.WriteString(w, openAIChunk(t, toolDelta(0, "call_1", "f", `{"q":"x"}`), nil))
+		_, _ = io.WriteString(w, openAIChunk(t, map[string]any{}, "tool_calls"))
+		_, _ = io.WriteString(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":5}}\n\ndata: [DONE]\n\n")
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:            "openai-compat",
+		URL:             upstream.URL,
+		ChannelType:     "openai",
+		AnthropicCompat: true,
+		ModelEntries:    []model.ModelEntry{{Model: "claude-x", RedirectModel: "gpt-x"}},
+		Enabled:         true,
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{ChannelID: cfg.ID, APIKey: "o-key", KeyStrategy: model.KeyStrategySequential}}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	if !channelMatchesType(cfg, "anthropic") {
+		t.Fatal("开启 anthropic_compat 的 openai 渠道应匹配 anthropic 请求")
+	}
+
+	body := []byte(`{"model":"claude-x","max_tokens":64,"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	hdr := http.Header{"Anthropic-Version": {"2023-06-01"}, "Content-Type": {"application/json"}}
+	w := httptest.NewRecorder()
+	res, err := srv.tryChannelWithKeys(ctx, cfg, &proxyRequestContext{
+		originalModel: "claude-x",
+		requestMethod: http.MethodPost,
+		requestPath:   "/v1/messages",
+		body:          body,
+		header:        hdr,
+		isStreaming:   true,
+	}, w)
+	if err != nil || res == nil || !res.succeeded {
+		t.Fatalf("转发失败: res=%+v err=%v", res, err)
+	}
+	if gotPath != "/v1/chat/completions" || gotAuth != "Bearer o-key" || gotVersion != "" {
+		t.Fatalf("上游请求不符: path=%s auth=%q anthropic-version=%q", gotPath, gotAuth, gotVersion)
+	}
+	if !strings.Contains(string(gotBody), `"model":"gpt-x"`) || !strings.Contains(string(gotBody), `"include_usage":true`) {
+		t.Fatalf("上游请求体应为 Chat Completions 格式: %s", gotBody)
+	}
+	out := w.Body.String()
+	for _, want := range []string{
+		"event: message_start", `"text":"Hi"`, `"input_json_delta"`, `"partial_json":"{\"q\":\"x\"}"`,
+		`"stop_reason":"tool_use"`, "event: message_stop",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("响应缺少 %s: %s", want, out)
+		}
+	}
+	if strings.Contains(out, "[DONE]") || strings.Contains(out, "chat.completion.chunk") {
+		t.Fatalf("OpenAI 格式不应透传给 Anthropic 客户端: %s", out)
+	}
+}
+
+func TestConvertMessagesToOpenAIChat_SearchResultAndDocument(t *testing.T) {
+	body := []byte(`{"model":"claude-x","max_tokens":64,"messages":[
+		{"role":"user","content":[
+			{"type":"search_result","source":"https://docs.example.com/a","title":"Doc A","content":[{"type":"text","text":"alpha"}]},
+			{"type":"document","title":"a.pdf","source":{"type":"base64","media_type":"application/pdf","data":"JVBERi0="}},
+			{"type":"text","text":"summarize"}
+		]},
+		{"role":"assistant","content":[{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{}},{"type":"text","text":"done"}]}
+	]}`)
+	out, _, err := convertMessagesToOpenAIChat(body, "gpt-x")
+	if err != nil {
+		t.Fatalf("包含 search_result 的请求应能转换: %v", err)
+	}
+	for _, want := range []string{
+		`"text":"Doc A\nSource: https://docs.example.com/a\nalpha"`,
+		`"type":"file"`, `"file_data":"data:application/pdf;base64,JVBERi0="`, `"filename":"a.pdf"`,
+		`{"content":"done","role":"assistant"}`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("转换结果缺少 %s: %s", want, out)
+		}
+	}
+}
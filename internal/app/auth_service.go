@@ -31,18 +31,29 @@ import (
 type AuthService struct {
 	// Token 认证（管理界面使用的动态 Token）
 	// [INFO] 安全修复：存储SHA256哈希而非明文(2025-12)
-	passwordHash []byte               // 管理员密码bcrypt哈希
-	validTokens  map[string]time.Time // TokenHash → 过期时间
-	tokensMux    sync.RWMutex         // 并发保护
+	passwordHash []byte                        // 管理员密码bcrypt哈希
+	validTokens  map[string]model.AdminSession // TokenHash → 过期时间与角色（2026-10增加角色）
+	tokensMux    sync.RWMutex                  // 并发保护
+
+	// SSO 登录（OIDC，nil 表示未启用，2026-10新增）
+	oidc *oidcProvider
 
 	// API 认证（代理 API 使用的数据库令牌）
 	// [FIX] 2025-12: 存储过期时间而非bool，支持懒惰过期校验
 	authTokens          map[string]int64          // Token哈希 → 过期时间(Unix毫秒，0=永不过期)
 	authTokenIDs        map[string]int64          // Token哈希 → Token ID 映射（用于日志记录，2025-12新增）
 	authTokenModels     map[string][]string       // Token哈希 → 允许的模型列表（2026-01新增）
+	authTokenBlocked    map[string][]string       // Token哈希 → 屏蔽的模型规则（2026-10新增）
 	authTokenCostLimits map[string]tokenCostLimit // Token哈希 → 费用限额状态（仅限额>0的令牌）
+	authTokenCertSubjs  map[string]string         // mTLS证书CN/SAN → Token哈希（2026-10新增）
+	authTokenFailover   map[string]struct{}       // 开启故障转移信息的Token哈希（2026-10新增）
+	authTokenRateLimits map[string]tokenRateLimit // Token哈希 → RPM/TPM上限（仅有限制的令牌，2026-10新增）
+	authTokenFallbacks  map[string]string         // Token哈希 → 默认回退模型（仅配置了回退的令牌，2026-10新增）
 	authTokensMux       sync.RWMutex              // 并发保护（支持热更新）
 
+	// 令牌速率限制计数（滑动窗口，热更新不重置）
+	rateLimiter *tokenRateLimiter
+
 	// 数据库依赖（用于热更新令牌）
 	store storage.Store
 
@@ -77,10 +88,12 @@ func NewAuthService(
 
 	s := &AuthService{
 		passwordHash:        passwordHash,
-		validTokens:         make(map[string]time.Time),
+		validTokens:         make(map[string]model.AdminSession),
 		authTokens:          make(map[string]int64),
 		authTokenIDs:        make(map[string]int64),
 		authTokenCostLimits: make(map[string]tokenCostLimit),
+		authTokenCertSubjs:  make(map[string]string),
+		rateLimiter:         newTokenRateLimiter(),
 		loginRateLimiter:    loginRateLimiter,
 		store:               store,
 		lastUsedCh:          make(chan string, 256), // 带缓冲，避免阻塞请求
@@ -116,8 +129,8 @@ func (s *AuthService) loadSessionsFromDB() error {
 	}
 
 	s.tokensMux.Lock()
-	for tokenHash, expiry := range sessions {
-		s.validTokens[tokenHash] = expiry
+	for tokenHash, session := range sessions {
+		s.validTokens[tokenHash] = session
 	}
 	s.tokensMux.Unlock()
 
@@ -167,30 +180,42 @@ func (s *AuthService) generateToken() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
-// isValidToken 验证Token有效性（检查过期时间）
+// isValidToken 验证Token有效性（检查过期时间，任意角色）
 // [INFO] 安全修复：通过tokenHash查询(2025-12)
 func (s *AuthService) isValidToken(token string) bool {
+	_, ok := s.tokenRole(token)
+	return ok
+}
+
+// isAdminRoleToken 验证Token有效且具备完全管理权限（viewer 会话返回 false）
+func (s *AuthService) isAdminRoleToken(token string) bool {
+	role, ok := s.tokenRole(token)
+	return ok && role == model.AdminRoleAdmin
+}
+
+// tokenRole 返回有效Token的会话角色
+func (s *AuthService) tokenRole(token string) (string, bool) {
 	tokenHash := model.HashToken(token)
 
 	s.tokensMux.RLock()
-	expiry, exists := s.validTokens[tokenHash]
+	session, exists := s.validTokens[tokenHash]
 	s.tokensMux.RUnlock()
 
 	if !exists {
-		return false
+		return "", false
 	}
 
 	// 检查是否过期
-	if time.Now().After(expiry) {
+	if time.Now().After(session.ExpiresAt) {
 		// 同步删除过期Token（避免goroutine泄漏）
 		// 原因：map删除操作非常快（O(1)），无需异步，异步反而导致goroutine泄漏
 		s.tokensMux.Lock()
 		delete(s.validTokens, tokenHash)
 		s.tokensMux.Unlock()
-		return false
+		return "", false
 	}
 
-	return true
+	return model.NormalizeAdminRole(session.Role), true
 }
 
 // CleanExpiredTokens 清理过期Token（定期任务）
@@ -201,8 +226,8 @@ func (s *AuthService) CleanExpiredTokens() {
 	// 使用快照模式避免长时间持锁
 	s.tokensMux.RLock()
 	toDelete := make([]string, 0, len(s.validTokens)/10)
-	for tokenHash, expiry := range s.validTokens {
-		if now.After(expiry) {
+	for tokenHash, session := range s.validTokens {
+		if now.After(session.ExpiresAt) {
 			toDelete = append(toDelete, tokenHash)
 		}
 	}
@@ -212,7 +237,7 @@ func (s *AuthService) CleanExpiredTokens() {
 	if len(toDelete) > 0 {
 		s.tokensMux.Lock()
 		for _, tokenHash := range toDelete {
-			if expiry, exists := s.validTokens[tokenHash]; exists && now.After(expiry) {
+			if session, exists := s.validTokens[tokenHash]; exists && now.After(session.ExpiresAt) {
 				delete(s.validTokens, tokenHash)
 			}
 		}
@@ -231,27 +256,107 @@ func (s *AuthService) CleanExpiredTokens() {
 // 认证中间件
 // ============================================================================
 
+// IsAdminRequest 检查请求是否携带有效的管理员Token（不中断请求，供公开端点做可选鉴权，任意角色）
+func (s *AuthService) IsAdminRequest(c *gin.Context) bool {
+	_, ok := s.requestRole(c)
+	return ok
+}
+
+// requestRole 从 Authorization 头解析管理员会话角色
+func (s *AuthService) requestRole(c *gin.Context) (string, bool) {
+	// 从 Authorization 头获取Token
+	authHeader := c.GetHeader("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authHeader, prefix) {
+		return "", false
+	}
+	// 检查动态Token（登录生成的24小时Token）
+	return s.tokenRole(strings.TrimPrefix(authHeader, prefix))
+}
+
+// viewerReadablePaths viewer 角色可读取的管理端点（显式白名单，新增端点默认仅管理员可访问）
+// 不在名单内的只读端点包括：Key明文（keys/export/remote-keys）、上游请求抓取（captures/traces）、
+// 同步清单、Webhook地址（可能内嵌凭据）、会向上游发请求的 models/fetch
+var viewerReadablePaths = map[string]struct{}{
+	"/admin/channels":                    {},
+	"/admin/channels/duplicate-keys":     {},
+	"/admin/channels/:id":                {},
+	"/admin/channels/:id/keys/report":    {},
+	"/admin/channels/:id/models/preview": {},
+	"/admin/channels/:id/suggestions":    {},
+	"/admin/channels/:id/rewrites":       {},
+	"/admin/channels/:id/vertex":         {},
+	"/admin/channels/:id/concurrency":    {},
+	"/admin/channel-health-checks":       {},
+	"/admin/concurrency":                 {},
+	"/admin/gemini-provisioners":         {},
+	"/admin/model-aliases":               {},
+	"/admin/cache-warmups":               {},
+	"/admin/logs":                        {},
+	"/admin/logs/cleanup":                {},
+	"/admin/active-requests":             {},
+	"/admin/events":                      {},
+	"/admin/metrics":                     {},
+	"/admin/metrics/distributions":       {},
+	"/admin/metrics/heatmap":             {},
+	"/admin/metrics/token-drift":         {},
+	"/admin/backup":                      {},
+	"/admin/replica":                     {},
+	"/admin/stats":                       {},
+	"/admin/stats/owners":                {},
+	"/admin/stats/errors":                {},
+	"/admin/stats/labels":                {},
+	"/admin/stats/warmups":               {},
+	"/admin/cooldown/stats":              {},
+	"/admin/token-anomalies":             {},
+	"/admin/token-estimators":            {},
+	"/admin/alerts":                      {},
+	"/admin/budgets":                     {},
+	"/admin/response-cache":              {},
+	"/admin/cache/entries":               {},
+	"/admin/model-flags":                 {},
+	"/admin/unmatched-models":            {},
+	"/admin/request-size-limits":         {},
+	"/admin/pricing":                     {},
+	"/admin/pricing/recompute":           {},
+	"/admin/pricing/recompute/:id":       {},
+	"/admin/test-prompts":                {},
+	"/admin/models":                      {},
+	"/admin/auth-tokens":                 {},
+	"/admin/auth-tokens/:id/forecast":    {},
+	"/admin/topology":                    {},
+	"/admin/settings":                    {},
+	"/admin/settings/:key":               {},
+}
+
+// viewerAllowed viewer 角色仅允许以只读方法访问白名单内的端点
+func viewerAllowed(method, fullPath string) bool {
+	if method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions {
+		return false
+	}
+	_, readable := viewerReadablePaths[fullPath]
+	return readable
+}
+
 // RequireTokenAuth Token 认证中间件（管理界面使用）
 func (s *AuthService) RequireTokenAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从 Authorization 头获取Token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
-			const prefix = "Bearer "
-			if strings.HasPrefix(authHeader, prefix) {
-				token := strings.TrimPrefix(authHeader, prefix)
+		role, ok := s.requestRole(c)
+		if !ok {
+			// 未授权
+			RespondErrorMsg(c, http.StatusUnauthorized, "未授权访问，请先登录")
+			c.Abort()
+			return
+		}
 
-				// 检查动态Token（登录生成的24小时Token）
-				if s.isValidToken(token) {
-					c.Next()
-					return
-				}
-			}
+		if role == model.AdminRoleViewer && !viewerAllowed(c.Request.Method, c.FullPath()) {
+			RespondErrorMsg(c, http.StatusForbidden, "只读账号无权执行此操作")
+			c.Abort()
+			return
 		}
 
-		// 未授权
-		RespondErrorMsg(c, http.StatusUnauthorized, "未授权访问，请先登录")
-		c.Abort()
+		c.Set("admin_role", role)
+		c.Next()
 	}
 }
 
@@ -310,15 +415,19 @@ func (s *AuthService) RequireAPIAuth() gin.HandlerFunc {
 			}
 		}
 
-		if !tokenFound {
+		// 计算令牌哈希并验证
+		var tokenHash string
+		if tokenFound {
+			tokenHash = model.HashToken(token)
+		} else if hash, ok := s.tokenHashFromClientCert(c.Request); ok {
+			// 未携带令牌时，尝试mTLS客户端证书（证书链已在TLS握手阶段校验，含吊销检查）
+			tokenHash = hash
+		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing authorization"})
 			c.Abort()
 			return
 		}
 
-		// 计算令牌哈希并验证
-		tokenHash := model.HashToken(token)
-
 		s.authTokensMux.RLock()
 		expiresAt, exists := s.authTokens[tokenHash]
 		tokenID, hasTokenID := s.authTokenIDs[tokenHash]
@@ -402,21 +511,39 @@ func (s *AuthService) HandleLogin(c *gin.Context) {
 	// 密码正确，重置速率限制
 	s.loginRateLimiter.RecordSuccess(clientIP)
 
-	// 生成Token
-	token, err := s.generateToken()
+	// 生成Token（密码登录始终为完全管理权限）
+	token, err := s.issueAdminSession(model.AdminRoleAdmin)
 	if err != nil {
 		log.Printf("ERROR: token generation failed: %v", err)
 		RespondErrorMsg(c, http.StatusInternalServerError, "internal error")
 		return
 	}
+
+	log.Printf("[INFO] 登录成功: IP=%s", clientIP)
+
+	// 返回明文Token给客户端（前端存储到localStorage）
+	RespondJSON(c, http.StatusOK, gin.H{
+		"token":     token,                             // 明文token返回给客户端
+		"expiresIn": int(config.TokenExpiry.Seconds()), // 秒数
+		"role":      model.AdminRoleAdmin,
+	})
+}
+
+// issueAdminSession 生成管理员会话Token（内存+数据库），返回明文Token
+func (s *AuthService) issueAdminSession(role string) (string, error) {
+	token, err := s.generateToken()
+	if err != nil {
+		return "", err
+	}
 	expiry := time.Now().Add(config.TokenExpiry)
+	role = model.NormalizeAdminRole(role)
 
 	// [INFO] 安全修复：存储tokenHash而非明文(2025-12)
 	tokenHash := model.HashToken(token)
 
 	// 存储TokenHash到内存
 	s.tokensMux.Lock()
-	s.validTokens[tokenHash] = expiry
+	s.validTokens[tokenHash] = model.AdminSession{ExpiresAt: expiry, Role: role}
 	s.tokensMux.Unlock()
 
 	// [INFO] 修复：同步写入数据库（SQLite本地写入极快，微秒级，无需异步）
@@ -425,18 +552,11 @@ func (s *AuthService) HandleLogin(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	if err := s.store.CreateAdminSession(ctx, token, expiry); err != nil {
+	if err := s.store.CreateAdminSession(ctx, token, expiry, role); err != nil {
 		log.Printf("[WARN]  保存管理员会话到数据库失败: %v", err)
 		// 注意：内存中的token仍然有效，下次重启会丢失此会话
 	}
-
-	log.Printf("[INFO] 登录成功: IP=%s", clientIP)
-
-	// 返回明文Token给客户端（前端存储到localStorage）
-	RespondJSON(c, http.StatusOK, gin.H{
-		"token":     token,                             // 明文token返回给客户端
-		"expiresIn": int(config.TokenExpiry.Seconds()), // 秒数
-	})
+	return token, nil
 }
 
 // HandleLogout 处理登出请求
@@ -489,7 +609,12 @@ func (s *AuthService) ReloadAuthTokens() error {
 	newTokens := make(map[string]int64, len(tokens))
 	newTokenIDs := make(map[string]int64, len(tokens))
 	newTokenModels := make(map[string][]string, len(tokens))
+	newTokenBlocked := make(map[string][]string)
 	newTokenCostLimits := make(map[string]tokenCostLimit, len(tokens))
+	newCertSubjects := make(map[string]string)
+	newTokenFailover := make(map[string]struct{})
+	newTokenRateLimits := make(map[string]tokenRateLimit)
+	newTokenFallbacks := make(map[string]string)
 	for _, t := range tokens {
 		// ExpiresAt: nil → 0 (永不过期), *int64 → Unix毫秒
 		var expiresAt int64
@@ -502,6 +627,18 @@ func (s *AuthService) ReloadAuthTokens() error {
 		if len(t.AllowedModels) > 0 {
 			newTokenModels[t.Token] = t.AllowedModels
 		}
+		if len(t.BlockedModels) > 0 {
+			newTokenBlocked[t.Token] = t.BlockedModels
+		}
+		if t.FailoverInfo {
+			newTokenFailover[t.Token] = struct{}{}
+		}
+		if t.RPMLimit > 0 || t.TPMLimit > 0 {
+			newTokenRateLimits[t.Token] = tokenRateLimit{rpm: max(t.RPMLimit, 0), tpm: max(t.TPMLimit, 0)}
+		}
+		if t.FallbackModel != "" {
+			newTokenFallbacks[t.Token] = t.FallbackModel
+		}
 		// 费用限额：只为“有限额”的令牌维护状态（避免无谓内存占用）
 		limitMicro := t.CostLimitMicroUSD
 		if limitMicro > 0 {
@@ -510,6 +647,14 @@ func (s *AuthService) ReloadAuthTokens() error {
 				limitMicroUSD: limitMicro,
 			}
 		}
+		// mTLS证书映射：同一证书标识只能对应一个令牌（按创建时间倒序，先到先得）
+		if subj := t.ClientCertSubject; subj != "" {
+			if _, dup := newCertSubjects[subj]; dup {
+				log.Printf("[WARN] 证书标识 %q 映射到多个令牌，已忽略令牌ID=%d", subj, t.ID)
+			} else {
+				newCertSubjects[subj] = t.Token
+			}
+		}
 	}
 
 	// 原子替换（避免读写竞争）
@@ -517,9 +662,18 @@ func (s *AuthService) ReloadAuthTokens() error {
 	s.authTokens = newTokens
 	s.authTokenIDs = newTokenIDs
 	s.authTokenModels = newTokenModels
+	s.authTokenBlocked = newTokenBlocked
 	s.authTokenCostLimits = newTokenCostLimits
+	s.authTokenCertSubjs = newCertSubjects
+	s.authTokenFailover = newTokenFailover
+	s.authTokenRateLimits = newTokenRateLimits
+	s.authTokenFallbacks = newTokenFallbacks
 	s.authTokensMux.Unlock()
 
+	if s.rateLimiter != nil {
+		s.rateLimiter.prune(newTokenRateLimits)
+	}
+
 	return nil
 }
 
@@ -542,6 +696,35 @@ func (s *AuthService) IsModelAllowed(tokenHash, model string) bool {
 	return false
 }
 
+// AllowedModels 返回令牌的模型白名单（nil 表示无限制）
+func (s *AuthService) AllowedModels(tokenHash string) []string {
+	s.authTokensMux.RLock()
+	defer s.authTokensMux.RUnlock()
+	return s.authTokenModels[tokenHash]
+}
+
+// BlockedModels 返回令牌的模型屏蔽规则（nil 表示无）
+func (s *AuthService) BlockedModels(tokenHash string) []string {
+	s.authTokensMux.RLock()
+	defer s.authTokensMux.RUnlock()
+	return s.authTokenBlocked[tokenHash]
+}
+
+// FailoverInfoEnabled 令牌是否开启了响应中的故障转移信息
+func (s *AuthService) FailoverInfoEnabled(tokenHash string) bool {
+	s.authTokensMux.RLock()
+	defer s.authTokensMux.RUnlock()
+	_, ok := s.authTokenFailover[tokenHash]
+	return ok
+}
+
+// FallbackModel 令牌的默认回退模型（空表示未配置）
+func (s *AuthService) FallbackModel(tokenHash string) string {
+	s.authTokensMux.RLock()
+	defer s.authTokensMux.RUnlock()
+	return s.authTokenFallbacks[tokenHash]
+}
+
 // IsCostLimitExceeded 检查令牌是否超过费用限额（微美元，整数比较）
 // 若令牌无限额/未启用限额：exceeded=false 且 used/limit=0
 func (s *AuthService) IsCostLimitExceeded(tokenHash string) (usedMicroUSD, limitMicroUSD int64, exceeded bool) {
@@ -558,16 +741,54 @@ func (s *AuthService) IsCostLimitExceeded(tokenHash string) (usedMicroUSD, limit
 
 // AddCostToCache 原子更新令牌的已消耗费用缓存
 // 仅更新内存缓存，数据库更新由 UpdateTokenStats 异步处理
-func (s *AuthService) AddCostToCache(tokenHash string, deltaMicroUSD int64) {
+// 返回更新后的已消耗费用与限额（令牌无限额时均为0）
+func (s *AuthService) AddCostToCache(tokenHash string, deltaMicroUSD int64) (usedMicroUSD, limitMicroUSD int64) {
 	if deltaMicroUSD <= 0 {
-		return
+		return 0, 0
 	}
 
 	s.authTokensMux.Lock()
+	defer s.authTokensMux.Unlock()
 	v, ok := s.authTokenCostLimits[tokenHash]
-	if ok && v.limitMicroUSD > 0 {
-		v.usedMicroUSD += deltaMicroUSD
-		s.authTokenCostLimits[tokenHash] = v
+	if !ok || v.limitMicroUSD <= 0 {
+		return 0, 0
 	}
-	s.authTokensMux.Unlock()
+	v.usedMicroUSD += deltaMicroUSD
+	s.authTokenCostLimits[tokenHash] = v
+	return v.usedMicroUSD, v.limitMicroUSD
+}
+
+// CheckRateLimit 令牌速率限制检查（RPM/TPM，2026-10新增）
+// 只对代理到上游的模型请求调用：门户日志、令牌自省、模型列表等本地查询不占用配额。
+// 超限时返回超出的维度（rpm/tpm）与建议的 Retry-After；未超限返回空字符串
+func (s *AuthService) CheckRateLimit(tokenHash string) (exceeded string, retryAfter time.Duration) {
+	if s == nil {
+		return "", 0
+	}
+	s.authTokensMux.RLock()
+	limit, ok := s.authTokenRateLimits[tokenHash]
+	s.authTokensMux.RUnlock()
+	if !ok || s.rateLimiter == nil {
+		return "", 0
+	}
+	return s.rateLimiter.allow(tokenHash, limit, time.Now())
+}
+
+// RecordTokenUsage 计入请求完成后的Token用量（仅对设置了TPM上限的令牌计数）
+func (s *AuthService) RecordTokenUsage(tokenHash string, tokens int64) {
+	s.authTokensMux.RLock()
+	limit, ok := s.authTokenRateLimits[tokenHash]
+	s.authTokensMux.RUnlock()
+	if !ok || limit.tpm <= 0 || s.rateLimiter == nil {
+		return
+	}
+	s.rateLimiter.addTokens(tokenHash, tokens, time.Now())
+}
+
+// TokenIDByHash 根据令牌哈希返回令牌ID
+func (s *AuthService) TokenIDByHash(tokenHash string) (int64, bool) {
+	s.authTokensMux.RLock()
+	defer s.authTokensMux.RUnlock()
+	id, ok := s.authTokenIDs[tokenHash]
+	return id, ok
 }
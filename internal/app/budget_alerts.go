@@ -0,0 +1,478 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 预算软告警（2026-10新增）
+// ============================================================================
+// 硬限额（令牌 cost_limit_usd、渠道 daily_cost_limit）触发时请求已被拒绝，
+// 软告警在花费越过限额的 50%/80%/95%（budget_alert_thresholds 可配）时提前提醒。
+// 月度预算（/admin/budgets，按令牌或渠道配置）只产生软告警，不拒绝请求；本月花费启动时从日志加载，
+// 请求完成后在内存中累加，跨月自动归零（令牌只计2xx请求，与实时计费一致）。
+// 告警投递：
+// - 记录到 budget_alerts 表（同一 范围+对象+周期+阈值 只记录一次，重启不重复）
+// - 输出 [ALERT] 日志，并作为 budget_alert 事件推送到订阅的 Webhook
+// - GET /admin/alerts 查看告警流，POST /admin/alerts/:id/ack 确认
+// 请求路径上的检查只读内存（渠道每日限额取自渠道缓存），告警经队列异步落库
+
+const (
+	defaultBudgetAlertThresholds = "50,80,95"
+	budgetAlertQueueSize         = 256
+	budgetAlertListDefaultLimit  = 100
+	budgetAlertListMaxLimit      = 1000
+)
+
+// parseBudgetAlertThresholds 解析逗号分隔的百分比阈值（1-100），返回去重升序列表；空字符串表示关闭
+func parseBudgetAlertThresholds(raw string) ([]int, error) {
+	seen := make(map[int]struct{})
+	thresholds := make([]int, 0, 3)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "%"))
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 || n > 100 {
+			return nil, fmt.Errorf("invalid threshold %q (must be 1-100)", part)
+		}
+		if _, dup := seen[n]; dup {
+			continue
+		}
+		seen[n] = struct{}{}
+		thresholds = append(thresholds, n)
+	}
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+// crossedBudgetThreshold 返回花费从 before 增加到 after 时越过的最高阈值（0=未越过）
+// 单次请求跨过多个阈值时只报告最高的一个，避免同时产生多条告警
+func crossedBudgetThreshold(before, after, limit float64, thresholds []int) int {
+	if limit <= 0 || after <= before {
+		return 0
+	}
+	crossed := 0
+	for _, t := range thresholds {
+		line := limit * float64(t) / 100
+		if before < line && after >= line {
+			crossed = t
+		}
+	}
+	return crossed
+}
+
+// checkChannelBudget 渠道今日成本从 before 增加到 after 后检查软告警阈值
+// 每日限额读自渠道缓存（该函数在每个计费请求上调用，不能查询数据库）
+func (s *Server) checkChannelBudget(channelID int64, before, after float64) {
+	if len(s.budgetAlertThresholds) == 0 || s.budgetAlertCh == nil {
+		return
+	}
+	cache := s.getChannelCache()
+	if cache == nil {
+		return
+	}
+	limit, ok := cache.ChannelDailyCostLimit(context.Background(), channelID)
+	if !ok || limit <= 0 {
+		return
+	}
+	threshold := crossedBudgetThreshold(before, after, limit, s.budgetAlertThresholds)
+	if threshold == 0 {
+		return
+	}
+	now := time.Now()
+	s.enqueueBudgetAlert(&model.BudgetAlert{
+		Scope:     model.BudgetAlertScopeChannel,
+		TargetID:  channelID,
+		Period:    todayStart(now).Format("2006-01-02"),
+		Threshold: threshold,
+		SpentUSD:  after,
+		LimitUSD:  limit,
+		CreatedAt: now.Unix(),
+	})
+}
+
+// monthlyBudgetKey 月度预算对象
+type monthlyBudgetKey struct {
+	scope string // token / channel
+	id    int64
+}
+
+// monthlyBudgetTracker 月度预算限额与本月花费（仅内存）
+// 限额在启动时与管理接口修改后整体替换；花费启动时从日志加载，跨月重置
+type monthlyBudgetTracker struct {
+	mu     sync.Mutex
+	limits map[monthlyBudgetKey]float64
+	month  string // 当前统计月份 YYYY-MM
+	spent  map[monthlyBudgetKey]float64
+}
+
+func newMonthlyBudgetTracker(now time.Time) *monthlyBudgetTracker {
+	return &monthlyBudgetTracker{
+		limits: make(map[monthlyBudgetKey]float64),
+		month:  now.Format("2006-01"),
+		spent:  make(map[monthlyBudgetKey]float64),
+	}
+}
+
+// monthStart 返回给定时间所在月份1日0点
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// setLimits 替换全部月度预算限额
+func (m *monthlyBudgetTracker) setLimits(budgets []*model.MonthlyBudget) {
+	limits := make(map[monthlyBudgetKey]float64, len(budgets))
+	for _, b := range budgets {
+		limits[monthlyBudgetKey{b.Scope, b.TargetID}] = b.LimitUSD
+	}
+	m.mu.Lock()
+	m.limits = limits
+	m.mu.Unlock()
+}
+
+// loadSpent 载入本月已花费（启动时调用）
+func (m *monthlyBudgetTracker) loadSpent(now time.Time, channels, tokens map[int64]float64) {
+	spent := make(map[monthlyBudgetKey]float64, len(channels)+len(tokens))
+	for id, v := range channels {
+		spent[monthlyBudgetKey{model.BudgetAlertScopeChannel, id}] = v
+	}
+	for id, v := range tokens {
+		spent[monthlyBudgetKey{model.BudgetAlertScopeToken, id}] = v
+	}
+	m.mu.Lock()
+	m.month = now.Format("2006-01")
+	m.spent = spent
+	m.mu.Unlock()
+}
+
+// add 累加本月花费，返回累加前后的花费、预算限额（0=未配置）与统计月份
+func (m *monthlyBudgetTracker) add(key monthlyBudgetKey, cost float64, now time.Time) (before, after, limit float64, month string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur := now.Format("2006-01"); cur != m.month {
+		m.month = cur
+		m.spent = make(map[monthlyBudgetKey]float64)
+	}
+	before = m.spent[key]
+	after = before + cost
+	m.spent[key] = after
+	return before, after, m.limits[key], m.month
+}
+
+// recordMonthlySpend 计费请求完成后累加渠道与令牌的本月花费并检查月度预算阈值
+func (s *Server) recordMonthlySpend(entry *model.LogEntry) {
+	m := s.monthlyBudgets
+	if m == nil || entry.Cost <= 0 {
+		return
+	}
+	now := time.Now()
+	if entry.ChannelID > 0 {
+		s.checkMonthlyBudget(monthlyBudgetKey{model.BudgetAlertScopeChannel, entry.ChannelID}, entry.Cost, now)
+	}
+	// 令牌只对2xx请求计费（与 updateTokenStatsAsync 一致）
+	if entry.AuthTokenID > 0 && entry.StatusCode >= 200 && entry.StatusCode < 300 {
+		s.checkMonthlyBudget(monthlyBudgetKey{model.BudgetAlertScopeToken, entry.AuthTokenID}, entry.Cost, now)
+	}
+}
+
+func (s *Server) checkMonthlyBudget(key monthlyBudgetKey, cost float64, now time.Time) {
+	before, after, limit, month := s.monthlyBudgets.add(key, cost, now)
+	if len(s.budgetAlertThresholds) == 0 || s.budgetAlertCh == nil || limit <= 0 {
+		return
+	}
+	threshold := crossedBudgetThreshold(before, after, limit, s.budgetAlertThresholds)
+	if threshold == 0 {
+		return
+	}
+	s.enqueueBudgetAlert(&model.BudgetAlert{
+		Scope:     key.scope,
+		TargetID:  key.id,
+		Period:    month,
+		Threshold: threshold,
+		SpentUSD:  after,
+		LimitUSD:  limit,
+		CreatedAt: now.Unix(),
+	})
+}
+
+// loadMonthlyBudgets 启动时加载月度预算限额与本月花费（须在重放预写日志之后）
+func (s *Server) loadMonthlyBudgets(ctx context.Context) {
+	now := time.Now()
+	s.monthlyBudgets = newMonthlyBudgetTracker(now)
+	s.reloadMonthlyBudgetLimits(ctx)
+	channels, tokens, err := s.store.GetMonthSpend(ctx, monthStart(now))
+	if err != nil {
+		log.Printf("[WARN] 加载本月花费失败: %v（月度预算告警可能不准确）", err)
+		return
+	}
+	s.monthlyBudgets.loadSpent(now, channels, tokens)
+}
+
+// reloadMonthlyBudgetLimits 从数据库重新加载月度预算限额（启动与管理接口修改后调用）
+func (s *Server) reloadMonthlyBudgetLimits(ctx context.Context) {
+	if s.monthlyBudgets == nil {
+		return
+	}
+	budgets, err := s.store.ListMonthlyBudgets(ctx)
+	if err != nil {
+		log.Printf("[WARN] 加载月度预算失败: %v", err)
+		return
+	}
+	s.monthlyBudgets.setLimits(budgets)
+}
+
+// checkTokenBudget 令牌已消耗费用（微美元）从 beforeMicro 增加到 afterMicro 后检查软告警阈值
+func (s *Server) checkTokenBudget(tokenHash string, beforeMicro, afterMicro, limitMicro int64) {
+	if len(s.budgetAlertThresholds) == 0 || s.budgetAlertCh == nil || limitMicro <= 0 {
+		return
+	}
+	threshold := crossedBudgetThreshold(float64(beforeMicro), float64(afterMicro), float64(limitMicro), s.budgetAlertThresholds)
+	if threshold == 0 {
+		return
+	}
+	tokenID, ok := s.authService.TokenIDByHash(tokenHash)
+	if !ok {
+		return
+	}
+	limitUSD := util.MicroUSDToUSD(limitMicro)
+	s.enqueueBudgetAlert(&model.BudgetAlert{
+		Scope:     model.BudgetAlertScopeToken,
+		TargetID:  tokenID,
+		Period:    fmt.Sprintf("total@%.2f", limitUSD),
+		Threshold: threshold,
+		SpentUSD:  util.MicroUSDToUSD(afterMicro),
+		LimitUSD:  limitUSD,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// enqueueBudgetAlert 非阻塞投递告警（队列满时丢弃并记录日志，不影响请求路径）
+func (s *Server) enqueueBudgetAlert(a *model.BudgetAlert) {
+	select {
+	case s.budgetAlertCh <- a:
+	default:
+		log.Printf("[WARN] 预算告警队列已满，丢弃告警: %s#%d 阈值=%d%%", a.Scope, a.TargetID, a.Threshold)
+	}
+}
+
+func (s *Server) budgetAlertWorker() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.shutdownCh:
+			for {
+				select {
+				case a := <-s.budgetAlertCh:
+					s.persistBudgetAlert(a)
+				default:
+					return
+				}
+			}
+		case a := <-s.budgetAlertCh:
+			s.persistBudgetAlert(a)
+		}
+	}
+}
+
+// persistBudgetAlert 写入告警表；首次写入时输出告警日志
+func (s *Server) persistBudgetAlert(a *model.BudgetAlert) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	created, err := s.store.CreateBudgetAlert(ctx, a)
+	if err != nil {
+		log.Printf("[WARN] 保存预算告警失败: %s#%d 阈值=%d%%, err=%v", a.Scope, a.TargetID, a.Threshold, err)
+		return
+	}
+	if !created {
+		return
+	}
+
+	target := "渠道ID"
+	if a.Scope == model.BudgetAlertScopeToken {
+		target = "令牌ID"
+	}
+	log.Printf("[WARN] [ALERT] [预算告警] %s=%d, 周期=%s, 已花费 $%.4f / 限额 $%.2f，已超过 %d%%",
+		target, a.TargetID, a.Period, a.SpentUSD, a.LimitUSD, a.Threshold)
+	s.webhooks.enqueue(WebhookEventBudgetAlert, a)
+}
+
+// HandleListBudgetAlerts 预算告警列表
+// GET /admin/alerts?unacked=true&limit=100
+func (s *Server) HandleListBudgetAlerts(c *gin.Context) {
+	unackedOnly, _ := strconv.ParseBool(c.DefaultQuery("unacked", "false"))
+	limit := budgetAlertListDefaultLimit
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = min(v, budgetAlertListMaxLimit)
+	}
+
+	alerts, err := s.store.ListBudgetAlerts(c.Request.Context(), unackedOnly, limit)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, alerts)
+}
+
+// HandleAckBudgetAlert 确认预算告警
+// POST /admin/alerts/:id/ack
+func (s *Server) HandleAckBudgetAlert(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid alert id")
+		return
+	}
+
+	ok, err := s.store.AcknowledgeBudgetAlert(c.Request.Context(), id, time.Now())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		RespondErrorMsg(c, http.StatusNotFound, "alert not found or already acknowledged")
+		return
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"id": id, "acknowledged": true})
+}
+
+// HandleListMonthlyBudgets 月度预算列表（2026-10新增）
+// GET /admin/budgets
+func (s *Server) HandleListMonthlyBudgets(c *gin.Context) {
+	budgets, err := s.store.ListMonthlyBudgets(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, budgets)
+}
+
+// HandleCreateMonthlyBudget 新增月度预算
+// POST /admin/budgets
+func (s *Server) HandleCreateMonthlyBudget(c *gin.Context) {
+	budget, ok := s.bindMonthlyBudget(c, 0)
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	budget.CreatedAt, budget.UpdatedAt = now, now
+	if err := s.store.CreateMonthlyBudget(c.Request.Context(), budget); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.reloadMonthlyBudgetLimits(c.Request.Context())
+	RespondJSON(c, http.StatusCreated, budget)
+}
+
+// HandleUpdateMonthlyBudget 更新月度预算
+// PUT /admin/budgets/:id
+func (s *Server) HandleUpdateMonthlyBudget(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid budget id")
+		return
+	}
+	budget, ok := s.bindMonthlyBudget(c, id)
+	if !ok {
+		return
+	}
+	budget.ID = id
+	budget.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateMonthlyBudget(c.Request.Context(), budget)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "budget not found")
+		return
+	}
+	s.reloadMonthlyBudgetLimits(c.Request.Context())
+	RespondJSON(c, http.StatusOK, budget)
+}
+
+// HandleDeleteMonthlyBudget 删除月度预算
+// DELETE /admin/budgets/:id
+func (s *Server) HandleDeleteMonthlyBudget(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid budget id")
+		return
+	}
+	found, err := s.store.DeleteMonthlyBudget(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "budget not found")
+		return
+	}
+	s.reloadMonthlyBudgetLimits(c.Request.Context())
+	RespondJSON(c, http.StatusOK, gin.H{"id": id})
+}
+
+// bindMonthlyBudget 解析并校验月度预算请求（selfID 为更新时的预算ID，创建时为0）
+func (s *Server) bindMonthlyBudget(c *gin.Context, selfID int64) (*model.MonthlyBudget, bool) {
+	var req MonthlyBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return nil, false
+	}
+	budget := req.ToMonthlyBudget()
+	if err := validateMonthlyBudget(budget); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	ctx := c.Request.Context()
+	if budget.Scope == model.BudgetAlertScopeChannel {
+		if cfg, err := s.store.GetConfig(ctx, budget.TargetID); err != nil || cfg == nil {
+			RespondErrorMsg(c, http.StatusBadRequest, "channel not found")
+			return nil, false
+		}
+	} else if token, err := s.store.GetAuthToken(ctx, budget.TargetID); err != nil || token == nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "token not found")
+		return nil, false
+	}
+	existing, err := s.store.ListMonthlyBudgets(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	for _, other := range existing {
+		if other.ID != selfID && other.Scope == budget.Scope && other.TargetID == budget.TargetID {
+			RespondErrorMsg(c, http.StatusConflict, fmt.Sprintf("%s %d already has a monthly budget", budget.Scope, budget.TargetID))
+			return nil, false
+		}
+	}
+	return budget, true
+}
+
+// validateMonthlyBudget 范围为 token/channel，对象ID与限额为正
+func validateMonthlyBudget(b *model.MonthlyBudget) error {
+	if b.Scope != model.BudgetAlertScopeToken && b.Scope != model.BudgetAlertScopeChannel {
+		return fmt.Errorf("scope must be %q or %q", model.BudgetAlertScopeToken, model.BudgetAlertScopeChannel)
+	}
+	if b.TargetID <= 0 {
+		return fmt.Errorf("target_id must be > 0")
+	}
+	if b.LimitUSD <= 0 {
+		return fmt.Errorf("limit_usd must be > 0")
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseBudgetAlertThresholds(t *testing.T) {
+	got, err := parseBudgetAlertThresholds(" 95, 50%,80,50 ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[0] != 50 || got[1] != 80 || got[2] != 95 {
+		t.Fatalf("expected [50 80 95], got %v", got)
+	}
+	if got, err := parseBudgetAlertThresholds(""); err != nil || len(got) != 0 {
+		t.Fatalf("empty input should disable alerts, got %v, %v", got, err)
+	}
+	for _, bad := range []string{"0", "101", "abc"} {
+		if _, err := parseBudgetAlertThresholds(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCrossedBudgetThreshold(t *testing.T) {
+	thresholds := []int{50, 80, 95}
+	cases := []struct {
+		before, after float64
+		want          int
+	}{
+		{before: 10, after: 40, want: 0},
+		{before: 40, after: 50, want: 50},
+		{before: 50, after: 60, want: 0},  // 已越过50%，不重复
+		{before: 40, after: 96, want: 95}, // 一次跨过多个阈值只报告最高的
+		{before: 96, after: 120, want: 0},
+	}
+	for _, tc := range cases {
+		if got := crossedBudgetThreshold(tc.before, tc.after, 100, thresholds); got != tc.want {
+			t.Errorf("crossed(%v→%v) = %d, want %d", tc.before, tc.after, got, tc.want)
+		}
+	}
+	if got := crossedBudgetThreshold(0, 100, 0, thresholds); got != 0 {
+		t.Errorf("no limit should never alert, got %d", got)
+	}
+}
+
+func TestBudgetAlerts_ChannelFlow(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:           "budget-channel",
+		URL:            "https://api.example.com",
+		DailyCostLimit: 10,
+		ModelEntries:   []model.ModelEntry{{Model: "m"}},
+		Enabled:        true,
+	})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	server.channelCache = storage.NewChannelCache(store, time.Minute) // 每日限额读自渠道缓存
+	server.budgetAlertThresholds = []int{50, 80, 95}
+	server.budgetAlertCh = make(chan *model.BudgetAlert, 4)
+
+	server.checkChannelBudget(cfg.ID, 4, 6) // 越过50%
+	server.checkChannelBudget(cfg.ID, 6, 7) // 未越过新阈值
+	if len(server.budgetAlertCh) != 1 {
+		t.Fatalf("expected 1 queued alert, got %d", len(server.budgetAlertCh))
+	}
+	alert := <-server.budgetAlertCh
+	server.persistBudgetAlert(alert)
+	// 同一周期+阈值重复写入被忽略
+	dup := *alert
+	server.persistBudgetAlert(&dup)
+
+	list := func(query string) []model.BudgetAlert {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/alerts"+query, nil)
+		server.HandleListBudgetAlerts(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list alerts status=%d body=%s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data []model.BudgetAlert `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Data
+	}
+
+	alerts := list("")
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+	got := alerts[0]
+	if got.Scope != model.BudgetAlertScopeChannel || got.TargetID != cfg.ID || got.Threshold != 50 || got.LimitUSD != 10 || got.AcknowledgedAt != 0 {
+		t.Fatalf("unexpected alert: %+v", got)
+	}
+
+	ack := func(id string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/alerts/"+id+"/ack", nil)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		server.HandleAckBudgetAlert(c)
+		return w.Code
+	}
+	id := strconv.FormatInt(got.ID, 10)
+	if code := ack(id); code != http.StatusOK {
+		t.Fatalf("ack status=%d", code)
+	}
+	if code := ack(id); code != http.StatusNotFound {
+		t.Fatalf("second ack should be 404, got %d", code)
+	}
+	if alerts := list("?unacked=true"); len(alerts) != 0 {
+		t.Fatalf("expected no unacked alerts, got %d", len(alerts))
+	}
+	if alerts := list(""); len(alerts) != 1 || alerts[0].AcknowledgedAt == 0 {
+		t.Fatalf("expected acknowledged alert in full list, got %+v", alerts)
+	}
+}
+
+func TestMonthlyBudgets_CRUDAndAlerts(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "monthly-channel", URL: "https://api.example.com",
+		ModelEntries: []model.ModelEntry{{Model: "m"}}, Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	token := &model.AuthToken{Token: model.HashToken("sk-monthly"), Description: "monthly", IsActive: true}
+	if err := store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	server.budgetAlertThresholds = []int{50, 80, 95}
+	server.budgetAlertCh = make(chan *model.BudgetAlert, 8)
+	server.webhooks = newWebhookDispatcher()
+	server.webhooks.set([]*model.Webhook{{ID: 1, URL: "https://hooks.example", Events: WebhookEventBudgetAlert, Enabled: true}})
+	server.loadMonthlyBudgets(ctx)
+
+	call := func(handler gin.HandlerFunc, method, body, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/admin/budgets", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if id != "" {
+			c.Params = gin.Params{{Key: "id", Value: id}}
+		}
+		handler(c)
+		return w
+	}
+	channelBody := `{"scope":"channel","target_id":` + strconv.FormatInt(cfg.ID, 10) + `,"limit_usd":10}`
+	for body, want := range map[string]int{
+		`{"scope":"model","target_id":1,"limit_usd":10}`:                                      http.StatusBadRequest,
+		`{"scope":"channel","target_id":` + strconv.FormatInt(cfg.ID, 10) + `,"limit_usd":0}`: http.StatusBadRequest,
+		`{"scope":"channel","target_id":9999,"limit_usd":10}`:                                 http.StatusBadRequest,
+		`{"scope":"token","target_id":9999,"limit_usd":10}`:                                   http.StatusBadRequest,
+	} {
+		if w := call(server.HandleCreateMonthlyBudget, http.MethodPost, body, ""); w.Code != want {
+			t.Fatalf("%s: want %d, got %d %s", body, want, w.Code, w.Body.String())
+		}
+	}
+	if w := call(server.HandleCreateMonthlyBudget, http.MethodPost, channelBody, ""); w.Code != http.StatusCreated {
+		t.Fatalf("create channel budget: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(server.HandleCreateMonthlyBudget, http.MethodPost, channelBody, ""); w.Code != http.StatusConflict {
+		t.Fatalf("duplicate budget should be 409, got %d", w.Code)
+	}
+	w := call(server.HandleCreateMonthlyBudget, http.MethodPost,
+		`{"scope":"token","target_id":`+strconv.FormatInt(token.ID, 10)+`,"limit_usd":2}`, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create token budget: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data model.MonthlyBudget `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+
+	// 渠道花费 6/10 越过50%；令牌的失败请求不计入
+	server.recordMonthlySpend(&model.LogEntry{ChannelID: cfg.ID, AuthTokenID: token.ID, StatusCode: 502, Cost: 6})
+	// 渠道 6.5/10 未越过新阈值；令牌2xx请求计入，0.5 → 1.7/2 越过80%
+	server.recordMonthlySpend(&model.LogEntry{ChannelID: cfg.ID, AuthTokenID: token.ID, StatusCode: 200, Cost: 0.5})
+	server.recordMonthlySpend(&model.LogEntry{ChannelID: cfg.ID + 1, AuthTokenID: token.ID, StatusCode: 200, Cost: 1.2})
+	if len(server.budgetAlertCh) != 2 {
+		t.Fatalf("expected 2 queued alerts, got %d", len(server.budgetAlertCh))
+	}
+	month := time.Now().Format("2006-01")
+	for _, want := range []struct {
+		scope     string
+		target    int64
+		threshold int
+	}{{model.BudgetAlertScopeChannel, cfg.ID, 50}, {model.BudgetAlertScopeToken, token.ID, 80}} {
+		a := <-server.budgetAlertCh
+		if a.Scope != want.scope || a.TargetID != want.target || a.Threshold != want.threshold || a.Period != month {
+			t.Fatalf("unexpected alert: %+v", a)
+		}
+		server.persistBudgetAlert(a)
+	}
+	// 落库的告警推送到订阅 budget_alert 的 Webhook
+	if n := len(server.webhooks.queue); n != 2 {
+		t.Fatalf("expected 2 webhook payloads, got %d", n)
+	}
+	if p := <-server.webhooks.queue; p.Type != WebhookEventBudgetAlert {
+		t.Fatalf("unexpected webhook event: %s", p.Type)
+	}
+
+	// 调低令牌预算后立即生效（1.7/1.8 越过 95%）
+	id := strconv.FormatInt(created.Data.ID, 10)
+	if w := call(server.HandleUpdateMonthlyBudget, http.MethodPut,
+		`{"scope":"token","target_id":`+strconv.FormatInt(token.ID, 10)+`,"limit_usd":1.8}`, id); w.Code != http.StatusOK {
+		t.Fatalf("update budget: %d %s", w.Code, w.Body.String())
+	}
+	server.recordMonthlySpend(&model.LogEntry{AuthTokenID: token.ID, StatusCode: 200, Cost: 0.05})
+	if a := <-server.budgetAlertCh; a.Threshold != 95 || a.LimitUSD != 1.8 {
+		t.Fatalf("unexpected alert after update: %+v", a)
+	}
+
+	if w := call(server.HandleDeleteMonthlyBudget, http.MethodDelete, "", id); w.Code != http.StatusOK {
+		t.Fatalf("delete budget: %d", w.Code)
+	}
+	if w := call(server.HandleDeleteMonthlyBudget, http.MethodDelete, "", id); w.Code != http.StatusNotFound {
+		t.Fatalf("second delete should be 404, got %d", w.Code)
+	}
+	server.recordMonthlySpend(&model.LogEntry{AuthTokenID: token.ID, StatusCode: 200, Cost: 10})
+	if len(server.budgetAlertCh) != 0 {
+		t.Fatal("deleted budget should not alert")
+	}
+}
+
+func TestMonthlyBudgetTracker_ResetsOnNewMonth(t *testing.T) {
+	oct := time.Date(2026, 10, 31, 23, 0, 0, 0, time.Local)
+	m := newMonthlyBudgetTracker(oct)
+	key := monthlyBudgetKey{model.BudgetAlertScopeChannel, 1}
+	m.loadSpent(oct, map[int64]float64{1: 5}, nil)
+	if before, after, _, month := m.add(key, 1, oct); before != 5 || after != 6 || month != "2026-10" {
+		t.Fatalf("unexpected spend: %v→%v %s", before, after, month)
+	}
+	if before, after, _, month := m.add(key, 1, oct.Add(2*time.Hour)); before != 0 || after != 1 || month != "2026-11" {
+		t.Fatalf("new month should reset spend: %v→%v %s", before, after, month)
+	}
+}
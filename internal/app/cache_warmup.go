@@ -0,0 +1,393 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Anthropic 提示缓存预热（2026-10新增）
+// ============================================================================
+// Anthropic 的提示缓存（cache_control: ephemeral）默认 5 分钟未命中即失效，低峰期过后的首个请求
+// 要按 cache_creation 重新计费。预热计划按 interval_seconds 周期向指定渠道重放一次最小请求：
+// 预热提示作为带 cache_control 的 system 块发送、max_tokens=1，响应 usage 中的
+// cache_read_input_tokens / cache_creation_input_tokens 记入计划（最近一次与累计值）。
+// 预热请求走管理端低优先级出站通道，不触发冷却、不记日志/费用；效果见 GET /admin/stats/warmups。
+
+const (
+	cacheWarmupCheckInterval  = 30 * time.Second // 调度检查间隔（决定实际运行时间的精度）
+	defaultCacheWarmupSeconds = 270              // 略短于 5 分钟缓存TTL
+	minCacheWarmupSeconds     = 60
+	maxCacheWarmupSeconds     = 86400
+	maxCacheWarmupPromptBytes = 512 * 1024
+	cacheWarmupRunTimeout     = 2 * time.Minute
+	cacheWarmupUserMessage    = "."
+)
+
+// cacheWarmupMu 串行化预热运行（调度与手动触发共用）
+var cacheWarmupMu sync.Mutex
+
+// runCacheWarmup 执行一次预热并记录结果；渠道不存在或没有可用Key时同样记为失败
+func (s *Server) runCacheWarmup(ctx context.Context, w *model.CacheWarmup) *model.CacheWarmup {
+	cacheWarmupMu.Lock()
+	defer cacheWarmupMu.Unlock()
+
+	start := time.Now()
+	cacheRead, cacheCreation, err := s.sendCacheWarmup(ctx, w)
+	w.LastRunAt = time.Now().Unix()
+	w.LastLatencyMs = time.Since(start).Milliseconds()
+	w.LastOK = err == nil
+	w.LastError = ""
+	w.LastCacheReadTokens, w.LastCacheCreationTokens = int64(cacheRead), int64(cacheCreation)
+	if err != nil {
+		w.LastError = util.RedactSecrets(err.Error())
+		log.Printf("[WARN] [缓存预热] 渠道ID=%d 模型=%s 预热失败: %s", w.ChannelID, w.Model, w.LastError)
+	}
+
+	if err := s.store.RecordCacheWarmupRun(ctx, w); err != nil {
+		log.Printf("[WARN] [缓存预热] 保存运行结果失败 (warmup=%d): %v", w.ID, err)
+		return w
+	}
+	if updated, err := s.store.GetCacheWarmup(ctx, w.ID); err == nil {
+		return updated
+	}
+	return w
+}
+
+// sendCacheWarmup 向渠道发送一次预热请求，返回 usage 中的缓存读取/写入token数
+func (s *Server) sendCacheWarmup(ctx context.Context, w *model.CacheWarmup) (cacheRead, cacheCreation int, err error) {
+	cfg, err := s.store.GetConfig(ctx, w.ChannelID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("channel not found")
+	}
+	if cfg.GetChannelType() != util.ChannelTypeAnthropic {
+		return 0, 0, fmt.Errorf("channel must be of type anthropic")
+	}
+	apiKeys, err := s.store.GetAPIKeys(ctx, cfg.ID)
+	if err != nil || len(apiKeys) == 0 {
+		return 0, 0, fmt.Errorf("channel has no API key")
+	}
+	key := apiKeys[0]
+	nowUnix := time.Now().Unix()
+	for _, k := range apiKeys {
+		if k.CooldownUntil <= nowUnix {
+			key = k
+			break
+		}
+	}
+
+	actualModel := w.Model
+	if redirect, ok := cfg.GetRedirectModel(w.Model); ok && redirect != "" {
+		actualModel = redirect
+	}
+	body, err := sonic.Marshal(map[string]any{
+		"model":      actualModel,
+		"max_tokens": 1,
+		"system": []any{map[string]any{
+			"type": "text", "text": w.Prompt, "cache_control": map[string]any{"type": "ephemeral"},
+		}},
+		"messages": []any{map[string]any{"role": "user", "content": cacheWarmupUserMessage}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	release, err := s.acquireAdminLane(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("admin lane busy: %w", err)
+	}
+	defer release()
+
+	const requestPath = "/v1/messages"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, buildUpstreamURL(cfg, requestPath, ""), bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	if profile, ok := s.clientProfile(cfg); ok {
+		profile.Apply(req.Header)
+	}
+	injectAPIKeyHeaders(req, key.APIKey, requestPath)
+
+	resp, err := s.httpClientFor(cfg).Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxUsageBodySize))
+	if err != nil {
+		return 0, 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, 0, fmt.Errorf("upstream HTTP %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Usage struct {
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		} `json:"usage"`
+	}
+	if err := sonic.Unmarshal(respBody, &parsed); err != nil {
+		return 0, 0, fmt.Errorf("invalid upstream response: %w", err)
+	}
+	return parsed.Usage.CacheReadInputTokens, parsed.Usage.CacheCreationInputTokens, nil
+}
+
+// cacheWarmupLoop 定期运行到期的预热计划
+func (s *Server) cacheWarmupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(cacheWarmupCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			s.runDueCacheWarmups()
+		}
+	}
+}
+
+func (s *Server) runDueCacheWarmups() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	warmups, err := s.store.ListCacheWarmups(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("[WARN] [缓存预热] 加载计划失败: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, w := range warmups {
+		if !w.Enabled || now.Sub(time.Unix(w.LastRunAt, 0)) < time.Duration(w.IntervalSeconds)*time.Second {
+			continue
+		}
+		if s.isShuttingDown.Load() {
+			return
+		}
+		runCtx, runCancel := context.WithTimeout(context.Background(), cacheWarmupRunTimeout)
+		s.runCacheWarmup(runCtx, w)
+		runCancel()
+	}
+}
+
+// HandleListCacheWarmups 提示缓存预热计划列表
+// GET /admin/cache-warmups
+func (s *Server) HandleListCacheWarmups(c *gin.Context) {
+	list, err := s.store.ListCacheWarmups(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, list)
+}
+
+// HandleCreateCacheWarmup 新增提示缓存预热计划（仅 anthropic 渠道）
+// POST /admin/cache-warmups
+func (s *Server) HandleCreateCacheWarmup(c *gin.Context) {
+	var req CacheWarmupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if req.ChannelID <= 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel_id is required")
+		return
+	}
+	cfg, err := s.store.GetConfig(c.Request.Context(), req.ChannelID)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel not found")
+		return
+	}
+	if cfg.GetChannelType() != util.ChannelTypeAnthropic {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel must be of type anthropic")
+		return
+	}
+
+	w := &model.CacheWarmup{ChannelID: req.ChannelID}
+	if !applyCacheWarmupRequest(c, cfg, w, &req) {
+		return
+	}
+	now := time.Now().Unix()
+	w.CreatedAt, w.UpdatedAt = now, now
+	if err := s.store.CreateCacheWarmup(c.Request.Context(), w); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusCreated, w)
+}
+
+// HandleUpdateCacheWarmup 更新提示缓存预热计划（不改变所属渠道与运行统计）
+// PUT /admin/cache-warmups/:id
+func (s *Server) HandleUpdateCacheWarmup(c *gin.Context) {
+	w, ok := s.loadCacheWarmup(c)
+	if !ok {
+		return
+	}
+	var req CacheWarmupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	cfg, err := s.store.GetConfig(c.Request.Context(), w.ChannelID)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel not found")
+		return
+	}
+	if !applyCacheWarmupRequest(c, cfg, w, &req) {
+		return
+	}
+	w.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateCacheWarmup(c.Request.Context(), w)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "cache warmup not found")
+		return
+	}
+	RespondJSON(c, http.StatusOK, w)
+}
+
+// HandleDeleteCacheWarmup 删除提示缓存预热计划
+// DELETE /admin/cache-warmups/:id
+func (s *Server) HandleDeleteCacheWarmup(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid cache warmup id")
+		return
+	}
+	found, err := s.store.DeleteCacheWarmup(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "cache warmup not found")
+		return
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"id": id, "deleted": true})
+}
+
+// HandleRunCacheWarmup 立即预热一次（计入运行统计，并顺延下次调度时间）
+// POST /admin/cache-warmups/:id/run
+func (s *Server) HandleRunCacheWarmup(c *gin.Context) {
+	w, ok := s.loadCacheWarmup(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), cacheWarmupRunTimeout)
+	defer cancel()
+	RespondJSON(c, http.StatusOK, s.runCacheWarmup(ctx, w))
+}
+
+// cacheWarmupStat 预热效果统计（/admin/stats/warmups）
+type cacheWarmupStat struct {
+	*model.CacheWarmup
+	ChannelName string  `json:"channel_name"`
+	HitRate     float64 `json:"hit_rate"`    // 累计 cache_read / (cache_read + cache_creation)，未产生缓存token时为0
+	NextRunAt   int64   `json:"next_run_at"` // Unix秒，计划停用时为0
+}
+
+// HandleCacheWarmupStats 各预热计划的运行次数、失败次数与缓存读取/写入token（提示不返回，避免大响应）
+// GET /admin/stats/warmups
+func (s *Server) HandleCacheWarmupStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	warmups, err := s.store.ListCacheWarmups(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	names := make(map[int64]string)
+	if configs, err := s.store.ListConfigs(ctx); err == nil {
+		for _, cfg := range configs {
+			names[cfg.ID] = cfg.Name
+		}
+	}
+	stats := make([]cacheWarmupStat, 0, len(warmups))
+	for _, w := range warmups {
+		st := cacheWarmupStat{CacheWarmup: w, ChannelName: names[w.ChannelID]}
+		if total := w.CacheReadTokens + w.CacheCreationTokens; total > 0 {
+			st.HitRate = float64(w.CacheReadTokens) / float64(total)
+		}
+		if w.Enabled {
+			st.NextRunAt = w.LastRunAt + int64(w.IntervalSeconds)
+		}
+		w.Prompt = ""
+		stats = append(stats, st)
+	}
+	RespondJSON(c, http.StatusOK, stats)
+}
+
+func (s *Server) loadCacheWarmup(c *gin.Context) (*model.CacheWarmup, bool) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid cache warmup id")
+		return nil, false
+	}
+	w, err := s.store.GetCacheWarmup(c.Request.Context(), id)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "cache warmup not found")
+		return nil, false
+	}
+	return w, true
+}
+
+// applyCacheWarmupRequest 校验并写入请求字段（模型为空时取渠道首个非通配符模型）
+func applyCacheWarmupRequest(c *gin.Context, cfg *model.Config, w *model.CacheWarmup, req *CacheWarmupRequest) bool {
+	w.Model = strings.TrimSpace(req.Model)
+	if w.Model == "" {
+		w.Model = statusProbeModel(cfg)
+	}
+	if w.Model == "" {
+		RespondErrorMsg(c, http.StatusBadRequest, "model is required")
+		return false
+	}
+	if !cfg.SupportsModel(w.Model) {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("channel does not support model %q", w.Model))
+		return false
+	}
+
+	w.Prompt = req.Prompt
+	if strings.TrimSpace(w.Prompt) == "" {
+		RespondErrorMsg(c, http.StatusBadRequest, "prompt is required")
+		return false
+	}
+	if len(w.Prompt) > maxCacheWarmupPromptBytes {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("prompt must not exceed %d bytes", maxCacheWarmupPromptBytes))
+		return false
+	}
+
+	w.IntervalSeconds = defaultCacheWarmupSeconds
+	if req.IntervalSeconds != nil {
+		w.IntervalSeconds = *req.IntervalSeconds
+	}
+	if w.IntervalSeconds < minCacheWarmupSeconds || w.IntervalSeconds > maxCacheWarmupSeconds {
+		RespondErrorMsg(c, http.StatusBadRequest,
+			fmt.Sprintf("interval_seconds must be between %d and %d", minCacheWarmupSeconds, maxCacheWarmupSeconds))
+		return false
+	}
+
+	w.Enabled = true
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+	return true
+}
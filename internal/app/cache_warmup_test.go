@@ -0,0 +1,116 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCacheWarmup_RunAndStats(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "k1" ||
+			!strings.Contains(string(body), `"cache_control":{"type":"ephemeral"}`) || !strings.Contains(string(body), `"max_tokens":1`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		usage := `{"input_tokens":1,"output_tokens":1,"cache_creation_input_tokens":1200,"cache_read_input_tokens":0}`
+		if calls.Add(1) > 1 {
+			usage = `{"input_tokens":1,"output_tokens":1,"cache_creation_input_tokens":0,"cache_read_input_tokens":1200}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"."}],"usage":` + usage + `}`))
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "warmup.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "claude", URL: upstream.URL, ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	openai, err := store.CreateConfig(ctx, &model.Config{
+		Name: "oa", URL: upstream.URL, ChannelType: "openai", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "gpt-4o"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	call := func(handler gin.HandlerFunc, method, path, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		handler(c)
+		return w
+	}
+
+	if w := call(srv.HandleCreateCacheWarmup, http.MethodPost, "/admin/cache-warmups",
+		`{"channel_id":`+strconv.FormatInt(openai.ID, 10)+`,"prompt":"x"}`, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("非 anthropic 渠道应被拒绝: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(srv.HandleCreateCacheWarmup, http.MethodPost, "/admin/cache-warmups",
+		`{"channel_id":`+strconv.FormatInt(cfg.ID, 10)+`,"prompt":"x","interval_seconds":5}`, nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("过短的间隔应被拒绝: %d %s", w.Code, w.Body.String())
+	}
+	w := call(srv.HandleCreateCacheWarmup, http.MethodPost, "/admin/cache-warmups",
+		`{"channel_id":`+strconv.FormatInt(cfg.ID, 10)+`,"prompt":"You are a helpful assistant."}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建预热计划失败: %d %s", w.Code, w.Body.String())
+	}
+	list, err := store.ListCacheWarmups(ctx)
+	if err != nil || len(list) != 1 || list[0].Model != "claude-sonnet-4-5" || list[0].IntervalSeconds != defaultCacheWarmupSeconds {
+		t.Fatalf("计划默认值不符: %+v err=%v", list, err)
+	}
+
+	// 首次由调度运行（从未运行即到期），第二次手动触发
+	srv.runDueCacheWarmups()
+	id := gin.Params{{Key: "id", Value: strconv.FormatInt(list[0].ID, 10)}}
+	if w := call(srv.HandleRunCacheWarmup, http.MethodPost, "/admin/cache-warmups/x/run", "", id); w.Code != http.StatusOK {
+		t.Fatalf("手动预热失败: %d %s", w.Code, w.Body.String())
+	}
+	srv.runDueCacheWarmups() // 未到期，不应再次运行
+	if calls.Load() != 2 {
+		t.Fatalf("上游应被调用2次，实际 %d", calls.Load())
+	}
+
+	got, err := store.GetCacheWarmup(ctx, list[0].ID)
+	if err != nil || got.Runs != 2 || got.Failures != 0 || !got.LastOK ||
+		got.LastCacheReadTokens != 1200 || got.CacheReadTokens != 1200 || got.CacheCreationTokens != 1200 {
+		t.Fatalf("运行统计不符: %+v err=%v", got, err)
+	}
+
+	w = call(srv.HandleCacheWarmupStats, http.MethodGet, "/admin/stats/warmups", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"hit_rate":0.5`) ||
+		!strings.Contains(w.Body.String(), `"channel_name":"claude"`) || strings.Contains(w.Body.String(), "helpful") {
+		t.Fatalf("统计结果不符: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,576 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 抓取记录对比（2026-10新增）
+// ============================================================================
+// 对比两条请求抓取记录（手动抓取或失败抓取），用于比较同一请求在不同渠道上的表现、或配置变更前后的差异：
+//   GET /admin/monitor/traces/diff?a=ID&b=ID
+// 返回：元数据差异、耗时/首字节/Token用量差值，入站/出站请求与上游响应的 URL、请求头、请求体差异。
+// 请求体/响应体均为JSON时按路径给出结构化差异；SSE响应先重组为完整结果（文本、思考、工具参数、
+// 结束原因、用量、事件计数）再对比；其余按文本对比并给出首个不同位置。
+// 抓取体本身最多保留 requestCaptureMaxBody 字节，任一侧被截断时差异可能不完整（truncated=true）。
+
+// maxCaptureDiffChanges 单个请求体/响应体最多返回的结构化差异条数
+const maxCaptureDiffChanges = 200
+
+// captureSummary 参与对比的记录概要
+type captureSummary struct {
+	ID          int64  `json:"id"`
+	At          int64  `json:"at"`
+	ChannelID   int64  `json:"channel_id"`
+	ChannelName string `json:"channel_name,omitempty"`
+	KeyIndex    int    `json:"key_index"`
+	Model       string `json:"model"`
+	ActualModel string `json:"actual_model"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ErrorClass  string `json:"error_class,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// captureValueDiff 两侧取值
+type captureValueDiff struct {
+	A any `json:"a"`
+	B any `json:"b"`
+}
+
+// captureNumberDiff 两侧数值与差值（b-a）
+type captureNumberDiff struct {
+	A     int64 `json:"a"`
+	B     int64 `json:"b"`
+	Delta int64 `json:"delta"`
+}
+
+func newCaptureNumberDiff(a, b int64) captureNumberDiff {
+	return captureNumberDiff{A: a, B: b, Delta: b - a}
+}
+
+// captureHeaderDiff 请求头差异：Added 仅 b 有，Removed 仅 a 有
+type captureHeaderDiff struct {
+	Added   map[string]string           `json:"added,omitempty"`
+	Removed map[string]string           `json:"removed,omitempty"`
+	Changed map[string]captureValueDiff `json:"changed,omitempty"`
+}
+
+// captureJSONChange 结构化差异条目（Path 形如 $.messages[0].content）
+type captureJSONChange struct {
+	Path string `json:"path"`
+	Op   string `json:"op"` // added / removed / changed
+	A    any    `json:"a,omitempty"`
+	B    any    `json:"b,omitempty"`
+}
+
+// captureBodyDiff 请求体/响应体差异
+type captureBodyDiff struct {
+	Format          string              `json:"format"` // json / sse / text / empty；两侧不同时为 "a格式/b格式"
+	Equal           bool                `json:"equal"`
+	Truncated       bool                `json:"truncated,omitempty"`
+	BytesA          int                 `json:"bytes_a"`
+	BytesB          int                 `json:"bytes_b"`
+	Changes         []captureJSONChange `json:"changes,omitempty"`
+	OmittedChanges  int                 `json:"omitted_changes,omitempty"`
+	FirstDiffOffset *int                `json:"first_diff_offset,omitempty"` // 文本对比时首个不同字节位置
+	ReassembledA    any                 `json:"reassembled_a,omitempty"`     // SSE 重组结果
+	ReassembledB    any                 `json:"reassembled_b,omitempty"`
+}
+
+// captureRequestDiff 入站/出站请求差异
+type captureRequestDiff struct {
+	Method  *captureValueDiff `json:"method,omitempty"`
+	URL     *captureValueDiff `json:"url,omitempty"`
+	Headers captureHeaderDiff `json:"headers"`
+	Body    captureBodyDiff   `json:"body"`
+}
+
+// captureResponseDiff 上游响应差异
+type captureResponseDiff struct {
+	Headers captureHeaderDiff `json:"headers"`
+	Body    captureBodyDiff   `json:"body"`
+}
+
+// captureDiffResult 对比结果
+type captureDiffResult struct {
+	A           captureSummary               `json:"a"`
+	B           captureSummary               `json:"b"`
+	Fields      map[string]captureValueDiff  `json:"fields"` // 取值不同的元数据字段
+	DurationMs  captureNumberDiff            `json:"duration_ms"`
+	FirstByteMs captureNumberDiff            `json:"first_byte_ms"`
+	Tokens      map[string]captureNumberDiff `json:"tokens,omitempty"` // 任一侧有用量时返回
+	Inbound     captureRequestDiff           `json:"inbound"`
+	Outbound    captureRequestDiff           `json:"outbound"`
+	Response    *captureResponseDiff         `json:"response,omitempty"` // 任一侧有响应快照时返回
+}
+
+func summarizeCapture(r *RequestCapture) captureSummary {
+	return captureSummary{
+		ID: r.ID, At: r.At, ChannelID: r.ChannelID, ChannelName: r.ChannelName, KeyIndex: r.KeyIndex,
+		Model: r.Model, ActualModel: r.ActualModel, StatusCode: r.StatusCode, ErrorClass: r.ErrorClass, Error: r.Error,
+	}
+}
+
+// diffCaptures 对比两条抓取记录
+func diffCaptures(a, b *RequestCapture) captureDiffResult {
+	res := captureDiffResult{
+		A:           summarizeCapture(a),
+		B:           summarizeCapture(b),
+		Fields:      map[string]captureValueDiff{},
+		DurationMs:  newCaptureNumberDiff(a.DurationMs, b.DurationMs),
+		FirstByteMs: newCaptureNumberDiff(a.FirstByteMs, b.FirstByteMs),
+		Inbound:     diffCapturedRequests(&a.Inbound, &b.Inbound),
+		Outbound:    diffCapturedRequests(&a.Outbound, &b.Outbound),
+	}
+	for _, f := range []struct {
+		name string
+		a, b any
+	}{
+		{"channel_id", a.ChannelID, b.ChannelID},
+		{"channel_name", a.ChannelName, b.ChannelName},
+		{"key_index", a.KeyIndex, b.KeyIndex},
+		{"model", a.Model, b.Model},
+		{"actual_model", a.ActualModel, b.ActualModel},
+		{"status_code", a.StatusCode, b.StatusCode},
+		{"error_class", a.ErrorClass, b.ErrorClass},
+		{"error", a.Error, b.Error},
+	} {
+		if f.a != f.b {
+			res.Fields[f.name] = captureValueDiff{A: f.a, B: f.b}
+		}
+	}
+
+	if a.Usage != nil || b.Usage != nil {
+		ua, ub := a.Usage, b.Usage
+		if ua == nil {
+			ua = &CapturedUsage{}
+		}
+		if ub == nil {
+			ub = &CapturedUsage{}
+		}
+		res.Tokens = map[string]captureNumberDiff{
+			"input_tokens":          newCaptureNumberDiff(int64(ua.InputTokens), int64(ub.InputTokens)),
+			"output_tokens":         newCaptureNumberDiff(int64(ua.OutputTokens), int64(ub.OutputTokens)),
+			"cache_read_tokens":     newCaptureNumberDiff(int64(ua.CacheReadTokens), int64(ub.CacheReadTokens)),
+			"cache_creation_tokens": newCaptureNumberDiff(int64(ua.CacheCreationTokens), int64(ub.CacheCreationTokens)),
+		}
+	}
+
+	if a.Response != nil || b.Response != nil {
+		ra, rb := a.Response, b.Response
+		if ra == nil {
+			ra = &CapturedResponse{}
+		}
+		if rb == nil {
+			rb = &CapturedResponse{}
+		}
+		res.Response = &captureResponseDiff{
+			Headers: diffCaptureHeaders(ra.Headers, rb.Headers),
+			Body:    diffCaptureBodies(ra.Body, rb.Body, ra.BodyBytes, rb.BodyBytes, ra.BodyTruncated || rb.BodyTruncated),
+		}
+	}
+	return res
+}
+
+func diffCapturedRequests(a, b *CapturedRequest) captureRequestDiff {
+	d := captureRequestDiff{
+		Headers: diffCaptureHeaders(a.Headers, b.Headers),
+		Body:    diffCaptureBodies(a.Body, b.Body, a.BodyBytes, b.BodyBytes, a.BodyTruncated || b.BodyTruncated),
+	}
+	if a.Method != b.Method {
+		d.Method = &captureValueDiff{A: a.Method, B: b.Method}
+	}
+	if a.URL != b.URL {
+		d.URL = &captureValueDiff{A: a.URL, B: b.URL}
+	}
+	return d
+}
+
+func diffCaptureHeaders(a, b map[string]string) captureHeaderDiff {
+	var d captureHeaderDiff
+	for k, va := range a {
+		vb, ok := b[k]
+		switch {
+		case !ok:
+			if d.Removed == nil {
+				d.Removed = map[string]string{}
+			}
+			d.Removed[k] = va
+		case va != vb:
+			if d.Changed == nil {
+				d.Changed = map[string]captureValueDiff{}
+			}
+			d.Changed[k] = captureValueDiff{A: va, B: vb}
+		}
+	}
+	for k, vb := range b {
+		if _, ok := a[k]; !ok {
+			if d.Added == nil {
+				d.Added = map[string]string{}
+			}
+			d.Added[k] = vb
+		}
+	}
+	return d
+}
+
+// decodeCaptureBody 按格式解码抓取体：JSON 解析为值，SSE 重组为摘要，其余返回 nil
+func decodeCaptureBody(body string) (string, any) {
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" {
+		return "empty", nil
+	}
+	var v any
+	if err := sonic.UnmarshalString(trimmed, &v); err == nil {
+		return "json", v
+	}
+	if strings.HasPrefix(trimmed, "data:") || strings.HasPrefix(trimmed, "event:") {
+		return "sse", reassembleSSE(body)
+	}
+	return "text", nil
+}
+
+func diffCaptureBodies(a, b string, bytesA, bytesB int, truncated bool) captureBodyDiff {
+	d := captureBodyDiff{Equal: a == b, Truncated: truncated, BytesA: bytesA, BytesB: bytesB}
+	fa, va := decodeCaptureBody(a)
+	fb, vb := decodeCaptureBody(b)
+	d.Format = fa
+	if fa != fb {
+		d.Format = fa + "/" + fb
+	}
+	if fa == "sse" {
+		d.ReassembledA = va
+	}
+	if fb == "sse" {
+		d.ReassembledB = vb
+	}
+	if d.Equal {
+		return d
+	}
+
+	// 两侧均可结构化（JSON 或 SSE 重组结果，或一侧为空）时给出结构化差异
+	if (va != nil || fa == "empty") && (vb != nil || fb == "empty") {
+		var changes []captureJSONChange
+		omitted := 0
+		diffCaptureJSON("$", va, vb, &changes, &omitted)
+		d.Changes, d.OmittedChanges = changes, omitted
+		return d
+	}
+	off := 0
+	for off < len(a) && off < len(b) && a[off] == b[off] {
+		off++
+	}
+	d.FirstDiffOffset = &off
+	return d
+}
+
+// diffCaptureJSON 递归对比两个JSON值（对象按键、数组按下标）
+func diffCaptureJSON(path string, a, b any, out *[]captureJSONChange, omitted *int) {
+	add := func(c captureJSONChange) {
+		if len(*out) >= maxCaptureDiffChanges {
+			*omitted++
+			return
+		}
+		*out = append(*out, c)
+	}
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		add(captureJSONChange{Path: path, Op: "added", B: b})
+		return
+	case b == nil:
+		add(captureJSONChange{Path: path, Op: "removed", A: a})
+		return
+	}
+
+	ma, okA := a.(map[string]any)
+	mb, okB := b.(map[string]any)
+	if okA && okB {
+		keys := make([]string, 0, len(ma)+len(mb))
+		for k := range ma {
+			keys = append(keys, k)
+		}
+		for k := range mb {
+			if _, ok := ma[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			va, inA := ma[k]
+			vb, inB := mb[k]
+			p := path + "." + k
+			switch {
+			case !inA:
+				add(captureJSONChange{Path: p, Op: "added", B: vb})
+			case !inB:
+				add(captureJSONChange{Path: p, Op: "removed", A: va})
+			default:
+				diffCaptureJSON(p, va, vb, out, omitted)
+			}
+		}
+		return
+	}
+
+	sa, okA := a.([]any)
+	sb, okB := b.([]any)
+	if okA && okB {
+		for i := 0; i < max(len(sa), len(sb)); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(sa):
+				add(captureJSONChange{Path: p, Op: "added", B: sb[i]})
+			case i >= len(sb):
+				add(captureJSONChange{Path: p, Op: "removed", A: sa[i]})
+			default:
+				diffCaptureJSON(p, sa[i], sb[i], out, omitted)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		add(captureJSONChange{Path: path, Op: "changed", A: a, B: b})
+	}
+}
+
+// reassembleSSE 将SSE流重组为完整结果（Anthropic/OpenAI Chat/OpenAI Responses/Gemini）：
+// 文本、思考内容与工具参数按块拼接（工具参数为合法JSON时解析为对象），保留结束原因、用量、错误与事件计数
+func reassembleSSE(body string) map[string]any {
+	events := parseSSEEvents([]byte(body))
+	var text, thinking strings.Builder
+	tools := map[string]*strings.Builder{}
+	toolNames := map[string]string{}
+	eventTypes := map[string]any{}
+	var stopReason, usage any
+	var errs []any
+	geminiCalls := 0
+
+	appendTool := func(key string, s any) {
+		str, _ := s.(string)
+		if tools[key] == nil {
+			tools[key] = &strings.Builder{}
+		}
+		tools[key].WriteString(str)
+	}
+	mergeUsage := func(u any) {
+		um, ok := u.(map[string]any)
+		if !ok {
+			return
+		}
+		merged, _ := usage.(map[string]any)
+		if merged == nil {
+			merged = map[string]any{}
+		}
+		for k, v := range um {
+			merged[k] = v
+		}
+		usage = merged
+	}
+
+	for _, ev := range events {
+		n, _ := eventTypes[ev.name].(int)
+		eventTypes[ev.name] = n + 1
+		if ev.data == "" || ev.data == "[DONE]" {
+			continue
+		}
+		var obj map[string]any
+		if err := sonic.UnmarshalString(ev.data, &obj); err != nil {
+			continue
+		}
+		if e, ok := obj["error"]; ok && e != nil {
+			errs = append(errs, e)
+		}
+		typ, _ := obj["type"].(string)
+		switch typ {
+		// Anthropic Messages
+		case "message_start":
+			if msg, ok := obj["message"].(map[string]any); ok {
+				mergeUsage(msg["usage"])
+			}
+			continue
+		case "content_block_start":
+			if cb, ok := obj["content_block"].(map[string]any); ok && cb["type"] == "tool_use" {
+				name, _ := cb["name"].(string)
+				toolNames[fmt.Sprint(obj["index"])] = name
+			}
+			continue
+		case "content_block_delta":
+			delta, _ := obj["delta"].(map[string]any)
+			switch delta["type"] {
+			case "text_delta":
+				text.WriteString(fmt.Sprint(delta["text"]))
+			case "thinking_delta":
+				thinking.WriteString(fmt.Sprint(delta["thinking"]))
+			case "input_json_delta":
+				appendTool(fmt.Sprint(obj["index"]), delta["partial_json"])
+			}
+			continue
+		case "message_delta":
+			if delta, ok := obj["delta"].(map[string]any); ok && delta["stop_reason"] != nil {
+				stopReason = delta["stop_reason"]
+			}
+			mergeUsage(obj["usage"])
+			continue
+		// OpenAI Responses
+		case "response.output_text.delta":
+			text.WriteString(fmt.Sprint(obj["delta"]))
+			continue
+		case "response.reasoning_text.delta", "response.reasoning_summary_text.delta":
+			thinking.WriteString(fmt.Sprint(obj["delta"]))
+			continue
+		case "response.function_call_arguments.delta":
+			appendTool(fmt.Sprint(obj["item_id"]), obj["delta"])
+			continue
+		case "response.completed", "response.incomplete", "response.failed":
+			if resp, ok := obj["response"].(map[string]any); ok {
+				stopReason = resp["status"]
+				mergeUsage(resp["usage"])
+			}
+			continue
+		}
+
+		// OpenAI Chat Completions
+		if choices, ok := obj["choices"].([]any); ok {
+			for _, ch := range choices {
+				choice, _ := ch.(map[string]any)
+				if delta, ok := choice["delta"].(map[string]any); ok {
+					if s, ok := delta["content"].(string); ok {
+						text.WriteString(s)
+					}
+					if s, ok := delta["reasoning_content"].(string); ok {
+						thinking.WriteString(s)
+					}
+					calls, _ := delta["tool_calls"].([]any)
+					for _, tc := range calls {
+						call, _ := tc.(map[string]any)
+						key := fmt.Sprint(call["index"])
+						fn, _ := call["function"].(map[string]any)
+						if name, ok := fn["name"].(string); ok && name != "" {
+							toolNames[key] = name
+						}
+						appendTool(key, fn["arguments"])
+					}
+				}
+				if fr := choice["finish_reason"]; fr != nil {
+					stopReason = fr
+				}
+			}
+		}
+		if u := obj["usage"]; u != nil {
+			mergeUsage(u)
+		}
+
+		// Gemini
+		if cands, ok := obj["candidates"].([]any); ok {
+			for _, c := range cands {
+				cand, _ := c.(map[string]any)
+				content, _ := cand["content"].(map[string]any)
+				parts, _ := content["parts"].([]any)
+				for _, p := range parts {
+					part, _ := p.(map[string]any)
+					if s, ok := part["text"].(string); ok {
+						if part["thought"] == true {
+							thinking.WriteString(s)
+						} else {
+							text.WriteString(s)
+						}
+					}
+					if fc, ok := part["functionCall"].(map[string]any); ok {
+						key := strconv.Itoa(geminiCalls)
+						geminiCalls++
+						name, _ := fc["name"].(string)
+						toolNames[key] = name
+						args, _ := sonic.MarshalString(fc["args"])
+						appendTool(key, args)
+					}
+				}
+				if fr := cand["finishReason"]; fr != nil {
+					stopReason = fr
+				}
+			}
+		}
+		if u := obj["usageMetadata"]; u != nil {
+			mergeUsage(u)
+		}
+	}
+
+	out := map[string]any{
+		"events":      len(events),
+		"event_types": eventTypes,
+		"text":        text.String(),
+	}
+	if thinking.Len() > 0 {
+		out["thinking"] = thinking.String()
+	}
+	if len(tools) > 0 {
+		inputs := map[string]any{}
+		for key, sb := range tools {
+			name := toolNames[key]
+			if name == "" {
+				name = key
+			} else {
+				name = key + ":" + name
+			}
+			var v any
+			if err := sonic.UnmarshalString(sb.String(), &v); err == nil {
+				inputs[name] = v
+			} else {
+				inputs[name] = sb.String()
+			}
+		}
+		out["tool_inputs"] = inputs
+	}
+	if stopReason != nil {
+		out["stop_reason"] = stopReason
+	}
+	if usage != nil {
+		out["usage"] = usage
+	}
+	if len(errs) > 0 {
+		out["errors"] = errs
+	}
+	return out
+}
+
+// findCapture 按ID查找抓取记录（手动抓取优先，其次失败抓取）
+func (s *Server) findCapture(id int64) (RequestCapture, bool) {
+	if r, ok := s.requestCaptures.get(id); ok {
+		return r, true
+	}
+	return s.errorCaptures.get(id, time.Now())
+}
+
+// HandleCaptureDiff 对比两条抓取记录
+// GET /admin/monitor/traces/diff?a=ID&b=ID
+func (s *Server) HandleCaptureDiff(c *gin.Context) {
+	ids := make([]int64, 0, 2)
+	for _, name := range []string{"a", "b"} {
+		id, err := strconv.ParseInt(c.Query(name), 10, 64)
+		if err != nil || id <= 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "a and b must be capture ids")
+			return
+		}
+		ids = append(ids, id)
+	}
+	recs := make([]RequestCapture, 0, 2)
+	for _, id := range ids {
+		r, ok := s.findCapture(id)
+		if !ok {
+			RespondErrorMsg(c, http.StatusNotFound, fmt.Sprintf("capture %d not found", id))
+			return
+		}
+		recs = append(recs, r)
+	}
+	RespondJSON(c, http.StatusOK, diffCaptures(&recs[0], &recs[1]))
+}
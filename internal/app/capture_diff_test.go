@@ -0,0 +1,125 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReassembleSSE_Anthropic(t *testing.T) {
+	stream := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":12}}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"lo\"}}\n\n" +
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"name\":\"get_weather\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"city\\\":\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"Paris\\\"}\"}}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":7}}\n\n"
+	got := reassembleSSE(stream)
+	if got["text"] != "Hello" || got["stop_reason"] != "tool_use" {
+		t.Fatalf("unexpected reassembly: %v", got)
+	}
+	tools, _ := got["tool_inputs"].(map[string]any)
+	if city := tools["1:get_weather"].(map[string]any)["city"]; city != "Paris" {
+		t.Fatalf("tool input not reassembled: %v", tools)
+	}
+	if usage := got["usage"].(map[string]any); usage["input_tokens"] != float64(12) || usage["output_tokens"] != float64(7) {
+		t.Fatalf("usage not merged: %v", usage)
+	}
+}
+
+func TestDiffCaptures(t *testing.T) {
+	a := &RequestCapture{
+		ID: 1, ChannelID: 1, ChannelName: "a", Model: "m", ActualModel: "m", StatusCode: 200, DurationMs: 900, FirstByteMs: 300,
+		Usage:    &CapturedUsage{InputTokens: 10, OutputTokens: 5},
+		Inbound:  CapturedRequest{Method: "POST", URL: "/v1/messages", Body: `{"model":"m","max_tokens":8}`},
+		Outbound: CapturedRequest{Method: "POST", URL: "https://a/v1/messages", Headers: map[string]string{"X-Trace": "1", "X-Old": "x"}, Body: `{"model":"m","max_tokens":8}`},
+		Response: &CapturedResponse{Body: "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n"},
+	}
+	b := &RequestCapture{
+		ID: 2, ChannelID: 2, ChannelName: "b", Model: "m", ActualModel: "m2", StatusCode: 200, DurationMs: 600, FirstByteMs: 350,
+		Usage:    &CapturedUsage{InputTokens: 10, OutputTokens: 9},
+		Inbound:  CapturedRequest{Method: "POST", URL: "/v1/messages", Body: `{"model":"m","max_tokens":8}`},
+		Outbound: CapturedRequest{Method: "POST", URL: "https://b/v1/messages", Headers: map[string]string{"X-Trace": "2", "X-New": "y"}, Body: `{"model":"m2","max_tokens":8,"stream":true}`},
+		Response: &CapturedResponse{Body: "data: {\"choices\":[{\"delta\":{\"content\":\"hello\"}}]}\n\ndata: {\"choices\":[{\"delta\":{},\"finish_reason\":\"length\"}]}\n\ndata: [DONE]\n\n"},
+	}
+	d := diffCaptures(a, b)
+
+	if _, ok := d.Fields["model"]; ok || d.Fields["actual_model"].B != "m2" || d.Fields["channel_id"].A != int64(1) {
+		t.Fatalf("unexpected field diff: %v", d.Fields)
+	}
+	if d.DurationMs.Delta != -300 || d.FirstByteMs.Delta != 50 || d.Tokens["output_tokens"].Delta != 4 {
+		t.Fatalf("unexpected numeric diff: %+v %+v %v", d.DurationMs, d.FirstByteMs, d.Tokens)
+	}
+	if !d.Inbound.Body.Equal || d.Inbound.URL != nil {
+		t.Fatalf("inbound should be identical: %+v", d.Inbound)
+	}
+	h := d.Outbound.Headers
+	if h.Added["X-New"] != "y" || h.Removed["X-Old"] != "x" || h.Changed["X-Trace"].B != "2" {
+		t.Fatalf("unexpected header diff: %+v", h)
+	}
+	want := map[string]string{"$.model": "changed", "$.stream": "added"}
+	if body := d.Outbound.Body; body.Format != "json" || len(body.Changes) != len(want) {
+		t.Fatalf("unexpected body diff: %+v", body)
+	}
+	for _, c := range d.Outbound.Body.Changes {
+		if want[c.Path] != c.Op {
+			t.Fatalf("unexpected change %+v", c)
+		}
+	}
+	resp := d.Response.Body
+	if resp.Format != "sse" || resp.ReassembledA == nil {
+		t.Fatalf("SSE responses should be reassembled: %+v", resp)
+	}
+	changed := map[string]bool{}
+	for _, c := range resp.Changes {
+		changed[c.Path] = true
+	}
+	if !changed["$.text"] || !changed["$.stop_reason"] || changed["$.events"] {
+		t.Fatalf("unexpected SSE diff: %+v", resp.Changes)
+	}
+}
+
+func TestHandleCaptureDiff(t *testing.T) {
+	server, _, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	server.requestCaptures = newRequestCaptureStore()
+	server.errorCaptures = newErrorCaptureStore(1<<20, time.Hour)
+
+	now := time.Now()
+	server.requestCaptures.setUntil(1, now.Add(time.Minute))
+	server.requestCaptures.add(RequestCapture{ID: 7, At: now.UnixMilli(), ChannelID: 1, StatusCode: 200, Inbound: CapturedRequest{Body: `{"a":1}`}})
+	server.errorCaptures.add(RequestCapture{ID: 8, At: now.UnixMilli(), ChannelID: 2, StatusCode: 502, Inbound: CapturedRequest{Body: `{"a":2}`}}, now)
+
+	do := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/monitor/traces/diff?"+query, nil)
+		server.HandleCaptureDiff(c)
+		return w
+	}
+
+	if w := do("a=7"); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing b should be rejected, got %d", w.Code)
+	}
+	if w := do("a=7&b=99"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown id should be 404, got %d", w.Code)
+	}
+	w := do("a=7&b=8")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data captureDiffResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.A.ID != 7 || resp.Data.B.ID != 8 || resp.Data.Fields["status_code"].B != float64(502) ||
+		len(resp.Data.Inbound.Body.Changes) != 1 {
+		t.Fatalf("unexpected diff: %+v", resp.Data)
+	}
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+)
+
+// ============================================================================
+// 无可用渠道时的503可用性摘要（2026-10新增）
+// ============================================================================
+// 所有渠道冷却/超限时，503 响应体在 error 文本之外附带 availability 字段：
+//   {"error":"...","availability":{"reason":"cooling","channel_types":["anthropic"],"channels_total":3,...}}
+// 客户端可据 soonest_recovery_seconds 决定等待还是放弃，运维可据 reason 判断是冷却、限额还是缺少渠道配置。
+// 令牌目前没有渠道级授权范围（仅限制模型，且模型不允许时已提前返回403），因此摘要中不包含令牌范围信息。
+
+// 不可用原因
+const (
+	availabilityNoChannels      = "no_channels"              // 没有支持该模型/协议的启用渠道
+	availabilityCooling         = "cooling"                  // 渠道均在冷却中（含全部Key冷却）
+	availabilityCostLimited     = "cost_limited"             // 渠道均已达到每日成本限额
+	availabilityCoolingAndLimit = "cooling_and_cost_limited" // 冷却与成本限额并存
+	availabilityKeysUnavailable = "keys_unavailable"         // 渠道未冷却，但转发时所有Key均不可用
+)
+
+// channelAvailability 503 响应中的渠道可用性摘要
+type channelAvailability struct {
+	Reason                 string   `json:"reason"`
+	Model                  string   `json:"model,omitempty"`
+	ChannelTypes           []string `json:"channel_types"`                      // 参与选路的渠道类型（含协议转换兼容渠道）
+	ChannelsTotal          int      `json:"channels_total"`                     // 支持该模型的启用渠道数
+	ChannelsCooling        int      `json:"channels_cooling"`                   // 冷却中（未超限额）的渠道数
+	ChannelsCostLimited    int      `json:"channels_cost_limited"`              // 已达每日成本限额的渠道数
+	SoonestRecoveryAt      string   `json:"soonest_recovery_at,omitempty"`      // 最早冷却结束时间（RFC3339）
+	SoonestRecoverySeconds int      `json:"soonest_recovery_seconds,omitempty"` // 距最早冷却结束的秒数（向上取整）
+	InFlightRequests       int      `json:"in_flight_requests"`                 // 当前占用的并发槽位
+	MaxConcurrency         int      `json:"max_concurrency"`
+}
+
+// buildChannelAvailability 按选路口径统计该请求可用渠道的状态
+func (s *Server) buildChannelAvailability(ctx context.Context, requestMethod, requestPath, originalModel string) *channelAvailability {
+	now := time.Now()
+	channelType := util.DetectChannelTypeFromPath(requestPath)
+	a := &channelAvailability{
+		Model:            originalModel,
+		ChannelTypes:     []string{},
+		InFlightRequests: len(s.concurrencySem),
+		MaxConcurrency:   s.maxConcurrency,
+	}
+	if channelType != "" {
+		a.ChannelTypes = append(a.ChannelTypes, channelType)
+	}
+
+	var channels []*model.Config
+	var err error
+	switch {
+	case channelType == "":
+	case requestMethod == http.MethodGet && channelType == util.ChannelTypeGemini:
+		channels, err = s.GetEnabledChannelsByType(ctx, util.ChannelTypeGemini)
+	default:
+		channels, err = s.scanEnabledChannelsForModel(ctx, originalModel, channelType)
+		if err == nil && !compatChannelsAllowed(channelType, requestMethod, requestPath) {
+			channels = filterExactChannelType(channels, channelType)
+		}
+	}
+	if err != nil {
+		channels = nil
+	}
+
+	channelCooldowns, err := s.getAllChannelCooldowns(ctx)
+	if err != nil {
+		channelCooldowns = map[int64]time.Time{}
+	}
+	keyCooldowns, err := s.getAllKeyCooldowns(ctx)
+	if err != nil {
+		keyCooldowns = map[int64]map[int]time.Time{}
+	}
+	var costs map[int64]float64
+	if s.costCache != nil {
+		costs = s.costCache.GetAll()
+	}
+
+	var soonest time.Time
+	for _, ch := range channels {
+		a.ChannelsTotal++
+		if t := ch.GetChannelType(); !slices.Contains(a.ChannelTypes, t) {
+			a.ChannelTypes = append(a.ChannelTypes, t)
+		}
+		if ch.DailyCostLimit > 0 && costs[ch.ID] >= ch.DailyCostLimit {
+			a.ChannelsCostLimited++
+			continue
+		}
+		if readyAt := channelReadyAt(ch, channelCooldowns, keyCooldowns, now); readyAt.After(now) {
+			a.ChannelsCooling++
+			if soonest.IsZero() || readyAt.Before(soonest) {
+				soonest = readyAt
+			}
+		}
+	}
+	if !soonest.IsZero() {
+		a.SoonestRecoveryAt = soonest.UTC().Format(time.RFC3339)
+		a.SoonestRecoverySeconds = int((soonest.Sub(now) + time.Second - 1) / time.Second)
+	}
+
+	switch {
+	case a.ChannelsTotal == 0:
+		a.Reason = availabilityNoChannels
+	case a.ChannelsCooling+a.ChannelsCostLimited < a.ChannelsTotal:
+		a.Reason = availabilityKeysUnavailable
+	case a.ChannelsCostLimited == 0:
+		a.Reason = availabilityCooling
+	case a.ChannelsCooling == 0:
+		a.Reason = availabilityCostLimited
+	default:
+		a.Reason = availabilityCoolingAndLimit
+	}
+	return a
+}
+
+// message 面向客户端的错误文本（同时用于请求日志）
+func (a *channelAvailability) message() string {
+	switch a.Reason {
+	case availabilityNoChannels:
+		return fmt.Sprintf("no available upstream: no enabled channel serves model '%s'", a.Model)
+	case availabilityCostLimited:
+		return fmt.Sprintf("no available upstream: all %d channel(s) reached daily cost limit", a.ChannelsTotal)
+	case availabilityKeysUnavailable:
+		return fmt.Sprintf("no available upstream: all keys unavailable on %d channel(s)", a.ChannelsTotal)
+	}
+	msg := fmt.Sprintf("no available upstream: %d of %d channel(s) cooling down", a.ChannelsCooling, a.ChannelsTotal)
+	if a.ChannelsCostLimited > 0 {
+		msg += fmt.Sprintf(", %d cost limited", a.ChannelsCostLimited)
+	}
+	if a.SoonestRecoverySeconds > 0 {
+		msg += fmt.Sprintf(", soonest recovery in %ds", a.SoonestRecoverySeconds)
+	}
+	return msg
+}
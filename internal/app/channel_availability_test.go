@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+func TestBuildChannelAvailability(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	create := func(name, channelType string) *model.Config {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: name, URL: "https://api.example.com", ChannelType: channelType, Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-opus"}},
+		})
+		if err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		return cfg
+	}
+
+	a := server.buildChannelAvailability(ctx, http.MethodPost, "/v1/messages", "claude-opus")
+	if a.Reason != availabilityNoChannels || a.ChannelsTotal != 0 || len(a.ChannelTypes) != 1 || a.ChannelTypes[0] != "anthropic" {
+		t.Fatalf("expected no_channels summary, got %+v", a)
+	}
+	if !strings.Contains(a.message(), "claude-opus") {
+		t.Fatalf("message should name the model: %s", a.message())
+	}
+
+	first := create("first", "anthropic")
+	second := create("second", "anthropic")
+	for id, d := range map[int64]time.Duration{first.ID: 30 * time.Second, second.ID: 90 * time.Second} {
+		if err := store.SetChannelCooldown(ctx, id, time.Now().Add(d)); err != nil {
+			t.Fatalf("set cooldown: %v", err)
+		}
+	}
+	a = server.buildChannelAvailability(ctx, http.MethodPost, "/v1/messages", "claude-opus")
+	if a.Reason != availabilityCooling || a.ChannelsTotal != 2 || a.ChannelsCooling != 2 {
+		t.Fatalf("expected both channels cooling, got %+v", a)
+	}
+	if a.SoonestRecoverySeconds < 29 || a.SoonestRecoverySeconds > 30 || a.SoonestRecoveryAt == "" {
+		t.Fatalf("soonest recovery should follow the earliest cooldown, got %+v", a)
+	}
+	if !strings.Contains(a.message(), "soonest recovery in") {
+		t.Fatalf("message should include recovery time: %s", a.message())
+	}
+
+	// 未冷却的渠道仍被计入：转发时Key全部不可用
+	_ = store.ResetChannelCooldown(ctx, first.ID)
+	if a = server.buildChannelAvailability(ctx, http.MethodPost, "/v1/messages", "claude-opus"); a.Reason != availabilityKeysUnavailable || a.ChannelsCooling != 1 {
+		t.Fatalf("expected keys_unavailable, got %+v", a)
+	}
+}
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/testutil"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 渠道定时健康检查（2026-10新增）
+// ============================================================================
+// 状态页探测只按渠道类型取首个候选渠道，无法发现排在后面的渠道已失效。
+// channel_health_check_interval_minutes > 0 时，后台定期对每个启用的渠道发起一次测试请求（复用渠道测试的
+// testutil.ChannelTester，max_tokens=16，走管理端低优先级出站通道，不触发冷却、不记日志/费用）：
+//   - 记录连续成功/失败次数与最近一次结果（channel_health_checks 表，重启不丢失）
+//   - 连续失败达到 channel_health_check_failure_threshold 次时自动禁用渠道
+//   - channel_health_check_auto_enable 开启时继续探测被自动禁用的渠道，探测成功后自动启用；
+//     手动禁用的渠道不探测、不自动启用；被自动禁用后管理员手动启用则清除自动禁用标记
+// GET /admin/channel-health-checks 查看状态，POST /admin/channels/:id/health-check 立即探测一次。
+
+const (
+	defaultChannelHealthFailThreshold = 3
+	maxChannelHealthFailThreshold     = 100
+	maxChannelHealthIntervalMinutes   = 1440
+	channelHealthProbeTimeout         = time.Minute
+)
+
+// channelHealthChecker 健康检查配置（启动时加载，修改后重启生效）
+type channelHealthChecker struct {
+	interval      time.Duration // 0 表示不定时探测（仍可手动探测）
+	failThreshold int
+	autoEnable    bool
+
+	mu sync.Mutex // 串行化探测轮次与手动探测，避免同一渠道的连续计数被并发覆盖
+}
+
+func newChannelHealthChecker(interval time.Duration, failThreshold int, autoEnable bool) *channelHealthChecker {
+	return &channelHealthChecker{interval: interval, failThreshold: failThreshold, autoEnable: autoEnable}
+}
+
+// channelHealthLoop 定期探测全部渠道
+func (s *Server) channelHealthLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.channelHealth.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			s.runChannelHealthChecks()
+		}
+	}
+}
+
+// runChannelHealthChecks 探测一轮：启用的渠道，以及（开启自动启用时）被自动禁用的渠道
+func (s *Server) runChannelHealthChecks() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	configs, err := s.store.ListConfigs(ctx)
+	var states map[int64]*model.ChannelHealthCheck
+	if err == nil {
+		states, err = s.channelHealthStates(ctx)
+	}
+	cancel()
+	if err != nil {
+		log.Printf("[WARN] [健康检查] 加载渠道失败: %v", err)
+		return
+	}
+
+	for _, cfg := range configs {
+		if s.isShuttingDown.Load() {
+			return
+		}
+		st := states[cfg.ID]
+		if !cfg.Enabled && (st == nil || !st.AutoDisabled || !s.channelHealth.autoEnable) {
+			continue
+		}
+		if _, err := s.checkChannelHealth(cfg.ID); err != nil {
+			log.Printf("[WARN] [健康检查] 探测渠道失败: channel_id=%d err=%v", cfg.ID, err)
+		}
+	}
+}
+
+func (s *Server) channelHealthStates(ctx context.Context) (map[int64]*model.ChannelHealthCheck, error) {
+	list, err := s.store.ListChannelHealthChecks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[int64]*model.ChannelHealthCheck, len(list))
+	for _, h := range list {
+		states[h.ChannelID] = h
+	}
+	return states, nil
+}
+
+// checkChannelHealth 探测单个渠道并更新连续计数，按阈值自动禁用/启用；渠道无可探测的模型或Key时返回 nil
+// 加锁后重新读取渠道与状态，避免探测轮次期间管理端修改（启用/禁用、手动探测）被旧快照覆盖
+func (s *Server) checkChannelHealth(channelID int64) (*model.ChannelHealthCheck, error) {
+	hc := s.channelHealth
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	loadCtx, loadCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	cfg, err := s.store.GetConfig(loadCtx, channelID)
+	if err != nil {
+		loadCancel()
+		return nil, err
+	}
+	states, err := s.channelHealthStates(loadCtx)
+	loadCancel()
+	if err != nil {
+		return nil, err
+	}
+
+	ok, latency, reason, probed := s.probeChannel(cfg)
+	if !probed {
+		return nil, nil
+	}
+
+	now := time.Now()
+	st := states[channelID]
+	if st == nil {
+		st = &model.ChannelHealthCheck{ChannelID: channelID}
+	}
+	// 被自动禁用后管理员已手动启用：清除标记，按普通启用渠道处理
+	if cfg.Enabled && st.AutoDisabled {
+		st.AutoDisabled, st.AutoDisabledAt = false, 0
+	}
+	st.LastCheckedAt = now.Unix()
+	st.LastOK = ok
+	st.LastError = reason
+	st.LastLatencyMs = latency.Milliseconds()
+	if ok {
+		st.PassStreak++
+		st.FailStreak = 0
+	} else {
+		st.FailStreak++
+		st.PassStreak = 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	switch {
+	case !ok && cfg.Enabled && st.FailStreak >= hc.failThreshold:
+		if err := s.store.SetChannelEnabled(ctx, cfg.ID, false); err != nil {
+			log.Printf("[WARN] [健康检查] 自动禁用渠道失败: channel_id=%d err=%v", cfg.ID, err)
+			break
+		}
+		st.AutoDisabled, st.AutoDisabledAt = true, now.Unix()
+		s.InvalidateChannelListCache()
+		log.Printf("[WARN] [健康检查] 渠道 %s(ID=%d) 连续%d次探测失败，已自动禁用: %s", cfg.Name, cfg.ID, st.FailStreak, reason)
+	case ok && !cfg.Enabled && st.AutoDisabled && hc.autoEnable:
+		if err := s.store.SetChannelEnabled(ctx, cfg.ID, true); err != nil {
+			log.Printf("[WARN] [健康检查] 自动启用渠道失败: channel_id=%d err=%v", cfg.ID, err)
+			break
+		}
+		st.AutoDisabled, st.AutoDisabledAt = false, 0
+		s.InvalidateChannelListCache()
+		log.Printf("[INFO] [健康检查] 渠道 %s(ID=%d) 探测恢复，已自动启用", cfg.Name, cfg.ID)
+	}
+
+	if err := s.store.SetChannelHealthCheck(ctx, st); err != nil {
+		return nil, fmt.Errorf("save health check state: %w", err)
+	}
+	return st, nil
+}
+
+// probeChannel 使用渠道测试发起一次最小请求（优先使用未冷却的Key）
+// probed=false 表示渠道没有可探测的模型或Key（不计入连续计数）
+func (s *Server) probeChannel(cfg *model.Config) (ok bool, latency time.Duration, reason string, probed bool) {
+	probeModel := statusProbeModel(cfg)
+	if probeModel == "" {
+		return false, 0, "", false
+	}
+	keyCtx, keyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	apiKeys, err := s.store.GetAPIKeys(keyCtx, cfg.ID)
+	keyCancel()
+	if err != nil || len(apiKeys) == 0 {
+		return false, 0, "", false
+	}
+	key := apiKeys[0]
+	nowUnix := time.Now().Unix()
+	for _, k := range apiKeys {
+		if k.CooldownUntil <= nowUnix {
+			key = k
+			break
+		}
+	}
+
+	laneCtx, laneCancel := context.WithTimeout(context.Background(), channelHealthProbeTimeout)
+	release, err := s.acquireAdminLane(laneCtx)
+	laneCancel()
+	if err != nil {
+		return false, 0, "", false // 管理端通道繁忙，跳过本轮
+	}
+	defer release()
+
+	start := time.Now()
+	result := s.testChannelAPI(cfg, key.APIKey, &testutil.TestChannelRequest{
+		Model:       probeModel,
+		MaxTokens:   statusProbeMaxTokens,
+		Content:     statusProbeContent,
+		ChannelType: cfg.GetChannelType(),
+	})
+	latency = time.Since(start)
+	if success, _ := result["success"].(bool); success {
+		return true, latency, "", true
+	}
+	if code, _ := result["status_code"].(int); code > 0 {
+		return false, latency, fmt.Sprintf("upstream HTTP %d", code), true
+	}
+	msg, _ := result["error"].(string)
+	if msg == "" {
+		msg = "network error"
+	}
+	return false, latency, util.RedactSecrets(msg), true
+}
+
+// channelHealthView 管理接口返回的健康检查状态（附带渠道名与启用状态）
+type channelHealthView struct {
+	*model.ChannelHealthCheck
+	ChannelName string `json:"channel_name"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// HandleListChannelHealthChecks 渠道健康检查状态列表
+// GET /admin/channel-health-checks
+func (s *Server) HandleListChannelHealthChecks(c *gin.Context) {
+	ctx := c.Request.Context()
+	configs, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	states, err := s.channelHealthStates(ctx)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	hc := s.channelHealth
+	views := make([]channelHealthView, 0, len(configs))
+	for _, cfg := range configs {
+		st := states[cfg.ID]
+		if st == nil {
+			st = &model.ChannelHealthCheck{ChannelID: cfg.ID}
+		}
+		views = append(views, channelHealthView{ChannelHealthCheck: st, ChannelName: cfg.Name, Enabled: cfg.Enabled})
+	}
+	RespondJSON(c, http.StatusOK, gin.H{
+		"interval_minutes":  int(hc.interval / time.Minute),
+		"failure_threshold": hc.failThreshold,
+		"auto_enable":       hc.autoEnable,
+		"channels":          views,
+	})
+}
+
+// HandleCheckChannelHealth 立即探测一次渠道（与定时探测相同的计数与自动禁用/启用规则）
+// POST /admin/channels/:id/health-check
+func (s *Server) HandleCheckChannelHealth(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	if _, err := s.store.GetConfig(c.Request.Context(), id); err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "channel not found")
+		return
+	}
+	st, err := s.checkChannelHealth(id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if st == nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel has no probeable model or API key")
+		return
+	}
+	RespondJSON(c, http.StatusOK, st)
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+)
+
+func TestChannelHealthCheck_AutoDisableAndEnable(t *testing.T) {
+	var healthy atomic.Bool
+	var probes atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		probes.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad gateway"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	store, _ := storage.CreateSQLiteStore(":memory:", nil)
+	srv := NewServer(store)
+	srv.channelHealth = newChannelHealthChecker(0, 2, true)
+
+	ctx := context.Background()
+	newChannel := func(name string) *model.Config {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: name, URL: upstream.URL, ChannelType: "anthropic", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-x"}},
+		})
+		if err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+		if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+			{ChannelID: cfg.ID, APIKey: "k-" + name, KeyStrategy: model.KeyStrategySequential},
+		}); err != nil {
+			t.Fatalf("创建Key失败: %v", err)
+		}
+		return cfg
+	}
+	auto := newChannel("auto")
+	manual := newChannel("manual")
+	if err := store.SetChannelEnabled(ctx, manual.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次失败未达阈值，第二次失败自动禁用
+	st, err := srv.checkChannelHealth(auto.ID)
+	if err != nil || st == nil || st.FailStreak != 1 || st.AutoDisabled {
+		t.Fatalf("first failure: st=%+v err=%v", st, err)
+	}
+	st, err = srv.checkChannelHealth(auto.ID)
+	if err != nil || st == nil || st.FailStreak != 2 || !st.AutoDisabled || st.LastError != "upstream HTTP 502" {
+		t.Fatalf("second failure: st=%+v err=%v", st, err)
+	}
+	if cfg, _ := store.GetConfig(ctx, auto.ID); cfg.Enabled {
+		t.Fatal("channel should be auto-disabled")
+	}
+
+	// 恢复后一轮探测即自动启用；手动禁用的渠道不探测
+	healthy.Store(true)
+	before := probes.Load()
+	srv.runChannelHealthChecks()
+	if got := probes.Load() - before; got != 1 {
+		t.Fatalf("expected only the auto-disabled channel to be probed, got %d probes", got)
+	}
+	if cfg, _ := store.GetConfig(ctx, auto.ID); !cfg.Enabled {
+		t.Fatal("channel should be re-enabled after a successful probe")
+	}
+	if cfg, _ := store.GetConfig(ctx, manual.ID); cfg.Enabled {
+		t.Fatal("manually disabled channel must stay disabled")
+	}
+
+	list, err := store.ListChannelHealthChecks(ctx)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected 1 health record, got %v err=%v", list, err)
+	}
+	if h := list[0]; h.ChannelID != auto.ID || !h.LastOK || h.PassStreak != 1 || h.FailStreak != 0 || h.AutoDisabled {
+		t.Fatalf("unexpected health record: %+v", h)
+	}
+}
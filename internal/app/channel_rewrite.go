@@ -0,0 +1,331 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 渠道请求体改写规则（2026-10新增）
+// ============================================================================
+// 每个渠道可配置一组 JSON 改写规则，在 prepareRequestBody 中（模型重定向之后、协议转换之前）按顺序应用：
+//   {"op":"remove","path":"metadata"}                      移除字段（点号路径，如 thinking.budget_tokens）
+//   {"op":"set","path":"temperature","value":1}            强制设置字段
+//   {"op":"default","path":"max_tokens","value":4096}      字段不存在时设置默认值
+//   {"op":"cap","path":"max_tokens","value":8192}          数值字段超过上限时截断
+//   {"op":"system","value":"You are ..."}                  请求未携带系统提示词时注入（按请求协议写入对应字段）
+// 每条规则可选 "models": ["claude-*"]，仅对匹配的实际模型（重定向后）生效。
+// 规则作用于客户端请求格式；请求体不是 JSON 对象时原样转发。
+
+const (
+	rewriteOpRemove  = "remove"
+	rewriteOpSet     = "set"
+	rewriteOpDefault = "default"
+	rewriteOpCap     = "cap"
+	rewriteOpSystem  = "system"
+
+	maxRewriteRules     = 32
+	maxRewriteRulesJSON = 4096 // 与 channels.rewrite_rules 列宽一致
+)
+
+// bodyRewriteRule 单条请求体改写规则
+type bodyRewriteRule struct {
+	Op     string   `json:"op"`
+	Path   string   `json:"path,omitempty"`
+	Value  any      `json:"value,omitempty"`
+	Models []string `json:"models,omitempty"` // 实际模型匹配（支持 * 通配），空表示全部模型
+}
+
+// validate 校验单条规则
+func (r *bodyRewriteRule) validate() error {
+	r.Op = strings.ToLower(strings.TrimSpace(r.Op))
+	r.Path = strings.TrimSpace(r.Path)
+	switch r.Op {
+	case rewriteOpRemove, rewriteOpSet, rewriteOpDefault, rewriteOpCap:
+		if r.Path == "" {
+			return fmt.Errorf("op %s requires path", r.Op)
+		}
+		for seg := range strings.SplitSeq(r.Path, ".") {
+			if seg == "" {
+				return fmt.Errorf("invalid path %q", r.Path)
+			}
+		}
+		if r.Path == "model" || r.Path == "stream" {
+			return fmt.Errorf("path %q cannot be rewritten (use model redirects instead)", r.Path)
+		}
+	case rewriteOpSystem:
+		if s, ok := r.Value.(string); !ok || strings.TrimSpace(s) == "" {
+			return fmt.Errorf("op system requires a non-empty string value")
+		}
+		r.Path = ""
+	default:
+		return fmt.Errorf("unknown op %q (remove/set/default/cap/system)", r.Op)
+	}
+	switch r.Op {
+	case rewriteOpSet, rewriteOpDefault:
+		if r.Value == nil {
+			return fmt.Errorf("op %s requires value", r.Op)
+		}
+	case rewriteOpCap:
+		if v, ok := r.Value.(float64); !ok || v < 0 {
+			return fmt.Errorf("op cap requires a non-negative number value")
+		}
+	case rewriteOpRemove:
+		r.Value = nil
+	}
+	models := r.Models[:0]
+	for _, m := range r.Models {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	r.Models = models
+	return nil
+}
+
+// matchesModel 规则是否适用于该模型
+func (r *bodyRewriteRule) matchesModel(actualModel string) bool {
+	if len(r.Models) == 0 {
+		return true
+	}
+	for _, p := range r.Models {
+		if model.MatchModelPattern(p, actualModel) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRewriteRules 校验规则并序列化为存储格式（空列表返回空串）
+func normalizeRewriteRules(rules []bodyRewriteRule) (string, error) {
+	if len(rules) == 0 {
+		return "", nil
+	}
+	if len(rules) > maxRewriteRules {
+		return "", fmt.Errorf("too many rules (max %d)", maxRewriteRules)
+	}
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return "", fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	data, err := sonic.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxRewriteRulesJSON {
+		return "", fmt.Errorf("rules too large (max %d bytes)", maxRewriteRulesJSON)
+	}
+	return string(data), nil
+}
+
+// parseRewriteRules 解析已存储的规则（存储前已校验，解析失败视为无规则）
+func parseRewriteRules(raw string) []bodyRewriteRule {
+	if raw == "" {
+		return nil
+	}
+	var rules []bodyRewriteRule
+	if err := sonic.UnmarshalString(raw, &rules); err != nil {
+		return nil
+	}
+	return rules
+}
+
+// applyRewriteRules 按顺序应用规则，返回是否修改了请求体
+func applyRewriteRules(rules []bodyRewriteRule, reqData map[string]any, requestPath, actualModel string) bool {
+	changed := false
+	for i := range rules {
+		r := &rules[i]
+		if !r.matchesModel(actualModel) {
+			continue
+		}
+		if r.Op == rewriteOpSystem {
+			if injectSystemPrompt(reqData, requestPath, r.Value.(string)) {
+				changed = true
+			}
+			continue
+		}
+
+		segs := strings.Split(r.Path, ".")
+		parent := reqData
+		for _, seg := range segs[:len(segs)-1] {
+			next, ok := parent[seg].(map[string]any)
+			if !ok {
+				if r.Op != rewriteOpSet && r.Op != rewriteOpDefault {
+					parent = nil
+					break
+				}
+				next = make(map[string]any)
+				parent[seg] = next
+			}
+			parent = next
+		}
+		if parent == nil {
+			continue
+		}
+		leaf := segs[len(segs)-1]
+		current, exists := parent[leaf]
+
+		switch r.Op {
+		case rewriteOpRemove:
+			if exists {
+				delete(parent, leaf)
+				changed = true
+			}
+		case rewriteOpSet:
+			parent[leaf] = r.Value
+			changed = true
+		case rewriteOpDefault:
+			if !exists || current == nil {
+				parent[leaf] = r.Value
+				changed = true
+			}
+		case rewriteOpCap:
+			limit, _ := r.Value.(float64)
+			if n, ok := jsonNumber(current); ok && n > limit {
+				parent[leaf] = limit
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// jsonNumber 将解码后的 JSON 数值转换为 float64
+func jsonNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// injectSystemPrompt 请求未携带系统提示词时注入（按请求协议选择字段）
+func injectSystemPrompt(reqData map[string]any, requestPath, prompt string) bool {
+	isEmpty := func(v any) bool {
+		switch t := v.(type) {
+		case nil:
+			return true
+		case string:
+			return strings.TrimSpace(t) == ""
+		case []any:
+			return len(t) == 0
+		}
+		return false
+	}
+
+	switch {
+	case strings.Contains(requestPath, "/chat/completions"):
+		// OpenAI Chat：system/developer 消息
+		msgs, _ := reqData["messages"].([]any)
+		for _, m := range msgs {
+			if mm, ok := m.(map[string]any); ok && (mm["role"] == "system" || mm["role"] == "developer") {
+				return false
+			}
+		}
+		reqData["messages"] = append([]any{map[string]any{"role": "system", "content": prompt}}, msgs...)
+	case reqData["contents"] != nil:
+		// Gemini：systemInstruction（兼容下划线写法）
+		if !isEmpty(reqData["systemInstruction"]) || !isEmpty(reqData["system_instruction"]) {
+			return false
+		}
+		reqData["systemInstruction"] = map[string]any{"parts": []any{map[string]any{"text": prompt}}}
+	case reqData["input"] != nil && reqData["messages"] == nil:
+		// OpenAI Responses（Codex）：instructions
+		if !isEmpty(reqData["instructions"]) {
+			return false
+		}
+		reqData["instructions"] = prompt
+	case reqData["messages"] != nil:
+		// Anthropic Messages：system
+		if !isEmpty(reqData["system"]) {
+			return false
+		}
+		reqData["system"] = prompt
+	default:
+		return false
+	}
+	return true
+}
+
+// ============================================================================
+// Admin API
+// ============================================================================
+
+// HandleGetChannelRewrites 获取渠道请求体改写规则
+// GET /admin/channels/:id/rewrites
+func (s *Server) HandleGetChannelRewrites(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	cfg, err := s.store.GetConfig(c.Request.Context(), channelID)
+	if err != nil {
+		RespondError(c, http.StatusNotFound, err)
+		return
+	}
+	rules := parseRewriteRules(cfg.RewriteRules)
+	if rules == nil {
+		rules = []bodyRewriteRule{}
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "rules": rules})
+}
+
+// HandleSetChannelRewrites 替换渠道请求体改写规则（空列表表示清除）
+// PUT /admin/channels/:id/rewrites
+func (s *Server) HandleSetChannelRewrites(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	var req struct {
+		Rules []bodyRewriteRule `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+	raw, err := normalizeRewriteRules(req.Rules)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.saveChannelRewrites(c, channelID, raw)
+}
+
+// HandleDeleteChannelRewrites 清除渠道请求体改写规则
+// DELETE /admin/channels/:id/rewrites
+func (s *Server) HandleDeleteChannelRewrites(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	s.saveChannelRewrites(c, channelID, "")
+}
+
+func (s *Server) saveChannelRewrites(c *gin.Context, channelID int64, raw string) {
+	if err := s.store.SetChannelRewriteRules(c.Request.Context(), channelID, raw); err != nil {
+		RespondError(c, http.StatusNotFound, err)
+		return
+	}
+	s.InvalidateChannelListCache()
+
+	rules := parseRewriteRules(raw)
+	if rules == nil {
+		rules = []bodyRewriteRule{}
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "rules": rules})
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestApplyRewriteRules(t *testing.T) {
+	raw, err := normalizeRewriteRules([]bodyRewriteRule{
+		{Op: "remove", Path: "metadata"},
+		{Op: "cap", Path: "max_tokens", Value: float64(4096)},
+		{Op: "default", Path: "thinking.budget_tokens", Value: float64(1024), Models: []string{"claude-*"}},
+		{Op: "set", Path: "temperature", Value: float64(1), Models: []string{"gpt-*"}},
+		{Op: "system", Value: "be brief"},
+	})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	rules := parseRewriteRules(raw)
+
+	reqData := map[string]any{
+		"model": "claude-opus", "max_tokens": float64(32000), "metadata": map[string]any{"user_id": "u"},
+		"messages": []any{map[string]any{"role": "user", "content": "hi"}},
+	}
+	if !applyRewriteRules(rules, reqData, "/v1/messages", "claude-opus") {
+		t.Fatal("expected body to change")
+	}
+	if _, ok := reqData["metadata"]; ok {
+		t.Fatal("metadata should be removed")
+	}
+	if reqData["max_tokens"] != float64(4096) {
+		t.Fatalf("max_tokens should be capped, got %v", reqData["max_tokens"])
+	}
+	if reqData["thinking"].(map[string]any)["budget_tokens"] != float64(1024) {
+		t.Fatal("nested default should be created")
+	}
+	if _, ok := reqData["temperature"]; ok {
+		t.Fatal("rule scoped to gpt-* must not apply to claude models")
+	}
+	if reqData["system"] != "be brief" {
+		t.Fatalf("anthropic system prompt should be injected, got %v", reqData["system"])
+	}
+
+	// 已有系统提示词：不覆盖
+	chat := map[string]any{"messages": []any{map[string]any{"role": "system", "content": "keep"}}}
+	applyRewriteRules(rules, chat, "/v1/chat/completions", "gpt-4o")
+	if msgs := chat["messages"].([]any); len(msgs) != 1 || chat["temperature"] != float64(1) {
+		t.Fatalf("existing system message must be kept and gpt rule applied, got %v", chat)
+	}
+	gemini := map[string]any{"contents": []any{}}
+	applyRewriteRules(rules, gemini, "/v1beta/models/gemini-pro:generateContent", "gemini-pro")
+	if gemini["systemInstruction"] == nil {
+		t.Fatal("gemini systemInstruction should be injected")
+	}
+
+	for _, bad := range []bodyRewriteRule{
+		{Op: "rename", Path: "a"},
+		{Op: "remove"},
+		{Op: "set", Path: "model", Value: "x"},
+		{Op: "cap", Path: "max_tokens", Value: "big"},
+		{Op: "system", Value: ""},
+		{Op: "remove", Path: "a..b"},
+	} {
+		if _, err := normalizeRewriteRules([]bodyRewriteRule{bad}); err == nil {
+			t.Fatalf("rule %+v should be rejected", bad)
+		}
+	}
+}
+
+func TestPrepareRequestBody_RewriteRules(t *testing.T) {
+	cfg := &model.Config{
+		ModelEntries: []model.ModelEntry{{Model: "claude-a", RedirectModel: "claude-b"}},
+		RewriteRules: `[{"op":"remove","path":"metadata"}]`,
+	}
+	reqCtx := &proxyRequestContext{originalModel: "claude-a", requestPath: "/v1/messages",
+		body: []byte(`{"model":"claude-a","metadata":{"user_id":"u"},"messages":[]}`)}
+	actual, body := prepareRequestBody(cfg, reqCtx)
+	var got map[string]any
+	_ = json.Unmarshal(body, &got)
+	if actual != "claude-b" || got["model"] != "claude-b" || got["metadata"] != nil {
+		t.Fatalf("redirect and rewrite should both apply, got %s", body)
+	}
+
+	// 无改动时原样转发
+	cfg = &model.Config{RewriteRules: `[{"op":"remove","path":"metadata"}]`}
+	reqCtx.body = []byte(`{"model":"claude-a"}`)
+	if _, body := prepareRequestBody(cfg, reqCtx); !bytes.Equal(body, reqCtx.body) {
+		t.Fatalf("unchanged body should be forwarded as-is, got %s", body)
+	}
+}
+
+func TestHandleChannelRewritesCRUD(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{Name: "rw", URL: "https://api.example.com", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/admin/channels/:id/rewrites", server.HandleGetChannelRewrites)
+	r.PUT("/admin/channels/:id/rewrites", server.HandleSetChannelRewrites)
+	r.DELETE("/admin/channels/:id/rewrites", server.HandleDeleteChannelRewrites)
+	do := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/admin/channels/"+strconv.FormatInt(cfg.ID, 10)+"/rewrites", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPut, `{"rules":[{"op":"bogus"}]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid rule should be rejected, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, `{"rules":[{"op":"cap","path":"max_tokens","value":8192}]}`); w.Code != http.StatusOK {
+		t.Fatalf("set rules: %d %s", w.Code, w.Body.String())
+	}
+
+	// 渠道编辑保存不覆盖改写规则
+	loaded, _ := store.GetConfig(ctx, cfg.ID)
+	loaded.RewriteRules = ""
+	if _, err := store.UpdateConfig(ctx, cfg.ID, loaded); err != nil {
+		t.Fatal(err)
+	}
+	w := do(http.MethodGet, "")
+	var resp struct {
+		Data struct {
+			Rules []bodyRewriteRule `json:"rules"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data.Rules) != 1 || resp.Data.Rules[0].Op != rewriteOpCap {
+		t.Fatalf("rules should survive channel update, got %s", w.Body.String())
+	}
+
+	if w := do(http.MethodDelete, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete rules: %d", w.Code)
+	}
+	if loaded, _ := store.GetConfig(ctx, cfg.ID); loaded.RewriteRules != "" {
+		t.Fatalf("rules should be cleared, got %q", loaded.RewriteRules)
+	}
+}
@@ -14,7 +14,7 @@ import (
 
 // ConfigService 配置管理服务
 // 职责: 启动时从数据库加载配置，提供只读访问
-// 配置修改后程序会自动重启，无需热重载
+// 配置修改后程序会自动重启；少数配置项支持热更新（见 hotReloadSettings）
 type ConfigService struct {
 	store  storage.Store
 	mu     sync.RWMutex                    // 保护 cache 并发访问
@@ -154,6 +154,17 @@ func (cs *ConfigService) UpdateSetting(ctx context.Context, key, value string) e
 	return cs.store.UpdateSetting(ctx, key, value)
 }
 
+// setCachedValue 热更新配置项后同步缓存（写时复制，不修改已返回给调用方的对象）
+func (cs *ConfigService) setCachedValue(key, value string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if old, ok := cs.cache[key]; ok {
+		updated := *old
+		updated.Value = value
+		cs.cache[key] = &updated
+	}
+}
+
 // ListAllSettings 获取所有配置(用于前端展示)
 func (cs *ConfigService) ListAllSettings(ctx context.Context) ([]*model.SystemSetting, error) {
 	return cs.store.ListAllSettings(ctx)
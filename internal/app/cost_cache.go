@@ -38,17 +38,16 @@ func (c *CostCache) checkAndResetIfNewDay(now time.Time) {
 	}
 }
 
-// Add 累加成本（请求完成后调用）
-func (c *CostCache) Add(channelID int64, cost float64) {
-	if cost <= 0 {
-		return
-	}
-
+// Add 累加成本（请求完成后调用），返回累加后的渠道今日成本
+func (c *CostCache) Add(channelID int64, cost float64) float64 {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.checkAndResetIfNewDay(time.Now())
-	c.costs[channelID] += cost
+	if cost > 0 {
+		c.costs[channelID] += cost
+	}
+	return c.costs[channelID]
 }
 
 // Get 获取渠道今日成本
@@ -0,0 +1,365 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 历史费用重算（2026-10新增）
+// ============================================================================
+// 上游追溯调价（如官方降价）后，按当前定价表重算指定时间范围内日志的费用：
+// - logs.cost 按 实际模型 + Token明细 + 日志时间生效的价格（价格表优先，见 model_price.go）重新计算，
+//   并乘以渠道当前的价格倍率（已删除渠道按官方定价）
+// - 差额同步到 auth_tokens.total_cost_usd / cost_used_microusd（令牌限额随之修正）
+// - 后台任务按日志ID分批推进，每批与游标同事务提交：暂停、重启后从游标继续，不会重复累加
+// 当前定价未知的模型（新费用为0）保留原费用，避免定价缺失时把历史费用清零。
+
+const (
+	costRecomputeBatchSize = 500
+	costRecomputeListLimit = 20
+	costRecomputeEpsilon   = 1e-9
+)
+
+// costRecomputeRunner 当前运行中的重算任务（同一时间只允许一个）
+type costRecomputeRunner struct {
+	mu     sync.Mutex
+	jobID  int64
+	cancel context.CancelFunc
+}
+
+// acquire 占用运行槽位，已有任务运行时返回 false
+func (r *costRecomputeRunner) acquire(jobID int64, cancel context.CancelFunc) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobID != 0 {
+		return false
+	}
+	r.jobID, r.cancel = jobID, cancel
+	return true
+}
+
+func (r *costRecomputeRunner) release(jobID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobID == jobID {
+		r.jobID, r.cancel = 0, nil
+	}
+}
+
+// stop 取消指定任务，任务未在运行时返回 false
+func (r *costRecomputeRunner) stop(jobID int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobID != jobID || r.cancel == nil {
+		return false
+	}
+	r.cancel()
+	return true
+}
+
+func (r *costRecomputeRunner) running() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.jobID
+}
+
+// recomputeLogCost 按日志时间生效的定价重算单条日志费用；无Token或模型定价缺失时返回原费用
+func recomputeLogCost(row model.LogCostRow, multipliers map[int64]float64) float64 {
+	if row.InputTokens == 0 && row.OutputTokens == 0 && row.CacheReadTokens == 0 &&
+		row.CacheCreationTokens == 0 && row.Cache5mInputTokens == 0 && row.Cache1hInputTokens == 0 {
+		return row.Cost
+	}
+	costModel := row.ActualModel
+	if costModel == "" {
+		costModel = row.Model
+	}
+	// 旧日志只记录了缓存写入总数时按5分钟TTL补齐，与实时计费口径一致
+	cache5m, cache1h := util.SplitCacheCreationTokens(row.CacheCreationTokens, row.Cache5mInputTokens, row.Cache1hInputTokens)
+	cost := util.CalculateCostDetailedAt(costModel, time.UnixMilli(row.Time), row.InputTokens, row.OutputTokens,
+		row.CacheReadTokens, cache5m, cache1h)
+	if cost == 0 {
+		return row.Cost
+	}
+	if mult, ok := multipliers[row.ChannelID]; ok && mult > 0 {
+		cost *= mult
+	}
+	return cost
+}
+
+// setCacheCostMultipliers 更新缓存Token计费倍率覆盖（启动加载与设置热更新共用）
+// 无效配置在保存时已被校验拦截；启动时若数据库中的值无效则沿用内置倍率
+func (s *Server) setCacheCostMultipliers(value string) {
+	if err := util.SetCacheCostMultipliers(value); err != nil {
+		log.Printf("[WARN] 无效的 cost_cache_multipliers 配置，已使用内置倍率: %v", err)
+		_ = util.SetCacheCostMultipliers("")
+	}
+}
+
+// startCostRecompute 在后台运行（或继续）重算任务
+func (s *Server) startCostRecompute(job *model.CostRecomputeJob) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !s.costRecompute.acquire(job.ID, cancel) {
+		cancel()
+		return false
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.costRecompute.release(job.ID)
+		defer cancel()
+
+		// 服务关闭时中断任务（状态保持running，下次启动自动继续）
+		go func() {
+			select {
+			case <-s.shutdownCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		s.runCostRecompute(ctx, job)
+	}()
+	return true
+}
+
+// runCostRecompute 分批重算直到完成、失败或被取消
+func (s *Server) runCostRecompute(ctx context.Context, job *model.CostRecomputeJob) {
+	multipliers := make(map[int64]float64)
+	if configs, err := s.store.ListConfigs(ctx); err == nil {
+		for _, cfg := range configs {
+			multipliers[cfg.ID] = cfg.GetCostMultiplier()
+		}
+	} else {
+		s.failCostRecompute(job, fmt.Errorf("load channels: %w", err))
+		return
+	}
+
+	log.Printf("[INFO] [费用重算] 任务#%d 开始: 游标=%d, 进度=%d/%d", job.ID, job.CursorID, job.ProcessedRows, job.TotalRows)
+	for {
+		if ctx.Err() != nil {
+			log.Printf("[INFO] [费用重算] 任务#%d 已中断: 进度=%d/%d", job.ID, job.ProcessedRows, job.TotalRows)
+			return
+		}
+
+		rows, err := s.store.ListLogCostRows(ctx, job.Since, job.Until, job.CursorID, costRecomputeBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.failCostRecompute(job, err)
+			}
+			return
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		updates := make([]model.LogCostUpdate, 0, len(rows))
+		next := *job
+		for _, row := range rows {
+			newCost := recomputeLogCost(row, multipliers)
+			if math.Abs(newCost-row.Cost) > costRecomputeEpsilon {
+				update := model.LogCostUpdate{LogID: row.ID, OldCost: row.Cost, NewCost: newCost}
+				// 与实时计费一致：只有2xx请求计入令牌累计费用，其余日志仅改写 logs.cost
+				if row.StatusCode >= 200 && row.StatusCode < 300 {
+					update.AuthTokenID = row.AuthTokenID
+				}
+				updates = append(updates, update)
+				next.CostDeltaUSD += newCost - row.Cost
+			}
+		}
+		next.CursorID = rows[len(rows)-1].ID
+		next.ProcessedRows += int64(len(rows))
+		next.UpdatedRows += int64(len(updates))
+
+		if err := s.store.ApplyCostRecomputeBatch(ctx, &next, updates); err != nil {
+			if ctx.Err() == nil {
+				s.failCostRecompute(job, err)
+			}
+			return
+		}
+		*job = next
+	}
+
+	if err := s.store.UpdateCostRecomputeJobStatus(context.Background(), job.ID, model.CostRecomputeCompleted, ""); err != nil {
+		log.Printf("[WARN] [费用重算] 任务#%d 更新状态失败: %v", job.ID, err)
+	}
+	job.Status = model.CostRecomputeCompleted
+	log.Printf("[INFO] [费用重算] 任务#%d 完成: 检查%d条, 修正%d条, 费用变化 $%.4f",
+		job.ID, job.ProcessedRows, job.UpdatedRows, job.CostDeltaUSD)
+
+	s.refreshCostCaches()
+}
+
+// failCostRecompute 标记任务失败（游标保留，可通过 resume 重试）
+func (s *Server) failCostRecompute(job *model.CostRecomputeJob, err error) {
+	log.Printf("[ERROR] [费用重算] 任务#%d 失败: %v", job.ID, err)
+	if updErr := s.store.UpdateCostRecomputeJobStatus(context.Background(), job.ID, model.CostRecomputeFailed, truncateErr(err.Error())); updErr != nil {
+		log.Printf("[WARN] [费用重算] 任务#%d 更新状态失败: %v", job.ID, updErr)
+	}
+}
+
+// refreshCostCaches 重算完成后刷新令牌限额缓存与渠道当日成本缓存
+func (s *Server) refreshCostCaches() {
+	if s.authService != nil {
+		if err := s.authService.ReloadAuthTokens(); err != nil {
+			log.Printf("[WARN] [费用重算] 刷新令牌缓存失败: %v", err)
+		}
+	}
+	if s.costCache != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if costs, err := s.store.GetTodayChannelCosts(ctx, s.costCache.DayStart()); err == nil {
+			s.costCache.Load(costs)
+		} else {
+			log.Printf("[WARN] [费用重算] 刷新渠道当日成本失败: %v", err)
+		}
+	}
+}
+
+// resumeCostRecomputeJobs 启动时继续上次未完成（服务关闭时中断）的重算任务
+func (s *Server) resumeCostRecomputeJobs(ctx context.Context) {
+	jobs, err := s.store.ListCostRecomputeJobs(ctx, model.CostRecomputeRunning, costRecomputeListLimit)
+	if err != nil {
+		log.Printf("[WARN] [费用重算] 加载未完成任务失败: %v", err)
+		return
+	}
+	for i, job := range jobs {
+		// 只允许一个任务运行：继续最新的一个，其余转为暂停
+		if i == 0 && s.startCostRecompute(job) {
+			continue
+		}
+		if err := s.store.UpdateCostRecomputeJobStatus(ctx, job.ID, model.CostRecomputePaused, ""); err != nil {
+			log.Printf("[WARN] [费用重算] 任务#%d 更新状态失败: %v", job.ID, err)
+		}
+	}
+}
+
+// HandleCreateCostRecompute 创建并启动历史费用重算任务
+// POST /admin/pricing/recompute {"since":"2026-10-01","until":"2026-10-15"}
+func (s *Server) HandleCreateCostRecompute(c *gin.Context) {
+	var req CostRecomputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	since, until, err := req.TimeRange(time.Now())
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if running := s.costRecompute.running(); running != 0 {
+		RespondErrorMsg(c, http.StatusConflict, fmt.Sprintf("cost recompute job #%d is already running", running))
+		return
+	}
+
+	job := &model.CostRecomputeJob{
+		Since:  since.UnixMilli(),
+		Until:  until.UnixMilli(),
+		Status: model.CostRecomputeRunning,
+	}
+	if err := s.store.CreateCostRecomputeJob(c.Request.Context(), job); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !s.startCostRecompute(job) {
+		_ = s.store.UpdateCostRecomputeJobStatus(c.Request.Context(), job.ID, model.CostRecomputePaused, "")
+		RespondErrorMsg(c, http.StatusConflict, "another cost recompute job is already running")
+		return
+	}
+	RespondJSON(c, http.StatusAccepted, job)
+}
+
+// HandleListCostRecomputes 最近的费用重算任务
+// GET /admin/pricing/recompute
+func (s *Server) HandleListCostRecomputes(c *gin.Context) {
+	jobs, err := s.store.ListCostRecomputeJobs(c.Request.Context(), "", costRecomputeListLimit)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, jobs)
+}
+
+// HandleGetCostRecompute 费用重算任务进度
+// GET /admin/pricing/recompute/:id
+func (s *Server) HandleGetCostRecompute(c *gin.Context) {
+	job, ok := s.loadCostRecomputeJob(c)
+	if !ok {
+		return
+	}
+	RespondJSON(c, http.StatusOK, job)
+}
+
+// HandlePauseCostRecompute 暂停费用重算任务（已提交的批次保留，可继续）
+// POST /admin/pricing/recompute/:id/pause
+func (s *Server) HandlePauseCostRecompute(c *gin.Context) {
+	job, ok := s.loadCostRecomputeJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != model.CostRecomputeRunning {
+		RespondErrorMsg(c, http.StatusConflict, "job is not running")
+		return
+	}
+	// 先写状态再取消：避免运行协程退出与状态写入之间被重启误判为需要继续
+	if err := s.store.UpdateCostRecomputeJobStatus(c.Request.Context(), job.ID, model.CostRecomputePaused, ""); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.costRecompute.stop(job.ID)
+	job.Status = model.CostRecomputePaused
+	RespondJSON(c, http.StatusOK, job)
+}
+
+// HandleResumeCostRecompute 从游标继续已暂停或失败的费用重算任务
+// POST /admin/pricing/recompute/:id/resume
+func (s *Server) HandleResumeCostRecompute(c *gin.Context) {
+	job, ok := s.loadCostRecomputeJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != model.CostRecomputePaused && job.Status != model.CostRecomputeFailed {
+		RespondErrorMsg(c, http.StatusConflict, "only paused or failed jobs can be resumed")
+		return
+	}
+	if running := s.costRecompute.running(); running != 0 {
+		RespondErrorMsg(c, http.StatusConflict, fmt.Sprintf("cost recompute job #%d is already running", running))
+		return
+	}
+	if err := s.store.UpdateCostRecomputeJobStatus(c.Request.Context(), job.ID, model.CostRecomputeRunning, ""); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	job.Status, job.Error = model.CostRecomputeRunning, ""
+	if !s.startCostRecompute(job) {
+		_ = s.store.UpdateCostRecomputeJobStatus(c.Request.Context(), job.ID, model.CostRecomputePaused, "")
+		RespondErrorMsg(c, http.StatusConflict, "another cost recompute job is already running")
+		return
+	}
+	RespondJSON(c, http.StatusAccepted, job)
+}
+
+func (s *Server) loadCostRecomputeJob(c *gin.Context) (*model.CostRecomputeJob, bool) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid job id")
+		return nil, false
+	}
+	job, err := s.store.GetCostRecomputeJob(c.Request.Context(), id)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	return job, true
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+func TestRecomputeLogCost(t *testing.T) {
+	row := model.LogCostRow{Model: "claude-sonnet-4-5", ChannelID: 1, InputTokens: 100_000, Cost: 9}
+	if got := recomputeLogCost(row, map[int64]float64{1: 0.5}); math.Abs(got-0.15) > 1e-9 {
+		t.Fatalf("expected $0.15 with 0.5 multiplier, got %v", got)
+	}
+	// 已删除渠道：按官方定价
+	if got := recomputeLogCost(row, nil); math.Abs(got-0.3) > 1e-9 {
+		t.Fatalf("expected $0.3 without multiplier, got %v", got)
+	}
+	// 定价缺失的模型保留原费用
+	unknown := model.LogCostRow{Model: "mystery-model", InputTokens: 1000, Cost: 0.42}
+	if got := recomputeLogCost(unknown, nil); got != 0.42 {
+		t.Fatalf("expected original cost for unknown model, got %v", got)
+	}
+	// 旧日志只有缓存写入总数：按5分钟TTL计费（1M × $3 × 1.25）
+	legacy := model.LogCostRow{Model: "claude-sonnet-4-5", CacheCreationTokens: 1_000_000, Cost: 0}
+	if got := recomputeLogCost(legacy, nil); math.Abs(got-3.75) > 1e-9 {
+		t.Fatalf("expected $3.75 for cache writes without breakdown, got %v", got)
+	}
+	// 无Token的日志（失败请求）不变
+	if got := recomputeLogCost(model.LogCostRow{Model: "claude-sonnet-4-5", Cost: 0}, nil); got != 0 {
+		t.Fatalf("expected 0 for log without tokens, got %v", got)
+	}
+}
+
+func TestRunCostRecompute(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:           "recompute-channel",
+		URL:            "https://api.example.com",
+		CostMultiplier: 0.5,
+		ModelEntries:   []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+		Enabled:        true,
+	})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	token := &model.AuthToken{Token: "hash-recompute", Description: "t", CreatedAt: time.Now(), IsActive: true}
+	if err := store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	// 旧定价下记了 $0.2 + $0.42
+	if err := store.UpdateTokenStats(ctx, token.Token, true, 1, false, 0, 101_000, 0, 0, 0, 0.62); err != nil {
+		t.Fatalf("update token stats: %v", err)
+	}
+
+	day := time.Date(2026, 10, 10, 12, 0, 0, 0, time.Local)
+	logs := []*model.LogEntry{
+		{Time: model.JSONTime{Time: day}, Model: "claude-sonnet-4-5", ChannelID: cfg.ID, AuthTokenID: token.ID, StatusCode: 200, Message: "ok", InputTokens: 100_000, Cost: 0.2},
+		{Time: model.JSONTime{Time: day.Add(time.Minute)}, Model: "mystery-model", ChannelID: cfg.ID, AuthTokenID: token.ID, StatusCode: 200, Message: "ok", InputTokens: 1000, Cost: 0.42},
+		{Time: model.JSONTime{Time: day.AddDate(0, 0, -5)}, Model: "claude-sonnet-4-5", ChannelID: cfg.ID, AuthTokenID: token.ID, StatusCode: 200, Message: "ok", InputTokens: 100_000, Cost: 0.2},
+		// 失败请求带用量也会记录费用，但实时计费从未向令牌收取
+		{Time: model.JSONTime{Time: day.Add(2 * time.Minute)}, Model: "claude-sonnet-4-5", ChannelID: cfg.ID, AuthTokenID: token.ID, StatusCode: 502, Message: "upstream error", InputTokens: 100_000, Cost: 0.2},
+	}
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("add logs: %v", err)
+	}
+
+	job := &model.CostRecomputeJob{
+		Since:  day.Add(-time.Hour).UnixMilli(),
+		Until:  day.Add(time.Hour).UnixMilli(),
+		Status: model.CostRecomputeRunning,
+	}
+	if err := store.CreateCostRecomputeJob(ctx, job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if job.TotalRows != 3 {
+		t.Fatalf("expected 3 rows in range, got %d", job.TotalRows)
+	}
+
+	server.runCostRecompute(ctx, job)
+
+	got, err := store.GetCostRecomputeJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if got.Status != model.CostRecomputeCompleted || got.ProcessedRows != 3 || got.UpdatedRows != 2 || math.Abs(got.CostDeltaUSD+0.1) > 1e-9 {
+		t.Fatalf("unexpected job state: %+v", got)
+	}
+
+	entries, err := store.ListLogsRange(ctx, day.AddDate(0, 0, -6), day.Add(time.Hour), 10, 0, nil)
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	costs := make(map[int64]float64)
+	for _, e := range entries {
+		costs[e.Time.UnixMilli()] = e.Cost
+	}
+	if c := costs[day.UnixMilli()]; math.Abs(c-0.15) > 1e-9 {
+		t.Fatalf("expected recomputed cost 0.15, got %v", c)
+	}
+	if c := costs[day.Add(2*time.Minute).UnixMilli()]; math.Abs(c-0.15) > 1e-9 {
+		t.Fatalf("failed log cost should still be rewritten, got %v", c)
+	}
+	if c := costs[day.AddDate(0, 0, -5).UnixMilli()]; c != 0.2 {
+		t.Fatalf("log outside range must be untouched, got %v", c)
+	}
+
+	updated, err := store.GetAuthToken(ctx, token.ID)
+	if err != nil {
+		t.Fatalf("get token: %v", err)
+	}
+	if math.Abs(updated.TotalCostUSD-0.57) > 1e-9 || updated.CostUsedMicroUSD != 570_000 {
+		t.Fatalf("expected token cost 0.57, got total=%v used_micro=%d", updated.TotalCostUSD, updated.CostUsedMicroUSD)
+	}
+}
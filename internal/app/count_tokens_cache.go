@@ -0,0 +1,127 @@
+package app
+
+import (
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// count_tokens 请求合并（2026-10新增）
+// ============================================================================
+// Agent框架会以相同请求体高频调用 count_tokens（每秒数十次）。按请求体哈希做短时 singleflight：
+// - 同一请求体的并发调用只计算一次，其余等待并共享结果
+// - 计算完成后结果保留 countTokensCacheTTL，期间的重复调用直接命中
+// 结果只取决于请求体（含400校验错误），因此整份响应一起缓存。
+// 命中统计见 GET /health?detail=1 的 count_tokens 组件。
+
+const (
+	countTokensCacheTTL      = 2 * time.Second
+	countTokensSweepInterval = 30 * time.Second
+)
+
+// countTokensResult 缓存的完整响应
+type countTokensResult struct {
+	status int
+	body   any
+}
+
+// countTokensCall 单个请求体的计算（进行中或已完成）
+type countTokensCall struct {
+	done    chan struct{}
+	result  countTokensResult
+	expires time.Time // 完成后设置
+}
+
+// countTokensCoalescer 请求合并器（nil 安全：直接计算）
+type countTokensCoalescer struct {
+	mu        sync.Mutex
+	calls     map[[sha256.Size]byte]*countTokensCall
+	lastSweep time.Time
+
+	hits     atomic.Uint64 // 命中已完成的缓存结果
+	shared   atomic.Uint64 // 等待进行中的计算
+	computed atomic.Uint64 // 实际计算次数
+}
+
+func newCountTokensCoalescer() *countTokensCoalescer {
+	return &countTokensCoalescer{calls: make(map[[sha256.Size]byte]*countTokensCall)}
+}
+
+// do 按请求体合并计算
+func (g *countTokensCoalescer) do(body []byte, fn func() countTokensResult) countTokensResult {
+	if g == nil {
+		return fn()
+	}
+	key := sha256.Sum256(body)
+	now := time.Now()
+
+	g.mu.Lock()
+	g.sweepLocked(now)
+	if call, ok := g.calls[key]; ok {
+		select {
+		case <-call.done:
+			if now.Before(call.expires) {
+				g.mu.Unlock()
+				g.hits.Add(1)
+				return call.result
+			}
+			// 已过期：重新计算
+		default:
+			g.mu.Unlock()
+			g.shared.Add(1)
+			<-call.done
+			return call.result
+		}
+	}
+	call := &countTokensCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	g.computed.Add(1)
+	defer func() {
+		g.mu.Lock()
+		call.expires = time.Now().Add(countTokensCacheTTL)
+		close(call.done)
+		g.mu.Unlock()
+	}()
+	call.result = fn()
+	return call.result
+}
+
+// sweepLocked 定期清理过期结果（调用方持有锁）
+func (g *countTokensCoalescer) sweepLocked(now time.Time) {
+	if now.Sub(g.lastSweep) < countTokensSweepInterval {
+		return
+	}
+	g.lastSweep = now
+	for key, call := range g.calls {
+		select {
+		case <-call.done:
+			if !now.Before(call.expires) {
+				delete(g.calls, key)
+			}
+		default:
+		}
+	}
+}
+
+// stats 命中统计
+func (g *countTokensCoalescer) stats() map[string]any {
+	g.mu.Lock()
+	entries := len(g.calls)
+	g.mu.Unlock()
+	hits, shared, computed := g.hits.Load(), g.shared.Load(), g.computed.Load()
+	hitRate := 0.0
+	if total := hits + shared + computed; total > 0 {
+		hitRate = float64(hits+shared) / float64(total)
+	}
+	return map[string]any{
+		"hits":     hits,
+		"shared":   shared,
+		"computed": computed,
+		"hit_rate": hitRate,
+		"entries":  entries,
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCountTokensCoalescer_SharesConcurrentCalls(t *testing.T) {
+	g := newCountTokensCoalescer()
+	body := []byte(`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}]}`)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() countTokensResult {
+		calls.Add(1)
+		<-release
+		return countTokensResult{status: http.StatusOK, body: CountTokensResponse{InputTokens: 42}}
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	results := make([]countTokensResult, n)
+	for i := range n {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = g.do(body, fn)
+		}(i)
+	}
+	// 等待所有调用进入合并等待
+	deadline := time.Now().Add(2 * time.Second)
+	for g.shared.Load() < n-1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("fn called %d times, want 1", got)
+	}
+	for i, r := range results {
+		if resp, ok := r.body.(CountTokensResponse); !ok || resp.InputTokens != 42 {
+			t.Fatalf("result[%d] = %+v", i, r)
+		}
+	}
+
+	// 完成后短时间内直接命中缓存
+	g.do(body, fn)
+	if calls.Load() != 1 || g.hits.Load() != 1 {
+		t.Fatalf("expected cache hit, calls=%d hits=%d", calls.Load(), g.hits.Load())
+	}
+
+	// 不同请求体独立计算
+	g.do([]byte(`{"other":true}`), func() countTokensResult {
+		calls.Add(1)
+		return countTokensResult{status: http.StatusOK}
+	})
+	if calls.Load() != 2 {
+		t.Fatalf("different body should compute separately, calls=%d", calls.Load())
+	}
+}
+
+func TestCountTokensCoalescer_Expires(t *testing.T) {
+	g := newCountTokensCoalescer()
+	body := []byte(`{}`)
+	calls := 0
+	fn := func() countTokensResult {
+		calls++
+		return countTokensResult{status: http.StatusOK}
+	}
+
+	g.do(body, fn)
+	// 手动让结果过期
+	for _, call := range g.calls {
+		call.expires = time.Now().Add(-time.Second)
+	}
+	g.do(body, fn)
+	if calls != 2 {
+		t.Fatalf("expired entry should be recomputed, calls=%d", calls)
+	}
+
+	var nilGroup *countTokensCoalescer
+	nilGroup.do(body, fn) // nil 安全
+	if calls != 3 {
+		t.Fatalf("nil coalescer should compute directly, calls=%d", calls)
+	}
+}
+
+func TestHandleCountTokens_Coalesced(t *testing.T) {
+	s := &Server{countTokens: newCountTokensCoalescer()}
+	body := []byte(`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"Hello, world"}]}`)
+
+	var first string
+	for i := range 3 {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages/count_tokens", bytes.NewReader(body))
+		s.handleCountTokens(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		if i == 0 {
+			first = w.Body.String()
+		} else if w.Body.String() != first {
+			t.Fatalf("cached response differs: %s vs %s", w.Body.String(), first)
+		}
+	}
+	if s.countTokens.computed.Load() != 1 || s.countTokens.hits.Load() != 2 {
+		t.Fatalf("computed=%d hits=%d, want 1/2", s.countTokens.computed.Load(), s.countTokens.hits.Load())
+	}
+
+	// 校验错误同样按请求体缓存
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages/count_tokens", bytes.NewReader([]byte(`{"model":"bad-model","messages":[{"role":"user","content":"x"}]}`)))
+	s.handleCountTokens(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid model status = %d", w.Code)
+	}
+}
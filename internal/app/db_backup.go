@@ -0,0 +1,490 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 数据库完整性检查与自动备份（2026-10新增，仅 SQLite 文件库）
+// ============================================================================
+// 数据库文件损坏时渠道/令牌配置随之丢失。后台协程定期：
+//   - 按 db_integrity_check_hours 执行 PRAGMA quick_check，发现问题记 ERROR 日志并在状态中标记
+//   - 按 db_backup_interval_hours 用 VACUUM INTO 生成带时间戳的快照（ccload-YYYYMMDD-HHMMSS.db），
+//     校验通过后保留最近 db_backup_keep 份；配置了 S3 兼容存储时同时上传
+//   - 完整性检查未通过时跳过备份，避免损坏的快照轮换掉仍然完好的旧备份
+// 管理接口：
+//   - GET  /admin/backup           状态与本地备份列表
+//   - POST /admin/backup/now       立即执行一次检查+备份（异步，进度通过 GET 查询）
+//   - POST /admin/backup/restore   {"name":"ccload-...db"} 校验备份并暂存为待恢复文件，重启服务后换入
+//   - DELETE /admin/backup/restore 取消待恢复
+// 备份目录：环境变量 CCLOAD_BACKUP_DIR，默认数据库同目录下的 backups/。
+// S3 上传（全部通过环境变量配置，凭据不入库）：CCLOAD_BACKUP_S3_ENDPOINT、CCLOAD_BACKUP_S3_BUCKET、
+// CCLOAD_BACKUP_S3_ACCESS_KEY、CCLOAD_BACKUP_S3_SECRET_KEY，可选 CCLOAD_BACKUP_S3_REGION、CCLOAD_BACKUP_S3_PREFIX。
+
+const (
+	defaultDBBackupIntervalHours = 24
+	defaultDBBackupKeep          = 7
+	defaultDBIntegrityCheckHours = 24
+	dbBackupMaxKeep              = 365
+	dbBackupTickInterval         = 10 * time.Minute
+	dbBackupRunTimeout           = 60 * time.Minute
+	dbBackupNamePrefix           = "ccload-"
+	dbBackupNameSuffix           = ".db"
+	dbBackupNameTimeLayout       = "20060102-150405"
+	dbBackupTriggerSchedule      = "schedule"
+	dbBackupTriggerManual        = "manual"
+)
+
+var (
+	errDBBackupUnsupported = errors.New("database backup is only available for file-based SQLite")
+	errDBBackupRunning     = errors.New("database backup already running")
+	errDBIntegrityFailed   = errors.New("database integrity check failed, backup skipped")
+)
+
+// dbBackupConfig 备份参数（启动时加载，修改后重启生效）
+type dbBackupConfig struct {
+	dir        string
+	interval   time.Duration // 0=关闭定时备份
+	keep       int
+	checkEvery time.Duration // 0=关闭定时完整性检查
+}
+
+// DBBackupFile 本地备份文件
+type DBBackupFile struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"created_at"` // Unix毫秒
+}
+
+// DBBackupStatus 备份状态（时间均为Unix毫秒，0表示未发生）
+type DBBackupStatus struct {
+	Supported           bool           `json:"supported"`
+	Dir                 string         `json:"dir,omitempty"`
+	IntervalHours       int            `json:"interval_hours"`
+	Keep                int            `json:"keep"`
+	IntegrityCheckHours int            `json:"integrity_check_hours"`
+	S3                  string         `json:"s3,omitempty"` // 上传目标（s3://bucket/prefix），未配置为空
+	Running             bool           `json:"running"`
+	Trigger             string         `json:"trigger,omitempty"`
+	Healthy             bool           `json:"healthy"` // 最近一次完整性检查是否通过（尚未检查时为 true）
+	LastCheckAt         int64          `json:"last_check_at,omitempty"`
+	IntegrityErrors     []string       `json:"integrity_errors,omitempty"`
+	LastBackupAt        int64          `json:"last_backup_at,omitempty"`
+	LastBackup          string         `json:"last_backup,omitempty"`
+	LastUploadAt        int64          `json:"last_upload_at,omitempty"`
+	LastError           string         `json:"last_error,omitempty"`
+	PendingRestore      bool           `json:"pending_restore"`
+	Backups             []DBBackupFile `json:"backups"`
+}
+
+// dbBackupService 完整性检查与备份（nil-safe 的状态读取；dbPath 为空表示不支持）
+type dbBackupService struct {
+	store   storage.Store
+	dbPath  string
+	cfg     dbBackupConfig
+	s3      *util.S3Uploader
+	trigger chan struct{}
+	now     func() time.Time
+
+	mu     sync.Mutex
+	status DBBackupStatus
+}
+
+func newDBBackupService(store storage.Store, dbPath string, cfg dbBackupConfig, s3 *util.S3Uploader) *dbBackupService {
+	if cfg.dir == "" && dbPath != "" {
+		cfg.dir = filepath.Join(filepath.Dir(dbPath), "backups")
+	}
+	return &dbBackupService{
+		store:   store,
+		dbPath:  dbPath,
+		cfg:     cfg,
+		s3:      s3,
+		trigger: make(chan struct{}, 1),
+		now:     time.Now,
+		status:  DBBackupStatus{Healthy: true},
+	}
+}
+
+// newDBBackupFromConfig 读取备份配置；非SQLite文件库返回不支持的服务（状态接口仍可用）
+func (s *Server) newDBBackupFromConfig(cs *ConfigService) *dbBackupService {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dbPath, err := s.store.DatabaseFile(ctx)
+	if err != nil {
+		log.Printf("[WARN] [数据库备份] 获取数据库文件路径失败，已禁用备份: %v", err)
+		dbPath = ""
+	}
+
+	intervalHours := cs.GetInt("db_backup_interval_hours", defaultDBBackupIntervalHours)
+	if intervalHours < 0 {
+		log.Printf("[WARN] 无效的 db_backup_interval_hours=%d（必须 >= 0），已使用默认值 %d", intervalHours, defaultDBBackupIntervalHours)
+		intervalHours = defaultDBBackupIntervalHours
+	}
+	keep := cs.GetInt("db_backup_keep", defaultDBBackupKeep)
+	if keep < 1 || keep > dbBackupMaxKeep {
+		log.Printf("[WARN] 无效的 db_backup_keep=%d（必须在 1-%d 之间），已使用默认值 %d", keep, dbBackupMaxKeep, defaultDBBackupKeep)
+		keep = defaultDBBackupKeep
+	}
+	checkHours := cs.GetInt("db_integrity_check_hours", defaultDBIntegrityCheckHours)
+	if checkHours < 0 {
+		log.Printf("[WARN] 无效的 db_integrity_check_hours=%d（必须 >= 0），已使用默认值 %d", checkHours, defaultDBIntegrityCheckHours)
+		checkHours = defaultDBIntegrityCheckHours
+	}
+
+	cfg := dbBackupConfig{
+		dir:        strings.TrimSpace(os.Getenv("CCLOAD_BACKUP_DIR")),
+		interval:   time.Duration(intervalHours) * time.Hour,
+		keep:       keep,
+		checkEvery: time.Duration(checkHours) * time.Hour,
+	}
+	var s3 *util.S3Uploader
+	if dbPath != "" {
+		s3 = dbBackupS3FromEnv("")
+	}
+	return newDBBackupService(s.store, dbPath, cfg, s3)
+}
+
+// dbBackupS3FromEnv 从环境变量加载 S3 上传配置（未配置 endpoint 时返回 nil），subPrefix 追加在对象键前缀之后
+func dbBackupS3FromEnv(subPrefix string) *util.S3Uploader {
+	endpoint := strings.TrimSpace(os.Getenv("CCLOAD_BACKUP_S3_ENDPOINT"))
+	if endpoint == "" {
+		return nil
+	}
+	u, err := util.NewS3Uploader(util.S3Config{
+		Endpoint:  endpoint,
+		Bucket:    strings.TrimSpace(os.Getenv("CCLOAD_BACKUP_S3_BUCKET")),
+		Region:    strings.TrimSpace(os.Getenv("CCLOAD_BACKUP_S3_REGION")),
+		AccessKey: strings.TrimSpace(os.Getenv("CCLOAD_BACKUP_S3_ACCESS_KEY")),
+		SecretKey: strings.TrimSpace(os.Getenv("CCLOAD_BACKUP_S3_SECRET_KEY")),
+		Prefix:    strings.TrimSpace(os.Getenv("CCLOAD_BACKUP_S3_PREFIX")) + subPrefix,
+	}, nil)
+	if err != nil {
+		log.Printf("[WARN] [数据库备份] S3 上传配置无效，仅保存本地备份: %v", err)
+		return nil
+	}
+	return u
+}
+
+func (b *dbBackupService) supported() bool {
+	return b != nil && b.dbPath != ""
+}
+
+func (b *dbBackupService) update(fn func(*DBBackupStatus)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fn(&b.status)
+}
+
+// Status 状态快照（含本地备份列表）
+func (b *dbBackupService) Status() DBBackupStatus {
+	if !b.supported() {
+		return DBBackupStatus{Healthy: true, Backups: []DBBackupFile{}}
+	}
+	b.mu.Lock()
+	st := b.status
+	st.IntegrityErrors = append([]string(nil), b.status.IntegrityErrors...)
+	b.mu.Unlock()
+
+	st.Supported = true
+	st.Dir = b.cfg.dir
+	st.IntervalHours = int(b.cfg.interval / time.Hour)
+	st.Keep = b.cfg.keep
+	st.IntegrityCheckHours = int(b.cfg.checkEvery / time.Hour)
+	if b.s3 != nil {
+		st.S3 = b.s3.Location("")
+	}
+	st.PendingRestore = storage.PendingSQLiteRestore(b.dbPath)
+	files, err := b.listBackups()
+	if err != nil && st.LastError == "" {
+		st.LastError = err.Error()
+	}
+	st.Backups = files
+	return st
+}
+
+// listBackups 本地备份（新→旧）
+func (b *dbBackupService) listBackups() ([]DBBackupFile, error) {
+	entries, err := os.ReadDir(b.cfg.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []DBBackupFile{}, nil
+		}
+		return []DBBackupFile{}, err
+	}
+	files := []DBBackupFile{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !isDBBackupName(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, DBBackupFile{Name: name, Size: info.Size(), CreatedAt: info.ModTime().UnixMilli()})
+	}
+	// 文件名中的时间戳可按字典序排序
+	sort.Slice(files, func(i, j int) bool { return files[i].Name > files[j].Name })
+	return files, nil
+}
+
+// isDBBackupName 是否为备份模块生成的文件名（同时用于恢复接口的路径校验）
+func isDBBackupName(name string) bool {
+	if !strings.HasPrefix(name, dbBackupNamePrefix) || !strings.HasSuffix(name, dbBackupNameSuffix) {
+		return false
+	}
+	ts := strings.TrimSuffix(strings.TrimPrefix(name, dbBackupNamePrefix), dbBackupNameSuffix)
+	_, err := time.Parse(dbBackupNameTimeLayout, ts)
+	return err == nil
+}
+
+// runCheck 执行完整性检查并更新状态，返回是否通过
+func (b *dbBackupService) runCheck(ctx context.Context) bool {
+	problems, err := b.store.CheckIntegrity(ctx)
+	now := b.now().UnixMilli()
+	if err != nil {
+		log.Printf("[WARN] [数据库检查] 完整性检查执行失败: %v", err)
+		b.update(func(st *DBBackupStatus) { st.LastError = err.Error() })
+		return false
+	}
+	if len(problems) > 0 {
+		log.Printf("[ERROR] [数据库检查] 数据库完整性检查未通过（%d项问题，首项: %s），请尽快通过 POST /admin/backup/restore 从备份恢复",
+			len(problems), problems[0])
+	}
+	b.update(func(st *DBBackupStatus) {
+		st.LastCheckAt = now
+		st.Healthy = len(problems) == 0
+		st.IntegrityErrors = problems
+	})
+	return len(problems) == 0
+}
+
+// runBackup 完整性检查通过后生成快照、校验、轮换并上传
+func (b *dbBackupService) runBackup(ctx context.Context, trigger string) (*DBBackupFile, error) {
+	if !b.supported() {
+		return nil, errDBBackupUnsupported
+	}
+	b.mu.Lock()
+	if b.status.Running {
+		b.mu.Unlock()
+		return nil, errDBBackupRunning
+	}
+	b.status.Running, b.status.Trigger, b.status.LastError = true, trigger, ""
+	b.mu.Unlock()
+	defer b.update(func(st *DBBackupStatus) { st.Running = false })
+
+	file, err := b.backupOnce(ctx)
+	if err != nil {
+		log.Printf("[WARN] [数据库备份] 备份失败: %v", err)
+		b.update(func(st *DBBackupStatus) { st.LastError = err.Error() })
+		return nil, err
+	}
+	return file, nil
+}
+
+func (b *dbBackupService) backupOnce(ctx context.Context) (*DBBackupFile, error) {
+	if !b.runCheck(ctx) {
+		return nil, errDBIntegrityFailed
+	}
+	if err := os.MkdirAll(b.cfg.dir, 0o750); err != nil { //nolint:gosec // G301: 备份目录需要服务进程可写
+		return nil, err
+	}
+
+	started := b.now()
+	name := dbBackupNamePrefix + started.Format(dbBackupNameTimeLayout) + dbBackupNameSuffix
+	path := filepath.Join(b.cfg.dir, name)
+	if err := writeSQLiteSnapshot(ctx, b.store, path); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	file := &DBBackupFile{Name: name, Size: info.Size(), CreatedAt: info.ModTime().UnixMilli()}
+	log.Printf("[INFO] [数据库备份] 已生成 %s（%d字节），耗时%v", path, file.Size, b.now().Sub(started).Round(time.Millisecond))
+	b.update(func(st *DBBackupStatus) { st.LastBackupAt, st.LastBackup = started.UnixMilli(), name })
+
+	b.rotate()
+
+	if b.s3 != nil {
+		if err := b.s3.UploadFile(ctx, path, name); err != nil {
+			// 上传失败不影响本地备份
+			log.Printf("[WARN] [数据库备份] 上传 %s 失败: %v", b.s3.Location(name), err)
+			b.update(func(st *DBBackupStatus) { st.LastError = "upload: " + err.Error() })
+		} else {
+			b.update(func(st *DBBackupStatus) { st.LastUploadAt = b.now().UnixMilli() })
+		}
+	}
+	return file, nil
+}
+
+// writeSQLiteSnapshot 用 VACUUM INTO 生成一致性快照，校验通过后原子替换 path（备份与快照复制共用）
+func writeSQLiteSnapshot(ctx context.Context, store storage.Store, path string) error {
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp) // 上次中断残留
+	if err := store.BackupTo(ctx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := storage.VerifySQLiteFile(ctx, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// rotate 只保留最近 keep 份本地备份
+func (b *dbBackupService) rotate() {
+	files, err := b.listBackups()
+	if err != nil || len(files) <= b.cfg.keep {
+		return
+	}
+	for _, f := range files[b.cfg.keep:] {
+		if err := os.Remove(filepath.Join(b.cfg.dir, f.Name)); err != nil {
+			log.Printf("[WARN] [数据库备份] 删除过期备份 %s 失败: %v", f.Name, err)
+		}
+	}
+}
+
+// TriggerBackup 请求后台协程立即执行一次备份
+func (b *dbBackupService) TriggerBackup() error {
+	if !b.supported() {
+		return errDBBackupUnsupported
+	}
+	if b.Status().Running {
+		return errDBBackupRunning
+	}
+	select {
+	case b.trigger <- struct{}{}:
+		return nil
+	default:
+		return errDBBackupRunning // 已有待执行的触发请求
+	}
+}
+
+// runDue 执行到期的检查/备份（备份本身包含完整性检查）
+func (b *dbBackupService) runDue() {
+	now := b.now()
+	st := b.Status()
+	backupDue := b.cfg.interval > 0
+	if backupDue && len(st.Backups) > 0 {
+		backupDue = now.Sub(time.UnixMilli(st.Backups[0].CreatedAt)) >= b.cfg.interval
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dbBackupRunTimeout)
+	defer cancel()
+	if backupDue {
+		_, _ = b.runBackup(ctx, dbBackupTriggerSchedule)
+		return
+	}
+	if b.cfg.checkEvery > 0 && now.Sub(time.UnixMilli(st.LastCheckAt)) >= b.cfg.checkEvery {
+		b.runCheck(ctx)
+	}
+}
+
+// dbBackupLoop 数据库检查与备份后台协程（首次检查在启动 dbBackupTickInterval 后，避免拖慢启动）
+func (s *Server) dbBackupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(dbBackupTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.dbBackup.runDue()
+		case <-s.dbBackup.trigger:
+			ctx, cancel := context.WithTimeout(context.Background(), dbBackupRunTimeout)
+			_, _ = s.dbBackup.runBackup(ctx, dbBackupTriggerManual)
+			cancel()
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
+
+// HandleDBBackupStatus 数据库备份状态与本地备份列表
+// GET /admin/backup
+func (s *Server) HandleDBBackupStatus(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, s.dbBackup.Status())
+}
+
+// HandleDBBackupNow 立即执行一次完整性检查与备份（异步）
+// POST /admin/backup/now
+func (s *Server) HandleDBBackupNow(c *gin.Context) {
+	if err := s.dbBackup.TriggerBackup(); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errDBBackupRunning) {
+			status = http.StatusConflict
+		}
+		RespondErrorMsg(c, status, err.Error())
+		return
+	}
+	RespondJSON(c, http.StatusAccepted, s.dbBackup.Status())
+}
+
+// HandleDBRestore 校验指定备份并暂存为待恢复文件，重启服务后生效
+// POST /admin/backup/restore {"name":"ccload-20261016-030000.db"}
+func (s *Server) HandleDBRestore(c *gin.Context) {
+	if !s.dbBackup.supported() {
+		RespondErrorMsg(c, http.StatusBadRequest, errDBBackupUnsupported.Error())
+		return
+	}
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "name is required")
+		return
+	}
+	if !isDBBackupName(req.Name) || filepath.Base(req.Name) != req.Name {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid backup name")
+		return
+	}
+	path := filepath.Join(s.dbBackup.cfg.dir, req.Name)
+	if _, err := os.Stat(path); err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "backup not found")
+		return
+	}
+	if err := storage.StageSQLiteRestore(c.Request.Context(), s.dbBackup.dbPath, path); err != nil {
+		RespondErrorMsg(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	log.Printf("[WARN] [数据库恢复] 已暂存备份 %s，重启服务后生效", req.Name)
+	RespondJSON(c, http.StatusOK, gin.H{
+		"pending_restore": true,
+		"name":            req.Name,
+		"message":         "备份已校验并暂存，重启服务后换入；当前数据库将保留为 .pre-restore-* 文件",
+	})
+}
+
+// HandleCancelDBRestore 取消待恢复的备份
+// DELETE /admin/backup/restore
+func (s *Server) HandleCancelDBRestore(c *gin.Context) {
+	if !s.dbBackup.supported() {
+		RespondErrorMsg(c, http.StatusBadRequest, errDBBackupUnsupported.Error())
+		return
+	}
+	if err := storage.CancelSQLiteRestore(s.dbBackup.dbPath); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"pending_restore": false})
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corruptStore 模拟完整性检查未通过的数据库
+type corruptStore struct {
+	storage.Store
+}
+
+func (corruptStore) CheckIntegrity(context.Context) ([]string, error) {
+	return []string{"*** in database main ***", "Page 42: btreeInitPage() returns error code 11"}, nil
+}
+
+func TestDBBackup_BackupRotateAndRestore(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ccload.db")
+	store, err := storage.CreateSQLiteStore(dbPath, nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := store.CreateConfig(ctx, &model.Config{Name: "before-backup", URL: "https://a.example", ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}}); err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+
+	var uploads atomic.Int32
+	s3srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		uploads.Add(1)
+	}))
+	defer s3srv.Close()
+	s3, err := util.NewS3Uploader(util.S3Config{Endpoint: s3srv.URL, Bucket: "bk", AccessKey: "ak", SecretKey: "sk"}, s3srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbFile, err := store.DatabaseFile(ctx)
+	if err != nil || dbFile == "" {
+		t.Fatalf("DatabaseFile = %q, %v", dbFile, err)
+	}
+	b := newDBBackupService(store, dbFile, dbBackupConfig{keep: 2, interval: time.Hour}, s3)
+	clock := time.Date(2026, 10, 16, 3, 0, 0, 0, time.Local)
+	b.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	first, err := b.runBackup(ctx, dbBackupTriggerManual)
+	if err != nil {
+		t.Fatalf("备份失败: %v", err)
+	}
+	if !isDBBackupName(first.Name) || !strings.HasPrefix(first.Name, "ccload-20261016-0300") || first.Size == 0 {
+		t.Fatalf("unexpected backup: %+v", first)
+	}
+	for range 2 {
+		if _, err := b.runBackup(ctx, dbBackupTriggerSchedule); err != nil {
+			t.Fatalf("备份失败: %v", err)
+		}
+	}
+	st := b.Status()
+	if len(st.Backups) != 2 || st.Backups[1].Name == first.Name || !st.Healthy || st.LastCheckAt == 0 {
+		t.Fatalf("应只保留最近2份: %+v", st)
+	}
+	if uploads.Load() != 3 || st.LastUploadAt == 0 || st.S3 != "s3://bk/" {
+		t.Fatalf("uploads=%d status=%+v", uploads.Load(), st)
+	}
+
+	// 备份后新增的渠道在恢复后应消失
+	if _, err := store.CreateConfig(ctx, &model.Config{Name: "after-backup", URL: "https://b.example", ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}}); err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+
+	srv := &Server{store: store, dbBackup: b}
+	restore := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/admin/backup/restore", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if method == http.MethodDelete {
+			srv.HandleCancelDBRestore(c)
+		} else {
+			srv.HandleDBRestore(c)
+		}
+		return w
+	}
+	if w := restore(http.MethodPost, `{"name":"../ccload.db"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("非法文件名应返回400, got %d", w.Code)
+	}
+	if w := restore(http.MethodPost, `{"name":"`+first.Name+`"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("已轮换的备份应返回404, got %d", w.Code)
+	}
+	latest := st.Backups[0].Name
+	if w := restore(http.MethodPost, `{"name":"`+latest+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("暂存恢复失败: %d %s", w.Code, w.Body.String())
+	}
+	if !b.Status().PendingRestore {
+		t.Fatal("应存在待恢复文件")
+	}
+	if w := restore(http.MethodDelete, ""); w.Code != http.StatusOK || b.Status().PendingRestore {
+		t.Fatalf("取消恢复失败: %d", w.Code)
+	}
+	if w := restore(http.MethodPost, `{"name":"`+latest+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("暂存恢复失败: %d %s", w.Code, w.Body.String())
+	}
+	_ = store.Close()
+
+	// 重新打开时换入备份，原库保留为 .pre-restore-*
+	reopened, err := storage.CreateSQLiteStore(dbPath, nil)
+	if err != nil {
+		t.Fatalf("重新打开失败: %v", err)
+	}
+	defer func() { _ = reopened.Close() }()
+	configs, err := reopened.ListConfigs(ctx)
+	if err != nil {
+		t.Fatalf("ListConfigs: %v", err)
+	}
+	if len(configs) != 1 || configs[0].Name != "before-backup" {
+		t.Fatalf("恢复后渠道不符: %d", len(configs))
+	}
+	if storage.PendingSQLiteRestore(dbPath) {
+		t.Fatal("换入后不应保留待恢复文件")
+	}
+	if matches, _ := filepath.Glob(dbPath + ".pre-restore-*"); len(matches) == 0 {
+		t.Fatal("原数据库应保留为 .pre-restore-* 文件")
+	}
+}
+
+func TestDBBackup_SkipsWhenIntegrityFails(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ccload.db")
+	store, err := storage.CreateSQLiteStore(dbPath, nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	dir := t.TempDir()
+	b := newDBBackupService(corruptStore{store}, dbPath, dbBackupConfig{dir: dir, keep: 3}, nil)
+	if _, err := b.runBackup(context.Background(), dbBackupTriggerManual); !errors.Is(err, errDBIntegrityFailed) {
+		t.Fatalf("完整性检查失败时应跳过备份, got %v", err)
+	}
+	st := b.Status()
+	if st.Healthy || len(st.IntegrityErrors) != 2 || len(st.Backups) != 0 || st.LastError == "" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("不应生成备份文件: %v", entries)
+	}
+
+	// 内存库/MySQL 不支持备份
+	mem := newDBBackupService(store, "", dbBackupConfig{}, nil)
+	if err := mem.TriggerBackup(); !errors.Is(err, errDBBackupUnsupported) || mem.Status().Supported {
+		t.Fatalf("内存库不应支持备份: %v", err)
+	}
+}
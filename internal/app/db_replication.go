@@ -0,0 +1,586 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// SQLite 快照复制与热备切换（2026-10新增，仅 SQLite 文件库）
+// ============================================================================
+// 定时备份最多丢失一个备份周期的数据。需要秒级 RPO 时推荐在同一主机上运行 Litestream（页级 WAL 流式复制，
+// 见 README「主机故障切换」）；内置复制不实现私有传输协议，只在 WAL checkpoint 时生成快照并交给现有通道：
+//   - 复制协程每 db_replication_interval_seconds 执行一次 PASSIVE checkpoint（不截断 WAL，与 Litestream 兼容），
+//     主库与 -wal 的大小/修改时间有变化时复用备份模块的 VACUUM INTO 路径生成校验过的快照 {复制目录}/snapshot.db
+//   - 快照上传到 S3（CCLOAD_REPLICA_S3=1，复用备份的 S3 配置，对象键 replica/latest.db，replica/latest.json 记录捕获时间）
+//   - checkpoint 钩子 CCLOAD_REPLICA_HOOK：快照生成后以 sh -c 执行，环境变量 CCLOAD_REPLICA_SNAPSHOT 为快照路径、
+//     CCLOAD_REPLICA_CAPTURED_AT 为捕获时间（Unix毫秒），如 rsync 到热备主机；失败时下个周期重试
+//   - 每次快照都要完整读写一遍数据库，库文件（含 -wal）超过 db_replication_max_db_mb 时拒绝快照并标记为不健康
+//   - 热备实例（CCLOAD_REPLICA_ACCEPT=1）不开放任何接收端点，只监视 {复制目录}/replica.db（由钩子或 litestream restore 写入）
+// 切换（提升）：POST /admin/replica/promote {"source":"standby"|"s3"}，校验副本后暂存为待恢复文件，重启后换入。
+// 滞后信号：GET /admin/replica 与 /health?detail=1 的 db_replication 组件；
+// 滞后超过 db_replication_lag_alert_seconds 时状态为 degraded。
+
+const (
+	defaultDBReplicationIntervalSeconds = 60
+	defaultDBReplicationLagAlertSeconds = 300
+	defaultDBReplicationMaxDBMB         = 1024
+	dbReplicationMaxIntervalSeconds     = 3600
+	replicaCycleTimeout                 = 10 * time.Minute
+	replicaShutdownFlushTimeout         = 15 * time.Second
+	replicaHookOutputLimit              = 512
+	replicaS3Prefix                     = "replica/"
+	replicaSnapshotObject               = "latest.db"
+	replicaManifestName                 = "latest.json"
+	replicaSnapshotName                 = "snapshot.db"
+	replicaStandbyName                  = "replica.db"
+	replicaPromoteName                  = "promote.db"
+	replicaSourceStandby                = "standby"
+	replicaSourceS3                     = "s3"
+	replicaTargetS3                     = "s3"
+	replicaTargetHook                   = "hook"
+)
+
+var (
+	errReplicaUnsupported = errors.New("database replication is only available for file-based SQLite")
+	errReplicaTooLarge    = errors.New("database exceeds db_replication_max_db_mb")
+	errReplicaNoData      = errors.New("no replicated data available")
+)
+
+// replicaManifest S3 上最新快照的元数据（replica/latest.json）
+type replicaManifest struct {
+	CapturedAt int64 `json:"captured_at"`
+	Size       int64 `json:"size"`
+	UpdatedAt  int64 `json:"updated_at"`
+}
+
+// replicaTarget 主端发送目标（seq/shipped 为已送达快照的序号与捕获时间，synced 为最近一次确认目标包含全部已提交数据的时间）
+type replicaTarget struct {
+	kind     string
+	location string
+	seq      int64
+	shipped  int64
+	synced   time.Time
+	sentAt   int64
+	lastErr  string
+}
+
+// ReplicaTargetStatus 发送目标状态
+type ReplicaTargetStatus struct {
+	Kind          string  `json:"kind"` // s3 | hook
+	Location      string  `json:"location"`
+	ShippedAt     int64   `json:"shipped_at,omitempty"` // 已送达快照的捕获时间
+	LastShippedAt int64   `json:"last_shipped_at,omitempty"`
+	LagSeconds    float64 `json:"lag_seconds"` // 距最近一次确认目标已包含全部已提交数据的时间
+	LastError     string  `json:"last_error,omitempty"`
+}
+
+// ReplicaPrimaryStatus 主端（发送方）状态
+type ReplicaPrimaryStatus struct {
+	LastCaptureAt    int64                 `json:"last_capture_at,omitempty"`
+	LastCaptureBytes int64                 `json:"last_capture_bytes"`
+	LastError        string                `json:"last_error,omitempty"`
+	Targets          []ReplicaTargetStatus `json:"targets"`
+}
+
+// ReplicaStandbyStatus 热备端状态（副本文件由钩子或 litestream restore 写入）
+type ReplicaStandbyStatus struct {
+	Path           string  `json:"path"`
+	Size           int64   `json:"size"`
+	ModifiedAt     int64   `json:"modified_at,omitempty"`
+	LagSeconds     float64 `json:"lag_seconds"` // 距副本文件最近一次更新的时间
+	PendingRestore bool    `json:"pending_restore"`
+}
+
+// DBReplicationStatus 复制状态（GET /admin/replica）
+type DBReplicationStatus struct {
+	Enabled         bool                  `json:"enabled"`
+	Dir             string                `json:"dir,omitempty"`
+	IntervalSeconds int                   `json:"interval_seconds"`
+	LagAlertSeconds int                   `json:"lag_alert_seconds"`
+	MaxDBMB         int                   `json:"max_db_mb"`   // 可快照的最大库大小（0=不限制）
+	LagSeconds      float64               `json:"lag_seconds"` // 各目标与热备端滞后的最大值
+	Healthy         bool                  `json:"healthy"`
+	Primary         *ReplicaPrimaryStatus `json:"primary,omitempty"`
+	Standby         *ReplicaStandbyStatus `json:"standby,omitempty"`
+}
+
+// dbReplicationConfig 复制参数（启动时加载，修改后重启生效）
+type dbReplicationConfig struct {
+	dir      string
+	interval time.Duration
+	lagAlert time.Duration // 0=不告警
+	maxDBMB  int           // 超过该大小（主库 + -wal）时拒绝快照；0=不限制
+	s3       *util.S3Uploader
+	hook     string
+	accept   bool
+}
+
+// dbReplicationService 快照复制（主端发送 + 热备监视，nil-safe 的状态读取；dbPath 为空表示不支持）
+type dbReplicationService struct {
+	store     storage.Store
+	dbPath    string
+	cfg       dbReplicationConfig
+	now       func() time.Time
+	startedAt time.Time
+
+	mu           sync.Mutex
+	lastStamp    [4]int64 // 最近一次快照时主库与 -wal 的大小、修改时间
+	captureSeq   int64    // 本进程已生成的快照数
+	captureAt    int64
+	captureBytes int64
+	lastErr      string
+	targets      []*replicaTarget
+}
+
+func newDBReplicationService(store storage.Store, dbPath string, cfg dbReplicationConfig) *dbReplicationService {
+	if cfg.dir == "" && dbPath != "" {
+		cfg.dir = filepath.Join(filepath.Dir(dbPath), "replica")
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = defaultDBReplicationIntervalSeconds * time.Second
+	}
+	r := &dbReplicationService{
+		store:     store,
+		dbPath:    dbPath,
+		cfg:       cfg,
+		now:       time.Now,
+		startedAt: time.Now(),
+	}
+	if cfg.s3 != nil {
+		r.targets = append(r.targets, &replicaTarget{kind: replicaTargetS3, location: cfg.s3.Location(replicaSnapshotObject), synced: r.startedAt})
+	}
+	if cfg.hook != "" {
+		r.targets = append(r.targets, &replicaTarget{kind: replicaTargetHook, location: cfg.hook, synced: r.startedAt})
+	}
+	return r
+}
+
+// newDBReplicationFromConfig 读取复制配置；非SQLite文件库返回不支持的服务（状态接口仍可用）
+func (s *Server) newDBReplicationFromConfig(cs *ConfigService) *dbReplicationService {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dbPath, err := s.store.DatabaseFile(ctx)
+	if err != nil {
+		log.Printf("[WARN] [数据库复制] 获取数据库文件路径失败，已禁用复制: %v", err)
+		dbPath = ""
+	}
+
+	intervalSeconds := cs.GetInt("db_replication_interval_seconds", defaultDBReplicationIntervalSeconds)
+	if intervalSeconds < 1 || intervalSeconds > dbReplicationMaxIntervalSeconds {
+		log.Printf("[WARN] 无效的 db_replication_interval_seconds=%d（必须在 1-%d 之间），已使用默认值 %d",
+			intervalSeconds, dbReplicationMaxIntervalSeconds, defaultDBReplicationIntervalSeconds)
+		intervalSeconds = defaultDBReplicationIntervalSeconds
+	}
+	lagAlertSeconds := cs.GetInt("db_replication_lag_alert_seconds", defaultDBReplicationLagAlertSeconds)
+	if lagAlertSeconds < 0 {
+		log.Printf("[WARN] 无效的 db_replication_lag_alert_seconds=%d（必须 >= 0），已使用默认值 %d", lagAlertSeconds, defaultDBReplicationLagAlertSeconds)
+		lagAlertSeconds = defaultDBReplicationLagAlertSeconds
+	}
+	maxDBMB := cs.GetInt("db_replication_max_db_mb", defaultDBReplicationMaxDBMB)
+	if maxDBMB < 0 {
+		log.Printf("[WARN] 无效的 db_replication_max_db_mb=%d（必须 >= 0），已使用默认值 %d", maxDBMB, defaultDBReplicationMaxDBMB)
+		maxDBMB = defaultDBReplicationMaxDBMB
+	}
+
+	cfg := dbReplicationConfig{
+		dir:      strings.TrimSpace(os.Getenv("CCLOAD_REPLICA_DIR")),
+		interval: time.Duration(intervalSeconds) * time.Second,
+		lagAlert: time.Duration(lagAlertSeconds) * time.Second,
+		maxDBMB:  maxDBMB,
+		hook:     strings.TrimSpace(os.Getenv("CCLOAD_REPLICA_HOOK")),
+		accept:   envFlag("CCLOAD_REPLICA_ACCEPT"),
+	}
+	if dbPath == "" {
+		if cfg.hook != "" || cfg.accept || envFlag("CCLOAD_REPLICA_S3") {
+			log.Print("[WARN] [数据库复制] 复制仅支持 SQLite 文件库，已忽略 CCLOAD_REPLICA_* 配置")
+		}
+		return newDBReplicationService(s.store, "", dbReplicationConfig{})
+	}
+	if envFlag("CCLOAD_REPLICA_S3") {
+		if cfg.s3 = dbBackupS3FromEnv(replicaS3Prefix); cfg.s3 == nil {
+			log.Print("[WARN] [数据库复制] CCLOAD_REPLICA_S3 已开启但 CCLOAD_BACKUP_S3_* 未配置或无效，已跳过 S3 复制")
+		}
+	}
+	return newDBReplicationService(s.store, dbPath, cfg)
+}
+
+// envFlag 布尔型环境变量（1/true/yes）
+func envFlag(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+func (r *dbReplicationService) supported() bool {
+	return r != nil && r.dbPath != ""
+}
+
+// shipping 是否作为主端发送快照
+func (r *dbReplicationService) shipping() bool {
+	return r.supported() && len(r.targets) > 0
+}
+
+// accepting 是否作为热备监视副本文件
+func (r *dbReplicationService) accepting() bool {
+	return r.supported() && r.cfg.accept
+}
+
+func (r *dbReplicationService) enabled() bool {
+	return r.shipping() || r.accepting()
+}
+
+func (r *dbReplicationService) snapshotPath() string {
+	return filepath.Join(r.cfg.dir, replicaSnapshotName)
+}
+
+func (r *dbReplicationService) standbyPath() string {
+	return filepath.Join(r.cfg.dir, replicaStandbyName)
+}
+
+// Status 复制状态快照
+func (r *dbReplicationService) Status() DBReplicationStatus {
+	if !r.enabled() {
+		return DBReplicationStatus{Healthy: true}
+	}
+	now := r.now()
+	st := DBReplicationStatus{
+		Enabled:         true,
+		Dir:             r.cfg.dir,
+		IntervalSeconds: int(r.cfg.interval / time.Second),
+		LagAlertSeconds: int(r.cfg.lagAlert / time.Second),
+		MaxDBMB:         r.cfg.maxDBMB,
+	}
+	if r.shipping() {
+		r.mu.Lock()
+		p := &ReplicaPrimaryStatus{LastCaptureAt: r.captureAt, LastCaptureBytes: r.captureBytes, LastError: r.lastErr}
+		for _, t := range r.targets {
+			ts := ReplicaTargetStatus{Kind: t.kind, Location: t.location, ShippedAt: t.shipped, LastShippedAt: t.sentAt,
+				LagSeconds: max(now.Sub(t.synced).Seconds(), 0), LastError: t.lastErr}
+			st.LagSeconds = max(st.LagSeconds, ts.LagSeconds)
+			p.Targets = append(p.Targets, ts)
+		}
+		r.mu.Unlock()
+		st.Primary = p
+	}
+	if r.accepting() {
+		sb := &ReplicaStandbyStatus{Path: r.standbyPath(), PendingRestore: storage.PendingSQLiteRestore(r.dbPath)}
+		lastUpdate := r.startedAt
+		if info, err := os.Stat(sb.Path); err == nil {
+			sb.Size, sb.ModifiedAt = info.Size(), info.ModTime().UnixMilli()
+			if info.ModTime().After(lastUpdate) {
+				lastUpdate = info.ModTime()
+			}
+		}
+		sb.LagSeconds = max(now.Sub(lastUpdate).Seconds(), 0)
+		st.LagSeconds = max(st.LagSeconds, sb.LagSeconds)
+		st.Standby = sb
+	}
+	st.Healthy = r.cfg.lagAlert <= 0 || st.LagSeconds <= r.cfg.lagAlert.Seconds()
+	if st.Primary != nil && st.Primary.LastError != "" {
+		st.Healthy = false
+	}
+	return st
+}
+
+// ============================================================================
+// 主端：checkpoint、快照与发送
+// ============================================================================
+
+// fileStamp 主库与 -wal 的大小和修改时间（不变时跳过快照）
+func (r *dbReplicationService) fileStamp() [4]int64 {
+	var stamp [4]int64
+	for i, p := range []string{r.dbPath, r.dbPath + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			stamp[2*i], stamp[2*i+1] = info.Size(), info.ModTime().UnixNano()
+		}
+	}
+	return stamp
+}
+
+// setError 记录主端错误（持续性错误每个周期都会出现，只在首次/变化时记录日志）
+func (r *dbReplicationService) setError(err error) {
+	r.mu.Lock()
+	repeated := r.lastErr == err.Error()
+	r.lastErr = err.Error()
+	r.mu.Unlock()
+	if !repeated {
+		log.Printf("[WARN] [数据库复制] %v", err)
+	}
+}
+
+// runCycle 执行 checkpoint，库有变更时生成快照，再把最新快照送到尚未送达的目标
+func (r *dbReplicationService) runCycle(ctx context.Context) {
+	if !r.shipping() {
+		return
+	}
+	started := r.now()
+	if err := r.store.CheckpointWAL(ctx); err != nil {
+		// checkpoint 失败不影响快照的一致性（VACUUM INTO 读取的是已提交数据）
+		log.Printf("[WARN] [数据库复制] WAL checkpoint 失败: %v", err)
+	}
+	stamp := r.fileStamp()
+	r.mu.Lock()
+	changed := stamp != r.lastStamp
+	r.mu.Unlock()
+	if changed {
+		if err := r.capture(ctx, stamp, started); err != nil {
+			r.setError(fmt.Errorf("snapshot: %w", err))
+			return
+		}
+	}
+
+	r.mu.Lock()
+	seq, capturedAt := r.captureSeq, r.captureAt
+	r.mu.Unlock()
+	for _, t := range r.targets {
+		r.mu.Lock()
+		pending := t.seq < seq
+		r.mu.Unlock()
+		var err error
+		if pending {
+			err = r.ship(ctx, t, capturedAt)
+		}
+		r.mu.Lock()
+		if err != nil {
+			if t.lastErr != err.Error() {
+				log.Printf("[WARN] [数据库复制] 发送快照到 %s 失败: %v", t.kind, err)
+			}
+			t.lastErr = err.Error()
+		} else {
+			if pending {
+				t.seq, t.shipped, t.sentAt = seq, capturedAt, r.now().UnixMilli()
+			}
+			t.synced, t.lastErr = started, ""
+		}
+		r.mu.Unlock()
+	}
+}
+
+// capture 复用备份模块的 VACUUM INTO 路径生成校验过的快照
+func (r *dbReplicationService) capture(ctx context.Context, stamp [4]int64, started time.Time) error {
+	// 快照需完整读写一遍数据库：超过上限时拒绝，避免每个周期都产生整库的读写
+	if limit := int64(r.cfg.maxDBMB) << 20; limit > 0 && stamp[0]+stamp[2] > limit {
+		return fmt.Errorf("%w: %d MB > %d MB, snapshot skipped", errReplicaTooLarge, (stamp[0]+stamp[2])>>20, r.cfg.maxDBMB)
+	}
+	if err := os.MkdirAll(r.cfg.dir, 0o750); err != nil { //nolint:gosec // G301: 复制目录需要服务进程可写
+		return err
+	}
+	if err := writeSQLiteSnapshot(ctx, r.store, r.snapshotPath()); err != nil {
+		return err
+	}
+	info, err := os.Stat(r.snapshotPath())
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.lastStamp = stamp
+	r.captureSeq++
+	r.captureAt, r.captureBytes, r.lastErr = started.UnixMilli(), info.Size(), ""
+	r.mu.Unlock()
+	return nil
+}
+
+// ship 把当前快照送到目标（S3 上传快照与清单；钩子执行运维配置的命令）
+func (r *dbReplicationService) ship(ctx context.Context, t *replicaTarget, capturedAt int64) error {
+	switch t.kind {
+	case replicaTargetS3:
+		info, err := os.Stat(r.snapshotPath())
+		if err != nil {
+			return err
+		}
+		if err := r.cfg.s3.UploadFile(ctx, r.snapshotPath(), replicaSnapshotObject); err != nil {
+			return err
+		}
+		data, err := sonic.Marshal(replicaManifest{CapturedAt: capturedAt, Size: info.Size(), UpdatedAt: r.now().UnixMilli()})
+		if err != nil {
+			return err
+		}
+		return r.cfg.s3.PutObject(ctx, replicaManifestName, data)
+	case replicaTargetHook:
+		return r.runHook(ctx, capturedAt)
+	}
+	return fmt.Errorf("unknown replication target %q", t.kind)
+}
+
+// runHook 执行 checkpoint 钩子命令，非零退出时返回输出的开头部分
+func (r *dbReplicationService) runHook(ctx context.Context, capturedAt int64) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", r.cfg.hook) //nolint:gosec // G204: 命令来自运维配置的环境变量
+	cmd.Env = append(os.Environ(),
+		"CCLOAD_REPLICA_SNAPSHOT="+r.snapshotPath(),
+		"CCLOAD_REPLICA_CAPTURED_AT="+strconv.FormatInt(capturedAt, 10),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(out) > replicaHookOutputLimit {
+			out = out[:replicaHookOutputLimit]
+		}
+		return fmt.Errorf("hook: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// dbReplicationLoop 复制后台协程（关闭时再执行一轮，尽量把最后的变更送出）
+func (s *Server) dbReplicationLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.dbReplication.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), replicaCycleTimeout)
+			s.dbReplication.runCycle(ctx)
+			cancel()
+		case <-s.shutdownCh:
+			ctx, cancel := context.WithTimeout(context.Background(), replicaShutdownFlushTimeout)
+			s.dbReplication.runCycle(ctx)
+			cancel()
+			return
+		}
+	}
+}
+
+// ============================================================================
+// 提升（切换）
+// ============================================================================
+
+// ReplicaPromoteResult 提升结果
+type ReplicaPromoteResult struct {
+	Source         string `json:"source"`
+	CapturedAt     int64  `json:"captured_at,omitempty"` // 副本对应的捕获时间（此后的变更未包含）
+	PendingRestore bool   `json:"pending_restore"`
+	Message        string `json:"message"`
+}
+
+// promote 从热备副本或 S3 最新快照恢复数据库，校验后暂存为待恢复文件（重启后换入）
+func (r *dbReplicationService) promote(ctx context.Context, source string) (*ReplicaPromoteResult, error) {
+	if !r.supported() {
+		return nil, errReplicaUnsupported
+	}
+	res := &ReplicaPromoteResult{Source: source}
+	var src string
+	switch source {
+	case replicaSourceStandby:
+		if !r.accepting() {
+			return nil, errors.New("this instance is not a replication standby (CCLOAD_REPLICA_ACCEPT)")
+		}
+		info, err := os.Stat(r.standbyPath())
+		if err != nil {
+			return nil, errReplicaNoData
+		}
+		src, res.CapturedAt = r.standbyPath(), info.ModTime().UnixMilli()
+	case replicaSourceS3:
+		if r.cfg.s3 == nil {
+			return nil, errors.New("s3 replication is not configured (CCLOAD_REPLICA_S3)")
+		}
+		if err := os.MkdirAll(r.cfg.dir, 0o750); err != nil { //nolint:gosec // G301: 复制目录需要服务进程可写
+			return nil, err
+		}
+		src = filepath.Join(r.cfg.dir, replicaPromoteName)
+		defer removeSQLiteFiles(src)
+		m, err := r.downloadFromS3(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+		res.CapturedAt = m.CapturedAt
+	default:
+		return nil, fmt.Errorf("invalid source %q (standby|s3)", source)
+	}
+
+	if err := storage.StageSQLiteRestore(ctx, r.dbPath, src); err != nil {
+		return nil, err
+	}
+	res.PendingRestore = true
+	res.Message = "副本已校验并暂存，重启服务后换入；当前数据库将保留为 .pre-restore-* 文件"
+	log.Printf("[WARN] [数据库复制] 已从%s暂存副本（捕获于 %s），重启服务后生效", source, time.UnixMilli(res.CapturedAt).Format(time.RFC3339))
+	return res, nil
+}
+
+// downloadFromS3 下载最新快照到 dst（清单缺失时仍可恢复，只是没有捕获时间）
+func (r *dbReplicationService) downloadFromS3(ctx context.Context, dst string) (replicaManifest, error) {
+	var m replicaManifest
+	var buf bytes.Buffer
+	if err := r.cfg.s3.DownloadTo(ctx, replicaManifestName, &buf); err == nil {
+		_ = sonic.Unmarshal(buf.Bytes(), &m)
+	}
+
+	removeSQLiteFiles(dst)
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // G304: 路径由复制模块生成
+	if err != nil {
+		return m, err
+	}
+	err = r.cfg.s3.DownloadTo(ctx, replicaSnapshotObject, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if errors.Is(err, util.ErrS3NotFound) {
+		return m, errReplicaNoData
+	}
+	if err != nil {
+		return m, fmt.Errorf("download %s: %w", replicaSnapshotObject, err)
+	}
+	return m, nil
+}
+
+// removeSQLiteFiles 删除临时库及校验时产生的 -wal/-shm
+func removeSQLiteFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		_ = os.Remove(path + suffix)
+	}
+}
+
+// ============================================================================
+// HTTP 接口
+// ============================================================================
+
+// HandleDBReplicationStatus 复制状态与滞后
+// GET /admin/replica
+func (s *Server) HandleDBReplicationStatus(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, s.dbReplication.Status())
+}
+
+// HandleDBReplicationPromote 将热备副本或 S3 上的最新快照暂存为待恢复文件，重启服务后生效
+// POST /admin/replica/promote {"source":"standby"|"s3"}
+func (s *Server) HandleDBReplicationPromote(c *gin.Context) {
+	var req struct {
+		Source string `json:"source"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Source != replicaSourceStandby && req.Source != replicaSourceS3) {
+		RespondErrorMsg(c, http.StatusBadRequest, "source is required (standby|s3)")
+		return
+	}
+	res, err := s.dbReplication.promote(c.Request.Context(), req.Source)
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		switch {
+		case errors.Is(err, errReplicaUnsupported):
+			status = http.StatusBadRequest
+		case errors.Is(err, errReplicaNoData):
+			status = http.StatusNotFound
+		}
+		RespondErrorMsg(c, status, err.Error())
+		return
+	}
+	RespondJSON(c, http.StatusOK, res)
+}
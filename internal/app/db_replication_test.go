@@ -0,0 +1,233 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+)
+
+// replicaChannelNames 读取副本文件中的渠道名（只读打开）
+func replicaChannelNames(t *testing.T, path string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	rows, err := db.Query("SELECT name FROM channels ORDER BY name")
+	if err != nil {
+		t.Fatalf("查询副本失败: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func newReplicationTestPrimary(t *testing.T) (storage.Store, string) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "ccload.db")
+	store, err := storage.CreateSQLiteStore(dbPath, nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store, dbPath
+}
+
+func createReplicaTestChannel(t *testing.T, store storage.Store, name string) {
+	t.Helper()
+	if _, err := store.CreateConfig(context.Background(), &model.Config{Name: name, URL: "https://a.example", ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 钩子把快照复制到热备目录，热备据副本文件计算滞后并可提升
+func TestDBReplication_HookShipsSnapshotToStandby(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	ctx := context.Background()
+	store, dbPath := newReplicationTestPrimary(t)
+
+	standbyDB := filepath.Join(t.TempDir(), "standby.db")
+	standby := newDBReplicationService(nil, standbyDB, dbReplicationConfig{dir: t.TempDir(), accept: true})
+	calls := filepath.Join(t.TempDir(), "calls")
+	hook := `cp "$CCLOAD_REPLICA_SNAPSHOT" "` + standby.standbyPath() + `" && echo "$CCLOAD_REPLICA_CAPTURED_AT" >> "` + calls + `"`
+	primary := newDBReplicationService(store, dbPath, dbReplicationConfig{dir: filepath.Join(t.TempDir(), "replica"), hook: hook})
+
+	if _, err := standby.promote(ctx, replicaSourceStandby); err == nil || !strings.Contains(err.Error(), errReplicaNoData.Error()) {
+		t.Fatalf("尚无副本时应报错, got %v", err)
+	}
+
+	createReplicaTestChannel(t, store, "one")
+	primary.runCycle(ctx)
+	createReplicaTestChannel(t, store, "two")
+	primary.runCycle(ctx)
+	st := primary.Status()
+	if !st.Healthy || st.Primary.LastCaptureAt == 0 || st.Primary.Targets[0].ShippedAt != st.Primary.LastCaptureAt || st.Primary.Targets[0].LastError != "" {
+		t.Fatalf("钩子应已送达最新快照, got %+v", st.Primary)
+	}
+	if names := replicaChannelNames(t, standby.standbyPath()); !slices.Equal(names, []string{"one", "two"}) {
+		t.Fatalf("副本渠道 = %v", names)
+	}
+
+	// 库未变化时不再生成快照、不再执行钩子
+	primary.runCycle(ctx)
+	data, err := os.ReadFile(calls) //nolint:gosec // G304: 测试临时文件
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Fatalf("钩子应执行2次, got %d", n)
+	}
+
+	sb := standby.Status()
+	if sb.Standby == nil || sb.Standby.Size == 0 || sb.Standby.LagSeconds > 60 {
+		t.Fatalf("热备应报告副本, got %+v", sb.Standby)
+	}
+	res, err := standby.promote(ctx, replicaSourceStandby)
+	if err != nil || !res.PendingRestore {
+		t.Fatalf("promote: %+v %v", res, err)
+	}
+	if !storage.PendingSQLiteRestore(standbyDB) {
+		t.Fatal("提升后应存在待恢复文件")
+	}
+	if _, err := primary.promote(ctx, replicaSourceStandby); err == nil {
+		t.Fatal("非热备实例不应允许从热备副本提升")
+	}
+
+	// 钩子失败：下个周期重试，滞后超过阈值后不健康
+	primary.cfg.hook = "echo boom >&2; exit 3"
+	primary.cfg.lagAlert = 5 * time.Minute
+	createReplicaTestChannel(t, store, "three")
+	primary.runCycle(ctx)
+	primary.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	if st := primary.Status(); st.Healthy || st.LagSeconds < 300 || !strings.Contains(st.Primary.Targets[0].LastError, "boom") {
+		t.Fatalf("钩子失败时应报告滞后, got %+v", st.Primary)
+	}
+}
+
+// fakeS3 内存对象存储（PUT 保存，GET 读取）
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(body)
+	}
+}
+
+func TestDBReplication_S3SnapshotAndPromote(t *testing.T) {
+	ctx := context.Background()
+	store, dbPath := newReplicationTestPrimary(t)
+	objects := &fakeS3{objects: map[string][]byte{}}
+	srv := httptest.NewServer(objects)
+	defer srv.Close()
+	s3 := func() *util.S3Uploader {
+		u, err := util.NewS3Uploader(util.S3Config{Endpoint: srv.URL, Bucket: "bk", AccessKey: "ak", SecretKey: "sk", Prefix: replicaS3Prefix}, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	// 新主机：S3 上尚无复制数据
+	newHostDB := filepath.Join(t.TempDir(), "ccload.db")
+	newHost := newDBReplicationService(nil, newHostDB, dbReplicationConfig{dir: filepath.Join(t.TempDir(), "replica"), s3: s3()})
+	if _, err := newHost.promote(ctx, replicaSourceS3); err == nil || !strings.Contains(err.Error(), errReplicaNoData.Error()) {
+		t.Fatalf("无复制数据时应报错, got %v", err)
+	}
+
+	primary := newDBReplicationService(store, dbPath, dbReplicationConfig{dir: filepath.Join(t.TempDir(), "replica"), s3: s3()})
+	createReplicaTestChannel(t, store, "one")
+	primary.runCycle(ctx)
+	createReplicaTestChannel(t, store, "two")
+	primary.runCycle(ctx)
+	st := primary.Status().Primary
+	if st.Targets[0].ShippedAt != st.LastCaptureAt || st.Targets[0].LastError != "" {
+		t.Fatalf("S3 应已收到最新快照, got %+v", st)
+	}
+	if _, ok := objects.objects["/bk/replica/"+replicaManifestName]; !ok {
+		t.Fatal("应上传 latest.json")
+	}
+
+	res, err := newHost.promote(ctx, replicaSourceS3)
+	if err != nil || res.CapturedAt != st.LastCaptureAt {
+		t.Fatalf("promote: %+v %v", res, err)
+	}
+	if names := replicaChannelNames(t, newHostDB+storage.PendingRestoreSuffix); !slices.Equal(names, []string{"one", "two"}) {
+		t.Fatalf("恢复后渠道 = %v", names)
+	}
+
+	// 快照损坏时拒绝提升
+	key := "/bk/replica/" + replicaSnapshotObject
+	objects.objects[key] = objects.objects[key][:len(objects.objects[key])/2]
+	if _, err := newHost.promote(ctx, replicaSourceS3); err == nil || !strings.Contains(err.Error(), "verification failed") {
+		t.Fatalf("损坏的快照应被拒绝, got %v", err)
+	}
+}
+
+// 每次快照都要完整读写一遍数据库：超过 db_replication_max_db_mb 时拒绝快照并标记为不健康
+func TestDBReplication_RefusesOversizedDatabase(t *testing.T) {
+	ctx := context.Background()
+	store, dbPath := newReplicationTestPrimary(t)
+	primary := newDBReplicationService(store, dbPath, dbReplicationConfig{dir: filepath.Join(t.TempDir(), "replica"),
+		hook: "true", maxDBMB: 1})
+	if primary.cfg.interval != defaultDBReplicationIntervalSeconds*time.Second || defaultDBReplicationIntervalSeconds < 60 {
+		t.Fatalf("默认快照间隔应不小于60秒, got %v", primary.cfg.interval)
+	}
+
+	big := strings.Repeat("x", 64<<10)
+	for range 24 {
+		if err := store.AddLog(ctx, &model.LogEntry{Time: model.JSONTime{Time: time.Now()}, StatusCode: 500, Message: big}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	primary.runCycle(ctx)
+	st := primary.Status()
+	if st.Healthy || st.MaxDBMB != 1 || st.Primary.LastCaptureAt != 0 || !strings.Contains(st.Primary.LastError, "db_replication_max_db_mb") {
+		t.Fatalf("超过上限应拒绝快照, got %+v %+v", st, st.Primary)
+	}
+
+	// 上限调高后恢复快照
+	primary.cfg.maxDBMB = 0
+	primary.runCycle(ctx)
+	if st := primary.Status().Primary; st.LastCaptureAt == 0 || st.LastError != "" {
+		t.Fatalf("不限制时应正常快照, got %+v", st)
+	}
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 费用展示币种（2026-10新增）
+// ============================================================================
+// 计费与存储始终使用美元；配置 display_currency（ISO 4217 代码，如 CNY）后，/admin/stats、/public/summary、
+// /admin/auth-tokens 在美元金额旁附带 *_display 换算金额及 currency（币种、汇率、来源、更新时间）。
+// 汇率来源：exchange_rate > 0 时使用手动汇率；否则每 6 小时从 exchange_rate_url 拉取
+// （兼容 {"rates":{"CNY":7.1}} / {"conversion_rates":{...}} 格式，基准货币须为 USD），
+// 拉取失败时保留上次成功的汇率。三项设置修改后立即生效。
+
+const (
+	exchangeRateRefreshInterval = 6 * time.Hour
+	exchangeRateFetchTimeout    = 10 * time.Second
+	exchangeRateMaxBodyBytes    = 1 << 20
+)
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// DisplayCurrencyInfo 展示币种与当前汇率（1 USD = Rate 单位展示币种）
+type DisplayCurrencyInfo struct {
+	Code      string  `json:"code"`
+	Rate      float64 `json:"rate"`                 // 0 表示汇率尚不可用（不输出换算金额）
+	Source    string  `json:"source"`               // manual / auto
+	UpdatedAt int64   `json:"updated_at,omitempty"` // 自动汇率最近成功拉取时间（Unix秒）
+	Error     string  `json:"error,omitempty"`      // 最近一次拉取失败原因
+}
+
+// displayCurrency 展示币种配置与自动汇率状态（nil 安全：未初始化时不换算）
+type displayCurrency struct {
+	client  *http.Client
+	refresh chan struct{} // 配置变更后触发立即拉取
+
+	mu         sync.RWMutex
+	code       string
+	manualRate float64
+	url        string
+	autoRate   float64
+	autoCode   string // autoRate 对应的币种（切换币种后旧汇率失效）
+	updatedAt  time.Time
+	lastErr    string
+}
+
+func newDisplayCurrency() *displayCurrency {
+	return &displayCurrency{
+		client:  &http.Client{Timeout: exchangeRateFetchTimeout},
+		refresh: make(chan struct{}, 1),
+	}
+}
+
+// parseDisplayCurrency 规范化币种代码（空表示仅显示美元）
+func parseDisplayCurrency(value string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(value))
+	if code != "" && !currencyCodePattern.MatchString(code) {
+		return "", fmt.Errorf("display_currency must be a 3-letter ISO 4217 code (e.g. CNY)")
+	}
+	return code, nil
+}
+
+// parseExchangeRate 解析手动汇率（空或0表示使用自动汇率）
+func parseExchangeRate(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1e6 {
+		return 0, fmt.Errorf("exchange_rate must be a number within 0-1000000 (0 = fetch from exchange_rate_url)")
+	}
+	return rate, nil
+}
+
+func (d *displayCurrency) setCode(value string) {
+	code, err := parseDisplayCurrency(value)
+	if err != nil {
+		log.Printf("[WARN] %v，已关闭展示币种", err)
+	}
+	d.mu.Lock()
+	d.code = code
+	d.mu.Unlock()
+	d.triggerRefresh()
+}
+
+func (d *displayCurrency) setManualRate(value string) {
+	rate, err := parseExchangeRate(value)
+	if err != nil {
+		log.Printf("[WARN] %v，已改用自动汇率", err)
+	}
+	d.mu.Lock()
+	d.manualRate = rate
+	d.mu.Unlock()
+	d.triggerRefresh()
+}
+
+func (d *displayCurrency) setURL(value string) {
+	d.mu.Lock()
+	d.url = strings.TrimSpace(value)
+	d.mu.Unlock()
+	d.triggerRefresh()
+}
+
+func (d *displayCurrency) triggerRefresh() {
+	select {
+	case d.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// info 当前展示币种；未配置（或为USD）时返回 nil
+func (d *displayCurrency) info() *DisplayCurrencyInfo {
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	switch {
+	case d.code == "" || d.code == "USD":
+		return nil
+	case d.manualRate > 0:
+		return &DisplayCurrencyInfo{Code: d.code, Rate: d.manualRate, Source: "manual"}
+	}
+	info := &DisplayCurrencyInfo{Code: d.code, Source: "auto", Error: d.lastErr}
+	if d.autoCode == d.code && d.autoRate > 0 {
+		info.Rate, info.UpdatedAt = d.autoRate, d.updatedAt.Unix()
+	} else if info.Error == "" && d.url == "" {
+		info.Error = "exchange_rate and exchange_rate_url are both empty"
+	}
+	return info
+}
+
+// convert 美元 → 展示币种；汇率不可用时返回 nil
+func (info *DisplayCurrencyInfo) convert(usd float64) *float64 {
+	if info == nil || info.Rate <= 0 {
+		return nil
+	}
+	v := usd * info.Rate
+	return &v
+}
+
+// needsFetch 是否需要自动拉取汇率（已配置非USD币种、无手动汇率且配置了拉取地址）
+func (d *displayCurrency) needsFetch() (code, url string, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.code == "" || d.code == "USD" || d.manualRate > 0 || d.url == "" {
+		return "", "", false
+	}
+	return d.code, d.url, true
+}
+
+// refreshRate 拉取并记录自动汇率（失败时保留上次成功的汇率）
+func (d *displayCurrency) refreshRate(ctx context.Context) {
+	code, url, ok := d.needsFetch()
+	if !ok {
+		return
+	}
+	rate, err := d.fetchRate(ctx, url, code)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.code != code {
+		return // 拉取期间币种已变更，等待下一次触发
+	}
+	if err != nil {
+		d.lastErr = util.RedactSecrets(err.Error())
+		log.Printf("[WARN] [展示币种] 拉取 USD→%s 汇率失败: %s", code, d.lastErr)
+		return
+	}
+	d.autoRate, d.autoCode, d.updatedAt, d.lastErr = rate, code, time.Now(), ""
+}
+
+// fetchRate 请求汇率接口并读取 USD→code 汇率
+func (d *displayCurrency) fetchRate(ctx context.Context, url, code string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, exchangeRateMaxBodyBytes))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("exchange rate source returned HTTP %d", resp.StatusCode)
+	}
+	return parseExchangeRateResponse(body, code)
+}
+
+// parseExchangeRateResponse 从常见汇率接口响应中读取 USD→code 汇率
+func parseExchangeRateResponse(body []byte, code string) (float64, error) {
+	var parsed struct {
+		Base            string             `json:"base"`
+		BaseCode        string             `json:"base_code"`
+		Rates           map[string]float64 `json:"rates"`
+		ConversionRates map[string]float64 `json:"conversion_rates"`
+	}
+	if err := sonic.Unmarshal(body, &parsed); err != nil {
+		return 0, fmt.Errorf("invalid exchange rate response: %w", err)
+	}
+	if base := strings.ToUpper(cmp.Or(parsed.Base, parsed.BaseCode)); base != "" && base != "USD" {
+		return 0, fmt.Errorf("exchange rate base currency is %s, expected USD", base)
+	}
+	rates := parsed.Rates
+	if len(rates) == 0 {
+		rates = parsed.ConversionRates
+	}
+	rate := rates[code]
+	if rate <= 0 {
+		return 0, fmt.Errorf("exchange rate for %s not found in response", code)
+	}
+	return rate, nil
+}
+
+// exchangeRateLoop 定期（及配置变更后）拉取自动汇率
+func (s *Server) exchangeRateLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(exchangeRateRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), exchangeRateFetchTimeout)
+		s.displayCurrency.refreshRate(ctx)
+		cancel()
+
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+		case <-s.displayCurrency.refresh:
+		}
+	}
+}
+
+func (s *Server) setDisplayCurrency(value string) { s.displayCurrency.setCode(value) }
+func (s *Server) setExchangeRate(value string)    { s.displayCurrency.setManualRate(value) }
+func (s *Server) setExchangeRateURL(value string) { s.displayCurrency.setURL(value) }
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseExchangeRateResponse(t *testing.T) {
+	for body, want := range map[string]float64{
+		`{"result":"success","base_code":"USD","rates":{"CNY":7.12,"EUR":0.9}}`: 7.12,
+		`{"base":"usd","rates":{"CNY":7.2}}`:                                    7.2,
+		`{"conversion_rates":{"CNY":7.3}}`:                                      7.3,
+	} {
+		if got, err := parseExchangeRateResponse([]byte(body), "CNY"); err != nil || got != want {
+			t.Fatalf("%s: 期望 %v，实际 %v err=%v", body, want, got, err)
+		}
+	}
+	for _, body := range []string{`{"base":"EUR","rates":{"CNY":7.8}}`, `{"rates":{"EUR":0.9}}`, `not json`} {
+		if _, err := parseExchangeRateResponse([]byte(body), "CNY"); err == nil {
+			t.Fatalf("%s: 应返回错误", body)
+		}
+	}
+}
+
+func TestDisplayCurrency_StatsAndSummary(t *testing.T) {
+	rateSource := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"base_code":"USD","rates":{"CNY":7.5,"JPY":150}}`))
+	}))
+	defer rateSource.Close()
+
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "currency.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "c", URL: "https://api.example.com", ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.BatchAddLogs(ctx, []*model.LogEntry{
+		{Time: model.JSONTime{Time: time.Now()}, Model: "claude-sonnet-4-5", ChannelID: cfg.ID, StatusCode: 200, Message: "ok", InputTokens: 10, Cost: 2},
+	}); err != nil {
+		t.Fatalf("写入日志失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	call := func(handler gin.HandlerFunc, method, path, body string, params gin.Params) map[string]any {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		handler(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s 返回 %d: %s", path, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Data
+	}
+	setSetting := func(key, value string) {
+		call(srv.AdminUpdateSetting, http.MethodPut, "/admin/settings/"+key, `{"value":"`+value+`"}`, gin.Params{{Key: "key", Value: key}})
+	}
+
+	// 未配置展示币种：只输出美元
+	summary := call(srv.HandlePublicSummary, http.MethodGet, "/public/summary?range=today", "", nil)
+	if summary["currency"] != nil || summary["total_cost"] != 2.0 {
+		t.Fatalf("未配置时不应换算: %v", summary)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/admin/settings/display_currency", strings.NewReader(`{"value":"yuan"}`))
+	c.Params = gin.Params{{Key: "key", Value: "display_currency"}}
+	srv.AdminUpdateSetting(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("非法币种应被拒绝: %d", w.Code)
+	}
+
+	setSetting("exchange_rate_url", rateSource.URL)
+	setSetting("display_currency", "cny")
+	deadline := time.Now().Add(3 * time.Second)
+	for srv.displayCurrency.info().Rate == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	info := srv.displayCurrency.info()
+	if info == nil || info.Code != "CNY" || info.Rate != 7.5 || info.Source != "auto" || info.UpdatedAt == 0 {
+		t.Fatalf("自动汇率不符: %+v", info)
+	}
+
+	summary = call(srv.HandlePublicSummary, http.MethodGet, "/public/summary?range=today", "", nil)
+	byType := summary["by_type"].(map[string]any)["anthropic"].(map[string]any)
+	if summary["total_cost_display"] != 15.0 || byType["total_cost"] != 2.0 || byType["total_cost_display"] != 15.0 {
+		t.Fatalf("摘要换算不符: %v", summary)
+	}
+
+	// 手动汇率优先
+	setSetting("exchange_rate", "7")
+	stats := call(srv.HandleStats, http.MethodGet, "/admin/stats?range=today", "", nil)
+	currency := stats["currency"].(map[string]any)
+	row := stats["stats"].([]any)[0].(map[string]any)
+	if currency["source"] != "manual" || currency["rate"] != 7.0 || stats["total_cost_display"] != 14.0 || row["total_cost_display"] != 14.0 {
+		t.Fatalf("统计换算不符: %v", stats)
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// 故障转移信息（2026-10新增）
+// ============================================================================
+// 令牌开启 failover_info 后，响应附带本次请求的上游尝试情况（不含渠道名称、Key等运营信息）：
+//   - 响应头：X-CCLoad-Attempts（上游尝试次数，含同渠道换Key）、X-CCLoad-Channel-Type（最终渠道类型）
+//   - 流式成功响应（text/event-stream）：在首个事件前插入 SSE 注释行
+//     ": ccload-failover attempts=N channel_type=T"，按SSE规范客户端会忽略注释，不影响现有SDK
+// 尝试次数 > 1 即表示发生了静默故障转移。
+
+const (
+	headerCCLoadAttempts    = "X-CCLoad-Attempts"
+	headerCCLoadChannelType = "X-CCLoad-Channel-Type"
+)
+
+// failoverInfo 单次请求的上游尝试记录（nil 表示令牌未开启）
+type failoverInfo struct {
+	attempts    int
+	channelType string // 最近一次尝试的渠道类型
+}
+
+// recordAttempt 记录一次上游尝试
+func (f *failoverInfo) recordAttempt(channelType string) {
+	if f == nil {
+		return
+	}
+	f.attempts++
+	f.channelType = channelType
+}
+
+// setHeaders 写入故障转移信息响应头（尚未发生上游尝试时不写）
+func (f *failoverInfo) setHeaders(h http.Header) {
+	if f == nil || f.attempts == 0 {
+		return
+	}
+	h.Set(headerCCLoadAttempts, strconv.Itoa(f.attempts))
+	if f.channelType != "" {
+		h.Set(headerCCLoadChannelType, f.channelType)
+	}
+}
+
+// failoverInfoWriter 在响应头提交时附带故障转移信息
+type failoverInfoWriter struct {
+	http.ResponseWriter
+	info        *failoverInfo
+	wroteHeader bool
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter（SetWriteDeadline 等）
+func (w *failoverInfoWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush 透传 Flush
+func (w *failoverInfoWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *failoverInfoWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.info.setHeaders(w.Header())
+	w.ResponseWriter.WriteHeader(code)
+	if code >= 200 && code < 300 && w.info.attempts > 0 &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		_, _ = fmt.Fprintf(w.ResponseWriter, ": ccload-failover attempts=%d channel_type=%s\n\n", w.info.attempts, w.info.channelType)
+	}
+}
+
+func (w *failoverInfoWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// newFailoverInfo 令牌开启故障转移信息时返回记录器，否则返回nil
+func (s *Server) newFailoverInfo(tokenHash string) *failoverInfo {
+	if tokenHash == "" || s.authService == nil || !s.authService.FailoverInfoEnabled(tokenHash) {
+		return nil
+	}
+	return &failoverInfo{}
+}
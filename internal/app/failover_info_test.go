@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestFailoverInfo_StreamAndHeaders 首个渠道失败后转移到第二个渠道：开启的令牌收到尝试次数与渠道类型，未开启的令牌不受影响
+func TestFailoverInfo_StreamAndHeaders(t *testing.T) {
+	const sse = "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-x","usage":{"input_tokens":3,"output_tokens":0}}}` + "\n\n" +
+		"event: message_stop\n" + `data: {"type":"message_stop"}` + "\n\n"
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, `{"error":"boom"}`)
+	}))
+	defer broken.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, sse)
+	}))
+	defer healthy.Close()
+
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	srv := NewServer(store)
+	srv.responseBufferBytes = 0
+	ctx := context.Background()
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	var brokenID int64
+	for _, ch := range []struct {
+		name     string
+		url      string
+		priority int
+	}{{"broken", broken.URL, 10}, {"healthy", healthy.URL, 1}} {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: ch.name, URL: ch.url, ChannelType: "anthropic", Priority: ch.priority, Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-x"}},
+		})
+		if err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+		if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+			{ChannelID: cfg.ID, APIKey: "sk-" + ch.name, KeyStrategy: model.KeyStrategySequential},
+		}); err != nil {
+			t.Fatalf("创建Key失败: %v", err)
+		}
+		if ch.name == "broken" {
+			brokenID = cfg.ID
+		}
+	}
+
+	optIn := &model.AuthToken{Token: model.HashToken("plain-opt-in"), Description: "opt-in", IsActive: true, FailoverInfo: true}
+	plain := &model.AuthToken{Token: model.HashToken("plain-default"), Description: "default", IsActive: true}
+	for _, tok := range []*model.AuthToken{optIn, plain} {
+		if err := store.CreateAuthToken(ctx, tok); err != nil {
+			t.Fatalf("创建令牌失败: %v", err)
+		}
+	}
+	if err := srv.authService.ReloadAuthTokens(); err != nil {
+		t.Fatalf("加载令牌失败: %v", err)
+	}
+
+	run := func(tok *model.AuthToken) *httptest.ResponseRecorder {
+		// 每次请求前清除冷却，保证两次请求都经历一次故障转移
+		_ = store.ResetChannelCooldown(ctx, brokenID)
+		srv.invalidateCooldownCache()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+			bytes.NewBufferString(`{"model":"claude-x","stream":true,"max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("token_hash", tok.Token)
+		c.Set("token_id", tok.ID)
+		srv.HandleProxyRequest(c)
+		return w
+	}
+
+	w := run(optIn)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(headerCCLoadAttempts); got != "2" {
+		t.Errorf("%s = %q, want 2", headerCCLoadAttempts, got)
+	}
+	if got := w.Header().Get(headerCCLoadChannelType); got != "anthropic" {
+		t.Errorf("%s = %q, want anthropic", headerCCLoadChannelType, got)
+	}
+	if !strings.HasPrefix(w.Body.String(), ": ccload-failover attempts=2 channel_type=anthropic\n\n") {
+		t.Errorf("流式响应应以故障转移注释开头, got %q", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "message_stop") {
+		t.Errorf("上游事件应完整透传, got %q", w.Body.String())
+	}
+
+	w = run(plain)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if w.Header().Get(headerCCLoadAttempts) != "" || strings.Contains(w.Body.String(), "ccload-failover") {
+		t.Errorf("未开启的令牌不应附带故障转移信息: headers=%v body=%q", w.Header(), w.Body.String())
+	}
+}
+
+// TestFailoverInfoWriter_NonStream 非流式响应只附带响应头，不改动响应体
+func TestFailoverInfoWriter_NonStream(t *testing.T) {
+	info := &failoverInfo{}
+	info.recordAttempt("openai")
+	info.recordAttempt("codex")
+
+	rec := httptest.NewRecorder()
+	w := &failoverInfoWriter{ResponseWriter: rec, info: info}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"ok":true}`))
+
+	if rec.Header().Get(headerCCLoadAttempts) != "2" || rec.Header().Get(headerCCLoadChannelType) != "codex" {
+		t.Fatalf("unexpected headers: %v", rec.Header())
+	}
+	if rec.Body.String() != `{"ok":true}` {
+		t.Fatalf("body modified: %q", rec.Body.String())
+	}
+
+	var nilInfo *failoverInfo
+	nilInfo.recordAttempt("openai") // nil 记录器为空操作
+	h := http.Header{}
+	nilInfo.setHeaders(h)
+	if len(h) != 0 {
+		t.Fatalf("nil info should not set headers: %v", h)
+	}
+}
@@ -116,6 +116,42 @@ func TestBuildProxyRequest(t *testing.T) {
 	}
 }
 
+// TestBuildProxyRequest_Azure Azure OpenAI 渠道按部署名映射端点并使用 api-key 认证
+func TestBuildProxyRequest_Azure(t *testing.T) {
+	store, _ := storage.CreateSQLiteStore(":memory:", nil)
+	srv := NewServer(store)
+
+	cfg := &model.Config{
+		ID:          1,
+		Name:        "azure",
+		URL:         "https://res.openai.azure.com/?api-version=2025-01-01-preview",
+		ChannelType: util.ChannelTypeAzure,
+	}
+	reqCtx := &requestContext{ctx: context.Background(), startTime: time.Now()}
+
+	req, err := srv.buildProxyRequest(reqCtx, cfg, "azure-key", http.MethodPost,
+		[]byte(`{"model":"gpt4o-prod","messages":[]}`),
+		http.Header{"Authorization": []string{"Bearer client-token"}, "Api-Key": []string{"client-token"}},
+		"", "/v1/chat/completions")
+	if err != nil {
+		t.Fatalf("buildProxyRequest failed: %v", err)
+	}
+	want := "https://res.openai.azure.com/openai/deployments/gpt4o-prod/chat/completions?api-version=2025-01-01-preview"
+	if req.URL.String() != want {
+		t.Errorf("URL = %s, want %s", req.URL.String(), want)
+	}
+	if req.Header.Get("api-key") != "azure-key" || req.Header.Get("Authorization") != "" {
+		t.Errorf("azure request should only carry the channel api-key, got %v", req.Header)
+	}
+
+	if !channelMatchesType(cfg, util.ChannelTypeOpenAI) || channelMatchesType(cfg, util.ChannelTypeAnthropic) {
+		t.Error("azure channels should serve openai requests only")
+	}
+	if got := filterExactChannelType([]*model.Config{cfg}, util.ChannelTypeOpenAI); len(got) != 1 {
+		t.Error("azure channels should be kept for native openai paths such as /v1/embeddings")
+	}
+}
+
 // TestHandleRequestError 测试错误处理
 func TestHandleRequestError(t *testing.T) {
 	store, _ := storage.CreateSQLiteStore(":memory:", nil)
@@ -0,0 +1,492 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Gemini API Key 自动供给（2026-10新增）
+// ============================================================================
+// 大规模 Gemini 渠道手工管理Key成本高。配置服务账号后，供给任务定期：
+// 1. 列出项目中的Key，剔除已在Google侧被删除的托管Key
+// 2. 托管Key超过轮换周期（或数量超出 key_count）时标记退役
+// 3. 创建新Key补足 key_count（限定只能调用 generativelanguage.googleapis.com）
+// 4. 重写渠道Key列表：手工Key保持原顺序在前，托管Key在后
+// 5. 渠道更新完成后再删除退役Key（避免新Key生效前出现空窗）
+// 创建失败时本轮不退役旧Key，保证渠道Key数量不会因供给失败而减少。
+
+const (
+	geminiProvisionCheckInterval = 10 * time.Minute // 调度检查间隔
+	geminiProvisionRunInterval   = time.Hour        // 每个配置的最小运行间隔（手动触发不受限）
+	geminiProvisionMaxKeys       = 50
+	geminiProvisionRunTimeout    = 5 * time.Minute
+)
+
+// newGCPAPIKeysClient 创建 Google API Keys 客户端（测试中替换为本地服务）
+var newGCPAPIKeysClient = func(sa *util.GCPServiceAccount) *util.GCPAPIKeysClient {
+	return util.NewGCPAPIKeysClient(sa, nil, "")
+}
+
+// geminiProvisionMu 串行化供给运行（调度与手动触发共用，避免并发创建重复Key）
+var geminiProvisionMu sync.Mutex
+
+// GeminiProvisionResult 单次供给运行结果
+type GeminiProvisionResult struct {
+	Created     int      `json:"created"`
+	Retired     int      `json:"retired"`
+	Managed     int      `json:"managed"`
+	ChannelKeys int      `json:"channel_keys"`
+	Errors      []string `json:"errors"`
+}
+
+// runGeminiProvisioner 执行一次供给，并保存运行状态
+func (s *Server) runGeminiProvisioner(ctx context.Context, p *model.GeminiKeyProvisioner) *GeminiProvisionResult {
+	geminiProvisionMu.Lock()
+	defer geminiProvisionMu.Unlock()
+
+	res := &GeminiProvisionResult{Errors: []string{}}
+	if err := s.provisionGeminiKeys(ctx, p, res); err != nil {
+		res.Errors = append(res.Errors, err.Error())
+	}
+
+	p.LastRunAt = time.Now().Unix()
+	p.LastError = strings.Join(res.Errors, "; ")
+	if err := s.store.UpdateGeminiKeyProvisionerState(ctx, p); err != nil {
+		log.Printf("[WARN] [Gemini供给] 保存运行状态失败 (provisioner=%d): %v", p.ID, err)
+	}
+	if len(res.Errors) > 0 {
+		log.Printf("[WARN] [Gemini供给] 渠道ID=%d 供给出错: %s", p.ChannelID, p.LastError)
+	} else if res.Created > 0 || res.Retired > 0 {
+		log.Printf("[INFO] [Gemini供给] 渠道ID=%d 新建%d个Key，退役%d个Key，当前托管%d个", p.ChannelID, res.Created, res.Retired, res.Managed)
+	}
+	return res
+}
+
+func (s *Server) provisionGeminiKeys(ctx context.Context, p *model.GeminiKeyProvisioner, res *GeminiProvisionResult) error {
+	sa, err := util.ParseGCPServiceAccount(p.Credentials)
+	if err != nil {
+		return err
+	}
+	client := newGCPAPIKeysClient(sa)
+
+	remote, err := client.ListKeys(ctx, p.ProjectID)
+	if err != nil {
+		return err
+	}
+	remoteNames := make(map[string]struct{}, len(remote))
+	for _, k := range remote {
+		remoteNames[k.Name] = struct{}{}
+	}
+
+	channelKeys, err := s.store.GetAPIKeys(ctx, p.ChannelID)
+	if err != nil {
+		return fmt.Errorf("load channel keys: %w", err)
+	}
+	keyByHash := make(map[string]string, len(channelKeys))
+	for _, k := range channelKeys {
+		keyByHash[model.HashToken(k.APIKey)] = k.APIKey
+	}
+
+	// 1-2. 区分保留与退役的托管Key
+	now := time.Now()
+	var kept, retired []model.GeminiManagedKey
+	for _, mk := range p.ManagedKeys {
+		if _, ok := remoteNames[mk.Name]; !ok {
+			continue // Google侧已删除
+		}
+		if p.RotateIntervalHours > 0 && now.Sub(time.Unix(mk.CreatedAt, 0)) >= time.Duration(p.RotateIntervalHours)*time.Hour {
+			retired = append(retired, mk)
+			continue
+		}
+		kept = append(kept, mk)
+	}
+	slices.SortStableFunc(kept, func(a, b model.GeminiManagedKey) int { return int(a.CreatedAt - b.CreatedAt) })
+	if len(kept) > p.KeyCount {
+		retired = append(retired, kept[:len(kept)-p.KeyCount]...) // 数量下调：退役最旧的
+		kept = kept[len(kept)-p.KeyCount:]
+	}
+
+	// 托管Key明文：优先取渠道中的Key，缺失（被手工删除）时从API找回
+	keyStrings := make(map[string]string, len(kept))
+	for _, mk := range kept {
+		if v, ok := keyByHash[mk.KeyHash]; ok {
+			keyStrings[mk.Name] = v
+			continue
+		}
+		v, err := client.KeyString(ctx, mk.Name)
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+			continue
+		}
+		keyStrings[mk.Name] = v
+	}
+
+	// 3. 补足数量
+	createFailed := false
+	for i := 0; len(kept) < p.KeyCount; i++ {
+		keyID := fmt.Sprintf("ccload-%d-%d-%d", p.ChannelID, now.UnixMilli(), i)
+		key, keyString, err := client.CreateGeminiKey(ctx, p.ProjectID, keyID, fmt.Sprintf("ccLoad channel #%d", p.ChannelID))
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+			createFailed = true
+			break
+		}
+		mk := model.GeminiManagedKey{Name: key.Name, KeyHash: model.HashToken(keyString), CreatedAt: now.Unix()}
+		kept = append(kept, mk)
+		keyStrings[mk.Name] = keyString
+		res.Created++
+	}
+	if createFailed {
+		// 新Key不足：本轮保留待退役的Key，下次运行再轮换
+		kept = append(kept, retired...)
+		for _, mk := range retired {
+			if v, ok := keyByHash[mk.KeyHash]; ok {
+				keyStrings[mk.Name] = v
+			}
+		}
+		retired = nil
+	}
+
+	// 4. 重写渠道Key列表
+	managedHashes := make(map[string]struct{}, len(p.ManagedKeys)+len(kept))
+	for _, mk := range p.ManagedKeys {
+		managedHashes[mk.KeyHash] = struct{}{}
+	}
+	for _, mk := range kept {
+		managedHashes[mk.KeyHash] = struct{}{}
+	}
+	desired := make([]string, 0, len(channelKeys)+len(kept))
+	for _, k := range channelKeys {
+		if _, managed := managedHashes[model.HashToken(k.APIKey)]; !managed {
+			desired = append(desired, k.APIKey)
+		}
+	}
+	for _, mk := range kept {
+		if v := keyStrings[mk.Name]; v != "" {
+			desired = append(desired, v)
+		}
+	}
+	if err := s.replaceChannelKeys(ctx, p.ChannelID, channelKeys, desired); err != nil {
+		return err
+	}
+	p.ManagedKeys = kept
+	res.Managed = len(kept)
+	res.ChannelKeys = len(desired)
+
+	// 5. 删除退役Key
+	for _, mk := range retired {
+		if err := client.DeleteKey(ctx, mk.Name); err != nil {
+			res.Errors = append(res.Errors, err.Error())
+			continue
+		}
+		res.Retired++
+	}
+	return nil
+}
+
+// replaceChannelKeys Key列表变化时重建渠道Key（保持原Key策略），并刷新缓存与脱敏集合
+func (s *Server) replaceChannelKeys(ctx context.Context, channelID int64, current []*model.APIKey, desired []string) error {
+	if len(current) == len(desired) {
+		same := true
+		for i, k := range current {
+			if k.APIKey != desired[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return nil
+		}
+	}
+
+	strategy := model.KeyStrategySequential
+	if len(current) > 0 && current[0].KeyStrategy != "" {
+		strategy = current[0].KeyStrategy
+	}
+	if err := s.store.DeleteAllAPIKeys(ctx, channelID); err != nil {
+		return fmt.Errorf("replace channel keys: %w", err)
+	}
+	now := time.Now()
+	keys := make([]*model.APIKey, 0, len(desired))
+	for i, v := range desired {
+		keys = append(keys, &model.APIKey{
+			ChannelID:   channelID,
+			KeyIndex:    i,
+			APIKey:      v,
+			KeyStrategy: strategy,
+			CreatedAt:   model.JSONTime{Time: now},
+			UpdatedAt:   model.JSONTime{Time: now},
+		})
+	}
+	if len(keys) > 0 {
+		if err := s.store.CreateAPIKeysBatch(ctx, keys); err != nil {
+			return fmt.Errorf("replace channel keys: %w", err)
+		}
+	}
+	s.invalidateChannelRelatedCache(channelID)
+	s.refreshKnownSecrets()
+	return nil
+}
+
+// geminiProvisionLoop 定期运行到期的供给配置
+func (s *Server) geminiProvisionLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(geminiProvisionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			s.runDueGeminiProvisioners()
+		}
+	}
+}
+
+func (s *Server) runDueGeminiProvisioners() {
+	ctx, cancel := context.WithTimeout(context.Background(), geminiProvisionRunTimeout)
+	defer cancel()
+
+	provisioners, err := s.store.ListGeminiKeyProvisioners(ctx)
+	if err != nil {
+		log.Printf("[WARN] [Gemini供给] 加载配置失败: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, p := range provisioners {
+		if !p.Enabled || now.Sub(time.Unix(p.LastRunAt, 0)) < geminiProvisionRunInterval {
+			continue
+		}
+		if s.isShuttingDown.Load() {
+			return
+		}
+		s.runGeminiProvisioner(ctx, p)
+	}
+}
+
+// geminiProvisionerView 补充展示字段（服务账号邮箱），凭据本身不返回
+func geminiProvisionerView(p *model.GeminiKeyProvisioner) *model.GeminiKeyProvisioner {
+	if sa, err := util.ParseGCPServiceAccount(p.Credentials); err == nil {
+		p.ClientEmail = sa.ClientEmail
+	}
+	return p
+}
+
+// HandleListGeminiProvisioners Gemini Key供给配置列表
+// GET /admin/gemini-provisioners
+func (s *Server) HandleListGeminiProvisioners(c *gin.Context) {
+	list, err := s.store.ListGeminiKeyProvisioners(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	for _, p := range list {
+		geminiProvisionerView(p)
+	}
+	RespondJSON(c, http.StatusOK, list)
+}
+
+// HandleCreateGeminiProvisioner 新增Gemini Key供给配置
+// POST /admin/gemini-provisioners
+func (s *Server) HandleCreateGeminiProvisioner(c *gin.Context) {
+	var req GeminiProvisionerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if req.ChannelID <= 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel_id is required")
+		return
+	}
+	cfg, err := s.store.GetConfig(c.Request.Context(), req.ChannelID)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel not found")
+		return
+	}
+	if util.NormalizeChannelType(cfg.GetChannelType()) != util.ChannelTypeGemini {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel must be of type gemini")
+		return
+	}
+
+	p := &model.GeminiKeyProvisioner{ChannelID: req.ChannelID, ManagedKeys: []model.GeminiManagedKey{}}
+	if !applyGeminiProvisionerRequest(c, p, &req) {
+		return
+	}
+	now := time.Now().Unix()
+	p.CreatedAt, p.UpdatedAt = now, now
+	if err := s.store.CreateGeminiKeyProvisioner(c.Request.Context(), p); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "unique") || strings.Contains(err.Error(), "Duplicate") {
+			RespondErrorMsg(c, http.StatusConflict, "channel already has a provisioner")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusCreated, geminiProvisionerView(p))
+}
+
+// HandleUpdateGeminiProvisioner 更新Gemini Key供给配置
+// PUT /admin/gemini-provisioners/:id
+func (s *Server) HandleUpdateGeminiProvisioner(c *gin.Context) {
+	p, ok := s.loadGeminiProvisioner(c)
+	if !ok {
+		return
+	}
+	var req GeminiProvisionerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if !applyGeminiProvisionerRequest(c, p, &req) {
+		return
+	}
+	p.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateGeminiKeyProvisioner(c.Request.Context(), p)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "provisioner not found")
+		return
+	}
+	RespondJSON(c, http.StatusOK, geminiProvisionerView(p))
+}
+
+// HandleDeleteGeminiProvisioner 删除Gemini Key供给配置（Google项目与渠道中的Key保留不动）
+// DELETE /admin/gemini-provisioners/:id
+func (s *Server) HandleDeleteGeminiProvisioner(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid provisioner id")
+		return
+	}
+	found, err := s.store.DeleteGeminiKeyProvisioner(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "provisioner not found")
+		return
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"id": id, "deleted": true})
+}
+
+// HandleRunGeminiProvisioner 立即执行一次供给
+// POST /admin/gemini-provisioners/:id/run
+func (s *Server) HandleRunGeminiProvisioner(c *gin.Context) {
+	p, ok := s.loadGeminiProvisioner(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), geminiProvisionRunTimeout)
+	defer cancel()
+	RespondJSON(c, http.StatusOK, s.runGeminiProvisioner(ctx, p))
+}
+
+// HandleListGeminiRemoteKeys 列出服务账号项目中的全部API Key（含非托管Key）
+// GET /admin/gemini-provisioners/:id/remote-keys
+func (s *Server) HandleListGeminiRemoteKeys(c *gin.Context) {
+	p, ok := s.loadGeminiProvisioner(c)
+	if !ok {
+		return
+	}
+	sa, err := util.ParseGCPServiceAccount(p.Credentials)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	keys, err := newGCPAPIKeysClient(sa).ListKeys(c.Request.Context(), p.ProjectID)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	managed := make(map[string]struct{}, len(p.ManagedKeys))
+	for _, mk := range p.ManagedKeys {
+		managed[mk.Name] = struct{}{}
+	}
+	type remoteKey struct {
+		util.GCPAPIKey
+		Managed bool `json:"managed"`
+	}
+	out := make([]remoteKey, 0, len(keys))
+	for _, k := range keys {
+		_, isManaged := managed[k.Name]
+		out = append(out, remoteKey{GCPAPIKey: k, Managed: isManaged})
+	}
+	RespondJSON(c, http.StatusOK, out)
+}
+
+func (s *Server) loadGeminiProvisioner(c *gin.Context) (*model.GeminiKeyProvisioner, bool) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid provisioner id")
+		return nil, false
+	}
+	p, err := s.store.GetGeminiKeyProvisioner(c.Request.Context(), id)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "provisioner not found")
+		return nil, false
+	}
+	return p, true
+}
+
+// applyGeminiProvisionerRequest 校验并写入请求字段（凭据为空表示保持不变）
+func applyGeminiProvisionerRequest(c *gin.Context, p *model.GeminiKeyProvisioner, req *GeminiProvisionerRequest) bool {
+	if creds := strings.TrimSpace(req.Credentials); creds != "" {
+		p.Credentials = creds
+	}
+	if p.Credentials == "" {
+		RespondErrorMsg(c, http.StatusBadRequest, "credentials is required")
+		return false
+	}
+	sa, err := util.ParseGCPServiceAccount(p.Credentials)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	p.ProjectID = strings.TrimSpace(req.ProjectID)
+	if p.ProjectID == "" {
+		p.ProjectID = sa.ProjectID
+	}
+	if p.ProjectID == "" {
+		RespondErrorMsg(c, http.StatusBadRequest, "project_id is required")
+		return false
+	}
+
+	p.KeyCount = 1
+	if req.KeyCount != nil {
+		p.KeyCount = *req.KeyCount
+	}
+	if p.KeyCount < 1 || p.KeyCount > geminiProvisionMaxKeys {
+		RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("key_count must be between 1 and %d", geminiProvisionMaxKeys))
+		return false
+	}
+	if req.RotateIntervalHours < 0 {
+		RespondErrorMsg(c, http.StatusBadRequest, "rotate_interval_hours must be >= 0")
+		return false
+	}
+	p.RotateIntervalHours = req.RotateIntervalHours
+
+	p.Enabled = true
+	if req.Enabled != nil {
+		p.Enabled = *req.Enabled
+	}
+	return true
+}
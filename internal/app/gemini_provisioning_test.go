@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeGCPKeys 模拟 Google API Keys 服务（创建操作立即完成）
+type fakeGCPKeys struct {
+	mu   sync.Mutex
+	keys map[string]string // name -> keyString
+}
+
+func newFakeGCPKeys(t *testing.T) (*fakeGCPKeys, string) {
+	t.Helper()
+	f := &fakeGCPKeys{keys: make(map[string]string)}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/v2/")
+		switch {
+		case r.Method == http.MethodPost:
+			name := path + "/" + r.URL.Query().Get("keyId")
+			f.keys[name] = "AIza-" + r.URL.Query().Get("keyId")
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "operations/x", "done": true, "response": map[string]string{"name": name}})
+		case r.Method == http.MethodGet && strings.HasSuffix(path, "/keyString"):
+			_ = json.NewEncoder(w).Encode(map[string]string{"keyString": f.keys[strings.TrimSuffix(path, "/keyString")]})
+		case r.Method == http.MethodGet:
+			out := make([]util.GCPAPIKey, 0, len(f.keys))
+			for name := range f.keys {
+				out = append(out, util.GCPAPIKey{Name: name})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": out})
+		case r.Method == http.MethodDelete:
+			delete(f.keys, path)
+			_, _ = w.Write([]byte(`{"done":true}`))
+		}
+	})
+
+	orig := newGCPAPIKeysClient
+	newGCPAPIKeysClient = func(sa *util.GCPServiceAccount) *util.GCPAPIKeysClient {
+		return util.NewGCPAPIKeysClient(sa, srv.Client(), srv.URL+"/v2")
+	}
+	t.Cleanup(func() { newGCPAPIKeysClient = orig })
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	creds, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "proj-1",
+		"client_email": "sa@proj-1.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    srv.URL + "/token",
+	})
+	return f, string(creds)
+}
+
+func (f *fakeGCPKeys) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.keys)
+}
+
+func TestRunGeminiProvisioner_ProvisionAndRotate(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+	fake, creds := newFakeGCPKeys(t)
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:         "gemini-auto",
+		URL:          "https://generativelanguage.googleapis.com",
+		ChannelType:  "gemini",
+		Enabled:      true,
+		ModelEntries: []model.ModelEntry{{Model: "gemini-2.5-pro"}},
+	})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{
+		ChannelID: cfg.ID, KeyIndex: 0, APIKey: "manual-key", KeyStrategy: model.KeyStrategySequential,
+	}}); err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	p := &model.GeminiKeyProvisioner{
+		ChannelID: cfg.ID, ProjectID: "proj-1", Credentials: creds,
+		KeyCount: 2, RotateIntervalHours: 24, Enabled: true,
+	}
+	if err := store.CreateGeminiKeyProvisioner(ctx, p); err != nil {
+		t.Fatalf("create provisioner: %v", err)
+	}
+
+	// 首次运行：创建2个托管Key，保留手工Key
+	res := server.runGeminiProvisioner(ctx, p)
+	if len(res.Errors) != 0 || res.Created != 2 || res.ChannelKeys != 3 {
+		t.Fatalf("unexpected first run: %+v", res)
+	}
+	keys, _ := store.GetAPIKeys(ctx, cfg.ID)
+	if len(keys) != 3 || keys[0].APIKey != "manual-key" || !strings.HasPrefix(keys[1].APIKey, "AIza-") {
+		t.Fatalf("unexpected channel keys: %v", keys)
+	}
+
+	// 再次运行：无变化
+	res = server.runGeminiProvisioner(ctx, p)
+	if res.Created != 0 || res.Retired != 0 || res.Managed != 2 {
+		t.Fatalf("second run should be a no-op: %+v", res)
+	}
+
+	// 托管Key过期：轮换为新Key，并删除Google侧旧Key
+	saved, err := store.GetGeminiKeyProvisioner(ctx, p.ID)
+	if err != nil || len(saved.ManagedKeys) != 2 {
+		t.Fatalf("managed keys not saved: %+v %v", saved, err)
+	}
+	oldHash := saved.ManagedKeys[0].KeyHash
+	for i := range saved.ManagedKeys {
+		saved.ManagedKeys[i].CreatedAt = time.Now().Add(-48 * time.Hour).Unix()
+	}
+	res = server.runGeminiProvisioner(ctx, saved)
+	if len(res.Errors) != 0 || res.Created != 2 || res.Retired != 2 {
+		t.Fatalf("unexpected rotation: %+v", res)
+	}
+	if fake.count() != 2 {
+		t.Fatalf("retired keys should be deleted remotely, %d remain", fake.count())
+	}
+	keys, _ = store.GetAPIKeys(ctx, cfg.ID)
+	if len(keys) != 3 || keys[0].APIKey != "manual-key" {
+		t.Fatalf("unexpected channel keys after rotation: %v", keys)
+	}
+	for _, k := range keys {
+		if model.HashToken(k.APIKey) == oldHash {
+			t.Fatalf("retired key still in channel")
+		}
+	}
+}
+
+func TestApplyGeminiProvisionerRequest_Validation(t *testing.T) {
+	_, creds := newFakeGCPKeys(t)
+	for _, req := range []GeminiProvisionerRequest{
+		{Credentials: "{}"},
+		{Credentials: creds, KeyCount: new(int)},
+		{Credentials: creds, RotateIntervalHours: -1},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		if applyGeminiProvisionerRequest(c, &model.GeminiKeyProvisioner{}, &req) || w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %+v", req)
+		}
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	p := &model.GeminiKeyProvisioner{}
+	if !applyGeminiProvisionerRequest(c, p, &GeminiProvisionerRequest{Credentials: creds}) {
+		t.Fatal("valid request rejected")
+	}
+	if p.ProjectID != "proj-1" || p.KeyCount != 1 || !p.Enabled {
+		t.Fatalf("defaults not applied: %+v", p)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"ccLoad/internal/model"
+	"ccLoad/internal/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -169,7 +170,7 @@ func RespondError(c *gin.Context, code int, err error) {
 
 	c.JSON(code, APIResponse[any]{
 		Success: false,
-		Error:   errMsg,
+		Error:   util.RedactSecrets(errMsg),
 	})
 }
 
@@ -177,7 +178,7 @@ func RespondError(c *gin.Context, code int, err error) {
 func RespondErrorMsg(c *gin.Context, code int, message string) {
 	c.JSON(code, APIResponse[any]{
 		Success: false,
-		Error:   message,
+		Error:   util.RedactSecrets(message),
 	})
 }
 
@@ -186,7 +187,7 @@ func RespondErrorMsg(c *gin.Context, code int, message string) {
 func RespondErrorWithData[T any](c *gin.Context, code int, message string, data T) {
 	c.JSON(code, APIResponse[T]{
 		Success: false,
-		Error:   message,
+		Error:   util.RedactSecrets(message),
 		Data:    data,
 	})
 }
@@ -266,5 +267,20 @@ func BuildLogFilter(c *gin.Context) model.LogFilter {
 		}
 	}
 
+	// 令牌归属方过滤（2026-10新增）
+	if owner := strings.TrimSpace(c.Query("owner")); owner != "" {
+		lf.Owner = owner
+	}
+
+	// 错误归类过滤（2026-10新增，未知类别忽略）
+	if ec := strings.TrimSpace(c.Query("error_class")); util.IsErrorClass(ec) {
+		lf.ErrorClass = ec
+	}
+
+	// 响应内容标签过滤（2026-10新增，非法标签名忽略）
+	if label := strings.TrimSpace(c.Query("label")); len(label) <= maxResponseLabelLen && responseLabelNamePattern.MatchString(label) {
+		lf.Label = label
+	}
+
 	return lf
 }
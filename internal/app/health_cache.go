@@ -20,6 +20,11 @@ type HealthCache struct {
 	// 读取时直接Load，更新时用新map整体替换，避免遍历删除的并发问题
 	healthStats atomic.Pointer[map[int64]model.ChannelHealthStats]
 
+	// 最近一次更新状态（健康检查用，2026-10新增）
+	statusMu   sync.Mutex
+	lastUpdate time.Time
+	updateErr  error
+
 	// 控制
 	stopCh chan struct{}
 	wg     *sync.WaitGroup
@@ -88,6 +93,12 @@ func (h *HealthCache) update() {
 
 	since := time.Now().Add(-time.Duration(h.config.WindowMinutes) * time.Minute)
 	stats, err := h.store.GetChannelSuccessRates(ctx, since)
+	h.statusMu.Lock()
+	h.updateErr = err
+	if err == nil {
+		h.lastUpdate = time.Now()
+	}
+	h.statusMu.Unlock()
 	if err != nil {
 		log.Printf("[WARN] 更新渠道成功率缓存失败: %v", err)
 		return
@@ -127,6 +138,13 @@ func (h *HealthCache) GetAllSuccessRates() map[int64]float64 {
 	return result
 }
 
+// UpdateStatus 最近一次成功更新的时间与最近一次更新的错误（nil 表示成功）
+func (h *HealthCache) UpdateStatus() (lastUpdate time.Time, err error) {
+	h.statusMu.Lock()
+	defer h.statusMu.Unlock()
+	return h.lastUpdate, h.updateErr
+}
+
 // Config 返回健康度配置
 func (h *HealthCache) Config() model.HealthScoreConfig {
 	return h.config
@@ -0,0 +1,373 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// JSON模式输出修复（2026-10新增）
+// ============================================================================
+// 客户端要求结构化JSON输出（response_format / text.format / responseMimeType / output_format）时，
+// 部分上游仍会输出 ```json 代码块或在JSON前后夹带说明文字。json_repair_enabled 开启后：
+// - 非流式：剥离代码块、提取第一个合法JSON并按客户端Schema校验，改写响应中的文本内容；
+//   无法修复时返回 422 结构化错误（上游已消耗的Token照常计费，不触发冷却）
+// - 流式：内容已实时发给客户端无法改写，仅在流结束后校验累计文本，
+//   无法修复时在流尾追加一个 error 事件
+
+const (
+	// maxJSONRepairBodySize 非流式修复时读取响应体的上限，超出则原样透传
+	maxJSONRepairBodySize = 8 << 20 // 8MB
+	// maxJSONRepairStreamSize 流式尾部校验时缓存的上限，超出则跳过校验
+	maxJSONRepairStreamSize = 4 << 20 // 4MB
+
+	jsonRepairErrorType = "invalid_json_output"
+)
+
+// jsonModeSpec 客户端请求的JSON输出要求
+type jsonModeSpec struct {
+	schema map[string]any // nil 表示只要求合法JSON（json_object 模式）
+}
+
+// detectJSONMode 从请求体识别JSON输出模式；未要求JSON输出时返回 nil
+func detectJSONMode(body []byte) *jsonModeSpec {
+	var req struct {
+		// OpenAI Chat Completions
+		ResponseFormat *struct {
+			Type       string `json:"type"`
+			JSONSchema *struct {
+				Schema map[string]any `json:"schema"`
+			} `json:"json_schema"`
+		} `json:"response_format"`
+		// OpenAI Responses
+		Text *struct {
+			Format *struct {
+				Type   string         `json:"type"`
+				Schema map[string]any `json:"schema"`
+			} `json:"format"`
+		} `json:"text"`
+		// Anthropic 结构化输出
+		OutputFormat *struct {
+			Type   string         `json:"type"`
+			Schema map[string]any `json:"schema"`
+		} `json:"output_format"`
+		// Gemini
+		GenerationConfig *struct {
+			ResponseMimeType   string         `json:"responseMimeType"`
+			ResponseSchema     map[string]any `json:"responseSchema"`
+			ResponseJSONSchema map[string]any `json:"responseJsonSchema"`
+		} `json:"generationConfig"`
+	}
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil
+	}
+
+	if rf := req.ResponseFormat; rf != nil {
+		switch rf.Type {
+		case "json_object":
+			return &jsonModeSpec{}
+		case "json_schema":
+			spec := &jsonModeSpec{}
+			if rf.JSONSchema != nil {
+				spec.schema = rf.JSONSchema.Schema
+			}
+			return spec
+		}
+	}
+	if req.Text != nil && req.Text.Format != nil {
+		switch req.Text.Format.Type {
+		case "json_object":
+			return &jsonModeSpec{}
+		case "json_schema":
+			return &jsonModeSpec{schema: req.Text.Format.Schema}
+		}
+	}
+	if of := req.OutputFormat; of != nil && of.Type == "json_schema" {
+		return &jsonModeSpec{schema: of.Schema}
+	}
+	if gc := req.GenerationConfig; gc != nil && strings.EqualFold(gc.ResponseMimeType, "application/json") {
+		if gc.ResponseJSONSchema != nil {
+			return &jsonModeSpec{schema: gc.ResponseJSONSchema}
+		}
+		return &jsonModeSpec{schema: gc.ResponseSchema}
+	}
+	return nil
+}
+
+// rewriteResponseTexts 对非流式响应中的每段模型文本调用 fn，返回改写后的响应体
+// 覆盖：OpenAI Chat（choices[].message.content）、Anthropic（content[].text）、
+// Responses（output[].content[].output_text）、Gemini（candidates[].content.parts[].text，跳过思考部分）
+// 响应中没有文本（如纯工具调用）时原样返回
+func rewriteResponseTexts(body []byte, fn func(string) (string, error)) ([]byte, bool, error) {
+	var resp map[string]any
+	if err := sonic.Unmarshal(body, &resp); err != nil || resp == nil {
+		return body, false, nil
+	}
+
+	changed := false
+	var firstErr error
+	apply := func(m map[string]any, field string) {
+		text, ok := m[field].(string)
+		if !ok || firstErr != nil {
+			return
+		}
+		out, err := fn(text)
+		if err != nil {
+			firstErr = err
+			return
+		}
+		if out != text {
+			m[field] = out
+			changed = true
+		}
+	}
+	eachObject := func(v any, visit func(map[string]any)) {
+		list, _ := v.([]any)
+		for _, item := range list {
+			if m, ok := item.(map[string]any); ok {
+				visit(m)
+			}
+		}
+	}
+
+	eachObject(resp["choices"], func(choice map[string]any) {
+		if msg, ok := choice["message"].(map[string]any); ok {
+			apply(msg, "content")
+		}
+	})
+	eachObject(resp["content"], func(block map[string]any) {
+		if block["type"] == "text" {
+			apply(block, "text")
+		}
+	})
+	eachObject(resp["output"], func(item map[string]any) {
+		eachObject(item["content"], func(part map[string]any) {
+			if part["type"] == "output_text" {
+				apply(part, "text")
+			}
+		})
+	})
+	eachObject(resp["candidates"], func(cand map[string]any) {
+		content, _ := cand["content"].(map[string]any)
+		eachObject(content["parts"], func(part map[string]any) {
+			if thought, _ := part["thought"].(bool); !thought {
+				apply(part, "text")
+			}
+		})
+	})
+
+	if firstErr != nil {
+		return body, false, firstErr
+	}
+	if !changed {
+		return body, false, nil
+	}
+	out, err := sonic.Marshal(resp)
+	if err != nil {
+		return body, false, err
+	}
+	return out, true, nil
+}
+
+// buildJSONRepairError 构造无法修复时返回给客户端的结构化错误
+func buildJSONRepairError(err error) []byte {
+	body, _ := sonic.Marshal(map[string]any{
+		"error": map[string]any{
+			"type":    jsonRepairErrorType,
+			"message": err.Error(),
+		},
+	})
+	return body
+}
+
+// enforceJSONModeResponse 非流式JSON模式响应的修复与校验
+// 返回 nil 表示不处理（调用方按原流程转发）；否则响应已写出
+func (s *Server) enforceJSONModeResponse(
+	reqCtx *requestContext,
+	resp *http.Response,
+	hdrClone http.Header,
+	w http.ResponseWriter,
+	channelType string,
+	readStats *streamReadStats,
+	firstBodyReadTimeSec *float64,
+) (*fwResult, float64, error) {
+	if !strings.Contains(resp.Header.Get("Content-Type"), "application/json") || resp.Header.Get("Content-Encoding") != "" {
+		return nil, 0, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJSONRepairBodySize+1))
+	if err != nil || len(body) > maxJSONRepairBodySize {
+		// 读取失败或超限：恢复已读部分交由原流程处理（读取错误会在原流程中暴露）
+		prependToBody(resp, body)
+		return nil, 0, nil
+	}
+
+	repaired, changed, repairErr := rewriteResponseTexts(body, func(text string) (string, error) {
+		out, _, err := util.RepairJSONOutput(text, reqCtx.jsonMode.schema)
+		return out, err
+	})
+
+	if repairErr == nil {
+		prependToBody(resp, repaired)
+		res, duration, err := s.handleSuccessResponse(reqCtx, resp, hdrClone, w, channelType, readStats, firstBodyReadTimeSec)
+		if res != nil && changed {
+			res.JSONRepairMsg = "json_mode: output repaired"
+		}
+		return res, duration, err
+	}
+
+	if !errors.Is(repairErr, util.ErrJSONOutputInvalid) {
+		// 改写失败（非输出问题）：原样转发
+		prependToBody(resp, body)
+		return s.handleSuccessResponse(reqCtx, resp, hdrClone, w, channelType, readStats, firstBodyReadTimeSec)
+	}
+
+	// 无法修复：返回结构化错误，usage 仍从原始响应提取用于计费
+	parser := newJSONUsageParser(channelType)
+	_ = parser.Feed(body)
+
+	errBody := buildJSONRepairError(repairErr)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_, _ = w.Write(errBody)
+
+	res := &fwResult{
+		Status:             resp.StatusCode,
+		Header:             hdrClone,
+		FirstByteTime:      *firstBodyReadTimeSec,
+		Cache5mInputTokens: parser.Cache5mInputTokens,
+		Cache1hInputTokens: parser.Cache1hInputTokens,
+		JSONRepairMsg:      fmt.Sprintf("json_mode: irreparable output: %s", repairErr.Error()),
+	}
+	res.InputTokens, res.OutputTokens, res.CacheReadInputTokens, res.CacheCreationInputTokens = parser.GetUsage()
+	log.Printf("[WARN] [JSON模式] 模型输出无法修复为合法JSON，已返回%d: %v", http.StatusUnprocessableEntity, repairErr)
+	return res, reqCtx.Duration().Seconds(), nil
+}
+
+// jsonModeStreamTap 流式JSON模式：在转发的同时缓存上游字节，流结束后校验累计文本
+type jsonModeStreamTap struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (t *jsonModeStreamTap) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 && !t.overflow {
+		if t.buf.Len()+n > maxJSONRepairStreamSize {
+			t.overflow = true
+			t.buf.Reset()
+		} else {
+			t.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+// streamedText 按渠道类型拼接SSE流中的模型文本增量
+func streamedText(channelType string, stream []byte) string {
+	var sb strings.Builder
+	for _, ev := range parseSSEEvents(stream) {
+		if ev.data == "" || ev.data == "[DONE]" {
+			continue
+		}
+		switch util.NormalizeChannelType(channelType) {
+		case util.ChannelTypeOpenAI:
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content string `json:"content"`
+					} `json:"delta"`
+				} `json:"choices"`
+			}
+			if sonic.UnmarshalString(ev.data, &chunk) == nil && len(chunk.Choices) > 0 {
+				sb.WriteString(chunk.Choices[0].Delta.Content)
+			}
+		case util.ChannelTypeCodex:
+			var chunk struct {
+				Type  string `json:"type"`
+				Delta string `json:"delta"`
+			}
+			if sonic.UnmarshalString(ev.data, &chunk) == nil && chunk.Type == "response.output_text.delta" {
+				sb.WriteString(chunk.Delta)
+			}
+		case util.ChannelTypeGemini:
+			var chunk struct {
+				Candidates []struct {
+					Content struct {
+						Parts []struct {
+							Text    string `json:"text"`
+							Thought bool   `json:"thought"`
+						} `json:"parts"`
+					} `json:"content"`
+				} `json:"candidates"`
+			}
+			if sonic.UnmarshalString(ev.data, &chunk) == nil && len(chunk.Candidates) > 0 {
+				for _, part := range chunk.Candidates[0].Content.Parts {
+					if !part.Thought {
+						sb.WriteString(part.Text)
+					}
+				}
+			}
+		default:
+			var chunk struct {
+				Type  string `json:"type"`
+				Delta struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"delta"`
+			}
+			if sonic.UnmarshalString(ev.data, &chunk) == nil && chunk.Type == "content_block_delta" && chunk.Delta.Type == "text_delta" {
+				sb.WriteString(chunk.Delta.Text)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// jsonRepairStreamErrorEvent 流尾追加的 error 事件（Anthropic 带 event: 行，其余只有 data:）
+func jsonRepairStreamErrorEvent(channelType string, err error) []byte {
+	payload := buildJSONRepairError(err)
+	if util.NormalizeChannelType(channelType) == util.ChannelTypeAnthropic {
+		payload, _ = sonic.Marshal(map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": jsonRepairErrorType, "message": err.Error()},
+		})
+		return []byte("event: error\ndata: " + string(payload) + "\n\n")
+	}
+	return []byte("data: " + string(payload) + "\n\n")
+}
+
+// checkJSONModeStreamTail 流结束后校验累计文本，无法修复时在流尾追加 error 事件
+// 返回写入日志的诊断信息（空表示通过或未校验）
+func checkJSONModeStreamTail(w http.ResponseWriter, tap *jsonModeStreamTap, spec *jsonModeSpec, channelType string) string {
+	if tap.overflow {
+		return ""
+	}
+	text := streamedText(channelType, tap.buf.Bytes())
+	if strings.TrimSpace(text) == "" {
+		return "" // 纯工具调用等无文本输出
+	}
+	_, changed, err := util.RepairJSONOutput(text, spec.schema)
+	if err == nil {
+		if changed {
+			return "json_mode: streamed output contains extra text around JSON"
+		}
+		return ""
+	}
+
+	if _, werr := w.Write(jsonRepairStreamErrorEvent(channelType, err)); werr == nil {
+		_ = http.NewResponseController(w).Flush()
+	}
+	log.Printf("[WARN] [JSON模式] 流式输出无法修复为合法JSON，已在流尾追加error事件: %v", err)
+	return fmt.Sprintf("json_mode: irreparable streamed output: %s", err.Error())
+}
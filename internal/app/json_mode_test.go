@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+func TestDetectJSONMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		want       bool
+		wantSchema bool
+	}{
+		{"plain request", `{"model":"gpt-4o","messages":[]}`, false, false},
+		{"text response_format", `{"response_format":{"type":"text"}}`, false, false},
+		{"json_object", `{"response_format":{"type":"json_object"}}`, true, false},
+		{"json_schema", `{"response_format":{"type":"json_schema","json_schema":{"name":"x","schema":{"type":"object"}}}}`, true, true},
+		{"responses text.format", `{"text":{"format":{"type":"json_schema","schema":{"type":"object"}}}}`, true, true},
+		{"anthropic output_format", `{"output_format":{"type":"json_schema","schema":{"type":"object"}}}`, true, true},
+		{"gemini mime only", `{"generationConfig":{"responseMimeType":"application/json"}}`, true, false},
+		{"gemini schema", `{"generationConfig":{"responseMimeType":"application/json","responseSchema":{"type":"OBJECT"}}}`, true, true},
+		{"invalid json", `not json`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := detectJSONMode([]byte(tt.body))
+			if (spec != nil) != tt.want {
+				t.Fatalf("spec=%v, want detected=%v", spec, tt.want)
+			}
+			if spec != nil && (spec.schema != nil) != tt.wantSchema {
+				t.Fatalf("schema=%v, want schema=%v", spec.schema, tt.wantSchema)
+			}
+		})
+	}
+}
+
+func runJSONModeResponse(t *testing.T, spec *jsonModeSpec, streaming bool, channelType, contentType, body string) (*fwResult, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     http.Header{"Content-Type": []string{contentType}},
+	}
+	reqCtx := &requestContext{
+		ctx:         context.Background(),
+		startTime:   time.Now(),
+		isStreaming: streaming,
+		jsonMode:    spec,
+	}
+	rec := httptest.NewRecorder()
+	res, _, err := (&Server{}).handleResponse(reqCtx, resp, rec, channelType, &model.Config{ID: 1}, "sk-test", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return res, rec
+}
+
+func TestJSONMode_NonStreamRepairsFencedOutput(t *testing.T) {
+	body := `{"choices":[{"message":{"role":"assistant","content":"Sure!\n` + "```json" + `\n{\"a\":1}\n` + "```" + `"}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`
+
+	res, rec := runJSONModeResponse(t, &jsonModeSpec{}, false, "openai", "application/json", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"content":"{\"a\":1}"`) {
+		t.Fatalf("content not repaired: %s", rec.Body.String())
+	}
+	if res.InputTokens != 10 || res.OutputTokens != 5 {
+		t.Fatalf("usage lost: in=%d out=%d", res.InputTokens, res.OutputTokens)
+	}
+	if res.JSONRepairMsg == "" {
+		t.Fatal("expected repair message for log")
+	}
+}
+
+func TestJSONMode_NonStreamValidOutputUntouched(t *testing.T) {
+	body := `{"content":[{"type":"text","text":"{\"a\":1}"}],"usage":{"input_tokens":3,"output_tokens":2}}`
+
+	res, rec := runJSONModeResponse(t, &jsonModeSpec{}, false, "anthropic", "application/json", body)
+	if rec.Body.String() != body {
+		t.Fatalf("body should be forwarded as-is, got %s", rec.Body.String())
+	}
+	if res.JSONRepairMsg != "" {
+		t.Fatalf("unexpected repair message: %s", res.JSONRepairMsg)
+	}
+}
+
+func TestJSONMode_NonStreamIrreparableReturnsStructuredError(t *testing.T) {
+	spec := &jsonModeSpec{schema: map[string]any{"type": "object", "required": []any{"name"}}}
+	body := `{"candidates":[{"content":{"parts":[{"text":"{\"other\":1}"}]}}],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":4}}`
+
+	res, rec := runJSONModeResponse(t, spec, false, "gemini", "application/json", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), jsonRepairErrorType) || !strings.Contains(rec.Body.String(), "name") {
+		t.Fatalf("unexpected error body: %s", rec.Body.String())
+	}
+	// 上游已成功生成：保留成功状态与usage用于计费
+	if res.Status != http.StatusOK || res.InputTokens != 7 || res.OutputTokens != 4 {
+		t.Fatalf("unexpected result: status=%d in=%d out=%d", res.Status, res.InputTokens, res.OutputTokens)
+	}
+}
+
+func TestJSONMode_StreamTailAppendsErrorEvent(t *testing.T) {
+	body := "data: {\"choices\":[{\"delta\":{\"content\":\"not \"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"json\"},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":1,\"completion_tokens\":2}}\n\n" +
+		"data: [DONE]\n\n"
+
+	res, rec := runJSONModeResponse(t, &jsonModeSpec{}, true, "openai", "text/event-stream", body)
+	out := rec.Body.String()
+	if !strings.HasPrefix(out, body) {
+		t.Fatalf("original stream must be forwarded unchanged, got %q", out)
+	}
+	if !strings.Contains(out[len(body):], jsonRepairErrorType) {
+		t.Fatalf("expected trailing error event, got %q", out[len(body):])
+	}
+	if res.JSONRepairMsg == "" {
+		t.Fatal("expected repair message for log")
+	}
+}
+
+func TestJSONMode_StreamValidOutputNoTail(t *testing.T) {
+	body := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":1}}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"{\\\"a\\\":\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"1}\"}}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":3}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	res, rec := runJSONModeResponse(t, &jsonModeSpec{}, true, "anthropic", "text/event-stream", body)
+	if rec.Body.String() != body {
+		t.Fatalf("stream should be unchanged, got %q", rec.Body.String())
+	}
+	if res.JSONRepairMsg != "" {
+		t.Fatalf("unexpected repair message: %s", res.JSONRepairMsg)
+	}
+}
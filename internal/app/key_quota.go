@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// ============================================================================
+// Key级上游配额跟踪（2026-10新增）
+// ============================================================================
+// 上游在响应头中报告每个Key的剩余配额（Anthropic: anthropic-ratelimit-*，
+// OpenAI: x-ratelimit-*）。记录到 api_keys 表后：
+// - KeySelector 跳过已报告耗尽（剩余=0且未到重置时间）的Key，不必等上游返回429
+// - GET /admin/channels/:id/keys 直接返回配额快照及 quota_updated_at 新鲜度
+// 写库按Key节流（耗尽状态变化时立即写入），避免每个请求都产生一次UPDATE。
+
+// keyQuotaPersistInterval 同一Key配额快照的最小写库间隔
+const keyQuotaPersistInterval = 30 * time.Second
+
+// quotaHeaderSet 一组配额响应头名称
+type quotaHeaderSet struct {
+	requestsRemaining string
+	requestsReset     string
+	tokensRemaining   string
+	tokensReset       string
+}
+
+// quotaHeaderSets 支持的上游配额响应头（按顺序匹配，命中即停止）
+var quotaHeaderSets = []quotaHeaderSet{
+	{ // Anthropic：重置时间为RFC3339
+		requestsRemaining: "anthropic-ratelimit-requests-remaining",
+		requestsReset:     "anthropic-ratelimit-requests-reset",
+		tokensRemaining:   "anthropic-ratelimit-tokens-remaining",
+		tokensReset:       "anthropic-ratelimit-tokens-reset",
+	},
+	{ // OpenAI：重置时间为相对时长（如 "6m0s"、"20ms"）
+		requestsRemaining: "x-ratelimit-remaining-requests",
+		requestsReset:     "x-ratelimit-reset-requests",
+		tokensRemaining:   "x-ratelimit-remaining-tokens",
+		tokensReset:       "x-ratelimit-reset-tokens",
+	},
+}
+
+// parseQuotaHeaders 从上游响应头解析Key配额快照
+// 返回 ok=false 表示响应头中没有任何配额信息
+func parseQuotaHeaders(h http.Header, now time.Time) (model.KeyQuota, bool) {
+	for _, set := range quotaHeaderSets {
+		reqRemaining, reqOK := parseQuotaRemaining(h.Get(set.requestsRemaining))
+		tokRemaining, tokOK := parseQuotaRemaining(h.Get(set.tokensRemaining))
+		if !reqOK && !tokOK {
+			continue
+		}
+		return model.KeyQuota{
+			QuotaRequestsRemaining: reqRemaining,
+			QuotaRequestsResetAt:   parseQuotaReset(h.Get(set.requestsReset), now),
+			QuotaTokensRemaining:   tokRemaining,
+			QuotaTokensResetAt:     parseQuotaReset(h.Get(set.tokensReset), now),
+			QuotaUpdatedAt:         now.Unix(),
+		}, true
+	}
+	return model.KeyQuota{}, false
+}
+
+// parseQuotaRemaining 解析剩余数量；缺失或非法返回 QuotaUnknown
+func parseQuotaRemaining(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return model.QuotaUnknown, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return model.QuotaUnknown, false
+	}
+	return n, true
+}
+
+// parseQuotaReset 解析重置时间为Unix秒（向上取整）
+// 支持：RFC3339时间戳、Go时长（"1m30s"/"20ms"）、纯数字秒数（相对时长）
+func parseQuotaReset(v string, now time.Time) int64 {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.Unix()
+	}
+	var d time.Duration
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		d = time.Duration(secs * float64(time.Second))
+	} else if parsed, err := time.ParseDuration(v); err == nil {
+		d = parsed
+	} else {
+		return 0
+	}
+	if d < 0 {
+		return 0
+	}
+	return int64(math.Ceil(float64(now.Add(d).UnixNano()) / float64(time.Second)))
+}
+
+// keyQuotaID 配额跟踪键
+type keyQuotaID struct {
+	channelID int64
+	keyIndex  int
+}
+
+// keyQuotaState 上次写库的状态
+type keyQuotaState struct {
+	persistedAt time.Time
+	exhausted   bool
+}
+
+// keyQuotaTracker 配额写库节流器
+type keyQuotaTracker struct {
+	mu    sync.Mutex
+	state map[keyQuotaID]keyQuotaState
+}
+
+func newKeyQuotaTracker() *keyQuotaTracker {
+	return &keyQuotaTracker{state: make(map[keyQuotaID]keyQuotaState)}
+}
+
+// shouldPersist 判断本次快照是否需要写库（首次、耗尽状态变化、或超过节流间隔）
+func (t *keyQuotaTracker) shouldPersist(id keyQuotaID, now time.Time, exhausted bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev, ok := t.state[id]
+	if ok && prev.exhausted == exhausted && now.Sub(prev.persistedAt) < keyQuotaPersistInterval {
+		return false
+	}
+	t.state[id] = keyQuotaState{persistedAt: now, exhausted: exhausted}
+	return true
+}
+
+// recordKeyQuota 记录上游响应头中的Key配额
+func (s *Server) recordKeyQuota(ctx context.Context, channelID int64, keyIndex int, h http.Header) {
+	if s.keyQuotas == nil || s.store == nil || h == nil {
+		return
+	}
+	now := time.Now()
+	quota, ok := parseQuotaHeaders(h, now)
+	if !ok {
+		return
+	}
+	exhausted := quota.IsQuotaExhausted(now)
+	if !s.keyQuotas.shouldPersist(keyQuotaID{channelID: channelID, keyIndex: keyIndex}, now, exhausted) {
+		return
+	}
+
+	// 请求可能已被客户端取消，写库不应随之失败
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+	if err := s.store.UpdateAPIKeyQuota(writeCtx, channelID, keyIndex, quota); err != nil {
+		log.Printf("[WARN] 保存Key配额失败: 渠道ID=%d, Key#%d, err=%v", channelID, keyIndex, err)
+		return
+	}
+	s.InvalidateAPIKeysCache(channelID)
+	s.adminEvents.publish(AdminEventKeyQuota, channelID, AdminKeyQuotaEvent{KeyIndex: keyIndex, Exhausted: exhausted, Quota: quota})
+
+	if exhausted {
+		log.Printf("[INFO] [Key配额耗尽] 渠道ID=%d, Key#%d, 上游报告剩余配额为0，%s 前跳过该Key",
+			channelID, keyIndex, time.Unix(quota.QuotaExhaustedUntil(now), 0).Format("2006-01-02 15:04:05"))
+	}
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/testutil"
+)
+
+func TestParseQuotaHeaders(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+
+	t.Run("anthropic", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("anthropic-ratelimit-requests-remaining", "0")
+		h.Set("anthropic-ratelimit-requests-reset", now.Add(90*time.Second).UTC().Format(time.RFC3339))
+		h.Set("anthropic-ratelimit-tokens-remaining", "12000")
+
+		q, ok := parseQuotaHeaders(h, now)
+		if !ok {
+			t.Fatal("expected quota headers to be parsed")
+		}
+		if q.QuotaRequestsRemaining != 0 || q.QuotaRequestsResetAt != now.Unix()+90 {
+			t.Fatalf("unexpected requests quota: %+v", q)
+		}
+		if q.QuotaTokensRemaining != 12000 || q.QuotaTokensResetAt != 0 {
+			t.Fatalf("unexpected tokens quota: %+v", q)
+		}
+		if q.QuotaUpdatedAt != now.Unix() {
+			t.Fatalf("QuotaUpdatedAt=%d, want %d", q.QuotaUpdatedAt, now.Unix())
+		}
+		if got := q.QuotaExhaustedUntil(now); got != now.Unix()+90 {
+			t.Fatalf("QuotaExhaustedUntil=%d, want %d", got, now.Unix()+90)
+		}
+	})
+
+	t.Run("openai相对时长", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("x-ratelimit-remaining-requests", "59")
+		h.Set("x-ratelimit-reset-requests", "1s")
+		h.Set("x-ratelimit-remaining-tokens", "0")
+		h.Set("x-ratelimit-reset-tokens", "6m0.5s")
+
+		q, ok := parseQuotaHeaders(h, now)
+		if !ok {
+			t.Fatal("expected quota headers to be parsed")
+		}
+		if q.QuotaRequestsRemaining != 59 || q.QuotaRequestsResetAt != now.Unix()+1 {
+			t.Fatalf("unexpected requests quota: %+v", q)
+		}
+		if q.QuotaTokensRemaining != 0 || q.QuotaTokensResetAt != now.Unix()+361 {
+			t.Fatalf("unexpected tokens quota: %+v", q)
+		}
+		if !q.IsQuotaExhausted(now) || q.IsQuotaExhausted(now.Add(400*time.Second)) {
+			t.Fatal("tokens quota should be exhausted until reset only")
+		}
+	})
+
+	t.Run("无配额头", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("Content-Type", "application/json")
+		if _, ok := parseQuotaHeaders(h, now); ok {
+			t.Fatal("expected no quota")
+		}
+	})
+
+	t.Run("剩余为0但无重置时间不视为耗尽", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("x-ratelimit-remaining-requests", "0")
+		q, _ := parseQuotaHeaders(h, now)
+		if q.IsQuotaExhausted(now) {
+			t.Fatal("quota without reset time must not block the key")
+		}
+		if q.QuotaTokensRemaining != model.QuotaUnknown {
+			t.Fatalf("missing tokens header should be unknown, got %d", q.QuotaTokensRemaining)
+		}
+	})
+}
+
+func TestRecordKeyQuota_SkipsExhaustedKey(t *testing.T) {
+	store, cleanup := testutil.SetupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:         "quota-channel",
+		URL:          "https://api.example.com",
+		Priority:     100,
+		ModelEntries: []model.ModelEntry{{Model: "m"}},
+		Enabled:      true,
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, KeyIndex: 0, APIKey: "sk-0", KeyStrategy: model.KeyStrategySequential},
+		{ChannelID: cfg.ID, KeyIndex: 1, APIKey: "sk-1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建API Key失败: %v", err)
+	}
+
+	// 新建Key的配额为未知
+	keys, err := store.GetAPIKeys(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("查询API Keys失败: %v", err)
+	}
+	if keys[0].QuotaRequestsRemaining != model.QuotaUnknown || keys[0].QuotaUpdatedAt != 0 {
+		t.Fatalf("new key should have unknown quota, got %+v", keys[0].KeyQuota)
+	}
+
+	srv := &Server{store: store, keyQuotas: newKeyQuotaTracker()}
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-requests-remaining", "0")
+	h.Set("anthropic-ratelimit-requests-reset", time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	srv.recordKeyQuota(ctx, cfg.ID, 0, h)
+
+	keys, err = store.GetAPIKeys(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("查询API Keys失败: %v", err)
+	}
+	if keys[0].QuotaRequestsRemaining != 0 || keys[0].QuotaUpdatedAt == 0 {
+		t.Fatalf("quota not persisted: %+v", keys[0].KeyQuota)
+	}
+
+	keyIndex, apiKey, err := NewKeySelector().SelectAvailableKey(cfg.ID, keys, nil)
+	if err != nil {
+		t.Fatalf("SelectAvailableKey失败: %v", err)
+	}
+	if keyIndex != 1 || apiKey != "sk-1" {
+		t.Fatalf("expected exhausted key 0 to be skipped, got key %d", keyIndex)
+	}
+
+	// 节流：状态未变化时不重复写库
+	h.Set("anthropic-ratelimit-requests-remaining", "5")
+	h.Set("anthropic-ratelimit-requests-reset", time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	srv.recordKeyQuota(ctx, cfg.ID, 1, h)
+	h.Set("anthropic-ratelimit-requests-remaining", "4")
+	srv.recordKeyQuota(ctx, cfg.ID, 1, h)
+	keys, _ = store.GetAPIKeys(ctx, cfg.ID)
+	if keys[1].QuotaRequestsRemaining != 5 {
+		t.Fatalf("expected throttled write to keep 5, got %d", keys[1].QuotaRequestsRemaining)
+	}
+}
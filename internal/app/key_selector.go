@@ -35,6 +35,7 @@ func NewKeySelector() *KeySelector {
 
 // SelectAvailableKey 返回 (keyIndex, apiKey, error)
 // 策略: sequential顺序尝试 | round_robin轮询选择
+// 跳过冷却中的Key，以及上游已报告配额耗尽的Key
 // excludeKeys: 避免同一请求内重复尝试
 // 移除store依赖，apiKeys由调用方传入，避免重复查询
 func (ks *KeySelector) SelectAvailableKey(channelID int64, apiKeys []*model.APIKey, excludeKeys map[int]bool) (int, string, error) {
@@ -56,6 +57,11 @@ func (ks *KeySelector) SelectAvailableKey(channelID int64, apiKeys []*model.APIK
 				keyIndex,
 				time.Unix(apiKeys[0].CooldownUntil, 0).Format("2006-01-02 15:04:05"))
 		}
+		if until := apiKeys[0].QuotaExhaustedUntil(time.Now()); until > 0 {
+			return -1, "", fmt.Errorf("single key (index=%d) quota exhausted until %s",
+				keyIndex,
+				time.Unix(until, 0).Format("2006-01-02 15:04:05"))
+		}
 		return keyIndex, apiKeys[0].APIKey, nil
 	}
 
@@ -85,7 +91,7 @@ func (ks *KeySelector) selectSequential(apiKeys []*model.APIKey, excludeKeys map
 			continue
 		}
 
-		if apiKey.IsCoolingDown(now) {
+		if apiKey.IsCoolingDown(now) || apiKey.IsQuotaExhausted(now) {
 			continue
 		}
 
@@ -173,7 +179,7 @@ func (ks *KeySelector) selectRoundRobin(channelID int64, apiKeys []*model.APIKey
 			continue
 		}
 
-		if selectedKey.IsCoolingDown(now) {
+		if selectedKey.IsCoolingDown(now) || selectedKey.IsQuotaExhausted(now) {
 			continue
 		}
 
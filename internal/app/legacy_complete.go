@@ -0,0 +1,413 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// Anthropic 旧版 Text Completions 兼容入口（2026-10新增）
+// ============================================================================
+// 旧版 SDK/工具仍调用 POST /v1/complete，现代上游只提供 Messages API。该路径按 anthropic 请求选路，
+// 每次渠道尝试时转换为 Messages 请求：
+//   - 请求：prompt 按 "\n\nHuman:" / "\n\nAssistant:" 拆分为 messages（首个 Human 之前的文本作为 system，
+//     末尾非空的 Assistant 段作为预填充），max_tokens_to_sample → max_tokens，其余采样参数原样透传
+//   - 响应：非流式 message → completion 对象；流式 text_delta 逐个转换为 event: completion 块，
+//     message_stop 时发送带 stop_reason 的结束块
+//   - gemini/openai 渠道（开启 anthropic_compat）：转换后的 Messages 请求继续经 Anthropic ⇄ Gemini / Chat Completions 转换层转发
+// 错误体格式与 Messages API 相同，无需转换；usage 统计仍基于上游原始字节。
+
+const (
+	legacyCompletePath = util.LegacyCompletePath
+
+	legacyHumanPrompt     = "\n\nHuman:"
+	legacyAssistantPrompt = "\n\nAssistant:"
+)
+
+// isLegacyCompleteRequest 仅 POST /v1/complete 可被转换
+func isLegacyCompleteRequest(method, path string) bool {
+	return method == http.MethodPost && path == legacyCompletePath
+}
+
+// legacyCompleteBridgeEnabled 判断本次渠道尝试是否需要 Text Completions → Messages 转换
+func legacyCompleteBridgeEnabled(cfg *model.Config, reqCtx *proxyRequestContext) bool {
+	if !isLegacyCompleteRequest(reqCtx.requestMethod, reqCtx.requestPath) {
+		return false
+	}
+	channelType := cfg.GetChannelType()
+	return channelType == util.ChannelTypeAnthropic || (cfg.AnthropicCompat && anthropicCompatChannelType(channelType))
+}
+
+// legacyCompleteRequest 旧版 Text Completions 请求体
+type legacyCompleteRequest struct {
+	Model             string          `json:"model"`
+	Prompt            string          `json:"prompt"`
+	MaxTokensToSample int             `json:"max_tokens_to_sample"`
+	StopSequences     []string        `json:"stop_sequences"`
+	Temperature       *float64        `json:"temperature"`
+	TopP              *float64        `json:"top_p"`
+	TopK              *int            `json:"top_k"`
+	Metadata          json.RawMessage `json:"metadata"`
+	Stream            bool            `json:"stream"`
+}
+
+// splitLegacyPrompt 将旧版 prompt 拆分为 system 与交替的 user/assistant 消息
+// 连续同角色的段落合并；末尾空的 Assistant 段是生成起点，丢弃；没有任何 Human/Assistant 标记时整体作为 user 消息
+func splitLegacyPrompt(prompt string) (string, []map[string]any, error) {
+	type turn struct{ role, text string }
+	var system string
+	var turns []turn
+	rest := prompt
+	role := ""
+	for {
+		h := strings.Index(rest, legacyHumanPrompt)
+		a := strings.Index(rest, legacyAssistantPrompt)
+		next, marker, nextRole := -1, "", ""
+		switch {
+		case h >= 0 && (a < 0 || h < a):
+			next, marker, nextRole = h, legacyHumanPrompt, "user"
+		case a >= 0:
+			next, marker, nextRole = a, legacyAssistantPrompt, "assistant"
+		}
+		segment := rest
+		if next >= 0 {
+			segment = rest[:next]
+		}
+		text := strings.TrimSpace(segment)
+		switch {
+		case role == "":
+			system = text
+		case len(turns) > 0 && turns[len(turns)-1].role == role:
+			if text != "" {
+				turns[len(turns)-1].text = strings.TrimSpace(turns[len(turns)-1].text + "\n\n" + text)
+			}
+		default:
+			turns = append(turns, turn{role: role, text: text})
+		}
+		if next < 0 {
+			break
+		}
+		role = nextRole
+		rest = rest[next+len(marker):]
+	}
+
+	if len(turns) == 0 {
+		if system == "" {
+			return "", nil, fmt.Errorf("prompt is required")
+		}
+		return "", []map[string]any{{"role": "user", "content": system}}, nil
+	}
+	if turns[0].role != "user" {
+		return "", nil, fmt.Errorf("prompt must contain %q before the first %q turn", legacyHumanPrompt, legacyAssistantPrompt)
+	}
+	if last := turns[len(turns)-1]; last.role == "assistant" && last.text == "" {
+		turns = turns[:len(turns)-1]
+	}
+
+	messages := make([]map[string]any, 0, len(turns))
+	for i, t := range turns {
+		if t.text == "" {
+			return "", nil, fmt.Errorf("prompt turn %d (%s) is empty", i+1, t.role)
+		}
+		messages = append(messages, map[string]any{"role": t.role, "content": t.text})
+	}
+	return system, messages, nil
+}
+
+// convertLegacyCompleteToAnthropic 将 Text Completions 请求转换为 Messages 请求，返回是否流式
+func convertLegacyCompleteToAnthropic(body []byte) ([]byte, bool, error) {
+	var req legacyCompleteRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, false, fmt.Errorf("invalid text completions request: %w", err)
+	}
+	system, messages, err := splitLegacyPrompt(req.Prompt)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid text completions prompt: %w", err)
+	}
+
+	maxTokens := req.MaxTokensToSample
+	if maxTokens <= 0 {
+		maxTokens = openaiCompatDefaultMaxTokens
+	}
+	out := map[string]any{
+		"model":      req.Model,
+		"messages":   messages,
+		"max_tokens": maxTokens,
+	}
+	if system != "" {
+		out["system"] = system
+	}
+	// "\n\nHuman:" 是旧版 API 的隐式停止序列，Messages API 按轮次结束，无需传递
+	var stops []string
+	for _, s := range req.StopSequences {
+		if s != "" && s != legacyHumanPrompt {
+			stops = append(stops, s)
+		}
+	}
+	if len(stops) > 0 {
+		out["stop_sequences"] = stops
+	}
+	if req.Temperature != nil {
+		out["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		out["top_p"] = *req.TopP
+	}
+	if req.TopK != nil {
+		out["top_k"] = *req.TopK
+	}
+	if len(req.Metadata) > 0 && string(req.Metadata) != "null" {
+		out["metadata"] = req.Metadata
+	}
+	if req.Stream {
+		out["stream"] = true
+	}
+
+	converted, err := sonic.Marshal(out)
+	if err != nil {
+		return nil, false, err
+	}
+	return converted, req.Stream, nil
+}
+
+// prepareLegacyCompleteBridge 转换请求并包装响应写入器，返回改写后的请求上下文（路径改为 /v1/messages）
+func prepareLegacyCompleteBridge(cfg *model.Config, reqCtx *proxyRequestContext, body []byte, w http.ResponseWriter) (*proxyRequestContext, []byte, *legacyCompleteWriter, error) {
+	converted, stream, err := convertLegacyCompleteToAnthropic(body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bridged := *reqCtx
+	bridged.header = openaiBridgeHeader(reqCtx.header, cfg.GetChannelType())
+	bridged.requestPath = anthropicMessagesPath
+	bridged.rawQuery = ""
+	bridged.protocolConverted = true
+	return &bridged, converted, newLegacyCompleteWriter(w, reqCtx.originalModel, stream), nil
+}
+
+// ---------------------------------------------------------------------------
+// 响应转换
+// ---------------------------------------------------------------------------
+
+// legacyStopReason Messages stop_reason → Text Completions stop_reason（自然结束视为命中隐式停止序列）
+func legacyStopReason(stopReason string) string {
+	if stopReason == "max_tokens" {
+		return "max_tokens"
+	}
+	return "stop_sequence"
+}
+
+// legacyCompletion 构造 completion 对象（stop 为命中的停止序列，未命中时为 null）
+func legacyCompletion(id, modelName, text string, stopReason any, stop any) map[string]any {
+	return map[string]any{
+		"type":        "completion",
+		"id":          id,
+		"completion":  text,
+		"stop_reason": stopReason,
+		"stop":        stop,
+		"model":       modelName,
+	}
+}
+
+func newLegacyCompletionID() string {
+	return newAnthropicID("compl_")
+}
+
+// convertAnthropicToLegacyComplete 将 Anthropic message 转换为 completion（仅保留 text 块）
+func convertAnthropicToLegacyComplete(body []byte, modelName string) ([]byte, error) {
+	var msg struct {
+		Type         string                  `json:"type"`
+		Content      []anthropicContentBlock `json:"content"`
+		StopReason   string                  `json:"stop_reason"`
+		StopSequence *string                 `json:"stop_sequence"`
+	}
+	if err := sonic.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Type != "message" {
+		return nil, fmt.Errorf("unexpected response type %q", msg.Type)
+	}
+	var text strings.Builder
+	for _, b := range msg.Content {
+		if b.Type == "text" {
+			text.WriteString(b.Text)
+		}
+	}
+	var stop any
+	if msg.StopSequence != nil {
+		stop = *msg.StopSequence
+	}
+	return sonic.Marshal(legacyCompletion(newLegacyCompletionID(), modelName, text.String(), legacyStopReason(msg.StopReason), stop))
+}
+
+// ---------------------------------------------------------------------------
+// 响应写入包装
+// ---------------------------------------------------------------------------
+
+// legacyCompleteWriter 将写向客户端的 Anthropic Messages 响应转换为 Text Completions 格式
+// 非流式：缓存完整响应体，finish 时整体转换写出
+// 流式：按 SSE 事件增量转换为 event: completion 块，ping/error 事件原样下发
+type legacyCompleteWriter struct {
+	http.ResponseWriter
+	model  string
+	stream bool
+	buf    bytes.Buffer // 非流式：完整响应体；流式：尚未成行的残余字节
+
+	// 流式状态
+	dataLines    []string
+	id           string
+	done         bool
+	stopReason   string
+	stopSequence any
+}
+
+func newLegacyCompleteWriter(w http.ResponseWriter, modelName string, stream bool) *legacyCompleteWriter {
+	return &legacyCompleteWriter{ResponseWriter: w, model: modelName, stream: stream, id: newLegacyCompletionID()}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter（SetWriteDeadline 等）
+func (w *legacyCompleteWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush 透传 Flush（流式转换后的块需立即下发）
+func (w *legacyCompleteWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *legacyCompleteWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if !w.stream {
+		return len(p), nil
+	}
+	for {
+		data := w.buf.Bytes()
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(bytes.TrimRight(data[:idx], "\r"))
+		w.buf.Next(idx + 1)
+		if err := w.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// handleLine 逐行解析上游 SSE：累积 data 行，空行时处理一个事件（事件类型取自 data 中的 type 字段）
+func (w *legacyCompleteWriter) handleLine(line string) error {
+	if after, ok := strings.CutPrefix(line, "data:"); ok {
+		w.dataLines = append(w.dataLines, strings.TrimSpace(after))
+		return nil
+	}
+	if line != "" || len(w.dataLines) == 0 {
+		return nil
+	}
+	payload := strings.Join(w.dataLines, "")
+	w.dataLines = w.dataLines[:0]
+	if w.done {
+		return nil
+	}
+	return w.handleEvent([]byte(payload))
+}
+
+func (w *legacyCompleteWriter) writeEvent(event string, payload []byte) error {
+	_, err := fmt.Fprintf(w.ResponseWriter, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+func (w *legacyCompleteWriter) writeCompletion(text string, stopReason any) error {
+	payload, err := sonic.Marshal(legacyCompletion(w.id, w.model, text, stopReason, w.stopSequence))
+	if err != nil {
+		return err
+	}
+	if err := w.writeEvent("completion", payload); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// handleEvent 单个 Anthropic 流式事件 → completion 块
+func (w *legacyCompleteWriter) handleEvent(payload []byte) error {
+	var ev struct {
+		Type         string `json:"type"`
+		ContentBlock *struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content_block"`
+		Delta *struct {
+			Type         string  `json:"type"`
+			Text         string  `json:"text"`
+			StopReason   string  `json:"stop_reason"`
+			StopSequence *string `json:"stop_sequence"`
+		} `json:"delta"`
+	}
+	if err := sonic.Unmarshal(payload, &ev); err != nil {
+		return nil // 无法解析的事件直接跳过（容错）
+	}
+	switch ev.Type {
+	case "content_block_start":
+		// 部分上游在 text 块起始事件中携带首段文本；其余块（thinking、server_tool_use 等）忽略
+		if ev.ContentBlock != nil && ev.ContentBlock.Type == "text" && ev.ContentBlock.Text != "" {
+			return w.writeCompletion(ev.ContentBlock.Text, nil)
+		}
+	case "content_block_delta":
+		// 只输出 text_delta；citations_delta、input_json_delta 等非文本增量忽略
+		if ev.Delta != nil && ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+			return w.writeCompletion(ev.Delta.Text, nil)
+		}
+	case "message_delta":
+		if ev.Delta != nil {
+			if ev.Delta.StopReason != "" {
+				w.stopReason = ev.Delta.StopReason
+			}
+			if ev.Delta.StopSequence != nil {
+				w.stopSequence = *ev.Delta.StopSequence
+			}
+		}
+	case "message_stop":
+		w.done = true
+		return w.writeCompletion("", legacyStopReason(w.stopReason))
+	case "ping":
+		return w.writeEvent("ping", payload)
+	case "error":
+		// 流内错误事件格式与旧版 API 相同，原样下发（不发送结束块，客户端据此识别失败）
+		w.done = true
+		err := w.writeEvent("error", payload)
+		w.Flush()
+		return err
+	}
+	return nil
+}
+
+// finishResponse 上游响应结束后收尾（gemini 链路须在 anthropicBridgeWriter.finishResponse 之后调用）
+// 流式响应在收到 message_stop 时已写出结束块；上游中断时不补发，客户端据此识别截断
+func (w *legacyCompleteWriter) finishResponse(completed bool) {
+	if !w.stream {
+		if w.buf.Len() == 0 {
+			return
+		}
+		out, err := convertAnthropicToLegacyComplete(w.buf.Bytes(), w.model)
+		if err != nil || !completed {
+			out = w.buf.Bytes() // 无法解析时原样返回，避免吞掉响应
+		}
+		_, _ = w.ResponseWriter.Write(out)
+		return
+	}
+
+	if w.buf.Len() > 0 {
+		_ = w.handleLine(strings.TrimRight(w.buf.String(), "\r"))
+		w.buf.Reset()
+	}
+	_ = w.handleLine("") // 处理末尾未以空行结束的事件
+	w.Flush()
+}
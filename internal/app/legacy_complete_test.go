@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+)
+
+func TestConvertLegacyCompleteToAnthropic(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-2.1",
+		"prompt": "You are terse.\n\nHuman: hi\n\nAssistant: hello\n\nHuman: weather?\n\nHuman: in Paris\n\nAssistant: It is",
+		"max_tokens_to_sample": 64,
+		"stop_sequences": ["\n\nHuman:", "END"],
+		"temperature": 0.2,
+		"stream": true
+	}`)
+
+	out, stream, err := convertLegacyCompleteToAnthropic(body)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if !stream {
+		t.Fatal("应识别为流式请求")
+	}
+	var got struct {
+		System    string `json:"system"`
+		MaxTokens int    `json:"max_tokens"`
+		Messages  []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		StopSequences []string `json:"stop_sequences"`
+		Temperature   float64  `json:"temperature"`
+	}
+	if err := sonic.Unmarshal(out, &got); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+	if got.System != "You are terse." || got.MaxTokens != 64 || got.Temperature != 0.2 {
+		t.Fatalf("system/max_tokens/temperature 不符: %s", out)
+	}
+	if len(got.StopSequences) != 1 || got.StopSequences[0] != "END" {
+		t.Fatalf("隐式停止序列应被移除: %v", got.StopSequences)
+	}
+	want := []struct{ role, content string }{
+		{"user", "hi"}, {"assistant", "hello"}, {"user", "weather?\n\nin Paris"}, {"assistant", "It is"},
+	}
+	if len(got.Messages) != len(want) {
+		t.Fatalf("消息数不符: %s", out)
+	}
+	for i, w := range want {
+		if got.Messages[i].Role != w.role || got.Messages[i].Content != w.content {
+			t.Fatalf("messages[%d] = %+v, want %+v", i, got.Messages[i], w)
+		}
+	}
+
+	// 末尾空 Assistant 段为生成起点；缺少 max_tokens_to_sample 时使用默认值
+	out, _, err = convertLegacyCompleteToAnthropic([]byte(`{"model":"m","prompt":"\n\nHuman: hi\n\nAssistant:"}`))
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if !strings.Contains(string(out), `"messages":[{"content":"hi","role":"user"}]`) || !strings.Contains(string(out), `"max_tokens":4096`) {
+		t.Fatalf("转换结果不符: %s", out)
+	}
+
+	if _, _, err := convertLegacyCompleteToAnthropic([]byte(`{"model":"m","prompt":"\n\nAssistant: hi"}`)); err == nil {
+		t.Fatal("以 Assistant 开头的 prompt 应返回错误")
+	}
+}
+
+func TestConvertAnthropicToLegacyComplete(t *testing.T) {
+	out, err := convertAnthropicToLegacyComplete([]byte(`{
+		"type": "message", "id": "msg_1", "model": "claude-x",
+		"content": [{"type": "thinking", "thinking": "..."}, {"type": "text", "text": "Hello"}],
+		"stop_reason": "max_tokens", "stop_sequence": null,
+		"usage": {"input_tokens": 3, "output_tokens": 2}
+	}`), "claude-2.1")
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	for _, want := range []string{`"type":"completion"`, `"completion":"Hello"`, `"stop_reason":"max_tokens"`, `"model":"claude-2.1"`, `"id":"compl_`} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("completion 缺少 %s: %s", want, out)
+		}
+	}
+}
+
+func TestTryChannelWithKeys_LegacyCompleteStream(t *testing.T) {
+	var gotPath string
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":12,"output_tokens":1}}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":7}}`,
+			`{"type":"message_stop"}`,
+		} {
+			_, _ = io.WriteString(w, "event: x\ndata: "+ev+"\n\n")
+		}
+	}))
+	defer upstream.Close()
+	srv, cfg := newOpenAICompatTestChannel(t, "anthropic", upstream.URL)
+
+	body := []byte(`{"model":"chat-x","stream":true,"max_tokens_to_sample":16,"prompt":"\n\nHuman: hi\n\nAssistant:"}`)
+	w := httptest.NewRecorder()
+	res, err := srv.tryChannelWithKeys(context.Background(), cfg, &proxyRequestContext{
+		originalModel: "chat-x",
+		requestMethod: http.MethodPost,
+		requestPath:   "/v1/complete",
+		body:          body,
+		header:        http.Header{"Content-Type": {"application/json"}},
+		isStreaming:   true,
+	}, w)
+	if err != nil || res == nil || !res.succeeded {
+		t.Fatalf("转发失败: res=%+v err=%v", res, err)
+	}
+	if gotPath != "/v1/messages" || !strings.Contains(string(gotBody), `"max_tokens":16`) {
+		t.Fatalf("上游请求不符: path=%s body=%s", gotPath, gotBody)
+	}
+
+	out := w.Body.String()
+	for _, want := range []string{
+		"event: ping", "event: completion", `"completion":"Hello"`, `"stop_reason":null`, `"stop_reason":"stop_sequence"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("客户端流缺少 %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "content_block_delta") || strings.Contains(out, "message_stop") {
+		t.Fatalf("客户端不应收到Messages原始事件:\n%s", out)
+	}
+}
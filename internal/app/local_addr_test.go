@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+)
+
+func TestLocalAddrDialContext(t *testing.T) {
+	var remote string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote = r.RemoteAddr
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+	addr := upstream.Listener.Addr().String()
+
+	t.Run("bound", func(t *testing.T) {
+		conn, err := localAddrDialContext(newUpstreamDialer(), "127.0.0.1", false)(context.Background(), "tcp", addr)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer conn.Close()
+		if ip := conn.LocalAddr().(*net.TCPAddr).IP; !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Fatalf("expected local 127.0.0.1, got %v", ip)
+		}
+	})
+
+	t.Run("unavailable strict", func(t *testing.T) {
+		_, err := localAddrDialContext(newUpstreamDialer(), "192.0.2.55", false)(context.Background(), "tcp", addr)
+		if !errors.Is(err, util.ErrLocalAddrUnavailable) {
+			t.Fatalf("expected ErrLocalAddrUnavailable, got %v", err)
+		}
+	})
+
+	t.Run("unavailable fallback", func(t *testing.T) {
+		conn, err := localAddrDialContext(newUpstreamDialer(), "192.0.2.55", true)(context.Background(), "tcp", addr)
+		if err != nil {
+			t.Fatalf("fallback dial failed: %v", err)
+		}
+		_ = conn.Close()
+	})
+
+	t.Run("httpClientFor", func(t *testing.T) {
+		s := &Server{client: &http.Client{Transport: buildHTTPTransport(false)}}
+		cfg := &model.Config{ID: 1, LocalAddr: "127.0.0.1"}
+		client := s.httpClientFor(cfg)
+		if client == s.client {
+			t.Fatal("expected a dedicated client for local_addr channel")
+		}
+		if s.httpClientFor(cfg) != client {
+			t.Fatal("expected dedicated client to be cached")
+		}
+		resp, err := client.Get(upstream.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if host, _, _ := net.SplitHostPort(remote); host != "127.0.0.1" {
+			t.Fatalf("unexpected remote addr %q", remote)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ccLoad/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 日志限速清理（2026-10新增）
+// ============================================================================
+// 原清理在一次调用内连续删除全部过期日志，SQLite 下会长时间持有写锁。现改为：
+//   - 每批删除 log_cleanup_batch_size 条，批间休眠 log_cleanup_batch_sleep_ms，让出写锁给日志写入
+//   - 每批独立提交：进程崩溃/重启后下一轮从剩余的过期日志继续，无需恢复状态
+//   - 清理结束后按 log_db_analyze_hours / log_db_vacuum_hours 调度 ANALYZE / VACUUM（0=关闭）
+//   - GET /admin/logs/cleanup 查看进度，POST /admin/logs/cleanup 立即触发一轮（?vacuum=1 同时回收空间）
+
+const (
+	defaultLogCleanupBatchSize    = 1000
+	defaultLogCleanupBatchSleepMs = 100
+	logCleanupBatchTimeout        = 30 * time.Second
+	logCleanupOptimizeTimeout     = 30 * time.Minute
+
+	logCleanupTriggerSchedule = "schedule"
+	logCleanupTriggerManual   = "manual"
+)
+
+var errLogCleanupRunning = errors.New("log cleanup already running")
+
+// logCleanupConfig 清理参数（启动时加载，修改后重启生效）
+type logCleanupConfig struct {
+	batchSize     int
+	batchSleep    time.Duration
+	analyzeEvery  time.Duration // 0=不调度 ANALYZE
+	vacuumEvery   time.Duration // 0=不调度 VACUUM
+	checkInterval time.Duration // 定时清理间隔
+}
+
+func defaultLogCleanupConfig() logCleanupConfig {
+	return logCleanupConfig{
+		batchSize:     defaultLogCleanupBatchSize,
+		batchSleep:    defaultLogCleanupBatchSleepMs * time.Millisecond,
+		analyzeEvery:  24 * time.Hour,
+		checkInterval: config.LogCleanupInterval,
+	}
+}
+
+// LogCleanupStatus 清理进度（时间均为Unix毫秒，0表示未发生）
+type LogCleanupStatus struct {
+	Enabled       bool   `json:"enabled"`
+	RetentionDays int    `json:"retention_days"`
+	Running       bool   `json:"running"`
+	Phase         string `json:"phase"` // idle / deleting / analyze / vacuum
+	Trigger       string `json:"trigger,omitempty"`
+	CutoffAt      int64  `json:"cutoff_at,omitempty"`
+	StartedAt     int64  `json:"started_at,omitempty"`
+	FinishedAt    int64  `json:"finished_at,omitempty"`
+	Batches       int    `json:"batches"`
+	Deleted       int64  `json:"deleted"`
+	LastError     string `json:"last_error,omitempty"`
+	LastAnalyzeAt int64  `json:"last_analyze_at,omitempty"`
+	LastVacuumAt  int64  `json:"last_vacuum_at,omitempty"`
+	BatchSize     int    `json:"batch_size"`
+	BatchSleepMs  int64  `json:"batch_sleep_ms"`
+}
+
+// logCleanupState 清理进度（后台协程写，管理接口读）
+type logCleanupState struct {
+	mu     sync.Mutex
+	status LogCleanupStatus
+}
+
+func (st *logCleanupState) update(fn func(*LogCleanupStatus)) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fn(&st.status)
+}
+
+func (st *logCleanupState) snapshot() LogCleanupStatus {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.status
+}
+
+// logCleanupRequest 手动触发请求
+type logCleanupRequest struct {
+	vacuum bool
+}
+
+// TriggerCleanup 手动触发一轮清理（清理协程未启动或已在运行时返回错误）
+func (s *LogService) TriggerCleanup(vacuum bool) error {
+	if s.cleanupTrigger == nil {
+		return errors.New("log retention is disabled")
+	}
+	if s.cleanupState.snapshot().Running {
+		return errLogCleanupRunning
+	}
+	select {
+	case s.cleanupTrigger <- logCleanupRequest{vacuum: vacuum}:
+		return nil
+	default:
+		return errLogCleanupRunning // 已有待执行的触发请求
+	}
+}
+
+// CleanupStatus 清理进度快照
+func (s *LogService) CleanupStatus() LogCleanupStatus {
+	st := s.cleanupState.snapshot()
+	st.Enabled = s.cleanupTrigger != nil
+	st.RetentionDays = s.retentionDays
+	st.BatchSize = s.cleanup.batchSize
+	st.BatchSleepMs = s.cleanup.batchSleep.Milliseconds()
+	if st.Phase == "" {
+		st.Phase = "idle"
+	}
+	return st
+}
+
+// cleanupOldLogsLoop 日志清理后台协程（私有方法）
+func (s *LogService) cleanupOldLogsLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cleanup.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.runCleanup(logCleanupTriggerSchedule, false)
+		case req := <-s.cleanupTrigger:
+			s.runCleanup(logCleanupTriggerManual, req.vacuum)
+		case <-s.shutdownCh:
+			// 收到关闭信号，直接退出（未删完的日志下一次启动后继续清理）
+			return
+		}
+	}
+}
+
+// sleepOrShutdown 批间休眠，收到关闭信号时返回 false
+func (s *LogService) sleepOrShutdown(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-s.shutdownCh:
+			return false
+		default:
+			return true
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-s.shutdownCh:
+		return false
+	}
+}
+
+// runCleanup 分批删除过期日志，之后按调度执行 ANALYZE/VACUUM
+func (s *LogService) runCleanup(trigger string, forceOptimize bool) {
+	cfg := s.cleanup
+	started := time.Now()
+	cutoff := started.AddDate(0, 0, -s.retentionDays)
+	s.cleanupState.update(func(st *LogCleanupStatus) {
+		st.Running, st.Phase, st.Trigger = true, "deleting", trigger
+		st.CutoffAt, st.StartedAt, st.FinishedAt = cutoff.UnixMilli(), started.UnixMilli(), 0
+		st.Batches, st.Deleted, st.LastError = 0, 0, ""
+	})
+	defer s.cleanupState.update(func(st *LogCleanupStatus) {
+		st.Running, st.Phase, st.FinishedAt = false, "idle", time.Now().UnixMilli()
+	})
+
+	var deleted int64
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), logCleanupBatchTimeout)
+		n, err := s.store.DeleteLogsBefore(ctx, cutoff, cfg.batchSize)
+		cancel()
+		if err != nil {
+			log.Printf("[WARN] [日志清理] 删除失败（已删除%d条）: %v", deleted, err)
+			s.cleanupState.update(func(st *LogCleanupStatus) { st.LastError = err.Error() })
+			return
+		}
+		deleted += n
+		s.cleanupState.update(func(st *LogCleanupStatus) { st.Batches++; st.Deleted = deleted })
+		if n < int64(cfg.batchSize) {
+			break
+		}
+		if !s.sleepOrShutdown(cfg.batchSleep) {
+			log.Printf("[INFO] [日志清理] 服务关闭，中断清理（已删除%d条）", deleted)
+			return
+		}
+	}
+	if deleted > 0 {
+		log.Printf("[INFO] [日志清理] 删除%d条%s之前的日志，耗时%v", deleted, cutoff.Format(time.DateTime), time.Since(started).Round(time.Millisecond))
+	}
+
+	s.optimizeAfterCleanup(cfg, forceOptimize)
+}
+
+// optimizeAfterCleanup 距上次执行超过调度间隔（或手动要求）时执行 ANALYZE / VACUUM
+func (s *LogService) optimizeAfterCleanup(cfg logCleanupConfig, force bool) {
+	now := time.Now()
+	st := s.cleanupState.snapshot()
+	due := func(every time.Duration, last int64) bool {
+		return every > 0 && now.Sub(time.UnixMilli(last)) >= every
+	}
+	vacuum := force || due(cfg.vacuumEvery, st.LastVacuumAt)
+	analyze := vacuum || due(cfg.analyzeEvery, st.LastAnalyzeAt)
+	if !analyze {
+		return
+	}
+	phase := "analyze"
+	if vacuum {
+		phase = "vacuum"
+	}
+	s.cleanupState.update(func(st *LogCleanupStatus) { st.Phase = phase })
+
+	ctx, cancel := context.WithTimeout(context.Background(), logCleanupOptimizeTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := s.store.OptimizeLogStorage(ctx, vacuum); err != nil {
+		log.Printf("[WARN] [日志清理] %s 失败: %v", phase, err)
+		s.cleanupState.update(func(st *LogCleanupStatus) { st.LastError = err.Error() })
+		return
+	}
+	log.Printf("[INFO] [日志清理] %s 完成，耗时%v", phase, time.Since(start).Round(time.Millisecond))
+	s.cleanupState.update(func(st *LogCleanupStatus) {
+		st.LastAnalyzeAt = now.UnixMilli()
+		if vacuum {
+			st.LastVacuumAt = now.UnixMilli()
+		}
+	})
+}
+
+// HandleLogCleanupStatus 日志清理进度
+// GET /admin/logs/cleanup
+func (s *Server) HandleLogCleanupStatus(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, s.logService.CleanupStatus())
+}
+
+// HandleTriggerLogCleanup 立即执行一轮日志清理（异步，进度通过 GET 查询）
+// POST /admin/logs/cleanup?vacuum=1
+func (s *Server) HandleTriggerLogCleanup(c *gin.Context) {
+	vacuum := c.Query("vacuum") == "1" || c.Query("vacuum") == "true"
+	if err := s.logService.TriggerCleanup(vacuum); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errLogCleanupRunning) {
+			status = http.StatusConflict
+		}
+		RespondErrorMsg(c, status, err.Error())
+		return
+	}
+	RespondJSON(c, http.StatusAccepted, s.logService.CleanupStatus())
+}
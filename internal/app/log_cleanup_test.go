@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+func TestLogCleanup_BatchedWithProgress(t *testing.T) {
+	_, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now()
+	var logs []*model.LogEntry
+	for i := range 5 {
+		logs = append(logs, &model.LogEntry{Time: model.JSONTime{Time: now.AddDate(0, 0, -10).Add(time.Duration(i) * time.Minute)}, Model: "m", StatusCode: 200, Message: "old"})
+	}
+	logs = append(logs, &model.LogEntry{Time: model.JSONTime{Time: now}, Model: "m", StatusCode: 200, Message: "new"})
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("add logs: %v", err)
+	}
+
+	var shuttingDown atomic.Bool
+	ls := NewLogService(store, 10, 1, 7, make(chan struct{}), &shuttingDown, &sync.WaitGroup{})
+	if err := ls.TriggerCleanup(false); err == nil {
+		t.Fatal("expected error when cleanup loop is not started")
+	}
+	ls.cleanup.batchSize = 2
+	ls.cleanup.batchSleep = 0
+
+	ls.runCleanup(logCleanupTriggerManual, false)
+
+	st := ls.CleanupStatus()
+	if st.Running || st.Phase != "idle" || st.Deleted != 5 || st.Batches != 3 || st.LastError != "" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if st.LastAnalyzeAt == 0 || st.LastVacuumAt != 0 {
+		t.Fatalf("expected analyze only on first run, got %+v", st)
+	}
+
+	remaining, err := store.ListLogsRange(ctx, now.AddDate(0, 0, -30), now.Add(time.Minute), 10, 0, nil)
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Message != "new" {
+		t.Fatalf("expected only the recent log to remain, got %d", len(remaining))
+	}
+
+	// 手动触发：已有待执行请求时返回冲突
+	ls.cleanupTrigger = make(chan logCleanupRequest, 1)
+	if err := ls.TriggerCleanup(true); err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	if err := ls.TriggerCleanup(true); !errors.Is(err, errLogCleanupRunning) {
+		t.Fatalf("expected running error, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"ccLoad/internal/config"
 	"ccLoad/internal/model"
 	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
 )
 
 // LogService 日志管理服务
@@ -32,6 +33,14 @@ type LogService struct {
 	// 日志保留天数（启动时确定，修改后重启生效）
 	retentionDays int
 
+	// 日志清理参数、进度与手动触发（2026-10新增）
+	cleanup        logCleanupConfig
+	cleanupState   logCleanupState
+	cleanupTrigger chan logCleanupRequest // nil 表示未启用清理
+
+	// 请求用量预写日志（nil 表示未启用，2026-10新增）
+	journal *requestJournal
+
 	// 优雅关闭
 	shutdownCh     chan struct{}
 	isShuttingDown *atomic.Bool
@@ -53,6 +62,7 @@ func NewLogService(
 		logChan:        make(chan *model.LogEntry, logBufferSize),
 		logWorkers:     logWorkers,
 		retentionDays:  retentionDays,
+		cleanup:        defaultLogCleanupConfig(),
 		shutdownCh:     shutdownCh,
 		isShuttingDown: isShuttingDown,
 		wg:             wg,
@@ -137,6 +147,14 @@ func (s *LogService) flushLogs(logs []*model.LogEntry) {
 	// [FIX] 数据库写入失败时记录错误，避免静默丢失日志
 	if err := s.store.BatchAddLogs(ctx, logs); err != nil {
 		log.Printf("[ERROR] 日志批量写入失败 (batch_size=%d): %v", len(logs), err)
+		return
+	}
+	if s.journal != nil {
+		ids := make([]uint64, 0, len(logs))
+		for _, e := range logs {
+			ids = append(ids, e.JournalID)
+		}
+		s.journal.ack(ids...)
 	}
 }
 
@@ -153,6 +171,12 @@ func (s *LogService) flushIfNeeded(batch []*model.LogEntry) {
 
 // AddLogAsync 异步添加日志
 func (s *LogService) AddLogAsync(entry *model.LogEntry) {
+	// 日志消息可能包含上游错误体回显的密钥
+	entry.Message = util.RedactSecrets(entry.Message)
+	// 带用量的日志先写预写日志：shutdown/队列满被丢弃时，下次启动重放
+	if journalWantsLog(entry) {
+		entry.JournalID = s.journal.appendLog(entry)
+	}
 	// shutdown时不再写入日志
 	if s.isShuttingDown.Load() {
 		return
@@ -172,43 +196,23 @@ func (s *LogService) AddLogAsync(entry *model.LogEntry) {
 	}
 }
 
+// QueueStats 返回日志队列深度、容量和累计丢弃数（用于健康检查）
+func (s *LogService) QueueStats() (depth, capacity int, dropped uint64) {
+	return len(s.logChan), cap(s.logChan), s.logDropCount.Load()
+}
+
 // ============================================================================
 // 日志清理
 // ============================================================================
 
 // StartCleanupLoop 启动日志清理后台协程
-// 每小时检查一次，删除3天前的日志
-// 支持优雅关闭
+// 每小时检查一次，分批删除保留天数之前的日志（详见 log_cleanup.go）
+// 支持优雅关闭与手动触发
 func (s *LogService) StartCleanupLoop() {
+	if s.cleanup.batchSize <= 0 || s.cleanup.checkInterval <= 0 {
+		s.cleanup = defaultLogCleanupConfig()
+	}
+	s.cleanupTrigger = make(chan logCleanupRequest, 1)
 	s.wg.Add(1)
 	go s.cleanupOldLogsLoop()
 }
-
-// cleanupOldLogsLoop 日志清理后台协程（私有方法）
-func (s *LogService) cleanupOldLogsLoop() {
-	defer s.wg.Done()
-
-	ticker := time.NewTicker(config.LogCleanupInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			// 使用带超时的context，避免日志清理阻塞关闭流程。
-			// [FIX] P0-4: WithTimeout 的 cancel 必须在每次循环内执行，不能在循环里 defer 到 goroutine 退出。
-			func() {
-				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				defer cancel()
-
-				cutoff := time.Now().AddDate(0, 0, -s.retentionDays)
-
-				// 通过Store接口清理旧日志，忽略错误（非关键操作）
-				_ = s.store.CleanupLogsBefore(ctx, cutoff)
-			}()
-
-		case <-s.shutdownCh:
-			// 收到关闭信号，直接退出（不执行最后一次清理）
-			return
-		}
-	}
-}
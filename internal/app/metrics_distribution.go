@@ -0,0 +1,311 @@
+package app
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 请求/响应分布统计（2026-10新增）
+// ============================================================================
+// 按 模型+渠道 记录成功请求的输入/输出Token与请求/响应体大小分布，用于容量规划与定价分析。
+// 每个指标使用固定对数桶直方图（每2倍细分为 distSubBuckets 个桶，分位误差约 ±9%），
+// 内存占用与请求量无关，且不同渠道/模型的直方图可直接逐桶相加合并。
+// 仅内存保存（自进程启动起累计），GET /admin/metrics/distributions 查询 p50/p90/p99。
+
+const (
+	distSubBuckets = 4   // 每个2倍区间的桶数
+	distBuckets    = 161 // 桶0记录0值，其余覆盖 [1, 2^40)
+	distMaxSeries  = 1000
+
+	distInputTokens   = "input_tokens" // 含缓存读取/写入的总输入Token
+	distOutputTokens  = "output_tokens"
+	distRequestBytes  = "request_bytes"
+	distResponseBytes = "response_bytes" // 上游响应体字节数
+)
+
+var distMetrics = []string{distInputTokens, distOutputTokens, distRequestBytes, distResponseBytes}
+
+// distHistogram 固定对数桶直方图
+type distHistogram struct {
+	buckets [distBuckets]uint64
+	count   uint64
+	sum     float64
+	min     int64
+	max     int64
+}
+
+func distBucketIndex(v int64) int {
+	if v <= 0 {
+		return 0
+	}
+	idx := 1 + int(math.Log2(float64(v))*distSubBuckets)
+	return min(idx, distBuckets-1)
+}
+
+// distBucketUpper 桶的上界（不含）
+func distBucketUpper(idx int) float64 {
+	if idx == 0 {
+		return 0
+	}
+	return math.Exp2(float64(idx) / distSubBuckets)
+}
+
+func (h *distHistogram) observe(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += float64(v)
+	h.buckets[distBucketIndex(v)]++
+}
+
+func (h *distHistogram) merge(o *distHistogram) {
+	if o.count == 0 {
+		return
+	}
+	if h.count == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.count += o.count
+	h.sum += o.sum
+	for i, n := range o.buckets {
+		h.buckets[i] += n
+	}
+}
+
+// quantile 估算分位值（取所在桶上界，并限制在 [min, max] 内）
+func (h *distHistogram) quantile(q float64) int64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	rank = max(rank, 1)
+	var seen uint64
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			v := int64(math.Ceil(distBucketUpper(i))) - 1
+			return min(max(v, h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// DistributionSummary 单个指标的分布摘要
+type DistributionSummary struct {
+	Count uint64  `json:"count"`
+	Avg   float64 `json:"avg"`
+	Min   int64   `json:"min"`
+	P50   int64   `json:"p50"`
+	P90   int64   `json:"p90"`
+	P99   int64   `json:"p99"`
+	Max   int64   `json:"max"`
+}
+
+func (h *distHistogram) summary() DistributionSummary {
+	if h.count == 0 {
+		return DistributionSummary{}
+	}
+	return DistributionSummary{
+		Count: h.count,
+		Avg:   math.Round(h.sum/float64(h.count)*100) / 100,
+		Min:   h.min,
+		P50:   h.quantile(0.50),
+		P90:   h.quantile(0.90),
+		P99:   h.quantile(0.99),
+		Max:   h.max,
+	}
+}
+
+type distSeriesKey struct {
+	model     string
+	channelID int64
+}
+
+type distSeries struct {
+	channelName string
+	metrics     [4]distHistogram // 顺序与 distMetrics 一致
+}
+
+// distributionCollector 分布统计（nil 时所有方法为空操作）
+type distributionCollector struct {
+	mu      sync.Mutex
+	series  map[distSeriesKey]*distSeries
+	since   time.Time
+	dropped atomic.Int64 // 超过 distMaxSeries 后丢弃的样本数
+}
+
+func newDistributionCollector() *distributionCollector {
+	return &distributionCollector{series: make(map[distSeriesKey]*distSeries), since: time.Now()}
+}
+
+// distSample 单次请求的分布样本（Token为0且未解析到usage时不计入Token分布）
+type distSample struct {
+	model         string
+	channelID     int64
+	channelName   string
+	hasUsage      bool
+	inputTokens   int64
+	outputTokens  int64
+	requestBytes  int64
+	responseBytes int64
+}
+
+func (d *distributionCollector) observe(s distSample) {
+	if d == nil || s.model == "" {
+		return
+	}
+	key := distSeriesKey{s.model, s.channelID}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	series, ok := d.series[key]
+	if !ok {
+		if len(d.series) >= distMaxSeries {
+			d.dropped.Add(1)
+			return
+		}
+		series = &distSeries{}
+		d.series[key] = series
+	}
+	series.channelName = s.channelName
+	if s.hasUsage {
+		series.metrics[0].observe(s.inputTokens)
+		series.metrics[1].observe(s.outputTokens)
+	}
+	series.metrics[2].observe(s.requestBytes)
+	if s.responseBytes > 0 {
+		series.metrics[3].observe(s.responseBytes)
+	}
+}
+
+// DistributionEntry 分组后的分布统计
+type DistributionEntry struct {
+	Model       string                         `json:"model,omitempty"`
+	ChannelID   int64                          `json:"channel_id,omitempty"`
+	ChannelName string                         `json:"channel_name,omitempty"`
+	Metrics     map[string]DistributionSummary `json:"metrics"`
+}
+
+// snapshot 按 groupBy（model/channel/model_channel）合并直方图，按请求数倒序
+func (d *distributionCollector) snapshot(groupBy, modelFilter string, channelFilter int64) []DistributionEntry {
+	entries := []DistributionEntry{}
+	if d == nil {
+		return entries
+	}
+	type group struct {
+		entry   DistributionEntry
+		metrics [4]distHistogram
+	}
+	groups := make(map[distSeriesKey]*group)
+	d.mu.Lock()
+	for key, series := range d.series {
+		if (modelFilter != "" && key.model != modelFilter) || (channelFilter > 0 && key.channelID != channelFilter) {
+			continue
+		}
+		gk := key
+		entry := DistributionEntry{Model: key.model, ChannelID: key.channelID, ChannelName: series.channelName}
+		switch groupBy {
+		case "model":
+			gk.channelID = 0
+			entry.ChannelID, entry.ChannelName = 0, ""
+		case "channel":
+			gk.model = ""
+			entry.Model = ""
+		}
+		g, ok := groups[gk]
+		if !ok {
+			g = &group{entry: entry}
+			groups[gk] = g
+		}
+		for i := range series.metrics {
+			g.metrics[i].merge(&series.metrics[i])
+		}
+	}
+	d.mu.Unlock()
+
+	for _, g := range groups {
+		g.entry.Metrics = make(map[string]DistributionSummary, len(distMetrics))
+		for i, name := range distMetrics {
+			g.entry.Metrics[name] = g.metrics[i].summary()
+		}
+		entries = append(entries, g.entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		ci, cj := entries[i].Metrics[distRequestBytes].Count, entries[j].Metrics[distRequestBytes].Count
+		if ci != cj {
+			return ci > cj
+		}
+		if entries[i].Model != entries[j].Model {
+			return entries[i].Model < entries[j].Model
+		}
+		return entries[i].ChannelID < entries[j].ChannelID
+	})
+	return entries
+}
+
+// observeDistribution 记录成功请求的分布样本
+func (s *Server) observeDistribution(reqCtx *proxyRequestContext, channelID int64, channelName string, res *fwResult) {
+	if s.distributions == nil || reqCtx == nil || res == nil {
+		return
+	}
+	input := res.InputTokens + res.CacheReadInputTokens + res.CacheCreationInputTokens
+	s.distributions.observe(distSample{
+		model:         reqCtx.originalModel,
+		channelID:     channelID,
+		channelName:   channelName,
+		hasUsage:      input > 0 || res.OutputTokens > 0,
+		inputTokens:   int64(input),
+		outputTokens:  int64(res.OutputTokens),
+		requestBytes:  int64(len(reqCtx.body)),
+		responseBytes: res.ResponseBytes,
+	})
+}
+
+// HandleMetricsDistributions 按模型/渠道的Token与请求体大小分布
+// GET /admin/metrics/distributions?group_by=model|channel|model_channel&model=xxx&channel_id=1
+func (s *Server) HandleMetricsDistributions(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "model_channel")
+	switch groupBy {
+	case "model", "channel", "model_channel":
+	default:
+		RespondErrorMsg(c, http.StatusBadRequest, "group_by must be model, channel or model_channel")
+		return
+	}
+	var channelID int64
+	if v := c.Query("channel_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid channel_id")
+			return
+		}
+		channelID = id
+	}
+	var since time.Time
+	var dropped int64
+	if s.distributions != nil {
+		since, dropped = s.distributions.since, s.distributions.dropped.Load()
+	}
+	RespondJSON(c, http.StatusOK, gin.H{
+		"since":           since.UnixMilli(),
+		"group_by":        groupBy,
+		"dropped_samples": dropped,
+		"entries":         s.distributions.snapshot(groupBy, c.Query("model"), channelID),
+	})
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDistHistogramQuantiles(t *testing.T) {
+	var h distHistogram
+	for v := int64(1); v <= 1000; v++ {
+		h.observe(v)
+	}
+	s := h.summary()
+	if s.Count != 1000 || s.Min != 1 || s.Max != 1000 || s.Avg != 500.5 {
+		t.Fatalf("摘要不符: %+v", s)
+	}
+	for _, c := range []struct {
+		got, want int64
+	}{{s.P50, 500}, {s.P90, 900}, {s.P99, 990}} {
+		// 固定对数桶：误差不超过一个桶宽（约19%）
+		if c.got < c.want || float64(c.got) > float64(c.want)*1.19 {
+			t.Fatalf("分位值超出桶误差: got=%d want≈%d", c.got, c.want)
+		}
+	}
+
+	var merged distHistogram
+	merged.merge(&h)
+	merged.merge(&distHistogram{})
+	if merged.summary() != s {
+		t.Fatal("合并空直方图不应改变结果")
+	}
+	if (&distHistogram{}).summary() != (DistributionSummary{}) {
+		t.Fatal("空直方图摘要应为零值")
+	}
+}
+
+func TestHandleMetricsDistributions(t *testing.T) {
+	srv := &Server{distributions: newDistributionCollector()}
+	for i := 0; i < 3; i++ {
+		srv.observeDistribution(&proxyRequestContext{originalModel: "m1", body: make([]byte, 100)}, 1, "a",
+			&fwResult{InputTokens: 10, CacheReadInputTokens: 90, OutputTokens: 20, ResponseBytes: 400})
+	}
+	srv.observeDistribution(&proxyRequestContext{originalModel: "m1", body: make([]byte, 100)}, 2, "b", &fwResult{})
+	srv.observeDistribution(&proxyRequestContext{originalModel: "m2", body: make([]byte, 10)}, 2, "b", &fwResult{OutputTokens: 1})
+
+	query := func(q string) (int, []DistributionEntry) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/metrics/distributions?"+q, nil)
+		srv.HandleMetricsDistributions(c)
+		var resp struct {
+			Data struct {
+				Entries []DistributionEntry `json:"entries"`
+			} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data.Entries
+	}
+
+	code, entries := query("")
+	if code != http.StatusOK || len(entries) != 3 || entries[0].Model != "m1" || entries[0].ChannelID != 1 {
+		t.Fatalf("按模型+渠道分组不符: %d %+v", code, entries)
+	}
+	if in := entries[0].Metrics[distInputTokens]; in.Count != 3 || in.P50 != 100 {
+		t.Fatalf("输入Token应包含缓存Token: %+v", in)
+	}
+
+	_, entries = query("group_by=model&model=m1")
+	if len(entries) != 1 || entries[0].ChannelID != 0 {
+		t.Fatalf("按模型分组不符: %+v", entries)
+	}
+	m := entries[0].Metrics
+	if m[distRequestBytes].Count != 4 || m[distInputTokens].Count != 3 || m[distResponseBytes].Count != 3 {
+		t.Fatalf("无usage/响应大小的样本只应计入请求体分布: %+v", m)
+	}
+
+	if _, entries = query("group_by=channel&channel_id=2"); len(entries) != 1 || entries[0].Model != "" || entries[0].ChannelName != "b" {
+		t.Fatalf("按渠道分组不符: %+v", entries)
+	}
+	if code, _ := query("group_by=key"); code != http.StatusBadRequest {
+		t.Fatalf("非法 group_by 应返回400，实际 %d", code)
+	}
+}
@@ -0,0 +1,433 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 内置模拟上游（2026-10新增）
+// channel_type=mock 的渠道不访问任何外部服务，由进程内 RoundTripper 按请求路径的协议
+// 生成确定性的合成响应（JSON 或 SSE），完整走代理 → 日志 → 统计 → 冷却 → 计费链路，
+// 用于演示环境与CI。渠道URL形如：
+//   mock://local?latency_ms=200&chunk_ms=20&failure_rate=0.1&failure_status=503&output_tokens=32
+// ============================================================================
+
+// mockUpstreamBase 模拟渠道出站请求使用的占位地址（仅用于构造 http.Request，不会真实拨号）
+const mockUpstreamBase = "http://ccload-mock.invalid"
+
+// mockOptions 模拟上游参数（来自渠道URL查询串）
+type mockOptions struct {
+	LatencyMs     int     // 响应头前的固定延迟
+	ChunkMs       int     // 流式响应相邻分片间隔
+	FailureRate   float64 // 失败比例（0-1），按请求序号确定性分布
+	FailureStatus int     // 失败时返回的状态码
+	InputTokens   int     // 输入token数（0=按请求体长度估算）
+	OutputTokens  int     // 输出token数（每个token对应一个合成单词）
+}
+
+// mockOptionBounds 各整数参数的取值范围
+var mockOptionBounds = map[string][2]int{
+	"latency_ms":     {0, 60000},
+	"chunk_ms":       {0, 10000},
+	"failure_status": {400, 599},
+	"input_tokens":   {0, 1000000},
+	"output_tokens":  {1, 4096},
+}
+
+// parseMockChannelURL 解析并标准化模拟渠道URL（未知参数/越界值直接报错）
+func parseMockChannelURL(raw string) (mockOptions, string, error) {
+	opts := mockOptions{FailureStatus: http.StatusServiceUnavailable, OutputTokens: 16}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return opts, "", fmt.Errorf("url cannot be empty")
+	}
+	u, err := neturl.Parse(raw)
+	if err != nil || u.Scheme != util.ChannelTypeMock {
+		return opts, "", fmt.Errorf("invalid mock url: %q (expected mock://local?latency_ms=...)", raw)
+	}
+	host := u.Host
+	if host == "" {
+		host = "local"
+	}
+
+	values, err := neturl.ParseQuery(u.RawQuery)
+	if err != nil {
+		return opts, "", fmt.Errorf("invalid mock url query: %w", err)
+	}
+	for key := range values {
+		value := values.Get(key)
+		if key == "failure_rate" {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(rate) || rate < 0 || rate > 1 {
+				return opts, "", fmt.Errorf("mock url: failure_rate must be between 0 and 1, got %q", value)
+			}
+			opts.FailureRate = rate
+			continue
+		}
+		bounds, ok := mockOptionBounds[key]
+		if !ok {
+			return opts, "", fmt.Errorf("mock url: unknown option %q", key)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < bounds[0] || n > bounds[1] {
+			return opts, "", fmt.Errorf("mock url: %s must be between %d and %d, got %q", key, bounds[0], bounds[1], value)
+		}
+		switch key {
+		case "latency_ms":
+			opts.LatencyMs = n
+		case "chunk_ms":
+			opts.ChunkMs = n
+		case "failure_status":
+			opts.FailureStatus = n
+		case "input_tokens":
+			opts.InputTokens = n
+		case "output_tokens":
+			opts.OutputTokens = n
+		}
+	}
+
+	normalized := "mock://" + host
+	if len(values) > 0 {
+		normalized += "?" + values.Encode()
+	}
+	return opts, normalized, nil
+}
+
+// isMockChannel 渠道是否为内置模拟上游
+func isMockChannel(cfg *model.Config) bool {
+	return cfg != nil && cfg.GetChannelType() == util.ChannelTypeMock
+}
+
+// upstreamProtocol 渠道上游使用的响应协议；模拟渠道按请求路径回应对应协议
+func upstreamProtocol(cfg *model.Config, requestPath string) string {
+	if isMockChannel(cfg) {
+		return util.DetectChannelTypeFromPath(requestPath)
+	}
+	return util.ProtocolChannelType(cfg.ChannelType)
+}
+
+// newMockHTTPClient 构造模拟渠道的客户端（URL在写入时已校验，解析失败时使用默认参数）
+func newMockHTTPClient(cfg *model.Config) *http.Client {
+	opts, _, _ := parseMockChannelURL(cfg.URL)
+	return &http.Client{Transport: &mockTransport{opts: opts}}
+}
+
+// mockTransport 进程内模拟上游，实现 http.RoundTripper
+type mockTransport struct {
+	opts     mockOptions
+	requests atomic.Int64
+}
+
+// mockWords 合成文本词表
+var mockWords = strings.Fields("the quick brown fox jumps over a lazy dog while ccload balances every request across " +
+	"healthy channels with cooldown retries stats and billing working together in this local mock demo")
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := sleepContext(req.Context(), time.Duration(t.opts.LatencyMs)*time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	// 第 n 个请求在 floor(n·rate) 跨过整数时失败：失败在请求序列中均匀分布且可复现
+	n := t.requests.Add(1)
+	if rate := t.opts.FailureRate; rate > 0 && math.Floor(float64(n)*rate) > math.Floor(float64(n-1)*rate) {
+		return mockJSONResponse(req, t.opts.FailureStatus, map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "api_error", "message": "mock upstream simulated failure"},
+		}), nil
+	}
+
+	path := req.URL.Path
+	g := newMockGeneration(path, body, t.opts)
+	if strings.HasSuffix(path, "/count_tokens") {
+		return mockJSONResponse(req, http.StatusOK, map[string]any{"input_tokens": g.inputTokens}), nil
+	}
+
+	var events []mockEvent
+	var final map[string]any
+	switch protocol := util.DetectChannelTypeFromPath(path); {
+	case protocol == util.ChannelTypeAnthropic && path == "/v1/messages":
+		events, final = g.anthropic()
+	case protocol == util.ChannelTypeOpenAI && path == "/v1/chat/completions":
+		events, final = g.openaiChat()
+	case protocol == util.ChannelTypeCodex && path == "/v1/responses":
+		events, final = g.responses()
+	case protocol == util.ChannelTypeGemini && (strings.HasSuffix(path, ":generateContent") || strings.HasSuffix(path, ":streamGenerateContent")):
+		events, final = g.gemini()
+	default:
+		return mockJSONResponse(req, http.StatusNotFound, map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "not_found_error", "message": "mock upstream does not support " + path},
+		}), nil
+	}
+
+	if !isStreamingRequest(path, body) {
+		return mockJSONResponse(req, http.StatusOK, final), nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		for i, ev := range events {
+			if i > 0 {
+				if err := sleepContext(req.Context(), time.Duration(t.opts.ChunkMs)*time.Millisecond); err != nil {
+					_ = pw.CloseWithError(err)
+					return
+				}
+			}
+			if _, err := pw.Write(ev.encode()); err != nil {
+				return
+			}
+		}
+		_ = pw.Close()
+	}()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Proto:      "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1,
+		Header:        http.Header{"Content-Type": []string{"text/event-stream"}, "Cache-Control": []string{"no-cache"}},
+		Body:          pr,
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+// sleepContext 等待 d 或上下文取消
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func mockJSONResponse(req *http.Request, status int, payload any) *http.Response {
+	data, _ := sonic.Marshal(payload)
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:      "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
+
+// mockEvent 单个SSE事件（Name 为空时只输出 data 行）
+type mockEvent struct {
+	Name string
+	Data any
+}
+
+func (e mockEvent) encode() []byte {
+	var buf bytes.Buffer
+	if e.Name != "" {
+		buf.WriteString("event: " + e.Name + "\n")
+	}
+	if s, ok := e.Data.(string); ok {
+		buf.WriteString("data: " + s + "\n\n")
+	} else {
+		data, _ := sonic.Marshal(e.Data)
+		buf.WriteString("data: ")
+		buf.Write(data)
+		buf.WriteString("\n\n")
+	}
+	return buf.Bytes()
+}
+
+// mockGeneration 一次请求的合成结果：相同模型与请求体总是得到相同文本
+type mockGeneration struct {
+	id           string
+	model        string
+	words        []string // 每个元素对应一个输出token（首个之后带前导空格）
+	inputTokens  int
+	outputTokens int
+}
+
+func newMockGeneration(path string, body []byte, opts mockOptions) *mockGeneration {
+	var req struct {
+		Model string `json:"model"`
+	}
+	_ = sonic.Unmarshal(body, &req)
+	modelName := req.Model
+	if modelName == "" {
+		// Gemini 模型在路径中：/v1beta/models/{model}:generateContent
+		if _, rest, ok := strings.Cut(path, "/models/"); ok {
+			modelName, _, _ = strings.Cut(rest, ":")
+		}
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(modelName))
+	_, _ = h.Write(body)
+	seed := h.Sum64()
+
+	g := &mockGeneration{
+		id:           fmt.Sprintf("%016x", seed),
+		model:        modelName,
+		inputTokens:  opts.InputTokens,
+		outputTokens: opts.OutputTokens,
+	}
+	if g.inputTokens == 0 {
+		g.inputTokens = max(1, len(body)/4)
+	}
+	g.words = make([]string, g.outputTokens)
+	for i := range g.words {
+		seed = seed*6364136223846793005 + 1442695040888963407
+		word := mockWords[(seed>>33)%uint64(len(mockWords))]
+		if i > 0 {
+			word = " " + word
+		}
+		g.words[i] = word
+	}
+	return g
+}
+
+func (g *mockGeneration) text() string {
+	return strings.Join(g.words, "")
+}
+
+// anthropic Messages API：message_start → content_block_delta × N → message_delta(usage) → message_stop
+func (g *mockGeneration) anthropic() ([]mockEvent, map[string]any) {
+	id := "msg_mock_" + g.id
+	events := []mockEvent{
+		{"message_start", map[string]any{"type": "message_start", "message": map[string]any{
+			"id": id, "type": "message", "role": "assistant", "model": g.model, "content": []any{},
+			"stop_reason": nil, "stop_sequence": nil,
+			"usage": map[string]any{"input_tokens": g.inputTokens, "output_tokens": 0},
+		}}},
+		{"content_block_start", map[string]any{"type": "content_block_start", "index": 0, "content_block": map[string]any{"type": "text", "text": ""}}},
+	}
+	for _, w := range g.words {
+		events = append(events, mockEvent{"content_block_delta", map[string]any{
+			"type": "content_block_delta", "index": 0, "delta": map[string]any{"type": "text_delta", "text": w},
+		}})
+	}
+	events = append(events,
+		mockEvent{"content_block_stop", map[string]any{"type": "content_block_stop", "index": 0}},
+		mockEvent{"message_delta", map[string]any{
+			"type":  "message_delta",
+			"delta": map[string]any{"stop_reason": "end_turn", "stop_sequence": nil},
+			"usage": map[string]any{"input_tokens": g.inputTokens, "output_tokens": g.outputTokens},
+		}},
+		mockEvent{"message_stop", map[string]any{"type": "message_stop"}},
+	)
+	final := map[string]any{
+		"id": id, "type": "message", "role": "assistant", "model": g.model,
+		"content":     []any{map[string]any{"type": "text", "text": g.text()}},
+		"stop_reason": "end_turn", "stop_sequence": nil,
+		"usage": map[string]any{"input_tokens": g.inputTokens, "output_tokens": g.outputTokens},
+	}
+	return events, final
+}
+
+// openaiChat Chat Completions：delta 分片 → finish_reason → usage 分片 → [DONE]
+func (g *mockGeneration) openaiChat() ([]mockEvent, map[string]any) {
+	id := "chatcmpl-mock-" + g.id
+	usage := map[string]any{
+		"prompt_tokens": g.inputTokens, "completion_tokens": g.outputTokens, "total_tokens": g.inputTokens + g.outputTokens,
+	}
+	chunk := func(choices []any) map[string]any {
+		return map[string]any{"id": id, "object": "chat.completion.chunk", "model": g.model, "choices": choices}
+	}
+	var events []mockEvent
+	for i, w := range g.words {
+		delta := map[string]any{"content": w}
+		if i == 0 {
+			delta["role"] = "assistant"
+		}
+		events = append(events, mockEvent{Data: chunk([]any{map[string]any{"index": 0, "delta": delta, "finish_reason": nil}})})
+	}
+	last := chunk([]any{})
+	last["usage"] = usage
+	events = append(events,
+		mockEvent{Data: chunk([]any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": "stop"}})},
+		mockEvent{Data: last},
+		mockEvent{Data: "[DONE]"},
+	)
+	final := map[string]any{
+		"id": id, "object": "chat.completion", "model": g.model,
+		"choices": []any{map[string]any{
+			"index": 0, "message": map[string]any{"role": "assistant", "content": g.text()}, "finish_reason": "stop",
+		}},
+		"usage": usage,
+	}
+	return events, final
+}
+
+// responses Responses API：response.created → response.output_text.delta × N → response.completed(usage)
+func (g *mockGeneration) responses() ([]mockEvent, map[string]any) {
+	id := "resp_mock_" + g.id
+	itemID := "msg_mock_" + g.id
+	response := func(status string, output []any, usage any) map[string]any {
+		return map[string]any{"id": id, "object": "response", "status": status, "model": g.model, "output": output, "usage": usage}
+	}
+	events := []mockEvent{{"response.created", map[string]any{"type": "response.created", "response": response("in_progress", []any{}, nil)}}}
+	for _, w := range g.words {
+		events = append(events, mockEvent{"response.output_text.delta", map[string]any{
+			"type": "response.output_text.delta", "item_id": itemID, "output_index": 0, "content_index": 0, "delta": w,
+		}})
+	}
+	final := response("completed", []any{map[string]any{
+		"type": "message", "id": itemID, "status": "completed", "role": "assistant",
+		"content": []any{map[string]any{"type": "output_text", "text": g.text(), "annotations": []any{}}},
+	}}, map[string]any{
+		"input_tokens": g.inputTokens, "output_tokens": g.outputTokens, "total_tokens": g.inputTokens + g.outputTokens,
+	})
+	events = append(events, mockEvent{"response.completed", map[string]any{"type": "response.completed", "response": final}})
+	return events, final
+}
+
+// gemini generateContent / streamGenerateContent：每个分片一段文本，末片带 finishReason 与 usageMetadata
+func (g *mockGeneration) gemini() ([]mockEvent, map[string]any) {
+	candidate := func(text string, finish bool) map[string]any {
+		c := map[string]any{"index": 0, "content": map[string]any{"role": "model", "parts": []any{map[string]any{"text": text}}}}
+		if finish {
+			c["finishReason"] = "STOP"
+		}
+		return c
+	}
+	usage := map[string]any{
+		"promptTokenCount": g.inputTokens, "candidatesTokenCount": g.outputTokens, "totalTokenCount": g.inputTokens + g.outputTokens,
+	}
+	var events []mockEvent
+	for i, w := range g.words {
+		chunk := map[string]any{"candidates": []any{candidate(w, i == len(g.words)-1)}, "modelVersion": g.model}
+		if i == len(g.words)-1 {
+			chunk["usageMetadata"] = usage
+		}
+		events = append(events, mockEvent{Data: chunk})
+	}
+	final := map[string]any{"candidates": []any{candidate(g.text(), true)}, "usageMetadata": usage, "modelVersion": g.model}
+	return events, final
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseMockChannelURL(t *testing.T) {
+	opts, normalized, err := parseMockChannelURL(" mock://local?output_tokens=4&failure_rate=0.5&latency_ms=10 ")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if opts.OutputTokens != 4 || opts.FailureRate != 0.5 || opts.LatencyMs != 10 || opts.FailureStatus != http.StatusServiceUnavailable {
+		t.Fatalf("参数不符: %+v", opts)
+	}
+	if normalized != "mock://local?failure_rate=0.5&latency_ms=10&output_tokens=4" {
+		t.Fatalf("标准化结果不符: %q", normalized)
+	}
+	for _, bad := range []string{"", "https://api.example.com", "mock://local?foo=1", "mock://local?failure_rate=2", "mock://local?output_tokens=0"} {
+		if _, _, err := parseMockChannelURL(bad); err == nil {
+			t.Fatalf("应拒绝无效URL: %q", bad)
+		}
+	}
+
+	cr := &ChannelRequest{Name: "demo", APIKey: "k", ChannelType: "Mock", URL: "mock://local",
+		Models: []model.ModelEntry{{Model: "claude-sonnet-4-5"}}}
+	if err := cr.Validate(); err != nil || cr.ChannelType != "mock" || cr.URL != "mock://local" {
+		t.Fatalf("mock 渠道应通过校验: %v %+v", err, cr)
+	}
+}
+
+func TestHandleProxyRequest_MockChannel(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "mock.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "demo", URL: "mock://local?output_tokens=5&input_tokens=7", ChannelType: "mock", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}, {Model: "gpt-4o"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	proxy := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		srv.HandleProxyRequest(c)
+		return w
+	}
+
+	const body = `{"model":"claude-sonnet-4-5","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`
+	first := proxy("/v1/messages", body)
+	if first.Code != http.StatusOK || !strings.Contains(first.Body.String(), `"output_tokens":5`) {
+		t.Fatalf("非流式请求应返回合成消息: %d %s", first.Code, first.Body.String())
+	}
+	if again := proxy("/v1/messages", body); again.Body.String() != first.Body.String() {
+		t.Fatalf("相同请求应得到相同合成内容:\n%s\n%s", first.Body.String(), again.Body.String())
+	}
+
+	stream := proxy("/v1/chat/completions", `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if stream.Code != http.StatusOK || !strings.Contains(stream.Body.String(), "chat.completion.chunk") ||
+		!strings.HasSuffix(strings.TrimSpace(stream.Body.String()), "data: [DONE]") {
+		t.Fatalf("流式请求应返回 OpenAI SSE: %d %s", stream.Code, stream.Body.String())
+	}
+
+	// 日志异步落库，轮询等待：usage 按请求路径的协议解析
+	since := time.Now().Add(-time.Minute)
+	var logs []*model.LogEntry
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		logs, err = store.ListLogs(ctx, since, 10, 0, nil)
+		if err == nil && len(logs) == 3 {
+			break
+		}
+	}
+	if len(logs) != 3 {
+		t.Fatalf("应记录3条日志: %+v err=%v", logs, err)
+	}
+	for _, l := range logs {
+		if l.StatusCode != http.StatusOK || l.InputTokens != 7 || l.OutputTokens != 5 {
+			t.Fatalf("日志usage不符: %+v", l)
+		}
+	}
+}
+
+func TestMockTransport_FailureRate(t *testing.T) {
+	tr := &mockTransport{opts: mockOptions{FailureRate: 0.25, FailureStatus: http.StatusTooManyRequests, OutputTokens: 1}}
+	var failures []int
+	for i := 1; i <= 8; i++ {
+		req := httptest.NewRequest(http.MethodPost, mockUpstreamBase+"/v1/messages", strings.NewReader(`{"model":"m"}`))
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip失败: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			failures = append(failures, i)
+		}
+	}
+	if len(failures) != 2 || failures[0] != 4 || failures[1] != 8 {
+		t.Fatalf("失败应确定性地落在第4、8个请求: %v", failures)
+	}
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 全局模型别名（2026-10新增）
+// ============================================================================
+// 渠道级模型重定向只在选中某个渠道后生效；全局别名在选路前生效：
+//   - 请求体中的 model 命中启用的别名时改写为目标模型，随后屏蔽/允许列表检查与选路均按目标模型进行
+//   - 响应头 X-CCLoad-Model-Alias: <别名> -> <目标模型>
+//
+// 别名精确匹配、只解析一跳（目标不能是另一个别名，避免链式与循环）；模型在URL路径中的请求（Gemini原生）不改写。
+// 别名随渠道缓存（ChannelCache）一起加载，管理接口修改后立即失效缓存。
+
+const headerCCLoadModelAlias = "X-CCLoad-Model-Alias"
+
+// resolveModelAlias 查询模型别名（缓存优先），未命中返回 ("", false)
+func (s *Server) resolveModelAlias(ctx context.Context, name string) (string, bool) {
+	if cache := s.getChannelCache(); cache != nil {
+		return cache.ResolveModelAlias(ctx, name)
+	}
+	if s.store == nil {
+		return "", false
+	}
+	aliases, err := s.store.ListModelAliases(ctx)
+	if err != nil {
+		return "", false
+	}
+	for _, a := range aliases {
+		if a.Enabled && a.Alias == name {
+			return a.Target, true
+		}
+	}
+	return "", false
+}
+
+// rewriteRequestModel 将请求体中的 model 从 from 替换为 to（模型不在请求体中时返回 false）
+func rewriteRequestModel(body []byte, from, to string) ([]byte, bool) {
+	var reqData map[string]any
+	if err := sonic.Unmarshal(body, &reqData); err != nil || reqData["model"] != from {
+		return nil, false
+	}
+	reqData["model"] = to
+	newBody, err := sonic.Marshal(reqData)
+	if err != nil {
+		return nil, false
+	}
+	return newBody, true
+}
+
+// HandleListModelAliases 模型别名列表
+// GET /admin/model-aliases
+func (s *Server) HandleListModelAliases(c *gin.Context) {
+	aliases, err := s.store.ListModelAliases(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, aliases)
+}
+
+// HandleCreateModelAlias 新增模型别名
+// POST /admin/model-aliases
+func (s *Server) HandleCreateModelAlias(c *gin.Context) {
+	alias, ok := s.bindModelAlias(c, 0)
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	alias.CreatedAt, alias.UpdatedAt = now, now
+	if err := s.store.CreateModelAlias(c.Request.Context(), alias); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.InvalidateChannelListCache()
+	RespondJSON(c, http.StatusCreated, alias)
+}
+
+// HandleUpdateModelAlias 更新模型别名
+// PUT /admin/model-aliases/:id
+func (s *Server) HandleUpdateModelAlias(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid alias id")
+		return
+	}
+	alias, ok := s.bindModelAlias(c, id)
+	if !ok {
+		return
+	}
+	alias.ID = id
+	alias.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateModelAlias(c.Request.Context(), alias)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "alias not found")
+		return
+	}
+	s.InvalidateChannelListCache()
+	RespondJSON(c, http.StatusOK, alias)
+}
+
+// HandleDeleteModelAlias 删除模型别名
+// DELETE /admin/model-aliases/:id
+func (s *Server) HandleDeleteModelAlias(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid alias id")
+		return
+	}
+	found, err := s.store.DeleteModelAlias(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "alias not found")
+		return
+	}
+	s.InvalidateChannelListCache()
+	RespondJSON(c, http.StatusOK, gin.H{"id": id})
+}
+
+// bindModelAlias 解析并校验别名请求（selfID 为更新时的别名ID，创建时为0）
+func (s *Server) bindModelAlias(c *gin.Context, selfID int64) (*model.ModelAlias, bool) {
+	var req ModelAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return nil, false
+	}
+	alias := req.ToModelAlias()
+	if err := validateModelAlias(alias); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	existing, err := s.store.ListModelAliases(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	for _, other := range existing {
+		if other.ID == selfID {
+			continue
+		}
+		switch {
+		case other.Alias == alias.Alias:
+			RespondErrorMsg(c, http.StatusConflict, fmt.Sprintf("alias %q already exists", alias.Alias))
+			return nil, false
+		case other.Alias == alias.Target:
+			RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("target %q is itself an alias; point to its target %q instead", alias.Target, other.Target))
+			return nil, false
+		case other.Target == alias.Alias:
+			RespondErrorMsg(c, http.StatusBadRequest, fmt.Sprintf("%q is the target of alias %q; aliases cannot be chained", alias.Alias, other.Alias))
+			return nil, false
+		}
+	}
+	return alias, true
+}
+
+// validateModelAlias 别名与目标必须是不同的具体模型名（不支持通配符）
+func validateModelAlias(a *model.ModelAlias) error {
+	if a.Alias == "" || a.Target == "" {
+		return fmt.Errorf("alias and target are required")
+	}
+	if len(a.Alias) > 191 || len(a.Target) > 191 {
+		return fmt.Errorf("alias and target must be at most 191 characters")
+	}
+	if model.IsModelPattern(a.Alias) || model.IsModelPattern(a.Target) {
+		return fmt.Errorf("alias and target must be concrete model names (wildcards are not supported)")
+	}
+	if strings.EqualFold(a.Alias, a.Target) {
+		return fmt.Errorf("alias and target must differ")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestModelAliases_CRUDAndRouting(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "alias.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "demo", URL: "mock://local", ChannelType: "mock", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	call := func(handler gin.HandlerFunc, method, path, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		handler(c)
+		return w
+	}
+	proxy := func(modelName string) *httptest.ResponseRecorder {
+		return call(srv.HandleProxyRequest, http.MethodPost, "/v1/messages",
+			`{"model":"`+modelName+`","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`, nil)
+	}
+
+	// 首次请求加载渠道缓存（此时尚无别名）
+	if w := proxy("gpt-4o"); w.Code == http.StatusOK {
+		t.Fatalf("未配置别名时 gpt-4o 不应有可用渠道: %d", w.Code)
+	}
+
+	for body, want := range map[string]int{
+		`{"alias":"gpt-4o","target":"gpt-4o"}`:  http.StatusBadRequest,
+		`{"alias":"gpt-*","target":"claude-x"}`: http.StatusBadRequest,
+		`{"alias":"gpt-4o"}`:                    http.StatusBadRequest,
+	} {
+		if w := call(srv.HandleCreateModelAlias, http.MethodPost, "/admin/model-aliases", body, nil); w.Code != want {
+			t.Fatalf("%s: 期望 %d，实际 %d %s", body, want, w.Code, w.Body.String())
+		}
+	}
+	w := call(srv.HandleCreateModelAlias, http.MethodPost, "/admin/model-aliases",
+		`{"alias":"gpt-4o","target":"claude-sonnet-4-5","description":"迁移"}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建别名失败: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data model.ModelAlias `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if w := call(srv.HandleCreateModelAlias, http.MethodPost, "/admin/model-aliases",
+		`{"alias":"gpt-4o","target":"claude-opus-4-1"}`, nil); w.Code != http.StatusConflict {
+		t.Fatalf("重复别名应返回409: %d", w.Code)
+	}
+	if w := call(srv.HandleCreateModelAlias, http.MethodPost, "/admin/model-aliases",
+		`{"alias":"gpt-5","target":"gpt-4o"}`, nil); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "itself an alias") {
+		t.Fatalf("链式别名应被拒绝: %d %s", w.Code, w.Body.String())
+	}
+
+	// 别名修改后立即失效渠道缓存，请求按目标模型选路
+	w = proxy("gpt-4o")
+	if w.Code != http.StatusOK || w.Header().Get(headerCCLoadModelAlias) != "gpt-4o -> claude-sonnet-4-5" ||
+		!strings.Contains(w.Body.String(), `"model":"claude-sonnet-4-5"`) {
+		t.Fatalf("别名未生效: %d %q %s", w.Code, w.Header().Get(headerCCLoadModelAlias), w.Body.String())
+	}
+	if w := proxy("claude-sonnet-4-5"); w.Code != http.StatusOK || w.Header().Get(headerCCLoadModelAlias) != "" {
+		t.Fatalf("非别名请求不应改写: %d %q", w.Code, w.Header().Get(headerCCLoadModelAlias))
+	}
+
+	id := gin.Params{{Key: "id", Value: strconv.FormatInt(created.Data.ID, 10)}}
+	if w := call(srv.HandleUpdateModelAlias, http.MethodPut, "/admin/model-aliases/x",
+		`{"alias":"gpt-4o","target":"claude-sonnet-4-5","enabled":false}`, id); w.Code != http.StatusOK {
+		t.Fatalf("更新别名失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := proxy("gpt-4o"); w.Code == http.StatusOK || w.Header().Get(headerCCLoadModelAlias) != "" {
+		t.Fatalf("禁用的别名不应生效: %d", w.Code)
+	}
+
+	if w := call(srv.HandleDeleteModelAlias, http.MethodDelete, "/admin/model-aliases/x", "", id); w.Code != http.StatusOK {
+		t.Fatalf("删除别名失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(srv.HandleDeleteModelAlias, http.MethodDelete, "/admin/model-aliases/x", "", id); w.Code != http.StatusNotFound {
+		t.Fatalf("重复删除应返回404: %d", w.Code)
+	}
+	if list, err := store.ListModelAliases(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("别名应已删除: %+v err=%v", list, err)
+	}
+}
@@ -0,0 +1,424 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 渠道按模型并发上限（2026-10新增）
+// ============================================================================
+// 部分上游套餐限制同时在途请求数（如 opus 最多2个并发）。每个渠道可配置：
+//   {"channel_max":8,"limits":[{"model":"claude-opus-*","max":2}],"mode":"queue","queue_timeout_ms":5000}
+// - channel_max：渠道总并发上限（0 表示不限），与模型上限同时生效
+// - limits：按实际模型（重定向后）匹配（支持 * 通配），命中的每条上限都需有空闲槽位
+// - mode：槽位已满时的行为
+//     spillover（默认）直接尝试下一个候选渠道（不触发冷却、不计失败）
+//     queue            在本渠道排队等待空闲槽位，超过 queue_timeout_ms 后再溢出到下一个渠道
+// 在途计数仅保存在本进程内存中，多实例部署时各实例独立计数。
+
+const (
+	concurrencyModeSpillover = "spillover"
+	concurrencyModeQueue     = "queue"
+
+	maxConcurrencyLimits        = 32
+	maxConcurrencyLimitsJSON    = 1024 // 与 channels.model_concurrency 列宽一致
+	maxConcurrencySlots         = 10000
+	defaultConcurrencyQueueWait = 10 * time.Second
+	maxConcurrencyQueueWait     = 60 * time.Second
+)
+
+// ErrModelConcurrencyFull 表示渠道（或其模型）并发槽位已满，应尝试下一个渠道
+var ErrModelConcurrencyFull = errors.New("channel model concurrency limit reached")
+
+// modelConcurrencyLimit 单条模型并发上限
+type modelConcurrencyLimit struct {
+	Model string `json:"model"` // 实际模型匹配（支持 * 通配）
+	Max   int    `json:"max"`
+}
+
+// modelConcurrencyConfig 渠道并发配置（channels.model_concurrency 存储格式）
+type modelConcurrencyConfig struct {
+	ChannelMax     int                     `json:"channel_max,omitempty"`
+	Limits         []modelConcurrencyLimit `json:"limits,omitempty"`
+	Mode           string                  `json:"mode,omitempty"`
+	QueueTimeoutMs int                     `json:"queue_timeout_ms,omitempty"`
+}
+
+// validate 校验并规范化配置
+func (c *modelConcurrencyConfig) validate() error {
+	if c.ChannelMax < 0 || c.ChannelMax > maxConcurrencySlots {
+		return fmt.Errorf("channel_max must be between 0 and %d", maxConcurrencySlots)
+	}
+	if len(c.Limits) > maxConcurrencyLimits {
+		return fmt.Errorf("too many limits (max %d)", maxConcurrencyLimits)
+	}
+	seen := make(map[string]bool, len(c.Limits))
+	for i := range c.Limits {
+		l := &c.Limits[i]
+		l.Model = strings.TrimSpace(l.Model)
+		if l.Model == "" {
+			return fmt.Errorf("limits[%d]: model is required", i)
+		}
+		if seen[l.Model] {
+			return fmt.Errorf("limits[%d]: duplicate model %q", i, l.Model)
+		}
+		seen[l.Model] = true
+		if l.Max < 1 || l.Max > maxConcurrencySlots {
+			return fmt.Errorf("limits[%d]: max must be between 1 and %d", i, maxConcurrencySlots)
+		}
+	}
+	c.Mode = strings.ToLower(strings.TrimSpace(c.Mode))
+	switch c.Mode {
+	case "", concurrencyModeSpillover:
+		c.Mode = concurrencyModeSpillover
+		c.QueueTimeoutMs = 0
+	case concurrencyModeQueue:
+		if c.QueueTimeoutMs < 0 || time.Duration(c.QueueTimeoutMs)*time.Millisecond > maxConcurrencyQueueWait {
+			return fmt.Errorf("queue_timeout_ms must be between 0 and %d", maxConcurrencyQueueWait.Milliseconds())
+		}
+	default:
+		return fmt.Errorf("unsupported mode %q", c.Mode)
+	}
+	return nil
+}
+
+// queueWait 排队模式下的最长等待时间（未配置时使用默认值）
+func (c *modelConcurrencyConfig) queueWait() time.Duration {
+	if c.QueueTimeoutMs <= 0 {
+		return defaultConcurrencyQueueWait
+	}
+	return time.Duration(c.QueueTimeoutMs) * time.Millisecond
+}
+
+// normalizeModelConcurrency 校验配置并序列化为存储格式（无任何上限时返回空串）
+func normalizeModelConcurrency(cfg modelConcurrencyConfig) (string, error) {
+	if err := cfg.validate(); err != nil {
+		return "", err
+	}
+	if cfg.ChannelMax == 0 && len(cfg.Limits) == 0 {
+		return "", nil
+	}
+	data, err := sonic.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxConcurrencyLimitsJSON {
+		return "", fmt.Errorf("limits too large (max %d bytes)", maxConcurrencyLimitsJSON)
+	}
+	return string(data), nil
+}
+
+// parseModelConcurrency 解析已存储的配置（存储前已校验，解析失败视为不限制）
+func parseModelConcurrency(raw string) *modelConcurrencyConfig {
+	if raw == "" {
+		return nil
+	}
+	var cfg modelConcurrencyConfig
+	if err := sonic.UnmarshalString(raw, &cfg); err != nil {
+		return nil
+	}
+	return &cfg
+}
+
+// ============================================================================
+// 在途计数
+// ============================================================================
+
+// concurrencySlotKey 并发槽位（limit 为空表示渠道总上限）
+type concurrencySlotKey struct {
+	channelID int64
+	limit     string
+}
+
+// concurrencyModelKey 按实际模型的在途计数
+type concurrencyModelKey struct {
+	channelID int64
+	model     string
+}
+
+// concurrencyCap 单次获取需满足的上限
+type concurrencyCap struct {
+	key concurrencySlotKey
+	max int
+}
+
+// modelConcurrencyTracker 进程内在途请求计数（所有渠道均计数，仅配置了上限的渠道会被限制）
+type modelConcurrencyTracker struct {
+	mu      sync.Mutex
+	slots   map[concurrencySlotKey]int
+	models  map[concurrencyModelKey]int
+	waiters int
+	wake    chan struct{} // 有排队者时，每次释放关闭并替换，唤醒全部排队者重新检查
+}
+
+func newModelConcurrencyTracker() *modelConcurrencyTracker {
+	return &modelConcurrencyTracker{
+		slots:  make(map[concurrencySlotKey]int),
+		models: make(map[concurrencyModelKey]int),
+		wake:   make(chan struct{}),
+	}
+}
+
+// capsFor 计算请求需要满足的上限列表
+func capsFor(channelID int64, cfg *modelConcurrencyConfig, actualModel string) []concurrencyCap {
+	if cfg == nil {
+		return nil
+	}
+	var caps []concurrencyCap
+	if cfg.ChannelMax > 0 {
+		caps = append(caps, concurrencyCap{key: concurrencySlotKey{channelID: channelID}, max: cfg.ChannelMax})
+	}
+	for _, l := range cfg.Limits {
+		if model.MatchModelPattern(l.Model, actualModel) {
+			caps = append(caps, concurrencyCap{key: concurrencySlotKey{channelID: channelID, limit: l.Model}, max: l.Max})
+		}
+	}
+	return caps
+}
+
+// tryAcquireLocked 所有上限均有空闲槽位时占用槽位；返回释放函数（nil 表示已满）
+func (t *modelConcurrencyTracker) tryAcquireLocked(channelID int64, actualModel string, caps []concurrencyCap) func() {
+	for _, c := range caps {
+		if t.slots[c.key] >= c.max {
+			return nil
+		}
+	}
+	// 渠道总计数始终维护（用于管理接口展示），即使未配置 channel_max
+	channelKey := concurrencySlotKey{channelID: channelID}
+	t.slots[channelKey]++
+	for _, c := range caps {
+		if c.key != channelKey {
+			t.slots[c.key]++
+		}
+	}
+	mk := concurrencyModelKey{channelID: channelID, model: actualModel}
+	t.models[mk]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.release(channelKey, caps, mk) })
+	}
+}
+
+func (t *modelConcurrencyTracker) release(channelKey concurrencySlotKey, caps []concurrencyCap, mk concurrencyModelKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.decrLocked(channelKey)
+	for _, c := range caps {
+		if c.key != channelKey {
+			t.decrLocked(c.key)
+		}
+	}
+	if t.models[mk] <= 1 {
+		delete(t.models, mk)
+	} else {
+		t.models[mk]--
+	}
+	if t.waiters > 0 {
+		close(t.wake)
+		t.wake = make(chan struct{})
+	}
+}
+
+func (t *modelConcurrencyTracker) decrLocked(k concurrencySlotKey) {
+	if t.slots[k] <= 1 {
+		delete(t.slots, k)
+	} else {
+		t.slots[k]--
+	}
+}
+
+// acquire 获取渠道并发槽位；ok=false 表示槽位已满（排队超时）或 ctx 已结束
+func (t *modelConcurrencyTracker) acquire(ctx context.Context, cfg *model.Config, actualModel string) (release func(), ok bool) {
+	if t == nil {
+		return func() {}, true
+	}
+	limits := parseModelConcurrency(cfg.ModelConcurrency)
+	caps := capsFor(cfg.ID, limits, actualModel)
+	queue := limits != nil && limits.Mode == concurrencyModeQueue
+
+	var timer *time.Timer
+	for {
+		// 检查与登记排队在同一临界区内完成，避免错过两者之间发生的释放
+		t.mu.Lock()
+		if release := t.tryAcquireLocked(cfg.ID, actualModel, caps); release != nil {
+			t.mu.Unlock()
+			return release, true
+		}
+		if !queue {
+			t.mu.Unlock()
+			return nil, false
+		}
+		t.waiters++
+		wake := t.wake
+		t.mu.Unlock()
+
+		if timer == nil {
+			timer = time.NewTimer(limits.queueWait())
+			defer timer.Stop()
+		}
+
+		var done bool
+		select {
+		case <-wake:
+		case <-timer.C:
+			done = true
+		case <-ctx.Done():
+			done = true
+		}
+
+		t.mu.Lock()
+		t.waiters--
+		t.mu.Unlock()
+		if done {
+			return nil, false
+		}
+	}
+}
+
+// channelInFlight 渠道在途请求快照
+type channelInFlight struct {
+	ChannelID int64          `json:"channel_id"`
+	InFlight  int            `json:"in_flight"`
+	Models    map[string]int `json:"models"`
+	Limits    map[string]int `json:"limits,omitempty"` // 按上限规则（model 模式）的在途数
+}
+
+// snapshot 返回在途请求快照（channelID=0 表示全部渠道，按渠道ID排序）
+func (t *modelConcurrencyTracker) snapshot(channelID int64) []channelInFlight {
+	if t == nil {
+		return []channelInFlight{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	byChannel := make(map[int64]*channelInFlight)
+	get := func(id int64) *channelInFlight {
+		item, ok := byChannel[id]
+		if !ok {
+			item = &channelInFlight{ChannelID: id, Models: map[string]int{}}
+			byChannel[id] = item
+		}
+		return item
+	}
+	for k, n := range t.slots {
+		if channelID != 0 && k.channelID != channelID {
+			continue
+		}
+		item := get(k.channelID)
+		if k.limit == "" {
+			item.InFlight = n
+			continue
+		}
+		if item.Limits == nil {
+			item.Limits = map[string]int{}
+		}
+		item.Limits[k.limit] = n
+	}
+	for k, n := range t.models {
+		if channelID != 0 && k.channelID != channelID {
+			continue
+		}
+		get(k.channelID).Models[k.model] = n
+	}
+
+	out := make([]channelInFlight, 0, len(byChannel))
+	for _, item := range byChannel {
+		out = append(out, *item)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ChannelID < out[j].ChannelID })
+	return out
+}
+
+// ============================================================================
+// Admin API
+// ============================================================================
+
+// HandleGetModelConcurrency 获取所有渠道的在途请求数（按模型）
+// GET /admin/concurrency
+func (s *Server) HandleGetModelConcurrency(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, gin.H{"channels": s.modelConcurrency.snapshot(0)})
+}
+
+// HandleGetChannelConcurrency 获取渠道并发上限配置与在途请求数
+// GET /admin/channels/:id/concurrency
+func (s *Server) HandleGetChannelConcurrency(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	cfg, err := s.store.GetConfig(c.Request.Context(), channelID)
+	if err != nil {
+		RespondError(c, http.StatusNotFound, err)
+		return
+	}
+	s.respondChannelConcurrency(c, channelID, cfg.ModelConcurrency)
+}
+
+// HandleSetChannelConcurrency 替换渠道并发上限配置（无任何上限表示清除）
+// PUT /admin/channels/:id/concurrency
+func (s *Server) HandleSetChannelConcurrency(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	var req modelConcurrencyConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+	raw, err := normalizeModelConcurrency(req)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.saveChannelConcurrency(c, channelID, raw)
+}
+
+// HandleDeleteChannelConcurrency 清除渠道并发上限配置
+// DELETE /admin/channels/:id/concurrency
+func (s *Server) HandleDeleteChannelConcurrency(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	s.saveChannelConcurrency(c, channelID, "")
+}
+
+func (s *Server) saveChannelConcurrency(c *gin.Context, channelID int64, raw string) {
+	if err := s.store.SetChannelModelConcurrency(c.Request.Context(), channelID, raw); err != nil {
+		RespondError(c, http.StatusNotFound, err)
+		return
+	}
+	s.InvalidateChannelListCache()
+	s.respondChannelConcurrency(c, channelID, raw)
+}
+
+func (s *Server) respondChannelConcurrency(c *gin.Context, channelID int64, raw string) {
+	cfg := parseModelConcurrency(raw)
+	if cfg == nil {
+		cfg = &modelConcurrencyConfig{Mode: concurrencyModeSpillover}
+	}
+	if cfg.Limits == nil {
+		cfg.Limits = []modelConcurrencyLimit{}
+	}
+	live := channelInFlight{ChannelID: channelID, Models: map[string]int{}}
+	if snap := s.modelConcurrency.snapshot(channelID); len(snap) > 0 {
+		live = snap[0]
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "config": cfg, "in_flight": live})
+}
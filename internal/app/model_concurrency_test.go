@@ -0,0 +1,172 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalizeModelConcurrency(t *testing.T) {
+	for _, bad := range []modelConcurrencyConfig{
+		{Limits: []modelConcurrencyLimit{{Model: "", Max: 1}}},
+		{Limits: []modelConcurrencyLimit{{Model: "claude-opus-*", Max: 0}}},
+		{Limits: []modelConcurrencyLimit{{Model: "a", Max: 1}, {Model: "a", Max: 2}}},
+		{ChannelMax: -1},
+		{ChannelMax: 1, Mode: "drop"},
+		{ChannelMax: 1, Mode: "queue", QueueTimeoutMs: 120000},
+	} {
+		if _, err := normalizeModelConcurrency(bad); err == nil {
+			t.Fatalf("expected validation error for %+v", bad)
+		}
+	}
+	if raw, err := normalizeModelConcurrency(modelConcurrencyConfig{Mode: "queue"}); err != nil || raw != "" {
+		t.Fatalf("config without limits should normalize to empty, got %q %v", raw, err)
+	}
+	raw, err := normalizeModelConcurrency(modelConcurrencyConfig{Limits: []modelConcurrencyLimit{{Model: " claude-opus-* ", Max: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := parseModelConcurrency(raw)
+	if cfg == nil || cfg.Mode != concurrencyModeSpillover || cfg.Limits[0].Model != "claude-opus-*" {
+		t.Fatalf("unexpected normalized config %s", raw)
+	}
+}
+
+func TestModelConcurrencyTracker(t *testing.T) {
+	ctx := context.Background()
+	tr := newModelConcurrencyTracker()
+	raw, _ := normalizeModelConcurrency(modelConcurrencyConfig{ChannelMax: 3, Limits: []modelConcurrencyLimit{{Model: "claude-opus-*", Max: 1}}})
+	cfg := &model.Config{ID: 7, ModelConcurrency: raw}
+
+	releaseOpus, ok := tr.acquire(ctx, cfg, "claude-opus-4")
+	if !ok {
+		t.Fatal("first opus request should get a slot")
+	}
+	if _, ok := tr.acquire(ctx, cfg, "claude-opus-4-1"); ok {
+		t.Fatal("second opus request should spill over")
+	}
+	releaseA, ok1 := tr.acquire(ctx, cfg, "claude-sonnet-4")
+	releaseB, ok2 := tr.acquire(ctx, cfg, "claude-sonnet-4")
+	if !ok1 || !ok2 {
+		t.Fatal("unlimited model should only be bound by channel_max")
+	}
+	if _, ok := tr.acquire(ctx, cfg, "claude-sonnet-4"); ok {
+		t.Fatal("channel_max should cap all models")
+	}
+
+	snap := tr.snapshot(7)
+	if len(snap) != 1 || snap[0].InFlight != 3 || snap[0].Models["claude-sonnet-4"] != 2 || snap[0].Limits["claude-opus-*"] != 1 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+
+	releaseOpus()
+	releaseOpus() // 重复释放无副作用
+	releaseA()
+	releaseB()
+	if snap := tr.snapshot(0); len(snap) != 0 {
+		t.Fatalf("all slots should be released, got %+v", snap)
+	}
+
+	// 未配置上限的渠道也计数但不限制
+	other := &model.Config{ID: 8}
+	for range 5 {
+		if _, ok := tr.acquire(ctx, other, "m"); !ok {
+			t.Fatal("channel without limits must never be limited")
+		}
+	}
+	if snap := tr.snapshot(8); snap[0].InFlight != 5 || snap[0].Models["m"] != 5 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+}
+
+func TestModelConcurrencyQueue(t *testing.T) {
+	ctx := context.Background()
+	tr := newModelConcurrencyTracker()
+	raw, _ := normalizeModelConcurrency(modelConcurrencyConfig{Limits: []modelConcurrencyLimit{{Model: "*", Max: 1}}, Mode: "queue", QueueTimeoutMs: 2000})
+	cfg := &model.Config{ID: 1, ModelConcurrency: raw}
+
+	release, _ := tr.acquire(ctx, cfg, "m")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+	start := time.Now()
+	release2, ok := tr.acquire(ctx, cfg, "m")
+	if !ok || time.Since(start) > time.Second {
+		t.Fatalf("queued request should get the released slot, ok=%v waited=%v", ok, time.Since(start))
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, ok := tr.acquire(canceled, cfg, "m"); ok {
+		t.Fatal("queued request should give up when the context ends")
+	}
+	release2()
+}
+
+func TestHandleChannelConcurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	server.modelConcurrency = newModelConcurrencyTracker()
+
+	cfg, err := store.CreateConfig(context.Background(), &model.Config{Name: "c", URL: "https://api.example.com", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-opus-4"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/admin/channels/:id/concurrency", server.HandleGetChannelConcurrency)
+	r.PUT("/admin/channels/:id/concurrency", server.HandleSetChannelConcurrency)
+	r.DELETE("/admin/channels/:id/concurrency", server.HandleDeleteChannelConcurrency)
+	r.GET("/admin/concurrency", server.HandleGetModelConcurrency)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	path := "/admin/channels/" + strconv.FormatInt(cfg.ID, 10) + "/concurrency"
+
+	if w := do(http.MethodPut, path, `{"limits":[{"model":"x","max":0}]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid limit should be rejected, got %d", w.Code)
+	}
+	w := do(http.MethodPut, path, `{"limits":[{"model":"claude-opus-*","max":2}],"mode":"queue"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"mode":"queue"`) {
+		t.Fatalf("set limits: %d %s", w.Code, w.Body.String())
+	}
+	stored, _ := store.GetConfig(context.Background(), cfg.ID)
+	release, ok := server.modelConcurrency.acquire(context.Background(), stored, "claude-opus-4")
+	if !ok {
+		t.Fatal("acquire should succeed below the limit")
+	}
+	defer release()
+
+	if w := do(http.MethodGet, path, ""); !strings.Contains(w.Body.String(), `"claude-opus-*":1`) || !strings.Contains(w.Body.String(), `"claude-opus-4":1`) {
+		t.Fatalf("channel view should include live counts: %s", w.Body.String())
+	}
+	if w := do(http.MethodGet, "/admin/concurrency", ""); !strings.Contains(w.Body.String(), `"in_flight":1`) {
+		t.Fatalf("overview should include live counts: %s", w.Body.String())
+	}
+
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete limits: %d", w.Code)
+	}
+	if stored, _ := store.GetConfig(context.Background(), cfg.ID); stored.ModelConcurrency != "" {
+		t.Fatalf("limits should be cleared, got %q", stored.ModelConcurrency)
+	}
+	if w := do(http.MethodPut, "/admin/channels/999/concurrency", `{"channel_max":1}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown channel should be 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 默认模型回退（2026-10新增）
+// ============================================================================
+// 请求模型未匹配任何启用渠道时原逻辑直接返回503。令牌配置 fallback_model 后改为：
+//   - 将请求体中的 model 替换为回退模型后重新选路（回退模型需对该令牌可用：未被屏蔽且在允许列表内）
+//   - 响应头 X-CCLoad-Model-Fallback: <请求模型> -> <回退模型>
+//
+// 仅"没有任何启用渠道配置该模型"时回退；渠道冷却、费用超限等临时不可用仍按原逻辑返回503。
+// 无论是否回退，未匹配的请求模型都会计数（仅内存，重启清空），GET /admin/unmatched-models 查看，
+// 用于发现客户端在请求哪些未提供的模型；DELETE 同路径清空计数。
+
+const (
+	headerCCLoadModelFallback = "X-CCLoad-Model-Fallback"
+
+	// maxUnmatchedModels 计数的模型名上限（模型名来自客户端，超出时淘汰最久未出现的）
+	maxUnmatchedModels = 500
+)
+
+// UnmatchedModelStat 未匹配任何渠道的请求模型计数
+type UnmatchedModelStat struct {
+	Model         string `json:"model"`
+	Requests      int64  `json:"requests"`                 // 未匹配次数（含回退）
+	FallbackCount int64  `json:"fallback_count"`           // 其中按令牌策略回退的次数
+	FallbackModel string `json:"fallback_model,omitempty"` // 最近一次回退到的模型
+	FirstSeen     int64  `json:"first_seen"`               // Unix毫秒
+	LastSeen      int64  `json:"last_seen"`                // Unix毫秒
+}
+
+// unmatchedModelTracker 未匹配模型计数（nil 时所有方法为空操作）
+type unmatchedModelTracker struct {
+	mu    sync.Mutex
+	stats map[string]*UnmatchedModelStat
+}
+
+func newUnmatchedModelTracker() *unmatchedModelTracker {
+	return &unmatchedModelTracker{stats: make(map[string]*UnmatchedModelStat)}
+}
+
+// record 记录一次未匹配；fallback 为空表示未回退
+func (t *unmatchedModelTracker) record(modelName, fallback string, now time.Time) {
+	if t == nil {
+		return
+	}
+	nowMs := now.UnixMilli()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.stats[modelName]
+	if !ok {
+		if len(t.stats) >= maxUnmatchedModels {
+			t.evictOldestLocked()
+		}
+		st = &UnmatchedModelStat{Model: modelName, FirstSeen: nowMs}
+		t.stats[modelName] = st
+	}
+	st.Requests++
+	st.LastSeen = nowMs
+	if fallback != "" {
+		st.FallbackCount++
+		st.FallbackModel = fallback
+	}
+}
+
+func (t *unmatchedModelTracker) evictOldestLocked() {
+	var oldest *UnmatchedModelStat
+	for _, st := range t.stats {
+		if oldest == nil || st.LastSeen < oldest.LastSeen {
+			oldest = st
+		}
+	}
+	if oldest != nil {
+		delete(t.stats, oldest.Model)
+	}
+}
+
+// list 按未匹配次数倒序返回
+func (t *unmatchedModelTracker) list() []UnmatchedModelStat {
+	out := []UnmatchedModelStat{}
+	if t == nil {
+		return out
+	}
+	t.mu.Lock()
+	for _, st := range t.stats {
+		out = append(out, *st)
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// clear 清空计数，返回清除的模型数
+func (t *unmatchedModelTracker) clear() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(t.stats)
+	t.stats = make(map[string]*UnmatchedModelStat)
+	return n
+}
+
+// resolveModelFallback 请求模型未匹配任何启用渠道时，返回令牌的回退模型与替换 model 后的请求体
+// 调用方仅在选路结果为空时调用；模型有渠道配置（只是暂不可用）时返回 ok=false 且不计数
+func (s *Server) resolveModelFallback(ctx context.Context, requestMethod, requestPath, tokenHash, originalModel string, body []byte) (fallback string, newBody []byte, ok bool) {
+	if originalModel == "" || originalModel == "*" {
+		return "", nil, false
+	}
+	channelType := util.DetectChannelTypeFromPath(requestPath)
+	if channelType == "" {
+		return "", nil, false
+	}
+	carried, err := s.scanEnabledChannelsForModel(ctx, originalModel, channelType)
+	if err == nil && !compatChannelsAllowed(channelType, requestMethod, requestPath) {
+		carried = filterExactChannelType(carried, channelType)
+	}
+	if err != nil || len(carried) > 0 {
+		return "", nil, false
+	}
+
+	now := time.Now()
+	if tokenHash != "" && s.authService != nil {
+		fallback = s.authService.FallbackModel(tokenHash)
+	}
+	if fallback == "" || strings.EqualFold(fallback, originalModel) || !s.isModelUsable(tokenHash, fallback) {
+		s.unmatchedModels.record(originalModel, "", now)
+		return "", nil, false
+	}
+	// 仅替换请求体中的模型名（模型在URL路径中的请求无法改写，按原逻辑处理）
+	newBody, ok = rewriteRequestModel(body, originalModel, fallback)
+	if !ok {
+		s.unmatchedModels.record(originalModel, "", now)
+		return "", nil, false
+	}
+	s.unmatchedModels.record(originalModel, fallback, now)
+	return fallback, newBody, true
+}
+
+// HandleListUnmatchedModels 未匹配任何渠道的请求模型计数
+// GET /admin/unmatched-models
+func (s *Server) HandleListUnmatchedModels(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, s.unmatchedModels.list())
+}
+
+// HandleClearUnmatchedModels 清空未匹配模型计数
+// DELETE /admin/unmatched-models
+func (s *Server) HandleClearUnmatchedModels(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, gin.H{"cleared": s.unmatchedModels.clear()})
+}
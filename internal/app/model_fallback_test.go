@@ -0,0 +1,118 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleProxyRequest_ModelFallback(t *testing.T) {
+	var mu sync.Mutex
+	var upstreamBodies []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		upstreamBodies = append(upstreamBodies, string(body))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "fallback.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "sonnet", URL: upstream.URL, ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	withFallback := &model.AuthToken{Token: "hash-fallback", Description: "f", CreatedAt: time.Now(), IsActive: true, FallbackModel: "claude-sonnet-4-5"}
+	noFallback := &model.AuthToken{Token: "hash-plain", Description: "p", CreatedAt: time.Now(), IsActive: true}
+	for _, tok := range []*model.AuthToken{withFallback, noFallback} {
+		if err := store.CreateAuthToken(ctx, tok); err != nil {
+			t.Fatalf("创建令牌失败: %v", err)
+		}
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	proxy := func(tokenHash, modelName string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+			bytes.NewBufferString(`{"model":"`+modelName+`","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("token_hash", tokenHash)
+		srv.HandleProxyRequest(c)
+		return w
+	}
+
+	// 配置回退的令牌：未提供的模型改用默认模型，并在响应头注明
+	w := proxy(withFallback.Token, "gpt-unknown")
+	if w.Code != http.StatusOK {
+		t.Fatalf("回退后应成功，实际 %d %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(headerCCLoadModelFallback); got != "gpt-unknown -> claude-sonnet-4-5" {
+		t.Fatalf("回退响应头不符: %q", got)
+	}
+	mu.Lock()
+	if len(upstreamBodies) != 1 || !bytes.Contains([]byte(upstreamBodies[0]), []byte(`"model":"claude-sonnet-4-5"`)) {
+		t.Fatalf("上游应收到回退模型: %v", upstreamBodies)
+	}
+	mu.Unlock()
+
+	// 已提供的模型不回退；未配置回退的令牌按原逻辑拒绝
+	if w := proxy(withFallback.Token, "claude-sonnet-4-5"); w.Code != http.StatusOK || w.Header().Get(headerCCLoadModelFallback) != "" {
+		t.Fatalf("已提供的模型不应回退: %d %q", w.Code, w.Header().Get(headerCCLoadModelFallback))
+	}
+	if w := proxy(noFallback.Token, "gpt-unknown"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("未配置回退应返回503，实际 %d", w.Code)
+	}
+
+	stats := srv.unmatchedModels.list()
+	if len(stats) != 1 || stats[0].Model != "gpt-unknown" || stats[0].Requests != 2 || stats[0].FallbackCount != 1 ||
+		stats[0].FallbackModel != "claude-sonnet-4-5" {
+		t.Fatalf("未匹配模型计数不符: %+v", stats)
+	}
+}
+
+func TestUnmatchedModelTracker_EvictsOldest(t *testing.T) {
+	tr := newUnmatchedModelTracker()
+	base := time.Now()
+	for i := 0; i <= maxUnmatchedModels; i++ {
+		tr.record(string(rune('a'+i%26))+time.Duration(i).String(), "", base.Add(time.Duration(i)*time.Millisecond))
+	}
+	stats := tr.list()
+	if len(stats) != maxUnmatchedModels {
+		t.Fatalf("应限制为 %d 个模型，实际 %d", maxUnmatchedModels, len(stats))
+	}
+	for _, st := range stats {
+		if st.Model == "a0s" {
+			t.Fatal("最久未出现的模型应被淘汰")
+		}
+	}
+	if n := tr.clear(); n != maxUnmatchedModels || len(tr.list()) != 0 {
+		t.Fatalf("清空失败: %d", n)
+	}
+}
@@ -0,0 +1,186 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 重定向模型不存在处理（2026-10新增）
+// ============================================================================
+// 渠道把请求模型重定向到上游模型后，上游返回 model_not_found 说明渠道模型配置已过期。
+// 原处理是按客户端错误直接返回，同一配置会被反复请求。现改为：
+//   - 临时标记该 渠道+请求模型 不可用（modelNotFoundFlagTTL 内选路跳过该渠道，不触发冷却）
+//   - 本次请求切换到其他渠道重试
+//   - 发出管理端告警：[ALERT] 日志 + model_unsupported 事件 + 健康检查 degraded
+// 修改渠道配置（编辑/删除模型）时清除该渠道的标记；GET/DELETE /admin/model-flags 查看/手动清除。
+
+const modelNotFoundFlagTTL = 30 * time.Minute
+
+// ModelNotFoundFlag 渠道模型不存在标记
+type ModelNotFoundFlag struct {
+	ChannelID     int64  `json:"channel_id"`
+	ChannelName   string `json:"channel_name"`
+	Model         string `json:"model"`          // 客户端请求的模型
+	RedirectModel string `json:"redirect_model"` // 重定向后发给上游的模型
+	StatusCode    int    `json:"status_code"`
+	FlaggedAt     int64  `json:"flagged_at"` // Unix毫秒
+	ExpiresAt     int64  `json:"expires_at"` // Unix毫秒
+}
+
+type modelFlagKey struct {
+	channelID int64
+	model     string
+}
+
+// modelNotFoundTracker 渠道模型不存在标记（仅内存，重启清空；nil 时所有方法为空操作）
+type modelNotFoundTracker struct {
+	mu    sync.RWMutex
+	flags map[modelFlagKey]*ModelNotFoundFlag
+}
+
+func newModelNotFoundTracker() *modelNotFoundTracker {
+	return &modelNotFoundTracker{flags: make(map[modelFlagKey]*ModelNotFoundFlag)}
+}
+
+// flag 记录标记，返回是否为新标记（已存在且未过期时仅延长有效期）
+func (t *modelNotFoundTracker) flag(f *ModelNotFoundFlag, now time.Time) bool {
+	if t == nil {
+		return false
+	}
+	key := modelFlagKey{f.ChannelID, f.Model}
+	f.ExpiresAt = now.Add(modelNotFoundFlagTTL).UnixMilli()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if old, ok := t.flags[key]; ok && old.ExpiresAt > now.UnixMilli() {
+		old.ExpiresAt = f.ExpiresAt
+		return false
+	}
+	f.FlaggedAt = now.UnixMilli()
+	t.flags[key] = f
+	return true
+}
+
+// isFlagged 渠道+模型是否处于标记期
+func (t *modelNotFoundTracker) isFlagged(channelID int64, modelName string, now time.Time) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.RLock()
+	f, ok := t.flags[modelFlagKey{channelID, modelName}]
+	t.mu.RUnlock()
+	return ok && f.ExpiresAt > now.UnixMilli()
+}
+
+// filter 剔除该模型已标记的渠道；全部被标记时原样返回（交由上游重新判定，避免标记把请求直接拒掉）
+func (t *modelNotFoundTracker) filter(modelName string, channels []*model.Config, now time.Time) []*model.Config {
+	if t == nil || len(channels) == 0 {
+		return channels
+	}
+	t.mu.RLock()
+	empty := len(t.flags) == 0
+	t.mu.RUnlock()
+	if empty {
+		return channels
+	}
+	kept := make([]*model.Config, 0, len(channels))
+	for _, cfg := range channels {
+		if !t.isFlagged(cfg.ID, modelName, now) {
+			kept = append(kept, cfg)
+		}
+	}
+	if len(kept) == 0 {
+		return channels
+	}
+	return kept
+}
+
+// clear 清除标记（channelID<=0 表示全部），返回清除数量
+func (t *modelNotFoundTracker) clear(channelID int64) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for key := range t.flags {
+		if channelID <= 0 || key.channelID == channelID {
+			delete(t.flags, key)
+			n++
+		}
+	}
+	return n
+}
+
+// list 返回未过期的标记（顺带清理过期项），按标记时间倒序
+func (t *modelNotFoundTracker) list(now time.Time) []ModelNotFoundFlag {
+	flags := []ModelNotFoundFlag{}
+	if t == nil {
+		return flags
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, f := range t.flags {
+		if f.ExpiresAt <= now.UnixMilli() {
+			delete(t.flags, key)
+			continue
+		}
+		flags = append(flags, *f)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].FlaggedAt > flags[j].FlaggedAt })
+	return flags
+}
+
+// handleRedirectedModelNotFound 重定向模型被上游判定不存在时标记并告警，返回是否应切换渠道重试
+func (s *Server) handleRedirectedModelNotFound(cfg *model.Config, reqCtx *proxyRequestContext, actualModel string, res *fwResult) bool {
+	if s.modelNotFound == nil || res == nil || reqCtx.originalModel == "" ||
+		actualModel == "" || actualModel == reqCtx.originalModel {
+		return false
+	}
+	if !util.IsModelNotFoundError(res.Status, res.Body) {
+		return false
+	}
+	f := &ModelNotFoundFlag{
+		ChannelID:     cfg.ID,
+		ChannelName:   cfg.Name,
+		Model:         reqCtx.originalModel,
+		RedirectModel: actualModel,
+		StatusCode:    res.Status,
+	}
+	if s.modelNotFound.flag(f, time.Now()) {
+		log.Printf("[WARN] [ALERT] [模型不存在] 渠道ID=%d(%s) 将 %s 重定向为 %s，上游返回 %d 模型不存在；%v 内跳过该渠道，请修正渠道模型配置",
+			cfg.ID, cfg.Name, f.Model, f.RedirectModel, f.StatusCode, modelNotFoundFlagTTL)
+		s.adminEvents.publish(AdminEventModelUnsupported, cfg.ID, *f)
+	}
+	return true
+}
+
+// HandleListModelFlags 渠道模型不存在标记列表
+// GET /admin/model-flags
+func (s *Server) HandleListModelFlags(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, s.modelNotFound.list(time.Now()))
+}
+
+// HandleClearModelFlags 清除渠道模型不存在标记
+// DELETE /admin/model-flags?channel_id=1（省略 channel_id 表示全部）
+func (s *Server) HandleClearModelFlags(c *gin.Context) {
+	var channelID int64
+	if v := c.Query("channel_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid channel_id")
+			return
+		}
+		channelID = id
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"cleared": s.modelNotFound.clear(channelID)})
+}
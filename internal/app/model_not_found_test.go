@@ -0,0 +1,131 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestModelNotFoundTracker(t *testing.T) {
+	tr := newModelNotFoundTracker()
+	now := time.Now()
+	a, b := &model.Config{ID: 1}, &model.Config{ID: 2}
+
+	if !tr.flag(&ModelNotFoundFlag{ChannelID: 1, Model: "m"}, now) {
+		t.Fatal("首次标记应返回 true")
+	}
+	if tr.flag(&ModelNotFoundFlag{ChannelID: 1, Model: "m"}, now.Add(time.Minute)) {
+		t.Fatal("重复标记不应再次告警")
+	}
+	if got := tr.filter("m", []*model.Config{a, b}, now); len(got) != 1 || got[0].ID != 2 {
+		t.Fatalf("应剔除已标记渠道: %v", got)
+	}
+	if got := tr.filter("other", []*model.Config{a, b}, now); len(got) != 2 {
+		t.Fatal("标记只作用于对应模型")
+	}
+	if got := tr.filter("m", []*model.Config{a}, now); len(got) != 1 {
+		t.Fatal("全部被标记时应保留原列表")
+	}
+	if tr.isFlagged(1, "m", now.Add(time.Minute+modelNotFoundFlagTTL)) {
+		t.Fatal("标记应在TTL后过期")
+	}
+	if n := tr.clear(1); n != 1 || len(tr.list(now)) != 0 {
+		t.Fatalf("按渠道清除失败: %d", n)
+	}
+
+	var nilTracker *modelNotFoundTracker
+	if nilTracker.flag(&ModelNotFoundFlag{ChannelID: 1, Model: "m"}, now) || len(nilTracker.list(now)) != 0 {
+		t.Fatal("nil tracker 应为空操作")
+	}
+}
+
+func TestHandleProxyRequest_RedirectedModelNotFound(t *testing.T) {
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+	srv.responseBufferBytes = 0
+
+	var staleHits int
+	stale := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		staleHits++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"type":"error","error":{"type":"not_found_error","message":"model: claude-old-20240101"}}`)
+	}))
+	defer stale.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":3,"output_tokens":1}}`)
+	}))
+	defer healthy.Close()
+
+	ctx := context.Background()
+	staleCfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "stale", URL: stale.URL, ChannelType: "anthropic", Priority: 10, Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-x", RedirectModel: "claude-old-20240101"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	healthyCfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "healthy", URL: healthy.URL, ChannelType: "anthropic", Priority: 1, Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-x"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: staleCfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+		{ChannelID: healthyCfg.ID, APIKey: "k2", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+
+	proxy := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+			bytes.NewBufferString(`{"model":"claude-x","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		srv.HandleProxyRequest(c)
+		return w
+	}
+
+	if w := proxy(); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok"`) {
+		t.Fatalf("应切换到其他渠道成功: %d %s", w.Code, w.Body.String())
+	}
+	flags := srv.modelNotFound.list(time.Now())
+	if len(flags) != 1 || flags[0].ChannelID != staleCfg.ID || flags[0].Model != "claude-x" || flags[0].RedirectModel != "claude-old-20240101" {
+		t.Fatalf("标记不符: %+v", flags)
+	}
+	if until, err := store.GetAllChannelCooldowns(ctx); err == nil && !until[staleCfg.ID].IsZero() {
+		t.Fatal("模型不存在不应冷却渠道")
+	}
+
+	// 标记期内不再请求过期配置
+	if w := proxy(); w.Code != http.StatusOK || staleHits != 1 {
+		t.Fatalf("标记期内应跳过该渠道: code=%d staleHits=%d", w.Code, staleHits)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/admin/model-flags?channel_id=1", nil)
+	srv.HandleClearModelFlags(c)
+	if w.Code != http.StatusOK || len(srv.modelNotFound.list(time.Now())) != 0 {
+		t.Fatalf("清除标记失败: %d %s", w.Code, w.Body.String())
+	}
+}
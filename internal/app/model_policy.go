@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+)
+
+// ============================================================================
+// 模型屏蔽策略（2026-10新增）
+// ============================================================================
+// 全局屏蔽列表来自系统设置 blocked_models（修改后立即生效，无需重启），
+// 令牌级屏蔽列表来自 auth_tokens.blocked_models（令牌CRUD后热更新）。
+// 规则不区分大小写，支持 * 通配符（如 claude-opus-*），在选路之前检查。
+
+const (
+	blockedModelScopeGlobal = "global"
+	blockedModelScopeToken  = "token"
+
+	// blockedModelMaxAlternatives 策略错误中返回的可用替代模型上限
+	blockedModelMaxAlternatives = 10
+)
+
+// parseModelList 解析逗号/换行分隔的模型列表
+func parseModelList(value string) []string {
+	return normalizeModelList(strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}))
+}
+
+// normalizeModelList 去除空白与空项，按不区分大小写去重（保留首次出现的写法）
+func normalizeModelList(models []string) []string {
+	out := make([]string, 0, len(models))
+	seen := make(map[string]struct{}, len(models))
+	for _, m := range models {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		key := strings.ToLower(m)
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, m)
+	}
+	return out
+}
+
+// matchBlockedModel 返回命中的屏蔽规则（空字符串表示未命中）
+func matchBlockedModel(rules []string, name string) string {
+	name = strings.ToLower(name)
+	for _, rule := range rules {
+		if model.MatchModelPattern(strings.ToLower(rule), name) {
+			return rule
+		}
+	}
+	return ""
+}
+
+// setBlockedModels 更新全局屏蔽列表（启动加载与设置热更新共用）
+func (s *Server) setBlockedModels(value string) {
+	rules := parseModelList(value)
+	s.blockedModels.Store(&rules)
+}
+
+// blockedModelRule 检查模型是否被屏蔽，返回命中的规则与来源（global/token），全局规则优先
+func (s *Server) blockedModelRule(tokenHash, name string) (rule, scope string) {
+	if p := s.blockedModels.Load(); p != nil {
+		if rule := matchBlockedModel(*p, name); rule != "" {
+			return rule, blockedModelScopeGlobal
+		}
+	}
+	if tokenHash != "" && s.authService != nil {
+		if rule := matchBlockedModel(s.authService.BlockedModels(tokenHash), name); rule != "" {
+			return rule, blockedModelScopeToken
+		}
+	}
+	return "", ""
+}
+
+// isModelUsable 模型对该令牌是否可用（未被屏蔽且在令牌允许列表内）
+func (s *Server) isModelUsable(tokenHash, name string) bool {
+	if rule, _ := s.blockedModelRule(tokenHash, name); rule != "" {
+		return false
+	}
+	return tokenHash == "" || s.authService == nil || s.authService.IsModelAllowed(tokenHash, name)
+}
+
+// blockedModelAlternatives 同协议启用渠道中该令牌可用的其他模型（按渠道优先级排序）
+func (s *Server) blockedModelAlternatives(ctx context.Context, requestPath, tokenHash, blocked string) []string {
+	alternatives := []string{}
+	channelType := util.DetectChannelTypeFromPath(requestPath)
+	if channelType == "" {
+		return alternatives
+	}
+	channels, err := s.GetEnabledChannelsByType(ctx, channelType)
+	if err != nil {
+		return alternatives
+	}
+	channels = slices.Clone(channels) // 不修改缓存中的切片顺序
+	slices.SortStableFunc(channels, func(a, b *model.Config) int { return b.Priority - a.Priority })
+	seen := map[string]struct{}{strings.ToLower(blocked): {}}
+	for _, ch := range channels {
+		for _, entry := range ch.ModelEntries {
+			name := entry.Model
+			key := strings.ToLower(name)
+			if _, dup := seen[key]; dup || model.IsModelPattern(name) {
+				continue
+			}
+			seen[key] = struct{}{}
+			if !s.isModelUsable(tokenHash, name) {
+				continue
+			}
+			alternatives = append(alternatives, name)
+			if len(alternatives) >= blockedModelMaxAlternatives {
+				return alternatives
+			}
+		}
+	}
+	return alternatives
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseModelListAndMatch(t *testing.T) {
+	rules := parseModelList(" claude-opus-* ,\nGPT-4o\r\n,gpt-4o,, ")
+	if !slices.Equal(rules, []string{"claude-opus-*", "GPT-4o"}) {
+		t.Fatalf("解析结果不符: %v", rules)
+	}
+	cases := map[string]string{
+		"claude-opus-4-1":   "claude-opus-*",
+		"Claude-Opus-4":     "claude-opus-*",
+		"gpt-4o":            "GPT-4o",
+		"gpt-4o-mini":       "",
+		"claude-sonnet-4-5": "",
+	}
+	for name, want := range cases {
+		if got := matchBlockedModel(rules, name); got != want {
+			t.Errorf("%s: 期望命中 %q，实际 %q", name, want, got)
+		}
+	}
+}
+
+func TestHandleProxyRequest_BlockedModels(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "policy.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	if _, err := store.CreateConfig(ctx, &model.Config{
+		Name:        "anthropic",
+		URL:         "https://example.invalid",
+		ChannelType: "anthropic",
+		ModelEntries: []model.ModelEntry{
+			{Model: "claude-opus-4-1"}, {Model: "claude-sonnet-4-5"}, {Model: "claude-haiku-4-5"}, {Model: "claude-*"},
+		},
+		Enabled: true,
+	}); err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	token := &model.AuthToken{Token: "hash-policy", Description: "t", CreatedAt: time.Now(), IsActive: true, BlockedModels: []string{"claude-haiku-*"}}
+	if err := store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	// 全局屏蔽经设置热更新立即生效（不触发重启）
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/admin/settings/blocked_models", strings.NewReader(`{"value":"Claude-Opus-*"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "key", Value: "blocked_models"}}
+	srv.AdminUpdateSetting(c)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "立即生效") {
+		t.Fatalf("热更新失败: %d %s", w.Code, w.Body.String())
+	}
+	if got := srv.configService.GetString("blocked_models", ""); got != "Claude-Opus-*" {
+		t.Fatalf("配置缓存应同步更新，实际 %q", got)
+	}
+
+	proxy := func(modelName string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+			bytes.NewBufferString(`{"model":"`+modelName+`","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("token_hash", token.Token)
+		srv.HandleProxyRequest(c)
+		var resp struct {
+			Error map[string]any `json:"error"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Error
+	}
+
+	code, errBody := proxy("claude-opus-4-1")
+	if code != http.StatusForbidden || errBody["code"] != "model_blocked" || errBody["blocked_by"] != blockedModelScopeGlobal {
+		t.Fatalf("全局屏蔽未生效: %d %v", code, errBody)
+	}
+	alts, _ := errBody["allowed_alternatives"].([]any)
+	if len(alts) != 1 || alts[0] != "claude-sonnet-4-5" {
+		t.Fatalf("替代模型应排除被屏蔽模型与通配符条目: %v", alts)
+	}
+
+	if code, errBody := proxy("claude-haiku-4-5"); code != http.StatusForbidden || errBody["blocked_by"] != blockedModelScopeToken {
+		t.Fatalf("令牌级屏蔽未生效: %d %v", code, errBody)
+	}
+}
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 模型价格表（2026-10新增）
+// ============================================================================
+// 内置定价表随版本发布更新，协议价、新模型或官方调价需要等待升级。价格表按 模型 × 生效时间
+// 维护输入/输出/缓存读取/缓存写入单价（美元/百万tokens），优先于内置定价参与计费：
+// - 实时请求（日志费用、令牌累计费用与限额）按当前时间生效的价格计费，日/周/月统计均由日志费用聚合
+// - 历史费用重算（POST /admin/pricing/recompute）按日志时间生效的价格重算，用于回填调价前后的历史日志
+// 价格表变更后立即生效，只影响之后的请求；历史日志需手动发起重算。
+
+const maxModelPrice = 10000 // 单价上限（美元/百万tokens），防止误填
+
+// reloadModelPrices 从数据库加载价格表到计费层（启动时与每次变更后调用）
+func (s *Server) reloadModelPrices(ctx context.Context) error {
+	prices, err := s.store.ListModelPrices(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make([]util.PriceOverride, 0, len(prices))
+	for _, p := range prices {
+		entries = append(entries, util.PriceOverride{
+			Model:             p.Model,
+			InputPrice:        p.InputPrice,
+			OutputPrice:       p.OutputPrice,
+			CacheReadPrice:    p.CacheReadPrice,
+			CacheWritePrice:   p.CacheWritePrice,
+			CacheWrite1hPrice: p.CacheWrite1hPrice,
+			EffectiveFrom:     p.EffectiveFrom,
+		})
+	}
+	util.SetModelPrices(entries)
+	return nil
+}
+
+// HandleListModelPrices 价格表列表
+// GET /admin/pricing
+func (s *Server) HandleListModelPrices(c *gin.Context) {
+	prices, err := s.store.ListModelPrices(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, prices)
+}
+
+// HandleCreateModelPrice 新增价格表条目
+// POST /admin/pricing
+func (s *Server) HandleCreateModelPrice(c *gin.Context) {
+	price, ok := s.bindModelPrice(c, 0)
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	price.CreatedAt, price.UpdatedAt = now, now
+	if err := s.store.CreateModelPrice(c.Request.Context(), price); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.applyModelPriceChange(c.Request.Context())
+	RespondJSON(c, http.StatusCreated, price)
+}
+
+// HandleUpdateModelPrice 更新价格表条目
+// PUT /admin/pricing/:id
+func (s *Server) HandleUpdateModelPrice(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid price id")
+		return
+	}
+	price, ok := s.bindModelPrice(c, id)
+	if !ok {
+		return
+	}
+	price.ID = id
+	price.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateModelPrice(c.Request.Context(), price)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "price not found")
+		return
+	}
+	s.applyModelPriceChange(c.Request.Context())
+	RespondJSON(c, http.StatusOK, price)
+}
+
+// HandleDeleteModelPrice 删除价格表条目（该模型回退到更早生效的价格或内置定价）
+// DELETE /admin/pricing/:id
+func (s *Server) HandleDeleteModelPrice(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid price id")
+		return
+	}
+	found, err := s.store.DeleteModelPrice(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "price not found")
+		return
+	}
+	s.applyModelPriceChange(c.Request.Context())
+	RespondJSON(c, http.StatusOK, gin.H{"id": id})
+}
+
+// applyModelPriceChange 价格表变更后重新加载（失败时保留原价格表，下次变更或重启时重试）
+func (s *Server) applyModelPriceChange(ctx context.Context) {
+	if err := s.reloadModelPrices(ctx); err != nil {
+		log.Printf("[WARN] 重新加载模型价格表失败: %v", err)
+	}
+}
+
+// bindModelPrice 解析并校验价格请求（selfID 为更新时的条目ID，创建时为0）
+func (s *Server) bindModelPrice(c *gin.Context, selfID int64) (*model.ModelPrice, bool) {
+	var req ModelPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return nil, false
+	}
+	price, err := req.ToModelPrice()
+	if err == nil {
+		err = validateModelPrice(price)
+	}
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	existing, err := s.store.ListModelPrices(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	for _, other := range existing {
+		if other.ID != selfID && strings.EqualFold(other.Model, price.Model) && other.EffectiveFrom == price.EffectiveFrom {
+			RespondErrorMsg(c, http.StatusConflict, fmt.Sprintf("price for %q with the same effective_from already exists (#%d)", price.Model, other.ID))
+			return nil, false
+		}
+	}
+	return price, true
+}
+
+// validateModelPrice 模型键为精确名或以 * 结尾的前缀；单价非负且不超过上限，输入/输出至少一项非零
+func validateModelPrice(p *model.ModelPrice) error {
+	if p.Model == "" || len(p.Model) > 191 {
+		return fmt.Errorf("model is required and must be at most 191 characters")
+	}
+	if p.Model == "*" || strings.Contains(strings.TrimSuffix(p.Model, "*"), "*") {
+		return fmt.Errorf("invalid model %q (exact name or prefix ending with *)", p.Model)
+	}
+	for name, v := range map[string]float64{
+		"input_price": p.InputPrice, "output_price": p.OutputPrice, "cache_read_price": p.CacheReadPrice,
+		"cache_write_price": p.CacheWritePrice, "cache_write_1h_price": p.CacheWrite1hPrice,
+	} {
+		if v < 0 || v > maxModelPrice {
+			return fmt.Errorf("%s must be within 0-%d", name, maxModelPrice)
+		}
+	}
+	if p.InputPrice == 0 && p.OutputPrice == 0 {
+		return fmt.Errorf("input_price or output_price must be greater than 0")
+	}
+	if len(p.Description) > 191 {
+		return fmt.Errorf("description must be at most 191 characters")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestModelPrices_CRUDAndCost(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "prices.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+	t.Cleanup(func() { util.SetModelPrices(nil) })
+
+	call := func(handler gin.HandlerFunc, method, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/admin/pricing", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		handler(c)
+		return w
+	}
+
+	for body, want := range map[string]int{
+		`{"model":"claude-sonnet-4-5"}`:                                                 http.StatusBadRequest,
+		`{"model":"claude-*-4-5","input_price":1}`:                                      http.StatusBadRequest,
+		`{"model":"claude-sonnet-4-5","input_price":-1,"output_price":1}`:               http.StatusBadRequest,
+		`{"model":"claude-sonnet-4-5","input_price":1,"effective_from":"next tuesday"}`: http.StatusBadRequest,
+	} {
+		if w := call(srv.HandleCreateModelPrice, http.MethodPost, body, nil); w.Code != want {
+			t.Fatalf("%s: 期望 %d，实际 %d %s", body, want, w.Code, w.Body.String())
+		}
+	}
+
+	w := call(srv.HandleCreateModelPrice, http.MethodPost,
+		`{"model":"claude-sonnet-4-5*","input_price":2,"output_price":10,"effective_from":"2026-10-01","description":"协议价"}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建价格失败: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data model.ModelPrice `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	oct1 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	if created.Data.EffectiveFrom != oct1.UnixMilli() {
+		t.Fatalf("生效时间应为当天零点: %+v", created.Data)
+	}
+	if w := call(srv.HandleCreateModelPrice, http.MethodPost,
+		`{"model":"CLAUDE-SONNET-4-5*","input_price":1,"output_price":1,"effective_from":"2026-10-01"}`, nil); w.Code != http.StatusConflict {
+		t.Fatalf("相同模型与生效时间应返回409: %d", w.Code)
+	}
+
+	// 变更立即生效：生效后按价格表计费，生效前按内置定价（$3/M）
+	if got := util.CalculateCostDetailed("claude-sonnet-4-5-20250929", 100_000, 0, 0, 0, 0); math.Abs(got-0.2) > 1e-9 {
+		t.Fatalf("实时计费应使用价格表: %v", got)
+	}
+	row := model.LogCostRow{Model: "claude-sonnet-4-5", ChannelID: 1, InputTokens: 100_000, Cost: 0.3}
+	row.Time = oct1.Add(-time.Hour).UnixMilli()
+	if got := recomputeLogCost(row, nil); math.Abs(got-0.3) > 1e-9 {
+		t.Fatalf("生效前的日志应按内置定价重算: %v", got)
+	}
+	row.Time = oct1.Add(time.Hour).UnixMilli()
+	if got := recomputeLogCost(row, map[int64]float64{1: 0.5}); math.Abs(got-0.1) > 1e-9 {
+		t.Fatalf("生效后的日志应按价格表重算并乘渠道倍率: %v", got)
+	}
+
+	id := gin.Params{{Key: "id", Value: strconv.FormatInt(created.Data.ID, 10)}}
+	if w := call(srv.HandleUpdateModelPrice, http.MethodPut,
+		`{"model":"claude-sonnet-4-5*","input_price":1.5,"output_price":10,"effective_from":"2026-10-01"}`, id); w.Code != http.StatusOK {
+		t.Fatalf("更新价格失败: %d %s", w.Code, w.Body.String())
+	}
+	if got := recomputeLogCost(row, nil); math.Abs(got-0.15) > 1e-9 {
+		t.Fatalf("更新后应按新价格计费: %v", got)
+	}
+
+	if w := call(srv.HandleDeleteModelPrice, http.MethodDelete, "", id); w.Code != http.StatusOK {
+		t.Fatalf("删除价格失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(srv.HandleDeleteModelPrice, http.MethodDelete, "", id); w.Code != http.StatusNotFound {
+		t.Fatalf("重复删除应返回404: %d", w.Code)
+	}
+	if got := recomputeLogCost(row, nil); math.Abs(got-0.3) > 1e-9 {
+		t.Fatalf("删除后应回到内置定价: %v", got)
+	}
+}
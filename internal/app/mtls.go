@@ -0,0 +1,229 @@
+package app
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 入站mTLS客户端证书认证（2026-10新增）
+// ============================================================================
+// 内网部署可以用客户端证书代替Bearer令牌：
+// - TLS层：MTLS_CLIENT_CA_FILE 指定CA证书包，握手时校验客户端证书链（VerifyClientCertIfGiven，
+//   未出示证书的客户端仍可走Bearer认证）；MTLS_CRL_FILE 指定吊销列表，吊销的证书在握手阶段被拒绝
+// - 认证层：请求未携带令牌时，证书 CN 或任一 SAN 等于某个令牌的 client_cert_subject 即视为持有该令牌，
+//   之后的模型限制、费用限额、统计与令牌认证完全一致
+
+// crlReloadInterval 吊销列表文件变更检查间隔
+const crlReloadInterval = 30 * time.Second
+
+// NewMTLSConfig 构建启用客户端证书校验的服务端TLS配置
+// crlFile 可为空（不做吊销检查）
+func NewMTLSConfig(caFile, crlFile string) (*tls.Config, error) {
+	pemData, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	cas, err := parseCertificatesPEM(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("parse client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	for _, ca := range cas {
+		pool.AddCert(ca)
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+	}
+
+	if crlFile != "" {
+		rev := &clientCertRevocation{path: crlFile, cas: cas}
+		if err := rev.reload(); err != nil {
+			return nil, err
+		}
+		cfg.VerifyPeerCertificate = func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+				return nil // 未出示证书（VerifyClientCertIfGiven）
+			}
+			leaf := verifiedChains[0][0]
+			if rev.isRevoked(leaf) {
+				log.Printf("[WARN] [mTLS] 拒绝已吊销的客户端证书: CN=%s, serial=%s", leaf.Subject.CommonName, leaf.SerialNumber)
+				return errors.New("client certificate revoked")
+			}
+			return nil
+		}
+	}
+	return cfg, nil
+}
+
+// parseCertificatesPEM 解析PEM证书包
+func parseCertificatesPEM(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// clientCertRevocation 客户端证书吊销列表（文件变更后自动重新加载）
+type clientCertRevocation struct {
+	path string
+	cas  []*x509.Certificate
+
+	mu        sync.Mutex
+	revoked   map[string]struct{} // RawIssuer + 序列号
+	modTime   time.Time
+	nextCheck time.Time
+}
+
+func revocationKey(rawIssuer []byte, serial string) string {
+	return string(rawIssuer) + "|" + serial
+}
+
+// reload 读取并校验吊销列表（PEM可包含多个 X509 CRL，也支持单个DER）
+// 每个CRL必须由配置的CA之一签发，否则拒绝加载
+func (r *clientCertRevocation) reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("stat CRL: %w", err)
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("read CRL: %w", err)
+	}
+
+	var ders [][]byte
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "X509 CRL" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		ders = [][]byte{data}
+	}
+
+	revoked := make(map[string]struct{})
+	for _, der := range ders {
+		rl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return fmt.Errorf("parse CRL: %w", err)
+		}
+		if !r.signedByCA(rl) {
+			return errors.New("CRL is not signed by any configured client CA")
+		}
+		if !rl.NextUpdate.IsZero() && time.Now().After(rl.NextUpdate) {
+			log.Printf("[WARN] [mTLS] 吊销列表已过期（NextUpdate=%s），请及时更新 %s", rl.NextUpdate.Format(time.RFC3339), r.path)
+		}
+		for _, entry := range rl.RevokedCertificateEntries {
+			revoked[revocationKey(rl.RawIssuer, entry.SerialNumber.String())] = struct{}{}
+		}
+	}
+
+	r.mu.Lock()
+	r.revoked = revoked
+	r.modTime = info.ModTime()
+	r.nextCheck = time.Now().Add(crlReloadInterval)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *clientCertRevocation) signedByCA(rl *x509.RevocationList) bool {
+	for _, ca := range r.cas {
+		if bytes.Equal(ca.RawSubject, rl.RawIssuer) && rl.CheckSignatureFrom(ca) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isRevoked 判断证书是否已吊销；文件变更时重新加载（加载失败保留旧列表）
+func (r *clientCertRevocation) isRevoked(cert *x509.Certificate) bool {
+	r.mu.Lock()
+	needCheck := time.Now().After(r.nextCheck)
+	if needCheck {
+		r.nextCheck = time.Now().Add(crlReloadInterval)
+	}
+	modTime := r.modTime
+	r.mu.Unlock()
+
+	if needCheck {
+		if info, err := os.Stat(r.path); err == nil && !info.ModTime().Equal(modTime) {
+			if err := r.reload(); err != nil {
+				log.Printf("[WARN] [mTLS] 重新加载吊销列表失败，继续使用旧列表: %v", err)
+			} else {
+				log.Printf("[INFO] [mTLS] 吊销列表已重新加载: %s", r.path)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, revoked := r.revoked[revocationKey(cert.RawIssuer, cert.SerialNumber.String())]
+	return revoked
+}
+
+// clientCertIdentities 证书可用于映射令牌的标识：CN 与全部 SAN
+func clientCertIdentities(cert *x509.Certificate) []string {
+	ids := make([]string, 0, 1+len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.URIs)+len(cert.IPAddresses))
+	if cert.Subject.CommonName != "" {
+		ids = append(ids, cert.Subject.CommonName)
+	}
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		ids = append(ids, u.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		ids = append(ids, ip.String())
+	}
+	return ids
+}
+
+// tokenHashFromClientCert 根据已校验的客户端证书查找映射的令牌哈希
+func (s *AuthService) tokenHashFromClientCert(r *http.Request) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+
+	s.authTokensMux.RLock()
+	defer s.authTokensMux.RUnlock()
+	for _, id := range clientCertIdentities(leaf) {
+		if hash, ok := s.authTokenCertSubjs[id]; ok {
+			return hash, true
+		}
+	}
+	return "", false
+}
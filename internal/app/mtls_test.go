@@ -0,0 +1,219 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, cn string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64, cn string, dnsNames []string, uris []*url.URL) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		URIs:         uris,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (ca *testCA) crlPEM(t *testing.T, revoked ...int64) []byte {
+	t.Helper()
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(time.Now().UnixNano()),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, serial := range revoked {
+		tmpl.RevokedCertificateEntries = append(tmpl.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Minute),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, ca.cert, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTokenHashFromClientCert(t *testing.T) {
+	ca := newTestCA(t, "test-ca")
+	svcURI, _ := url.Parse("spiffe://internal/billing")
+
+	s := &AuthService{authTokenCertSubjs: map[string]string{
+		"billing.internal":          "hash-dns",
+		"spiffe://internal/billing": "hash-uri",
+		"worker-1":                  "hash-cn",
+	}}
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		wantHash string
+		wantOK   bool
+	}{
+		{"CN匹配", ca.issue(t, 10, "worker-1", nil, nil), "hash-cn", true},
+		{"DNS SAN匹配", ca.issue(t, 11, "unmapped", []string{"billing.internal"}, nil), "hash-dns", true},
+		{"URI SAN匹配", ca.issue(t, 12, "", nil, []*url.URL{svcURI}), "hash-uri", true},
+		{"未映射", ca.issue(t, 13, "stranger", []string{"other.internal"}, nil), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/messages", nil)
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert, ca.cert}}}
+			hash, ok := s.tokenHashFromClientCert(req)
+			if ok != tt.wantOK || hash != tt.wantHash {
+				t.Fatalf("got (%q, %v), want (%q, %v)", hash, ok, tt.wantHash, tt.wantOK)
+			}
+		})
+	}
+
+	// 非TLS请求或未出示证书
+	req := httptest.NewRequest("POST", "/v1/messages", nil)
+	if _, ok := s.tokenHashFromClientCert(req); ok {
+		t.Fatal("plain HTTP request should not map to a token")
+	}
+	req.TLS = &tls.ConnectionState{}
+	if _, ok := s.tokenHashFromClientCert(req); ok {
+		t.Fatal("TLS request without client cert should not map to a token")
+	}
+}
+
+func TestNewMTLSConfig_CRL(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	good := ca.issue(t, 100, "good", nil, nil)
+	revoked := ca.issue(t, 101, "revoked", nil, nil)
+
+	caFile := writeTestFile(t, dir, "ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+	crlFile := writeTestFile(t, dir, "crl.pem", ca.crlPEM(t, 101))
+
+	cfg, err := NewMTLSConfig(caFile, crlFile)
+	if err != nil {
+		t.Fatalf("NewMTLSConfig: %v", err)
+	}
+	if cfg.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Fatalf("ClientAuth = %v, want VerifyClientCertIfGiven", cfg.ClientAuth)
+	}
+
+	verify := func(cert *x509.Certificate) error {
+		return cfg.VerifyPeerCertificate(nil, [][]*x509.Certificate{{cert, ca.cert}})
+	}
+	if err := verify(good); err != nil {
+		t.Fatalf("good cert rejected: %v", err)
+	}
+	if err := verify(revoked); err == nil {
+		t.Fatal("revoked cert accepted")
+	}
+	if err := cfg.VerifyPeerCertificate(nil, nil); err != nil {
+		t.Fatalf("connection without client cert rejected: %v", err)
+	}
+}
+
+func TestNewMTLSConfig_RejectsForeignCRL(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	other := newTestCA(t, "other-ca")
+
+	caFile := writeTestFile(t, dir, "ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+	crlFile := writeTestFile(t, dir, "crl.pem", other.crlPEM(t, 1))
+
+	if _, err := NewMTLSConfig(caFile, crlFile); err == nil {
+		t.Fatal("CRL signed by an unknown CA should be rejected")
+	}
+}
+
+func TestClientCertRevocation_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	cert := ca.issue(t, 200, "worker", nil, nil)
+	crlFile := writeTestFile(t, dir, "crl.pem", ca.crlPEM(t))
+
+	rev := &clientCertRevocation{path: crlFile, cas: []*x509.Certificate{ca.cert}}
+	if err := rev.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if rev.isRevoked(cert) {
+		t.Fatal("cert should not be revoked yet")
+	}
+
+	writeTestFile(t, dir, "crl.pem", ca.crlPEM(t, 200))
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(crlFile, future, future); err != nil {
+		t.Fatal(err)
+	}
+	rev.mu.Lock()
+	rev.nextCheck = time.Time{} // 跳过检查间隔
+	rev.mu.Unlock()
+
+	if !rev.isRevoked(cert) {
+		t.Fatal("cert should be revoked after CRL reload")
+	}
+}
@@ -0,0 +1,1082 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// OpenAI Chat Completions 兼容入口（2026-10新增）
+// ============================================================================
+// 开启 openai_compat 的 anthropic / gemini / codex 渠道可服务 OpenAI POST /v1/chat/completions 请求，
+// 任意 OpenAI SDK 客户端无需改动即可使用这些渠道：
+//   - anthropic：请求转换为 Messages（/v1/messages），响应 Messages → Chat Completions
+//   - gemini：请求先转换为 Messages，再经 Anthropic ⇄ Gemini 转换层（anthropic_gemini.go）转发，
+//     响应按相反顺序转换回来（无需同时开启 anthropic_compat）
+//   - codex：请求转换为 Responses（/v1/responses），响应 Responses → Chat Completions
+// 流式响应逐事件转换为 chat.completion.chunk 并以 data: [DONE] 结束；客户端设置 stream_options.include_usage
+// 时在结束前额外发送仅含 usage 的块。上游错误体统一转换为 OpenAI error 格式。
+// usage 统计仍基于上游原始字节（按渠道类型解析），转换只作用于写回客户端的数据。
+
+const (
+	openaiChatCompletionsPath = "/v1/chat/completions"
+	codexResponsesPath        = "/v1/responses"
+
+	// openaiCompatDefaultMaxTokens Anthropic 要求 max_tokens，客户端未指定时使用
+	openaiCompatDefaultMaxTokens = 4096
+	anthropicAPIVersion          = "2023-06-01"
+
+	openaiSourceAnthropic = "anthropic" // 上游（或转换层）输出 Anthropic Messages 格式
+	openaiSourceResponses = "responses" // 上游输出 OpenAI Responses 格式（codex）
+)
+
+// openaiCompatChannelType 可开启 openai_compat 的渠道类型
+func openaiCompatChannelType(channelType string) bool {
+	switch channelType {
+	case util.ChannelTypeAnthropic, util.ChannelTypeGemini, util.ChannelTypeCodex:
+		return true
+	}
+	return false
+}
+
+// isOpenAIChatRequest 仅 POST /v1/chat/completions 可被转换（embeddings/completions 等不支持）
+func isOpenAIChatRequest(method, path string) bool {
+	return method == http.MethodPost && path == openaiChatCompletionsPath
+}
+
+// openaiBridgeEnabled 判断本次渠道尝试是否需要 Chat Completions → 渠道协议转换
+func openaiBridgeEnabled(cfg *model.Config, reqCtx *proxyRequestContext) bool {
+	return cfg.OpenAICompat &&
+		openaiCompatChannelType(cfg.GetChannelType()) &&
+		isOpenAIChatRequest(reqCtx.requestMethod, reqCtx.requestPath)
+}
+
+// ---------------------------------------------------------------------------
+// 请求转换
+// ---------------------------------------------------------------------------
+
+type openaiChatRequest struct {
+	Model               string              `json:"model"`
+	Messages            []openaiChatMessage `json:"messages"`
+	MaxTokens           int                 `json:"max_tokens"`
+	MaxCompletionTokens int                 `json:"max_completion_tokens"`
+	Temperature         *float64            `json:"temperature"`
+	TopP                *float64            `json:"top_p"`
+	Stop                json.RawMessage     `json:"stop"` // 字符串或字符串数组
+	Stream              bool                `json:"stream"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+	Tools      []openaiChatTool `json:"tools"`
+	ToolChoice json.RawMessage  `json:"tool_choice"` // "auto"/"none"/"required" 或 {"type":"function","function":{"name":...}}
+}
+
+type openaiChatMessage struct {
+	Role       string           `json:"role"`
+	Content    json.RawMessage  `json:"content"` // 字符串、内容块数组或 null
+	ToolCalls  []openaiToolCall `json:"tool_calls"`
+	ToolCallID string           `json:"tool_call_id"`
+}
+
+type openaiContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+type openaiToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openaiChatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+// openaiChatOptions 转换后需要在响应侧使用的客户端选项
+type openaiChatOptions struct {
+	stream       bool
+	includeUsage bool
+}
+
+func (req *openaiChatRequest) options() openaiChatOptions {
+	return openaiChatOptions{stream: req.Stream, includeUsage: req.Stream && req.StreamOptions != nil && req.StreamOptions.IncludeUsage}
+}
+
+// maxTokens max_completion_tokens 优先（新版SDK），其次 max_tokens
+func (req *openaiChatRequest) maxTokens() int {
+	if req.MaxCompletionTokens > 0 {
+		return req.MaxCompletionTokens
+	}
+	return req.MaxTokens
+}
+
+// stopSequences stop 字段（字符串或数组）→ 字符串数组
+func (req *openaiChatRequest) stopSequences() []string {
+	trimmed := bytes.TrimSpace(req.Stop)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+	var one string
+	if sonic.Unmarshal(trimmed, &one) == nil {
+		if one == "" {
+			return nil
+		}
+		return []string{one}
+	}
+	var many []string
+	_ = sonic.Unmarshal(trimmed, &many)
+	return many
+}
+
+// toolChoice 解析 tool_choice，返回模式（auto/none/required/function）与指定的函数名
+func (req *openaiChatRequest) toolChoice() (mode, name string) {
+	trimmed := bytes.TrimSpace(req.ToolChoice)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return "", ""
+	}
+	if sonic.Unmarshal(trimmed, &mode) == nil {
+		return mode, ""
+	}
+	var obj struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if sonic.Unmarshal(trimmed, &obj) == nil && obj.Function.Name != "" {
+		return "function", obj.Function.Name
+	}
+	return "", ""
+}
+
+func parseOpenAIChatRequest(body []byte) (*openaiChatRequest, error) {
+	var req openaiChatRequest
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid chat completions request: %w", err)
+	}
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("invalid chat completions request: messages is empty")
+	}
+	return &req, nil
+}
+
+// parseOpenAIContent 解析 content 字段（字符串视为单个 text 块）
+func parseOpenAIContent(raw json.RawMessage) ([]openaiContentPart, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+	if trimmed[0] == '"' {
+		var text string
+		if err := sonic.Unmarshal(trimmed, &text); err != nil {
+			return nil, err
+		}
+		return []openaiContentPart{{Type: "text", Text: text}}, nil
+	}
+	var parts []openaiContentPart
+	if err := sonic.Unmarshal(trimmed, &parts); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// openaiContentText 拼接内容块中的文本（system/tool 消息只取文本）
+func openaiContentText(parts []openaiContentPart) string {
+	var sb strings.Builder
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			if sb.Len() > 0 {
+				sb.WriteByte('\n')
+			}
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// anthropicImageSource image_url → Anthropic image source（data URL 转 base64，其余按 URL 引用）
+func anthropicImageSource(url string) map[string]any {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if meta, data, found := strings.Cut(rest, ","); found && strings.HasSuffix(meta, ";base64") {
+			return map[string]any{"type": "base64", "media_type": strings.TrimSuffix(meta, ";base64"), "data": data}
+		}
+	}
+	return map[string]any{"type": "url", "url": url}
+}
+
+// toolArgumentsJSON 工具调用参数字符串 → JSON 对象（无法解析时为空对象）
+func toolArgumentsJSON(args string) json.RawMessage {
+	trimmed := strings.TrimSpace(args)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	return json.RawMessage("{}")
+}
+
+// convertOpenAIChatToAnthropic 将 Chat Completions 请求体转换为 Anthropic Messages 请求体
+// system/developer 消息合并为 system；相邻同角色消息（含多个 tool 结果）合并为一条
+func convertOpenAIChatToAnthropic(body []byte) ([]byte, openaiChatOptions, error) {
+	req, err := parseOpenAIChatRequest(body)
+	if err != nil {
+		return nil, openaiChatOptions{}, err
+	}
+
+	var system []string
+	messages := make([]map[string]any, 0, len(req.Messages))
+	appendBlocks := func(role string, blocks []map[string]any) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+			messages[n-1]["content"] = append(messages[n-1]["content"].([]map[string]any), blocks...)
+			return
+		}
+		messages = append(messages, map[string]any{"role": role, "content": blocks})
+	}
+
+	for i, msg := range req.Messages {
+		parts, err := parseOpenAIContent(msg.Content)
+		if err != nil {
+			return nil, openaiChatOptions{}, fmt.Errorf("invalid chat completions messages[%d].content: %w", i, err)
+		}
+		switch msg.Role {
+		case "system", "developer":
+			if text := openaiContentText(parts); text != "" {
+				system = append(system, text)
+			}
+		case "tool":
+			appendBlocks("user", []map[string]any{{
+				"type": "tool_result", "tool_use_id": msg.ToolCallID, "content": openaiContentText(parts),
+			}})
+		case "assistant":
+			blocks := make([]map[string]any, 0, len(parts)+len(msg.ToolCalls))
+			if text := openaiContentText(parts); text != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": text})
+			}
+			for _, tc := range msg.ToolCalls {
+				blocks = append(blocks, map[string]any{
+					"type": "tool_use", "id": tc.ID, "name": tc.Function.Name, "input": toolArgumentsJSON(tc.Function.Arguments),
+				})
+			}
+			appendBlocks("assistant", blocks)
+		default: // user
+			blocks := make([]map[string]any, 0, len(parts))
+			for _, p := range parts {
+				switch {
+				case p.Type == "text" && p.Text != "":
+					blocks = append(blocks, map[string]any{"type": "text", "text": p.Text})
+				case p.Type == "image_url" && p.ImageURL != nil:
+					blocks = append(blocks, map[string]any{"type": "image", "source": anthropicImageSource(p.ImageURL.URL)})
+				}
+			}
+			appendBlocks("user", blocks)
+		}
+	}
+
+	out := map[string]any{
+		"model":      req.Model,
+		"messages":   messages,
+		"max_tokens": openaiCompatDefaultMaxTokens,
+	}
+	if n := req.maxTokens(); n > 0 {
+		out["max_tokens"] = n
+	}
+	if len(system) > 0 {
+		out["system"] = strings.Join(system, "\n\n")
+	}
+	if req.Temperature != nil {
+		out["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		out["top_p"] = *req.TopP
+	}
+	if stops := req.stopSequences(); len(stops) > 0 {
+		out["stop_sequences"] = stops
+	}
+	if req.Stream {
+		out["stream"] = true
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			if t.Function.Name == "" {
+				continue
+			}
+			schema := t.Function.Parameters
+			if schema == nil {
+				schema = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			tool := map[string]any{"name": t.Function.Name, "input_schema": schema}
+			if t.Function.Description != "" {
+				tool["description"] = t.Function.Description
+			}
+			tools = append(tools, tool)
+		}
+		if len(tools) > 0 {
+			out["tools"] = tools
+		}
+	}
+	if _, hasTools := out["tools"]; hasTools {
+		switch mode, name := req.toolChoice(); mode {
+		case "auto":
+			out["tool_choice"] = map[string]any{"type": "auto"}
+		case "none":
+			out["tool_choice"] = map[string]any{"type": "none"}
+		case "required":
+			out["tool_choice"] = map[string]any{"type": "any"}
+		case "function":
+			out["tool_choice"] = map[string]any{"type": "tool", "name": name}
+		}
+	}
+
+	converted, err := sonic.Marshal(out)
+	if err != nil {
+		return nil, openaiChatOptions{}, err
+	}
+	return converted, req.options(), nil
+}
+
+// convertOpenAIChatToResponses 将 Chat Completions 请求体转换为 OpenAI Responses 请求体（codex 渠道）
+// Responses 不支持 stop，转换时丢弃
+func convertOpenAIChatToResponses(body []byte) ([]byte, openaiChatOptions, error) {
+	req, err := parseOpenAIChatRequest(body)
+	if err != nil {
+		return nil, openaiChatOptions{}, err
+	}
+
+	var instructions []string
+	input := make([]map[string]any, 0, len(req.Messages))
+	for i, msg := range req.Messages {
+		parts, err := parseOpenAIContent(msg.Content)
+		if err != nil {
+			return nil, openaiChatOptions{}, fmt.Errorf("invalid chat completions messages[%d].content: %w", i, err)
+		}
+		switch msg.Role {
+		case "system", "developer":
+			if text := openaiContentText(parts); text != "" {
+				instructions = append(instructions, text)
+			}
+		case "tool":
+			input = append(input, map[string]any{"type": "function_call_output", "call_id": msg.ToolCallID, "output": openaiContentText(parts)})
+		case "assistant":
+			if text := openaiContentText(parts); text != "" {
+				input = append(input, map[string]any{
+					"type": "message", "role": "assistant",
+					"content": []map[string]any{{"type": "output_text", "text": text}},
+				})
+			}
+			for _, tc := range msg.ToolCalls {
+				input = append(input, map[string]any{
+					"type": "function_call", "call_id": tc.ID, "name": tc.Function.Name, "arguments": tc.Function.Arguments,
+				})
+			}
+		default: // user
+			content := make([]map[string]any, 0, len(parts))
+			for _, p := range parts {
+				switch {
+				case p.Type == "text" && p.Text != "":
+					content = append(content, map[string]any{"type": "input_text", "text": p.Text})
+				case p.Type == "image_url" && p.ImageURL != nil:
+					content = append(content, map[string]any{"type": "input_image", "image_url": p.ImageURL.URL})
+				}
+			}
+			if len(content) > 0 {
+				input = append(input, map[string]any{"type": "message", "role": "user", "content": content})
+			}
+		}
+	}
+
+	out := map[string]any{
+		"model": req.Model,
+		"input": input,
+		"store": false,
+	}
+	if len(instructions) > 0 {
+		out["instructions"] = strings.Join(instructions, "\n\n")
+	}
+	if n := req.maxTokens(); n > 0 {
+		out["max_output_tokens"] = n
+	}
+	if req.Temperature != nil {
+		out["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		out["top_p"] = *req.TopP
+	}
+	if req.Stream {
+		out["stream"] = true
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			if t.Function.Name == "" {
+				continue
+			}
+			tool := map[string]any{"type": "function", "name": t.Function.Name}
+			if t.Function.Description != "" {
+				tool["description"] = t.Function.Description
+			}
+			if t.Function.Parameters != nil {
+				tool["parameters"] = t.Function.Parameters
+			}
+			tools = append(tools, tool)
+		}
+		if len(tools) > 0 {
+			out["tools"] = tools
+		}
+	}
+	if _, hasTools := out["tools"]; hasTools {
+		switch mode, name := req.toolChoice(); mode {
+		case "auto", "none", "required":
+			out["tool_choice"] = mode
+		case "function":
+			out["tool_choice"] = map[string]any{"type": "function", "name": name}
+		}
+	}
+
+	converted, err := sonic.Marshal(out)
+	if err != nil {
+		return nil, openaiChatOptions{}, err
+	}
+	return converted, req.options(), nil
+}
+
+// openaiBridgeHeader 复制客户端请求头；anthropic 渠道补充 anthropic-version（OpenAI SDK 不会发送）
+func openaiBridgeHeader(src http.Header, channelType string) http.Header {
+	hdr := src.Clone()
+	hdr.Set("Content-Type", "application/json")
+	if channelType == util.ChannelTypeAnthropic && hdr.Get("anthropic-version") == "" {
+		hdr.Set("anthropic-version", anthropicAPIVersion)
+	}
+	return hdr
+}
+
+// prepareOpenAIBridge 转换请求并包装响应写入器，返回改写后的请求上下文
+func prepareOpenAIBridge(cfg *model.Config, reqCtx *proxyRequestContext, body []byte, w http.ResponseWriter) (*proxyRequestContext, []byte, *openaiChatWriter, error) {
+	channelType := cfg.GetChannelType()
+	bridged := *reqCtx
+	bridged.header = openaiBridgeHeader(reqCtx.header, channelType)
+	bridged.rawQuery = ""
+	bridged.protocolConverted = true
+
+	var converted []byte
+	var opts openaiChatOptions
+	var err error
+	source := openaiSourceAnthropic
+	if channelType == util.ChannelTypeCodex {
+		converted, opts, err = convertOpenAIChatToResponses(body)
+		bridged.requestPath = codexResponsesPath
+		source = openaiSourceResponses
+	} else {
+		converted, opts, err = convertOpenAIChatToAnthropic(body)
+		bridged.requestPath = anthropicMessagesPath
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return &bridged, converted, newOpenAIChatWriter(w, source, reqCtx.originalModel, opts), nil
+}
+
+// ---------------------------------------------------------------------------
+// 响应转换
+// ---------------------------------------------------------------------------
+
+type anthropicUsageFields struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+}
+
+type responsesObject struct {
+	ID                string                   `json:"id"`
+	Status            string                   `json:"status"`
+	IncompleteDetails *struct{ Reason string } `json:"incomplete_details"`
+	Output            []responsesOutputItem    `json:"output"`
+	Usage             *responsesUsage          `json:"usage"`
+	Error             *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type responsesOutputItem struct {
+	Type      string `json:"type"` // message / function_call / reasoning 等
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Content   []struct {
+		Type    string `json:"type"` // output_text / refusal
+		Text    string `json:"text"`
+		Refusal string `json:"refusal"`
+	} `json:"content"`
+}
+
+type responsesUsage struct {
+	InputTokens        int64 `json:"input_tokens"`
+	OutputTokens       int64 `json:"output_tokens"`
+	InputTokensDetails *struct {
+		CachedTokens int64 `json:"cached_tokens"`
+	} `json:"input_tokens_details"`
+}
+
+// openaiUsage 构造 Chat Completions usage（prompt_tokens 含缓存命中部分）
+func openaiUsage(prompt, completion, cached int64) map[string]any {
+	return map[string]any{
+		"prompt_tokens":         prompt,
+		"completion_tokens":     completion,
+		"total_tokens":          prompt + completion,
+		"prompt_tokens_details": map[string]any{"cached_tokens": cached},
+	}
+}
+
+// usageFromAnthropic Anthropic input_tokens 不含缓存读写，需要加回
+func usageFromAnthropic(u anthropicUsageFields) map[string]any {
+	return openaiUsage(u.InputTokens+u.CacheReadInputTokens+u.CacheCreationInputTokens, u.OutputTokens, u.CacheReadInputTokens)
+}
+
+func usageFromResponses(u *responsesUsage) map[string]any {
+	if u == nil {
+		return openaiUsage(0, 0, 0)
+	}
+	var cached int64
+	if u.InputTokensDetails != nil {
+		cached = u.InputTokensDetails.CachedTokens
+	}
+	return openaiUsage(u.InputTokens, u.OutputTokens, cached)
+}
+
+// openaiFinishFromAnthropic Anthropic stop_reason → finish_reason
+func openaiFinishFromAnthropic(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+// openaiFinishFromResponses Responses 状态 → finish_reason
+func openaiFinishFromResponses(resp *responsesObject, hasToolCalls bool) string {
+	if resp != nil && resp.Status == "incomplete" && resp.IncompleteDetails != nil {
+		if resp.IncompleteDetails.Reason == "content_filter" {
+			return "content_filter"
+		}
+		return "length"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func newChatCompletionID() string {
+	return newAnthropicID("chatcmpl-")
+}
+
+// openaiChatCompletion 构造非流式 chat.completion
+func openaiChatCompletion(modelName, content string, toolCalls []map[string]any, finish string, usage map[string]any) map[string]any {
+	message := map[string]any{"role": "assistant", "content": nil}
+	if content != "" {
+		message["content"] = content
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+	return map[string]any{
+		"id":      newChatCompletionID(),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   modelName,
+		"choices": []map[string]any{{"index": 0, "message": message, "finish_reason": finish}},
+		"usage":   usage,
+	}
+}
+
+func openaiToolCallEntry(id, name, arguments string) map[string]any {
+	return map[string]any{"id": id, "type": "function", "function": map[string]any{"name": name, "arguments": arguments}}
+}
+
+// convertAnthropicToOpenAIChat 将 Anthropic message 转换为 chat.completion
+// thinking、server_tool_use、web_search_tool_result 等块丢弃；text 块的 citations 不映射（正文保持完整）
+func convertAnthropicToOpenAIChat(body []byte, modelName string) ([]byte, error) {
+	var msg struct {
+		Content    []anthropicContentBlock `json:"content"`
+		StopReason string                  `json:"stop_reason"`
+		Usage      anthropicUsageFields    `json:"usage"`
+	}
+	if err := sonic.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	var toolCalls []map[string]any
+	for _, b := range msg.Content {
+		switch b.Type {
+		case "text":
+			text.WriteString(b.Text)
+		case "tool_use":
+			args := string(b.Input)
+			if args == "" {
+				args = "{}"
+			}
+			toolCalls = append(toolCalls, openaiToolCallEntry(b.ID, b.Name, args))
+		}
+	}
+	return sonic.Marshal(openaiChatCompletion(modelName, text.String(), toolCalls,
+		openaiFinishFromAnthropic(msg.StopReason), usageFromAnthropic(msg.Usage)))
+}
+
+// convertResponsesToOpenAIChat 将 Responses 响应对象转换为 chat.completion（reasoning 项丢弃）
+func convertResponsesToOpenAIChat(body []byte, modelName string) ([]byte, error) {
+	var resp responsesObject
+	if err := sonic.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	var toolCalls []map[string]any
+	refused := false
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "message":
+			for _, c := range item.Content {
+				switch c.Type {
+				case "output_text":
+					text.WriteString(c.Text)
+				case "refusal":
+					text.WriteString(c.Refusal)
+					refused = true
+				}
+			}
+		case "function_call":
+			toolCalls = append(toolCalls, openaiToolCallEntry(item.CallID, item.Name, item.Arguments))
+		}
+	}
+	finish := openaiFinishFromResponses(&resp, len(toolCalls) > 0)
+	if refused {
+		finish = "content_filter"
+	}
+	return sonic.Marshal(openaiChatCompletion(modelName, text.String(), toolCalls, finish, usageFromResponses(resp.Usage)))
+}
+
+// openaiErrorType HTTP状态码 → OpenAI error.type
+func openaiErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	default:
+		return "server_error"
+	}
+}
+
+// openaiErrorBody 构造 OpenAI error 响应体
+func openaiErrorBody(status int, message string) []byte {
+	out, _ := sonic.Marshal(map[string]any{
+		"error": map[string]any{"message": message, "type": openaiErrorType(status), "param": nil, "code": nil},
+	})
+	return out
+}
+
+// convertErrorToOpenAI 将上游错误体（Anthropic/Responses 格式或纯文本）转换为 OpenAI error 格式
+func convertErrorToOpenAI(status int, body []byte) []byte {
+	msg := strings.TrimSpace(string(body))
+	var parsed struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Detail string `json:"detail"`
+	}
+	if sonic.Unmarshal(body, &parsed) == nil {
+		switch {
+		case parsed.Error != nil && parsed.Error.Message != "":
+			msg = parsed.Error.Message
+		case parsed.Detail != "":
+			msg = parsed.Detail
+		}
+	}
+	return openaiErrorBody(status, msg)
+}
+
+// ---------------------------------------------------------------------------
+// 响应写入包装
+// ---------------------------------------------------------------------------
+
+// openaiChatWriter 将写向客户端的 Anthropic / Responses 响应转换为 Chat Completions 格式
+// 非流式：缓存完整响应体，finish 时整体转换写出
+// 流式：按 SSE 事件增量转换为 chat.completion.chunk，收到上游结束事件时写出 [DONE]
+type openaiChatWriter struct {
+	http.ResponseWriter
+	source string
+	model  string
+	opts   openaiChatOptions
+	buf    bytes.Buffer // 非流式：完整响应体；流式：尚未成行的残余字节
+
+	// 流式状态
+	dataLines []string
+	id        string
+	created   int64
+	started   bool
+	done      bool
+	toolIndex map[int]int // 上游内容块/输出项下标 → tool_calls 下标
+	usage     anthropicUsageFields
+	stop      string
+}
+
+func newOpenAIChatWriter(w http.ResponseWriter, source, modelName string, opts openaiChatOptions) *openaiChatWriter {
+	return &openaiChatWriter{ResponseWriter: w, source: source, model: modelName, opts: opts, toolIndex: make(map[int]int)}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter（SetWriteDeadline 等）
+func (w *openaiChatWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Flush 透传 Flush（流式转换后的块需立即下发）
+func (w *openaiChatWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *openaiChatWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if !w.opts.stream {
+		return len(p), nil
+	}
+	for {
+		data := w.buf.Bytes()
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(bytes.TrimRight(data[:idx], "\r"))
+		w.buf.Next(idx + 1)
+		if err := w.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// handleLine 逐行解析上游 SSE：累积 data 行，空行时处理一个事件（事件类型取自 data 中的 type 字段）
+func (w *openaiChatWriter) handleLine(line string) error {
+	if after, ok := strings.CutPrefix(line, "data:"); ok {
+		w.dataLines = append(w.dataLines, strings.TrimSpace(after))
+		return nil
+	}
+	if line != "" || len(w.dataLines) == 0 {
+		return nil
+	}
+	payload := strings.Join(w.dataLines, "")
+	w.dataLines = w.dataLines[:0]
+	if w.done || payload == "[DONE]" {
+		return nil
+	}
+	if w.source == openaiSourceResponses {
+		return w.handleResponsesEvent([]byte(payload))
+	}
+	return w.handleAnthropicEvent([]byte(payload))
+}
+
+func (w *openaiChatWriter) writeData(v any) error {
+	payload, err := sonic.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.ResponseWriter, "data: %s\n\n", payload)
+	return err
+}
+
+func (w *openaiChatWriter) chunk(choices []map[string]any) map[string]any {
+	return map[string]any{
+		"id":      w.id,
+		"object":  "chat.completion.chunk",
+		"created": w.created,
+		"model":   w.model,
+		"choices": choices,
+	}
+}
+
+func (w *openaiChatWriter) writeDelta(delta map[string]any, finish any) error {
+	return w.writeData(w.chunk([]map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}}))
+}
+
+// ensureStarted 首个块携带 role
+func (w *openaiChatWriter) ensureStarted() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	w.id = newChatCompletionID()
+	w.created = time.Now().Unix()
+	return w.writeDelta(map[string]any{"role": "assistant", "content": ""}, nil)
+}
+
+// startToolCall 新的工具调用（upstreamIndex 为上游内容块/输出项下标）
+func (w *openaiChatWriter) startToolCall(upstreamIndex int, id, name string) error {
+	idx := len(w.toolIndex)
+	w.toolIndex[upstreamIndex] = idx
+	return w.writeDelta(map[string]any{"tool_calls": []map[string]any{{
+		"index": idx, "id": id, "type": "function", "function": map[string]any{"name": name, "arguments": ""},
+	}}}, nil)
+}
+
+func (w *openaiChatWriter) appendToolArguments(upstreamIndex int, partial string) error {
+	idx, ok := w.toolIndex[upstreamIndex]
+	if !ok || partial == "" {
+		return nil
+	}
+	return w.writeDelta(map[string]any{"tool_calls": []map[string]any{{
+		"index": idx, "function": map[string]any{"arguments": partial},
+	}}}, nil)
+}
+
+// finishStream 写出 finish_reason、可选的 usage 块与 [DONE]
+func (w *openaiChatWriter) finishStream(finish string, usage map[string]any) error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	if err := w.writeDelta(map[string]any{}, finish); err != nil {
+		return err
+	}
+	if w.opts.includeUsage {
+		c := w.chunk([]map[string]any{})
+		c["usage"] = usage
+		if err := w.writeData(c); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprint(w.ResponseWriter, "data: [DONE]\n\n")
+	w.Flush()
+	return err
+}
+
+// writeStreamError 上游流内错误事件以 OpenAI error 块下发（不写 [DONE]，客户端据此识别失败）
+func (w *openaiChatWriter) writeStreamError(message string) error {
+	w.done = true
+	err := w.writeData(map[string]any{"error": map[string]any{"message": message, "type": "server_error", "param": nil, "code": nil}})
+	w.Flush()
+	return err
+}
+
+// handleAnthropicEvent 单个 Anthropic 流式事件 → chat.completion.chunk
+func (w *openaiChatWriter) handleAnthropicEvent(payload []byte) error {
+	var ev struct {
+		Type    string `json:"type"`
+		Index   int    `json:"index"`
+		Message *struct {
+			Usage anthropicUsageFields `json:"usage"`
+		} `json:"message"`
+		ContentBlock *anthropicContentBlock `json:"content_block"`
+		Delta        *struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			PartialJSON string `json:"partial_json"`
+			StopReason  string `json:"stop_reason"`
+		} `json:"delta"`
+		Usage *anthropicUsageFields `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := sonic.Unmarshal(payload, &ev); err != nil {
+		return nil // 无法解析的事件直接跳过（容错）
+	}
+	switch ev.Type {
+	case "message_start":
+		if ev.Message != nil {
+			w.usage = ev.Message.Usage
+		}
+		return w.ensureStarted()
+	case "content_block_start":
+		if err := w.ensureStarted(); err != nil {
+			return err
+		}
+		// server_tool_use/web_search_tool_result 等服务端工具块由上游自行执行，不映射为 tool_calls；
+		// 其 input_json_delta 因未登记索引被 appendToolArguments 忽略
+		if ev.ContentBlock == nil {
+			return nil
+		}
+		switch ev.ContentBlock.Type {
+		case "tool_use":
+			return w.startToolCall(ev.Index, ev.ContentBlock.ID, ev.ContentBlock.Name)
+		case "text":
+			// 部分上游（如带 citations 的回复）在块起始事件中携带首段文本
+			if ev.ContentBlock.Text != "" {
+				if err := w.writeDelta(map[string]any{"content": ev.ContentBlock.Text}, nil); err != nil {
+					return err
+				}
+				w.Flush()
+			}
+		}
+	case "content_block_delta":
+		if ev.Delta == nil {
+			return nil
+		}
+		if err := w.ensureStarted(); err != nil {
+			return err
+		}
+		switch ev.Delta.Type {
+		case "text_delta":
+			if ev.Delta.Text != "" {
+				if err := w.writeDelta(map[string]any{"content": ev.Delta.Text}, nil); err != nil {
+					return err
+				}
+				w.Flush()
+			}
+		case "input_json_delta":
+			return w.appendToolArguments(ev.Index, ev.Delta.PartialJSON)
+		}
+		// citations_delta、thinking_delta、signature_delta 等无对应字段，忽略
+	case "message_delta":
+		if ev.Delta != nil && ev.Delta.StopReason != "" {
+			w.stop = ev.Delta.StopReason
+		}
+		if ev.Usage != nil {
+			// message_delta 的 usage 为累计值，输出Token以此为准；输入Token部分网关只在此处给出
+			w.usage.OutputTokens = ev.Usage.OutputTokens
+			if ev.Usage.InputTokens > 0 {
+				w.usage.InputTokens = ev.Usage.InputTokens
+			}
+			w.usage.CacheReadInputTokens = max(w.usage.CacheReadInputTokens, ev.Usage.CacheReadInputTokens)
+			w.usage.CacheCreationInputTokens = max(w.usage.CacheCreationInputTokens, ev.Usage.CacheCreationInputTokens)
+		}
+	case "message_stop":
+		if err := w.ensureStarted(); err != nil {
+			return err
+		}
+		return w.finishStream(openaiFinishFromAnthropic(w.stop), usageFromAnthropic(w.usage))
+	case "error":
+		msg := "upstream stream error"
+		if ev.Error != nil && ev.Error.Message != "" {
+			msg = ev.Error.Message
+		}
+		return w.writeStreamError(msg)
+	}
+	return nil
+}
+
+// handleResponsesEvent 单个 Responses 流式事件 → chat.completion.chunk
+func (w *openaiChatWriter) handleResponsesEvent(payload []byte) error {
+	var ev struct {
+		Type        string               `json:"type"`
+		Delta       string               `json:"delta"`
+		OutputIndex int                  `json:"output_index"`
+		Item        *responsesOutputItem `json:"item"`
+		Response    *responsesObject     `json:"response"`
+		Message     string               `json:"message"`
+	}
+	if err := sonic.Unmarshal(payload, &ev); err != nil {
+		return nil // 无法解析的事件直接跳过（容错）
+	}
+	switch ev.Type {
+	case "response.created", "response.in_progress":
+		return w.ensureStarted()
+	case "response.output_item.added":
+		if err := w.ensureStarted(); err != nil {
+			return err
+		}
+		if ev.Item != nil && ev.Item.Type == "function_call" {
+			return w.startToolCall(ev.OutputIndex, ev.Item.CallID, ev.Item.Name)
+		}
+	case "response.output_text.delta", "response.refusal.delta":
+		if ev.Delta == "" {
+			return nil
+		}
+		if ev.Type == "response.refusal.delta" {
+			w.stop = "refusal"
+		}
+		if err := w.ensureStarted(); err != nil {
+			return err
+		}
+		if err := w.writeDelta(map[string]any{"content": ev.Delta}, nil); err != nil {
+			return err
+		}
+		w.Flush()
+	case "response.function_call_arguments.delta":
+		return w.appendToolArguments(ev.OutputIndex, ev.Delta)
+	case "response.completed", "response.incomplete":
+		if err := w.ensureStarted(); err != nil {
+			return err
+		}
+		finish := openaiFinishFromResponses(ev.Response, len(w.toolIndex) > 0)
+		if w.stop == "refusal" {
+			finish = "content_filter"
+		}
+		var usage *responsesUsage
+		if ev.Response != nil {
+			usage = ev.Response.Usage
+		}
+		return w.finishStream(finish, usageFromResponses(usage))
+	case "response.failed":
+		msg := "upstream response failed"
+		if ev.Response != nil && ev.Response.Error != nil && ev.Response.Error.Message != "" {
+			msg = ev.Response.Error.Message
+		}
+		return w.writeStreamError(msg)
+	case "error":
+		msg := ev.Message
+		if msg == "" {
+			msg = "upstream stream error"
+		}
+		return w.writeStreamError(msg)
+	}
+	return nil
+}
+
+// finishResponse 上游响应结束后收尾（gemini 链路须在 anthropicBridgeWriter.finishResponse 之后调用）
+// 流式响应在收到上游结束事件时已写出 [DONE]；上游中断时不补发，客户端据此识别截断
+func (w *openaiChatWriter) finishResponse(completed bool) {
+	if !w.opts.stream {
+		if w.buf.Len() == 0 {
+			return
+		}
+		convert := convertAnthropicToOpenAIChat
+		if w.source == openaiSourceResponses {
+			convert = convertResponsesToOpenAIChat
+		}
+		out, err := convert(w.buf.Bytes(), w.model)
+		if err != nil || !completed {
+			out = w.buf.Bytes() // 无法解析时原样返回，避免吞掉响应
+		}
+		_, _ = w.ResponseWriter.Write(out)
+		return
+	}
+
+	if w.buf.Len() > 0 {
+		_ = w.handleLine(strings.TrimRight(w.buf.String(), "\r"))
+		w.buf.Reset()
+	}
+	_ = w.handleLine("") // 处理末尾未以空行结束的事件
+	w.Flush()
+}
@@ -0,0 +1,316 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/bytedance/sonic"
+)
+
+func TestConvertOpenAIChatToAnthropic(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-x",
+		"max_completion_tokens": 128,
+		"stop": "END",
+		"stream": true,
+		"stream_options": {"include_usage": true},
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}}}}],
+		"tool_choice": "required",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": [{"type": "text", "text": "weather?"}, {"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}}]},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}
+		]
+	}`)
+
+	out, opts, err := convertOpenAIChatToAnthropic(body)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if !opts.stream || !opts.includeUsage {
+		t.Fatalf("客户端选项不符: %+v", opts)
+	}
+
+	var req struct {
+		System        string             `json:"system"`
+		MaxTokens     int                `json:"max_tokens"`
+		StopSequences []string           `json:"stop_sequences"`
+		Messages      []anthropicMessage `json:"messages"`
+		ToolChoice    map[string]any     `json:"tool_choice"`
+		Tools         []anthropicTool    `json:"tools"`
+	}
+	if err := sonic.Unmarshal(out, &req); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+	if req.System != "be brief" || req.MaxTokens != 128 || len(req.StopSequences) != 1 {
+		t.Fatalf("system/max_tokens/stop 映射不符: %s", out)
+	}
+	if len(req.Messages) != 3 || req.Messages[1].Role != "assistant" || req.Messages[2].Role != "user" {
+		t.Fatalf("messages 映射不符: %s", out)
+	}
+	for _, want := range []string{`"type":"image"`, `"media_type":"image/png"`, `"type":"tool_use"`, `"city":"Paris"`, `"tool_use_id":"call_1"`} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("转换结果缺少 %s: %s", want, out)
+		}
+	}
+	if req.ToolChoice["type"] != "any" || len(req.Tools) != 1 || req.Tools[0].Name != "get_weather" {
+		t.Fatalf("tools/tool_choice 映射不符: %s", out)
+	}
+
+	out, _, err = convertOpenAIChatToAnthropic([]byte(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil || !strings.Contains(string(out), `"max_tokens":4096`) {
+		t.Fatalf("未指定 max_tokens 时应使用默认值: %s err=%v", out, err)
+	}
+	if _, _, err := convertOpenAIChatToAnthropic([]byte(`{"messages":[]}`)); err == nil {
+		t.Fatal("空 messages 应报错")
+	}
+}
+
+func TestConvertOpenAIChatToResponses(t *testing.T) {
+	body := []byte(`{
+		"model": "gpt-5-codex",
+		"max_tokens": 64,
+		"tool_choice": {"type": "function", "function": {"name": "f"}},
+		"tools": [{"type": "function", "function": {"name": "f", "parameters": {"type": "object"}}}],
+		"messages": [
+			{"role": "developer", "content": "rules"},
+			{"role": "user", "content": "hi"},
+			{"role": "assistant", "content": "calling", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "ok"}
+		]
+	}`)
+
+	out, opts, err := convertOpenAIChatToResponses(body)
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	if opts.stream {
+		t.Fatal("未设置stream时不应为流式")
+	}
+	var req struct {
+		Instructions    string           `json:"instructions"`
+		MaxOutputTokens int              `json:"max_output_tokens"`
+		Input           []map[string]any `json:"input"`
+		ToolChoice      map[string]any   `json:"tool_choice"`
+		Store           bool             `json:"store"`
+	}
+	if err := sonic.Unmarshal(out, &req); err != nil {
+		t.Fatalf("解析转换结果失败: %v", err)
+	}
+	if req.Instructions != "rules" || req.MaxOutputTokens != 64 || req.Store {
+		t.Fatalf("instructions/max_output_tokens/store 映射不符: %s", out)
+	}
+	if len(req.Input) != 4 || req.Input[2]["type"] != "function_call" || req.Input[3]["type"] != "function_call_output" {
+		t.Fatalf("input 映射不符: %s", out)
+	}
+	if req.ToolChoice["name"] != "f" {
+		t.Fatalf("tool_choice 映射不符: %s", out)
+	}
+}
+
+func TestConvertToOpenAIChatResponse(t *testing.T) {
+	out, err := convertAnthropicToOpenAIChat([]byte(`{"content":[{"type":"thinking","thinking":"..."},{"type":"text","text":"Hi"},{"type":"tool_use","id":"toolu_1","name":"f","input":{"a":1}}],
+		"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":30}}`), "claude-x")
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	for _, want := range []string{`"object":"chat.completion"`, `"content":"Hi"`, `"finish_reason":"tool_calls"`, `"arguments":"{\"a\":1}"`, `"prompt_tokens":40`, `"cached_tokens":30`} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("Anthropic 响应转换缺少 %s: %s", want, out)
+		}
+	}
+
+	out, err = convertResponsesToOpenAIChat([]byte(`{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"},
+		"output":[{"type":"reasoning"},{"type":"message","content":[{"type":"output_text","text":"partial"}]}],
+		"usage":{"input_tokens":8,"output_tokens":2,"input_tokens_details":{"cached_tokens":4}}}`), "gpt-5-codex")
+	if err != nil {
+		t.Fatalf("转换失败: %v", err)
+	}
+	for _, want := range []string{`"content":"partial"`, `"finish_reason":"length"`, `"prompt_tokens":8`, `"total_tokens":10`} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("Responses 响应转换缺少 %s: %s", want, out)
+		}
+	}
+
+	errBody := convertErrorToOpenAI(http.StatusTooManyRequests, []byte(`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`))
+	if !strings.Contains(string(errBody), `"message":"slow down"`) || !strings.Contains(string(errBody), `"type":"rate_limit_error"`) {
+		t.Fatalf("错误体转换不符: %s", errBody)
+	}
+}
+
+func TestChannelMatchesType_OpenAICompat(t *testing.T) {
+	cfg := &model.Config{ChannelType: "codex", OpenAICompat: true}
+	if !channelMatchesType(cfg, "openai") {
+		t.Fatal("开启 openai_compat 的 codex 渠道应匹配 openai 请求")
+	}
+	cfg.OpenAICompat = false
+	if channelMatchesType(cfg, "openai") {
+		t.Fatal("未开启 openai_compat 的 codex 渠道不应匹配 openai 请求")
+	}
+}
+
+// newOpenAICompatTestChannel 创建开启 openai_compat 的渠道及其Key
+func newOpenAICompatTestChannel(t *testing.T, channelType, upstreamURL string) (*Server, *model.Config) {
+	t.Helper()
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	srv := NewServer(store)
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+	srv.responseBufferBytes = 0 // 本地上游一次性写完，关闭缓冲窗口避免首段读到EOF
+
+	ctx := context.Background()
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:         channelType + "-openai-compat",
+		URL:          upstreamURL,
+		ChannelType:  channelType,
+		OpenAICompat: true,
+		ModelEntries: []model.ModelEntry{{Model: "chat-x"}},
+		Enabled:      true,
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{ChannelID: cfg.ID, APIKey: "k", KeyStrategy: model.KeyStrategySequential}}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	return srv, cfg
+}
+
+func TestTryChannelWithKeys_OpenAIChatToAnthropicStream(t *testing.T) {
+	var gotPath, gotVersion string
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotVersion = r.URL.Path, r.Header.Get("anthropic-version")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":12,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"f"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"q\":1}"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+			`{"type":"message_stop"}`,
+		} {
+			_, _ = io.WriteString(w, "event: x\ndata: "+ev+"\n\n")
+		}
+	}))
+	defer upstream.Close()
+	srv, cfg := newOpenAICompatTestChannel(t, "anthropic", upstream.URL)
+
+	body := []byte(`{"model":"chat-x","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hi"}]}`)
+	w := httptest.NewRecorder()
+	res, err := srv.tryChannelWithKeys(context.Background(), cfg, &proxyRequestContext{
+		originalModel: "chat-x",
+		requestMethod: http.MethodPost,
+		requestPath:   "/v1/chat/completions",
+		body:          body,
+		header:        http.Header{"Content-Type": {"application/json"}},
+		isStreaming:   true,
+	}, w)
+	if err != nil || res == nil || !res.succeeded {
+		t.Fatalf("转发失败: res=%+v err=%v", res, err)
+	}
+	if gotPath != "/v1/messages" || gotVersion != anthropicAPIVersion {
+		t.Fatalf("上游请求不符: path=%s anthropic-version=%q", gotPath, gotVersion)
+	}
+	if !strings.Contains(string(gotBody), `"max_tokens"`) {
+		t.Fatalf("上游请求体应为Messages格式: %s", gotBody)
+	}
+
+	out := w.Body.String()
+	for _, want := range []string{
+		`"object":"chat.completion.chunk"`, `"role":"assistant"`, `"content":"Hello"`, `"id":"toolu_1"`,
+		`"arguments":"{\"q\":1}"`, `"finish_reason":"tool_calls"`, `"completion_tokens":7`, "data: [DONE]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("客户端流缺少 %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "content_block_delta") {
+		t.Fatalf("客户端不应收到Anthropic原始事件:\n%s", out)
+	}
+}
+
+func TestTryChannelWithKeys_OpenAIChatToGemini(t *testing.T) {
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Bonjour"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":9,"candidatesTokenCount":3}}`)
+	}))
+	defer upstream.Close()
+	srv, cfg := newOpenAICompatTestChannel(t, "gemini", upstream.URL)
+
+	w := httptest.NewRecorder()
+	res, err := srv.tryChannelWithKeys(context.Background(), cfg, &proxyRequestContext{
+		originalModel: "chat-x",
+		requestMethod: http.MethodPost,
+		requestPath:   "/v1/chat/completions",
+		body:          []byte(`{"model":"chat-x","messages":[{"role":"user","content":"hi"}]}`),
+		header:        http.Header{"Content-Type": {"application/json"}},
+	}, w)
+	if err != nil || res == nil || !res.succeeded {
+		t.Fatalf("转发失败: res=%+v err=%v", res, err)
+	}
+	if gotPath != "/v1beta/models/chat-x:generateContent" {
+		t.Fatalf("上游路径不符: %s", gotPath)
+	}
+	out := w.Body.String()
+	for _, want := range []string{`"object":"chat.completion"`, `"content":"Bonjour"`, `"finish_reason":"stop"`, `"prompt_tokens":9`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("客户端响应缺少 %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestOpenAIChatWriter_AnthropicServerToolAndCitationEvents(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newOpenAIChatWriter(rec, openaiSourceAnthropic, "chat-x", openaiChatOptions{stream: true})
+	for _, ev := range []string{
+		`{"type":"message_start","message":{"usage":{"input_tokens":5,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\":\"q\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","url":"https://x.example","title":"X"}]}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"text","text":"Per ","citations":[]}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"citations_delta","citation":{"type":"search_result_location","source":"https://x.example","cited_text":"x"}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"X, yes."}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":9}}`,
+		`{"type":"message_stop"}`,
+	} {
+		if _, err := io.WriteString(w, "data: "+ev+"\n\n"); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+	out := rec.Body.String()
+	for _, want := range []string{`"content":"Per "`, `"content":"X, yes."`, `"finish_reason":"stop"`, "data: [DONE]"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("客户端流缺少 %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "tool_calls") || strings.Contains(out, "srvtoolu_1") || strings.Contains(out, "cited_text") {
+		t.Fatalf("服务端工具块与 citations 不应映射到客户端流:\n%s", out)
+	}
+
+	resp, err := convertAnthropicToOpenAIChat([]byte(`{"content":[
+		{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{"query":"q"}},
+		{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","url":"https://x.example"}]},
+		{"type":"text","text":"X.","citations":[{"type":"search_result_location","source":"https://x.example"}]}
+	],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`), "chat-x")
+	if err != nil || !strings.Contains(string(resp), `"content":"X."`) || strings.Contains(string(resp), "tool_calls") {
+		t.Fatalf("非流式转换不符: %s err=%v", resp, err)
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 令牌自助日志查询（2026-10新增）
+// ============================================================================
+// 下游开发者排查接入问题时需要看自己的请求日志，但不应获得管理权限。
+// GET /portal/logs 使用API令牌认证，只返回调用令牌自身的日志：
+//   - 分页：limit（默认200，最大1000）/offset
+//   - 时间：range（today/yesterday/this_week 等，同管理端）或 since/until（Unix毫秒，优先于range，跨度最长 portalLogsMaxSpan）
+//   - 过滤：model、status_code
+// 返回字段不含渠道、上游Key、客户端IP等运营信息，错误消息经 util.RedactSecrets 脱敏。
+
+const portalLogsMaxSpan = 31 * 24 * time.Hour
+
+// PortalLogEntry 令牌自助日志条目（LogEntry 的脱敏子集）
+type PortalLogEntry struct {
+	ID                       int64          `json:"id"`
+	Time                     model.JSONTime `json:"time"`
+	Model                    string         `json:"model"`
+	StatusCode               int            `json:"status_code"`
+	Message                  string         `json:"message"`
+	Duration                 float64        `json:"duration"`
+	IsStreaming              bool           `json:"is_streaming"`
+	FirstByteTime            float64        `json:"first_byte_time"`
+	InputTokens              int            `json:"input_tokens"`
+	OutputTokens             int            `json:"output_tokens"`
+	CacheReadInputTokens     int            `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int            `json:"cache_creation_input_tokens"`
+	Cost                     float64        `json:"cost"`
+}
+
+func newPortalLogEntry(e *model.LogEntry) PortalLogEntry {
+	return PortalLogEntry{
+		ID:                       e.ID,
+		Time:                     e.Time,
+		Model:                    e.Model,
+		StatusCode:               e.StatusCode,
+		Message:                  util.RedactSecrets(e.Message),
+		Duration:                 e.Duration,
+		IsStreaming:              e.IsStreaming,
+		FirstByteTime:            e.FirstByteTime,
+		InputTokens:              e.InputTokens,
+		OutputTokens:             e.OutputTokens,
+		CacheReadInputTokens:     e.CacheReadInputTokens,
+		CacheCreationInputTokens: e.CacheCreationInputTokens,
+		Cost:                     e.Cost,
+	}
+}
+
+// parsePortalTimeRange 解析 since/until（Unix毫秒）；均未提供时回退到 range 预设
+func parsePortalTimeRange(c *gin.Context, params *PaginationParams) (time.Time, time.Time, bool) {
+	sinceStr, untilStr := strings.TrimSpace(c.Query("since")), strings.TrimSpace(c.Query("until"))
+	if sinceStr == "" && untilStr == "" {
+		since, until := params.GetTimeRange()
+		return since, until, true
+	}
+	until := time.Now()
+	if untilStr != "" {
+		ms, err := strconv.ParseInt(untilStr, 10, 64)
+		if err != nil || ms <= 0 {
+			return time.Time{}, time.Time{}, false
+		}
+		until = time.UnixMilli(ms)
+	}
+	since := until.Add(-24 * time.Hour)
+	if sinceStr != "" {
+		ms, err := strconv.ParseInt(sinceStr, 10, 64)
+		if err != nil || ms <= 0 {
+			return time.Time{}, time.Time{}, false
+		}
+		since = time.UnixMilli(ms)
+	}
+	if !since.Before(until) || until.Sub(since) > portalLogsMaxSpan {
+		return time.Time{}, time.Time{}, false
+	}
+	return since, until, true
+}
+
+// HandlePortalLogs 查询调用令牌自身的请求日志
+// GET /portal/logs?limit=50&offset=0&since=1760000000000&until=1760086400000&model=xxx&status_code=429
+func (s *Server) HandlePortalLogs(c *gin.Context) {
+	tokenID, _ := c.Get("token_id")
+	tokenIDInt64, _ := tokenID.(int64)
+	if tokenIDInt64 <= 0 {
+		RespondErrorMsg(c, http.StatusForbidden, "token has no log access")
+		return
+	}
+
+	params := ParsePaginationParams(c)
+	since, until, ok := parsePortalTimeRange(c, params)
+	if !ok {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid since/until (unix ms, span up to 31 days)")
+		return
+	}
+
+	// 只开放与自身请求相关的过滤条件，令牌ID强制为调用方
+	lf := model.LogFilter{AuthTokenID: &tokenIDInt64}
+	if m := strings.TrimSpace(c.Query("model")); m != "" {
+		lf.Model = m
+	}
+	if scStr := strings.TrimSpace(c.Query("status_code")); scStr != "" {
+		if code, err := strconv.Atoi(scStr); err == nil && code > 0 {
+			lf.StatusCode = &code
+		}
+	}
+
+	ctx := c.Request.Context()
+	logs, err := s.store.ListLogsRange(ctx, since, until, params.Limit, params.Offset, &lf)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	total, err := s.store.CountLogsRange(ctx, since, until, &lf)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	entries := make([]PortalLogEntry, 0, len(logs))
+	for _, e := range logs {
+		entries = append(entries, newPortalLogEntry(e))
+	}
+	RespondJSONWithCount(c, http.StatusOK, entries, total)
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlePortalLogs_OnlyOwnRedactedLogs(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now()
+	secret := "sk-ant-REDACTED"
+	var logs []*model.LogEntry
+	for i := range 3 {
+		logs = append(logs, &model.LogEntry{
+			Time:        model.JSONTime{Time: now.Add(-time.Duration(i+1) * time.Minute)},
+			Model:       "claude-sonnet",
+			ChannelID:   9,
+			StatusCode:  200,
+			APIKeyUsed:  "sk-a...wxyz",
+			AuthTokenID: 1,
+			ClientIP:    "10.0.0.1",
+			Message:     "ok",
+		})
+	}
+	logs = append(logs,
+		&model.LogEntry{Time: model.JSONTime{Time: now.Add(-time.Minute)}, Model: "claude-sonnet", StatusCode: 401, AuthTokenID: 1,
+			Message: "invalid key " + secret},
+		&model.LogEntry{Time: model.JSONTime{Time: now.Add(-time.Minute)}, Model: "claude-sonnet", StatusCode: 200, AuthTokenID: 2, Message: "other"},
+		&model.LogEntry{Time: model.JSONTime{Time: now.Add(-48 * time.Hour)}, Model: "claude-sonnet", StatusCode: 200, AuthTokenID: 1, Message: "old"},
+	)
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("写入日志失败: %v", err)
+	}
+
+	call := func(tokenID any, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/portal/logs"+query, nil)
+		if tokenID != nil {
+			c.Set("token_id", tokenID)
+		}
+		server.HandlePortalLogs(c)
+		return w
+	}
+	type portalResp struct {
+		Data  []map[string]any `json:"data"`
+		Count int              `json:"count"`
+	}
+	decode := func(w *httptest.ResponseRecorder) portalResp {
+		if w.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		var resp portalResp
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		return resp
+	}
+
+	if w := call(nil, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("无令牌ID应返回403, got %d", w.Code)
+	}
+
+	since := strconv.FormatInt(now.Add(-time.Hour).UnixMilli(), 10)
+	resp := decode(call(int64(1), "?since="+since+"&limit=2"))
+	if resp.Count != 4 || len(resp.Data) != 2 {
+		t.Fatalf("应只返回本令牌窗口内日志: count=%d len=%d", resp.Count, len(resp.Data))
+	}
+	for _, field := range []string{"channel_id", "channel_name", "api_key_used", "client_ip", "auth_token_id"} {
+		if _, ok := resp.Data[0][field]; ok {
+			t.Fatalf("不应返回运营字段 %s: %v", field, resp.Data[0])
+		}
+	}
+
+	resp = decode(call(int64(1), "?since="+since+"&status_code=401"))
+	if resp.Count != 1 || strings.Contains(resp.Data[0]["message"].(string), secret) {
+		t.Fatalf("错误消息应脱敏: %+v", resp.Data)
+	}
+
+	// range 预设同样可用；时间参数非法或跨度过大返回400
+	if resp = decode(call(int64(2), "?range=today")); resp.Count != 1 {
+		t.Fatalf("令牌2今日日志数应为1, got %d", resp.Count)
+	}
+	tooOld := strconv.FormatInt(now.Add(-40*24*time.Hour).UnixMilli(), 10)
+	for _, q := range []string{"?since=abc", "?since=" + tooOld, "?since=" + since + "&until=" + since} {
+		if w := call(int64(1), q); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s 应返回400, got %d", q, w.Code)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log"
+	"net/http"
 	"sync/atomic"
 	"time"
 
@@ -42,6 +43,12 @@ func (s *Server) applyCooldownDecision(
 
 	if action == cooldown.ActionRetryKey || action == cooldown.ActionRetryChannel {
 		s.invalidateChannelRelatedCache(cfg.ID)
+
+		keyIndex := in.KeyIndex
+		if action == cooldown.ActionRetryChannel {
+			keyIndex = -1
+		}
+		s.publishCooldownEvent(cfg.ID, AdminCooldownEvent{KeyIndex: keyIndex, Action: "cooldown", Source: "auto", StatusCode: in.StatusCode})
 	}
 
 	return action
@@ -86,36 +93,60 @@ func (s *Server) logProxyResult(
 	reqCtx *proxyRequestContext,
 	cfg *model.Config,
 	actualModel string,
+	keyIndex int,
 	selectedKey string,
 	statusCode int,
 	duration float64,
 	res *fwResult,
 	errMsg string,
+	networkErr bool,
 ) {
 	s.AddLogAsync(buildLogEntry(logEntryParams{
 		RequestModel: reqCtx.originalModel,
 		ActualModel:  actualModel,
 		ChannelID:    cfg.ID,
+		CostMult:     cfg.GetCostMultiplier(),
 		StatusCode:   statusCode,
 		Duration:     duration,
 		IsStreaming:  reqCtx.isStreaming,
 		APIKeyUsed:   selectedKey,
+		KeyIndex:     keyIndex,
 		AuthTokenID:  reqCtx.tokenID,
 		ClientIP:     reqCtx.clientIP,
 		Result:       res,
 		ErrMsg:       errMsg,
 		StartTime:    reqCtx.attemptStartTime,
+		NetworkErr:   networkErr,
+		Converted:    reqCtx.protocolConverted,
 	}))
 }
 
+// logConversionFailure 记录本地协议转换失败（请求未发往上游，归为 conversion_bug，2026-10新增）
+func (s *Server) logConversionFailure(reqCtx *proxyRequestContext, cfg *model.Config, actualModel string, convErr error) {
+	entry := buildLogEntry(logEntryParams{
+		RequestModel: reqCtx.originalModel,
+		ActualModel:  actualModel,
+		ChannelID:    cfg.ID,
+		StatusCode:   http.StatusBadRequest,
+		IsStreaming:  reqCtx.isStreaming,
+		KeyIndex:     -1,
+		AuthTokenID:  reqCtx.tokenID,
+		ClientIP:     reqCtx.clientIP,
+		ErrMsg:       "protocol conversion failed: " + convErr.Error(),
+	})
+	entry.ErrorClass = util.ErrorClassConversionBug
+	s.AddLogAsync(entry)
+}
+
 func (s *Server) updateTokenStatsForProxy(
 	reqCtx *proxyRequestContext,
+	cfg *model.Config,
 	isSuccess bool,
 	duration float64,
 	res *fwResult,
 	actualModel string,
 ) {
-	s.updateTokenStatsAsync(reqCtx.tokenHash, isSuccess, duration, reqCtx.isStreaming, res, actualModel)
+	s.updateTokenStatsAsync(reqCtx.tokenHash, isSuccess, duration, reqCtx.isStreaming, res, actualModel, cfg.GetCostMultiplier())
 }
 
 // handleNetworkError 处理网络错误
@@ -138,7 +169,11 @@ func (s *Server) handleNetworkError(
 	statusCode, _, shouldRetry := util.ClassifyError(err)
 
 	// 记录日志：requestModel=原始请求模型，actualModel=实际转发模型
-	s.logProxyResult(reqCtx, cfg, actualModel, selectedKey, statusCode, duration, res, err.Error())
+	errMsg := err.Error()
+	if cause := context.Cause(ctx); errors.Is(err, context.Canceled) && errors.Is(cause, errRequestCancelledByAdmin) {
+		errMsg = cause.Error() // 区分管理员取消与客户端断开
+	}
+	s.logProxyResult(reqCtx, cfg, actualModel, keyIndex, selectedKey, statusCode, duration, res, errMsg, true)
 
 	failure := &proxyResult{
 		status:           statusCode,
@@ -154,7 +189,7 @@ func (s *Server) handleNetworkError(
 	// 修复：即使请求失败，也记录已解析的 token 统计（用于计费和统计）
 	if res != nil && hasConsumedTokens(res) {
 		// isSuccess=false 表示请求失败，但仍记录已消耗的 token
-		s.updateTokenStatsForProxy(reqCtx, false, duration, res, actualModel)
+		s.updateTokenStatsForProxy(reqCtx, cfg, false, duration, res, actualModel)
 	}
 
 	if !shouldRetry {
@@ -188,6 +223,7 @@ type tokenStatsUpdate struct {
 	cacheReadTokens     int64
 	cacheCreationTokens int64
 	costUSD             float64
+	journalID           uint64 // 预写日志记录ID（0表示未记录，2026-10新增）
 }
 
 func (s *Server) tokenStatsWorker() {
@@ -219,19 +255,24 @@ func (s *Server) drainTokenStats() {
 	}
 }
 
-func (s *Server) applyTokenStatsUpdate(upd tokenStatsUpdate) {
+// applyTokenStatsUpdate 写入Token统计，返回是否落库成功
+func (s *Server) applyTokenStatsUpdate(upd tokenStatsUpdate) bool {
 	updateCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	if err := s.store.UpdateTokenStats(updateCtx, upd.tokenHash, upd.isSuccess, upd.duration, upd.isStreaming, upd.firstByteTime, upd.promptTokens, upd.completionTokens, upd.cacheReadTokens, upd.cacheCreationTokens, upd.costUSD); err != nil {
 		log.Printf("ERROR: failed to update token stats for hash=%s: %v", upd.tokenHash, err)
-		return // 数据库更新失败，不更新内存缓存，保持一致性
+		return false // 数据库更新失败，不更新内存缓存，保持一致性（预写日志保留，下次启动重放）
 	}
+	s.journal.ack(upd.journalID)
 
 	// 数据库更新成功后，同步更新费用缓存（用于限额检查，2026-01新增）
 	if upd.isSuccess && upd.costUSD > 0 {
-		s.authService.AddCostToCache(upd.tokenHash, util.USDToMicroUSD(upd.costUSD))
+		deltaMicro := util.USDToMicroUSD(upd.costUSD)
+		usedMicro, limitMicro := s.authService.AddCostToCache(upd.tokenHash, deltaMicro)
+		s.checkTokenBudget(upd.tokenHash, usedMicro-deltaMicro, usedMicro, limitMicro)
 	}
+	return true
 }
 
 // updateTokenStatsAsync 异步更新Token统计（DRY原则：消除重复代码）
@@ -242,7 +283,8 @@ func (s *Server) applyTokenStatsUpdate(upd tokenStatsUpdate) {
 //   - isStreaming: 是否流式请求
 //   - res: 转发结果（成功时用于提取token数量，失败时传nil）
 //   - actualModel: 实际模型名称（用于计费）
-func (s *Server) updateTokenStatsAsync(tokenHash string, isSuccess bool, duration float64, isStreaming bool, res *fwResult, actualModel string) {
+//   - costMult: 渠道价格倍率（<=0 视为1）
+func (s *Server) updateTokenStatsAsync(tokenHash string, isSuccess bool, duration float64, isStreaming bool, res *fwResult, actualModel string, costMult float64) {
 	if tokenHash == "" || s.tokenStatsCh == nil {
 		return
 	}
@@ -267,6 +309,9 @@ func (s *Server) updateTokenStatsAsync(tokenHash string, isSuccess bool, duratio
 			res.Cache5mInputTokens,
 			res.Cache1hInputTokens,
 		)
+		if costMult > 0 {
+			costUSD *= costMult
+		}
 
 		// 财务安全检查：费用为0但有token消耗时告警（可能是定价缺失）
 		if costUSD == 0.0 && (res.InputTokens > 0 || res.OutputTokens > 0) {
@@ -274,6 +319,11 @@ func (s *Server) updateTokenStatsAsync(tokenHash string, isSuccess bool, duratio
 				actualModel, res.InputTokens, res.OutputTokens, res.CacheReadInputTokens, res.Cache5mInputTokens, res.Cache1hInputTokens)
 		}
 		// 注意：费用缓存更新已移至 applyTokenStatsUpdate，确保数据库先写成功
+
+		// TPM限流计数即时生效（不等待落库）
+		if s.authService != nil {
+			s.authService.RecordTokenUsage(tokenHash, promptTokens+completionTokens)
+		}
 	}
 
 	upd := tokenStatsUpdate{
@@ -288,6 +338,9 @@ func (s *Server) updateTokenStatsAsync(tokenHash string, isSuccess bool, duratio
 		cacheCreationTokens: cacheCreationTokens,
 		costUSD:             costUSD,
 	}
+	if journalWantsStats(&upd) {
+		upd.journalID = s.journal.appendStats(&upd)
+	}
 
 	// ✅ shutdown期间仍需保证在途请求的计费/用量落库：
 	// - 这时 worker 可能正在退出/队列可能不再被消费
@@ -361,12 +414,28 @@ func (s *Server) handleProxySuccess(
 
 	// 冷却状态已恢复，刷新相关缓存避免下次命中过期数据
 	s.invalidateChannelRelatedCache(cfg.ID)
+	s.notifyChannelSucceeded(cfg.ID, keyIndex)
 
 	// 记录成功日志
-	s.logProxyResult(reqCtx, cfg, actualModel, selectedKey, res.Status, duration, res, "")
+	s.logProxyResult(reqCtx, cfg, actualModel, keyIndex, selectedKey, res.Status, duration, res, "", false)
 
 	// 异步更新Token统计
-	s.updateTokenStatsForProxy(reqCtx, true, duration, res, actualModel)
+	s.updateTokenStatsForProxy(reqCtx, cfg, true, duration, res, actualModel)
+
+	// 输出Token异常检测（失控循环告警）
+	s.observeTokenAnomaly(reqCtx, cfg, res)
+
+	// Token估算引擎误差采样
+	s.observeTokenEstimate(reqCtx, cfg, res)
+
+	// Token估算漂移告警
+	s.observeTokenDrift(reqCtx, cfg, actualModel, res)
+
+	// Token与请求体大小分布
+	s.observeDistribution(reqCtx, cfg.ID, cfg.Name, res)
+
+	// 渠道已接受的请求体大小
+	s.requestSizes.observeAccepted(cfg.ID, int64(len(reqCtx.body)))
 
 	return &proxyResult{
 		status:     res.Status,
@@ -392,7 +461,7 @@ func (s *Server) handleStreamingErrorNoRetry(
 	reqCtx *proxyRequestContext,
 ) (*proxyResult, cooldown.Action) {
 	// 记录错误日志
-	s.logProxyResult(reqCtx, cfg, actualModel, selectedKey, res.Status, duration, res, res.StreamDiagMsg)
+	s.logProxyResult(reqCtx, cfg, actualModel, keyIndex, selectedKey, res.Status, duration, res, res.StreamDiagMsg, false)
 
 	// 触发冷却（保护后续请求）
 	_ = s.applyCooldownDecision(ctx, cfg, httpErrorInput(cfg.ID, keyIndex, res))
@@ -426,10 +495,10 @@ func (s *Server) handleProxyErrorResponse(
 		errMsg = "upstream returned 499 (not client cancel)"
 	}
 
-	s.logProxyResult(reqCtx, cfg, actualModel, selectedKey, res.Status, duration, res, errMsg)
+	s.logProxyResult(reqCtx, cfg, actualModel, keyIndex, selectedKey, res.Status, duration, res, errMsg, false)
 
 	// 异步更新Token统计（失败请求不计费）
-	s.updateTokenStatsForProxy(reqCtx, false, duration, res, actualModel)
+	s.updateTokenStatsForProxy(reqCtx, cfg, false, duration, res, actualModel)
 
 	failure := &proxyResult{
 		status:    res.Status,
@@ -440,6 +509,18 @@ func (s *Server) handleProxyErrorResponse(
 		succeeded: false,
 	}
 
+	// 重定向模型上游不存在：标记该渠道模型并切换渠道，不冷却渠道/Key（2026-10新增）
+	if s.handleRedirectedModelNotFound(cfg, reqCtx, actualModel, res) {
+		failure.nextAction = cooldown.ActionRetryChannel
+		return failure, cooldown.ActionRetryChannel
+	}
+
+	// 请求体过大：记录渠道上限并直接返回客户端，不冷却（2026-10新增）
+	if s.handleRequestTooLarge(cfg, reqCtx, failure) {
+		failure.nextAction = cooldown.ActionReturnClient
+		return failure, cooldown.ActionReturnClient
+	}
+
 	action := s.applyCooldownDecision(ctx, cfg, httpErrorInput(cfg.ID, keyIndex, res))
 	failure.nextAction = action
 	return failure, action
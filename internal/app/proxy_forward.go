@@ -9,6 +9,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -69,8 +70,12 @@ func (s *Server) buildProxyRequest(
 	hdr http.Header,
 	rawQuery, requestPath string,
 ) (*http.Request, error) {
-	// 1. 构建完整 URL
+	// 1. 构建完整 URL（Azure OpenAI 按部署名映射端点）
 	upstreamURL := buildUpstreamURL(cfg, requestPath, rawQuery)
+	isAzure := cfg.GetChannelType() == util.ChannelTypeAzure
+	if isAzure {
+		upstreamURL = util.AzureUpstreamURL(cfg.URL, requestPath, azureDeployment(body), rawQuery)
+	}
 
 	// 2. 创建带上下文的请求
 	req, err := buildUpstreamRequest(reqCtx.ctx, method, upstreamURL, body)
@@ -81,8 +86,29 @@ func (s *Server) buildProxyRequest(
 	// 3. 复制请求头
 	copyRequestHeaders(req, hdr)
 
-	// 4. 注入认证头
+	// 3.5 渠道指定的客户端profile（覆盖客户端同名头）
+	if profile, ok := s.clientProfile(cfg); ok {
+		profile.Apply(req.Header)
+	}
+
+	// 3.6 按渠道声明的beta功能改写 anthropic-beta（在profile之后，profile自带的标记同样受约束）
+	util.ApplyBetaFeatureHeader(cfg.BetaFeatures, req.Header, body)
+
+	// 4. 注入认证头（Vertex AI 渠道改用服务账号访问令牌并映射端点路径）
 	injectAPIKeyHeaders(req, apiKey, requestPath)
+	if isAzure {
+		req.Header.Del("Authorization")
+		req.Header.Set(util.AzureAPIKeyHeader, apiKey)
+	}
+	if err := s.applyVertexAuth(reqCtx.ctx, req, cfg); err != nil {
+		return nil, err
+	}
+
+	// 5. 渠道级上游压缩（请求体gzip / 强制Accept-Encoding）
+	applyUpstreamCompression(req, cfg, body)
+
+	// 6. 渠道级出站幂等键（同一尝试内的微重试复用）
+	applyIdempotencyKey(req, cfg)
 
 	return req, nil
 }
@@ -130,6 +156,9 @@ func (s *Server) handleRequestError(
 	} else {
 		// 其他错误：使用统一分类器
 		statusCode, _, _ = util.ClassifyError(err)
+		if statusCode == util.StatusCertPinMismatch {
+			log.Printf("[ERROR] [ALERT] [证书指纹不匹配] 渠道ID=%d, 上游证书与固定指纹不符，疑似中间人，已中止请求: %v", cfg.ID, err)
+		}
 	}
 
 	return &fwResult{
@@ -263,11 +292,28 @@ func (s *Server) handleSuccessResponse(
 	filterAndWriteResponseHeaders(w, resp.Header)
 	w.WriteHeader(resp.StatusCode)
 
+	// JSON模式（流式）：转发的同时缓存上游字节，流结束后校验
+	var jsonTap *jsonModeStreamTap
+	if reqCtx.jsonMode != nil && reqCtx.isStreaming {
+		jsonTap = &jsonModeStreamTap{ReadCloser: resp.Body}
+		resp.Body = jsonTap
+	}
+
+	// 响应内容打标：配置了规则时缓存响应前缀，转发结束后匹配（2026-10新增）
+	var labelTap *responseLabelTap
+	labelRules := s.loadResponseLabelRules()
+	if len(labelRules) > 0 {
+		labelTap = &responseLabelTap{ReadCloser: resp.Body}
+		resp.Body = labelTap
+	}
+
 	// 流式传输并解析usage
 	contentType := resp.Header.Get("Content-Type")
+	_, streamSpan := startSpan(reqCtx.ctx, "proxy.stream", spanKindInternal)
 	parser, streamErr := streamAndParseResponse(
 		reqCtx.ctx, resp.Body, w, contentType, channelType, reqCtx.isStreaming,
 	)
+	defer streamSpan.finish()
 
 	// 构建结果
 	result := &fwResult{
@@ -275,6 +321,9 @@ func (s *Server) handleSuccessResponse(
 		Header:        hdrClone,
 		FirstByteTime: *firstBodyReadTimeSec,
 	}
+	if readStats != nil {
+		result.ResponseBytes = readStats.totalBytes
+	}
 
 	// 提取usage数据和错误事件
 	var streamComplete bool
@@ -321,6 +370,32 @@ func (s *Server) handleSuccessResponse(
 		}
 	}
 
+	// JSON模式流尾校验：仅在流完整结束时进行
+	if jsonTap != nil && streamErr == nil && result.StreamDiagMsg == "" && result.SSEErrorEvent == nil {
+		result.JSONRepairMsg = checkJSONModeStreamTail(w, jsonTap, reqCtx.jsonMode, channelType)
+	}
+	if labelTap != nil {
+		result.Labels = classifyResponseText(labelRules, responseText(channelType, labelTap.buf.Bytes()))
+	}
+
+	if streamSpan != nil {
+		streamSpan.setAttr("ccload.streaming", reqCtx.isStreaming)
+		streamSpan.setAttr("ccload.stream_complete", streamComplete)
+		streamSpan.setAttr("ccload.input_tokens", result.InputTokens)
+		streamSpan.setAttr("ccload.output_tokens", result.OutputTokens)
+		if readStats != nil {
+			streamSpan.setAttr("ccload.stream_bytes", readStats.totalBytes)
+		}
+		switch {
+		case result.StreamDiagMsg != "":
+			streamSpan.setStatus(spanStatusError, result.StreamDiagMsg)
+		case result.SSEErrorEvent != nil:
+			streamSpan.setStatus(spanStatusError, "sse error event")
+		default:
+			streamSpan.setError(streamErr)
+		}
+	}
+
 	return result, reqCtx.Duration().Seconds(), streamErr
 }
 
@@ -361,7 +436,7 @@ func (s *Server) handleResponse(
 	// 流式请求：该时刻同时用于停止 firstByteTimeout。
 	firstBodyReadTimeSec := 0.0
 	readStats := &streamReadStats{}
-	resp.Body = &firstByteDetector{
+	detector := &firstByteDetector{
 		ReadCloser: resp.Body,
 		stats:      readStats,
 		onFirstRead: func() {
@@ -370,6 +445,7 @@ func (s *Server) handleResponse(
 			}
 			if firstBodyReadTimeSec == 0 {
 				firstBodyReadTimeSec = reqCtx.Duration().Seconds()
+				spanFromContext(reqCtx.ctx).addEvent("first_byte")
 			}
 			if reqCtx.isStreaming && observer != nil && observer.OnFirstByteRead != nil {
 				observer.OnFirstByteRead()
@@ -381,6 +457,10 @@ func (s *Server) handleResponse(
 			}
 		},
 	}
+	if observer != nil {
+		detector.onData = observer.OnResponseData
+	}
+	resp.Body = detector
 
 	// [INFO] 软错误检测：200状态码但响应体包含明确错误信息（如"当前模型负载过高"）
 	// 检测条件：Content-Type为text/plain或application/json
@@ -450,6 +530,20 @@ func (s *Server) handleResponse(
 		}, duration, err
 	}
 
+	// JSON模式输出修复（非流式）：提交响应前读取完整响应体进行修复/校验
+	if reqCtx.jsonMode != nil && !reqCtx.isStreaming {
+		if res, duration, err := s.enforceJSONModeResponse(reqCtx, resp, hdrClone, w, channelType, readStats, &firstBodyReadTimeSec); res != nil {
+			return res, duration, err
+		}
+	}
+
+	// 响应缓冲窗口：提交响应头前先缓冲SSE首段，窗口内失败仍可无感重试
+	if s.shouldBufferResponse(reqCtx, resp) {
+		if res, duration, err := s.bufferStreamingResponse(reqCtx, resp, hdrClone, channelType, &firstBodyReadTimeSec); res != nil {
+			return res, duration, err
+		}
+	}
+
 	// 成功状态：流式转发（传递渠道信息用于日志记录，传递观测回调）
 	return s.handleSuccessResponse(reqCtx, resp, hdrClone, w, channelType, readStats, &firstBodyReadTimeSec)
 }
@@ -463,6 +557,31 @@ func (s *Server) handleResponse(
 // 参数新增 apiKey 用于直接传递已选中的API Key（从KeySelector获取）
 // 参数新增 method 用于支持任意HTTP方法（GET、POST、PUT、DELETE等）
 func (s *Server) forwardOnceAsync(ctx context.Context, cfg *model.Config, apiKey string, method string, body []byte, hdr http.Header, rawQuery, requestPath string, w http.ResponseWriter, observer *ForwardObserver) (*fwResult, float64, error) {
+	// 链路追踪：上游调用span（首字节/微重试记为事件，流式转发为子span）
+	ctx, span := startSpan(ctx, "proxy.upstream", spanKindClient)
+	if span != nil {
+		span.setAttr("http.request.method", method)
+		span.setAttr("url.path", requestPath)
+		if u, err := url.Parse(cfg.URL); err == nil {
+			span.setAttr("server.address", u.Host)
+		}
+		span.setAttr("http.request.body.size", len(body))
+	}
+	res, duration, err := s.forwardOnce(ctx, cfg, apiKey, method, body, hdr, rawQuery, requestPath, w, observer)
+	if span != nil {
+		if res != nil {
+			span.setHTTPStatus(res.Status)
+			span.setAttr("ccload.first_byte_seconds", res.FirstByteTime)
+			span.setAttr("http.response.body.size", res.ResponseBytes)
+		}
+		span.setError(err)
+		span.finish()
+	}
+	return res, duration, err
+}
+
+// forwardOnce forwardOnceAsync 的实现（不含追踪埋点）
+func (s *Server) forwardOnce(ctx context.Context, cfg *model.Config, apiKey string, method string, body []byte, hdr http.Header, rawQuery, requestPath string, w http.ResponseWriter, observer *ForwardObserver) (*fwResult, float64, error) {
 	// 1. 创建请求上下文（处理超时）
 	reqCtx := s.newRequestContext(ctx, requestPath, body)
 	defer reqCtx.cleanup() // [INFO] 统一清理：定时器 + context（总是安全）
@@ -472,9 +591,25 @@ func (s *Server) forwardOnceAsync(ctx context.Context, cfg *model.Config, apiKey
 	if err != nil {
 		return nil, 0, err
 	}
+	if observer != nil && observer.OnUpstreamRequest != nil {
+		observer.OnUpstreamRequest(req, body)
+	}
 
-	// 3. 发送请求
-	resp, err := s.client.Do(req)
+	// 3. 发送请求（socket瞬断且可安全重放时用新连接微重试一次）
+	trace := &forwardTrace{}
+	req = trace.attach(req)
+	resp, err := s.httpClientFor(cfg).Do(req)
+	if err != nil {
+		if retryResp, retryErr := s.microRetryForward(reqCtx.ctx, cfg, req, trace, err); retryResp != nil {
+			spanFromContext(ctx).addEvent("micro_retry", traceAttr{"error.message", err.Error()})
+			resp, err = retryResp, nil
+		} else {
+			err = retryErr
+		}
+	}
+	if err == nil {
+		decodeUpstreamResponse(resp, cfg) // 强制Accept-Encoding的渠道需自行解压
+	}
 
 	// [INFO] 修复（2025-12）：客户端取消时主动关闭 response body，立即中断上游传输
 	// 问题：streamCopy 中的 Read 阻塞时，无法立即响应 context 取消，上游继续生成完整响应
@@ -504,7 +639,7 @@ func (s *Server) forwardOnceAsync(ctx context.Context, cfg *model.Config, apiKey
 	// 4. 处理响应(传递channelType用于精确识别usage格式,传递渠道信息用于日志记录,传递观测回调)
 	var res *fwResult
 	var duration float64
-	res, duration, err = s.handleResponse(reqCtx, resp, w, cfg.ChannelType, cfg, apiKey, observer)
+	res, duration, err = s.handleResponse(reqCtx, resp, w, upstreamProtocol(cfg, requestPath), cfg, apiKey, observer)
 
 	// [FIX] 2025-12: 流式传输过程中首字节超时的错误修正
 	// 场景：响应头已收到(200 OK)，但在读取响应体时超时定时器触发
@@ -539,13 +674,34 @@ func (s *Server) forwardAttempt(
 	actualModel string, // [INFO] 重定向后的实际模型名称
 	bodyToSend []byte,
 	w http.ResponseWriter,
-) (*proxyResult, cooldown.Action) {
+) (result *proxyResult, action cooldown.Action) {
+	// 链路追踪：单次Key尝试span
+	ctx, span := startSpan(ctx, "proxy.attempt", spanKindInternal)
+	if span != nil {
+		span.setAttr("ccload.key_index", keyIndex)
+		span.setAttr("ccload.model", actualModel)
+		defer func() {
+			span.setAttr("ccload.next_action", traceActionName(action))
+			span.finishProxyResult(result, nil)
+		}()
+	}
+
 	// 记录渠道尝试开始时间（用于日志记录，每次渠道/Key切换时更新）
 	reqCtx.attemptStartTime = time.Now()
+	reqCtx.failover.recordAttempt(cfg.GetChannelType())
+
+	// 渠道开启请求抓取时记录入站/出站请求（2026-10新增）
+	observer, capture := s.beginRequestCapture(cfg, keyIndex, reqCtx, actualModel)
 
 	// 转发请求（传递实际的API Key字符串和观测回调）
 	res, duration, err := s.forwardOnceAsync(ctx, cfg, selectedKey, reqCtx.requestMethod,
-		bodyToSend, reqCtx.header, reqCtx.rawQuery, reqCtx.requestPath, w, reqCtx.observer)
+		bodyToSend, reqCtx.header, reqCtx.rawQuery, reqCtx.requestPath, w, observer)
+	s.finishRequestCapture(capture, res, duration, err)
+
+	// 记录上游报告的Key剩余配额（成功/失败响应头均可能携带）
+	if res != nil {
+		s.recordKeyQuota(ctx, cfg.ID, keyIndex, res.Header)
+	}
 
 	// 处理网络错误或异常响应（如空响应）
 	// [INFO] 修复：handleResponse可能返回err即使StatusCode=200（例如Content-Length=0）
@@ -604,6 +760,16 @@ func (s *Server) forwardAttempt(
 // tryChannelWithKeys 在单个渠道内尝试多个Key（Key级重试）
 // 从proxy.go提取，遵循SRP原则
 func (s *Server) tryChannelWithKeys(ctx context.Context, cfg *model.Config, reqCtx *proxyRequestContext, w http.ResponseWriter) (*proxyResult, error) {
+	// 链路追踪：渠道级span（Key重试记为事件）
+	ctx, span := startSpan(ctx, "proxy.channel", spanKindInternal)
+	span.setChannelAttrs(cfg)
+	result, err := s.tryChannelKeys(ctx, cfg, reqCtx, w)
+	span.finishProxyResult(result, err)
+	return result, err
+}
+
+// tryChannelKeys tryChannelWithKeys 的实现（不含渠道级追踪埋点）
+func (s *Server) tryChannelKeys(ctx context.Context, cfg *model.Config, reqCtx *proxyRequestContext, w http.ResponseWriter) (*proxyResult, error) {
 	makeCtxDoneResult := func(ctxErr error) *proxyResult {
 		status := util.StatusClientClosedRequest
 		isClientCanceled := errors.Is(ctxErr, context.Canceled)
@@ -647,6 +813,97 @@ func (s *Server) tryChannelWithKeys(ctx context.Context, cfg *model.Config, reqC
 	// 准备请求体（处理模型重定向）
 	// [INFO] 修复：保存重定向后的模型名称，用于日志记录和调试
 	actualModel, bodyToSend := prepareRequestBody(cfg, reqCtx)
+	bodyToSend = util.StripDisabledBetaBody(cfg.BetaFeatures, bodyToSend) // 移除渠道不支持的beta功能字段
+
+	// 渠道/模型并发上限（2026-10新增）：槽位已满时按配置排队或溢出到下一个渠道
+	releaseSlot, ok := s.modelConcurrency.acquire(ctx, cfg, actualModel)
+	if !ok {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return makeCtxDoneResult(ctxErr), nil
+		}
+		return nil, ErrModelConcurrencyFull
+	}
+	defer releaseSlot()
+
+	// 旧版 Text Completions → Messages 转换（2026-10新增）：gemini 渠道随后经下方 Anthropic → Gemini 转换
+	var completeBridge *legacyCompleteWriter
+	if legacyCompleteBridgeEnabled(cfg, reqCtx) {
+		bridged, converted, writer, convErr := prepareLegacyCompleteBridge(cfg, reqCtx, bodyToSend, w)
+		if convErr != nil {
+			s.logConversionFailure(reqCtx, cfg, actualModel, convErr)
+			return &proxyResult{
+				status:     http.StatusBadRequest,
+				body:       convertGeminiErrorToAnthropic(http.StatusBadRequest, []byte(convErr.Error())),
+				channelID:  &cfg.ID,
+				nextAction: cooldown.ActionReturnClient,
+			}, nil
+		}
+		reqCtx, bodyToSend = bridged, converted
+		completeBridge = writer
+		w = completeBridge
+	}
+
+	// OpenAI Chat Completions → 渠道协议转换（2026-10新增）：gemini 渠道转换为 Messages 后继续经下方 Gemini 转换
+	var chatBridge *openaiChatWriter
+	if openaiBridgeEnabled(cfg, reqCtx) {
+		bridged, converted, writer, convErr := prepareOpenAIBridge(cfg, reqCtx, bodyToSend, w)
+		if convErr != nil {
+			s.logConversionFailure(reqCtx, cfg, actualModel, convErr)
+			return &proxyResult{
+				status:     http.StatusBadRequest,
+				body:       openaiErrorBody(http.StatusBadRequest, convErr.Error()),
+				channelID:  &cfg.ID,
+				nextAction: cooldown.ActionReturnClient,
+			}, nil
+		}
+		reqCtx, bodyToSend = bridged, converted
+		chatBridge = writer
+		w = chatBridge
+	}
+
+	// Anthropic → Gemini 协议转换（2026-10新增）：改写本渠道尝试的请求体/路径，响应经包装写回
+	var bridge *anthropicBridgeWriter
+	if anthropicBridgeEnabled(cfg, reqCtx) || (chatBridge != nil && cfg.GetChannelType() == util.ChannelTypeGemini) {
+		converted, stream, convErr := convertAnthropicToGemini(bodyToSend, reqCtx.header)
+		if convErr != nil {
+			s.logConversionFailure(reqCtx, cfg, actualModel, convErr)
+			errBody := convertGeminiErrorToAnthropic(http.StatusBadRequest, []byte(convErr.Error()))
+			if chatBridge != nil {
+				errBody = openaiErrorBody(http.StatusBadRequest, convErr.Error())
+			}
+			return &proxyResult{
+				status:     http.StatusBadRequest,
+				body:       errBody,
+				channelID:  &cfg.ID,
+				nextAction: cooldown.ActionReturnClient,
+			}, nil
+		}
+		bridged := *reqCtx
+		bridged.requestPath, bridged.rawQuery = geminiBridgePath(actualModel, stream)
+		bridged.header = geminiBridgeHeader(reqCtx.header)
+		bridged.protocolConverted = true
+		reqCtx, bodyToSend = &bridged, converted
+		bridge = newAnthropicBridgeWriter(w, reqCtx.originalModel, stream)
+		w = bridge
+	}
+
+	// Anthropic → OpenAI Chat Completions 协议转换（2026-10新增）：openai 渠道承接 Messages 请求
+	var chatCompatBridge *anthropicOpenAIWriter
+	if anthropicOpenAIBridgeEnabled(cfg, reqCtx) {
+		bridged, converted, writer, convErr := prepareAnthropicOpenAIBridge(reqCtx, bodyToSend, actualModel, w)
+		if convErr != nil {
+			s.logConversionFailure(reqCtx, cfg, actualModel, convErr)
+			return &proxyResult{
+				status:     http.StatusBadRequest,
+				body:       convertGeminiErrorToAnthropic(http.StatusBadRequest, []byte(convErr.Error())),
+				channelID:  &cfg.ID,
+				nextAction: cooldown.ActionReturnClient,
+			}, nil
+		}
+		reqCtx, bodyToSend = bridged, converted
+		chatCompatBridge = writer
+		w = chatCompatBridge
+	}
 
 	// Key重试循环
 	for range maxKeyRetries {
@@ -655,8 +912,8 @@ func (s *Server) tryChannelWithKeys(ctx context.Context, cfg *model.Config, reqC
 			return makeCtxDoneResult(ctxErr), nil
 		}
 
-		// 选择可用的API Key（直接传入apiKeys，避免重复查询）
-		keyIndex, selectedKey, selectErr := s.keySelector.SelectAvailableKey(cfg.ID, apiKeys, triedKeys)
+		// 选择可用的API Key（直接传入apiKeys，避免重复查询；会话绑定的Key优先）
+		keyIndex, selectedKey, selectErr := s.selectKeyWithAffinity(cfg, reqCtx, apiKeys, triedKeys)
 		if selectErr != nil {
 			// 所有Key都在冷却中，返回特殊错误标识（使用sentinel error而非魔法字符串）
 			return nil, fmt.Errorf("%w: %v", ErrAllKeysUnavailable, selectErr)
@@ -675,14 +932,57 @@ func (s *Server) tryChannelWithKeys(ctx context.Context, cfg *model.Config, reqC
 		result, nextAction := s.forwardAttempt(
 			ctx, cfg, keyIndex, selectedKey, reqCtx, actualModel, bodyToSend, w)
 
+		if bridge != nil && result != nil {
+			if result.succeeded {
+				// 流中途出错（597/599）同样标记为succeeded，此时不补发 message_stop
+				bridge.finishResponse(result.status >= 200 && result.status < 300)
+			} else if len(result.body) > 0 && !result.isClientCanceled {
+				result.body = convertGeminiErrorToAnthropic(result.status, result.body)
+				if result.header != nil {
+					result.header = result.header.Clone()
+					result.header.Set("Content-Type", "application/json")
+				}
+			}
+		}
+		if chatCompatBridge != nil && result != nil {
+			if result.succeeded {
+				chatCompatBridge.finishResponse(result.status >= 200 && result.status < 300)
+			} else if len(result.body) > 0 && !result.isClientCanceled {
+				result.body = convertGeminiErrorToAnthropic(result.status, result.body)
+				if result.header != nil {
+					result.header = result.header.Clone()
+					result.header.Set("Content-Type", "application/json")
+				}
+			}
+		}
+		if completeBridge != nil && result != nil && result.succeeded {
+			// 错误体格式与 Messages API 相同，无需转换
+			completeBridge.finishResponse(result.status >= 200 && result.status < 300)
+		}
+		if chatBridge != nil && result != nil {
+			if result.succeeded {
+				chatBridge.finishResponse(result.status >= 200 && result.status < 300)
+			} else if len(result.body) > 0 && !result.isClientCanceled {
+				result.body = convertErrorToOpenAI(result.status, result.body)
+				if result.header != nil {
+					result.header = result.header.Clone()
+					result.header.Set("Content-Type", "application/json")
+				}
+			}
+		}
+
 		if result != nil {
 			if result.succeeded {
+				if result.status >= 200 && result.status < 300 {
+					s.sessionAffinity.pin(reqCtx.affinityKey, cfg.ID, keyIndex, time.Now())
+				}
 				return result, nil
 			}
 			lastFailure = result
 		}
 
 		if nextAction == cooldown.ActionRetryKey {
+			spanFromContext(ctx).addEvent("key_retry", traceAttr{"ccload.key_index", keyIndex})
 			continue
 		}
 		if nextAction == cooldown.ActionRetryChannel {
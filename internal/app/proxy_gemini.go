@@ -1,38 +1,103 @@
 package app
 
 import (
+	"context"
 	"net/http"
+	"slices"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ============================================================================
-// Gemini API 特殊处理
+// 模型列表（/v1/models、/v1beta/models）
 // ============================================================================
 
-// handleListGeminiModels 处理 GET /v1beta/models 请求，返回本地 Gemini 模型列表
+// listedModel 模型列表条目：模型名与提供该模型的启用渠道类型（按 util.ChannelTypes 顺序）
+type listedModel struct {
+	Name         string
+	ChannelTypes []string
+}
+
+// listTokenModels 汇总全部启用渠道中令牌可用的具体模型（2026-10新增），按模型名排序。
+// 按令牌 allowed_models 与全局/令牌 blocked_models 过滤；通配符条目不直接列出，
+// 令牌有 allowed_models 时展开为其中匹配的模型
+func (s *Server) listTokenModels(ctx context.Context, tokenHash string) ([]listedModel, error) {
+	var allowed []string
+	if tokenHash != "" && s.authService != nil {
+		allowed = s.authService.AllowedModels(tokenHash)
+	}
+	index := make(map[string]int)
+	var models []listedModel
+	add := func(name, channelType string) {
+		if !s.isModelUsable(tokenHash, name) {
+			return
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(models)
+			index[name] = i
+			models = append(models, listedModel{Name: name})
+		}
+		if !slices.Contains(models[i].ChannelTypes, channelType) {
+			models[i].ChannelTypes = append(models[i].ChannelTypes, channelType)
+		}
+	}
+	for _, ct := range util.ChannelTypes {
+		channels, err := s.GetEnabledChannelsByType(ctx, ct.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, cfg := range channels {
+			for _, name := range cfg.GetModels() {
+				if !model.IsModelPattern(name) {
+					add(name, ct.Value)
+					continue
+				}
+				for _, a := range allowed {
+					if !model.IsModelPattern(a) && model.MatchModelPattern(strings.ToLower(name), strings.ToLower(a)) {
+						add(a, ct.Value)
+					}
+				}
+			}
+		}
+	}
+	slices.SortFunc(models, func(a, b listedModel) int { return strings.Compare(a.Name, b.Name) })
+	return models, nil
+}
+
+// requestTokenHash 当前请求的令牌哈希（未鉴权时为空）
+func requestTokenHash(c *gin.Context) string {
+	tokenHash, _ := c.Get("token_hash")
+	s, _ := tokenHash.(string)
+	return s
+}
+
+// handleListGeminiModels 处理 GET /v1beta/models 请求，返回调用方令牌可用的聚合模型列表（Gemini 格式）
 // 从proxy.go提取，遵循SRP原则
 func (s *Server) handleListGeminiModels(c *gin.Context) {
-	ctx := c.Request.Context()
-
-	// 获取所有 gemini 渠道的去重模型列表
-	models, err := s.getModelsByChannelType(ctx, "gemini")
+	models, err := s.listTokenModels(c.Request.Context(), requestTokenHash(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load models"})
 		return
 	}
 
-	// 构造 Gemini API 响应格式
+	// 构造 Gemini API 响应格式（channelTypes 为扩展字段）
 	type ModelInfo struct {
-		Name        string `json:"name"`
-		DisplayName string `json:"displayName"`
+		Name         string   `json:"name"`
+		DisplayName  string   `json:"displayName"`
+		ChannelTypes []string `json:"channelTypes"`
 	}
 
 	modelList := make([]ModelInfo, 0, len(models))
-	for _, model := range models {
+	for _, m := range models {
 		modelList = append(modelList, ModelInfo{
-			Name:        "models/" + model,
-			DisplayName: formatModelDisplayName(model),
+			Name:         "models/" + m.Name,
+			DisplayName:  formatModelDisplayName(m.Name),
+			ChannelTypes: m.ChannelTypes,
 		})
 	}
 
@@ -41,12 +106,10 @@ func (s *Server) handleListGeminiModels(c *gin.Context) {
 	})
 }
 
-// handleListOpenAIModels 处理 GET /v1/models 请求，返回本地 OpenAI 模型列表
+// handleListOpenAIModels 处理 GET /v1/models 请求，返回调用方令牌可用的聚合模型列表（OpenAI 格式）
+// owned_by 为首个提供该模型的渠道类型，channel_types 列出全部渠道类型
 func (s *Server) handleListOpenAIModels(c *gin.Context) {
-	ctx := c.Request.Context()
-
-	// 获取所有 openai 渠道的去重模型列表
-	models, err := s.getModelsByChannelType(ctx, "openai")
+	models, err := s.listTokenModels(c.Request.Context(), requestTokenHash(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load models"})
 		return
@@ -54,19 +117,21 @@ func (s *Server) handleListOpenAIModels(c *gin.Context) {
 
 	// 构造 OpenAI API 响应格式
 	type ModelInfo struct {
-		ID      string `json:"id"`
-		Object  string `json:"object"`
-		Created int64  `json:"created"`
-		OwnedBy string `json:"owned_by"`
+		ID           string   `json:"id"`
+		Object       string   `json:"object"`
+		Created      int64    `json:"created"`
+		OwnedBy      string   `json:"owned_by"`
+		ChannelTypes []string `json:"channel_types"`
 	}
 
 	modelList := make([]ModelInfo, 0, len(models))
-	for _, model := range models {
+	for _, m := range models {
 		modelList = append(modelList, ModelInfo{
-			ID:      model,
-			Object:  "model",
-			Created: 0,
-			OwnedBy: "system",
+			ID:           m.Name,
+			Object:       "model",
+			Created:      0,
+			OwnedBy:      m.ChannelTypes[0],
+			ChannelTypes: m.ChannelTypes,
 		})
 	}
 
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListModels_AggregatedPerToken(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "models.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	channels := []*model.Config{
+		{Name: "claude", URL: "https://a.invalid", ChannelType: "anthropic", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}, {Model: "claude-opus-4-1"}, {Model: "claude-*"}}},
+		{Name: "oa", URL: "https://o.invalid", ChannelType: "openai", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "gpt-4o"}, {Model: "claude-sonnet-4-5"}}},
+		{Name: "gm", URL: "https://g.invalid", ChannelType: "gemini", Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "gemini-2.5-pro"}}},
+		{Name: "off", URL: "https://x.invalid", ChannelType: "openai", Enabled: false,
+			ModelEntries: []model.ModelEntry{{Model: "gpt-disabled"}}},
+	}
+	for _, cfg := range channels {
+		if _, err := store.CreateConfig(ctx, cfg); err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+	}
+	token := &model.AuthToken{Token: "hash-models", Description: "t", CreatedAt: time.Now(), IsActive: true,
+		AllowedModels: []string{"claude-sonnet-4-5", "claude-opus-4-1", "claude-haiku-4-5", "gpt-4o"}, BlockedModels: []string{"claude-opus-*"}}
+	if err := store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+
+	call := func(handler gin.HandlerFunc, path, tokenHash string) []byte {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		if tokenHash != "" {
+			c.Set("token_hash", tokenHash)
+		}
+		handler(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s 返回 %d: %s", path, w.Code, w.Body.String())
+		}
+		return w.Body.Bytes()
+	}
+
+	var openaiResp struct {
+		Object string `json:"object"`
+		Data   []struct {
+			ID           string   `json:"id"`
+			OwnedBy      string   `json:"owned_by"`
+			ChannelTypes []string `json:"channel_types"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(call(srv.handleListOpenAIModels, "/v1/models", token.Token), &openaiResp); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	// 令牌：opus 被屏蔽、gemini 不在允许列表；haiku 经通配符渠道可用；同名模型合并渠道类型；禁用渠道不列出
+	if openaiResp.Object != "list" || len(openaiResp.Data) != 3 ||
+		openaiResp.Data[0].ID != "claude-haiku-4-5" || openaiResp.Data[0].OwnedBy != "anthropic" ||
+		openaiResp.Data[1].ID != "claude-sonnet-4-5" ||
+		!slices.Equal(openaiResp.Data[1].ChannelTypes, []string{"anthropic", "openai"}) ||
+		openaiResp.Data[2].ID != "gpt-4o" || openaiResp.Data[2].OwnedBy != "openai" {
+		t.Fatalf("OpenAI 模型列表不符: %+v", openaiResp)
+	}
+
+	var geminiResp struct {
+		Models []struct {
+			Name         string   `json:"name"`
+			DisplayName  string   `json:"displayName"`
+			ChannelTypes []string `json:"channelTypes"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(call(srv.handleListGeminiModels, "/v1beta/models", ""), &geminiResp); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	var names []string
+	for _, m := range geminiResp.Models {
+		names = append(names, m.Name)
+	}
+	if !slices.Equal(names, []string{"models/claude-opus-4-1", "models/claude-sonnet-4-5", "models/gemini-2.5-pro", "models/gpt-4o"}) ||
+		geminiResp.Models[2].ChannelTypes[0] != "gemini" {
+		t.Fatalf("无令牌时应列出全部具体模型（通配符不展开）: %+v", geminiResp)
+	}
+}
@@ -116,7 +116,11 @@ func (s *Server) selectRouteCandidates(ctx context.Context, c *gin.Context, orig
 	// 智能路由选择：根据请求类型选择不同的路由策略
 	if requestMethod == http.MethodGet && util.DetectChannelTypeFromPath(requestPath) == util.ChannelTypeGemini {
 		// 按渠道类型筛选Gemini渠道
-		return s.selectCandidatesByChannelType(ctx, util.ChannelTypeGemini)
+		cands, err := s.selectCandidatesByChannelType(ctx, util.ChannelTypeGemini)
+		if err != nil {
+			return nil, err
+		}
+		return s.preferClientRegion(c.ClientIP(), cands), nil
 	}
 
 	channelType := util.DetectChannelTypeFromPath(requestPath)
@@ -124,7 +128,40 @@ func (s *Server) selectRouteCandidates(ctx context.Context, c *gin.Context, orig
 		return nil, errUnknownChannelType
 	}
 
-	return s.selectCandidatesByModelAndType(ctx, originalModel, channelType)
+	cands, err := s.selectCandidatesByModelAndType(ctx, originalModel, channelType)
+	if err != nil {
+		return nil, err
+	}
+	cands = s.preferClientRegion(c.ClientIP(), cands)
+	if compatChannelsAllowed(channelType, requestMethod, requestPath) {
+		return cands, nil
+	}
+	return filterExactChannelType(cands, channelType), nil
+}
+
+// compatChannelsAllowed 该请求是否可由协议转换的兼容渠道承接
+func compatChannelsAllowed(channelType, requestMethod, requestPath string) bool {
+	switch {
+	case channelType == util.ChannelTypeAnthropic && !isAnthropicMessagesRequest(requestMethod, requestPath) &&
+		!isLegacyCompleteRequest(requestMethod, requestPath):
+		// 协议转换仅支持 POST /v1/messages（及经其转换的 /v1/complete），其余 Anthropic 路径剔除 gemini 兼容渠道
+		return false
+	case channelType == util.ChannelTypeOpenAI && !isOpenAIChatRequest(requestMethod, requestPath):
+		// 协议转换仅支持 POST /v1/chat/completions，其余 OpenAI 路径剔除兼容渠道
+		return false
+	}
+	return true
+}
+
+// filterExactChannelType 原地保留协议完全一致的渠道（剔除兼容渠道，azure 视为 openai，mock 回应任意协议）
+func filterExactChannelType(cands []*model.Config, channelType string) []*model.Config {
+	filtered := cands[:0]
+	for _, cfg := range cands {
+		if util.ProtocolChannelType(cfg.GetChannelType()) == channelType || isMockChannel(cfg) {
+			filtered = append(filtered, cfg)
+		}
+	}
+	return filtered
 }
 
 // ============================================================================
@@ -155,6 +192,18 @@ func (s *Server) handleSpecialRoutes(c *gin.Context) bool {
 func (s *Server) HandleProxyRequest(c *gin.Context) {
 	startTime := time.Now()
 
+	// 链路追踪根span（2026-10新增）：覆盖排队、选路、各渠道/Key尝试与流式转发
+	if traceCtx, span := s.tracer.startRequest(c.Request.Context(), c.Request.Header, "proxy.request", startTime); span != nil {
+		c.Request = c.Request.WithContext(traceCtx)
+		c.Header(headerCCLoadTraceID, span.TraceID())
+		span.setAttr("http.request.method", c.Request.Method)
+		span.setAttr("url.path", c.Request.URL.Path)
+		defer func() {
+			span.setHTTPStatus(c.Writer.Status())
+			span.finish()
+		}()
+	}
+
 	// 并发控制
 	release, ok := s.acquireConcurrencySlot(c)
 	if !ok {
@@ -167,6 +216,17 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		return
 	}
 
+	// 令牌速率限制：只计入代理到上游的模型请求（特殊路由在本地应答，不占用配额）
+	if v, ok := c.Get("token_hash"); ok {
+		if tokenHash, _ := v.(string); tokenHash != "" {
+			if exceeded, retryAfter := s.authService.CheckRateLimit(tokenHash); exceeded != "" {
+				c.Header("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded (" + exceeded + ")"})
+				return
+			}
+		}
+	}
+
 	requestPath := c.Request.URL.Path
 	requestMethod := c.Request.Method
 
@@ -180,11 +240,61 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		return
 	}
 
+	// 请求体预校验（2026-10新增）：畸形请求本地返回400，不消耗上游尝试/不触发冷却
+	if s.shouldValidateRequest(requestMethod, requestPath) {
+		if err := validateAnthropicMessagesRequest(all); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"type":    "invalid_request_error",
+					"message": err.Error(),
+				},
+			})
+			return
+		}
+	}
+
+	// 单次请求重定向覆盖（2026-10新增）：仅携带有效管理员Token时生效
+	override, err := parseRedirectOverride(c.Request.Header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if override != nil && !s.authService.isAdminRoleToken(c.GetHeader(headerCCLoadAdminToken)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "redirect override headers require a valid " + headerCCLoadAdminToken})
+		return
+	}
+
 	tokenHashStr := ""
 	if v, ok := c.Get("token_hash"); ok {
 		tokenHashStr, _ = v.(string)
 	}
 
+	// 全局模型别名（2026-10新增）：选路前改写为目标模型，后续策略检查与选路均按目标模型进行
+	if originalModel != "" && originalModel != "*" {
+		if target, ok := s.resolveModelAlias(c.Request.Context(), originalModel); ok {
+			if body, ok := rewriteRequestModel(all, originalModel, target); ok {
+				c.Header(headerCCLoadModelAlias, originalModel+" -> "+target)
+				originalModel, all = target, body
+			}
+		}
+	}
+
+	// 模型屏蔽策略（2026-10新增）：全局/令牌级屏蔽在选路前拒绝，并给出可用的替代模型
+	if originalModel != "" {
+		if rule, scope := s.blockedModelRule(tokenHashStr, originalModel); rule != "" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": gin.H{
+					"type":                 "permission_error",
+					"code":                 "model_blocked",
+					"message":              fmt.Sprintf("model '%s' is blocked by %s policy (rule '%s')", originalModel, scope, rule),
+					"blocked_by":           scope,
+					"allowed_alternatives": s.blockedModelAlternatives(c.Request.Context(), requestPath, tokenHashStr, originalModel),
+				},
+			})
+			return
+		}
+	}
+
 	// 检查令牌模型限制（2026-01新增）
 	if tokenHashStr != "" && originalModel != "" {
 		if !s.authService.IsModelAllowed(tokenHashStr, originalModel) {
@@ -205,6 +315,7 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		if exceeded {
 			used := util.MicroUSDToUSD(usedMicro)
 			limit := util.MicroUSDToUSD(limitMicro)
+			s.notifyTokenBudgetExceeded(tokenHashStr, usedMicro, limitMicro)
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": gin.H{
 					"message": fmt.Sprintf("Cost limit exceeded: $%.2f used of $%.2f limit", used, limit),
@@ -216,19 +327,54 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		}
 	}
 
+	// 非流式响应缓存（2026-10新增）：完全相同的请求在选路前直接返回缓存的响应
+	cacheKey, cacheModel := "", originalModel
+	if override == nil && s.responseCacheEligible(c.Request, isStreaming) {
+		cacheKey = responseCacheKey(requestMethod, requestPath, c.Request.URL.RawQuery,
+			s.responseCacheScope(tokenHashStr), c.Request.Header, all)
+		if e, ok := s.responseCache.get(cacheKey, time.Now()); ok {
+			s.serveCachedResponse(c, e, originalModel, startTime)
+			return
+		}
+		c.Header(headerCCLoadCache, "MISS")
+	}
+
 	// 注册活跃请求（内存状态，用于前端实时显示）
 	activeID := s.activeRequests.Register(startTime, originalModel, c.ClientIP(), isStreaming)
 	defer s.activeRequests.Remove(activeID)
 
+	// 管理员可从实时视图取消该请求（2026-10新增）
+	ctx, cancelReq := context.WithCancelCause(c.Request.Context())
+	defer cancelReq(nil)
+	s.activeRequests.SetCancel(activeID, cancelReq)
+
 	timeout := parseTimeout(c.Request.URL.Query(), c.Request.Header)
-	ctx := c.Request.Context()
 	var cancel context.CancelFunc
 	if timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
+	rootSpan := spanFromContext(ctx)
+	rootSpan.setAttr("ccload.model", originalModel)
+	rootSpan.setAttr("ccload.streaming", isStreaming)
+	_, selectSpan := startSpan(ctx, "proxy.select_channels", spanKindInternal)
 	cands, err := s.selectRouteCandidates(ctx, c, originalModel)
+	// 默认模型回退（2026-10新增）：请求模型未匹配任何启用渠道时按令牌策略改用默认模型重新选路
+	if err == nil && len(cands) == 0 {
+		if fallback, body, ok := s.resolveModelFallback(ctx, requestMethod, requestPath, tokenHashStr, originalModel, all); ok {
+			c.Header(headerCCLoadModelFallback, originalModel+" -> "+fallback)
+			selectSpan.setAttr("ccload.fallback_model", fallback)
+			originalModel, all = fallback, body
+			cands, err = s.selectRouteCandidates(ctx, c, originalModel)
+		}
+	}
+	if err == nil {
+		cands = filterByBetaFeatures(util.DetectBetaFeatures(c.Request.Header, all), cands)
+	}
+	selectSpan.setAttr("ccload.candidates", len(cands))
+	selectSpan.setError(err)
+	selectSpan.finish()
 	if err != nil {
 		if errors.Is(err, errUnknownChannelType) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "unsupported path"})
@@ -238,36 +384,80 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		return
 	}
 
+	// 请求体大小预检（2026-10新增）：跳过已知会拒绝该大小的渠道，全部会拒绝时本地返回413
+	if len(cands) > 0 {
+		kept, limit := s.requestSizes.filter(int64(len(all)), cands, time.Now())
+		if kept == nil {
+			s.AddLogAsync(&model.LogEntry{
+				Time:        model.JSONTime{Time: time.Now()},
+				Model:       originalModel,
+				StatusCode:  http.StatusRequestEntityTooLarge,
+				Message:     fmt.Sprintf("request body %d bytes exceeds known channel limits (rejected locally)", len(all)),
+				IsStreaming: isStreaming,
+				ClientIP:    c.ClientIP(),
+			})
+			c.JSON(http.StatusRequestEntityTooLarge, requestTooLargeBody(int64(len(all)), limit))
+			return
+		}
+		cands = kept
+	}
+
 	if len(cands) == 0 {
+		availability := s.buildChannelAvailability(ctx, requestMethod, requestPath, originalModel)
+		msg := availability.message()
 		s.AddLogAsync(&model.LogEntry{
 			Time:        model.JSONTime{Time: time.Now()},
 			Model:       originalModel,
 			StatusCode:  503,
-			Message:     "no available upstream (all cooled or none)",
+			Message:     msg,
 			IsStreaming: isStreaming,
 			ClientIP:    c.ClientIP(),
 		})
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no available upstream (all cooled or none)"})
+		resp := gin.H{"error": msg, "availability": availability}
+		if s.shouldAttachRetryHints(http.StatusServiceUnavailable) {
+			hints := s.buildRetryHints(ctx, originalModel, requestPath, tokenHashStr, nil)
+			setRetryAfterHeader(c.Writer, nil, hints)
+			resp["retry_hints"] = hints
+		}
+		c.JSON(http.StatusServiceUnavailable, resp)
 		return
 	}
 
+	// 会话粘性路由（2026-10新增）：会话绑定的渠道仍在候选中时优先尝试
+	affinityKey := ""
+	if s.sessionAffinity != nil {
+		affinityKey = sessionAffinityKey(tokenHashStr, c.Request.Header, all)
+		if pin, ok := s.sessionAffinity.get(affinityKey, time.Now()); ok {
+			cands = preferPinnedChannel(cands, pin.channelID)
+		}
+	}
+
 	// 从context提取tokenID（用于统计和日志，2025-12新增tokenID）
 	tokenID, _ := c.Get("token_id")
 	tokenIDInt64, _ := tokenID.(int64)
+	if tokenIDInt64 > 0 {
+		rootSpan.setAttr("ccload.token_id", tokenIDInt64)
+	}
+
+	// 输出Token异常限流（2026-10新增）：异常窗口内注入 max_tokens 上限
+	all = s.applyTokenAnomalyCap(tokenIDInt64, originalModel, requestPath, all)
 
 	reqCtx := &proxyRequestContext{
-		originalModel: originalModel,
-		requestMethod: requestMethod,
-		requestPath:   requestPath,
-		rawQuery:      c.Request.URL.RawQuery,
-		body:          all,
-		header:        c.Request.Header,
-		isStreaming:   isStreaming,
-		tokenHash:     tokenHashStr,
-		tokenID:       tokenIDInt64,
-		clientIP:      c.ClientIP(),
-		activeReqID:   activeID,
-		startTime:     startTime,
+		originalModel:    originalModel,
+		requestMethod:    requestMethod,
+		requestPath:      requestPath,
+		rawQuery:         c.Request.URL.RawQuery,
+		body:             all,
+		header:           c.Request.Header,
+		isStreaming:      isStreaming,
+		tokenHash:        tokenHashStr,
+		tokenID:          tokenIDInt64,
+		clientIP:         c.ClientIP(),
+		activeReqID:      activeID,
+		startTime:        startTime,
+		redirectOverride: override,
+		failover:         s.newFailoverInfo(tokenHashStr),
+		affinityKey:      affinityKey,
 		observer: &ForwardObserver{
 			OnBytesRead: func(n int64) {
 				s.activeRequests.AddBytes(activeID, n)
@@ -278,10 +468,22 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		},
 	}
 
+	// 令牌开启故障转移信息时，提交响应头时附带上游尝试情况（2026-10新增）
+	var w http.ResponseWriter = c.Writer
+	if reqCtx.failover != nil {
+		w = &failoverInfoWriter{ResponseWriter: c.Writer, info: reqCtx.failover}
+	}
+	// 响应缓存未命中：记录写给客户端的响应，成功后写入缓存
+	var cacheRec *responseCacheRecorder
+	if cacheKey != "" {
+		cacheRec = &responseCacheRecorder{ResponseWriter: w}
+		w = cacheRec
+	}
+
 	// 按优先级遍历候选渠道，尝试转发
 	var lastResult *proxyResult
 	for _, cfg := range cands {
-		result, err := s.tryChannelWithKeys(ctx, cfg, reqCtx, c.Writer)
+		result, err := s.tryChannelWithKeys(ctx, cfg, reqCtx, w)
 
 		// 所有Key冷却：触发渠道级冷却(503)，防止后续请求重复尝试
 		// 使用 cooldownManager.HandleError 统一处理（DRY原则）
@@ -291,6 +493,12 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 			continue
 		}
 
+		// 渠道/模型并发槽位已满：溢出到下一个渠道，不触发冷却（2026-10新增）
+		if err != nil && errors.Is(err, ErrModelConcurrencyFull) {
+			log.Printf("[INFO] 渠道 %s (ID=%d) 并发上限已满，尝试下一个渠道", cfg.Name, cfg.ID)
+			continue
+		}
+
 		// [WARN] 所有Key验证失败，尝试下一个渠道
 		if err != nil && errors.Is(err, ErrAllKeysExhausted) {
 			log.Printf("[WARN] 渠道 %s (ID=%d) 所有Key验证失败，跳过该渠道", cfg.Name, cfg.ID)
@@ -299,6 +507,9 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 
 		if result != nil {
 			if result.succeeded {
+				if cacheRec != nil {
+					s.storeCachedResponse(cacheKey, cacheModel, cacheRec)
+				}
 				return
 			}
 
@@ -315,6 +526,12 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		}
 	}
 
+	// 管理员取消：以终止错误结束响应（渠道级日志已记录）
+	if lastResult != nil && lastResult.isClientCanceled && errors.Is(context.Cause(ctx), errRequestCancelledByAdmin) {
+		writeAdminCancelledResponse(c, isStreaming)
+		return
+	}
+
 	// 所有渠道都失败：返回“最后一次实际失败”的状态码（并映射内部状态码），避免一律伪装成503。
 	finalStatus := determineFinalClientStatus(lastResult)
 
@@ -344,13 +561,56 @@ func (s *Server) HandleProxyRequest(c *gin.Context) {
 		})
 	}
 
+	// 429/5xx 附加重试提示（2026-10新增）
+	var hints *retryHints
+	if !(lastResult != nil && lastResult.isClientCanceled) && s.shouldAttachRetryHints(finalStatus) {
+		var upstreamHeader http.Header
+		if lastResult != nil {
+			upstreamHeader = lastResult.header
+		}
+		hints = s.buildRetryHints(ctx, originalModel, requestPath, tokenHashStr, upstreamHeader)
+		setRetryAfterHeader(c.Writer, upstreamHeader, hints)
+	}
+
 	if lastResult != nil && lastResult.status != 0 {
-		// 透明代理原则：透传所有上游响应（状态码+header+body）
-		writeResponseWithHeaders(c.Writer, finalStatus, lastResult.header, lastResult.body)
+		// 透明代理原则：透传所有上游响应（状态码+header+body），仅在JSON错误体中追加 retry_hints
+		body := lastResult.body
+		if hints != nil {
+			body = appendRetryHints(body, lastResult.header, hints)
+		}
+		writeResponseWithHeaders(w, finalStatus, lastResult.header, body)
 		return
 	}
 
-	c.JSON(finalStatus, gin.H{"error": "no upstream available"})
+	resp := gin.H{"error": "no upstream available"}
+	if finalStatus == http.StatusServiceUnavailable {
+		// 候选渠道均因Key不可用被跳过：附带渠道可用性摘要（2026-10新增）
+		availability := s.buildChannelAvailability(ctx, requestMethod, requestPath, originalModel)
+		resp["error"] = availability.message()
+		resp["availability"] = availability
+	}
+	if hints != nil {
+		resp["retry_hints"] = hints
+	}
+	reqCtx.failover.setHeaders(c.Writer.Header())
+	c.JSON(finalStatus, resp)
+}
+
+// adminCancelledErrorBody 管理员取消请求时返回给客户端的错误体（Anthropic/OpenAI 客户端均可识别 error.message）
+const adminCancelledErrorBody = `{"type":"error","error":{"type":"request_cancelled","code":"request_cancelled","message":"request cancelled by administrator"}}`
+
+// writeAdminCancelledResponse 管理员取消后结束客户端响应
+// 流式响应已开始输出时追加 SSE error 事件（客户端据此终止而非等待超时），否则返回 499
+func writeAdminCancelledResponse(c *gin.Context, isStreaming bool) {
+	if !c.Writer.Written() {
+		c.Data(StatusClientClosedRequest, "application/json", []byte(adminCancelledErrorBody))
+		return
+	}
+	if !isStreaming {
+		return // 非流式响应体已部分写出，无法追加错误
+	}
+	_, _ = c.Writer.WriteString("event: error\ndata: " + adminCancelledErrorBody + "\n\n")
+	c.Writer.Flush()
 }
 
 func determineFinalClientStatus(lastResult *proxyResult) int {
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ccLoad/internal/util"
+)
+
+// ============================================================================
+// 流式响应缓冲窗口（2026-10新增）
+// ============================================================================
+// 问题：响应头一旦写给客户端，后续失败就无法再切换渠道（已写出字节即禁止重试），
+// 而部分上游会在 200 + 响应头之后立刻发送 error 事件或直接断流。
+// 方案：SSE 成功响应先在本地缓冲首段数据，直到出现首个内容事件、达到缓冲上限或流结束，
+// 期间发现 error 事件/读错误/无结束标志的提前断流，都按普通失败处理（可无感重试其他渠道）。
+// 由系统设置 response_buffer_bytes 控制，默认 0（关闭），运维按需开启（建议 2048）：
+// 缓冲期间 message_start / ping 保活事件同样被暂存，长时间预填充或扩展思考的请求在首个内容事件前
+// 不会向客户端发送响应头与保活，客户端或中间代理的空闲超时需大于上游首个内容事件的耗时。
+
+// defaultResponseBufferBytes 默认缓冲窗口大小（0=关闭）
+const defaultResponseBufferBytes = 0
+
+// maxResponseBufferBytes 缓冲窗口上限（过大会明显推迟首字时间）
+const maxResponseBufferBytes = 64 * 1024
+
+// sseContentMarkers 视为"内容已开始"的SSE事件特征（Anthropic/OpenAI Responses/OpenAI Chat/Gemini）
+var sseContentMarkers = [][]byte{
+	[]byte(`"content_block_delta"`),
+	[]byte(`"response.output_text.delta"`),
+	[]byte(`"choices"`),
+	[]byte(`"candidates"`),
+}
+
+// errStreamEndedBeforeCommit 缓冲窗口内上游流结束但未出现结束标志
+var errStreamEndedBeforeCommit = errors.New("upstream stream ended before response was committed")
+
+// hasSSEContentEvent 判断缓冲数据中是否已有完整的内容事件
+// 只检查最后一个事件分隔符之前的部分，避免半个事件被误判为已开始输出
+func hasSSEContentEvent(buf []byte) bool {
+	end := bytes.LastIndex(buf, []byte("\n\n"))
+	if end < 0 {
+		return false
+	}
+	complete := buf[:end]
+	for _, marker := range sseContentMarkers {
+		if bytes.Contains(complete, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// readStreamPrefix 从 body 读取流首段，直到出现内容事件、达到 limit 或读取出错（含EOF）
+func readStreamPrefix(body io.Reader, limit int) ([]byte, error) {
+	buf := make([]byte, 0, limit)
+	chunk := make([]byte, limit)
+	for len(buf) < limit {
+		n, err := body.Read(chunk[:limit-len(buf)])
+		buf = append(buf, chunk[:n]...)
+		if err != nil {
+			return buf, err
+		}
+		if hasSSEContentEvent(buf) {
+			break
+		}
+	}
+	return buf, nil
+}
+
+// shouldBufferResponse 判断当前成功响应是否需要经过缓冲窗口
+func (s *Server) shouldBufferResponse(reqCtx *requestContext, resp *http.Response) bool {
+	if s.responseBufferBytes <= 0 || !reqCtx.isStreaming {
+		return false
+	}
+	return strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// bufferStreamingResponse 在提交响应前缓冲SSE首段数据并检查失败信号
+// 返回非nil结果表示本次尝试已按失败处理（尚未向客户端写出任何字节，可重试）；
+// 返回nil结果时缓冲数据已放回 resp.Body，调用方照常提交响应。
+func (s *Server) bufferStreamingResponse(
+	reqCtx *requestContext,
+	resp *http.Response,
+	hdrClone http.Header,
+	channelType string,
+	firstBodyReadTimeSec *float64,
+) (*fwResult, float64, error) {
+	prefix, readErr := readStreamPrefix(resp.Body, s.responseBufferBytes)
+
+	// 客户端取消/超时：保持原有语义（由上层识别499或首字节超时）
+	if ctxErr := reqCtx.ctx.Err(); ctxErr != nil && readErr != nil && readErr != io.EOF {
+		return &fwResult{Status: resp.StatusCode, Header: hdrClone, FirstByteTime: *firstBodyReadTimeSec},
+			reqCtx.Duration().Seconds(), ctxErr
+	}
+
+	parser := newSSEUsageParser(channelType)
+	_ = parser.Feed(prefix)
+
+	// 窗口内出现 error 事件：转为 597/596 交给错误处理流程（与软错误检测一致）
+	if errorEvent := parser.GetLastError(); errorEvent != nil {
+		if _, is1308 := util.ParseResetTimeFrom1308Error(errorEvent); is1308 {
+			resp.StatusCode = util.StatusQuotaExceeded // 596
+		} else {
+			resp.StatusCode = util.StatusSSEError // 597
+		}
+		// 只交出 error 事件本身，不再等待上游剩余数据
+		resp.Body = prependedBody{Reader: bytes.NewReader(errorEvent), Closer: resp.Body}
+		return s.handleErrorResponse(reqCtx, resp, hdrClone, firstBodyReadTimeSec)
+	}
+
+	// 窗口内读取失败或提前断流：作为上游网络错误返回（可重试）
+	if readErr != nil && (readErr != io.EOF || !parser.IsStreamComplete()) {
+		if readErr == io.EOF {
+			readErr = errStreamEndedBeforeCommit
+		}
+		err := fmt.Errorf("%w (buffered %d bytes)", readErr, len(prefix))
+		return &fwResult{
+			Status:        resp.StatusCode,
+			Header:        hdrClone,
+			Body:          []byte(err.Error()),
+			FirstByteTime: *firstBodyReadTimeSec,
+		}, reqCtx.Duration().Seconds(), err
+	}
+
+	if len(prefix) > 0 {
+		prependToBody(resp, prefix)
+	}
+	return nil, 0, nil
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+)
+
+func runBufferedStream(t *testing.T, body string) (*fwResult, *httptest.ResponseRecorder, error) {
+	t.Helper()
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+	}
+	reqCtx := &requestContext{
+		ctx:         context.Background(),
+		startTime:   time.Now(),
+		isStreaming: true,
+	}
+	rec := httptest.NewRecorder()
+	s := &Server{responseBufferBytes: 2048} // 默认关闭，测试显式开启
+
+	res, _, err := s.handleResponse(reqCtx, resp, rec, "anthropic", &model.Config{ID: 1}, "sk-test", nil)
+	return res, rec, err
+}
+
+func TestResponseBuffer_ErrorEventBeforeCommitIsRetryable(t *testing.T) {
+	body := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":5}}}\n\n" +
+		"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+
+	res, rec, err := runBufferedStream(t, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Status != util.StatusSSEError {
+		t.Fatalf("expected status %d, got %d", util.StatusSSEError, res.Status)
+	}
+	if !strings.Contains(string(res.Body), "overloaded_error") {
+		t.Fatalf("expected error event in body, got %q", res.Body)
+	}
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+		t.Fatalf("nothing should be written to client, got headers=%v body=%q", rec.Header(), rec.Body.String())
+	}
+}
+
+func TestResponseBuffer_StreamEndedBeforeCommitIsRetryable(t *testing.T) {
+	body := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{}}\n\n"
+
+	_, rec, err := runBufferedStream(t, body)
+	if !errors.Is(err, errStreamEndedBeforeCommit) {
+		t.Fatalf("expected errStreamEndedBeforeCommit, got %v", err)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("nothing should be written to client, got %q", rec.Body.String())
+	}
+}
+
+func TestResponseBuffer_CommitsOnContentEvent(t *testing.T) {
+	body := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"text\":\"hi\"}}\n\n" +
+		strings.Repeat("event: ping\ndata: {\"type\":\"ping\"}\n\n", 200) +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	res, rec, err := runBufferedStream(t, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Status)
+	}
+	if rec.Body.String() != body {
+		t.Fatalf("forwarded body mismatch: got %d bytes, want %d", rec.Body.Len(), len(body))
+	}
+}
+
+func TestResponseBuffer_ShortCompleteStreamCommits(t *testing.T) {
+	body := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	_, rec, err := runBufferedStream(t, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Body.String() != body {
+		t.Fatalf("forwarded body mismatch: %q", rec.Body.String())
+	}
+}
+
+func TestHasSSEContentEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  string
+		want bool
+	}{
+		{"无完整事件", `data: {"type":"content_block_delta"`, false},
+		{"仅message_start", "data: {\"type\":\"message_start\"}\n\n", false},
+		{"anthropic内容事件", "data: {\"type\":\"content_block_delta\"}\n\n", true},
+		{"openai chat", "data: {\"choices\":[{\"delta\":{}}]}\n\n", true},
+		{"gemini", "data: {\"candidates\":[]}\n\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasSSEContentEvent([]byte(tt.buf)); got != tt.want {
+				t.Fatalf("hasSSEContentEvent()=%v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 缓冲窗口默认关闭：message_start/ping 立即写给客户端，需运维显式开启
+func TestResponseBuffer_DisabledByDefault(t *testing.T) {
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "buffer.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+	if srv.responseBufferBytes != 0 {
+		t.Fatalf("response_buffer_bytes 默认应关闭，实际 %d", srv.responseBufferBytes)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"slices"
 	"strings"
+
+	"ccLoad/internal/util"
 )
 
 // ============================================================================
@@ -455,6 +457,9 @@ func (u *usageAccumulator) applyAnthropicOrResponsesUsage(usage map[string]any)
 		// 更新兼容字段
 		u.CacheCreationInputTokens = u.Cache5mInputTokens + u.Cache1hInputTokens
 	}
+	// 仅有总数无细分时按5分钟TTL补齐，保证缓存写入计费（2026-10新增）
+	u.Cache5mInputTokens, u.Cache1hInputTokens = util.SplitCacheCreationTokens(
+		u.CacheCreationInputTokens, u.Cache5mInputTokens, u.Cache1hInputTokens)
 
 	// OpenAI Responses API缓存字段: input_tokens_details.cached_tokens
 	if details, ok := usage["input_tokens_details"].(map[string]any); ok {
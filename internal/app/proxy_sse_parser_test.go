@@ -590,3 +590,25 @@ data: {"type":"message_delta","usage":{"output_tokens":100}}
 	t.Logf("[INFO] 流式SSE响应1h缓存解析正确: cache_5m=%d, cache_1h=%d",
 		parser.Cache5mInputTokens, parser.Cache1hInputTokens)
 }
+
+// TestSSEUsageParser_CacheCreationWithoutBreakdown 仅有缓存写入总数时按5分钟TTL补齐（否则缓存写入不计费）
+func TestSSEUsageParser_CacheCreationWithoutBreakdown(t *testing.T) {
+	parser := newSSEUsageParser("anthropic")
+	feedAndAssertUsage(t, parser, `event: message_start
+data: {"type":"message_start","message":{"usage":{"cache_creation_input_tokens":278,"cache_read_input_tokens":100,"input_tokens":12,"output_tokens":1}}}
+
+`, 12, 1, 100, 278)
+	if parser.Cache5mInputTokens != 278 || parser.Cache1hInputTokens != 0 {
+		t.Fatalf("expected 278 tokens attributed to 5m TTL, got 5m=%d 1h=%d", parser.Cache5mInputTokens, parser.Cache1hInputTokens)
+	}
+
+	// 有细分时保持上游细分
+	parser = newSSEUsageParser("anthropic")
+	feedAndAssertUsage(t, parser, `event: message_start
+data: {"type":"message_start","message":{"usage":{"cache_creation_input_tokens":300,"cache_creation":{"ephemeral_5m_input_tokens":100,"ephemeral_1h_input_tokens":200},"input_tokens":12,"output_tokens":1}}}
+
+`, 12, 1, 0, 300)
+	if parser.Cache5mInputTokens != 100 || parser.Cache1hInputTokens != 200 {
+		t.Fatalf("breakdown must be kept, got 5m=%d 1h=%d", parser.Cache5mInputTokens, parser.Cache1hInputTokens)
+	}
+}
@@ -21,7 +21,8 @@ type firstByteDetector struct {
 	io.ReadCloser
 	stats       *streamReadStats
 	onFirstRead func()
-	onBytesRead func(int64) // 可选：每次读取后的回调（nil 时不触发）
+	onBytesRead func(int64)  // 可选：每次读取后的回调（nil 时不触发）
+	onData      func([]byte) // 可选：每次读取到的数据（nil 时不触发）
 }
 
 // Read 实现io.Reader接口，记录读取统计
@@ -42,6 +43,9 @@ func (r *firstByteDetector) Read(p []byte) (n int, err error) {
 		if r.onBytesRead != nil {
 			r.onBytesRead(int64(n))
 		}
+		if r.onData != nil {
+			r.onData(p[:n])
+		}
 	}
 	return
 }
@@ -86,12 +86,29 @@ type fwResult struct {
 	// 用于捕获SSE流中的error事件（如1308错误），在流结束后触发冷却逻辑
 	// 虽然HTTP状态码是200，但error事件表示实际上发生了错误
 	SSEErrorEvent []byte // SSE流中检测到的最后一个error事件的完整JSON
+
+	// JSON模式修复诊断（2026-10新增）：输出被修复/无法修复时写入成功日志的Message字段
+	JSONRepairMsg string
+
+	// 上游响应体字节数（2026-10新增，用于分布统计）
+	ResponseBytes int64
+
+	// 响应内容标签（2026-10新增，逗号分隔，见 response_label_rules）
+	Labels string
 }
 
 // ForwardObserver 封装转发过程中的观测回调（遵循SRP，避免函数签名膨胀）
 type ForwardObserver struct {
 	OnBytesRead     func(int64) // 字节读取回调（可选）
 	OnFirstByteRead func()      // 首字节读取回调（可选）
+
+	// OnUpstreamRequest 出站请求构建完成后回调（可选，请求抓取用，2026-10新增）
+	// body 为转换后、压缩前的请求体
+	OnUpstreamRequest func(req *http.Request, body []byte)
+
+	// OnResponseData 每次读取到上游响应数据后回调（可选，请求抓取用，2026-10新增）
+	// p 仅在回调期间有效，需要保留时调用方自行复制
+	OnResponseData func(p []byte)
 }
 
 // proxyRequestContext 代理请求上下文（封装请求信息，遵循DIP原则）
@@ -103,13 +120,56 @@ type proxyRequestContext struct {
 	body             []byte
 	header           http.Header
 	isStreaming      bool
-	tokenHash        string           // Token哈希值（用于统计）
-	tokenID          int64            // Token ID（用于日志记录，0表示未使用token）
-	clientIP         string           // 客户端IP地址（用于日志记录）
-	activeReqID      int64            // 活跃请求ID（用于更新渠道信息）
-	observer         *ForwardObserver // 转发观测回调（可选）
-	startTime        time.Time        // 请求开始时间（用于统计）
-	attemptStartTime time.Time        // 渠道尝试开始时间（用于日志记录）
+	tokenHash        string            // Token哈希值（用于统计）
+	tokenID          int64             // Token ID（用于日志记录，0表示未使用token）
+	clientIP         string            // 客户端IP地址（用于日志记录）
+	activeReqID      int64             // 活跃请求ID（用于更新渠道信息）
+	observer         *ForwardObserver  // 转发观测回调（可选）
+	startTime        time.Time         // 请求开始时间（用于统计）
+	attemptStartTime time.Time         // 渠道尝试开始时间（用于日志记录）
+	redirectOverride *redirectOverride // 单次请求的模型重定向覆盖（仅管理员，可选）
+	failover         *failoverInfo     // 上游尝试记录（令牌开启 failover_info 时非nil）
+	affinityKey      string            // 会话粘性路由绑定键（未启用或无会话标识时为空）
+
+	// 本渠道尝试经过协议转换（2026-10新增）：上游拒绝转换后的请求时归为 conversion_bug
+	protocolConverted bool
+}
+
+// redirectOverride 单次请求的模型重定向覆盖（2026-10新增）
+// 用于A/B对比真实模型与重定向模型，无需修改渠道配置
+type redirectOverride struct {
+	disable bool   // X-CCLoad-No-Redirect: 1 → 跳过渠道重定向
+	from    string // X-CCLoad-Redirect: from=to → 将 from 重定向为 to（优先于渠道配置）
+	to      string
+}
+
+// 重定向覆盖相关请求头（X-CCLoad-* 头不会透传到上游）
+const (
+	headerCCLoadPrefix     = "X-Ccload-"
+	headerCCLoadAdminToken = "X-CCLoad-Admin-Token"
+	headerCCLoadNoRedirect = "X-CCLoad-No-Redirect"
+	headerCCLoadRedirect   = "X-CCLoad-Redirect"
+)
+
+// parseRedirectOverride 解析重定向覆盖请求头
+// 返回 (nil, nil) 表示未使用覆盖；格式错误返回 error
+func parseRedirectOverride(h http.Header) (*redirectOverride, error) {
+	noRedirect := strings.TrimSpace(h.Get(headerCCLoadNoRedirect))
+	redirect := strings.TrimSpace(h.Get(headerCCLoadRedirect))
+	if noRedirect == "" && redirect == "" {
+		return nil, nil
+	}
+
+	o := &redirectOverride{disable: noRedirect == "1" || strings.EqualFold(noRedirect, "true")}
+	if redirect != "" {
+		from, to, ok := strings.Cut(redirect, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid %s header, expected from=to", headerCCLoadRedirect)
+		}
+		o.from, o.to = from, to
+	}
+	return o, nil
 }
 
 // proxyResult 代理请求结果
@@ -155,7 +215,11 @@ func isStreamingRequest(path string, body []byte) bool {
 
 // buildUpstreamURL 构建上游完整URL（KISS）
 func buildUpstreamURL(cfg *model.Config, requestPath, rawQuery string) string {
-	upstreamURL := strings.TrimRight(cfg.URL, "/") + requestPath
+	base := strings.TrimRight(cfg.URL, "/")
+	if isMockChannel(cfg) {
+		base = mockUpstreamBase // 模拟渠道URL承载的是参数，不是真实地址
+	}
+	upstreamURL := base + requestPath
 
 	// 移除 key 参数（Gemini API 认证格式），避免泄露到上游
 	if rawQuery != "" {
@@ -171,6 +235,15 @@ func buildUpstreamURL(cfg *model.Config, requestPath, rawQuery string) string {
 	return upstreamURL
 }
 
+// azureDeployment Azure OpenAI 部署名（即重定向后请求体中的模型名）
+func azureDeployment(body []byte) string {
+	var reqModel struct {
+		Model string `json:"model"`
+	}
+	_ = sonic.Unmarshal(body, &reqModel)
+	return reqModel.Model
+}
+
 // buildUpstreamRequest 创建带上下文的HTTP请求
 func buildUpstreamRequest(ctx context.Context, method, upstreamURL string, body []byte) (*http.Request, error) {
 	var bodyReader io.Reader
@@ -247,7 +320,12 @@ func copyRequestHeaders(dst *http.Request, src http.Header) {
 		// 不透传认证头（由上游注入）
 		if strings.EqualFold(k, "Authorization") ||
 			strings.EqualFold(k, "X-Api-Key") ||
-			strings.EqualFold(k, "x-goog-api-key") {
+			strings.EqualFold(k, "x-goog-api-key") ||
+			strings.EqualFold(k, util.AzureAPIKeyHeader) {
+			continue
+		}
+		// 不透传 ccLoad 内部控制头（含管理员Token）
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), headerCCLoadPrefix) {
 			continue
 		}
 		// 不透传 Accept-Encoding，避免上游返回 br/gzip 压缩导致错误体乱码
@@ -347,25 +425,42 @@ func extractModelFromPath(path string) string {
 	return remaining[:end]
 }
 
-// prepareRequestBody 准备请求体（处理模型重定向）
-// 遵循SRP原则：单一职责 - 仅负责模型重定向和请求体准备
+// prepareRequestBody 准备请求体（处理模型重定向与渠道请求体改写规则）
+// 遵循SRP原则：单一职责 - 仅负责模型重定向、改写规则和请求体准备
 func prepareRequestBody(cfg *model.Config, reqCtx *proxyRequestContext) (actualModel string, bodyToSend []byte) {
 	actualModel = reqCtx.originalModel
 
-	// 检查模型重定向
-	if redirectModel, ok := cfg.GetRedirectModel(reqCtx.originalModel); ok && redirectModel != "" {
-		actualModel = redirectModel
+	// 检查模型重定向（单次请求覆盖优先于渠道配置）
+	o := reqCtx.redirectOverride
+	switch {
+	case o != nil && o.from == reqCtx.originalModel:
+		actualModel = o.to
+	case o != nil && o.disable:
+		// 跳过渠道重定向，直接使用原始模型
+	default:
+		if redirectModel, ok := cfg.GetRedirectModel(reqCtx.originalModel); ok && redirectModel != "" {
+			actualModel = redirectModel
+		}
 	}
 
 	bodyToSend = reqCtx.body
 
-	// 如果模型发生重定向，修改请求体
-	if actualModel != reqCtx.originalModel {
+	// 如果模型发生重定向或渠道配置了改写规则，修改请求体
+	rules := parseRewriteRules(cfg.RewriteRules)
+	if actualModel != reqCtx.originalModel || len(rules) > 0 {
 		var reqData map[string]any
-		if err := sonic.Unmarshal(reqCtx.body, &reqData); err == nil {
-			reqData["model"] = actualModel
-			if modifiedBody, err := sonic.Marshal(reqData); err == nil {
-				bodyToSend = modifiedBody
+		if err := sonic.Unmarshal(reqCtx.body, &reqData); err == nil && reqData != nil {
+			changed := actualModel != reqCtx.originalModel
+			if changed {
+				reqData["model"] = actualModel
+			}
+			if applyRewriteRules(rules, reqData, reqCtx.requestPath, actualModel) {
+				changed = true
+			}
+			if changed {
+				if modifiedBody, err := sonic.Marshal(reqData); err == nil {
+					bodyToSend = modifiedBody
+				}
 			}
 		}
 	}
@@ -382,15 +477,38 @@ type logEntryParams struct {
 	RequestModel string // 客户端请求的原始模型名称
 	ActualModel  string // 实际转发到上游的模型名称（可能经过重定向）
 	ChannelID    int64
+	CostMult     float64 // 渠道价格倍率（<=0 视为1）
 	StatusCode   int
 	Duration     float64
 	IsStreaming  bool
 	APIKeyUsed   string
+	KeyIndex     int // 使用的Key下标（2026-10新增，用于Key维度报表）
 	AuthTokenID  int64
 	ClientIP     string
 	Result       *fwResult
 	ErrMsg       string
 	StartTime    time.Time // 渠道尝试开始时间（用于日志记录）
+	NetworkErr   bool      // 网络错误（未收到上游响应）
+	Converted    bool      // 请求经过协议转换
+}
+
+// logErrorClass 计算日志的错误归类（2026-10新增）
+func logErrorClass(p logEntryParams) string {
+	if p.NetworkErr {
+		if p.StatusCode == util.StatusClientClosedRequest {
+			return "" // 客户端取消不是上游错误
+		}
+		return util.ErrorClassNetwork
+	}
+	var body []byte
+	if p.Result != nil {
+		body = p.Result.Body
+	}
+	class := util.ClassifyUpstreamError(p.StatusCode, body)
+	if class == util.ErrorClassInvalidRequest && p.Converted {
+		return util.ErrorClassConversionBug
+	}
+	return class
 }
 
 // buildLogEntry 构建日志条目（消除重复代码，遵循DRY原则）
@@ -407,8 +525,10 @@ func buildLogEntry(p logEntryParams) *model.LogEntry {
 		Duration:    p.Duration,
 		IsStreaming: p.IsStreaming,
 		APIKeyUsed:  p.APIKeyUsed,
+		KeyIndex:    p.KeyIndex,
 		AuthTokenID: p.AuthTokenID,
 		ClientIP:    p.ClientIP,
+		ErrorClass:  logErrorClass(p),
 	}
 
 	// 记录实际转发的模型（仅当发生重定向时）
@@ -424,9 +544,12 @@ func buildLogEntry(p logEntryParams) *model.LogEntry {
 			// [INFO] 2025-12: 流传输诊断信息优先于 "ok"
 			if res.StreamDiagMsg != "" {
 				entry.Message = res.StreamDiagMsg
+			} else if res.JSONRepairMsg != "" {
+				entry.Message = truncateErr(res.JSONRepairMsg)
 			} else {
 				entry.Message = "ok"
 			}
+			entry.Labels = res.Labels
 		} else {
 			msg := fmt.Sprintf("upstream status %d", p.StatusCode)
 			if len(res.Body) > 0 {
@@ -464,6 +587,10 @@ func buildLogEntry(p logEntryParams) *model.LogEntry {
 				res.Cache5mInputTokens,
 				res.Cache1hInputTokens,
 			)
+			// 渠道价格倍率：记录实际采购成本而非官方定价
+			if p.CostMult > 0 {
+				entry.Cost *= p.CostMult
+			}
 		}
 	} else {
 		entry.Message = "unknown"
@@ -3,7 +3,11 @@ package app
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
 )
 
 func TestWriteResponseWithHeaders_PreservesContentType(t *testing.T) {
@@ -127,6 +131,47 @@ func TestBuildLogEntry_StreamDiagMsg(t *testing.T) {
 	})
 }
 
+func TestBuildLogEntry_CostMultiplier(t *testing.T) {
+	res := &fwResult{Status: 200, InputTokens: 1000, OutputTokens: 1000}
+	base := buildLogEntry(logEntryParams{
+		RequestModel: "claude-sonnet-4-5",
+		StatusCode:   200,
+		Result:       res,
+	})
+	discounted := buildLogEntry(logEntryParams{
+		RequestModel: "claude-sonnet-4-5",
+		StatusCode:   200,
+		CostMult:     0.5,
+		Result:       res,
+	})
+	if base.Cost <= 0 {
+		t.Fatalf("expected positive base cost, got %f", base.Cost)
+	}
+	if diff := discounted.Cost - base.Cost*0.5; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("expected discounted cost %f, got %f", base.Cost*0.5, discounted.Cost)
+	}
+}
+
+func TestBuildLogEntry_ErrorClass(t *testing.T) {
+	invalid := &fwResult{Status: 400, Body: []byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad field"}}`)}
+	tests := []struct {
+		name string
+		p    logEntryParams
+		want string
+	}{
+		{"success", logEntryParams{StatusCode: 200, Result: &fwResult{Status: 200}}, ""},
+		{"upstream invalid request", logEntryParams{StatusCode: 400, Result: invalid}, util.ErrorClassInvalidRequest},
+		{"converted invalid request", logEntryParams{StatusCode: 400, Result: invalid, Converted: true}, util.ErrorClassConversionBug},
+		{"network error", logEntryParams{StatusCode: 504, ErrMsg: "context deadline exceeded", NetworkErr: true}, util.ErrorClassNetwork},
+		{"client cancel", logEntryParams{StatusCode: 499, ErrMsg: "context canceled", NetworkErr: true}, ""},
+	}
+	for _, tt := range tests {
+		if got := buildLogEntry(tt.p).ErrorClass; got != tt.want {
+			t.Errorf("%s: ErrorClass=%q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestCopyRequestHeaders_StripsHopByHopAndAuth(t *testing.T) {
 	req, err := http.NewRequest(http.MethodGet, "https://example.com", nil)
 	if err != nil {
@@ -145,6 +190,7 @@ func TestCopyRequestHeaders_StripsHopByHopAndAuth(t *testing.T) {
 	src.Set("X-API-Key", "client-token2")
 	src.Set("x-goog-api-key", "client-goog")
 	src.Set("Accept-Encoding", "br")
+	src.Set("X-CCLoad-Admin-Token", "admin-secret")
 	src.Set("X-Pass", "ok")
 
 	copyRequestHeaders(req, src)
@@ -168,6 +214,7 @@ func TestCopyRequestHeaders_StripsHopByHopAndAuth(t *testing.T) {
 		"X-API-Key",
 		"x-goog-api-key",
 		"Accept-Encoding",
+		"X-CCLoad-Admin-Token",
 	} {
 		if v := req.Header.Get(k); v != "" {
 			t.Fatalf("expected header %q stripped, got %q", k, v)
@@ -175,6 +222,55 @@ func TestCopyRequestHeaders_StripsHopByHopAndAuth(t *testing.T) {
 	}
 }
 
+func TestPrepareRequestBody_RedirectOverride(t *testing.T) {
+	cfg := &model.Config{ModelEntries: []model.ModelEntry{
+		{Model: "claude-sonnet-4-5", RedirectModel: "claude-opus-4-5"},
+	}}
+	body := []byte(`{"model":"claude-sonnet-4-5"}`)
+
+	tests := []struct {
+		name     string
+		override *redirectOverride
+		want     string
+	}{
+		{"渠道重定向", nil, "claude-opus-4-5"},
+		{"跳过重定向", &redirectOverride{disable: true}, "claude-sonnet-4-5"},
+		{"覆盖重定向", &redirectOverride{from: "claude-sonnet-4-5", to: "claude-haiku-4-5"}, "claude-haiku-4-5"},
+		{"from不匹配时沿用渠道配置", &redirectOverride{from: "other", to: "x"}, "claude-opus-4-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, sent := prepareRequestBody(cfg, &proxyRequestContext{
+				originalModel:    "claude-sonnet-4-5",
+				body:             body,
+				redirectOverride: tt.override,
+			})
+			if actual != tt.want {
+				t.Fatalf("actualModel = %q, want %q", actual, tt.want)
+			}
+			if !strings.Contains(string(sent), tt.want) {
+				t.Fatalf("body not rewritten: %s", sent)
+			}
+		})
+	}
+}
+
+func TestParseRedirectOverride(t *testing.T) {
+	h := http.Header{}
+	if o, err := parseRedirectOverride(h); o != nil || err != nil {
+		t.Fatalf("expected no override, got %+v, %v", o, err)
+	}
+	h.Set("X-CCLoad-Redirect", "a = b")
+	o, err := parseRedirectOverride(h)
+	if err != nil || o.from != "a" || o.to != "b" {
+		t.Fatalf("unexpected override %+v, %v", o, err)
+	}
+	h.Set("X-CCLoad-Redirect", "missing-target")
+	if _, err := parseRedirectOverride(h); err == nil {
+		t.Fatal("expected error for malformed header")
+	}
+}
+
 func TestFilterAndWriteResponseHeaders_StripsHopByHop(t *testing.T) {
 	w := httptest.NewRecorder()
 
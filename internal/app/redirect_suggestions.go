@@ -0,0 +1,260 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 模型重定向建议（2026-10新增）
+// ============================================================================
+// 上游改名模型（如 claude-3.5-sonnet → claude-3-5-sonnet-20241022）后，需要人工发现并补充重定向。
+// GET /admin/channels/:id/suggestions 分析渠道近 days 天（默认7，最长30）的日志：
+//   - 请求模型发到上游后返回 400/404 且错误信息判定为"模型不存在"
+//   - 同一渠道上名称相近的模型请求成功过
+// 满足时建议把请求模型重定向到成功的模型。请求模型在最后一次失败之后已成功（配置已修正）的不再建议。
+// POST /admin/channels/:id/suggestions/apply {"model","redirect_model"} 一键写入渠道模型配置。
+
+const (
+	defaultSuggestionDays    = 7
+	maxSuggestionDays        = 30
+	minSuggestionSimilarity  = 0.6
+	suggestionSampleMsgLimit = 200
+)
+
+// RedirectSuggestion 单条重定向建议
+type RedirectSuggestion struct {
+	Model             string  `json:"model"`            // 客户端请求的模型
+	FailedModel       string  `json:"failed_model"`     // 发给上游被拒的模型（重定向后的模型或请求模型本身）
+	CurrentRedirect   string  `json:"current_redirect"` // 渠道当前配置的重定向（空表示未配置）
+	SuggestedRedirect string  `json:"suggested_redirect"`
+	Failures          int64   `json:"failures"`
+	LastFailureAt     int64   `json:"last_failure_at"` // Unix毫秒
+	LastError         string  `json:"last_error"`
+	TargetSuccesses   int64   `json:"target_successes"` // 建议目标在窗口内的成功次数
+	Similarity        float64 `json:"similarity"`       // 名称相似度 0-1
+}
+
+type suggestionFailure struct {
+	sent    string
+	count   int64
+	lastAt  int64
+	message string
+}
+
+// buildRedirectSuggestions 根据日志聚合结果生成重定向建议（按失败次数降序）
+func buildRedirectSuggestions(cfg *model.Config, outcomes []model.ModelOutcome) []RedirectSuggestion {
+	failures := make(map[string]*suggestionFailure) // 请求模型 → 模型不存在失败
+	successes := make(map[string]int64)             // 实际发送的模型 → 成功次数
+	lastSuccess := make(map[string]int64)           // 请求模型 → 最后成功时间
+	for _, o := range outcomes {
+		sent := o.ActualModel
+		if sent == "" {
+			sent = o.Model
+		}
+		if o.StatusCode >= 200 && o.StatusCode < 300 {
+			successes[sent] += o.Count
+			lastSuccess[o.Model] = max(lastSuccess[o.Model], o.LastAt)
+			continue
+		}
+		if !util.IsModelNotFoundError(o.StatusCode, []byte(o.SampleMessage)) {
+			continue
+		}
+		f := failures[o.Model]
+		if f == nil {
+			f = &suggestionFailure{}
+			failures[o.Model] = f
+		}
+		f.count += o.Count
+		if o.LastAt >= f.lastAt {
+			f.lastAt, f.sent, f.message = o.LastAt, sent, o.SampleMessage
+		}
+	}
+
+	suggestions := []RedirectSuggestion{}
+	for requested, f := range failures {
+		if lastSuccess[requested] > f.lastAt {
+			continue // 配置已修正
+		}
+		target, score := "", 0.0
+		for cand, n := range successes {
+			if cand == f.sent {
+				continue
+			}
+			sim := max(modelNameSimilarity(requested, cand), modelNameSimilarity(f.sent, cand))
+			if sim > score || (sim == score && target != "" && n > successes[target]) {
+				target, score = cand, sim
+			}
+		}
+		if target == "" || score < minSuggestionSimilarity {
+			continue
+		}
+		current := ""
+		if entry, ok := findModelEntry(cfg, requested); ok {
+			current = entry.RedirectModel
+		}
+		if current == target || (current == "" && target == requested) {
+			continue // 已是建议配置
+		}
+		msg := f.message
+		if len(msg) > suggestionSampleMsgLimit {
+			msg = msg[:suggestionSampleMsgLimit]
+		}
+		suggestions = append(suggestions, RedirectSuggestion{
+			Model:             requested,
+			FailedModel:       f.sent,
+			CurrentRedirect:   current,
+			SuggestedRedirect: target,
+			Failures:          f.count,
+			LastFailureAt:     f.lastAt,
+			LastError:         util.RedactSecrets(msg),
+			TargetSuccesses:   successes[target],
+			Similarity:        float64(int(score*1000)) / 1000,
+		})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Failures != suggestions[j].Failures {
+			return suggestions[i].Failures > suggestions[j].Failures
+		}
+		return suggestions[i].Model < suggestions[j].Model
+	})
+	return suggestions
+}
+
+// findModelEntry 按精确模型名查找渠道模型条目
+func findModelEntry(cfg *model.Config, modelName string) (model.ModelEntry, bool) {
+	for _, e := range cfg.ModelEntries {
+		if e.Model == modelName {
+			return e, true
+		}
+	}
+	return model.ModelEntry{}, false
+}
+
+// normalizeModelName 统一大小写与分隔符（claude-3.5_sonnet 与 Claude-3-5-Sonnet 视为相同）
+func normalizeModelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '_', ':', '/', ' ':
+			return '-'
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// modelNameSimilarity 规范化后按编辑距离计算相似度（1 表示相同）
+func modelNameSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeModelName(a)), []rune(normalizeModelName(b))
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	// 单行滚动数组的 Levenshtein 距离
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		diag := prev[0]
+		prev[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			diag, prev[j] = prev[j], min(prev[j]+1, prev[j-1]+1, diag+cost)
+		}
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// HandleChannelRedirectSuggestions 渠道模型重定向建议
+// GET /admin/channels/:id/suggestions?days=7
+func (s *Server) HandleChannelRedirectSuggestions(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	days := defaultSuggestionDays
+	if v := c.Query("days"); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 || days > maxSuggestionDays {
+			RespondErrorMsg(c, http.StatusBadRequest, "days must be within 1-30")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	cfg, err := s.store.GetConfig(ctx, id)
+	if err != nil {
+		RespondError(c, http.StatusNotFound, err)
+		return
+	}
+	outcomes, err := s.store.GetChannelModelOutcomes(ctx, id, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, gin.H{
+		"channel_id":  id,
+		"days":        days,
+		"suggestions": buildRedirectSuggestions(cfg, outcomes),
+	})
+}
+
+// HandleApplyRedirectSuggestion 写入重定向建议（已有同名模型条目时更新其重定向，否则新增条目）
+// POST /admin/channels/:id/suggestions/apply
+func (s *Server) HandleApplyRedirectSuggestion(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	var entry model.ModelEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request")
+		return
+	}
+	if err := entry.Validate(); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if entry.RedirectModel == entry.Model {
+		entry.RedirectModel = "" // 直接请求原模型即可
+	}
+
+	ctx := c.Request.Context()
+	cfg, err := s.store.GetConfig(ctx, id)
+	if err != nil {
+		RespondError(c, http.StatusNotFound, err)
+		return
+	}
+	updated := false
+	for i := range cfg.ModelEntries {
+		if cfg.ModelEntries[i].Model == entry.Model {
+			cfg.ModelEntries[i].RedirectModel = entry.RedirectModel
+			updated = true
+			break
+		}
+	}
+	if !updated {
+		cfg.ModelEntries = append(cfg.ModelEntries, entry)
+	}
+	if _, err := s.store.UpdateConfig(ctx, id, cfg); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.modelNotFound.clear(id)
+	s.InvalidateChannelListCache()
+	RespondJSON(c, http.StatusOK, gin.H{"model": entry.Model, "redirect_model": entry.RedirectModel, "updated": updated})
+}
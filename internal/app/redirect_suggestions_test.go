@@ -0,0 +1,137 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestModelNameSimilarity(t *testing.T) {
+	if got := modelNameSimilarity("claude-3.5-sonnet", "Claude-3-5-Sonnet"); got != 1 {
+		t.Fatalf("分隔符/大小写差异应视为相同, got %v", got)
+	}
+	if got := modelNameSimilarity("gpt-4o", "claude-3-opus"); got >= minSuggestionSimilarity {
+		t.Fatalf("不相关模型相似度过高: %v", got)
+	}
+	if got := modelNameSimilarity("", ""); got != 0 {
+		t.Fatalf("空字符串相似度应为0, got %v", got)
+	}
+}
+
+func TestHandleChannelRedirectSuggestions(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name:     "suggest",
+		URL:      "https://api.example.com",
+		Priority: 1,
+		ModelEntries: []model.ModelEntry{
+			{Model: "claude-sonnet", RedirectModel: "claude-3.5-sonnet"},
+			{Model: "claude-3-5-sonnet-20241022"},
+			{Model: "gpt-4o"},
+		},
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+
+	now := time.Now()
+	notFound := `{"type":"error","error":{"type":"not_found_error","message":"model: claude-3.5-sonnet"}}`
+	var logs []*model.LogEntry
+	add := func(ago time.Duration, requested, actual string, status, n int, msg string) {
+		for range n {
+			logs = append(logs, &model.LogEntry{
+				Time:        model.JSONTime{Time: now.Add(-ago)},
+				Model:       requested,
+				ActualModel: actual,
+				ChannelID:   cfg.ID,
+				StatusCode:  status,
+				Message:     msg,
+			})
+		}
+	}
+	// 重定向目标被上游改名：claude-sonnet → claude-3.5-sonnet 返回404，相近的新名称请求成功
+	add(time.Hour, "claude-sonnet", "claude-3.5-sonnet", 404, 5, notFound)
+	add(2*time.Hour, "claude-3-5-sonnet-20241022", "", 200, 3, "ok")
+	// gpt-4o 曾因模型不存在失败，但之后已成功（配置已修正）→ 不建议
+	add(3*time.Hour, "gpt-4o", "gpt-4o-old", 404, 2, `{"error":{"code":"model_not_found"}}`)
+	add(time.Hour, "gpt-4o", "", 200, 1, "ok")
+	// 普通参数错误不计入
+	add(time.Hour, "claude-3-haiku", "", 400, 4, `{"error":{"message":"max_tokens too large"}}`)
+	// 窗口外日志不计入
+	add(10*24*time.Hour, "claude-old", "", 404, 9, notFound)
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("写入日志失败: %v", err)
+	}
+
+	call := func(method, query string, body []byte, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(cfg.ID, 10)}}
+		c.Request = httptest.NewRequest(method, "/admin/channels/x/suggestions"+query, bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler(c)
+		return w
+	}
+
+	if w := call(http.MethodGet, "?days=31", nil, server.HandleChannelRedirectSuggestions); w.Code != http.StatusBadRequest {
+		t.Fatalf("days超限应返回400, got %d", w.Code)
+	}
+
+	w := call(http.MethodGet, "", nil, server.HandleChannelRedirectSuggestions)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Suggestions []RedirectSuggestion `json:"suggestions"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(resp.Data.Suggestions) != 1 {
+		t.Fatalf("应只有1条建议: %+v", resp.Data.Suggestions)
+	}
+	sg := resp.Data.Suggestions[0]
+	if sg.Model != "claude-sonnet" || sg.FailedModel != "claude-3.5-sonnet" || sg.CurrentRedirect != "claude-3.5-sonnet" ||
+		sg.SuggestedRedirect != "claude-3-5-sonnet-20241022" || sg.Failures != 5 || sg.TargetSuccesses != 3 {
+		t.Fatalf("建议内容不符: %+v", sg)
+	}
+
+	// 一键应用：更新已有条目的重定向
+	body, _ := json.Marshal(model.ModelEntry{Model: sg.Model, RedirectModel: sg.SuggestedRedirect})
+	if w := call(http.MethodPost, "/apply", body, server.HandleApplyRedirectSuggestion); w.Code != http.StatusOK {
+		t.Fatalf("应用建议失败: %d %s", w.Code, w.Body.String())
+	}
+	updated, err := store.GetConfig(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("读取渠道失败: %v", err)
+	}
+	if entry, ok := findModelEntry(updated, "claude-sonnet"); !ok || entry.RedirectModel != "claude-3-5-sonnet-20241022" {
+		t.Fatalf("重定向未更新: %+v", updated.ModelEntries)
+	}
+	if len(updated.ModelEntries) != 3 {
+		t.Fatalf("不应新增条目: %+v", updated.ModelEntries)
+	}
+
+	// 应用后建议消失
+	w = call(http.MethodGet, "", nil, server.HandleChannelRedirectSuggestions)
+	resp.Data.Suggestions = nil
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data.Suggestions) != 0 {
+		t.Fatalf("应用后不应再有建议: %+v", resp.Data.Suggestions)
+	}
+}
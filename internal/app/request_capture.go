@@ -0,0 +1,617 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 上游请求抓取（2026-10新增）
+// ============================================================================
+// 排查格式转换问题（Codex/Gemini 等）时需要知道"实际发给上游的是什么"。管理员按渠道临时开启抓取后，
+// 该渠道的每次转发尝试都会同时记录入站请求与构建完成的出站请求（最终URL、请求头、转换后的请求体）：
+//   - POST /admin/channels/:id/captures {"minutes": 15} 开启（最长 requestCaptureMaxMinutes，到期自动停止；0=立即停止）
+//   - GET /admin/channels/:id/captures 查看（新→旧），DELETE 清空
+// 认证类请求头只保留前4位/后4位，其余请求头、URL与请求体经 util.RedactSecrets 脱敏；请求体超过
+// requestCaptureMaxBody 截断。记录仅保存在内存中（每渠道最近 requestCaptureMaxRecords 条），不落库。
+//
+// 失败请求常驻抓取（2026-10新增）：独立于手动开关，所有渠道的失败尝试（非2xx、流内error事件、网络错误；
+// 客户端取消除外）始终记录完整上下文（含上游错误响应），受 error_capture_budget_mb 内存预算与
+// error_capture_retention_hours 保留时长约束，超出时淘汰最旧记录：
+//   - GET /admin/captures/errors?channel_id=&error_class=&limit= 查看（新→旧），DELETE 清空
+// 为避免拖慢成功请求，尝试期间只保留原始引用，确认失败后才脱敏生成记录。
+//
+// 抓取对比（2026-10新增）：记录ID全局递增（同一尝试在手动抓取与失败抓取中ID相同），手动抓取时段内
+// 成功尝试同样记录上游响应（头与前 requestCaptureMaxBody 字节）及Token用量，
+// GET /admin/monitor/traces/diff?a=ID&b=ID 对比两条记录（见 capture_diff.go）。
+
+const (
+	requestCaptureMaxMinutes = 120
+	requestCaptureMaxRecords = 20
+	requestCaptureMaxBody    = 64 << 10
+
+	defaultErrorCaptureBudgetMB       = 16
+	defaultErrorCaptureRetentionHours = 24
+	maxErrorCaptureBudgetMB           = 1024
+	maxErrorCaptureRetentionHours     = 720
+	errorCaptureListDefaultLimit      = 50
+	errorCaptureListMaxLimit          = 500
+	errorCaptureRecordOverhead        = 512 // 单条记录的固定开销估算（字节）
+)
+
+// captureSensitiveHeaders 整体脱敏的请求头（小写）
+var captureSensitiveHeaders = map[string]struct{}{
+	"authorization":        {},
+	"proxy-authorization":  {},
+	"x-api-key":            {},
+	"api-key":              {}, // Azure OpenAI
+	"x-goog-api-key":       {},
+	"cookie":               {},
+	"x-ccload-admin-token": {},
+}
+
+// CapturedRequest 单个方向的请求快照
+type CapturedRequest struct {
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers"`
+	Body          string            `json:"body"`
+	BodyBytes     int               `json:"body_bytes"` // 原始长度（截断前）
+	BodyTruncated bool              `json:"body_truncated,omitempty"`
+}
+
+// CapturedResponse 上游响应快照（失败尝试；手动抓取时段内的成功尝试同样记录）
+type CapturedResponse struct {
+	Headers       map[string]string `json:"headers,omitempty"`
+	Body          string            `json:"body"`
+	BodyBytes     int               `json:"body_bytes"`
+	BodyTruncated bool              `json:"body_truncated,omitempty"`
+}
+
+// RequestCapture 一次转发尝试的入站/出站请求对
+type RequestCapture struct {
+	ID          int64             `json:"id,omitempty"` // 抓取记录ID（全局递增）
+	At          int64             `json:"at"`           // Unix毫秒
+	ChannelID   int64             `json:"channel_id"`
+	ChannelName string            `json:"channel_name,omitempty"`
+	KeyIndex    int               `json:"key_index"`
+	AuthTokenID int64             `json:"auth_token_id,omitempty"`
+	ClientIP    string            `json:"client_ip,omitempty"`
+	Model       string            `json:"model"`
+	ActualModel string            `json:"actual_model"`
+	StatusCode  int               `json:"status_code,omitempty"`
+	ErrorClass  string            `json:"error_class,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	DurationMs  int64             `json:"duration_ms"`
+	FirstByteMs int64             `json:"first_byte_ms,omitempty"`
+	Usage       *CapturedUsage    `json:"usage,omitempty"`
+	Inbound     CapturedRequest   `json:"inbound"`
+	Outbound    CapturedRequest   `json:"outbound"`
+	Response    *CapturedResponse `json:"response,omitempty"`
+}
+
+// CapturedUsage 成功尝试的Token用量
+type CapturedUsage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	CacheReadTokens     int `json:"cache_read_tokens"`
+	CacheCreationTokens int `json:"cache_creation_tokens"`
+}
+
+// size 记录的内存占用估算（用于失败抓取预算）
+func (r *RequestCapture) size() int64 {
+	n := errorCaptureRecordOverhead + len(r.Inbound.Body) + len(r.Inbound.URL) + len(r.Outbound.Body) + len(r.Outbound.URL) + len(r.Error)
+	for _, h := range []map[string]string{r.Inbound.Headers, r.Outbound.Headers} {
+		for k, v := range h {
+			n += len(k) + len(v)
+		}
+	}
+	if r.Response != nil {
+		n += len(r.Response.Body)
+		for k, v := range r.Response.Headers {
+			n += len(k) + len(v)
+		}
+	}
+	return int64(n)
+}
+
+type channelCapture struct {
+	until   time.Time
+	records []RequestCapture // 按时间升序
+}
+
+// requestCaptureStore 按渠道保存抓取开关与记录
+type requestCaptureStore struct {
+	mu       sync.Mutex
+	channels map[int64]*channelCapture
+}
+
+func newRequestCaptureStore() *requestCaptureStore {
+	return &requestCaptureStore{channels: make(map[int64]*channelCapture)}
+}
+
+// enabled 渠道当前是否处于抓取时段
+func (cs *requestCaptureStore) enabled(channelID int64, now time.Time) bool {
+	if cs == nil {
+		return false
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	ch := cs.channels[channelID]
+	return ch != nil && now.Before(ch.until)
+}
+
+// setUntil 设置抓取截止时间（零值表示停止，已有记录保留）
+func (cs *requestCaptureStore) setUntil(channelID int64, until time.Time) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	ch := cs.channels[channelID]
+	if ch == nil {
+		if until.IsZero() {
+			return
+		}
+		ch = &channelCapture{}
+		cs.channels[channelID] = ch
+	}
+	ch.until = until
+}
+
+func (cs *requestCaptureStore) add(rec RequestCapture) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	ch := cs.channels[rec.ChannelID]
+	if ch == nil {
+		return // 抓取期间被清空
+	}
+	ch.records = append(ch.records, rec)
+	if len(ch.records) > requestCaptureMaxRecords {
+		ch.records = ch.records[len(ch.records)-requestCaptureMaxRecords:]
+	}
+}
+
+// get 按ID查找记录
+func (cs *requestCaptureStore) get(id int64) (RequestCapture, bool) {
+	if cs == nil {
+		return RequestCapture{}, false
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for _, ch := range cs.channels {
+		for _, r := range ch.records {
+			if r.ID == id {
+				return r, true
+			}
+		}
+	}
+	return RequestCapture{}, false
+}
+
+// snapshot 返回截止时间与记录（新→旧）
+func (cs *requestCaptureStore) snapshot(channelID int64) (time.Time, []RequestCapture) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	out := []RequestCapture{}
+	ch := cs.channels[channelID]
+	if ch == nil {
+		return time.Time{}, out
+	}
+	for i := len(ch.records) - 1; i >= 0; i-- {
+		out = append(out, ch.records[i])
+	}
+	return ch.until, out
+}
+
+func (cs *requestCaptureStore) clear(channelID int64) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.channels, channelID)
+}
+
+// captureHeaders 复制并脱敏请求头（多值以逗号拼接）
+func captureHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		v := strings.Join(values, ", ")
+		if _, ok := captureSensitiveHeaders[strings.ToLower(name)]; ok {
+			if scheme, token, found := strings.Cut(v, " "); found && strings.EqualFold(scheme, "bearer") {
+				v = scheme + " " + util.MaskAPIKey(token)
+			} else {
+				v = util.MaskAPIKey(v)
+			}
+		} else {
+			v = util.RedactSecrets(v)
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// captureRequest 生成请求快照
+func captureRequest(method, rawURL string, h http.Header, body []byte) CapturedRequest {
+	cr := CapturedRequest{
+		Method:    method,
+		URL:       util.RedactSecrets(rawURL),
+		Headers:   captureHeaders(h),
+		BodyBytes: len(body),
+	}
+	cr.Body, cr.BodyTruncated = captureBody(body)
+	return cr
+}
+
+// captureBody 截断并脱敏请求/响应体
+func captureBody(body []byte) (string, bool) {
+	truncated := false
+	if len(body) > requestCaptureMaxBody {
+		body = body[:requestCaptureMaxBody]
+		truncated = true
+	}
+	return util.RedactSecrets(string(body)), truncated
+}
+
+// requestCaptureDraft 一次转发尝试的待定抓取：尝试期间只保留原始引用，结束后按需脱敏生成记录
+type requestCaptureDraft struct {
+	rec    RequestCapture
+	manual bool // 渠道处于手动抓取时段（无论成败都记录）
+	reqCtx *proxyRequestContext
+
+	outMethod string
+	outURL    *url.URL
+	outHeader http.Header
+	outBody   []byte
+	respBody  []byte // 手动抓取时段内的上游响应前 requestCaptureMaxBody 字节
+	respBytes int
+}
+
+// beginRequestCapture 渠道处于抓取时段或失败常驻抓取开启时，返回带出站回调的观测器副本与待定记录；否则返回原观测器与nil
+// 副本避免把回调挂到跨渠道共享的 reqCtx.observer 上
+func (s *Server) beginRequestCapture(cfg *model.Config, keyIndex int, reqCtx *proxyRequestContext, actualModel string) (*ForwardObserver, *requestCaptureDraft) {
+	now := time.Now()
+	manual := s.requestCaptures.enabled(cfg.ID, now)
+	if !manual && s.errorCaptures == nil {
+		return reqCtx.observer, nil
+	}
+	d := &requestCaptureDraft{
+		rec: RequestCapture{
+			At:          now.UnixMilli(),
+			ChannelID:   cfg.ID,
+			ChannelName: cfg.Name,
+			KeyIndex:    keyIndex,
+			AuthTokenID: reqCtx.tokenID,
+			ClientIP:    reqCtx.clientIP,
+			Model:       reqCtx.originalModel,
+			ActualModel: actualModel,
+		},
+		manual: manual,
+		reqCtx: reqCtx,
+	}
+	obs := &ForwardObserver{}
+	if reqCtx.observer != nil {
+		*obs = *reqCtx.observer
+	}
+	obs.OnUpstreamRequest = func(req *http.Request, body []byte) {
+		d.outMethod, d.outURL, d.outHeader, d.outBody = req.Method, req.URL, req.Header, body
+	}
+	if manual {
+		obs.OnResponseData = func(p []byte) {
+			d.respBytes += len(p)
+			if room := requestCaptureMaxBody - len(d.respBody); room > 0 {
+				d.respBody = append(d.respBody, p[:min(len(p), room)]...)
+			}
+		}
+	}
+	return obs, d
+}
+
+// finishRequestCapture 补全上游结果；手动抓取时段内总是保存，失败尝试同时写入失败抓取
+func (s *Server) finishRequestCapture(d *requestCaptureDraft, res *fwResult, duration float64, err error) {
+	if d == nil {
+		return
+	}
+	failed, errorClass := captureFailure(res, err)
+	if !d.manual && !(failed && s.errorCaptures != nil) {
+		return
+	}
+
+	rec := d.rec
+	rec.ID = s.captureSeq.Add(1)
+	rec.DurationMs = int64(duration * 1000)
+	rec.ErrorClass = errorClass
+	if res != nil {
+		rec.StatusCode = res.Status
+		rec.FirstByteMs = int64(res.FirstByteTime * 1000)
+		if !failed {
+			rec.Usage = &CapturedUsage{
+				InputTokens:         res.InputTokens,
+				OutputTokens:        res.OutputTokens,
+				CacheReadTokens:     res.CacheReadInputTokens,
+				CacheCreationTokens: res.CacheCreationInputTokens,
+			}
+		}
+	}
+	if err != nil {
+		rec.Error = util.RedactSecrets(err.Error())
+	}
+	inboundURL := d.reqCtx.requestPath
+	if d.reqCtx.rawQuery != "" {
+		inboundURL += "?" + d.reqCtx.rawQuery
+	}
+	rec.Inbound = captureRequest(d.reqCtx.requestMethod, inboundURL, d.reqCtx.header, d.reqCtx.body)
+	if d.outURL != nil {
+		rec.Outbound = captureRequest(d.outMethod, d.outURL.String(), d.outHeader, d.outBody)
+	}
+	if failed && res != nil {
+		body := res.Body
+		if len(res.SSEErrorEvent) > 0 {
+			body = res.SSEErrorEvent
+		}
+		resp := &CapturedResponse{Headers: captureHeaders(res.Header), BodyBytes: len(body)}
+		resp.Body, resp.BodyTruncated = captureBody(body)
+		rec.Response = resp
+	} else if d.manual && res != nil {
+		resp := &CapturedResponse{Headers: captureHeaders(res.Header), BodyBytes: d.respBytes}
+		resp.Body, _ = captureBody(d.respBody)
+		resp.BodyTruncated = d.respBytes > len(d.respBody)
+		rec.Response = resp
+	}
+
+	if d.manual {
+		s.requestCaptures.add(rec)
+	}
+	if failed {
+		s.errorCaptures.add(rec, time.Now())
+	}
+}
+
+// captureFailure 判断尝试是否失败并给出错误归类（客户端取消不算失败）
+func captureFailure(res *fwResult, err error) (bool, string) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || (res != nil && res.Status == util.StatusClientClosedRequest) {
+			return false, ""
+		}
+		return true, util.ErrorClassNetwork
+	}
+	if res == nil {
+		return false, ""
+	}
+	if len(res.SSEErrorEvent) > 0 {
+		return true, util.ClassifyUpstreamError(util.StatusSSEError, res.SSEErrorEvent)
+	}
+	if res.Status >= 200 && res.Status < 300 || res.Status == util.StatusClientClosedRequest {
+		return false, ""
+	}
+	return true, util.ClassifyUpstreamError(res.Status, res.Body)
+}
+
+// errorCaptureStore 失败请求常驻抓取（全渠道共享，按内存预算与保留时长淘汰最旧记录；nil 表示关闭）
+type errorCaptureStore struct {
+	mu        sync.Mutex
+	maxBytes  int64
+	retention time.Duration
+	records   []RequestCapture // 按时间升序
+	bytes     int64
+	seq       int64
+}
+
+func newErrorCaptureStore(maxBytes int64, retention time.Duration) *errorCaptureStore {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &errorCaptureStore{maxBytes: maxBytes, retention: retention}
+}
+
+func (es *errorCaptureStore) add(rec RequestCapture, now time.Time) {
+	if es == nil {
+		return
+	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if rec.ID == 0 {
+		es.seq++
+		rec.ID = es.seq
+	}
+	es.records = append(es.records, rec)
+	es.bytes += rec.size()
+	es.evictLocked(now)
+}
+
+// evictLocked 淘汰过期记录，并在超出预算时淘汰最旧记录（至少保留最新一条）
+func (es *errorCaptureStore) evictLocked(now time.Time) {
+	cutoff := now.Add(-es.retention).UnixMilli()
+	drop := 0
+	for drop < len(es.records)-1 && (es.bytes > es.maxBytes || es.records[drop].At < cutoff) {
+		es.bytes -= es.records[drop].size()
+		drop++
+	}
+	if drop > 0 {
+		es.records = slices.Delete(es.records, 0, drop)
+	}
+}
+
+// list 按条件返回记录（新→旧）
+func (es *errorCaptureStore) list(channelID int64, errorClass string, limit int, now time.Time) []RequestCapture {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.evictLocked(now)
+	cutoff := now.Add(-es.retention).UnixMilli()
+	out := []RequestCapture{}
+	for i := len(es.records) - 1; i >= 0 && len(out) < limit; i-- {
+		r := es.records[i]
+		if r.At < cutoff || (channelID > 0 && r.ChannelID != channelID) || (errorClass != "" && r.ErrorClass != errorClass) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// get 按ID查找未过期记录
+func (es *errorCaptureStore) get(id int64, now time.Time) (RequestCapture, bool) {
+	if es == nil {
+		return RequestCapture{}, false
+	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.evictLocked(now)
+	for _, r := range es.records {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return RequestCapture{}, false
+}
+
+func (es *errorCaptureStore) usage() (count int, bytes int64) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return len(es.records), es.bytes
+}
+
+func (es *errorCaptureStore) clear() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.records = nil
+	es.bytes = 0
+}
+
+// requestCaptureStatus 抓取状态响应
+type requestCaptureStatus struct {
+	ChannelID    int64            `json:"channel_id"`
+	Enabled      bool             `json:"enabled"`
+	EnabledUntil int64            `json:"enabled_until,omitempty"` // Unix毫秒
+	Captures     []RequestCapture `json:"captures"`
+}
+
+func (s *Server) requestCaptureStatus(channelID int64) requestCaptureStatus {
+	until, records := s.requestCaptures.snapshot(channelID)
+	st := requestCaptureStatus{ChannelID: channelID, Captures: records}
+	if time.Now().Before(until) {
+		st.Enabled = true
+		st.EnabledUntil = until.UnixMilli()
+	}
+	return st
+}
+
+// HandleChannelCaptures 查看渠道的请求抓取记录
+// GET /admin/channels/:id/captures
+func (s *Server) HandleChannelCaptures(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	RespondJSON(c, http.StatusOK, s.requestCaptureStatus(id))
+}
+
+// HandleSetChannelCapture 开启/停止渠道的请求抓取
+// POST /admin/channels/:id/captures {"minutes": 15}
+func (s *Server) HandleSetChannelCapture(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	var req struct {
+		Minutes int `json:"minutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Minutes < 0 || req.Minutes > requestCaptureMaxMinutes {
+		RespondErrorMsg(c, http.StatusBadRequest, "minutes must be within 0-120")
+		return
+	}
+	if _, err := s.store.GetConfig(c.Request.Context(), id); err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "channel not found")
+		return
+	}
+
+	var until time.Time
+	if req.Minutes > 0 {
+		until = time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+	}
+	s.requestCaptures.setUntil(id, until)
+	RespondJSON(c, http.StatusOK, s.requestCaptureStatus(id))
+}
+
+// HandleClearChannelCaptures 停止抓取并清空记录
+// DELETE /admin/channels/:id/captures
+func (s *Server) HandleClearChannelCaptures(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	s.requestCaptures.clear(id)
+	RespondJSON(c, http.StatusOK, s.requestCaptureStatus(id))
+}
+
+// errorCaptureStatus 失败抓取查询响应
+type errorCaptureStatus struct {
+	Enabled        bool             `json:"enabled"`
+	BudgetBytes    int64            `json:"budget_bytes,omitempty"`
+	UsedBytes      int64            `json:"used_bytes,omitempty"`
+	RetentionHours int              `json:"retention_hours,omitempty"`
+	Count          int              `json:"count"` // 当前保存的记录总数（过滤前）
+	Captures       []RequestCapture `json:"captures"`
+}
+
+// HandleErrorCaptures 查看失败请求抓取记录
+// GET /admin/captures/errors?channel_id=&error_class=&limit=
+func (s *Server) HandleErrorCaptures(c *gin.Context) {
+	es := s.errorCaptures
+	if es == nil {
+		RespondJSON(c, http.StatusOK, errorCaptureStatus{Captures: []RequestCapture{}})
+		return
+	}
+	var channelID int64
+	if v := c.Query("channel_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid channel_id")
+			return
+		}
+		channelID = id
+	}
+	errorClass := c.Query("error_class")
+	if errorClass != "" && !util.IsErrorClass(errorClass) {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid error_class")
+		return
+	}
+	limit := errorCaptureListDefaultLimit
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = min(v, errorCaptureListMaxLimit)
+	}
+
+	captures := es.list(channelID, errorClass, limit, time.Now())
+	count, used := es.usage()
+	RespondJSON(c, http.StatusOK, errorCaptureStatus{
+		Enabled:        true,
+		BudgetBytes:    es.maxBytes,
+		UsedBytes:      used,
+		RetentionHours: int(es.retention / time.Hour),
+		Count:          count,
+		Captures:       captures,
+	})
+}
+
+// HandleClearErrorCaptures 清空失败请求抓取记录（常驻抓取继续）
+// DELETE /admin/captures/errors
+func (s *Server) HandleClearErrorCaptures(c *gin.Context) {
+	if s.errorCaptures != nil {
+		s.errorCaptures.clear()
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"cleared": true})
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+	"ccLoad/internal/util"
+)
+
+func TestRequestCapture_RecordsInboundAndOutbound(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer upstream.Close()
+
+	store, _ := storage.CreateSQLiteStore(":memory:", nil)
+	srv := NewServer(store)
+	cfg := &model.Config{ID: 7, Name: "capture", URL: upstream.URL}
+	reqCtx := &proxyRequestContext{
+		originalModel: "claude-x",
+		requestMethod: http.MethodPost,
+		requestPath:   "/v1/messages",
+		rawQuery:      "beta=true",
+		body:          []byte(`{"model":"claude-x"}`),
+		header:        http.Header{"Authorization": []string{"Bearer client-token-1234567890"}},
+	}
+
+	// 未开启抓取（含失败常驻抓取）：原样返回观测器，不产生记录
+	srv.errorCaptures = nil
+	if obs, rec := srv.beginRequestCapture(cfg, 0, reqCtx, "claude-y"); obs != nil || rec != nil {
+		t.Fatalf("capture should be disabled, got %v %v", obs, rec)
+	}
+
+	srv.requestCaptures.setUntil(cfg.ID, time.Now().Add(time.Minute))
+	obs, rec := srv.beginRequestCapture(cfg, 0, reqCtx, "claude-y")
+	if rec == nil || obs == nil || obs.OnUpstreamRequest == nil {
+		t.Fatal("capture should be enabled")
+	}
+	res, duration, err := srv.forwardOnceAsync(context.Background(), cfg, "sk-upstream-secret-abcdef", http.MethodPost,
+		[]byte(`{"model":"claude-y"}`), reqCtx.header, reqCtx.rawQuery, reqCtx.requestPath, httptest.NewRecorder(), obs)
+	if err != nil {
+		t.Fatalf("forward: %v", err)
+	}
+	srv.finishRequestCapture(rec, res, duration, err)
+
+	st := srv.requestCaptureStatus(cfg.ID)
+	if !st.Enabled || len(st.Captures) != 1 {
+		t.Fatalf("status = %+v", st)
+	}
+	got := st.Captures[0]
+	if got.StatusCode != 200 || got.Model != "claude-x" || got.ActualModel != "claude-y" {
+		t.Fatalf("capture meta = %+v", got)
+	}
+	if got.Inbound.URL != "/v1/messages?beta=true" || got.Inbound.Body != `{"model":"claude-x"}` {
+		t.Fatalf("inbound = %+v", got.Inbound)
+	}
+	if !strings.HasPrefix(got.Outbound.URL, upstream.URL+"/v1/messages") || got.Outbound.Body != `{"model":"claude-y"}` {
+		t.Fatalf("outbound = %+v", got.Outbound)
+	}
+	for _, h := range []map[string]string{got.Inbound.Headers, got.Outbound.Headers} {
+		for name, v := range h {
+			if strings.Contains(v, "client-token-1234567890") || strings.Contains(v, "sk-upstream-secret-abcdef") {
+				t.Fatalf("header %s leaks secret: %q", name, v)
+			}
+		}
+	}
+	if got.Outbound.Headers["X-Api-Key"] == "" {
+		t.Fatalf("outbound headers missing x-api-key: %v", got.Outbound.Headers)
+	}
+	if got.ID == 0 || got.Response == nil || got.Response.Body != `{"id":"ok"}` || got.Usage == nil {
+		t.Fatalf("manual capture should record id, response and usage: %+v", got)
+	}
+
+	// 停止后保留记录，清空后删除
+	srv.requestCaptures.setUntil(cfg.ID, time.Time{})
+	if st := srv.requestCaptureStatus(cfg.ID); st.Enabled || len(st.Captures) != 1 {
+		t.Fatalf("after stop = %+v", st)
+	}
+	srv.requestCaptures.clear(cfg.ID)
+	if st := srv.requestCaptureStatus(cfg.ID); len(st.Captures) != 0 {
+		t.Fatalf("after clear = %+v", st)
+	}
+}
+
+// TestCaptureHeaders_MasksKeyHeaders 各家上游的Key请求头无论长短都整体脱敏，不依赖密钥格式识别
+func TestCaptureHeaders_MasksKeyHeaders(t *testing.T) {
+	for _, name := range []string{"Api-Key", "X-Goog-Api-Key", "X-Api-Key"} {
+		for _, value := range []string{"0123456789abcdef0123456789abcdef", "short"} {
+			got := captureHeaders(http.Header{name: []string{value}})[name]
+			if strings.Contains(got, value) || got == "" {
+				t.Errorf("%s=%q should be masked, got %q", name, value, got)
+			}
+		}
+	}
+}
+
+func TestRequestCaptureStore_KeepsRecentRecords(t *testing.T) {
+	cs := newRequestCaptureStore()
+	cs.add(RequestCapture{ChannelID: 1}) // 未开启抓取的渠道不保存
+	if _, recs := cs.snapshot(1); len(recs) != 0 {
+		t.Fatalf("unexpected records: %d", len(recs))
+	}
+	cs.setUntil(1, time.Now().Add(time.Minute))
+	for i := range requestCaptureMaxRecords + 5 {
+		cs.add(RequestCapture{ChannelID: 1, At: int64(i)})
+	}
+	_, recs := cs.snapshot(1)
+	if len(recs) != requestCaptureMaxRecords || recs[0].At != requestCaptureMaxRecords+4 {
+		t.Fatalf("records = %d, newest = %d", len(recs), recs[0].At)
+	}
+}
+
+func TestErrorCapture_AlwaysOnForFailures(t *testing.T) {
+	var fail atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if fail.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer upstream.Close()
+
+	store, _ := storage.CreateSQLiteStore(":memory:", nil)
+	srv := NewServer(store)
+	if srv.errorCaptures == nil {
+		t.Fatal("error capture should be on by default")
+	}
+	cfg := &model.Config{ID: 9, Name: "flaky", URL: upstream.URL}
+	reqCtx := &proxyRequestContext{
+		originalModel: "claude-x",
+		requestMethod: http.MethodPost,
+		requestPath:   "/v1/messages",
+		body:          []byte(`{"model":"claude-x"}`),
+		header:        http.Header{},
+		tokenID:       3,
+	}
+	attempt := func() {
+		obs, d := srv.beginRequestCapture(cfg, 1, reqCtx, "claude-x")
+		res, duration, err := srv.forwardOnceAsync(context.Background(), cfg, "sk-upstream-secret-abcdef", http.MethodPost,
+			reqCtx.body, reqCtx.header, "", reqCtx.requestPath, httptest.NewRecorder(), obs)
+		srv.finishRequestCapture(d, res, duration, err)
+	}
+
+	// 成功请求不记录；手动抓取未开启时失败请求仍记录
+	attempt()
+	fail.Store(true)
+	attempt()
+	recs := srv.errorCaptures.list(0, "", 10, time.Now())
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 error capture, got %d", len(recs))
+	}
+	got := recs[0]
+	if got.StatusCode != http.StatusUnauthorized || got.ErrorClass != util.ErrorClassAuth || got.ChannelName != "flaky" || got.AuthTokenID != 3 {
+		t.Fatalf("capture meta = %+v", got)
+	}
+	if got.Response == nil || !strings.Contains(got.Response.Body, "authentication_error") || got.Outbound.Body != `{"model":"claude-x"}` {
+		t.Fatalf("capture should include outbound request and upstream response: %+v", got)
+	}
+	if st := srv.requestCaptureStatus(cfg.ID); len(st.Captures) != 0 {
+		t.Fatalf("manual captures should stay empty, got %d", len(st.Captures))
+	}
+	if recs := srv.errorCaptures.list(cfg.ID, util.ErrorClassNetwork, 10, time.Now()); len(recs) != 0 {
+		t.Fatalf("error_class filter should exclude auth errors, got %d", len(recs))
+	}
+}
+
+func TestErrorCaptureStore_BudgetAndRetention(t *testing.T) {
+	now := time.Now()
+	body := strings.Repeat("x", 1000)
+	rec := func(at time.Time) RequestCapture {
+		return RequestCapture{ChannelID: 1, At: at.UnixMilli(), Inbound: CapturedRequest{Body: body}}
+	}
+	sample := rec(now)
+	size := sample.size()
+
+	es := newErrorCaptureStore(size*3, time.Hour)
+	for i := range 5 {
+		es.add(rec(now), now.Add(time.Duration(i)))
+	}
+	if count, used := es.usage(); count != 3 || used > size*3 {
+		t.Fatalf("budget not enforced: count=%d used=%d", count, used)
+	}
+	if recs := es.list(0, "", 10, now); recs[0].ID != 5 || recs[2].ID != 3 {
+		t.Fatalf("oldest records should be evicted first, got ids %d..%d", recs[0].ID, recs[2].ID)
+	}
+
+	es.clear()
+	es.add(rec(now.Add(-2*time.Hour)), now)
+	es.add(rec(now), now)
+	if recs := es.list(0, "", 10, now); len(recs) != 1 || recs[0].ID != 7 {
+		t.Fatalf("expired records should be dropped, got %+v", recs)
+	}
+	if newErrorCaptureStore(0, time.Hour) != nil {
+		t.Fatal("zero budget should disable error capture")
+	}
+}
@@ -16,6 +16,7 @@ type requestContext struct {
 	isStreaming       bool
 	firstByteTimer    *time.Timer
 	firstByteTimedOut atomic.Bool
+	jsonMode          *jsonModeSpec // 客户端要求JSON输出且开启 json_repair_enabled 时非 nil
 }
 
 // newRequestContext 创建请求上下文（处理超时控制）
@@ -47,6 +48,9 @@ func (s *Server) newRequestContext(parentCtx context.Context, requestPath string
 		startTime:   time.Now(),
 		isStreaming: isStreaming,
 	}
+	if s.jsonRepairEnabled {
+		reqCtx.jsonMode = detectJSONMode(body)
+	}
 
 	// 流式请求的首字节超时定时器
 	if isStreaming && s.firstByteTimeout > 0 {
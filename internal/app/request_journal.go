@@ -0,0 +1,463 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 请求用量预写日志（2026-10新增）
+// ============================================================================
+// 日志与Token统计均经内存队列异步批量落库，进程崩溃时队列中的用量会丢失（影响计费）。
+// 预写日志在入队前把带用量的日志条目/Token统计追加到本地文件（每条一次 write 系统调用，
+// 进程崩溃不丢；未 fsync，主机掉电不在保证范围内），落库成功后追加确认记录。
+// 启动时重放所有未确认的记录后压缩文件：
+//   - 日志：按 时间(毫秒)+渠道+模型+状态码+Token+Key 查重，已落库（确认记录写出前崩溃）的不重复写入
+//   - Token统计：只能以确认记录为准（无逐请求明细可查重）
+//
+// 文件路径由环境变量 CCLOAD_REQUEST_JOURNAL 指定（off 关闭），默认 data/request_journal.log。
+
+const (
+	requestJournalEnv         = "CCLOAD_REQUEST_JOURNAL"
+	defaultRequestJournalPath = "data/request_journal.log"
+
+	journalOpLog   = "log"
+	journalOpStats = "stats"
+	journalOpAck   = "ack"
+
+	// journalCompactBytes 文件超过该大小时在确认后压缩（仅保留未确认记录）
+	journalCompactBytes = 8 << 20
+)
+
+// journalStats Token统计记录（tokenStatsUpdate 的可序列化形式）
+type journalStats struct {
+	TokenHash           string  `json:"token_hash"`
+	IsSuccess           bool    `json:"is_success"`
+	Duration            float64 `json:"duration"`
+	IsStreaming         bool    `json:"is_streaming"`
+	FirstByteTime       float64 `json:"first_byte_time"`
+	PromptTokens        int64   `json:"prompt_tokens"`
+	CompletionTokens    int64   `json:"completion_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CostUSD             float64 `json:"cost_usd"`
+}
+
+func (js *journalStats) update() tokenStatsUpdate {
+	return tokenStatsUpdate{
+		tokenHash:           js.TokenHash,
+		isSuccess:           js.IsSuccess,
+		duration:            js.Duration,
+		isStreaming:         js.IsStreaming,
+		firstByteTime:       js.FirstByteTime,
+		promptTokens:        js.PromptTokens,
+		completionTokens:    js.CompletionTokens,
+		cacheReadTokens:     js.CacheReadTokens,
+		cacheCreationTokens: js.CacheCreationTokens,
+		costUSD:             js.CostUSD,
+	}
+}
+
+// journalRecord 预写日志的一行
+type journalRecord struct {
+	Op     string          `json:"op"`
+	ID     uint64          `json:"id,omitempty"`
+	IDs    []uint64        `json:"ids,omitempty"`     // ack
+	TimeMs int64           `json:"time_ms,omitempty"` // log：LogEntry.Time 的JSON形式只有秒级精度
+	Log    *model.LogEntry `json:"log,omitempty"`
+	Stats  *journalStats   `json:"stats,omitempty"`
+}
+
+// requestJournal 预写日志（nil 表示未启用，所有方法均为空操作）
+type requestJournal struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	size    int64
+	nextID  uint64
+	pending map[uint64][]byte // 未确认记录（原始行，压缩时原样写回）
+
+	replayedLogs  atomic.Int64
+	replayedStats atomic.Int64
+	writeErrors   atomic.Int64
+}
+
+// journalWantsLog 仅记录带用量的日志（失败且无消耗的请求不影响计费）
+func journalWantsLog(e *model.LogEntry) bool {
+	return e.Cost > 0 || e.InputTokens > 0 || e.OutputTokens > 0 ||
+		e.CacheReadInputTokens > 0 || e.CacheCreationInputTokens > 0
+}
+
+func journalWantsStats(upd *tokenStatsUpdate) bool {
+	return upd.costUSD > 0 || upd.promptTokens > 0 || upd.completionTokens > 0 ||
+		upd.cacheReadTokens > 0 || upd.cacheCreationTokens > 0
+}
+
+// requestJournalPathFromEnv 解析预写日志路径（空字符串表示关闭）
+func requestJournalPathFromEnv() string {
+	v := strings.TrimSpace(os.Getenv(requestJournalEnv))
+	switch strings.ToLower(v) {
+	case "":
+		return defaultRequestJournalPath
+	case "off", "false", "0", "none":
+		return ""
+	}
+	return v
+}
+
+// openRequestJournal 打开预写日志并加载未确认记录（不重放）
+func openRequestJournal(path string) (*requestJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	j := &requestJournal{path: path, nextID: 1, pending: make(map[uint64][]byte)}
+
+	data, err := os.ReadFile(path) //nolint:gosec // G304: 路径来自启动环境变量
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var rec journalRecord
+		if err := sonic.Unmarshal(line, &rec); err != nil {
+			continue // 崩溃时写了一半的末行
+		}
+		switch rec.Op {
+		case journalOpLog, journalOpStats:
+			j.pending[rec.ID] = append([]byte(nil), line...)
+			j.nextID = max(j.nextID, rec.ID+1)
+		case journalOpAck:
+			for _, id := range rec.IDs {
+				delete(j.pending, id)
+			}
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // G304: 同上
+	if err != nil {
+		return nil, err
+	}
+	j.f = f
+	j.size = int64(len(data))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		// 补齐崩溃时写了一半的末行，避免与后续记录拼接成一行
+		if _, err := f.Write([]byte{'\n'}); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		j.size++
+	}
+	return j, nil
+}
+
+// appendLocked 追加一行（调用方持有锁）
+func (j *requestJournal) appendLocked(rec *journalRecord) ([]byte, error) {
+	line, err := sonic.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	line = append(line, '\n')
+	if _, err := j.f.Write(line); err != nil {
+		j.writeErrors.Add(1)
+		return nil, err
+	}
+	j.size += int64(len(line))
+	return line, nil
+}
+
+func (j *requestJournal) appendPending(rec *journalRecord) uint64 {
+	if j == nil {
+		return 0
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return 0
+	}
+	rec.ID = j.nextID
+	line, err := j.appendLocked(rec)
+	if err != nil {
+		log.Printf("[ERROR] 预写日志写入失败（本条用量仅依赖内存队列落库）: %v", err)
+		return 0
+	}
+	j.nextID++
+	j.pending[rec.ID] = line[:len(line)-1]
+	return rec.ID
+}
+
+// appendLog 记录一条日志，返回记录ID（0表示未记录）
+func (j *requestJournal) appendLog(e *model.LogEntry) uint64 {
+	if j == nil {
+		return 0
+	}
+	cp := *e
+	if cp.APIKeyUsed != "" {
+		cp.APIKeyUsed = util.MaskAPIKey(cp.APIKeyUsed) // 与落库一致：文件中不落明文Key
+	}
+	return j.appendPending(&journalRecord{Op: journalOpLog, TimeMs: e.Time.UnixMilli(), Log: &cp})
+}
+
+// appendStats 记录一条Token统计，返回记录ID（0表示未记录）
+func (j *requestJournal) appendStats(upd *tokenStatsUpdate) uint64 {
+	return j.appendPending(&journalRecord{Op: journalOpStats, Stats: &journalStats{
+		TokenHash:           upd.tokenHash,
+		IsSuccess:           upd.isSuccess,
+		Duration:            upd.duration,
+		IsStreaming:         upd.isStreaming,
+		FirstByteTime:       upd.firstByteTime,
+		PromptTokens:        upd.promptTokens,
+		CompletionTokens:    upd.completionTokens,
+		CacheReadTokens:     upd.cacheReadTokens,
+		CacheCreationTokens: upd.cacheCreationTokens,
+		CostUSD:             upd.costUSD,
+	}})
+}
+
+// ack 确认记录已落库；文件过大时顺带压缩
+func (j *requestJournal) ack(ids ...uint64) {
+	if j == nil {
+		return
+	}
+	acked := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 {
+			acked = append(acked, id)
+		}
+	}
+	if len(acked) == 0 {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return
+	}
+	for _, id := range acked {
+		delete(j.pending, id)
+	}
+	if _, err := j.appendLocked(&journalRecord{Op: journalOpAck, IDs: acked}); err != nil {
+		log.Printf("[WARN] 预写日志确认记录写入失败（重启时可能重复重放Token统计）: %v", err)
+	}
+	if j.size > journalCompactBytes {
+		if err := j.compactLocked(); err != nil {
+			log.Printf("[WARN] 预写日志压缩失败: %v", err)
+		}
+	}
+}
+
+// compactLocked 仅保留未确认记录重写文件（临时文件+rename，崩溃时新旧文件总有一个完整）
+func (j *requestJournal) compactLocked() error {
+	ids := make([]uint64, 0, len(j.pending))
+	for id := range j.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+
+	var buf bytes.Buffer
+	for _, id := range ids {
+		buf.Write(j.pending[id])
+		buf.WriteByte('\n')
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // G304: 同上
+	if err != nil {
+		return err
+	}
+	_ = j.f.Close()
+	j.f = f
+	j.size = int64(buf.Len())
+	return nil
+}
+
+// pendingRecords 返回指定类型的未确认记录（按ID升序）
+func (j *requestJournal) pendingRecords(op string) []journalRecord {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	recs := make([]journalRecord, 0)
+	for _, line := range j.pending {
+		var rec journalRecord
+		if sonic.Unmarshal(line, &rec) == nil && rec.Op == op {
+			recs = append(recs, rec)
+		}
+	}
+	sort.Slice(recs, func(a, b int) bool { return recs[a].ID < recs[b].ID })
+	return recs
+}
+
+// compact 重放结束后压缩文件
+func (j *requestJournal) compact() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	return j.compactLocked()
+}
+
+// close 关闭文件；之后的追加/确认均为空操作（未确认记录留待下次启动重放）
+func (j *requestJournal) close() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f != nil {
+		_ = j.f.Close()
+		j.f = nil
+	}
+}
+
+// stats 健康检查用统计
+func (j *requestJournal) stats() map[string]any {
+	j.mu.Lock()
+	pending, size := len(j.pending), j.size
+	j.mu.Unlock()
+	return map[string]any{
+		"path":           j.path,
+		"pending":        pending,
+		"size_bytes":     size,
+		"replayed_logs":  j.replayedLogs.Load(),
+		"replayed_stats": j.replayedStats.Load(),
+		"write_errors":   j.writeErrors.Load(),
+	}
+}
+
+// ============================================================================
+// 启动重放
+// ============================================================================
+
+// journalLogPersisted 判断日志是否已落库（崩溃发生在落库之后、确认记录写出之前）
+func journalLogPersisted(ctx context.Context, store interface {
+	ListLogsRange(ctx context.Context, since, until time.Time, limit, offset int, filter *model.LogFilter) ([]*model.LogEntry, error)
+}, e *model.LogEntry) (bool, error) {
+	channelID, status := e.ChannelID, e.StatusCode
+	rows, err := store.ListLogsRange(ctx, e.Time.Time, e.Time.Time, 50, 0, &model.LogFilter{
+		ChannelID:  &channelID,
+		Model:      e.Model,
+		StatusCode: &status,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, r := range rows {
+		if r.InputTokens == e.InputTokens && r.OutputTokens == e.OutputTokens &&
+			r.CacheReadInputTokens == e.CacheReadInputTokens && r.CacheCreationInputTokens == e.CacheCreationInputTokens &&
+			r.APIKeyUsed == e.APIKeyUsed && r.AuthTokenID == e.AuthTokenID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// replayJournalLogs 重放未确认的日志（需在加载今日渠道成本缓存之前执行）
+func (s *Server) replayJournalLogs(ctx context.Context) {
+	recs := s.journal.pendingRecords(journalOpLog)
+	if len(recs) == 0 {
+		return
+	}
+	var (
+		toWrite []*model.LogEntry
+		ids     []uint64
+		skipped int
+	)
+	for _, rec := range recs {
+		if rec.Log == nil {
+			ids = append(ids, rec.ID)
+			continue
+		}
+		e := rec.Log
+		e.ID = 0
+		e.Time = model.JSONTime{Time: time.UnixMilli(rec.TimeMs)}
+		persisted, err := journalLogPersisted(ctx, s.store, e)
+		if err != nil {
+			log.Printf("[ERROR] 预写日志重放查重失败，保留待下次启动: %v", err)
+			return
+		}
+		if persisted {
+			skipped++
+		} else {
+			toWrite = append(toWrite, e)
+		}
+		ids = append(ids, rec.ID)
+	}
+	if len(toWrite) > 0 {
+		if err := s.store.BatchAddLogs(ctx, toWrite); err != nil {
+			log.Printf("[ERROR] 预写日志重放写入失败，保留待下次启动: %v", err)
+			return
+		}
+	}
+	s.journal.ack(ids...)
+	s.journal.replayedLogs.Add(int64(len(toWrite)))
+	log.Printf("[INFO] 预写日志：已重放 %d 条未落库日志（%d 条已落库跳过）", len(toWrite), skipped)
+}
+
+// replayJournalStats 重放未确认的Token统计（需在 AuthService 初始化之后执行，以同步费用缓存）
+func (s *Server) replayJournalStats() {
+	recs := s.journal.pendingRecords(journalOpStats)
+	replayed := 0
+	for _, rec := range recs {
+		if rec.Stats == nil {
+			s.journal.ack(rec.ID)
+			continue
+		}
+		upd := rec.Stats.update()
+		upd.journalID = rec.ID
+		if s.applyTokenStatsUpdate(upd) {
+			replayed++
+		}
+	}
+	if replayed > 0 {
+		s.journal.replayedStats.Add(int64(replayed))
+		log.Printf("[INFO] 预写日志：已重放 %d 条未落库Token统计", replayed)
+	}
+	if err := s.journal.compact(); err != nil {
+		log.Printf("[WARN] 预写日志压缩失败: %v", err)
+	}
+}
+
+// initRequestJournal 打开预写日志（失败时降级为不启用，不阻塞启动）
+func initRequestJournal() *requestJournal {
+	path := requestJournalPathFromEnv()
+	if path == "" {
+		log.Print("[INFO] 请求用量预写日志已关闭")
+		return nil
+	}
+	j, err := openRequestJournal(path)
+	if err != nil {
+		log.Printf("[WARN] 请求用量预写日志不可用（崩溃时队列中的用量可能丢失）: %v", fmt.Errorf("open %s: %w", path, err))
+		return nil
+	}
+	if n := len(j.pending); n > 0 {
+		log.Printf("[INFO] 预写日志：发现 %d 条未确认记录，开始重放", n)
+	}
+	return j
+}
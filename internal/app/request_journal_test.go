@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+)
+
+func TestRequestJournal_AckAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.log")
+	j, err := openRequestJournal(path)
+	if err != nil {
+		t.Fatalf("打开预写日志失败: %v", err)
+	}
+	now := time.Now()
+	id1 := j.appendLog(&model.LogEntry{Time: model.JSONTime{Time: now}, Model: "m", APIKeyUsed: "sk-plaintext-secret", InputTokens: 10, Cost: 0.1})
+	id2 := j.appendStats(&tokenStatsUpdate{tokenHash: "h", isSuccess: true, promptTokens: 10, costUSD: 0.1})
+	id3 := j.appendLog(&model.LogEntry{Time: model.JSONTime{Time: now}, Model: "m", OutputTokens: 5})
+	if id1 == 0 || id2 != id1+1 || id3 != id2+1 {
+		t.Fatalf("记录ID应递增: %d %d %d", id1, id2, id3)
+	}
+	j.ack(id1, 0)
+	j.close()
+
+	// 模拟崩溃后重启：末尾残留半行
+	appendRaw(t, path, `{"op":"log","id":99,"log":{`)
+
+	j, err = openRequestJournal(path)
+	if err != nil {
+		t.Fatalf("重新打开预写日志失败: %v", err)
+	}
+	if got := len(j.pending); got != 2 {
+		t.Fatalf("未确认记录应为2条，实际 %d", got)
+	}
+	logs := j.pendingRecords(journalOpLog)
+	if len(logs) != 1 || logs[0].ID != id3 || logs[0].TimeMs != now.UnixMilli() {
+		t.Fatalf("未确认日志不符: %+v", logs)
+	}
+	if stats := j.pendingRecords(journalOpStats); len(stats) != 1 || stats[0].Stats.TokenHash != "h" {
+		t.Fatalf("未确认Token统计不符: %+v", stats)
+	}
+	if next := j.appendStats(&tokenStatsUpdate{tokenHash: "h", costUSD: 1}); next != id3+1 {
+		t.Fatalf("重启后记录ID应接续，实际 %d", next)
+	}
+
+	j.close()
+	j, err = openRequestJournal(path)
+	if err != nil {
+		t.Fatalf("重新打开预写日志失败: %v", err)
+	}
+	if got := len(j.pending); got != 3 {
+		t.Fatalf("残留半行之后追加的记录应可读回，实际未确认 %d 条", got)
+	}
+
+	if err := j.compact(); err != nil {
+		t.Fatalf("压缩失败: %v", err)
+	}
+	if j.size == 0 || len(j.pending) != 3 {
+		t.Fatalf("压缩后应只保留未确认记录: size=%d pending=%d", j.size, len(j.pending))
+	}
+	j.close()
+}
+
+func TestRequestJournal_MasksAPIKey(t *testing.T) {
+	j, err := openRequestJournal(filepath.Join(t.TempDir(), "journal.log"))
+	if err != nil {
+		t.Fatalf("打开预写日志失败: %v", err)
+	}
+	defer j.close()
+	e := &model.LogEntry{Time: model.JSONTime{Time: time.Now()}, APIKeyUsed: "sk-plaintext-secret", InputTokens: 1}
+	j.appendLog(e)
+	if e.APIKeyUsed != "sk-plaintext-secret" {
+		t.Fatal("不应修改调用方的日志条目")
+	}
+	if recs := j.pendingRecords(journalOpLog); len(recs) != 1 || recs[0].Log.APIKeyUsed != "sk-p...cret" {
+		t.Fatalf("预写日志中的Key应脱敏: %+v", recs)
+	}
+}
+
+func TestNewServer_ReplaysRequestJournal(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "ccload.db")
+	journalPath := filepath.Join(dir, "journal.log")
+	t.Setenv(requestJournalEnv, journalPath)
+	ctx := context.Background()
+
+	store, err := storage.CreateSQLiteStore(dbPath, nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	token := &model.AuthToken{Token: "hash-journal", Description: "t", CreatedAt: time.Now(), IsActive: true}
+	if err := store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+
+	// 上次进程：两条日志入队，其中一条已落库但确认记录未写出；一条Token统计未落库
+	now := time.Now()
+	persisted := &model.LogEntry{Time: model.JSONTime{Time: now}, Model: "m", ChannelID: 1, StatusCode: 200, AuthTokenID: token.ID, InputTokens: 10, Cost: 0.1}
+	lost := &model.LogEntry{Time: model.JSONTime{Time: now.Add(time.Millisecond)}, Model: "m", ChannelID: 1, StatusCode: 200, AuthTokenID: token.ID, InputTokens: 20, Cost: 0.2}
+	j, err := openRequestJournal(journalPath)
+	if err != nil {
+		t.Fatalf("打开预写日志失败: %v", err)
+	}
+	j.appendLog(persisted)
+	j.appendLog(lost)
+	j.appendStats(&tokenStatsUpdate{tokenHash: token.Token, isSuccess: true, duration: 1, promptTokens: 20, costUSD: 0.2})
+	j.close()
+	if err := store.BatchAddLogs(ctx, []*model.LogEntry{persisted}); err != nil {
+		t.Fatalf("写入日志失败: %v", err)
+	}
+	_ = store.Close()
+
+	for round := 1; round <= 2; round++ { // 第二轮验证重放后不会重复计入
+		store, err := storage.CreateSQLiteStore(dbPath, nil)
+		if err != nil {
+			t.Fatalf("重新打开存储失败: %v", err)
+		}
+		srv := NewServer(store)
+		if srv.journal == nil {
+			t.Fatal("预写日志应已启用")
+		}
+		if n := srv.journal.stats()["pending"]; n != 0 {
+			t.Fatalf("第%d轮：重放后不应残留未确认记录，实际 %v", round, n)
+		}
+
+		logs, err := store.ListLogsRange(ctx, now.Add(-time.Minute), now.Add(time.Minute), 100, 0, nil)
+		if err != nil {
+			t.Fatalf("查询日志失败: %v", err)
+		}
+		if len(logs) != 2 {
+			t.Fatalf("第%d轮：日志应恰好2条（已落库的不重复写入），实际 %d", round, len(logs))
+		}
+		got, err := store.GetAuthTokenByValue(ctx, token.Token)
+		if err != nil {
+			t.Fatalf("查询令牌失败: %v", err)
+		}
+		if got.SuccessCount != 1 || got.TotalCostUSD < 0.199 || got.TotalCostUSD > 0.201 {
+			t.Fatalf("第%d轮：Token统计应恰好重放一次: success=%d cost=%f", round, got.SuccessCount, got.TotalCostUSD)
+		}
+		_ = srv.Shutdown(ctx)
+	}
+}
+
+func appendRaw(t *testing.T, path, s string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("打开文件失败: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 请求体大小上限学习（2026-10新增）
+// ============================================================================
+// 上游返回 413 / request_too_large 时按客户端错误处理（不冷却，见 util.IsRequestTooLargeError），
+// 同时按渠道记录观测到的大小：
+//   - maxAccepted：成功请求的最大请求体字节数（已知可接受的上限）
+//   - minRejected：被拒请求的最小请求体字节数（requestSizeRejectTTL 内有效，上游调高限制后自动失效）
+// 返回给客户端的 413 错误体追加 max_request_bytes（已知可接受的上限，未知时不追加）；
+// 后续请求体 >= minRejected 时跳过该渠道，所有候选渠道都会拒绝时直接本地返回 413，不再浪费上游请求。
+// GET /admin/request-size-limits 查看各渠道观测值。
+
+const requestSizeRejectTTL = 24 * time.Hour
+
+// ChannelRequestSizeLimit 渠道请求体大小观测值
+type ChannelRequestSizeLimit struct {
+	ChannelID   int64 `json:"channel_id"`
+	MaxAccepted int64 `json:"max_accepted_bytes"`           // 0 表示尚无成功样本
+	MinRejected int64 `json:"min_rejected_bytes,omitempty"` // 0 表示未被拒绝（或已过期）
+	RejectedAt  int64 `json:"rejected_at,omitempty"`        // Unix毫秒
+}
+
+// requestSizeTracker 按渠道记录请求体大小观测值（仅内存，重启清空；nil 时所有方法为空操作）
+type requestSizeTracker struct {
+	mu       sync.RWMutex
+	channels map[int64]*ChannelRequestSizeLimit
+}
+
+func newRequestSizeTracker() *requestSizeTracker {
+	return &requestSizeTracker{channels: make(map[int64]*ChannelRequestSizeLimit)}
+}
+
+func (t *requestSizeTracker) entry(channelID int64) *ChannelRequestSizeLimit {
+	e := t.channels[channelID]
+	if e == nil {
+		e = &ChannelRequestSizeLimit{ChannelID: channelID}
+		t.channels[channelID] = e
+	}
+	return e
+}
+
+// observeAccepted 记录成功请求的大小（超过已记录的拒绝值说明上游已调高限制，清除拒绝记录）
+func (t *requestSizeTracker) observeAccepted(channelID, size int64) {
+	if t == nil || size <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.entry(channelID)
+	e.MaxAccepted = max(e.MaxAccepted, size)
+	if e.MinRejected > 0 && size >= e.MinRejected {
+		e.MinRejected, e.RejectedAt = 0, 0
+	}
+}
+
+// observeRejected 记录被拒请求的大小，返回渠道已知可接受的上限
+func (t *requestSizeTracker) observeRejected(channelID, size int64, now time.Time) int64 {
+	if t == nil || size <= 0 {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.entry(channelID)
+	if e.MinRejected == 0 || size < e.MinRejected || !t.rejectActive(e, now) {
+		e.MinRejected = size
+	}
+	e.RejectedAt = now.UnixMilli()
+	// 不同Key/模型的上限可能不同，已接受值大于拒绝值时以拒绝值为准
+	return min(e.MaxAccepted, e.MinRejected-1)
+}
+
+func (t *requestSizeTracker) rejectActive(e *ChannelRequestSizeLimit, now time.Time) bool {
+	return e.MinRejected > 0 && now.UnixMilli()-e.RejectedAt < requestSizeRejectTTL.Milliseconds()
+}
+
+// wouldReject 渠道是否已知会拒绝该大小的请求
+func (t *requestSizeTracker) wouldReject(channelID, size int64, now time.Time) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	e := t.channels[channelID]
+	return e != nil && t.rejectActive(e, now) && size >= e.MinRejected
+}
+
+// filter 剔除已知会拒绝该大小请求的渠道；全部剔除时返回nil与这些渠道中最大的已知可接受上限
+func (t *requestSizeTracker) filter(size int64, channels []*model.Config, now time.Time) ([]*model.Config, int64) {
+	if t == nil || len(channels) == 0 {
+		return channels, 0
+	}
+	kept := make([]*model.Config, 0, len(channels))
+	for _, cfg := range channels {
+		if !t.wouldReject(cfg.ID, size, now) {
+			kept = append(kept, cfg)
+		}
+	}
+	if len(kept) > 0 {
+		return kept, 0
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var limit int64
+	for _, cfg := range channels {
+		if e := t.channels[cfg.ID]; e != nil {
+			limit = max(limit, min(e.MaxAccepted, e.MinRejected-1))
+		}
+	}
+	return nil, limit
+}
+
+// list 返回各渠道观测值（过期的拒绝记录不展示）
+func (t *requestSizeTracker) list(now time.Time) []ChannelRequestSizeLimit {
+	out := []ChannelRequestSizeLimit{}
+	if t == nil {
+		return out
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, e := range t.channels {
+		item := *e
+		if !t.rejectActive(e, now) {
+			item.MinRejected, item.RejectedAt = 0, 0
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// appendMaxRequestBytes 在JSON错误体中追加 max_request_bytes（非JSON、压缩或已存在时原样返回）
+func appendMaxRequestBytes(body []byte, hdr http.Header, limit int64) []byte {
+	if limit <= 0 || (hdr != nil && hdr.Get("Content-Encoding") != "") {
+		return body
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) < 2 || trimmed[0] != '{' || trimmed[len(trimmed)-1] != '}' {
+		return body
+	}
+	var probe map[string]sonic.NoCopyRawMessage
+	if err := sonic.Unmarshal(trimmed, &probe); err != nil {
+		return body
+	}
+	if _, exists := probe["max_request_bytes"]; exists {
+		return body
+	}
+	out := make([]byte, 0, len(trimmed)+32)
+	out = append(out, trimmed[:len(trimmed)-1]...)
+	if len(probe) > 0 {
+		out = append(out, ',')
+	}
+	out = append(out, `"max_request_bytes":`...)
+	out = strconv.AppendInt(out, limit, 10)
+	return append(out, '}')
+}
+
+// handleRequestTooLarge 上游拒绝请求体过大时记录观测值并在错误体中追加已知上限，返回是否命中
+func (s *Server) handleRequestTooLarge(cfg *model.Config, reqCtx *proxyRequestContext, failure *proxyResult) bool {
+	if !util.IsRequestTooLargeError(failure.status, failure.body) {
+		return false
+	}
+	limit := s.requestSizes.observeRejected(cfg.ID, int64(len(reqCtx.body)), time.Now())
+	failure.body = appendMaxRequestBytes(failure.body, failure.header, limit)
+	return true
+}
+
+// requestTooLargeBody 本地拒绝时返回的错误体（Anthropic 格式，OpenAI 客户端可识别 error.message）
+func requestTooLargeBody(size, limit int64) gin.H {
+	msg := fmt.Sprintf("request body of %d bytes exceeds the size accepted by all upstream channels", size)
+	resp := gin.H{
+		"type":  "error",
+		"error": gin.H{"type": "request_too_large", "message": msg},
+	}
+	if limit > 0 {
+		resp["max_request_bytes"] = limit
+	}
+	return resp
+}
+
+// HandleRequestSizeLimits 查看各渠道请求体大小观测值
+// GET /admin/request-size-limits
+func (s *Server) HandleRequestSizeLimits(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, s.requestSizes.list(time.Now()))
+}
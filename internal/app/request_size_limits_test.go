@@ -0,0 +1,131 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestSizeTracker(t *testing.T) {
+	tr := newRequestSizeTracker()
+	now := time.Now()
+	a, b := &model.Config{ID: 1}, &model.Config{ID: 2}
+
+	tr.observeAccepted(1, 1000)
+	if limit := tr.observeRejected(1, 5000, now); limit != 1000 {
+		t.Fatalf("已知上限应为最大成功值, got %d", limit)
+	}
+	if !tr.wouldReject(1, 5000, now) || tr.wouldReject(1, 4999, now) || tr.wouldReject(2, 9999, now) {
+		t.Fatal("wouldReject 判定不符")
+	}
+	if kept, _ := tr.filter(6000, []*model.Config{a, b}, now); len(kept) != 1 || kept[0].ID != 2 {
+		t.Fatalf("应只保留未拒绝的渠道: %v", kept)
+	}
+	if kept, limit := tr.filter(6000, []*model.Config{a}, now); kept != nil || limit != 1000 {
+		t.Fatalf("全部拒绝时应返回nil与已知上限, got %v %d", kept, limit)
+	}
+	if tr.wouldReject(1, 5000, now.Add(requestSizeRejectTTL)) {
+		t.Fatal("拒绝记录应在TTL后失效")
+	}
+
+	// 上游调高限制：更大的请求成功后清除拒绝记录
+	tr.observeAccepted(1, 8000)
+	if tr.wouldReject(1, 5000, now) {
+		t.Fatal("成功请求大于拒绝值后应清除拒绝记录")
+	}
+	if items := tr.list(now); len(items) != 1 || items[0].MaxAccepted != 8000 || items[0].MinRejected != 0 {
+		t.Fatalf("list = %+v", items)
+	}
+}
+
+func TestHandleProxyRequest_RequestTooLarge(t *testing.T) {
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+	srv.responseBufferBytes = 0
+
+	const limit = 2048
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if len(body) > limit {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_, _ = io.WriteString(w, `{"type":"error","error":{"type":"request_too_large","message":"Request exceeds the maximum allowed number of bytes."}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":3,"output_tokens":1}}`)
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "sized", URL: upstream.URL, ChannelType: "anthropic", Priority: 1, Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-x"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+
+	proxy := func(textLen int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := `{"model":"claude-x","max_tokens":8,"messages":[{"role":"user","content":"` + strings.Repeat("a", textLen) + `"}]}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		srv.HandleProxyRequest(c)
+		return w
+	}
+	maxBytes := func(w *httptest.ResponseRecorder) int64 {
+		var resp struct {
+			MaxRequestBytes int64 `json:"max_request_bytes"`
+		}
+		_ = sonic.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.MaxRequestBytes
+	}
+
+	if w := proxy(100); w.Code != http.StatusOK {
+		t.Fatalf("小请求应成功: %d %s", w.Code, w.Body.String())
+	}
+	w := proxy(4000)
+	if w.Code != http.StatusRequestEntityTooLarge || maxBytes(w) <= 0 || maxBytes(w) > limit {
+		t.Fatalf("上游413应透传并附带已知上限: %d %s", w.Code, w.Body.String())
+	}
+	if got, _ := store.GetConfig(ctx, cfg.ID); got.CooldownUntil > 0 {
+		t.Fatal("请求体过大不应冷却渠道")
+	}
+
+	before := hits.Load()
+	w = proxy(5000)
+	if w.Code != http.StatusRequestEntityTooLarge || hits.Load() != before {
+		t.Fatalf("已知过大的请求应本地拒绝: code=%d hits=%d→%d", w.Code, before, hits.Load())
+	}
+	if !strings.Contains(w.Body.String(), "request_too_large") || maxBytes(w) <= 0 {
+		t.Fatalf("本地拒绝响应体不符: %s", w.Body.String())
+	}
+	if w := proxy(100); w.Code != http.StatusOK {
+		t.Fatalf("拒绝记录不应影响小请求: %d", w.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// Anthropic /v1/messages 请求预校验（2026-10新增）
+// ============================================================================
+// 设计目标：明显畸形的请求在本地直接返回400，避免浪费上游尝试次数，
+// 更避免上游返回 invalid_request_error 触发Key级冷却（见 util.classifier）。
+// 由系统设置 request_validation_enabled 控制，默认关闭。
+
+// maxAnthropicMaxTokens max_tokens 上限（Claude 当前最大输出为128K）
+const maxAnthropicMaxTokens = 128000
+
+// anthropicContentBlockTypes 已知的内容块类型白名单
+var anthropicContentBlockTypes = map[string]struct{}{
+	"text":                   {},
+	"image":                  {},
+	"document":               {},
+	"search_result":          {},
+	"tool_use":               {},
+	"tool_result":            {},
+	"thinking":               {},
+	"redacted_thinking":      {},
+	"server_tool_use":        {},
+	"web_search_tool_result": {},
+	"web_fetch_tool_result":  {},
+	"container_upload":       {},
+	"mcp_tool_use":           {},
+	"mcp_tool_result":        {},
+}
+
+// shouldValidateRequest 判断是否对当前请求执行预校验
+func (s *Server) shouldValidateRequest(method, path string) bool {
+	if method != http.MethodPost || path != "/v1/messages" {
+		return false
+	}
+	if s.configService == nil {
+		return false
+	}
+	return s.configService.GetBool("request_validation_enabled", false)
+}
+
+// validateAnthropicMessagesRequest 校验 /v1/messages 请求体
+// 检查项：model必填、max_tokens范围、messages非空、角色交替、内容块类型
+func validateAnthropicMessagesRequest(body []byte) error {
+	var req struct {
+		Model     string `json:"model"`
+		MaxTokens *int64 `json:"max_tokens"`
+		Messages  []struct {
+			Role    string `json:"role"`
+			Content any    `json:"content"`
+		} `json:"messages"`
+	}
+	if err := sonic.Unmarshal(body, &req); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+
+	if req.Model == "" {
+		return fmt.Errorf("model: field required")
+	}
+	if req.MaxTokens == nil {
+		return fmt.Errorf("max_tokens: field required")
+	}
+	if *req.MaxTokens < 1 || *req.MaxTokens > maxAnthropicMaxTokens {
+		return fmt.Errorf("max_tokens: must be between 1 and %d, got %d", maxAnthropicMaxTokens, *req.MaxTokens)
+	}
+	if len(req.Messages) == 0 {
+		return fmt.Errorf("messages: at least one message is required")
+	}
+
+	prevRole := ""
+	for i, msg := range req.Messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			return fmt.Errorf("messages.%d.role: must be 'user' or 'assistant', got %q", i, msg.Role)
+		}
+		if i == 0 && msg.Role != "user" {
+			return fmt.Errorf("messages.0.role: first message must use the 'user' role")
+		}
+		if msg.Role == prevRole {
+			return fmt.Errorf("messages.%d.role: roles must alternate between 'user' and 'assistant'", i)
+		}
+		prevRole = msg.Role
+
+		if err := validateAnthropicContent(msg.Content); err != nil {
+			return fmt.Errorf("messages.%d.content%w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateAnthropicContent 校验消息内容（string 或 内容块数组）
+// 返回的错误以路径后缀开头（如 ".0.type: ..."），便于调用方拼接字段路径
+func validateAnthropicContent(content any) error {
+	switch v := content.(type) {
+	case string:
+		return nil
+	case []any:
+		for j, raw := range v {
+			block, ok := raw.(map[string]any)
+			if !ok {
+				return fmt.Errorf(".%d: content block must be an object", j)
+			}
+			blockType, _ := block["type"].(string)
+			if blockType == "" {
+				return fmt.Errorf(".%d.type: field required", j)
+			}
+			if _, known := anthropicContentBlockTypes[blockType]; !known {
+				return fmt.Errorf(".%d.type: unsupported content block type %q", j, blockType)
+			}
+		}
+		return nil
+	case nil:
+		return fmt.Errorf(": field required")
+	default:
+		return fmt.Errorf(": must be a string or an array of content blocks")
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAnthropicMessagesRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string // 空表示期望通过
+	}{
+		{
+			name: "合法请求-字符串内容",
+			body: `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name: "合法请求-内容块与多轮",
+			body: `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[
+				{"role":"user","content":[{"type":"text","text":"hi"}]},
+				{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"f","input":{}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}]}`,
+		},
+		{
+			name:    "非法JSON",
+			body:    `{"model":`,
+			wantErr: "invalid request body",
+		},
+		{
+			name:    "缺少model",
+			body:    `{"max_tokens":1024,"messages":[{"role":"user","content":"hi"}]}`,
+			wantErr: "model: field required",
+		},
+		{
+			name:    "缺少max_tokens",
+			body:    `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}]}`,
+			wantErr: "max_tokens: field required",
+		},
+		{
+			name:    "max_tokens越界",
+			body:    `{"model":"claude-sonnet-4-5","max_tokens":0,"messages":[{"role":"user","content":"hi"}]}`,
+			wantErr: "max_tokens: must be between",
+		},
+		{
+			name:    "messages为空",
+			body:    `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[]}`,
+			wantErr: "messages: at least one message",
+		},
+		{
+			name:    "非法角色",
+			body:    `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[{"role":"system","content":"hi"}]}`,
+			wantErr: "messages.0.role",
+		},
+		{
+			name:    "首条消息非user",
+			body:    `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[{"role":"assistant","content":"hi"}]}`,
+			wantErr: "first message",
+		},
+		{
+			name:    "角色未交替",
+			body:    `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[{"role":"user","content":"a"},{"role":"user","content":"b"}]}`,
+			wantErr: "messages.1.role: roles must alternate",
+		},
+		{
+			name:    "未知内容块类型",
+			body:    `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[{"role":"user","content":[{"type":"video"}]}]}`,
+			wantErr: `messages.0.content.0.type: unsupported content block type "video"`,
+		},
+		{
+			name:    "缺少content",
+			body:    `{"model":"claude-sonnet-4-5","max_tokens":1024,"messages":[{"role":"user"}]}`,
+			wantErr: "messages.0.content: field required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnthropicMessagesRequest([]byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("期望通过，实际错误: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("期望错误包含 %q，实际通过", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("期望错误包含 %q，实际: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package app
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 非流式响应缓存（2026-10新增）
+// ============================================================================
+// response_cache_ttl_seconds > 0 时，对完全相同的非流式请求（方法+路径+查询串+请求体的 SHA-256，请求体含 model）
+// 在选路之前直接返回缓存的响应，用于测试套件、确定性请求等重复提示词场景节省费用：
+//   - 缓存键同时包含令牌的路由范围（允许/屏蔽模型、回退模型）与影响输出的协议头（responseCacheVaryHeaders），
+//     范围不同的令牌、协议版本/beta 标记不同的请求互不共享条目
+//   - 只缓存返回给客户端的 200 JSON 响应（协议转换/JSON修复之后的内容），单条上限 responseCacheMaxEntryBytes
+//   - 内存 LRU：条目数上限 response_cache_max_entries，总大小上限 responseCacheMaxBytes，超出淘汰最久未使用
+//   - response_cache_persist 开启时同时写入 response_cache 表，重启后预热未过期条目
+//   - 客户端请求头 Cache-Control: no-cache / no-store 跳过缓存；响应头 X-CCLoad-Cache: HIT/MISS
+//   - 缓存命中记录 channel_id=0、费用为0的日志；模型屏蔽/令牌模型限制/费用限额检查仍在命中前执行
+// GET /admin/response-cache 查看命中率等计数，DELETE /admin/response-cache?model= 失效缓存（不传 model 清空全部）。
+
+const (
+	headerCCLoadCache = "X-CCLoad-Cache"
+
+	responseCacheMaxEntryBytes = 1 << 20
+	responseCacheMaxBytes      = 64 << 20
+
+	maxResponseCacheTTLSeconds     = 7 * 24 * 3600
+	defaultResponseCacheMaxEntries = 1000
+	maxResponseCacheMaxEntries     = 100000
+)
+
+// responseCache 响应缓存（nil 表示未启用，所有方法为空操作）
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+	persist    bool
+
+	mu    sync.Mutex
+	ll    *list.List // 队首为最近使用
+	items map[string]*list.Element
+	bytes int64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	stores    atomic.Int64
+	evictions atomic.Int64
+}
+
+func newResponseCache(ttl time.Duration, maxEntries int, persist bool) *responseCache {
+	return &responseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		persist:    persist,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// get 返回未过期的条目并计入命中/未命中
+func (rc *responseCache) get(key string, now time.Time) (*model.ResponseCacheEntry, bool) {
+	if rc == nil {
+		return nil, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	el, ok := rc.items[key]
+	if ok {
+		e := el.Value.(*model.ResponseCacheEntry)
+		if e.ExpiresAt > now.Unix() {
+			rc.ll.MoveToFront(el)
+			rc.hits.Add(1)
+			return e, true
+		}
+		rc.removeLocked(el)
+	}
+	rc.misses.Add(1)
+	return nil, false
+}
+
+// put 新增或替换条目，超出条目数/总大小上限时淘汰最久未使用的条目
+func (rc *responseCache) put(e *model.ResponseCacheEntry) {
+	if rc == nil || len(e.Body) > responseCacheMaxEntryBytes {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if el, ok := rc.items[e.Key]; ok {
+		rc.removeLocked(el)
+	}
+	rc.items[e.Key] = rc.ll.PushFront(e)
+	rc.bytes += int64(len(e.Body))
+	for rc.ll.Len() > rc.maxEntries || rc.bytes > responseCacheMaxBytes {
+		rc.removeLocked(rc.ll.Back())
+		rc.evictions.Add(1)
+	}
+}
+
+func (rc *responseCache) removeLocked(el *list.Element) {
+	e := rc.ll.Remove(el).(*model.ResponseCacheEntry)
+	delete(rc.items, e.Key)
+	rc.bytes -= int64(len(e.Body))
+}
+
+// invalidate 删除指定模型的条目（modelName 为空时清空），返回删除条数
+func (rc *responseCache) invalidate(modelName string) int {
+	if rc == nil {
+		return 0
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	n := 0
+	for el := rc.ll.Front(); el != nil; {
+		next := el.Next()
+		if modelName == "" || el.Value.(*model.ResponseCacheEntry).Model == modelName {
+			rc.removeLocked(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+// prune 清理过期条目
+func (rc *responseCache) prune(now time.Time) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for el := rc.ll.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*model.ResponseCacheEntry).ExpiresAt <= now.Unix() {
+			rc.removeLocked(el)
+		}
+		el = next
+	}
+}
+
+// stats 命中率等计数（仅内存，重启清零）
+func (rc *responseCache) stats() gin.H {
+	if rc == nil {
+		return gin.H{"enabled": false}
+	}
+	rc.mu.Lock()
+	entries, size := rc.ll.Len(), rc.bytes
+	rc.mu.Unlock()
+	hits, misses := rc.hits.Load(), rc.misses.Load()
+	hitRate := 0.0
+	if hits+misses > 0 {
+		hitRate = float64(hits) / float64(hits+misses)
+	}
+	return gin.H{
+		"enabled":     true,
+		"ttl_seconds": int(rc.ttl / time.Second),
+		"max_entries": rc.maxEntries,
+		"persist":     rc.persist,
+		"entries":     entries,
+		"bytes":       size,
+		"hits":        hits,
+		"misses":      misses,
+		"stores":      rc.stores.Load(),
+		"evictions":   rc.evictions.Load(),
+		"hit_rate":    hitRate,
+	}
+}
+
+// responseCacheVaryHeaders 影响上游输出的协议头，参与缓存键计算
+var responseCacheVaryHeaders = []string{"Anthropic-Version", "Anthropic-Beta", "Openai-Beta"}
+
+// responseCacheKey 计算请求的缓存键（scope 为令牌路由范围，见 responseCacheScope）
+func responseCacheKey(method, path, rawQuery, scope string, header http.Header, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + "\n" + path + "\n" + rawQuery + "\n" + scope + "\n"))
+	for _, name := range responseCacheVaryHeaders {
+		h.Write([]byte(name + ":" + strings.Join(header.Values(name), ",") + "\n"))
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCacheScope 令牌的路由范围（允许/屏蔽模型、回退模型）；无令牌或令牌无限制时为空
+// 范围相同的令牌可以共享缓存条目，范围不同时命中结果可能来自该令牌无权使用的渠道/模型
+func (s *Server) responseCacheScope(tokenHash string) string {
+	if tokenHash == "" || s.authService == nil {
+		return ""
+	}
+	normalize := func(models []string) string {
+		out := make([]string, len(models))
+		for i, m := range models {
+			out[i] = strings.ToLower(strings.TrimSpace(m))
+		}
+		slices.Sort(out)
+		return strings.Join(slices.Compact(out), ",")
+	}
+	allowed := normalize(s.authService.AllowedModels(tokenHash))
+	blocked := normalize(s.authService.BlockedModels(tokenHash))
+	fallback := strings.ToLower(s.authService.FallbackModel(tokenHash))
+	if allowed == "" && blocked == "" && fallback == "" {
+		return ""
+	}
+	return "allowed=" + allowed + ";blocked=" + blocked + ";fallback=" + fallback
+}
+
+// responseCacheEligible 请求是否参与响应缓存：仅非流式 POST，且客户端未要求跳过缓存
+func (s *Server) responseCacheEligible(req *http.Request, isStreaming bool) bool {
+	if s.responseCache == nil || isStreaming || req.Method != http.MethodPost {
+		return false
+	}
+	cc := strings.ToLower(req.Header.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-cache") && !strings.Contains(cc, "no-store")
+}
+
+// serveCachedResponse 返回缓存的响应并记录日志
+func (s *Server) serveCachedResponse(c *gin.Context, e *model.ResponseCacheEntry, originalModel string, startTime time.Time) {
+	c.Header(headerCCLoadCache, "HIT")
+	c.Header("Age", strconv.FormatInt(max(0, startTime.Unix()-e.CreatedAt), 10))
+	c.Data(http.StatusOK, e.ContentType, e.Body)
+
+	tokenID, _ := c.Get("token_id")
+	tokenIDInt64, _ := tokenID.(int64)
+	s.AddLogAsync(&model.LogEntry{
+		Time:        model.JSONTime{Time: startTime},
+		Model:       originalModel,
+		StatusCode:  http.StatusOK,
+		Message:     "response cache hit",
+		Duration:    time.Since(startTime).Seconds(),
+		AuthTokenID: tokenIDInt64,
+		ClientIP:    c.ClientIP(),
+	})
+}
+
+// storeCachedResponse 缓存成功写出的响应（非200/非JSON/已压缩/超出单条上限的响应不缓存）
+func (s *Server) storeCachedResponse(key, modelName string, rec *responseCacheRecorder) {
+	rc := s.responseCache
+	if rc == nil || rec.status != http.StatusOK || rec.overflow || rec.buf.Len() == 0 {
+		return
+	}
+	hdr := rec.Header()
+	contentType := hdr.Get("Content-Type")
+	if hdr.Get("Content-Encoding") != "" || !strings.Contains(strings.ToLower(contentType), "json") {
+		return
+	}
+	now := time.Now()
+	e := &model.ResponseCacheEntry{
+		Key:         key,
+		Model:       modelName,
+		ContentType: contentType,
+		Body:        bytes.Clone(rec.buf.Bytes()),
+		CreatedAt:   now.Unix(),
+		ExpiresAt:   now.Add(rc.ttl).Unix(),
+	}
+	rc.put(e)
+	rc.stores.Add(1)
+	if rc.persist {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := s.store.PutResponseCacheEntry(ctx, e); err != nil {
+			log.Printf("[WARN] 响应缓存持久化失败: %v", err)
+		}
+	}
+}
+
+// loadPersistedResponseCache 启动时清理过期记录并预热未过期条目
+func (s *Server) loadPersistedResponseCache() {
+	rc := s.responseCache
+	if rc == nil || !rc.persist {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now().Unix()
+	if _, err := s.store.PurgeExpiredResponseCache(ctx, now); err != nil {
+		log.Printf("[WARN] 清理过期响应缓存失败: %v", err)
+	}
+	entries, err := s.store.ListResponseCacheEntries(ctx, now, rc.maxEntries)
+	if err != nil {
+		log.Printf("[WARN] 加载响应缓存失败: %v", err)
+		return
+	}
+	// 按创建时间从旧到新放入，最新的条目位于LRU队首
+	for i := len(entries) - 1; i >= 0; i-- {
+		rc.put(entries[i])
+	}
+	log.Printf("[INFO] 已预热 %d 条响应缓存", len(entries))
+}
+
+// pruneResponseCache 清理过期条目（后台清理循环调用）
+func (s *Server) pruneResponseCache(now time.Time) {
+	rc := s.responseCache
+	if rc == nil {
+		return
+	}
+	rc.prune(now)
+	if rc.persist {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := s.store.PurgeExpiredResponseCache(ctx, now.Unix()); err != nil {
+			log.Printf("[WARN] 清理过期响应缓存失败: %v", err)
+		}
+	}
+}
+
+// responseCacheRecorder 转发的同时记录写给客户端的状态码与响应体（超出单条上限后停止记录）
+type responseCacheRecorder struct {
+	http.ResponseWriter
+	status   int
+	buf      bytes.Buffer
+	overflow bool
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (r *responseCacheRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// Flush 透传 Flush
+func (r *responseCacheRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseCacheRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseCacheRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if !r.overflow {
+		if r.buf.Len()+len(p) > responseCacheMaxEntryBytes {
+			r.overflow = true
+			r.buf.Reset()
+		} else {
+			r.buf.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// HandleResponseCacheStats 响应缓存命中率等计数
+// GET /admin/response-cache
+func (s *Server) HandleResponseCacheStats(c *gin.Context) {
+	RespondJSON(c, http.StatusOK, s.responseCache.stats())
+}
+
+// HandleInvalidateResponseCache 失效响应缓存（内存与持久化记录）
+// DELETE /admin/response-cache?model=claude-sonnet-4-5（不传 model 清空全部）
+func (s *Server) HandleInvalidateResponseCache(c *gin.Context) {
+	rc := s.responseCache
+	if rc == nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "response cache not enabled")
+		return
+	}
+	modelName := strings.TrimSpace(c.Query("model"))
+	cleared := rc.invalidate(modelName)
+	var persisted int64
+	if rc.persist {
+		n, err := s.store.DeleteResponseCacheEntries(c.Request.Context(), modelName)
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		persisted = n
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"cleared": cleared, "persisted_cleared": persisted})
+}
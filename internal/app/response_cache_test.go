@@ -0,0 +1,204 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleProxyRequest_ResponseCache(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"4"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "c1", URL: upstream.URL, ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+	srv.responseCache = newResponseCache(time.Minute, 10, true)
+
+	const body = `{"model":"claude-sonnet-4-5","max_tokens":8,"messages":[{"role":"user","content":"2+2"}]}`
+	proxy := func(body, cacheControl string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if cacheControl != "" {
+			c.Request.Header.Set("Cache-Control", cacheControl)
+		}
+		srv.HandleProxyRequest(c)
+		return w
+	}
+
+	first := proxy(body, "")
+	if first.Code != http.StatusOK || first.Header().Get(headerCCLoadCache) != "MISS" {
+		t.Fatalf("首次请求应未命中: %d %q", first.Code, first.Header().Get(headerCCLoadCache))
+	}
+	second := proxy(body, "")
+	if second.Code != http.StatusOK || second.Header().Get(headerCCLoadCache) != "HIT" || second.Body.String() != first.Body.String() {
+		t.Fatalf("相同请求应命中缓存: %d %q %s", second.Code, second.Header().Get(headerCCLoadCache), second.Body.String())
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("命中缓存不应请求上游，实际上游调用 %d 次", calls.Load())
+	}
+
+	// no-cache 跳过缓存；流式请求不参与缓存
+	if w := proxy(body, "no-cache"); w.Header().Get(headerCCLoadCache) != "" || calls.Load() != 2 {
+		t.Fatalf("no-cache 应跳过缓存: %q calls=%d", w.Header().Get(headerCCLoadCache), calls.Load())
+	}
+	streamBody := `{"model":"claude-sonnet-4-5","max_tokens":8,"stream":true,"messages":[{"role":"user","content":"2+2"}]}`
+	if w := proxy(streamBody, ""); w.Header().Get(headerCCLoadCache) != "" {
+		t.Fatalf("流式请求不应参与缓存: %q", w.Header().Get(headerCCLoadCache))
+	}
+
+	if st := srv.responseCache.stats(); st["hits"] != int64(1) || st["misses"] != int64(1) || st["stores"] != int64(1) || st["entries"] != 1 {
+		t.Fatalf("缓存计数不符: %v", st)
+	}
+
+	// 持久化条目在重启后预热
+	restarted := &Server{store: store, responseCache: newResponseCache(time.Minute, 10, true)}
+	restarted.loadPersistedResponseCache()
+	if e, ok := restarted.responseCache.get(responseCacheKey(http.MethodPost, "/v1/messages", "", "", http.Header{}, []byte(body)), time.Now()); !ok ||
+		e.Model != "claude-sonnet-4-5" || string(e.Body) != first.Body.String() {
+		t.Fatalf("持久化条目应在重启后预热: %+v ok=%v", e, ok)
+	}
+
+	// 按模型失效：内存与持久化记录一并清除
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/admin/response-cache?model=claude-sonnet-4-5", nil)
+	srv.HandleInvalidateResponseCache(c)
+	if w.Code != http.StatusOK || srv.responseCache.stats()["entries"] != 0 {
+		t.Fatalf("失效失败: %d %s", w.Code, w.Body.String())
+	}
+	if entries, err := store.ListResponseCacheEntries(ctx, time.Now().Unix(), 10); err != nil || len(entries) != 0 {
+		t.Fatalf("持久化记录应被清除: %v err=%v", entries, err)
+	}
+}
+
+func TestResponseCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	rc := newResponseCache(time.Minute, 2, false)
+	now := time.Now()
+	entry := func(key string) *model.ResponseCacheEntry {
+		return &model.ResponseCacheEntry{Key: key, Body: []byte("{}"), CreatedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+	}
+	rc.put(entry("a"))
+	rc.put(entry("b"))
+	if _, ok := rc.get("a", now); !ok {
+		t.Fatal("a 应命中")
+	}
+	rc.put(entry("c"))
+	if _, ok := rc.get("b", now); ok {
+		t.Fatal("最久未使用的 b 应被淘汰")
+	}
+	if _, ok := rc.get("a", now.Add(2*time.Minute)); ok {
+		t.Fatal("过期条目不应命中")
+	}
+	if st := rc.stats(); st["evictions"] != int64(1) || st["entries"] != 1 {
+		t.Fatalf("计数不符: %v", st)
+	}
+}
+
+// TestHandleProxyRequest_ResponseCacheScopedByTokenAndHeaders 路由范围不同的令牌、协议头不同的请求不共享缓存条目
+func TestHandleProxyRequest_ResponseCacheScopedByTokenAndHeaders(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"4"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "c1", URL: upstream.URL, ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+	srv.responseCache = newResponseCache(time.Minute, 10, false)
+
+	// narrow / narrow-twin 范围相同；wide 额外允许其他模型
+	srv.authService.authTokensMux.Lock()
+	srv.authService.authTokenModels = map[string][]string{
+		"narrow":      {"claude-sonnet-4-5"},
+		"narrow-twin": {"Claude-Sonnet-4-5"},
+		"wide":        {"claude-sonnet-4-5", "claude-opus-4-1"},
+	}
+	srv.authService.authTokensMux.Unlock()
+
+	const body = `{"model":"claude-sonnet-4-5","max_tokens":8,"messages":[{"role":"user","content":"2+2"}]}`
+	proxy := func(tokenHash, beta string) string {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		if beta != "" {
+			c.Request.Header.Set("anthropic-beta", beta)
+		}
+		c.Set("token_hash", tokenHash)
+		srv.HandleProxyRequest(c)
+		if w.Code != http.StatusOK {
+			t.Fatalf("token %s: %d %s", tokenHash, w.Code, w.Body.String())
+		}
+		return w.Header().Get(headerCCLoadCache)
+	}
+
+	for i, step := range []struct {
+		token, beta, want string
+	}{
+		{"narrow", "", "MISS"},
+		{"wide", "", "MISS"},         // 范围不同：不共享
+		{"narrow-twin", "", "HIT"},   // 范围相同：共享
+		{"narrow", "beta-x", "MISS"}, // 协议头不同：不共享
+		{"narrow", "beta-x", "HIT"},
+	} {
+		if got := proxy(step.token, step.beta); got != step.want {
+			t.Fatalf("step %d (%s %q): 期望 %s，实际 %s", i, step.token, step.beta, step.want, got)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("上游应被调用3次，实际 %d", calls.Load())
+	}
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 响应内容策略标签（2026-10新增）
+// ============================================================================
+// 系统设置 response_label_rules 配置后（修改后立即生效），对成功响应的模型输出文本逐条匹配规则，
+// 命中的标签以逗号分隔写入 logs.labels，用于按渠道监控拒答率等（如低价转售渠道偷换模型后拒答率异常升高）。
+// 规则每行一条：<标签>=<正则>，# 开头为注释；标签仅允许小写字母/数字/-/_，同一标签可写多行（任一命中即打标）。
+// 关键字匹配直接写字面量即可，不区分大小写用 (?i) 前缀，例如：
+//   refusal-detected=(?i)(I can't help with|I cannot assist|我无法协助)
+//   contains-code=```
+// 只检查响应前 maxResponseLabelBytes 字节（流式响应按渠道类型拼接文本增量）；未配置规则时不缓存响应、零开销。
+// 日志查询支持 label=<标签> 过滤；GET /admin/stats/labels?range= 按渠道输出各标签命中次数与占比。
+
+const (
+	maxResponseLabelBytes  = 256 << 10
+	maxResponseLabelLen    = 32
+	maxResponseLabelsField = 255 // logs.labels 列宽
+)
+
+var responseLabelNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// responseLabelRule 单条打标规则
+type responseLabelRule struct {
+	label   string
+	pattern *regexp.Regexp
+}
+
+// parseResponseLabelRules 解析 response_label_rules 配置（空配置返回 nil）
+func parseResponseLabelRules(value string) ([]responseLabelRule, error) {
+	var rules []responseLabelRule
+	for i, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		label, expr, ok := strings.Cut(line, "=")
+		label = strings.TrimSpace(label)
+		if !ok || strings.TrimSpace(expr) == "" {
+			return nil, fmt.Errorf("line %d: expected <label>=<regex>", i+1)
+		}
+		if len(label) > maxResponseLabelLen || !responseLabelNamePattern.MatchString(label) {
+			return nil, fmt.Errorf("line %d: label must be 1-%d chars of [a-z0-9_-]", i+1, maxResponseLabelLen)
+		}
+		re, err := regexp.Compile(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rules = append(rules, responseLabelRule{label: label, pattern: re})
+	}
+	return rules, nil
+}
+
+// setResponseLabelRules 更新打标规则（启动加载与设置热更新共用），无效配置视为关闭
+func (s *Server) setResponseLabelRules(value string) {
+	rules, err := parseResponseLabelRules(value)
+	if err != nil {
+		log.Printf("[WARN] 无效的 response_label_rules 配置，已关闭响应打标: %v", err)
+		rules = nil
+	}
+	s.responseLabelRules.Store(&rules)
+}
+
+// loadResponseLabelRules 返回当前规则（未配置返回 nil）
+func (s *Server) loadResponseLabelRules() []responseLabelRule {
+	if p := s.responseLabelRules.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// classifyResponseText 按规则顺序返回命中的标签（去重，逗号分隔，超出列宽的标签丢弃）
+func classifyResponseText(rules []responseLabelRule, text string) string {
+	if text == "" {
+		return ""
+	}
+	var labels []string
+	size := 0
+	for _, r := range rules {
+		if slices.Contains(labels, r.label) || !r.pattern.MatchString(text) {
+			continue
+		}
+		if size+len(r.label) > maxResponseLabelsField {
+			continue
+		}
+		labels = append(labels, r.label)
+		size += len(r.label) + 1 // 含分隔逗号
+	}
+	return strings.Join(labels, ",")
+}
+
+// responseLabelTap 转发的同时缓存响应前缀（超出上限后停止缓存，不影响转发）
+type responseLabelTap struct {
+	io.ReadCloser
+	buf bytes.Buffer
+}
+
+func (t *responseLabelTap) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 && t.buf.Len() < maxResponseLabelBytes {
+		t.buf.Write(p[:min(n, maxResponseLabelBytes-t.buf.Len())])
+	}
+	return n, err
+}
+
+// responseText 提取响应中的模型输出文本：SSE按渠道类型拼接增量，JSON取各协议的文本字段
+func responseText(channelType string, body []byte) string {
+	if looksLikeSSE(body) {
+		return streamedText(channelType, body)
+	}
+	var sb strings.Builder
+	_, _, _ = rewriteResponseTexts(body, func(text string) (string, error) {
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(text)
+		return text, nil
+	})
+	return sb.String()
+}
+
+// HandleResponseLabelStats 按渠道汇总成功请求的响应标签命中次数与占比（2026-10新增）
+// GET /admin/stats/labels?range=today&channel_id=1
+// 占比分母为区间内该渠道全部成功请求（含规则配置前的请求），请选择规则生效后的时间范围
+func (s *Server) HandleResponseLabelStats(c *gin.Context) {
+	params := ParsePaginationParams(c)
+	lf := BuildLogFilter(c)
+	startTime, endTime := params.GetTimeRange()
+	stats, err := s.store.GetResponseLabelStats(c.Request.Context(), startTime, endTime, &lf)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	labels := []string{}
+	for _, r := range s.loadResponseLabelRules() {
+		if !slices.Contains(labels, r.label) {
+			labels = append(labels, r.label)
+		}
+	}
+	RespondJSON(c, http.StatusOK, gin.H{
+		"stats":  stats,
+		"labels": labels,
+	})
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseResponseLabelRules(t *testing.T) {
+	rules, err := parseResponseLabelRules("# 注释\nrefusal-detected=(?i)i can't help\n\ncontains-code=```\nrefusal-detected=我无法")
+	if err != nil || len(rules) != 3 {
+		t.Fatalf("解析失败: %v %d", err, len(rules))
+	}
+	for _, bad := range []string{"no-separator", "Bad=x", "x=", "x=(unclosed"} {
+		if _, err := parseResponseLabelRules(bad); err == nil {
+			t.Fatalf("应拒绝无效规则: %q", bad)
+		}
+	}
+
+	if got := classifyResponseText(rules, "抱歉，我无法协助。```go\n```"); got != "contains-code,refusal-detected" {
+		t.Fatalf("标签不符: %q", got)
+	}
+	if got := classifyResponseText(rules, ""); got != "" {
+		t.Fatalf("空文本不应打标: %q", got)
+	}
+
+	sse := "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"I can't \"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"help with that\"}}\n\n"
+	if got := responseText("anthropic", []byte(sse)); got != "I can't help with that" {
+		t.Fatalf("SSE文本提取不符: %q", got)
+	}
+	if got := responseText("openai", []byte(`{"choices":[{"message":{"content":"hi"}}]}`)); got != "hi" {
+		t.Fatalf("JSON文本提取不符: %q", got)
+	}
+}
+
+func TestHandleProxyRequest_ResponseLabels(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Sorry, I can't help with that."}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "labels.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	cfg, err := store.CreateConfig(ctx, &model.Config{
+		Name: "reseller", URL: upstream.URL, ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "k1", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(ctx) }()
+	srv.setResponseLabelRules("refusal-detected=(?i)i can't help\ncontains-code=```")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+		bytes.NewBufferString(`{"model":"claude-sonnet-4-5","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	srv.HandleProxyRequest(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("请求应成功，实际 %d %s", w.Code, w.Body.String())
+	}
+
+	// 日志异步落库，轮询等待
+	since := time.Now().Add(-time.Minute)
+	var logs []*model.LogEntry
+	for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		logs, err = store.ListLogs(ctx, since, 10, 0, &model.LogFilter{Label: "refusal-detected"})
+		if err == nil && len(logs) > 0 {
+			break
+		}
+	}
+	if len(logs) != 1 || logs[0].Labels != "refusal-detected" {
+		t.Fatalf("成功日志应带拒答标签: %+v err=%v", logs, err)
+	}
+	if other, _ := store.ListLogs(ctx, since, 10, 0, &model.LogFilter{Label: "contains-code"}); len(other) != 0 {
+		t.Fatalf("未命中的标签不应匹配: %+v", other)
+	}
+
+	stats, err := store.GetResponseLabelStats(ctx, since, time.Now().Add(time.Minute), nil)
+	if err != nil || len(stats) != 1 {
+		t.Fatalf("标签统计失败: %+v err=%v", stats, err)
+	}
+	if st := stats[0]; st.ChannelID != cfg.ID || st.Total != 1 || st.Labels["refusal-detected"] != 1 || st.Rates["refusal-detected"] != 1 {
+		t.Fatalf("标签统计不符: %+v", st)
+	}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 错误响应重试提示（2026-10新增）
+// ============================================================================
+// 返回给客户端的 429/5xx 错误体中追加扩展字段 retry_hints（上游错误体的其他字段原样保留）：
+//   {"type":"error","error":{...},"retry_hints":{"retry_after_seconds":12,"alternative_models":["..."]}}
+// - retry_after_seconds：根据冷却状态估算该模型最早可用时间；无冷却时取上游 Retry-After
+// - alternative_models：令牌当前可用、且有未冷却渠道的其他模型（同协议，按渠道优先级排序）
+// 同时在缺少 Retry-After 响应头时补充该头。由 error_retry_hints_enabled 开关控制。
+
+const (
+	retryHintsMaxAlternatives = 5
+	retryHintsMaxRetryAfter   = 3600 // 秒，超过则截断（长时间冷却的提示对客户端没有意义）
+)
+
+// retryHints 错误体扩展字段
+type retryHints struct {
+	RetryAfterSeconds int      `json:"retry_after_seconds"`
+	AlternativeModels []string `json:"alternative_models"`
+}
+
+// shouldAttachRetryHints 仅对限流与服务端错误附加提示（客户端错误重试无意义）
+func (s *Server) shouldAttachRetryHints(status int) bool {
+	return s.retryHintsEnabled && (status == http.StatusTooManyRequests || status >= 500)
+}
+
+// buildRetryHints 根据当前冷却状态与令牌权限生成重试提示
+func (s *Server) buildRetryHints(ctx context.Context, originalModel, requestPath, tokenHash string, upstreamHeader http.Header) *retryHints {
+	now := time.Now()
+	channelType := util.DetectChannelTypeFromPath(requestPath)
+	hints := &retryHints{AlternativeModels: []string{}}
+
+	channelCooldowns, err := s.getAllChannelCooldowns(ctx)
+	if err != nil {
+		channelCooldowns = map[int64]time.Time{}
+	}
+	keyCooldowns, err := s.getAllKeyCooldowns(ctx)
+	if err != nil {
+		keyCooldowns = map[int64]map[int]time.Time{}
+	}
+
+	// 1. 建议重试时间：该模型所有渠道中最早恢复的时间
+	var readyIn time.Duration = -1
+	for _, ch := range s.retryHintChannels(ctx, originalModel, channelType) {
+		d := channelReadyAt(ch, channelCooldowns, keyCooldowns, now).Sub(now)
+		if readyIn < 0 || d < readyIn {
+			readyIn = d
+		}
+	}
+	retryAfter := 0
+	if readyIn > 0 {
+		retryAfter = int((readyIn + time.Second - 1) / time.Second)
+	} else if v, ok := parseRetryAfterHeader(upstreamHeader, now); ok {
+		retryAfter = v
+	}
+	hints.RetryAfterSeconds = min(max(retryAfter, 1), retryHintsMaxRetryAfter)
+
+	// 2. 替代模型：同协议、未冷却、令牌允许使用且未被屏蔽的其他模型
+	if channelType == "" {
+		return hints
+	}
+	channels, err := s.GetEnabledChannelsByType(ctx, channelType)
+	if err != nil {
+		return hints
+	}
+	available := s.filterCooledChannels(s.filterCostLimitExceededChannels(channels), channelCooldowns, keyCooldowns, now)
+	slices.SortStableFunc(available, func(a, b *model.Config) int { return b.Priority - a.Priority })
+	seen := map[string]struct{}{originalModel: {}}
+	for _, ch := range available {
+		for _, entry := range ch.ModelEntries {
+			name := entry.Model
+			if _, dup := seen[name]; dup || model.IsModelPattern(name) {
+				continue
+			}
+			seen[name] = struct{}{}
+			if !s.isModelUsable(tokenHash, name) {
+				continue
+			}
+			hints.AlternativeModels = append(hints.AlternativeModels, name)
+			if len(hints.AlternativeModels) >= retryHintsMaxAlternatives {
+				return hints
+			}
+		}
+	}
+	return hints
+}
+
+// retryHintChannels 支持该模型的启用渠道（含冷却中的渠道）
+// 数据库查询会排除渠道级冷却的渠道，结果为空时与选路一样回退到全量查询
+func (s *Server) retryHintChannels(ctx context.Context, originalModel, channelType string) []*model.Config {
+	if originalModel == "" {
+		return nil
+	}
+	normalizedType := util.NormalizeChannelType(channelType)
+	matches := func(ch *model.Config) bool {
+		return ch != nil && (channelType == "" || util.ProtocolChannelType(ch.GetChannelType()) == normalizedType || isMockChannel(ch))
+	}
+	channels, err := s.GetEnabledChannelsByModel(ctx, originalModel)
+	if err == nil {
+		channels = slices.DeleteFunc(channels, func(ch *model.Config) bool { return !matches(ch) })
+	}
+	if len(channels) > 0 {
+		return channels
+	}
+	all, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		return nil
+	}
+	channels = channels[:0]
+	for _, ch := range all {
+		if matches(ch) && ch.Enabled && ch.SupportsModel(originalModel) {
+			channels = append(channels, ch)
+		}
+	}
+	return channels
+}
+
+// parseRetryAfterHeader 解析上游 Retry-After（秒数或 HTTP-date）
+func parseRetryAfterHeader(hdr http.Header, now time.Time) (int, bool) {
+	if hdr == nil {
+		return 0, false
+	}
+	v := strings.TrimSpace(hdr.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return secs, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(int(t.Sub(now).Seconds()+0.5), 0), true
+	}
+	return 0, false
+}
+
+// appendRetryHints 向JSON对象错误体追加 retry_hints 字段；非JSON对象（或已压缩）的错误体原样返回
+func appendRetryHints(body []byte, hdr http.Header, hints *retryHints) []byte {
+	if hints == nil || (hdr != nil && hdr.Get("Content-Encoding") != "") {
+		return body
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) < 2 || trimmed[0] != '{' || trimmed[len(trimmed)-1] != '}' {
+		return body
+	}
+	var probe map[string]sonic.NoCopyRawMessage
+	if err := sonic.Unmarshal(trimmed, &probe); err != nil {
+		return body
+	}
+	if _, exists := probe["retry_hints"]; exists {
+		return body
+	}
+	data, err := sonic.Marshal(hints)
+	if err != nil {
+		return body
+	}
+	out := make([]byte, 0, len(trimmed)+len(data)+16)
+	out = append(out, trimmed[:len(trimmed)-1]...)
+	if len(probe) > 0 {
+		out = append(out, ',')
+	}
+	out = append(out, `"retry_hints":`...)
+	out = append(out, data...)
+	return append(out, '}')
+}
+
+// setRetryAfterHeader 上游未提供 Retry-After 时按提示补充（须在写回上游响应头前调用）
+func setRetryAfterHeader(w http.ResponseWriter, upstreamHeader http.Header, hints *retryHints) {
+	if hints == nil || hints.RetryAfterSeconds <= 0 || (upstreamHeader != nil && upstreamHeader.Get("Retry-After") != "") {
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(hints.RetryAfterSeconds))
+}
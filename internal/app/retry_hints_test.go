@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+func TestAppendRetryHints(t *testing.T) {
+	hints := &retryHints{RetryAfterSeconds: 5, AlternativeModels: []string{"claude-haiku"}}
+	tests := []struct {
+		name string
+		body string
+		hdr  http.Header
+		want string
+	}{
+		{"anthropic error", `{"type":"error","error":{"type":"overloaded_error"}}`, nil,
+			`{"type":"error","error":{"type":"overloaded_error"},"retry_hints":{"retry_after_seconds":5,"alternative_models":["claude-haiku"]}}`},
+		{"empty object", ` {} `, nil, `{"retry_hints":{"retry_after_seconds":5,"alternative_models":["claude-haiku"]}}`},
+		{"plain text", `upstream exploded`, nil, `upstream exploded`},
+		{"array", `[1,2]`, nil, `[1,2]`},
+		{"invalid json", `{"a":}`, nil, `{"a":}`},
+		{"already present", `{"retry_hints":1}`, nil, `{"retry_hints":1}`},
+		{"encoded", `{"a":1}`, http.Header{"Content-Encoding": {"br"}}, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(appendRetryHints([]byte(tt.body), tt.hdr, hints)); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfterHeader(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if v, ok := parseRetryAfterHeader(http.Header{"Retry-After": {"30"}}, now); !ok || v != 30 {
+		t.Fatalf("seconds: got %d %v", v, ok)
+	}
+	date := now.Add(90 * time.Second).Format(http.TimeFormat)
+	if v, ok := parseRetryAfterHeader(http.Header{"Retry-After": {date}}, now); !ok || v != 90 {
+		t.Fatalf("http-date: got %d %v", v, ok)
+	}
+	if _, ok := parseRetryAfterHeader(http.Header{"Retry-After": {"soon"}}, now); ok {
+		t.Fatal("expected invalid value to be ignored")
+	}
+}
+
+func TestBuildRetryHints(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	create := func(name string, priority int, models ...string) *model.Config {
+		entries := make([]model.ModelEntry, 0, len(models))
+		for _, m := range models {
+			entries = append(entries, model.ModelEntry{Model: m})
+		}
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: name, URL: "https://api.example.com", Priority: priority, Enabled: true, ModelEntries: entries,
+		})
+		if err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{
+			ChannelID: cfg.ID, APIKey: "sk-" + name, KeyStrategy: model.KeyStrategySequential,
+		}}); err != nil {
+			t.Fatalf("create key: %v", err)
+		}
+		return cfg
+	}
+	busy := create("busy", 10, "claude-opus")
+	create("spare-low", 1, "claude-haiku", "claude-3-*")
+	create("spare-high", 5, "claude-sonnet", "claude-opus")
+	opusChannels, _ := store.GetEnabledChannelsByModel(ctx, "claude-opus")
+	if len(opusChannels) != 2 {
+		t.Fatalf("expected 2 claude-opus channels, got %d", len(opusChannels))
+	}
+	for _, ch := range opusChannels {
+		until := time.Now().Add(40 * time.Second)
+		if ch.ID == busy.ID {
+			until = time.Now().Add(20 * time.Second)
+		}
+		if err := store.SetChannelCooldown(ctx, ch.ID, until); err != nil {
+			t.Fatalf("set cooldown: %v", err)
+		}
+	}
+
+	hints := server.buildRetryHints(ctx, "claude-opus", "/v1/messages", "", nil)
+	if hints.RetryAfterSeconds < 19 || hints.RetryAfterSeconds > 20 {
+		t.Fatalf("retry_after_seconds should follow earliest cooldown, got %d", hints.RetryAfterSeconds)
+	}
+	// spare-high 冷却中（含 claude-opus 与 claude-sonnet），只剩 spare-low 的精确模型
+	if !slices.Equal(hints.AlternativeModels, []string{"claude-haiku"}) {
+		t.Fatalf("unexpected alternatives: %v", hints.AlternativeModels)
+	}
+
+	// 无冷却时使用上游 Retry-After
+	for _, ch := range opusChannels {
+		_ = store.ResetChannelCooldown(ctx, ch.ID)
+	}
+	hints = server.buildRetryHints(ctx, "claude-opus", "/v1/messages", "", http.Header{"Retry-After": {"7"}})
+	if hints.RetryAfterSeconds != 7 || !slices.Equal(hints.AlternativeModels, []string{"claude-sonnet", "claude-haiku"}) {
+		t.Fatalf("unexpected hints without cooldown: %+v", hints)
+	}
+}
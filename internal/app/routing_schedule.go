@@ -0,0 +1,323 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 分时路由（2026-10新增）
+// ============================================================================
+// 部分上游有夜间折扣或闲时容量更充足，分时路由规则在 cron 窗口内调整渠道的：
+// - 优先级（priority_delta，累加到渠道优先级，参与分组/健康度排序）
+// - 同优先级负载均衡权重（weight_multiplier，乘到有效Key数量上）
+// 规则存储在 routing_schedules 表，启动时与每次管理接口修改后重新加载；
+// 生效状态按分钟缓存，选路热路径只做一次 map 查找。
+
+const maxRoutingWeightMultiplier = 100
+
+// routingAdjustment 单个渠道当前生效的调整量
+type routingAdjustment struct {
+	priorityDelta    int
+	weightMultiplier float64
+}
+
+// compiledRoutingSchedule 预解析的规则（cron/时区解析只在加载时做一次）
+type compiledRoutingSchedule struct {
+	rule   *model.RoutingSchedule
+	window *util.CronWindow
+	loc    *time.Location
+}
+
+func (c *compiledRoutingSchedule) activeAt(now time.Time) bool {
+	return c.window.Matches(now.In(c.loc))
+}
+
+// routingScheduler 分时路由规则集合（nil 安全：未初始化时不做任何调整）
+type routingScheduler struct {
+	mu    sync.Mutex
+	rules []*compiledRoutingSchedule
+
+	cachedMinute int64
+	cached       map[int64]routingAdjustment
+}
+
+func newRoutingScheduler() *routingScheduler {
+	return &routingScheduler{cachedMinute: -1}
+}
+
+// compileRoutingSchedule 校验并预解析规则
+func compileRoutingSchedule(r *model.RoutingSchedule) (*compiledRoutingSchedule, error) {
+	window, err := util.ParseCronWindow(r.Cron)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if r.Timezone != "" {
+		if loc, err = time.LoadLocation(r.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", r.Timezone, err)
+		}
+	}
+	if r.WeightMultiplier < 0 || r.WeightMultiplier > maxRoutingWeightMultiplier || math.IsNaN(r.WeightMultiplier) {
+		return nil, fmt.Errorf("weight_multiplier must be between 0 and %d", maxRoutingWeightMultiplier)
+	}
+	return &compiledRoutingSchedule{rule: r, window: window, loc: loc}, nil
+}
+
+// set 替换规则集合（跳过禁用或无法解析的规则）
+func (rs *routingScheduler) set(rules []*model.RoutingSchedule) {
+	compiled := make([]*compiledRoutingSchedule, 0, len(rules))
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		c, err := compileRoutingSchedule(r)
+		if err != nil {
+			log.Printf("[WARN] 分时路由规则 #%d 无效，已跳过: %v", r.ID, err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	rs.mu.Lock()
+	rs.rules = compiled
+	rs.cachedMinute = -1
+	rs.cached = nil
+	rs.mu.Unlock()
+}
+
+// active 返回当前分钟生效的调整（channelID → 调整量）；无生效规则时返回 nil
+func (rs *routingScheduler) active(now time.Time) map[int64]routingAdjustment {
+	if rs == nil {
+		return nil
+	}
+	minute := now.Unix() / 60
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if minute == rs.cachedMinute {
+		return rs.cached
+	}
+
+	var adj map[int64]routingAdjustment
+	for _, c := range rs.rules {
+		if !c.activeAt(now) {
+			continue
+		}
+		if adj == nil {
+			adj = make(map[int64]routingAdjustment)
+		}
+		cur, ok := adj[c.rule.ChannelID]
+		if !ok {
+			cur.weightMultiplier = 1
+		}
+		cur.priorityDelta += c.rule.PriorityDelta
+		cur.weightMultiplier *= c.rule.WeightMultiplier
+		adj[c.rule.ChannelID] = cur
+	}
+	rs.cachedMinute = minute
+	rs.cached = adj
+	return adj
+}
+
+// reloadRoutingSchedules 从数据库重新加载分时路由规则
+func (s *Server) reloadRoutingSchedules(ctx context.Context) error {
+	if s.routingSchedules == nil {
+		return nil
+	}
+	rules, err := s.store.ListRoutingSchedules(ctx)
+	if err != nil {
+		return err
+	}
+	s.routingSchedules.set(rules)
+	return nil
+}
+
+// scheduledPriority 渠道当前的优先级（含分时路由调整）
+func scheduledPriority(ch *model.Config, adj map[int64]routingAdjustment) int {
+	return ch.Priority + adj[ch.ID].priorityDelta
+}
+
+// selectBalanced 同优先级组内负载均衡：权重 = 渠道基础权重（weight 或有效Key数）× 分时路由倍率
+// 按 channel_balance_mode 使用平滑加权轮询（默认）或加权随机
+func (s *Server) selectBalanced(
+	group []*model.Config,
+	keyCooldowns map[int64]map[int]time.Time,
+	now time.Time,
+	adj map[int64]routingAdjustment,
+) []*model.Config {
+	scaled := false
+	for _, ch := range group {
+		if a, ok := adj[ch.ID]; ok && a.weightMultiplier != 1 {
+			scaled = true
+			break
+		}
+	}
+
+	weights := make([]int, len(group))
+	for i, ch := range group {
+		weights[i] = channelBaseWeight(ch, keyCooldowns, now)
+		if scaled {
+			// 权重放大100倍以保留倍率的小数部分
+			w := float64(weights[i] * 100)
+			if a, ok := adj[ch.ID]; ok {
+				w *= a.weightMultiplier
+			}
+			weights[i] = int(math.Round(w))
+		}
+	}
+	if s.channelBalanceMode == channelBalanceRandom {
+		return weightedRandomOrder(group, weights, rand.IntN)
+	}
+	return s.channelBalancer.Select(group, weights)
+}
+
+// sortByScheduledPriority 按调整后的优先级稳定降序排序（无调整时保持原顺序）
+func sortByScheduledPriority(channels []*model.Config, adj map[int64]routingAdjustment) {
+	if len(adj) == 0 {
+		return
+	}
+	slices.SortStableFunc(channels, func(a, b *model.Config) int {
+		return cmp.Compare(scheduledPriority(b, adj), scheduledPriority(a, adj))
+	})
+}
+
+// routingScheduleView 管理接口返回的规则（附带当前是否生效）
+type routingScheduleView struct {
+	*model.RoutingSchedule
+	Active bool `json:"active"`
+}
+
+// HandleListRoutingSchedules 分时路由规则列表
+// GET /admin/routing-schedules
+func (s *Server) HandleListRoutingSchedules(c *gin.Context) {
+	rules, err := s.store.ListRoutingSchedules(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now()
+	views := make([]routingScheduleView, 0, len(rules))
+	for _, r := range rules {
+		v := routingScheduleView{RoutingSchedule: r}
+		if compiled, err := compileRoutingSchedule(r); err == nil && r.Enabled {
+			v.Active = compiled.activeAt(now)
+		}
+		views = append(views, v)
+	}
+	RespondJSON(c, http.StatusOK, views)
+}
+
+// HandleCreateRoutingSchedule 新增分时路由规则
+// POST /admin/routing-schedules
+func (s *Server) HandleCreateRoutingSchedule(c *gin.Context) {
+	rule, ok := s.bindRoutingSchedule(c)
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	rule.CreatedAt, rule.UpdatedAt = now, now
+	if err := s.store.CreateRoutingSchedule(c.Request.Context(), rule); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.afterRoutingScheduleChange(c.Request.Context())
+	RespondJSON(c, http.StatusCreated, rule)
+}
+
+// HandleUpdateRoutingSchedule 更新分时路由规则
+// PUT /admin/routing-schedules/:id
+func (s *Server) HandleUpdateRoutingSchedule(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid schedule id")
+		return
+	}
+	rule, ok := s.bindRoutingSchedule(c)
+	if !ok {
+		return
+	}
+	rule.ID = id
+	rule.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateRoutingSchedule(c.Request.Context(), rule)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "schedule not found")
+		return
+	}
+	s.afterRoutingScheduleChange(c.Request.Context())
+	RespondJSON(c, http.StatusOK, rule)
+}
+
+// HandleDeleteRoutingSchedule 删除分时路由规则
+// DELETE /admin/routing-schedules/:id
+func (s *Server) HandleDeleteRoutingSchedule(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid schedule id")
+		return
+	}
+	found, err := s.store.DeleteRoutingSchedule(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "schedule not found")
+		return
+	}
+	s.afterRoutingScheduleChange(c.Request.Context())
+	RespondJSON(c, http.StatusOK, gin.H{"id": id, "deleted": true})
+}
+
+// bindRoutingSchedule 解析并校验请求体（渠道需存在；cron/时区/倍率需合法）
+func (s *Server) bindRoutingSchedule(c *gin.Context) (*model.RoutingSchedule, bool) {
+	var req RoutingScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return nil, false
+	}
+	rule := req.ToRoutingSchedule()
+	if _, err := compileRoutingSchedule(rule); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if _, err := s.store.GetConfig(c.Request.Context(), rule.ChannelID); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel not found")
+		return nil, false
+	}
+	return rule, true
+}
+
+// afterRoutingScheduleChange 规则变更后立即重新加载（加载失败不影响接口结果，下次变更或重启时恢复）
+func (s *Server) afterRoutingScheduleChange(ctx context.Context) {
+	if err := s.reloadRoutingSchedules(ctx); err != nil {
+		log.Printf("[WARN] 重新加载分时路由规则失败: %v", err)
+	}
+}
+
+// normalizeTimezone 规范化时区输入（去除空白；"local" 视为服务器时区）
+func normalizeTimezone(tz string) string {
+	tz = strings.TrimSpace(tz)
+	if strings.EqualFold(tz, "local") {
+		return ""
+	}
+	return tz
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutingScheduler_ActiveAggregatesRules(t *testing.T) {
+	rs := newRoutingScheduler()
+	rs.set([]*model.RoutingSchedule{
+		{ID: 1, ChannelID: 10, Cron: "* 0-7 * * *", Timezone: "UTC", PriorityDelta: 5, WeightMultiplier: 2, Enabled: true},
+		{ID: 2, ChannelID: 10, Cron: "* * * * *", Timezone: "UTC", PriorityDelta: -1, WeightMultiplier: 1.5, Enabled: true},
+		{ID: 3, ChannelID: 20, Cron: "* 0-7 * * *", Timezone: "UTC", PriorityDelta: 9, WeightMultiplier: 1, Enabled: false}, // 禁用
+		{ID: 4, ChannelID: 30, Cron: "bad cron", PriorityDelta: 9, WeightMultiplier: 1, Enabled: true},                      // 无效
+	})
+
+	night := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+	adj := rs.active(night)
+	if got := adj[10]; got.priorityDelta != 4 || got.weightMultiplier != 3 {
+		t.Fatalf("channel 10 adjustment = %+v, want delta=4 weight=3", got)
+	}
+	if _, ok := adj[20]; ok {
+		t.Fatal("disabled rule should not apply")
+	}
+	if _, ok := adj[30]; ok {
+		t.Fatal("invalid rule should be skipped")
+	}
+
+	day := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if got := rs.active(day)[10]; got.priorityDelta != -1 || got.weightMultiplier != 1.5 {
+		t.Fatalf("daytime adjustment = %+v, want delta=-1 weight=1.5", got)
+	}
+
+	// 时区：UTC 03:00 = Asia/Shanghai 11:00，不在 0-7 窗口内
+	rs.set([]*model.RoutingSchedule{
+		{ID: 5, ChannelID: 10, Cron: "* 0-7 * * *", Timezone: "Asia/Shanghai", PriorityDelta: 5, WeightMultiplier: 1, Enabled: true},
+	})
+	if adj := rs.active(night); adj != nil {
+		t.Fatalf("expected no adjustment in Asia/Shanghai daytime, got %+v", adj)
+	}
+
+	var nilScheduler *routingScheduler
+	if nilScheduler.active(night) != nil {
+		t.Fatal("nil scheduler should not adjust")
+	}
+}
+
+func TestBalanceSamePriorityChannels_RoutingSchedule(t *testing.T) {
+	now := time.Now()
+	a := &model.Config{ID: 1, Name: "day", Priority: 10, KeyCount: 1}
+	b := &model.Config{ID: 2, Name: "night", Priority: 5, KeyCount: 1}
+
+	server := &Server{channelBalancer: NewSmoothWeightedRR(), routingSchedules: newRoutingScheduler()}
+
+	// 无规则：保持优先级顺序
+	if got := server.balanceSamePriorityChannels([]*model.Config{a, b}, nil, now); got[0].ID != 1 {
+		t.Fatalf("expected channel 1 first without schedule, got %d", got[0].ID)
+	}
+
+	// 窗口内提升 night 渠道优先级
+	server.routingSchedules.set([]*model.RoutingSchedule{
+		{ID: 1, ChannelID: 2, Cron: "* * * * *", PriorityDelta: 10, WeightMultiplier: 1, Enabled: true},
+	})
+	if got := server.balanceSamePriorityChannels([]*model.Config{a, b}, nil, now); got[0].ID != 2 {
+		t.Fatalf("expected scheduled channel 2 first, got %d", got[0].ID)
+	}
+
+	// 同优先级时按权重倍率分流：3:1
+	server.routingSchedules.set([]*model.RoutingSchedule{
+		{ID: 1, ChannelID: 2, Cron: "* * * * *", PriorityDelta: 5, WeightMultiplier: 3, Enabled: true},
+	})
+	counts := map[int64]int{}
+	for range 40 {
+		got := server.balanceSamePriorityChannels([]*model.Config{a, b}, nil, now)
+		counts[got[0].ID]++
+	}
+	if counts[2] != 30 || counts[1] != 10 {
+		t.Fatalf("expected 30/10 split, got %v", counts)
+	}
+}
+
+func TestHandleRoutingSchedules_CRUD(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	server.routingSchedules = newRoutingScheduler()
+
+	ctx := context.Background()
+	ch, err := store.CreateConfig(ctx, &model.Config{
+		Name: "night-plan", URL: "https://api.example.com", Priority: 5,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}, Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("创建测试渠道失败: %v", err)
+	}
+
+	do := func(method, path, body string, params gin.Params, h gin.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		h(c)
+		return w
+	}
+
+	// 参数校验
+	for _, body := range []string{
+		`{"channel_id":` + strconv.FormatInt(ch.ID, 10) + `,"cron":"* 25 * * *"}`,
+		`{"channel_id":` + strconv.FormatInt(ch.ID, 10) + `,"cron":"* * * * *","timezone":"Mars/Base"}`,
+		`{"channel_id":` + strconv.FormatInt(ch.ID, 10) + `,"cron":"* * * * *","weight_multiplier":-1}`,
+		`{"channel_id":999,"cron":"* * * * *"}`,
+	} {
+		if w := do(http.MethodPost, "/admin/routing-schedules", body, nil, server.HandleCreateRoutingSchedule); w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d (%s)", body, w.Code, w.Body.String())
+		}
+	}
+
+	w := do(http.MethodPost, "/admin/routing-schedules",
+		`{"channel_id":`+strconv.FormatInt(ch.ID, 10)+`,"cron":"* * * * *","priority_delta":7}`, nil, server.HandleCreateRoutingSchedule)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%s)", w.Code, w.Body.String())
+	}
+	if got := server.routingSchedules.active(time.Now())[ch.ID]; got.priorityDelta != 7 {
+		t.Fatalf("rule should be active immediately after create, got %+v", got)
+	}
+
+	rules, _ := store.ListRoutingSchedules(ctx)
+	if len(rules) != 1 || rules[0].WeightMultiplier != 1 || !rules[0].Enabled {
+		t.Fatalf("unexpected stored rules: %+v", rules)
+	}
+	id := strconv.FormatInt(rules[0].ID, 10)
+
+	w = do(http.MethodPut, "/admin/routing-schedules/"+id,
+		`{"channel_id":`+strconv.FormatInt(ch.ID, 10)+`,"cron":"* * * * *","priority_delta":7,"enabled":false}`,
+		gin.Params{{Key: "id", Value: id}}, server.HandleUpdateRoutingSchedule)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", w.Code, w.Body.String())
+	}
+	if server.routingSchedules.active(time.Now()) != nil {
+		t.Fatal("disabled rule should no longer apply")
+	}
+
+	w = do(http.MethodDelete, "/admin/routing-schedules/"+id, "", gin.Params{{Key: "id", Value: id}}, server.HandleDeleteRoutingSchedule)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	w = do(http.MethodDelete, "/admin/routing-schedules/"+id, "", gin.Params{{Key: "id", Value: id}}, server.HandleDeleteRoutingSchedule)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 on second delete, got %d", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"time"
 
 	modelpkg "ccLoad/internal/model"
 	"ccLoad/internal/util"
@@ -46,7 +47,7 @@ func (s *Server) selectCandidatesByModelAndType(ctx context.Context, model strin
 		}
 		filtered := make([]*modelpkg.Config, 0, len(channels))
 		for _, cfg := range channels {
-			if cfg.GetChannelType() == normalizedType {
+			if channelMatchesType(cfg, normalizedType) {
 				filtered = append(filtered, cfg)
 			}
 		}
@@ -76,23 +77,95 @@ func (s *Server) selectCandidatesByModelAndType(ctx context.Context, model strin
 
 	// 兜底：全量查询（用于“全冷却兜底”场景）
 	if len(channels) == 0 {
-		all, err := s.store.ListConfigs(ctx)
+		channels, err = s.scanEnabledChannelsForModel(ctx, model, channelType)
 		if err != nil {
 			return nil, err
 		}
-		channels = make([]*modelpkg.Config, 0, len(all))
-		for _, cfg := range all {
-			if cfg == nil || !cfg.Enabled {
-				continue
-			}
-			if channelType != "" && cfg.GetChannelType() != normalizedType {
-				continue
-			}
-			if s.configSupportsModelWithDateFallback(cfg, model) {
-				channels = append(channels, cfg)
-			}
-		}
 	}
 
+	// 跳过上游已报告该模型不存在的渠道（2026-10新增）
+	channels = s.modelNotFound.filter(model, channels, time.Now())
+
 	return s.filterCooldownChannels(ctx, channels)
 }
+
+// scanEnabledChannelsForModel 全量扫描支持该模型且类型匹配的启用渠道（包含渠道级冷却中的渠道，2026-10抽取）
+func (s *Server) scanEnabledChannelsForModel(ctx context.Context, model string, channelType string) ([]*modelpkg.Config, error) {
+	normalizedType := util.NormalizeChannelType(channelType)
+	all, err := s.store.ListConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]*modelpkg.Config, 0, len(all))
+	for _, cfg := range all {
+		if cfg == nil || !cfg.Enabled {
+			continue
+		}
+		if channelType != "" && !channelMatchesType(cfg, normalizedType) {
+			continue
+		}
+		if s.configSupportsModelWithDateFallback(cfg, model) {
+			channels = append(channels, cfg)
+		}
+	}
+	return channels, nil
+}
+
+// channelMatchesType 渠道类型匹配
+// anthropic 请求同时接受开启 anthropic_compat 的 gemini/openai 渠道（2026-10新增，由 selectRouteCandidates 限定到 /v1/messages）
+// openai 请求同时接受开启 openai_compat 的 anthropic/gemini/codex 渠道（2026-10新增，由 selectRouteCandidates 限定到 /v1/chat/completions）
+// 以及原生 OpenAI 协议的 azure 渠道（2026-10新增）
+// mock 渠道按请求路径回应对应协议，匹配任意类型（2026-10新增）
+func channelMatchesType(cfg *modelpkg.Config, normalizedType string) bool {
+	channelType := cfg.GetChannelType()
+	if channelType == normalizedType || channelType == util.ChannelTypeMock {
+		return true
+	}
+	switch normalizedType {
+	case util.ChannelTypeAnthropic:
+		return cfg.AnthropicCompat && anthropicCompatChannelType(channelType)
+	case util.ChannelTypeOpenAI:
+		return channelType == util.ChannelTypeAzure || (cfg.OpenAICompat && openaiCompatChannelType(channelType))
+	}
+	return false
+}
+
+// preferClientRegion 地域路由（2026-10新增）：客户端IP命中 geo_regions 中的地域时，
+// 将带该地域标签的渠道稳定前移，其余渠道保持原顺序作为回退；未配置/未命中时原样返回
+func (s *Server) preferClientRegion(clientIP string, channels []*modelpkg.Config) []*modelpkg.Config {
+	if s.geoRegions == nil || len(channels) < 2 {
+		return channels
+	}
+	region := s.geoRegions.Lookup(clientIP)
+	if region == "" {
+		return channels
+	}
+	ordered := make([]*modelpkg.Config, 0, len(channels))
+	var rest []*modelpkg.Config
+	for _, cfg := range channels {
+		if util.HasRegionTag(cfg.Regions, region) {
+			ordered = append(ordered, cfg)
+		} else {
+			rest = append(rest, cfg)
+		}
+	}
+	return append(ordered, rest...)
+}
+
+// filterByBetaFeatures Beta功能路由（2026-10新增）：请求使用了beta功能时跳过声明了但不支持的渠道；
+// 全部候选都不支持时原样返回（转发时由 util.StripDisabledBetaBody/ApplyBetaFeatureHeader 降级请求）
+func filterByBetaFeatures(requested []string, channels []*modelpkg.Config) []*modelpkg.Config {
+	if len(requested) == 0 || len(channels) == 0 {
+		return channels
+	}
+	kept := make([]*modelpkg.Config, 0, len(channels))
+	for _, cfg := range channels {
+		if util.ChannelSupportsBetaFeatures(cfg.BetaFeatures, requested) {
+			kept = append(kept, cfg)
+		}
+	}
+	if len(kept) == 0 {
+		return channels
+	}
+	return kept
+}
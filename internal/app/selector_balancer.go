@@ -3,6 +3,7 @@ package app
 import (
 	"math"
 	"sort"
+	"strings"
 	"time"
 
 	modelpkg "ccLoad/internal/model"
@@ -12,8 +13,72 @@ const (
 	// effPriorityPrecision 有效优先级分组精度（*10可区分0.1差异，如5.0 vs 5.1）
 	// 设计考虑：优先级通常是整数（5, 10），成功率惩罚基于统计（精度有限），0.1精度已足够
 	effPriorityPrecision = 10
+
+	// maxChannelWeight 渠道负载均衡权重上限
+	maxChannelWeight = 10000
+)
+
+// 同优先级渠道负载均衡模式（channel_balance_mode，2026-10新增）
+const (
+	channelBalanceSmooth = "smooth" // 平滑加权轮询：确定性分流，短时间内即严格按权重比例
+	channelBalanceRandom = "random" // 加权随机：多实例部署时各实例无需共享轮询状态
 )
 
+// parseChannelBalanceMode 解析负载均衡模式（空值为 smooth）
+func parseChannelBalanceMode(raw string) (string, bool) {
+	switch mode := strings.ToLower(strings.TrimSpace(raw)); mode {
+	case "", channelBalanceSmooth:
+		return channelBalanceSmooth, true
+	case channelBalanceRandom:
+		return channelBalanceRandom, true
+	default:
+		return channelBalanceSmooth, false
+	}
+}
+
+// channelBaseWeight 渠道基础权重：配置了 weight 时使用配置值，否则为有效Key数量
+func channelBaseWeight(ch *modelpkg.Config, keyCooldowns map[int64]map[int]time.Time, now time.Time) int {
+	if ch.Weight > 0 {
+		return ch.Weight
+	}
+	return calcEffectiveKeyCount(ch, keyCooldowns, now)
+}
+
+// weightedRandomOrder 按权重随机选出首选渠道并移到首位，其余渠道保持原顺序（失败回退可预测）
+// intn 返回 [0,n) 的随机数（测试时可注入确定性实现）
+func weightedRandomOrder(channels []*modelpkg.Config, weights []int, intn func(int) int) []*modelpkg.Config {
+	n := len(channels)
+	if n <= 1 || len(weights) != n {
+		return channels
+	}
+	total := 0
+	for _, w := range weights {
+		total += max(w, 0)
+	}
+	if total <= 0 {
+		return channels
+	}
+
+	r := intn(total)
+	selected := 0
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if r < w {
+			selected = i
+			break
+		}
+		r -= w
+	}
+
+	result := make([]*modelpkg.Config, 0, n)
+	result = append(result, channels[selected])
+	result = append(result, channels[:selected]...)
+	result = append(result, channels[selected+1:]...)
+	return result
+}
+
 func effPriorityBucket(p float64) int64 {
 	scaled := p * float64(effPriorityPrecision)
 	// 浮点误差修正：避免 5.1*10 得到 50.999999... 被截断到 50
@@ -59,8 +124,8 @@ func (s *Server) sortChannelsByHealth(
 		return scored[i].effPriority > scored[j].effPriority
 	})
 
-	// 同有效优先级内按 KeyCount 平滑加权轮询（负载均衡）
-	// 说明：healthCache 开启后仍需按 Key 数量分流。
+	// 同有效优先级内按权重（weight 或 KeyCount）负载均衡
+	// 说明：healthCache 开启后仍需按权重分流。
 	// 这里仅把“本轮选中的渠道”移动到组首，确保首选渠道按权重分布；其余顺序保持稳定，便于失败回退时可预测。
 	result := make([]*modelpkg.Config, len(scored))
 	groupStart := 0
@@ -80,14 +145,14 @@ func (s *Server) sortChannelsByHealth(
 }
 
 // calculateEffectivePriority 计算渠道的有效优先级
-// 有效优先级 = 基础优先级 - 成功率惩罚 × 置信度（越大越优先）
+// 有效优先级 = 基础优先级（含分时路由调整） - 成功率惩罚 × 置信度（越大越优先）
 // 置信度 = min(1.0, 样本量 / 置信阈值)，样本量越小惩罚越轻
 func (s *Server) calculateEffectivePriority(
 	ch *modelpkg.Config,
 	stats modelpkg.ChannelHealthStats,
 	cfg modelpkg.HealthScoreConfig,
 ) float64 {
-	basePriority := float64(ch.Priority)
+	basePriority := float64(scheduledPriority(ch, s.routingSchedules.active(time.Now())))
 
 	successRate := stats.SuccessRate
 	if successRate < 0 {
@@ -109,7 +174,7 @@ func (s *Server) calculateEffectivePriority(
 	return basePriority - penalty
 }
 
-// balanceSamePriorityChannels 按优先级分组，组内使用平滑加权轮询
+// balanceSamePriorityChannels 按优先级分组，组内按权重负载均衡（默认平滑加权轮询）
 // 用于 healthCache 关闭时的场景，确保确定性分流
 func (s *Server) balanceSamePriorityChannels(
 	channels []*modelpkg.Config,
@@ -129,13 +194,17 @@ func (s *Server) balanceSamePriorityChannels(
 	result := make([]*modelpkg.Config, n)
 	copy(result, channels)
 
-	// 按优先级分组，组内使用平滑加权轮询
+	// 分时路由：按调整后的优先级重新排序
+	adj := s.routingSchedules.active(now)
+	sortByScheduledPriority(result, adj)
+
+	// 按优先级分组，组内按权重负载均衡
 	groupStart := 0
 	for i := 1; i <= n; i++ {
-		if i == n || result[i].Priority != result[groupStart].Priority {
+		if i == n || scheduledPriority(result[i], adj) != scheduledPriority(result[groupStart], adj) {
 			if i-groupStart > 1 {
 				group := result[groupStart:i]
-				balanced := s.channelBalancer.SelectWithCooldown(group, keyCooldowns, now)
+				balanced := s.selectBalanced(group, keyCooldowns, now, adj)
 				copy(result[groupStart:i], balanced)
 			}
 			groupStart = i
@@ -145,7 +214,7 @@ func (s *Server) balanceSamePriorityChannels(
 	return result
 }
 
-// balanceScoredChannelsInPlace 对带分数的渠道列表进行平滑加权轮询
+// balanceScoredChannelsInPlace 对带分数的渠道列表按权重负载均衡
 // 用于 healthCache 开启时的同有效优先级组内负载均衡（仅决定组内“首选”渠道）
 func (s *Server) balanceScoredChannelsInPlace(
 	items []channelWithScore,
@@ -169,7 +238,7 @@ func (s *Server) balanceScoredChannelsInPlace(
 	}
 
 	// 使用平滑加权轮询获取排序后的结果
-	balanced := s.channelBalancer.SelectWithCooldown(configs, keyCooldowns, now)
+	balanced := s.selectBalanced(configs, keyCooldowns, now, s.routingSchedules.active(now))
 
 	// 按轮询结果重排 items（O(n) 交换）
 	// balanced[0] 是选中的渠道，需要把它移到 items[0]
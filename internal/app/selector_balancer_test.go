@@ -3,6 +3,9 @@ package app
 import (
 	"math"
 	"testing"
+	"time"
+
+	"ccLoad/internal/model"
 )
 
 func TestEffPriorityBucket_FloatEdge(t *testing.T) {
@@ -19,3 +22,70 @@ func TestEffPriorityBucket_FloatEdge(t *testing.T) {
 		t.Fatalf("expected bucket=-51, got %d (p=%v scaled=%v)", got, pNeg, pNeg*10)
 	}
 }
+
+func TestBalanceSamePriorityChannels_Weight(t *testing.T) {
+	now := time.Now()
+	a := &model.Config{ID: 1, Priority: 10, KeyCount: 5, Weight: 70}
+	b := &model.Config{ID: 2, Priority: 10, KeyCount: 1, Weight: 30}
+
+	// 平滑加权轮询：每100次严格 70/30（配置权重优先于Key数量）
+	server := &Server{channelBalancer: NewSmoothWeightedRR()}
+	counts := map[int64]int{}
+	for range 100 {
+		counts[server.balanceSamePriorityChannels([]*model.Config{a, b}, nil, now)[0].ID]++
+	}
+	if counts[1] != 70 || counts[2] != 30 {
+		t.Fatalf("smooth mode expected 70/30, got %v", counts)
+	}
+
+	// 加权随机：大样本下接近 70/30
+	server = &Server{channelBalancer: NewSmoothWeightedRR(), channelBalanceMode: channelBalanceRandom}
+	counts = map[int64]int{}
+	for range 10000 {
+		counts[server.balanceSamePriorityChannels([]*model.Config{a, b}, nil, now)[0].ID]++
+	}
+	if counts[1] < 6500 || counts[1] > 7500 {
+		t.Fatalf("random mode expected ~70/30, got %v", counts)
+	}
+}
+
+func TestWeightedRandomOrder(t *testing.T) {
+	chs := []*model.Config{{ID: 1}, {ID: 2}, {ID: 3}}
+	weights := []int{2, 0, 3}
+
+	ids := func(list []*model.Config) []int64 {
+		out := make([]int64, len(list))
+		for i, ch := range list {
+			out[i] = ch.ID
+		}
+		return out
+	}
+	// r=0,1 落在渠道1；r=2..4 落在渠道3（权重0的渠道不会被选中），其余渠道保持原顺序
+	for r, want := range map[int][]int64{0: {1, 2, 3}, 1: {1, 2, 3}, 2: {3, 1, 2}, 4: {3, 1, 2}} {
+		got := ids(weightedRandomOrder(chs, weights, func(n int) int {
+			if n != 5 {
+				t.Fatalf("intn called with %d, want 5", n)
+			}
+			return r
+		}))
+		if len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Fatalf("r=%d: got %v, want %v", r, got, want)
+		}
+	}
+
+	// 总权重为0：原样返回
+	if got := weightedRandomOrder(chs, []int{0, 0, 0}, func(int) int { panic("unexpected") }); got[0].ID != 1 {
+		t.Fatalf("zero weights should keep order, got %v", ids(got))
+	}
+}
+
+func TestParseChannelBalanceMode(t *testing.T) {
+	for raw, want := range map[string]string{"": channelBalanceSmooth, "smooth": channelBalanceSmooth, " Random ": channelBalanceRandom} {
+		if got, ok := parseChannelBalanceMode(raw); !ok || got != want {
+			t.Fatalf("parse(%q) = %q,%v want %q", raw, got, ok, want)
+		}
+	}
+	if got, ok := parseChannelBalanceMode("roundrobin"); ok || got != channelBalanceSmooth {
+		t.Fatalf("invalid mode should fall back to smooth, got %q,%v", got, ok)
+	}
+}
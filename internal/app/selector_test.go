@@ -2,12 +2,14 @@ package app
 
 import (
 	"context"
+	"slices"
 	"testing"
 	"time"
 
 	"ccLoad/internal/model"
 	"ccLoad/internal/storage"
 	"ccLoad/internal/testutil"
+	"ccLoad/internal/util"
 )
 
 // TestSelectRouteCandidates_NormalRequest 测试普通请求的路由选择
@@ -1092,3 +1094,100 @@ func TestSortChannelsByHealth_WeightedByEffectiveKeyCount(t *testing.T) {
 func setupTestStore(t *testing.T) (storage.Store, func()) {
 	return testutil.SetupTestStore(t)
 }
+
+// TestPreferClientRegion 地域路由：命中地域的渠道稳定前移，其余按原顺序回退（持久化后标签可读回）
+func TestPreferClientRegion(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var channels []*model.Config
+	for i, regions := range []string{"", "us", "eu,us", "eu"} {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name:         "geo-" + string(rune('a'+i)),
+			URL:          "https://api.example.com",
+			Priority:     100 - i,
+			ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+			Enabled:      true,
+			Regions:      regions,
+		})
+		if err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		got, err := store.GetConfig(ctx, cfg.ID)
+		if err != nil || got.Regions != regions {
+			t.Fatalf("regions not persisted: got %q, want %q (%v)", got.Regions, regions, err)
+		}
+		channels = append(channels, got)
+	}
+
+	tbl, err := util.ParseGeoRegions(`{"eu":["10.0.0.0/8"],"us":["192.168.0.0/16"]}`)
+	if err != nil {
+		t.Fatalf("parse geo regions: %v", err)
+	}
+	server := &Server{store: store, geoRegions: tbl}
+	names := func(list []*model.Config) []string {
+		out := make([]string, len(list))
+		for i, c := range list {
+			out[i] = c.Name
+		}
+		return out
+	}
+
+	if got := names(server.preferClientRegion("10.1.2.3", channels)); got[0] != "geo-c" || got[1] != "geo-d" || got[2] != "geo-a" || got[3] != "geo-b" {
+		t.Fatalf("eu client order = %v", got)
+	}
+	if got := names(server.preferClientRegion("192.168.1.1", channels)); got[0] != "geo-b" || got[1] != "geo-c" || got[2] != "geo-a" {
+		t.Fatalf("us client order = %v", got)
+	}
+	if got := names(server.preferClientRegion("8.8.8.8", channels)); got[0] != "geo-a" || got[3] != "geo-d" {
+		t.Fatalf("unmatched client must keep global order, got %v", got)
+	}
+}
+
+// TestFilterByBetaFeatures Beta功能路由：跳过不支持所请求功能的渠道，全部不支持时保留原列表（持久化后可读回）
+func TestFilterByBetaFeatures(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var channels []*model.Config
+	for i, features := range []string{"", "none", "extended-thinking,computer-use", "computer-use"} {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name:         "beta-" + string(rune('a'+i)),
+			URL:          "https://api.example.com",
+			Priority:     100 - i,
+			ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}},
+			BetaFeatures: features,
+			Enabled:      true,
+		})
+		if err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+		loaded, err := store.GetConfig(ctx, cfg.ID)
+		if err != nil || loaded.BetaFeatures != features {
+			t.Fatalf("beta_features 未持久化: %v %q", err, loaded.BetaFeatures)
+		}
+		channels = append(channels, loaded)
+	}
+	names := func(list []*model.Config) []string {
+		out := make([]string, len(list))
+		for i, c := range list {
+			out[i] = c.Name
+		}
+		return out
+	}
+
+	if got := filterByBetaFeatures(nil, channels); len(got) != 4 {
+		t.Fatalf("未使用beta功能应保留全部渠道, got %v", names(got))
+	}
+	if got := names(filterByBetaFeatures([]string{"computer-use"}, channels)); !slices.Equal(got, []string{"beta-a", "beta-c", "beta-d"}) {
+		t.Fatalf("computer-use 路由结果不符: %v", got)
+	}
+	if got := names(filterByBetaFeatures([]string{"extended-thinking", "computer-use"}, channels)); !slices.Equal(got, []string{"beta-a", "beta-c"}) {
+		t.Fatalf("组合功能路由结果不符: %v", got)
+	}
+	if got := filterByBetaFeatures([]string{"context-management"}, channels[1:]); len(got) != 3 {
+		t.Fatalf("全部不支持时应保留原列表, got %v", names(got))
+	}
+}
@@ -3,11 +3,14 @@ package app
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -34,15 +37,36 @@ type Server struct {
 	// ============================================================================
 	// 核心字段
 	// ============================================================================
-	store           storage.Store
-	channelCache    *storage.ChannelCache // 高性能渠道缓存层
-	keySelector     *KeySelector          // Key选择器（多Key支持）
-	cooldownManager *cooldown.Manager     // 统一冷却管理器
-	healthCache     *HealthCache          // 渠道健康度缓存
-	costCache       *CostCache            // 渠道每日成本缓存
-	channelBalancer *SmoothWeightedRR     // 渠道负载均衡器（平滑加权轮询）
-	client          *http.Client          // HTTP客户端
-	activeRequests  *activeRequestManager // 进行中请求（内存状态，不持久化）
+	store              storage.Store
+	channelCache       *storage.ChannelCache  // 高性能渠道缓存层
+	keySelector        *KeySelector           // Key选择器（多Key支持）
+	cooldownManager    *cooldown.Manager      // 统一冷却管理器
+	healthCache        *HealthCache           // 渠道健康度缓存
+	costCache          *CostCache             // 渠道每日成本缓存
+	channelBalancer    *SmoothWeightedRR      // 渠道负载均衡器（平滑加权轮询）
+	channelBalanceMode string                 // 同优先级负载均衡模式（smooth/random，启动时加载）
+	client             *http.Client           // HTTP客户端
+	activeRequests     *activeRequestManager  // 进行中请求（内存状态，不持久化）
+	adminEvents        *adminEventBus         // 管理端统一事件流（2026-10新增）
+	journal            *requestJournal        // 请求用量预写日志（nil 表示未启用，2026-10新增）
+	modelNotFound      *modelNotFoundTracker  // 上游报告不存在的渠道模型临时标记（2026-10新增）
+	unmatchedModels    *unmatchedModelTracker // 未匹配任何渠道的请求模型计数（2026-10新增）
+	requestSizes       *requestSizeTracker    // 渠道请求体大小上限观测值（2026-10新增）
+	distributions      *distributionCollector // 按模型/渠道的Token与请求体大小分布（2026-10新增）
+	tokenHandoffs      *tokenHandoffStore     // 令牌一次性取回链接（仅内存，2026-10新增）
+	dbBackup           *dbBackupService       // 数据库完整性检查与自动备份（2026-10新增）
+	dbReplication      *dbReplicationService  // SQLite 快照复制与热备切换（2026-10新增）
+	vcr                *util.VCRTransport     // 上游交互录制/回放（nil 表示未启用，仅环境变量，2026-10新增）
+
+	// 代理链路追踪（OTLP/HTTP 导出；nil 表示未启用，启动时加载，2026-10新增）
+	tracer *tracer
+
+	// 合成探测与状态页（启动时加载，修改后重启生效；statusTracker 为 nil 表示未启用，2026-10新增）
+	statusTracker *statusTracker
+
+	// 渠道定时健康检查（启动时加载，修改后重启生效，2026-10新增）
+	channelHealth    *channelHealthChecker
+	statusPagePublic bool // true: /status 公开访问；false: 需API令牌
 
 	// 异步统计（有界队列，避免每请求起goroutine）
 	tokenStatsCh        chan tokenStatsUpdate
@@ -56,6 +80,94 @@ type Server struct {
 	modelLookupStripDateSuffix bool // 未命中时去除末尾-YYYYMMDD日期后缀再匹配渠道（优先精确匹配）
 	modelFuzzyMatch            bool // 未命中时启用模糊匹配（子串匹配+版本排序）
 
+	// 客户端请求头profile（启动时从 client_profiles 加载，修改后重启生效）
+	clientProfiles map[string]util.ClientProfile
+
+	// 客户端IP → 地域映射（启动时从 geo_regions 加载，nil 表示未启用地域路由，2026-10新增）
+	geoRegions *util.GeoRegionTable
+
+	// 证书固定/出站地址绑定渠道的专用HTTP客户端（按 cert_pins|local_addr 缓存，连接池与共享客户端隔离）
+	channelClients sync.Map // string → *http.Client
+
+	// 渠道出站地址不可用时是否改走默认路由（启动时加载，修改后重启生效）
+	localAddrFallback bool
+
+	// 429/5xx 错误体附加重试提示（启动时加载，修改后重启生效）
+	retryHintsEnabled bool
+
+	// 上游socket瞬断时新连接微重试（启动时加载，修改后重启生效）及其计数
+	microRetryEnabled bool
+	microRetry        microRetryCounters
+
+	// 流式响应缓冲窗口（字节，0=关闭；启动时加载，修改后重启生效）
+	responseBufferBytes int
+
+	// JSON模式输出修复/Schema校验（启动时加载，修改后重启生效）
+	jsonRepairEnabled bool
+
+	// 全局模型屏蔽列表（启动时从 blocked_models 加载，修改后立即生效）
+	blockedModels atomic.Pointer[[]string]
+
+	// 响应内容打标规则（启动时从 response_label_rules 加载，修改后立即生效，2026-10新增）
+	responseLabelRules atomic.Pointer[[]responseLabelRule]
+
+	// 会话粘性路由（启动时加载TTL，nil 表示未启用，2026-10新增）
+	sessionAffinity *sessionAffinity
+
+	// 非流式响应缓存（启动时加载配置，nil 表示未启用，2026-10新增）
+	responseCache *responseCache
+
+	// 分时路由规则（启动时加载，管理接口修改后立即重新加载）
+	routingSchedules *routingScheduler
+
+	// 出站Webhook端点与投递队列（启动时加载，管理接口修改后立即重新加载）
+	webhooks *webhookDispatcher
+
+	// 费用展示币种与汇率（2026-10新增）
+	displayCurrency *displayCurrency
+
+	// Key级上游配额写库节流（配额快照本身持久化在 api_keys 表）
+	keyQuotas *keyQuotaTracker
+
+	// 渠道级上游请求抓取（管理员临时开启，仅内存，2026-10新增）
+	requestCaptures *requestCaptureStore
+	captureSeq      atomic.Int64 // 抓取记录ID序列（手动抓取与失败抓取共用）
+
+	// 失败请求常驻抓取（启动时加载预算，nil 表示关闭，2026-10新增）
+	errorCaptures *errorCaptureStore
+
+	// 输出Token异常检测（启动时加载阈值，修改后重启生效）
+	tokenAnomaly *tokenAnomalyDetector
+
+	// Token估算引擎误差跟踪（2026-10新增）
+	tokenEstimates *tokenEstimateTracker
+
+	// Token估算漂移告警（启动时加载阈值，修改后重启生效）
+	tokenDrift *tokenDriftTracker
+
+	// count_tokens 请求合并（2026-10新增）
+	countTokens *countTokensCoalescer
+
+	// 预算软告警（阈值启动时加载；告警经有界队列异步写库）
+	budgetAlertThresholds []int
+	budgetAlertCh         chan *model.BudgetAlert
+	monthlyBudgets        *monthlyBudgetTracker // 月度预算限额与本月花费（2026-10新增）
+
+	// 历史费用重算任务（同一时间最多一个在运行）
+	costRecompute costRecomputeRunner
+
+	// 管理端发起的上游调用（渠道测试等）走低优先级通道，避免与生产流量争抢上游限额
+	adminLane *backgroundLane
+
+	// 实例间差异同步对端白名单（CCLOAD_SYNC_PEERS，空表示未启用，2026-10新增）
+	syncPeers []string
+
+	// Vertex AI 渠道凭据与访问令牌（启动时加载，管理接口修改后立即生效，2026-10新增）
+	vertex *vertexRegistry
+
+	// 渠道按模型并发上限的在途计数（2026-10新增）
+	modelConcurrency *modelConcurrencyTracker
+
 	// 登录速率限制器（用于传递给AuthService）
 	loginRateLimiter *util.LoginRateLimiter
 
@@ -128,6 +240,19 @@ func NewServer(store storage.Store) *Server {
 		log.Print("[INFO] 已启用模型模糊匹配：未命中时进行子串匹配并按版本排序选择最新模型")
 	}
 
+	clientProfiles, err := util.ParseClientProfiles(configService.GetString("client_profiles", ""))
+	if err != nil {
+		log.Printf("[WARN] 无效的 client_profiles 配置，已仅使用内置profile: %v", err)
+		clientProfiles, _ = util.ParseClientProfiles("")
+	}
+
+	geoRegions, err := util.ParseGeoRegions(configService.GetString("geo_regions", ""))
+	if err != nil {
+		log.Printf("[WARN] 无效的 geo_regions 配置，已禁用地域路由: %v", err)
+	} else if geoRegions != nil {
+		log.Printf("[INFO] 已启用地域路由：地域 %v 的客户端优先使用带相同标签的渠道", geoRegions.Regions())
+	}
+
 	// 最大并发数保留环境变量读取（启动参数，不支持Web管理）
 	maxConcurrency := config.DefaultMaxConcurrency
 	if concEnv := os.Getenv("CCLOAD_MAX_CONCURRENCY"); concEnv != "" {
@@ -147,6 +272,13 @@ func NewServer(store storage.Store) *Server {
 	transport := buildHTTPTransport(skipTLSVerify)
 	log.Print("[INFO] HTTP/2已启用（头部压缩+多路复用，HTTPS自动协商）")
 
+	// 上游交互录制/回放（仅环境变量，用于CI全链路测试与离线演示，2026-10新增）
+	var upstreamTransport http.RoundTripper = transport
+	vcr := newVCRTransportFromEnv(transport)
+	if vcr != nil {
+		upstreamTransport = vcr
+	}
+
 	s := &Server{
 		store:            store,
 		configService:    configService,
@@ -159,17 +291,23 @@ func NewServer(store storage.Store) *Server {
 		// 模型匹配配置（启动时加载，修改后重启生效）
 		modelLookupStripDateSuffix: modelLookupStripDateSuffix,
 		modelFuzzyMatch:            modelFuzzyMatch,
+		clientProfiles:             clientProfiles,
+		geoRegions:                 geoRegions,
 
 		// HTTP客户端
 		client: &http.Client{
-			Transport: transport,
+			Transport: upstreamTransport,
 			Timeout:   0, // 不设置全局超时，避免中断长时间任务
 		},
 
+		vcr: vcr,
+
 		// 并发控制：使用信号量限制最大并发请求数
 		concurrencySem: make(chan struct{}, maxConcurrency),
 		maxConcurrency: maxConcurrency,
 
+		modelConcurrency: newModelConcurrencyTracker(),
+
 		// 初始化优雅关闭机制
 		shutdownCh:   make(chan struct{}),
 		shutdownDone: make(chan struct{}),
@@ -177,15 +315,166 @@ func NewServer(store storage.Store) *Server {
 		// Token统计队列（避免每请求起goroutine）
 		tokenStatsCh: make(chan tokenStatsUpdate, config.DefaultTokenStatsBufferSize),
 
-		activeRequests: newActiveRequestManager(),
+		activeRequests:  newActiveRequestManager(),
+		adminEvents:     newAdminEventBus(),
+		keyQuotas:       newKeyQuotaTracker(),
+		requestCaptures: newRequestCaptureStore(),
+		modelNotFound:   newModelNotFoundTracker(),
+		unmatchedModels: newUnmatchedModelTracker(),
+		requestSizes:    newRequestSizeTracker(),
+		distributions:   newDistributionCollector(),
+		tokenHandoffs:   newTokenHandoffStore(),
+		budgetAlertCh:   make(chan *model.BudgetAlert, budgetAlertQueueSize),
 	}
 
+	// 管理端出站通道（启动时加载，修改后重启生效）
+	adminLaneConcurrency := configService.GetInt("admin_lane_concurrency", defaultAdminLaneConcurrency)
+	if adminLaneConcurrency < 1 {
+		log.Printf("[WARN] 无效的 admin_lane_concurrency=%d（必须 >= 1），已使用默认值 %d", adminLaneConcurrency, defaultAdminLaneConcurrency)
+		adminLaneConcurrency = defaultAdminLaneConcurrency
+	}
+	adminLaneIntervalMs := configService.GetInt("admin_lane_interval_ms", int(defaultAdminLaneInterval/time.Millisecond))
+	if adminLaneIntervalMs < 0 {
+		log.Printf("[WARN] 无效的 admin_lane_interval_ms=%d（必须 >= 0），已使用默认值 %v", adminLaneIntervalMs, defaultAdminLaneInterval)
+		adminLaneIntervalMs = int(defaultAdminLaneInterval / time.Millisecond)
+	}
+	s.adminLane = newBackgroundLane(adminLaneConcurrency, time.Duration(adminLaneIntervalMs)*time.Millisecond, s.productionBusy)
+
+	// 合成探测与状态页（启动时加载，修改后重启生效）
+	if minutes := configService.GetInt("status_probe_interval_minutes", 0); minutes > 0 {
+		s.statusTracker = newStatusTracker(time.Duration(minutes) * time.Minute)
+	}
+	s.statusPagePublic = configService.GetBool("status_page_public", false)
+
+	// 渠道定时健康检查（启动时加载，修改后重启生效）
+	healthCheckMinutes := configService.GetInt("channel_health_check_interval_minutes", 0)
+	if healthCheckMinutes < 0 || healthCheckMinutes > maxChannelHealthIntervalMinutes {
+		log.Printf("[WARN] 无效的 channel_health_check_interval_minutes=%d（必须在 0-%d 之间），定时健康检查保持关闭", healthCheckMinutes, maxChannelHealthIntervalMinutes)
+		healthCheckMinutes = 0
+	}
+	healthFailThreshold := configService.GetInt("channel_health_check_failure_threshold", defaultChannelHealthFailThreshold)
+	if healthFailThreshold < 1 || healthFailThreshold > maxChannelHealthFailThreshold {
+		log.Printf("[WARN] 无效的 channel_health_check_failure_threshold=%d（必须在 1-%d 之间），已使用默认值 %d", healthFailThreshold, maxChannelHealthFailThreshold, defaultChannelHealthFailThreshold)
+		healthFailThreshold = defaultChannelHealthFailThreshold
+	}
+	s.channelHealth = newChannelHealthChecker(time.Duration(healthCheckMinutes)*time.Minute, healthFailThreshold,
+		configService.GetBool("channel_health_check_auto_enable", true))
+
+	// 渠道出站地址不可用时的回退策略（启动时加载，修改后重启生效）
+	s.localAddrFallback = configService.GetBool("local_addr_fallback", false)
+
+	// 错误响应重试提示（启动时加载，修改后重启生效）
+	s.retryHintsEnabled = configService.GetBool("error_retry_hints_enabled", true)
+
+	// 上游瞬断微重试（启动时加载，修改后重启生效）
+	s.microRetryEnabled = configService.GetBool("upstream_micro_retry_enabled", true)
+
+	// JSON模式输出修复（启动时加载，修改后重启生效）
+	s.jsonRepairEnabled = configService.GetBool("json_repair_enabled", false)
+
+	// 会话粘性路由（启动时加载，修改后重启生效）
+	if ttl := configService.GetInt("sticky_session_ttl_seconds", 0); ttl > 0 {
+		s.sessionAffinity = newSessionAffinity(time.Duration(ttl) * time.Second)
+	} else if ttl < 0 {
+		log.Printf("[WARN] 无效的 sticky_session_ttl_seconds=%d（必须 >= 0），会话粘性路由保持关闭", ttl)
+	}
+
+	// 非流式响应缓存（启动时加载，修改后重启生效）
+	if ttl := configService.GetInt("response_cache_ttl_seconds", 0); ttl > 0 && ttl <= maxResponseCacheTTLSeconds {
+		maxEntries := configService.GetInt("response_cache_max_entries", defaultResponseCacheMaxEntries)
+		if maxEntries < 1 || maxEntries > maxResponseCacheMaxEntries {
+			log.Printf("[WARN] 无效的 response_cache_max_entries=%d（必须在 1-%d 之间），已使用默认值 %d", maxEntries, maxResponseCacheMaxEntries, defaultResponseCacheMaxEntries)
+			maxEntries = defaultResponseCacheMaxEntries
+		}
+		s.responseCache = newResponseCache(time.Duration(ttl)*time.Second, maxEntries, configService.GetBool("response_cache_persist", false))
+		s.loadPersistedResponseCache()
+	} else if ttl != 0 {
+		log.Printf("[WARN] 无效的 response_cache_ttl_seconds=%d（必须在 0-%d 之间），响应缓存保持关闭", ttl, maxResponseCacheTTLSeconds)
+	}
+
+	// 失败请求常驻抓取（启动时加载，修改后重启生效）
+	captureBudgetMB := configService.GetInt("error_capture_budget_mb", defaultErrorCaptureBudgetMB)
+	if captureBudgetMB < 0 || captureBudgetMB > maxErrorCaptureBudgetMB {
+		log.Printf("[WARN] 无效的 error_capture_budget_mb=%d（必须在 0-%d 之间），已使用默认值 %d", captureBudgetMB, maxErrorCaptureBudgetMB, defaultErrorCaptureBudgetMB)
+		captureBudgetMB = defaultErrorCaptureBudgetMB
+	}
+	captureRetentionHours := configService.GetInt("error_capture_retention_hours", defaultErrorCaptureRetentionHours)
+	if captureRetentionHours < 1 || captureRetentionHours > maxErrorCaptureRetentionHours {
+		log.Printf("[WARN] 无效的 error_capture_retention_hours=%d（必须在 1-%d 之间），已使用默认值 %d", captureRetentionHours, maxErrorCaptureRetentionHours, defaultErrorCaptureRetentionHours)
+		captureRetentionHours = defaultErrorCaptureRetentionHours
+	}
+	s.errorCaptures = newErrorCaptureStore(int64(captureBudgetMB)<<20, time.Duration(captureRetentionHours)*time.Hour)
+
+	// 全局模型屏蔽列表（修改后经设置热更新立即生效）
+	s.setBlockedModels(configService.GetString("blocked_models", ""))
+	s.setCacheCostMultipliers(configService.GetString("cost_cache_multipliers", ""))
+	s.setResponseLabelRules(configService.GetString("response_label_rules", ""))
+	s.displayCurrency = newDisplayCurrency()
+	s.setDisplayCurrency(configService.GetString("display_currency", ""))
+	s.setExchangeRate(configService.GetString("exchange_rate", "0"))
+	s.setExchangeRateURL(configService.GetString("exchange_rate_url", ""))
+
+	// 预算软告警阈值（启动时加载，修改后重启生效）
+	budgetThresholds, err := parseBudgetAlertThresholds(configService.GetString("budget_alert_thresholds", defaultBudgetAlertThresholds))
+	if err != nil {
+		log.Printf("[WARN] 无效的 budget_alert_thresholds（%v），已使用默认值 %s", err, defaultBudgetAlertThresholds)
+		budgetThresholds, _ = parseBudgetAlertThresholds(defaultBudgetAlertThresholds)
+	}
+	s.budgetAlertThresholds = budgetThresholds
+
+	// 流式响应缓冲窗口（启动时加载，修改后重启生效）
+	s.responseBufferBytes = configService.GetInt("response_buffer_bytes", defaultResponseBufferBytes)
+	if s.responseBufferBytes < 0 || s.responseBufferBytes > maxResponseBufferBytes {
+		log.Printf("[WARN] 无效的 response_buffer_bytes=%d（必须在 0-%d 之间），已使用默认值 %d", s.responseBufferBytes, maxResponseBufferBytes, defaultResponseBufferBytes)
+		s.responseBufferBytes = defaultResponseBufferBytes
+	}
+
+	// 输出Token异常检测（启动时加载，修改后重启生效）
+	anomalyMultiplier := configService.GetInt("token_anomaly_multiplier", defaultTokenAnomalyMultiplier)
+	if anomalyMultiplier < 0 {
+		log.Printf("[WARN] 无效的 token_anomaly_multiplier=%d（必须 >= 0），已使用默认值 %d", anomalyMultiplier, defaultTokenAnomalyMultiplier)
+		anomalyMultiplier = defaultTokenAnomalyMultiplier
+	}
+	anomalyMinOutput := configService.GetInt("token_anomaly_min_output_tokens", defaultTokenAnomalyMinOutputTokens)
+	if anomalyMinOutput < 0 {
+		log.Printf("[WARN] 无效的 token_anomaly_min_output_tokens=%d（必须 >= 0），已使用默认值 %d", anomalyMinOutput, defaultTokenAnomalyMinOutputTokens)
+		anomalyMinOutput = defaultTokenAnomalyMinOutputTokens
+	}
+	anomalyCap := configService.GetInt("token_anomaly_cap_tokens", 0)
+	if anomalyCap < 0 {
+		log.Printf("[WARN] 无效的 token_anomaly_cap_tokens=%d（必须 >= 0），已禁用异常限流", anomalyCap)
+		anomalyCap = 0
+	}
+	s.tokenAnomaly = newTokenAnomalyDetector(anomalyMultiplier, int64(anomalyMinOutput), anomalyCap)
+	s.tokenEstimates = newTokenEstimateTracker()
+
+	// Token估算漂移告警（启动时加载，修改后重启生效）
+	driftThreshold := configService.GetInt("token_drift_threshold_pct", defaultTokenDriftThresholdPct)
+	if driftThreshold < 0 {
+		log.Printf("[WARN] 无效的 token_drift_threshold_pct=%d（必须 >= 0），已使用默认值 %d", driftThreshold, defaultTokenDriftThresholdPct)
+		driftThreshold = defaultTokenDriftThresholdPct
+	}
+	driftWindow := configService.GetInt("token_drift_window_minutes", defaultTokenDriftWindowMinutes)
+	if driftWindow < 1 || driftWindow > maxTokenDriftWindowMinutes {
+		log.Printf("[WARN] 无效的 token_drift_window_minutes=%d（必须在 1-%d 之间），已使用默认值 %d", driftWindow, maxTokenDriftWindowMinutes, defaultTokenDriftWindowMinutes)
+		driftWindow = defaultTokenDriftWindowMinutes
+	}
+	s.tokenDrift = newTokenDriftTracker(driftThreshold, driftWindow)
+	s.countTokens = newCountTokensCoalescer()
+
 	// 初始化高性能缓存层（60秒TTL，避免数据库性能杀手查询）
 	s.channelCache = storage.NewChannelCache(store, 60*time.Second)
 
 	// 初始化冷却管理器（统一管理渠道级和Key级冷却）
 	// 传入Server作为configGetter，利用缓存层查询渠道配置
 	s.cooldownManager = cooldown.NewManager(store, s)
+	// 账号分组冷却同步到其他渠道后失效这些渠道的Key缓存（2026-10新增）
+	s.cooldownManager.SetAccountGroupHook(func(channelIDs []int64) {
+		for _, id := range channelIDs {
+			s.InvalidateAPIKeysCache(id)
+		}
+		s.invalidateCooldownCache()
+	})
 
 	// 初始化Key选择器（移除store依赖，避免重复查询）
 	s.keySelector = NewKeySelector()
@@ -193,6 +482,32 @@ func NewServer(store storage.Store) *Server {
 	// 初始化渠道负载均衡器（平滑加权轮询，确定性分流）
 	s.channelBalancer = NewSmoothWeightedRR()
 
+	// 负载均衡模式（启动时加载，修改后重启生效）
+	balanceMode, ok := parseChannelBalanceMode(configService.GetString("channel_balance_mode", channelBalanceSmooth))
+	if !ok {
+		log.Printf("[WARN] 无效的 channel_balance_mode（必须为 smooth 或 random），已使用默认值 %s", channelBalanceSmooth)
+	}
+	s.channelBalanceMode = balanceMode
+
+	// 密钥脱敏：注入全部渠道Key，日志与管理接口错误信息中出现时精确替换
+	s.refreshKnownSecrets()
+
+	// 分时路由规则（加载失败时不调整路由）
+	s.routingSchedules = newRoutingScheduler()
+	if err := s.reloadRoutingSchedules(context.Background()); err != nil {
+		log.Printf("[WARN] 加载分时路由规则失败: %v", err)
+	}
+
+	// 出站Webhook端点（加载失败时不投递）
+	s.webhooks = newWebhookDispatcher()
+	if err := s.reloadWebhooks(context.Background()); err != nil {
+		log.Printf("[WARN] 加载Webhook端点失败: %v", err)
+	}
+
+	// Vertex AI 渠道凭据（无效凭据跳过，对应渠道按普通 Gemini 渠道转发）
+	s.vertex = newVertexRegistry()
+	s.loadVertexCredentials(context.Background())
+
 	// 初始化健康度缓存（启动时读取配置，修改后重启生效）
 	defaultHealthCfg := model.DefaultHealthScoreConfig()
 	successRatePenaltyWeight := configService.GetInt("success_rate_penalty_weight", defaultHealthCfg.SuccessRatePenaltyWeight)
@@ -228,6 +543,14 @@ func NewServer(store storage.Store) *Server {
 		log.Print("[INFO] 健康度排序已启用（基于成功率动态调整渠道优先级；冷却仍按原规则过滤）")
 	}
 
+	// 打开请求用量预写日志，重放上次崩溃时未落库的日志（须在加载当日成本之前）
+	s.journal = initRequestJournal()
+	if s.journal != nil {
+		replayCtx, replayCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		s.replayJournalLogs(replayCtx)
+		replayCancel()
+	}
+
 	// 初始化成本缓存（启动时从数据库加载当日成本）
 	s.costCache = NewCostCache()
 	costLoadCtx, costCancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -239,6 +562,7 @@ func NewServer(store storage.Store) *Server {
 		s.costCache.Load(todayCosts)
 		log.Printf("[INFO] 已加载今日渠道成本缓存（%d个渠道有消耗）", len(todayCosts))
 	}
+	s.loadMonthlyBudgets(costLoadCtx)
 
 	// ============================================================================
 	// 创建服务层（仅保留有价值的服务）
@@ -254,6 +578,14 @@ func NewServer(store storage.Store) *Server {
 		&s.isShuttingDown,
 		&s.wg,
 	)
+	s.logService.journal = s.journal
+	s.logService.cleanup = logCleanupConfig{
+		batchSize:     configService.GetInt("log_cleanup_batch_size", defaultLogCleanupBatchSize),
+		batchSleep:    time.Duration(configService.GetInt("log_cleanup_batch_sleep_ms", defaultLogCleanupBatchSleepMs)) * time.Millisecond,
+		analyzeEvery:  time.Duration(configService.GetInt("log_db_analyze_hours", 24)) * time.Hour,
+		vacuumEvery:   time.Duration(configService.GetInt("log_db_vacuum_hours", 0)) * time.Hour,
+		checkInterval: config.LogCleanupInterval,
+	}
 	// 启动日志 Workers
 	s.logService.StartWorkers()
 
@@ -262,6 +594,15 @@ func NewServer(store storage.Store) *Server {
 		s.logService.StartCleanupLoop()
 	}
 
+	// 数据库完整性检查与自动备份（仅SQLite文件库；启动时加载，修改后重启生效）
+	s.dbBackup = s.newDBBackupFromConfig(configService)
+
+	// 数据库连续复制（S3/热备实例，仅SQLite文件库；启动时加载，修改后重启生效）
+	s.dbReplication = s.newDBReplicationFromConfig(configService)
+
+	// 代理链路追踪（启动时加载，修改后重启生效）
+	s.tracer = newTracerFromConfig(configService)
+
 	// 2. AuthService（负责认证授权）
 	// 初始化时自动从数据库加载API访问令牌
 	s.authService = NewAuthService(
@@ -269,11 +610,65 @@ func NewServer(store storage.Store) *Server {
 		s.loginRateLimiter,
 		store, // 传入store用于热更新令牌
 	)
+	// 可选：管理界面 SSO 登录（CCLOAD_OIDC_* 环境变量，密码登录保持可用）
+	s.authService.oidc = loadOIDCProvider()
+	s.syncPeers = syncPeersFromEnv()
+
+	// 重放未落库的Token统计（须在 AuthService 加载费用缓存之后）
+	if s.journal != nil {
+		s.replayJournalStats()
+	}
 
 	// 启动Token统计Worker（有界队列：性能可控，Shutdown可等待）
 	s.wg.Add(1)
 	go s.tokenStatsWorker()
 
+	// 启动预算告警Worker
+	s.wg.Add(1)
+	go s.budgetAlertWorker()
+
+	// 启动Webhook投递Worker
+	s.wg.Add(1)
+	go s.webhookWorker()
+
+	// 启动Token估算采样Worker
+	s.wg.Add(1)
+	go s.tokenEstimateWorker()
+
+	// 启动Token估算漂移Worker
+	if s.tokenDrift.enabled() {
+		s.wg.Add(1)
+		go s.tokenDriftWorker()
+	}
+
+	// 启动Gemini Key自动供给调度
+	s.wg.Add(1)
+	go s.geminiProvisionLoop()
+
+	// 启动提示缓存预热调度
+	s.wg.Add(1)
+	go s.cacheWarmupLoop()
+
+	// 启动展示币种汇率刷新
+	s.wg.Add(1)
+	go s.exchangeRateLoop()
+
+	// 启动 Vertex AI 访问令牌续期
+	s.wg.Add(1)
+	go s.vertexRefreshLoop()
+
+	// 启动状态页合成探测
+	if s.statusTracker != nil {
+		s.wg.Add(1)
+		go s.statusProbeLoop()
+	}
+
+	// 启动渠道定时健康检查
+	if s.channelHealth.interval > 0 {
+		s.wg.Add(1)
+		go s.channelHealthLoop()
+	}
+
 	// 启动后台清理协程（Token 认证）
 	s.wg.Add(1)
 	go s.tokenCleanupLoop() // 定期清理过期Token
@@ -282,6 +677,36 @@ func NewServer(store storage.Store) *Server {
 	s.wg.Add(1)
 	go s.stateCleanupLoop()
 
+	// 启动管理端事件流的进行中请求快照推送
+	s.wg.Add(1)
+	go s.adminEventsSnapshotLoop()
+
+	// 启动数据库检查与备份
+	if s.dbBackup.supported() {
+		s.wg.Add(1)
+		go s.dbBackupLoop()
+	}
+
+	// 启动数据库连续复制
+	if s.dbReplication.shipping() {
+		s.wg.Add(1)
+		go s.dbReplicationLoop()
+	}
+
+	// 启动链路追踪导出
+	if s.tracer != nil {
+		s.wg.Add(1)
+		go s.traceExportLoop()
+	}
+
+	// 加载模型价格表，随后继续上次服务关闭时中断的费用重算任务（重算依赖价格表）
+	resumeCtx, resumeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := s.reloadModelPrices(resumeCtx); err != nil {
+		log.Printf("[WARN] 加载模型价格表失败，使用内置定价: %v", err)
+	}
+	s.resumeCostRecomputeJobs(resumeCtx)
+	resumeCancel()
+
 	return s
 
 }
@@ -299,15 +724,7 @@ func (s *Server) getChannelCache() *storage.ChannelCache {
 // 参数:
 //   - skipTLSVerify: 是否跳过TLS证书验证
 func buildHTTPTransport(skipTLSVerify bool) *http.Transport {
-	dialer := &net.Dialer{
-		Timeout:   config.HTTPDialTimeout,
-		KeepAlive: config.HTTPKeepAliveInterval,
-		Control: func(_, _ string, c syscall.RawConn) error {
-			return c.Control(func(fd uintptr) {
-				_ = setTCPNoDelay(fd)
-			})
-		},
-	}
+	dialer := newUpstreamDialer()
 
 	transport := &http.Transport{
 		Proxy:               http.ProxyFromEnvironment, // 支持 HTTPS_PROXY/HTTP_PROXY/NO_PROXY
@@ -330,9 +747,146 @@ func buildHTTPTransport(skipTLSVerify bool) *http.Transport {
 	return transport // HTTP/2 已通过 ForceAttemptHTTP2 启用
 }
 
+// newUpstreamDialer 上游连接拨号器（TCP_NODELAY + KeepAlive）
+func newUpstreamDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   config.HTTPDialTimeout,
+		KeepAlive: config.HTTPKeepAliveInterval,
+		Control: func(_, _ string, c syscall.RawConn) error {
+			return c.Control(func(fd uintptr) {
+				_ = setTCPNoDelay(fd)
+			})
+		},
+	}
+}
+
 // NOTE: 这些缓存fallback函数存在重复逻辑，可使用泛型重构（Go 1.18+）
 // 当前设计选择：保持简单直接，避免过度抽象（YAGNI）
 
+// lookupClientProfile 按名称解析客户端profile（内置 + client_profiles 覆盖；代理流量与渠道测试共用）
+// profile不存在时返回nil
+func (s *Server) lookupClientProfile(name string) util.ClientProfile {
+	return s.clientProfiles[name]
+}
+
+// clientProfile 返回渠道选择的客户端profile（未配置或profile不存在时返回false）
+func (s *Server) clientProfile(cfg *model.Config) (util.ClientProfile, bool) {
+	if cfg == nil || cfg.ClientProfile == "" {
+		return nil, false
+	}
+	profile := s.lookupClientProfile(cfg.ClientProfile)
+	return profile, profile != nil
+}
+
+// httpClientFor 返回渠道使用的HTTP客户端
+// 未配置证书固定/出站地址的渠道共用 s.client；配置了 cert_pins 或 local_addr 的渠道使用独立连接池的专用客户端，
+// 避免复用其他渠道已建立（未经指纹校验、或从其他出口建立）的连接。
+// 启用录制/回放时专用客户端同样经由磁带（录制模式下证书固定/出站地址照常生效）
+func (s *Server) httpClientFor(cfg *model.Config) *http.Client {
+	if isMockChannel(cfg) {
+		// 模拟渠道按URL参数各自持有进程内Transport（录制回放模式下同样不访问外部服务）
+		cacheKey := "mock|" + cfg.URL
+		if cached, ok := s.channelClients.Load(cacheKey); ok {
+			return cached.(*http.Client)
+		}
+		actual, _ := s.channelClients.LoadOrStore(cacheKey, newMockHTTPClient(cfg))
+		return actual.(*http.Client)
+	}
+	if cfg == nil || (cfg.CertPins == "" && cfg.LocalAddr == "") {
+		return s.client
+	}
+	cacheKey := cfg.CertPins + "|" + cfg.LocalAddr
+	if cached, ok := s.channelClients.Load(cacheKey); ok {
+		return cached.(*http.Client)
+	}
+
+	actual, _ := s.channelClients.LoadOrStore(cacheKey, s.newChannelHTTPClient(cfg))
+	return actual.(*http.Client)
+}
+
+// newChannelHTTPClient 构造应用渠道证书固定/出站地址的独立客户端（不缓存，渠道校验等一次性场景直接使用）
+func (s *Server) newChannelHTTPClient(cfg *model.Config) *http.Client {
+	var transport *http.Transport
+	var timeout time.Duration
+	var vcr *util.VCRTransport
+	if s.client != nil {
+		timeout = s.client.Timeout
+		base := s.client.Transport
+		if v, ok := base.(*util.VCRTransport); ok {
+			vcr, base = v, v.Inner()
+		}
+		if base, ok := base.(*http.Transport); ok {
+			transport = base.Clone()
+		}
+	}
+	if transport == nil {
+		transport = buildHTTPTransport(false)
+	}
+
+	if cfg.CertPins != "" {
+		pins, err := util.ParseCertPins(cfg.CertPins)
+		if err != nil || len(pins) == 0 {
+			// 写入时已校验，理论不可达；拒绝降级为不固定，构造必然失败的校验器
+			log.Printf("[ERROR] 渠道ID=%d 证书指纹配置无效，拒绝连接: %v", cfg.ID, err)
+			pins = nil
+		}
+		transport.TLSClientConfig.VerifyConnection = util.CertPinVerifier(pins)
+	}
+	if cfg.LocalAddr != "" {
+		transport.DialContext = localAddrDialContext(newUpstreamDialer(), cfg.LocalAddr, s.localAddrFallback)
+	}
+
+	if vcr != nil {
+		return &http.Client{Transport: vcr.WithInner(transport), Timeout: timeout}
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// newVCRTransportFromEnv 根据 CCLOAD_VCR_MODE（record/replay）与 CCLOAD_VCR_CASSETTE（磁带文件路径）创建录制/回放Transport
+// 未设置 CCLOAD_VCR_MODE 时返回nil；配置无效时记录错误并禁用（不影响正常转发）
+func newVCRTransportFromEnv(inner http.RoundTripper) *util.VCRTransport {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("CCLOAD_VCR_MODE")))
+	if mode == "" {
+		return nil
+	}
+	vcr, err := util.NewVCRTransport(mode, os.Getenv("CCLOAD_VCR_CASSETTE"), inner)
+	if err != nil {
+		log.Printf("[ERROR] 上游录制/回放配置无效，已禁用: %v", err)
+		return nil
+	}
+	if mode == util.VCRModeReplay {
+		log.Printf("[WARN] 已启用上游回放模式：不会访问真实上游，响应来自磁带 %s（%d 条交互）", os.Getenv("CCLOAD_VCR_CASSETTE"), vcr.Interactions())
+	} else {
+		log.Printf("[WARN] 已启用上游录制模式：上游交互将写入磁带 %s", os.Getenv("CCLOAD_VCR_CASSETTE"))
+	}
+	return vcr
+}
+
+// localAddrDialContext 返回绑定出站地址的拨号函数
+// 每次拨号重新解析 local_addr（网卡地址可能变化）；地址不可用时：
+// fallback=false 返回 util.ErrLocalAddrUnavailable（渠道级失败，切换其他渠道），
+// fallback=true 记录告警后改走默认路由
+func localAddrDialContext(dialer *net.Dialer, localAddr string, fallback bool) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		laddr, err := util.ResolveLocalAddr(localAddr)
+		if err == nil {
+			bound := *dialer
+			bound.LocalAddr = laddr
+			conn, dialErr := bound.DialContext(ctx, network, addr)
+			if dialErr == nil || !errors.Is(dialErr, syscall.EADDRNOTAVAIL) {
+				return conn, dialErr
+			}
+			// 解析与bind之间地址被撤销
+			err = fmt.Errorf("%w: %v", util.ErrLocalAddrUnavailable, dialErr)
+		}
+		if !fallback {
+			return nil, err
+		}
+		log.Printf("[WARN] 出站地址 %s 不可用，已改走默认路由: %v", localAddr, err)
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
 // GetConfig 获取渠道配置（实现cooldown.ConfigGetter接口）
 func (s *Server) GetConfig(ctx context.Context, channelID int64) (*model.Config, error) {
 	if cache := s.getChannelCache(); cache != nil {
@@ -414,6 +968,29 @@ func (s *Server) InvalidateAllAPIKeysCache() {
 	if cache := s.getChannelCache(); cache != nil {
 		cache.InvalidateAllAPIKeysCache()
 	}
+	s.refreshKnownSecrets()
+}
+
+// refreshKnownSecrets 重新注入脱敏用的已知密钥（读取失败时保留旧集合）
+// 只在Key新增/变更后调用；请求热路径上的缓存失效不触发（已删除的Key留在集合中无害）
+func (s *Server) refreshKnownSecrets() {
+	if s.store == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	allKeys, err := s.store.GetAllAPIKeys(ctx)
+	if err != nil {
+		log.Printf("[WARN] 加载脱敏密钥集合失败: %v", err)
+		return
+	}
+	secrets := make([]string, 0, len(allKeys))
+	for _, keys := range allKeys {
+		for _, k := range keys {
+			secrets = append(secrets, k.APIKey)
+		}
+	}
+	util.SetKnownSecrets(secrets)
 }
 
 func (s *Server) invalidateCooldownCache() {
@@ -465,15 +1042,33 @@ func (s *Server) SetupRoutes(r *gin.Engine) {
 	{
 		public.GET("/summary", s.HandlePublicSummary)
 		public.GET("/channel-types", s.HandleGetChannelTypes)
+		public.GET("/beta-features", s.HandleGetBetaFeatures) // 上游Beta功能注册表（2026-10新增）
 		public.GET("/version", s.HandlePublicVersion)
+		public.POST("/token-handoff/:id", s.HandleConsumeTokenHandoff) // 令牌一次性取回（单次有效）
+	}
+
+	// 服务状态页数据（status_page_public=false 时需API令牌认证，页面见 /web/status.html）
+	if s.statusPagePublic {
+		r.GET("/status", s.HandleStatusPage)
+	} else {
+		r.GET("/status", s.authService.RequireAPIAuth(), s.HandleStatusPage)
 	}
 
+	// 令牌自省（令牌持有者查询自身状态，需API令牌认证）
+	r.GET("/api/token/introspect", s.authService.RequireAPIAuth(), s.HandleIntrospectSelf)
+
+	// 令牌自助日志查询（只返回调用令牌自身的脱敏日志，需API令牌认证，2026-10新增）
+	r.GET("/portal/logs", s.authService.RequireAPIAuth(), s.HandlePortalLogs)
+
 	// 事件日志（公开访问，兼容性占位接口）
 	r.POST("/api/event_logging/batch", s.HandleEventLoggingBatch)
 
 	// 登录相关（公开访问）
 	r.POST("/login", s.authService.HandleLogin)
 	r.POST("/logout", s.authService.HandleLogout)
+	r.GET("/login/oidc/status", s.authService.HandleOIDCStatus)
+	r.GET("/login/oidc", s.authService.HandleOIDCLogin)
+	r.GET("/login/oidc/callback", s.authService.HandleOIDCCallback)
 
 	// 需要身份验证的admin APIs（使用Token认证）
 	admin := r.Group("/admin")
@@ -485,32 +1080,147 @@ func (s *Server) SetupRoutes(r *gin.Engine) {
 		admin.GET("/channels/export", s.HandleExportChannelsCSV)
 		admin.POST("/channels/import", s.HandleImportChannelsCSV)
 		admin.POST("/channels/batch-priority", s.HandleBatchUpdatePriority) // 批量更新渠道优先级
+		admin.POST("/channels/validate", s.HandleValidateChannel)           // 校验渠道配置（不保存，可选连通性检查）
+		admin.GET("/channels/duplicate-keys", s.HandleDuplicateKeys)        // 跨渠道重复Key报告（2026-10新增）
 		admin.GET("/channels/:id", s.HandleChannelByID)
 		admin.PUT("/channels/:id", s.HandleChannelByID)
 		admin.DELETE("/channels/:id", s.HandleChannelByID)
 		admin.GET("/channels/:id/keys", s.HandleChannelKeys)
-		admin.POST("/channels/models/fetch", s.HandleFetchModelsPreview) // 临时渠道配置获取模型列表
-		admin.GET("/channels/:id/models/fetch", s.HandleFetchModels)     // 获取渠道可用模型列表(新增)
-		admin.POST("/channels/:id/models", s.HandleAddModels)            // 添加渠道模型
-		admin.DELETE("/channels/:id/models", s.HandleDeleteModels)       // 删除渠道模型
+		admin.GET("/channels/:id/keys/report", s.HandleChannelKeyReport)               // Key历史表现对比报表（2026-10新增）
+		admin.POST("/channels/:id/clone", s.HandleCloneChannel)                        // 克隆渠道配置（不含Key）
+		admin.POST("/channels/models/fetch", s.HandleFetchModelsPreview)               // 临时渠道配置获取模型列表
+		admin.GET("/channels/:id/models/fetch", s.HandleFetchModels)                   // 获取渠道可用模型列表(新增)
+		admin.GET("/channels/:id/models/preview", s.HandlePreviewModelMatches)         // 预览模型(含通配符)匹配到的请求模型
+		admin.POST("/channels/:id/models", s.HandleAddModels)                          // 添加渠道模型
+		admin.DELETE("/channels/:id/models", s.HandleDeleteModels)                     // 删除渠道模型
+		admin.GET("/channels/:id/suggestions", s.HandleChannelRedirectSuggestions)     // 模型重定向建议（2026-10新增）
+		admin.POST("/channels/:id/suggestions/apply", s.HandleApplyRedirectSuggestion) // 一键应用重定向建议
 		admin.POST("/channels/:id/test", s.HandleChannelTest)
+		admin.POST("/channels/:id/test-all-keys", s.HandleChannelTestAllKeys) // 并发测试所有Key（支持SSE进度）
 		admin.POST("/channels/:id/cooldown", s.HandleSetChannelCooldown)
+		admin.GET("/channels/:id/captures", s.HandleChannelCaptures)         // 上游请求抓取记录（2026-10新增）
+		admin.POST("/channels/:id/captures", s.HandleSetChannelCapture)      // 开启/停止请求抓取
+		admin.DELETE("/channels/:id/captures", s.HandleClearChannelCaptures) // 清空抓取记录
+		admin.POST("/channels/:id/health-check", s.HandleCheckChannelHealth) // 立即健康检查（2026-10新增）
+		admin.GET("/channel-health-checks", s.HandleListChannelHealthChecks) // 渠道健康检查状态
+		admin.GET("/captures/errors", s.HandleErrorCaptures)                 // 失败请求常驻抓取记录（2026-10新增）
+		admin.DELETE("/captures/errors", s.HandleClearErrorCaptures)         // 清空失败请求抓取记录
+		admin.GET("/monitor/traces/diff", s.HandleCaptureDiff)               // 对比两条抓取记录（2026-10新增）
+		admin.GET("/channels/:id/rewrites", s.HandleGetChannelRewrites)      // 请求体改写规则（2026-10新增）
+		admin.PUT("/channels/:id/rewrites", s.HandleSetChannelRewrites)
+		admin.DELETE("/channels/:id/rewrites", s.HandleDeleteChannelRewrites)
+		admin.GET("/channels/:id/vertex", s.HandleGetChannelVertex) // Vertex AI 服务账号凭据（2026-10新增）
+		admin.PUT("/channels/:id/vertex", s.HandleSetChannelVertex)
+		admin.DELETE("/channels/:id/vertex", s.HandleDeleteChannelVertex)
+		admin.GET("/channels/:id/concurrency", s.HandleGetChannelConcurrency) // 按模型并发上限（2026-10新增）
+		admin.PUT("/channels/:id/concurrency", s.HandleSetChannelConcurrency)
+		admin.DELETE("/channels/:id/concurrency", s.HandleDeleteChannelConcurrency)
+		admin.GET("/concurrency", s.HandleGetModelConcurrency)
 		admin.POST("/channels/:id/keys/:keyIndex/cooldown", s.HandleSetKeyCooldown)
 		admin.DELETE("/channels/:id/keys/:keyIndex", s.HandleDeleteAPIKey)
+		admin.PUT("/channels/:id/keys/:keyIndex/account-group", s.HandleSetKeyAccountGroup) // 设置Key上游账号分组（2026-10新增）
+
+		// Gemini Key自动供给（Google Cloud服务账号）
+		admin.GET("/webhooks", s.HandleListWebhooks)
+		admin.POST("/webhooks", s.HandleCreateWebhook)
+		admin.PUT("/webhooks/:id", s.HandleUpdateWebhook)
+		admin.DELETE("/webhooks/:id", s.HandleDeleteWebhook)
+		admin.POST("/webhooks/:id/test", s.HandleTestWebhook) // 同步投递一次 ping 事件
+		admin.GET("/gemini-provisioners", s.HandleListGeminiProvisioners)
+		admin.POST("/gemini-provisioners", s.HandleCreateGeminiProvisioner)
+		admin.PUT("/gemini-provisioners/:id", s.HandleUpdateGeminiProvisioner)
+		admin.DELETE("/gemini-provisioners/:id", s.HandleDeleteGeminiProvisioner)
+		admin.POST("/gemini-provisioners/:id/run", s.HandleRunGeminiProvisioner)        // 立即执行一次供给/轮换
+		admin.GET("/gemini-provisioners/:id/remote-keys", s.HandleListGeminiRemoteKeys) // 项目中的全部Key
+
+		// 全局模型别名（2026-10新增）
+		admin.GET("/model-aliases", s.HandleListModelAliases)
+		admin.POST("/model-aliases", s.HandleCreateModelAlias)
+		admin.PUT("/model-aliases/:id", s.HandleUpdateModelAlias)
+		admin.DELETE("/model-aliases/:id", s.HandleDeleteModelAlias)
+
+		// 提示缓存预热计划（2026-10新增）
+		admin.GET("/cache-warmups", s.HandleListCacheWarmups)
+		admin.POST("/cache-warmups", s.HandleCreateCacheWarmup)
+		admin.PUT("/cache-warmups/:id", s.HandleUpdateCacheWarmup)
+		admin.DELETE("/cache-warmups/:id", s.HandleDeleteCacheWarmup)
+		admin.POST("/cache-warmups/:id/run", s.HandleRunCacheWarmup) // 立即预热一次
 
 		// 统计分析
 		admin.GET("/logs", s.HandleErrors)
-		admin.GET("/active-requests", s.HandleActiveRequests) // 进行中请求（内存状态）
+		admin.GET("/logs/cleanup", s.HandleLogCleanupStatus) // 日志清理进度（分批限速）
+		admin.POST("/logs/cleanup", s.HandleTriggerLogCleanup)
+		admin.GET("/active-requests", s.HandleActiveRequests)             // 进行中请求（内存状态）
+		admin.DELETE("/logs/active/:id", s.HandleCancelActiveRequest)     // 取消进行中请求（2026-10新增）
+		admin.DELETE("/active-requests/:id", s.HandleCancelActiveRequest) // 同上（别名）
+		admin.GET("/events", s.HandleAdminEvents)                         // 统一事件流（SSE，2026-10新增）
 		admin.GET("/metrics", s.HandleMetrics)
+		admin.GET("/metrics/distributions", s.HandleMetricsDistributions) // Token/请求体大小分布（内存统计）
+		admin.GET("/metrics/heatmap", s.HandleMetricsHeatmap)             // 星期×小时延迟热力图（2026-10新增）
+		admin.GET("/metrics/token-drift", s.HandleTokenDriftMetrics)      // Token估算漂移序列（内存统计）
+
+		// 数据库备份与恢复（2026-10新增）
+		admin.GET("/backup", s.HandleDBBackupStatus)
+		admin.POST("/backup/now", s.HandleDBBackupNow)
+		admin.POST("/backup/restore", s.HandleDBRestore)
+		admin.DELETE("/backup/restore", s.HandleCancelDBRestore)
+		admin.GET("/replica", s.HandleDBReplicationStatus)
+		admin.POST("/replica/promote", s.HandleDBReplicationPromote)
+
+		// 实例间差异同步（2026-10新增）
+		admin.GET("/sync/manifest", s.HandleSyncManifest)
+		admin.POST("/sync/items", s.HandleSyncItems)
+		admin.POST("/sync/apply", s.HandleSyncApply)
+		admin.POST("/sync/pull", s.HandleSyncPull)
+		admin.POST("/sync/push", s.HandleSyncPush)
 		admin.GET("/stats", s.HandleStats)
+		admin.GET("/stats/owners", s.HandleOwnerStats)         // 按令牌归属方汇总（成本分摊）
+		admin.GET("/stats/errors", s.HandleErrorClassStats)    // 按错误归类汇总（2026-10新增）
+		admin.GET("/stats/labels", s.HandleResponseLabelStats) // 按响应标签汇总（2026-10新增）
+		admin.GET("/stats/warmups", s.HandleCacheWarmupStats)  // 提示缓存预热效果（2026-10新增）
 		admin.GET("/cooldown/stats", s.HandleCooldownStats)
+		admin.GET("/token-anomalies", s.HandleTokenAnomalies)   // 输出Token异常日报
+		admin.GET("/token-estimators", s.HandleTokenEstimators) // Token估算引擎误差对比
+		admin.GET("/alerts", s.HandleListBudgetAlerts)          // 预算软告警
+		admin.POST("/alerts/:id/ack", s.HandleAckBudgetAlert)
+		admin.GET("/budgets", s.HandleListMonthlyBudgets) // 月度预算（2026-10新增）
+		admin.POST("/budgets", s.HandleCreateMonthlyBudget)
+		admin.PUT("/budgets/:id", s.HandleUpdateMonthlyBudget)
+		admin.DELETE("/budgets/:id", s.HandleDeleteMonthlyBudget)
+		admin.GET("/response-cache", s.HandleResponseCacheStats)            // 非流式响应缓存计数（2026-10新增）
+		admin.DELETE("/response-cache", s.HandleInvalidateResponseCache)    // 失效响应缓存
+		admin.GET("/cache/entries", s.HandleCacheEntries)                   // 缓存内容与新鲜度（2026-10新增）
+		admin.DELETE("/cache", s.HandleInvalidateCache)                     // 按类型失效缓存
+		admin.DELETE("/cache/channels/:id", s.HandleInvalidateChannelCache) // 失效单个渠道相关缓存
+		admin.GET("/model-flags", s.HandleListModelFlags)                   // 渠道模型不存在标记
+		admin.DELETE("/model-flags", s.HandleClearModelFlags)
+		admin.GET("/unmatched-models", s.HandleListUnmatchedModels) // 未匹配任何渠道的请求模型计数
+		admin.DELETE("/unmatched-models", s.HandleClearUnmatchedModels)
+		admin.GET("/request-size-limits", s.HandleRequestSizeLimits) // 渠道请求体大小上限观测值（2026-10新增）
+		admin.GET("/pricing", s.HandleListModelPrices)               // 模型价格表（2026-10新增）
+		admin.POST("/pricing", s.HandleCreateModelPrice)
+		admin.PUT("/pricing/:id", s.HandleUpdateModelPrice)
+		admin.DELETE("/pricing/:id", s.HandleDeleteModelPrice)
+		admin.GET("/test-prompts", s.HandleListTestPrompts) // 渠道测试提示词库（2026-10新增）
+		admin.POST("/test-prompts", s.HandleCreateTestPrompt)
+		admin.PUT("/test-prompts/:id", s.HandleUpdateTestPrompt)
+		admin.DELETE("/test-prompts/:id", s.HandleDeleteTestPrompt)
+		admin.GET("/pricing/recompute", s.HandleListCostRecomputes) // 历史费用重算任务
+		admin.POST("/pricing/recompute", s.HandleCreateCostRecompute)
+		admin.GET("/pricing/recompute/:id", s.HandleGetCostRecompute)
+		admin.POST("/pricing/recompute/:id/pause", s.HandlePauseCostRecompute)
+		admin.POST("/pricing/recompute/:id/resume", s.HandleResumeCostRecompute)
 		admin.GET("/models", s.HandleGetModels)
 
 		// API访问令牌管理
 		admin.GET("/auth-tokens", s.HandleListAuthTokens)
 		admin.POST("/auth-tokens", s.HandleCreateAuthToken)
+		admin.POST("/auth-tokens/batch", s.HandleBatchCreateAuthTokens)    // 按模板批量创建（明文仅返回一次）
+		admin.POST("/auth-tokens/introspect", s.HandleIntrospectAuthToken) // 令牌自省（外部网关集成）
 		admin.PUT("/auth-tokens/:id", s.HandleUpdateAuthToken)
 		admin.DELETE("/auth-tokens/:id", s.HandleDeleteAuthToken)
+		admin.GET("/auth-tokens/:id/forecast", s.HandleAuthTokenForecast) // 用量预测（月末费用/额度耗尽时间，2026-10新增）
+		admin.GET("/topology", s.HandleTopology)                          // 令牌→渠道→端点→模型访问拓扑（2026-10新增）
 
 		// 系统配置管理
 		admin.GET("/settings", s.AdminListSettings)
@@ -584,6 +1294,12 @@ func (s *Server) stateCleanupLoop() {
 			if s.keySelector != nil {
 				s.keySelector.CleanupInactiveCounters(24 * time.Hour)
 			}
+
+			// 清理过期的会话粘性绑定
+			s.sessionAffinity.prune(time.Now())
+
+			// 清理过期的响应缓存
+			s.pruneResponseCache(time.Now())
 		}
 	}
 }
@@ -593,31 +1309,16 @@ func (s *Server) stateCleanupLoop() {
 func (s *Server) AddLogAsync(entry *model.LogEntry) {
 	// 更新成本缓存（用于每日成本限额功能）
 	if s.costCache != nil && entry.ChannelID > 0 && entry.Cost > 0 {
-		s.costCache.Add(entry.ChannelID, entry.Cost)
+		total := s.costCache.Add(entry.ChannelID, entry.Cost)
+		s.checkChannelBudget(entry.ChannelID, total-entry.Cost, total)
 	}
+	s.recordMonthlySpend(entry)
 
 	// 委托给 LogService 处理日志写入
 	s.logService.AddLogAsync(entry)
-}
 
-// getModelsByChannelType 获取指定渠道类型的去重模型列表
-func (s *Server) getModelsByChannelType(ctx context.Context, channelType string) ([]string, error) {
-	// 直接查询数据库（KISS原则，避免过度设计）
-	channels, err := s.store.GetEnabledChannelsByType(ctx, channelType)
-	if err != nil {
-		return nil, err
-	}
-	modelSet := make(map[string]struct{})
-	for _, cfg := range channels {
-		for _, modelName := range cfg.GetModels() {
-			modelSet[modelName] = struct{}{}
-		}
-	}
-	models := make([]string, 0, len(modelSet))
-	for name := range modelSet {
-		models = append(models, name)
-	}
-	return models, nil
+	// 推送到管理端事件流（无订阅者时直接返回）
+	s.publishLogEvent(entry)
 }
 
 // HandleChannelKeys 获取渠道的所有API Keys
@@ -650,6 +1351,9 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	// 关闭shutdownCh，通知所有goroutine退出（幂等：由isShuttingDown守护）
 	close(s.shutdownCh)
 
+	// 结束管理端事件流（通常已由 http.Server 的 OnShutdown 钩子完成，幂等）
+	s.CloseAdminEvents()
+
 	// 停止LoginRateLimiter的cleanupLoop
 	if s.loginRateLimiter != nil {
 		s.loginRateLimiter.Stop()
@@ -677,6 +1381,9 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		err = ctx.Err()
 	}
 
+	// 关闭预写日志（未确认记录留待下次启动重放）
+	s.journal.close()
+
 	// 无论成功还是超时，都要关闭数据库连接
 	if closer, ok := s.store.(interface{ Close() error }); ok {
 		if closeErr := closer.Close(); closeErr != nil {
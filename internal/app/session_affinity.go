@@ -0,0 +1,160 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 会话粘性路由（2026-10新增）
+// ============================================================================
+// 同一会话的连续请求固定到上次成功的渠道与Key，提高 Anthropic 上游的提示缓存命中率。
+// - 会话标识：X-CCLoad-Session-Id 请求头优先，其次请求体 metadata.user_id（Claude Code 等客户端会携带）
+// - 绑定键：令牌哈希 + 会话标识的 SHA-256（不同令牌的相同标识互不影响，内存中不保存原始标识）
+// - 固定渠道冷却/禁用时不在候选列表中，按正常顺序选路；成功后绑定更新为新渠道
+// - 固定Key冷却/配额耗尽时按Key策略正常选择
+// 由 sticky_session_ttl_seconds 控制（0=关闭），绑定在最后一次成功后 TTL 内有效。
+
+// headerCCLoadSessionID 会话标识请求头（X-CCLoad-* 头不会透传到上游）
+const headerCCLoadSessionID = "X-CCLoad-Session-Id"
+
+// sessionAffinityMaxEntries 绑定数上限：达到上限时先清理过期绑定，仍满则不再新增
+const sessionAffinityMaxEntries = 100000
+
+// affinityPin 会话绑定的渠道与Key
+type affinityPin struct {
+	channelID int64
+	keyIndex  int
+	expiresAt time.Time
+}
+
+// sessionAffinity 会话 → 渠道/Key 绑定表（nil 表示未启用）
+type sessionAffinity struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]affinityPin
+}
+
+func newSessionAffinity(ttl time.Duration) *sessionAffinity {
+	return &sessionAffinity{ttl: ttl, entries: make(map[string]affinityPin)}
+}
+
+// get 返回未过期的绑定
+func (a *sessionAffinity) get(key string, now time.Time) (affinityPin, bool) {
+	if a == nil || key == "" {
+		return affinityPin{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pin, ok := a.entries[key]
+	if !ok {
+		return affinityPin{}, false
+	}
+	if !pin.expiresAt.After(now) {
+		delete(a.entries, key)
+		return affinityPin{}, false
+	}
+	return pin, true
+}
+
+// pin 记录（或续期）会话绑定
+func (a *sessionAffinity) pin(key string, channelID int64, keyIndex int, now time.Time) {
+	if a == nil || key == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.entries[key]; !ok && len(a.entries) >= sessionAffinityMaxEntries {
+		a.pruneLocked(now)
+		if len(a.entries) >= sessionAffinityMaxEntries {
+			return
+		}
+	}
+	a.entries[key] = affinityPin{channelID: channelID, keyIndex: keyIndex, expiresAt: now.Add(a.ttl)}
+}
+
+// prune 清理过期绑定，返回清理数量
+func (a *sessionAffinity) prune(now time.Time) int {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pruneLocked(now)
+}
+
+func (a *sessionAffinity) pruneLocked(now time.Time) int {
+	removed := 0
+	for key, pin := range a.entries {
+		if !pin.expiresAt.After(now) {
+			delete(a.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// sessionAffinityKey 从请求头/请求体提取会话标识并生成绑定键；无会话标识返回空串
+func sessionAffinityKey(tokenHash string, header http.Header, body []byte) string {
+	sessionID := strings.TrimSpace(header.Get(headerCCLoadSessionID))
+	if sessionID == "" {
+		if node, err := sonic.Get(body, "metadata", "user_id"); err == nil {
+			sessionID, _ = node.String()
+			sessionID = strings.TrimSpace(sessionID)
+		}
+	}
+	if sessionID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(tokenHash + "\x00" + sessionID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// preferPinnedChannel 将会话绑定的渠道移到候选列表首位（其余顺序不变）；绑定渠道不在候选中时原样返回
+func preferPinnedChannel(cands []*model.Config, channelID int64) []*model.Config {
+	idx := slices.IndexFunc(cands, func(cfg *model.Config) bool { return cfg.ID == channelID })
+	if idx <= 0 {
+		return cands
+	}
+	pinned := cands[idx]
+	copy(cands[1:idx+1], cands[:idx])
+	cands[0] = pinned
+	return cands
+}
+
+// selectKeyWithAffinity 会话绑定到本渠道且绑定Key可用时优先使用该Key，否则按Key策略选择
+func (s *Server) selectKeyWithAffinity(cfg *model.Config, reqCtx *proxyRequestContext, apiKeys []*model.APIKey, triedKeys map[int]bool) (int, string, error) {
+	now := time.Now()
+	if pin, ok := s.sessionAffinity.get(reqCtx.affinityKey, now); ok && pin.channelID == cfg.ID {
+		if key, ok := pinnedKeyUsable(apiKeys, pin.keyIndex, triedKeys, now); ok {
+			return pin.keyIndex, key, nil
+		}
+	}
+	return s.keySelector.SelectAvailableKey(cfg.ID, apiKeys, triedKeys)
+}
+
+// pinnedKeyUsable 绑定的Key是否可直接使用（本次请求未尝试、未冷却、配额未耗尽）
+func pinnedKeyUsable(apiKeys []*model.APIKey, keyIndex int, triedKeys map[int]bool, now time.Time) (string, bool) {
+	if triedKeys[keyIndex] {
+		return "", false
+	}
+	for _, k := range apiKeys {
+		if k.KeyIndex != keyIndex {
+			continue
+		}
+		if k.IsCoolingDown(now) || k.QuotaExhaustedUntil(now) > 0 {
+			return "", false
+		}
+		return k.APIKey, true
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+func TestSessionAffinityKey(t *testing.T) {
+	body := []byte(`{"model":"claude-x","metadata":{"user_id":"user_abc_session_1"}}`)
+	fromBody := sessionAffinityKey("tok", http.Header{}, body)
+	if fromBody == "" {
+		t.Fatal("metadata.user_id should produce an affinity key")
+	}
+	if sessionAffinityKey("tok", http.Header{}, body) != fromBody {
+		t.Fatal("affinity key should be stable")
+	}
+	if sessionAffinityKey("other", http.Header{}, body) == fromBody {
+		t.Fatal("different tokens must not share affinity")
+	}
+	h := http.Header{}
+	h.Set(headerCCLoadSessionID, "conv-1")
+	if got := sessionAffinityKey("tok", h, body); got == "" || got == fromBody {
+		t.Fatalf("session header should take precedence over metadata.user_id, got %q", got)
+	}
+	if got := sessionAffinityKey("tok", http.Header{}, []byte(`{"model":"claude-x"}`)); got != "" {
+		t.Fatalf("request without session identifier should not be pinned, got %q", got)
+	}
+}
+
+func TestSessionAffinity_PinAndExpire(t *testing.T) {
+	a := newSessionAffinity(time.Minute)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	a.pin("k", 7, 2, now)
+	if pin, ok := a.get("k", now.Add(30*time.Second)); !ok || pin.channelID != 7 || pin.keyIndex != 2 {
+		t.Fatalf("expected pin to channel 7 key 2, got %+v %v", pin, ok)
+	}
+	// 成功后续期
+	a.pin("k", 7, 2, now.Add(50*time.Second))
+	if _, ok := a.get("k", now.Add(90*time.Second)); !ok {
+		t.Fatal("pin should be renewed on success")
+	}
+	if _, ok := a.get("k", now.Add(3*time.Minute)); ok {
+		t.Fatal("pin should expire after TTL")
+	}
+
+	a.pin("x", 1, 0, now)
+	if removed := a.prune(now.Add(2 * time.Minute)); removed != 1 {
+		t.Fatalf("expected 1 expired pin pruned, got %d", removed)
+	}
+
+	var disabled *sessionAffinity
+	disabled.pin("k", 1, 0, now)
+	if _, ok := disabled.get("k", now); ok {
+		t.Fatal("nil affinity should never pin")
+	}
+}
+
+func TestPreferPinnedChannel(t *testing.T) {
+	cands := []*model.Config{{ID: 1}, {ID: 2}, {ID: 3}}
+	got := preferPinnedChannel(cands, 3)
+	if got[0].ID != 3 || got[1].ID != 1 || got[2].ID != 2 {
+		t.Fatalf("pinned channel should move to front keeping order, got %d,%d,%d", got[0].ID, got[1].ID, got[2].ID)
+	}
+	// 固定渠道已冷却（不在候选中）：保持原顺序
+	got = preferPinnedChannel([]*model.Config{{ID: 1}, {ID: 2}}, 9)
+	if got[0].ID != 1 || got[1].ID != 2 {
+		t.Fatal("missing pinned channel should keep candidate order")
+	}
+}
+
+func TestSelectKeyWithAffinity(t *testing.T) {
+	now := time.Now()
+	srv := &Server{keySelector: NewKeySelector(), sessionAffinity: newSessionAffinity(time.Hour)}
+	cfg := &model.Config{ID: 5}
+	keys := []*model.APIKey{
+		{ChannelID: 5, KeyIndex: 0, APIKey: "sk-0", KeyStrategy: model.KeyStrategySequential},
+		{ChannelID: 5, KeyIndex: 1, APIKey: "sk-1", KeyStrategy: model.KeyStrategySequential},
+	}
+	reqCtx := &proxyRequestContext{affinityKey: "k"}
+
+	srv.sessionAffinity.pin("k", 5, 1, now)
+	if idx, key, err := srv.selectKeyWithAffinity(cfg, reqCtx, keys, map[int]bool{}); err != nil || idx != 1 || key != "sk-1" {
+		t.Fatalf("pinned key should be preferred, got %d %q %v", idx, key, err)
+	}
+	// 本次请求已尝试过绑定Key：按策略选择其他Key
+	if idx, _, err := srv.selectKeyWithAffinity(cfg, reqCtx, keys, map[int]bool{1: true}); err != nil || idx != 0 {
+		t.Fatalf("expected fallback to key 0, got %d %v", idx, err)
+	}
+	// 绑定Key冷却中：按策略选择
+	keys[1].CooldownUntil = now.Add(time.Minute).Unix()
+	if idx, _, err := srv.selectKeyWithAffinity(cfg, reqCtx, keys, map[int]bool{}); err != nil || idx != 0 {
+		t.Fatalf("cooled pinned key should be skipped, got %d %v", idx, err)
+	}
+	// 绑定到其他渠道：不影响本渠道
+	srv.sessionAffinity.pin("k", 6, 1, now)
+	keys[1].CooldownUntil = 0
+	if idx, _, err := srv.selectKeyWithAffinity(cfg, reqCtx, keys, map[int]bool{}); err != nil || idx != 0 {
+		t.Fatalf("pin for another channel should be ignored, got %d %v", idx, err)
+	}
+}
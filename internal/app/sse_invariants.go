@@ -0,0 +1,445 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 渠道测试诊断模式：SSE端到端校验（2026-10新增）
+// ============================================================================
+// 诊断模式下，渠道测试把上游原始字节送入代理链路的客户端写出路径（streamCopySSE），
+// 并排返回 原始上游流 与 客户端实际收到的流，同时按渠道类型校验事件顺序不变量，
+// 便于快速定位某个上游（或代理写出路径）的事件顺序问题。
+// 渠道启用了协议转换（anthropic_compat / openai_compat）时，同一上游流还会经过与转发相同的
+// 转换写出器（Gemini/OpenAI → Messages、Messages/Responses → Chat Completions、Messages → Text Completions），
+// 按客户端协议分别校验。
+
+// sseEvent 解析后的单个SSE事件
+type sseEvent struct {
+	name string // event: 字段；缺失时取 data.type（OpenAI Chat/Gemini 为 "chunk"）
+	data string
+}
+
+// parseSSEEvents 将SSE字节流解析为事件序列（以空行分隔事件）
+func parseSSEEvents(stream []byte) []sseEvent {
+	var events []sseEvent
+	var name string
+	var dataLines []string
+
+	flush := func() {
+		if name == "" && len(dataLines) == 0 {
+			return
+		}
+		ev := sseEvent{name: name, data: strings.Join(dataLines, "\n")}
+		if ev.name == "" {
+			ev.name = sseEventNameFromData(ev.data)
+		}
+		events = append(events, ev)
+		name, dataLines = "", nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	flush()
+	return events
+}
+
+// sseEventNameFromData 从data推断事件名（无 event: 字段的上游）
+func sseEventNameFromData(data string) string {
+	if data == "[DONE]" {
+		return "[DONE]"
+	}
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if err := sonic.UnmarshalString(data, &obj); err == nil && obj.Type != "" {
+		return obj.Type
+	}
+	return "chunk"
+}
+
+// checkSSEInvariants 按渠道类型校验事件顺序不变量，返回事件名序列与违规描述
+func checkSSEInvariants(channelType string, stream []byte) (names []string, violations []string) {
+	events := parseSSEEvents(stream)
+	names = make([]string, 0, len(events))
+	for _, ev := range events {
+		names = append(names, ev.name)
+	}
+	if len(events) == 0 {
+		return names, []string{"stream contains no SSE events"}
+	}
+
+	// 上游error事件单独报告（不再继续校验顺序）
+	for i, ev := range events {
+		if ev.name == "error" {
+			return names, []string{fmt.Sprintf("event #%d is an error event: %s", i, truncateErr(ev.data))}
+		}
+	}
+
+	if channelType == sseProtocolLegacyComplete {
+		return names, checkLegacyCompleteInvariants(events)
+	}
+	switch util.NormalizeChannelType(channelType) {
+	case util.ChannelTypeCodex:
+		violations = checkResponsesInvariants(names)
+	case util.ChannelTypeOpenAI:
+		violations = checkChatCompletionsInvariants(events)
+	case util.ChannelTypeGemini:
+		violations = checkGeminiInvariants(events)
+	default:
+		violations = checkAnthropicInvariants(events)
+	}
+	return names, violations
+}
+
+// checkAnthropicInvariants message_start最先、内容块start/delta/stop配对、message_stop唯一且最后
+func checkAnthropicInvariants(events []sseEvent) []string {
+	var violations []string
+	if events[0].name != "message_start" {
+		violations = append(violations, fmt.Sprintf("first event is %q, want message_start", events[0].name))
+	}
+
+	openBlocks := make(map[int]bool)
+	stops, messageDeltas := 0, 0
+	for i, ev := range events {
+		if stops > 0 {
+			violations = append(violations, fmt.Sprintf("event #%d %q after message_stop", i, ev.name))
+			continue
+		}
+		switch ev.name {
+		case "message_start":
+			if i != 0 {
+				violations = append(violations, fmt.Sprintf("duplicate message_start at event #%d", i))
+			}
+		case "content_block_start", "content_block_delta", "content_block_stop":
+			var blk struct {
+				Index int `json:"index"`
+			}
+			_ = sonic.UnmarshalString(ev.data, &blk)
+			switch ev.name {
+			case "content_block_start":
+				if openBlocks[blk.Index] {
+					violations = append(violations, fmt.Sprintf("content block %d started twice (event #%d)", blk.Index, i))
+				}
+				openBlocks[blk.Index] = true
+			case "content_block_delta":
+				if !openBlocks[blk.Index] {
+					violations = append(violations, fmt.Sprintf("content_block_delta for unopened block %d (event #%d)", blk.Index, i))
+				}
+			case "content_block_stop":
+				if !openBlocks[blk.Index] {
+					violations = append(violations, fmt.Sprintf("content_block_stop for unopened block %d (event #%d)", blk.Index, i))
+				}
+				delete(openBlocks, blk.Index)
+			}
+		case "message_delta":
+			messageDeltas++
+			if len(openBlocks) > 0 {
+				violations = append(violations, fmt.Sprintf("message_delta while %d content block(s) still open (event #%d)", len(openBlocks), i))
+			}
+		case "message_stop":
+			stops++
+		}
+	}
+	if messageDeltas > 1 {
+		violations = append(violations, fmt.Sprintf("message_delta appears %d times, want at most 1", messageDeltas))
+	}
+	if stops == 0 {
+		violations = append(violations, "missing message_stop")
+	}
+	return violations
+}
+
+// checkResponsesInvariants response.created最先、终止事件唯一且最后
+func checkResponsesInvariants(names []string) []string {
+	var violations []string
+	if names[0] != "response.created" {
+		violations = append(violations, fmt.Sprintf("first event is %q, want response.created", names[0]))
+	}
+	terminals := 0
+	for i, name := range names {
+		if terminals > 0 {
+			violations = append(violations, fmt.Sprintf("event #%d %q after terminal event", i, name))
+			continue
+		}
+		switch name {
+		case "response.completed", "response.failed", "response.incomplete":
+			terminals++
+		}
+	}
+	if terminals == 0 {
+		violations = append(violations, "missing terminal event (response.completed/failed/incomplete)")
+	}
+	return violations
+}
+
+// checkChatCompletionsInvariants finish_reason唯一、之后只允许usage块、[DONE]唯一且最后
+func checkChatCompletionsInvariants(events []sseEvent) []string {
+	var violations []string
+	finished, done := false, false
+	for i, ev := range events {
+		if done {
+			violations = append(violations, fmt.Sprintf("event #%d after [DONE]", i))
+			continue
+		}
+		if ev.name == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := sonic.UnmarshalString(ev.data, &chunk); err != nil {
+			violations = append(violations, fmt.Sprintf("event #%d is not valid JSON", i))
+			continue
+		}
+		if finished && len(chunk.Choices) > 0 {
+			violations = append(violations, fmt.Sprintf("choice delta after finish_reason (event #%d)", i))
+		}
+		for _, c := range chunk.Choices {
+			if c.FinishReason != nil && *c.FinishReason != "" {
+				finished = true
+			}
+		}
+	}
+	if !finished {
+		violations = append(violations, "missing finish_reason")
+	}
+	if !done {
+		violations = append(violations, "missing [DONE]")
+	}
+	return violations
+}
+
+// checkLegacyCompleteInvariants 只含 completion/ping 事件，最后一个 completion 带 stop_reason 且之后不再有 completion
+func checkLegacyCompleteInvariants(events []sseEvent) []string {
+	var violations []string
+	stopped := false
+	for i, ev := range events {
+		switch ev.name {
+		case "ping":
+			continue
+		case "completion":
+		default:
+			violations = append(violations, fmt.Sprintf("unexpected event %q (event #%d)", ev.name, i))
+			continue
+		}
+		if stopped {
+			violations = append(violations, fmt.Sprintf("completion after stop_reason (event #%d)", i))
+			continue
+		}
+		var chunk struct {
+			StopReason *string `json:"stop_reason"`
+		}
+		if err := sonic.UnmarshalString(ev.data, &chunk); err != nil {
+			violations = append(violations, fmt.Sprintf("event #%d is not valid JSON", i))
+			continue
+		}
+		stopped = chunk.StopReason != nil && *chunk.StopReason != ""
+	}
+	if !stopped {
+		violations = append(violations, "missing final completion with stop_reason")
+	}
+	return violations
+}
+
+// checkGeminiInvariants finishReason必须出现，且之后不再有带candidates的块
+func checkGeminiInvariants(events []sseEvent) []string {
+	var violations []string
+	finishedAt := -1
+	for i, ev := range events {
+		var chunk struct {
+			Candidates []struct {
+				FinishReason string `json:"finishReason"`
+			} `json:"candidates"`
+		}
+		if err := sonic.UnmarshalString(ev.data, &chunk); err != nil {
+			violations = append(violations, fmt.Sprintf("event #%d is not valid JSON", i))
+			continue
+		}
+		if len(chunk.Candidates) == 0 {
+			continue // usageMetadata-only 块
+		}
+		if finishedAt >= 0 {
+			violations = append(violations, fmt.Sprintf("candidates after finishReason (event #%d)", i))
+		}
+		for _, c := range chunk.Candidates {
+			if c.FinishReason != "" && finishedAt < 0 {
+				finishedAt = i
+			}
+		}
+	}
+	if finishedAt < 0 {
+		violations = append(violations, "missing finishReason")
+	}
+	return violations
+}
+
+// diagnosisWriter 收集客户端写出字节的最小 ResponseWriter（诊断模式不经网络）
+type diagnosisWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *diagnosisWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *diagnosisWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *diagnosisWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+func (w *diagnosisWriter) Flush() {}
+
+// sseProtocolLegacyComplete 旧版 Text Completions 流（event: completion）的校验协议名
+const sseProtocolLegacyComplete = "legacy_complete"
+
+// diagnosisClientEntries 诊断时模拟的客户端入口：协议转换只对这些路径生效
+var diagnosisClientEntries = []struct{ path, protocol string }{
+	{anthropicMessagesPath, util.ChannelTypeAnthropic},
+	{openaiChatCompletionsPath, util.ChannelTypeOpenAI},
+	{legacyCompletePath, sseProtocolLegacyComplete},
+}
+
+// wrapBridgeWriters 按 forwardAttempt 相同的启用条件与套用顺序，为客户端入口 path 包装协议转换写出器。
+// 返回上游字节的写入端与按 forwardAttempt 顺序收尾的函数；本渠道对该入口不做转换时返回 nil
+func wrapBridgeWriters(cfg *model.Config, path, modelName string, w http.ResponseWriter) (http.ResponseWriter, func()) {
+	reqCtx := &proxyRequestContext{requestMethod: http.MethodPost, requestPath: path, originalModel: modelName}
+	var completeBridge *legacyCompleteWriter
+	var chatBridge *openaiChatWriter
+	var bridge *anthropicBridgeWriter
+	var chatCompatBridge *anthropicOpenAIWriter
+
+	if legacyCompleteBridgeEnabled(cfg, reqCtx) {
+		completeBridge = newLegacyCompleteWriter(w, modelName, true)
+		w = completeBridge
+		reqCtx.requestPath = anthropicMessagesPath
+	}
+	if openaiBridgeEnabled(cfg, reqCtx) {
+		source := openaiSourceAnthropic
+		reqCtx.requestPath = anthropicMessagesPath
+		if cfg.GetChannelType() == util.ChannelTypeCodex {
+			source = openaiSourceResponses
+			reqCtx.requestPath = codexResponsesPath
+		}
+		chatBridge = newOpenAIChatWriter(w, source, modelName, openaiChatOptions{stream: true, includeUsage: true})
+		w = chatBridge
+	}
+	if anthropicBridgeEnabled(cfg, reqCtx) || (chatBridge != nil && cfg.GetChannelType() == util.ChannelTypeGemini) {
+		bridge = newAnthropicBridgeWriter(w, modelName, true)
+		w = bridge
+		reqCtx.requestPath, _ = geminiBridgePath(modelName, true)
+	}
+	if anthropicOpenAIBridgeEnabled(cfg, reqCtx) {
+		chatCompatBridge = newAnthropicOpenAIWriter(w, modelName, true)
+		w = chatCompatBridge
+	}
+	if completeBridge == nil && chatBridge == nil && bridge == nil && chatCompatBridge == nil {
+		return nil, nil
+	}
+
+	finish := func() {
+		if bridge != nil {
+			bridge.finishResponse(true)
+		}
+		if chatCompatBridge != nil {
+			chatCompatBridge.finishResponse(true)
+		}
+		if completeBridge != nil {
+			completeBridge.finishResponse(true)
+		}
+		if chatBridge != nil {
+			chatBridge.finishResponse(true)
+		}
+	}
+	return w, finish
+}
+
+// diagnoseSSEStream 将原始上游流送入代理的客户端写出路径，返回诊断结果：
+// 顶层为原样透传（客户端协议与渠道相同）的结果，bridges 为本渠道启用的各协议转换入口的结果
+func diagnoseSSEStream(cfg *model.Config, channelType, modelName string, raw []byte) map[string]any {
+	out := &diagnosisWriter{}
+	parser := newSSEUsageParser(channelType)
+	copyErr := streamCopySSE(context.Background(), bytes.NewReader(raw), out, parser.Feed)
+	clientStream := out.body.Bytes()
+
+	names, violations := checkSSEInvariants(channelType, clientStream)
+	passed := len(violations) == 0 && copyErr == nil
+	diag := map[string]any{
+		"raw_upstream":    string(raw),
+		"client_stream":   string(clientStream),
+		"identical":       bytes.Equal(raw, clientStream),
+		"events":          names,
+		"stream_complete": parser.IsStreamComplete(),
+		"violations":      violations,
+	}
+	if copyErr != nil {
+		diag["copy_error"] = copyErr.Error()
+	}
+
+	// 测试请求按渠道原生协议发出；渠道类型与测试类型不一致时无法模拟转换链路
+	var bridges []map[string]any
+	if cfg != nil && util.NormalizeChannelType(cfg.GetChannelType()) == channelType {
+		for _, entry := range diagnosisClientEntries {
+			out := &diagnosisWriter{}
+			w, finish := wrapBridgeWriters(cfg, entry.path, modelName, out)
+			if w == nil {
+				continue
+			}
+			copyErr := streamCopySSE(context.Background(), bytes.NewReader(raw), w, nil)
+			finish()
+			names, violations := checkSSEInvariants(entry.protocol, out.body.Bytes())
+			result := map[string]any{
+				"client_path":   entry.path,
+				"client_stream": out.body.String(),
+				"events":        names,
+				"violations":    violations,
+				"passed":        len(violations) == 0 && copyErr == nil,
+			}
+			if copyErr != nil {
+				result["copy_error"] = copyErr.Error()
+			}
+			passed = passed && result["passed"] == true
+			bridges = append(bridges, result)
+		}
+	}
+	if len(bridges) > 0 {
+		diag["bridges"] = bridges
+	}
+	diag["passed"] = passed
+	return diag
+}
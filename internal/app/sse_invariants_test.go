@@ -0,0 +1,155 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+)
+
+func sseStream(events ...string) string {
+	var b strings.Builder
+	for _, ev := range events {
+		b.WriteString(ev)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+func TestCheckSSEInvariants(t *testing.T) {
+	anthropicOK := sseStream(
+		"event: message_start\ndata: {\"type\":\"message_start\"}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\"}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+	)
+
+	tests := []struct {
+		name        string
+		channelType string
+		stream      string
+		wantViol    string // 空表示期望无违规
+	}{
+		{"anthropic正常", "anthropic", anthropicOK, ""},
+		{"anthropic delta先于message_start", "anthropic", sseStream(
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0}",
+			"event: message_start\ndata: {\"type\":\"message_start\"}",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+		), "want message_start"},
+		{"anthropic重复stop", "anthropic", anthropicOK + sseStream("event: message_stop\ndata: {\"type\":\"message_stop\"}"), "after message_stop"},
+		{"anthropic缺少stop", "anthropic", sseStream("event: message_start\ndata: {\"type\":\"message_start\"}"), "missing message_stop"},
+		{"anthropic错误事件", "anthropic", sseStream("event: error\ndata: {\"type\":\"error\"}"), "error event"},
+		{"openai正常", "openai", sseStream(
+			`data: {"choices":[{"delta":{"content":"hi"},"finish_reason":null}]}`,
+			`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`,
+			`data: {"choices":[],"usage":{"total_tokens":3}}`,
+			`data: [DONE]`,
+		), ""},
+		{"openai缺少DONE", "openai", sseStream(`data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`), "missing [DONE]"},
+		{"codex正常", "codex", sseStream(
+			"event: response.created\ndata: {\"type\":\"response.created\"}",
+			"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\"}",
+			"event: response.completed\ndata: {\"type\":\"response.completed\"}",
+		), ""},
+		{"codex终止后仍有事件", "codex", sseStream(
+			"event: response.created\ndata: {\"type\":\"response.created\"}",
+			"event: response.completed\ndata: {\"type\":\"response.completed\"}",
+			"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\"}",
+		), "after terminal event"},
+		{"gemini正常", "gemini", sseStream(
+			`data: {"candidates":[{"content":{}}]}`,
+			`data: {"candidates":[{"finishReason":"STOP"}],"usageMetadata":{}}`,
+		), ""},
+		{"gemini缺少finishReason", "gemini", sseStream(`data: {"candidates":[{"content":{}}]}`), "missing finishReason"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, violations := checkSSEInvariants(tt.channelType, []byte(tt.stream))
+			if tt.wantViol == "" {
+				if len(violations) != 0 {
+					t.Fatalf("expected no violations, got %v", violations)
+				}
+				return
+			}
+			joined := strings.Join(violations, "; ")
+			if !strings.Contains(joined, tt.wantViol) {
+				t.Fatalf("expected violation containing %q, got %v", tt.wantViol, violations)
+			}
+		})
+	}
+}
+
+func TestDiagnoseSSEStream_ClientStreamMatchesUpstream(t *testing.T) {
+	raw := sseStream(
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":3}}}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+	)
+	diag := diagnoseSSEStream(&model.Config{ChannelType: "anthropic"}, "anthropic", "claude-test", []byte(raw))
+	if diag["identical"] != true {
+		t.Fatalf("client stream should be byte-identical to upstream: %v", diag["client_stream"])
+	}
+	if diag["passed"] != true || diag["stream_complete"] != true {
+		t.Fatalf("unexpected diagnostics: %+v", diag)
+	}
+	if names, _ := diag["events"].([]string); len(names) != 2 {
+		t.Fatalf("unexpected events: %v", diag["events"])
+	}
+	// anthropic 渠道始终承接旧版 /v1/complete；未开启 openai_compat 时不转换 Chat Completions
+	if bridges, _ := diag["bridges"].([]map[string]any); len(bridges) != 1 || bridges[0]["client_path"] != "/v1/complete" || bridges[0]["passed"] != true {
+		t.Fatalf("unexpected bridges: %v", diag["bridges"])
+	}
+}
+
+// 启用协议转换的渠道：同一上游流经转发所用的转换写出器后按客户端协议校验
+func TestDiagnoseSSEStream_RunsBridgeWriters(t *testing.T) {
+	gemini := sseStream(
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"!"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2}}`,
+	)
+	cfg := &model.Config{ChannelType: "gemini", AnthropicCompat: true, OpenAICompat: true}
+	diag := diagnoseSSEStream(cfg, "gemini", "gemini-test", []byte(gemini))
+	bridges, _ := diag["bridges"].([]map[string]any)
+	paths := make([]string, 0, len(bridges))
+	for _, b := range bridges {
+		paths = append(paths, b["client_path"].(string))
+		if b["passed"] != true {
+			t.Fatalf("bridge %s failed: %v\n%s", b["client_path"], b["violations"], b["client_stream"])
+		}
+	}
+	if strings.Join(paths, ",") != "/v1/messages,/v1/chat/completions,/v1/complete" {
+		t.Fatalf("unexpected bridge entries: %v", paths)
+	}
+	if diag["passed"] != true || diag["identical"] != true {
+		t.Fatalf("unexpected diagnostics: %+v", diag)
+	}
+	if msgs := bridges[0]["client_stream"].(string); !strings.Contains(msgs, "event: message_start") || strings.Contains(msgs, "candidates") {
+		t.Fatalf("messages entry should receive converted events: %s", msgs)
+	}
+	if chat := bridges[1]["client_stream"].(string); !strings.Contains(chat, "chat.completion.chunk") || !strings.Contains(chat, "[DONE]") {
+		t.Fatalf("chat entry should receive converted chunks: %s", chat)
+	}
+
+	// 上游未结束：转换后的客户端流缺少终止事件，诊断应失败
+	truncated := sseStream(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`)
+	if diag := diagnoseSSEStream(cfg, "gemini", "gemini-test", []byte(truncated)); diag["passed"] != false {
+		t.Fatalf("truncated stream should fail diagnosis: %+v", diag)
+	}
+}
+
+func TestCheckLegacyCompleteInvariants(t *testing.T) {
+	ok := sseStream(
+		`event: completion`+"\n"+`data: {"completion":"hi","stop_reason":null}`,
+		`event: ping`+"\n"+`data: {"type":"ping"}`,
+		`event: completion`+"\n"+`data: {"completion":"","stop_reason":"stop_sequence"}`,
+	)
+	if _, v := checkSSEInvariants(sseProtocolLegacyComplete, []byte(ok)); len(v) != 0 {
+		t.Fatalf("expected no violations, got %v", v)
+	}
+	missing := sseStream(`event: completion` + "\n" + `data: {"completion":"hi","stop_reason":null}`)
+	if _, v := checkSSEInvariants(sseProtocolLegacyComplete, []byte(missing)); len(v) == 0 || !strings.Contains(v[0], "stop_reason") {
+		t.Fatalf("expected missing stop_reason violation, got %v", v)
+	}
+}
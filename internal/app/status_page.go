@@ -0,0 +1,336 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/testutil"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 合成探测与状态页（2026-10新增）
+// ============================================================================
+// status_probe_interval_minutes > 0 时，后台按渠道类型定期发起一次最小请求（max_tokens=16）：
+//   - 探测目标与真实请求的选路一致（跳过冷却/超限渠道，按优先级/健康度排序后取第一个）
+//   - 探测走管理端低优先级出站通道，不与生产流量争抢上游限额；不触发冷却、不记日志/费用
+//   - 连续 statusIncidentThreshold 次失败记为事故，下次成功时标记恢复
+// GET /status 返回各渠道类型的当前状态、1h/24h/7d 可用率与近期事故（status_page_public=false 时需API令牌），
+// /web/status.html 为对应的简易页面。历史仅保存在内存中，重启后重新累计。
+// 对外只暴露渠道类型级别的结果，不包含渠道名称、URL或上游错误原文。
+
+const (
+	statusHistoryWindow     = 7 * 24 * time.Hour
+	statusIncidentThreshold = 2  // 连续失败次数达到该值记为事故
+	statusMaxIncidents      = 50 // 保留的事故条数
+	statusRecentSamples     = 30 // 响应中附带的最近探测点数
+	statusProbeMaxTokens    = 16
+	statusProbeContent      = "ping"
+
+	statusOperational = "operational"
+	statusDegraded    = "degraded"
+	statusOutage      = "outage"
+	statusUnknown     = "unknown"
+)
+
+// statusProbeSample 单次探测结果
+type statusProbeSample struct {
+	At        int64 `json:"at"` // Unix毫秒
+	OK        bool  `json:"ok"`
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// StatusIncident 事故记录（连续探测失败）
+type StatusIncident struct {
+	ChannelType  string `json:"channel_type"`
+	StartedAt    int64  `json:"started_at"`            // 首次失败时间（Unix毫秒）
+	ResolvedAt   int64  `json:"resolved_at,omitempty"` // 0 表示进行中
+	FailedProbes int    `json:"failed_probes"`
+	Reason       string `json:"reason"` // 归类后的原因（不含上游错误原文）
+}
+
+type statusTypeHistory struct {
+	samples         []statusProbeSample // 按时间升序，仅保留 statusHistoryWindow 内
+	consecutiveFail int
+	firstFailAt     int64
+	lastReason      string
+	incident        *StatusIncident // 进行中的事故
+}
+
+// statusTracker 按渠道类型记录探测历史（nil 表示未启用探测）
+type statusTracker struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	types     map[string]*statusTypeHistory
+	incidents []*StatusIncident // 按开始时间升序
+}
+
+func newStatusTracker(interval time.Duration) *statusTracker {
+	return &statusTracker{interval: interval, types: make(map[string]*statusTypeHistory)}
+}
+
+// record 记录一次探测结果，返回新开启/恢复的事故（用于日志）
+func (t *statusTracker) record(channelType string, ok bool, latency time.Duration, reason string, now time.Time) (opened, resolved *StatusIncident) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.types[channelType]
+	if h == nil {
+		h = &statusTypeHistory{}
+		t.types[channelType] = h
+	}
+	nowMs := now.UnixMilli()
+	h.samples = append(h.samples, statusProbeSample{At: nowMs, OK: ok, LatencyMs: latency.Milliseconds()})
+	cutoff := now.Add(-statusHistoryWindow).UnixMilli()
+	drop := 0
+	for drop < len(h.samples) && h.samples[drop].At < cutoff {
+		drop++
+	}
+	h.samples = h.samples[drop:]
+
+	if ok {
+		h.consecutiveFail = 0
+		if h.incident != nil {
+			h.incident.ResolvedAt = nowMs
+			resolved, h.incident = h.incident, nil
+		}
+		return nil, resolved
+	}
+
+	if h.consecutiveFail == 0 {
+		h.firstFailAt = nowMs
+	}
+	h.consecutiveFail++
+	h.lastReason = reason
+	if h.incident != nil {
+		h.incident.FailedProbes = h.consecutiveFail
+		h.incident.Reason = reason
+		return nil, nil
+	}
+	if h.consecutiveFail >= statusIncidentThreshold {
+		h.incident = &StatusIncident{ChannelType: channelType, StartedAt: h.firstFailAt, FailedProbes: h.consecutiveFail, Reason: reason}
+		t.incidents = append(t.incidents, h.incident)
+		if len(t.incidents) > statusMaxIncidents {
+			t.incidents = t.incidents[len(t.incidents)-statusMaxIncidents:]
+		}
+		return h.incident, nil
+	}
+	return nil, nil
+}
+
+// StatusTypeReport 单个渠道类型的状态
+type StatusTypeReport struct {
+	ChannelType   string              `json:"channel_type"`
+	DisplayName   string              `json:"display_name"`
+	Status        string              `json:"status"`
+	LastProbeAt   int64               `json:"last_probe_at,omitempty"`
+	LastLatencyMs int64               `json:"last_latency_ms,omitempty"`
+	Uptime        map[string]*float64 `json:"uptime"` // 1h/24h/7d 可用率（百分比，无探测数据时为null）
+	Recent        []statusProbeSample `json:"recent"`
+}
+
+// StatusReport 状态页数据
+type StatusReport struct {
+	Status               string             `json:"status"` // 全部类型中最差的状态
+	UpdatedAt            int64              `json:"updated_at"`
+	ProbeIntervalSeconds int64              `json:"probe_interval_seconds"`
+	Types                []StatusTypeReport `json:"types"`
+	Incidents            []StatusIncident   `json:"incidents"` // 最近的事故（新→旧）
+}
+
+var statusSeverity = map[string]int{statusOperational: 0, statusUnknown: 1, statusDegraded: 2, statusOutage: 3}
+
+// report 生成状态页数据（按 util.ChannelTypes 顺序，仅包含有探测记录的类型）
+func (t *statusTracker) report(now time.Time) StatusReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rep := StatusReport{
+		Status:               statusUnknown,
+		UpdatedAt:            now.UnixMilli(),
+		ProbeIntervalSeconds: int64(t.interval / time.Second),
+		Types:                []StatusTypeReport{},
+		Incidents:            []StatusIncident{},
+	}
+	worst := -1
+	for _, ct := range util.ChannelTypes {
+		h := t.types[ct.Value]
+		if h == nil || len(h.samples) == 0 {
+			continue
+		}
+		last := h.samples[len(h.samples)-1]
+		tr := StatusTypeReport{
+			ChannelType:   ct.Value,
+			DisplayName:   ct.DisplayName,
+			Status:        statusOperational,
+			LastProbeAt:   last.At,
+			LastLatencyMs: last.LatencyMs,
+			Uptime: map[string]*float64{
+				"1h":  statusUptime(h.samples, now.Add(-time.Hour).UnixMilli()),
+				"24h": statusUptime(h.samples, now.Add(-24*time.Hour).UnixMilli()),
+				"7d":  statusUptime(h.samples, now.Add(-statusHistoryWindow).UnixMilli()),
+			},
+		}
+		switch {
+		case h.consecutiveFail >= statusIncidentThreshold:
+			tr.Status = statusOutage
+		case h.consecutiveFail > 0:
+			tr.Status = statusDegraded
+		}
+		recent := h.samples
+		if len(recent) > statusRecentSamples {
+			recent = recent[len(recent)-statusRecentSamples:]
+		}
+		tr.Recent = append([]statusProbeSample(nil), recent...)
+		rep.Types = append(rep.Types, tr)
+		if sev := statusSeverity[tr.Status]; sev > worst {
+			worst, rep.Status = sev, tr.Status
+		}
+	}
+	for i := len(t.incidents) - 1; i >= 0; i-- {
+		rep.Incidents = append(rep.Incidents, *t.incidents[i])
+	}
+	return rep
+}
+
+// statusUptime 计算 since 之后的探测成功率（百分比）
+func statusUptime(samples []statusProbeSample, since int64) *float64 {
+	var total, ok int
+	for _, s := range samples {
+		if s.At < since {
+			continue
+		}
+		total++
+		if s.OK {
+			ok++
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	v := float64(ok) * 100 / float64(total)
+	return &v
+}
+
+// statusProbeLoop 定期探测各渠道类型
+func (s *Server) statusProbeLoop() {
+	defer s.wg.Done()
+
+	// 启动后先探测一轮，状态页无需等待一个完整间隔
+	s.runStatusProbes()
+
+	ticker := time.NewTicker(s.statusTracker.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			s.runStatusProbes()
+		}
+	}
+}
+
+// runStatusProbes 对每个已配置渠道的类型执行一次探测
+func (s *Server) runStatusProbes() {
+	for _, ct := range util.ChannelTypes {
+		if s.isShuttingDown.Load() {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		configured, err := s.GetEnabledChannelsByType(ctx, ct.Value)
+		cancel()
+		if err != nil || len(configured) == 0 {
+			continue // 未提供该类型的服务
+		}
+
+		ok, latency, reason := s.probeChannelType(ct.Value)
+		opened, resolved := s.statusTracker.record(ct.Value, ok, latency, reason, time.Now())
+		if opened != nil {
+			log.Printf("[WARN] [状态探测] %s 连续%d次探测失败，记为事故: %s", ct.Value, opened.FailedProbes, reason)
+		}
+		if resolved != nil {
+			log.Printf("[INFO] [状态探测] %s 已恢复（事故持续%v）", ct.Value,
+				time.Duration(resolved.ResolvedAt-resolved.StartedAt)*time.Millisecond)
+		}
+	}
+}
+
+// probeChannelType 按真实选路取首个候选渠道发起一次最小请求
+// 返回是否成功、耗时与归类后的失败原因
+func (s *Server) probeChannelType(channelType string) (bool, time.Duration, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	cands, err := s.selectCandidatesByChannelType(ctx, channelType)
+	cancel()
+	if err != nil {
+		return false, 0, "internal error"
+	}
+	if len(cands) == 0 {
+		return false, 0, "no available channel"
+	}
+	cfg := cands[0]
+	probeModel := statusProbeModel(cfg)
+	if probeModel == "" {
+		return false, 0, "no probe model"
+	}
+
+	keyCtx, keyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	apiKeys, err := s.store.GetAPIKeys(keyCtx, cfg.ID)
+	keyCancel()
+	if err != nil || len(apiKeys) == 0 {
+		return false, 0, "no available key"
+	}
+
+	laneCtx, laneCancel := context.WithTimeout(context.Background(), time.Minute)
+	release, err := s.acquireAdminLane(laneCtx)
+	laneCancel()
+	if err != nil {
+		return false, 0, "probe queue timeout"
+	}
+	defer release()
+
+	start := time.Now()
+	result := s.testChannelAPI(cfg, apiKeys[0].APIKey, &testutil.TestChannelRequest{
+		Model:       probeModel,
+		MaxTokens:   statusProbeMaxTokens,
+		Content:     statusProbeContent,
+		ChannelType: cfg.GetChannelType(),
+	})
+	latency := time.Since(start)
+	if ok, _ := result["success"].(bool); ok {
+		return true, latency, ""
+	}
+	if code, _ := result["status_code"].(int); code > 0 {
+		return false, latency, fmt.Sprintf("upstream HTTP %d", code)
+	}
+	return false, latency, "network error"
+}
+
+// statusProbeModel 渠道的第一个非通配符模型
+func statusProbeModel(cfg *model.Config) string {
+	for _, m := range cfg.GetModels() {
+		if !model.IsModelPattern(m) {
+			return m
+		}
+	}
+	return ""
+}
+
+// HandleStatusPage 服务状态（渠道类型级别的可用率与事故）
+// GET /status
+func (s *Server) HandleStatusPage(c *gin.Context) {
+	if s.statusTracker == nil {
+		RespondErrorMsg(c, http.StatusNotFound, "status page is disabled (status_probe_interval_minutes=0)")
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	RespondJSON(c, http.StatusOK, s.statusTracker.report(time.Now()))
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStatusTracker_UptimeAndIncidents(t *testing.T) {
+	tr := newStatusTracker(5 * time.Minute)
+	base := time.Now().Add(-2 * time.Hour)
+
+	// 2小时前成功两次（计入24h，不计入1h）
+	tr.record("anthropic", true, 800*time.Millisecond, "", base)
+	tr.record("anthropic", true, 900*time.Millisecond, "", base.Add(time.Minute))
+
+	now := time.Now()
+	if opened, _ := tr.record("anthropic", false, 0, "upstream HTTP 529", now.Add(-3*time.Minute)); opened != nil {
+		t.Fatal("single failure must not open an incident")
+	}
+	if rep := tr.report(now); rep.Types[0].Status != statusDegraded {
+		t.Fatalf("expected degraded after one failure, got %s", rep.Types[0].Status)
+	}
+	opened, _ := tr.record("anthropic", false, 0, "upstream HTTP 529", now.Add(-2*time.Minute))
+	if opened == nil || opened.StartedAt != now.Add(-3*time.Minute).UnixMilli() {
+		t.Fatalf("expected incident starting at first failure, got %+v", opened)
+	}
+	tr.record("codex", true, time.Second, "", now)
+
+	rep := tr.report(now)
+	if rep.Status != statusOutage || len(rep.Types) != 2 || rep.Types[0].ChannelType != "anthropic" {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	a := rep.Types[0]
+	if a.Status != statusOutage || *a.Uptime["1h"] != 0 || *a.Uptime["24h"] != 50 {
+		t.Fatalf("unexpected anthropic status: %s uptime=%v/%v", a.Status, *a.Uptime["1h"], *a.Uptime["24h"])
+	}
+	if len(rep.Incidents) != 1 || rep.Incidents[0].ResolvedAt != 0 || rep.Incidents[0].FailedProbes != 2 {
+		t.Fatalf("unexpected incidents: %+v", rep.Incidents)
+	}
+
+	_, resolved := tr.record("anthropic", true, time.Second, "", now.Add(time.Minute))
+	if resolved == nil || resolved.ResolvedAt != now.Add(time.Minute).UnixMilli() {
+		t.Fatalf("expected incident resolved, got %+v", resolved)
+	}
+	if rep := tr.report(now.Add(time.Minute)); rep.Status != statusOperational || rep.Incidents[0].ResolvedAt == 0 {
+		t.Fatalf("expected operational with resolved incident, got %+v", rep)
+	}
+}
+
+func TestHandleStatusPage_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/status", nil)
+	s.HandleStatusPage(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when probes are disabled, got %d", w.Code)
+	}
+}
@@ -10,6 +10,7 @@ import (
 func TestMain(m *testing.M) {
 	originalPass, hadPass := os.LookupEnv("CCLOAD_PASS")
 	_ = os.Setenv("CCLOAD_PASS", "test_password_123")
+	_ = os.Setenv(requestJournalEnv, "off") // 测试不落预写日志文件
 	gin.SetMode(gin.TestMode)
 
 	code := m.Run()
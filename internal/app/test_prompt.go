@@ -0,0 +1,189 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 渠道测试提示词库（2026-10新增）
+// ============================================================================
+// 取代单一的系统设置 channel_test_content：提示词以名称区分存入 test_prompts 表，
+// 首次迁移时写入内置提示词（short-ping / tool-use-check / long-context-check / chinese-text-check），
+// 旧设置若被修改过则迁移为默认提示词 legacy-default。
+// 渠道测试与全部Key测试的请求体可用 prompt_id 或 prompt（名称）选用提示词；
+// 均未指定且 content 为空时使用默认提示词。提示词的 max_tokens 仅在请求未指定时生效。
+
+// fallbackTestContent 提示词库中没有默认提示词时的测试内容
+const fallbackTestContent = "test"
+
+// maxTestPromptContentBytes 提示词内容上限（MySQL TEXT 列最大 64KB）
+const maxTestPromptContentBytes = 60000
+
+// resolveTestPrompt 按 prompt_id / prompt 选用提示词（未指定且无 content 时取默认提示词），
+// 将内容与 max_tokens 填入测试请求；返回选用的提示词名称（未使用提示词库时为空）。
+// 指定的提示词不存在时响应404并返回 ok=false
+func (s *Server) resolveTestPrompt(c *gin.Context, req *testutil.TestChannelRequest) (string, bool) {
+	selected := req.PromptID != 0 || strings.TrimSpace(req.Prompt) != ""
+	if !selected && strings.TrimSpace(req.Content) != "" {
+		return "", true
+	}
+	prompts, err := s.store.ListTestPrompts(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return "", false
+	}
+	var prompt *model.TestPrompt
+	for _, p := range prompts {
+		switch {
+		case req.PromptID != 0:
+			if p.ID == req.PromptID {
+				prompt = p
+			}
+		case selected:
+			if p.Name == strings.TrimSpace(req.Prompt) {
+				prompt = p
+			}
+		case p.IsDefault:
+			prompt = p
+		}
+		if prompt != nil {
+			break
+		}
+	}
+	if prompt == nil {
+		if selected {
+			RespondErrorMsg(c, http.StatusNotFound, "test prompt not found")
+			return "", false
+		}
+		return "", true
+	}
+	req.Content = prompt.Content
+	if req.MaxTokens == 0 && prompt.MaxTokens > 0 {
+		req.MaxTokens = prompt.MaxTokens
+	}
+	return prompt.Name, true
+}
+
+// HandleListTestPrompts 测试提示词列表（默认提示词在前）
+// GET /admin/test-prompts
+func (s *Server) HandleListTestPrompts(c *gin.Context) {
+	prompts, err := s.store.ListTestPrompts(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusOK, prompts)
+}
+
+// HandleCreateTestPrompt 新增测试提示词
+// POST /admin/test-prompts
+func (s *Server) HandleCreateTestPrompt(c *gin.Context) {
+	prompt, ok := s.bindTestPrompt(c, 0)
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	prompt.CreatedAt, prompt.UpdatedAt = now, now
+	if err := s.store.CreateTestPrompt(c.Request.Context(), prompt); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	RespondJSON(c, http.StatusCreated, prompt)
+}
+
+// HandleUpdateTestPrompt 更新测试提示词
+// PUT /admin/test-prompts/:id
+func (s *Server) HandleUpdateTestPrompt(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid test prompt id")
+		return
+	}
+	prompt, ok := s.bindTestPrompt(c, id)
+	if !ok {
+		return
+	}
+	prompt.ID = id
+	prompt.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateTestPrompt(c.Request.Context(), prompt)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "test prompt not found")
+		return
+	}
+	RespondJSON(c, http.StatusOK, prompt)
+}
+
+// HandleDeleteTestPrompt 删除测试提示词
+// DELETE /admin/test-prompts/:id
+func (s *Server) HandleDeleteTestPrompt(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid test prompt id")
+		return
+	}
+	found, err := s.store.DeleteTestPrompt(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "test prompt not found")
+		return
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"id": id})
+}
+
+// bindTestPrompt 解析并校验提示词请求（selfID 为更新时的提示词ID，创建时为0）
+func (s *Server) bindTestPrompt(c *gin.Context, selfID int64) (*model.TestPrompt, bool) {
+	var req TestPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return nil, false
+	}
+	prompt := req.ToTestPrompt()
+	if err := validateTestPrompt(prompt); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	existing, err := s.store.ListTestPrompts(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	for _, other := range existing {
+		if other.ID != selfID && other.Name == prompt.Name {
+			RespondErrorMsg(c, http.StatusConflict, fmt.Sprintf("test prompt %q already exists", prompt.Name))
+			return nil, false
+		}
+	}
+	return prompt, true
+}
+
+// validateTestPrompt 名称与内容必填，内容不超过 TEXT 列容量
+func validateTestPrompt(p *model.TestPrompt) error {
+	if p.Name == "" || strings.TrimSpace(p.Content) == "" {
+		return fmt.Errorf("name and content are required")
+	}
+	if len(p.Name) > 191 || len(p.Description) > 191 {
+		return fmt.Errorf("name and description must be at most 191 characters")
+	}
+	if len(p.Content) > maxTestPromptContentBytes {
+		return fmt.Errorf("content must be at most %d bytes", maxTestPromptContentBytes)
+	}
+	if p.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be >= 0")
+	}
+	return nil
+}
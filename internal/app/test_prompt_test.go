@@ -0,0 +1,166 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTestPrompts_CRUDAndChannelTest(t *testing.T) {
+	var mu sync.Mutex
+	var lastBody map[string]any
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		_ = json.Unmarshal(raw, &lastBody)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"pong"}],"usage":{"input_tokens":3,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	srv, cleanup := setupTestServer(t)
+	defer cleanup()
+	srv.client = upstream.Client()
+
+	ctx := context.Background()
+	cfg, err := srv.store.CreateConfig(ctx, &model.Config{
+		Name: "prompt-ch", URL: upstream.URL, Priority: 1, Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "test-model"}},
+	})
+	if err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+	if err := srv.store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: cfg.ID, APIKey: "sk-test", KeyStrategy: model.KeyStrategySequential},
+	}); err != nil {
+		t.Fatalf("创建Key失败: %v", err)
+	}
+
+	call := func(handler gin.HandlerFunc, method, path, body string, params gin.Params) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		handler(c)
+		return w
+	}
+	channelTest := func(body string) (*httptest.ResponseRecorder, string, float64) {
+		w := call(srv.HandleChannelTest, http.MethodPost, "/admin/channels/x/test", body,
+			gin.Params{{Key: "id", Value: strconv.FormatInt(cfg.ID, 10)}})
+		mu.Lock()
+		defer mu.Unlock()
+		if lastBody == nil {
+			return w, "", 0
+		}
+		msgs, _ := lastBody["messages"].([]any)
+		content := ""
+		if len(msgs) > 0 {
+			raw, _ := json.Marshal(msgs[0])
+			content = string(raw)
+		}
+		maxTokens, _ := lastBody["max_tokens"].(float64)
+		lastBody = nil
+		return w, content, maxTokens
+	}
+
+	// 迁移写入内置提示词，short-ping 为默认
+	prompts, err := srv.store.ListTestPrompts(ctx)
+	if err != nil {
+		t.Fatalf("列出提示词失败: %v", err)
+	}
+	var names []string
+	for _, p := range prompts {
+		names = append(names, p.Name)
+	}
+	if strings.Join(names, ",") != "short-ping,chinese-text-check,long-context-check,tool-use-check" || !prompts[0].IsDefault {
+		t.Fatalf("内置提示词不符: %v", names)
+	}
+	if long := prompts[2]; len(long.Content) < 20000 || !strings.Contains(long.Content, "AMBER-FALCON-7319") {
+		t.Fatalf("长上下文提示词不符: %d bytes", len(long.Content))
+	}
+
+	// 未指定提示词与内容时使用默认提示词（含其 max_tokens）
+	w, content, maxTokens := channelTest(`{"model":"test-model"}`)
+	if w.Code != http.StatusOK || !strings.Contains(content, "pong") || maxTokens != 16 ||
+		!strings.Contains(w.Body.String(), `"test_prompt":"short-ping"`) {
+		t.Fatalf("默认提示词未生效: %d %s content=%s max=%v", w.Code, w.Body.String(), content, maxTokens)
+	}
+	// 显式 content 不走提示词库
+	if w, content, _ := channelTest(`{"model":"test-model","content":"custom text"}`); !strings.Contains(content, "custom text") ||
+		strings.Contains(w.Body.String(), "test_prompt") {
+		t.Fatalf("显式内容应原样使用: %s %s", content, w.Body.String())
+	}
+	// 按名称选用，请求 max_tokens 优先
+	if _, content, maxTokens := channelTest(`{"model":"test-model","prompt":"chinese-text-check","max_tokens":64}`); !strings.Contains(content, "中国的首都") || maxTokens != 64 {
+		t.Fatalf("按名称选用提示词失败: %s max=%v", content, maxTokens)
+	}
+	if w, _, _ := channelTest(`{"model":"test-model","prompt":"missing"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("不存在的提示词应返回404: %d", w.Code)
+	}
+
+	for body, want := range map[string]int{
+		`{"name":"x"}`:                                http.StatusBadRequest,
+		`{"name":"x","content":"  "}`:                 http.StatusBadRequest,
+		`{"name":"x","content":"hi","max_tokens":-1}`: http.StatusBadRequest,
+		`{"name":"short-ping","content":"hi"}`:        http.StatusConflict,
+	} {
+		if w := call(srv.HandleCreateTestPrompt, http.MethodPost, "/admin/test-prompts", body, nil); w.Code != want {
+			t.Fatalf("%s: 期望 %d，实际 %d %s", body, want, w.Code, w.Body.String())
+		}
+	}
+	w = call(srv.HandleCreateTestPrompt, http.MethodPost, "/admin/test-prompts",
+		`{"name":"team-check","content":"team prompt","description":"团队","is_default":true}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建提示词失败: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data model.TestPrompt `json:"data"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+
+	// 新默认提示词取代 short-ping，按ID选用
+	if prompts, _ := srv.store.ListTestPrompts(ctx); prompts[0].Name != "team-check" || prompts[1].IsDefault {
+		t.Fatalf("默认提示词应唯一: %+v %+v", prompts[0], prompts[1])
+	}
+	if _, content, _ := channelTest(`{"model":"test-model"}`); !strings.Contains(content, "team prompt") {
+		t.Fatalf("新默认提示词未生效: %s", content)
+	}
+	if _, content, _ := channelTest(`{"model":"test-model","prompt_id":` + strconv.FormatInt(created.Data.ID, 10) + `}`); !strings.Contains(content, "team prompt") {
+		t.Fatalf("按ID选用提示词失败: %s", content)
+	}
+
+	id := gin.Params{{Key: "id", Value: strconv.FormatInt(created.Data.ID, 10)}}
+	if w := call(srv.HandleUpdateTestPrompt, http.MethodPut, "/admin/test-prompts/x",
+		`{"name":"tool-use-check","content":"x"}`, id); w.Code != http.StatusConflict {
+		t.Fatalf("改名冲突应返回409: %d", w.Code)
+	}
+	if w := call(srv.HandleUpdateTestPrompt, http.MethodPut, "/admin/test-prompts/x",
+		`{"name":"team-check","content":"team prompt v2"}`, id); w.Code != http.StatusOK {
+		t.Fatalf("更新提示词失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(srv.HandleDeleteTestPrompt, http.MethodDelete, "/admin/test-prompts/x", "", id); w.Code != http.StatusOK {
+		t.Fatalf("删除提示词失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(srv.HandleUpdateTestPrompt, http.MethodPut, "/admin/test-prompts/x",
+		`{"name":"team-check","content":"x"}`, id); w.Code != http.StatusNotFound {
+		t.Fatalf("更新不存在的提示词应返回404: %d", w.Code)
+	}
+
+	// 没有默认提示词时回退到内置兜底内容
+	if _, content, _ := channelTest(`{"model":"test-model"}`); !strings.Contains(content, `"`+fallbackTestContent+`"`) {
+		t.Fatalf("应回退到兜底内容: %s", content)
+	}
+}
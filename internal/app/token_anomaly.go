@@ -0,0 +1,247 @@
+package app
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 输出Token异常检测（2026-10新增）
+// ============================================================================
+// 失控的Agent循环会让单个令牌在短时间内产生大量超长输出，费用悄悄累积。
+// - 运行时：按 令牌ID+模型 维护输出Token的EWMA基线，单次输出超过 基线×倍数 即告警
+// - 可选限流：token_anomaly_cap_tokens>0 时，异常后一段时间内该令牌+模型的请求注入 max_tokens 上限
+// - 日报：GET /admin/token-anomalies?date=YYYY-MM-DD，基于logs表与前7天基线对比（重启不丢失）
+
+const (
+	defaultTokenAnomalyMultiplier      = 10
+	defaultTokenAnomalyMinOutputTokens = 8000
+
+	tokenAnomalyMinSamples     = 20               // 基线样本不足时不判定
+	tokenAnomalyEWMAAlpha      = 0.1              // 基线平滑系数
+	tokenAnomalyAlertInterval  = 10 * time.Minute // 同一令牌+模型的告警最小间隔
+	tokenAnomalyCapWindow      = 30 * time.Minute // 异常后注入 max_tokens 上限的持续时间
+	tokenAnomalyBaselineWindow = 7 * 24 * time.Hour
+	tokenAnomalyReportLimit    = 500
+)
+
+// tokenAnomalyKey 基线维度
+type tokenAnomalyKey struct {
+	tokenID int64
+	model   string
+}
+
+// tokenAnomalyStats 单个维度的基线状态
+type tokenAnomalyStats struct {
+	samples   int
+	mean      float64
+	lastAlert time.Time
+	capUntil  time.Time
+}
+
+// tokenAnomalyDetector 运行时输出Token异常检测器
+type tokenAnomalyDetector struct {
+	multiplier int   // 判定倍数（<=0 表示关闭）
+	minOutput  int64 // 绝对下限
+	capTokens  int   // 异常后注入的 max_tokens 上限（0=仅告警）
+
+	mu    sync.Mutex
+	stats map[tokenAnomalyKey]*tokenAnomalyStats
+}
+
+func newTokenAnomalyDetector(multiplier int, minOutput int64, capTokens int) *tokenAnomalyDetector {
+	return &tokenAnomalyDetector{
+		multiplier: multiplier,
+		minOutput:  minOutput,
+		capTokens:  capTokens,
+		stats:      make(map[tokenAnomalyKey]*tokenAnomalyStats),
+	}
+}
+
+// observe 记录一次成功请求的输出Token
+// 返回 anomalous 表示本次输出异常，alert 表示需要输出告警（受告警间隔限制）
+// 异常样本不计入基线，避免失控循环把基线抬高后不再告警
+func (d *tokenAnomalyDetector) observe(tokenID int64, modelName string, output int64, now time.Time) (anomalous, alert bool, baseline float64) {
+	if d == nil || d.multiplier <= 0 || output <= 0 {
+		return false, false, 0
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := tokenAnomalyKey{tokenID: tokenID, model: modelName}
+	st := d.stats[key]
+	if st == nil {
+		st = &tokenAnomalyStats{}
+		d.stats[key] = st
+	}
+
+	baseline = st.mean
+	if st.samples >= tokenAnomalyMinSamples && output >= d.minOutput && float64(output) > baseline*float64(d.multiplier) {
+		if d.capTokens > 0 {
+			st.capUntil = now.Add(tokenAnomalyCapWindow)
+		}
+		if now.Sub(st.lastAlert) >= tokenAnomalyAlertInterval {
+			st.lastAlert = now
+			alert = true
+		}
+		return true, alert, baseline
+	}
+
+	// 样本不足时用算术平均快速建立基线，之后切换为EWMA
+	st.samples++
+	if st.samples <= tokenAnomalyMinSamples {
+		st.mean += (float64(output) - st.mean) / float64(st.samples)
+	} else {
+		st.mean += tokenAnomalyEWMAAlpha * (float64(output) - st.mean)
+	}
+	return false, false, baseline
+}
+
+// capFor 返回当前令牌+模型需要注入的 max_tokens 上限（0=不限制）
+func (d *tokenAnomalyDetector) capFor(tokenID int64, modelName string, now time.Time) int {
+	if d == nil || d.capTokens <= 0 {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if st := d.stats[tokenAnomalyKey{tokenID: tokenID, model: modelName}]; st != nil && now.Before(st.capUntil) {
+		return d.capTokens
+	}
+	return 0
+}
+
+// observeTokenAnomaly 成功请求后更新基线并在异常时告警
+func (s *Server) observeTokenAnomaly(reqCtx *proxyRequestContext, cfg *model.Config, res *fwResult) {
+	anomalous, alert, baseline := s.tokenAnomaly.observe(reqCtx.tokenID, reqCtx.originalModel, int64(res.OutputTokens), time.Now())
+	if !anomalous || !alert {
+		return
+	}
+	log.Printf("[WARN] [ALERT] [Token异常] 令牌ID=%d, 模型=%s, 渠道ID=%d, 输出Token=%d, 基线均值=%.0f (%.1fx)，疑似失控循环",
+		reqCtx.tokenID, reqCtx.originalModel, cfg.ID, res.OutputTokens, baseline, float64(res.OutputTokens)/baseline)
+}
+
+// capMaxTokensInBody 将请求体的输出上限字段压到 limit 以内，缺失时按请求路径注入
+// 支持：max_tokens（Anthropic/OpenAI Chat）、max_completion_tokens、max_output_tokens（Responses）、
+// generationConfig.maxOutputTokens（Gemini）
+func capMaxTokensInBody(body []byte, requestPath string, limit int) ([]byte, bool) {
+	var req map[string]any
+	if err := sonic.Unmarshal(body, &req); err != nil || req == nil {
+		return body, false
+	}
+
+	changed, found := false, false
+	capField := func(m map[string]any, field string) {
+		v, ok := m[field].(float64)
+		if !ok {
+			return
+		}
+		found = true
+		if v > float64(limit) {
+			m[field] = limit
+			changed = true
+		}
+	}
+	for _, field := range []string{"max_tokens", "max_completion_tokens", "max_output_tokens"} {
+		capField(req, field)
+	}
+	if gc, ok := req["generationConfig"].(map[string]any); ok {
+		capField(gc, "maxOutputTokens")
+	}
+
+	if !found {
+		switch {
+		case strings.HasPrefix(requestPath, "/v1beta/"):
+			gc, _ := req["generationConfig"].(map[string]any)
+			if gc == nil {
+				gc = make(map[string]any)
+				req["generationConfig"] = gc
+			}
+			gc["maxOutputTokens"] = limit
+		case requestPath == "/v1/responses":
+			req["max_output_tokens"] = limit
+		default:
+			req["max_tokens"] = limit
+		}
+		changed = true
+	}
+
+	if !changed {
+		return body, false
+	}
+	modified, err := sonic.Marshal(req)
+	if err != nil {
+		return body, false
+	}
+	return modified, true
+}
+
+// applyTokenAnomalyCap 对处于异常限流窗口的令牌+模型注入 max_tokens 上限
+func (s *Server) applyTokenAnomalyCap(tokenID int64, modelName, requestPath string, body []byte) []byte {
+	limit := s.tokenAnomaly.capFor(tokenID, modelName, time.Now())
+	if limit <= 0 {
+		return body
+	}
+	capped, ok := capMaxTokensInBody(body, requestPath, limit)
+	if ok {
+		log.Printf("[INFO] [Token异常限流] 令牌ID=%d, 模型=%s, 已注入max_tokens上限=%d", tokenID, modelName, limit)
+	}
+	return capped
+}
+
+// HandleTokenAnomalies 输出Token异常日报
+// GET /admin/token-anomalies?date=YYYY-MM-DD（默认今天，按服务器时区）
+func (s *Server) HandleTokenAnomalies(c *gin.Context) {
+	day := time.Now()
+	if v := strings.TrimSpace(c.Query("date")); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+			return
+		}
+		day = parsed
+	}
+	since := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	until := since.AddDate(0, 0, 1)
+
+	multiplier := defaultTokenAnomalyMultiplier
+	minOutput := int64(defaultTokenAnomalyMinOutputTokens)
+	if s.tokenAnomaly != nil && s.tokenAnomaly.multiplier > 0 {
+		multiplier = s.tokenAnomaly.multiplier
+		minOutput = s.tokenAnomaly.minOutput
+	}
+
+	anomalies, err := s.store.ListTokenAnomalies(c.Request.Context(), model.TokenAnomalyQuery{
+		Since:           since,
+		Until:           until,
+		BaselineSince:   since.Add(-tokenAnomalyBaselineWindow),
+		Multiplier:      float64(multiplier),
+		MinOutputTokens: minOutput,
+		MinSamples:      tokenAnomalyMinSamples,
+		Limit:           tokenAnomalyReportLimit,
+	})
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	summary := TokenAnomalyReport{
+		Date:            since.Format("2006-01-02"),
+		Multiplier:      multiplier,
+		MinOutputTokens: minOutput,
+		Anomalies:       anomalies,
+	}
+	for _, a := range anomalies {
+		summary.TotalOutputTokens += a.OutputTokens
+		summary.TotalCost += a.Cost
+	}
+	RespondJSON(c, http.StatusOK, summary)
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenAnomalyDetector(t *testing.T) {
+	d := newTokenAnomalyDetector(10, 8000, 4096)
+	now := time.Now()
+
+	// 样本不足时不判定
+	if anomalous, _, _ := d.observe(1, "m", 50000, now); anomalous {
+		t.Fatal("should not flag before baseline is established")
+	}
+	d = newTokenAnomalyDetector(10, 8000, 4096)
+	for range tokenAnomalyMinSamples {
+		d.observe(1, "m", 1000, now)
+	}
+
+	// 超过倍数但低于绝对下限：不告警
+	if anomalous, _, _ := d.observe(1, "m", 7999, now); anomalous {
+		t.Fatal("output below min_output_tokens should not be flagged")
+	}
+
+	anomalous, alert, baseline := d.observe(1, "m", 50000, now)
+	if !anomalous || !alert {
+		t.Fatalf("expected anomaly with alert, got anomalous=%v alert=%v", anomalous, alert)
+	}
+	if baseline < 1000 || baseline > 2000 { // 7999 未达下限，已计入基线
+		t.Fatalf("unexpected baseline %.1f", baseline)
+	}
+
+	// 告警节流 + 异常样本不抬高基线
+	anomalous, alert, _ = d.observe(1, "m", 50000, now.Add(time.Minute))
+	if !anomalous || alert {
+		t.Fatalf("expected throttled alert, got anomalous=%v alert=%v", anomalous, alert)
+	}
+
+	// 限流窗口
+	if got := d.capFor(1, "m", now.Add(time.Minute)); got != 4096 {
+		t.Fatalf("capFor=%d, want 4096", got)
+	}
+	if got := d.capFor(1, "m", now.Add(time.Minute+tokenAnomalyCapWindow+time.Second)); got != 0 {
+		t.Fatalf("cap should expire, got %d", got)
+	}
+	if got := d.capFor(2, "m", now); got != 0 {
+		t.Fatalf("other tokens must not be capped, got %d", got)
+	}
+
+	// 关闭时无副作用
+	var off *tokenAnomalyDetector
+	if anomalous, _, _ := off.observe(1, "m", 1, now); anomalous || off.capFor(1, "m", now) != 0 {
+		t.Fatal("nil detector must be a no-op")
+	}
+}
+
+func TestCapMaxTokensInBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		want    string // 期望出现在结果中的片段
+		changed bool
+	}{
+		{"超过上限被压低", "/v1/messages", `{"model":"m","max_tokens":32000}`, `"max_tokens":4096`, true},
+		{"低于上限不变", "/v1/messages", `{"model":"m","max_tokens":100}`, `"max_tokens":100`, false},
+		{"chat缺失时注入", "/v1/chat/completions", `{"model":"m"}`, `"max_tokens":4096`, true},
+		{"responses注入", "/v1/responses", `{"model":"m"}`, `"max_output_tokens":4096`, true},
+		{"gemini注入", "/v1beta/models/g:generateContent", `{"contents":[]}`, `"maxOutputTokens":4096`, true},
+		{"gemini压低", "/v1beta/models/g:generateContent", `{"generationConfig":{"maxOutputTokens":65536}}`, `"maxOutputTokens":4096`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := capMaxTokensInBody([]byte(tt.body), tt.path, 4096)
+			if changed != tt.changed {
+				t.Fatalf("changed=%v, want %v", changed, tt.changed)
+			}
+			if !strings.Contains(string(got), tt.want) {
+				t.Fatalf("result %s does not contain %s", got, tt.want)
+			}
+		})
+	}
+}
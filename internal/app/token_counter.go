@@ -2,11 +2,13 @@ package app
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // CountTokensRequest 符合Anthropic官方API规范的请求结构
@@ -42,10 +44,8 @@ type CountTokensResponse struct {
 // - 向后兼容: 支持所有Claude模型和消息格式
 // - 本地计算: 避免引入复杂依赖
 func (s *Server) handleCountTokens(c *gin.Context) {
-	var req CountTokensRequest
-
-	// 解析请求体
-	if err := c.ShouldBindJSON(&req); err != nil {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": gin.H{
 				"type":    "invalid_request_error",
@@ -55,24 +55,44 @@ func (s *Server) handleCountTokens(c *gin.Context) {
 		return
 	}
 
+	// 相同请求体的并发/短时重复调用共享一次计算
+	res := s.countTokens.do(body, func() countTokensResult {
+		return computeCountTokens(body)
+	})
+	c.JSON(res.status, res.body)
+}
+
+// computeCountTokens 解析请求体并计算token数量，返回完整响应（结果只取决于请求体）
+func computeCountTokens(body []byte) countTokensResult {
+	var req CountTokensRequest
+
+	// 解析请求体
+	if err := binding.JSON.BindBody(body, &req); err != nil {
+		return countTokensResult{status: http.StatusBadRequest, body: gin.H{
+			"error": gin.H{
+				"type":    "invalid_request_error",
+				"message": fmt.Sprintf("Invalid request body: %v", err),
+			},
+		}}
+	}
+
 	// 验证模型参数（支持所有Claude模型）
 	if !isValidClaudeModel(req.Model) {
-		c.JSON(http.StatusBadRequest, gin.H{
+		return countTokensResult{status: http.StatusBadRequest, body: gin.H{
 			"error": gin.H{
 				"type":    "invalid_request_error",
 				"message": fmt.Sprintf("Invalid model: %s", req.Model),
 			},
-		})
-		return
+		}}
 	}
 
-	// 计算token数量
-	tokenCount := estimateTokens(&req)
+	// 计算token数量（按模型选择估算引擎）
+	tokenCount := estimateTokensWith(&req, selectTokenEstimator("", req.Model))
 
 	// 返回符合官方API格式的响应
-	c.JSON(http.StatusOK, CountTokensResponse{
+	return countTokensResult{status: http.StatusOK, body: CountTokensResponse{
 		InputTokens: tokenCount,
-	})
+	}}
 }
 
 // estimateTokens 估算消息的token数量
@@ -82,7 +102,13 @@ func (s *Server) handleCountTokens(c *gin.Context) {
 // - 工具开销: 每个工具定义约50-200 tokens
 //
 // 注意：此为快速估算，与官方tokenizer可能有±10%误差
+// 文本部分使用 heuristic 引擎；其他引擎见 token_estimator.go
 func estimateTokens(req *CountTokensRequest) int {
+	return estimateTokensWith(req, heuristicEstimator{})
+}
+
+// estimateTokensWith 使用指定估算引擎计算（文本部分由引擎估算，结构开销共用）
+func estimateTokensWith(req *CountTokensRequest, est tokenEstimator) int {
 	totalTokens := 0
 
 	// 1. 系统提示词（system prompt）
@@ -92,13 +118,13 @@ func estimateTokens(req *CountTokensRequest) int {
 		case string:
 			// 字符串格式（旧版本兼容）
 			if sys != "" {
-				totalTokens += estimateTextTokens(sys)
+				totalTokens += est.TextTokens(sys)
 				totalTokens += 5 // 系统提示的固定开销
 			}
 		case []any:
 			// 数组格式（Beta版本）
 			for _, block := range sys {
-				totalTokens += estimateContentBlock(block)
+				totalTokens += estimateContentBlockWith(block, est)
 			}
 			totalTokens += 5 // 系统提示的固定开销
 		default:
@@ -118,11 +144,11 @@ func estimateTokens(req *CountTokensRequest) int {
 		switch content := msg.Content.(type) {
 		case string:
 			// 文本消息
-			totalTokens += estimateTextTokens(content)
+			totalTokens += est.TextTokens(content)
 		case []any:
 			// 复杂内容块（文本、图片、文档等）
 			for _, block := range content {
-				totalTokens += estimateContentBlock(block)
+				totalTokens += estimateContentBlockWith(block, est)
 			}
 		default:
 			// 其他格式：保守估算为JSON长度
@@ -163,7 +189,7 @@ func estimateTokens(req *CountTokensRequest) int {
 			totalTokens += nameTokens
 
 			// 工具描述
-			totalTokens += estimateTextTokens(tool.Description)
+			totalTokens += est.TextTokens(tool.Description)
 
 			// 工具schema（JSON Schema）
 			if tool.InputSchema != nil {
@@ -296,6 +322,11 @@ func estimateTextTokens(text string) int {
 // - image: 图片（固定1000 tokens估算）
 // - document: 文档（根据大小估算）
 func estimateContentBlock(block any) int {
+	return estimateContentBlockWith(block, heuristicEstimator{})
+}
+
+// estimateContentBlockWith 使用指定估算引擎估算内容块
+func estimateContentBlockWith(block any, est tokenEstimator) int {
 	blockMap, ok := block.(map[string]any)
 	if !ok {
 		return 10 // 未知格式，保守估算
@@ -307,7 +338,7 @@ func estimateContentBlock(block any) int {
 	case "text":
 		// 文本块
 		if text, ok := blockMap["text"].(string); ok {
-			return estimateTextTokens(text)
+			return est.TextTokens(text)
 		}
 		return 10
 
@@ -332,7 +363,7 @@ func estimateContentBlock(block any) int {
 	case "tool_result":
 		// 工具执行结果
 		if content, ok := blockMap["content"].(string); ok {
-			return estimateTextTokens(content)
+			return est.TextTokens(content)
 		}
 		return 50
 
@@ -0,0 +1,343 @@
+package app
+
+import (
+	"cmp"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Token估算漂移告警（2026-10新增）
+// ============================================================================
+// 本地估算（count_tokens、预估费用等）依赖 selectTokenEstimator 选出的引擎，
+// 协议转换器或估算器的缺陷会让估算值与上游实际计费Token持续偏离，进而扭曲计费。
+// - 每个成功请求（上游报告了输入Token时）在后台用该渠道类型+模型的默认引擎估算请求体，
+//   按 渠道+模型 累计到分钟桶，滚动窗口为 token_drift_window_minutes
+// - 窗口内 (估算-实际)/实际 的绝对值超过 token_drift_threshold_pct 且样本足够时告警：
+//   输出 [ALERT] 日志并推送管理端 token_drift 事件（同一渠道+模型受告警间隔限制，回落后再次超过立即告警）
+// - GET /admin/metrics/token-drift 查看各 渠道+模型 的逐分钟漂移序列（仅内存，重启清空）
+
+const (
+	defaultTokenDriftThresholdPct  = 25
+	defaultTokenDriftWindowMinutes = 60
+	maxTokenDriftWindowMinutes     = 1440
+
+	tokenDriftMinSamples    = 20               // 窗口样本不足时不判定
+	tokenDriftAlertInterval = 30 * time.Minute // 持续漂移时同一渠道+模型的告警最小间隔
+	tokenDriftQueueSize     = 256              // 估算队列（满时丢弃）
+	tokenDriftMaxSeries     = 1000             // 渠道+模型组合上限（超出后新组合不再统计）
+)
+
+// tokenDriftKey 漂移统计维度
+type tokenDriftKey struct {
+	channelID int64
+	model     string
+}
+
+// tokenDriftBucket 单分钟累计
+type tokenDriftBucket struct {
+	minute    int64 // Unix分钟
+	samples   int64
+	estimated int64
+	actual    int64
+}
+
+// tokenDriftSeries 单个 渠道+模型 的滚动窗口（环形分钟桶）
+type tokenDriftSeries struct {
+	channelName string
+	channelType string
+	estimator   string
+	buckets     []tokenDriftBucket
+	alerting    bool
+	lastAlert   time.Time
+}
+
+// totals 窗口内（截至 nowMinute）的累计
+func (s *tokenDriftSeries) totals(nowMinute int64) (samples, estimated, actual int64) {
+	window := int64(len(s.buckets))
+	for _, b := range s.buckets {
+		if b.samples > 0 && nowMinute-b.minute < window {
+			samples += b.samples
+			estimated += b.estimated
+			actual += b.actual
+		}
+	}
+	return samples, estimated, actual
+}
+
+// tokenDriftPct 相对漂移百分比（正数表示高估）
+func tokenDriftPct(estimated, actual int64) float64 {
+	if actual <= 0 {
+		return 0
+	}
+	return float64(estimated-actual) / float64(actual) * 100
+}
+
+// tokenDriftSample 待估算的请求
+type tokenDriftSample struct {
+	channelID   int64
+	channelName string
+	channelType string
+	model       string
+	body        []byte
+	actual      int
+	at          time.Time
+}
+
+// TokenDriftAlert token_drift 事件数据
+type TokenDriftAlert struct {
+	ChannelID      int64   `json:"channel_id"`
+	ChannelName    string  `json:"channel_name"`
+	ChannelType    string  `json:"channel_type"`
+	Model          string  `json:"model"`
+	Estimator      string  `json:"estimator"`
+	WindowMinutes  int     `json:"window_minutes"`
+	Samples        int64   `json:"samples"`
+	EstimatedTotal int64   `json:"estimated_tokens"`
+	ActualTotal    int64   `json:"actual_tokens"`
+	DriftPct       float64 `json:"drift_pct"`
+	ThresholdPct   int     `json:"threshold_pct"`
+}
+
+// tokenDriftTracker 估算漂移跟踪器（nil 安全；thresholdPct<=0 表示关闭）
+type tokenDriftTracker struct {
+	thresholdPct int
+	window       int // 分钟
+	ch           chan tokenDriftSample
+
+	mu     sync.Mutex
+	series map[tokenDriftKey]*tokenDriftSeries
+}
+
+func newTokenDriftTracker(thresholdPct, windowMinutes int) *tokenDriftTracker {
+	return &tokenDriftTracker{
+		thresholdPct: thresholdPct,
+		window:       windowMinutes,
+		ch:           make(chan tokenDriftSample, tokenDriftQueueSize),
+		series:       make(map[tokenDriftKey]*tokenDriftSeries),
+	}
+}
+
+func (t *tokenDriftTracker) enabled() bool {
+	return t != nil && t.thresholdPct > 0 && t.window > 0
+}
+
+// offer 投递请求（非阻塞，队列满时丢弃）
+func (t *tokenDriftTracker) offer(sample tokenDriftSample) {
+	if !t.enabled() || sample.actual <= 0 || len(sample.body) == 0 || len(sample.body) > tokenEstimateMaxBodySize {
+		return
+	}
+	select {
+	case t.ch <- sample:
+	default:
+	}
+}
+
+// record 估算请求体并计入窗口，漂移超过阈值且需要告警时返回告警内容
+func (t *tokenDriftTracker) record(sample tokenDriftSample) *TokenDriftAlert {
+	est := selectTokenEstimator(sample.channelType, sample.model)
+	estimated := estimateBodyTokens(sample.body, est)
+	if estimated <= 0 {
+		return nil
+	}
+	return t.add(sample, est.Name(), int64(estimated))
+}
+
+// add 计入一次 估算/实际 对比（与估算分离，便于测试）
+func (t *tokenDriftTracker) add(sample tokenDriftSample, estimator string, estimated int64) *TokenDriftAlert {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := tokenDriftKey{channelID: sample.channelID, model: sample.model}
+	st := t.series[key]
+	if st == nil {
+		if len(t.series) >= tokenDriftMaxSeries {
+			return nil
+		}
+		st = &tokenDriftSeries{buckets: make([]tokenDriftBucket, t.window)}
+		t.series[key] = st
+	}
+	st.channelName, st.channelType, st.estimator = sample.channelName, sample.channelType, estimator
+
+	minute := sample.at.Unix() / 60
+	b := &st.buckets[minute%int64(t.window)]
+	if b.minute != minute {
+		*b = tokenDriftBucket{minute: minute}
+	}
+	b.samples++
+	b.estimated += estimated
+	b.actual += int64(sample.actual)
+
+	samples, estTotal, actualTotal := st.totals(minute)
+	drift := tokenDriftPct(estTotal, actualTotal)
+	if samples < tokenDriftMinSamples || math.Abs(drift) < float64(t.thresholdPct) {
+		st.alerting = false
+		return nil
+	}
+	if st.alerting && sample.at.Sub(st.lastAlert) < tokenDriftAlertInterval {
+		return nil
+	}
+	st.alerting = true
+	st.lastAlert = sample.at
+	return &TokenDriftAlert{
+		ChannelID:      sample.channelID,
+		ChannelName:    sample.channelName,
+		ChannelType:    sample.channelType,
+		Model:          sample.model,
+		Estimator:      estimator,
+		WindowMinutes:  t.window,
+		Samples:        samples,
+		EstimatedTotal: estTotal,
+		ActualTotal:    actualTotal,
+		DriftPct:       math.Round(drift*100) / 100,
+		ThresholdPct:   t.thresholdPct,
+	}
+}
+
+// tokenDriftWorker 后台估算（估算需要完整解析请求体，不放在请求路径上）
+func (s *Server) tokenDriftWorker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case sample := <-s.tokenDrift.ch:
+			if alert := s.tokenDrift.record(sample); alert != nil {
+				s.raiseTokenDriftAlert(alert)
+			}
+		}
+	}
+}
+
+// raiseTokenDriftAlert 输出告警日志并推送管理端事件
+func (s *Server) raiseTokenDriftAlert(a *TokenDriftAlert) {
+	log.Printf("[WARN] [ALERT] [Token估算漂移] 渠道ID=%d(%s), 模型=%s, 引擎=%s, 近%d分钟 %d 个请求估算 %d / 实际 %d (%+.1f%%，阈值 %d%%)，请检查协议转换或估算器",
+		a.ChannelID, a.ChannelName, a.Model, a.Estimator, a.WindowMinutes, a.Samples, a.EstimatedTotal, a.ActualTotal, a.DriftPct, a.ThresholdPct)
+	s.adminEvents.publish(AdminEventTokenDrift, a.ChannelID, *a)
+}
+
+// observeTokenDrift 成功请求后投递估算对比（上游报告的输入Token含缓存读取/写入）
+func (s *Server) observeTokenDrift(reqCtx *proxyRequestContext, cfg *model.Config, actualModel string, res *fwResult) {
+	if !s.tokenDrift.enabled() {
+		return
+	}
+	s.tokenDrift.offer(tokenDriftSample{
+		channelID:   cfg.ID,
+		channelName: cfg.Name,
+		channelType: cfg.GetChannelType(),
+		model:       actualModel,
+		body:        reqCtx.body,
+		actual:      res.InputTokens + res.CacheReadInputTokens + res.CacheCreationInputTokens,
+		at:          time.Now(),
+	})
+}
+
+// TokenDriftPoint 单分钟漂移
+type TokenDriftPoint struct {
+	Time      int64   `json:"time"` // 分钟起点（Unix秒）
+	Samples   int64   `json:"samples"`
+	Estimated int64   `json:"estimated_tokens"`
+	Actual    int64   `json:"actual_tokens"`
+	DriftPct  float64 `json:"drift_pct"`
+}
+
+// TokenDriftSeries 单个 渠道+模型 的漂移序列
+type TokenDriftSeries struct {
+	ChannelID   int64             `json:"channel_id"`
+	ChannelName string            `json:"channel_name"`
+	ChannelType string            `json:"channel_type"`
+	Model       string            `json:"model"`
+	Estimator   string            `json:"estimator"`
+	Samples     int64             `json:"samples"`
+	DriftPct    float64           `json:"drift_pct"` // 整个窗口的漂移
+	Alerting    bool              `json:"alerting"`
+	Points      []TokenDriftPoint `json:"points"`
+}
+
+// snapshot 窗口内的序列（channelID>0 时仅返回该渠道）
+func (t *tokenDriftTracker) snapshot(channelID int64, now time.Time) []TokenDriftSeries {
+	out := make([]TokenDriftSeries, 0)
+	if t == nil {
+		return out
+	}
+	nowMinute := now.Unix() / 60
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, st := range t.series {
+		if channelID > 0 && key.channelID != channelID {
+			continue
+		}
+		samples, estimated, actual := st.totals(nowMinute)
+		if samples == 0 {
+			continue
+		}
+		series := TokenDriftSeries{
+			ChannelID:   key.channelID,
+			ChannelName: st.channelName,
+			ChannelType: st.channelType,
+			Model:       key.model,
+			Estimator:   st.estimator,
+			Samples:     samples,
+			DriftPct:    math.Round(tokenDriftPct(estimated, actual)*100) / 100,
+			Alerting:    st.alerting,
+		}
+		for _, b := range st.buckets {
+			if b.samples == 0 || nowMinute-b.minute >= int64(len(st.buckets)) {
+				continue
+			}
+			series.Points = append(series.Points, TokenDriftPoint{
+				Time:      b.minute * 60,
+				Samples:   b.samples,
+				Estimated: b.estimated,
+				Actual:    b.actual,
+				DriftPct:  math.Round(tokenDriftPct(b.estimated, b.actual)*100) / 100,
+			})
+		}
+		slices.SortFunc(series.Points, func(a, b TokenDriftPoint) int { return cmp.Compare(a.Time, b.Time) })
+		out = append(out, series)
+	}
+	slices.SortFunc(out, func(a, b TokenDriftSeries) int {
+		if c := cmp.Compare(a.ChannelID, b.ChannelID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Model, b.Model)
+	})
+	return out
+}
+
+// HandleTokenDriftMetrics Token估算漂移序列
+// GET /admin/metrics/token-drift?channel_id=1
+func (s *Server) HandleTokenDriftMetrics(c *gin.Context) {
+	var channelID int64
+	if v := c.Query("channel_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			RespondErrorMsg(c, http.StatusBadRequest, "invalid channel_id")
+			return
+		}
+		channelID = id
+	}
+
+	resp := gin.H{
+		"enabled":        s.tokenDrift.enabled(),
+		"threshold_pct":  0,
+		"window_minutes": 0,
+		"min_samples":    tokenDriftMinSamples,
+		"series":         s.tokenDrift.snapshot(channelID, time.Now()),
+	}
+	if s.tokenDrift != nil {
+		resp["threshold_pct"] = s.tokenDrift.thresholdPct
+		resp["window_minutes"] = s.tokenDrift.window
+	}
+	RespondJSON(c, http.StatusOK, resp)
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenDriftTracker(t *testing.T) {
+	tr := newTokenDriftTracker(25, 10)
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(at time.Time) tokenDriftSample {
+		return tokenDriftSample{channelID: 1, channelName: "c1", channelType: "anthropic", model: "m", actual: 100, at: at}
+	}
+
+	// 偏差在阈值内：不告警
+	for i := range tokenDriftMinSamples {
+		if a := tr.add(sample(base.Add(time.Duration(i)*time.Second)), "heuristic", 110); a != nil {
+			t.Fatalf("漂移10%%不应告警: %+v", a)
+		}
+	}
+
+	// 持续高估到窗口整体超过25%：告警一次，随后受告警间隔限制
+	var alert *TokenDriftAlert
+	at := base.Add(time.Minute)
+	for i := range 40 {
+		at = base.Add(time.Minute + time.Duration(i)*time.Second)
+		if a := tr.add(sample(at), "heuristic", 200); a != nil {
+			if alert != nil {
+				t.Fatalf("告警间隔内不应重复告警: %+v", a)
+			}
+			alert = a
+		}
+	}
+	if alert == nil || alert.DriftPct < 25 || alert.ChannelID != 1 || alert.Model != "m" || alert.WindowMinutes != 10 {
+		t.Fatalf("期望漂移告警，实际 %+v", alert)
+	}
+
+	// 窗口滚动后旧样本移出：回落到阈值内后解除告警，再次超过时立即告警
+	later := at.Add(20 * time.Minute)
+	for i := range tokenDriftMinSamples {
+		if a := tr.add(sample(later.Add(time.Duration(i)*time.Second)), "heuristic", 100); a != nil {
+			t.Fatalf("无偏差样本不应告警: %+v", a)
+		}
+	}
+	var realert *TokenDriftAlert
+	for i := range 60 {
+		if a := tr.add(sample(later.Add(time.Minute+time.Duration(i)*time.Second)), "heuristic", 40); a != nil {
+			realert = a
+			break
+		}
+	}
+	if realert == nil || realert.DriftPct > -25 {
+		t.Fatalf("低估超过阈值应再次告警，实际 %+v", realert)
+	}
+
+	series := tr.snapshot(0, later.Add(2*time.Minute))
+	if len(series) != 1 || !series[0].Alerting || len(series[0].Points) != 2 {
+		t.Fatalf("序列快照不符: %+v", series)
+	}
+	if got := tr.snapshot(2, later); len(got) != 0 {
+		t.Fatalf("按渠道过滤后应为空: %+v", got)
+	}
+}
+
+func TestHandleTokenDriftMetrics(t *testing.T) {
+	srv := &Server{tokenDrift: newTokenDriftTracker(25, 60)}
+	srv.tokenDrift.add(tokenDriftSample{channelID: 3, model: "m", actual: 100, at: time.Now()}, "heuristic", 150)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/admin/metrics/token-drift?channel_id=3", nil)
+	srv.HandleTokenDriftMetrics(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Enabled bool               `json:"enabled"`
+			Series  []TokenDriftSeries `json:"series"`
+		} `json:"data"`
+	}
+	if err := sonic.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if !resp.Data.Enabled || len(resp.Data.Series) != 1 || resp.Data.Series[0].DriftPct != 50 {
+		t.Fatalf("响应不符: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/admin/metrics/token-drift?channel_id=x", nil)
+	srv.HandleTokenDriftMetrics(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("非法 channel_id 应返回400，实际 %d", w.Code)
+	}
+}
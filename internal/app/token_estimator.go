@@ -0,0 +1,399 @@
+package app
+
+import (
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 可插拔Token估算引擎（2026-10新增）
+// ============================================================================
+// estimateTextTokens 的字符比率针对 Claude 调校，对 OpenAI/Gemini 模型偏差较大。
+// 按渠道类型/模型选择估算引擎：
+// - heuristic：原有字符比率估算（Claude 及未知模型的兜底）
+// - tiktoken-cl100k / tiktoken-o200k：按 tiktoken 的预分词规则切分后逐段估算（不内置BPE词表）
+// - sentencepiece：按 SentencePiece 习惯（空白并入词首、数字逐位切分）估算 Gemini
+// 成功请求按比例采样，用各引擎估算请求体并与上游返回的输入Token对比，
+// GET /admin/token-estimators 查看各引擎在各渠道类型上的误差，便于调整选择。
+
+const (
+	tokenEstimatorHeuristic = "heuristic"
+	tokenEstimatorCL100K    = "tiktoken-cl100k"
+	tokenEstimatorO200K     = "tiktoken-o200k"
+	tokenEstimatorSentence  = "sentencepiece"
+
+	tokenEstimateSampleEvery  = 10        // 每N个成功请求采样一次
+	tokenEstimateMaxBodySize  = 512 << 10 // 超过该大小的请求体不采样
+	tokenEstimateQueueSize    = 64        // 采样队列（满时丢弃）
+	tokenEstimateImageTokens  = 1500      // 图片块固定估算（与 estimateContentBlock 一致）
+	tokenEstimateObjectTokens = 3         // 每个消息/内容对象的结构开销
+)
+
+// tokenEstimator 文本Token估算引擎
+type tokenEstimator interface {
+	Name() string
+	TextTokens(text string) int
+}
+
+// heuristicEstimator 原有字符比率估算
+type heuristicEstimator struct{}
+
+func (heuristicEstimator) Name() string               { return tokenEstimatorHeuristic }
+func (heuristicEstimator) TextTokens(text string) int { return estimateTextTokens(text) }
+
+// tiktokenPretokenize 近似 tiktoken cl100k/o200k 的预分词正则（RE2 不支持 \s+(?!\S) 前瞻，空白整段归为一片）
+var tiktokenPretokenize = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s+`)
+
+// bpeEstimator tiktoken 风格估算：预分词后，常见短词计1个token，长词按平均子词长度切分
+type bpeEstimator struct {
+	name          string
+	wordChars     int     // 单token可覆盖的最长词（含前导空格）
+	subwordChars  float64 // 长词的平均子词长度
+	cjkPerRune    float64 // CJK 字符的 token/字 比率
+	symbolsPerTok float64 // 标点符号串的平均字符数/token
+}
+
+func (e *bpeEstimator) Name() string { return e.name }
+
+func (e *bpeEstimator) TextTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	total := 0.0
+	for _, piece := range tiktokenPretokenize.FindAllString(text, -1) {
+		total += e.pieceTokens(piece)
+	}
+	return max(int(math.Round(total)), 1)
+}
+
+func (e *bpeEstimator) pieceTokens(piece string) float64 {
+	first, _ := utf8.DecodeRuneInString(strings.TrimLeft(piece, " "))
+	switch {
+	case strings.TrimSpace(piece) == "":
+		return 1
+	case unicode.IsDigit(first):
+		return 1 // 数字最多3位一段
+	case unicode.IsLetter(first):
+		if cjk := countCJKRunes(piece); cjk > 0 {
+			rest := utf8.RuneCountInString(piece) - cjk
+			return float64(cjk)*e.cjkPerRune + math.Ceil(float64(rest)/e.subwordChars)
+		}
+		n := len(piece)
+		if n <= e.wordChars {
+			return 1
+		}
+		return math.Ceil(float64(n) / e.subwordChars)
+	default:
+		return math.Max(1, math.Ceil(float64(utf8.RuneCountInString(strings.TrimSpace(piece)))/e.symbolsPerTok))
+	}
+}
+
+// sentencePieceEstimator Gemini 风格估算：空白切词并入词首，数字逐位成token，CJK 按字计
+type sentencePieceEstimator struct{}
+
+func (sentencePieceEstimator) Name() string { return tokenEstimatorSentence }
+
+func (sentencePieceEstimator) TextTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	total := 0.0
+	for _, word := range strings.Fields(text) {
+		letters := 0
+		symbols := 0
+		for _, r := range word {
+			switch {
+			case unicode.IsDigit(r):
+				total++
+			case isCJKRune(r):
+				total += 0.75
+			case unicode.IsLetter(r):
+				letters++
+			default:
+				symbols++
+			}
+		}
+		if letters > 0 {
+			total += math.Ceil(float64(letters+1) / 6) // +1：词首的▁
+		}
+		total += math.Ceil(float64(symbols) / 2)
+	}
+	// 换行在 SentencePiece 中单独成token
+	total += float64(strings.Count(text, "\n"))
+	return max(int(math.Round(total)), 1)
+}
+
+func isCJKRune(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+func countCJKRunes(s string) int {
+	n := 0
+	for _, r := range s {
+		if isCJKRune(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// tokenEstimators 已注册的估算引擎（按名称）
+var tokenEstimators = map[string]tokenEstimator{
+	tokenEstimatorHeuristic: heuristicEstimator{},
+	tokenEstimatorCL100K:    &bpeEstimator{name: tokenEstimatorCL100K, wordChars: 7, subwordChars: 4, cjkPerRune: 1.0, symbolsPerTok: 2},
+	tokenEstimatorO200K:     &bpeEstimator{name: tokenEstimatorO200K, wordChars: 8, subwordChars: 4.5, cjkPerRune: 0.75, symbolsPerTok: 2.5},
+	tokenEstimatorSentence:  sentencePieceEstimator{},
+}
+
+// tokenEstimatorNames 注册的引擎名称（稳定顺序）
+func tokenEstimatorNames() []string {
+	names := make([]string, 0, len(tokenEstimators))
+	for name := range tokenEstimators {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// selectTokenEstimator 按模型名优先、渠道类型其次选择估算引擎，均无法识别时使用 heuristic
+func selectTokenEstimator(channelType, modelName string) tokenEstimator {
+	m := strings.ToLower(modelName)
+	switch {
+	case strings.HasPrefix(m, "claude") || strings.HasPrefix(m, "anthropic."):
+		return tokenEstimators[tokenEstimatorHeuristic]
+	case strings.HasPrefix(m, "gpt-4o") || strings.HasPrefix(m, "gpt-4.1") || strings.HasPrefix(m, "gpt-5") ||
+		strings.HasPrefix(m, "chatgpt-") || strings.HasPrefix(m, "o1") || strings.HasPrefix(m, "o3") ||
+		strings.HasPrefix(m, "o4") || strings.HasPrefix(m, "codex-"):
+		return tokenEstimators[tokenEstimatorO200K]
+	case strings.HasPrefix(m, "gpt-") || strings.HasPrefix(m, "text-"):
+		return tokenEstimators[tokenEstimatorCL100K]
+	case strings.HasPrefix(m, "gemini") || strings.HasPrefix(m, "gemma"):
+		return tokenEstimators[tokenEstimatorSentence]
+	}
+
+	if channelType == "" {
+		return tokenEstimators[tokenEstimatorHeuristic]
+	}
+	switch util.NormalizeChannelType(channelType) {
+	case util.ChannelTypeOpenAI, util.ChannelTypeCodex, util.ChannelTypeAzure:
+		return tokenEstimators[tokenEstimatorO200K]
+	case util.ChannelTypeGemini:
+		return tokenEstimators[tokenEstimatorSentence]
+	}
+	return tokenEstimators[tokenEstimatorHeuristic]
+}
+
+// estimateBodyTokens 用指定引擎估算任意格式请求体（Anthropic/OpenAI/Responses/Gemini）的输入Token
+// 遍历JSON中的文本字段；工具定义整体按JSON文本估算；图片等二进制内容按固定值计
+func estimateBodyTokens(body []byte, est tokenEstimator) int {
+	var root any
+	if err := sonic.Unmarshal(body, &root); err != nil {
+		return 0
+	}
+	return estimateJSONTokens(root, est)
+}
+
+// tokenEstimateSkipKeys 不计入Token的字段（参数、标识、二进制数据）
+var tokenEstimateSkipKeys = map[string]bool{
+	"model": true, "stream": true, "type": true, "role": true, "id": true,
+	"tool_call_id": true, "tool_use_id": true, "signature": true, "data": true,
+	"media_type": true, "mime_type": true, "mimeType": true, "metadata": true,
+	"cache_control": true, "stream_options": true, "user": true,
+}
+
+func estimateJSONTokens(v any, est tokenEstimator) int {
+	switch val := v.(type) {
+	case string:
+		return est.TextTokens(val)
+	case []any:
+		total := 0
+		for _, item := range val {
+			total += estimateJSONTokens(item, est)
+		}
+		return total
+	case map[string]any:
+		switch val["type"] {
+		case "image", "image_url", "input_image", "document", "input_file":
+			return tokenEstimateImageTokens
+		}
+		if _, ok := val["inlineData"]; ok {
+			return tokenEstimateImageTokens
+		}
+		if _, ok := val["inline_data"]; ok {
+			return tokenEstimateImageTokens
+		}
+
+		total := tokenEstimateObjectTokens
+		for k, child := range val {
+			switch {
+			case tokenEstimateSkipKeys[k]:
+			case k == "tools" || k == "functionDeclarations":
+				if raw, err := sonic.Marshal(child); err == nil {
+					total += est.TextTokens(string(raw))
+				}
+			default:
+				total += estimateJSONTokens(child, est)
+			}
+		}
+		return total
+	}
+	return 0
+}
+
+// tokenEstimateKey 误差统计维度
+type tokenEstimateKey struct {
+	channelType string
+	estimator   string
+}
+
+// tokenEstimateStats 相对误差累计（(估算-实际)/实际）
+type tokenEstimateStats struct {
+	samples   int64
+	sumErr    float64
+	sumAbsErr float64
+}
+
+// tokenEstimateSample 待估算的采样
+type tokenEstimateSample struct {
+	channelType string
+	body        []byte
+	actual      int
+}
+
+// tokenEstimateTracker 估算引擎误差跟踪（nil 安全）
+type tokenEstimateTracker struct {
+	counter atomic.Uint64
+	ch      chan tokenEstimateSample
+
+	mu    sync.Mutex
+	stats map[tokenEstimateKey]*tokenEstimateStats
+}
+
+func newTokenEstimateTracker() *tokenEstimateTracker {
+	return &tokenEstimateTracker{
+		ch:    make(chan tokenEstimateSample, tokenEstimateQueueSize),
+		stats: make(map[tokenEstimateKey]*tokenEstimateStats),
+	}
+}
+
+// offer 采样投递（非阻塞，队列满时丢弃）
+func (t *tokenEstimateTracker) offer(sample tokenEstimateSample) {
+	if t == nil || sample.actual <= 0 || len(sample.body) == 0 || len(sample.body) > tokenEstimateMaxBodySize {
+		return
+	}
+	if t.counter.Add(1)%tokenEstimateSampleEvery != 1 {
+		return
+	}
+	select {
+	case t.ch <- sample:
+	default:
+	}
+}
+
+// record 用全部引擎估算采样并累计误差
+func (t *tokenEstimateTracker) record(sample tokenEstimateSample) {
+	estimates := make(map[string]int, len(tokenEstimators))
+	for name, est := range tokenEstimators {
+		estimates[name] = estimateBodyTokens(sample.body, est)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, estimate := range estimates {
+		if estimate <= 0 {
+			continue
+		}
+		key := tokenEstimateKey{channelType: sample.channelType, estimator: name}
+		st := t.stats[key]
+		if st == nil {
+			st = &tokenEstimateStats{}
+			t.stats[key] = st
+		}
+		relErr := float64(estimate-sample.actual) / float64(sample.actual)
+		st.samples++
+		st.sumErr += relErr
+		st.sumAbsErr += math.Abs(relErr)
+	}
+}
+
+// tokenEstimateWorker 后台估算采样（估算需要完整解析请求体，不放在请求路径上）
+func (s *Server) tokenEstimateWorker() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case sample := <-s.tokenEstimates.ch:
+			s.tokenEstimates.record(sample)
+		}
+	}
+}
+
+// observeTokenEstimate 成功请求后按比例采样，对比估算值与上游实际输入Token
+func (s *Server) observeTokenEstimate(reqCtx *proxyRequestContext, cfg *model.Config, res *fwResult) {
+	actual := res.InputTokens + res.CacheReadInputTokens + res.CacheCreationInputTokens
+	s.tokenEstimates.offer(tokenEstimateSample{
+		channelType: cfg.GetChannelType(),
+		body:        reqCtx.body,
+		actual:      actual,
+	})
+}
+
+// TokenEstimatorComparison 单个引擎在某渠道类型上的误差
+type TokenEstimatorComparison struct {
+	ChannelType     string  `json:"channel_type"`
+	Estimator       string  `json:"estimator"`
+	Selected        bool    `json:"selected"` // 该渠道类型默认使用的引擎
+	Samples         int64   `json:"samples"`
+	MeanAbsErrorPct float64 `json:"mean_abs_error_pct"`
+	MeanBiasPct     float64 `json:"mean_bias_pct"` // 正数表示高估
+}
+
+// HandleTokenEstimators 估算引擎误差对比
+// GET /admin/token-estimators
+func (s *Server) HandleTokenEstimators(c *gin.Context) {
+	comparisons := make([]TokenEstimatorComparison, 0)
+	if t := s.tokenEstimates; t != nil {
+		t.mu.Lock()
+		for key, st := range t.stats {
+			if st.samples == 0 {
+				continue
+			}
+			comparisons = append(comparisons, TokenEstimatorComparison{
+				ChannelType:     key.channelType,
+				Estimator:       key.estimator,
+				Selected:        selectTokenEstimator(key.channelType, "").Name() == key.estimator,
+				Samples:         st.samples,
+				MeanAbsErrorPct: math.Round(st.sumAbsErr/float64(st.samples)*10000) / 100,
+				MeanBiasPct:     math.Round(st.sumErr/float64(st.samples)*10000) / 100,
+			})
+		}
+		t.mu.Unlock()
+	}
+	slices.SortFunc(comparisons, func(a, b TokenEstimatorComparison) int {
+		if c := strings.Compare(a.ChannelType, b.ChannelType); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Estimator, b.Estimator)
+	})
+
+	RespondJSON(c, http.StatusOK, gin.H{
+		"estimators":   tokenEstimatorNames(),
+		"sample_every": tokenEstimateSampleEvery,
+		"comparisons":  comparisons,
+	})
+}
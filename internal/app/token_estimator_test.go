@@ -0,0 +1,147 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSelectTokenEstimator(t *testing.T) {
+	tests := []struct {
+		channelType string
+		model       string
+		want        string
+	}{
+		{"anthropic", "claude-sonnet-4-5", tokenEstimatorHeuristic},
+		{"openai", "gpt-4o-mini", tokenEstimatorO200K},
+		{"openai", "o3-mini", tokenEstimatorO200K},
+		{"openai", "gpt-4-turbo", tokenEstimatorCL100K},
+		{"gemini", "gemini-2.5-pro", tokenEstimatorSentence},
+		{"codex", "some-custom-model", tokenEstimatorO200K},
+		{"gemini", "custom-model", tokenEstimatorSentence},
+		{"anthropic", "custom-model", tokenEstimatorHeuristic},
+		{"", "unknown", tokenEstimatorHeuristic},
+		// 模型名优先于渠道类型
+		{"openai", "claude-3-5-haiku", tokenEstimatorHeuristic},
+	}
+	for _, tt := range tests {
+		if got := selectTokenEstimator(tt.channelType, tt.model).Name(); got != tt.want {
+			t.Errorf("selectTokenEstimator(%q, %q) = %s, want %s", tt.channelType, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestTokenEstimators_TextTokens(t *testing.T) {
+	english := "The quick brown fox jumps over the lazy dog. Internationalization is a long word."
+	chinese := "今天天气很好，我们一起去公园散步吧。"
+
+	for _, name := range tokenEstimatorNames() {
+		est := tokenEstimators[name]
+		if got := est.TextTokens(""); got != 0 {
+			t.Errorf("%s: empty text = %d, want 0", name, got)
+		}
+		// 英文约 15-25 token，中文约 12-20 token：只校验量级合理
+		if got := est.TextTokens(english); got < 10 || got > 35 {
+			t.Errorf("%s: english = %d, out of plausible range", name, got)
+		}
+		if got := est.TextTokens(chinese); got < 8 || got > 30 {
+			t.Errorf("%s: chinese = %d, out of plausible range", name, got)
+		}
+	}
+
+	// SentencePiece 数字逐位切分，tiktoken 3位一段
+	digits := "1234567890"
+	if sp, bpe := tokenEstimators[tokenEstimatorSentence].TextTokens(digits), tokenEstimators[tokenEstimatorCL100K].TextTokens(digits); sp != 10 || bpe != 4 {
+		t.Errorf("digits: sentencepiece=%d (want 10), cl100k=%d (want 4)", sp, bpe)
+	}
+}
+
+func TestEstimateBodyTokens_SkipsBinaryAndParams(t *testing.T) {
+	est := tokenEstimators[tokenEstimatorO200K]
+	text := strings.Repeat("hello world ", 50)
+
+	plain := `{"model":"gpt-4o","max_tokens":100,"messages":[{"role":"user","content":"` + text + `"}]}`
+	withImage := `{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"text","text":"` + text + `"},` +
+		`{"type":"image_url","image_url":{"url":"data:image/png;base64,` + strings.Repeat("QUFB", 20000) + `"}}]}]}`
+
+	base := estimateBodyTokens([]byte(plain), est)
+	if base < 100 || base > 130 {
+		t.Fatalf("plain body estimate = %d, want ~100", base)
+	}
+	if got := estimateBodyTokens([]byte(withImage), est); got != base+tokenEstimateObjectTokens+tokenEstimateImageTokens {
+		t.Fatalf("image body estimate = %d, want base(%d)+object+image", got, base)
+	}
+	if got := estimateBodyTokens([]byte("not json"), est); got != 0 {
+		t.Fatalf("invalid body estimate = %d, want 0", got)
+	}
+}
+
+func TestHandleTokenEstimators(t *testing.T) {
+	tracker := newTokenEstimateTracker()
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"` + strings.Repeat("hello world ", 50) + `"}]}`)
+	tracker.record(tokenEstimateSample{channelType: "openai", body: body, actual: 110})
+	tracker.record(tokenEstimateSample{channelType: "openai", body: body, actual: 110})
+
+	s := &Server{tokenEstimates: tracker}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/admin/token-estimators", nil)
+	s.HandleTokenEstimators(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var resp struct {
+		Data struct {
+			Estimators  []string                   `json:"estimators"`
+			Comparisons []TokenEstimatorComparison `json:"comparisons"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Estimators) != len(tokenEstimators) {
+		t.Fatalf("estimators = %v", resp.Data.Estimators)
+	}
+	if len(resp.Data.Comparisons) != len(tokenEstimators) {
+		t.Fatalf("comparisons = %+v", resp.Data.Comparisons)
+	}
+	selected := 0
+	for _, cmp := range resp.Data.Comparisons {
+		if cmp.ChannelType != "openai" || cmp.Samples != 2 {
+			t.Fatalf("unexpected comparison: %+v", cmp)
+		}
+		if cmp.Selected {
+			selected++
+			if cmp.Estimator != tokenEstimatorO200K {
+				t.Fatalf("openai should select %s, got %s", tokenEstimatorO200K, cmp.Estimator)
+			}
+		}
+	}
+	if selected != 1 {
+		t.Fatalf("expected exactly one selected estimator, got %d", selected)
+	}
+}
+
+func TestTokenEstimateTracker_Sampling(t *testing.T) {
+	tracker := newTokenEstimateTracker()
+	for range tokenEstimateSampleEvery * 3 {
+		tracker.offer(tokenEstimateSample{channelType: "anthropic", body: []byte(`{}`), actual: 10})
+	}
+	if got := len(tracker.ch); got != 3 {
+		t.Fatalf("queued samples = %d, want 3", got)
+	}
+	// 无实际用量或请求体过大时不采样
+	tracker.offer(tokenEstimateSample{channelType: "anthropic", body: []byte(`{}`), actual: 0})
+	tracker.offer(tokenEstimateSample{channelType: "anthropic", body: make([]byte, tokenEstimateMaxBodySize+1), actual: 10})
+	if got := len(tracker.ch); got != 3 {
+		t.Fatalf("queued samples = %d, want 3", got)
+	}
+
+	var nilTracker *tokenEstimateTracker
+	nilTracker.offer(tokenEstimateSample{body: []byte(`{}`), actual: 1}) // 不应panic
+}
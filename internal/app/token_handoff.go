@@ -0,0 +1,117 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 令牌一次性取回链接（2026-10新增）
+// ============================================================================
+// 创建令牌时指定 handoff_minutes，额外生成一次性取回链接，明文令牌可交给同事自行取回，
+// 无需粘贴到聊天工具中：
+//   - 链接形如 /web/handoff.html#<id>：id 位于URL片段，不会出现在服务端访问日志与链接预览请求中
+//   - 页面需点击"显示令牌"才会调用 POST /public/token-handoff/:id，取回后立即作废（单次查看）
+//   - 明文仅保存在内存中直到被取回/过期（重启即失效），数据库仍只保存哈希
+//   - 取回事件写入日志（令牌ID、来源IP）；删除令牌时同时作废其未取回的链接
+
+const (
+	tokenHandoffMaxMinutes = 24 * 60
+	tokenHandoffPagePath   = "/web/handoff.html#"
+)
+
+type tokenHandoff struct {
+	tokenID     int64
+	description string
+	plain       string
+	expiresAt   time.Time
+}
+
+// tokenHandoffStore 未取回的一次性链接（nil 时所有方法为空操作）
+type tokenHandoffStore struct {
+	mu      sync.Mutex
+	entries map[string]*tokenHandoff
+}
+
+func newTokenHandoffStore() *tokenHandoffStore {
+	return &tokenHandoffStore{entries: make(map[string]*tokenHandoff)}
+}
+
+// pruneLocked 清理已过期的链接（调用方持有锁）
+func (hs *tokenHandoffStore) pruneLocked(now time.Time) {
+	for id, h := range hs.entries {
+		if !now.Before(h.expiresAt) {
+			delete(hs.entries, id)
+		}
+	}
+}
+
+// create 生成一次性链接ID（32字节随机数）
+func (hs *tokenHandoffStore) create(tokenID int64, description, plain string, ttl time.Duration) (string, time.Time, error) {
+	if hs == nil {
+		return "", time.Time{}, errors.New("token handoff store not initialized")
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	id := hex.EncodeToString(b)
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.pruneLocked(now)
+	hs.entries[id] = &tokenHandoff{tokenID: tokenID, description: description, plain: plain, expiresAt: expiresAt}
+	return id, expiresAt, nil
+}
+
+// consume 取回并作废链接（不存在/已过期返回 nil）
+func (hs *tokenHandoffStore) consume(id string) *tokenHandoff {
+	if hs == nil {
+		return nil
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.pruneLocked(time.Now())
+	h := hs.entries[id]
+	delete(hs.entries, id)
+	return h
+}
+
+// revokeToken 作废指定令牌的全部未取回链接
+func (hs *tokenHandoffStore) revokeToken(tokenID int64) {
+	if hs == nil {
+		return
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	for id, h := range hs.entries {
+		if h.tokenID == tokenID {
+			delete(hs.entries, id)
+		}
+	}
+}
+
+// HandleConsumeTokenHandoff 通过一次性链接取回明文令牌（公开访问，取回后立即作废）
+// POST /public/token-handoff/:id
+func (s *Server) HandleConsumeTokenHandoff(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	h := s.tokenHandoffs.consume(c.Param("id"))
+	if h == nil {
+		RespondErrorMsg(c, http.StatusNotFound, "link is invalid, expired or already used")
+		return
+	}
+	log.Printf("[INFO] [令牌交接] 令牌ID=%d(%s) 已通过一次性链接取回，来源IP=%s", h.tokenID, h.description, c.ClientIP())
+	RespondJSON(c, http.StatusOK, gin.H{
+		"id":          h.tokenID,
+		"description": h.description,
+		"token":       h.plain,
+	})
+}
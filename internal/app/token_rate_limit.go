@@ -0,0 +1,162 @@
+package app
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 令牌速率限制（RPM/TPM，2026-10新增）
+// ============================================================================
+// 令牌配置 rpm_limit / tpm_limit（0=不限制）后，代理到上游的模型请求按滑动窗口计数器限流
+// （门户日志、令牌自省、模型列表等本地查询不计入）：
+//   - 估算值 = 上一分钟计数 × 上一分钟仍落在窗口内的比例 + 当前分钟计数
+//   - RPM：本次请求计入后超过上限则拒绝；TPM：窗口内已用Token数达到上限则拒绝
+//     （Token数在请求完成后才知道，按输入+输出Token计入，因此可能略超上限）
+//   - 拒绝时返回 429 与 Retry-After（估算值回落到上限以内所需秒数）
+// 计数仅在内存中维护（多实例部署时各自独立计数），令牌热更新不重置窗口。
+
+const tokenRateWindow = time.Minute
+
+// tokenRateLimit 令牌的速率上限（0=不限制）
+type tokenRateLimit struct {
+	rpm int
+	tpm int64
+}
+
+// slidingCounter 固定分钟窗口 + 上一窗口加权的滑动窗口计数器
+type slidingCounter struct {
+	windowStart int64 // 当前窗口起点（Unix秒，按分钟对齐）
+	cur, prev   int64
+}
+
+// advance 滚动到 now 所在窗口
+func (c *slidingCounter) advance(now time.Time) {
+	start := now.Truncate(tokenRateWindow).Unix()
+	switch {
+	case start == c.windowStart:
+		return
+	case start-c.windowStart == int64(tokenRateWindow/time.Second):
+		c.prev, c.cur = c.cur, 0
+	default:
+		c.prev, c.cur = 0, 0
+	}
+	c.windowStart = start
+}
+
+// estimate 滑动窗口估算值及当前窗口已过去的比例
+func (c *slidingCounter) estimate(now time.Time) (float64, float64) {
+	elapsed := float64(now.Unix()-c.windowStart) + float64(now.Nanosecond())/1e9
+	frac := min(elapsed/tokenRateWindow.Seconds(), 1)
+	return float64(c.prev)*(1-frac) + float64(c.cur), frac
+}
+
+// retryAfter 估算值 + need 回落到 limit 以内所需的等待时间（0 表示当前即可通过）
+func (c *slidingCounter) retryAfter(now time.Time, need, limit int64) time.Duration {
+	est, frac := c.estimate(now)
+	if est+float64(need) <= float64(limit) {
+		return 0
+	}
+	window := tokenRateWindow.Seconds()
+	var wait float64
+	if float64(c.cur+need) <= float64(limit) && c.prev > 0 {
+		// 当前窗口内等待上一窗口的权重衰减
+		target := 1 - float64(limit-need-c.cur)/float64(c.prev)
+		wait = (target - frac) * window
+	} else {
+		// 需要进入下一窗口，并等待当前窗口计数（届时成为上一窗口）衰减
+		wait = (1 - frac) * window
+		if c.cur > 0 {
+			wait += max(0, 1-float64(limit-need)/float64(c.cur)) * window
+		}
+	}
+	return time.Duration(math.Ceil(max(wait, 1))) * time.Second
+}
+
+// tokenRateState 单个令牌的请求/Token计数
+type tokenRateState struct {
+	requests slidingCounter
+	tokens   slidingCounter
+}
+
+// tokenRateLimiter 按令牌哈希维护滑动窗口计数
+type tokenRateLimiter struct {
+	mu     sync.Mutex
+	states map[string]*tokenRateState
+}
+
+func newTokenRateLimiter() *tokenRateLimiter {
+	return &tokenRateLimiter{states: make(map[string]*tokenRateState)}
+}
+
+func (l *tokenRateLimiter) state(tokenHash string, now time.Time) *tokenRateState {
+	st, ok := l.states[tokenHash]
+	if !ok {
+		st = &tokenRateState{}
+		l.states[tokenHash] = st
+	}
+	st.requests.advance(now)
+	st.tokens.advance(now)
+	return st
+}
+
+// allow 检查并计入一次请求；被拒绝时返回超限维度（rpm/tpm）与建议等待时间
+func (l *tokenRateLimiter) allow(tokenHash string, limit tokenRateLimit, now time.Time) (exceeded string, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st := l.state(tokenHash, now)
+	if limit.tpm > 0 {
+		if wait := st.tokens.retryAfter(now, 1, limit.tpm); wait > 0 {
+			return "tpm", wait
+		}
+	}
+	if limit.rpm > 0 {
+		if wait := st.requests.retryAfter(now, 1, int64(limit.rpm)); wait > 0 {
+			return "rpm", wait
+		}
+	}
+	st.requests.cur++
+	return "", 0
+}
+
+// addTokens 计入请求完成后的Token用量
+func (l *tokenRateLimiter) addTokens(tokenHash string, tokens int64, now time.Time) {
+	if tokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.state(tokenHash, now).tokens.cur += tokens
+}
+
+// usage 令牌当前滑动窗口内的请求数与Token数估算值（只读，不计入请求、不创建状态）
+func (l *tokenRateLimiter) usage(tokenHash string, now time.Time) (requests, tokens int64) {
+	l.mu.Lock()
+	st, ok := l.states[tokenHash]
+	var snap tokenRateState
+	if ok {
+		snap = *st
+	}
+	l.mu.Unlock()
+	if !ok {
+		return 0, 0
+	}
+	snap.requests.advance(now)
+	snap.tokens.advance(now)
+	reqEst, _ := snap.requests.estimate(now)
+	tokEst, _ := snap.tokens.estimate(now)
+	return int64(math.Ceil(reqEst)), int64(math.Ceil(tokEst))
+}
+
+// prune 清理不再受限的令牌状态（令牌热更新后调用）
+func (l *tokenRateLimiter) prune(limited map[string]tokenRateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for hash := range l.states {
+		if _, ok := limited[hash]; !ok {
+			delete(l.states, hash)
+		}
+	}
+}
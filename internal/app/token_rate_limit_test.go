@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestTokenRateLimiter_RPM 窗口内请求数达到上限后拒绝，滑动到下一分钟后按上一窗口权重逐步放行
+func TestTokenRateLimiter_RPM(t *testing.T) {
+	l := newTokenRateLimiter()
+	limit := tokenRateLimit{rpm: 3}
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := range 3 {
+		if exceeded, _ := l.allow("h", limit, base.Add(time.Duration(i)*time.Second)); exceeded != "" {
+			t.Fatalf("第%d次请求不应被限流: %s", i+1, exceeded)
+		}
+	}
+	exceeded, wait := l.allow("h", limit, base.Add(10*time.Second))
+	if exceeded != "rpm" {
+		t.Fatalf("期望rpm超限，实际 %q", exceeded)
+	}
+	if wait < time.Second || wait > 2*time.Minute {
+		t.Fatalf("Retry-After 不合理: %v", wait)
+	}
+
+	// 下一分钟开头：上一窗口3次仍几乎全额计入
+	if exceeded, _ := l.allow("h", limit, base.Add(61*time.Second)); exceeded != "rpm" {
+		t.Fatalf("下一分钟开头仍应限流，实际 %q", exceeded)
+	}
+	// 下一分钟过半：估算值 1.5，可再放行一次
+	if exceeded, _ := l.allow("h", limit, base.Add(90*time.Second)); exceeded != "" {
+		t.Fatalf("窗口滑动后应放行，实际 %q", exceeded)
+	}
+	// 间隔超过两分钟：计数清零
+	for i := range 3 {
+		if exceeded, _ := l.allow("h", limit, base.Add(5*time.Minute+time.Duration(i)*time.Second)); exceeded != "" {
+			t.Fatalf("计数清零后第%d次请求不应被限流", i+1)
+		}
+	}
+}
+
+// TestTokenRateLimiter_TPM 完成请求的Token用量计入后，窗口内达到上限即拒绝新请求
+func TestTokenRateLimiter_TPM(t *testing.T) {
+	l := newTokenRateLimiter()
+	limit := tokenRateLimit{tpm: 1000}
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	if exceeded, _ := l.allow("h", limit, base); exceeded != "" {
+		t.Fatalf("首个请求不应被限流: %s", exceeded)
+	}
+	l.addTokens("h", 1200, base.Add(time.Second))
+	exceeded, wait := l.allow("h", limit, base.Add(2*time.Second))
+	if exceeded != "tpm" {
+		t.Fatalf("期望tpm超限，实际 %q", exceeded)
+	}
+	if wait <= 58*time.Second {
+		t.Fatalf("Token数超出上限时需至少等到下一窗口，实际 %v", wait)
+	}
+
+	// prune 移除不再受限的令牌状态
+	l.prune(map[string]tokenRateLimit{})
+	if exceeded, _ := l.allow("h", limit, base.Add(3*time.Second)); exceeded != "" {
+		t.Fatalf("状态清理后应重新计数，实际 %q", exceeded)
+	}
+}
+
+// TestTokenRateLimit_OnlyProxiedModelRequests 门户日志、令牌自省、模型列表不占用RPM配额，只有代理的模型请求计数
+func TestTokenRateLimit_OnlyProxiedModelRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "rl.db"), nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	server := NewServer(store)
+	defer func() { _ = server.Shutdown(ctx) }()
+	r := gin.New()
+	server.SetupRoutes(r)
+
+	const plain = "rate-limited-token"
+	token := &model.AuthToken{Token: model.HashToken(plain), Description: "rl", IsActive: true, RPMLimit: 1}
+	if err := store.CreateAuthToken(ctx, token); err != nil {
+		t.Fatalf("CreateAuthToken failed: %v", err)
+	}
+	if err := server.authService.ReloadAuthTokens(); err != nil {
+		t.Fatalf("ReloadAuthTokens failed: %v", err)
+	}
+
+	call := func(method, path string) int {
+		var body io.Reader
+		if method == http.MethodPost {
+			body = strings.NewReader(`{"model":"claude-x","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`)
+		}
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("Authorization", "Bearer "+plain)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	for range 3 {
+		for _, path := range []string{"/portal/logs", "/api/token/introspect", "/v1/models"} {
+			if code := call(http.MethodGet, path); code == http.StatusTooManyRequests {
+				t.Fatalf("GET %s 不应计入速率限制", path)
+			}
+		}
+	}
+	if code := call(http.MethodPost, "/v1/messages"); code == http.StatusTooManyRequests {
+		t.Fatal("首个模型请求不应被限流")
+	}
+	if code := call(http.MethodPost, "/v1/messages"); code != http.StatusTooManyRequests {
+		t.Fatalf("第二个模型请求应被限流, got %d", code)
+	}
+}
@@ -63,7 +63,7 @@ func TestUpdateTokenStatsDuringShutdown(t *testing.T) {
 		OutputTokens:             20,
 		CacheReadInputTokens:     5,
 		CacheCreationInputTokens: 3,
-	}, "gpt-5.1-codex")
+	}, "gpt-5.1-codex", 1)
 
 	got, err := store.GetAuthTokenByValue(ctx, tokenHash)
 	if err != nil {
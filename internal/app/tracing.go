@@ -0,0 +1,612 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ccLoad/internal/cooldown"
+	"ccLoad/internal/model"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 代理链路追踪（2026-10新增，OpenTelemetry 兼容）
+// ============================================================================
+// 多渠道/多Key重试时，单靠日志很难还原一次请求的耗时分布。开启后每个代理请求生成一条 trace：
+//   proxy.request（入站请求）
+//   ├─ proxy.select_channels（候选渠道选择）
+//   └─ proxy.channel（每个尝试的渠道，Key重试记为 key_retry 事件）
+//      └─ proxy.attempt（每次Key尝试）
+//         └─ proxy.upstream（上游调用，首字节记为 first_byte 事件）
+//            └─ proxy.stream（响应体转发/SSE流）
+// 入站请求携带 W3C traceparent 时沿用其 trace；响应头 X-CCLoad-Trace-Id 返回 trace ID 便于检索。
+// 不向上游注入 traceparent（避免把网关内部标识泄露给第三方）。
+// 导出：OTLP/HTTP JSON（POST {endpoint}/v1/traces），后台批量发送，队列满时丢弃并计数，不阻塞请求。
+// 只依赖标准库，不引入 OpenTelemetry SDK。配置见系统设置 otel_*（修改后重启生效）。
+
+const (
+	headerTraceParent      = "traceparent"
+	headerCCLoadTraceID    = "X-CCLoad-Trace-Id"
+	defaultOTelServiceName = "ccload"
+	traceQueueSize         = 4096
+	traceBatchSize         = 512
+	traceFlushInterval     = 5 * time.Second
+	traceExportTimeout     = 10 * time.Second
+	traceScopeName         = "ccLoad/proxy"
+)
+
+// OTLP SpanKind / StatusCode
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+
+	spanStatusOK    = 1
+	spanStatusError = 2
+)
+
+// tracingConfig 追踪配置（启动时加载）
+type tracingConfig struct {
+	endpoint      string            // OTLP/HTTP 完整地址（.../v1/traces）
+	headers       map[string]string // 导出请求附加头（如认证）
+	serviceName   string
+	samplePercent int // 无上游 traceparent 时的采样比例（0-100）
+}
+
+// tracer 采样、span 收集与批量导出（nil 表示关闭，所有方法 nil-safe）
+type tracer struct {
+	cfg    tracingConfig
+	queue  chan *traceSpan
+	kick   chan struct{} // 队列积累满一批时提前唤醒导出协程
+	client *http.Client
+
+	exported     atomic.Int64
+	dropped      atomic.Int64
+	exportErrors atomic.Int64
+	failing      atomic.Bool // 最近一次导出失败（健康检查据此降级）
+}
+
+func newTracer(cfg tracingConfig) *tracer {
+	if cfg.serviceName == "" {
+		cfg.serviceName = defaultOTelServiceName
+	}
+	return &tracer{
+		cfg:    cfg,
+		queue:  make(chan *traceSpan, traceQueueSize),
+		kick:   make(chan struct{}, 1),
+		client: &http.Client{Timeout: traceExportTimeout},
+	}
+}
+
+// newTracerFromConfig 读取 otel_* 设置；未开启或配置无效时返回 nil
+func newTracerFromConfig(cs *ConfigService) *tracer {
+	if !cs.GetBool("otel_tracing_enabled", false) {
+		return nil
+	}
+	endpoint, err := otlpTracesEndpoint(cs.GetString("otel_exporter_otlp_endpoint", ""))
+	if err != nil {
+		log.Printf("[WARN] [链路追踪] %v，已禁用追踪", err)
+		return nil
+	}
+	headers, err := parseOTLPHeaders(cs.GetString("otel_exporter_otlp_headers", ""))
+	if err != nil {
+		log.Printf("[WARN] [链路追踪] %v，已禁用追踪", err)
+		return nil
+	}
+	percent := cs.GetInt("otel_sample_percent", 100)
+	if percent < 0 || percent > 100 {
+		log.Printf("[WARN] 无效的 otel_sample_percent=%d（必须在 0-100 之间），已使用默认值 100", percent)
+		percent = 100
+	}
+	log.Printf("[INFO] [链路追踪] 已启用，导出到 %s（采样 %d%%）", endpoint, percent)
+	return newTracer(tracingConfig{
+		endpoint:      endpoint,
+		headers:       headers,
+		serviceName:   strings.TrimSpace(cs.GetString("otel_service_name", defaultOTelServiceName)),
+		samplePercent: percent,
+	})
+}
+
+// otlpTracesEndpoint 规范化导出地址：只填 collector 根地址时补全 /v1/traces
+func otlpTracesEndpoint(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("otel_exporter_otlp_endpoint is required when tracing is enabled")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid otel_exporter_otlp_endpoint %q", raw)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimRight(u.Path, "/") + "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// parseOTLPHeaders 解析 "k1=v1,k2=v2" 格式的导出请求头
+func parseOTLPHeaders(raw string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid otel_exporter_otlp_headers entry %q (want key=value)", part)
+		}
+		headers[k] = strings.TrimSpace(v)
+	}
+	return headers, nil
+}
+
+// ============================================================================
+// Span
+// ============================================================================
+
+type traceAttr struct {
+	key   string
+	value any // string | int | int64 | float64 | bool
+}
+
+type traceEvent struct {
+	name  string
+	at    time.Time
+	attrs []traceAttr
+}
+
+// traceSpan 一个计时区间（nil 表示未采样，所有方法 nil-safe）
+type traceSpan struct {
+	tr       *tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+
+	mu        sync.Mutex
+	end       time.Time
+	attrs     []traceAttr
+	events    []traceEvent
+	status    int
+	statusMsg string
+}
+
+type traceSpanKey struct{}
+
+// spanFromContext 取出当前 span（未开启追踪或未采样时为 nil）
+func spanFromContext(ctx context.Context) *traceSpan {
+	sp, _ := ctx.Value(traceSpanKey{}).(*traceSpan)
+	return sp
+}
+
+// startRequest 开始入站请求的根 span：沿用合法的 traceparent（尊重其采样标志），否则按比例采样
+func (t *tracer) startRequest(ctx context.Context, hdr http.Header, name string, start time.Time) (context.Context, *traceSpan) {
+	if t == nil {
+		return ctx, nil
+	}
+	sp := &traceSpan{tr: t, name: name, kind: spanKindServer, start: start}
+	if traceID, parentID, sampled, ok := parseTraceParent(hdr.Get(headerTraceParent)); ok {
+		if !sampled {
+			return ctx, nil
+		}
+		sp.traceID, sp.parentID = traceID, parentID
+	} else {
+		if !t.sample() {
+			return ctx, nil
+		}
+		_, _ = rand.Read(sp.traceID[:])
+	}
+	_, _ = rand.Read(sp.spanID[:])
+	return context.WithValue(ctx, traceSpanKey{}, sp), sp
+}
+
+func (t *tracer) sample() bool {
+	if t.cfg.samplePercent >= 100 {
+		return true
+	}
+	if t.cfg.samplePercent <= 0 {
+		return false
+	}
+	var b [2]byte
+	_, _ = rand.Read(b[:])
+	return int(b[0])<<8|int(b[1]) < t.cfg.samplePercent*65536/100
+}
+
+// startSpan 在 ctx 当前 span 下开始子 span（父 span 不存在时不记录）
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *traceSpan) {
+	parent := spanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	sp := &traceSpan{tr: parent.tr, traceID: parent.traceID, parentID: parent.spanID, name: name, kind: kind, start: time.Now()}
+	_, _ = rand.Read(sp.spanID[:])
+	return context.WithValue(ctx, traceSpanKey{}, sp), sp
+}
+
+// TraceID 十六进制 trace ID
+func (sp *traceSpan) TraceID() string {
+	if sp == nil {
+		return ""
+	}
+	return hex.EncodeToString(sp.traceID[:])
+}
+
+func (sp *traceSpan) setAttr(key string, value any) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	sp.attrs = append(sp.attrs, traceAttr{key, value})
+	sp.mu.Unlock()
+}
+
+func (sp *traceSpan) addEvent(name string, attrs ...traceAttr) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	sp.events = append(sp.events, traceEvent{name: name, at: time.Now(), attrs: attrs})
+	sp.mu.Unlock()
+}
+
+// setError 标记失败（err 为 nil 时忽略）
+func (sp *traceSpan) setError(err error) {
+	if sp == nil || err == nil {
+		return
+	}
+	sp.setStatus(spanStatusError, err.Error())
+}
+
+func (sp *traceSpan) setStatus(code int, msg string) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	sp.status, sp.statusMsg = code, msg
+	sp.mu.Unlock()
+}
+
+// setHTTPStatus 记录响应状态码，>=500（含内部状态码）视为失败
+func (sp *traceSpan) setHTTPStatus(status int) {
+	if sp == nil || status == 0 {
+		return
+	}
+	sp.setAttr("http.response.status_code", status)
+	if status >= 500 {
+		sp.setStatus(spanStatusError, http.StatusText(status))
+	}
+}
+
+// finish 结束 span 并放入导出队列（重复调用无副作用；队列满时丢弃）
+func (sp *traceSpan) finish() {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	if !sp.end.IsZero() {
+		sp.mu.Unlock()
+		return
+	}
+	sp.end = time.Now()
+	sp.mu.Unlock()
+	select {
+	case sp.tr.queue <- sp:
+	default:
+		sp.tr.dropped.Add(1)
+		return
+	}
+	if len(sp.tr.queue) >= traceBatchSize {
+		select {
+		case sp.tr.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// parseTraceParent 解析 W3C traceparent（00-{32hex}-{16hex}-{2hex}）
+func parseTraceParent(v string) (traceID [16]byte, parentID [8]byte, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, parentID, false, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return traceID, parentID, false, false
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || parentID == [8]byte{} {
+		return traceID, parentID, false, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return traceID, parentID, false, false
+	}
+	return traceID, parentID, flags&1 == 1, true
+}
+
+// ============================================================================
+// 代理链路埋点辅助
+// ============================================================================
+
+// setChannelAttrs 渠道维度属性
+func (sp *traceSpan) setChannelAttrs(cfg *model.Config) {
+	if sp == nil || cfg == nil {
+		return
+	}
+	sp.setAttr("ccload.channel.id", cfg.ID)
+	sp.setAttr("ccload.channel.name", cfg.Name)
+	sp.setAttr("ccload.channel.type", cfg.GetChannelType())
+}
+
+// finishProxyResult 以代理结果结束 span（渠道/尝试级）
+func (sp *traceSpan) finishProxyResult(res *proxyResult, err error) {
+	if sp == nil {
+		return
+	}
+	if res != nil {
+		sp.setHTTPStatus(res.status)
+		if res.isClientCanceled {
+			sp.setAttr("ccload.client_canceled", true)
+		}
+		switch {
+		case res.succeeded:
+			sp.setStatus(spanStatusOK, "")
+		case res.status > 0 && res.status < 500:
+			sp.setStatus(spanStatusError, http.StatusText(res.status))
+		}
+	}
+	sp.setError(err)
+	sp.finish()
+}
+
+// traceActionName 冷却决策的可读名称（span 属性）
+func traceActionName(action cooldown.Action) string {
+	switch action {
+	case cooldown.ActionRetryKey:
+		return "retry_key"
+	case cooldown.ActionRetryChannel:
+		return "retry_channel"
+	case cooldown.ActionReturnClient:
+		return "return_client"
+	}
+	return "unknown"
+}
+
+// ============================================================================
+// OTLP/HTTP JSON 导出
+// ============================================================================
+
+// otlpAnyValue OTLP AnyValue（int64 按规范编码为字符串）
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpValue(v any) otlpAnyValue {
+	switch x := v.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &x}
+	case bool:
+		return otlpAnyValue{BoolValue: &x}
+	case int:
+		s := strconv.Itoa(x)
+		return otlpAnyValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		return otlpAnyValue{IntValue: &s}
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			s := strconv.FormatFloat(x, 'g', -1, 64)
+			return otlpAnyValue{StringValue: &s}
+		}
+		return otlpAnyValue{DoubleValue: &x}
+	default:
+		s := fmt.Sprint(x)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+func otlpAttrs(attrs []traceAttr) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]otlpKeyValue, len(attrs))
+	for i, a := range attrs {
+		out[i] = otlpKeyValue{Key: a.key, Value: otlpValue(a.value)}
+	}
+	return out
+}
+
+func (sp *traceSpan) toOTLP() otlpSpan {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	out := otlpSpan{
+		TraceID:           hex.EncodeToString(sp.traceID[:]),
+		SpanID:            hex.EncodeToString(sp.spanID[:]),
+		Name:              sp.name,
+		Kind:              sp.kind,
+		StartTimeUnixNano: strconv.FormatInt(sp.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(sp.end.UnixNano(), 10),
+		Attributes:        otlpAttrs(sp.attrs),
+		Status:            otlpStatus{Code: sp.status, Message: sp.statusMsg},
+	}
+	if sp.parentID != ([8]byte{}) {
+		out.ParentSpanID = hex.EncodeToString(sp.parentID[:])
+	}
+	for _, e := range sp.events {
+		out.Events = append(out.Events, otlpEvent{TimeUnixNano: strconv.FormatInt(e.at.UnixNano(), 10), Name: e.name, Attributes: otlpAttrs(e.attrs)})
+	}
+	return out
+}
+
+// export 发送一批 span（失败只计数，不重试，避免追踪后端故障时积压内存）
+func (t *tracer) export(ctx context.Context, batch []*traceSpan) {
+	if len(batch) == 0 {
+		return
+	}
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, len(batch))}
+	scope.Scope.Name = traceScopeName
+	for i, sp := range batch {
+		scope.Spans[i] = sp.toOTLP()
+	}
+	rs := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	rs.Resource.Attributes = otlpAttrs([]traceAttr{{"service.name", t.cfg.serviceName}})
+	data, err := sonic.Marshal(otlpTraceRequest{ResourceSpans: []otlpResourceSpans{rs}})
+	if err != nil {
+		t.exportErrors.Add(1)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.endpoint, bytes.NewReader(data))
+	if err != nil {
+		t.exportErrors.Add(1)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.cfg.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.failing.Store(true)
+		if t.exportErrors.Add(1) == 1 {
+			log.Printf("[WARN] [链路追踪] 导出失败（后续失败只计数）: %v", err)
+		}
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		t.failing.Store(true)
+		if t.exportErrors.Add(1) == 1 {
+			log.Printf("[WARN] [链路追踪] 导出失败（后续失败只计数）: status %d", resp.StatusCode)
+		}
+		return
+	}
+	t.failing.Store(false)
+	t.exported.Add(int64(len(batch)))
+}
+
+// flush 导出队列中已有的全部 span
+func (t *tracer) flush(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	for {
+		batch := make([]*traceSpan, 0, traceBatchSize)
+	drain:
+		for len(batch) < traceBatchSize {
+			select {
+			case sp := <-t.queue:
+				batch = append(batch, sp)
+			default:
+				break drain
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		t.export(ctx, batch)
+	}
+}
+
+func (t *tracer) stats() map[string]any {
+	return map[string]any{
+		"endpoint":       t.cfg.endpoint,
+		"sample_percent": t.cfg.samplePercent,
+		"queued":         len(t.queue),
+		"exported":       t.exported.Load(),
+		"dropped":        t.dropped.Load(),
+		"export_errors":  t.exportErrors.Load(),
+	}
+}
+
+// traceExportLoop 追踪批量导出后台协程（关闭时导出剩余 span）
+func (s *Server) traceExportLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+			s.tracer.flush(ctx)
+			cancel()
+		case <-s.tracer.kick:
+			ctx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+			s.tracer.flush(ctx)
+			cancel()
+		case <-s.shutdownCh:
+			ctx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+			s.tracer.flush(ctx)
+			cancel()
+			return
+		}
+	}
+}
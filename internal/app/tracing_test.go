@@ -0,0 +1,259 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	traceID, parentID, sampled, ok := parseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok || !sampled || traceID[0] != 0x4b || parentID[7] != 0xb7 {
+		t.Fatalf("valid traceparent: ok=%v sampled=%v", ok, sampled)
+	}
+	if _, _, sampled, ok := parseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"); !ok || sampled {
+		t.Fatalf("unsampled flag should be honored, ok=%v sampled=%v", ok, sampled)
+	}
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, _, _, ok := parseTraceParent(bad); ok {
+			t.Fatalf("invalid traceparent accepted: %q", bad)
+		}
+	}
+}
+
+func TestOTLPConfigParsing(t *testing.T) {
+	if got, err := otlpTracesEndpoint("http://collector:4318"); err != nil || got != "http://collector:4318/v1/traces" {
+		t.Fatalf("endpoint = %q %v", got, err)
+	}
+	if got, _ := otlpTracesEndpoint("https://otlp.example.com/v1/traces"); got != "https://otlp.example.com/v1/traces" {
+		t.Fatalf("endpoint with path should be kept, got %q", got)
+	}
+	if _, err := otlpTracesEndpoint("collector:4318"); err == nil {
+		t.Fatal("endpoint without scheme should be rejected")
+	}
+	h, err := parseOTLPHeaders(" Authorization=Bearer a=b , x-tenant=t1,")
+	if err != nil || h["Authorization"] != "Bearer a=b" || h["x-tenant"] != "t1" {
+		t.Fatalf("headers = %v %v", h, err)
+	}
+	if _, err := parseOTLPHeaders("novalue"); err == nil {
+		t.Fatal("entry without '=' should be rejected")
+	}
+}
+
+// fakeOTLPCollector 收集导出的 span（按名称索引）
+type fakeOTLPCollector struct {
+	mu      sync.Mutex
+	headers http.Header
+	spans   []otlpSpan
+}
+
+func (f *fakeOTLPCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpTraceRequest
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.headers = r.Header.Clone()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			f.spans = append(f.spans, ss.Spans...)
+		}
+	}
+}
+
+func (f *fakeOTLPCollector) byName(name string) []otlpSpan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []otlpSpan
+	for _, sp := range f.spans {
+		if sp.Name == name {
+			out = append(out, sp)
+		}
+	}
+	return out
+}
+
+func spanAttr(sp otlpSpan, key string) string {
+	for _, kv := range sp.Attributes {
+		if kv.Key != key {
+			continue
+		}
+		switch {
+		case kv.Value.StringValue != nil:
+			return *kv.Value.StringValue
+		case kv.Value.IntValue != nil:
+			return *kv.Value.IntValue
+		}
+	}
+	return ""
+}
+
+// TestTracing_ProxyFailover 故障转移请求导出完整的 span 树：根span → 两个渠道 → 各自的尝试与上游调用
+func TestTracing_ProxyFailover(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, `{"error":"boom"}`)
+	}))
+	defer broken.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"msg_1","type":"message","model":"claude-x","content":[],"usage":{"input_tokens":3,"output_tokens":5}}`)
+	}))
+	defer healthy.Close()
+	collector := &fakeOTLPCollector{}
+	otlp := httptest.NewServer(collector)
+	defer otlp.Close()
+
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	srv := NewServer(store)
+	srv.responseBufferBytes = 0
+	ctx := context.Background()
+	defer func() { _ = srv.Shutdown(ctx) }()
+	srv.tracer = newTracer(tracingConfig{endpoint: otlp.URL + "/v1/traces", headers: map[string]string{"X-Tenant": "t1"}, samplePercent: 100})
+
+	for _, ch := range []struct {
+		name     string
+		url      string
+		priority int
+	}{{"broken", broken.URL, 10}, {"healthy", healthy.URL, 1}} {
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: ch.name, URL: ch.url, ChannelType: "anthropic", Priority: ch.priority, Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-x"}},
+		})
+		if err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+		if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+			{ChannelID: cfg.ID, APIKey: "sk-" + ch.name, KeyStrategy: model.KeyStrategySequential},
+		}); err != nil {
+			t.Fatalf("创建Key失败: %v", err)
+		}
+	}
+
+	const parentTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+		bytes.NewBufferString(`{"model":"claude-x","max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set(headerTraceParent, "00-"+parentTrace+"-00f067aa0ba902b7-01")
+	srv.HandleProxyRequest(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(headerCCLoadTraceID); got != parentTrace {
+		t.Fatalf("%s = %q, want caller trace %s", headerCCLoadTraceID, got, parentTrace)
+	}
+
+	srv.tracer.flush(ctx)
+	if collector.headers.Get("X-Tenant") != "t1" {
+		t.Errorf("导出请求应携带配置的请求头, got %v", collector.headers)
+	}
+
+	roots := collector.byName("proxy.request")
+	if len(roots) != 1 {
+		t.Fatalf("应导出1个根span, got %d", len(roots))
+	}
+	root := roots[0]
+	if root.TraceID != parentTrace || root.ParentSpanID != "00f067aa0ba902b7" || root.Kind != spanKindServer {
+		t.Fatalf("根span应延续上游trace: %+v", root)
+	}
+	if spanAttr(root, "http.response.status_code") != "200" || spanAttr(root, "ccload.model") != "claude-x" {
+		t.Errorf("根span属性不完整: %+v", root.Attributes)
+	}
+	if sel := collector.byName("proxy.select_channels"); len(sel) != 1 || sel[0].ParentSpanID != root.SpanID || spanAttr(sel[0], "ccload.candidates") != "2" {
+		t.Errorf("选路span: %+v", sel)
+	}
+
+	channels := collector.byName("proxy.channel")
+	if len(channels) != 2 {
+		t.Fatalf("应导出2个渠道span, got %d", len(channels))
+	}
+	channelByName := map[string]otlpSpan{}
+	for _, ch := range channels {
+		if ch.ParentSpanID != root.SpanID || ch.TraceID != parentTrace {
+			t.Errorf("渠道span应挂在根span下: %+v", ch)
+		}
+		channelByName[spanAttr(ch, "ccload.channel.name")] = ch
+	}
+	if st := channelByName["broken"].Status; st.Code != spanStatusError {
+		t.Errorf("失败渠道span应标记错误, got %+v", st)
+	}
+	if st := channelByName["healthy"].Status; st.Code != spanStatusOK {
+		t.Errorf("成功渠道span应标记OK, got %+v", st)
+	}
+
+	attempts := collector.byName("proxy.attempt")
+	upstreams := collector.byName("proxy.upstream")
+	if len(attempts) != 2 || len(upstreams) != 2 {
+		t.Fatalf("应有2次尝试与2次上游调用, got %d/%d", len(attempts), len(upstreams))
+	}
+	attemptIDs := map[string]bool{}
+	for _, a := range attempts {
+		attemptIDs[a.SpanID] = true
+		if a.ParentSpanID != channelByName["broken"].SpanID && a.ParentSpanID != channelByName["healthy"].SpanID {
+			t.Errorf("尝试span应挂在渠道span下: %+v", a)
+		}
+	}
+	var failedUpstream bool
+	for _, u := range upstreams {
+		if !attemptIDs[u.ParentSpanID] || u.Kind != spanKindClient {
+			t.Errorf("上游span应为尝试span的CLIENT子span: %+v", u)
+		}
+		if spanAttr(u, "http.response.status_code") == "500" && u.Status.Code == spanStatusError {
+			failedUpstream = true
+		}
+	}
+	if !failedUpstream {
+		t.Error("500 上游调用应标记为错误")
+	}
+	if streams := collector.byName("proxy.stream"); len(streams) != 1 || spanAttr(streams[0], "ccload.output_tokens") != "5" {
+		t.Errorf("成功响应应有流式转发span并记录Token: %+v", streams)
+	}
+}
+
+// TestTracing_Sampling 未携带 traceparent 时按比例采样；上游未采样时不记录
+func TestTracing_Sampling(t *testing.T) {
+	ctx := context.Background()
+	hdr := http.Header{}
+	if _, sp := newTracer(tracingConfig{samplePercent: 0}).startRequest(ctx, hdr, "r", time.Now()); sp != nil {
+		t.Fatal("0% 采样不应创建span")
+	}
+	tr := newTracer(tracingConfig{samplePercent: 100})
+	hdr.Set(headerTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if _, sp := tr.startRequest(ctx, hdr, "r", time.Now()); sp != nil {
+		t.Fatal("上游未采样时不应创建span")
+	}
+	var nilTracer *tracer
+	if _, sp := nilTracer.startRequest(ctx, http.Header{}, "r", time.Now()); sp != nil {
+		t.Fatal("关闭追踪时不应创建span")
+	}
+	if _, sp := startSpan(ctx, "child", spanKindInternal); sp != nil {
+		t.Fatal("无父span时不应创建子span")
+	}
+}
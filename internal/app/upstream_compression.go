@@ -0,0 +1,72 @@
+package app
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+)
+
+// ============================================================================
+// 渠道级上游压缩（2026-10新增）
+// ============================================================================
+// - request_compression=gzip：请求体 >= 1KB 且压缩后更小时以 Content-Encoding: gzip 发送
+//   （工具定义多的大 prompt 在慢链路上收益明显；仅对明确支持的上游开启）
+// - accept_encoding：强制响应编码偏好（如 identity），此时由 decodeUpstreamResponse 代替 Transport 解压
+// 效果可通过渠道测试接口对比：结果中的 compression 字段给出请求/响应在线路上的字节数，
+// 传 disable_compression=true 可得到同一请求不压缩时的基线耗时。
+
+// applyUpstreamCompression 按渠道配置压缩请求体并设置 Accept-Encoding，返回请求体在线路上的字节数
+func applyUpstreamCompression(req *http.Request, cfg *model.Config, body []byte) int {
+	wireBytes := len(body)
+	if cfg == nil {
+		return wireBytes
+	}
+	if cfg.RequestCompression == util.RequestCompressionGzip && req.Header.Get("Content-Encoding") == "" {
+		if compressed, ok := util.GzipBody(body); ok {
+			req.Body = io.NopCloser(bytes.NewReader(compressed))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(compressed)), nil
+			}
+			req.ContentLength = int64(len(compressed))
+			req.Header.Set("Content-Encoding", util.RequestCompressionGzip)
+			wireBytes = len(compressed)
+		}
+	}
+	if cfg.AcceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", cfg.AcceptEncoding)
+	}
+	return wireBytes
+}
+
+// decodeUpstreamResponse 强制了 Accept-Encoding 的渠道由此解压响应（Transport 此时不会自动解压）
+// 解压后移除 Content-Encoding/Content-Length，与 Transport 自动解压后的响应形态一致
+func decodeUpstreamResponse(resp *http.Response, cfg *model.Config) {
+	if resp == nil || cfg == nil || cfg.AcceptEncoding == "" || resp.Uncompressed {
+		return
+	}
+	body, ok := util.DecodeContentEncoding(resp.Body, resp.Header.Get("Content-Encoding"))
+	if !ok {
+		return
+	}
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// countingReadCloser 统计读取字节数（测试接口用于测量响应在线路上的大小）
+type countingReadCloser struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
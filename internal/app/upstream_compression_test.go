@@ -0,0 +1,81 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+)
+
+func TestUpstreamCompression_RoundTrip(t *testing.T) {
+	var gotEncoding, gotAccept string
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		gotAccept = r.Header.Get("Accept-Encoding")
+		var body io.Reader = r.Body
+		if gotEncoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		gotBody, _ = io.ReadAll(body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(gotAccept, "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write([]byte(`{"ok":true}`))
+			_ = zw.Close()
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	payload := []byte(`{"tools":"` + strings.Repeat("tool definition ", 200) + `"}`)
+	send := func(cfg *model.Config) (int, string) {
+		t.Helper()
+		req, _ := buildUpstreamRequest(t.Context(), http.MethodPost, upstream.URL, payload)
+		wire := applyUpstreamCompression(req, cfg, payload)
+		resp, err := upstream.Client().Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		decodeUpstreamResponse(resp, cfg)
+		if resp.Header.Get("Content-Encoding") != "" {
+			t.Fatalf("response should be decoded, Content-Encoding=%q", resp.Header.Get("Content-Encoding"))
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return wire, string(data)
+	}
+
+	wire, out := send(&model.Config{RequestCompression: "gzip", AcceptEncoding: "gzip"})
+	if gotEncoding != "gzip" || wire >= len(payload) || !bytes.Equal(gotBody, payload) {
+		t.Fatalf("body not gzip-compressed: encoding=%q wire=%d", gotEncoding, wire)
+	}
+	if gotAccept != "gzip" || out != `{"ok":true}` {
+		t.Fatalf("forced gzip response not decoded: accept=%q body=%q", gotAccept, out)
+	}
+
+	wire, out = send(&model.Config{AcceptEncoding: "identity"})
+	if gotEncoding != "" || wire != len(payload) || gotAccept != "identity" || out != `{"ok":true}` {
+		t.Fatalf("unexpected identity exchange: encoding=%q accept=%q body=%q", gotEncoding, gotAccept, out)
+	}
+
+	// 小请求体不压缩
+	small := []byte(`{"a":1}`)
+	req, _ := buildUpstreamRequest(t.Context(), http.MethodPost, upstream.URL, small)
+	if wire := applyUpstreamCompression(req, &model.Config{RequestCompression: "gzip"}, small); wire != len(small) || req.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("small body should not be compressed")
+	}
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"ccLoad/internal/model"
+)
+
+// ============================================================================
+// 上游网络瞬断微重试（2026-10新增）
+// ============================================================================
+// 连接被重置/EOF 且尚未收到任何响应字节时，对可安全重放的转发在同一Key上用新拨号的连接重试一次，
+// 成功则不进入Key冷却与重试循环（区分socket瞬断与供应商故障）。
+// 可安全重放的判定（避免上游已处理请求后重复计费）：
+//   - 幂等方法（GET/HEAD/OPTIONS）或请求携带 Idempotency-Key / X-Idempotency-Key；
+//   - 或请求体尚未完整写出（上游不可能已处理该请求，典型为复用了被上游关闭的 keep-alive 连接）。
+// 由 upstream_micro_retry_enabled 开关控制；计数见 /health?detail=1 的 upstream_micro_retry。
+// 渠道开启 idempotency_keys 时每次转发尝试自动携带 Idempotency-Key（微重试克隆请求时原样复用），
+// 支持幂等键的上游（如 OpenAI）据此对重放去重，请求体已写出后的瞬断同样可以安全重试。
+
+// microRetryCounters 微重试计数
+type microRetryCounters struct {
+	attempts  atomic.Uint64 // 触发微重试次数
+	recovered atomic.Uint64 // 重试后拿到上游响应
+	failed    atomic.Uint64 // 重试仍失败（交由原有错误处理/冷却逻辑）
+}
+
+func (m *microRetryCounters) stats() map[string]any {
+	return map[string]any{
+		"attempts":  m.attempts.Load(),
+		"recovered": m.recovered.Load(),
+		"failed":    m.failed.Load(),
+	}
+}
+
+// forwardTrace 记录请求是否已完整写出（httptrace.WroteRequest）
+type forwardTrace struct {
+	wroteRequest atomic.Bool
+}
+
+// attach 将追踪挂到请求上下文（返回新请求）
+func (t *forwardTrace) attach(req *http.Request) *http.Request {
+	trace := &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				t.wroteRequest.Store(true)
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// applyIdempotencyKey 渠道开启 idempotency_keys 时为本次转发尝试生成幂等键（客户端已携带时沿用）
+func applyIdempotencyKey(req *http.Request, cfg *model.Config) {
+	if !cfg.IdempotencyKeys || req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != "" {
+		return
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	req.Header.Set("Idempotency-Key", "ccload-"+hex.EncodeToString(b))
+}
+
+// isTransientConnError 连接重置/EOF/对端关闭等socket级瞬断（不含超时、TLS、证书错误）
+func isTransientConnError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "server closed idle connection") ||
+		strings.Contains(msg, "connection reset by peer") ||
+		strings.Contains(msg, "broken pipe")
+}
+
+// isReplayableForward 请求是否可安全重放
+func isReplayableForward(req *http.Request, trace *forwardTrace) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != "" {
+		return true
+	}
+	return trace != nil && !trace.wroteRequest.Load()
+}
+
+// microRetryForward 对瞬断失败的请求用新连接重试一次；不满足条件时原样返回 (nil, err)
+func (s *Server) microRetryForward(ctx context.Context, cfg *model.Config, req *http.Request, trace *forwardTrace, err error) (*http.Response, error) {
+	if !s.microRetryEnabled || ctx.Err() != nil || !isTransientConnError(err) || !isReplayableForward(req, trace) {
+		return nil, err
+	}
+
+	retryReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		retryReq.Body = body
+	}
+
+	s.microRetry.attempts.Add(1)
+	resp, retryErr := s.freshDialClientFor(cfg).Do(retryReq)
+	if retryErr != nil {
+		s.microRetry.failed.Add(1)
+		log.Printf("[WARN] [上游瞬断] 渠道ID=%d 新连接重试仍失败: %v（首次: %v）", cfg.ID, retryErr, err)
+		return nil, retryErr
+	}
+	s.microRetry.recovered.Add(1)
+	log.Printf("[INFO] [上游瞬断] 渠道ID=%d 新连接重试成功（首次: %v）", cfg.ID, err)
+	return resp, nil
+}
+
+// freshDialClientFor 返回禁用连接复用的客户端（每次请求都新建连接），按渠道客户端配置缓存
+// 共享客户端的 Transport 不是 *http.Transport 时（如测试替身）无法强制新拨号，退回渠道客户端
+func (s *Server) freshDialClientFor(cfg *model.Config) *http.Client {
+	base := s.httpClientFor(cfg)
+	if base == nil {
+		return http.DefaultClient
+	}
+	if _, ok := base.Transport.(*http.Transport); !ok {
+		return base
+	}
+	cacheKey := "fresh|" + cfg.CertPins + "|" + cfg.LocalAddr
+	if cached, ok := s.channelClients.Load(cacheKey); ok {
+		return cached.(*http.Client)
+	}
+	transport := base.Transport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	client := &http.Client{Transport: transport, Timeout: base.Timeout}
+	actual, _ := s.channelClients.LoadOrStore(cacheKey, client)
+	return actual.(*http.Client)
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"ccLoad/internal/model"
+)
+
+// newFlakyUpstream 前 failFirst 个请求直接断开连接（读完请求后不写响应），之后返回200
+func newFlakyUpstream(t *testing.T, failFirst int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if calls.Add(1) <= failFirst {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func doWithMicroRetry(t *testing.T, s *Server, req *http.Request) (*http.Response, error) {
+	t.Helper()
+	cfg := &model.Config{ID: 1}
+	trace := &forwardTrace{}
+	req = trace.attach(req)
+	resp, err := s.httpClientFor(cfg).Do(req)
+	if err == nil {
+		return resp, nil
+	}
+	return s.microRetryForward(context.Background(), cfg, req, trace, err)
+}
+
+func TestMicroRetry_RetriesIdempotentForwardOnce(t *testing.T) {
+	srv, calls := newFlakyUpstream(t, 1)
+	s := &Server{client: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}, microRetryEnabled: true}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/messages", bytes.NewReader([]byte(`{"a":1}`)))
+	req.Header.Set("Idempotency-Key", "k1")
+	resp, err := doWithMicroRetry(t, s, req)
+	if err != nil {
+		t.Fatalf("微重试后应成功: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("响应不符: %d %q", resp.StatusCode, body)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("上游应收到2次请求，实际=%d", calls.Load())
+	}
+	if s.microRetry.attempts.Load() != 1 || s.microRetry.recovered.Load() != 1 || s.microRetry.failed.Load() != 0 {
+		t.Fatalf("计数不符: %v", s.microRetry.stats())
+	}
+}
+
+func TestMicroRetry_SkipsWrittenNonIdempotentRequest(t *testing.T) {
+	srv, calls := newFlakyUpstream(t, 1)
+	s := &Server{client: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}, microRetryEnabled: true}
+
+	// 请求体已完整写出的POST：上游可能已处理，不能重放
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/messages", bytes.NewReader([]byte(`{"a":1}`)))
+	if _, err := doWithMicroRetry(t, s, req); err == nil {
+		t.Fatal("期望返回原始网络错误")
+	}
+	if calls.Load() != 1 || s.microRetry.attempts.Load() != 0 {
+		t.Fatalf("不应重试: calls=%d stats=%v", calls.Load(), s.microRetry.stats())
+	}
+}
+
+func TestMicroRetry_FailedRetryCounted(t *testing.T) {
+	srv, calls := newFlakyUpstream(t, 2)
+	s := &Server{client: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}, microRetryEnabled: true}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+	if _, err := doWithMicroRetry(t, s, req); err == nil {
+		t.Fatal("两次都断开时应返回错误")
+	}
+	if calls.Load() != 2 || s.microRetry.failed.Load() != 1 {
+		t.Fatalf("应只重试一次并计为失败: calls=%d stats=%v", calls.Load(), s.microRetry.stats())
+	}
+}
+
+func TestApplyIdempotencyKey_ReusedAcrossMicroRetry(t *testing.T) {
+	var keys []string
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if calls.Add(1) == 1 {
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	s := &Server{client: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}, microRetryEnabled: true}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", bytes.NewReader([]byte(`{"a":1}`)))
+	applyIdempotencyKey(req, &model.Config{IdempotencyKeys: true})
+	resp, err := doWithMicroRetry(t, s, req)
+	if err != nil {
+		t.Fatalf("携带幂等键的请求写出后瞬断应可微重试: %v", err)
+	}
+	_ = resp.Body.Close()
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("微重试应复用同一幂等键: %q", keys)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, nil)
+	req.Header.Set("Idempotency-Key", "client-key")
+	applyIdempotencyKey(req, &model.Config{IdempotencyKeys: true})
+	if got := req.Header.Get("Idempotency-Key"); got != "client-key" {
+		t.Fatalf("客户端已携带幂等键时应沿用: %q", got)
+	}
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, nil)
+	applyIdempotencyKey(req, &model.Config{})
+	if req.Header.Get("Idempotency-Key") != "" {
+		t.Fatal("未开启 idempotency_keys 时不应添加幂等键")
+	}
+}
+
+func TestIsReplayableForward(t *testing.T) {
+	post := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "http://x/v1/messages", bytes.NewReader([]byte("{}")))
+		return req
+	}
+	if !isReplayableForward(post(), &forwardTrace{}) {
+		t.Error("请求体未写出的POST应可重放")
+	}
+	written := &forwardTrace{}
+	written.wroteRequest.Store(true)
+	if isReplayableForward(post(), written) {
+		t.Error("已写出的POST不可重放")
+	}
+	req := post()
+	req.Header.Set("X-Idempotency-Key", "k")
+	if !isReplayableForward(req, written) {
+		t.Error("带幂等键的请求应可重放")
+	}
+	req = post()
+	req.GetBody = nil
+	if isReplayableForward(req, &forwardTrace{}) {
+		t.Error("无法重建请求体时不可重放")
+	}
+}
+
+func TestIsTransientConnError(t *testing.T) {
+	if !isTransientConnError(io.EOF) || !isTransientConnError(io.ErrUnexpectedEOF) {
+		t.Error("EOF应视为瞬断")
+	}
+	if isTransientConnError(context.Canceled) || isTransientConnError(context.DeadlineExceeded) {
+		t.Error("取消/超时不是瞬断")
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestUpstreamVCR_ReplayRunsFullPipeline 录制一次真实上游流式交互，关闭上游后回放：响应、用量统计与日志与录制时一致
+func TestUpstreamVCR_ReplayRunsFullPipeline(t *testing.T) {
+	const sse = "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-x","usage":{"input_tokens":12,"output_tokens":0}}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}` + "\n\n" +
+		"event: message_delta\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}` + "\n\n" +
+		"event: message_stop\n" + `data: {"type":"message_stop"}` + "\n\n"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, sse)
+	}))
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	run := func(mode, upstreamURL string) (*httptest.ResponseRecorder, []*model.LogEntry) {
+		t.Setenv("CCLOAD_VCR_MODE", mode)
+		t.Setenv("CCLOAD_VCR_CASSETTE", cassette)
+		store, err := storage.CreateSQLiteStore(":memory:", nil)
+		if err != nil {
+			t.Fatalf("创建存储失败: %v", err)
+		}
+		srv := NewServer(store)
+		if srv.vcr == nil || srv.vcr.Mode() != mode {
+			t.Fatalf("%s 模式未启用", mode)
+		}
+		srv.responseBufferBytes = 0
+
+		ctx := context.Background()
+		cfg, err := store.CreateConfig(ctx, &model.Config{
+			Name: "vcr", URL: upstreamURL, ChannelType: "anthropic", Priority: 1, Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-x"}},
+		})
+		if err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+		if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+			{ChannelID: cfg.ID, APIKey: "sk-vcr-key", KeyStrategy: model.KeyStrategySequential},
+		}); err != nil {
+			t.Fatalf("创建Key失败: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages",
+			bytes.NewBufferString(`{"model":"claude-x","stream":true,"max_tokens":8,"messages":[{"role":"user","content":"hi"}]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		srv.HandleProxyRequest(c)
+
+		defer func() { _ = srv.Shutdown(ctx) }()
+
+		// 日志异步落库，轮询等待
+		var logs []*model.LogEntry
+		for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			logs, err = store.ListLogsRange(ctx, time.Now().Add(-time.Minute), time.Now().Add(time.Minute), 10, 0, nil)
+			if err != nil {
+				t.Fatalf("查询日志失败: %v", err)
+			}
+			if len(logs) > 0 {
+				break
+			}
+		}
+		return w, logs
+	}
+
+	recorded, recLogs := run("record", upstream.URL)
+	upstream.Close()
+	replayed, playLogs := run("replay", "http://upstream.invalid")
+
+	if recorded.Code != http.StatusOK || replayed.Code != http.StatusOK {
+		t.Fatalf("status record=%d replay=%d body=%s", recorded.Code, replayed.Code, replayed.Body.String())
+	}
+	if !strings.Contains(replayed.Body.String(), `"text":"hi"`) || replayed.Body.String() != recorded.Body.String() {
+		t.Fatalf("回放响应与录制不一致:\n%s\n---\n%s", recorded.Body.String(), replayed.Body.String())
+	}
+	if len(recLogs) != 1 || len(playLogs) != 1 {
+		t.Fatalf("日志数 record=%d replay=%d", len(recLogs), len(playLogs))
+	}
+	if got := playLogs[0]; got.StatusCode != 200 || got.InputTokens != 12 || got.OutputTokens != 5 {
+		t.Fatalf("回放日志用量不符: %+v", got)
+	}
+}
+
+// TestUpstreamVCR_RecordKeepsChannelTransport 录制模式下配置了 local_addr/cert_pins 的渠道仍使用专用Transport，并写入同一磁带
+func TestUpstreamVCR_RecordKeepsChannelTransport(t *testing.T) {
+	var remoteAddr string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer upstream.Close()
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	t.Setenv("CCLOAD_VCR_MODE", "record")
+	t.Setenv("CCLOAD_VCR_CASSETTE", cassette)
+	store, err := storage.CreateSQLiteStore(":memory:", nil)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	srv := NewServer(store)
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+
+	cfg := &model.Config{ID: 1, URL: upstream.URL, LocalAddr: "127.0.0.1"}
+	client := srv.httpClientFor(cfg)
+	if client == srv.client {
+		t.Fatal("录制模式下专用渠道不应退回共享客户端")
+	}
+	resp, err := client.Get(upstream.URL + "/v1/ping")
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.HasPrefix(remoteAddr, "127.0.0.1:") {
+		t.Fatalf("未使用渠道出站地址: %s", remoteAddr)
+	}
+	if srv.vcr.Interactions() != 1 {
+		t.Fatalf("专用客户端的交互应写入磁带: %d", srv.vcr.Interactions())
+	}
+}
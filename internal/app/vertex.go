@@ -0,0 +1,353 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// Vertex AI 渠道（2026-10新增）
+// ============================================================================
+// Gemini 类型渠道绑定服务账号凭据后按 Vertex AI 方式转发：
+//   - 认证：服务账号 JWT 断言换取访问令牌（Authorization: Bearer），不再发送 x-goog-api-key
+//   - 路径：/v1beta/models/{model}:{method} → /v1/projects/{project}/locations/{location}/publishers/google/models/{model}:{method}
+//   - 渠道URL填写区域端点（如 https://us-central1-aiplatform.googleapis.com，global 区域为 https://aiplatform.googleapis.com）
+// 渠道Key仅用于选路与统计（绑定凭据时若渠道没有Key会自动补一个占位Key）。
+// 访问令牌由后台刷新循环在过期前续期，请求路径上通常直接命中缓存。
+
+const (
+	vertexRefreshInterval = time.Minute
+	vertexRefreshWindow   = 5 * time.Minute // 令牌剩余有效期低于该值时后台续期
+	vertexPlaceholderKey  = "vertex-service-account"
+	vertexGlobalLocation  = "global"
+)
+
+// newVertexTokenSource 创建访问令牌源（测试中替换为本地令牌端点）
+var newVertexTokenSource = func(sa *util.GCPServiceAccount) *util.GCPTokenSource {
+	return util.NewGCPTokenSource(sa, nil)
+}
+
+// vertexChannel 渠道的 Vertex AI 运行时状态
+type vertexChannel struct {
+	projectID string
+	location  string
+	tokens    *util.GCPTokenSource
+}
+
+// vertexRegistry 渠道ID → Vertex AI 凭据（启动时加载，管理接口修改后立即更新）
+type vertexRegistry struct {
+	mu       sync.RWMutex
+	channels map[int64]*vertexChannel
+}
+
+func newVertexRegistry() *vertexRegistry {
+	return &vertexRegistry{channels: make(map[int64]*vertexChannel)}
+}
+
+func (r *vertexRegistry) get(channelID int64) *vertexChannel {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.channels[channelID]
+}
+
+// set 注册渠道凭据（凭据解析失败时移除，避免继续用过期配置转发）
+func (r *vertexRegistry) set(v *model.VertexCredential) error {
+	sa, err := util.ParseGCPServiceAccount(v.Credentials)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		delete(r.channels, v.ChannelID)
+		return err
+	}
+	r.channels[v.ChannelID] = &vertexChannel{projectID: v.ProjectID, location: v.Location, tokens: newVertexTokenSource(sa)}
+	return nil
+}
+
+func (r *vertexRegistry) remove(channelID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.channels, channelID)
+}
+
+func (r *vertexRegistry) snapshot() map[int64]*vertexChannel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[int64]*vertexChannel, len(r.channels))
+	for id, ch := range r.channels {
+		out[id] = ch
+	}
+	return out
+}
+
+// loadVertexCredentials 启动时加载全部 Vertex AI 凭据
+func (s *Server) loadVertexCredentials(ctx context.Context) {
+	list, err := s.store.ListVertexCredentials(ctx)
+	if err != nil {
+		log.Printf("[WARN] 加载 Vertex AI 凭据失败: %v", err)
+		return
+	}
+	for _, v := range list {
+		if err := s.vertex.set(v); err != nil {
+			log.Printf("[WARN] 渠道 %d 的 Vertex AI 凭据无效: %v", v.ChannelID, err)
+		}
+	}
+}
+
+// vertexRefreshLoop 定期为即将过期的访问令牌续期（只处理已签发过令牌的渠道）
+func (s *Server) vertexRefreshLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(vertexRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			s.refreshVertexTokens()
+		}
+	}
+}
+
+func (s *Server) refreshVertexTokens() {
+	for id, ch := range s.vertex.snapshot() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if _, err := ch.tokens.RefreshIfExpiring(ctx, vertexRefreshWindow); err != nil {
+			log.Printf("[WARN] 渠道 %d 的 Vertex AI 访问令牌续期失败: %v", id, err)
+		}
+		cancel()
+	}
+}
+
+// vertexLocationFromURL 从区域端点推断区域（{location}-aiplatform.googleapis.com），其他地址视为 global
+func vertexLocationFromURL(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return vertexGlobalLocation
+	}
+	if loc, ok := strings.CutSuffix(u.Hostname(), "-aiplatform.googleapis.com"); ok && loc != "" {
+		return loc
+	}
+	return vertexGlobalLocation
+}
+
+// vertexUpstreamPath 将 Gemini API 模型路径映射为 Vertex AI 端点；非模型调用路径原样返回
+func vertexUpstreamPath(requestPath, projectID, location string) string {
+	idx := strings.Index(requestPath, "/models/")
+	if idx < 0 {
+		return requestPath
+	}
+	return "/v1/projects/" + neturl.PathEscape(projectID) + "/locations/" + neturl.PathEscape(location) +
+		"/publishers/google" + requestPath[idx:]
+}
+
+// applyVertexAuth 按 Vertex AI 方式改写上游请求（路径与认证头）
+func (s *Server) applyVertexAuth(ctx context.Context, req *http.Request, cfg *model.Config) error {
+	if util.NormalizeChannelType(cfg.GetChannelType()) != util.ChannelTypeGemini {
+		return nil
+	}
+	vc := s.vertex.get(cfg.ID)
+	if vc == nil {
+		return nil
+	}
+	token, err := vc.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("vertex ai access token: %w", err)
+	}
+	basePath := vertexBasePath(cfg.URL)
+	req.URL.Path = basePath + vertexUpstreamPath(strings.TrimPrefix(req.URL.Path, basePath), vc.projectID, vc.location)
+	req.URL.RawPath = ""
+	req.Header.Del("x-goog-api-key")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// vertexBasePath 渠道URL中的路径前缀（反向代理部署时可能非空）
+func vertexBasePath(rawURL string) string {
+	u, err := neturl.Parse(strings.TrimRight(rawURL, "/"))
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// ============================================================================
+// Admin API
+// ============================================================================
+
+// vertexCredentialRequest 绑定凭据请求
+type vertexCredentialRequest struct {
+	Credentials string `json:"credentials"` // 服务账号JSON（为空表示保持不变）
+	ProjectID   string `json:"project_id"`  // 默认取服务账号 project_id
+	Location    string `json:"location"`    // 默认从渠道URL推断
+}
+
+// HandleGetChannelVertex 获取渠道 Vertex AI 凭据（不含私钥）
+// GET /admin/channels/:id/vertex
+func (s *Server) HandleGetChannelVertex(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	v, err := s.findVertexCredential(c.Request.Context(), channelID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if v == nil {
+		RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "configured": false})
+		return
+	}
+	RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "configured": true, "credential": vertexCredentialView(v)})
+}
+
+// HandleSetChannelVertex 绑定或更新渠道 Vertex AI 凭据（渠道须为 gemini 类型）
+// PUT /admin/channels/:id/vertex
+func (s *Server) HandleSetChannelVertex(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	var req vertexCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	cfg, err := s.store.GetConfig(ctx, channelID)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "channel not found")
+		return
+	}
+	if util.NormalizeChannelType(cfg.GetChannelType()) != util.ChannelTypeGemini {
+		RespondErrorMsg(c, http.StatusBadRequest, "channel must be of type gemini")
+		return
+	}
+
+	existing, err := s.findVertexCredential(ctx, channelID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now().Unix()
+	v := &model.VertexCredential{ChannelID: channelID, CreatedAt: now, UpdatedAt: now}
+	if existing != nil {
+		v.Credentials, v.CreatedAt = existing.Credentials, existing.CreatedAt
+	}
+	if creds := strings.TrimSpace(req.Credentials); creds != "" {
+		v.Credentials = creds
+	}
+	if v.Credentials == "" {
+		RespondErrorMsg(c, http.StatusBadRequest, "credentials is required")
+		return
+	}
+	sa, err := util.ParseGCPServiceAccount(v.Credentials)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if v.ProjectID = strings.TrimSpace(req.ProjectID); v.ProjectID == "" {
+		v.ProjectID = sa.ProjectID
+	}
+	if v.ProjectID == "" {
+		RespondErrorMsg(c, http.StatusBadRequest, "project_id is required")
+		return
+	}
+	if v.Location = strings.TrimSpace(req.Location); v.Location == "" {
+		v.Location = vertexLocationFromURL(cfg.URL)
+	}
+
+	if err := s.store.SetVertexCredential(ctx, v); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := s.ensureVertexPlaceholderKey(ctx, channelID); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := s.vertex.set(v); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.invalidateChannelRelatedCache(channelID)
+	RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "configured": true, "credential": vertexCredentialView(v)})
+}
+
+// HandleDeleteChannelVertex 解除渠道 Vertex AI 凭据（恢复为 Gemini API Key 认证）
+// DELETE /admin/channels/:id/vertex
+func (s *Server) HandleDeleteChannelVertex(c *gin.Context) {
+	channelID, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid channel id")
+		return
+	}
+	deleted, err := s.store.DeleteVertexCredential(c.Request.Context(), channelID)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !deleted {
+		RespondErrorMsg(c, http.StatusNotFound, "vertex credential not found")
+		return
+	}
+	s.vertex.remove(channelID)
+	s.invalidateChannelRelatedCache(channelID)
+	RespondJSON(c, http.StatusOK, gin.H{"channel_id": channelID, "configured": false})
+}
+
+func (s *Server) findVertexCredential(ctx context.Context, channelID int64) (*model.VertexCredential, error) {
+	list, err := s.store.ListVertexCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range list {
+		if v.ChannelID == channelID {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// vertexCredentialView 补充展示字段（服务账号邮箱），凭据本身不返回
+func vertexCredentialView(v *model.VertexCredential) *model.VertexCredential {
+	if sa, err := util.ParseGCPServiceAccount(v.Credentials); err == nil {
+		v.ClientEmail = sa.ClientEmail
+	}
+	return v
+}
+
+// ensureVertexPlaceholderKey 渠道没有Key时补一个占位Key（选路依赖Key，实际认证使用服务账号）
+func (s *Server) ensureVertexPlaceholderKey(ctx context.Context, channelID int64) error {
+	keys, err := s.store.GetAPIKeys(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return nil
+	}
+	now := model.JSONTime{Time: time.Now()}
+	return s.store.CreateAPIKeysBatch(ctx, []*model.APIKey{{
+		ChannelID:   channelID,
+		APIKey:      vertexPlaceholderKey,
+		KeyStrategy: model.KeyStrategySequential,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}})
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVertexUpstreamPath(t *testing.T) {
+	got := vertexUpstreamPath("/v1beta/models/gemini-2.5-pro:streamGenerateContent", "proj-1", "us-central1")
+	want := "/v1/projects/proj-1/locations/us-central1/publishers/google/models/gemini-2.5-pro:streamGenerateContent"
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if got := vertexUpstreamPath("/v1beta/files", "p", "global"); got != "/v1beta/files" {
+		t.Fatalf("non-model paths should be kept, got %s", got)
+	}
+	for url, want := range map[string]string{
+		"https://europe-west4-aiplatform.googleapis.com": "europe-west4",
+		"https://aiplatform.googleapis.com":              vertexGlobalLocation,
+		"https://vertex-proxy.internal/google":           vertexGlobalLocation,
+	} {
+		if got := vertexLocationFromURL(url); got != want {
+			t.Fatalf("%s: got location %s, want %s", url, got, want)
+		}
+	}
+}
+
+func TestHandleChannelVertex(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	server.vertex = newVertexRegistry()
+	_, creds := newFakeGCPKeys(t)
+	ctx := context.Background()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{Name: "vertex", URL: "https://us-central1-aiplatform.googleapis.com/",
+		ChannelType: "gemini", Enabled: true, ModelEntries: []model.ModelEntry{{Model: "gemini-2.5-pro"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claude, err := store.CreateConfig(ctx, &model.Config{Name: "claude", URL: "https://api.example.com", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/admin/channels/:id/vertex", server.HandleGetChannelVertex)
+	r.PUT("/admin/channels/:id/vertex", server.HandleSetChannelVertex)
+	r.DELETE("/admin/channels/:id/vertex", server.HandleDeleteChannelVertex)
+	do := func(method string, id int64, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/admin/channels/"+strconv.FormatInt(id, 10)+"/vertex", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	credsJSON := strconv.Quote(creds)
+
+	if w := do(http.MethodPut, claude.ID, `{"credentials":`+credsJSON+`}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("non-gemini channel should be rejected, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, cfg.ID, `{"credentials":"{}"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid service account should be rejected, got %d", w.Code)
+	}
+	w := do(http.MethodPut, cfg.ID, `{"credentials":`+credsJSON+`}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"location":"us-central1"`) || !strings.Contains(w.Body.String(), `"project_id":"proj-1"`) {
+		t.Fatalf("set credential: %d %s", w.Code, w.Body.String())
+	}
+	if keys, _ := store.GetAPIKeys(ctx, cfg.ID); len(keys) != 1 || keys[0].APIKey != vertexPlaceholderKey {
+		t.Fatalf("placeholder key should be added to a keyless channel, got %+v", keys)
+	}
+	w = do(http.MethodGet, cfg.ID, "")
+	if !strings.Contains(w.Body.String(), `"client_email":"sa@proj-1.iam.gserviceaccount.com"`) || strings.Contains(w.Body.String(), "PRIVATE KEY") {
+		t.Fatalf("view should show the account but never the key: %s", w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "https://us-central1-aiplatform.googleapis.com/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", nil)
+	req.Header.Set("x-goog-api-key", vertexPlaceholderKey)
+	if err := server.applyVertexAuth(ctx, req, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.Path != "/v1/projects/proj-1/locations/us-central1/publishers/google/models/gemini-2.5-pro:streamGenerateContent" ||
+		req.URL.Query().Get("alt") != "sse" {
+		t.Fatalf("unexpected vertex url %s", req.URL)
+	}
+	if req.Header.Get("Authorization") != "Bearer tok" || req.Header.Get("x-goog-api-key") != "" {
+		t.Fatalf("vertex request should use the service account token, got %v", req.Header)
+	}
+
+	if w := do(http.MethodDelete, cfg.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete credential: %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:generateContent", nil)
+	if err := server.applyVertexAuth(ctx, req, cfg); err != nil || req.URL.Path != "/v1beta/models/gemini-2.5-pro:generateContent" {
+		t.Fatalf("request should be untouched after unbinding, got %s %v", req.URL, err)
+	}
+	if w := do(http.MethodDelete, cfg.ID, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("second delete should be 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,654 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// 出站Webhook通知（2026-10新增）
+// ============================================================================
+// 管理端事件流只服务于打开着的页面；Webhook 把关键状态变化主动推送给外部系统（IM机器人、值班平台）：
+//   - channel_cooldown / key_cooldown：渠道或Key进入冷却（自动判定与手动设置）
+//   - channel_recovered：冷却中的渠道再次请求成功，或被手动解除冷却
+//   - token_budget_exceeded：令牌费用超过限额，请求开始被拒绝
+//   - budget_alert：令牌/渠道花费越过限额或月度预算的告警阈值（同 /admin/alerts）
+//
+// 端点存储在 webhooks 表，启动时与每次管理接口修改后重新加载。
+// 事件经有界队列异步投递，不阻塞请求路径；投递失败（网络错误/429/5xx）按退避重试。
+// 配置了 secret 的端点附带签名：X-CCLoad-Signature = "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))，
+// timestamp 即 X-CCLoad-Timestamp（Unix秒），接收方应校验签名并拒绝过旧的时间戳。
+// 自动冷却事件按 渠道/Key 去重：进入冷却后到恢复前只通知一次，避免故障期间每次失败都推送。
+
+// Webhook 事件类型
+const (
+	WebhookEventChannelCooldown     = "channel_cooldown"
+	WebhookEventKeyCooldown         = "key_cooldown"
+	WebhookEventChannelRecovered    = "channel_recovered"
+	WebhookEventTokenBudgetExceeded = "token_budget_exceeded"
+	WebhookEventBudgetAlert         = "budget_alert"
+	WebhookEventPing                = "ping" // 仅用于管理接口测试投递
+)
+
+// webhookEventTypes 可订阅的事件类型
+var webhookEventTypes = []string{WebhookEventChannelCooldown, WebhookEventKeyCooldown, WebhookEventChannelRecovered, WebhookEventTokenBudgetExceeded, WebhookEventBudgetAlert}
+
+const (
+	webhookQueueSize       = 256
+	webhookMaxInflight     = 8
+	webhookHTTPTimeout     = 10 * time.Second
+	webhookBudgetDedupeTTL = time.Hour // 同一令牌+限额的超限通知间隔
+
+	headerWebhookEvent     = "X-CCLoad-Event"
+	headerWebhookDelivery  = "X-CCLoad-Delivery"
+	headerWebhookTimestamp = "X-CCLoad-Timestamp"
+	headerWebhookSignature = "X-CCLoad-Signature"
+)
+
+// webhookRetryBackoff 失败后的重试间隔（共 1+len 次尝试）
+var webhookRetryBackoff = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+// WebhookPayload 投递的事件体
+type WebhookPayload struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Time int64  `json:"time"` // Unix毫秒
+	Data any    `json:"data"`
+}
+
+// WebhookCooldownData channel_cooldown / key_cooldown / channel_recovered 事件数据
+type WebhookCooldownData struct {
+	ChannelID   int64  `json:"channel_id"`
+	ChannelName string `json:"channel_name,omitempty"`
+	KeyIndex    int    `json:"key_index"`             // -1 表示渠道级
+	Source      string `json:"source"`                // auto / manual
+	Until       int64  `json:"until,omitempty"`       // 冷却截止时间（Unix毫秒，仅手动设置时已知）
+	StatusCode  int    `json:"status_code,omitempty"` // 触发冷却的上游状态码（自动判定时）
+}
+
+// WebhookBudgetData token_budget_exceeded 事件数据
+type WebhookBudgetData struct {
+	TokenID  int64   `json:"token_id"`
+	UsedUSD  float64 `json:"used_usd"`
+	LimitUSD float64 `json:"limit_usd"`
+}
+
+// compiledWebhook 预解析的端点（事件订阅解析只在加载时做一次）
+type compiledWebhook struct {
+	*model.Webhook
+	events map[string]bool // 空表示全部
+}
+
+func (w *compiledWebhook) wants(eventType string) bool {
+	return eventType == WebhookEventPing || len(w.events) == 0 || w.events[eventType]
+}
+
+// webhookDeliveryStatus 端点最近一次投递结果（仅内存，管理接口展示）
+type webhookDeliveryStatus struct {
+	LastEvent      string `json:"last_event,omitempty"`
+	LastDeliveryAt int64  `json:"last_delivery_at,omitempty"` // Unix秒
+	LastStatus     int    `json:"last_status,omitempty"`      // 最后一次尝试的HTTP状态码（0=未收到响应）
+	LastError      string `json:"last_error,omitempty"`
+	Delivered      int64  `json:"delivered"`
+	Failed         int64  `json:"failed"` // 重试耗尽仍失败的事件数
+}
+
+// webhookCoolingKey 已通知冷却的渠道/Key（keyIndex=-1 表示渠道级）
+type webhookCoolingKey struct {
+	channelID int64
+	keyIndex  int
+}
+
+// webhookDispatcher 端点集合与投递队列（nil 安全：未初始化时不投递）
+type webhookDispatcher struct {
+	client  *http.Client
+	queue   chan *WebhookPayload
+	backoff []time.Duration
+
+	mu        sync.RWMutex
+	endpoints []*compiledWebhook
+	status    map[int64]*webhookDeliveryStatus
+
+	stateMu     sync.Mutex
+	cooling     map[webhookCoolingKey]struct{}
+	budgetSent  map[string]time.Time // tokenHash@limit → 最近通知时间
+	inflight    sync.WaitGroup
+	inflightSem chan struct{}
+}
+
+func newWebhookDispatcher() *webhookDispatcher {
+	return &webhookDispatcher{
+		client:      &http.Client{Timeout: webhookHTTPTimeout},
+		queue:       make(chan *WebhookPayload, webhookQueueSize),
+		backoff:     webhookRetryBackoff,
+		status:      make(map[int64]*webhookDeliveryStatus),
+		cooling:     make(map[webhookCoolingKey]struct{}),
+		budgetSent:  make(map[string]time.Time),
+		inflightSem: make(chan struct{}, webhookMaxInflight),
+	}
+}
+
+// set 替换端点集合（跳过禁用的端点）
+func (d *webhookDispatcher) set(hooks []*model.Webhook) {
+	compiled := make([]*compiledWebhook, 0, len(hooks))
+	for _, h := range hooks {
+		if !h.Enabled {
+			continue
+		}
+		compiled = append(compiled, &compiledWebhook{Webhook: h, events: parseWebhookEvents(h.Events)})
+	}
+	d.mu.Lock()
+	d.endpoints = compiled
+	d.mu.Unlock()
+}
+
+// active 是否存在订阅了该事件的端点
+func (d *webhookDispatcher) active(eventType string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, ep := range d.endpoints {
+		if ep.wants(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *webhookDispatcher) statusOf(id int64) webhookDeliveryStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if st := d.status[id]; st != nil {
+		return *st
+	}
+	return webhookDeliveryStatus{}
+}
+
+// enqueue 非阻塞投递事件（队列满时丢弃并记录日志，不影响请求路径）
+func (d *webhookDispatcher) enqueue(eventType string, data any) {
+	if !d.active(eventType) {
+		return
+	}
+	p := newWebhookPayload(eventType, data)
+	select {
+	case d.queue <- p:
+	default:
+		log.Printf("[WARN] Webhook队列已满，丢弃事件: %s", eventType)
+	}
+}
+
+// markCooling 记录渠道/Key进入冷却；已处于冷却（尚未恢复）时返回 false
+func (d *webhookDispatcher) markCooling(channelID int64, keyIndex int) bool {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	k := webhookCoolingKey{channelID, keyIndex}
+	if _, ok := d.cooling[k]; ok {
+		return false
+	}
+	d.cooling[k] = struct{}{}
+	return true
+}
+
+// clearCooling 清除冷却记录；返回渠道级冷却此前是否已通知
+func (d *webhookDispatcher) clearCooling(channelID int64, keyIndex int) bool {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	if keyIndex >= 0 {
+		delete(d.cooling, webhookCoolingKey{channelID, keyIndex})
+	}
+	k := webhookCoolingKey{channelID, -1}
+	_, wasCooling := d.cooling[k]
+	delete(d.cooling, k)
+	return wasCooling
+}
+
+// markBudgetExceeded 记录令牌超限通知；同一令牌+限额在去重窗口内已通知时返回 false
+func (d *webhookDispatcher) markBudgetExceeded(tokenHash string, limitMicro int64, now time.Time) bool {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	k := tokenHash + "@" + strconv.FormatInt(limitMicro, 10)
+	if last, ok := d.budgetSent[k]; ok && now.Sub(last) < webhookBudgetDedupeTTL {
+		return false
+	}
+	for key, t := range d.budgetSent {
+		if now.Sub(t) >= webhookBudgetDedupeTTL {
+			delete(d.budgetSent, key)
+		}
+	}
+	d.budgetSent[k] = now
+	return true
+}
+
+// dispatch 将事件投递给所有订阅的端点（每个端点独立重试，并发受 webhookMaxInflight 限制）
+func (d *webhookDispatcher) dispatch(ctx context.Context, p *WebhookPayload) {
+	body, err := sonic.Marshal(p)
+	if err != nil {
+		log.Printf("[WARN] Webhook事件序列化失败: %s: %v", p.Type, err)
+		return
+	}
+	d.mu.RLock()
+	targets := make([]*compiledWebhook, 0, len(d.endpoints))
+	for _, ep := range d.endpoints {
+		if ep.wants(p.Type) {
+			targets = append(targets, ep)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, ep := range targets {
+		select {
+		case d.inflightSem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		d.inflight.Add(1)
+		go func() {
+			defer d.inflight.Done()
+			defer func() { <-d.inflightSem }()
+			d.deliverWithRetry(ctx, ep.Webhook, p, body)
+		}()
+	}
+}
+
+// deliverWithRetry 投递单个端点，可重试错误按退避重试
+func (d *webhookDispatcher) deliverWithRetry(ctx context.Context, hook *model.Webhook, p *WebhookPayload, body []byte) {
+	var status int
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		status, retryable, err = d.deliver(ctx, hook, p, body)
+		if err == nil || !retryable || attempt >= len(d.backoff) {
+			break
+		}
+		select {
+		case <-time.After(d.backoff[attempt]):
+		case <-ctx.Done():
+			err = fmt.Errorf("%w (aborted: shutting down)", err)
+			d.recordStatus(hook.ID, p.Type, status, err)
+			return
+		}
+	}
+	d.recordStatus(hook.ID, p.Type, status, err)
+	if err != nil {
+		log.Printf("[WARN] Webhook投递失败: id=%d event=%s: %s", hook.ID, p.Type, err.Error())
+	}
+}
+
+// deliver 单次投递；返回状态码、是否可重试与错误（2xx 视为成功）
+func (d *webhookDispatcher) deliver(ctx context.Context, hook *model.Webhook, p *WebhookPayload, body []byte) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("build request: %w", err)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ccLoad-Webhook")
+	req.Header.Set(headerWebhookEvent, p.Type)
+	req.Header.Set(headerWebhookDelivery, p.ID)
+	req.Header.Set(headerWebhookTimestamp, ts)
+	if hook.Secret != "" {
+		req.Header.Set(headerWebhookSignature, signWebhookPayload(hook.Secret, ts, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		// 不在错误信息中带出URL（IM机器人地址本身即凭据）
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return 0, true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return resp.StatusCode, retryable, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+func (d *webhookDispatcher) recordStatus(id int64, eventType string, status int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := d.status[id]
+	if st == nil {
+		st = &webhookDeliveryStatus{}
+		d.status[id] = st
+	}
+	st.LastEvent = eventType
+	st.LastDeliveryAt = time.Now().Unix()
+	st.LastStatus = status
+	st.LastError = ""
+	if err != nil {
+		st.LastError = err.Error()
+		st.Failed++
+	} else {
+		st.Delivered++
+	}
+}
+
+// signWebhookPayload 计算签名头：sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookPayload(eventType string, data any) *WebhookPayload {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return &WebhookPayload{ID: "evt_" + hex.EncodeToString(id[:]), Type: eventType, Time: time.Now().UnixMilli(), Data: data}
+}
+
+// parseWebhookEvents 解析逗号分隔的事件订阅（空表示全部）
+func parseWebhookEvents(raw string) map[string]bool {
+	events := make(map[string]bool)
+	for _, e := range strings.Split(raw, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events[e] = true
+		}
+	}
+	return events
+}
+
+// normalizeWebhookEvents 校验事件类型并规范化为逗号分隔的存储格式
+func normalizeWebhookEvents(events []string) (string, error) {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if e == "" || slices.Contains(out, e) {
+			continue
+		}
+		if !slices.Contains(webhookEventTypes, e) {
+			return "", fmt.Errorf("unknown event type %q (allowed: %s)", e, strings.Join(webhookEventTypes, ", "))
+		}
+		out = append(out, e)
+	}
+	return strings.Join(out, ","), nil
+}
+
+// validateWebhookURL 端点地址必须为 http/https 绝对地址
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	return nil
+}
+
+// ================== 事件来源 ==================
+
+// notifyCooldownWebhook 渠道/Key冷却状态变化（由 publishCooldownEvent 调用）
+func (s *Server) notifyCooldownWebhook(channelID int64, ev AdminCooldownEvent) {
+	d := s.webhooks
+	if d == nil {
+		return
+	}
+	data := WebhookCooldownData{ChannelID: channelID, KeyIndex: ev.KeyIndex, Source: ev.Source, Until: ev.Until, StatusCode: ev.StatusCode}
+	switch ev.Action {
+	case "cooldown":
+		// 自动冷却去重；手动设置总是通知
+		if !d.markCooling(channelID, ev.KeyIndex) && ev.Source == "auto" {
+			return
+		}
+		eventType := WebhookEventKeyCooldown
+		if ev.KeyIndex < 0 {
+			eventType = WebhookEventChannelCooldown
+		}
+		s.enqueueChannelWebhook(eventType, data)
+	case "cleared":
+		if d.clearCooling(channelID, ev.KeyIndex) && ev.KeyIndex < 0 {
+			s.enqueueChannelWebhook(WebhookEventChannelRecovered, data)
+		}
+	}
+}
+
+// notifyChannelSucceeded 请求成功：清除冷却记录，渠道此前处于冷却时通知恢复
+func (s *Server) notifyChannelSucceeded(channelID int64, keyIndex int) {
+	d := s.webhooks
+	if d == nil || !d.clearCooling(channelID, keyIndex) {
+		return
+	}
+	s.enqueueChannelWebhook(WebhookEventChannelRecovered, WebhookCooldownData{ChannelID: channelID, KeyIndex: -1, Source: "auto"})
+}
+
+// notifyTokenBudgetExceeded 令牌费用超限导致请求被拒绝
+func (s *Server) notifyTokenBudgetExceeded(tokenHash string, usedMicro, limitMicro int64) {
+	d := s.webhooks
+	if !d.active(WebhookEventTokenBudgetExceeded) || !d.markBudgetExceeded(tokenHash, limitMicro, time.Now()) {
+		return
+	}
+	tokenID, _ := s.authService.TokenIDByHash(tokenHash)
+	d.enqueue(WebhookEventTokenBudgetExceeded, WebhookBudgetData{
+		TokenID:  tokenID,
+		UsedUSD:  util.MicroUSDToUSD(usedMicro),
+		LimitUSD: util.MicroUSDToUSD(limitMicro),
+	})
+}
+
+// enqueueChannelWebhook 补充渠道名后投递（渠道配置走缓存）
+func (s *Server) enqueueChannelWebhook(eventType string, data WebhookCooldownData) {
+	if !s.webhooks.active(eventType) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if cfg, err := s.GetConfig(ctx, data.ChannelID); err == nil && cfg != nil {
+		data.ChannelName = cfg.Name
+	}
+	s.webhooks.enqueue(eventType, data)
+}
+
+// webhookWorker 消费事件队列；关闭时停止重试并等待进行中的投递结束
+func (s *Server) webhookWorker() {
+	defer s.wg.Done()
+
+	d := s.webhooks
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		d.inflight.Wait()
+	}()
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case p := <-d.queue:
+			d.dispatch(ctx, p)
+		}
+	}
+}
+
+// reloadWebhooks 从数据库重新加载Webhook端点
+func (s *Server) reloadWebhooks(ctx context.Context) error {
+	hooks, err := s.store.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+	s.webhooks.set(hooks)
+	return nil
+}
+
+// ================== 管理接口 ==================
+
+// webhookView 管理接口返回的端点（附带最近投递结果）
+type webhookView struct {
+	*model.Webhook
+	Delivery webhookDeliveryStatus `json:"delivery"`
+}
+
+// HandleListWebhooks Webhook端点列表
+// GET /admin/webhooks
+func (s *Server) HandleListWebhooks(c *gin.Context) {
+	hooks, err := s.store.ListWebhooks(c.Request.Context())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	views := make([]webhookView, 0, len(hooks))
+	for _, h := range hooks {
+		views = append(views, webhookView{Webhook: h, Delivery: s.webhooks.statusOf(h.ID)})
+	}
+	RespondJSON(c, http.StatusOK, views)
+}
+
+// HandleCreateWebhook 新增Webhook端点
+// POST /admin/webhooks
+func (s *Server) HandleCreateWebhook(c *gin.Context) {
+	hook := &model.Webhook{}
+	if !bindWebhookRequest(c, hook) {
+		return
+	}
+	now := time.Now().Unix()
+	hook.CreatedAt, hook.UpdatedAt = now, now
+	if err := s.store.CreateWebhook(c.Request.Context(), hook); err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.afterWebhookChange(c.Request.Context())
+	RespondJSON(c, http.StatusCreated, hook)
+}
+
+// HandleUpdateWebhook 更新Webhook端点（secret 省略时保持不变）
+// PUT /admin/webhooks/:id
+func (s *Server) HandleUpdateWebhook(c *gin.Context) {
+	hook, ok := s.loadWebhook(c)
+	if !ok {
+		return
+	}
+	if !bindWebhookRequest(c, hook) {
+		return
+	}
+	hook.UpdatedAt = time.Now().Unix()
+	found, err := s.store.UpdateWebhook(c.Request.Context(), hook)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "webhook not found")
+		return
+	}
+	s.afterWebhookChange(c.Request.Context())
+	RespondJSON(c, http.StatusOK, hook)
+}
+
+// HandleDeleteWebhook 删除Webhook端点
+// DELETE /admin/webhooks/:id
+func (s *Server) HandleDeleteWebhook(c *gin.Context) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid webhook id")
+		return
+	}
+	found, err := s.store.DeleteWebhook(c.Request.Context(), id)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		RespondErrorMsg(c, http.StatusNotFound, "webhook not found")
+		return
+	}
+	s.afterWebhookChange(c.Request.Context())
+	RespondJSON(c, http.StatusOK, gin.H{"id": id, "deleted": true})
+}
+
+// HandleTestWebhook 同步投递一次 ping 事件（不重试），返回端点响应状态
+// POST /admin/webhooks/:id/test
+func (s *Server) HandleTestWebhook(c *gin.Context) {
+	hook, ok := s.loadWebhook(c)
+	if !ok {
+		return
+	}
+	p := newWebhookPayload(WebhookEventPing, gin.H{"webhook_id": hook.ID, "name": hook.Name})
+	body, err := sonic.Marshal(p)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	status, _, err := s.webhooks.deliver(c.Request.Context(), hook, p, body)
+	s.webhooks.recordStatus(hook.ID, p.Type, status, err)
+	result := gin.H{"delivered": err == nil, "status_code": status}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+	RespondJSON(c, http.StatusOK, result)
+}
+
+func (s *Server) loadWebhook(c *gin.Context) (*model.Webhook, bool) {
+	id, err := ParseInt64Param(c, "id")
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid webhook id")
+		return nil, false
+	}
+	hook, err := s.store.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusNotFound, "webhook not found")
+		return nil, false
+	}
+	return hook, true
+}
+
+// bindWebhookRequest 解析并校验请求体，写入端点字段
+func bindWebhookRequest(c *gin.Context, hook *model.Webhook) bool {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return false
+	}
+	hook.URL = strings.TrimSpace(req.URL)
+	if err := validateWebhookURL(hook.URL); err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		RespondErrorMsg(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+	hook.Name = strings.TrimSpace(req.Name)
+	hook.Events = events
+	if req.Secret != nil {
+		hook.Secret = *req.Secret
+	}
+	hook.HasSecret = hook.Secret != ""
+	hook.Enabled = true
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	return true
+}
+
+// afterWebhookChange 端点变更后立即重新加载（加载失败不影响接口结果，下次变更或重启时恢复）
+func (s *Server) afterWebhookChange(ctx context.Context) {
+	if err := s.reloadWebhooks(ctx); err != nil {
+		log.Printf("[WARN] 重新加载Webhook端点失败: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhookReceiver 记录收到的投递；前 failFirst 次返回 500
+type webhookReceiver struct {
+	mu        sync.Mutex
+	failFirst int
+	attempts  int
+	headers   []http.Header
+	bodies    [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.attempts <= r.failFirst {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	r.headers = append(r.headers, req.Header.Clone())
+	r.bodies = append(r.bodies, body)
+}
+
+// drainWebhooks 同步投递队列中的全部事件
+func drainWebhooks(d *webhookDispatcher) {
+	for {
+		select {
+		case p := <-d.queue:
+			d.dispatch(context.Background(), p)
+		default:
+			d.inflight.Wait()
+			return
+		}
+	}
+}
+
+func TestWebhooks_CooldownRecoveryDelivery(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	server.webhooks = newWebhookDispatcher()
+	server.webhooks.backoff = []time.Duration{time.Millisecond, time.Millisecond}
+
+	recv := &webhookReceiver{failFirst: 2}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	ctx := context.Background()
+	ch, err := store.CreateConfig(ctx, &model.Config{Name: "upstream-a", URL: "https://a.example", ChannelType: "anthropic", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateWebhook(ctx, &model.Webhook{URL: ts.URL, Secret: "s3cret", Events: "channel_cooldown,channel_recovered", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.reloadWebhooks(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 自动冷却去重：恢复前重复失败只通知一次；未订阅的 key_cooldown 不投递
+	server.publishCooldownEvent(ch.ID, AdminCooldownEvent{KeyIndex: -1, Action: "cooldown", Source: "auto", StatusCode: 503})
+	server.publishCooldownEvent(ch.ID, AdminCooldownEvent{KeyIndex: -1, Action: "cooldown", Source: "auto", StatusCode: 503})
+	server.publishCooldownEvent(ch.ID, AdminCooldownEvent{KeyIndex: 0, Action: "cooldown", Source: "auto", StatusCode: 429})
+	drainWebhooks(server.webhooks)
+
+	// 成功请求触发恢复；再次成功不重复通知
+	server.notifyChannelSucceeded(ch.ID, 0)
+	server.notifyChannelSucceeded(ch.ID, 0)
+	drainWebhooks(server.webhooks)
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	if recv.attempts != 4 || len(recv.bodies) != 2 {
+		t.Fatalf("expected 2 deliveries after 2 retried failures, got attempts=%d delivered=%d", recv.attempts, len(recv.bodies))
+	}
+	var cooldownEv struct {
+		WebhookPayload
+		Data WebhookCooldownData `json:"data"`
+	}
+	if err := json.Unmarshal(recv.bodies[0], &cooldownEv); err != nil {
+		t.Fatal(err)
+	}
+	if cooldownEv.Type != WebhookEventChannelCooldown || cooldownEv.Data.ChannelName != "upstream-a" || cooldownEv.Data.StatusCode != 503 {
+		t.Fatalf("unexpected cooldown payload: %s", recv.bodies[0])
+	}
+	h := recv.headers[0]
+	if got, want := h.Get(headerWebhookSignature), signWebhookPayload("s3cret", h.Get(headerWebhookTimestamp), recv.bodies[0]); got != want {
+		t.Fatalf("signature mismatch: got %q want %q", got, want)
+	}
+	if h.Get(headerWebhookEvent) != WebhookEventChannelCooldown || h.Get(headerWebhookDelivery) != cooldownEv.ID {
+		t.Fatalf("unexpected headers: %v", h)
+	}
+	if !bytes.Contains(recv.bodies[1], []byte(`"type":"channel_recovered"`)) {
+		t.Fatalf("expected recovery event, got %s", recv.bodies[1])
+	}
+	if st := server.webhooks.statusOf(1); st.Delivered != 2 || st.Failed != 0 || st.LastStatus != http.StatusOK {
+		t.Fatalf("unexpected delivery status: %+v", st)
+	}
+}
+
+func TestWebhooks_BudgetExceededDedupe(t *testing.T) {
+	d := newWebhookDispatcher()
+	now := time.Now()
+	if !d.markBudgetExceeded("h1", 5_000_000, now) {
+		t.Fatal("first exceed should notify")
+	}
+	if d.markBudgetExceeded("h1", 5_000_000, now.Add(time.Minute)) {
+		t.Fatal("repeated exceed within window should be suppressed")
+	}
+	if !d.markBudgetExceeded("h1", 8_000_000, now.Add(time.Minute)) {
+		t.Fatal("raised limit should notify again")
+	}
+	if !d.markBudgetExceeded("h1", 5_000_000, now.Add(webhookBudgetDedupeTTL+time.Second)) {
+		t.Fatal("exceed after window should notify again")
+	}
+}
+
+func TestHandleWebhooks_CRUD(t *testing.T) {
+	server, store, cleanup := setupAdminTestServer(t)
+	defer cleanup()
+	server.webhooks = newWebhookDispatcher()
+
+	recv := &webhookReceiver{}
+	ts := httptest.NewServer(recv)
+	defer ts.Close()
+
+	do := func(method, path, body string, params gin.Params, h gin.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		h(c)
+		return w
+	}
+
+	for _, body := range []string{
+		`{"url":"ftp://hooks.example.com"}`,
+		`{"url":"/relative"}`,
+		`{"url":"https://hooks.example.com","events":["nope"]}`,
+	} {
+		if w := do(http.MethodPost, "/admin/webhooks", body, nil, server.HandleCreateWebhook); w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d (%s)", body, w.Code, w.Body.String())
+		}
+	}
+
+	w := do(http.MethodPost, "/admin/webhooks", `{"name":"ops","url":"`+ts.URL+`","secret":"k","events":["channel_cooldown"]}`, nil, server.HandleCreateWebhook)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d (%s)", w.Code, w.Body.String())
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte(`"k"`)) || !bytes.Contains(w.Body.Bytes(), []byte(`"has_secret":true`)) {
+		t.Fatalf("secret must not be returned: %s", w.Body.String())
+	}
+	if !server.webhooks.active(WebhookEventChannelCooldown) || server.webhooks.active(WebhookEventKeyCooldown) {
+		t.Fatal("endpoint should be active for subscribed events only")
+	}
+
+	hooks, _ := store.ListWebhooks(context.Background())
+	if len(hooks) != 1 {
+		t.Fatalf("expected 1 webhook, got %d", len(hooks))
+	}
+	id := strconv.FormatInt(hooks[0].ID, 10)
+	params := gin.Params{{Key: "id", Value: id}}
+
+	// 省略 secret 保持不变
+	w = do(http.MethodPut, "/admin/webhooks/"+id, `{"name":"ops","url":"`+ts.URL+`","enabled":false}`, params, server.HandleUpdateWebhook)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", w.Code, w.Body.String())
+	}
+	if got, _ := store.GetWebhook(context.Background(), hooks[0].ID); got.Secret != "k" || got.Enabled || got.Events != "" {
+		t.Fatalf("unexpected stored webhook: %+v", got)
+	}
+	if server.webhooks.active(WebhookEventChannelCooldown) {
+		t.Fatal("disabled endpoint should not receive events")
+	}
+
+	// 测试投递对禁用端点同样生效
+	w = do(http.MethodPost, "/admin/webhooks/"+id+"/test", "", params, server.HandleTestWebhook)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"delivered":true`)) {
+		t.Fatalf("test delivery failed: %d %s", w.Code, w.Body.String())
+	}
+	if len(recv.headers) != 1 || recv.headers[0].Get(headerWebhookEvent) != WebhookEventPing || recv.headers[0].Get(headerWebhookSignature) == "" {
+		t.Fatalf("expected signed ping, got %v", recv.headers)
+	}
+
+	w = do(http.MethodDelete, "/admin/webhooks/"+id, "", params, server.HandleDeleteWebhook)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	w = do(http.MethodDelete, "/admin/webhooks/"+id, "", params, server.HandleDeleteWebhook)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 on second delete, got %d", w.Code)
+	}
+}
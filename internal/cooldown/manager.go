@@ -48,6 +48,9 @@ type ConfigGetter interface {
 type Manager struct {
 	store        storage.Store
 	configGetter ConfigGetter // 可选：优先使用缓存层（性能提升~60%）
+
+	// onAccountGroupCooldown 账号分组冷却同步到其他渠道后的回调（用于失效这些渠道的缓存），可为nil
+	onAccountGroupCooldown func(channelIDs []int64)
 }
 
 // NewManager 创建冷却管理器实例
@@ -59,6 +62,11 @@ func NewManager(store storage.Store, configGetter ConfigGetter) *Manager {
 	}
 }
 
+// SetAccountGroupHook 设置账号分组冷却同步回调（2026-10新增）
+func (m *Manager) SetAccountGroupHook(fn func(channelIDs []int64)) {
+	m.onAccountGroupCooldown = fn
+}
+
 // HandleError 统一错误处理与冷却决策
 // 将proxy_error.go中的handleProxyError逻辑提取到专用模块
 //
@@ -94,13 +102,17 @@ func (m *Manager) HandleError(ctx context.Context, in ErrorInput) Action {
 
 	// 2. [INFO] 特定渠道类型的400错误策略覆盖
 	// anthropic (Claude Code) 和 codex 是专用软件渠道，400错误一定是上游问题而非客户端请求问题
-	if statusCode == 400 && (in.ChannelType == util.ChannelTypeAnthropic || in.ChannelType == util.ChannelTypeCodex) {
+	// 例外：请求体过大（request_too_large）是请求本身的问题，按客户端错误处理，不冷却（2026-10新增）
+	if util.IsRequestTooLargeError(statusCode, errorBody) {
+		errLevel = util.ErrorLevelClient
+	} else if statusCode == 400 && (in.ChannelType == util.ChannelTypeAnthropic || in.ChannelType == util.ChannelTypeCodex) {
 		errLevel = util.ErrorLevelChannel
 	}
 
 	// 3. [TARGET] 动态调整:单Key渠道的Key级错误应该直接冷却渠道
 	// 设计原则:如果没有其他Key可以重试,Key级错误等同于渠道级错误
 	// [WARN] 例外：1308错误保持Key级（因为它有精确时间，后续会特殊处理）
+	keyScoped := errLevel == util.ErrorLevelKey // 升级前为Key级：错误属于上游账号本身，需同步到账号分组
 	if errLevel == util.ErrorLevelKey && !has1308Time {
 		var config *model.Config
 		var err error
@@ -137,16 +149,20 @@ func (m *Manager) HandleError(ctx context.Context, in ErrorInput) Action {
 					duration := time.Until(reset1308Time)
 					log.Printf("[COOLDOWN] Key冷却(1308): 渠道=%d Key=%d 禁用至 %s (%.1f分钟)",
 						channelID, keyIndex, reset1308Time.Format("2006-01-02 15:04:05"), duration.Minutes())
+					m.propagateAccountGroup(ctx, channelID, keyIndex, reset1308Time)
 				}
 				return ActionRetryKey
 			}
 
 			// 默认逻辑: 使用指数退避策略
-			_, err := m.store.BumpKeyCooldown(ctx, channelID, keyIndex, time.Now(), statusCode)
+			now := time.Now()
+			duration, err := m.store.BumpKeyCooldown(ctx, channelID, keyIndex, now, statusCode)
 			if err != nil {
 				// 冷却更新失败是非致命错误
 				// 记录日志但不中断请求处理,避免因数据库BUSY导致无限重试
 				log.Printf("[WARN] Failed to update key cooldown (channel=%d, key=%d): %v", channelID, keyIndex, err)
+			} else {
+				m.propagateAccountGroup(ctx, channelID, keyIndex, now.Add(duration))
 			}
 		}
 		return ActionRetryKey
@@ -167,12 +183,16 @@ func (m *Manager) HandleError(ctx context.Context, in ErrorInput) Action {
 		}
 
 		// 默认逻辑: 使用指数退避策略
-		_, err := m.store.BumpChannelCooldown(ctx, channelID, time.Now(), statusCode)
+		now := time.Now()
+		duration, err := m.store.BumpChannelCooldown(ctx, channelID, now, statusCode)
 		if err != nil {
 			// 冷却更新失败是非致命错误
 			// 设计原则: 数据库故障不应阻塞用户请求,系统应降级服务
 			// 影响: 可能导致短暂的冷却状态不一致,但总比拒绝服务更好
 			log.Printf("[WARN] Failed to update channel cooldown (channel=%d): %v", channelID, err)
+		} else if keyScoped && keyIndex != NoKeyIndex {
+			// 单Key渠道的Key级错误：渠道冷却的同时把账号冷却同步到其他渠道
+			m.propagateAccountGroup(ctx, channelID, keyIndex, now.Add(duration))
 		}
 		return ActionRetryChannel
 
@@ -182,6 +202,23 @@ func (m *Manager) HandleError(ctx context.Context, in ErrorInput) Action {
 	}
 }
 
+// propagateAccountGroup 将Key冷却同步到同一上游账号分组的其他Key（失败仅记录日志）
+func (m *Manager) propagateAccountGroup(ctx context.Context, channelID int64, keyIndex int, until time.Time) {
+	channelIDs, err := m.store.SetAccountGroupCooldown(ctx, channelID, keyIndex, until)
+	if err != nil {
+		log.Printf("[WARN] Failed to propagate account group cooldown (channel=%d, key=%d): %v", channelID, keyIndex, err)
+		return
+	}
+	if len(channelIDs) == 0 {
+		return
+	}
+	log.Printf("[COOLDOWN] 账号分组冷却同步: 源渠道=%d Key=%d → 渠道%v 冷却至 %s",
+		channelID, keyIndex, channelIDs, until.Format("2006-01-02 15:04:05"))
+	if m.onAccountGroupCooldown != nil {
+		m.onAccountGroupCooldown(channelIDs)
+	}
+}
+
 // ClearChannelCooldown 清除渠道冷却状态
 // 简化成功后的冷却清除逻辑
 func (m *Manager) ClearChannelCooldown(ctx context.Context, channelID int64) error {
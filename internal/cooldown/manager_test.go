@@ -35,13 +35,17 @@ func TestHandleError_ClientError(t *testing.T) {
 	cfg := createTestChannel(t, store, "test-client-error")
 
 	testCases := []struct {
-		name       string
-		statusCode int
-		errorBody  []byte
+		name        string
+		statusCode  int
+		errorBody   []byte
+		channelType string
 	}{
-		{"404未找到", 404, []byte(`{"error":"not found"}`)},
+		{"404未找到", 404, []byte(`{"error":"not found"}`), ""},
 		// 注意：405 已改为渠道级错误（上游endpoint配置问题）
 		// 注意：400 Bad Request 被分类为Key级错误（API Key格式错误），不是客户端错误
+		{"413请求体过大", 413, []byte(`{"type":"error","error":{"type":"request_too_large","message":"Request exceeds the maximum allowed number of bytes."}}`), "anthropic"},
+		// anthropic 渠道的400默认升级为渠道级，但 request_too_large 属于请求本身的问题
+		{"400请求体过大(anthropic)", 400, []byte(`{"type":"error","error":{"type":"request_too_large","message":"too large"}}`), "anthropic"},
 	}
 
 	for _, tc := range testCases {
@@ -53,6 +57,7 @@ func TestHandleError_ClientError(t *testing.T) {
 				ErrorBody:      tc.errorBody,
 				IsNetworkError: false,
 				Headers:        nil,
+				ChannelType:    tc.channelType,
 			})
 
 			if action != ActionReturnClient {
@@ -612,6 +617,83 @@ func TestHandleError_RateLimitClassification(t *testing.T) {
 	}
 }
 
+// TestHandleError_AccountGroupPropagation 测试Key级冷却同步到同账号分组的其他渠道Key
+func TestHandleError_AccountGroupPropagation(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	manager := NewManager(store, nil)
+	ctx := context.Background()
+
+	var hooked []int64
+	manager.SetAccountGroupHook(func(ids []int64) { hooked = append(hooked, ids...) })
+
+	// 渠道A：两个Key，Key0 属于 acme 分组
+	chA := createTestChannel(t, store, "group-a")
+	_ = store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: chA.ID, KeyIndex: 0, APIKey: "sk-acme", AccountGroup: "acme"},
+		{ChannelID: chA.ID, KeyIndex: 1, APIKey: "sk-other"},
+	})
+	// 渠道B：同一账号经不同入口（单Key）
+	chB := createTestChannel(t, store, "group-b")
+	_ = store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: chB.ID, KeyIndex: 0, APIKey: "sk-acme-proxy", AccountGroup: "acme"},
+	})
+	// 渠道C：不同分组，不受影响
+	chC := createTestChannel(t, store, "group-c")
+	_ = store.CreateAPIKeysBatch(ctx, []*model.APIKey{
+		{ChannelID: chC.ID, KeyIndex: 0, APIKey: "sk-beta", AccountGroup: "beta"},
+	})
+
+	action := manager.HandleError(ctx, ErrorInput{
+		ChannelID:  chA.ID,
+		KeyIndex:   0,
+		StatusCode: 429,
+		ErrorBody:  []byte(`{"error":{"type":"rate_limit_error"}}`),
+	})
+	if action != ActionRetryKey {
+		t.Fatalf("Expected ActionRetryKey, got %v", action)
+	}
+
+	srcUntil, ok := getKeyCooldownUntil(ctx, store, chA.ID, 0)
+	if !ok {
+		t.Fatal("source key should be cooled down")
+	}
+	if until, ok := getKeyCooldownUntil(ctx, store, chB.ID, 0); !ok || !until.Equal(srcUntil) {
+		t.Errorf("grouped key in channel B should share cooldown %v, got %v (exists=%v)", srcUntil, until, ok)
+	}
+	if _, ok := getKeyCooldownUntil(ctx, store, chA.ID, 1); ok {
+		t.Error("ungrouped key in the same channel should not be cooled down")
+	}
+	if _, ok := getKeyCooldownUntil(ctx, store, chC.ID, 0); ok {
+		t.Error("key in another group should not be cooled down")
+	}
+	if len(hooked) != 1 || hooked[0] != chB.ID {
+		t.Errorf("hook should report channel B only, got %v", hooked)
+	}
+
+	// 单Key渠道B的Key级错误升级为渠道级，账号冷却仍同步回渠道A的Key0
+	_ = store.ResetKeyCooldown(ctx, chA.ID, 0)
+	action = manager.HandleError(ctx, ErrorInput{
+		ChannelID:  chB.ID,
+		KeyIndex:   0,
+		StatusCode: 401,
+		ErrorBody:  []byte(`{"error":{"type":"authentication_error"}}`),
+	})
+	if action != ActionRetryChannel {
+		t.Fatalf("Expected ActionRetryChannel for single-key channel, got %v", action)
+	}
+	if _, ok := getKeyCooldownUntil(ctx, store, chA.ID, 0); !ok {
+		t.Error("escalated key-level error should still propagate to the account group")
+	}
+
+	// 渠道级错误（5xx）与账号无关，不同步
+	_ = store.ResetKeyCooldown(ctx, chA.ID, 0)
+	_ = manager.HandleError(ctx, ErrorInput{ChannelID: chB.ID, KeyIndex: 0, StatusCode: 502})
+	if _, ok := getKeyCooldownUntil(ctx, store, chA.ID, 0); ok {
+		t.Error("channel-level error should not propagate to the account group")
+	}
+}
+
 // ========== 辅助函数 ==========
 
 // getKeyCooldownUntil 获取指定Key的冷却时间（测试辅助函数）
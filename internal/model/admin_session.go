@@ -0,0 +1,34 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 管理员会话角色（2026-10新增）
+// 密码登录始终为 admin；SSO 登录按身份提供方的组映射为 admin 或 viewer
+const (
+	AdminRoleAdmin  = "admin"  // 完全管理权限
+	AdminRoleViewer = "viewer" // 只读：仅可查看（不含Key明文、抓取内容等敏感数据）
+)
+
+// AdminSession 管理员会话（token 以哈希形式存储）
+type AdminSession struct {
+	ExpiresAt time.Time
+	Role      string
+}
+
+// IsValidAdminRole 检查角色是否受支持
+func IsValidAdminRole(role string) bool {
+	return role == AdminRoleAdmin || role == AdminRoleViewer
+}
+
+// NormalizeAdminRole 规范化角色：空值按 admin 处理（兼容角色字段新增前的会话），未知值按最小权限 viewer 处理
+func NormalizeAdminRole(role string) string {
+	switch role = strings.ToLower(strings.TrimSpace(role)); role {
+	case "", AdminRoleAdmin:
+		return AdminRoleAdmin
+	default:
+		return AdminRoleViewer
+	}
+}
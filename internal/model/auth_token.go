@@ -31,11 +31,12 @@ type AuthToken struct {
 	NonStreamCount int64   `json:"non_stream_count"`  // 非流式请求计数(用于计算平均值)
 
 	// Token成本统计（2025-12新增）
-	PromptTokensTotal        int64   `json:"prompt_tokens_total"`         // 累计输入Token数
-	CompletionTokensTotal    int64   `json:"completion_tokens_total"`     // 累计输出Token数
-	CacheReadTokensTotal     int64   `json:"cache_read_tokens_total"`     // 累计缓存读Token数
-	CacheCreationTokensTotal int64   `json:"cache_creation_tokens_total"` // 累计缓存写Token数
-	TotalCostUSD             float64 `json:"total_cost_usd"`              // 累计成本(美元)
+	PromptTokensTotal        int64    `json:"prompt_tokens_total"`          // 累计输入Token数
+	CompletionTokensTotal    int64    `json:"completion_tokens_total"`      // 累计输出Token数
+	CacheReadTokensTotal     int64    `json:"cache_read_tokens_total"`      // 累计缓存读Token数
+	CacheCreationTokensTotal int64    `json:"cache_creation_tokens_total"`  // 累计缓存写Token数
+	TotalCostUSD             float64  `json:"total_cost_usd"`               // 累计成本(美元)
+	TotalCostDisplay         *float64 `json:"total_cost_display,omitempty"` // 累计成本(展示币种，仅接口输出，2026-10新增)
 
 	// 费用限额（2026-01新增）
 	// 使用微美元整数存储，避免浮点误差。JSON序列化时自动转换为USD浮点数。
@@ -50,6 +51,25 @@ type AuthToken struct {
 
 	// 模型限制（2026-01新增）
 	AllowedModels []string `json:"allowed_models,omitempty"` // 允许的模型列表，空表示无限制
+
+	// 模型屏蔽（2026-10新增）：禁止使用的模型（支持 * 通配符，不区分大小写），优先于 AllowedModels
+	BlockedModels []string `json:"blocked_models,omitempty"`
+
+	// mTLS客户端证书映射（2026-10新增）：证书 CN 或任一 SAN（DNS/邮箱/URI/IP）等于该值时视为持有此令牌
+	ClientCertSubject string `json:"client_cert_subject,omitempty"`
+
+	// 归属方（2026-10新增）：团队/负责人标识，统计接口可按其过滤和分组，用于内部成本分摊
+	Owner string `json:"owner,omitempty"`
+
+	// 故障转移信息（2026-10新增）：开启后在响应中附带上游尝试次数与最终渠道类型（响应头/SSE注释）
+	FailoverInfo bool `json:"failover_info,omitempty"`
+
+	// 速率限制（2026-10新增）：每分钟请求数/Token数上限（滑动窗口，0=不限制），超限返回429
+	RPMLimit int   `json:"rpm_limit,omitempty"`
+	TPMLimit int64 `json:"tpm_limit,omitempty"`
+
+	// 默认回退模型（2026-10新增）：请求模型未匹配任何启用渠道时改用该模型（空=不回退，直接拒绝）
+	FallbackModel string `json:"fallback_model,omitempty"`
 }
 
 // AuthTokenRangeStats 某个时间范围内的token统计（从logs表聚合，2025-12新增）
@@ -71,6 +91,21 @@ type AuthTokenRangeStats struct {
 	RecentRPM float64 `json:"recent_rpm"` // 最近一分钟RPM（仅本日有效）
 }
 
+// OwnerStats 按令牌归属方聚合的统计（从logs表聚合，2026-10新增）
+// Model 仅在按 owner+model 分组时有值；Owner 为空表示令牌未分配归属方
+type OwnerStats struct {
+	Owner               string  `json:"owner"`
+	Model               string  `json:"model,omitempty"`
+	TokenCount          int64   `json:"token_count"`           // 有请求的令牌数
+	SuccessCount        int64   `json:"success_count"`         // 成功次数
+	FailureCount        int64   `json:"failure_count"`         // 失败次数
+	PromptTokens        int64   `json:"prompt_tokens"`         // 输入Token总数
+	CompletionTokens    int64   `json:"completion_tokens"`     // 输出Token总数
+	CacheReadTokens     int64   `json:"cache_read_tokens"`     // 缓存读Token总数
+	CacheCreationTokens int64   `json:"cache_creation_tokens"` // 缓存写Token总数
+	TotalCost           float64 `json:"total_cost"`            // 总费用(美元)
+}
+
 // HashToken 计算令牌的SHA256哈希值
 // 用于安全存储令牌到数据库
 func HashToken(token string) string {
@@ -165,6 +200,11 @@ type authTokenJSON struct {
 	AvgRPM                   float64   `json:"avg_rpm,omitempty"`
 	RecentRPM                float64   `json:"recent_rpm,omitempty"`
 	AllowedModels            []string  `json:"allowed_models,omitempty"`
+	BlockedModels            []string  `json:"blocked_models,omitempty"`
+	FailoverInfo             bool      `json:"failover_info,omitempty"`
+	RPMLimit                 int       `json:"rpm_limit,omitempty"`
+	TPMLimit                 int64     `json:"tpm_limit,omitempty"`
+	FallbackModel            string    `json:"fallback_model,omitempty"`
 }
 
 // MarshalJSON 自定义JSON序列化，将MicroUSD转换为USD浮点数
@@ -194,5 +234,10 @@ func (t AuthToken) MarshalJSON() ([]byte, error) {
 		AvgRPM:                   t.AvgRPM,
 		RecentRPM:                t.RecentRPM,
 		AllowedModels:            t.AllowedModels,
+		BlockedModels:            t.BlockedModels,
+		FailoverInfo:             t.FailoverInfo,
+		RPMLimit:                 t.RPMLimit,
+		TPMLimit:                 t.TPMLimit,
+		FallbackModel:            t.FallbackModel,
 	})
 }
@@ -0,0 +1,32 @@
+package model
+
+// 预算告警范围
+const (
+	BudgetAlertScopeToken   = "token"   // API令牌：费用上限（cost_limit_usd，累计）或月度预算
+	BudgetAlertScopeChannel = "channel" // 渠道：每日成本限额（daily_cost_limit）或月度预算
+)
+
+// BudgetAlert 预算软告警（2026-10新增）
+// 花费首次越过硬限额的某个百分比阈值时生成一条，同一 范围+对象+周期+阈值 只记录一次
+type BudgetAlert struct {
+	ID             int64   `json:"id"`
+	Scope          string  `json:"scope"`           // token / channel
+	TargetID       int64   `json:"target_id"`       // 令牌ID或渠道ID
+	Period         string  `json:"period"`          // 渠道每日：YYYY-MM-DD；令牌累计：total@<限额>（调整限额后重新计数）；月度预算：YYYY-MM
+	Threshold      int     `json:"threshold"`       // 越过的阈值（百分比）
+	SpentUSD       float64 `json:"spent_usd"`       // 告警时已花费
+	LimitUSD       float64 `json:"limit_usd"`       // 告警时的硬限额
+	CreatedAt      int64   `json:"created_at"`      // Unix秒
+	AcknowledgedAt int64   `json:"acknowledged_at"` // 确认时间（Unix秒，0=未确认）
+}
+
+// MonthlyBudget 令牌/渠道月度预算（2026-10新增）
+// 仅用于软告警：本月花费越过预算的阈值百分比时生成告警，不拒绝请求
+type MonthlyBudget struct {
+	ID        int64   `json:"id"`
+	Scope     string  `json:"scope"`     // token / channel
+	TargetID  int64   `json:"target_id"` // 令牌ID或渠道ID
+	LimitUSD  float64 `json:"limit_usd"`
+	CreatedAt int64   `json:"created_at"` // Unix秒
+	UpdatedAt int64   `json:"updated_at"` // Unix秒
+}
@@ -0,0 +1,27 @@
+package model
+
+// CacheWarmup Anthropic 提示缓存预热计划（2026-10新增）
+// 按 IntervalSeconds 周期向渠道重放带 cache_control 的预热提示，使上游提示缓存保持命中；
+// Last* 记录最近一次运行结果，Runs/Failures/CacheReadTokens/CacheCreationTokens 为累计值。
+type CacheWarmup struct {
+	ID              int64  `json:"id"`
+	ChannelID       int64  `json:"channel_id"`
+	Model           string `json:"model"`
+	Prompt          string `json:"prompt"`           // 预热的系统提示（作为带 cache_control 的 system 块发送）
+	IntervalSeconds int    `json:"interval_seconds"` // 重放间隔（应小于上游缓存TTL，默认270秒）
+	Enabled         bool   `json:"enabled"`
+
+	LastRunAt               int64  `json:"last_run_at"` // Unix秒，0=从未运行
+	LastOK                  bool   `json:"last_ok"`
+	LastError               string `json:"last_error"`
+	LastLatencyMs           int64  `json:"last_latency_ms"`
+	LastCacheReadTokens     int64  `json:"last_cache_read_tokens"`
+	LastCacheCreationTokens int64  `json:"last_cache_creation_tokens"`
+	Runs                    int64  `json:"runs"`
+	Failures                int64  `json:"failures"`
+	CacheReadTokens         int64  `json:"cache_read_tokens"`
+	CacheCreationTokens     int64  `json:"cache_creation_tokens"`
+
+	CreatedAt int64 `json:"created_at"` // Unix秒
+	UpdatedAt int64 `json:"updated_at"` // Unix秒
+}
@@ -0,0 +1,16 @@
+package model
+
+// ChannelHealthCheck 渠道定时健康检查状态（2026-10新增）
+// 记录最近一次探测结果与连续成功/失败次数；AutoDisabled 表示渠道由健康检查自动禁用，
+// 探测恢复后只会自动启用这类渠道，手动禁用的渠道不受影响。
+type ChannelHealthCheck struct {
+	ChannelID      int64  `json:"channel_id"`
+	FailStreak     int    `json:"fail_streak"`
+	PassStreak     int    `json:"pass_streak"`
+	LastCheckedAt  int64  `json:"last_checked_at"` // Unix秒
+	LastOK         bool   `json:"last_ok"`
+	LastError      string `json:"last_error"`
+	LastLatencyMs  int64  `json:"last_latency_ms"`
+	AutoDisabled   bool   `json:"auto_disabled"`
+	AutoDisabledAt int64  `json:"auto_disabled_at"` // Unix秒，0=未被自动禁用
+}
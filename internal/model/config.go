@@ -42,6 +42,9 @@ type Config struct {
 	Priority    int    `json:"priority"`
 	Enabled     bool   `json:"enabled"`
 
+	// 负载均衡权重（2026-10新增）：同优先级渠道按权重分流（如 70/30），0 表示按有效Key数量
+	Weight int `json:"weight"`
+
 	// 模型配置（统一管理模型和重定向）
 	ModelEntries []ModelEntry `json:"models"`
 
@@ -52,6 +55,49 @@ type Config struct {
 	// 每日成本限额
 	DailyCostLimit float64 `json:"daily_cost_limit"` // 每日成本限额（美元），0表示无限制
 
+	// 价格倍率（2026-10新增）：渠道实际采购价 = 官方定价 × 倍率（如 0.8 表示八折）
+	CostMultiplier float64 `json:"cost_multiplier"` // <=0 视为 1（按官方定价计费）
+
+	// 客户端请求头profile（2026-10新增）：空表示透传客户端原始请求头
+	ClientProfile string `json:"client_profile"`
+
+	// 上游证书固定（2026-10新增）：逗号分隔的 sha256/<base64> SPKI 指纹，空表示不固定
+	CertPins string `json:"cert_pins"`
+
+	// 出站地址绑定（2026-10新增）：本机IP或网卡名，空表示使用系统默认路由
+	LocalAddr string `json:"local_addr"`
+
+	// 上游压缩（2026-10新增）：request_compression=gzip 时压缩较大的请求体；
+	// accept_encoding 非空时强制响应编码偏好（如 identity 关闭响应压缩），空表示由 Transport 协商 gzip
+	RequestCompression string `json:"request_compression"`
+	AcceptEncoding     string `json:"accept_encoding"`
+
+	// Anthropic协议兼容（2026-10新增）：仅 gemini 渠道有效，开启后可服务 Anthropic /v1/messages 请求（请求/响应自动转换）
+	AnthropicCompat bool `json:"anthropic_compat"`
+
+	// OpenAI协议兼容（2026-10新增）：anthropic/gemini/codex 渠道开启后可服务 OpenAI POST /v1/chat/completions 请求（请求/响应自动转换）
+	OpenAICompat bool `json:"openai_compat"`
+
+	// 出站幂等键（2026-10新增）：开启后为每次转发尝试生成 Idempotency-Key（客户端已携带时沿用），
+	// 同一尝试内的微重试复用该键，避免上游已处理但响应丢失时重复计费
+	IdempotencyKeys bool `json:"idempotency_keys"`
+
+	// 地域标签（2026-10新增）：逗号分隔的小写地域名（如 eu,us），与系统设置 geo_regions 中的地域对应；
+	// 客户端IP命中某地域时优先使用带该标签的渠道，空表示全局渠道（不参与地域优先）
+	Regions string `json:"regions"`
+
+	// Beta功能声明（2026-10新增）：逗号分隔的功能名（见 util.BetaFeatures），none 表示全部不支持；
+	// 空表示不管理（anthropic-beta 请求头与请求体原样透传，也不参与按功能路由）
+	BetaFeatures string `json:"beta_features"`
+
+	// 请求体改写规则（2026-10新增）：JSON 数组（见 app.bodyRewriteRule），转发前按顺序应用；
+	// 空表示不改写。通过 /admin/channels/:id/rewrites 单独维护，渠道编辑保存不会覆盖
+	RewriteRules string `json:"rewrite_rules"`
+
+	// 按模型并发上限（2026-10新增）：JSON 对象（见 app.modelConcurrencyConfig），限制该渠道上匹配模型的同时在途请求数；
+	// 空表示不限制。通过 /admin/channels/:id/concurrency 单独维护，渠道编辑保存不会覆盖
+	ModelConcurrency string `json:"model_concurrency"`
+
 	CreatedAt JSONTime `json:"created_at"` // 使用JSONTime确保序列化格式一致（RFC3339）
 	UpdatedAt JSONTime `json:"updated_at"` // 使用JSONTime确保序列化格式一致（RFC3339）
 
@@ -59,8 +105,44 @@ type Config struct {
 	KeyCount int `json:"key_count"` // API Key数量（查询时JOIN计算）
 
 	// 模型查找索引（懒加载，不序列化）
-	modelIndex map[string]*ModelEntry `json:"-"`
-	indexMu    sync.RWMutex           `json:"-"` // 保护索引的并发访问
+	modelIndex    map[string]*ModelEntry `json:"-"`
+	modelPatterns []*ModelEntry          `json:"-"` // 通配符模型条目（如 claude-*），按配置顺序匹配
+	indexMu       sync.RWMutex           `json:"-"` // 保护索引的并发访问
+}
+
+// IsModelPattern 判断模型名是否为通配符模式（包含 *）
+func IsModelPattern(name string) bool {
+	return strings.Contains(name, "*")
+}
+
+// MatchModelPattern 通配符匹配（仅支持 *，匹配任意长度字符，区分大小写）
+// 例：claude-* 匹配 claude-sonnet-4-5；gpt-4o* 匹配 gpt-4o 与 gpt-4o-mini
+func MatchModelPattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(name, part)
+		if idx < 0 {
+			return false
+		}
+		name = name[idx+len(part):]
+	}
+	return len(name) >= len(last) && strings.HasSuffix(name, last)
+}
+
+// HasModelPatterns 渠道是否配置了通配符模型
+func (c *Config) HasModelPatterns() bool {
+	c.buildIndexIfNeeded()
+	c.indexMu.RLock()
+	defer c.indexMu.RUnlock()
+	return len(c.modelPatterns) > 0
 }
 
 // GetModels 获取所有支持的模型名称列表
@@ -91,29 +173,58 @@ func (c *Config) buildIndexIfNeeded() {
 		return
 	}
 	c.modelIndex = make(map[string]*ModelEntry, len(c.ModelEntries))
+	c.modelPatterns = nil
 	for i := range c.ModelEntries {
-		c.modelIndex[c.ModelEntries[i].Model] = &c.ModelEntries[i]
+		entry := &c.ModelEntries[i]
+		c.modelIndex[entry.Model] = entry
+		if IsModelPattern(entry.Model) {
+			c.modelPatterns = append(c.modelPatterns, entry)
+		}
 	}
 }
 
+// lookupModelEntry 查找模型条目：精确匹配优先，其次按配置顺序匹配通配符（调用方需持有读锁）
+func (c *Config) lookupModelEntry(model string) (*ModelEntry, bool) {
+	if entry, exists := c.modelIndex[model]; exists {
+		return entry, true
+	}
+	for _, entry := range c.modelPatterns {
+		if MatchModelPattern(entry.Model, model) {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
 // GetRedirectModel 获取模型的重定向目标
 // 返回 (目标模型, 是否有重定向)
 func (c *Config) GetRedirectModel(model string) (string, bool) {
 	c.buildIndexIfNeeded()
 	c.indexMu.RLock()
 	defer c.indexMu.RUnlock()
-	if entry, exists := c.modelIndex[model]; exists && entry.RedirectModel != "" {
+	if entry, exists := c.lookupModelEntry(model); exists && entry.RedirectModel != "" {
 		return entry.RedirectModel, true
 	}
 	return "", false
 }
 
+// MatchedModelEntry 返回命中指定模型的渠道模型条目（精确匹配优先，其次通配符）
+func (c *Config) MatchedModelEntry(model string) (ModelEntry, bool) {
+	c.buildIndexIfNeeded()
+	c.indexMu.RLock()
+	defer c.indexMu.RUnlock()
+	if entry, exists := c.lookupModelEntry(model); exists {
+		return *entry, true
+	}
+	return ModelEntry{}, false
+}
+
 // SupportsModel 检查渠道是否支持指定模型
 func (c *Config) SupportsModel(model string) bool {
 	c.buildIndexIfNeeded()
 	c.indexMu.RLock()
 	defer c.indexMu.RUnlock()
-	_, exists := c.modelIndex[model]
+	_, exists := c.lookupModelEntry(model)
 	return exists
 }
 
@@ -125,6 +236,14 @@ func (c *Config) GetChannelType() string {
 	return c.ChannelType
 }
 
+// GetCostMultiplier 返回渠道价格倍率，未配置（<=0）时返回1
+func (c *Config) GetCostMultiplier() float64 {
+	if c.CostMultiplier <= 0 {
+		return 1
+	}
+	return c.CostMultiplier
+}
+
 // IsCoolingDown 检查渠道是否处于冷却状态
 func (c *Config) IsCoolingDown(now time.Time) bool {
 	return c.CooldownUntil > now.Unix()
@@ -150,10 +269,17 @@ type APIKey struct {
 
 	KeyStrategy string `json:"key_strategy"` // "sequential" | "round_robin"
 
+	// AccountGroup 上游账号分组（2026-10新增）：同一上游账号挂在多个渠道下时填相同标识，
+	// 任一渠道上该账号触发Key级冷却（429/额度耗尽等）会同步冷却组内所有Key；空表示不分组
+	AccountGroup string `json:"account_group,omitempty"`
+
 	// Key级冷却（从key_cooldowns表迁移）
 	CooldownUntil      int64 `json:"cooldown_until"`
 	CooldownDurationMs int64 `json:"cooldown_duration_ms"`
 
+	// 上游报告的剩余配额（来自响应头，JSON平铺输出）
+	KeyQuota
+
 	CreatedAt JSONTime `json:"created_at"`
 	UpdatedAt JSONTime `json:"updated_at"`
 }
@@ -163,6 +289,38 @@ func (k *APIKey) IsCoolingDown(now time.Time) bool {
 	return k.CooldownUntil > now.Unix()
 }
 
+// QuotaUnknown 剩余配额未知（上游未返回对应响应头）
+const QuotaUnknown int64 = -1
+
+// KeyQuota 上游按Key报告的剩余配额快照（2026-10新增）
+// 来源：anthropic-ratelimit-* / x-ratelimit-* 响应头；时间字段均为Unix秒
+type KeyQuota struct {
+	QuotaRequestsRemaining int64 `json:"quota_requests_remaining"` // 剩余请求数（-1=未知）
+	QuotaRequestsResetAt   int64 `json:"quota_requests_reset_at"`  // 请求配额重置时间
+	QuotaTokensRemaining   int64 `json:"quota_tokens_remaining"`   // 剩余Token数（-1=未知）
+	QuotaTokensResetAt     int64 `json:"quota_tokens_reset_at"`    // Token配额重置时间
+	QuotaUpdatedAt         int64 `json:"quota_updated_at"`         // 快照时间（0=从未上报），用于判断新鲜度
+}
+
+// QuotaExhaustedUntil 返回配额耗尽的解除时间；未耗尽或已过重置时间返回0
+// 仅当上游明确报告剩余为0且给出未来的重置时间时才视为耗尽
+func (q KeyQuota) QuotaExhaustedUntil(now time.Time) int64 {
+	nowUnix := now.Unix()
+	var until int64
+	if q.QuotaRequestsRemaining == 0 && q.QuotaRequestsResetAt > nowUnix {
+		until = q.QuotaRequestsResetAt
+	}
+	if q.QuotaTokensRemaining == 0 && q.QuotaTokensResetAt > nowUnix {
+		until = max(until, q.QuotaTokensResetAt)
+	}
+	return until
+}
+
+// IsQuotaExhausted 检查Key的上游配额是否已耗尽
+func (q KeyQuota) IsQuotaExhausted(now time.Time) bool {
+	return q.QuotaExhaustedUntil(now) > 0
+}
+
 // ChannelWithKeys 用于Redis完整同步
 // 设计目标：解决Redis恢复后渠道缺少API Keys的问题
 type ChannelWithKeys struct {
@@ -182,6 +340,10 @@ func (c *Config) FuzzyMatchModel(query string) (string, bool) {
 	var matches []string
 
 	for _, entry := range c.ModelEntries {
+		// 通配符条目不是具体模型名，不能作为模糊匹配结果
+		if IsModelPattern(entry.Model) {
+			continue
+		}
 		if strings.Contains(strings.ToLower(entry.Model), queryLower) {
 			matches = append(matches, entry.Model)
 		}
@@ -0,0 +1,52 @@
+package model
+
+// 费用重算任务状态
+const (
+	CostRecomputeRunning   = "running"
+	CostRecomputePaused    = "paused"
+	CostRecomputeCompleted = "completed"
+	CostRecomputeFailed    = "failed"
+)
+
+// CostRecomputeJob 历史费用重算任务（2026-10新增）
+// 按日志ID升序分批处理，每批与游标在同一事务内提交，中断后可从 CursorID 继续
+type CostRecomputeJob struct {
+	ID            int64   `json:"id"`
+	Since         int64   `json:"since"`  // 日志时间下界（Unix毫秒，含）
+	Until         int64   `json:"until"`  // 日志时间上界（Unix毫秒，不含）
+	Status        string  `json:"status"` // running / paused / completed / failed
+	CursorID      int64   `json:"cursor_id"`
+	TotalRows     int64   `json:"total_rows"`     // 创建任务时范围内的日志数
+	ProcessedRows int64   `json:"processed_rows"` // 已检查的日志数
+	UpdatedRows   int64   `json:"updated_rows"`   // 费用发生变化的日志数
+	CostDeltaUSD  float64 `json:"cost_delta_usd"` // 累计费用变化（新-旧）
+	Error         string  `json:"error,omitempty"`
+	CreatedAt     int64   `json:"created_at"` // Unix秒
+	UpdatedAt     int64   `json:"updated_at"` // Unix秒
+}
+
+// LogCostRow 费用重算所需的日志字段
+type LogCostRow struct {
+	ID                  int64
+	Time                int64 // Unix毫秒（按价格表生效时间计费）
+	Model               string
+	ActualModel         string
+	ChannelID           int64
+	AuthTokenID         int64
+	StatusCode          int
+	InputTokens         int
+	OutputTokens        int
+	CacheReadTokens     int
+	CacheCreationTokens int // 5m+1h总数（旧日志可能只有总数无细分）
+	Cache5mInputTokens  int
+	Cache1hInputTokens  int
+	Cost                float64
+}
+
+// LogCostUpdate 单条日志的费用修正
+type LogCostUpdate struct {
+	LogID       int64
+	AuthTokenID int64 // 需同步累计费用的令牌；非2xx日志为0（实时计费不向令牌收取失败/取消请求的费用）
+	OldCost     float64
+	NewCost     float64
+}
@@ -0,0 +1,28 @@
+package model
+
+// GeminiKeyProvisioner Gemini API Key 自动供给配置（2026-10新增）
+// 使用 Google Cloud 服务账号在指定项目中创建/轮换 API Key，并自动写入绑定渠道的Key列表。
+// 渠道中手工添加的Key不受影响，只有 ManagedKeys 记录的Key由供给任务管理。
+type GeminiKeyProvisioner struct {
+	ID                  int64              `json:"id"`
+	ChannelID           int64              `json:"channel_id"`
+	ProjectID           string             `json:"project_id"`
+	Credentials         string             `json:"-"`                     // 服务账号JSON（敏感，不返回前端）
+	ClientEmail         string             `json:"client_email"`          // 服务账号邮箱（从凭据解析，仅展示）
+	KeyCount            int                `json:"key_count"`             // 期望维持的托管Key数量
+	RotateIntervalHours int                `json:"rotate_interval_hours"` // 轮换周期（小时），0=不轮换
+	Enabled             bool               `json:"enabled"`
+	ManagedKeys         []GeminiManagedKey `json:"managed_keys"`
+	LastRunAt           int64              `json:"last_run_at"` // Unix秒，0=从未运行
+	LastError           string             `json:"last_error"`
+	CreatedAt           int64              `json:"created_at"`
+	UpdatedAt           int64              `json:"updated_at"`
+}
+
+// GeminiManagedKey 供给任务创建的Key
+// 不保存Key明文（明文只在渠道Key中），通过哈希与渠道Key对应
+type GeminiManagedKey struct {
+	Name      string `json:"name"`       // Google资源名 projects/{p}/locations/global/keys/{id}
+	KeyHash   string `json:"key_hash"`   // Key明文的SHA256
+	CreatedAt int64  `json:"created_at"` // Unix秒
+}
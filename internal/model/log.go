@@ -43,12 +43,15 @@ type LogEntry struct {
 	ChannelName   string   `json:"channel_name,omitempty"`
 	StatusCode    int      `json:"status_code"`
 	Message       string   `json:"message"`
-	Duration      float64  `json:"duration"`        // 总耗时（秒）
-	IsStreaming   bool     `json:"is_streaming"`    // 是否为流式请求
-	FirstByteTime float64  `json:"first_byte_time"` // 上游首字节响应时间（秒）
-	APIKeyUsed    string   `json:"api_key_used"`    // 使用的API Key（写入时强制脱敏为 abcd...klmn 格式，数据库不存明文）
-	AuthTokenID   int64    `json:"auth_token_id"`   // 客户端使用的API令牌ID（新增2025-12，0表示未使用token）
-	ClientIP      string   `json:"client_ip"`       // 客户端IP地址（新增2025-12）
+	Duration      float64  `json:"duration"`         // 总耗时（秒）
+	IsStreaming   bool     `json:"is_streaming"`     // 是否为流式请求
+	FirstByteTime float64  `json:"first_byte_time"`  // 上游首字节响应时间（秒）
+	APIKeyUsed    string   `json:"api_key_used"`     // 使用的API Key（写入时强制脱敏为 abcd...klmn 格式，数据库不存明文）
+	KeyIndex      int      `json:"key_index"`        // 使用的Key下标（2026-10新增；APIKeyUsed 为空时无意义，存储为-1）
+	AuthTokenID   int64    `json:"auth_token_id"`    // 客户端使用的API令牌ID（新增2025-12，0表示未使用token）
+	ClientIP      string   `json:"client_ip"`        // 客户端IP地址（新增2025-12）
+	ErrorClass    string   `json:"error_class"`      // 错误归类（2026-10新增，见 util.ErrorClasses；成功或非上游错误为空）
+	Labels        string   `json:"labels,omitempty"` // 响应内容标签（2026-10新增，逗号分隔，见 response_label_rules）
 
 	// Token统计（2025-11新增，支持Claude API usage字段）
 	InputTokens              int     `json:"input_tokens"`
@@ -58,6 +61,8 @@ type LogEntry struct {
 	Cache5mInputTokens       int     `json:"cache_5m_input_tokens"`       // 5分钟缓存写入Token数（新增2025-12）
 	Cache1hInputTokens       int     `json:"cache_1h_input_tokens"`       // 1小时缓存写入Token数（新增2025-12）
 	Cost                     float64 `json:"cost"`                        // 请求成本（美元）
+
+	JournalID uint64 `json:"-"` // 请求用量预写日志记录ID（仅内存，落库后确认用，2026-10新增）
 }
 
 // LogFilter 日志查询过滤条件
@@ -70,4 +75,7 @@ type LogFilter struct {
 	StatusCode      *int
 	ChannelType     string // 渠道类型过滤（anthropic/openai/gemini/codex）
 	AuthTokenID     *int64 // API令牌ID过滤
+	Owner           string // 令牌归属方过滤（2026-10新增，匹配 auth_tokens.owner）
+	ErrorClass      string // 错误归类过滤（2026-10新增）
+	Label           string // 响应内容标签过滤（2026-10新增，匹配 labels 中的单个标签）
 }
@@ -0,0 +1,13 @@
+package model
+
+// ModelAlias 全局模型别名（2026-10新增）
+// 请求模型命中 Alias 时，在选路前改写为 Target 再按 Target 选择渠道（渠道级模型重定向随后照常生效）。
+type ModelAlias struct {
+	ID          int64  `json:"id"`
+	Alias       string `json:"alias"`  // 客户端请求的模型名（精确匹配）
+	Target      string `json:"target"` // 实际用于选路的模型名
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	CreatedAt   int64  `json:"created_at"` // Unix秒
+	UpdatedAt   int64  `json:"updated_at"` // Unix秒
+}
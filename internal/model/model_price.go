@@ -0,0 +1,18 @@
+package model
+
+// ModelPrice 模型价格表条目（2026-10新增），单位：美元/百万tokens
+// 同一模型可有多条不同生效时间的记录：计费时取 EffectiveFrom ≤ 请求时间的最新一条，
+// 实时请求按当前时间，历史费用重算按日志时间。未配置的模型沿用内置定价。
+type ModelPrice struct {
+	ID                int64   `json:"id"`
+	Model             string  `json:"model"` // 精确模型名或以 * 结尾的前缀（如 claude-sonnet-4-5*）
+	InputPrice        float64 `json:"input_price"`
+	OutputPrice       float64 `json:"output_price"`
+	CacheReadPrice    float64 `json:"cache_read_price"`     // 0 表示按缓存倍率由输入价推算
+	CacheWritePrice   float64 `json:"cache_write_price"`    // 5分钟缓存写入；0 表示按缓存倍率推算
+	CacheWrite1hPrice float64 `json:"cache_write_1h_price"` // 1小时缓存写入；0 表示按缓存倍率推算
+	EffectiveFrom     int64   `json:"effective_from"`       // 生效时间（Unix毫秒，与日志时间同口径）
+	Description       string  `json:"description"`
+	CreatedAt         int64   `json:"created_at"` // Unix秒
+	UpdatedAt         int64   `json:"updated_at"` // Unix秒
+}
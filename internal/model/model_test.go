@@ -426,3 +426,56 @@ func TestExtractVersionNumbers(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchModelPattern(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"claude-*", "claude-sonnet-4-5", true},
+		{"claude-*", "claude-", true},
+		{"claude-*", "gpt-4o", false},
+		{"gpt-4o*", "gpt-4o", true},
+		{"gpt-4o*", "gpt-4o-mini", true},
+		{"*-mini", "gpt-4o-mini", true},
+		{"claude-*-4-5", "claude-sonnet-4-5", true},
+		{"claude-*-4-5", "claude-sonnet-4-1", false},
+		{"a*a", "a", false},
+		{"*", "anything", true},
+		{"exact", "exact", true},
+	}
+	for _, tt := range tests {
+		if got := MatchModelPattern(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchModelPattern(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_WildcardModels(t *testing.T) {
+	cfg := &Config{ModelEntries: []ModelEntry{
+		{Model: "claude-haiku-4-5", RedirectModel: "claude-3-5-haiku"},
+		{Model: "claude-*"},
+		{Model: "gpt-4o*", RedirectModel: "gpt-4o-2024-11-20"},
+	}}
+
+	if !cfg.SupportsModel("claude-opus-4-1") || cfg.SupportsModel("gemini-2.5-pro") {
+		t.Fatal("wildcard support mismatch")
+	}
+	// 精确条目优先于通配符
+	if got, ok := cfg.GetRedirectModel("claude-haiku-4-5"); !ok || got != "claude-3-5-haiku" {
+		t.Fatalf("exact redirect = %q, %v", got, ok)
+	}
+	if _, ok := cfg.GetRedirectModel("claude-opus-4-1"); ok {
+		t.Fatal("pattern without redirect should not redirect")
+	}
+	if got, ok := cfg.GetRedirectModel("gpt-4o-mini"); !ok || got != "gpt-4o-2024-11-20" {
+		t.Fatalf("pattern redirect = %q, %v", got, ok)
+	}
+	if entry, ok := cfg.MatchedModelEntry("claude-opus-4-1"); !ok || entry.Model != "claude-*" {
+		t.Fatalf("MatchedModelEntry = %+v, %v", entry, ok)
+	}
+	// 模糊匹配不返回通配符条目
+	if got, ok := cfg.FuzzyMatchModel("gpt"); ok {
+		t.Fatalf("fuzzy match returned pattern %q", got)
+	}
+}
@@ -0,0 +1,12 @@
+package model
+
+// ResponseCacheEntry 非流式响应缓存条目（2026-10新增）
+// Key 为请求方法+路径+查询串+请求体的 SHA-256（十六进制），Body 为返回给客户端的原始响应体
+type ResponseCacheEntry struct {
+	Key         string `json:"key"`
+	Model       string `json:"model"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"-"`
+	CreatedAt   int64  `json:"created_at"` // Unix秒
+	ExpiresAt   int64  `json:"expires_at"` // Unix秒
+}
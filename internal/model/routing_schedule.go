@@ -0,0 +1,17 @@
+package model
+
+// RoutingSchedule 分时路由规则（2026-10新增）
+// 当前时间（按 Timezone）落在 Cron 窗口内时，对渠道的优先级与负载均衡权重进行调整，
+// 用于利用上游的夜间折扣/闲时容量。同一渠道多条规则同时生效时：优先级调整累加、权重倍率相乘。
+type RoutingSchedule struct {
+	ID               int64   `json:"id"`
+	ChannelID        int64   `json:"channel_id"`
+	Description      string  `json:"description"`
+	Cron             string  `json:"cron"`              // 5段cron表达式，描述生效窗口（如 "* 0-7 * * *"）
+	Timezone         string  `json:"timezone"`          // IANA时区（如 Asia/Shanghai），空表示服务器时区
+	PriorityDelta    int     `json:"priority_delta"`    // 窗口内优先级调整（可为负）
+	WeightMultiplier float64 `json:"weight_multiplier"` // 窗口内同优先级负载均衡权重倍率（1=不变，0=窗口内不作为首选）
+	Enabled          bool    `json:"enabled"`
+	CreatedAt        int64   `json:"created_at"` // Unix秒
+	UpdatedAt        int64   `json:"updated_at"` // Unix秒
+}
@@ -70,6 +70,7 @@ type StatsEntry struct {
 	TotalCacheReadInputTokens     *int64   `json:"total_cache_read_input_tokens,omitempty"`     // 总缓存读取Token
 	TotalCacheCreationInputTokens *int64   `json:"total_cache_creation_input_tokens,omitempty"` // 总缓存创建Token
 	TotalCost                     *float64 `json:"total_cost,omitempty"`                        // 总成本（美元）
+	TotalCostDisplay              *float64 `json:"total_cost_display,omitempty"`                // 总成本（展示币种，配置 display_currency 时，2026-10新增）
 
 	// 健康状态时间线（2025-12新增）
 	HealthTimeline []HealthPoint `json:"health_timeline,omitempty"` // 固定24个时间点的健康状态
@@ -84,3 +85,92 @@ type RPMStats struct {
 	RecentRPM float64 `json:"recent_rpm"` // 最近一分钟RPM（仅本日有效）
 	RecentQPS float64 `json:"recent_qps"` // 最近一分钟QPS（仅本日有效）
 }
+
+// ErrorClassStats 按渠道+错误归类聚合的错误次数（2026-10新增）
+type ErrorClassStats struct {
+	ChannelID   int64  `json:"channel_id"`
+	ChannelName string `json:"channel_name,omitempty"`
+	ErrorClass  string `json:"error_class"` // 见 util.ErrorClasses
+	Count       int64  `json:"count"`
+}
+
+// ResponseLabelStats 按渠道汇总的成功请求响应标签命中情况（2026-10新增）
+type ResponseLabelStats struct {
+	ChannelID   int64              `json:"channel_id"`
+	ChannelName string             `json:"channel_name,omitempty"`
+	Total       int64              `json:"total"`  // 区间内成功请求数（占比分母）
+	Labels      map[string]int64   `json:"labels"` // 标签 -> 命中次数
+	Rates       map[string]float64 `json:"rates"`  // 标签 -> 命中占比（0-1）
+}
+
+// TokenAnomaly 输出Token异常请求（2026-10新增）
+// 输出Token数远超同一令牌+模型的历史均值，常见于失控的Agent循环
+type TokenAnomaly struct {
+	LogID          int64    `json:"log_id"`
+	Time           JSONTime `json:"time"`
+	AuthTokenID    int64    `json:"auth_token_id"`
+	Model          string   `json:"model"`
+	ChannelID      int64    `json:"channel_id"`
+	OutputTokens   int64    `json:"output_tokens"`
+	BaselineOutput float64  `json:"baseline_output"` // 基线区间内的平均输出Token
+	Ratio          float64  `json:"ratio"`           // output_tokens / baseline_output
+	Cost           float64  `json:"cost"`            // 该请求成本（美元）
+}
+
+// TokenAnomalyQuery 输出Token异常查询参数
+type TokenAnomalyQuery struct {
+	Since           time.Time // 报告区间起点
+	Until           time.Time // 报告区间终点（不含）
+	BaselineSince   time.Time // 基线区间起点（基线区间为 [BaselineSince, Since)）
+	Multiplier      float64   // 判定倍数：output_tokens > 基线均值 × Multiplier
+	MinOutputTokens int64     // 绝对下限：低于该值的请求不判定为异常
+	MinSamples      int       // 基线最少样本数（样本不足不判定）
+	Limit           int       // 最多返回条数
+}
+
+// KeyDailyStats 单个Key按天聚合的请求统计（从logs表聚合，2026-10新增）
+// KeyIndex 为写入日志时的下标（历史日志为-1）；APIKeyUsed 为脱敏Key，用于在下标因删除Key而前移后仍能归属到正确的Key
+type KeyDailyStats struct {
+	KeyIndex            int     `json:"key_index"`
+	APIKeyUsed          string  `json:"api_key_used"`
+	Day                 int64   `json:"day"` // 自Unix纪元起的本地日序号（已按时区偏移）
+	SuccessCount        int64   `json:"success_count"`
+	FailureCount        int64   `json:"failure_count"`
+	DurationSum         float64 `json:"duration_sum"` // 成功请求总耗时（秒），用于跨行合并后计算平均延迟
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	TotalCost           float64 `json:"total_cost"`
+}
+
+// TokenDailyCost 单个API令牌按天聚合的请求数与费用（从logs表聚合，用于用量预测，2026-10新增）
+type TokenDailyCost struct {
+	Day      int64   `json:"day"` // 自Unix纪元起的本地日序号（已按时区偏移）
+	Requests int64   `json:"requests"`
+	Cost     float64 `json:"cost"`
+}
+
+// ModelOutcome 渠道内 请求模型×实际模型×状态码 聚合（从logs表聚合，用于重定向建议，2026-10新增）
+// 仅包含成功（2xx）与 400/404 的记录；SampleMessage 为该组中任意一条错误信息，用于识别"模型不存在"
+type ModelOutcome struct {
+	Model         string `json:"model"`
+	ActualModel   string `json:"actual_model"` // 空表示未重定向
+	StatusCode    int    `json:"status_code"`
+	Count         int64  `json:"count"`
+	LastAt        int64  `json:"last_at"` // Unix毫秒
+	SampleMessage string `json:"sample_message"`
+}
+
+// HourlyLatency 按UTC整点小时聚合的请求延迟（从logs表聚合，用于延迟热力图，2026-10新增）
+// 保留求和与计数而非平均值，便于按 星期×小时 跨周合并
+type HourlyLatency struct {
+	Hour          int64   `json:"hour"` // 自Unix纪元起的UTC小时序号
+	Requests      int64   `json:"requests"`
+	Errors        int64   `json:"errors"`
+	DurationSum   float64 `json:"duration_sum"`   // 成功请求总耗时（秒）
+	DurationCount int64   `json:"duration_count"` // 计入 DurationSum 的请求数
+	MaxDuration   float64 `json:"max_duration"`   // 成功请求最大耗时（秒）
+	TTFBSum       float64 `json:"ttfb_sum"`       // 成功流式请求首字节时间总和（秒）
+	TTFBCount     int64   `json:"ttfb_count"`
+}
@@ -0,0 +1,14 @@
+package model
+
+// TestPrompt 渠道测试提示词（2026-10新增，取代系统设置 channel_test_content）
+// 团队共享一组命名的验证提示词，渠道测试与全部Key测试可按ID或名称选用。
+type TestPrompt struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"` // 唯一
+	Content     string `json:"content"`
+	Description string `json:"description"`
+	MaxTokens   int    `json:"max_tokens"` // 0=使用测试请求/默认值
+	IsDefault   bool   `json:"is_default"` // 测试请求未指定提示词与内容时使用（最多一条）
+	CreatedAt   int64  `json:"created_at"` // Unix秒
+	UpdatedAt   int64  `json:"updated_at"` // Unix秒
+}
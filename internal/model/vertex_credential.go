@@ -0,0 +1,14 @@
+package model
+
+// VertexCredential Vertex AI 渠道凭据（2026-10新增）
+// 绑定到 Gemini 类型渠道后，请求改用服务账号换取的访问令牌认证，
+// 并将 Gemini API 路径（/v1beta/models/{model}:{method}）映射为 Vertex publishers/google/models 端点。
+type VertexCredential struct {
+	ChannelID   int64  `json:"channel_id"`
+	ProjectID   string `json:"project_id"`   // GCP 项目ID（默认取服务账号 project_id）
+	Location    string `json:"location"`     // 区域（如 us-central1；global 使用全局端点）
+	Credentials string `json:"-"`            // 服务账号JSON（敏感，不返回前端）
+	ClientEmail string `json:"client_email"` // 服务账号邮箱（从凭据解析，仅展示）
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+// Webhook 出站Webhook端点（2026-10新增）
+// 渠道/Key冷却、渠道恢复、令牌预算超限等事件发生时，向 URL POST JSON 事件体；
+// 配置了 Secret 时附带 HMAC-SHA256 签名，接收方据此校验来源。
+type Webhook struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Secret    string `json:"-"`          // HMAC签名密钥（敏感，不返回前端）
+	HasSecret bool   `json:"has_secret"` // 是否已配置签名密钥（仅展示）
+	Events    string `json:"events"`     // 订阅的事件类型（逗号分隔），空表示全部
+	Enabled   bool   `json:"enabled"`
+	CreatedAt int64  `json:"created_at"` // Unix秒
+	UpdatedAt int64  `json:"updated_at"` // Unix秒
+}
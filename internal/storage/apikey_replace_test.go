@@ -0,0 +1,45 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+)
+
+// TestReplaceAPIKeys_RollbackKeepsOldKeys 写入新Key失败时整体回滚，渠道保留原有Key
+func TestReplaceAPIKeys_RollbackKeepsOldKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "replace_keys.db"), nil)
+	if err != nil {
+		t.Fatalf("failed to create sqlite store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	cfg, err := store.CreateConfig(ctx, &model.Config{Name: "replace", URL: "https://example.com", Enabled: true,
+		ModelEntries: []model.ModelEntry{{Model: "m"}}})
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	if err := store.CreateAPIKeysBatch(ctx, []*model.APIKey{{ChannelID: cfg.ID, KeyIndex: 0, APIKey: "old"}}); err != nil {
+		t.Fatalf("failed to create keys: %v", err)
+	}
+
+	// 重复的 key_index 违反唯一约束
+	dup := []*model.APIKey{{ChannelID: cfg.ID, KeyIndex: 0, APIKey: "new-a"}, {ChannelID: cfg.ID, KeyIndex: 0, APIKey: "new-b"}}
+	if err := store.ReplaceAPIKeys(ctx, cfg.ID, dup); err == nil {
+		t.Fatal("expected replace to fail on duplicate key_index")
+	}
+	if keys, _ := store.GetAPIKeys(ctx, cfg.ID); len(keys) != 1 || keys[0].APIKey != "old" {
+		t.Fatalf("failed replace must keep old keys, got %+v", keys)
+	}
+
+	if err := store.ReplaceAPIKeys(ctx, cfg.ID, []*model.APIKey{{ChannelID: cfg.ID, KeyIndex: 0, APIKey: "new"}}); err != nil {
+		t.Fatalf("replace failed: %v", err)
+	}
+	if keys, _ := store.GetAPIKeys(ctx, cfg.ID); len(keys) != 1 || keys[0].APIKey != "new" {
+		t.Fatalf("unexpected keys after replace: %+v", keys)
+	}
+}
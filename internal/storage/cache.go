@@ -3,10 +3,13 @@
 package storage
 
 import (
+	"cmp"
 	"context"
 	"log"
 	"maps"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	modelpkg "ccLoad/internal/model"
@@ -19,13 +22,20 @@ type ChannelCache struct {
 	store           Store
 	channelsByModel map[string][]*modelpkg.Config // model → channels
 	channelsByType  map[string][]*modelpkg.Config // type → channels
+	patternChannels []*modelpkg.Config            // 含通配符模型（如 claude-*）的渠道
 	allChannels     []*modelpkg.Config            // 所有渠道
+	modelAliases    map[string]string             // 全局模型别名 alias → target（仅启用的别名，2026-10新增）
 	lastUpdate      time.Time
 	mutex           sync.RWMutex
 	ttl             time.Duration
 
+	// 刷新健康状态（健康检查用）：缓存按需刷新，空闲时 lastUpdate 变旧属正常
+	lastAccess atomic.Int64 // 最近一次按需刷新检查的时间（Unix纳秒）
+	refreshErr error        // 最近一次刷新失败的错误，成功后清空（mutex 保护）
+
 	// 扩展缓存支持更多关键查询
 	apiKeysByChannelID map[int64][]*modelpkg.APIKey // channelID → API keys
+	apiKeysLoadedAt    map[int64]time.Time          // channelID → API keys 加载时间（管理端缓存快照用）
 	cooldownCache      struct {
 		channels   map[int64]time.Time         // channelID → cooldown until
 		keys       map[int64]map[int]time.Time // channelID→keyIndex→cooldown until
@@ -45,6 +55,7 @@ func NewChannelCache(store Store, ttl time.Duration) *ChannelCache {
 
 		// 初始化扩展缓存
 		apiKeysByChannelID: make(map[int64][]*modelpkg.APIKey),
+		apiKeysLoadedAt:    make(map[int64]time.Time),
 		cooldownCache: struct {
 			channels   map[int64]time.Time
 			keys       map[int64]map[int]time.Time
@@ -77,6 +88,20 @@ func deepCopyConfig(src *modelpkg.Config) *modelpkg.Config {
 		CooldownUntil:      src.CooldownUntil,
 		CooldownDurationMs: src.CooldownDurationMs,
 		DailyCostLimit:     src.DailyCostLimit,
+		CostMultiplier:     src.CostMultiplier,
+		ClientProfile:      src.ClientProfile,
+		CertPins:           src.CertPins,
+		LocalAddr:          src.LocalAddr,
+		RequestCompression: src.RequestCompression,
+		AcceptEncoding:     src.AcceptEncoding,
+		AnthropicCompat:    src.AnthropicCompat,
+		OpenAICompat:       src.OpenAICompat,
+		IdempotencyKeys:    src.IdempotencyKeys,
+		Weight:             src.Weight,
+		Regions:            src.Regions,
+		BetaFeatures:       src.BetaFeatures,
+		RewriteRules:       src.RewriteRules,
+		ModelConcurrency:   src.ModelConcurrency,
 		CreatedAt:          src.CreatedAt,
 		UpdatedAt:          src.UpdatedAt,
 		KeyCount:           src.KeyCount,
@@ -121,13 +146,23 @@ func (c *ChannelCache) GetEnabledChannelsByModel(ctx context.Context, model stri
 		return deepCopyConfigs(c.allChannels), nil
 	}
 
-	// 返回指定模型的渠道深拷贝
-	channels, exists := c.channelsByModel[model]
-	if !exists {
-		return []*modelpkg.Config{}, nil
+	// 无通配符渠道时直接走精确索引
+	if len(c.patternChannels) == 0 {
+		channels, exists := c.channelsByModel[model]
+		if !exists {
+			return []*modelpkg.Config{}, nil
+		}
+		return deepCopyConfigs(channels), nil
 	}
 
-	return deepCopyConfigs(channels), nil
+	// 存在通配符渠道：按 allChannels 的优先级顺序合并精确命中与通配符命中
+	result := make([]*modelpkg.Config, 0)
+	for _, channel := range c.allChannels {
+		if channel.SupportsModel(model) {
+			result = append(result, deepCopyConfig(channel))
+		}
+	}
+	return result, nil
 }
 
 // GetEnabledChannelsByType 缓存优先的类型查询
@@ -151,6 +186,22 @@ func (c *ChannelCache) GetEnabledChannelsByType(ctx context.Context, channelType
 	return deepCopyConfigs(channels), nil
 }
 
+// ChannelDailyCostLimit 从缓存的启用渠道中读取每日成本限额（2026-10新增，供请求路径上的预算告警使用，不查询数据库）
+// 渠道未启用或缓存刷新失败时返回 (0, false)
+func (c *ChannelCache) ChannelDailyCostLimit(ctx context.Context, channelID int64) (float64, bool) {
+	if err := c.refreshIfNeeded(ctx); err != nil {
+		return 0, false
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, channel := range c.allChannels {
+		if channel.ID == channelID {
+			return channel.DailyCostLimit, true
+		}
+	}
+	return 0, false
+}
+
 // GetConfig 获取指定ID的渠道配置
 // 直接查询数据库，保证数据永远是最新的
 func (c *ChannelCache) GetConfig(ctx context.Context, channelID int64) (*modelpkg.Config, error) {
@@ -159,6 +210,7 @@ func (c *ChannelCache) GetConfig(ctx context.Context, channelID int64) (*modelpk
 
 // refreshIfNeeded 智能缓存刷新
 func (c *ChannelCache) refreshIfNeeded(ctx context.Context) error {
+	c.lastAccess.Store(time.Now().UnixNano())
 	c.mutex.RLock()
 	needsRefresh := time.Since(c.lastUpdate) > c.ttl
 	c.mutex.RUnlock()
@@ -176,7 +228,9 @@ func (c *ChannelCache) refreshIfNeeded(ctx context.Context) error {
 		return nil
 	}
 
-	return c.refreshCache(ctx)
+	err := c.refreshCache(ctx)
+	c.refreshErr = err
+	return err
 }
 
 // refreshCache 刷新缓存数据
@@ -192,6 +246,7 @@ func (c *ChannelCache) refreshCache(ctx context.Context) error {
 	// 构建按类型分组的索引（内部共享指针，对外深拷贝隔离）
 	byModel := make(map[string][]*modelpkg.Config)
 	byType := make(map[string][]*modelpkg.Config)
+	var patternChannels []*modelpkg.Config
 
 	for _, channel := range allChannels {
 		channelType := channel.GetChannelType()
@@ -201,12 +256,23 @@ func (c *ChannelCache) refreshCache(ctx context.Context) error {
 		for _, model := range channel.GetModels() {
 			byModel[model] = append(byModel[model], channel) // 内部共享
 		}
+		if channel.HasModelPatterns() {
+			patternChannels = append(patternChannels, channel)
+		}
+	}
+
+	// 模型别名随渠道一起刷新；加载失败时沿用旧数据，不影响渠道缓存
+	if aliases, err := c.loadModelAliases(ctx); err == nil {
+		c.modelAliases = aliases
+	} else {
+		log.Printf("[WARN]  加载模型别名失败: %v", err)
 	}
 
 	// 原子性更新缓存（整体替换，不修改单个对象）
 	c.allChannels = allChannels
 	c.channelsByModel = byModel
 	c.channelsByType = byType
+	c.patternChannels = patternChannels
 	c.lastUpdate = time.Now()
 
 	refreshDuration := time.Since(start)
@@ -218,6 +284,51 @@ func (c *ChannelCache) refreshCache(ctx context.Context) error {
 	return nil
 }
 
+// loadModelAliases 从数据库加载启用的模型别名
+func (c *ChannelCache) loadModelAliases(ctx context.Context) (map[string]string, error) {
+	list, err := c.store.ListModelAliases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string, len(list))
+	for _, a := range list {
+		if a.Enabled {
+			aliases[a.Alias] = a.Target
+		}
+	}
+	return aliases, nil
+}
+
+// ResolveModelAlias 缓存优先的模型别名查询（2026-10新增），未命中返回 ("", false)
+func (c *ChannelCache) ResolveModelAlias(ctx context.Context, model string) (string, bool) {
+	if err := c.refreshIfNeeded(ctx); err != nil {
+		// 缓存失败时降级到数据库查询
+		aliases, err := c.loadModelAliases(ctx)
+		if err != nil {
+			return "", false
+		}
+		target, ok := aliases[model]
+		return target, ok
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	target, ok := c.modelAliases[model]
+	return target, ok
+}
+
+// RefreshStatus 返回最近一次成功刷新时间、最近一次访问时间（零值表示尚未加载/访问）
+// 与最近一次刷新的错误（nil 表示最近一次刷新成功或尚未刷新）
+func (c *ChannelCache) RefreshStatus() (lastRefresh, lastAccess time.Time, err error) {
+	c.mutex.RLock()
+	lastRefresh, err = c.lastUpdate, c.refreshErr
+	c.mutex.RUnlock()
+	if ns := c.lastAccess.Load(); ns > 0 {
+		lastAccess = time.Unix(0, ns)
+	}
+	return lastRefresh, lastAccess, err
+}
+
 // InvalidateCache 手动失效缓存
 func (c *ChannelCache) InvalidateCache() {
 	c.mutex.Lock()
@@ -251,6 +362,7 @@ func (c *ChannelCache) GetAPIKeys(ctx context.Context, channelID int64) ([]*mode
 	// 存储到缓存（只存 slice 本身；对外总是返回深拷贝，避免污染缓存）
 	c.mutex.Lock()
 	c.apiKeysByChannelID[channelID] = keys
+	c.apiKeysLoadedAt[channelID] = time.Now()
 	c.mutex.Unlock()
 
 	result := make([]*modelpkg.APIKey, len(keys))
@@ -334,6 +446,7 @@ func (c *ChannelCache) InvalidateAPIKeysCache(channelID int64) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	delete(c.apiKeysByChannelID, channelID)
+	delete(c.apiKeysLoadedAt, channelID)
 }
 
 // InvalidateAllAPIKeysCache 清空所有API Key缓存（批量操作后使用）
@@ -341,6 +454,7 @@ func (c *ChannelCache) InvalidateAllAPIKeysCache() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.apiKeysByChannelID = make(map[int64][]*modelpkg.APIKey)
+	c.apiKeysLoadedAt = make(map[int64]time.Time)
 }
 
 // InvalidateCooldownCache 手动失效冷却缓存
@@ -349,3 +463,124 @@ func (c *ChannelCache) InvalidateCooldownCache() {
 	defer c.mutex.Unlock()
 	c.cooldownCache.lastUpdate = time.Time{}
 }
+
+// CacheSnapshot 缓存内容快照（2026-10新增，管理端排查缓存导致的路由问题；不含Key明文）
+type CacheSnapshot struct {
+	Channels  CacheChannelsSnapshot  `json:"channels"`
+	APIKeys   []CacheAPIKeysEntry    `json:"api_keys"`
+	Cooldowns CacheCooldownsSnapshot `json:"cooldowns"`
+}
+
+// CacheChannelsSnapshot 渠道列表缓存（整体按TTL刷新）
+type CacheChannelsSnapshot struct {
+	LastRefresh *time.Time          `json:"last_refresh"` // nil 表示尚未加载或已失效
+	AgeSeconds  float64             `json:"age_seconds"`
+	TTLSeconds  float64             `json:"ttl_seconds"`
+	Stale       bool                `json:"stale"` // 下次查询将重新加载
+	Entries     []CacheChannelEntry `json:"entries"`
+}
+
+// CacheChannelEntry 缓存中的单个渠道
+type CacheChannelEntry struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	ChannelType string `json:"channel_type"`
+	Priority    int    `json:"priority"`
+	ModelCount  int    `json:"model_count"`
+	KeyCount    int    `json:"key_count"`
+}
+
+// CacheAPIKeysEntry 单个渠道的 API Keys 缓存（按渠道懒加载，变更时失效）
+type CacheAPIKeysEntry struct {
+	ChannelID  int64     `json:"channel_id"`
+	KeyCount   int       `json:"key_count"`
+	LoadedAt   time.Time `json:"loaded_at"`
+	AgeSeconds float64   `json:"age_seconds"`
+}
+
+// CacheCooldownsSnapshot 冷却状态缓存（渠道与Key共用一个刷新时间）
+type CacheCooldownsSnapshot struct {
+	LastUpdate *time.Time           `json:"last_update"` // nil 表示尚未加载或已失效
+	AgeSeconds float64              `json:"age_seconds"`
+	TTLSeconds float64              `json:"ttl_seconds"`
+	Stale      bool                 `json:"stale"`
+	Channels   []CacheCooldownEntry `json:"channels"`
+	Keys       []CacheCooldownEntry `json:"keys"`
+}
+
+// CacheCooldownEntry 缓存中的冷却记录（渠道冷却时 KeyIndex 为 nil）
+type CacheCooldownEntry struct {
+	ChannelID int64     `json:"channel_id"`
+	KeyIndex  *int      `json:"key_index,omitempty"`
+	Until     time.Time `json:"until"`
+}
+
+// Snapshot 返回缓存当前内容与新鲜度（只读，不触发刷新）
+func (c *ChannelCache) Snapshot(now time.Time) CacheSnapshot {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var snap CacheSnapshot
+	snap.Channels.TTLSeconds = c.ttl.Seconds()
+	snap.Channels.Stale = now.Sub(c.lastUpdate) > c.ttl
+	snap.Channels.Entries = make([]CacheChannelEntry, 0, len(c.allChannels))
+	if !c.lastUpdate.IsZero() {
+		last := c.lastUpdate
+		snap.Channels.LastRefresh = &last
+		snap.Channels.AgeSeconds = now.Sub(last).Seconds()
+		for _, cfg := range c.allChannels {
+			snap.Channels.Entries = append(snap.Channels.Entries, CacheChannelEntry{
+				ID:          cfg.ID,
+				Name:        cfg.Name,
+				ChannelType: cfg.GetChannelType(),
+				Priority:    cfg.Priority,
+				ModelCount:  len(cfg.ModelEntries),
+				KeyCount:    cfg.KeyCount,
+			})
+		}
+	}
+
+	snap.APIKeys = make([]CacheAPIKeysEntry, 0, len(c.apiKeysByChannelID))
+	for channelID, keys := range c.apiKeysByChannelID {
+		loadedAt := c.apiKeysLoadedAt[channelID]
+		snap.APIKeys = append(snap.APIKeys, CacheAPIKeysEntry{
+			ChannelID:  channelID,
+			KeyCount:   len(keys),
+			LoadedAt:   loadedAt,
+			AgeSeconds: now.Sub(loadedAt).Seconds(),
+		})
+	}
+	slices.SortFunc(snap.APIKeys, func(a, b CacheAPIKeysEntry) int { return cmp.Compare(a.ChannelID, b.ChannelID) })
+
+	cd := &c.cooldownCache
+	snap.Cooldowns.TTLSeconds = cd.ttl.Seconds()
+	snap.Cooldowns.Stale = now.Sub(cd.lastUpdate) > cd.ttl
+	snap.Cooldowns.Channels = make([]CacheCooldownEntry, 0, len(cd.channels))
+	snap.Cooldowns.Keys = make([]CacheCooldownEntry, 0)
+	if !cd.lastUpdate.IsZero() {
+		last := cd.lastUpdate
+		snap.Cooldowns.LastUpdate = &last
+		snap.Cooldowns.AgeSeconds = now.Sub(last).Seconds()
+	}
+	for channelID, until := range cd.channels {
+		snap.Cooldowns.Channels = append(snap.Cooldowns.Channels, CacheCooldownEntry{ChannelID: channelID, Until: until})
+	}
+	for channelID, byKey := range cd.keys {
+		for keyIndex, until := range byKey {
+			snap.Cooldowns.Keys = append(snap.Cooldowns.Keys, CacheCooldownEntry{ChannelID: channelID, KeyIndex: &keyIndex, Until: until})
+		}
+	}
+	byChannelKey := func(a, b CacheCooldownEntry) int {
+		if n := cmp.Compare(a.ChannelID, b.ChannelID); n != 0 {
+			return n
+		}
+		if a.KeyIndex == nil || b.KeyIndex == nil {
+			return 0
+		}
+		return cmp.Compare(*a.KeyIndex, *b.KeyIndex)
+	}
+	slices.SortFunc(snap.Cooldowns.Channels, byChannelKey)
+	slices.SortFunc(snap.Cooldowns.Keys, byChannelKey)
+
+	return snap
+}
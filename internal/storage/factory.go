@@ -141,6 +141,11 @@ func createSQLiteStore(path string, redisSync RedisSync) (*sqlstore.SQLStore, er
 		return nil, err
 	}
 
+	// 存在待恢复的备份时先换入（2026-10新增，见 sqlite_restore.go）
+	if path != ":memory:" {
+		applyPendingSQLiteRestore(path)
+	}
+
 	// 打开SQLite数据库
 	dsn := buildSQLiteDSN(path)
 	db, err := sql.Open("sqlite", dsn)
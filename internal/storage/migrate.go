@@ -29,6 +29,8 @@ var sqliteMigratableTables = map[string]bool{
 	"auth_tokens":       true,
 	"channel_models":    true,
 	"channels":          true,
+	"api_keys":          true,
+	"admin_sessions":    true,
 	"schema_migrations": true,
 }
 
@@ -54,6 +56,19 @@ func migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
 		schema.DefineSystemSettingsTable,
 		schema.DefineAdminSessionsTable,
 		schema.DefineLogsTable,
+		schema.DefineBudgetAlertsTable,
+		schema.DefineMonthlyBudgetsTable,
+		schema.DefineCostRecomputeJobsTable,
+		schema.DefineRoutingSchedulesTable,
+		schema.DefineGeminiKeyProvisionersTable,
+		schema.DefineVertexCredentialsTable,
+		schema.DefineWebhooksTable,
+		schema.DefineChannelHealthChecksTable,
+		schema.DefineResponseCacheTable,
+		schema.DefineCacheWarmupsTable,
+		schema.DefineModelAliasesTable,
+		schema.DefineModelPricesTable,
+		schema.DefineTestPromptsTable,
 	}
 
 	// 创建表和索引
@@ -77,6 +92,69 @@ func migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
 			if err := ensureChannelsDailyCostLimit(ctx, db, dialect); err != nil {
 				return fmt.Errorf("migrate channels daily_cost_limit: %w", err)
 			}
+			// 增量迁移：确保channels表有cost_multiplier字段（2026-10新增）
+			if err := ensureChannelsCostMultiplier(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels cost_multiplier: %w", err)
+			}
+			// 增量迁移：确保channels表有client_profile字段（2026-10新增）
+			if err := ensureChannelsClientProfile(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels client_profile: %w", err)
+			}
+			// 增量迁移：确保channels表有cert_pins字段（2026-10新增）
+			if err := ensureChannelsCertPins(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels cert_pins: %w", err)
+			}
+			// 增量迁移：确保channels表有local_addr字段（2026-10新增）
+			if err := ensureChannelsLocalAddr(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels local_addr: %w", err)
+			}
+			// 增量迁移：确保channels表有上游压缩字段（2026-10新增）
+			if err := ensureChannelsCompressionColumns(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels compression: %w", err)
+			}
+			// 增量迁移：确保channels表有anthropic_compat字段（2026-10新增）
+			if err := ensureChannelsAnthropicCompat(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels anthropic_compat: %w", err)
+			}
+			// 增量迁移：确保channels表有openai_compat字段（2026-10新增）
+			if err := ensureChannelsOpenAICompat(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels openai_compat: %w", err)
+			}
+			// 增量迁移：确保channels表有idempotency_keys字段（2026-10新增）
+			if err := ensureChannelsIdempotencyKeys(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels idempotency_keys: %w", err)
+			}
+			// 增量迁移：确保channels表有weight字段（2026-10新增）
+			if err := ensureChannelsWeight(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels weight: %w", err)
+			}
+			// 增量迁移：确保channels表有regions字段（2026-10新增）
+			if err := ensureChannelsRegions(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels regions: %w", err)
+			}
+			// 增量迁移：确保channels表有beta_features字段（2026-10新增）
+			if err := ensureChannelsBetaFeatures(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels beta_features: %w", err)
+			}
+			// 增量迁移：确保channels表有rewrite_rules字段（2026-10新增）
+			if err := ensureChannelsRewriteRules(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels rewrite_rules: %w", err)
+			}
+			// 增量迁移：确保channels表有model_concurrency字段（2026-10新增）
+			if err := ensureChannelsModelConcurrency(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate channels model_concurrency: %w", err)
+			}
+		}
+
+		// 增量迁移：确保api_keys表有上游配额字段（2026-10新增）
+		if tb.Name() == "api_keys" {
+			if err := ensureAPIKeysQuotaColumns(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate api_keys quota columns: %w", err)
+			}
+			// 增量迁移：确保api_keys表有account_group字段（2026-10新增）
+			if err := ensureAPIKeysAccountGroup(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate api_keys account_group: %w", err)
+			}
 		}
 
 		// 增量迁移：确保auth_tokens表有缓存token字段（2025-12新增）
@@ -92,6 +170,37 @@ func migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
 			if err := ensureAuthTokensCostLimit(ctx, db, dialect); err != nil {
 				return fmt.Errorf("migrate auth_tokens cost_limit: %w", err)
 			}
+			// 增量迁移：确保auth_tokens表有mTLS证书映射字段（2026-10新增）
+			if err := ensureAuthTokensClientCertSubject(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate auth_tokens client_cert_subject: %w", err)
+			}
+			// 增量迁移：确保auth_tokens表有归属方字段（2026-10新增）
+			if err := ensureAuthTokensOwner(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate auth_tokens owner: %w", err)
+			}
+			// 增量迁移：确保auth_tokens表有模型屏蔽字段（2026-10新增）
+			if err := ensureAuthTokensBlockedModels(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate auth_tokens blocked_models: %w", err)
+			}
+			// 增量迁移：确保auth_tokens表有failover_info字段（2026-10新增）
+			if err := ensureAuthTokensFailoverInfo(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate auth_tokens failover_info: %w", err)
+			}
+			// 增量迁移：确保auth_tokens表有rpm_limit/tpm_limit字段（2026-10新增）
+			if err := ensureAuthTokensRateLimits(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate auth_tokens rate limits: %w", err)
+			}
+			// 增量迁移：确保auth_tokens表有fallback_model字段（2026-10新增）
+			if err := ensureAuthTokensFallbackModel(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate auth_tokens fallback_model: %w", err)
+			}
+		}
+
+		// 增量迁移：确保admin_sessions表有role字段（2026-10新增）
+		if tb.Name() == "admin_sessions" {
+			if err := ensureAdminSessionsRole(ctx, db, dialect); err != nil {
+				return fmt.Errorf("migrate admin_sessions role: %w", err)
+			}
 		}
 
 		// 增量迁移：channel_models表添加redirect_model字段，迁移数据后删除channels冗余字段
@@ -119,6 +228,11 @@ func migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
 		return err
 	}
 
+	// 渠道测试提示词库：写入内置提示词并迁移旧的 channel_test_content 设置（2026-10新增）
+	if err := seedTestPrompts(ctx, db, dialect); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -130,6 +244,78 @@ func cleanupRemovedSettings(ctx context.Context, db *sql.DB, dialect Dialect) er
 	return nil
 }
 
+// legacyChannelTestContent 旧系统设置 channel_test_content 的默认值
+const legacyChannelTestContent = "sonnet 4.0的发布日期是什么"
+
+// builtinTestPrompts 内置渠道测试提示词（仅首次迁移时写入，之后可自由编辑/删除）
+var builtinTestPrompts = []struct {
+	name, description, content string
+	maxTokens                  int
+}{
+	{"short-ping", "最短连通性检查", "Reply with the single word: pong", 16},
+	{"tool-use-check", "结构化输出检查：按要求只返回工具调用JSON",
+		`You are testing tool calling. Do not explain anything. Respond with exactly this JSON object and nothing else: {"tool":"get_weather","arguments":{"city":"Paris","unit":"celsius"}}`, 128},
+	{"long-context-check", "长上下文检查：约 20KB 文本中找出口令", longContextTestPrompt(), 32},
+	{"chinese-text-check", "中文文本检查", "请用简体中文回答：中国的首都是哪座城市？请用一句话回答，并说明它位于哪个方向的地区。", 128},
+}
+
+// longContextTestPrompt 构造长上下文测试内容：填充段落中间埋入口令，要求模型原样找出
+func longContextTestPrompt() string {
+	const filler = "This paragraph is filler text used to verify that the upstream accepts long prompts without truncation. "
+	var b strings.Builder
+	b.WriteString("Read the following document and answer the question at the end.\n\n")
+	for i := range 200 {
+		if i == 100 {
+			b.WriteString("The secret passphrase is AMBER-FALCON-7319. ")
+		}
+		b.WriteString(filler)
+	}
+	b.WriteString("\n\nQuestion: what is the secret passphrase? Reply with the passphrase only.")
+	return b.String()
+}
+
+// seedTestPrompts 首次迁移时写入内置测试提示词，并将旧设置 channel_test_content 的自定义值迁移为默认提示词
+// 以 schema_migrations 标记只执行一次，用户删除内置提示词后不会被重新写入
+func seedTestPrompts(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	const marker = "test_prompts_seeded"
+	if hasMigration(ctx, db, marker) {
+		return nil
+	}
+
+	keyCol := "key"
+	if dialect == DialectMySQL {
+		keyCol = "`key`"
+	}
+	legacy := ""
+	//nolint:gosec // G201: keyCol 仅为 "key" 或 "`key`"，由内部逻辑控制
+	_ = db.QueryRowContext(ctx, fmt.Sprintf("SELECT value FROM system_settings WHERE %s = 'channel_test_content'", keyCol)).Scan(&legacy)
+	legacy = strings.TrimSpace(legacy)
+
+	insertSQL := "INSERT OR IGNORE INTO test_prompts (name, content, description, max_tokens, is_default, created_at, updated_at) VALUES (?, ?, ?, ?, ?, unixepoch(), unixepoch())"
+	if dialect == DialectMySQL {
+		insertSQL = "INSERT IGNORE INTO test_prompts (name, content, description, max_tokens, is_default, created_at, updated_at) VALUES (?, ?, ?, ?, ?, UNIX_TIMESTAMP(), UNIX_TIMESTAMP())"
+	}
+	customized := legacy != "" && legacy != legacyChannelTestContent
+	for i, p := range builtinTestPrompts {
+		isDefault := 0
+		if i == 0 && !customized {
+			isDefault = 1
+		}
+		if _, err := db.ExecContext(ctx, insertSQL, p.name, p.content, p.description, p.maxTokens, isDefault); err != nil {
+			return fmt.Errorf("seed test prompt %s: %w", p.name, err)
+		}
+	}
+	if customized {
+		if _, err := db.ExecContext(ctx, insertSQL, "legacy-default", legacy, "迁移自系统设置 channel_test_content", 0, 1); err != nil {
+			return fmt.Errorf("seed legacy test prompt: %w", err)
+		}
+	}
+	if err := deleteSystemSetting(ctx, db, dialect, "channel_test_content"); err != nil {
+		return err
+	}
+	return recordMigration(ctx, db, marker, dialect)
+}
+
 func deleteSystemSetting(ctx context.Context, db *sql.DB, dialect Dialect, key string) error {
 	query := "DELETE FROM system_settings WHERE key = ?"
 	if dialect == DialectMySQL {
@@ -167,7 +353,15 @@ func ensureLogsNewColumns(ctx context.Context, db *sql.DB, dialect Dialect) erro
 		if err := ensureLogsCacheFieldsMySQL(ctx, db); err != nil {
 			return err
 		}
-		return ensureLogsActualModelMySQL(ctx, db)
+		if err := ensureLogsActualModelMySQL(ctx, db); err != nil {
+			return err
+		}
+		// Key维度聚合、错误归类、响应标签（2026-10新增）
+		return ensureMySQLColumns(ctx, db, "logs", []mysqlColumnDef{
+			{name: "key_index", definition: "INT NOT NULL DEFAULT -1"},
+			{name: "error_class", definition: "VARCHAR(32) NOT NULL DEFAULT ''"},
+			{name: "labels", definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+		})
 	}
 	// SQLite: 使用PRAGMA table_info检查列
 	return ensureLogsColumnsSQLite(ctx, db)
@@ -236,6 +430,9 @@ func ensureLogsColumnsSQLite(ctx context.Context, db *sql.DB) error {
 		{name: "cache_5m_input_tokens", definition: "INTEGER NOT NULL DEFAULT 0"},
 		{name: "cache_1h_input_tokens", definition: "INTEGER NOT NULL DEFAULT 0"},
 		{name: "actual_model", definition: "TEXT NOT NULL DEFAULT ''"}, // 实际转发的模型
+		{name: "key_index", definition: "INTEGER NOT NULL DEFAULT -1"}, // 使用的Key下标（2026-10新增）
+		{name: "error_class", definition: "TEXT NOT NULL DEFAULT ''"},  // 错误归类（2026-10新增）
+		{name: "labels", definition: "TEXT NOT NULL DEFAULT ''"},       // 响应内容标签（2026-10新增）
 	}); err != nil {
 		return err
 	}
@@ -551,12 +748,27 @@ func initDefaultSettings(ctx context.Context, db *sql.DB, dialect Dialect) error
 		key, value, valueType, desc, defaultVal string
 	}{
 		{"log_retention_days", "7", "int", "日志保留天数(-1永久保留,1-365天)", "7"},
+		{"log_cleanup_batch_size", "1000", "int", "日志清理每批删除条数(100-50000，越小锁表越短)", "1000"},
+		{"log_cleanup_batch_sleep_ms", "100", "int", "日志清理批间休眠毫秒(0-10000，让出写锁给日志写入)", "100"},
+		{"log_db_analyze_hours", "24", "int", "日志清理后执行ANALYZE的最小间隔小时(0=关闭)", "24"},
+		{"log_db_vacuum_hours", "0", "int", "日志清理后执行VACUUM/OPTIMIZE的最小间隔小时(0=关闭，执行期间阻塞写入)", "0"},
+		// 数据库维护（仅SQLite）
+		{"db_backup_interval_hours", "24", "int", "SQLite自动备份间隔小时(0=关闭;备份目录由CCLOAD_BACKUP_DIR指定,默认数据库同目录backups/,修改后重启生效)", "24"},
+		{"db_backup_keep", "7", "int", "SQLite本地备份保留份数(1-365,修改后重启生效)", "7"},
+		{"db_replication_interval_seconds", "60", "int", "SQLite快照复制间隔秒(1-3600;每次执行WAL checkpoint,库有变更时用VACUUM INTO生成完整快照,库越大间隔应越长;仅配置了CCLOAD_REPLICA_S3或CCLOAD_REPLICA_HOOK时生效,修改后重启生效)", "60"},
+		{"db_replication_max_db_mb", "1024", "int", "SQLite快照复制允许的最大库大小MB(主库+WAL超过时拒绝快照并标记db_replication为不健康,大库请改用Litestream;0=不限制,修改后重启生效)", "1024"},
+		{"db_replication_lag_alert_seconds", "300", "int", "数据库复制滞后告警阈值秒(超过时健康检查db_replication为degraded;0=不告警,修改后重启生效)", "300"},
+		{"otel_tracing_enabled", "false", "bool", "启用代理链路追踪(OTLP/HTTP JSON导出,修改后重启生效)", "false"},
+		{"otel_exporter_otlp_endpoint", "", "string", "OTLP collector地址(如http://otel-collector:4318,自动补全/v1/traces,修改后重启生效)", ""},
+		{"otel_exporter_otlp_headers", "", "string", "OTLP导出请求头(key=value逗号分隔,如Authorization=Bearer xxx,修改后重启生效)", ""},
+		{"otel_service_name", "ccload", "string", "链路追踪service.name(修改后重启生效)", "ccload"},
+		{"otel_sample_percent", "100", "int", "链路追踪采样百分比(0-100;请求携带traceparent时沿用上游采样决定,修改后重启生效)", "100"},
+		{"db_integrity_check_hours", "24", "int", "SQLite完整性检查(quick_check)间隔小时(0=关闭;未通过时跳过备份,修改后重启生效)", "24"},
 		{"max_key_retries", "3", "int", "单渠道最大Key重试次数", "3"},
 		{"upstream_first_byte_timeout", "0", "duration", "上游首块响应体超时(秒,0=禁用，仅流式)", "0"},
 		{"non_stream_timeout", "120", "duration", "非流式请求超时(秒,0=禁用)", "120"},
 		{"model_lookup_strip_date_suffix", "true", "bool", "模型匹配失败时，忽略末尾-YYYYMMDD日期后缀进行渠道匹配(优先精确匹配)", "true"},
 		{"model_fuzzy_match", "false", "bool", "模型匹配失败时，使用子串模糊匹配(多匹配时选最新版本)", "false"},
-		{"channel_test_content", "sonnet 4.0的发布日期是什么", "string", "渠道测试默认内容", "sonnet 4.0的发布日期是什么"},
 		{"channel_stats_range", "today", "string", "渠道管理费用统计范围", "today"},
 		// 健康度排序配置
 		{"enable_health_score", "false", "bool", "启用基于健康度的渠道动态排序", "false"},
@@ -566,6 +778,51 @@ func initDefaultSettings(ctx context.Context, db *sql.DB, dialect Dialect) error
 		{"health_min_confident_sample", "20", "int", "置信样本量阈值(样本量达到此值时惩罚全额生效)", "20"},
 		// 冷却兜底配置
 		{"cooldown_fallback_enabled", "true", "bool", "所有渠道冷却时选最优渠道兜底(关闭则直接拒绝请求)", "true"},
+		// 客户端请求头profile
+		{"client_profiles", "", "string", "自定义客户端请求头profile(JSON: {\"名称\":{\"User-Agent\":\"...\"}}，同名覆盖内置claude-cli/codex-cli)", ""},
+		// 合成探测与状态页
+		{"status_probe_interval_minutes", "0", "int", "状态页合成探测间隔分钟(按渠道类型发起max_tokens=16的最小请求,0=关闭,1-1440,修改后重启生效)", "0"},
+		{"channel_health_check_interval_minutes", "0", "int", "渠道定时健康检查间隔分钟(对每个启用渠道发起max_tokens=16的测试请求,0=关闭,1-1440,修改后重启生效)", "0"},
+		{"channel_health_check_failure_threshold", "3", "int", "健康检查连续失败多少次后自动禁用渠道(1-100,修改后重启生效)", "3"},
+		{"channel_health_check_auto_enable", "true", "bool", "继续探测被健康检查自动禁用的渠道,探测成功后自动启用(手动禁用的渠道不受影响,修改后重启生效)", "true"},
+		{"status_page_public", "false", "bool", "状态页 /status 公开访问(关闭则需API令牌,修改后重启生效)", "false"},
+		// 缓存Token计费倍率
+		{"cost_cache_multipliers", "", "string", "按模型覆盖缓存Token计费倍率(JSON: {\"claude-opus-4*\":{\"read\":0.1,\"write_5m\":1.25,\"write_1h\":2}}，键为模型名或*结尾前缀，留空=内置倍率,立即生效；历史日志可通过费用重算修正)", ""},
+		// 费用展示币种
+		{"display_currency", "", "string", "费用展示币种(ISO 4217代码,如CNY;统计/摘要/令牌接口在美元金额旁附带换算金额;留空=仅显示美元;立即生效)", ""},
+		{"exchange_rate", "0", "string", "展示币种手动汇率(1美元=多少展示币种;0=从exchange_rate_url自动拉取;立即生效)", "0"},
+		{"exchange_rate_url", "", "string", "自动汇率接口(返回USD基准的rates/conversion_rates JSON,如 https://open.er-api.com/v6/latest/USD;每6小时刷新,失败时沿用上次汇率;立即生效)", ""},
+		// 地域路由
+		{"geo_regions", "", "string", "客户端IP地域映射(JSON: {\"eu\":[\"2.16.0.0/13\"],\"us\":[\"3.0.0.0/9\"]})，命中地域的请求优先使用带相同regions标签的渠道，留空=关闭(修改后重启生效)", ""},
+		// 管理端出站通道
+		{"admin_lane_concurrency", "2", "int", "管理端上游调用(渠道测试等)最大并发，与生产流量隔离(修改后重启生效)", "2"},
+		{"admin_lane_interval_ms", "200", "int", "管理端上游调用最小启动间隔(毫秒,0=不节流，修改后重启生效)", "200"},
+		{"token_anomaly_multiplier", "10", "int", "输出Token异常判定倍数(单次输出超过同令牌+模型历史均值的倍数即告警,0=关闭,修改后重启生效)", "10"},
+		{"token_anomaly_min_output_tokens", "8000", "int", "输出Token异常判定下限(输出低于该值不告警,修改后重启生效)", "8000"},
+		{"token_anomaly_cap_tokens", "0", "int", "检测到输出Token异常后30分钟内,对该令牌+模型的请求注入的max_tokens上限(0=仅告警,修改后重启生效)", "0"},
+		{"token_drift_threshold_pct", "25", "int", "Token估算漂移告警阈值(渠道+模型在滚动窗口内本地估算输入Token与上游实际值的偏差百分比超过该值时告警,0=关闭,修改后重启生效)", "25"},
+		{"token_drift_window_minutes", "60", "int", "Token估算漂移统计滚动窗口(分钟,1-1440,修改后重启生效)", "60"},
+		{"budget_alert_thresholds", "50,80,95", "string", "预算软告警阈值(逗号分隔的百分比,花费越过令牌费用上限/渠道每日限额/月度预算的该比例时告警,留空=关闭,修改后重启生效)", "50,80,95"},
+		{"local_addr_fallback", "false", "bool", "渠道配置的出站IP/网卡不可用时改走默认路由(关闭则该渠道请求失败并切换其他渠道,修改后重启生效)", "false"},
+		{"error_retry_hints_enabled", "true", "bool", "返回给客户端的429/5xx错误体附加retry_hints扩展字段(建议重试秒数/当前可用的替代模型),并补充Retry-After头(修改后重启生效)", "true"},
+		{"upstream_micro_retry_enabled", "true", "bool", "上游连接被重置/EOF且未收到响应时,对可安全重放的请求(幂等方法/带Idempotency-Key/请求体未写出)用新连接在同一Key上重试一次,成功则不触发Key冷却(修改后重启生效)", "true"},
+		{"sticky_session_ttl_seconds", "0", "int", "会话粘性路由保持时长(秒,0=关闭):同一会话(X-CCLoad-Session-Id头或metadata.user_id)的请求优先固定到上次成功的渠道/Key以提高提示缓存命中率,固定渠道冷却时自动回退(修改后重启生效)", "0"},
+		{"json_repair_enabled", "false", "bool", "JSON模式输出修复(客户端要求JSON输出时剥离代码块/多余文字并按客户端Schema校验,无法修复时返回结构化错误,修改后重启生效)", "false"},
+		{"error_capture_budget_mb", "16", "int", "失败请求常驻抓取内存预算(MB,0=关闭,最大1024):独立于渠道手动抓取开关,所有非2xx/流内错误/网络错误的转发尝试均记录脱敏后的入站/出站请求与上游错误响应,超出预算淘汰最旧记录(修改后重启生效)", "16"},
+		{"error_capture_retention_hours", "24", "int", "失败请求抓取保留时长(小时,1-720,修改后重启生效)", "24"},
+		{"response_buffer_bytes", "0", "int", "流式响应提交前的缓冲窗口(字节,窗口内上游失败可无感重试其他渠道,建议2048;首个内容事件前不发送响应头和ping保活,长时间思考请求需客户端空闲超时足够;0=关闭,最大65536,修改后重启生效)", "0"},
+		// 非流式响应缓存
+		{"response_cache_ttl_seconds", "0", "int", "非流式响应缓存有效期(秒,0=关闭,最大604800):方法+路径+请求体完全相同的非流式请求在选路前直接返回缓存的200 JSON响应(不产生上游费用),客户端带Cache-Control: no-cache/no-store时跳过(修改后重启生效)", "0"},
+		{"response_cache_max_entries", "1000", "int", "响应缓存内存条目数上限(1-100000,另有64MB总大小上限,超出淘汰最久未使用,修改后重启生效)", "1000"},
+		{"response_cache_persist", "false", "bool", "响应缓存同时写入数据库,重启后预热未过期条目(修改后重启生效)", "false"},
+		// 模型屏蔽策略
+		{"blocked_models", "", "string", "全局屏蔽的模型(逗号或换行分隔,支持*通配符,不区分大小写;命中时选路前返回403并给出可用替代模型;修改后立即生效)", ""},
+		// 响应内容打标
+		{"response_label_rules", "", "string", "响应内容打标规则(每行一条<标签>=<正则>,#开头为注释;成功响应的模型输出文本命中时将标签写入日志,用于按渠道监控拒答率等,如 refusal-detected=(?i)I can't help;留空=关闭;修改后立即生效)", ""},
+		// 请求预校验
+		{"channel_balance_mode", "smooth", "string", "同优先级渠道负载均衡模式(smooth=平滑加权轮询,确定性分流;random=加权随机;权重为渠道weight,未设置时按有效Key数量,修改后重启生效)", "smooth"},
+		{"reject_duplicate_api_keys", "false", "bool", "创建/更新渠道时拒绝已配置在其他渠道的API Key(按Key指纹比对,返回409并列出冲突渠道;关闭时仅在渠道校验与重复Key报告中告警,立即生效)", "false"},
+		{"request_validation_enabled", "false", "bool", "转发前校验/v1/messages请求体(必填字段/max_tokens/角色交替/内容块类型)，畸形请求本地返回400", "false"},
 	}
 
 	var query string
@@ -1008,6 +1265,272 @@ func ensureChannelsDailyCostLimit(ctx context.Context, db *sql.DB, dialect Diale
 	})
 }
 
+// ensureChannelsCostMultiplier 确保channels表有cost_multiplier字段（价格倍率，默认1）
+func ensureChannelsCostMultiplier(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		var count int
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME='channels' AND COLUMN_NAME='cost_multiplier'",
+		).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("check cost_multiplier field: %w", err)
+		}
+		if count == 0 {
+			if _, err := db.ExecContext(ctx,
+				"ALTER TABLE channels ADD COLUMN cost_multiplier DOUBLE NOT NULL DEFAULT 1"); err != nil {
+				return fmt.Errorf("add cost_multiplier column: %w", err)
+			}
+			log.Printf("[MIGRATE] Added channels.cost_multiplier column")
+		}
+		return nil
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "cost_multiplier", definition: "REAL NOT NULL DEFAULT 1"},
+	})
+}
+
+// ensureChannelsClientProfile 确保channels表有client_profile字段（客户端请求头profile）
+func ensureChannelsClientProfile(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		var count int
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME='channels' AND COLUMN_NAME='client_profile'",
+		).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("check client_profile field: %w", err)
+		}
+		if count == 0 {
+			if _, err := db.ExecContext(ctx,
+				"ALTER TABLE channels ADD COLUMN client_profile VARCHAR(64) NOT NULL DEFAULT ''"); err != nil {
+				return fmt.Errorf("add client_profile column: %w", err)
+			}
+			log.Printf("[MIGRATE] Added channels.client_profile column")
+		}
+		return nil
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "client_profile", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureChannelsCertPins 确保channels表有cert_pins字段（上游证书SPKI指纹固定）
+func ensureChannelsCertPins(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		var count int
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME='channels' AND COLUMN_NAME='cert_pins'",
+		).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("check cert_pins field: %w", err)
+		}
+		if count == 0 {
+			if _, err := db.ExecContext(ctx,
+				"ALTER TABLE channels ADD COLUMN cert_pins VARCHAR(1024) NOT NULL DEFAULT ''"); err != nil {
+				return fmt.Errorf("add cert_pins column: %w", err)
+			}
+			log.Printf("[MIGRATE] Added channels.cert_pins column")
+		}
+		return nil
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "cert_pins", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureAPIKeysQuotaColumns 确保api_keys表有上游配额快照字段
+func ensureAPIKeysQuotaColumns(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "api_keys", []mysqlColumnDef{
+			{name: "quota_requests_remaining", definition: "BIGINT NOT NULL DEFAULT -1"},
+			{name: "quota_requests_reset_at", definition: "BIGINT NOT NULL DEFAULT 0"},
+			{name: "quota_tokens_remaining", definition: "BIGINT NOT NULL DEFAULT -1"},
+			{name: "quota_tokens_reset_at", definition: "BIGINT NOT NULL DEFAULT 0"},
+			{name: "quota_updated_at", definition: "BIGINT NOT NULL DEFAULT 0"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "api_keys", []sqliteColumnDef{
+		{name: "quota_requests_remaining", definition: "INTEGER NOT NULL DEFAULT -1"},
+		{name: "quota_requests_reset_at", definition: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "quota_tokens_remaining", definition: "INTEGER NOT NULL DEFAULT -1"},
+		{name: "quota_tokens_reset_at", definition: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "quota_updated_at", definition: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// ensureAPIKeysAccountGroup 确保api_keys表有上游账号分组字段
+func ensureAPIKeysAccountGroup(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "api_keys", []mysqlColumnDef{
+			{name: "account_group", definition: "VARCHAR(64) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "api_keys", []sqliteColumnDef{
+		{name: "account_group", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureChannelsLocalAddr 确保channels表有local_addr字段（出站地址/网卡绑定）
+func ensureChannelsLocalAddr(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		var count int
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA=DATABASE() AND TABLE_NAME='channels' AND COLUMN_NAME='local_addr'",
+		).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("check local_addr field: %w", err)
+		}
+		if count == 0 {
+			if _, err := db.ExecContext(ctx,
+				"ALTER TABLE channels ADD COLUMN local_addr VARCHAR(64) NOT NULL DEFAULT ''"); err != nil {
+				return fmt.Errorf("add local_addr column: %w", err)
+			}
+			log.Printf("[MIGRATE] Added channels.local_addr column")
+		}
+		return nil
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "local_addr", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureChannelsCompressionColumns 确保channels表有上游压缩字段（请求体压缩/响应编码偏好）
+func ensureChannelsCompressionColumns(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "request_compression", definition: "VARCHAR(16) NOT NULL DEFAULT ''"},
+			{name: "accept_encoding", definition: "VARCHAR(64) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "request_compression", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "accept_encoding", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureChannelsAnthropicCompat 确保channels表有anthropic_compat字段（gemini渠道的Anthropic协议兼容开关）
+func ensureChannelsAnthropicCompat(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "anthropic_compat", definition: "TINYINT NOT NULL DEFAULT 0"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "anthropic_compat", definition: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// ensureChannelsOpenAICompat 确保channels表有openai_compat字段（非openai渠道的Chat Completions协议兼容开关）
+func ensureChannelsOpenAICompat(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "openai_compat", definition: "TINYINT NOT NULL DEFAULT 0"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "openai_compat", definition: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// ensureChannelsIdempotencyKeys 确保channels表有idempotency_keys字段（出站幂等键开关）
+func ensureChannelsIdempotencyKeys(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "idempotency_keys", definition: "TINYINT NOT NULL DEFAULT 0"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "idempotency_keys", definition: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// ensureChannelsWeight 确保channels表有weight字段（负载均衡权重）
+func ensureChannelsWeight(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "weight", definition: "INT NOT NULL DEFAULT 0"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "weight", definition: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// ensureAdminSessionsRole 确保admin_sessions表有role字段（管理员会话角色，已有会话均为admin）
+func ensureAdminSessionsRole(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "admin_sessions", []mysqlColumnDef{
+			{name: "role", definition: "VARCHAR(16) NOT NULL DEFAULT 'admin'"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "admin_sessions", []sqliteColumnDef{
+		{name: "role", definition: "TEXT NOT NULL DEFAULT 'admin'"},
+	})
+}
+
+// ensureChannelsRegions 确保channels表有regions字段（地域路由标签）
+func ensureChannelsRegions(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "regions", definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "regions", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureChannelsBetaFeatures 确保channels表有beta_features字段（Beta功能声明）
+func ensureChannelsBetaFeatures(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "beta_features", definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "beta_features", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureChannelsRewriteRules 确保channels表有rewrite_rules字段（请求体改写规则）
+func ensureChannelsRewriteRules(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "rewrite_rules", definition: "VARCHAR(4096) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "rewrite_rules", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureChannelsModelConcurrency 确保channels表有model_concurrency字段（按模型并发上限）
+func ensureChannelsModelConcurrency(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "channels", []mysqlColumnDef{
+			{name: "model_concurrency", definition: "VARCHAR(1024) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "channels", []sqliteColumnDef{
+		{name: "model_concurrency", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
 // ensureAuthTokensAllowedModels 确保auth_tokens表有allowed_models字段
 func ensureAuthTokensAllowedModels(ctx context.Context, db *sql.DB, dialect Dialect) error {
 	if dialect == DialectMySQL {
@@ -1050,3 +1573,83 @@ func ensureAuthTokensCostLimit(ctx context.Context, db *sql.DB, dialect Dialect)
 		{name: "cost_limit_microusd", definition: "INTEGER NOT NULL DEFAULT 0"},
 	})
 }
+
+// ensureAuthTokensClientCertSubject 确保auth_tokens表有mTLS证书映射字段（2026-10新增）
+func ensureAuthTokensClientCertSubject(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "auth_tokens", []mysqlColumnDef{
+			{name: "client_cert_subject", definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "auth_tokens", []sqliteColumnDef{
+		{name: "client_cert_subject", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureAuthTokensOwner 确保auth_tokens表有归属方字段（2026-10新增）
+func ensureAuthTokensOwner(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "auth_tokens", []mysqlColumnDef{
+			{name: "owner", definition: "VARCHAR(64) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "auth_tokens", []sqliteColumnDef{
+		{name: "owner", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureAuthTokensBlockedModels 确保auth_tokens表有模型屏蔽字段（2026-10新增）
+func ensureAuthTokensBlockedModels(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "auth_tokens", []mysqlColumnDef{
+			{name: "blocked_models", definition: "VARCHAR(2048) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "auth_tokens", []sqliteColumnDef{
+		{name: "blocked_models", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
+
+// ensureAuthTokensFailoverInfo 确保auth_tokens表有故障转移信息开关字段（2026-10新增）
+func ensureAuthTokensFailoverInfo(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "auth_tokens", []mysqlColumnDef{
+			{name: "failover_info", definition: "TINYINT NOT NULL DEFAULT 0"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "auth_tokens", []sqliteColumnDef{
+		{name: "failover_info", definition: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// ensureAuthTokensRateLimits 确保auth_tokens表有速率限制字段（2026-10新增）
+func ensureAuthTokensRateLimits(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "auth_tokens", []mysqlColumnDef{
+			{name: "rpm_limit", definition: "INT NOT NULL DEFAULT 0"},
+			{name: "tpm_limit", definition: "BIGINT NOT NULL DEFAULT 0"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "auth_tokens", []sqliteColumnDef{
+		{name: "rpm_limit", definition: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "tpm_limit", definition: "INTEGER NOT NULL DEFAULT 0"},
+	})
+}
+
+// ensureAuthTokensFallbackModel 确保auth_tokens表有默认回退模型字段（2026-10新增）
+func ensureAuthTokensFallbackModel(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	if dialect == DialectMySQL {
+		return ensureMySQLColumns(ctx, db, "auth_tokens", []mysqlColumnDef{
+			{name: "fallback_model", definition: "VARCHAR(191) NOT NULL DEFAULT ''"},
+		})
+	}
+
+	return ensureSQLiteColumns(ctx, db, "auth_tokens", []sqliteColumnDef{
+		{name: "fallback_model", definition: "TEXT NOT NULL DEFAULT ''"},
+	})
+}
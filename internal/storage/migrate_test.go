@@ -0,0 +1,59 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"ccLoad/internal/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+// TestMigrate_LegacyChannelTestContent 旧设置 channel_test_content 的自定义值迁移为默认测试提示词，设置项随后删除
+func TestMigrate_LegacyChannelTestContent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "prompts.db")
+	store, err := storage.CreateSQLiteStore(path, nil)
+	if err != nil {
+		t.Fatalf("创建 store 失败: %v", err)
+	}
+	if _, err := store.GetSetting(ctx, "channel_test_content"); err == nil {
+		t.Fatal("channel_test_content 不应再作为系统设置存在")
+	}
+	_ = store.Close()
+
+	// 模拟升级前的数据库：存在自定义的旧设置、尚未写入提示词
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	for _, stmt := range []string{
+		"DELETE FROM test_prompts",
+		"DELETE FROM schema_migrations WHERE version = 'test_prompts_seeded'",
+		"INSERT INTO system_settings (key, value, value_type, description, default_value, updated_at) VALUES ('channel_test_content', '用一句话介绍你自己', 'string', '', '', 0)",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = db.Close()
+
+	store, err = storage.CreateSQLiteStore(path, nil)
+	if err != nil {
+		t.Fatalf("重新打开 store 失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	prompts, err := store.ListTestPrompts(ctx)
+	if err != nil {
+		t.Fatalf("列出提示词失败: %v", err)
+	}
+	if len(prompts) != 5 || prompts[0].Name != "legacy-default" || !prompts[0].IsDefault ||
+		prompts[0].Content != "用一句话介绍你自己" || prompts[1].IsDefault {
+		t.Fatalf("旧设置迁移不符: %+v", prompts[0])
+	}
+	if _, err := store.GetSetting(ctx, "channel_test_content"); err == nil {
+		t.Fatal("迁移后应删除旧设置")
+	}
+}
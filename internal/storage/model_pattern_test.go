@@ -0,0 +1,76 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+)
+
+// TestGetEnabledChannelsByModel_Wildcard 验证通配符模型在数据库查询与缓存层均生效，且保持优先级顺序
+func TestGetEnabledChannelsByModel_Wildcard(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "wildcard.db"), nil)
+	if err != nil {
+		t.Fatalf("创建 store 失败: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	channels := []*model.Config{
+		{Name: "exact", URL: "https://a.example.com", Priority: 5, Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-sonnet-4-5"}}},
+		{Name: "wildcard", URL: "https://b.example.com", Priority: 10, Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "claude-*"}, {Model: "gpt-4o"}}},
+		{Name: "other", URL: "https://c.example.com", Priority: 20, Enabled: true,
+			ModelEntries: []model.ModelEntry{{Model: "gpt-*"}}},
+	}
+	for _, cfg := range channels {
+		if _, err := store.CreateConfig(ctx, cfg); err != nil {
+			t.Fatalf("创建渠道失败: %v", err)
+		}
+	}
+
+	cache := storage.NewChannelCache(store, time.Minute)
+	sources := map[string]func(context.Context, string) ([]*model.Config, error){
+		"store": store.GetEnabledChannelsByModel,
+		"cache": cache.GetEnabledChannelsByModel,
+	}
+	for name, query := range sources {
+		t.Run(name, func(t *testing.T) {
+			got, err := query(ctx, "claude-sonnet-4-5")
+			if err != nil {
+				t.Fatalf("查询失败: %v", err)
+			}
+			if len(got) != 2 || got[0].Name != "wildcard" || got[1].Name != "exact" {
+				t.Fatalf("期望 [wildcard exact]，实际 %v", channelNames(got))
+			}
+
+			got, err = query(ctx, "gpt-4o")
+			if err != nil {
+				t.Fatalf("查询失败: %v", err)
+			}
+			if len(got) != 2 || got[0].Name != "other" || got[1].Name != "wildcard" {
+				t.Fatalf("期望 [other wildcard]，实际 %v", channelNames(got))
+			}
+
+			got, err = query(ctx, "gemini-2.5-pro")
+			if err != nil {
+				t.Fatalf("查询失败: %v", err)
+			}
+			if len(got) != 0 {
+				t.Fatalf("期望无匹配，实际 %v", channelNames(got))
+			}
+		})
+	}
+}
+
+func channelNames(cfgs []*model.Config) []string {
+	names := make([]string, len(cfgs))
+	for i, c := range cfgs {
+		names[i] = c.Name
+	}
+	return names
+}
@@ -0,0 +1,46 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+)
+
+// TestGetMonthSpend 渠道累计本月全部日志费用，令牌只累计2xx请求
+func TestGetMonthSpend(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "month_spend.db"), nil)
+	if err != nil {
+		t.Fatalf("failed to create sqlite store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	logs := []*model.LogEntry{
+		{Time: model.JSONTime{Time: now}, ChannelID: 1, AuthTokenID: 7, StatusCode: 200, Cost: 1.5},
+		{Time: model.JSONTime{Time: now}, ChannelID: 1, AuthTokenID: 7, StatusCode: 502, Cost: 0.5},
+		{Time: model.JSONTime{Time: now}, ChannelID: 2, StatusCode: 200, Cost: 2},
+		{Time: model.JSONTime{Time: start.Add(-time.Hour)}, ChannelID: 1, AuthTokenID: 7, StatusCode: 200, Cost: 100}, // 上月
+	}
+	for _, e := range logs {
+		if err := store.AddLog(ctx, e); err != nil {
+			t.Fatalf("failed to add log: %v", err)
+		}
+	}
+
+	channels, tokens, err := store.GetMonthSpend(ctx, start)
+	if err != nil {
+		t.Fatalf("GetMonthSpend error: %v", err)
+	}
+	if len(channels) != 2 || channels[1] != 2 || channels[2] != 2 {
+		t.Fatalf("unexpected channel spend: %v", channels)
+	}
+	if len(tokens) != 1 || tokens[7] != 1.5 {
+		t.Fatalf("unexpected token spend: %v", tokens)
+	}
+}
@@ -70,6 +70,14 @@ func (rs *RedisSync) IsEnabled() bool {
 	return rs.enabled
 }
 
+// Ping 检查Redis连接是否可用（用于健康检查，2026-10新增）
+func (rs *RedisSync) Ping(ctx context.Context) error {
+	if !rs.enabled {
+		return nil
+	}
+	return rs.client.Ping(ctx).Err()
+}
+
 // LoadChannelsWithKeysFromRedis 从Redis加载所有渠道（含API Keys）
 // [INFO] 修复（2025-10-10）：完整恢复渠道和API Keys，解决Redis恢复后缺少Keys的问题
 func (rs *RedisSync) LoadChannelsWithKeysFromRedis(ctx context.Context) ([]*model.ChannelWithKeys, error) {
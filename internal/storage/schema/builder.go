@@ -9,6 +9,9 @@ import (
 
 var varcharRegex = regexp.MustCompile(`VARCHAR\(\d+\)`)
 
+// uniqueKeyRegex 匹配MySQL命名唯一约束（UNIQUE KEY uk_xxx）
+var uniqueKeyRegex = regexp.MustCompile(`UNIQUE KEY \w+`)
+
 // TableBuilder 轻量级表构建器（方言无关）
 type TableBuilder struct {
 	name    string
@@ -85,7 +88,7 @@ func mysqlToSQLite(mysqlCol string) string {
 	col = replaceVarchar(col)
 
 	// 索引约束简化（MySQL的UNIQUE KEY → SQLite的UNIQUE）
-	col = strings.ReplaceAll(col, "UNIQUE KEY uk_channel_key", "UNIQUE")
+	col = uniqueKeyRegex.ReplaceAllString(col, "UNIQUE")
 
 	return col
 }
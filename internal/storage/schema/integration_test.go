@@ -380,6 +380,7 @@ func TestTypeConversionCorrectness(t *testing.T) {
 		{"DOUBLE NOT NULL DEFAULT 0.0", "REAL NOT NULL DEFAULT 0.0", "Double column"},
 		{"VARCHAR(255) UNIQUE", "TEXT UNIQUE", "Varchar with unique constraint"},
 		{"INT PRIMARY KEY", "INTEGER PRIMARY KEY", "Primary key without auto increment"},
+		{"UNIQUE KEY uk_budget_alert (scope, target_id)", "UNIQUE (scope, target_id)", "Named unique key"},
 	}
 
 	for _, tc := range testCases {
@@ -12,6 +12,20 @@ func DefineChannelsTable() *TableBuilder {
 		Column("cooldown_until BIGINT NOT NULL DEFAULT 0").
 		Column("cooldown_duration_ms BIGINT NOT NULL DEFAULT 0").
 		Column("daily_cost_limit DOUBLE NOT NULL DEFAULT 0").
+		Column("cost_multiplier DOUBLE NOT NULL DEFAULT 1").
+		Column("client_profile VARCHAR(64) NOT NULL DEFAULT ''").
+		Column("cert_pins VARCHAR(1024) NOT NULL DEFAULT ''").
+		Column("local_addr VARCHAR(64) NOT NULL DEFAULT ''").
+		Column("request_compression VARCHAR(16) NOT NULL DEFAULT ''").
+		Column("accept_encoding VARCHAR(64) NOT NULL DEFAULT ''").
+		Column("anthropic_compat TINYINT NOT NULL DEFAULT 0").
+		Column("openai_compat TINYINT NOT NULL DEFAULT 0").
+		Column("idempotency_keys TINYINT NOT NULL DEFAULT 0").
+		Column("weight INT NOT NULL DEFAULT 0").
+		Column("regions VARCHAR(255) NOT NULL DEFAULT ''").
+		Column("beta_features VARCHAR(255) NOT NULL DEFAULT ''").
+		Column("rewrite_rules VARCHAR(4096) NOT NULL DEFAULT ''").
+		Column("model_concurrency VARCHAR(1024) NOT NULL DEFAULT ''").
 		Column("created_at BIGINT NOT NULL").
 		Column("updated_at BIGINT NOT NULL").
 		Index("idx_channels_enabled", "enabled").
@@ -30,12 +44,19 @@ func DefineAPIKeysTable() *TableBuilder {
 		Column("key_strategy VARCHAR(32) NOT NULL DEFAULT 'sequential'").
 		Column("cooldown_until BIGINT NOT NULL DEFAULT 0").
 		Column("cooldown_duration_ms BIGINT NOT NULL DEFAULT 0").
+		Column("quota_requests_remaining BIGINT NOT NULL DEFAULT -1"). // 上游报告的剩余请求数（-1=未知）
+		Column("quota_requests_reset_at BIGINT NOT NULL DEFAULT 0").
+		Column("quota_tokens_remaining BIGINT NOT NULL DEFAULT -1"). // 上游报告的剩余Token数（-1=未知）
+		Column("quota_tokens_reset_at BIGINT NOT NULL DEFAULT 0").
+		Column("quota_updated_at BIGINT NOT NULL DEFAULT 0").
+		Column("account_group VARCHAR(64) NOT NULL DEFAULT ''"). // 上游账号分组（冷却跨渠道共享）
 		Column("created_at BIGINT NOT NULL").
 		Column("updated_at BIGINT NOT NULL").
 		Column("UNIQUE KEY uk_channel_key (channel_id, key_index)").
 		Column("FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE").
 		Index("idx_api_keys_cooldown", "cooldown_until").
-		Index("idx_api_keys_channel_cooldown", "channel_id, cooldown_until")
+		Index("idx_api_keys_channel_cooldown", "channel_id, cooldown_until").
+		Index("idx_api_keys_account_group", "account_group")
 }
 
 // DefineChannelModelsTable 定义channel_models表结构
@@ -73,7 +94,15 @@ func DefineAuthTokensTable() *TableBuilder {
 		Column("total_cost_usd DOUBLE NOT NULL DEFAULT 0.0").
 		Column("cost_used_microusd BIGINT NOT NULL DEFAULT 0").
 		Column("cost_limit_microusd BIGINT NOT NULL DEFAULT 0").
+		Column("client_cert_subject VARCHAR(255) NOT NULL DEFAULT ''"). // mTLS证书CN/SAN映射（空=不支持证书认证）
+		Column("owner VARCHAR(64) NOT NULL DEFAULT ''").                // 归属团队/负责人（空=未分配）
+		Column("blocked_models VARCHAR(2048) NOT NULL DEFAULT ''").     // 禁止使用的模型（JSON数组，空=不屏蔽）
+		Column("failover_info TINYINT NOT NULL DEFAULT 0").             // 响应附带故障转移信息（0=关闭）
+		Column("rpm_limit INT NOT NULL DEFAULT 0").                     // 每分钟请求数上限（0=不限制）
+		Column("tpm_limit BIGINT NOT NULL DEFAULT 0").                  // 每分钟Token数上限（0=不限制）
+		Column("fallback_model VARCHAR(191) NOT NULL DEFAULT ''").      // 未匹配任何渠道时改用的默认模型（空=不回退）
 		Index("idx_auth_tokens_active", "is_active").
+		Index("idx_auth_tokens_owner", "owner").
 		Index("idx_auth_tokens_expires", "expires_at")
 }
 
@@ -94,6 +123,7 @@ func DefineAdminSessionsTable() *TableBuilder {
 		Column("token VARCHAR(64) PRIMARY KEY"). // SHA256哈希(64字符十六进制,2025-12改为存储哈希而非明文)
 		Column("expires_at BIGINT NOT NULL").
 		Column("created_at BIGINT NOT NULL").
+		Column("role VARCHAR(16) NOT NULL DEFAULT 'admin'"). // 会话角色 admin/viewer（2026-10新增，SSO按组映射）
 		Index("idx_admin_sessions_expires", "expires_at")
 }
 
@@ -119,6 +149,7 @@ func DefineLogsTable() *TableBuilder {
 		Column("is_streaming TINYINT NOT NULL DEFAULT 0").
 		Column("first_byte_time DOUBLE NOT NULL DEFAULT 0.0").
 		Column("api_key_used VARCHAR(191) NOT NULL DEFAULT ''").
+		Column("key_index INT NOT NULL DEFAULT -1").         // 使用的Key下标（2026-10新增，-1表示未知/未选Key）
 		Column("auth_token_id BIGINT NOT NULL DEFAULT 0").   // 客户端使用的API令牌ID（新增2025-12）
 		Column("client_ip VARCHAR(45) NOT NULL DEFAULT ''"). // 客户端IP地址（新增2025-12）
 		Column("input_tokens INT NOT NULL DEFAULT 0").
@@ -128,10 +159,214 @@ func DefineLogsTable() *TableBuilder {
 		Column("cache_5m_input_tokens INT NOT NULL DEFAULT 0").       // 5分钟缓存写入Token数（新增2025-12）
 		Column("cache_1h_input_tokens INT NOT NULL DEFAULT 0").       // 1小时缓存写入Token数（新增2025-12）
 		Column("cost DOUBLE NOT NULL DEFAULT 0.0").
+		Column("error_class VARCHAR(32) NOT NULL DEFAULT ''"). // 错误归类（2026-10新增）
+		Column("labels VARCHAR(255) NOT NULL DEFAULT ''").     // 响应内容标签（2026-10新增，逗号分隔）
 		Index("idx_logs_time_model", "time, model").
 		Index("idx_logs_time_status", "time, status_code").
 		Index("idx_logs_time_channel_model", "time, channel_id, model").
 		Index("idx_logs_minute_channel_model", "minute_bucket, channel_id, model").
-		Index("idx_logs_time_auth_token", "time, auth_token_id"). // 按时间+令牌查询
-		Index("idx_logs_time_actual_model", "time, actual_model") // 按时间+实际模型查询
+		Index("idx_logs_time_auth_token", "time, auth_token_id").         // 按时间+令牌查询
+		Index("idx_logs_time_actual_model", "time, actual_model").        // 按时间+实际模型查询
+		Index("idx_logs_channel_time_key", "channel_id, time, key_index") // Key维度历史报表（2026-10新增）
+}
+
+// DefineBudgetAlertsTable 定义budget_alerts表结构（预算软告警，2026-10新增）
+func DefineBudgetAlertsTable() *TableBuilder {
+	return NewTable("budget_alerts").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("scope VARCHAR(16) NOT NULL").  // token / channel
+		Column("target_id BIGINT NOT NULL").   // 令牌ID或渠道ID
+		Column("period VARCHAR(32) NOT NULL"). // 渠道：YYYY-MM-DD；令牌：total@<限额>
+		Column("threshold INT NOT NULL").      // 百分比
+		Column("spent_usd DOUBLE NOT NULL DEFAULT 0.0").
+		Column("limit_usd DOUBLE NOT NULL DEFAULT 0.0").
+		Column("created_at BIGINT NOT NULL").
+		Column("acknowledged_at BIGINT NOT NULL DEFAULT 0"). // 0=未确认
+		Column("UNIQUE KEY uk_budget_alert (scope, target_id, period, threshold)").
+		Index("idx_budget_alerts_created", "created_at").
+		Index("idx_budget_alerts_ack", "acknowledged_at")
+}
+
+// DefineMonthlyBudgetsTable 定义monthly_budgets表结构（令牌/渠道月度预算，2026-10新增）
+func DefineMonthlyBudgetsTable() *TableBuilder {
+	return NewTable("monthly_budgets").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("scope VARCHAR(16) NOT NULL"). // token / channel
+		Column("target_id INT NOT NULL").
+		Column("limit_usd DOUBLE NOT NULL DEFAULT 0.0").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL").
+		Column("UNIQUE KEY uk_monthly_budget (scope, target_id)")
+}
+
+// DefineCostRecomputeJobsTable 定义cost_recompute_jobs表结构（历史费用重算任务，2026-10新增）
+func DefineCostRecomputeJobsTable() *TableBuilder {
+	return NewTable("cost_recompute_jobs").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("since_ms BIGINT NOT NULL").
+		Column("until_ms BIGINT NOT NULL").
+		Column("status VARCHAR(16) NOT NULL").         // running / paused / completed / failed
+		Column("cursor_id BIGINT NOT NULL DEFAULT 0"). // 已处理的最大日志ID
+		Column("total_rows BIGINT NOT NULL DEFAULT 0").
+		Column("processed_rows BIGINT NOT NULL DEFAULT 0").
+		Column("updated_rows BIGINT NOT NULL DEFAULT 0").
+		Column("cost_delta_usd DOUBLE NOT NULL DEFAULT 0.0").
+		Column("error VARCHAR(512) NOT NULL DEFAULT ''").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL").
+		Index("idx_cost_recompute_jobs_status", "status")
+}
+
+// DefineRoutingSchedulesTable 定义routing_schedules表结构（分时路由规则，2026-10新增）
+func DefineRoutingSchedulesTable() *TableBuilder {
+	return NewTable("routing_schedules").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("channel_id INT NOT NULL").
+		Column("description VARCHAR(191) NOT NULL DEFAULT ''").
+		Column("cron VARCHAR(128) NOT NULL").
+		Column("timezone VARCHAR(64) NOT NULL DEFAULT ''"). // 空=服务器时区
+		Column("priority_delta INT NOT NULL DEFAULT 0").
+		Column("weight_multiplier DOUBLE NOT NULL DEFAULT 1").
+		Column("enabled TINYINT NOT NULL DEFAULT 1").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL").
+		Column("FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE").
+		Index("idx_routing_schedules_channel", "channel_id")
+}
+
+// DefineGeminiKeyProvisionersTable 定义gemini_key_provisioners表结构（Gemini Key自动供给，2026-10新增）
+func DefineGeminiKeyProvisionersTable() *TableBuilder {
+	return NewTable("gemini_key_provisioners").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("channel_id INT NOT NULL UNIQUE").
+		Column("project_id VARCHAR(128) NOT NULL").
+		Column("credentials TEXT NOT NULL"). // 服务账号JSON
+		Column("key_count INT NOT NULL DEFAULT 1").
+		Column("rotate_interval_hours INT NOT NULL DEFAULT 0").
+		Column("enabled TINYINT NOT NULL DEFAULT 1").
+		Column("managed_keys TEXT NOT NULL"). // JSON数组
+		Column("last_run_at BIGINT NOT NULL DEFAULT 0").
+		Column("last_error TEXT NOT NULL").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL").
+		Column("FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE")
+}
+
+// DefineVertexCredentialsTable 定义vertex_credentials表结构（Vertex AI 渠道服务账号凭据，2026-10新增）
+func DefineVertexCredentialsTable() *TableBuilder {
+	return NewTable("vertex_credentials").
+		Column("channel_id INT PRIMARY KEY").
+		Column("project_id VARCHAR(128) NOT NULL").
+		Column("location VARCHAR(64) NOT NULL").
+		Column("credentials TEXT NOT NULL"). // 服务账号JSON
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL").
+		Column("FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE")
+}
+
+// DefineWebhooksTable 定义webhooks表结构（出站Webhook端点，2026-10新增）
+func DefineWebhooksTable() *TableBuilder {
+	return NewTable("webhooks").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("name VARCHAR(191) NOT NULL DEFAULT ''").
+		Column("url TEXT NOT NULL").
+		Column("secret TEXT NOT NULL").                    // HMAC签名密钥，空=不签名
+		Column("events VARCHAR(255) NOT NULL DEFAULT ''"). // 逗号分隔，空=全部事件
+		Column("enabled TINYINT NOT NULL DEFAULT 1").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL")
+}
+
+// DefineCacheWarmupsTable 定义cache_warmups表结构（Anthropic 提示缓存预热计划，2026-10新增）
+func DefineCacheWarmupsTable() *TableBuilder {
+	return NewTable("cache_warmups").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("channel_id INT NOT NULL").
+		Column("model VARCHAR(191) NOT NULL").
+		Column("prompt MEDIUMTEXT NOT NULL").
+		Column("interval_seconds INT NOT NULL DEFAULT 270").
+		Column("enabled TINYINT NOT NULL DEFAULT 1").
+		Column("last_run_at BIGINT NOT NULL DEFAULT 0").
+		Column("last_ok TINYINT NOT NULL DEFAULT 0").
+		Column("last_error TEXT NOT NULL").
+		Column("last_latency_ms BIGINT NOT NULL DEFAULT 0").
+		Column("last_cache_read_tokens BIGINT NOT NULL DEFAULT 0").
+		Column("last_cache_creation_tokens BIGINT NOT NULL DEFAULT 0").
+		Column("runs BIGINT NOT NULL DEFAULT 0").
+		Column("failures BIGINT NOT NULL DEFAULT 0").
+		Column("cache_read_tokens BIGINT NOT NULL DEFAULT 0").     // 累计
+		Column("cache_creation_tokens BIGINT NOT NULL DEFAULT 0"). // 累计
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL").
+		Column("FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE").
+		Index("idx_cache_warmups_channel", "channel_id")
+}
+
+// DefineModelAliasesTable 定义model_aliases表结构（全局模型别名，2026-10新增）
+func DefineModelAliasesTable() *TableBuilder {
+	return NewTable("model_aliases").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("alias VARCHAR(191) NOT NULL UNIQUE").
+		Column("target VARCHAR(191) NOT NULL").
+		Column("description VARCHAR(191) NOT NULL DEFAULT ''").
+		Column("enabled TINYINT NOT NULL DEFAULT 1").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL")
+}
+
+// DefineModelPricesTable 定义model_prices表结构（模型价格表，2026-10新增）
+func DefineModelPricesTable() *TableBuilder {
+	return NewTable("model_prices").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("model VARCHAR(191) NOT NULL").
+		Column("input_price DOUBLE NOT NULL DEFAULT 0").
+		Column("output_price DOUBLE NOT NULL DEFAULT 0").
+		Column("cache_read_price DOUBLE NOT NULL DEFAULT 0").
+		Column("cache_write_price DOUBLE NOT NULL DEFAULT 0").
+		Column("cache_write_1h_price DOUBLE NOT NULL DEFAULT 0").
+		Column("effective_from BIGINT NOT NULL DEFAULT 0"). // Unix毫秒
+		Column("description VARCHAR(191) NOT NULL DEFAULT ''").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL").
+		Column("UNIQUE KEY uk_model_price (model, effective_from)")
+}
+
+// DefineResponseCacheTable 定义response_cache表结构（非流式响应缓存持久化，2026-10新增）
+func DefineResponseCacheTable() *TableBuilder {
+	return NewTable("response_cache").
+		Column("cache_key VARCHAR(64) PRIMARY KEY"). // 请求的SHA-256
+		Column("model VARCHAR(191) NOT NULL DEFAULT ''").
+		Column("content_type VARCHAR(128) NOT NULL DEFAULT ''").
+		Column("body MEDIUMBLOB NOT NULL").
+		Column("created_at BIGINT NOT NULL").
+		Column("expires_at BIGINT NOT NULL").
+		Index("idx_response_cache_expires", "expires_at")
+}
+
+// DefineChannelHealthChecksTable 定义channel_health_checks表结构（渠道定时健康检查状态，2026-10新增）
+func DefineChannelHealthChecksTable() *TableBuilder {
+	return NewTable("channel_health_checks").
+		Column("channel_id INT PRIMARY KEY").
+		Column("fail_streak INT NOT NULL DEFAULT 0").
+		Column("pass_streak INT NOT NULL DEFAULT 0").
+		Column("last_checked_at BIGINT NOT NULL DEFAULT 0").
+		Column("last_ok TINYINT NOT NULL DEFAULT 0").
+		Column("last_error TEXT NOT NULL").
+		Column("last_latency_ms BIGINT NOT NULL DEFAULT 0").
+		Column("auto_disabled TINYINT NOT NULL DEFAULT 0"). // 1=由健康检查自动禁用（恢复后自动启用）
+		Column("auto_disabled_at BIGINT NOT NULL DEFAULT 0").
+		Column("FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE")
+}
+
+// DefineTestPromptsTable 定义test_prompts表结构（渠道测试提示词库，2026-10新增）
+func DefineTestPromptsTable() *TableBuilder {
+	return NewTable("test_prompts").
+		Column("id INT PRIMARY KEY AUTO_INCREMENT").
+		Column("name VARCHAR(191) NOT NULL UNIQUE").
+		Column("content TEXT NOT NULL").
+		Column("description VARCHAR(191) NOT NULL DEFAULT ''").
+		Column("max_tokens INT NOT NULL DEFAULT 0").
+		Column("is_default TINYINT NOT NULL DEFAULT 0").
+		Column("created_at BIGINT NOT NULL").
+		Column("updated_at BIGINT NOT NULL")
 }
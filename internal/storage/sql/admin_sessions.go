@@ -13,13 +13,13 @@ import (
 
 // CreateAdminSession 创建管理员会话
 // [INFO] 安全修复：存储token的SHA256哈希而非明文(2025-12)
-func (s *SQLStore) CreateAdminSession(ctx context.Context, token string, expiresAt time.Time) error {
+func (s *SQLStore) CreateAdminSession(ctx context.Context, token string, expiresAt time.Time, role string) error {
 	tokenHash := model.HashToken(token)
 	now := timeToUnix(time.Now())
 	_, err := s.db.ExecContext(ctx, `
-		REPLACE INTO admin_sessions (token, expires_at, created_at)
-		VALUES (?, ?, ?)
-	`, tokenHash, timeToUnix(expiresAt), now)
+		REPLACE INTO admin_sessions (token, expires_at, created_at, role)
+		VALUES (?, ?, ?, ?)
+	`, tokenHash, timeToUnix(expiresAt), now, model.NormalizeAdminRole(role))
 	return err
 }
 
@@ -58,25 +58,25 @@ func (s *SQLStore) CleanExpiredSessions(ctx context.Context) error {
 }
 
 // LoadAllSessions 加载所有未过期的会话（启动时调用）
-// [INFO] 安全修复：返回tokenHash→expiry映射(2025-12)
-func (s *SQLStore) LoadAllSessions(ctx context.Context) (map[string]time.Time, error) {
+// [INFO] 安全修复：返回tokenHash→会话映射(2025-12，2026-10增加角色)
+func (s *SQLStore) LoadAllSessions(ctx context.Context) (map[string]model.AdminSession, error) {
 	now := timeToUnix(time.Now())
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT token, expires_at FROM admin_sessions WHERE expires_at > ?
+		SELECT token, expires_at, role FROM admin_sessions WHERE expires_at > ?
 	`, now)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
-	sessions := make(map[string]time.Time)
+	sessions := make(map[string]model.AdminSession)
 	for rows.Next() {
-		var tokenHash string
+		var tokenHash, role string
 		var expiresUnix int64
-		if err := rows.Scan(&tokenHash, &expiresUnix); err != nil {
+		if err := rows.Scan(&tokenHash, &expiresUnix, &role); err != nil {
 			return nil, err
 		}
-		sessions[tokenHash] = unixToTime(expiresUnix)
+		sessions[tokenHash] = model.AdminSession{ExpiresAt: unixToTime(expiresUnix), Role: model.NormalizeAdminRole(role)}
 	}
 
 	return sessions, rows.Err()
@@ -18,7 +18,9 @@ import (
 func (s *SQLStore) GetAPIKeys(ctx context.Context, channelID int64) ([]*model.APIKey, error) {
 	query := `
 		SELECT id, channel_id, key_index, api_key, key_strategy,
-		       cooldown_until, cooldown_duration_ms, created_at, updated_at
+		       cooldown_until, cooldown_duration_ms,
+		       quota_requests_remaining, quota_requests_reset_at, quota_tokens_remaining, quota_tokens_reset_at, quota_updated_at,
+		       account_group, created_at, updated_at
 		FROM api_keys
 		WHERE channel_id = ?
 		ORDER BY key_index ASC
@@ -42,6 +44,12 @@ func (s *SQLStore) GetAPIKeys(ctx context.Context, channelID int64) ([]*model.AP
 			&key.KeyStrategy,
 			&key.CooldownUntil,
 			&key.CooldownDurationMs,
+			&key.QuotaRequestsRemaining,
+			&key.QuotaRequestsResetAt,
+			&key.QuotaTokensRemaining,
+			&key.QuotaTokensResetAt,
+			&key.QuotaUpdatedAt,
+			&key.AccountGroup,
 			&createdAt,
 			&updatedAt,
 		)
@@ -68,7 +76,9 @@ func (s *SQLStore) GetAPIKeys(ctx context.Context, channelID int64) ([]*model.AP
 func (s *SQLStore) GetAPIKey(ctx context.Context, channelID int64, keyIndex int) (*model.APIKey, error) {
 	query := `
 		SELECT id, channel_id, key_index, api_key, key_strategy,
-		       cooldown_until, cooldown_duration_ms, created_at, updated_at
+		       cooldown_until, cooldown_duration_ms,
+		       quota_requests_remaining, quota_requests_reset_at, quota_tokens_remaining, quota_tokens_reset_at, quota_updated_at,
+		       account_group, created_at, updated_at
 		FROM api_keys
 		WHERE channel_id = ? AND key_index = ?
 	`
@@ -85,6 +95,12 @@ func (s *SQLStore) GetAPIKey(ctx context.Context, channelID int64, keyIndex int)
 		&key.KeyStrategy,
 		&key.CooldownUntil,
 		&key.CooldownDurationMs,
+		&key.QuotaRequestsRemaining,
+		&key.QuotaRequestsResetAt,
+		&key.QuotaTokensRemaining,
+		&key.QuotaTokensResetAt,
+		&key.QuotaUpdatedAt,
+		&key.AccountGroup,
 		&createdAt,
 		&updatedAt,
 	)
@@ -107,8 +123,6 @@ func (s *SQLStore) CreateAPIKeysBatch(ctx context.Context, keys []*model.APIKey)
 		return nil
 	}
 
-	nowUnix := timeToUnix(time.Now())
-
 	// 使用事务确保原子性
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -116,6 +130,43 @@ func (s *SQLStore) CreateAPIKeysBatch(ctx context.Context, keys []*model.APIKey)
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	if err := insertAPIKeysTx(ctx, tx, keys); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	// 触发异步Redis同步(确保批量新增操作同步到Redis)
+	s.triggerAsyncSync(syncChannels)
+
+	return nil
+}
+
+// ReplaceAPIKeys 在同一事务中删除渠道全部Key并写入新Key（2026-10新增）
+// 避免删除成功而写入失败时渠道短暂或永久无Key
+func (s *SQLStore) ReplaceAPIKeys(ctx context.Context, channelID int64, keys []*model.APIKey) error {
+	err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM api_keys WHERE channel_id = ?", channelID); err != nil {
+			return fmt.Errorf("delete all api keys: %w", err)
+		}
+		return insertAPIKeysTx(ctx, tx, keys)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 触发异步Redis同步
+	s.triggerAsyncSync(syncChannels)
+
+	return nil
+}
+
+// insertAPIKeysTx 在事务内批量插入 API Keys
+func insertAPIKeysTx(ctx context.Context, tx *sql.Tx, keys []*model.APIKey) error {
+	nowUnix := timeToUnix(time.Now())
+
 	// 构建批量插入语句（每批最多100条，避免SQL语句过长）
 	const batchSize = 100
 	for i := 0; i < len(keys); i += batchSize {
@@ -128,35 +179,27 @@ func (s *SQLStore) CreateAPIKeysBatch(ctx context.Context, keys []*model.APIKey)
 		// 构建 VALUES 部分
 		var sb strings.Builder
 		sb.WriteString(`INSERT INTO api_keys (channel_id, key_index, api_key, key_strategy,
-		                      cooldown_until, cooldown_duration_ms, created_at, updated_at) VALUES `)
+		                      cooldown_until, cooldown_duration_ms, account_group, created_at, updated_at) VALUES `)
 
-		args := make([]any, 0, len(batch)*8)
+		args := make([]any, 0, len(batch)*9)
 		for j, key := range batch {
 			if j > 0 {
 				sb.WriteString(",")
 			}
-			sb.WriteString("(?, ?, ?, ?, ?, ?, ?, ?)")
+			sb.WriteString("(?, ?, ?, ?, ?, ?, ?, ?, ?)")
 
 			strategy := key.KeyStrategy
 			if strategy == "" {
 				strategy = model.KeyStrategySequential
 			}
 			args = append(args, key.ChannelID, key.KeyIndex, key.APIKey, strategy,
-				key.CooldownUntil, key.CooldownDurationMs, nowUnix, nowUnix)
+				key.CooldownUntil, key.CooldownDurationMs, key.AccountGroup, nowUnix, nowUnix)
 		}
 
 		if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
 			return fmt.Errorf("batch insert api keys: %w", err)
 		}
 	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
-	}
-
-	// 触发异步Redis同步(确保批量新增操作同步到Redis)
-	s.triggerAsyncSync(syncChannels)
-
 	return nil
 }
 
@@ -184,6 +227,43 @@ func (s *SQLStore) UpdateAPIKeysStrategy(ctx context.Context, channelID int64, s
 	return nil
 }
 
+// UpdateAPIKeyQuota 更新Key的上游剩余配额快照（2026-10新增）
+// 不触发Redis同步：配额是易变的运行时数据，恢复后由后续响应头重新上报
+func (s *SQLStore) UpdateAPIKeyQuota(ctx context.Context, channelID int64, keyIndex int, quota model.KeyQuota) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET quota_requests_remaining = ?, quota_requests_reset_at = ?,
+		    quota_tokens_remaining = ?, quota_tokens_reset_at = ?, quota_updated_at = ?
+		WHERE channel_id = ? AND key_index = ?
+	`, quota.QuotaRequestsRemaining, quota.QuotaRequestsResetAt,
+		quota.QuotaTokensRemaining, quota.QuotaTokensResetAt, quota.QuotaUpdatedAt,
+		channelID, keyIndex)
+	if err != nil {
+		return fmt.Errorf("update api key quota: %w", err)
+	}
+	return nil
+}
+
+// UpdateAPIKeyAccountGroup 设置Key的上游账号分组（2026-10新增，空字符串表示取消分组）
+func (s *SQLStore) UpdateAPIKeyAccountGroup(ctx context.Context, channelID int64, keyIndex int, group string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET account_group = ?, updated_at = ?
+		WHERE channel_id = ? AND key_index = ?
+	`, group, timeToUnix(time.Now()), channelID, keyIndex)
+	if err != nil {
+		return fmt.Errorf("update api key account group: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("api key not found")
+	}
+
+	// 触发异步Redis同步
+	s.triggerAsyncSync(syncChannels)
+
+	return nil
+}
+
 // DeleteAPIKey 删除指定的 API Key
 func (s *SQLStore) DeleteAPIKey(ctx context.Context, channelID int64, keyIndex int) error {
 	_, err := s.db.ExecContext(ctx, `
@@ -394,7 +474,9 @@ func (s *SQLStore) ImportChannelBatch(ctx context.Context, channels []*model.Cha
 func (s *SQLStore) GetAllAPIKeys(ctx context.Context) (map[int64][]*model.APIKey, error) {
 	query := `
 		SELECT id, channel_id, key_index, api_key, key_strategy,
-		       cooldown_until, cooldown_duration_ms, created_at, updated_at
+		       cooldown_until, cooldown_duration_ms,
+		       quota_requests_remaining, quota_requests_reset_at, quota_tokens_remaining, quota_tokens_reset_at, quota_updated_at,
+		       account_group, created_at, updated_at
 		FROM api_keys
 		ORDER BY channel_id ASC, key_index ASC
 	`
@@ -417,6 +499,12 @@ func (s *SQLStore) GetAllAPIKeys(ctx context.Context) (map[int64][]*model.APIKey
 			&key.KeyStrategy,
 			&key.CooldownUntil,
 			&key.CooldownDurationMs,
+			&key.QuotaRequestsRemaining,
+			&key.QuotaRequestsResetAt,
+			&key.QuotaTokensRemaining,
+			&key.QuotaTokensResetAt,
+			&key.QuotaUpdatedAt,
+			&key.AccountGroup,
 			&createdAt,
 			&updatedAt,
 		)
@@ -159,3 +159,35 @@ func (s *SQLStore) FillAuthTokenRPMStats(ctx context.Context, stats map[int64]*m
 
 	return nil
 }
+
+// GetAuthTokenDailyCosts 按本地自然日聚合单个令牌的请求数与费用（用于用量预测，2026-10新增）
+// tzOffset 为本地时区相对UTC的偏移，Day 为偏移后的日序号；排除499（客户端取消）
+func (s *SQLStore) GetAuthTokenDailyCosts(ctx context.Context, tokenID int64, since, until time.Time, tzOffset time.Duration) ([]model.TokenDailyCost, error) {
+	query := `
+		SELECT
+			FLOOR((time + ?) / ?) AS day_bucket,
+			COUNT(*) AS requests,
+			SUM(COALESCE(cost, 0.0)) AS total_cost
+		FROM logs
+		WHERE auth_token_id = ? AND time >= ? AND time < ? AND status_code != 499
+		GROUP BY day_bucket
+		ORDER BY day_bucket ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, tzOffset.Milliseconds(), dayMs, tokenID, since.UnixMilli(), until.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	costs := make([]model.TokenDailyCost, 0)
+	for rows.Next() {
+		var dc model.TokenDailyCost
+		var day float64 // FLOOR 在 SQLite 返回 REAL、在 MySQL 返回 DECIMAL，统一按浮点扫描
+		if err := rows.Scan(&day, &dc.Requests, &dc.Cost); err != nil {
+			return nil, err
+		}
+		dc.Day = int64(day)
+		costs = append(costs, dc)
+	}
+	return costs, rows.Err()
+}
@@ -17,7 +17,7 @@ const authTokenSelectColumns = `
 	id, token, description, created_at, expires_at, last_used_at, is_active,
 	success_count, failure_count, stream_avg_ttfb, non_stream_avg_rt, stream_count, non_stream_count,
 	prompt_tokens_total, completion_tokens_total, cache_read_tokens_total, cache_creation_tokens_total, total_cost_usd,
-	cost_used_microusd, cost_limit_microusd, allowed_models
+	cost_used_microusd, cost_limit_microusd, allowed_models, client_cert_subject, owner, blocked_models, failover_info, rpm_limit, tpm_limit, fallback_model
 `
 
 func scanAuthToken(scanner interface {
@@ -26,8 +26,8 @@ func scanAuthToken(scanner interface {
 	token := &model.AuthToken{}
 	var createdAtMs int64
 	var expiresAt, lastUsedAt sql.NullInt64
-	var isActive int
-	var allowedModelsJSON string
+	var isActive, failoverInfo int
+	var allowedModelsJSON, blockedModelsJSON string
 	var costUsedMicroUSD int64
 	var costLimitMicroUSD int64
 
@@ -53,6 +53,13 @@ func scanAuthToken(scanner interface {
 		&costUsedMicroUSD,
 		&costLimitMicroUSD,
 		&allowedModelsJSON,
+		&token.ClientCertSubject,
+		&token.Owner,
+		&blockedModelsJSON,
+		&failoverInfo,
+		&token.RPMLimit,
+		&token.TPMLimit,
+		&token.FallbackModel,
 	); err != nil {
 		return nil, err
 	}
@@ -67,6 +74,7 @@ func scanAuthToken(scanner interface {
 		token.LastUsedAt = &v
 	}
 	token.IsActive = isActive != 0
+	token.FailoverInfo = failoverInfo != 0
 	token.CostUsedMicroUSD = costUsedMicroUSD
 	token.CostLimitMicroUSD = costLimitMicroUSD
 
@@ -77,10 +85,27 @@ func scanAuthToken(scanner interface {
 			token.AllowedModels = nil
 		}
 	}
+	if blockedModelsJSON != "" {
+		if err := json.Unmarshal([]byte(blockedModelsJSON), &token.BlockedModels); err != nil {
+			token.BlockedModels = nil
+		}
+	}
 
 	return token, nil
 }
 
+// modelListJSON 序列化模型列表（空列表存空字符串，表示无限制）
+func modelListJSON(models []string) string {
+	if len(models) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(models)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
 // ============================================================================
 // Auth Tokens Management - API访问令牌管理
 // ============================================================================
@@ -101,23 +126,19 @@ func (s *SQLStore) CreateAuthToken(ctx context.Context, token *model.AuthToken)
 		lastUsedAt = *token.LastUsedAt
 	}
 
-	// 序列化 allowed_models 为 JSON
-	var allowedModelsJSON string
-	if len(token.AllowedModels) > 0 {
-		if data, err := json.Marshal(token.AllowedModels); err == nil {
-			allowedModelsJSON = string(data)
-		}
-	}
+	// 序列化 allowed_models / blocked_models 为 JSON
+	allowedModelsJSON := modelListJSON(token.AllowedModels)
+	blockedModelsJSON := modelListJSON(token.BlockedModels)
 
 	result, err := s.db.ExecContext(ctx, `
 			INSERT INTO auth_tokens (
 				token, description, created_at, expires_at, last_used_at, is_active,
 				success_count, failure_count, stream_avg_ttfb, non_stream_avg_rt, stream_count, non_stream_count,
 				prompt_tokens_total, completion_tokens_total, total_cost_usd, allowed_models,
-				cost_used_microusd, cost_limit_microusd
+				cost_used_microusd, cost_limit_microusd, client_cert_subject, owner, blocked_models, failover_info, rpm_limit, tpm_limit, fallback_model
 			)
-			VALUES (?, ?, ?, ?, ?, ?, 0, 0, 0.0, 0.0, 0, 0, 0, 0, 0.0, ?, 0, ?)
-		`, token.Token, token.Description, token.CreatedAt.UnixMilli(), expiresAt, lastUsedAt, boolToInt(token.IsActive), allowedModelsJSON, token.CostLimitMicroUSD)
+			VALUES (?, ?, ?, ?, ?, ?, 0, 0, 0.0, 0.0, 0, 0, 0, 0, 0.0, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, token.Token, token.Description, token.CreatedAt.UnixMilli(), expiresAt, lastUsedAt, boolToInt(token.IsActive), allowedModelsJSON, token.CostLimitMicroUSD, token.ClientCertSubject, token.Owner, blockedModelsJSON, boolToInt(token.FailoverInfo), token.RPMLimit, token.TPMLimit, token.FallbackModel)
 
 	if err != nil {
 		return fmt.Errorf("create auth token: %w", err)
@@ -236,13 +257,9 @@ func (s *SQLStore) UpdateAuthToken(ctx context.Context, token *model.AuthToken)
 		lastUsedAt = *token.LastUsedAt
 	}
 
-	// 序列化 allowed_models 为 JSON
-	var allowedModelsJSON string
-	if len(token.AllowedModels) > 0 {
-		if data, err := json.Marshal(token.AllowedModels); err == nil {
-			allowedModelsJSON = string(data)
-		}
-	}
+	// 序列化 allowed_models / blocked_models 为 JSON
+	allowedModelsJSON := modelListJSON(token.AllowedModels)
+	blockedModelsJSON := modelListJSON(token.BlockedModels)
 
 	result, err := s.db.ExecContext(ctx, `
 		UPDATE auth_tokens
@@ -251,9 +268,16 @@ func (s *SQLStore) UpdateAuthToken(ctx context.Context, token *model.AuthToken)
 		    last_used_at = ?,
 		    is_active = ?,
 		    cost_limit_microusd = ?,
-		    allowed_models = ?
+		    allowed_models = ?,
+		    client_cert_subject = ?,
+		    owner = ?,
+		    blocked_models = ?,
+		    failover_info = ?,
+		    rpm_limit = ?,
+		    tpm_limit = ?,
+		    fallback_model = ?
 		WHERE id = ?
-	`, token.Description, expiresAt, lastUsedAt, boolToInt(token.IsActive), token.CostLimitMicroUSD, allowedModelsJSON, token.ID)
+	`, token.Description, expiresAt, lastUsedAt, boolToInt(token.IsActive), token.CostLimitMicroUSD, allowedModelsJSON, token.ClientCertSubject, token.Owner, blockedModelsJSON, boolToInt(token.FailoverInfo), token.RPMLimit, token.TPMLimit, token.FallbackModel, token.ID)
 
 	if err != nil {
 		return fmt.Errorf("update auth token: %w", err)
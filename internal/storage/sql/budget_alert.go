@@ -0,0 +1,193 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// CreateBudgetAlert 记录预算软告警（2026-10新增）
+// 同一 范围+对象+周期+阈值 已存在时忽略，返回 created=false
+func (s *SQLStore) CreateBudgetAlert(ctx context.Context, a *model.BudgetAlert) (bool, error) {
+	insert := "INSERT OR IGNORE INTO"
+	if !s.IsSQLite() {
+		insert = "INSERT IGNORE INTO"
+	}
+	res, err := s.db.ExecContext(ctx, insert+` budget_alerts
+		(scope, target_id, period, threshold, spent_usd, limit_usd, created_at, acknowledged_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0)`,
+		a.Scope, a.TargetID, a.Period, a.Threshold, a.SpentUSD, a.LimitUSD, a.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("create budget alert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("create budget alert: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		a.ID = id
+	}
+	return true, nil
+}
+
+// ListBudgetAlerts 按时间倒序列出预算告警；unackedOnly=true 时只返回未确认的告警
+func (s *SQLStore) ListBudgetAlerts(ctx context.Context, unackedOnly bool, limit int) ([]model.BudgetAlert, error) {
+	query := `SELECT id, scope, target_id, period, threshold, spent_usd, limit_usd, created_at, acknowledged_at
+		FROM budget_alerts`
+	if unackedOnly {
+		query += " WHERE acknowledged_at = 0"
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list budget alerts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]model.BudgetAlert, 0)
+	for rows.Next() {
+		var a model.BudgetAlert
+		if err := rows.Scan(&a.ID, &a.Scope, &a.TargetID, &a.Period, &a.Threshold,
+			&a.SpentUSD, &a.LimitUSD, &a.CreatedAt, &a.AcknowledgedAt); err != nil {
+			return nil, fmt.Errorf("scan budget alert: %w", err)
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate budget alerts: %w", err)
+	}
+	return result, nil
+}
+
+// AcknowledgeBudgetAlert 确认预算告警；告警不存在或已确认时返回 false
+func (s *SQLStore) AcknowledgeBudgetAlert(ctx context.Context, id int64, at time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE budget_alerts SET acknowledged_at = ? WHERE id = ? AND acknowledged_at = 0",
+		at.Unix(), id)
+	if err != nil {
+		return false, fmt.Errorf("acknowledge budget alert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("acknowledge budget alert: %w", err)
+	}
+	return n > 0, nil
+}
+
+const monthlyBudgetColumns = "id, scope, target_id, limit_usd, created_at, updated_at"
+
+// ListMonthlyBudgets 列出全部月度预算（2026-10新增），按范围、对象ID排序
+func (s *SQLStore) ListMonthlyBudgets(ctx context.Context) ([]*model.MonthlyBudget, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+monthlyBudgetColumns+" FROM monthly_budgets ORDER BY scope ASC, target_id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list monthly budgets: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.MonthlyBudget, 0)
+	for rows.Next() {
+		var b model.MonthlyBudget
+		if err := rows.Scan(&b.ID, &b.Scope, &b.TargetID, &b.LimitUSD, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan monthly budget: %w", err)
+		}
+		result = append(result, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate monthly budgets: %w", err)
+	}
+	return result, nil
+}
+
+// CreateMonthlyBudget 新增月度预算，成功后回填ID
+func (s *SQLStore) CreateMonthlyBudget(ctx context.Context, b *model.MonthlyBudget) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO monthly_budgets
+		(scope, target_id, limit_usd, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		b.Scope, b.TargetID, b.LimitUSD, b.CreatedAt, b.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create monthly budget: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create monthly budget: %w", err)
+	}
+	b.ID = id
+	return nil
+}
+
+// UpdateMonthlyBudget 更新月度预算；预算不存在时返回 false
+func (s *SQLStore) UpdateMonthlyBudget(ctx context.Context, b *model.MonthlyBudget) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE monthly_budgets
+		SET scope = ?, target_id = ?, limit_usd = ?, updated_at = ?
+		WHERE id = ?`,
+		b.Scope, b.TargetID, b.LimitUSD, b.UpdatedAt, b.ID)
+	if err != nil {
+		return false, fmt.Errorf("update monthly budget: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update monthly budget: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// MySQL 对未变更的行返回 affected=0，需再确认预算是否存在
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM monthly_budgets WHERE id = ?", b.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("update monthly budget: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// DeleteMonthlyBudget 删除月度预算；预算不存在时返回 false
+func (s *SQLStore) DeleteMonthlyBudget(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM monthly_budgets WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete monthly budget: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete monthly budget: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetMonthSpend 汇总 monthStart 之后各渠道与各令牌的花费
+// 令牌只计2xx请求（与实时计费口径一致），渠道计全部请求（与每日成本限额口径一致）
+func (s *SQLStore) GetMonthSpend(ctx context.Context, monthStart time.Time) (map[int64]float64, map[int64]float64, error) {
+	since := monthStart.UnixMilli()
+	load := func(query string) (map[int64]float64, error) {
+		rows, err := s.db.QueryContext(ctx, query, since)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rows.Close() }()
+		result := make(map[int64]float64)
+		for rows.Next() {
+			var id int64
+			var total float64
+			if err := rows.Scan(&id, &total); err != nil {
+				return nil, err
+			}
+			result[id] = total
+		}
+		return result, rows.Err()
+	}
+	channels, err := load(`SELECT channel_id, COALESCE(SUM(cost), 0) FROM logs
+		WHERE time >= ? AND channel_id > 0 GROUP BY channel_id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get channel month spend: %w", err)
+	}
+	tokens, err := load(`SELECT auth_token_id, COALESCE(SUM(cost), 0) FROM logs
+		WHERE time >= ? AND auth_token_id > 0 AND status_code BETWEEN 200 AND 299 GROUP BY auth_token_id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get token month spend: %w", err)
+	}
+	return channels, tokens, nil
+}
@@ -0,0 +1,138 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const cacheWarmupColumns = `id, channel_id, model, prompt, interval_seconds, enabled,
+	last_run_at, last_ok, last_error, last_latency_ms, last_cache_read_tokens, last_cache_creation_tokens,
+	runs, failures, cache_read_tokens, cache_creation_tokens, created_at, updated_at`
+
+func scanCacheWarmup(scanner interface {
+	Scan(...any) error
+}) (*model.CacheWarmup, error) {
+	var w model.CacheWarmup
+	var enabled, lastOK int
+	if err := scanner.Scan(&w.ID, &w.ChannelID, &w.Model, &w.Prompt, &w.IntervalSeconds, &enabled,
+		&w.LastRunAt, &lastOK, &w.LastError, &w.LastLatencyMs, &w.LastCacheReadTokens, &w.LastCacheCreationTokens,
+		&w.Runs, &w.Failures, &w.CacheReadTokens, &w.CacheCreationTokens, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Enabled = enabled != 0
+	w.LastOK = lastOK != 0
+	return &w, nil
+}
+
+// ListCacheWarmups 列出全部提示缓存预热计划（2026-10新增），按渠道ID、计划ID升序
+func (s *SQLStore) ListCacheWarmups(ctx context.Context) ([]*model.CacheWarmup, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+cacheWarmupColumns+" FROM cache_warmups ORDER BY channel_id ASC, id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list cache warmups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.CacheWarmup, 0)
+	for rows.Next() {
+		w, err := scanCacheWarmup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan cache warmup: %w", err)
+		}
+		result = append(result, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate cache warmups: %w", err)
+	}
+	return result, nil
+}
+
+// GetCacheWarmup 按ID获取预热计划
+func (s *SQLStore) GetCacheWarmup(ctx context.Context, id int64) (*model.CacheWarmup, error) {
+	w, err := scanCacheWarmup(s.db.QueryRowContext(ctx,
+		"SELECT "+cacheWarmupColumns+" FROM cache_warmups WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("cache warmup not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cache warmup: %w", err)
+	}
+	return w, nil
+}
+
+// CreateCacheWarmup 新增预热计划，成功后回填ID
+func (s *SQLStore) CreateCacheWarmup(ctx context.Context, w *model.CacheWarmup) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO cache_warmups
+		(channel_id, model, prompt, interval_seconds, enabled, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		w.ChannelID, w.Model, w.Prompt, w.IntervalSeconds, boolToInt(w.Enabled), w.LastError, w.CreatedAt, w.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create cache warmup: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create cache warmup: %w", err)
+	}
+	w.ID = id
+	return nil
+}
+
+// UpdateCacheWarmup 更新预热计划（不含运行结果）；计划不存在时返回 false
+func (s *SQLStore) UpdateCacheWarmup(ctx context.Context, w *model.CacheWarmup) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE cache_warmups
+		SET model = ?, prompt = ?, interval_seconds = ?, enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		w.Model, w.Prompt, w.IntervalSeconds, boolToInt(w.Enabled), w.UpdatedAt, w.ID)
+	if err != nil {
+		return false, fmt.Errorf("update cache warmup: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update cache warmup: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// MySQL 对未变更的行返回 affected=0，需再确认计划是否存在
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM cache_warmups WHERE id = ?", w.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("update cache warmup: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// RecordCacheWarmupRun 写入一次运行的结果（Last* 字段），并在数据库侧累加运行/失败次数与缓存token
+func (s *SQLStore) RecordCacheWarmupRun(ctx context.Context, w *model.CacheWarmup) error {
+	failed := 1
+	if w.LastOK {
+		failed = 0
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE cache_warmups
+		SET last_run_at = ?, last_ok = ?, last_error = ?, last_latency_ms = ?,
+			last_cache_read_tokens = ?, last_cache_creation_tokens = ?,
+			runs = runs + 1, failures = failures + ?,
+			cache_read_tokens = cache_read_tokens + ?, cache_creation_tokens = cache_creation_tokens + ?
+		WHERE id = ?`,
+		w.LastRunAt, boolToInt(w.LastOK), w.LastError, w.LastLatencyMs,
+		w.LastCacheReadTokens, w.LastCacheCreationTokens, failed,
+		w.LastCacheReadTokens, w.LastCacheCreationTokens, w.ID); err != nil {
+		return fmt.Errorf("record cache warmup run: %w", err)
+	}
+	return nil
+}
+
+// DeleteCacheWarmup 删除预热计划；计划不存在时返回 false
+func (s *SQLStore) DeleteCacheWarmup(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM cache_warmups WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete cache warmup: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete cache warmup: %w", err)
+	}
+	return n > 0, nil
+}
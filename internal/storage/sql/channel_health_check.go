@@ -0,0 +1,67 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const channelHealthCheckColumns = "channel_id, fail_streak, pass_streak, last_checked_at, last_ok, last_error, last_latency_ms, auto_disabled, auto_disabled_at"
+
+// ListChannelHealthChecks 列出全部渠道健康检查状态（2026-10新增），按渠道ID升序
+func (s *SQLStore) ListChannelHealthChecks(ctx context.Context) ([]*model.ChannelHealthCheck, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+channelHealthCheckColumns+" FROM channel_health_checks ORDER BY channel_id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list channel health checks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.ChannelHealthCheck, 0)
+	for rows.Next() {
+		var h model.ChannelHealthCheck
+		var lastOK, autoDisabled int
+		if err := rows.Scan(&h.ChannelID, &h.FailStreak, &h.PassStreak, &h.LastCheckedAt, &lastOK, &h.LastError,
+			&h.LastLatencyMs, &autoDisabled, &h.AutoDisabledAt); err != nil {
+			return nil, fmt.Errorf("scan channel health check: %w", err)
+		}
+		h.LastOK = lastOK != 0
+		h.AutoDisabled = autoDisabled != 0
+		result = append(result, &h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate channel health checks: %w", err)
+	}
+	return result, nil
+}
+
+// SetChannelHealthCheck 新增或替换渠道的健康检查状态
+func (s *SQLStore) SetChannelHealthCheck(ctx context.Context, h *model.ChannelHealthCheck) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE channel_health_checks
+		SET fail_streak = ?, pass_streak = ?, last_checked_at = ?, last_ok = ?, last_error = ?, last_latency_ms = ?,
+			auto_disabled = ?, auto_disabled_at = ?
+		WHERE channel_id = ?`,
+		h.FailStreak, h.PassStreak, h.LastCheckedAt, boolToInt(h.LastOK), h.LastError, h.LastLatencyMs,
+		boolToInt(h.AutoDisabled), h.AutoDisabledAt, h.ChannelID)
+	if err != nil {
+		return fmt.Errorf("set channel health check: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	// 不存在（或 MySQL 对未变更的行返回 affected=0）时插入；已存在说明内容未变更
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM channel_health_checks WHERE channel_id = ?", h.ChannelID).Scan(&exists); err != nil {
+		return fmt.Errorf("set channel health check: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO channel_health_checks
+		(`+channelHealthCheckColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		h.ChannelID, h.FailStreak, h.PassStreak, h.LastCheckedAt, boolToInt(h.LastOK), h.LastError, h.LastLatencyMs,
+		boolToInt(h.AutoDisabled), h.AutoDisabledAt); err != nil {
+		return fmt.Errorf("set channel health check: %w", err)
+	}
+	return nil
+}
@@ -20,7 +20,7 @@ func (s *SQLStore) ListConfigs(ctx context.Context) ([]*model.Config, error) {
 	// 注意：不再从 channels 表读取 models 和 model_redirects
 	query := `
 			SELECT c.id, c.name, c.url, c.priority, c.channel_type, c.enabled,
-			       c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit,
+			       c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit, c.cost_multiplier, c.client_profile, c.cert_pins, c.local_addr, c.request_compression, c.accept_encoding, c.anthropic_compat, c.openai_compat, c.idempotency_keys, c.weight, c.regions, c.beta_features, c.rewrite_rules, c.model_concurrency,
 			       COUNT(k.id) as key_count,
 			       c.created_at, c.updated_at
 			FROM channels c
@@ -55,7 +55,7 @@ func (s *SQLStore) GetConfig(ctx context.Context, id int64) (*model.Config, erro
 	// 注意：不再从 channels 表读取 models 和 model_redirects
 	query := `
 			SELECT c.id, c.name, c.url, c.priority, c.channel_type, c.enabled,
-			       c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit,
+			       c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit, c.cost_multiplier, c.client_profile, c.cert_pins, c.local_addr, c.request_compression, c.accept_encoding, c.anthropic_compat, c.openai_compat, c.idempotency_keys, c.weight, c.regions, c.beta_features, c.rewrite_rules, c.model_concurrency,
 			       COUNT(k.id) as key_count,
 			       c.created_at, c.updated_at
 			FROM channels c
@@ -95,7 +95,7 @@ func (s *SQLStore) GetEnabledChannelsByModel(ctx context.Context, modelName stri
 		query = `
 	            SELECT c.id, c.name, c.url, c.priority,
 	                   c.channel_type, c.enabled,
-	                   c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit,
+	                   c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit, c.cost_multiplier, c.client_profile, c.cert_pins, c.local_addr, c.request_compression, c.accept_encoding, c.anthropic_compat, c.openai_compat, c.idempotency_keys, c.weight, c.regions, c.beta_features, c.rewrite_rules, c.model_concurrency,
 	                   COUNT(k.id) as key_count,
 	                   c.created_at, c.updated_at
 	            FROM channels c
@@ -108,17 +108,19 @@ func (s *SQLStore) GetEnabledChannelsByModel(ctx context.Context, modelName stri
 		args = []any{nowUnix}
 	} else {
 		// 精确匹配：使用 channel_models 索引表
+		// 同时取出含通配符模型（如 claude-*）的渠道作为候选，加载模型后在内存中过滤
+		// 注意：同一渠道可能命中多行 channel_models，key_count 需 DISTINCT
 		query = `
 	            SELECT c.id, c.name, c.url, c.priority,
 	                   c.channel_type, c.enabled,
-	                   c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit,
-	                   COUNT(k.id) as key_count,
+	                   c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit, c.cost_multiplier, c.client_profile, c.cert_pins, c.local_addr, c.request_compression, c.accept_encoding, c.anthropic_compat, c.openai_compat, c.idempotency_keys, c.weight, c.regions, c.beta_features, c.rewrite_rules, c.model_concurrency,
+	                   COUNT(DISTINCT k.id) as key_count,
 	                   c.created_at, c.updated_at
 	            FROM channels c
 	            INNER JOIN channel_models cm ON c.id = cm.channel_id
 	            LEFT JOIN api_keys k ON c.id = k.channel_id
 	            WHERE c.enabled = 1
-              AND cm.model = ?
+              AND (cm.model = ? OR cm.model LIKE '%*%')
               AND (c.cooldown_until = 0 OR c.cooldown_until <= ?)
             GROUP BY c.id
             ORDER BY c.priority DESC, c.id ASC
@@ -143,6 +145,17 @@ func (s *SQLStore) GetEnabledChannelsByModel(ctx context.Context, modelName stri
 		return nil, err
 	}
 
+	if modelName != "*" {
+		// 过滤通配符候选中实际不匹配的渠道（精确命中的渠道必然通过）
+		matched := configs[:0]
+		for _, cfg := range configs {
+			if cfg.SupportsModel(modelName) {
+				matched = append(matched, cfg)
+			}
+		}
+		configs = matched
+	}
+
 	return configs, nil
 }
 
@@ -153,7 +166,7 @@ func (s *SQLStore) GetEnabledChannelsByType(ctx context.Context, channelType str
 	query := `
 			SELECT c.id, c.name, c.url, c.priority,
 			       c.channel_type, c.enabled,
-			       c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit,
+			       c.cooldown_until, c.cooldown_duration_ms, c.daily_cost_limit, c.cost_multiplier, c.client_profile, c.cert_pins, c.local_addr, c.request_compression, c.accept_encoding, c.anthropic_compat, c.openai_compat, c.idempotency_keys, c.weight, c.regions, c.beta_features, c.rewrite_rules, c.model_concurrency,
 			       COUNT(k.id) as key_count,
 			       c.created_at, c.updated_at
 			FROM channels c
@@ -196,10 +209,10 @@ func (s *SQLStore) CreateConfig(ctx context.Context, c *model.Config) (*model.Co
 	err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
 		// 插入渠道记录
 		res, err := tx.ExecContext(ctx, `
-			INSERT INTO channels(name, url, priority, channel_type, enabled, daily_cost_limit, created_at, updated_at)
-			VALUES(?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO channels(name, url, priority, channel_type, enabled, daily_cost_limit, cost_multiplier, client_profile, cert_pins, local_addr, request_compression, accept_encoding, anthropic_compat, openai_compat, idempotency_keys, weight, regions, beta_features, rewrite_rules, model_concurrency, created_at, updated_at)
+			VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`, c.Name, c.URL, c.Priority, channelType,
-			boolToInt(c.Enabled), c.DailyCostLimit, nowUnix, nowUnix)
+			boolToInt(c.Enabled), c.DailyCostLimit, c.GetCostMultiplier(), c.ClientProfile, c.CertPins, c.LocalAddr, c.RequestCompression, c.AcceptEncoding, boolToInt(c.AnthropicCompat), boolToInt(c.OpenAICompat), boolToInt(c.IdempotencyKeys), c.Weight, c.Regions, c.BetaFeatures, c.RewriteRules, c.ModelConcurrency, nowUnix, nowUnix)
 		if err != nil {
 			return err
 		}
@@ -232,6 +245,66 @@ func (s *SQLStore) CreateConfig(ctx context.Context, c *model.Config) (*model.Co
 	return config, nil
 }
 
+// SetChannelRewriteRules 更新渠道请求体改写规则（JSON，空表示清除；调用方负责校验）
+func (s *SQLStore) SetChannelRewriteRules(ctx context.Context, id int64, rules string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE channels
+		SET rewrite_rules = ?, updated_at = ?
+		WHERE id = ?
+	`, rules, timeToUnix(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("set channel rewrite rules: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("channel not found")
+	}
+
+	// 异步同步渠道配置到Redis（非阻塞，立即返回）
+	s.triggerAsyncSync(syncChannels)
+
+	return nil
+}
+
+// SetChannelModelConcurrency 更新渠道按模型并发上限（JSON，空表示清除；调用方负责校验）
+func (s *SQLStore) SetChannelModelConcurrency(ctx context.Context, id int64, limits string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE channels
+		SET model_concurrency = ?, updated_at = ?
+		WHERE id = ?
+	`, limits, timeToUnix(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("set channel model concurrency: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("channel not found")
+	}
+
+	// 异步同步渠道配置到Redis（非阻塞，立即返回）
+	s.triggerAsyncSync(syncChannels)
+
+	return nil
+}
+
+// SetChannelEnabled 仅更新渠道启用状态（不触碰其他字段，避免与管理端编辑互相覆盖）
+func (s *SQLStore) SetChannelEnabled(ctx context.Context, id int64, enabled bool) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE channels
+		SET enabled = ?, updated_at = ?
+		WHERE id = ?
+	`, boolToInt(enabled), timeToUnix(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("set channel enabled: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("channel not found")
+	}
+
+	// 异步同步渠道配置到Redis（非阻塞，立即返回）
+	s.triggerAsyncSync(syncChannels)
+
+	return nil
+}
+
 // UpdateConfig 更新渠道配置
 func (s *SQLStore) UpdateConfig(ctx context.Context, id int64, upd *model.Config) (*model.Config, error) {
 	if upd == nil {
@@ -254,10 +327,10 @@ func (s *SQLStore) UpdateConfig(ctx context.Context, id int64, upd *model.Config
 		// 更新渠道记录
 		_, err := tx.ExecContext(ctx, `
 			UPDATE channels
-			SET name=?, url=?, priority=?, channel_type=?, enabled=?, daily_cost_limit=?, updated_at=?
+			SET name=?, url=?, priority=?, channel_type=?, enabled=?, daily_cost_limit=?, cost_multiplier=?, client_profile=?, cert_pins=?, local_addr=?, request_compression=?, accept_encoding=?, anthropic_compat=?, openai_compat=?, idempotency_keys=?, weight=?, regions=?, beta_features=?, updated_at=?
 			WHERE id=?
 		`, name, url, upd.Priority, channelType,
-			boolToInt(upd.Enabled), upd.DailyCostLimit, updatedAtUnix, id)
+			boolToInt(upd.Enabled), upd.DailyCostLimit, upd.GetCostMultiplier(), upd.ClientProfile, upd.CertPins, upd.LocalAddr, upd.RequestCompression, upd.AcceptEncoding, boolToInt(upd.AnthropicCompat), boolToInt(upd.OpenAICompat), boolToInt(upd.IdempotencyKeys), upd.Weight, upd.Regions, upd.BetaFeatures, updatedAtUnix, id)
 		if err != nil {
 			return err
 		}
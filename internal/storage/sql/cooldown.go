@@ -244,6 +244,62 @@ func (s *SQLStore) SetKeyCooldown(ctx context.Context, configID int64, keyIndex
 	return err
 }
 
+// SetAccountGroupCooldown 将源Key的冷却同步到同一上游账号分组的其他Key（2026-10新增）
+// 只延长不缩短：组内已冷却到更晚时间的Key保持不变。源Key未分组时为空操作。
+// 返回: 冷却状态发生变化的渠道ID（去重，用于失效缓存）
+func (s *SQLStore) SetAccountGroupCooldown(ctx context.Context, configID int64, keyIndex int, until time.Time) ([]int64, error) {
+	var group string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT account_group FROM api_keys WHERE channel_id = ? AND key_index = ?
+	`, configID, keyIndex).Scan(&group)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query key account group: %w", err)
+	}
+	if group == "" {
+		return nil, nil
+	}
+
+	now := time.Now()
+	untilUnix := timeToUnix(until)
+	// 先查后改：MySQL 不允许在 UPDATE 的子查询中引用目标表
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT channel_id FROM api_keys
+		WHERE account_group = ? AND NOT (channel_id = ? AND key_index = ?) AND cooldown_until < ?
+	`, group, configID, keyIndex, untilUnix)
+	if err != nil {
+		return nil, fmt.Errorf("query account group keys: %w", err)
+	}
+	var channelIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan account group channel: %w", err)
+		}
+		channelIDs = append(channelIDs, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate account group keys: %w", err)
+	}
+	if len(channelIDs) == 0 {
+		return nil, nil
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET cooldown_until = ?, cooldown_duration_ms = ?, updated_at = ?
+		WHERE account_group = ? AND NOT (channel_id = ? AND key_index = ?) AND cooldown_until < ?
+	`, untilUnix, util.CalculateCooldownDuration(until, now), timeToUnix(now), group, configID, keyIndex, untilUnix)
+	if err != nil {
+		return nil, fmt.Errorf("update account group cooldown: %w", err)
+	}
+	return channelIDs, nil
+}
+
 // ResetKeyCooldown 重置指定Key的冷却状态（操作 api_keys 表）
 // 优化：仅更新实际处于冷却中的记录，避免无谓的写入
 func (s *SQLStore) ResetKeyCooldown(ctx context.Context, configID int64, keyIndex int) error {
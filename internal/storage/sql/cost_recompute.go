@@ -0,0 +1,187 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/util"
+)
+
+const costRecomputeJobColumns = `id, since_ms, until_ms, status, cursor_id, total_rows, processed_rows,
+	updated_rows, cost_delta_usd, error, created_at, updated_at`
+
+func scanCostRecomputeJob(scanner interface {
+	Scan(...any) error
+}) (*model.CostRecomputeJob, error) {
+	var j model.CostRecomputeJob
+	if err := scanner.Scan(&j.ID, &j.Since, &j.Until, &j.Status, &j.CursorID, &j.TotalRows, &j.ProcessedRows,
+		&j.UpdatedRows, &j.CostDeltaUSD, &j.Error, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// CreateCostRecomputeJob 创建费用重算任务（2026-10新增），同时统计范围内的日志数作为进度分母
+func (s *SQLStore) CreateCostRecomputeJob(ctx context.Context, job *model.CostRecomputeJob) error {
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM logs WHERE time >= ? AND time < ?", job.Since, job.Until,
+	).Scan(&job.TotalRows); err != nil {
+		return fmt.Errorf("count logs for recompute: %w", err)
+	}
+
+	now := time.Now().Unix()
+	job.CreatedAt, job.UpdatedAt = now, now
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO cost_recompute_jobs (since_ms, until_ms, status, cursor_id, total_rows, processed_rows,
+			updated_rows, cost_delta_usd, error, created_at, updated_at)
+		VALUES (?, ?, ?, 0, ?, 0, 0, 0, '', ?, ?)
+	`, job.Since, job.Until, job.Status, job.TotalRows, now, now)
+	if err != nil {
+		return fmt.Errorf("create cost recompute job: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("get cost recompute job id: %w", err)
+	}
+	job.ID = id
+	return nil
+}
+
+// GetCostRecomputeJob 获取费用重算任务
+func (s *SQLStore) GetCostRecomputeJob(ctx context.Context, id int64) (*model.CostRecomputeJob, error) {
+	job, err := scanCostRecomputeJob(s.db.QueryRowContext(ctx,
+		"SELECT "+costRecomputeJobColumns+" FROM cost_recompute_jobs WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("cost recompute job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cost recompute job: %w", err)
+	}
+	return job, nil
+}
+
+// ListCostRecomputeJobs 按创建时间倒序列出费用重算任务；status 非空时按状态过滤
+func (s *SQLStore) ListCostRecomputeJobs(ctx context.Context, status string, limit int) ([]*model.CostRecomputeJob, error) {
+	query := "SELECT " + costRecomputeJobColumns + " FROM cost_recompute_jobs"
+	args := make([]any, 0, 2)
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list cost recompute jobs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	jobs := make([]*model.CostRecomputeJob, 0)
+	for rows.Next() {
+		job, err := scanCostRecomputeJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan cost recompute job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate cost recompute jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// UpdateCostRecomputeJobStatus 更新费用重算任务状态
+func (s *SQLStore) UpdateCostRecomputeJobStatus(ctx context.Context, id int64, status, errMsg string) error {
+	if _, err := s.db.ExecContext(ctx,
+		"UPDATE cost_recompute_jobs SET status = ?, error = ?, updated_at = ? WHERE id = ?",
+		status, errMsg, time.Now().Unix(), id); err != nil {
+		return fmt.Errorf("update cost recompute job status: %w", err)
+	}
+	return nil
+}
+
+// ListLogCostRows 按ID升序读取 (afterID, ...) 范围内、时间在 [since, until) 的日志费用字段
+func (s *SQLStore) ListLogCostRows(ctx context.Context, since, until, afterID int64, limit int) ([]model.LogCostRow, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, time, model, actual_model, channel_id, auth_token_id, status_code, input_tokens, output_tokens,
+			cache_read_input_tokens, cache_creation_input_tokens, cache_5m_input_tokens, cache_1h_input_tokens, cost
+		FROM logs
+		WHERE id > ? AND time >= ? AND time < ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, since, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list log cost rows: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]model.LogCostRow, 0, limit)
+	for rows.Next() {
+		var r model.LogCostRow
+		if err := rows.Scan(&r.ID, &r.Time, &r.Model, &r.ActualModel, &r.ChannelID, &r.AuthTokenID, &r.StatusCode, &r.InputTokens, &r.OutputTokens,
+			&r.CacheReadTokens, &r.CacheCreationTokens, &r.Cache5mInputTokens, &r.Cache1hInputTokens, &r.Cost); err != nil {
+			return nil, fmt.Errorf("scan log cost row: %w", err)
+		}
+		result = append(result, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate log cost rows: %w", err)
+	}
+	return result, nil
+}
+
+// ApplyCostRecomputeBatch 在同一事务中写入一批日志费用修正、同步令牌累计费用并推进任务游标
+// job 的 CursorID/ProcessedRows/UpdatedRows/CostDeltaUSD 需由调用方预先更新为本批处理后的值
+func (s *SQLStore) ApplyCostRecomputeBatch(ctx context.Context, job *model.CostRecomputeJob, updates []model.LogCostUpdate) error {
+	tokenDeltas := make(map[int64]float64)
+	for _, u := range updates {
+		if u.AuthTokenID > 0 {
+			tokenDeltas[u.AuthTokenID] += u.NewCost - u.OldCost
+		}
+	}
+
+	err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		for _, u := range updates {
+			if _, err := tx.ExecContext(ctx, "UPDATE logs SET cost = ? WHERE id = ?", u.NewCost, u.LogID); err != nil {
+				return fmt.Errorf("update log cost: %w", err)
+			}
+		}
+		for tokenID, delta := range tokenDeltas {
+			// USDToMicroUSD 只接受非负金额，降价时按绝对值换算后取负
+			deltaMicro := util.USDToMicroUSD(delta)
+			if delta < 0 {
+				deltaMicro = -util.USDToMicroUSD(-delta)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE auth_tokens
+				SET total_cost_usd = CASE WHEN total_cost_usd + ? < 0 THEN 0 ELSE total_cost_usd + ? END,
+					cost_used_microusd = CASE WHEN cost_used_microusd + ? < 0 THEN 0 ELSE cost_used_microusd + ? END
+				WHERE id = ?
+			`, delta, delta, deltaMicro, deltaMicro, tokenID); err != nil {
+				return fmt.Errorf("update token cost: %w", err)
+			}
+		}
+		job.UpdatedAt = time.Now().Unix()
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE cost_recompute_jobs
+			SET cursor_id = ?, processed_rows = ?, updated_rows = ?, cost_delta_usd = ?, updated_at = ?
+			WHERE id = ?
+		`, job.CursorID, job.ProcessedRows, job.UpdatedRows, job.CostDeltaUSD, job.UpdatedAt, job.ID); err != nil {
+			return fmt.Errorf("update cost recompute job progress: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(tokenDeltas) > 0 {
+		s.triggerAsyncSync(syncAuthTokens)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// GetErrorClassStats 按渠道+错误归类聚合指定时间范围的错误次数（2026-10新增）
+// 只统计已归类的渠道级日志（汇总日志 channel_id=0 与成功请求不含错误归类）
+func (s *SQLStore) GetErrorClassStats(ctx context.Context, startTime, endTime time.Time, filter *model.LogFilter) ([]model.ErrorClassStats, error) {
+	qb := NewQueryBuilder(`SELECT channel_id, error_class, COUNT(*) AS error_count FROM logs`).
+		Where("time >= ?", startTime.UnixMilli()).
+		Where("time <= ?", endTime.UnixMilli()).
+		Where("channel_id > 0").
+		Where("error_class != ''")
+
+	_, isEmpty, err := s.applyChannelFilter(ctx, qb, filter)
+	if err != nil {
+		return nil, err
+	}
+	if isEmpty {
+		return []model.ErrorClassStats{}, nil
+	}
+	qb.ApplyFilter(filter)
+
+	query, args := qb.BuildWithSuffix("GROUP BY channel_id, error_class ORDER BY channel_id ASC, error_count DESC, error_class ASC")
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := make([]model.ErrorClassStats, 0)
+	channelIDs := make(map[int64]bool)
+	for rows.Next() {
+		var st model.ErrorClassStats
+		if err := rows.Scan(&st.ChannelID, &st.ErrorClass, &st.Count); err != nil {
+			return nil, err
+		}
+		channelIDs[st.ChannelID] = true
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(channelIDs) > 0 {
+		if names, err := s.fetchChannelNamesBatch(ctx, channelIDs); err == nil {
+			for i := range stats {
+				stats[i].ChannelName = names[stats[i].ChannelID]
+			}
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,145 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const geminiProvisionerColumns = `id, channel_id, project_id, credentials, key_count, rotate_interval_hours, enabled,
+	managed_keys, last_run_at, last_error, created_at, updated_at`
+
+func scanGeminiProvisioner(scanner interface {
+	Scan(...any) error
+}) (*model.GeminiKeyProvisioner, error) {
+	var p model.GeminiKeyProvisioner
+	var enabled int
+	var managedJSON string
+	if err := scanner.Scan(&p.ID, &p.ChannelID, &p.ProjectID, &p.Credentials, &p.KeyCount, &p.RotateIntervalHours,
+		&enabled, &managedJSON, &p.LastRunAt, &p.LastError, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.Enabled = enabled != 0
+	p.ManagedKeys = make([]model.GeminiManagedKey, 0)
+	if managedJSON != "" {
+		if err := json.Unmarshal([]byte(managedJSON), &p.ManagedKeys); err != nil {
+			return nil, fmt.Errorf("decode managed keys: %w", err)
+		}
+	}
+	return &p, nil
+}
+
+func encodeManagedKeys(keys []model.GeminiManagedKey) string {
+	if len(keys) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// ListGeminiKeyProvisioners 列出全部Gemini Key供给配置（2026-10新增），按ID升序
+func (s *SQLStore) ListGeminiKeyProvisioners(ctx context.Context) ([]*model.GeminiKeyProvisioner, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+geminiProvisionerColumns+" FROM gemini_key_provisioners ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list gemini key provisioners: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.GeminiKeyProvisioner, 0)
+	for rows.Next() {
+		p, err := scanGeminiProvisioner(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan gemini key provisioner: %w", err)
+		}
+		result = append(result, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate gemini key provisioners: %w", err)
+	}
+	return result, nil
+}
+
+// GetGeminiKeyProvisioner 按ID获取供给配置
+func (s *SQLStore) GetGeminiKeyProvisioner(ctx context.Context, id int64) (*model.GeminiKeyProvisioner, error) {
+	p, err := scanGeminiProvisioner(s.db.QueryRowContext(ctx,
+		"SELECT "+geminiProvisionerColumns+" FROM gemini_key_provisioners WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("gemini key provisioner not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get gemini key provisioner: %w", err)
+	}
+	return p, nil
+}
+
+// CreateGeminiKeyProvisioner 新增供给配置，成功后回填ID
+func (s *SQLStore) CreateGeminiKeyProvisioner(ctx context.Context, p *model.GeminiKeyProvisioner) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO gemini_key_provisioners
+		(channel_id, project_id, credentials, key_count, rotate_interval_hours, enabled, managed_keys, last_run_at, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ChannelID, p.ProjectID, p.Credentials, p.KeyCount, p.RotateIntervalHours, boolToInt(p.Enabled),
+		encodeManagedKeys(p.ManagedKeys), p.LastRunAt, p.LastError, p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create gemini key provisioner: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create gemini key provisioner: %w", err)
+	}
+	p.ID = id
+	return nil
+}
+
+// UpdateGeminiKeyProvisioner 更新供给配置（不含运行状态）；配置不存在时返回 false
+func (s *SQLStore) UpdateGeminiKeyProvisioner(ctx context.Context, p *model.GeminiKeyProvisioner) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE gemini_key_provisioners
+		SET project_id = ?, credentials = ?, key_count = ?, rotate_interval_hours = ?, enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		p.ProjectID, p.Credentials, p.KeyCount, p.RotateIntervalHours, boolToInt(p.Enabled), p.UpdatedAt, p.ID)
+	if err != nil {
+		return false, fmt.Errorf("update gemini key provisioner: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update gemini key provisioner: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// MySQL 对未变更的行返回 affected=0，需再确认配置是否存在
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gemini_key_provisioners WHERE id = ?", p.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("update gemini key provisioner: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// UpdateGeminiKeyProvisionerState 保存一次供给运行的结果（托管Key列表、运行时间、错误信息）
+func (s *SQLStore) UpdateGeminiKeyProvisionerState(ctx context.Context, p *model.GeminiKeyProvisioner) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE gemini_key_provisioners
+		SET managed_keys = ?, last_run_at = ?, last_error = ? WHERE id = ?`,
+		encodeManagedKeys(p.ManagedKeys), p.LastRunAt, p.LastError, p.ID); err != nil {
+		return fmt.Errorf("update gemini key provisioner state: %w", err)
+	}
+	return nil
+}
+
+// DeleteGeminiKeyProvisioner 删除供给配置（不删除Google项目中的Key）；配置不存在时返回 false
+func (s *SQLStore) DeleteGeminiKeyProvisioner(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM gemini_key_provisioners WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete gemini key provisioner: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete gemini key provisioner: %w", err)
+	}
+	return n > 0, nil
+}
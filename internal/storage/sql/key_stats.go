@@ -0,0 +1,52 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+const dayMs int64 = 86_400_000
+
+// GetKeyDailyStats 按 Key × 自然日 聚合指定渠道的请求统计（2026-10新增）
+// tzOffset 为本地时区相对UTC的偏移，用于按本地自然日切分；排除499（客户端取消）与未选Key的汇总日志
+func (s *SQLStore) GetKeyDailyStats(ctx context.Context, channelID int64, since, until time.Time, tzOffset time.Duration) ([]model.KeyDailyStats, error) {
+	offsetMs := tzOffset.Milliseconds()
+	query := `
+		SELECT
+			key_index,
+			api_key_used,
+			FLOOR((time + ?) / ?) AS day_bucket,
+			SUM(CASE WHEN status_code >= 200 AND status_code < 300 THEN 1 ELSE 0 END) AS success_count,
+			SUM(CASE WHEN (status_code < 200 OR status_code >= 300) AND status_code != 499 THEN 1 ELSE 0 END) AS failure_count,
+			SUM(CASE WHEN status_code >= 200 AND status_code < 300 AND duration > 0 THEN duration ELSE 0 END) AS duration_sum,
+			SUM(COALESCE(input_tokens, 0)) AS input_tokens,
+			SUM(COALESCE(output_tokens, 0)) AS output_tokens,
+			SUM(COALESCE(cache_read_input_tokens, 0)) AS cache_read_tokens,
+			SUM(COALESCE(cache_creation_input_tokens, 0)) AS cache_creation_tokens,
+			SUM(COALESCE(cost, 0.0)) AS total_cost
+		FROM logs
+		WHERE channel_id = ? AND time >= ? AND time < ? AND status_code != 499 AND api_key_used != ''
+		GROUP BY key_index, api_key_used, day_bucket
+		ORDER BY day_bucket ASC, key_index ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, offsetMs, dayMs, channelID, since.UnixMilli(), until.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := make([]model.KeyDailyStats, 0)
+	for rows.Next() {
+		var st model.KeyDailyStats
+		var day float64 // FLOOR 在 SQLite 返回 REAL、在 MySQL 返回 DECIMAL，统一按浮点扫描
+		if err := rows.Scan(&st.KeyIndex, &st.APIKeyUsed, &day, &st.SuccessCount, &st.FailureCount, &st.DurationSum,
+			&st.InputTokens, &st.OutputTokens, &st.CacheReadTokens, &st.CacheCreationTokens, &st.TotalCost); err != nil {
+			return nil, err
+		}
+		st.Day = int64(day)
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
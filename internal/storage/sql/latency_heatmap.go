@@ -0,0 +1,60 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// GetHourlyLatency 按UTC整点小时聚合请求延迟（2026-10新增，用于 星期×小时 热力图）
+// 按UTC小时分组后由调用方换算本地时间，夏令时切换也能落入正确的本地小时；排除499（客户端取消）
+func (s *SQLStore) GetHourlyLatency(ctx context.Context, since, until time.Time, filter *model.LogFilter) ([]model.HourlyLatency, error) {
+	baseQuery := `
+		SELECT
+			FLOOR(time / ?) AS hour_bucket,
+			COUNT(*) AS requests,
+			SUM(CASE WHEN status_code < 200 OR status_code >= 300 THEN 1 ELSE 0 END) AS errors,
+			SUM(CASE WHEN status_code >= 200 AND status_code < 300 AND duration > 0 THEN duration ELSE 0 END) AS duration_sum,
+			SUM(CASE WHEN status_code >= 200 AND status_code < 300 AND duration > 0 THEN 1 ELSE 0 END) AS duration_count,
+			MAX(CASE WHEN status_code >= 200 AND status_code < 300 THEN duration ELSE 0 END) AS max_duration,
+			SUM(CASE WHEN status_code >= 200 AND status_code < 300 AND is_streaming = 1 AND first_byte_time > 0 THEN first_byte_time ELSE 0 END) AS ttfb_sum,
+			SUM(CASE WHEN status_code >= 200 AND status_code < 300 AND is_streaming = 1 AND first_byte_time > 0 THEN 1 ELSE 0 END) AS ttfb_count
+		FROM logs`
+
+	qb := NewQueryBuilder(baseQuery).
+		Where("time >= ?", since.UnixMilli()).
+		Where("time < ?", until.UnixMilli()).
+		Where("channel_id > 0").
+		Where("status_code != 499")
+
+	_, isEmpty, err := s.applyChannelFilter(ctx, qb, filter)
+	if err != nil {
+		return nil, err
+	}
+	if isEmpty {
+		return []model.HourlyLatency{}, nil
+	}
+	qb.ApplyFilter(filter)
+
+	query, args := qb.BuildWithSuffix("GROUP BY hour_bucket ORDER BY hour_bucket ASC")
+	args = append([]any{int64(time.Hour / time.Millisecond)}, args...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]model.HourlyLatency, 0)
+	for rows.Next() {
+		var h model.HourlyLatency
+		var hour float64 // FLOOR 在 SQLite 返回 REAL、在 MySQL 返回 DECIMAL，统一按浮点扫描
+		if err := rows.Scan(&hour, &h.Requests, &h.Errors, &h.DurationSum, &h.DurationCount,
+			&h.MaxDuration, &h.TTFBSum, &h.TTFBCount); err != nil {
+			return nil, err
+		}
+		h.Hour = int64(hour)
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
@@ -27,7 +27,7 @@ func scanLogEntry(scanner interface {
 	var cost sql.NullFloat64
 
 	if err := scanner.Scan(&e.ID, &timeMs, &e.Model, &actualModel, &e.ChannelID,
-		&e.StatusCode, &e.Message, &duration, &isStreamingInt, &firstByteTime, &apiKeyUsed, &e.AuthTokenID, &clientIP,
+		&e.StatusCode, &e.Message, &duration, &isStreamingInt, &firstByteTime, &apiKeyUsed, &e.KeyIndex, &e.AuthTokenID, &clientIP, &e.ErrorClass, &e.Labels,
 		&inputTokens, &outputTokens, &cacheReadTokens, &cacheCreationTokens, &cache5mTokens, &cache1hTokens, &cost); err != nil {
 		return nil, err
 	}
@@ -118,16 +118,24 @@ func (s *SQLStore) AddLog(ctx context.Context, e *model.LogEntry) error {
 
 	// 直接写入日志数据库（简化预编译语句缓存）
 	query := `
-		INSERT INTO logs(time, minute_bucket, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, auth_token_id, client_ip,
+		INSERT INTO logs(time, minute_bucket, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, key_index, auth_token_id, client_ip, error_class, labels,
 			input_tokens, output_tokens, cache_read_input_tokens, cache_creation_input_tokens, cache_5m_input_tokens, cache_1h_input_tokens, cost)
-		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query, timeMs, minuteBucket, e.Model, e.ActualModel, e.ChannelID, e.StatusCode, e.Message, e.Duration, e.IsStreaming, e.FirstByteTime, maskedKey, e.AuthTokenID, e.ClientIP,
+	_, err := s.db.ExecContext(ctx, query, timeMs, minuteBucket, e.Model, e.ActualModel, e.ChannelID, e.StatusCode, e.Message, e.Duration, e.IsStreaming, e.FirstByteTime, maskedKey, storedKeyIndex(e), e.AuthTokenID, e.ClientIP, e.ErrorClass, e.Labels,
 		e.InputTokens, e.OutputTokens, e.CacheReadInputTokens, e.CacheCreationInputTokens, e.Cache5mInputTokens, e.Cache1hInputTokens, e.Cost)
 	return err
 }
 
+// storedKeyIndex 写入logs.key_index的值：未选Key的汇总日志记为-1（避免与Key#0混淆）
+func storedKeyIndex(e *model.LogEntry) int {
+	if e.APIKeyUsed == "" || e.KeyIndex < 0 {
+		return -1
+	}
+	return e.KeyIndex
+}
+
 // BatchAddLogs 批量写入日志（单事务+预编译语句，提升刷盘性能）
 // OCP：作为扩展方法提供，调用方可通过类型断言优先使用
 func (s *SQLStore) BatchAddLogs(ctx context.Context, logs []*model.LogEntry) error {
@@ -142,9 +150,9 @@ func (s *SQLStore) BatchAddLogs(ctx context.Context, logs []*model.LogEntry) err
 	defer func() { _ = tx.Rollback() }()
 
 	stmt, err := tx.PrepareContext(ctx, `
-        INSERT INTO logs(time, minute_bucket, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, auth_token_id, client_ip,
+        INSERT INTO logs(time, minute_bucket, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, key_index, auth_token_id, client_ip, error_class, labels,
 			input_tokens, output_tokens, cache_read_input_tokens, cache_creation_input_tokens, cache_5m_input_tokens, cache_1h_input_tokens, cost)
-        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `)
 	if err != nil {
 		return err
@@ -177,8 +185,11 @@ func (s *SQLStore) BatchAddLogs(ctx context.Context, logs []*model.LogEntry) err
 			e.IsStreaming,
 			e.FirstByteTime,
 			maskedKey,
+			storedKeyIndex(e),
 			e.AuthTokenID,
 			e.ClientIP,
+			e.ErrorClass,
+			e.Labels,
 			e.InputTokens,
 			e.OutputTokens,
 			e.CacheReadInputTokens,
@@ -199,7 +210,7 @@ func (s *SQLStore) ListLogs(ctx context.Context, since time.Time, limit, offset
 	// 使用查询构建器构建复杂查询
 	// 消除 N+1：渠道过滤/名称解析用一次批量查询完成
 	baseQuery := `
-			SELECT id, time, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, auth_token_id, client_ip,
+			SELECT id, time, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, key_index, auth_token_id, client_ip, error_class, labels,
 				input_tokens, output_tokens, cache_read_input_tokens, cache_creation_input_tokens, cache_5m_input_tokens, cache_1h_input_tokens, cost
 			FROM logs`
 
@@ -280,7 +291,7 @@ func (s *SQLStore) CountLogs(ctx context.Context, since time.Time, filter *model
 // ListLogsRange 查询指定时间范围内的日志（支持精确日期范围如"昨日"）
 func (s *SQLStore) ListLogsRange(ctx context.Context, since, until time.Time, limit, offset int, filter *model.LogFilter) ([]*model.LogEntry, error) {
 	baseQuery := `
-		SELECT id, time, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, auth_token_id, client_ip,
+		SELECT id, time, model, actual_model, channel_id, status_code, message, duration, is_streaming, first_byte_time, api_key_used, key_index, auth_token_id, client_ip, error_class, labels,
 			input_tokens, output_tokens, cache_read_input_tokens, cache_creation_input_tokens, cache_5m_input_tokens, cache_1h_input_tokens, cost
 		FROM logs`
 
@@ -78,6 +78,12 @@ func (s *SQLStore) AggregateRangeWithFilter(ctx context.Context, since, until ti
 			query += " AND logs.auth_token_id = ?"
 			args = append(args, *filter.AuthTokenID)
 		}
+
+		// 添加令牌归属方过滤
+		if filter.Owner != "" {
+			query += " AND logs.auth_token_id IN (SELECT id FROM auth_tokens WHERE owner = ?)"
+			args = append(args, filter.Owner)
+		}
 	}
 
 	query += `
@@ -0,0 +1,88 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const modelAliasColumns = "id, alias, target, description, enabled, created_at, updated_at"
+
+// ListModelAliases 列出全部模型别名（2026-10新增），按别名升序
+func (s *SQLStore) ListModelAliases(ctx context.Context) ([]*model.ModelAlias, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+modelAliasColumns+" FROM model_aliases ORDER BY alias ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list model aliases: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.ModelAlias, 0)
+	for rows.Next() {
+		var a model.ModelAlias
+		var enabled int
+		if err := rows.Scan(&a.ID, &a.Alias, &a.Target, &a.Description, &enabled, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan model alias: %w", err)
+		}
+		a.Enabled = enabled != 0
+		result = append(result, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate model aliases: %w", err)
+	}
+	return result, nil
+}
+
+// CreateModelAlias 新增模型别名，成功后回填ID
+func (s *SQLStore) CreateModelAlias(ctx context.Context, a *model.ModelAlias) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO model_aliases
+		(alias, target, description, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		a.Alias, a.Target, a.Description, boolToInt(a.Enabled), a.CreatedAt, a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create model alias: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create model alias: %w", err)
+	}
+	a.ID = id
+	return nil
+}
+
+// UpdateModelAlias 更新模型别名；别名不存在时返回 false
+func (s *SQLStore) UpdateModelAlias(ctx context.Context, a *model.ModelAlias) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE model_aliases
+		SET alias = ?, target = ?, description = ?, enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		a.Alias, a.Target, a.Description, boolToInt(a.Enabled), a.UpdatedAt, a.ID)
+	if err != nil {
+		return false, fmt.Errorf("update model alias: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update model alias: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// MySQL 对未变更的行返回 affected=0，需再确认别名是否存在
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM model_aliases WHERE id = ?", a.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("update model alias: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// DeleteModelAlias 删除模型别名；别名不存在时返回 false
+func (s *SQLStore) DeleteModelAlias(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM model_aliases WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete model alias: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete model alias: %w", err)
+	}
+	return n > 0, nil
+}
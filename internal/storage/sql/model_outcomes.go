@@ -0,0 +1,36 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// GetChannelModelOutcomes 按 请求模型 × 实际模型 × 状态码 聚合指定渠道的成功与 400/404 日志（2026-10新增）
+// 用于从"请求模型被拒、相近模型成功"的模式中生成模型重定向建议
+func (s *SQLStore) GetChannelModelOutcomes(ctx context.Context, channelID int64, since time.Time) ([]model.ModelOutcome, error) {
+	query := `
+		SELECT model, actual_model, status_code, COUNT(*), MAX(time), MAX(message)
+		FROM logs
+		WHERE channel_id = ? AND time >= ? AND model != ''
+			AND ((status_code >= 200 AND status_code < 300) OR status_code IN (400, 404))
+		GROUP BY model, actual_model, status_code
+		ORDER BY model ASC, actual_model ASC, status_code ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, channelID, since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	outcomes := make([]model.ModelOutcome, 0)
+	for rows.Next() {
+		var o model.ModelOutcome
+		if err := rows.Scan(&o.Model, &o.ActualModel, &o.StatusCode, &o.Count, &o.LastAt, &o.SampleMessage); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
@@ -0,0 +1,90 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const modelPriceColumns = "id, model, input_price, output_price, cache_read_price, cache_write_price, cache_write_1h_price, effective_from, description, created_at, updated_at"
+
+// ListModelPrices 列出全部价格表条目（2026-10新增），按模型升序、生效时间降序
+func (s *SQLStore) ListModelPrices(ctx context.Context) ([]*model.ModelPrice, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+modelPriceColumns+" FROM model_prices ORDER BY model ASC, effective_from DESC")
+	if err != nil {
+		return nil, fmt.Errorf("list model prices: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.ModelPrice, 0)
+	for rows.Next() {
+		var p model.ModelPrice
+		if err := rows.Scan(&p.ID, &p.Model, &p.InputPrice, &p.OutputPrice, &p.CacheReadPrice, &p.CacheWritePrice,
+			&p.CacheWrite1hPrice, &p.EffectiveFrom, &p.Description, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan model price: %w", err)
+		}
+		result = append(result, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate model prices: %w", err)
+	}
+	return result, nil
+}
+
+// CreateModelPrice 新增价格表条目，成功后回填ID
+func (s *SQLStore) CreateModelPrice(ctx context.Context, p *model.ModelPrice) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO model_prices
+		(model, input_price, output_price, cache_read_price, cache_write_price, cache_write_1h_price, effective_from, description, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Model, p.InputPrice, p.OutputPrice, p.CacheReadPrice, p.CacheWritePrice, p.CacheWrite1hPrice,
+		p.EffectiveFrom, p.Description, p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create model price: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create model price: %w", err)
+	}
+	p.ID = id
+	return nil
+}
+
+// UpdateModelPrice 更新价格表条目；条目不存在时返回 false
+func (s *SQLStore) UpdateModelPrice(ctx context.Context, p *model.ModelPrice) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE model_prices
+		SET model = ?, input_price = ?, output_price = ?, cache_read_price = ?, cache_write_price = ?, cache_write_1h_price = ?,
+			effective_from = ?, description = ?, updated_at = ?
+		WHERE id = ?`,
+		p.Model, p.InputPrice, p.OutputPrice, p.CacheReadPrice, p.CacheWritePrice, p.CacheWrite1hPrice,
+		p.EffectiveFrom, p.Description, p.UpdatedAt, p.ID)
+	if err != nil {
+		return false, fmt.Errorf("update model price: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update model price: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// MySQL 对未变更的行返回 affected=0，需再确认条目是否存在
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM model_prices WHERE id = ?", p.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("update model price: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// DeleteModelPrice 删除价格表条目；条目不存在时返回 false
+func (s *SQLStore) DeleteModelPrice(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM model_prices WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete model price: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete model price: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,74 @@
+package sql
+
+import (
+	"context"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// GetOwnerStatsInRange 按令牌归属方聚合指定时间范围的统计（2026-10新增）
+// byModel=true 时按 owner+model 分组；只统计使用令牌的请求，未分配归属方的令牌归入 owner=""
+// 与 GetAuthTokenStatsInRange 一致排除499（客户端取消）
+func (s *SQLStore) GetOwnerStatsInRange(ctx context.Context, startTime, endTime time.Time, filter *model.LogFilter, byModel bool) ([]model.OwnerStats, error) {
+	modelColumn := "''"
+	suffix := "GROUP BY owner_key ORDER BY total_cost DESC, owner_key ASC"
+	if byModel {
+		modelColumn = "COALESCE(logs.model, '')"
+		suffix = "GROUP BY owner_key, model_key ORDER BY owner_key ASC, total_cost DESC, model_key ASC"
+	}
+
+	// modelColumn 为固定字面量，不含用户输入
+	// 别名避开 owner/model 列名：GROUP BY 遇到同名列时 MySQL 优先取列而非别名（NULL 与 '' 会被分成两组）
+	baseQuery := `
+		SELECT
+			COALESCE(auth_tokens.owner, '') AS owner_key,
+			` + modelColumn + ` AS model_key,
+			COUNT(DISTINCT logs.auth_token_id) AS token_count,
+			SUM(CASE WHEN logs.status_code >= 200 AND logs.status_code < 300 THEN 1 ELSE 0 END) AS success_count,
+			SUM(CASE WHEN (logs.status_code < 200 OR logs.status_code >= 300) AND logs.status_code != 499 THEN 1 ELSE 0 END) AS failure_count,
+			SUM(COALESCE(logs.input_tokens, 0)) AS prompt_tokens,
+			SUM(COALESCE(logs.output_tokens, 0)) AS completion_tokens,
+			SUM(COALESCE(logs.cache_read_input_tokens, 0)) AS cache_read_tokens,
+			SUM(COALESCE(logs.cache_creation_input_tokens, 0)) AS cache_creation_tokens,
+			SUM(COALESCE(logs.cost, 0.0)) AS total_cost
+		FROM logs
+		LEFT JOIN auth_tokens ON auth_tokens.id = logs.auth_token_id`
+
+	qb := NewQueryBuilder(baseQuery).
+		Where("logs.time >= ?", startTime.UnixMilli()).
+		Where("logs.time <= ?", endTime.UnixMilli()).
+		Where("logs.auth_token_id > 0").
+		Where("logs.status_code != 499")
+
+	// 应用渠道类型或名称过滤
+	_, isEmpty, err := s.applyChannelFilter(ctx, qb, filter)
+	if err != nil {
+		return nil, err
+	}
+	if isEmpty {
+		return []model.OwnerStats{}, nil
+	}
+
+	// 应用其余过滤器（模型/状态码/令牌/归属方；logs 与 auth_tokens 无同名过滤列）
+	qb.ApplyFilter(filter)
+
+	query, args := qb.BuildWithSuffix(suffix)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := make([]model.OwnerStats, 0)
+	for rows.Next() {
+		var st model.OwnerStats
+		if err := rows.Scan(&st.Owner, &st.Model, &st.TokenCount, &st.SuccessCount, &st.FailureCount,
+			&st.PromptTokens, &st.CompletionTokens, &st.CacheReadTokens, &st.CacheCreationTokens,
+			&st.TotalCost); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
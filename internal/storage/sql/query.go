@@ -74,6 +74,18 @@ func (wb *WhereBuilder) ApplyLogFilter(filter *model.LogFilter) *WhereBuilder {
 	if filter.AuthTokenID != nil {
 		wb.AddCondition("auth_token_id = ?", *filter.AuthTokenID)
 	}
+	if filter.Owner != "" {
+		// logs 与 auth_tokens 同库，子查询即可；owner 有索引
+		wb.AddCondition("auth_token_id IN (SELECT id FROM auth_tokens WHERE owner = ?)", filter.Owner)
+	}
+	if filter.ErrorClass != "" {
+		wb.AddCondition("error_class = ?", filter.ErrorClass)
+	}
+	if filter.Label != "" {
+		// labels 为逗号分隔列表，按完整标签匹配（标签字符集由调用方校验）
+		wb.AddCondition("(labels = ? OR labels LIKE ? OR labels LIKE ? OR labels LIKE ?)",
+			filter.Label, filter.Label+",%", "%,"+filter.Label, "%,"+filter.Label+",%")
+	}
 	return wb
 }
 
@@ -107,19 +119,22 @@ func (cs *ConfigScanner) ScanConfig(scanner interface {
 	Scan(...any) error
 }) (*model.Config, error) {
 	var c model.Config
-	var enabledInt int
+	var enabledInt, anthropicCompatInt, openaiCompatInt, idempotencyKeysInt int
 	var createdAtRaw, updatedAtRaw any // 使用any接受任意类型（兼容字符串、整数或RFC3339）
 
 	// 扫描key_count字段（从JOIN查询获取）
 	// 注意：不再包含 models 和 model_redirects 字段
 	if err := scanner.Scan(&c.ID, &c.Name, &c.URL, &c.Priority,
 		&c.ChannelType, &enabledInt,
-		&c.CooldownUntil, &c.CooldownDurationMs, &c.DailyCostLimit, &c.KeyCount,
+		&c.CooldownUntil, &c.CooldownDurationMs, &c.DailyCostLimit, &c.CostMultiplier, &c.ClientProfile, &c.CertPins, &c.LocalAddr, &c.RequestCompression, &c.AcceptEncoding, &anthropicCompatInt, &openaiCompatInt, &idempotencyKeysInt, &c.Weight, &c.Regions, &c.BetaFeatures, &c.RewriteRules, &c.ModelConcurrency, &c.KeyCount,
 		&createdAtRaw, &updatedAtRaw); err != nil {
 		return nil, err
 	}
 
 	c.Enabled = enabledInt != 0
+	c.AnthropicCompat = anthropicCompatInt != 0
+	c.OpenAICompat = openaiCompatInt != 0
+	c.IdempotencyKeys = idempotencyKeysInt != 0
 
 	// 转换时间戳（支持不同数据库）
 	now := time.Now()
@@ -95,10 +95,10 @@ func (s *SQLStore) LoadChannelsFromRedis(ctx context.Context) error {
 					for _, key := range cwk.APIKeys {
 						_, err := tx.ExecContext(ctx, `
 						INSERT INTO api_keys (channel_id, key_index, api_key, key_strategy,
-						                      cooldown_until, cooldown_duration_ms, created_at, updated_at)
-						VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+						                      cooldown_until, cooldown_duration_ms, account_group, created_at, updated_at)
+						VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 					`, channelID, key.KeyIndex, key.APIKey, key.KeyStrategy,
-							key.CooldownUntil, key.CooldownDurationMs, nowUnix, nowUnix)
+							key.CooldownUntil, key.CooldownDurationMs, key.AccountGroup, nowUnix, nowUnix)
 
 						if err != nil {
 							log.Printf("Warning: failed to restore API key %d for channel %d: %v", key.KeyIndex, channelID, err)
@@ -0,0 +1,75 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const responseCacheColumns = "cache_key, model, content_type, body, created_at, expires_at"
+
+// ListResponseCacheEntries 列出未过期的响应缓存条目（2026-10新增），按创建时间倒序，最多 limit 条
+func (s *SQLStore) ListResponseCacheEntries(ctx context.Context, now int64, limit int) ([]*model.ResponseCacheEntry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+responseCacheColumns+" FROM response_cache WHERE expires_at > ? ORDER BY created_at DESC LIMIT ?", now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list response cache: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.ResponseCacheEntry, 0)
+	for rows.Next() {
+		var e model.ResponseCacheEntry
+		if err := rows.Scan(&e.Key, &e.Model, &e.ContentType, &e.Body, &e.CreatedAt, &e.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan response cache: %w", err)
+		}
+		result = append(result, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate response cache: %w", err)
+	}
+	return result, nil
+}
+
+// PutResponseCacheEntry 按Key新增或替换响应缓存条目
+func (s *SQLStore) PutResponseCacheEntry(ctx context.Context, e *model.ResponseCacheEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("put response cache: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM response_cache WHERE cache_key = ?", e.Key); err != nil {
+		return fmt.Errorf("put response cache: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO response_cache ("+responseCacheColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		e.Key, e.Model, e.ContentType, e.Body, e.CreatedAt, e.ExpiresAt); err != nil {
+		return fmt.Errorf("put response cache: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteResponseCacheEntries 删除指定模型的响应缓存（modelName 为空时清空全部），返回删除条数
+func (s *SQLStore) DeleteResponseCacheEntries(ctx context.Context, modelName string) (int64, error) {
+	query, args := "DELETE FROM response_cache", []any{}
+	if modelName != "" {
+		query, args = query+" WHERE model = ?", append(args, modelName)
+	}
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete response cache: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// PurgeExpiredResponseCache 删除已过期的响应缓存条目，返回删除条数
+func (s *SQLStore) PurgeExpiredResponseCache(ctx context.Context, now int64) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM response_cache WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("purge response cache: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,82 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// GetResponseLabelStats 按渠道汇总指定时间范围内成功请求的响应标签命中次数与占比（2026-10新增）
+// 按 (channel_id, labels) 分组后在内存中拆分逗号分隔的标签，分组数受标签组合数限制
+func (s *SQLStore) GetResponseLabelStats(ctx context.Context, startTime, endTime time.Time, filter *model.LogFilter) ([]model.ResponseLabelStats, error) {
+	qb := NewQueryBuilder(`SELECT channel_id, labels, COUNT(*) AS cnt FROM logs`).
+		Where("time >= ?", startTime.UnixMilli()).
+		Where("time <= ?", endTime.UnixMilli()).
+		Where("channel_id > 0").
+		Where("status_code >= 200").
+		Where("status_code < 300")
+
+	_, isEmpty, err := s.applyChannelFilter(ctx, qb, filter)
+	if err != nil {
+		return nil, err
+	}
+	if isEmpty {
+		return []model.ResponseLabelStats{}, nil
+	}
+	qb.ApplyFilter(filter)
+
+	query, args := qb.BuildWithSuffix("GROUP BY channel_id, labels ORDER BY channel_id ASC")
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := make([]model.ResponseLabelStats, 0)
+	index := make(map[int64]int)
+	channelIDs := make(map[int64]bool)
+	for rows.Next() {
+		var channelID, count int64
+		var labels string
+		if err := rows.Scan(&channelID, &labels, &count); err != nil {
+			return nil, err
+		}
+		i, ok := index[channelID]
+		if !ok {
+			i = len(stats)
+			index[channelID] = i
+			channelIDs[channelID] = true
+			stats = append(stats, model.ResponseLabelStats{
+				ChannelID: channelID,
+				Labels:    map[string]int64{},
+				Rates:     map[string]float64{},
+			})
+		}
+		st := &stats[i]
+		st.Total += count
+		for _, label := range strings.Split(labels, ",") {
+			if label != "" {
+				st.Labels[label] += count
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		for label, n := range stats[i].Labels {
+			stats[i].Rates[label] = float64(n) / float64(stats[i].Total)
+		}
+	}
+	if len(channelIDs) > 0 {
+		if names, err := s.fetchChannelNamesBatch(ctx, channelIDs); err == nil {
+			for i := range stats {
+				stats[i].ChannelName = names[stats[i].ChannelID]
+			}
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const routingScheduleColumns = "id, channel_id, description, cron, timezone, priority_delta, weight_multiplier, enabled, created_at, updated_at"
+
+// ListRoutingSchedules 列出全部分时路由规则（2026-10新增），按渠道ID、规则ID升序
+func (s *SQLStore) ListRoutingSchedules(ctx context.Context) ([]*model.RoutingSchedule, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+routingScheduleColumns+" FROM routing_schedules ORDER BY channel_id ASC, id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list routing schedules: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.RoutingSchedule, 0)
+	for rows.Next() {
+		var r model.RoutingSchedule
+		var enabled int
+		if err := rows.Scan(&r.ID, &r.ChannelID, &r.Description, &r.Cron, &r.Timezone,
+			&r.PriorityDelta, &r.WeightMultiplier, &enabled, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan routing schedule: %w", err)
+		}
+		r.Enabled = enabled != 0
+		result = append(result, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate routing schedules: %w", err)
+	}
+	return result, nil
+}
+
+// CreateRoutingSchedule 新增分时路由规则，成功后回填ID
+func (s *SQLStore) CreateRoutingSchedule(ctx context.Context, r *model.RoutingSchedule) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO routing_schedules
+		(channel_id, description, cron, timezone, priority_delta, weight_multiplier, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ChannelID, r.Description, r.Cron, r.Timezone, r.PriorityDelta, r.WeightMultiplier,
+		boolToInt(r.Enabled), r.CreatedAt, r.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create routing schedule: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create routing schedule: %w", err)
+	}
+	r.ID = id
+	return nil
+}
+
+// UpdateRoutingSchedule 更新分时路由规则；规则不存在时返回 false
+func (s *SQLStore) UpdateRoutingSchedule(ctx context.Context, r *model.RoutingSchedule) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE routing_schedules
+		SET channel_id = ?, description = ?, cron = ?, timezone = ?, priority_delta = ?, weight_multiplier = ?, enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		r.ChannelID, r.Description, r.Cron, r.Timezone, r.PriorityDelta, r.WeightMultiplier,
+		boolToInt(r.Enabled), r.UpdatedAt, r.ID)
+	if err != nil {
+		return false, fmt.Errorf("update routing schedule: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update routing schedule: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// MySQL 对未变更的行返回 affected=0，需再确认规则是否存在
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM routing_schedules WHERE id = ?", r.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("update routing schedule: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// DeleteRoutingSchedule 删除分时路由规则；规则不存在时返回 false
+func (s *SQLStore) DeleteRoutingSchedule(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM routing_schedules WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete routing schedule: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete routing schedule: %w", err)
+	}
+	return n > 0, nil
+}
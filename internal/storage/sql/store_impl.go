@@ -3,6 +3,7 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,6 +26,7 @@ const (
 // 支持渠道配置和Auth Tokens的双向同步
 type RedisSync interface {
 	IsEnabled() bool
+	Ping(ctx context.Context) error
 	LoadChannelsWithKeysFromRedis(ctx context.Context) ([]*model.ChannelWithKeys, error)
 	SyncAllChannelsWithKeys(ctx context.Context, channels []*model.ChannelWithKeys) error
 	// Auth Tokens同步
@@ -93,6 +95,14 @@ func (s *SQLStore) IsRedisEnabled() bool {
 	return s.redisSync != nil && s.redisSync.IsEnabled()
 }
 
+// PingRedis 检查Redis连接是否可用（未启用Redis时返回nil，2026-10新增）
+func (s *SQLStore) PingRedis(ctx context.Context) error {
+	if !s.IsRedisEnabled() {
+		return nil
+	}
+	return s.redisSync.Ping(ctx)
+}
+
 // IsSQLite 检查是否为SQLite驱动
 func (s *SQLStore) IsSQLite() bool {
 	return s.driverName == "sqlite"
@@ -123,29 +133,133 @@ func (s *SQLStore) Close() error {
 
 // CleanupLogsBefore 清理指定时间之前的日志
 func (s *SQLStore) CleanupLogsBefore(ctx context.Context, cutoff time.Time) error {
-	// time 字段是 BIGINT 毫秒时间戳
 	// 分批删除避免长时间锁表（P2优化）
-	cutoffMs := cutoff.UnixMilli()
 	const batchSize = 5000
 
 	for {
-		var query string
-		if s.IsSQLite() {
-			// SQLite: 使用子查询实现分批删除（默认不支持 DELETE LIMIT）
-			query = `DELETE FROM logs WHERE id IN (SELECT id FROM logs WHERE time < ? LIMIT ?)`
-		} else {
-			// MySQL: 直接使用 LIMIT
-			query = `DELETE FROM logs WHERE time < ? LIMIT ?`
-		}
-
-		result, err := s.db.ExecContext(ctx, query, cutoffMs, batchSize)
+		affected, err := s.DeleteLogsBefore(ctx, cutoff, batchSize)
 		if err != nil {
 			return err
 		}
-		affected, _ := result.RowsAffected()
 		if affected < batchSize {
 			break // 已删完
 		}
 	}
 	return nil
 }
+
+// DeleteLogsBefore 删除一批指定时间之前的日志，返回删除条数（小于 limit 表示已删完）
+func (s *SQLStore) DeleteLogsBefore(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	// time 字段是 BIGINT 毫秒时间戳
+	var query string
+	if s.IsSQLite() {
+		// SQLite: 使用子查询实现分批删除（默认不支持 DELETE LIMIT）
+		query = `DELETE FROM logs WHERE id IN (SELECT id FROM logs WHERE time < ? LIMIT ?)`
+	} else {
+		// MySQL: 直接使用 LIMIT
+		query = `DELETE FROM logs WHERE time < ? LIMIT ?`
+	}
+	result, err := s.db.ExecContext(ctx, query, cutoff.UnixMilli(), limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// OptimizeLogStorage 更新查询计划统计信息；vacuum=true 时回收已删除日志占用的空间
+// SQLite VACUUM 会重写整个数据库文件，期间阻塞写入，只应低频调度
+func (s *SQLStore) OptimizeLogStorage(ctx context.Context, vacuum bool) error {
+	if s.IsSQLite() {
+		if _, err := s.db.ExecContext(ctx, "ANALYZE"); err != nil {
+			return fmt.Errorf("analyze: %w", err)
+		}
+		if vacuum {
+			if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+				return fmt.Errorf("vacuum: %w", err)
+			}
+		}
+		return nil
+	}
+	// MySQL: ANALYZE/OPTIMIZE TABLE 返回结果集，需读取并关闭
+	stmt := "ANALYZE TABLE logs"
+	if vacuum {
+		stmt = "OPTIMIZE TABLE logs" // InnoDB 下等价于重建表 + ANALYZE
+	}
+	rows, err := s.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", stmt, err)
+	}
+	return rows.Close()
+}
+
+// DatabaseFile SQLite 主库文件路径（2026-10新增，用于备份/恢复）；MySQL 或内存库返回空字符串
+func (s *SQLStore) DatabaseFile(ctx context.Context) (string, error) {
+	if !s.IsSQLite() {
+		return "", nil
+	}
+	rows, err := s.db.QueryContext(ctx, "PRAGMA database_list")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var seq int
+		var name, file string
+		if err := rows.Scan(&seq, &name, &file); err != nil {
+			return "", err
+		}
+		if name == "main" {
+			return file, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// CheckIntegrity 数据库完整性检查（2026-10新增），返回发现的问题（空表示正常）
+// SQLite 使用 PRAGMA quick_check（跳过索引内容与表数据的交叉校验，耗时远小于 integrity_check）；
+// MySQL 的数据文件由服务端自身保证，不检查
+func (s *SQLStore) CheckIntegrity(ctx context.Context) ([]string, error) {
+	if !s.IsSQLite() {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, "PRAGMA quick_check")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// BackupTo 将 SQLite 数据库在线备份到指定文件（2026-10新增，VACUUM INTO 生成一致性快照，目标文件必须不存在）
+func (s *SQLStore) BackupTo(ctx context.Context, path string) error {
+	if !s.IsSQLite() {
+		return fmt.Errorf("backup is only supported for SQLite")
+	}
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("vacuum into: %w", err)
+	}
+	return nil
+}
+
+// CheckpointWAL 执行一次 PASSIVE 模式的 WAL checkpoint（2026-10新增，快照复制的触发点）
+// PASSIVE 不等待读事务、不截断 WAL，与同时运行的 Litestream 等外部复制工具兼容；非 WAL 模式下为空操作
+func (s *SQLStore) CheckpointWAL(ctx context.Context) error {
+	if !s.IsSQLite() {
+		return nil
+	}
+	var busy, walFrames, checkpointed int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &walFrames, &checkpointed); err != nil {
+		return fmt.Errorf("wal checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const testPromptColumns = "id, name, content, description, max_tokens, is_default, created_at, updated_at"
+
+// ListTestPrompts 列出全部渠道测试提示词（2026-10新增），默认提示词在前，其余按名称升序
+func (s *SQLStore) ListTestPrompts(ctx context.Context) ([]*model.TestPrompt, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+testPromptColumns+" FROM test_prompts ORDER BY is_default DESC, name ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list test prompts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.TestPrompt, 0)
+	for rows.Next() {
+		var p model.TestPrompt
+		var isDefault int
+		if err := rows.Scan(&p.ID, &p.Name, &p.Content, &p.Description, &p.MaxTokens, &isDefault, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan test prompt: %w", err)
+		}
+		p.IsDefault = isDefault != 0
+		result = append(result, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate test prompts: %w", err)
+	}
+	return result, nil
+}
+
+// CreateTestPrompt 新增测试提示词，成功后回填ID；设为默认时同时取消其他提示词的默认标记
+func (s *SQLStore) CreateTestPrompt(ctx context.Context, p *model.TestPrompt) error {
+	return s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `INSERT INTO test_prompts
+			(name, content, description, max_tokens, is_default, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			p.Name, p.Content, p.Description, p.MaxTokens, boolToInt(p.IsDefault), p.CreatedAt, p.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("create test prompt: %w", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("create test prompt: %w", err)
+		}
+		p.ID = id
+		return clearOtherDefaultTestPrompts(ctx, tx, p)
+	})
+}
+
+// UpdateTestPrompt 更新测试提示词；提示词不存在时返回 false
+func (s *SQLStore) UpdateTestPrompt(ctx context.Context, p *model.TestPrompt) (bool, error) {
+	var found bool
+	err := s.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// MySQL 对未变更的行返回 affected=0，先确认提示词是否存在
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM test_prompts WHERE id = ?", p.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("update test prompt: %w", err)
+		}
+		if found = exists > 0; !found {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE test_prompts
+			SET name = ?, content = ?, description = ?, max_tokens = ?, is_default = ?, updated_at = ?
+			WHERE id = ?`,
+			p.Name, p.Content, p.Description, p.MaxTokens, boolToInt(p.IsDefault), p.UpdatedAt, p.ID); err != nil {
+			return fmt.Errorf("update test prompt: %w", err)
+		}
+		return clearOtherDefaultTestPrompts(ctx, tx, p)
+	})
+	return found, err
+}
+
+// DeleteTestPrompt 删除测试提示词；提示词不存在时返回 false
+func (s *SQLStore) DeleteTestPrompt(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM test_prompts WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete test prompt: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete test prompt: %w", err)
+	}
+	return n > 0, nil
+}
+
+// clearOtherDefaultTestPrompts 保证最多一条默认提示词
+func clearOtherDefaultTestPrompts(ctx context.Context, tx *sql.Tx, p *model.TestPrompt) error {
+	if !p.IsDefault {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE test_prompts SET is_default = 0 WHERE id <> ? AND is_default = 1", p.ID); err != nil {
+		return fmt.Errorf("clear default test prompt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ccLoad/internal/model"
+)
+
+// ListTokenAnomalies 查询输出Token异常请求（2026-10新增）
+// 基线：[BaselineSince, Since) 内每个 (auth_token_id, model) 的成功请求平均输出Token；
+// 异常：[Since, Until) 内输出Token同时超过绝对下限与 基线×倍数 的成功请求，按输出Token降序
+func (s *SQLStore) ListTokenAnomalies(ctx context.Context, q model.TokenAnomalyQuery) ([]model.TokenAnomaly, error) {
+	query := `
+		SELECT l.id, l.time, l.auth_token_id, l.model, l.channel_id, l.output_tokens, l.cost, b.avg_output
+		FROM logs l
+		JOIN (
+			SELECT auth_token_id, model, AVG(output_tokens) AS avg_output
+			FROM logs
+			WHERE time >= ? AND time < ? AND status_code >= 200 AND status_code < 300 AND output_tokens > 0
+			GROUP BY auth_token_id, model
+			HAVING COUNT(*) >= ?
+		) b ON b.auth_token_id = l.auth_token_id AND b.model = l.model
+		WHERE l.time >= ? AND l.time < ?
+		  AND l.status_code >= 200 AND l.status_code < 300
+		  AND l.output_tokens >= ?
+		  AND l.output_tokens > b.avg_output * ?
+		ORDER BY l.output_tokens DESC
+		LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query,
+		q.BaselineSince.UnixMilli(), q.Since.UnixMilli(), q.MinSamples,
+		q.Since.UnixMilli(), q.Until.UnixMilli(), q.MinOutputTokens, q.Multiplier, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query token anomalies: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]model.TokenAnomaly, 0)
+	for rows.Next() {
+		var a model.TokenAnomaly
+		var timeMs int64
+		if err := rows.Scan(&a.LogID, &timeMs, &a.AuthTokenID, &a.Model, &a.ChannelID,
+			&a.OutputTokens, &a.Cost, &a.BaselineOutput); err != nil {
+			return nil, fmt.Errorf("scan token anomaly: %w", err)
+		}
+		a.Time = model.JSONTime{Time: time.UnixMilli(timeMs)}
+		if a.BaselineOutput > 0 {
+			a.Ratio = float64(a.OutputTokens) / a.BaselineOutput
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
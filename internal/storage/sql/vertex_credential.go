@@ -0,0 +1,71 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+// ListVertexCredentials 列出全部 Vertex AI 渠道凭据（2026-10新增），按渠道ID升序
+func (s *SQLStore) ListVertexCredentials(ctx context.Context) ([]*model.VertexCredential, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT channel_id, project_id, location, credentials, created_at, updated_at
+		FROM vertex_credentials ORDER BY channel_id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list vertex credentials: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.VertexCredential, 0)
+	for rows.Next() {
+		var v model.VertexCredential
+		if err := rows.Scan(&v.ChannelID, &v.ProjectID, &v.Location, &v.Credentials, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan vertex credential: %w", err)
+		}
+		result = append(result, &v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate vertex credentials: %w", err)
+	}
+	return result, nil
+}
+
+// SetVertexCredential 新增或替换渠道的 Vertex AI 凭据（保留原创建时间）
+func (s *SQLStore) SetVertexCredential(ctx context.Context, v *model.VertexCredential) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE vertex_credentials
+		SET project_id = ?, location = ?, credentials = ?, updated_at = ? WHERE channel_id = ?`,
+		v.ProjectID, v.Location, v.Credentials, v.UpdatedAt, v.ChannelID)
+	if err != nil {
+		return fmt.Errorf("set vertex credential: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	// 不存在（或 MySQL 对未变更的行返回 affected=0）时插入；已存在说明内容未变更
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM vertex_credentials WHERE channel_id = ?", v.ChannelID).Scan(&exists); err != nil {
+		return fmt.Errorf("set vertex credential: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO vertex_credentials
+		(channel_id, project_id, location, credentials, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		v.ChannelID, v.ProjectID, v.Location, v.Credentials, v.CreatedAt, v.UpdatedAt); err != nil {
+		return fmt.Errorf("set vertex credential: %w", err)
+	}
+	return nil
+}
+
+// DeleteVertexCredential 删除渠道的 Vertex AI 凭据；不存在时返回 false
+func (s *SQLStore) DeleteVertexCredential(ctx context.Context, channelID int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM vertex_credentials WHERE channel_id = ?", channelID)
+	if err != nil {
+		return false, fmt.Errorf("delete vertex credential: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete vertex credential: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,115 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"ccLoad/internal/model"
+)
+
+const webhookColumns = "id, name, url, secret, events, enabled, created_at, updated_at"
+
+func scanWebhook(scanner interface {
+	Scan(...any) error
+}) (*model.Webhook, error) {
+	var w model.Webhook
+	var enabled int
+	if err := scanner.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, &w.Events, &enabled, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Enabled = enabled != 0
+	w.HasSecret = w.Secret != ""
+	return &w, nil
+}
+
+// ListWebhooks 列出全部Webhook端点（2026-10新增），按ID升序
+func (s *SQLStore) ListWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := make([]*model.Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		result = append(result, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate webhooks: %w", err)
+	}
+	return result, nil
+}
+
+// GetWebhook 按ID获取Webhook端点
+func (s *SQLStore) GetWebhook(ctx context.Context, id int64) (*model.Webhook, error) {
+	w, err := scanWebhook(s.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook: %w", err)
+	}
+	return w, nil
+}
+
+// CreateWebhook 新增Webhook端点，成功后回填ID
+func (s *SQLStore) CreateWebhook(ctx context.Context, w *model.Webhook) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO webhooks
+		(name, url, secret, events, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		w.Name, w.URL, w.Secret, w.Events, boolToInt(w.Enabled), w.CreatedAt, w.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+	w.ID = id
+	w.HasSecret = w.Secret != ""
+	return nil
+}
+
+// UpdateWebhook 更新Webhook端点；端点不存在时返回 false
+func (s *SQLStore) UpdateWebhook(ctx context.Context, w *model.Webhook) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE webhooks
+		SET name = ?, url = ?, secret = ?, events = ?, enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		w.Name, w.URL, w.Secret, w.Events, boolToInt(w.Enabled), w.UpdatedAt, w.ID)
+	if err != nil {
+		return false, fmt.Errorf("update webhook: %w", err)
+	}
+	w.HasSecret = w.Secret != ""
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update webhook: %w", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// MySQL 对未变更的行返回 affected=0，需再确认端点是否存在
+	var exists int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhooks WHERE id = ?", w.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("update webhook: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// DeleteWebhook 删除Webhook端点；端点不存在时返回 false
+func (s *SQLStore) DeleteWebhook(ctx context.Context, id int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("delete webhook: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete webhook: %w", err)
+	}
+	return n > 0, nil
+}
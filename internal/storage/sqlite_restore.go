@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// SQLite 备份校验与恢复（2026-10新增）
+// ============================================================================
+// 运行中的进程持有数据库连接，不能直接替换数据库文件。恢复分两步：
+//  1. StageSQLiteRestore 校验备份后复制为 {db}.restore（待恢复文件）
+//  2. 下次启动打开数据库前 applyPendingSQLiteRestore 将当前库（连同 -wal/-shm）改名为
+//     {db}.pre-restore-{时间戳} 保留现场，再把待恢复文件换入
+// 换入失败时保留待恢复文件并继续使用当前库启动，不会导致服务无法启动。
+
+// PendingRestoreSuffix 待恢复文件后缀
+const PendingRestoreSuffix = ".restore"
+
+// sqliteSidecarSuffixes WAL 模式下随主库一起移动的附属文件
+var sqliteSidecarSuffixes = []string{"-wal", "-shm"}
+
+// VerifySQLiteFile 以只读方式打开 SQLite 文件并执行 quick_check，同时确认包含渠道与令牌表
+func VerifySQLiteFile(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	rows, err := db.QueryContext(ctx, "PRAGMA quick_check")
+	if err != nil {
+		return fmt.Errorf("quick_check: %w", err)
+	}
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+
+	for _, table := range []string{"channels", "auth_tokens"} {
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("missing table %s", table)
+		}
+	}
+	return nil
+}
+
+// StageSQLiteRestore 校验备份文件并复制为 dbPath 的待恢复文件（重启后生效）
+func StageSQLiteRestore(ctx context.Context, dbPath, backupPath string) error {
+	if err := VerifySQLiteFile(ctx, backupPath); err != nil {
+		return fmt.Errorf("backup verification failed: %w", err)
+	}
+	target := dbPath + PendingRestoreSuffix
+	tmp := target + ".tmp"
+	if err := copyFile(backupPath, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// PendingSQLiteRestore 是否存在待恢复文件
+func PendingSQLiteRestore(dbPath string) bool {
+	_, err := os.Stat(dbPath + PendingRestoreSuffix)
+	return err == nil
+}
+
+// CancelSQLiteRestore 删除待恢复文件（不存在时不报错）
+func CancelSQLiteRestore(dbPath string) error {
+	if err := os.Remove(dbPath + PendingRestoreSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// applyPendingSQLiteRestore 打开数据库前换入待恢复文件
+func applyPendingSQLiteRestore(dbPath string) {
+	pending := dbPath + PendingRestoreSuffix
+	if _, err := os.Stat(pending); err != nil {
+		return
+	}
+	if err := VerifySQLiteFile(context.Background(), pending); err != nil {
+		log.Printf("[ERROR] [数据库恢复] 待恢复文件 %s 校验失败，继续使用当前数据库: %v", pending, err)
+		return
+	}
+
+	aside := fmt.Sprintf("%s.pre-restore-%s", dbPath, time.Now().Format("20060102-150405"))
+	if _, err := os.Stat(dbPath); err == nil {
+		if err := os.Rename(dbPath, aside); err != nil {
+			log.Printf("[ERROR] [数据库恢复] 移走当前数据库失败，继续使用当前数据库: %v", err)
+			return
+		}
+		for _, suffix := range sqliteSidecarSuffixes {
+			if err := os.Rename(dbPath+suffix, aside+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("[WARN] [数据库恢复] 移走 %s 失败: %v", dbPath+suffix, err)
+			}
+		}
+	}
+	if err := os.Rename(pending, dbPath); err != nil {
+		log.Printf("[ERROR] [数据库恢复] 换入备份失败: %v（原数据库保留在 %s）", err, aside)
+		_ = os.Rename(aside, dbPath) // 尽量回滚，避免以空库启动
+		return
+	}
+	log.Printf("[WARN] [数据库恢复] 已从备份恢复数据库，原数据库保留在 %s", aside)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src) //nolint:gosec // G304: 路径由备份模块生成并校验
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) //nolint:gosec // G304: 同上
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -20,6 +20,9 @@ type Store interface {
 	GetConfig(ctx context.Context, id int64) (*model.Config, error)
 	CreateConfig(ctx context.Context, c *model.Config) (*model.Config, error)
 	UpdateConfig(ctx context.Context, id int64, upd *model.Config) (*model.Config, error)
+	SetChannelRewriteRules(ctx context.Context, id int64, rules string) error      // 请求体改写规则（UpdateConfig 不修改该字段）
+	SetChannelModelConcurrency(ctx context.Context, id int64, limits string) error // 按模型并发上限（UpdateConfig 不修改该字段）
+	SetChannelEnabled(ctx context.Context, id int64, enabled bool) error           // 仅切换启用状态（健康检查自动禁用/启用）
 	DeleteConfig(ctx context.Context, id int64) error
 	GetEnabledChannelsByModel(ctx context.Context, modelName string) ([]*model.Config, error)
 	GetEnabledChannelsByType(ctx context.Context, channelType string) ([]*model.Config, error)
@@ -34,9 +37,12 @@ type Store interface {
 	GetAllAPIKeys(ctx context.Context) (map[int64][]*model.APIKey, error)
 	CreateAPIKeysBatch(ctx context.Context, keys []*model.APIKey) error
 	UpdateAPIKeysStrategy(ctx context.Context, channelID int64, strategy string) error
+	UpdateAPIKeyQuota(ctx context.Context, channelID int64, keyIndex int, quota model.KeyQuota) error
+	UpdateAPIKeyAccountGroup(ctx context.Context, channelID int64, keyIndex int, group string) error // 上游账号分组（2026-10新增）
 	DeleteAPIKey(ctx context.Context, channelID int64, keyIndex int) error
 	CompactKeyIndices(ctx context.Context, channelID int64, removedIndex int) error
 	DeleteAllAPIKeys(ctx context.Context, channelID int64) error
+	ReplaceAPIKeys(ctx context.Context, channelID int64, keys []*model.APIKey) error // 单事务替换渠道全部Key（2026-10新增）
 
 	// === Cooldown Management ===
 	// Channel-level cooldown
@@ -49,6 +55,7 @@ type Store interface {
 	BumpKeyCooldown(ctx context.Context, channelID int64, keyIndex int, now time.Time, statusCode int) (time.Duration, error)
 	ResetKeyCooldown(ctx context.Context, channelID int64, keyIndex int) error
 	SetKeyCooldown(ctx context.Context, channelID int64, keyIndex int, until time.Time) error
+	SetAccountGroupCooldown(ctx context.Context, channelID int64, keyIndex int, until time.Time) ([]int64, error) // 同步冷却到同账号分组的Key（2026-10新增）
 
 	// === Log Management ===
 	AddLog(ctx context.Context, e *model.LogEntry) error
@@ -58,6 +65,14 @@ type Store interface {
 	CountLogs(ctx context.Context, since time.Time, filter *model.LogFilter) (int, error)
 	CountLogsRange(ctx context.Context, since, until time.Time, filter *model.LogFilter) (int, error)
 	CleanupLogsBefore(ctx context.Context, cutoff time.Time) error
+	DeleteLogsBefore(ctx context.Context, cutoff time.Time, limit int) (int64, error) // 单批删除（限速清理，2026-10新增）
+	OptimizeLogStorage(ctx context.Context, vacuum bool) error                        // ANALYZE，vacuum=true 时回收空间
+
+	// === Database Maintenance（2026-10新增）===
+	DatabaseFile(ctx context.Context) (string, error)     // SQLite 主库文件路径（MySQL/内存库为空）
+	CheckIntegrity(ctx context.Context) ([]string, error) // 完整性检查，返回问题列表（空=正常）
+	BackupTo(ctx context.Context, path string) error      // 在线备份到指定文件（仅SQLite）
+	CheckpointWAL(ctx context.Context) error              // PASSIVE 模式 WAL checkpoint（快照复制的触发点，仅SQLite）
 
 	// === Metrics & Statistics ===
 	AggregateRangeWithFilter(ctx context.Context, since, until time.Time, bucket time.Duration, filter *model.LogFilter) ([]model.MetricPoint, error)
@@ -67,7 +82,91 @@ type Store interface {
 	GetRPMStats(ctx context.Context, startTime, endTime time.Time, filter *model.LogFilter, isToday bool) (*model.RPMStats, error)
 	GetChannelSuccessRates(ctx context.Context, since time.Time) (map[int64]model.ChannelHealthStats, error)
 	GetHealthTimeline(ctx context.Context, query string, args ...any) (*sql.Rows, error)
-	GetTodayChannelCosts(ctx context.Context, todayStart time.Time) (map[int64]float64, error) // 获取今日各渠道成本（启动时加载）
+	GetTodayChannelCosts(ctx context.Context, todayStart time.Time) (map[int64]float64, error)                                            // 获取今日各渠道成本（启动时加载）
+	ListTokenAnomalies(ctx context.Context, q model.TokenAnomalyQuery) ([]model.TokenAnomaly, error)                                      // 输出Token异常请求（基线对比）
+	GetKeyDailyStats(ctx context.Context, channelID int64, since, until time.Time, tzOffset time.Duration) ([]model.KeyDailyStats, error) // Key×自然日统计（Key对比报表）
+	GetChannelModelOutcomes(ctx context.Context, channelID int64, since time.Time) ([]model.ModelOutcome, error)                          // 渠道 请求模型×实际模型×状态码 统计（重定向建议）
+	GetHourlyLatency(ctx context.Context, since, until time.Time, filter *model.LogFilter) ([]model.HourlyLatency, error)                 // UTC整点小时延迟聚合（延迟热力图）
+
+	// === Budget Alerts ===
+	CreateBudgetAlert(ctx context.Context, a *model.BudgetAlert) (created bool, err error)
+	ListBudgetAlerts(ctx context.Context, unackedOnly bool, limit int) ([]model.BudgetAlert, error)
+	AcknowledgeBudgetAlert(ctx context.Context, id int64, at time.Time) (bool, error)
+	ListMonthlyBudgets(ctx context.Context) ([]*model.MonthlyBudget, error)
+	CreateMonthlyBudget(ctx context.Context, b *model.MonthlyBudget) error
+	UpdateMonthlyBudget(ctx context.Context, b *model.MonthlyBudget) (bool, error)
+	DeleteMonthlyBudget(ctx context.Context, id int64) (bool, error)
+	GetMonthSpend(ctx context.Context, monthStart time.Time) (channels, tokens map[int64]float64, err error) // 本月各渠道/令牌花费（启动时加载）
+
+	// === Cost Recompute ===
+	CreateCostRecomputeJob(ctx context.Context, job *model.CostRecomputeJob) error
+	GetCostRecomputeJob(ctx context.Context, id int64) (*model.CostRecomputeJob, error)
+	ListCostRecomputeJobs(ctx context.Context, status string, limit int) ([]*model.CostRecomputeJob, error)
+	UpdateCostRecomputeJobStatus(ctx context.Context, id int64, status, errMsg string) error
+	ListLogCostRows(ctx context.Context, since, until, afterID int64, limit int) ([]model.LogCostRow, error)
+	ApplyCostRecomputeBatch(ctx context.Context, job *model.CostRecomputeJob, updates []model.LogCostUpdate) error
+
+	// === Routing Schedules ===
+	ListRoutingSchedules(ctx context.Context) ([]*model.RoutingSchedule, error)
+	CreateRoutingSchedule(ctx context.Context, r *model.RoutingSchedule) error
+	UpdateRoutingSchedule(ctx context.Context, r *model.RoutingSchedule) (bool, error)
+	DeleteRoutingSchedule(ctx context.Context, id int64) (bool, error)
+
+	// === Model Aliases ===
+	ListModelAliases(ctx context.Context) ([]*model.ModelAlias, error)
+	CreateModelAlias(ctx context.Context, a *model.ModelAlias) error
+	UpdateModelAlias(ctx context.Context, a *model.ModelAlias) (bool, error)
+	DeleteModelAlias(ctx context.Context, id int64) (bool, error)
+
+	// === Model Prices ===
+	ListModelPrices(ctx context.Context) ([]*model.ModelPrice, error)
+	CreateModelPrice(ctx context.Context, p *model.ModelPrice) error
+	UpdateModelPrice(ctx context.Context, p *model.ModelPrice) (bool, error)
+	DeleteModelPrice(ctx context.Context, id int64) (bool, error)
+
+	// === Test Prompts ===
+	ListTestPrompts(ctx context.Context) ([]*model.TestPrompt, error)
+	CreateTestPrompt(ctx context.Context, p *model.TestPrompt) error
+	UpdateTestPrompt(ctx context.Context, p *model.TestPrompt) (bool, error)
+	DeleteTestPrompt(ctx context.Context, id int64) (bool, error)
+
+	// === Gemini Key Provisioners ===
+	ListGeminiKeyProvisioners(ctx context.Context) ([]*model.GeminiKeyProvisioner, error)
+	GetGeminiKeyProvisioner(ctx context.Context, id int64) (*model.GeminiKeyProvisioner, error)
+	CreateGeminiKeyProvisioner(ctx context.Context, p *model.GeminiKeyProvisioner) error
+	UpdateGeminiKeyProvisioner(ctx context.Context, p *model.GeminiKeyProvisioner) (bool, error)
+	UpdateGeminiKeyProvisionerState(ctx context.Context, p *model.GeminiKeyProvisioner) error
+	DeleteGeminiKeyProvisioner(ctx context.Context, id int64) (bool, error)
+
+	// === Vertex AI Credentials ===
+	ListVertexCredentials(ctx context.Context) ([]*model.VertexCredential, error)
+	SetVertexCredential(ctx context.Context, v *model.VertexCredential) error // 按渠道新增或替换
+	DeleteVertexCredential(ctx context.Context, channelID int64) (bool, error)
+
+	// === Channel Health Checks ===
+	ListChannelHealthChecks(ctx context.Context) ([]*model.ChannelHealthCheck, error)
+	SetChannelHealthCheck(ctx context.Context, h *model.ChannelHealthCheck) error // 按渠道新增或替换
+
+	// === Response Cache ===
+	ListResponseCacheEntries(ctx context.Context, now int64, limit int) ([]*model.ResponseCacheEntry, error) // 未过期条目，按创建时间倒序
+	PutResponseCacheEntry(ctx context.Context, e *model.ResponseCacheEntry) error                            // 按Key新增或替换
+	DeleteResponseCacheEntries(ctx context.Context, modelName string) (int64, error)                         // modelName 为空时清空全部
+	PurgeExpiredResponseCache(ctx context.Context, now int64) (int64, error)
+
+	// === Cache Warmups ===
+	ListCacheWarmups(ctx context.Context) ([]*model.CacheWarmup, error)
+	GetCacheWarmup(ctx context.Context, id int64) (*model.CacheWarmup, error)
+	CreateCacheWarmup(ctx context.Context, w *model.CacheWarmup) error
+	UpdateCacheWarmup(ctx context.Context, w *model.CacheWarmup) (bool, error) // 仅更新配置字段
+	RecordCacheWarmupRun(ctx context.Context, w *model.CacheWarmup) error      // 写入 Last* 运行结果并累加计数
+	DeleteCacheWarmup(ctx context.Context, id int64) (bool, error)
+
+	// === Webhooks ===
+	ListWebhooks(ctx context.Context) ([]*model.Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (*model.Webhook, error)
+	CreateWebhook(ctx context.Context, w *model.Webhook) error
+	UpdateWebhook(ctx context.Context, w *model.Webhook) (bool, error)
+	DeleteWebhook(ctx context.Context, id int64) (bool, error)
 
 	// === Auth Token Management ===
 	CreateAuthToken(ctx context.Context, token *model.AuthToken) error
@@ -80,7 +179,11 @@ type Store interface {
 	UpdateTokenLastUsed(ctx context.Context, tokenHash string, now time.Time) error
 	UpdateTokenStats(ctx context.Context, tokenHash string, isSuccess bool, duration float64, isStreaming bool, firstByteTime float64, promptTokens int64, completionTokens int64, cacheReadTokens int64, cacheCreationTokens int64, costUSD float64) error
 	GetAuthTokenStatsInRange(ctx context.Context, startTime, endTime time.Time) (map[int64]*model.AuthTokenRangeStats, error)
+	GetAuthTokenDailyCosts(ctx context.Context, tokenID int64, since, until time.Time, tzOffset time.Duration) ([]model.TokenDailyCost, error) // 令牌×自然日费用（用量预测，2026-10新增）
 	FillAuthTokenRPMStats(ctx context.Context, stats map[int64]*model.AuthTokenRangeStats, startTime, endTime time.Time, isToday bool) error
+	GetOwnerStatsInRange(ctx context.Context, startTime, endTime time.Time, filter *model.LogFilter, byModel bool) ([]model.OwnerStats, error)
+	GetErrorClassStats(ctx context.Context, startTime, endTime time.Time, filter *model.LogFilter) ([]model.ErrorClassStats, error)       // 按渠道+错误归类聚合
+	GetResponseLabelStats(ctx context.Context, startTime, endTime time.Time, filter *model.LogFilter) ([]model.ResponseLabelStats, error) // 按渠道聚合响应标签命中率（2026-10新增）
 
 	// === System Settings ===
 	GetSetting(ctx context.Context, key string) (*model.SystemSetting, error)
@@ -89,17 +192,18 @@ type Store interface {
 	BatchUpdateSettings(ctx context.Context, updates map[string]string) error
 
 	// === Admin Session Management ===
-	CreateAdminSession(ctx context.Context, token string, expiresAt time.Time) error
+	CreateAdminSession(ctx context.Context, token string, expiresAt time.Time, role string) error
 	GetAdminSession(ctx context.Context, token string) (expiresAt time.Time, exists bool, err error)
 	DeleteAdminSession(ctx context.Context, token string) error
 	CleanExpiredSessions(ctx context.Context) error
-	LoadAllSessions(ctx context.Context) (map[string]time.Time, error)
+	LoadAllSessions(ctx context.Context) (map[string]model.AdminSession, error)
 
 	// === Batch Operations ===
 	ImportChannelBatch(ctx context.Context, channels []*model.ChannelWithKeys) (created, updated int, err error)
 
 	// === Infrastructure ===
 	IsRedisEnabled() bool
+	PingRedis(ctx context.Context) error // 健康检查（未启用Redis时返回nil，2026-10新增）
 	Ping(ctx context.Context) error
 	Close() error
 }
@@ -0,0 +1,63 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ccLoad/internal/model"
+	"ccLoad/internal/storage"
+)
+
+func TestListTokenAnomalies_ComparesAgainstBaseline(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.CreateSQLiteStore(filepath.Join(t.TempDir(), "anomaly.db"), nil)
+	if err != nil {
+		t.Fatalf("failed to create sqlite store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	day := time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local)
+	var logs []*model.LogEntry
+	// 基线：令牌7的 model-a 前一天 30 次，每次输出 1000
+	for i := range 30 {
+		logs = append(logs, &model.LogEntry{
+			Time: model.JSONTime{Time: day.Add(-time.Duration(i+1) * time.Minute)}, Model: "model-a",
+			AuthTokenID: 7, ChannelID: 1, StatusCode: 200, Message: "ok", OutputTokens: 1000,
+		})
+	}
+	logs = append(logs,
+		// 当天：一次正常、一次异常、一次失败请求（不计入）、一次无基线的模型
+		&model.LogEntry{Time: model.JSONTime{Time: day.Add(time.Hour)}, Model: "model-a", AuthTokenID: 7, ChannelID: 1, StatusCode: 200, Message: "ok", OutputTokens: 1200},
+		&model.LogEntry{Time: model.JSONTime{Time: day.Add(2 * time.Hour)}, Model: "model-a", AuthTokenID: 7, ChannelID: 1, StatusCode: 200, Message: "ok", OutputTokens: 60000, Cost: 4.5},
+		&model.LogEntry{Time: model.JSONTime{Time: day.Add(3 * time.Hour)}, Model: "model-a", AuthTokenID: 7, ChannelID: 1, StatusCode: 502, Message: "bad", OutputTokens: 90000},
+		&model.LogEntry{Time: model.JSONTime{Time: day.Add(4 * time.Hour)}, Model: "model-b", AuthTokenID: 7, ChannelID: 1, StatusCode: 200, Message: "ok", OutputTokens: 90000},
+	)
+	if err := store.BatchAddLogs(ctx, logs); err != nil {
+		t.Fatalf("failed to add logs: %v", err)
+	}
+
+	anomalies, err := store.ListTokenAnomalies(ctx, model.TokenAnomalyQuery{
+		Since:           day,
+		Until:           day.AddDate(0, 0, 1),
+		BaselineSince:   day.AddDate(0, 0, -7),
+		Multiplier:      10,
+		MinOutputTokens: 8000,
+		MinSamples:      20,
+		Limit:           100,
+	})
+	if err != nil {
+		t.Fatalf("ListTokenAnomalies error: %v", err)
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d: %+v", len(anomalies), anomalies)
+	}
+	a := anomalies[0]
+	if a.OutputTokens != 60000 || a.AuthTokenID != 7 || a.Model != "model-a" || a.Cost != 4.5 {
+		t.Fatalf("unexpected anomaly: %+v", a)
+	}
+	if a.BaselineOutput != 1000 || a.Ratio != 60 {
+		t.Fatalf("unexpected baseline/ratio: %.1f / %.1f", a.BaselineOutput, a.Ratio)
+	}
+}
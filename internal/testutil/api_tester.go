@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"ccLoad/internal/model"
+	"ccLoad/internal/util"
 
 	"github.com/bytedance/sonic"
 )
@@ -66,8 +67,19 @@ func parseAPIResponse(respBody []byte, extractText func(map[string]any) (string,
 	return out
 }
 
+// profileOr 返回调用方解析好的客户端profile；未提供时回退到内置 profile
+func profileOr(p util.ClientProfile, builtin string) util.ClientProfile {
+	if p != nil {
+		return p
+	}
+	return util.BuiltinClientProfiles[builtin]
+}
+
 // CodexTester 兼容 Codex 风格（渠道类型: codex）
-type CodexTester struct{}
+type CodexTester struct {
+	// Profile 默认客户端请求头（由调用方按代理相同的规则解析 codex-cli，含 client_profiles 覆盖；为空时使用内置值）
+	Profile util.ClientProfile
+}
 
 // Build 构建 Codex 格式的 API 请求
 func (t *CodexTester) Build(cfg *model.Config, apiKey string, req *TestChannelRequest) (string, http.Header, []byte, error) {
@@ -91,9 +103,8 @@ func (t *CodexTester) Build(cfg *model.Config, apiKey string, req *TestChannelRe
 	h := make(http.Header)
 	h.Set("Content-Type", "application/json")
 	h.Set("Authorization", "Bearer "+apiKey)
-	h.Set("User-Agent", "codex_cli_rs/0.41.0 (Mac OS 26.0.0; arm64) iTerm.app/3.6.1")
-	h.Set("Openai-Beta", "responses=experimental")
-	h.Set("Originator", "codex_cli_rs")
+	// Codex CLI headers（默认profile，渠道可通过 client_profile 覆盖）
+	profileOr(t.Profile, util.ClientProfileCodexCLI).Apply(h)
 	if req.Stream {
 		h.Set("Accept", "text/event-stream")
 	}
@@ -202,6 +213,26 @@ func (t *OpenAITester) Parse(_ int, respBody []byte) map[string]any {
 	return out
 }
 
+// AzureTester Azure OpenAI 格式（渠道类型: azure，2026-10新增）
+// 测试模型（重定向后）即部署名，请求发往 /openai/deployments/{deployment}/chat/completions，使用 api-key 认证
+type AzureTester struct{}
+
+// Build 构建 Azure OpenAI 格式的 API 请求
+func (t *AzureTester) Build(cfg *model.Config, apiKey string, req *TestChannelRequest) (string, http.Header, []byte, error) {
+	_, h, body, err := (&OpenAITester{}).Build(cfg, apiKey, req)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	h.Del("Authorization")
+	h.Set(util.AzureAPIKeyHeader, apiKey)
+	return util.AzureUpstreamURL(cfg.URL, "/v1/chat/completions", req.Model, ""), h, body, nil
+}
+
+// Parse 解析 Azure OpenAI 响应（与 OpenAI 格式一致）
+func (t *AzureTester) Parse(statusCode int, respBody []byte) map[string]any {
+	return (&OpenAITester{}).Parse(statusCode, respBody)
+}
+
 // GeminiTester 实现 Google Gemini 测试协议
 type GeminiTester struct{}
 
@@ -267,7 +298,10 @@ func (t *GeminiTester) Parse(_ int, respBody []byte) map[string]any {
 }
 
 // AnthropicTester 实现 Anthropic 测试协议
-type AnthropicTester struct{}
+type AnthropicTester struct {
+	// Profile 默认客户端请求头（由调用方按代理相同的规则解析 claude-cli，含 client_profiles 覆盖；为空时使用内置值）
+	Profile util.ClientProfile
+}
 
 // newClaudeCLIUserID 生成 Claude CLI 用户ID
 func newClaudeCLIUserID() string {
@@ -317,21 +351,9 @@ func (t *AnthropicTester) Build(cfg *model.Config, apiKey string, req *TestChann
 	h.Set("Accept", "application/json")
 	h.Set("Content-Type", "application/json")
 	h.Set("Authorization", "Bearer "+apiKey)
-	// Claude Code CLI headers
-	h.Set("User-Agent", "claude-cli/2.0.76 (external, cli)")
-	h.Set("x-app", "cli")
 	h.Set("anthropic-version", "2023-06-01")
-	h.Set("anthropic-beta", "interleaved-thinking-2025-05-14,advanced-tool-use-2025-11-20")
-	h.Set("anthropic-dangerous-direct-browser-access", "true")
-	// x-stainless-* headers
-	h.Set("x-stainless-arch", "arm64")
-	h.Set("x-stainless-lang", "js")
-	h.Set("x-stainless-os", "MacOS")
-	h.Set("x-stainless-package-version", "0.70.0")
-	h.Set("x-stainless-retry-count", "0")
-	h.Set("x-stainless-runtime", "node")
-	h.Set("x-stainless-runtime-version", "v24.3.0")
-	h.Set("x-stainless-timeout", "600")
+	// Claude Code CLI headers（默认profile，渠道可通过 client_profile 覆盖）
+	profileOr(t.Profile, util.ClientProfileClaudeCLI).Apply(h)
 	if req.Stream {
 		h.Set("x-stainless-helper-method", "stream")
 	}
@@ -7,10 +7,15 @@ type TestChannelRequest struct {
 	Model       string            `json:"model" binding:"required"`
 	MaxTokens   int               `json:"max_tokens,omitempty"`   // 可选，默认512
 	Stream      bool              `json:"stream,omitempty"`       // 可选，流式响应
-	Content     string            `json:"content,omitempty"`      // 可选，测试内容，默认"test"
+	Content     string            `json:"content,omitempty"`      // 可选，测试内容；为空时使用提示词库中的默认提示词
+	PromptID    int64             `json:"prompt_id,omitempty"`    // 可选，按ID选用提示词库中的测试提示词（优先于 content）
+	Prompt      string            `json:"prompt,omitempty"`       // 可选，按名称选用测试提示词（优先于 content）
 	Headers     map[string]string `json:"headers,omitempty"`      // 可选，自定义请求头
 	ChannelType string            `json:"channel_type,omitempty"` // 可选，渠道类型：anthropic(默认)、codex、gemini
 	KeyIndex    int               `json:"key_index,omitempty"`    // 可选，指定测试的Key索引，默认0（第一个）
+	Diagnose    bool              `json:"diagnose,omitempty"`     // 可选，诊断模式：并排返回原始上游流与客户端流并校验事件顺序
+
+	DisableCompression bool `json:"disable_compression,omitempty"` // 可选，忽略渠道上游压缩配置（与开启时对比耗时/字节数）
 }
 
 // Validate 实现RequestValidator接口
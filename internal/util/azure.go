@@ -0,0 +1,51 @@
+package util
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ============================================================================
+// Azure OpenAI 端点映射（2026-10新增）
+// ============================================================================
+// 渠道URL填写资源端点，可在查询参数中指定 api-version：
+//   https://my-resource.openai.azure.com?api-version=2024-10-21
+// 渠道模型（或其重定向目标）即 Azure 部署名：
+//   /v1/chat/completions → /openai/deployments/{deployment}/chat/completions?api-version=...
+// 认证使用 api-key 请求头。
+
+// AzureDefaultAPIVersion 渠道URL未指定 api-version 时使用的版本（GA）
+const AzureDefaultAPIVersion = "2024-10-21"
+
+// AzureAPIKeyHeader Azure OpenAI 认证头
+const AzureAPIKeyHeader = "api-key"
+
+// AzureUpstreamURL 构建 Azure OpenAI 上游地址
+// deployment 为空时（如 GET /v1/models）映射为资源级路径 /openai/...
+func AzureUpstreamURL(channelURL, requestPath, deployment, rawQuery string) string {
+	base, apiVersion := strings.TrimRight(channelURL, "/"), AzureDefaultAPIVersion
+	if u, err := url.Parse(base); err == nil && u.RawQuery != "" {
+		if v := strings.TrimSpace(u.Query().Get("api-version")); v != "" {
+			apiVersion = v
+		}
+		u.RawQuery = ""
+		base = strings.TrimRight(u.String(), "/")
+	}
+
+	rest := strings.TrimPrefix(requestPath, "/v1")
+	if deployment != "" {
+		base += "/openai/deployments/" + url.PathEscape(deployment) + rest
+	} else {
+		base += "/openai" + rest
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		values = url.Values{}
+	}
+	values.Del("key")
+	if values.Get("api-version") == "" {
+		values.Set("api-version", apiVersion)
+	}
+	return base + "?" + values.Encode()
+}
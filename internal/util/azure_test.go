@@ -0,0 +1,25 @@
+package util
+
+import "testing"
+
+func TestAzureUpstreamURL(t *testing.T) {
+	for _, tc := range []struct {
+		channelURL, path, deployment, query, want string
+	}{
+		{"https://res.openai.azure.com", "/v1/chat/completions", "gpt4o", "",
+			"https://res.openai.azure.com/openai/deployments/gpt4o/chat/completions?api-version=" + AzureDefaultAPIVersion},
+		{"https://res.openai.azure.com/?api-version=2025-01-01-preview", "/v1/embeddings", "emb", "key=secret",
+			"https://res.openai.azure.com/openai/deployments/emb/embeddings?api-version=2025-01-01-preview"},
+		{"https://gw.example.com/azure", "/v1/chat/completions", "a b", "api-version=2024-06-01",
+			"https://gw.example.com/azure/openai/deployments/a%20b/chat/completions?api-version=2024-06-01"},
+		{"https://res.openai.azure.com", "/v1/models", "", "",
+			"https://res.openai.azure.com/openai/models?api-version=" + AzureDefaultAPIVersion},
+	} {
+		if got := AzureUpstreamURL(tc.channelURL, tc.path, tc.deployment, tc.query); got != tc.want {
+			t.Errorf("AzureUpstreamURL(%q, %q, %q, %q) = %s, want %s", tc.channelURL, tc.path, tc.deployment, tc.query, got, tc.want)
+		}
+	}
+	if ProtocolChannelType(ChannelTypeAzure) != ChannelTypeOpenAI || ProtocolChannelType(ChannelTypeGemini) != ChannelTypeGemini {
+		t.Error("azure should use the openai protocol")
+	}
+}
@@ -0,0 +1,256 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 上游Beta功能注册表（2026-10新增）
+// ============================================================================
+// Anthropic 的部分功能需要 anthropic-beta 请求头标记，且并非所有上游（中转/云厂商）都支持。
+// 渠道通过 beta_features 声明支持的功能（逗号分隔的功能名，none 表示全部不支持，留空表示不管理、原样透传）：
+//   - 路由：请求使用了某功能（请求头带标记或请求体使用对应字段）时，跳过声明了但不支持该功能的渠道；
+//     所有候选渠道都不支持时保留原列表（由下面的改写降级请求）
+//   - 改写：发往声明了 beta_features 的渠道时，移除未启用功能的请求头标记与请求体字段；
+//     已启用功能在请求体使用但缺少标记时自动补充（AutoHeader）
+
+// BetaHeader Anthropic beta 功能请求头
+const BetaHeader = "anthropic-beta"
+
+// BetaFeaturesNone 渠道声明不支持任何 beta 功能
+const BetaFeaturesNone = "none"
+
+// BetaFeature 单个beta功能定义
+type BetaFeature struct {
+	Name           string `json:"name"`
+	DisplayName    string `json:"display_name"`
+	HeaderToken    string `json:"header_token"`               // anthropic-beta 中的标记
+	AutoHeader     bool   `json:"auto_header"`                // 请求体使用该功能但缺少标记时自动补充
+	BodyField      string `json:"body_field,omitempty"`       // 使用该功能的顶层请求体字段
+	ToolTypePrefix string `json:"tool_type_prefix,omitempty"` // 使用该功能的 tools[].type 前缀
+}
+
+// BetaFeatures 已注册的beta功能（顺序即规范化后的顺序）
+var BetaFeatures = []BetaFeature{
+	{Name: "extended-thinking", DisplayName: "扩展思考（交错思考）", HeaderToken: "interleaved-thinking-2025-05-14", BodyField: "thinking"},
+	{Name: "computer-use", DisplayName: "Computer Use", HeaderToken: "computer-use-2025-01-24", AutoHeader: true, ToolTypePrefix: "computer_"},
+	{Name: "context-management", DisplayName: "上下文管理", HeaderToken: "context-management-2025-06-27", AutoHeader: true, BodyField: "context_management"},
+}
+
+// NormalizeBetaFeatures 校验并规范化渠道 beta_features（按注册表顺序去重）
+// 空输入返回空字符串（不管理）；none 单独出现时原样返回
+func NormalizeBetaFeatures(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if strings.EqualFold(raw, BetaFeaturesNone) {
+		return BetaFeaturesNone, nil
+	}
+	enabled := make(map[string]bool)
+	for part := range strings.SplitSeq(raw, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(BetaFeatures, func(f BetaFeature) bool { return f.Name == name }) {
+			return "", fmt.Errorf("unknown beta feature %q", name)
+		}
+		enabled[name] = true
+	}
+	var names []string
+	for _, f := range BetaFeatures {
+		if enabled[f.Name] {
+			names = append(names, f.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	return strings.Join(names, ","), nil
+}
+
+// betaFeatureEnabled 渠道是否启用某功能（beta_features 为空表示不管理，视为启用）
+func betaFeatureEnabled(channelFeatures, name string) bool {
+	if channelFeatures == "" {
+		return true
+	}
+	for f := range strings.SplitSeq(channelFeatures, ",") {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelSupportsBetaFeatures 渠道是否支持全部请求功能
+func ChannelSupportsBetaFeatures(channelFeatures string, requested []string) bool {
+	for _, name := range requested {
+		if !betaFeatureEnabled(channelFeatures, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// betaHeaderTokens 解析 anthropic-beta 请求头（可能多值、逗号分隔）
+func betaHeaderTokens(h http.Header) []string {
+	var tokens []string
+	for _, v := range h.Values(BetaHeader) {
+		for tok := range strings.SplitSeq(v, ",") {
+			if tok = strings.TrimSpace(tok); tok != "" {
+				tokens = append(tokens, tok)
+			}
+		}
+	}
+	return tokens
+}
+
+// bodyUsesBetaFeature 请求体是否使用了某功能（reqData 为解析后的请求体，可为nil）
+func bodyUsesBetaFeature(f BetaFeature, reqData map[string]any) bool {
+	if reqData == nil {
+		return false
+	}
+	if f.BodyField != "" {
+		if v, ok := reqData[f.BodyField]; ok && v != nil {
+			// thinking: {"type":"disabled"} 不算使用
+			if m, isMap := v.(map[string]any); !isMap || m["type"] != "disabled" {
+				return true
+			}
+		}
+	}
+	if f.ToolTypePrefix != "" {
+		tools, _ := reqData["tools"].([]any)
+		for _, t := range tools {
+			if tm, ok := t.(map[string]any); ok {
+				if typ, _ := tm["type"].(string); strings.HasPrefix(typ, f.ToolTypePrefix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// parseBetaBody 仅在请求体可能使用注册功能时解析（快速字节预筛，避免每个请求都反序列化）
+func parseBetaBody(body []byte) map[string]any {
+	mayUse := false
+	for _, f := range BetaFeatures {
+		if (f.BodyField != "" && bytes.Contains(body, []byte(`"`+f.BodyField+`"`))) ||
+			(f.ToolTypePrefix != "" && bytes.Contains(body, []byte(`"`+f.ToolTypePrefix))) {
+			mayUse = true
+			break
+		}
+	}
+	if !mayUse {
+		return nil
+	}
+	var reqData map[string]any
+	if err := sonic.Unmarshal(body, &reqData); err != nil {
+		return nil
+	}
+	return reqData
+}
+
+// DetectBetaFeatures 返回请求使用的beta功能名（请求头标记或请求体字段，按注册表顺序）
+func DetectBetaFeatures(h http.Header, body []byte) []string {
+	tokens := betaHeaderTokens(h)
+	reqData := parseBetaBody(body)
+	var used []string
+	for _, f := range BetaFeatures {
+		if slices.Contains(tokens, f.HeaderToken) || bodyUsesBetaFeature(f, reqData) {
+			used = append(used, f.Name)
+		}
+	}
+	return used
+}
+
+// StripDisabledBetaBody 移除渠道未启用功能的请求体字段（未管理的渠道或无需改动时原样返回）
+func StripDisabledBetaBody(channelFeatures string, body []byte) []byte {
+	if channelFeatures == "" {
+		return body
+	}
+	reqData := parseBetaBody(body)
+	if reqData == nil {
+		return body
+	}
+	changed := false
+	for _, f := range BetaFeatures {
+		if betaFeatureEnabled(channelFeatures, f.Name) || !bodyUsesBetaFeature(f, reqData) {
+			continue
+		}
+		if f.BodyField != "" {
+			delete(reqData, f.BodyField)
+		}
+		if f.ToolTypePrefix != "" {
+			tools, _ := reqData["tools"].([]any)
+			kept := make([]any, 0, len(tools))
+			for _, t := range tools {
+				if tm, ok := t.(map[string]any); ok {
+					if typ, _ := tm["type"].(string); strings.HasPrefix(typ, f.ToolTypePrefix) {
+						continue
+					}
+				}
+				kept = append(kept, t)
+			}
+			if len(kept) == 0 {
+				delete(reqData, "tools")
+				delete(reqData, "tool_choice")
+			} else {
+				reqData["tools"] = kept
+			}
+		}
+		changed = true
+	}
+	if !changed {
+		return body
+	}
+	out, err := sonic.Marshal(reqData)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// ApplyBetaFeatureHeader 按渠道启用的功能改写 anthropic-beta 请求头（body 为实际发送的请求体）
+// 未启用功能的标记被移除；启用且 AutoHeader 的功能在请求体使用但缺少标记时补充；未注册的标记原样保留
+func ApplyBetaFeatureHeader(channelFeatures string, h http.Header, body []byte) {
+	if channelFeatures == "" {
+		return
+	}
+	tokens := betaHeaderTokens(h)
+	var reqData map[string]any
+	parsed := false
+	out := make([]string, 0, len(tokens)+1)
+	for _, tok := range tokens {
+		idx := slices.IndexFunc(BetaFeatures, func(f BetaFeature) bool { return f.HeaderToken == tok })
+		if idx >= 0 && !betaFeatureEnabled(channelFeatures, BetaFeatures[idx].Name) {
+			continue
+		}
+		if !slices.Contains(out, tok) {
+			out = append(out, tok)
+		}
+	}
+	for _, f := range BetaFeatures {
+		if !f.AutoHeader || !betaFeatureEnabled(channelFeatures, f.Name) || slices.Contains(out, f.HeaderToken) {
+			continue
+		}
+		if !parsed {
+			reqData, parsed = parseBetaBody(body), true
+		}
+		if bodyUsesBetaFeature(f, reqData) {
+			out = append(out, f.HeaderToken)
+		}
+	}
+	if len(out) == 0 {
+		h.Del(BetaHeader)
+		return
+	}
+	h.Set(BetaHeader, strings.Join(out, ","))
+}
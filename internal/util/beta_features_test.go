@@ -0,0 +1,95 @@
+package util
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bytedance/sonic"
+)
+
+func TestNormalizeBetaFeatures(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: " NONE ", want: BetaFeaturesNone},
+		{in: "context-management, extended-thinking,extended-thinking", want: "extended-thinking,context-management"},
+		{in: "computer-use,unknown", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := NormalizeBetaFeatures(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeBetaFeatures(%q) expected error", tc.in)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("NormalizeBetaFeatures(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestDetectBetaFeatures(t *testing.T) {
+	h := http.Header{}
+	h.Add(BetaHeader, "interleaved-thinking-2025-05-14, other-beta")
+	body := []byte(`{"model":"m","tools":[{"type":"computer_20250124","name":"computer"}]}`)
+	if got := DetectBetaFeatures(h, body); !reflect.DeepEqual(got, []string{"extended-thinking", "computer-use"}) {
+		t.Fatalf("DetectBetaFeatures = %v", got)
+	}
+	if got := DetectBetaFeatures(http.Header{}, []byte(`{"thinking":{"type":"disabled"}}`)); len(got) != 0 {
+		t.Fatalf("disabled thinking should not count, got %v", got)
+	}
+	if !ChannelSupportsBetaFeatures("", []string{"computer-use"}) || ChannelSupportsBetaFeatures(BetaFeaturesNone, []string{"computer-use"}) ||
+		!ChannelSupportsBetaFeatures("computer-use", []string{"computer-use"}) {
+		t.Fatal("ChannelSupportsBetaFeatures mismatch")
+	}
+}
+
+func TestStripDisabledBetaBodyAndHeader(t *testing.T) {
+	body := []byte(`{"model":"m","thinking":{"type":"enabled","budget_tokens":1024},"context_management":{"edits":[]},` +
+		`"tools":[{"type":"computer_20250124","name":"computer"}],"tool_choice":{"type":"auto"}}`)
+
+	// 未管理的渠道原样透传
+	if got := StripDisabledBetaBody("", body); string(got) != string(body) {
+		t.Fatal("unmanaged channel should not modify body")
+	}
+
+	stripped := StripDisabledBetaBody("context-management", body)
+	var data map[string]any
+	if err := sonic.Unmarshal(stripped, &data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, k := range []string{"thinking", "tools", "tool_choice"} {
+		if _, ok := data[k]; ok {
+			t.Errorf("field %q should be stripped: %s", k, stripped)
+		}
+	}
+	if _, ok := data["context_management"]; !ok || data["model"] != "m" {
+		t.Fatalf("enabled feature/other fields should be kept: %s", stripped)
+	}
+
+	h := http.Header{}
+	h.Set(BetaHeader, "interleaved-thinking-2025-05-14,custom-beta")
+	ApplyBetaFeatureHeader("context-management", h, stripped)
+	if got := h.Get(BetaHeader); got != "custom-beta,context-management-2025-06-27" {
+		t.Fatalf("anthropic-beta = %q", got)
+	}
+
+	h = http.Header{}
+	h.Set(BetaHeader, "interleaved-thinking-2025-05-14")
+	ApplyBetaFeatureHeader(BetaFeaturesNone, h, []byte(`{"model":"m"}`))
+	if _, ok := h[http.CanonicalHeaderKey(BetaHeader)]; ok {
+		t.Fatalf("all tokens removed should delete header, got %v", h)
+	}
+
+	// 非 computer 工具保留
+	mixed := []byte(`{"tools":[{"type":"computer_20250124","name":"computer"},{"name":"search","input_schema":{}}]}`)
+	if got := string(StripDisabledBetaBody(BetaFeaturesNone, mixed)); strings.Contains(got, "computer_") || !strings.Contains(got, "search") {
+		t.Fatalf("tools filter mismatch: %s", got)
+	}
+}
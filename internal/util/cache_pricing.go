@@ -0,0 +1,132 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 缓存Token计费倍率覆盖（2026-10新增）
+// ============================================================================
+// 默认倍率（相对基础输入价格）：缓存读取 0.1x（OpenAI按系列 0.1/0.25/0.5x），5分钟缓存写入 1.25x，1小时缓存写入 2x。
+// 系统设置 cost_cache_multipliers 可按模型覆盖，用于与上游账单对齐（如协议价/新模型定价调整）：
+//   {"claude-opus-4*": {"read": 0.1, "write_5m": 1.25, "write_1h": 2}, "gpt-5*": {"read": 0.1}}
+// 键为精确模型名或以 * 结尾的前缀，多条命中时最长键优先；未填写（或为0）的倍率沿用默认值。
+
+// CacheCostMultipliers 缓存Token计费倍率（相对基础输入价格）
+type CacheCostMultipliers struct {
+	Read    float64 `json:"read"`
+	Write5m float64 `json:"write_5m"`
+	Write1h float64 `json:"write_1h"`
+}
+
+type cacheCostRule struct {
+	pattern string // 小写；以 * 结尾表示前缀匹配
+	mult    CacheCostMultipliers
+}
+
+// cacheCostOverrides 当前生效的覆盖规则（按键长度降序；nil 表示无覆盖）
+var cacheCostOverrides atomic.Pointer[[]cacheCostRule]
+
+// parseCacheCostMultipliers 解析 cost_cache_multipliers 配置
+func parseCacheCostMultipliers(raw string) ([]cacheCostRule, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var m map[string]CacheCostMultipliers
+	if err := sonic.UnmarshalString(raw, &m); err != nil {
+		return nil, fmt.Errorf("invalid cost_cache_multipliers json: %w", err)
+	}
+	rules := make([]cacheCostRule, 0, len(m))
+	for key, mult := range m {
+		pattern := strings.ToLower(strings.TrimSpace(key))
+		if pattern == "" || pattern == "*" || strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+			return nil, fmt.Errorf("cost_cache_multipliers: invalid model pattern %q (exact name or prefix ending with *)", key)
+		}
+		if mult.Read < 0 || mult.Write5m < 0 || mult.Write1h < 0 || mult.Read > 10 || mult.Write5m > 10 || mult.Write1h > 10 {
+			return nil, fmt.Errorf("cost_cache_multipliers: %q multipliers must be within 0-10", key)
+		}
+		rules = append(rules, cacheCostRule{pattern: pattern, mult: mult})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if len(rules[i].pattern) != len(rules[j].pattern) {
+			return len(rules[i].pattern) > len(rules[j].pattern)
+		}
+		return rules[i].pattern < rules[j].pattern
+	})
+	return rules, nil
+}
+
+// ValidateCacheCostMultipliers 校验 cost_cache_multipliers 配置格式
+func ValidateCacheCostMultipliers(raw string) error {
+	_, err := parseCacheCostMultipliers(raw)
+	return err
+}
+
+// SetCacheCostMultipliers 替换缓存计费倍率覆盖（启动加载与设置热更新共用）
+// 配置无效时保留原有规则并返回错误
+func SetCacheCostMultipliers(raw string) error {
+	rules, err := parseCacheCostMultipliers(raw)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		cacheCostOverrides.Store(nil)
+		return nil
+	}
+	cacheCostOverrides.Store(&rules)
+	return nil
+}
+
+// defaultCacheCostMultipliers 内置缓存计费倍率
+func defaultCacheCostMultipliers(model string) CacheCostMultipliers {
+	read := cacheReadMultiplierClaude // Claude全系/Gemini: 10%
+	if isOpenAIModel(model) {
+		// OpenAI缓存折扣率按模型系列区分（2025-12官方定价）
+		read = getOpenAICacheMultiplier(model)
+	} else if isOpusModel(model) {
+		read = cacheReadMultiplierOpus
+	}
+	return CacheCostMultipliers{Read: read, Write5m: cacheWrite5mMultiplier, Write1h: cacheWrite1hMultiplier}
+}
+
+// CacheCostMultipliersFor 返回模型实际生效的缓存计费倍率（内置默认值叠加配置覆盖）
+func CacheCostMultipliersFor(model string) CacheCostMultipliers {
+	mult := defaultCacheCostMultipliers(model)
+	p := cacheCostOverrides.Load()
+	if p == nil {
+		return mult
+	}
+	lower := strings.ToLower(model)
+	for _, r := range *p {
+		prefix, isPrefix := strings.CutSuffix(r.pattern, "*")
+		if (isPrefix && strings.HasPrefix(lower, prefix)) || (!isPrefix && lower == r.pattern) {
+			if r.mult.Read > 0 {
+				mult.Read = r.mult.Read
+			}
+			if r.mult.Write5m > 0 {
+				mult.Write5m = r.mult.Write5m
+			}
+			if r.mult.Write1h > 0 {
+				mult.Write1h = r.mult.Write1h
+			}
+			break
+		}
+	}
+	return mult
+}
+
+// SplitCacheCreationTokens 补齐缓存写入的TTL细分
+// 上游只返回 cache_creation_input_tokens 总数而无 5m/1h 细分时（旧版API、部分中转/云厂商），
+// 差额按默认的5分钟TTL计费，避免缓存写入漏计
+func SplitCacheCreationTokens(total, cache5m, cache1h int) (int, int) {
+	if rest := total - cache5m - cache1h; rest > 0 {
+		cache5m += rest
+	}
+	return cache5m, cache1h
+}
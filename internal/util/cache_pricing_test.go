@@ -0,0 +1,66 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCacheCostMultipliers(t *testing.T) {
+	t.Cleanup(func() { _ = SetCacheCostMultipliers("") })
+
+	def := CacheCostMultipliersFor("claude-sonnet-4-5")
+	if def.Read != 0.1 || def.Write5m != 1.25 || def.Write1h != 2 {
+		t.Fatalf("unexpected defaults: %+v", def)
+	}
+	if got := CacheCostMultipliersFor("gpt-4o").Read; got != 0.5 {
+		t.Fatalf("gpt-4o read multiplier = %v, want 0.5", got)
+	}
+
+	for _, bad := range []string{`{`, `{"*":{"read":0.2}}`, `{"claude-*-opus":{"read":0.2}}`, `{"claude-*":{"read":-1}}`} {
+		if err := ValidateCacheCostMultipliers(bad); err == nil {
+			t.Errorf("ValidateCacheCostMultipliers(%q) expected error", bad)
+		}
+	}
+
+	if err := SetCacheCostMultipliers(`{"claude-*":{"read":0.2},"claude-opus-4*":{"write_1h":3},"claude-haiku-4-5":{"write_5m":1.5}}`); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	// 最长键优先，未填写的倍率沿用默认值
+	if got := CacheCostMultipliersFor("Claude-Opus-4-5"); got.Read != 0.1 || got.Write5m != 1.25 || got.Write1h != 3 {
+		t.Fatalf("opus override: %+v", got)
+	}
+	if got := CacheCostMultipliersFor("claude-sonnet-4-5"); got.Read != 0.2 || got.Write1h != 2 {
+		t.Fatalf("prefix override: %+v", got)
+	}
+	if got := CacheCostMultipliersFor("claude-haiku-4-5"); got.Write5m != 1.5 {
+		t.Fatalf("exact override: %+v", got)
+	}
+
+	// 1M 缓存读取：sonnet input=$3 → 0.2x = $0.6
+	if cost := CalculateCostDetailed("claude-sonnet-4-5", 0, 0, 1_000_000, 0, 0); math.Abs(cost-0.6) > 1e-9 {
+		t.Fatalf("cache read cost = %v, want 0.6", cost)
+	}
+
+	// 无效配置保留原规则
+	if err := SetCacheCostMultipliers(`{`); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := CacheCostMultipliersFor("claude-sonnet-4-5").Read; got != 0.2 {
+		t.Fatalf("invalid config must keep previous rules, got %v", got)
+	}
+}
+
+func TestSplitCacheCreationTokens(t *testing.T) {
+	cases := []struct{ total, in5m, in1h, want5m, want1h int }{
+		{278, 0, 0, 278, 0},
+		{300, 100, 200, 100, 200},
+		{300, 0, 200, 100, 200},
+		{0, 10, 0, 10, 0},
+	}
+	for _, tc := range cases {
+		got5m, got1h := SplitCacheCreationTokens(tc.total, tc.in5m, tc.in1h)
+		if got5m != tc.want5m || got1h != tc.want1h {
+			t.Errorf("SplitCacheCreationTokens(%d,%d,%d) = %d,%d; want %d,%d", tc.total, tc.in5m, tc.in1h, got5m, got1h, tc.want5m, tc.want1h)
+		}
+	}
+}
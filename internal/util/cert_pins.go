@@ -0,0 +1,74 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// 上游证书固定（Certificate Pinning）
+// ============================================================================
+// 敏感渠道可固定上游证书的 SPKI 指纹（与 HPKP 格式一致：sha256/<base64>）。
+// 只要证书链中任一证书的公钥指纹命中即通过，因此既可固定叶子证书，也可固定中间CA。
+// 指纹不匹配时 TLS 握手直接失败，请求头（含 API Key）不会发送到对端。
+
+// ErrCertPinMismatch 上游证书SPKI指纹与渠道固定值不匹配
+var ErrCertPinMismatch = errors.New("upstream certificate pin mismatch")
+
+const certPinPrefix = "sha256/"
+
+// ParseCertPins 解析逗号/换行分隔的证书指纹列表，返回规范化后的指纹（sha256/<base64>）
+// 允许省略 sha256/ 前缀；空输入返回空列表
+func ParseCertPins(raw string) ([]string, error) {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' ' || r == '\t'
+	})
+	pins := make([]string, 0, len(fields))
+	seen := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		b64 := strings.TrimPrefix(f, certPinPrefix)
+		sum, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid cert pin %q: expected sha256/<base64 of 32 bytes>", f)
+		}
+		pin := certPinPrefix + b64
+		if _, dup := seen[pin]; dup {
+			continue
+		}
+		seen[pin] = struct{}{}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// CertSPKIPin 计算证书公钥（SubjectPublicKeyInfo）的 sha256/<base64> 指纹
+func CertSPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return certPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// CertPinVerifier 构造 tls.Config.VerifyConnection 回调
+// 在常规证书链校验之后执行：对端证书链中没有任何证书命中指纹时返回 ErrCertPinMismatch
+func CertPinVerifier(pins []string) func(tls.ConnectionState) error {
+	allowed := make(map[string]struct{}, len(pins))
+	for _, p := range pins {
+		allowed[p] = struct{}{}
+	}
+	return func(cs tls.ConnectionState) error {
+		for _, cert := range cs.PeerCertificates {
+			if _, ok := allowed[CertSPKIPin(cert)]; ok {
+				return nil
+			}
+		}
+		got := ""
+		if len(cs.PeerCertificates) > 0 {
+			got = CertSPKIPin(cs.PeerCertificates[0])
+		}
+		return fmt.Errorf("%w: host=%s leaf=%s", ErrCertPinMismatch, cs.ServerName, got)
+	}
+}
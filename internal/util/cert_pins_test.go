@@ -0,0 +1,69 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseCertPins(t *testing.T) {
+	sum := sha256.Sum256([]byte("spki"))
+	b64 := base64.StdEncoding.EncodeToString(sum[:])
+
+	pins, err := ParseCertPins("sha256/" + b64 + ",\n" + b64)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pins) != 1 || pins[0] != "sha256/"+b64 {
+		t.Fatalf("expected one normalized pin, got %v", pins)
+	}
+
+	if pins, err := ParseCertPins("  "); err != nil || len(pins) != 0 {
+		t.Fatalf("expected empty result, got %v, %v", pins, err)
+	}
+	if _, err := ParseCertPins("sha256/not-base64"); err == nil {
+		t.Fatal("expected error for invalid base64")
+	}
+	if _, err := ParseCertPins("sha256/" + base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Fatal("expected error for wrong digest length")
+	}
+}
+
+func TestCertPinVerifier(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	newClient := func(pins []string) *http.Client {
+		transport := srv.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.VerifyConnection = CertPinVerifier(pins)
+		return &http.Client{Transport: transport}
+	}
+
+	goodPin := CertSPKIPin(srv.Certificate())
+	resp, err := newClient([]string{goodPin}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("matching pin should connect: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	sum := sha256.Sum256([]byte("other"))
+	badPin := "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+	_, err = newClient([]string{badPin}).Get(srv.URL)
+	if !errors.Is(err, ErrCertPinMismatch) {
+		t.Fatalf("expected ErrCertPinMismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), goodPin) {
+		t.Fatalf("error should report presented pin, got %v", err)
+	}
+
+	status, level, retry := ClassifyError(err)
+	if status != StatusCertPinMismatch || level != ErrorLevelChannel || !retry {
+		t.Fatalf("unexpected classification: %d %v %v", status, level, retry)
+	}
+}
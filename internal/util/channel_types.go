@@ -41,6 +41,20 @@ var ChannelTypes = []ChannelTypeConfig{
 		PathPatterns: []string{"/v1beta/"},
 		MatchType:    MatchTypeContains,
 	},
+	{
+		// 使用 OpenAI 协议承接 OpenAI 请求，不参与路径检测（2026-10新增）
+		Value:       ChannelTypeAzure,
+		DisplayName: "Azure OpenAI",
+		Description: "Azure OpenAI（按部署名路由）",
+		MatchType:   MatchTypePrefix,
+	},
+	{
+		// 本地生成模拟响应，承接全部协议的请求，不参与路径检测（2026-10新增）
+		Value:       ChannelTypeMock,
+		DisplayName: "Mock",
+		Description: "本地模拟上游（演示/CI，不访问外部服务）",
+		MatchType:   MatchTypePrefix,
+	},
 }
 
 // IsValidChannelType 验证渠道类型是否有效（替代models.go中的硬编码）
@@ -76,17 +90,34 @@ const (
 	ChannelTypeCodex     = "codex"
 	ChannelTypeOpenAI    = "openai"
 	ChannelTypeGemini    = "gemini"
+	ChannelTypeAzure     = "azure"
+	ChannelTypeMock      = "mock"
 )
 
+// ProtocolChannelType 渠道上游使用的请求/响应协议（azure 为 OpenAI 协议，其余类型即自身，2026-10新增）
+func ProtocolChannelType(channelType string) string {
+	if channelType == ChannelTypeAzure {
+		return ChannelTypeOpenAI
+	}
+	return channelType
+}
+
 // 匹配类型常量（路径匹配方式）
 const (
 	MatchTypePrefix   = "prefix"   // 前缀匹配（strings.HasPrefix）
 	MatchTypeContains = "contains" // 包含匹配（strings.Contains）
 )
 
+// LegacyCompletePath Anthropic 旧版 Text Completions 路径（转换为 Messages 后由 anthropic 渠道服务）
+const LegacyCompletePath = "/v1/complete"
+
 // DetectChannelTypeFromPath 根据请求路径自动检测渠道类型
 // 使用 ChannelTypes 配置进行统一检测，遵循DRY原则
 func DetectChannelTypeFromPath(path string) string {
+	// 旧版 /v1/complete 精确匹配（不能作为前缀模式，否则会吞掉 /v1/completions）
+	if path == LegacyCompletePath {
+		return ChannelTypeAnthropic
+	}
 	for _, ct := range ChannelTypes {
 		if matchPath(path, ct.PathPatterns, ct.MatchType) {
 			return ct.Value
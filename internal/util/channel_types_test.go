@@ -11,6 +11,7 @@ func TestDetectChannelTypeFromPath(t *testing.T) {
 		// Anthropic/Claude paths
 		{"Claude Messages", "/v1/messages", ChannelTypeAnthropic},
 		{"Claude Count Tokens", "/v1/messages/count_tokens", ChannelTypeAnthropic},
+		{"Claude Legacy Complete", "/v1/complete", ChannelTypeAnthropic},
 
 		// Codex paths
 		{"Codex Responses", "/v1/responses", ChannelTypeCodex},
@@ -77,8 +78,8 @@ func TestMatchTypeConstants(t *testing.T) {
 
 func TestChannelTypesConfiguration(t *testing.T) {
 	// 验证 ChannelTypes 配置使用了正确的常量
-	if len(ChannelTypes) != 4 {
-		t.Errorf("Expected 4 channel types, got %d", len(ChannelTypes))
+	if len(ChannelTypes) != 6 {
+		t.Errorf("Expected 6 channel types, got %d", len(ChannelTypes))
 	}
 
 	// 验证每个配置的 Value 和 MatchType 使用了常量
@@ -87,6 +88,8 @@ func TestChannelTypesConfiguration(t *testing.T) {
 		ChannelTypeCodex:     true,
 		ChannelTypeOpenAI:    true,
 		ChannelTypeGemini:    true,
+		ChannelTypeAzure:     true,
+		ChannelTypeMock:      true,
 	}
 
 	for _, ct := range ChannelTypes {
@@ -99,8 +102,8 @@ func TestChannelTypesConfiguration(t *testing.T) {
 			t.Errorf("Channel %q has invalid MatchType: %q", ct.Value, ct.MatchType)
 		}
 
-		// 验证 PathPatterns 不为空
-		if len(ct.PathPatterns) == 0 {
+		// 验证 PathPatterns 不为空（沿用其他类型协议的渠道如 azure、按路径回应任意协议的 mock 除外）
+		if len(ct.PathPatterns) == 0 && ProtocolChannelType(ct.Value) == ct.Value && ct.Value != ChannelTypeMock {
 			t.Errorf("Channel %q has no PathPatterns", ct.Value)
 		}
 	}
@@ -28,6 +28,10 @@ const (
 	// 来源：(1) context.Canceled → 不重试  (2) 上游返回499 → 重试其他渠道
 	StatusClientClosedRequest = 499
 
+	// StatusCertPinMismatch 上游证书指纹不匹配（自定义状态码）
+	// 渠道开启证书固定后TLS握手校验失败，疑似中间人，触发渠道级冷却
+	StatusCertPinMismatch = 595
+
 	// StatusQuotaExceeded 1308配额超限（自定义状态码）
 	// 即使HTTP状态码为200，但响应体为1308错误。需从成功率计算中排除
 	StatusQuotaExceeded = 596
@@ -129,6 +133,7 @@ var statusCodeMetaMap = map[int]StatusCodeMeta{
 	524: {ErrorLevelChannel}, // Cloudflare: A Timeout Occurred
 
 	// === 自定义内部状态码 ===
+	StatusCertPinMismatch:  {ErrorLevelChannel}, // Certificate pin mismatch
 	StatusQuotaExceeded:    {ErrorLevelKey},     // 1308 quota exceeded
 	StatusSSEError:         {ErrorLevelKey},     // SSE error event
 	StatusFirstByteTimeout: {ErrorLevelChannel}, // First byte timeout
@@ -179,6 +184,8 @@ func ClientStatusFor(status int) int {
 
 	// 内部状态码：无条件映射为标准 HTTP 语义值
 	switch status {
+	case StatusCertPinMismatch:
+		return http.StatusBadGateway
 	case StatusQuotaExceeded:
 		return http.StatusTooManyRequests
 	case StatusSSEError:
@@ -433,6 +440,41 @@ func classify404Error(responseBody []byte) ErrorLevel {
 	return ErrorLevelClient
 }
 
+// IsModelNotFoundError 判断上游错误是否为“模型不存在”（2026-10新增）
+// 覆盖常见格式：OpenAI code=model_not_found、Anthropic not_found_error("model: xxx")、
+// Gemini "models/xxx is not found"，以及 "The model xxx does not exist"
+func IsModelNotFoundError(statusCode int, responseBody []byte) bool {
+	if (statusCode != 400 && statusCode != 404) || len(responseBody) == 0 {
+		return false
+	}
+	bodyLower := strings.ToLower(string(responseBody))
+	if strings.Contains(bodyLower, "model_not_found") {
+		return true
+	}
+	if !strings.Contains(bodyLower, "model") {
+		return false
+	}
+	return strings.Contains(bodyLower, "not_found_error") ||
+		strings.Contains(bodyLower, "not found") ||
+		strings.Contains(bodyLower, "could not be found") ||
+		strings.Contains(bodyLower, "does not exist")
+}
+
+// IsRequestTooLargeError 判断上游错误是否为"请求体过大"（2026-10新增）
+// 覆盖 HTTP 413 与部分中转以 400 返回的 Anthropic request_too_large / "request entity too large"
+func IsRequestTooLargeError(statusCode int, responseBody []byte) bool {
+	if statusCode == http.StatusRequestEntityTooLarge {
+		return true
+	}
+	if statusCode != 400 || len(responseBody) == 0 {
+		return false
+	}
+	bodyLower := strings.ToLower(string(responseBody))
+	return strings.Contains(bodyLower, "request_too_large") ||
+		strings.Contains(bodyLower, "request entity too large") ||
+		strings.Contains(bodyLower, "payload too large")
+}
+
 // ParseResetTimeFrom1308Error 从1308错误响应中提取重置时间
 // 错误格式: {"type":"error","error":{"type":"1308","message":"已达到 5 小时的使用上限。您的限额将在 2025-12-09 18:08:11 重置。"},"request_id":"..."}
 //
@@ -502,6 +544,16 @@ func ClassifyError(err error) (statusCode int, errorLevel ErrorLevel, shouldRetr
 		return StatusFirstByteTimeout, ErrorLevelChannel, true
 	}
 
+	// 快速路径1.5：证书指纹不匹配（可能遭遇中间人），冷却渠道并切换
+	if errors.Is(err, ErrCertPinMismatch) {
+		return StatusCertPinMismatch, ErrorLevelChannel, true
+	}
+
+	// 快速路径1.6：渠道配置的出站地址在本机不可用，冷却渠道并切换
+	if errors.Is(err, ErrLocalAddrUnavailable) {
+		return 502, ErrorLevelChannel, true
+	}
+
 	// 快速路径2：处理客户端主动取消
 	if errors.Is(err, context.Canceled) {
 		return 499, ErrorLevelClient, false // StatusClientClosedRequest
@@ -941,4 +941,51 @@ func TestClientStatusFor(t *testing.T) {
 	}
 }
 
+func TestIsModelNotFoundError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"openai_code", 404, `{"error":{"message":"The model 'gpt-5' does not exist","code":"model_not_found"}}`, true},
+		{"anthropic_not_found", 404, `{"type":"error","error":{"type":"not_found_error","message":"model: claude-x"}}`, true},
+		{"gemini_models_path", 404, `{"error":{"code":404,"message":"models/gemini-x is not found for API version v1beta","status":"NOT_FOUND"}}`, true},
+		{"bad_request_model", 400, `{"error":{"message":"The model gpt-x does not exist"}}`, true},
+		{"generic_resource", 404, `{"error":{"message":"The requested resource does not exist"}}`, false},
+		{"html_page", 404, `<html><body>404 Not Found</body></html>`, false},
+		{"wrong_status", 500, `{"error":{"code":"model_not_found"}}`, false},
+		{"empty_body", 404, ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsModelNotFoundError(tt.status, []byte(tt.body)); got != tt.want {
+				t.Errorf("IsModelNotFoundError(%d, %s) = %v, want %v", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRequestTooLargeError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"status_413", 413, ``, true},
+		{"anthropic_type_400", 400, `{"type":"error","error":{"type":"request_too_large","message":"Request exceeds the maximum size"}}`, true},
+		{"nginx_text_400", 400, `<html><head><title>413 Request Entity Too Large</title></head></html>`, true},
+		{"other_400", 400, `{"error":{"message":"max_tokens: too large"}}`, false},
+		{"wrong_status", 500, `{"error":{"type":"request_too_large"}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRequestTooLargeError(tt.status, []byte(tt.body)); got != tt.want {
+				t.Errorf("IsRequestTooLargeError(%d, %s) = %v, want %v", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
 // IsRetryableStatus 已移除：重试决策不应依赖静态状态码表，而应依赖 errorLevel/shouldRetry 等语义信息。
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 客户端请求头伪装配置（Client Profiles）
+// ============================================================================
+// 部分上游按客户端指纹（UA、x-app、x-stainless-*、beta标记）开放功能，
+// 而官方CLI每次发版这些字符串都会变化。将其抽成可配置的 profile：
+// - 内置 profile 作为默认值（渠道测试默认使用）
+// - 系统设置 client_profiles(JSON) 可覆盖/新增 profile，无需发版
+// - 渠道可选择 profile，同时作用于渠道测试和代理流量
+
+// ClientProfile 客户端请求头集合（header名 → 值）
+type ClientProfile map[string]string
+
+// 内置 profile 名称
+const (
+	ClientProfileClaudeCLI = "claude-cli"
+	ClientProfileCodexCLI  = "codex-cli"
+)
+
+// BuiltinClientProfiles 内置客户端 profile
+var BuiltinClientProfiles = map[string]ClientProfile{
+	ClientProfileClaudeCLI: {
+		"User-Agent":     "claude-cli/2.0.76 (external, cli)",
+		"x-app":          "cli",
+		"anthropic-beta": "interleaved-thinking-2025-05-14,advanced-tool-use-2025-11-20",
+		"anthropic-dangerous-direct-browser-access": "true",
+		"x-stainless-arch":                          "arm64",
+		"x-stainless-lang":                          "js",
+		"x-stainless-os":                            "MacOS",
+		"x-stainless-package-version":               "0.70.0",
+		"x-stainless-retry-count":                   "0",
+		"x-stainless-runtime":                       "node",
+		"x-stainless-runtime-version":               "v24.3.0",
+		"x-stainless-timeout":                       "600",
+	},
+	ClientProfileCodexCLI: {
+		"User-Agent":  "codex_cli_rs/0.41.0 (Mac OS 26.0.0; arm64) iTerm.app/3.6.1",
+		"Openai-Beta": "responses=experimental",
+		"Originator":  "codex_cli_rs",
+	},
+}
+
+// ParseClientProfiles 解析 client_profiles 配置并与内置 profile 合并
+// 格式：{"profile名": {"Header": "value", ...}, ...}，同名 profile 整体覆盖内置定义
+// 空字符串返回内置 profile 的副本
+func ParseClientProfiles(raw string) (map[string]ClientProfile, error) {
+	profiles := make(map[string]ClientProfile, len(BuiltinClientProfiles))
+	for name, p := range BuiltinClientProfiles {
+		profiles[name] = p
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return profiles, nil
+	}
+
+	var custom map[string]ClientProfile
+	if err := sonic.UnmarshalString(raw, &custom); err != nil {
+		return nil, fmt.Errorf("invalid client_profiles json: %w", err)
+	}
+	for name, p := range custom {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("client profile name cannot be empty")
+		}
+		for k, v := range p {
+			if strings.TrimSpace(k) == "" || strings.ContainsAny(k+v, "\r\n") {
+				return nil, fmt.Errorf("client profile %q: invalid header %q", name, k)
+			}
+		}
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// Apply 将 profile 中的请求头写入 h（覆盖同名头）
+func (p ClientProfile) Apply(h http.Header) {
+	for k, v := range p {
+		h.Set(k, v)
+	}
+}
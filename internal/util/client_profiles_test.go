@@ -0,0 +1,66 @@
+package util
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseClientProfiles(t *testing.T) {
+	t.Run("空配置返回内置profile", func(t *testing.T) {
+		profiles, err := ParseClientProfiles("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := profiles[ClientProfileClaudeCLI]; !ok {
+			t.Fatalf("missing builtin profile %s", ClientProfileClaudeCLI)
+		}
+		if _, ok := profiles[ClientProfileCodexCLI]; !ok {
+			t.Fatalf("missing builtin profile %s", ClientProfileCodexCLI)
+		}
+	})
+
+	t.Run("自定义profile覆盖内置并新增", func(t *testing.T) {
+		raw := `{"claude-cli":{"User-Agent":"claude-cli/9.9.9 (external, cli)"},"my-sdk":{"User-Agent":"my-sdk/1.0","X-Client":"x"}}`
+		profiles, err := ParseClientProfiles(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := profiles[ClientProfileClaudeCLI]["User-Agent"]; got != "claude-cli/9.9.9 (external, cli)" {
+			t.Errorf("override not applied, got %q", got)
+		}
+		if len(profiles[ClientProfileClaudeCLI]) != 1 {
+			t.Errorf("expected override to replace whole profile, got %d headers", len(profiles[ClientProfileClaudeCLI]))
+		}
+		if _, ok := profiles["my-sdk"]; !ok {
+			t.Errorf("custom profile missing")
+		}
+		// 内置定义不应被修改
+		if BuiltinClientProfiles[ClientProfileClaudeCLI]["User-Agent"] == "claude-cli/9.9.9 (external, cli)" {
+			t.Errorf("builtin profile mutated")
+		}
+	})
+
+	t.Run("非法配置", func(t *testing.T) {
+		for _, raw := range []string{
+			`not json`,
+			`{"":{"User-Agent":"x"}}`,
+			`{"p":{"X-Bad":"a\r\nInjected: 1"}}`,
+		} {
+			if _, err := ParseClientProfiles(raw); err == nil {
+				t.Errorf("expected error for %q", raw)
+			}
+		}
+	})
+}
+
+func TestClientProfileApply(t *testing.T) {
+	h := http.Header{}
+	h.Set("User-Agent", "original")
+	ClientProfile{"User-Agent": "profile-ua", "x-app": "cli"}.Apply(h)
+	if got := h.Get("User-Agent"); got != "profile-ua" {
+		t.Errorf("User-Agent = %q, want profile-ua", got)
+	}
+	if got := h.Get("X-App"); got != "cli" {
+		t.Errorf("X-App = %q, want cli", got)
+	}
+}
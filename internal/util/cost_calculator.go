@@ -1,8 +1,10 @@
 package util
 
 import (
+	"cmp"
 	"log"
 	"strings"
+	"time"
 )
 
 // ============================================================================
@@ -383,6 +385,12 @@ const (
 //
 // 返回：总成本（美元），如果模型未知则返回0.0
 func CalculateCostDetailed(model string, inputTokens, outputTokens, cacheReadTokens, cache5mTokens, cache1hTokens int) float64 {
+	return CalculateCostDetailedAt(model, time.Now(), inputTokens, outputTokens, cacheReadTokens, cache5mTokens, cache1hTokens)
+}
+
+// CalculateCostDetailedAt 按指定时间生效的定价计算成本（2026-10新增，历史费用重算按日志时间计费）
+// 价格表（/admin/pricing）中有 at 时已生效的条目时优先使用，否则使用内置定价
+func CalculateCostDetailedAt(model string, at time.Time, inputTokens, outputTokens, cacheReadTokens, cache5mTokens, cache1hTokens int) float64 {
 	// 防御性检查:拒绝负数token
 	if inputTokens < 0 || outputTokens < 0 || cacheReadTokens < 0 || cache5mTokens < 0 || cache1hTokens < 0 {
 		log.Printf("ERROR: negative tokens detected (model=%s): input=%d output=%d cache_read=%d cache_5m=%d cache_1h=%d",
@@ -390,6 +398,10 @@ func CalculateCostDetailed(model string, inputTokens, outputTokens, cacheReadTok
 		return 0.0
 	}
 
+	if override, ok := LookupPriceOverride(model, at); ok {
+		return calculateOverrideCost(model, override, inputTokens, outputTokens, cacheReadTokens, cache5mTokens, cache1hTokens)
+	}
+
 	pricing, ok := getPricing(model)
 	if !ok {
 		// 尝试模糊匹配(例如:claude-3-opus-xxx → claude-3-opus)
@@ -426,34 +438,43 @@ func CalculateCostDetailed(model string, inputTokens, outputTokens, cacheReadTok
 		cost += float64(outputTokens) * outputPricePerM / 1_000_000
 	}
 
-	// 3. 缓存读取成本（OpenAI按模型系列有不同折扣率）
+	// 缓存倍率：内置默认值（OpenAI按模型系列有不同折扣率），可由 cost_cache_multipliers 按模型覆盖
+	cacheMult := CacheCostMultipliersFor(model)
+
+	// 3. 缓存读取成本
 	if cacheReadTokens > 0 {
-		cacheMultiplier := cacheReadMultiplierClaude // Claude全系/Gemini: 10%折扣
-		if isOpenAIModel(model) {
-			// OpenAI缓存折扣率按模型系列区分（2025-12官方定价）
-			cacheMultiplier = getOpenAICacheMultiplier(model)
-		} else if isOpusModel(model) {
-			cacheMultiplier = cacheReadMultiplierOpus // Opus: 10%折扣
-		}
-		cacheReadPrice := inputPricePerM * cacheMultiplier
+		cacheReadPrice := inputPricePerM * cacheMult.Read
 		cost += float64(cacheReadTokens) * cacheReadPrice / 1_000_000
 	}
 
-	// 4. 5分钟缓存创建成本(1.25x基础价格,仅Claude支持)
+	// 4. 5分钟缓存创建成本(默认1.25x基础价格,仅Claude支持)
 	if cache5mTokens > 0 {
-		cache5mWritePrice := inputPricePerM * cacheWrite5mMultiplier
+		cache5mWritePrice := inputPricePerM * cacheMult.Write5m
 		cost += float64(cache5mTokens) * cache5mWritePrice / 1_000_000
 	}
 
-	// 5. 1小时缓存创建成本(2.0x基础价格,仅Claude支持)
+	// 5. 1小时缓存创建成本(默认2.0x基础价格,仅Claude支持)
 	if cache1hTokens > 0 {
-		cache1hWritePrice := inputPricePerM * cacheWrite1hMultiplier
+		cache1hWritePrice := inputPricePerM * cacheMult.Write1h
 		cost += float64(cache1hTokens) * cache1hWritePrice / 1_000_000
 	}
 
 	return cost
 }
 
+// calculateOverrideCost 按价格表条目计算成本（缓存价格为0时按缓存倍率由输入价推算）
+func calculateOverrideCost(model string, p PriceOverride, inputTokens, outputTokens, cacheReadTokens, cache5mTokens, cache1hTokens int) float64 {
+	cacheMult := CacheCostMultipliersFor(model)
+	cacheRead := cmp.Or(p.CacheReadPrice, p.InputPrice*cacheMult.Read)
+	cache5m := cmp.Or(p.CacheWritePrice, p.InputPrice*cacheMult.Write5m)
+	cache1h := cmp.Or(p.CacheWrite1hPrice, p.InputPrice*cacheMult.Write1h)
+	return (float64(inputTokens)*p.InputPrice +
+		float64(outputTokens)*p.OutputPrice +
+		float64(cacheReadTokens)*cacheRead +
+		float64(cache5mTokens)*cache5m +
+		float64(cache1hTokens)*cache1h) / 1_000_000
+}
+
 // isOpenAIModel 判断是否为OpenAI模型
 // OpenAI模型包括：gpt-*, o*, chatgpt-*, davinci-*, babbage-*, computer-use-preview, codex-*
 func isOpenAIModel(model string) bool {
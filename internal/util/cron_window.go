@@ -0,0 +1,152 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Cron时间窗口
+// ============================================================================
+// 用标准5段cron表达式描述"生效时间窗口"（按分钟匹配），而不是触发时刻：
+//   分 时 日 月 周
+//   * 0-7 * * *        每天 00:00-07:59
+//   * 22-23,0-5 * * 1-5 工作日夜间
+// 支持 *、数字、范围 a-b、步长 */n 与 a-b/n、逗号列表；周支持 0-7（0和7均为周日）及 sun-sat，
+// 月支持 jan-dec。日与周同时受限时按cron惯例取"或"。
+
+// CronWindow 解析后的cron时间窗口
+type CronWindow struct {
+	minutes  uint64 // bit i = 第i分钟
+	hours    uint64
+	days     uint64 // 1-31
+	months   uint64 // 1-12
+	weekdays uint64 // 0-6（周日=0）
+
+	dayRestricted     bool
+	weekdayRestricted bool
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronWeekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// ParseCronWindow 解析5段cron表达式
+func ParseCronWindow(expr string) (*CronWindow, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron %q: expected 5 fields (minute hour day month weekday)", expr)
+	}
+
+	w := &CronWindow{}
+	var err error
+	if w.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron minute field: %w", err)
+	}
+	if w.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron hour field: %w", err)
+	}
+	if w.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron day field: %w", err)
+	}
+	if w.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("invalid cron month field: %w", err)
+	}
+	if w.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return nil, fmt.Errorf("invalid cron weekday field: %w", err)
+	}
+	// 7 与 0 同为周日
+	if w.weekdays&(1<<7) != 0 {
+		w.weekdays = w.weekdays&^(1<<7) | 1
+	}
+	w.dayRestricted = fields[2] != "*" && fields[2] != "?"
+	w.weekdayRestricted = fields[4] != "*" && fields[4] != "?"
+	return w, nil
+}
+
+// Matches 判断时间点（按分钟）是否落在窗口内；时区由调用方通过 t.In(loc) 决定
+func (w *CronWindow) Matches(t time.Time) bool {
+	if w.minutes&(1<<uint(t.Minute())) == 0 ||
+		w.hours&(1<<uint(t.Hour())) == 0 ||
+		w.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dayOK := w.days&(1<<uint(t.Day())) != 0
+	weekdayOK := w.weekdays&(1<<uint(t.Weekday())) != 0
+	if w.dayRestricted && w.weekdayRestricted {
+		return dayOK || weekdayOK
+	}
+	return dayOK && weekdayOK
+}
+
+// parseCronField 解析单个字段为位图
+func parseCronField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, fmt.Errorf("empty list item in %q", field)
+		}
+
+		step := 1
+		if rangePart, stepPart, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+			part = rangePart
+		}
+
+		start, end := lo, hi
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			a, b, _ := strings.Cut(part, "-")
+			var err error
+			if start, err = parseCronValue(a, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(b, lo, hi, names); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := parseCronValue(part, lo, hi, names)
+			if err != nil {
+				return 0, err
+			}
+			start = v
+			if step == 1 {
+				end = v
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, lo, hi int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < lo || v > hi {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, lo, hi)
+	}
+	return v, nil
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronWindow_Matches(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		expr string
+		time string
+		want bool
+	}{
+		{"* * * * *", "2026-10-16 12:34", true},
+		{"* 0-7 * * *", "2026-10-16 03:00", true},
+		{"* 0-7 * * *", "2026-10-16 08:00", false},
+		{"* 22-23,0-5 * * 1-5", "2026-10-16 23:10", true},  // 周五
+		{"* 22-23,0-5 * * 1-5", "2026-10-17 23:10", false}, // 周六
+		{"* * * * sat,sun", "2026-10-18 10:00", true},      // 周日
+		{"* * * * 7", "2026-10-18 10:00", true},            // 7=周日
+		{"*/15 * * * *", "2026-10-16 10:30", true},
+		{"*/15 * * * *", "2026-10-16 10:31", false},
+		{"0-29/10 * * * *", "2026-10-16 10:20", true},
+		{"* * * oct *", "2026-10-16 10:00", true},
+		{"* * * jan-mar *", "2026-10-16 10:00", false},
+		// 日与周同时受限：满足任一即可
+		{"* * 1 * mon", "2026-10-19 10:00", true},
+		{"* * 1 * mon", "2026-11-01 10:00", true},
+		{"* * 1 * mon", "2026-10-16 10:00", false},
+	}
+	for _, tt := range tests {
+		w, err := ParseCronWindow(tt.expr)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.expr, err)
+		}
+		if got := w.Matches(at(tt.time)); got != tt.want {
+			t.Errorf("%q at %s: got %v, want %v", tt.expr, tt.time, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronWindow_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "1,,2 * * * *",
+	} {
+		if _, err := ParseCronWindow(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// 上游错误归类（2026-10新增）
+// 按状态码之外的错误语义归类，写入 logs.error_class，作为统计维度定位渠道错误率上升的原因。
+const (
+	ErrorClassAuth           = "auth_error"      // 认证/权限失败（Key失效、无权限）
+	ErrorClassQuota          = "quota_exhausted" // 配额/余额耗尽
+	ErrorClassOverloaded     = "overloaded"      // 限流/过载/暂不可用
+	ErrorClassContentFilter  = "content_filter"  // 内容安全策略拦截
+	ErrorClassInvalidRequest = "invalid_request" // 请求参数错误
+	ErrorClassNetwork        = "network"         // 网络/超时/流中断
+	ErrorClassConversionBug  = "conversion_bug"  // 协议转换产生的无效请求（本地转换失败或转换后上游拒绝）
+	ErrorClassOther          = "other"           // 无法归类的上游错误（如500）
+)
+
+// ErrorClasses 全部错误类别（用于参数校验与前端展示）
+var ErrorClasses = []string{
+	ErrorClassAuth, ErrorClassQuota, ErrorClassOverloaded, ErrorClassContentFilter,
+	ErrorClassInvalidRequest, ErrorClassNetwork, ErrorClassConversionBug, ErrorClassOther,
+}
+
+// IsErrorClass 判断是否为已知错误类别
+func IsErrorClass(s string) bool {
+	for _, c := range ErrorClasses {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
+
+// errorTaxonomyScanBytes 关键字匹配仅扫描错误体前缀（错误体通常很短，避免大响应体拖慢日志路径）
+const errorTaxonomyScanBytes = 4096
+
+// errorBodyKeywords 按优先级排列的错误体关键字（小写匹配，覆盖 Anthropic/OpenAI/Gemini 的 type/code/status 字段）
+// 顺序有意义：如 Anthropic 余额不足返回 invalid_request_error + "credit balance"，应归为配额耗尽；
+// 同一类别可出现多次以穿插优先级
+var errorBodyKeywords = []struct {
+	class    string
+	keywords [][]byte
+}{
+	{ErrorClassQuota, [][]byte{
+		[]byte("insufficient_quota"), []byte("credit balance"), []byte("exceeded your current"),
+		[]byte("payment required"), []byte("余额不足"),
+	}},
+	{ErrorClassAuth, [][]byte{
+		[]byte("authentication_error"), []byte("permission_error"), []byte("invalid_api_key"), []byte("invalid x-api-key"),
+		[]byte("api key not valid"), []byte("incorrect api key"), []byte("unauthenticated"), []byte("permission_denied"),
+		[]byte("unauthorized"), []byte("account has been disabled"), []byte("organization has been disabled"),
+	}},
+	{ErrorClassContentFilter, [][]byte{
+		[]byte("content_filter"), []byte("content_policy"), []byte("content management policy"),
+		[]byte("responsibleaipolicyviolation"), []byte("prohibited_content"), []byte(`"safety"`),
+		[]byte("blocklist"), []byte("moderation"),
+	}},
+	{ErrorClassOverloaded, [][]byte{
+		[]byte("overloaded"), []byte("rate_limit"), []byte("rate limit"), []byte("too many requests"),
+		[]byte("per minute"), []byte("capacity"), []byte("server is busy"), []byte("try again later"),
+	}},
+	// 通用配额字样放在限流之后：Gemini 按分钟限流的错误体同样包含 quota
+	{ErrorClassQuota, [][]byte{
+		[]byte("quota"), []byte("billing"), []byte("resource_exhausted"), []byte("额度"),
+	}},
+	{ErrorClassInvalidRequest, [][]byte{
+		[]byte("invalid_request"), []byte("invalid_argument"), []byte("not_found_error"), []byte("model_not_found"),
+		[]byte("request_too_large"), []byte("context_length_exceeded"), []byte("prompt is too long"),
+	}},
+}
+
+// ClassifyUpstreamError 按状态码与错误体归类上游错误（2xx 返回空串）
+// 错误体关键字优先于状态码：同一状态码（如400、429）在不同上游可能代表不同原因。
+func ClassifyUpstreamError(statusCode int, body []byte) string {
+	if statusCode == 0 || (statusCode >= 200 && statusCode < 300) {
+		return ""
+	}
+	if statusCode == StatusQuotaExceeded {
+		return ErrorClassQuota
+	}
+
+	if len(body) > errorTaxonomyScanBytes {
+		body = body[:errorTaxonomyScanBytes]
+	}
+	if len(body) > 0 {
+		lower := bytes.ToLower(body)
+		for _, group := range errorBodyKeywords {
+			for _, kw := range group.keywords {
+				if bytes.Contains(lower, kw) {
+					return group.class
+				}
+			}
+		}
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorClassAuth
+	case http.StatusPaymentRequired:
+		return ErrorClassQuota
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, 529:
+		return ErrorClassOverloaded
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return ErrorClassInvalidRequest
+	case StatusClientClosedRequest, http.StatusBadGateway, http.StatusGatewayTimeout,
+		StatusCertPinMismatch, StatusFirstByteTimeout, StatusStreamIncomplete:
+		return ErrorClassNetwork
+	}
+	return ErrorClassOther
+}
@@ -0,0 +1,37 @@
+package util
+
+import "testing"
+
+func TestClassifyUpstreamError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"success", 200, `{"id":"x"}`, ""},
+		{"anthropic auth", 401, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, ErrorClassAuth},
+		{"forbidden without body", 403, "", ErrorClassAuth},
+		{"anthropic credit balance", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"Your credit balance is too low"}}`, ErrorClassQuota},
+		{"openai insufficient quota", 429, `{"error":{"type":"insufficient_quota","code":"insufficient_quota"}}`, ErrorClassQuota},
+		{"1308 quota", StatusQuotaExceeded, "", ErrorClassQuota},
+		{"gemini per-minute limit", 429, `{"error":{"status":"RESOURCE_EXHAUSTED","message":"Quota exceeded for requests per minute"}}`, ErrorClassOverloaded},
+		{"gemini daily quota", 429, `{"error":{"status":"RESOURCE_EXHAUSTED","message":"Quota exceeded for metric generate_requests_per_day"}}`, ErrorClassQuota},
+		{"anthropic overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrorClassOverloaded},
+		{"503 without body", 503, "", ErrorClassOverloaded},
+		{"azure content filter", 400, `{"error":{"code":"content_filter","message":"The response was filtered"}}`, ErrorClassContentFilter},
+		{"gemini safety block", 400, `{"promptFeedback":{"blockReason":"SAFETY"}}`, ErrorClassContentFilter},
+		{"invalid request", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: field required"}}`, ErrorClassInvalidRequest},
+		{"not found without body", 404, "", ErrorClassInvalidRequest},
+		{"bad gateway", 502, "", ErrorClassNetwork},
+		{"first byte timeout", StatusFirstByteTimeout, "", ErrorClassNetwork},
+		{"internal server error", 500, `{"error":"boom"}`, ErrorClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyUpstreamError(tt.status, []byte(tt.body)); got != tt.want {
+				t.Errorf("ClassifyUpstreamError(%d, %q) = %q, want %q", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package util
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// Google Cloud API Keys 客户端
+// ============================================================================
+// 使用服务账号（JWT Bearer 授权）调用 apikeys.googleapis.com v2，
+// 用于在项目中列出/创建/删除 Gemini API Key。服务账号需要 roles/serviceusage.apiKeysAdmin 权限。
+// 只依赖标准库，不引入 Google SDK。
+
+const (
+	gcpDefaultTokenURI  = "https://oauth2.googleapis.com/token"
+	gcpAPIKeysBaseURL   = "https://apikeys.googleapis.com/v2"
+	gcpCloudScope       = "https://www.googleapis.com/auth/cloud-platform"
+	gcpGeminiService    = "generativelanguage.googleapis.com"
+	gcpOperationTimeout = 60 * time.Second
+)
+
+// GCPServiceAccount 服务账号凭据（JSON key 文件的必要字段）
+type GCPServiceAccount struct {
+	Type        string `json:"type"`
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+}
+
+// ParseGCPServiceAccount 解析服务账号JSON并校验私钥
+func ParseGCPServiceAccount(data string) (*GCPServiceAccount, error) {
+	var sa GCPServiceAccount
+	if err := json.Unmarshal([]byte(data), &sa); err != nil {
+		return nil, fmt.Errorf("invalid service account JSON: %w", err)
+	}
+	if sa.Type != "" && sa.Type != "service_account" {
+		return nil, fmt.Errorf("credentials type must be service_account, got %q", sa.Type)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, errors.New("service account JSON missing client_email or private_key")
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, errors.New("service account private_key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if rsaKey, err2 := x509.ParsePKCS1PrivateKey(block.Bytes); err2 == nil {
+			parsed = rsaKey
+		} else {
+			return nil, fmt.Errorf("parse service account private_key: %w", err)
+		}
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private_key must be an RSA key")
+	}
+	sa.key = rsaKey
+	if sa.TokenURI == "" {
+		sa.TokenURI = gcpDefaultTokenURI
+	}
+	return &sa, nil
+}
+
+// GCPAPIKey API Key 资源（只包含用到的字段）
+type GCPAPIKey struct {
+	Name        string `json:"name"` // projects/{p}/locations/global/keys/{id}
+	UID         string `json:"uid"`
+	DisplayName string `json:"displayName"`
+	CreateTime  string `json:"createTime"`
+}
+
+// GCPAPIKeysClient API Keys 客户端（访问令牌自动缓存与续期）
+type GCPAPIKeysClient struct {
+	tokens  *GCPTokenSource
+	client  *http.Client
+	baseURL string
+}
+
+// NewGCPAPIKeysClient 创建客户端；baseURL 为空时使用官方地址（测试时可替换）
+func NewGCPAPIKeysClient(sa *GCPServiceAccount, client *http.Client, baseURL string) *GCPAPIKeysClient {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if baseURL == "" {
+		baseURL = gcpAPIKeysBaseURL
+	}
+	return &GCPAPIKeysClient{tokens: NewGCPTokenSource(sa, client), client: client, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// call 发起带授权的API请求，out 为 nil 时忽略响应体
+func (c *GCPAPIKeysClient) call(ctx context.Context, method, path string, body any, out any) error {
+	tok, err := c.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = strings.NewReader(string(data))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/"+strings.TrimLeft(path, "/"), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.doJSON(req, out)
+}
+
+func (c *GCPAPIKeysClient) doJSON(req *http.Request, out any) error {
+	return gcpDoJSON(c.client, req, out)
+}
+
+// gcpDoJSON 发送请求并解析JSON响应，非2xx返回包含响应体的错误；out 为 nil 时忽略响应体
+func gcpDoJSON(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func gcpKeysParent(projectID string) string {
+	return "projects/" + url.PathEscape(projectID) + "/locations/global"
+}
+
+// ListKeys 列出项目中的全部API Key（自动翻页，不含已删除的Key）
+func (c *GCPAPIKeysClient) ListKeys(ctx context.Context, projectID string) ([]GCPAPIKey, error) {
+	var all []GCPAPIKey
+	pageToken := ""
+	for {
+		path := gcpKeysParent(projectID) + "/keys?pageSize=300"
+		if pageToken != "" {
+			path += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		var out struct {
+			Keys          []GCPAPIKey `json:"keys"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := c.call(ctx, http.MethodGet, path, nil, &out); err != nil {
+			return nil, fmt.Errorf("list api keys: %w", err)
+		}
+		all = append(all, out.Keys...)
+		if out.NextPageToken == "" {
+			return all, nil
+		}
+		pageToken = out.NextPageToken
+	}
+}
+
+// CreateGeminiKey 创建仅限 Gemini API 使用的Key，等待长操作完成后返回Key资源与明文
+func (c *GCPAPIKeysClient) CreateGeminiKey(ctx context.Context, projectID, keyID, displayName string) (*GCPAPIKey, string, error) {
+	body := map[string]any{
+		"displayName": displayName,
+		"restrictions": map[string]any{
+			"apiTargets": []map[string]string{{"service": gcpGeminiService}},
+		},
+	}
+	var op gcpOperation
+	if err := c.call(ctx, http.MethodPost, gcpKeysParent(projectID)+"/keys?keyId="+url.QueryEscape(keyID), body, &op); err != nil {
+		return nil, "", fmt.Errorf("create api key: %w", err)
+	}
+	if err := c.waitOperation(ctx, &op); err != nil {
+		return nil, "", fmt.Errorf("create api key: %w", err)
+	}
+	var key GCPAPIKey
+	if err := json.Unmarshal(op.Response, &key); err != nil || key.Name == "" {
+		return nil, "", errors.New("create api key: operation returned no key")
+	}
+	keyString, err := c.KeyString(ctx, key.Name)
+	if err != nil {
+		return &key, "", err
+	}
+	return &key, keyString, nil
+}
+
+// KeyString 获取Key明文
+func (c *GCPAPIKeysClient) KeyString(ctx context.Context, name string) (string, error) {
+	var out struct {
+		KeyString string `json:"keyString"`
+	}
+	if err := c.call(ctx, http.MethodGet, name+"/keyString", nil, &out); err != nil {
+		return "", fmt.Errorf("get key string: %w", err)
+	}
+	if out.KeyString == "" {
+		return "", errors.New("get key string: empty keyString")
+	}
+	return out.KeyString, nil
+}
+
+// DeleteKey 删除Key（Google侧为软删除，30天内可恢复）
+func (c *GCPAPIKeysClient) DeleteKey(ctx context.Context, name string) error {
+	var op gcpOperation
+	if err := c.call(ctx, http.MethodDelete, name, nil, &op); err != nil {
+		return fmt.Errorf("delete api key: %w", err)
+	}
+	return nil
+}
+
+// gcpOperation 长操作
+type gcpOperation struct {
+	Name     string          `json:"name"`
+	Done     bool            `json:"done"`
+	Response json.RawMessage `json:"response"`
+	Error    *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// waitOperation 轮询长操作直到完成
+func (c *GCPAPIKeysClient) waitOperation(ctx context.Context, op *gcpOperation) error {
+	ctx, cancel := context.WithTimeout(ctx, gcpOperationTimeout)
+	defer cancel()
+
+	delay := 500 * time.Millisecond
+	for !op.Done {
+		if op.Name == "" {
+			return errors.New("operation has no name")
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait operation %s: %w", op.Name, ctx.Err())
+		case <-time.After(delay):
+		}
+		if err := c.call(ctx, http.MethodGet, op.Name, nil, op); err != nil {
+			return err
+		}
+		delay = min(delay*2, 5*time.Second)
+	}
+	if op.Error != nil {
+		return fmt.Errorf("operation failed (code %d): %s", op.Error.Code, op.Error.Message)
+	}
+	return nil
+}
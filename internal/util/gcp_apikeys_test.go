@@ -0,0 +1,143 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testServiceAccountJSON(t *testing.T, tokenURI string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	data, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "proj-1",
+		"client_email": "sa@proj-1.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	return string(data)
+}
+
+func TestParseGCPServiceAccount(t *testing.T) {
+	sa, err := ParseGCPServiceAccount(testServiceAccountJSON(t, ""))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if sa.ProjectID != "proj-1" || sa.TokenURI != gcpDefaultTokenURI {
+		t.Fatalf("unexpected account: %+v", sa)
+	}
+
+	for _, bad := range []string{
+		"not json",
+		`{"type":"authorized_user","client_email":"a","private_key":"b"}`,
+		`{"client_email":"a"}`,
+		`{"client_email":"a","private_key":"not pem"}`,
+	} {
+		if _, err := ParseGCPServiceAccount(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestGCPAPIKeysClient(t *testing.T) {
+	var mu sync.Mutex
+	tokenCalls := 0
+	keys := map[string]string{} // name -> keyString
+	var createBody map[string]any
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.Form.Get("assertion"), ".") != 2 {
+			http.Error(w, "bad grant", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		tokenCalls++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/v2/")
+		switch {
+		case r.Method == http.MethodPost && path == "projects/proj-1/locations/global/keys":
+			_ = json.NewDecoder(r.Body).Decode(&createBody)
+			name := "projects/proj-1/locations/global/keys/" + r.URL.Query().Get("keyId")
+			keys[name] = "AIzaSecret-" + r.URL.Query().Get("keyId")
+			_, _ = w.Write([]byte(`{"name":"operations/op-1","done":false}`))
+		case r.Method == http.MethodGet && path == "operations/op-1":
+			_, _ = w.Write([]byte(`{"name":"operations/op-1","done":true,"response":{"name":"projects/proj-1/locations/global/keys/k1"}}`))
+		case r.Method == http.MethodGet && path == "projects/proj-1/locations/global/keys":
+			var out []GCPAPIKey
+			for name := range keys {
+				out = append(out, GCPAPIKey{Name: name})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": out})
+		case r.Method == http.MethodGet && strings.HasSuffix(path, "/keyString"):
+			_ = json.NewEncoder(w).Encode(map[string]string{"keyString": keys[strings.TrimSuffix(path, "/keyString")]})
+		case r.Method == http.MethodDelete:
+			delete(keys, path)
+			_, _ = w.Write([]byte(`{"name":"operations/op-2","done":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	sa, err := ParseGCPServiceAccount(testServiceAccountJSON(t, srv.URL+"/token"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	client := NewGCPAPIKeysClient(sa, srv.Client(), srv.URL+"/v2")
+	ctx := context.Background()
+
+	key, keyString, err := client.CreateGeminiKey(ctx, "proj-1", "k1", "test")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if key.Name != "projects/proj-1/locations/global/keys/k1" || keyString != "AIzaSecret-k1" {
+		t.Fatalf("unexpected key: %+v %q", key, keyString)
+	}
+	targets, _ := createBody["restrictions"].(map[string]any)["apiTargets"].([]any)
+	if len(targets) != 1 || targets[0].(map[string]any)["service"] != gcpGeminiService {
+		t.Fatalf("key must be restricted to Gemini API, got %v", createBody["restrictions"])
+	}
+
+	list, err := client.ListKeys(ctx, "proj-1")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("list: %v %v", list, err)
+	}
+	if err := client.DeleteKey(ctx, key.Name); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if list, _ := client.ListKeys(ctx, "proj-1"); len(list) != 0 {
+		t.Fatalf("key not deleted: %v", list)
+	}
+	if tokenCalls != 1 {
+		t.Fatalf("access token should be cached, got %d exchanges", tokenCalls)
+	}
+}
@@ -0,0 +1,113 @@
+package util
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gcpTokenRenewBefore 访问令牌提前续期时间
+const gcpTokenRenewBefore = time.Minute
+
+// GCPTokenSource 服务账号访问令牌（JWT Bearer 授权换取，自动缓存与续期，2026-10新增）
+// 供 API Keys 客户端与 Vertex AI 渠道共用。
+type GCPTokenSource struct {
+	sa     *GCPServiceAccount
+	client *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewGCPTokenSource 创建令牌源；client 为 nil 时使用30秒超时的默认客户端
+func NewGCPTokenSource(sa *GCPServiceAccount, client *http.Client) *GCPTokenSource {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &GCPTokenSource{sa: sa, client: client}
+}
+
+// Token 获取访问令牌（提前1分钟续期）
+func (t *GCPTokenSource) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.accessToken != "" && time.Now().Add(gcpTokenRenewBefore).Before(t.tokenExpiry) {
+		return t.accessToken, nil
+	}
+	return t.exchangeLocked(ctx)
+}
+
+// RefreshIfExpiring 已签发的令牌将在 window 内过期时提前换取新令牌（从未签发过则不处理）
+// 返回是否发生了续期，供后台刷新循环使用，避免请求路径上同步等待令牌交换。
+func (t *GCPTokenSource) RefreshIfExpiring(ctx context.Context, window time.Duration) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.accessToken == "" || time.Now().Add(window).Before(t.tokenExpiry) {
+		return false, nil
+	}
+	if _, err := t.exchangeLocked(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (t *GCPTokenSource) exchangeLocked(ctx context.Context) (string, error) {
+	assertion, err := t.signJWT(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.sa.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := gcpDoJSON(t.client, req, &out); err != nil {
+		return "", fmt.Errorf("exchange service account token: %w", err)
+	}
+	if out.AccessToken == "" {
+		return "", errors.New("exchange service account token: empty access_token")
+	}
+	t.accessToken = out.AccessToken
+	t.tokenExpiry = time.Now().Add(time.Duration(out.ExpiresIn) * time.Second)
+	return t.accessToken, nil
+}
+
+// signJWT 构造 RS256 签名的授权断言
+func (t *GCPTokenSource) signJWT(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   t.sa.ClientEmail,
+		"scope": gcpCloudScope,
+		"aud":   t.sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(nil, t.sa.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign service account JWT: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,139 @@
+package util
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 客户端地域识别（2026-10新增）
+// ============================================================================
+// 按客户端IP将请求映射到地域，路由时优先选择带相同地域标签的渠道（延迟敏感的EU/US客户）。
+// geo_regions 配置格式：{"地域名": ["CIDR", ...], ...}，例如
+//   {"eu": ["2.16.0.0/13", "2a02:2e0::/29"], "us": ["3.0.0.0/9"]}
+// 多个地域的CIDR重叠时最长前缀优先；未命中任何CIDR的客户端不做地域优先。
+
+// regionNamePattern 地域名：小写字母/数字/下划线/连字符，最长32字符
+var regionNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// NormalizeRegionTags 校验并规范化渠道地域标签（逗号分隔，转小写、去重、保持顺序）
+// 空输入返回空字符串（全局渠道）
+func NormalizeRegionTags(raw string) (string, error) {
+	var tags []string
+	seen := make(map[string]struct{})
+	for part := range strings.SplitSeq(raw, ",") {
+		tag := strings.ToLower(strings.TrimSpace(part))
+		if tag == "" {
+			continue
+		}
+		if !regionNamePattern.MatchString(tag) {
+			return "", fmt.Errorf("invalid region %q: use lowercase letters, digits, '_' or '-' (max 32)", tag)
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	joined := strings.Join(tags, ",")
+	if len(joined) > 255 {
+		return "", fmt.Errorf("regions too long (max 255)")
+	}
+	return joined, nil
+}
+
+// HasRegionTag 判断逗号分隔的地域标签中是否包含 region（标签已规范化）
+func HasRegionTag(tags, region string) bool {
+	if tags == "" || region == "" {
+		return false
+	}
+	for tag := range strings.SplitSeq(tags, ",") {
+		if tag == region {
+			return true
+		}
+	}
+	return false
+}
+
+type geoRegionPrefix struct {
+	prefix netip.Prefix
+	region string
+}
+
+// GeoRegionTable CIDR → 地域映射（只读，可并发使用；nil 表示未配置）
+type GeoRegionTable struct {
+	prefixes []geoRegionPrefix // 按前缀长度降序
+}
+
+// ParseGeoRegions 解析 geo_regions 配置；空字符串返回 nil（不启用地域路由）
+func ParseGeoRegions(raw string) (*GeoRegionTable, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var m map[string][]string
+	if err := sonic.UnmarshalString(raw, &m); err != nil {
+		return nil, fmt.Errorf("invalid geo_regions json: %w", err)
+	}
+	t := &GeoRegionTable{}
+	for name, cidrs := range m {
+		region := strings.ToLower(strings.TrimSpace(name))
+		if !regionNamePattern.MatchString(region) {
+			return nil, fmt.Errorf("geo_regions: invalid region name %q", name)
+		}
+		for _, cidr := range cidrs {
+			p, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+			if err != nil {
+				return nil, fmt.Errorf("geo_regions: region %q: invalid cidr %q", region, cidr)
+			}
+			t.prefixes = append(t.prefixes, geoRegionPrefix{prefix: p.Masked(), region: region})
+		}
+	}
+	if len(t.prefixes) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(t.prefixes, func(i, j int) bool {
+		return t.prefixes[i].prefix.Bits() > t.prefixes[j].prefix.Bits()
+	})
+	return t, nil
+}
+
+// Lookup 返回客户端IP所属地域；未命中或IP无效返回空字符串
+func (t *GeoRegionTable) Lookup(ip string) string {
+	if t == nil {
+		return ""
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap() // ::ffff:1.2.3.4 按IPv4匹配
+	for _, p := range t.prefixes {
+		if p.prefix.Contains(addr) {
+			return p.region
+		}
+	}
+	return ""
+}
+
+// Regions 返回已配置的地域名（排序去重）
+func (t *GeoRegionTable) Regions() []string {
+	if t == nil {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var out []string
+	for _, p := range t.prefixes {
+		if _, ok := seen[p.region]; !ok {
+			seen[p.region] = struct{}{}
+			out = append(out, p.region)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeRegionTags(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: " EU , us,eu,, ", want: "eu,us"},
+		{in: "ap-southeast_1", want: "ap-southeast_1"},
+		{in: "eu west", wantErr: true},
+		{in: "-eu", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := NormalizeRegionTags(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeRegionTags(%q) expected error, got %q", tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("NormalizeRegionTags(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+	if !HasRegionTag("eu,us", "us") || HasRegionTag("eu,us", "u") || HasRegionTag("", "eu") {
+		t.Fatal("HasRegionTag mismatch")
+	}
+}
+
+func TestParseGeoRegions(t *testing.T) {
+	if tbl, err := ParseGeoRegions("  "); err != nil || tbl != nil {
+		t.Fatalf("empty config should disable geo routing, got %v, %v", tbl, err)
+	}
+	for _, bad := range []string{`{`, `{"EU WEST":["10.0.0.0/8"]}`, `{"eu":["10.0.0.0"]}`} {
+		if _, err := ParseGeoRegions(bad); err == nil {
+			t.Errorf("ParseGeoRegions(%q) expected error", bad)
+		}
+	}
+
+	tbl, err := ParseGeoRegions(`{"EU":["10.0.0.0/8","2001:db8::/32"],"office":["10.1.2.0/24"]}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cases := map[string]string{
+		"10.9.9.9":        "eu",
+		"10.1.2.3":        "office", // 最长前缀优先
+		"::ffff:10.9.9.9": "eu",
+		"2001:db8::1":     "eu",
+		"192.168.1.1":     "",
+		"not-an-ip":       "",
+	}
+	for ip, want := range cases {
+		if got := tbl.Lookup(ip); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", ip, got, want)
+		}
+	}
+	if got := tbl.Regions(); !reflect.DeepEqual(got, []string{"eu", "office"}) {
+		t.Fatalf("Regions() = %v", got)
+	}
+	var nilTbl *GeoRegionTable
+	if nilTbl.Lookup("10.0.0.1") != "" {
+		t.Fatal("nil table should not match")
+	}
+}
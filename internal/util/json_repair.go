@@ -0,0 +1,309 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ============================================================================
+// JSON模式输出修复与Schema校验
+// ============================================================================
+// 客户端要求结构化JSON输出时，部分上游仍会在JSON前后夹带说明文字或 ```json 代码块。
+// RepairJSONOutput 剥离代码块、提取第一个合法的JSON值，并按客户端提供的 JSON Schema 校验。
+// Schema 校验只覆盖结构化输出常用子集：type/properties/required/additionalProperties/
+// items/enum/const/minItems/maxItems/minLength/maxLength/minimum/maximum/anyOf，
+// 其余关键字（$ref、pattern、format 等）忽略；type 不区分大小写（兼容 Gemini responseSchema 的 OBJECT/STRING）。
+
+// ErrJSONOutputInvalid 模型输出无法修复为满足要求的JSON
+var ErrJSONOutputInvalid = errors.New("model output is not valid JSON")
+
+// jsonFencePattern 匹配 markdown 代码块（```json ... ``` / ``` ... ```）
+var jsonFencePattern = regexp.MustCompile("(?s)```[A-Za-z0-9_-]*[ \t]*\r?\n?(.*?)```")
+
+// RepairJSONOutput 修复模型输出的JSON文本
+// schema 为 nil 时只要求是合法JSON对象/数组（json_object模式）
+// 返回修复后的文本与是否发生改写；无法修复时返回 ErrJSONOutputInvalid 包装的错误
+func RepairJSONOutput(text string, schema map[string]any) (string, bool, error) {
+	var lastErr error
+	for _, candidate := range jsonCandidates(text) {
+		value, raw, ok := decodeFirstJSON(candidate)
+		if !ok {
+			continue
+		}
+		if schema != nil {
+			if err := ValidateJSONSchema(value, schema); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		return raw, raw != text, nil
+	}
+	if lastErr != nil {
+		return text, false, fmt.Errorf("%w: %v", ErrJSONOutputInvalid, lastErr)
+	}
+	return text, false, fmt.Errorf("%w: no JSON object found in output", ErrJSONOutputInvalid)
+}
+
+// jsonCandidates 按优先级返回待解析的文本片段：原文 → 各代码块内容
+func jsonCandidates(text string) []string {
+	candidates := []string{text}
+	for _, m := range jsonFencePattern.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, m[1])
+	}
+	return candidates
+}
+
+// decodeFirstJSON 从文本中提取第一个可完整解析的JSON对象/数组
+// 返回解析值与对应的原文片段（保留字段顺序与格式）
+func decodeFirstJSON(text string) (any, string, bool) {
+	trimmed := strings.TrimSpace(text)
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] != '{' && trimmed[i] != '[' {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(trimmed[i:]))
+		dec.UseNumber()
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			continue
+		}
+		var value any
+		vdec := json.NewDecoder(bytes.NewReader(raw))
+		vdec.UseNumber()
+		if err := vdec.Decode(&value); err != nil {
+			continue
+		}
+		if i == 0 && int(dec.InputOffset()) == len(trimmed) {
+			return value, trimmed, true
+		}
+		return value, string(raw), true
+	}
+	return nil, "", false
+}
+
+// ValidateJSONSchema 按 JSON Schema 常用子集校验已解析的值（数字需以 json.Number 或 float64 表示）
+func ValidateJSONSchema(value any, schema map[string]any) error {
+	return validateSchemaAt("$", value, schema)
+}
+
+func validateSchemaAt(path string, value any, schema map[string]any) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok && len(anyOf) > 0 {
+		matched := false
+		for _, sub := range anyOf {
+			if subSchema, ok := sub.(map[string]any); ok && validateSchemaAt(path, value, subSchema) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: does not match any schema in anyOf", path)
+		}
+	}
+
+	if err := checkSchemaType(path, value, schema["type"]); err != nil {
+		return err
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsJSONValue(enum, value) {
+		return fmt.Errorf("%s: value is not one of the allowed enum values", path)
+	}
+	if c, ok := schema["const"]; ok && !jsonValuesEqual(c, value) {
+		return fmt.Errorf("%s: value does not equal const", path)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return validateSchemaObject(path, v, schema)
+	case []any:
+		if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < n {
+			return fmt.Errorf("%s: expected at least %v items, got %d", path, n, len(v))
+		}
+		if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > n {
+			return fmt.Errorf("%s: expected at most %v items, got %d", path, n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchemaAt(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := len([]rune(v))
+		if n, ok := schemaNumber(schema["minLength"]); ok && float64(length) < n {
+			return fmt.Errorf("%s: string shorter than minLength %v", path, n)
+		}
+		if n, ok := schemaNumber(schema["maxLength"]); ok && float64(length) > n {
+			return fmt.Errorf("%s: string longer than maxLength %v", path, n)
+		}
+	default:
+		if num, ok := schemaNumber(value); ok {
+			if n, ok := schemaNumber(schema["minimum"]); ok && num < n {
+				return fmt.Errorf("%s: %v is less than minimum %v", path, num, n)
+			}
+			if n, ok := schemaNumber(schema["maximum"]); ok && num > n {
+				return fmt.Errorf("%s: %v is greater than maximum %v", path, num, n)
+			}
+		}
+	}
+	return nil
+}
+
+func validateSchemaObject(path string, obj map[string]any, schema map[string]any) error {
+	props, _ := schema["properties"].(map[string]any)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := obj[name]; name != "" && !present {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+	}
+
+	// 按键名排序，保证错误信息稳定
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		propSchema, known := props[k].(map[string]any)
+		if known {
+			if err := validateSchemaAt(path+"."+k, obj[k], propSchema); err != nil {
+				return err
+			}
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return fmt.Errorf("%s: unexpected property %q", path, k)
+			}
+		case map[string]any:
+			if err := validateSchemaAt(path+"."+k, obj[k], extra); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkSchemaType 校验 type 关键字（支持字符串或字符串数组）
+func checkSchemaType(path string, value any, typ any) error {
+	var types []string
+	switch t := typ.(type) {
+	case string:
+		types = []string{strings.ToLower(t)}
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, strings.ToLower(s))
+			}
+		}
+	default:
+		return nil
+	}
+	for _, t := range types {
+		if jsonValueHasType(value, t) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected type %s, got %s", path, strings.Join(types, "|"), jsonTypeName(value))
+}
+
+func jsonValueHasType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := schemaNumber(value)
+		return ok
+	case "integer":
+		n, ok := schemaNumber(value)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	if _, ok := schemaNumber(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaNumber 将 json.Number / float64 / int 统一转换为 float64
+func schemaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func containsJSONValue(list []any, value any) bool {
+	for _, item := range list {
+		if jsonValuesEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValuesEqual 比较两个JSON值（数字按数值比较，复合类型按序列化结果比较）
+func jsonValuesEqual(a, b any) bool {
+	if na, ok := schemaNumber(a); ok {
+		nb, ok := schemaNumber(b)
+		return ok && na == nb
+	}
+	switch a.(type) {
+	case map[string]any, []any:
+		ja, errA := json.Marshal(a)
+		jb, errB := json.Marshal(b)
+		return errA == nil && errB == nil && bytes.Equal(ja, jb)
+	}
+	return a == b
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bytedance/sonic"
+)
+
+func mustSchema(t *testing.T, raw string) map[string]any {
+	t.Helper()
+	var schema map[string]any
+	if err := sonic.UnmarshalString(raw, &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	return schema
+}
+
+func TestRepairJSONOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		changed bool
+	}{
+		{"already valid", `{"a":1}`, `{"a":1}`, false},
+		{"surrounding whitespace", "  {\"a\":1}\n", `{"a":1}`, true},
+		{"markdown fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`, true},
+		{"fence without language", "```\n[1,2]\n```", `[1,2]`, true},
+		{"leading prose", "Here is the result: {\"a\":{\"b\":[1]}} hope it helps", `{"a":{"b":[1]}}`, true},
+		{"brace in prose before json", "Use {braces} wisely: {\"ok\":true}", `{"ok":true}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := RepairJSONOutput(tt.in, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want || changed != tt.changed {
+				t.Fatalf("got (%q, %v), want (%q, %v)", got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestRepairJSONOutput_Irreparable(t *testing.T) {
+	for _, in := range []string{"", "sorry, I cannot do that", `{"a":`} {
+		if _, _, err := RepairJSONOutput(in, nil); !errors.Is(err, ErrJSONOutputInvalid) {
+			t.Fatalf("%q: expected ErrJSONOutputInvalid, got %v", in, err)
+		}
+	}
+}
+
+func TestRepairJSONOutput_SchemaPicksMatchingCandidate(t *testing.T) {
+	schema := mustSchema(t, `{"type":"object","required":["name"]}`)
+	in := "Example: {\"x\":1}\n```json\n{\"name\":\"ok\"}\n```"
+	got, _, err := RepairJSONOutput(in, schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != `{"name":"ok"}` {
+		t.Fatalf("got %q", got)
+	}
+
+	if _, _, err := RepairJSONOutput(`{"x":1}`, schema); !errors.Is(err, ErrJSONOutputInvalid) {
+		t.Fatalf("expected schema failure, got %v", err)
+	}
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := mustSchema(t, `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"age": {"type": "integer", "minimum": 0},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"kind": {"enum": ["a", "b"]},
+			"note": {"type": ["string", "null"]}
+		},
+		"required": ["name", "age"],
+		"additionalProperties": false
+	}`)
+
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"valid", `{"name":"x","age":3,"tags":["t"],"kind":"a","note":null}`, true},
+		{"missing required", `{"name":"x"}`, false},
+		{"wrong type", `{"name":"x","age":"3"}`, false},
+		{"non integer", `{"name":"x","age":1.5}`, false},
+		{"below minimum", `{"name":"x","age":-1}`, false},
+		{"empty string", `{"name":"","age":1}`, false},
+		{"bad item", `{"name":"x","age":1,"tags":[1]}`, false},
+		{"too many items", `{"name":"x","age":1,"tags":["a","b","c"]}`, false},
+		{"enum mismatch", `{"name":"x","age":1,"kind":"c"}`, false},
+		{"additional property", `{"name":"x","age":1,"extra":true}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := RepairJSONOutput(tt.value, schema)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ok=%v, err=%v", tt.ok, err)
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchema_GeminiUppercaseTypes(t *testing.T) {
+	schema := mustSchema(t, `{"type":"OBJECT","properties":{"n":{"type":"NUMBER"}}}`)
+	if _, _, err := RepairJSONOutput(`{"n":2}`, schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := RepairJSONOutput(`{"n":"2"}`, schema); err == nil {
+		t.Fatal("expected type mismatch")
+	}
+}
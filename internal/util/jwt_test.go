@@ -0,0 +1,85 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func b64url(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func signTestJWT(t *testing.T, alg, kid string, key crypto.Signer, payload string) string {
+	t.Helper()
+	signed := b64url([]byte(`{"alg":"`+alg+`","kid":"`+kid+`"}`)) + "." + b64url([]byte(payload))
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + b64url(sig)
+}
+
+func TestVerifyJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	jwks := `{"keys":[` +
+		`{"kty":"RSA","kid":"r1","use":"sig","n":"` + b64url(rsaKey.N.Bytes()) + `","e":"` + b64url(big.NewInt(int64(rsaKey.E)).Bytes()) + `"},` +
+		`{"kty":"EC","kid":"e1","crv":"P-256","x":"` + b64url(ecKey.X.FillBytes(make([]byte, 32))) + `","y":"` + b64url(ecKey.Y.FillBytes(make([]byte, 32))) + `"},` +
+		`{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"}]}`
+	keys, err := ParseJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatalf("parse jwks: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 signing keys (encryption key skipped), got %d", len(keys))
+	}
+
+	for _, tc := range []struct {
+		alg, kid string
+		key      crypto.Signer
+	}{{"RS256", "r1", rsaKey}, {"ES256", "e1", ecKey}} {
+		token := signTestJWT(t, tc.alg, tc.kid, tc.key, `{"sub":"alice"}`)
+		claims, err := VerifyJWT(token, keys)
+		if err != nil || claims["sub"] != "alice" {
+			t.Fatalf("%s: expected valid token, got %v %v", tc.alg, claims, err)
+		}
+
+		parts := strings.Split(token, ".")
+		tampered := parts[0] + "." + b64url([]byte(`{"sub":"mallory"}`)) + "." + parts[2]
+		if _, err := VerifyJWT(tampered, keys); err == nil {
+			t.Fatalf("%s: tampered payload should fail verification", tc.alg)
+		}
+	}
+
+	if _, err := VerifyJWT(signTestJWT(t, "RS256", "rotated", rsaKey, `{}`), keys); !errors.Is(err, ErrJWTUnknownKey) {
+		t.Fatalf("unknown kid should return ErrJWTUnknownKey, got %v", err)
+	}
+	// alg 与密钥类型不匹配（如 ES256 头部配 RSA 密钥）必须拒绝
+	if _, err := VerifyJWT(signTestJWT(t, "ES256", "r1", rsaKey, `{}`), keys); err == nil {
+		t.Fatal("alg/key type mismatch should fail")
+	}
+	none := b64url([]byte(`{"alg":"none","kid":"r1"}`)) + "." + b64url([]byte(`{}`)) + "."
+	if _, err := VerifyJWT(none, keys); err == nil {
+		t.Fatal("alg=none must be rejected")
+	}
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// ============================================================================
+// 渠道出站地址绑定
+// ============================================================================
+// 多出口主机上，部分渠道需要固定从某个本机IP（或网卡）出站（计费/地域要求不同）。
+// local_addr 支持两种写法：
+//   - IP字面量：203.0.113.10、2001:db8::10
+//   - 网卡名：eth1（拨号时取该网卡当前第一个全局单播地址，优先IPv4）
+
+// ErrLocalAddrUnavailable 渠道配置的出站地址在本机不可用（网卡不存在/无地址/IP未分配）
+var ErrLocalAddrUnavailable = errors.New("channel local address unavailable")
+
+// interfaceNamePattern 网卡名（Linux IFNAMSIZ=16，含结尾\0）
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:@-]{0,14}$`)
+
+// NormalizeLocalAddr 校验并规范化 local_addr 配置；空输入返回空字符串
+// 只校验格式，不要求地址/网卡在当前主机存在（配置可能先于网卡就绪）
+func NormalizeLocalAddr(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if ip := net.ParseIP(raw); ip != nil {
+		if ip.IsUnspecified() || ip.IsMulticast() {
+			return "", fmt.Errorf("invalid local_addr %q: must be a unicast address", raw)
+		}
+		return ip.String(), nil
+	}
+	if !interfaceNamePattern.MatchString(raw) {
+		return "", fmt.Errorf("invalid local_addr %q: expected an IP address or a network interface name", raw)
+	}
+	return raw, nil
+}
+
+// ResolveLocalAddr 将 local_addr 解析为拨号用的本地地址
+// IP字面量需已分配在本机某个网卡上；网卡名需存在、已启用且有全局单播地址
+func ResolveLocalAddr(localAddr string) (*net.TCPAddr, error) {
+	if ip := net.ParseIP(localAddr); ip != nil {
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLocalAddrUnavailable, err)
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return &net.TCPAddr{IP: ip}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s is not assigned to any interface", ErrLocalAddrUnavailable, localAddr)
+	}
+
+	iface, err := net.InterfaceByName(localAddr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: interface %s: %v", ErrLocalAddrUnavailable, localAddr, err)
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("%w: interface %s is down", ErrLocalAddrUnavailable, localAddr)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("%w: interface %s: %v", ErrLocalAddrUnavailable, localAddr, err)
+	}
+	var v6 net.IP
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || !(ipNet.IP.IsGlobalUnicast() || ipNet.IP.IsLoopback()) {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return &net.TCPAddr{IP: ipNet.IP}, nil
+		}
+		if v6 == nil {
+			v6 = ipNet.IP
+		}
+	}
+	if v6 != nil {
+		return &net.TCPAddr{IP: v6}, nil
+	}
+	return nil, fmt.Errorf("%w: interface %s has no usable address", ErrLocalAddrUnavailable, localAddr)
+}
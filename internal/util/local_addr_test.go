@@ -0,0 +1,63 @@
+package util
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestNormalizeLocalAddr(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: " 203.0.113.10 ", want: "203.0.113.10"},
+		{in: "2001:DB8::10", want: "2001:db8::10"},
+		{in: "eth1", want: "eth1"},
+		{in: "enp3s0.100", want: "enp3s0.100"},
+		{in: "0.0.0.0", wantErr: true},
+		{in: "224.0.0.1", wantErr: true},
+		{in: "eth 1", wantErr: true},
+		{in: "an-interface-name-too-long", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := NormalizeLocalAddr(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeLocalAddr(%q) expected error, got %q", tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("NormalizeLocalAddr(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestResolveLocalAddr(t *testing.T) {
+	addr, err := ResolveLocalAddr("127.0.0.1")
+	if err != nil {
+		t.Fatalf("loopback should resolve: %v", err)
+	}
+	if !addr.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("unexpected addr: %v", addr)
+	}
+
+	// TEST-NET-1，不会分配在本机
+	if _, err := ResolveLocalAddr("192.0.2.55"); !errors.Is(err, ErrLocalAddrUnavailable) {
+		t.Fatalf("expected ErrLocalAddrUnavailable, got %v", err)
+	}
+	if _, err := ResolveLocalAddr("ccload-nope0"); !errors.Is(err, ErrLocalAddrUnavailable) {
+		t.Fatalf("expected ErrLocalAddrUnavailable for missing interface, got %v", err)
+	}
+}
+
+func TestClassifyError_LocalAddrUnavailable(t *testing.T) {
+	err := errors.Join(errors.New("dial tcp"), ErrLocalAddrUnavailable)
+	status, level, retry := ClassifyError(err)
+	if status != 502 || level != ErrorLevelChannel || !retry {
+		t.Fatalf("got status=%d level=%v retry=%v", status, level, retry)
+	}
+}
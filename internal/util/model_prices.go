@@ -0,0 +1,92 @@
+package util
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// 模型价格表覆盖（2026-10新增）
+// ============================================================================
+// 管理后台 /admin/pricing 维护的价格表优先于内置 basePricing：
+// - 模型键为精确模型名或以 * 结尾的前缀，多条命中时最长键优先
+// - 同一键可有多条不同生效时间的价格，按计费时间取已生效的最新一条；尚未生效时回退内置定价
+// - 缓存价格为0时按缓存倍率（含 cost_cache_multipliers 覆盖）由输入价推算
+// 价格表条目不区分长上下文分段，输入/输出价对全部请求生效。
+
+// PriceOverride 价格表条目（单位：美元/百万tokens）
+type PriceOverride struct {
+	Model             string
+	InputPrice        float64
+	OutputPrice       float64
+	CacheReadPrice    float64
+	CacheWritePrice   float64 // 5分钟缓存写入
+	CacheWrite1hPrice float64
+	EffectiveFrom     int64 // Unix毫秒
+}
+
+type priceOverrideGroup struct {
+	pattern string          // 小写；以 * 结尾表示前缀匹配
+	prices  []PriceOverride // 按生效时间降序
+}
+
+// priceOverrides 当前生效的价格表（按键长度降序；nil 表示无覆盖）
+var priceOverrides atomic.Pointer[[]priceOverrideGroup]
+
+// SetModelPrices 替换价格表覆盖（启动加载与价格表变更后调用）
+func SetModelPrices(entries []PriceOverride) {
+	byPattern := make(map[string]*priceOverrideGroup)
+	for _, e := range entries {
+		pattern := strings.ToLower(strings.TrimSpace(e.Model))
+		if pattern == "" {
+			continue
+		}
+		g, ok := byPattern[pattern]
+		if !ok {
+			g = &priceOverrideGroup{pattern: pattern}
+			byPattern[pattern] = g
+		}
+		g.prices = append(g.prices, e)
+	}
+	if len(byPattern) == 0 {
+		priceOverrides.Store(nil)
+		return
+	}
+	groups := make([]priceOverrideGroup, 0, len(byPattern))
+	for _, g := range byPattern {
+		sort.SliceStable(g.prices, func(i, j int) bool { return g.prices[i].EffectiveFrom > g.prices[j].EffectiveFrom })
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].pattern) != len(groups[j].pattern) {
+			return len(groups[i].pattern) > len(groups[j].pattern)
+		}
+		return groups[i].pattern < groups[j].pattern
+	})
+	priceOverrides.Store(&groups)
+}
+
+// LookupPriceOverride 返回模型在指定时间生效的价格表条目
+// 取最长匹配键下 EffectiveFrom ≤ at 的最新价格；该键尚未生效时继续尝试更短的键
+func LookupPriceOverride(model string, at time.Time) (PriceOverride, bool) {
+	p := priceOverrides.Load()
+	if p == nil {
+		return PriceOverride{}, false
+	}
+	lower := strings.ToLower(model)
+	atMs := at.UnixMilli()
+	for _, g := range *p {
+		prefix, isPrefix := strings.CutSuffix(g.pattern, "*")
+		if (isPrefix && !strings.HasPrefix(lower, prefix)) || (!isPrefix && lower != g.pattern) {
+			continue
+		}
+		for _, price := range g.prices {
+			if price.EffectiveFrom <= atMs {
+				return price, true
+			}
+		}
+	}
+	return PriceOverride{}, false
+}
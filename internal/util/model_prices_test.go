@@ -0,0 +1,58 @@
+package util
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestModelPriceOverrides(t *testing.T) {
+	t.Cleanup(func() { SetModelPrices(nil) })
+	oct1 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	oct10 := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	SetModelPrices([]PriceOverride{
+		{Model: "Claude-Sonnet-4-5*", InputPrice: 2, OutputPrice: 10, EffectiveFrom: oct1.UnixMilli()},
+		{Model: "claude-sonnet-4-5*", InputPrice: 1, OutputPrice: 5, CacheReadPrice: 0.05, CacheWritePrice: 1.5, EffectiveFrom: oct10.UnixMilli()},
+		{Model: "claude-sonnet-4-5-20250929", InputPrice: 4, OutputPrice: 20, EffectiveFrom: oct10.UnixMilli()},
+		{Model: "claude-*", InputPrice: 9, OutputPrice: 9},
+	})
+
+	cost := func(model string, at time.Time, in, out, read, w5m, w1h int) float64 {
+		return CalculateCostDetailedAt(model, at, in, out, read, w5m, w1h)
+	}
+	cases := []struct {
+		name  string
+		model string
+		at    time.Time
+		want  float64
+	}{
+		{"生效前回退更短的键", "claude-sonnet-4-5", oct1.Add(-time.Hour), 18},
+		{"第一档价格", "claude-sonnet-4-5", oct1.Add(time.Hour), 12},
+		{"第二档价格", "claude-sonnet-4-5", oct10, 6},
+		{"更长的精确键优先", "claude-sonnet-4-5-20250929", oct10, 24},
+		{"精确键未生效时回退前缀", "claude-sonnet-4-5-20250929", oct1.Add(time.Hour), 12},
+	}
+	for _, tc := range cases {
+		if got := cost(tc.model, tc.at, 1_000_000, 1_000_000, 0, 0, 0); math.Abs(got-tc.want) > 1e-9 {
+			t.Fatalf("%s: 期望 $%v，实际 $%v", tc.name, tc.want, got)
+		}
+	}
+
+	// 缓存价格：显式价格优先，未填写时按倍率由输入价推算（1h = 1 × 2）
+	if got := cost("claude-sonnet-4-5", oct10, 0, 0, 1_000_000, 1_000_000, 1_000_000); math.Abs(got-(0.05+1.5+2)) > 1e-9 {
+		t.Fatalf("缓存计费不符: %v", got)
+	}
+
+	// 未命中价格表的模型沿用内置定价
+	if got := cost("gpt-4o", oct10, 1_000_000, 0, 0, 0, 0); math.Abs(got-2.5) > 1e-9 {
+		t.Fatalf("内置定价不符: %v", got)
+	}
+
+	SetModelPrices(nil)
+	if _, ok := LookupPriceOverride("claude-sonnet-4-5", oct10); ok {
+		t.Fatal("清空后不应命中价格表")
+	}
+	if got := cost("claude-sonnet-4-5", oct10, 100_000, 0, 0, 0, 0); math.Abs(got-0.3) > 1e-9 {
+		t.Fatalf("清空后应回到内置定价: %v", got)
+	}
+}
@@ -0,0 +1,115 @@
+package util
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ============================================================================
+// 密钥脱敏
+// ============================================================================
+// 上游错误体、调试日志中偶尔会回显 API Key 或令牌。RedactSecrets 统一替换两类内容：
+// - 已知密钥：渠道中存储的全部 Key（由 SetKnownSecrets 注入，精确匹配）
+// - 常见密钥形态：sk-/sk-ant- 前缀、Google AIza 前缀、Bearer 头、x-api-key/api_key/?key= 等参数值
+// 替换结果与 MaskAPIKey 一致（前4位...后4位），便于排障时对应到具体 Key。
+
+// minKnownSecretLen 已知密钥的最小长度（过短的值容易误伤正常文本）
+const minKnownSecretLen = 12
+
+// knownSecrets 已知密钥替换器（nil 表示尚未注入）
+var knownSecrets atomic.Pointer[strings.Replacer]
+
+// secretPatterns 常见密钥形态（带前缀分组的模式只脱敏值部分）
+var secretPatterns = []struct {
+	re        *regexp.Regexp
+	hasPrefix bool
+}{
+	{regexp.MustCompile(`(?i)(bearer\s+)([A-Za-z0-9._~+/=-]{16,})`), true},
+	{regexp.MustCompile(`(?i)((?:x-api-key|x-goog-api-key|api[_-]?key|access[_-]?token|secret)["']?\s*[:=]\s*["']?)([A-Za-z0-9._~+/-]{16,})`), true},
+	{regexp.MustCompile(`([?&]key=)([A-Za-z0-9._~-]{16,})`), true},
+	{regexp.MustCompile(`sk-[A-Za-z0-9_-]{16,}`), false},
+	{regexp.MustCompile(`AIza[0-9A-Za-z_-]{30,}`), false},
+}
+
+// SetKnownSecrets 替换已知密钥集合（渠道Key变更后调用）
+func SetKnownSecrets(secrets []string) {
+	uniq := make(map[string]struct{}, len(secrets))
+	for _, s := range secrets {
+		s = strings.TrimSpace(s)
+		if len(s) >= minKnownSecretLen {
+			uniq[s] = struct{}{}
+		}
+	}
+	if len(uniq) == 0 {
+		knownSecrets.Store(nil)
+		return
+	}
+
+	// 长的优先：避免某个Key是另一个Key的前缀时只替换一部分
+	list := make([]string, 0, len(uniq))
+	for s := range uniq {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i]) != len(list[j]) {
+			return len(list[i]) > len(list[j])
+		}
+		return list[i] < list[j]
+	})
+
+	pairs := make([]string, 0, len(list)*2)
+	for _, s := range list {
+		pairs = append(pairs, s, MaskAPIKey(s))
+	}
+	knownSecrets.Store(strings.NewReplacer(pairs...))
+}
+
+// RedactSecrets 脱敏文本中的密钥
+func RedactSecrets(s string) string {
+	if s == "" {
+		return s
+	}
+	if r := knownSecrets.Load(); r != nil {
+		s = r.Replace(s)
+	}
+	for _, p := range secretPatterns {
+		if p.hasPrefix {
+			s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+				sub := p.re.FindStringSubmatch(m)
+				return sub[1] + maskSecretValue(sub[2])
+			})
+		} else {
+			s = p.re.ReplaceAllStringFunc(s, maskSecretValue)
+		}
+	}
+	return s
+}
+
+// maskSecretValue 脱敏单个值；已是脱敏形式（含...）时保持不变
+func maskSecretValue(v string) string {
+	if strings.Contains(v, "...") {
+		return v
+	}
+	return MaskAPIKey(v)
+}
+
+// redactingWriter 写入前脱敏的 io.Writer（用于标准库 log 输出）
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter 包装 w，写入内容先经过 RedactSecrets
+// log 包每条日志只调用一次 Write，因此不会出现密钥被拆分到两次写入的情况
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, RedactSecrets(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
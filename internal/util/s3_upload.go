@@ -0,0 +1,191 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// S3 兼容对象存储上传（2026-10新增）
+// ============================================================================
+// 用于数据库备份异地保存：AWS S3 / MinIO / Cloudflare R2 等兼容 SigV4 的服务。
+// 使用 path-style 地址（{endpoint}/{bucket}/{key}），单次 PUT 上传（S3 单对象上限 5GB）；
+// 数据库复制同时使用 GET 下载对象。
+// 只依赖标准库，不引入 AWS SDK。
+
+const s3UploadTimeout = 30 * time.Minute
+
+// s3EmptyPayloadHash 空请求体的 SHA256（GET 请求签名使用）
+const s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// ErrS3NotFound 对象不存在
+var ErrS3NotFound = errors.New("s3 object not found")
+
+// S3Config S3 兼容存储配置
+type S3Config struct {
+	Endpoint  string // 如 https://s3.us-east-1.amazonaws.com、http://minio:9000
+	Bucket    string
+	Region    string // 默认 us-east-1（MinIO/R2 通常接受任意值或 auto）
+	AccessKey string
+	SecretKey string
+	Prefix    string // 对象键前缀，如 ccload/backups/
+}
+
+// S3Uploader S3 兼容存储上传客户端
+type S3Uploader struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Uploader 校验配置并创建上传客户端
+func NewS3Uploader(cfg S3Config, client *http.Client) (*S3Uploader, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("s3 endpoint, bucket, access key and secret key are required")
+	}
+	base, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: s3UploadTimeout}
+	}
+	return &S3Uploader{cfg: cfg, base: base, client: client, now: time.Now}, nil
+}
+
+// Location 对象的展示地址（s3://bucket/key）
+func (u *S3Uploader) Location(name string) string {
+	return "s3://" + u.cfg.Bucket + "/" + u.cfg.Prefix + name
+}
+
+// UploadFile 上传本地文件，对象键为 Prefix+name
+func (u *S3Uploader) UploadFile(ctx context.Context, path, name string) error {
+	f, err := os.Open(path) //nolint:gosec // G304: 路径由备份模块生成
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	// SigV4 需要请求体哈希：先完整读一遍计算，再回到开头上传
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return u.put(ctx, name, io.NopCloser(f), size, hex.EncodeToString(h.Sum(nil)))
+}
+
+// PutObject 上传内存数据（2026-10新增，用于复制清单等小对象）
+func (u *S3Uploader) PutObject(ctx context.Context, name string, data []byte) error {
+	sum := sha256.Sum256(data)
+	return u.put(ctx, name, io.NopCloser(bytes.NewReader(data)), int64(len(data)), hex.EncodeToString(sum[:]))
+}
+
+// DownloadTo 下载对象写入 w（2026-10新增，用于从复制数据重建数据库）；对象不存在返回 ErrS3NotFound
+func (u *S3Uploader) DownloadTo(ctx context.Context, name string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.objectURL(name), nil)
+	if err != nil {
+		return err
+	}
+	signS3Request(req, s3EmptyPayloadHash, u.cfg, u.now().UTC())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrS3NotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 download failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (u *S3Uploader) objectURL(name string) string {
+	target := *u.base
+	target.Path = target.Path + "/" + u.cfg.Bucket + "/" + u.cfg.Prefix + name
+	return target.String()
+}
+
+func (u *S3Uploader) put(ctx context.Context, name string, body io.ReadCloser, size int64, payloadHash string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.objectURL(name), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	signS3Request(req, payloadHash, u.cfg, u.now().UTC())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 upload failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// signS3Request 按 AWS Signature Version 4 签名（签名头：host、x-amz-content-sha256、x-amz-date）
+func signS3Request(req *http.Request, payloadHash string, cfg S3Config, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + cfg.Region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretKey), day)
+	key = hmacSHA256(key, cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ============================================================================
+// 上游压缩（2026-10新增）
+// ============================================================================
+// 渠道可开启请求体 gzip（Content-Encoding: gzip，仅适用于明确支持的上游），
+// 并强制 Accept-Encoding 偏好。手工设置 Accept-Encoding 后 Go Transport 不再自动解压，
+// 因此响应由 DecodeContentEncoding 解压（仅支持标准库可处理的 gzip/deflate）。
+
+const (
+	RequestCompressionGzip = "gzip"
+
+	// RequestCompressionMinBytes 小于该大小的请求体不压缩（收益小于压缩开销）
+	RequestCompressionMinBytes = 1024
+)
+
+// supportedAcceptEncodings 允许强制的响应编码（须能在本地解压）
+var supportedAcceptEncodings = map[string]struct{}{
+	"gzip":     {},
+	"deflate":  {},
+	"identity": {},
+}
+
+// NormalizeRequestCompression 校验请求体压缩方式（空表示不压缩）
+func NormalizeRequestCompression(raw string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	if v == "" || v == RequestCompressionGzip {
+		return v, nil
+	}
+	return "", fmt.Errorf("request_compression must be empty or gzip, got %q", raw)
+}
+
+// NormalizeAcceptEncoding 校验并规范化 Accept-Encoding 偏好（逗号分隔，可带 q 值，如 "gzip, identity;q=0.5"）
+func NormalizeAcceptEncoding(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	parts := strings.Split(raw, ",")
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if _, ok := supportedAcceptEncodings[coding]; !ok {
+			return "", fmt.Errorf("accept_encoding: unsupported coding %q (allowed: gzip, deflate, identity)", coding)
+		}
+		if params = strings.TrimSpace(params); params != "" {
+			q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+			if !ok || q == "" || strings.Trim(q, "0123456789.") != "" {
+				return "", fmt.Errorf("accept_encoding: invalid parameter %q", params)
+			}
+			coding += ";q=" + q
+		}
+		out = append(out, coding)
+	}
+	v := strings.Join(out, ", ")
+	if len(v) > 64 {
+		return "", fmt.Errorf("accept_encoding too long (max 64)")
+	}
+	return v, nil
+}
+
+// GzipBody 压缩请求体；体积过小或压缩后不更小时返回 false
+func GzipBody(body []byte) ([]byte, bool) {
+	if len(body) < RequestCompressionMinBytes {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(body) / 3)
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.DefaultCompression)
+	if _, err := zw.Write(body); err != nil {
+		return nil, false
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false
+	}
+	if buf.Len() >= len(body) {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// DecodeContentEncoding 按 Content-Encoding 包装解压读取器；不支持的编码返回 false（调用方原样透传）
+// 解压器延迟到首次 Read 才初始化，不会在此阻塞读取流式响应的首字节
+func DecodeContentEncoding(body io.ReadCloser, encoding string) (io.ReadCloser, bool) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip", "deflate":
+		return &lazyDecodeReader{src: body, encoding: strings.ToLower(strings.TrimSpace(encoding))}, true
+	default:
+		return body, false
+	}
+}
+
+// lazyDecodeReader 延迟初始化的解压读取器，Close 关闭底层响应体
+type lazyDecodeReader struct {
+	src      io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (l *lazyDecodeReader) Read(p []byte) (int, error) {
+	if l.r == nil && l.err == nil {
+		if l.encoding == "deflate" {
+			// HTTP deflate 规范为 zlib 封装，部分服务端发送裸 deflate，此处只支持规范格式
+			l.r, l.err = zlib.NewReader(l.src)
+		} else {
+			l.r, l.err = gzip.NewReader(l.src)
+		}
+		if l.err != nil {
+			l.err = fmt.Errorf("decode %s response: %w", l.encoding, l.err)
+		}
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.r.Read(p)
+}
+
+func (l *lazyDecodeReader) Close() error {
+	return l.src.Close()
+}
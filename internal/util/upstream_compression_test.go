@@ -0,0 +1,31 @@
+package util
+
+import "testing"
+
+func TestNormalizeAcceptEncoding(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{" Identity ", "identity", false},
+		{"gzip,deflate", "gzip, deflate", false},
+		{"gzip, identity; q=0.5", "gzip, identity;q=0.5", false},
+		{"br", "", true},
+		{"gzip;level=9", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeAcceptEncoding(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeAcceptEncoding(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+
+	if v, err := NormalizeRequestCompression("GZIP"); err != nil || v != "gzip" {
+		t.Errorf("NormalizeRequestCompression(GZIP) = %q, %v", v, err)
+	}
+	if _, err := NormalizeRequestCompression("br"); err == nil {
+		t.Error("expected error for br")
+	}
+}
@@ -0,0 +1,292 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/bytedance/sonic"
+)
+
+// ============================================================================
+// 上游交互录制/回放（2026-10新增）
+// ============================================================================
+// VCR 风格的 http.RoundTripper：
+//   - record：请求照常发往上游，响应体在流式返回给调用方的同时被记录，关闭响应体后追加到磁带文件
+//   - replay：不访问上游，按 方法+路径+查询串(+请求体) 从磁带中取出录制的响应
+//
+// 用于在CI中跑完整代理链路（格式转换、冷却、统计）以及离线演示仪表盘。
+// 磁带不保存请求头（含上游Key），查询串与请求体经 RedactSecrets 脱敏；响应头去掉 Set-Cookie 等连接相关头。
+// 不含主机名，录制后渠道URL变化（如测试中每次随机端口）不影响回放。
+
+// VCR 模式
+const (
+	VCRModeRecord = "record"
+	VCRModeReplay = "replay"
+)
+
+// ErrVCRNoInteraction 回放时磁带中没有匹配的交互
+var ErrVCRNoInteraction = errors.New("vcr: no recorded interaction matches request")
+
+// VCRRequest 录制的请求（不含请求头）
+type VCRRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// VCRResponse 录制的响应（Body 为UTF-8文本；二进制响应改存 BodyBase64）
+type VCRResponse struct {
+	Status     int               `json:"status"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	BodyBase64 string            `json:"body_base64,omitempty"`
+}
+
+// VCRInteraction 一次上游交互
+type VCRInteraction struct {
+	Request  VCRRequest  `json:"request"`
+	Response VCRResponse `json:"response"`
+}
+
+// VCRCassette 磁带文件内容
+type VCRCassette struct {
+	Version      int              `json:"version"`
+	Interactions []VCRInteraction `json:"interactions"`
+}
+
+// vcrSkipResponseHeaders 不录制的响应头（小写）
+var vcrSkipResponseHeaders = map[string]struct{}{
+	"set-cookie":        {},
+	"content-length":    {},
+	"connection":        {},
+	"transfer-encoding": {},
+	"date":              {},
+	"keep-alive":        {},
+}
+
+// VCRTransport 录制/回放上游交互的 RoundTripper
+type VCRTransport struct {
+	mode  string
+	path  string
+	inner http.RoundTripper // 仅 record 模式使用
+
+	mu       sync.Mutex
+	cassette VCRCassette
+	used     []bool
+}
+
+// NewVCRTransport 创建录制/回放 Transport
+// record 模式下磁带文件已存在时在其后追加；replay 模式下磁带文件必须存在
+func NewVCRTransport(mode, path string, inner http.RoundTripper) (*VCRTransport, error) {
+	if mode != VCRModeRecord && mode != VCRModeReplay {
+		return nil, fmt.Errorf("invalid vcr mode %q (want record or replay)", mode)
+	}
+	if strings.TrimSpace(path) == "" {
+		return nil, errors.New("vcr cassette path is empty")
+	}
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	t := &VCRTransport{mode: mode, path: path, inner: inner, cassette: VCRCassette{Version: 1}}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := sonic.Unmarshal(data, &t.cassette); err != nil {
+			return nil, fmt.Errorf("parse vcr cassette %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && mode == VCRModeRecord:
+	default:
+		return nil, fmt.Errorf("read vcr cassette: %w", err)
+	}
+	t.used = make([]bool, len(t.cassette.Interactions))
+	return t, nil
+}
+
+// Mode 返回当前模式
+func (t *VCRTransport) Mode() string { return t.mode }
+
+// Interactions 返回磁带中的交互数
+func (t *VCRTransport) Interactions() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.cassette.Interactions)
+}
+
+// Inner 返回录制模式下实际访问上游的 RoundTripper
+func (t *VCRTransport) Inner() http.RoundTripper { return t.inner }
+
+// WithInner 返回共用同一磁带、但录制时经由 inner 访问上游的 RoundTripper
+// 用于渠道专用Transport（证书固定/出站地址）：录制模式下这些安全设置照常生效
+func (t *VCRTransport) WithInner(inner http.RoundTripper) http.RoundTripper {
+	return vcrInnerTransport{t: t, inner: inner}
+}
+
+// vcrInnerTransport 使用指定 inner 的录制/回放视图
+type vcrInnerTransport struct {
+	t     *VCRTransport
+	inner http.RoundTripper
+}
+
+func (v vcrInnerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return v.t.roundTrip(req, v.inner)
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *VCRTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.roundTrip(req, t.inner)
+}
+
+// roundTrip 回放模式查磁带；录制模式经由 inner 访问上游并记录交互
+func (t *VCRTransport) roundTrip(req *http.Request, inner http.RoundTripper) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	key := VCRRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  RedactSecrets(req.URL.RawQuery),
+		Body:   RedactSecrets(string(body)),
+	}
+	if t.mode == VCRModeReplay {
+		return t.replay(req, key)
+	}
+
+	resp, err := inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &vcrRecordingBody{ReadCloser: resp.Body, t: t, req: key, resp: resp}
+	return resp, nil
+}
+
+// replay 查找顺序：未使用的完全匹配 → 未使用的同路由匹配（忽略请求体）→ 已使用的同路由匹配（演示模式循环回放）
+func (t *VCRTransport) replay(req *http.Request, key VCRRequest) (*http.Response, error) {
+	t.mu.Lock()
+	idx := -1
+	sameRoute := func(r VCRRequest) bool {
+		return r.Method == key.Method && r.Path == key.Path && r.Query == key.Query
+	}
+	for pass := 0; pass < 3 && idx < 0; pass++ {
+		for i, it := range t.cassette.Interactions {
+			if !sameRoute(it.Request) || (pass < 2 && t.used[i]) || (pass == 0 && it.Request.Body != key.Body) {
+				continue
+			}
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s %s", ErrVCRNoInteraction, key.Method, key.Path)
+	}
+	t.used[idx] = true
+	rec := t.cassette.Interactions[idx].Response
+	t.mu.Unlock()
+
+	respBody := []byte(rec.Body)
+	if rec.BodyBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(rec.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("vcr: decode body: %w", err)
+		}
+		respBody = decoded
+	}
+	header := make(http.Header, len(rec.Headers))
+	for k, v := range rec.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// record 追加交互并写回磁带文件（临时文件+重命名，避免中途崩溃留下半个文件）
+func (t *VCRTransport) record(it VCRInteraction) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, it)
+	t.used = append(t.used, false)
+
+	data, err := sonic.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), ".vcr-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), t.path)
+}
+
+// vcrRecordingBody 边读边记录响应体，关闭时保存交互（流式响应中途断开时保存已读部分）
+type vcrRecordingBody struct {
+	io.ReadCloser
+	t    *VCRTransport
+	req  VCRRequest
+	resp *http.Response
+	buf  bytes.Buffer
+	once sync.Once
+}
+
+func (b *vcrRecordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *vcrRecordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		rec := VCRResponse{Status: b.resp.StatusCode, Headers: make(map[string]string, len(b.resp.Header))}
+		for k, v := range b.resp.Header {
+			if _, skip := vcrSkipResponseHeaders[strings.ToLower(k)]; !skip && len(v) > 0 {
+				rec.Headers[k] = strings.Join(v, ", ")
+			}
+		}
+		if data := b.buf.Bytes(); utf8.Valid(data) {
+			rec.Body = RedactSecrets(string(data))
+		} else {
+			rec.BodyBase64 = base64.StdEncoding.EncodeToString(data)
+		}
+		if recErr := b.t.record(VCRInteraction{Request: b.req, Response: rec}); recErr != nil {
+			log.Printf("[WARN] [VCR] 保存磁带失败: %v", recErr)
+		}
+	})
+	return err
+}
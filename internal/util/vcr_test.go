@@ -0,0 +1,83 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVCRTransport_RecordThenReplay(t *testing.T) {
+	hits := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = io.WriteString(w, `{"echo":`+string(body)+`,"n":`+string(rune('0'+hits))+`}`)
+	}))
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	rec, err := NewVCRTransport(VCRModeRecord, cassette, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("NewVCRTransport: %v", err)
+	}
+	client := &http.Client{Transport: rec}
+	send := func(c *http.Client, base, body string) (int, string, error) {
+		req, _ := http.NewRequest(http.MethodPost, base+"/v1/messages?key=AIzaSyA1234567890abcdefghijklmnopqrstu", strings.NewReader(body))
+		req.Header.Set("x-api-key", "sk-ant-REDACTED")
+		resp, err := c.Do(req)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data), nil
+	}
+	for _, body := range []string{`{"a":1}`, `{"a":2}`} {
+		if _, _, err := send(client, upstream.URL, body); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	upstream.Close()
+
+	raw, _ := os.ReadFile(cassette)
+	for _, secret := range []string{"AIzaSyA1234567890", "sk-ant-api03-secret", "session=abc", "127.0.0.1"} {
+		if strings.Contains(string(raw), secret) {
+			t.Fatalf("cassette leaks %q: %s", secret, raw)
+		}
+	}
+
+	play, err := NewVCRTransport(VCRModeReplay, cassette, nil)
+	if err != nil || play.Interactions() != 2 {
+		t.Fatalf("replay transport: %v, interactions=%d", err, play.Interactions())
+	}
+	client = &http.Client{Transport: play}
+	// 任意主机均可回放；请求体精确匹配优先
+	status, body, err := send(client, "http://replay.invalid", `{"a":2}`)
+	if err != nil || status != 200 || body != `{"echo":{"a":2},"n":2}` {
+		t.Fatalf("replay exact = %d %q %v", status, body, err)
+	}
+	// 请求体不匹配时按路由取未使用的交互，用尽后循环
+	if _, body, _ = send(client, "http://replay.invalid", `{"a":9}`); body != `{"echo":{"a":1},"n":1}` {
+		t.Fatalf("replay by route = %q", body)
+	}
+	if _, body, _ = send(client, "http://replay.invalid", `{"a":9}`); body == "" {
+		t.Fatal("exhausted cassette should loop")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://replay.invalid/v1/models", nil)
+	if _, err := play.RoundTrip(req); !errors.Is(err, ErrVCRNoInteraction) {
+		t.Fatalf("unmatched route err = %v", err)
+	}
+	if _, err := NewVCRTransport(VCRModeReplay, filepath.Join(t.TempDir(), "missing.json"), nil); err == nil {
+		t.Fatal("replay without cassette should fail")
+	}
+	if _, err := NewVCRTransport("bogus", cassette, nil); err == nil {
+		t.Fatal("invalid mode should fail")
+	}
+}
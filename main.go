@@ -82,6 +82,11 @@ func getTrustedProxies() []string {
 }
 
 func main() {
+	// 日志输出统一脱敏（上游错误体、调试日志中可能回显 API Key）
+	log.SetOutput(util.NewRedactingWriter(os.Stderr))
+	gin.DefaultWriter = util.NewRedactingWriter(os.Stdout)
+	gin.DefaultErrorWriter = util.NewRedactingWriter(os.Stderr)
+
 	// 打印启动 Banner
 	version.PrintBanner()
 
@@ -181,11 +186,34 @@ func main() {
 		IdleTimeout:       60 * time.Second,  // 防止keep-alive连接占用fd
 	}
 	log.Printf("[CONFIG] HTTP WriteTimeout: %v", writeTimeout)
+	// 关闭时立即结束管理端事件流（SSE长连接），避免拖满Shutdown超时
+	httpServer.RegisterOnShutdown(srv.CloseAdminEvents)
+
+	// TLS / mTLS（2026-10新增）：配置 MTLS_CLIENT_CA_FILE 后按客户端证书映射API令牌，与Bearer认证共存
+	tlsCertFile := os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+	if clientCAFile := os.Getenv("MTLS_CLIENT_CA_FILE"); clientCAFile != "" {
+		if tlsCertFile == "" || tlsKeyFile == "" {
+			log.Fatalf("MTLS_CLIENT_CA_FILE 需要同时配置 TLS_CERT_FILE 与 TLS_KEY_FILE")
+		}
+		tlsConfig, err := app.NewMTLSConfig(clientCAFile, os.Getenv("MTLS_CRL_FILE"))
+		if err != nil {
+			log.Fatalf("mTLS配置加载失败: %v", err)
+		}
+		httpServer.TLSConfig = tlsConfig
+		log.Printf("[CONFIG] mTLS客户端证书认证已启用 (CA: %s)", clientCAFile)
+	}
 
 	// 启动HTTP服务器（在goroutine中）
 	go func() {
 		log.Printf("listening on %s", addr)
-		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if tlsCertFile != "" && tlsKeyFile != "" {
+			err = httpServer.ListenAndServeTLS(tlsCertFile, tlsKeyFile)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("HTTP服务器启动失败: %v", err)
 		}
 	}()
@@ -284,6 +284,10 @@ func (m *mockRedisSync) IsEnabled() bool {
 	return true // 假装Redis已启用，绕过安全检查
 }
 
+func (m *mockRedisSync) Ping(_ context.Context) error {
+	return nil // 健康检查探测视为可达
+}
+
 func (m *mockRedisSync) LoadChannelsWithKeysFromRedis(_ context.Context) ([]*model.ChannelWithKeys, error) {
 	return nil, nil // 测试环境无需从Redis加载
 }
@@ -105,12 +105,13 @@ function toSafeNumber(value) {
   return Number.isFinite(num) ? num : 0;
 }
 
-// 加载默认测试内容（从系统设置）
+// 加载默认测试内容（从测试提示词库）
 async function loadDefaultTestContent() {
   try {
-    const setting = await fetchDataWithAuth('/admin/settings/channel_test_content');
-    if (setting && setting.value) {
-      defaultTestContent = setting.value;
+    const prompts = await fetchDataWithAuth('/admin/test-prompts');
+    const prompt = Array.isArray(prompts) && prompts.find(p => p.is_default);
+    if (prompt && prompt.content) {
+      defaultTestContent = prompt.content;
     }
   } catch (e) {
     console.warn('加载默认测试内容失败，使用内置默认值', e);
@@ -65,7 +65,12 @@ async function handleImportCSV(event, importBtn) {
   if (importBtn) importBtn.disabled = true;
 
   try {
-    const resp = await fetchAPIWithAuth('/admin/channels/import', {
+    // 按扩展名识别格式：.json → one-api/new-api 渠道导出，.yaml/.yml → litellm 配置
+    const ext = (file.name.split('.').pop() || '').toLowerCase();
+    const format = ext === 'json' ? 'oneapi' : (ext === 'yaml' || ext === 'yml') ? 'litellm' : '';
+    const url = format ? `/admin/channels/import?format=${format}` : '/admin/channels/import';
+
+    const resp = await fetchAPIWithAuth(url, {
       method: 'POST',
       body: formData
     });
@@ -58,6 +58,7 @@ document.addEventListener('DOMContentLoaded', async () => {
   setupImportExport();
   setupKeyImportPreview();
   setupModelImportPreview();
+  setupChannelValidation();
 
   await window.ChannelTypeManager.renderChannelTypeRadios('channelTypeRadios');
 
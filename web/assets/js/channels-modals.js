@@ -21,6 +21,7 @@ function showAddModal() {
   document.getElementById('inlineEyeOffIcon').style.display = 'block';
   renderInlineKeyTable();
 
+  clearChannelValidationHints();
   document.getElementById('channelModal').classList.add('show');
 }
 
@@ -70,7 +71,19 @@ async function editChannel(id) {
     strategyRadio.checked = true;
   }
   document.getElementById('channelPriority').value = channel.priority;
+  document.getElementById('channelWeight').value = channel.weight || 0;
   document.getElementById('channelDailyCostLimit').value = channel.daily_cost_limit || 0;
+  document.getElementById('channelCostMultiplier').value = channel.cost_multiplier || 1;
+  document.getElementById('channelClientProfile').value = channel.client_profile || '';
+  document.getElementById('channelCertPins').value = channel.cert_pins || '';
+  document.getElementById('channelLocalAddr').value = channel.local_addr || '';
+  document.getElementById('channelRegions').value = channel.regions || '';
+  document.getElementById('channelBetaFeatures').value = channel.beta_features || '';
+  document.getElementById('channelRequestCompression').value = channel.request_compression || '';
+  document.getElementById('channelAcceptEncoding').value = channel.accept_encoding || '';
+  document.getElementById('channelAnthropicCompat').checked = !!channel.anthropic_compat;
+  document.getElementById('channelOpenAICompat').checked = !!channel.openai_compat;
+  document.getElementById('channelIdempotencyKeys').checked = !!channel.idempotency_keys;
   document.getElementById('channelEnabled').checked = channel.enabled;
 
   // 加载模型配置（新格式：models是 {model, redirect_model} 数组）
@@ -84,6 +97,7 @@ async function editChannel(id) {
   if (modelFilterInput) modelFilterInput.value = '';
   renderRedirectTable();
 
+  clearChannelValidationHints();
   document.getElementById('channelModal').classList.add('show');
 }
 
@@ -96,16 +110,9 @@ function closeModal() {
   resetChannelFormDirty();
 }
 
-async function saveChannel(event) {
-  event.preventDefault();
-
+// 从表单构建渠道请求体（保存与实时校验共用）
+function buildChannelFormData() {
   const validKeys = inlineKeyTableData.filter(k => k && k.trim());
-  if (validKeys.length === 0) {
-    alert('请至少添加一个有效的API Key');
-    return;
-  }
-
-  document.getElementById('channelApiKey').value = validKeys.join(',');
 
   // 构建模型配置（新格式：models 数组）
   const models = redirectTableData
@@ -118,17 +125,43 @@ async function saveChannel(event) {
   const channelType = document.querySelector('input[name="channelType"]:checked')?.value || 'anthropic';
   const keyStrategy = document.querySelector('input[name="keyStrategy"]:checked')?.value || 'sequential';
 
-  const formData = {
+  return {
     name: document.getElementById('channelName').value.trim(),
     url: document.getElementById('channelUrl').value.trim(),
     api_key: validKeys.join(','),
     channel_type: channelType,
     key_strategy: keyStrategy,
     priority: parseInt(document.getElementById('channelPriority').value) || 0,
+    weight: parseInt(document.getElementById('channelWeight').value) || 0,
     daily_cost_limit: parseFloat(document.getElementById('channelDailyCostLimit').value) || 0,
+    cost_multiplier: parseFloat(document.getElementById('channelCostMultiplier').value) || 1,
+    client_profile: document.getElementById('channelClientProfile').value.trim(),
+    cert_pins: document.getElementById('channelCertPins').value.trim(),
+    local_addr: document.getElementById('channelLocalAddr').value.trim(),
+    regions: document.getElementById('channelRegions').value.trim(),
+    beta_features: channelType === 'anthropic' ? document.getElementById('channelBetaFeatures').value.trim() : '',
+    request_compression: document.getElementById('channelRequestCompression').value,
+    accept_encoding: document.getElementById('channelAcceptEncoding').value.trim(),
+    anthropic_compat: ['gemini', 'openai'].includes(channelType) && document.getElementById('channelAnthropicCompat').checked,
+    openai_compat: ['anthropic', 'gemini', 'codex'].includes(channelType) && document.getElementById('channelOpenAICompat').checked,
+    idempotency_keys: document.getElementById('channelIdempotencyKeys').checked,
     models: models,
     enabled: document.getElementById('channelEnabled').checked
   };
+}
+
+async function saveChannel(event) {
+  event.preventDefault();
+
+  const validKeys = inlineKeyTableData.filter(k => k && k.trim());
+  if (validKeys.length === 0) {
+    alert('请至少添加一个有效的API Key');
+    return;
+  }
+
+  document.getElementById('channelApiKey').value = validKeys.join(',');
+
+  const formData = buildChannelFormData();
 
   if (!formData.name || !formData.url || !formData.api_key || formData.models.length === 0) {
     if (window.showError) window.showError('请填写所有必填字段（至少添加一个模型）');
@@ -173,6 +206,84 @@ async function saveChannel(event) {
   }
 }
 
+// ============================================================
+// 渠道配置实时校验（POST /admin/channels/validate，不保存）
+// ============================================================
+let channelValidateSeq = 0;
+
+function clearChannelValidationHints() {
+  channelValidateSeq++;
+  const box = document.getElementById('channelValidationHints');
+  if (box) {
+    box.innerHTML = '';
+    box.style.display = 'none';
+  }
+}
+
+async function validateChannelForm(reachability) {
+  const seq = ++channelValidateSeq;
+  const payload = { ...buildChannelFormData(), channel_id: editingChannelId || 0, reachability: !!reachability };
+  const box = document.getElementById('channelValidationHints');
+  if (!box) return;
+  if (reachability) {
+    box.style.display = 'block';
+    box.innerHTML = '<span style="color: var(--neutral-500);">正在检查连通性…</span>';
+  }
+
+  let result;
+  try {
+    const resp = await fetchAPIWithAuth('/admin/channels/validate', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(payload)
+    });
+    if (!resp.success) throw new Error(resp.error || '校验失败');
+    result = resp.data;
+  } catch (e) {
+    console.error('渠道校验失败', e);
+    return;
+  }
+  if (seq !== channelValidateSeq) return; // 已有更新的校验请求
+
+  renderChannelValidationHints(result);
+}
+
+function renderChannelValidationHints(result) {
+  const box = document.getElementById('channelValidationHints');
+  if (!box) return;
+  const lines = [];
+  (result.errors || []).forEach(e => {
+    lines.push(`<div style="color: var(--error-600);">✗ ${escapeHtml(e.field)}: ${escapeHtml(e.message)}</div>`);
+  });
+  (result.warnings || []).forEach(w => {
+    lines.push(`<div style="color: var(--warning-600);">⚠ ${escapeHtml(w.field)}: ${escapeHtml(w.message)}</div>`);
+  });
+  (result.checks || []).forEach(c => {
+    const label = c.name.toUpperCase();
+    if (c.skipped) {
+      lines.push(`<div style="color: var(--neutral-500);">– ${label} 跳过${c.detail ? '（' + escapeHtml(c.detail) + '）' : ''}</div>`);
+    } else if (c.ok) {
+      lines.push(`<div style="color: var(--success-600);">✓ ${label} ${c.duration_ms}ms${c.detail ? ' · ' + escapeHtml(c.detail) : ''}</div>`);
+    } else {
+      lines.push(`<div style="color: var(--error-600);">✗ ${label} ${c.duration_ms}ms · ${escapeHtml(c.error || '')}</div>`);
+    }
+  });
+  box.innerHTML = lines.join('');
+  box.style.display = lines.length ? 'block' : 'none';
+}
+
+function setupChannelValidation() {
+  const form = document.getElementById('channelForm');
+  if (!form || typeof window.debounce !== 'function') return;
+  const debounced = window.debounce(() => {
+    if (document.getElementById('channelModal').classList.contains('show')) {
+      validateChannelForm(false);
+    }
+  }, 600);
+  form.addEventListener('input', debounced);
+  form.addEventListener('change', debounced);
+}
+
 function deleteChannel(id, name) {
   deletingChannelId = id;
   document.getElementById('deleteChannelName').textContent = name;
@@ -261,7 +372,19 @@ async function copyChannel(id, name) {
     strategyRadio.checked = true;
   }
   document.getElementById('channelPriority').value = channel.priority;
+  document.getElementById('channelWeight').value = channel.weight || 0;
   document.getElementById('channelDailyCostLimit').value = channel.daily_cost_limit || 0;
+  document.getElementById('channelCostMultiplier').value = channel.cost_multiplier || 1;
+  document.getElementById('channelClientProfile').value = channel.client_profile || '';
+  document.getElementById('channelCertPins').value = channel.cert_pins || '';
+  document.getElementById('channelLocalAddr').value = channel.local_addr || '';
+  document.getElementById('channelRegions').value = channel.regions || '';
+  document.getElementById('channelBetaFeatures').value = channel.beta_features || '';
+  document.getElementById('channelRequestCompression').value = channel.request_compression || '';
+  document.getElementById('channelAcceptEncoding').value = channel.accept_encoding || '';
+  document.getElementById('channelAnthropicCompat').checked = !!channel.anthropic_compat;
+  document.getElementById('channelOpenAICompat').checked = !!channel.openai_compat;
+  document.getElementById('channelIdempotencyKeys').checked = !!channel.idempotency_keys;
   document.getElementById('channelEnabled').checked = true;
 
   // 加载模型配置（新格式：models是 {model, redirect_model} 数组）
@@ -275,6 +398,7 @@ async function copyChannel(id, name) {
   if (modelFilterInput) modelFilterInput.value = '';
   renderRedirectTable();
 
+  clearChannelValidationHints();
   document.getElementById('channelModal').classList.add('show');
 }
 
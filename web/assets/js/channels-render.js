@@ -115,6 +115,18 @@ function getChannelTypeConfig(channelType) {
       color: '#2563eb',
       bgColor: '#dbeafe',
       borderColor: '#93c5fd'
+    },
+    'azure': {
+      text: 'Azure',
+      color: '#0369a1',
+      bgColor: '#e0f2fe',
+      borderColor: '#7dd3fc'
+    },
+    'mock': {
+      text: 'Mock',
+      color: '#6b7280',
+      bgColor: '#f3f4f6',
+      borderColor: '#d1d5db'
     }
   };
   const type = (channelType || '').toLowerCase();
@@ -105,6 +105,7 @@ function getChannelTypeColor(type) {
     anthropic: '#3b82f6',
     openai: '#10b981',
     azure: '#0ea5e9',
+    mock: '#6b7280',
     bedrock: '#f59e0b',
     vertex: '#8b5cf6',
     openrouter: '#ec4899',
@@ -8,7 +8,7 @@ let currentChannelKeyCooldowns = []; // 当前编辑渠道的Key冷却信息
 let redirectTableData = []; // 模型重定向表格数据: [{from: '', to: ''}]
 let selectedModelIndices = new Set(); // 选中的模型索引集合
 let currentModelFilter = ''; // 模型名称筛选关键字
-let defaultTestContent = 'sonnet 4.0的发布日期是什么'; // 默认测试内容（从设置加载）
+let defaultTestContent = 'test'; // 默认测试内容（从测试提示词库加载）
 let channelStatsRange = 'today'; // 渠道统计时间范围（从设置加载）
 let channelsCache = {}; // 按类型缓存渠道数据: {type: channels[]}
 
@@ -0,0 +1,55 @@
+(function() {
+    // 链接ID位于URL片段（#之后），不会发送到服务端访问日志或链接预览
+    const handoffId = window.location.hash.replace(/^#/, '');
+    const errorMessage = document.getElementById('error-message');
+    const errorText = document.getElementById('error-text');
+    const revealButton = document.getElementById('reveal-button');
+    const revealBox = document.getElementById('handoff-reveal');
+    const resultBox = document.getElementById('handoff-result');
+    const tokenValue = document.getElementById('token-value');
+
+    function showError(message) {
+      errorText.textContent = message;
+      errorMessage.style.display = 'flex';
+    }
+
+    if (!/^[0-9a-f]{64}$/.test(handoffId)) {
+      revealBox.style.display = 'none';
+      showError('链接无效，请向管理员重新索取');
+      return;
+    }
+
+    // 读取后立即从地址栏移除链接ID，避免残留在浏览器历史中
+    history.replaceState(null, '', window.location.pathname);
+
+    revealButton.addEventListener('click', async () => {
+      revealButton.classList.add('loading');
+      revealButton.disabled = true;
+      try {
+        const resp = await fetchAPI('/public/token-handoff/' + handoffId, { method: 'POST' });
+        if (!resp.success) {
+          throw new Error(resp.error || '取回失败');
+        }
+        const data = resp.data || {};
+        tokenValue.value = data.token;
+        if (data.description) {
+          document.getElementById('handoff-title').textContent = '取回API令牌：' + data.description;
+        }
+        revealBox.style.display = 'none';
+        resultBox.style.display = 'block';
+      } catch (error) {
+        revealBox.style.display = 'none';
+        showError('链接已失效、已过期或已被使用：' + error.message);
+      }
+    });
+
+    document.getElementById('copy-button').addEventListener('click', async () => {
+      tokenValue.select();
+      try {
+        await navigator.clipboard.writeText(tokenValue.value);
+      } catch (_) {
+        document.execCommand('copy');
+      }
+      if (window.showNotification) window.showNotification('已复制到剪贴板', 'success');
+    });
+})();
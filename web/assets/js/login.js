@@ -94,6 +94,28 @@
       }
     });
 
+    // SSO 登录回调：会话Token通过 URL 片段传递（不会出现在服务端日志中）
+    const fragment = new URLSearchParams(window.location.hash.slice(1));
+    const ssoToken = fragment.get('sso_token');
+    if (ssoToken) {
+      history.replaceState(null, '', window.location.pathname);
+      localStorage.setItem('ccload_token', ssoToken);
+      localStorage.setItem('ccload_token_expiry', Date.now() + Number(fragment.get('expires_in') || 0) * 1000);
+      const redirect = fragment.get('redirect') || '/web/index.html';
+      window.location.href = redirect.startsWith('/') && !redirect.startsWith('//') ? redirect : '/web/index.html';
+      return;
+    }
+
+    // 启用 SSO 时显示 SSO 登录按钮（保留 redirect 参数）
+    const ssoButton = document.getElementById('sso-login-button');
+    fetchAPI('/login/oidc/status').then((resp) => {
+      if (resp.success && resp.data && resp.data.enabled) {
+        const redirect = new URLSearchParams(window.location.search).get('redirect');
+        if (redirect) ssoButton.href = '/login/oidc?redirect=' + encodeURIComponent(redirect);
+        ssoButton.style.display = 'flex';
+      }
+    }).catch(() => {});
+
     // 检查URL参数中的错误信息
     const urlParams = new URLSearchParams(window.location.search);
     const errorParam = urlParams.get('error');
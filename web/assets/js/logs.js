@@ -4,10 +4,11 @@
     let totalLogs = 0;
     let currentChannelType = 'all'; // 当前选中的渠道类型
     let authTokens = []; // 令牌列表
-    let defaultTestContent = 'sonnet 4.0的发布日期是什么'; // 默认测试内容（从设置加载）
+    let defaultTestContent = 'test'; // 默认测试内容（从测试提示词库加载）
 
     const ACTIVE_REQUESTS_POLL_INTERVAL_MS = 2000;
     let activeRequestsPollTimer = null;
+    let activeRequestsEventSub = null; // 统一事件流订阅（连接正常时替代轮询）
     let activeRequestsFetchInFlight = false;
     let lastActiveRequestIDs = null; // 上次活跃请求ID集合（后端原始数据，用于检测完成）
     let logsLoadInFlight = false;
@@ -81,12 +82,41 @@
     }
 
     function ensureActiveRequestsPollingStarted() {
+      // 优先订阅统一事件流，断线期间回退到轮询
+      if (!activeRequestsEventSub && typeof window.subscribeAdminEvents === 'function') {
+        activeRequestsEventSub = window.subscribeAdminEvents({ types: ['active_requests'] }, {
+          onEvent(type, ev) {
+            if (type === 'hello') {
+              stopActiveRequestsPolling();
+            } else if (type === 'active_requests') {
+              if (currentLogsPage !== 1 || !activeRequestsVisible()) return;
+              applyActiveRequests(Array.isArray(ev.data) ? ev.data : []);
+            } else if (type === 'dropped') {
+              fetchActiveRequests();
+            }
+          },
+          onError() {
+            startActiveRequestsPolling();
+          }
+        });
+        return;
+      }
+      if (!activeRequestsEventSub) startActiveRequestsPolling();
+    }
+
+    function startActiveRequestsPolling() {
       if (activeRequestsPollTimer) return;
       activeRequestsPollTimer = setInterval(async () => {
         if (currentLogsPage !== 1) return;
         await fetchActiveRequests();
       }, ACTIVE_REQUESTS_POLL_INTERVAL_MS);
     }
+
+    function stopActiveRequestsPolling() {
+      if (!activeRequestsPollTimer) return;
+      clearInterval(activeRequestsPollTimer);
+      activeRequestsPollTimer = null;
+    }
     // 生成流式标志HTML（公共函数，避免重复）
     function getStreamFlagHtml(isStreaming) {
       return isStreaming
@@ -94,12 +124,13 @@
         : '<span class="stream-flag placeholder">流</span>';
     }
 
-    // 加载默认测试内容（从系统设置）
+    // 加载默认测试内容（从测试提示词库）
     async function loadDefaultTestContent() {
       try {
-        const setting = await fetchDataWithAuth('/admin/settings/channel_test_content');
-        if (setting && setting.value) {
-          defaultTestContent = setting.value;
+        const prompts = await fetchDataWithAuth('/admin/test-prompts');
+        const prompt = Array.isArray(prompts) && prompts.find(p => p.is_default);
+        if (prompt && prompt.content) {
+          defaultTestContent = prompt.content;
         }
       } catch (e) {
         console.warn('加载默认测试内容失败，使用内置默认值', e);
@@ -241,54 +272,63 @@
     }
 
     // 获取进行中的请求
-    async function fetchActiveRequests() {
-      if (activeRequestsFetchInFlight) return;
-
-      // 优化：当筛选条件不可能匹配进行中请求时，跳过请求
+    // 当前筛选条件下是否可能显示进行中请求（进行中的请求只存在于"本日"，且没有状态码）
+    function activeRequestsVisible() {
       const hours = (document.getElementById('f_hours')?.value || '').trim();
       const status = (document.getElementById('f_status')?.value || '').trim();
-      // 进行中的请求只存在于"本日"，且没有状态码
       if ((hours && hours !== 'today') || status) {
         clearActiveRequestsRows();
         lastActiveRequestIDs = null;
-        return;
+        return false;
       }
+      return true;
+    }
+
+    async function fetchActiveRequests() {
+      if (activeRequestsFetchInFlight) return;
+
+      // 优化：当筛选条件不可能匹配进行中请求时，跳过请求
+      if (!activeRequestsVisible()) return;
 
       activeRequestsFetchInFlight = true;
       try {
         const response = await fetchAPIWithAuth('/admin/active-requests');
         const rawActiveRequests = (response.success && Array.isArray(response.data)) ? response.data : [];
+        applyActiveRequests(rawActiveRequests);
+      } catch (e) {
+        // 静默失败，不影响主日志显示
+      } finally {
+        activeRequestsFetchInFlight = false;
+      }
+    }
 
-        // 检测请求完成：用后端原始ID集合判断“消失的ID”，避免筛选条件变化导致误判
-        const currentIDs = new Set();
-        for (const req of rawActiveRequests) {
-          if (req && (req.id !== undefined && req.id !== null)) {
-            currentIDs.add(String(req.id));
-          }
+    // 处理进行中请求快照（轮询响应与事件流共用）
+    function applyActiveRequests(rawActiveRequests) {
+      // 检测请求完成：用后端原始ID集合判断“消失的ID”，避免筛选条件变化导致误判
+      const currentIDs = new Set();
+      for (const req of rawActiveRequests) {
+        if (req && (req.id !== undefined && req.id !== null)) {
+          currentIDs.add(String(req.id));
         }
-        if (lastActiveRequestIDs !== null) {
-          let hasCompleted = false;
-          for (const id of lastActiveRequestIDs) {
-            if (!currentIDs.has(id)) {
-              hasCompleted = true;
-              break;
-            }
-          }
-          if (hasCompleted && currentLogsPage === 1) {
-            scheduleLoad();
+      }
+      if (lastActiveRequestIDs !== null) {
+        let hasCompleted = false;
+        for (const id of lastActiveRequestIDs) {
+          if (!currentIDs.has(id)) {
+            hasCompleted = true;
+            break;
           }
         }
-        lastActiveRequestIDs = currentIDs;
+        if (hasCompleted && currentLogsPage === 1) {
+          scheduleLoad();
+        }
+      }
+      lastActiveRequestIDs = currentIDs;
 
-        // 根据当前筛选条件过滤（只影响展示，不影响完成检测）
-        const activeRequests = filterActiveRequests(rawActiveRequests);
+      // 根据当前筛选条件过滤（只影响展示，不影响完成检测）
+      const activeRequests = filterActiveRequests(rawActiveRequests);
 
-        renderActiveRequests(activeRequests);
-      } catch (e) {
-        // 静默失败，不影响主日志显示
-      } finally {
-        activeRequestsFetchInFlight = false;
-      }
+      renderActiveRequests(activeRequests);
     }
 
     // 渲染进行中的请求（插入到表格顶部）
@@ -332,6 +372,8 @@
         const hasBytes = !!bytesInfo;
         const infoDisplay = hasBytes ? `已接收 ${bytesInfo}` : '请求处理中...';
         const infoColor = hasBytes ? 'var(--success-600)' : 'var(--neutral-500)';
+        // 取消按钮（中断上游请求，2026-10新增）
+        const cancelBtn = `<button type="button" class="btn btn-secondary btn-sm cancel-active-btn" data-active-id="${Number(req.id)}" title="中断该请求（上游连接断开，客户端收到错误）" style="margin-left: 8px; padding: 0 6px;">终止</button>`;
 
         const row = document.createElement('tr');
         row.className = 'pending-row';
@@ -344,6 +386,7 @@
               <span style="margin-left: 8px;">${escapeHtml(req.model || '-')}</span>
               <span style="margin-left: 8px;">${durationDisplay} ${streamFlag}</span>
               <span style="margin-left: 8px; color: ${infoColor};">${escapeHtml(infoDisplay)}</span>
+              ${cancelBtn}
             </td>
           `;
         } else {
@@ -358,7 +401,7 @@
             <td><span class="status-pending">进行中</span></td>
             <td style="text-align: right;">${durationDisplay} ${streamFlag}</td>
             ${emptyCells}
-            <td><span style="color: ${infoColor};">${escapeHtml(infoDisplay)}</span></td>
+            <td><span style="color: ${infoColor};">${escapeHtml(infoDisplay)}</span>${cancelBtn}</td>
           `;
         }
         fragment.appendChild(row);
@@ -873,6 +916,12 @@
       const tbody = document.getElementById('tbody');
       if (tbody) {
         tbody.addEventListener('click', (e) => {
+          const cancelBtn = e.target.closest('.cancel-active-btn[data-active-id]');
+          if (cancelBtn) {
+            cancelActiveRequest(parseInt(cancelBtn.dataset.activeId), cancelBtn);
+            return;
+          }
+
           const btn = e.target.closest('.test-key-btn[data-action]');
           if (!btn) return;
 
@@ -1199,6 +1248,23 @@
       }
     }
 
+    // ========== 取消进行中的请求（2026-10新增） ==========
+    async function cancelActiveRequest(id, btn) {
+      if (!id) return;
+      if (!confirm(`确定终止进行中的请求 #${id} 吗？上游请求将被中断，客户端会收到错误。`)) return;
+
+      if (btn) btn.disabled = true;
+      try {
+        const resp = await fetchAPIWithAuth(`/admin/logs/active/${id}`, { method: 'DELETE' });
+        if (!resp.success) throw new Error(resp.error || '终止请求失败');
+        if (window.showSuccess) window.showSuccess(`已终止请求 #${id}`);
+      } catch (e) {
+        console.error('终止请求失败', e);
+        if (window.showError) window.showError(e.message || '终止请求失败');
+        if (btn) btn.disabled = false;
+      }
+    }
+
     // ========== 删除 Key（从日志列表入口） ==========
     async function deleteKeyFromLog(channelId, channelName, maskedApiKey) {
       if (!channelId || !maskedApiKey) return;
@@ -5,14 +5,13 @@ let channelsList = [];
 let selectedChannel = null;
 let newModels = new Set(); // 新获取的模型
 
-// 加载默认测试内容
+// 加载默认测试内容（从测试提示词库）
 async function loadDefaultTestContent() {
   try {
-    const settings = await fetchDataWithAuth('/admin/settings');
-    if (!Array.isArray(settings)) return;
-    const setting = settings.find(s => s.key === 'channel_test_content');
-    if (setting) {
-      document.getElementById('modelTestContent').value = setting.value;
+    const prompts = await fetchDataWithAuth('/admin/test-prompts');
+    const prompt = Array.isArray(prompts) && prompts.find(p => p.is_default);
+    if (prompt) {
+      document.getElementById('modelTestContent').value = prompt.content;
       document.getElementById('modelTestContent').placeholder = '';
     }
   } catch (e) {
@@ -0,0 +1,86 @@
+(function() {
+    const TOKEN_KEY = 'ccload_status_token';
+    const STATUS_TEXT = { operational: '正常', degraded: '部分异常', outage: '服务中断', unknown: '暂无数据' };
+    const summaryEl = document.getElementById('status-summary');
+    const typesEl = document.getElementById('status-types');
+    const incidentsEl = document.getElementById('status-incidents');
+    const authEl = document.getElementById('status-auth');
+
+    function escapeText(value) {
+      const div = document.createElement('div');
+      div.textContent = value == null ? '' : String(value);
+      return div.innerHTML;
+    }
+
+    function formatTime(ms) {
+      return ms ? new Date(ms).toLocaleString() : '-';
+    }
+
+    function formatUptime(v) {
+      return v == null ? '-' : v.toFixed(2) + '%';
+    }
+
+    function render(data) {
+      summaryEl.innerHTML = `<span class="status-dot status-${escapeText(data.status)}"></span>` +
+        escapeText(STATUS_TEXT[data.status] || data.status) +
+        ` · 每${Math.round(data.probe_interval_seconds / 60)}分钟探测 · 更新于 ${formatTime(data.updated_at)}`;
+
+      if (!data.types.length) {
+        typesEl.innerHTML = '<div class="channel-card" style="padding: 16px;">尚无探测数据</div>';
+      } else {
+        typesEl.innerHTML = data.types.map(t => `
+          <div class="channel-card mb-4" style="padding: 16px;">
+            <div style="display: flex; justify-content: space-between; align-items: center;">
+              <strong><span class="status-dot status-${escapeText(t.status)}"></span>${escapeText(t.display_name)}</strong>
+              <span>${escapeText(STATUS_TEXT[t.status] || t.status)} · ${t.last_latency_ms}ms</span>
+            </div>
+            <div style="margin-top: 6px; color: var(--color-text-secondary, #6b7280); font-size: 13px;">
+              可用率 1小时 ${formatUptime(t.uptime['1h'])} · 24小时 ${formatUptime(t.uptime['24h'])} · 7天 ${formatUptime(t.uptime['7d'])}
+            </div>
+            <div class="status-bars">
+              ${t.recent.map(s => `<span class="${s.ok ? 'status-operational' : 'status-outage'}" title="${escapeText(formatTime(s.at))} ${s.ok ? s.latency_ms + 'ms' : '失败'}"></span>`).join('')}
+            </div>
+          </div>`).join('');
+      }
+
+      if (!data.incidents.length) {
+        incidentsEl.textContent = '暂无事故';
+      } else {
+        incidentsEl.innerHTML = data.incidents.map(i => `
+          <div style="padding: 6px 0; border-bottom: 1px solid var(--color-border, #e5e7eb);">
+            <strong>${escapeText(i.channel_type)}</strong> ${escapeText(i.reason)}（连续${i.failed_probes}次失败）<br>
+            <span style="font-size: 13px;">${formatTime(i.started_at)} → ${i.resolved_at ? formatTime(i.resolved_at) : '进行中'}</span>
+          </div>`).join('');
+      }
+    }
+
+    async function load() {
+      const token = sessionStorage.getItem(TOKEN_KEY);
+      const headers = token ? { Authorization: 'Bearer ' + token } : {};
+      try {
+        const res = await fetch('/status', { headers });
+        if (res.status === 401) {
+          sessionStorage.removeItem(TOKEN_KEY);
+          authEl.style.display = 'block';
+          summaryEl.textContent = token ? '令牌无效，请重新输入' : '需要API令牌';
+          return;
+        }
+        const resp = await res.json();
+        if (!resp.success) throw new Error(resp.error || '加载失败');
+        authEl.style.display = 'none';
+        render(resp.data);
+      } catch (error) {
+        summaryEl.textContent = '加载失败：' + error.message;
+      }
+    }
+
+    document.getElementById('status-token-submit').addEventListener('click', () => {
+      const value = document.getElementById('status-token').value.trim();
+      if (!value) return;
+      sessionStorage.setItem(TOKEN_KEY, value);
+      load();
+    });
+
+    load();
+    setInterval(load, 60000);
+})();
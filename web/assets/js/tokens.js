@@ -439,6 +439,7 @@
       document.getElementById('tokenExpiry').value = 'never';
       document.getElementById('tokenCostLimitUSD').value = 0;
       document.getElementById('tokenActive').checked = true;
+      document.getElementById('tokenHandoff').value = '0';
       document.getElementById('customExpiryContainer').style.display = 'none';
       document.getElementById('createModal').style.display = 'block';
     }
@@ -469,6 +470,7 @@
         }
       }
       const isActive = document.getElementById('tokenActive').checked;
+      const handoffMinutes = parseInt(document.getElementById('tokenHandoff').value) || 0;
       const costLimitUSD = parseFloat(document.getElementById('tokenCostLimitUSD').value) || 0;
       if (costLimitUSD < 0) {
         window.showNotification('费用上限不能为负数', 'error');
@@ -480,11 +482,19 @@
           headers: {
             'Content-Type': 'application/json'
           },
-          body: JSON.stringify({ description, expires_at: expiresAt, is_active: isActive, cost_limit_usd: costLimitUSD })
+          body: JSON.stringify({ description, expires_at: expiresAt, is_active: isActive, cost_limit_usd: costLimitUSD, handoff_minutes: handoffMinutes })
         });
 
         closeCreateModal();
         document.getElementById('newTokenValue').value = data.token;
+        const handoffContainer = document.getElementById('handoffUrlContainer');
+        if (data.handoff_url) {
+          document.getElementById('handoffUrl').value = window.location.origin + data.handoff_url;
+          document.getElementById('handoffExpiresAt').textContent = new Date(data.handoff_expires_at).toLocaleString();
+          handoffContainer.style.display = 'block';
+        } else {
+          handoffContainer.style.display = 'none';
+        }
         document.getElementById('tokenResultModal').style.display = 'block';
         loadTokens();
         window.showNotification('令牌创建成功', 'success');
@@ -501,9 +511,17 @@
       window.showNotification('已复制到剪贴板', 'success');
     }
 
+    function copyHandoffUrl() {
+      const input = document.getElementById('handoffUrl');
+      input.select();
+      document.execCommand('copy');
+      window.showNotification('已复制到剪贴板', 'success');
+    }
+
     function closeTokenResultModal() {
       document.getElementById('tokenResultModal').style.display = 'none';
       document.getElementById('newTokenValue').value = '';
+      document.getElementById('handoffUrl').value = '';
     }
 
     function editToken(id) {
@@ -530,6 +548,9 @@
       const costUsed = token.cost_used_usd || 0;
       costUsedDisplay.textContent = costUsed > 0 ? `已消耗: $${costUsed.toFixed(4)}` : '';
 
+      // 模型屏蔽（2026-10新增）
+      document.getElementById('editBlockedModels').value = (token.blocked_models || []).join(', ');
+
       // 初始化模型限制状态（2026-01新增）
       editAllowedModels = (token.allowed_models || []).slice();
       selectedAllowedModelIndices.clear();
@@ -553,6 +574,8 @@
       const isActive = document.getElementById('editTokenActive').checked;
       const expiryType = document.getElementById('editTokenExpiry').value;
       const costLimitUSD = parseFloat(document.getElementById('editCostLimitUSD').value) || 0;
+      const blockedModels = document.getElementById('editBlockedModels').value
+        .split(/[,\n]/).map(m => m.trim()).filter(Boolean);
       let expiresAt = null;
       if (expiryType !== 'never') {
         if (expiryType === 'custom') {
@@ -578,7 +601,8 @@
             is_active: isActive,
             expires_at: expiresAt,
             allowed_models: editAllowedModels,  // 2026-01新增：模型限制
-            cost_limit_usd: costLimitUSD         // 2026-01新增：费用上限
+            cost_limit_usd: costLimitUSD,        // 2026-01新增：费用上限
+            blocked_models: blockedModels        // 2026-10新增：模型屏蔽
           })
         });
         closeEditModal();
@@ -119,6 +119,86 @@
   window.fetchDataWithAuth = fetchDataWithAuth;
 })();
 
+// ============================================================
+// 管理端统一事件流（2026-10新增）：GET /admin/events（SSE）
+// EventSource 不支持 Authorization 头，这里用 fetch 流式读取并按SSE格式解析
+// 用法：const sub = subscribeAdminEvents({ types: ['log'], channels: [1] }, { onEvent, onError }); sub.close();
+// 断线后按退避自动重连（基础间隔取服务端 retry 提示），重连时携带 Last-Event-ID 补发错过的事件；
+// onError 在每次断线时调用，调用方可据此回退到轮询；错过的事件无法补发时收到 dropped 事件
+// ============================================================
+(function() {
+  function subscribeAdminEvents(options = {}, handlers = {}) {
+    const params = new URLSearchParams();
+    if (options.types && options.types.length) params.set('types', options.types.join(','));
+    if (options.channels && options.channels.length) params.set('channel_id', options.channels.join(','));
+    const url = '/admin/events' + (params.toString() ? `?${params}` : '');
+
+    let controller = null;
+    let closed = false;
+    let baseRetry = 1000;
+    let retryDelay = baseRetry;
+    let lastEventId = '';
+
+    function dispatch(block) {
+      let type = 'message';
+      let data = '';
+      for (const line of block.split('\n')) {
+        if (line.startsWith('event: ')) type = line.slice(7);
+        else if (line.startsWith('data: ')) data += line.slice(6);
+        else if (line.startsWith('id: ')) lastEventId = line.slice(4);
+        else if (line.startsWith('retry: ')) {
+          const ms = parseInt(line.slice(7), 10);
+          if (ms > 0) baseRetry = ms;
+        }
+      }
+      if (!data) return; // 心跳注释 / retry 提示
+      try {
+        const ev = JSON.parse(data);
+        if (type === 'hello') retryDelay = baseRetry;
+        if (handlers.onEvent) handlers.onEvent(type, ev);
+      } catch (_) { /* 忽略无法解析的事件 */ }
+    }
+
+    async function connect() {
+      controller = new AbortController();
+      try {
+        const headers = lastEventId ? { 'Last-Event-ID': lastEventId } : {};
+        const res = await fetchWithAuth(url, { signal: controller.signal, headers });
+        if (!res.ok || !res.body) throw new Error(`HTTP ${res.status}`);
+        const reader = res.body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = '';
+        for (;;) {
+          const { value, done } = await reader.read();
+          if (done) break;
+          buffer += decoder.decode(value, { stream: true });
+          let idx;
+          while ((idx = buffer.indexOf('\n\n')) >= 0) {
+            dispatch(buffer.slice(0, idx));
+            buffer = buffer.slice(idx + 2);
+          }
+        }
+        throw new Error('stream closed');
+      } catch (err) {
+        if (closed) return;
+        if (handlers.onError) handlers.onError(err);
+        setTimeout(() => { if (!closed) connect(); }, retryDelay);
+        retryDelay = Math.min(retryDelay * 2, 30000);
+      }
+    }
+
+    connect();
+    return {
+      close() {
+        closed = true;
+        if (controller) controller.abort();
+      }
+    };
+  }
+
+  window.subscribeAdminEvents = subscribeAdminEvents;
+})();
+
 // ============================================================
 // 共享UI：顶部导航与背景动画（KISS/DRY）
 // 使用方式：在页面底部引入本文件，并调用 initTopbar('index'|'configs'|'stats'|'trend'|'errors')